@@ -58,6 +58,7 @@ export class CreateDatabaseModalComponent implements OnInit {
       name: ['', [Validators.required, Validators.pattern(CUSTOM_FIELD_NAME_PATTERN)]],
       type: ['TEXT', Validators.required],
       is_indexed: [true],
+      is_sensitive: [false],
     });
     this.customFields.push(fieldGroup);
   }
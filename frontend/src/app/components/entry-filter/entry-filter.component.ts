@@ -162,8 +162,6 @@ export class EntryFilterComponent implements OnInit, OnChanges {
             const lowerVal = filterValue.toLowerCase();
             if (lowerVal === 'true' || lowerVal === '1') { filterValue = true; }
             else if (lowerVal === 'false' || lowerVal === '0') { filterValue = false; }
-          } else if (fieldDefinition.type === 'TEXT' && filter.operator === 'LIKE') {
-            filterValue = `%${filterValue}%`;
           }
         }
         
@@ -197,12 +195,26 @@ export class EntryFilterComponent implements OnInit, OnChanges {
       case 'BOOLEAN':
         return ['=', '!='];
       case 'TEXT':
-        return ['=', '!=', 'LIKE'];
+        return ['=', '!=', 'contains', 'starts_with', 'ends_with', 'equals_ci'];
       default:
         return ['=', '!='];
     }
   }
 
+  /**
+   * Display name of a search operator, the text operators match the value literally
+   */
+  getOperatorLabel(op: string): string {
+    return EntryFilterComponent.operatorLabels[op] ?? op;
+  }
+
+  private static readonly operatorLabels: Record<string, string> = {
+    contains: 'contains',
+    starts_with: 'starts with',
+    ends_with: 'ends with',
+    equals_ci: '= (ignore case)'
+  };
+
   getSelectedFieldType(index: number): string | null {
     const group = this.customFilters.at(index);
     return this.getSelectedFieldTypeForGroup(group);
@@ -163,7 +163,8 @@ export class EntryGridComponent implements OnChanges {
       const width = this.el.nativeElement.getBoundingClientRect().width;
       if (width > 0) return width;
     }
-    const isSidebarShown = window.location.pathname === '/dashboard' || window.location.pathname === '/';
+    const appPath = '/' + window.location.pathname.slice(new URL(document.baseURI).pathname.length);
+    const isSidebarShown = appPath === '/dashboard' || appPath === '/';
     const sidebarWidth = isSidebarShown ? 260 : 0;
     const padding = 48;
     return window.innerWidth - sidebarWidth - padding;
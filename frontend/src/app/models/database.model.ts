@@ -5,22 +5,47 @@ export interface CustomField {
   name: string;
   type: 'TEXT' | 'INTEGER' | 'REAL' | 'BOOLEAN';
   is_indexed?: boolean;
+  is_sensitive?: boolean;
 }
 
 export interface Housekeeping {
   interval: string;
   disk_space: string;
   max_age: string;
+  disk_space_warn_percent?: number; // alert threshold in percent of disk_space, 0 disables it
 }
 
 export interface Stats {
-  entry_count: number;
+  entry_count: number; // all entries, including the processing and failed ones
   total_disk_space_bytes: number;
+  processing_count?: number; // queued or processing entries
+  usage_percent?: number | null; // null if disk_space is disabled
+  alert_active?: boolean;
 }
 
 export interface DatabaseConfig {
   create_preview?: boolean;
   auto_conversion?: string; 
+  conversion_rules?: ConversionRule[]; // per-mime conversions, they take precedence over auto_conversion
+  transcription?: TranscriptionConfig; // audio databases only, omitted if disabled
+  metadata_defaults?: Record<string, unknown> | null; // custom field values filled into uploads lacking them
+  metadata_overrides?: Record<string, unknown> | null; // custom field values forced on every upload
+  waveform_width?: number; // audio previews, 0 or omitted for the default 200x120
+  waveform_height?: number;
+  waveform_color?: string; // hex, e.g. "#1e90ff"
+  waveform_background?: string; // hex or "transparent"
+}
+
+export interface ConversionRule {
+  from: string;
+  to: string;
+}
+
+export interface TranscriptionConfig {
+  endpoint: string;
+  model: string;
+  field: string; // TEXT custom field receiving the text
+  language?: string;
 }
 
 export interface Database {
@@ -31,6 +56,7 @@ export interface Database {
   config: DatabaseConfig;
   housekeeping: Housekeeping;
   custom_fields: CustomField[];
+  custom_indexes?: string[][]; // composite indexes, e.g. [["status", "timestamp"]]
   stats?: Stats;
 }
 
@@ -24,6 +24,7 @@ export interface Entry {
   // Grouped metadata arrays (Used consistently across all GET endpoints now)
   media_fields?: MediaFields;
   custom_fields?: Record<string, any>;
+  transcription_status?: 'pending' | 'done' | 'failed'; // only set on transcribed audio entries
 }
 
 export interface PartialEntryResponse {
@@ -14,7 +14,17 @@ export interface AppInfo {
     client_id: string;
     redirect_url: string;
   };
+  auth?: {
+    methods: string[];     // accepted on the protected routes: 'bearer', 'basic'
+    token_login: string[]; // accepted by POST /api/token: 'basic', 'json', 'oidc'
+  };
   features?: {
     audit_logs: boolean;
   };
+  limits?: {
+    max_custom_fields: number;
+    max_field_name_length: number;
+    max_databases?: number; // 0 for unlimited
+    max_entries_per_database?: number; // 0 for unlimited
+  };
 }
\ No newline at end of file
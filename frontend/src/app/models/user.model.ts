@@ -14,6 +14,9 @@ export interface User {
   username: string;
   is_admin: boolean;
   is_service_account: boolean;
+  disabled: boolean;
+  last_login_at: number | null; // Unix ms, null if never
+  last_activity_at: number | null; // Unix ms, null if never
   permissions: Permission[]; 
   
   // Optional tracking fields (if your backend still returns them)
@@ -63,8 +63,10 @@ export class LoginPageComponent implements OnInit, OnDestroy {
     
     const { username, password } = this.loginForm.value;
     
-    // UPDATED: Call the new basicAuthLogin method
-    this.authService.basicAuthLogin(username, password).pipe(
+    // UPDATED: Call the new basicAuthLogin method, with a JSON body if the server refuses Basic Auth
+    const tokenLogin = this.appInfo?.auth?.token_login;
+    const jsonBody = !!tokenLogin && !tokenLogin.includes('basic') && tokenLogin.includes('json');
+    this.authService.basicAuthLogin(username, password, jsonBody).pipe(
       finalize(() => this.isLoading = false)
     ).subscribe({
       next: () => this.router.navigate(['/dashboard']),
@@ -93,7 +95,7 @@ export class LoginPageComponent implements OnInit, OnDestroy {
     // Construct the standard OIDC Authorization URL using the nested object
     const authEndpoint = `${oidcConfig.issuer_url}/protocol/openid-connect/auth`;
     const clientId = encodeURIComponent(oidcConfig.client_id);
-    const redirectUri = encodeURIComponent(oidcConfig.redirect_url || window.location.origin + '/login');
+    const redirectUri = encodeURIComponent(oidcConfig.redirect_url || new URL('login', document.baseURI).href);
     
     const oidcUrl = `${authEndpoint}?client_id=${clientId}&redirect_uri=${redirectUri}&response_type=code&scope=openid`;
     
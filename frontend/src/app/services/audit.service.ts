@@ -64,7 +64,7 @@ export class AuditService {
     }
 
     // Execute the GET request to /api/audit
-    return this.http.get<AuditLog[]>(`/${this.apiUrl}`, { params }).pipe(
+    return this.http.get<AuditLog[]>(`${this.apiUrl}`, { params }).pipe(
       catchError((error: HttpErrorResponse) => this.handleError(error))
     );
   }
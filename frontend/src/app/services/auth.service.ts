@@ -15,7 +15,7 @@ import { Router } from '@angular/router';
   providedIn: 'root',
 })
 export class AuthService {
-  private readonly apiUrl = '/api';
+  private readonly apiUrl = 'api';
   private readonly ACCESS_TOKEN_KEY = 'access_token';
   private readonly REFRESH_TOKEN_KEY = 'refresh_token';
 
@@ -30,14 +30,16 @@ export class AuthService {
   /**
    * Logs the user in by exchanging credentials for JWT tokens,
    * then fetching the user's profile.
+   * @param jsonBody Send the credentials as JSON body instead of Basic Auth, for servers with Basic Auth disabled
    */
-  basicAuthLogin(username: string, password: string): Observable<User> {
-    // 1. Prepare Basic Auth header for the token endpoint
+  basicAuthLogin(username: string, password: string, jsonBody: boolean = false): Observable<User> {
+    // 1. Prepare Basic Auth header (or the JSON body) for the token endpoint
     const basicAuth = 'Basic ' + btoa(`${username}:${password}`);
-    const headers = new HttpHeaders({ Authorization: basicAuth });
+    const headers = jsonBody ? new HttpHeaders() : new HttpHeaders({ Authorization: basicAuth });
+    const body = jsonBody ? { username, password } : {};
 
     // 2. Call POST /api/token to get the tokens
-    return this.http.post<TokenResponse>(`${this.apiUrl}/token`, {}, { headers }).pipe(
+    return this.http.post<TokenResponse>(`${this.apiUrl}/token`, body, { headers }).pipe(
       tap((tokens) => {
         this.storeTokens(tokens);
       }),
@@ -16,7 +16,9 @@ export const ALLOWED_MIME_TYPES: Record<ContentType, MimeConfig[]> = {
     { mime: 'image/png', streamable: false },
     { mime: 'image/gif', streamable: false },
     { mime: 'image/webp', streamable: false },
-    { mime: 'image/avif', streamable: false }
+    { mime: 'image/avif', streamable: false },
+    { mime: 'image/heic', streamable: false },
+    { mime: 'image/heif', streamable: false }
   ],
   [ContentType.Audio]: [
     { mime: 'audio/mpeg', streamable: true },
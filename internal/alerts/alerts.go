@@ -0,0 +1,52 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Alert is a notification for administrators.
+type Alert struct {
+	Kind         string    `json:"kind"` // e.g. "disk_space_warning"
+	DatabaseID   string    `json:"database_id,omitempty"`
+	DatabaseName string    `json:"database_name,omitempty"`
+	Message      string    `json:"message"`
+	Details      any       `json:"details,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// Subject is a one-line summary of the alert, used e.g. as mail subject.
+func (a Alert) Subject() string {
+	if a.DatabaseName != "" {
+		return fmt.Sprintf("[MediaHub] %s: %s", a.Kind, a.DatabaseName)
+	}
+	return "[MediaHub] " + a.Kind
+}
+
+// Notifier delivers alerts to administrators.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Sinks of the [alerts] section.
+const (
+	SinkLog     = "log"
+	SinkWebhook = "webhook"
+	SinkSMTP    = "smtp"
+)
+
+// LogNotifier writes alerts to the application log. It is the default, so alerts work without further setup.
+type LogNotifier struct {
+	Logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{Logger: logger}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, alert Alert) error {
+	n.Logger.Warn("Alert: "+alert.Message, "kind", alert.Kind, "database_id", alert.DatabaseID, "database_name", alert.DatabaseName)
+	return nil
+}
@@ -0,0 +1,91 @@
+package alerts
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier mails alerts. STARTTLS is used if the server offers it; authentication only if a username is set,
+// net/smtp refuses to send credentials over an unencrypted connection to a remote host.
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+	Timeout  time.Duration // upper bound for delivering one mail, 0 for none
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, alert Alert) error {
+	if n.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(n.Host, strconv.Itoa(n.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to connect to mail server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, n.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start smtp session: %w", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.Host}); err != nil {
+			return fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	if n.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", n.Username, n.Password, n.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := c.Mail(n.From); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start mail body: %w", err)
+	}
+	if _, err := w.Write(n.message(alert)); err != nil {
+		return fmt.Errorf("failed to write mail body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send alert mail: %w", err)
+	}
+	return c.Quit()
+}
+
+func (n *SMTPNotifier) message(alert Alert) []byte {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", alert.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", alert.Time.Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(alert.Message)
+	msg.WriteString("\r\n")
+	return []byte(msg.String())
+}
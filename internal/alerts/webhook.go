@@ -0,0 +1,44 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookNotifier posts alerts as JSON to a URL, e.g. a chat integration or an incident tool.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -34,8 +34,8 @@ func NewRootCMD(frontendFS fs.FS) *cobra.Command {
 		// PersistentPreRunE runs after flags are parsed but before any subcommand's Run
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			// Skip configuration and logger initialization if the user is just asking for help
-			// or running the root command without subcommands.
-			if cmd.Name() == "mediahub" || cmd.Name() == "help" {
+			// or running the root command without subcommands. Exports are verified without a configuration.
+			if cmd.Name() == "mediahub" || cmd.Name() == "help" || cmd.Name() == "verify-export" {
 				return nil
 			}
 
@@ -47,6 +47,8 @@ func NewRootCMD(frontendFS fs.FS) *cobra.Command {
 					cfgPath = envPath
 				}
 			}
+			// Kept for configuration reloads
+			globalOptions.CfgFilePath = cfgPath
 
 			// Load the base configuration from the TOML file
 			loadedConfig, err := conf.LoadConfig(cfgPath, true)
@@ -78,7 +80,11 @@ func NewRootCMD(frontendFS fs.FS) *cobra.Command {
 	// add subcommands
 	rootCMD.AddCommand(NewServeCommand(globalOptions, frontendFS))
 	rootCMD.AddCommand(NewMigrateCommand(globalOptions))
+	rootCMD.AddCommand(NewDBCommand(globalOptions))
 	rootCMD.AddCommand(NewRecoveryCommand(globalOptions))
+	rootCMD.AddCommand(NewDoctorCommand(globalOptions))
+	rootCMD.AddCommand(NewVerifyExportCommand())
+	rootCMD.AddCommand(NewSeedCommand(globalOptions))
 
 	return rootCMD
 }
@@ -3,10 +3,104 @@ package config
 import (
 	"fmt"
 	"mediahub_oss/internal/shared"
+	"net/netip"
 	"runtime"
 	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultMaxJSONFileSize is used if server.max_json_file_size is not configured.
+const DefaultMaxJSONFileSize = "32MB"
+
+// DefaultIdempotencyKeyTTL is used if server.idempotency_key_ttl is not configured.
+const DefaultIdempotencyKeyTTL = "24h"
+
+// Defaults of the upload timestamp checks in [server].
+const (
+	DefaultTimestampPolicy = "reject"
+	DefaultMaxFutureSkew   = "1h"
+	DefaultMinTimestamp    = "2000-01-01"
+)
+
+// DefaultAnonymousRateLimit is used if server.anonymous_rate_limit is not configured.
+const DefaultAnonymousRateLimit = 60
+
+// Defaults of the export limits in [server].
+const (
+	DefaultMaxConcurrentExports = 2
+	DefaultExportWriteTimeout   = "1m"
+)
+
+// DefaultAsyncDeleteThreshold is used if server.async_delete_threshold is not configured.
+const DefaultAsyncDeleteThreshold = 1000
+
+// DefaultShutdownDrain is used if server.shutdown_drain is not configured.
+const DefaultShutdownDrain = "60s"
+
+// DefaultVacuumThreshold is used if database.vacuum_threshold is not configured.
+const DefaultVacuumThreshold = 1000
+
+// DefaultMaxDatabases is used if database.max_databases is not configured.
+const DefaultMaxDatabases = 500
+
+// Defaults for the thresholds above which deleting a database has to be confirmed.
+const (
+	DefaultConfirmDeleteEntries = 10000
+	DefaultConfirmDeleteSize    = "1GB"
+)
+
+// Defaults for the optional ClamAV integration in [security.clamav].
+const (
+	DefaultClamdAddress = "tcp://127.0.0.1:3310"
+	DefaultScanTimeout  = "60s"
+)
+
+// DefaultHealthCriticalChecks are the readiness checks that fail /health/ready if server.health_critical_checks is unset.
+var DefaultHealthCriticalChecks = []string{"database", "storage"}
+
+// DefaultMaxSegmentDuration limits the length of extracted audio segments if [media] max_segment_duration is unset.
+const DefaultMaxSegmentDuration = "10m"
+
+// DefaultFailedUploadRetention is used if [media] failed_upload_retention is unset.
+const DefaultFailedUploadRetention = "1h"
+
+// DefaultOperationLogSize is the number of media operations kept in memory if [media] operation_log_size is unset.
+const DefaultOperationLogSize = 500
+
+// DefaultTranscriptionTimeout is used if [media.transcription] timeout is unset.
+const DefaultTranscriptionTimeout = "2m"
+
+// Defaults for the verification of stored files in [storage.integrity].
+const (
+	DefaultIntegrityInterval = "24h"
+	DefaultIntegrityBudget   = 1000
+	DefaultIntegrityMaxRate  = "20MB"
+	DefaultIntegrityPause    = "1s"
+)
+
+// Sources of the JWT secret in [auth.jwt] secret_source.
+const (
+	JWTSecretSourceConfig = "config" // secret (and previous_secret) from the configuration
+	JWTSecretSourceFile   = "file"   // secret_file, one secret per line, the first one signs
+	JWTSecretSourceDB     = "db"     // shared by all instances through the database
+)
+
+// DefaultJWTRotationGrace is used if [auth.jwt] rotation_grace is unset.
+const DefaultJWTRotationGrace = "1h"
+
+// DefaultActivityInterval is used if [auth] activity_interval is unset.
+const DefaultActivityInterval = "5m"
+
+// Defaults for the notification of administrators in [alerts].
+const (
+	DefaultAlertsSink      = "log"
+	DefaultAlertsTimeout   = "10s"
+	DefaultProcessingAge   = "30m"
+	DefaultSMTPPort        = 587
+	DefaultNoticeRetention = "30d"
 )
 
 // Config holds the application's configuration.
@@ -17,6 +111,9 @@ type Config struct {
 	Logging  LoggingConfig        `toml:"logging" mapstructure:"logging"`
 	Media    MediaConfig          `toml:"media" mapstructure:"media"`
 	Auth     AuthConfig           `toml:"auth" mapstructure:"auth"`
+	Security SecurityConfig       `toml:"security" mapstructure:"security"`
+	Alerts   alertsConfigInternal `toml:"alerts" mapstructure:"alerts"`
+	GRPC     GRPCConfig           `toml:"grpc" mapstructure:"grpc"`
 }
 
 //--------------------
@@ -29,13 +126,44 @@ type DatabaseConfig struct {
 	Source       string `toml:"source" mapstructure:"source"`
 	MaxOpenConns int    `toml:"max_open_conns" mapstructure:"max_open_conns"`
 	MaxIdleConns int    `toml:"max_idle_conns" mapstructure:"max_idle_conns"`
+
+	// Page sizes of entry listings and searches, 0 uses the defaults (100 and 1000)
+	DefaultPageSize int `toml:"default_page_size" mapstructure:"default_page_size"`
+	MaxPageSize     int `toml:"max_page_size" mapstructure:"max_page_size"`
+
+	// Limits of the custom fields of a database, 0 uses the defaults (64 fields, names of 64 characters)
+	MaxCustomFields    int `toml:"max_custom_fields" mapstructure:"max_custom_fields"`
+	MaxFieldNameLength int `toml:"max_field_name_length" mapstructure:"max_field_name_length"`
+
+	// Databases that can be created, and entries per database, 0 for unlimited (defaults 500 and unlimited)
+	MaxDatabases          *int `toml:"max_databases" mapstructure:"max_databases"`
+	MaxEntriesPerDatabase int  `toml:"max_entries_per_database" mapstructure:"max_entries_per_database"`
+
+	// Housekeeping runs deleting more entries release the free pages of the database file, 0 disables it
+	VacuumThreshold *int `toml:"vacuum_threshold" mapstructure:"vacuum_threshold"`
+
+	// Deleting a database with at least this many entries or bytes needs a confirmation token, 0 disables a threshold
+	ConfirmDeleteEntries *int   `toml:"confirm_delete_entries" mapstructure:"confirm_delete_entries"`
+	ConfirmDeleteSize    string `toml:"confirm_delete_size" mapstructure:"confirm_delete_size"`
+
+	// Items the repository cache keeps per namespace (users, databases, entries), 0 uses the default (10000)
+	CacheMaxEntries int `toml:"cache_max_entries" mapstructure:"cache_max_entries"`
+
+	// Locale of the "unicode" collation of searches sorting TEXT fields, e.g. "de" or "sv", empty for the root locale
+	SortLocale string `toml:"sort_locale" mapstructure:"sort_locale"`
+}
+
+// GRPCConfig holds the settings of the gRPC API, which listens on the host of the HTTP server.
+type GRPCConfig struct {
+	Port int `toml:"port" mapstructure:"port"` // 0 disables the gRPC API
 }
 
 // StorageConfig holds settings for file storage.
 type StorageConfig struct {
-	Type  string      `toml:"type" mapstructure:"type"` // "local" or "s3"
-	Local LocalConfig `toml:"local" mapstructure:"local"`
-	S3    S3Config    `toml:"s3" mapstructure:"s3"`
+	Type      string                  `toml:"type" mapstructure:"type"` // "local" or "s3"
+	Local     LocalConfig             `toml:"local" mapstructure:"local"`
+	S3        S3Config                `toml:"s3" mapstructure:"s3"`
+	Integrity integrityConfigInternal `toml:"integrity" mapstructure:"integrity"`
 }
 
 type LocalConfig struct {
@@ -60,13 +188,22 @@ type LoggingConfig struct {
 type AuditConfig struct {
 	Type      string `toml:"type" mapstructure:"type"` // "stdio" or "database"
 	Enabled   bool   `toml:"enabled" mapstructure:"enabled"`
-	Retention string `toml:"retention" mapstructure:"retention"` // How long to keep audit logs (e.g., "7d" for 7 days)
+	Retention string `toml:"retention" mapstructure:"retention"`   // How long to keep audit logs (e.g., "7d" for 7 days)
+	QueueSize int    `toml:"queue_size" mapstructure:"queue_size"` // Events waiting for their delivery, more are dropped instead of delaying requests
 }
 
 // MediaConfig holds media processing settings.
 type MediaConfig struct {
-	FFmpegPath  string `toml:"ffmpeg_path" mapstructure:"ffmpeg_path"`
-	FFprobePath string `toml:"ffprobe_path" mapstructure:"ffprobe_path"`
+	FFmpegPath      string `toml:"ffmpeg_path" mapstructure:"ffmpeg_path"`
+	FFprobePath     string `toml:"ffprobe_path" mapstructure:"ffprobe_path"`
+	PDFRendererPath string `toml:"pdf_renderer_path" mapstructure:"pdf_renderer_path"` // pdftoppm or mutool for PDF previews, looked up in the PATH if empty
+	SelfTest        bool   `toml:"self_test" mapstructure:"self_test"`                 // Run the media self-test on startup
+
+	MaxSegmentDuration    string `toml:"max_segment_duration" mapstructure:"max_segment_duration"`       // Longest audio segment that can be extracted, e.g. "10m"
+	FailedUploadRetention string `toml:"failed_upload_retention" mapstructure:"failed_upload_retention"` // How long the source of a failed async upload is kept for retries, "0" disables
+	OperationLogSize      *int   `toml:"operation_log_size" mapstructure:"operation_log_size"`           // Recent FFmpeg/FFprobe runs kept for GET /api/admin/media_log, 0 disables
+
+	Transcription transcriptionConfigInternal `toml:"transcription" mapstructure:"transcription"`
 }
 
 //--------------------
@@ -74,12 +211,28 @@ type MediaConfig struct {
 //--------------------
 
 type serverConfigInternal struct {
-	Host               string                   `toml:"host" mapstructure:"host"`
-	Port               int                      `toml:"port" mapstructure:"port"`
-	Basepath           string                   `toml:"basepath" mapstructure:"basepath"`
-	MaxSyncUploadSize  string                   `toml:"max_sync_upload_size" mapstructure:"max_sync_upload_size"`
-	CorsAllowedOrigins []string                 `toml:"cors_allowed_origins" mapstructure:"cors_allowed_origins"`
-	Processing         processingConfigInternal `toml:"processing" mapstructure:"processing"`
+	Host                 string                   `toml:"host" mapstructure:"host"`
+	Port                 int                      `toml:"port" mapstructure:"port"`
+	Basepath             string                   `toml:"basepath" mapstructure:"basepath"`
+	RoutePrefix          string                   `toml:"route_prefix" mapstructure:"route_prefix"` // Prefix all routes are served below, e.g. "/mediahub". Unlike basepath, which only sets the <base href> for proxies that strip the prefix, it moves the routes themselves
+	BaseURL              string                   `toml:"base_url" mapstructure:"base_url"`
+	MaxSyncUploadSize    string                   `toml:"max_sync_upload_size" mapstructure:"max_sync_upload_size"`
+	MaxJSONFileSize      string                   `toml:"max_json_file_size" mapstructure:"max_json_file_size"`
+	IdempotencyKeyTTL    string                   `toml:"idempotency_key_ttl" mapstructure:"idempotency_key_ttl"`
+	CorsAllowedOrigins   []string                 `toml:"cors_allowed_origins" mapstructure:"cors_allowed_origins"`
+	HealthCritical       []string                 `toml:"health_critical_checks" mapstructure:"health_critical_checks"` // Readiness checks that return 503 on failure
+	TrustedProxies       []string                 `toml:"trusted_proxies" mapstructure:"trusted_proxies"`               // IPs or CIDRs whose X-Forwarded-* headers are honored
+	AnonymousRateLimit   *int                     `toml:"anonymous_rate_limit" mapstructure:"anonymous_rate_limit"`     // Requests per minute and client IP without authentication, 0 for unlimited
+	TimestampPolicy      string                   `toml:"timestamp_policy" mapstructure:"timestamp_policy"`             // "reject" or "clamp" upload timestamps outside the bounds
+	MaxFutureSkew        string                   `toml:"max_future_skew" mapstructure:"max_future_skew"`               // How far upload timestamps may be ahead of the server time
+	MinTimestamp         string                   `toml:"min_timestamp" mapstructure:"min_timestamp"`                   // Earliest accepted upload timestamp, a date or RFC 3339 time
+	StrictFileNames      bool                     `toml:"strict_filenames" mapstructure:"strict_filenames"`             // Reject renames to the extension of another file type instead of correcting it
+	MaxConcurrentExports *int                     `toml:"max_concurrent_exports" mapstructure:"max_concurrent_exports"` // Exports streamed at the same time, 0 for unlimited
+	ExportWriteTimeout   string                   `toml:"export_write_timeout" mapstructure:"export_write_timeout"`     // Exports to a client that accepts no data for this long are aborted, "0" disables
+	ShutdownDrain        string                   `toml:"shutdown_drain" mapstructure:"shutdown_drain"`                 // How long a shutdown waits for running requests and background processing
+	AsyncDeleteThreshold *int                     `toml:"async_delete_threshold" mapstructure:"async_delete_threshold"` // Bulk deletions of more IDs run as background jobs, 0 only with async=true
+	DisableFrontend      bool                     `toml:"disable_frontend" mapstructure:"disable_frontend"`             // Serve the API only, without the embedded web frontend
+	Processing           processingConfigInternal `toml:"processing" mapstructure:"processing"`
 }
 
 type processingConfigInternal struct {
@@ -88,8 +241,11 @@ type processingConfigInternal struct {
 }
 
 type AuthConfig struct {
-	OIDC oidcConfigInternal `toml:"oidc" mapstructure:"oidc"`
-	JWT  jwtConfigInternal  `toml:"jwt" mapstructure:"jwt"`
+	BasicAuthEnabled *bool              `toml:"basic_auth_enabled" mapstructure:"basic_auth_enabled"` // Basic credentials on the protected routes, default true
+	TokenJSONLogin   bool               `toml:"token_json_login" mapstructure:"token_json_login"`     // POST /api/token accepts {"username", "password"}
+	ActivityInterval string             `toml:"activity_interval" mapstructure:"activity_interval"`   // how often the last activity of a user is written, "0" disables
+	OIDC             oidcConfigInternal `toml:"oidc" mapstructure:"oidc"`
+	JWT              jwtConfigInternal  `toml:"jwt" mapstructure:"jwt"`
 }
 
 type oidcConfigInternal struct {
@@ -102,10 +258,65 @@ type oidcConfigInternal struct {
 	RedirectURL       string `toml:"redirect_url" mapstructure:"redirect_url"`
 }
 
+type SecurityConfig struct {
+	IPAllowlist          []string             `toml:"ip_allowlist" mapstructure:"ip_allowlist"`                       // IPs or CIDRs that may reach the server, empty allows all
+	IPDenylist           []string             `toml:"ip_denylist" mapstructure:"ip_denylist"`                         // IPs or CIDRs that are rejected, wins over ip_allowlist
+	IPFilterExemptHealth bool                 `toml:"ip_filter_exempt_health" mapstructure:"ip_filter_exempt_health"` // The health endpoints are reachable from everywhere
+	IPFilterAudit        bool                 `toml:"ip_filter_audit" mapstructure:"ip_filter_audit"`                 // Log blocked requests as audit events, at most once per minute
+	ClamAV               clamAVConfigInternal `toml:"clamav" mapstructure:"clamav"`
+}
+
+// transcriptionConfigInternal holds the settings shared by the transcription services of all databases.
+// The services themselves are configured per database.
+type transcriptionConfigInternal struct {
+	AuthHeader string `toml:"auth_header" mapstructure:"auth_header"` // Authorization header sent to the services, e.g. "Bearer <key>"
+	Timeout    string `toml:"timeout" mapstructure:"timeout"`         // Upper bound for a single transcription
+}
+
+type clamAVConfigInternal struct {
+	Enabled      bool     `toml:"enabled" mapstructure:"enabled"`
+	Address      string   `toml:"address" mapstructure:"address"`             // "tcp://host:port" or "unix:///path/to/clamd.sock"
+	Timeout      string   `toml:"timeout" mapstructure:"timeout"`             // Upper bound for a single scan
+	FailOpen     bool     `toml:"fail_open" mapstructure:"fail_open"`         // Accept files if clamd is unreachable
+	ContentTypes []string `toml:"content_types" mapstructure:"content_types"` // Content types whose uploads are scanned
+}
+
+type alertsConfigInternal struct {
+	Sink       string     `toml:"sink" mapstructure:"sink"`               // "log", "webhook" or "smtp"
+	WebhookURL string     `toml:"webhook_url" mapstructure:"webhook_url"` // Receives the alerts as JSON POST
+	Timeout    string     `toml:"timeout" mapstructure:"timeout"`         // Upper bound for delivering one alert
+	SMTP       SMTPConfig `toml:"smtp" mapstructure:"smtp"`
+
+	ProcessingAgeAlert string `toml:"processing_age_alert" mapstructure:"processing_age_alert"` // Alert once an entry is processing for longer, "0" disables it
+	NoticeRetention    string `toml:"notice_retention" mapstructure:"notice_retention"`         // How long server notices are kept after their latest repetition, "0" keeps them
+}
+
+// SMTPConfig holds the mail server settings of the smtp alert sink.
+type SMTPConfig struct {
+	Host     string   `toml:"host" mapstructure:"host"`
+	Port     int      `toml:"port" mapstructure:"port"`
+	Username string   `toml:"username" mapstructure:"username"`
+	Password string   `toml:"password" mapstructure:"password"`
+	From     string   `toml:"from" mapstructure:"from"`
+	To       []string `toml:"to" mapstructure:"to"`
+}
+
+type integrityConfigInternal struct {
+	Enabled  bool   `toml:"enabled" mapstructure:"enabled"`
+	Interval string `toml:"interval" mapstructure:"interval"` // Time between two verification runs
+	Budget   int    `toml:"budget" mapstructure:"budget"`     // Entries verified per database and run
+	MaxRate  string `toml:"max_rate" mapstructure:"max_rate"` // Read limit per second, "0" is unlimited
+	Pause    string `toml:"pause" mapstructure:"pause"`       // Sleep between two files
+}
+
 type jwtConfigInternal struct {
 	AccessDuration  string `toml:"access_duration" mapstructure:"access_duration"`
 	RefreshDuration string `toml:"refresh_duration" mapstructure:"refresh_duration"`
 	Secret          string `toml:"secret" mapstructure:"secret"`
+	PreviousSecret  string `toml:"previous_secret" mapstructure:"previous_secret"` // still accepted for validation, config source only
+	SecretSource    string `toml:"secret_source" mapstructure:"secret_source"`
+	SecretFile      string `toml:"secret_file" mapstructure:"secret_file"`
+	RotationGrace   string `toml:"rotation_grace" mapstructure:"rotation_grace"` // how long a rotated secret is accepted, db source only
 }
 
 // --------------------
@@ -113,19 +324,89 @@ type jwtConfigInternal struct {
 // --------------------
 
 type ServerConfig struct {
-	Host               string
-	Port               int
-	Basepath           string
-	MaxSyncUploadSize  uint64 // Threshold in bytes
-	CorsAllowedOrigins []string
-	NFfmpegAsync       int
-	NFfmpegTotal       int
+	Host                 string
+	Port                 int
+	Basepath             string        // <base href> of the frontend, ends with a slash
+	RoutePrefix          string        // Prefix all routes are served below, without trailing slash, empty at the root. Unlike Basepath it moves the routes, not only the <base href>
+	BaseURL              string        // External prefix for generated links, without trailing slash
+	MaxSyncUploadSize    uint64        // Threshold in bytes
+	MaxJSONFileSize      uint64        // Largest file served as base64 JSON, in bytes
+	IdempotencyKeyTTL    time.Duration // How long upload results are replayed for a repeated Idempotency-Key
+	CorsAllowedOrigins   []string
+	HealthCritical       []string       // "database", "storage" and/or "ffmpeg"
+	TrustedProxies       []netip.Prefix // proxies whose X-Forwarded-* headers are honored, single IPs as /32 or /128
+	AnonymousRateLimit   int            // requests per minute and client IP to public databases without authentication, 0 for unlimited
+	TimestampPolicy      string         // "reject" or "clamp" upload timestamps outside MinTimestamp and the server time plus MaxFutureSkew
+	MaxFutureSkew        time.Duration
+	MinTimestamp         time.Time
+	StrictFileNames      bool          // renames to the extension of another file type fail with 400 instead of being corrected
+	MaxConcurrentExports int           // exports streamed at the same time, 0 for unlimited
+	ExportWriteTimeout   time.Duration // exports to a stalled client are aborted after it, 0 disables it
+	ShutdownDrain        time.Duration // how long a shutdown waits for running requests and background processing
+	AsyncDeleteThreshold int           // bulk deletions of more IDs run as background jobs, 0 only on request
+	DisableFrontend      bool          // the embedded web frontend is not served, for API-only deployments
+	NFfmpegAsync         int
+	NFfmpegTotal         int
+}
+
+type AlertsConfig struct {
+	Sink               string
+	WebhookURL         string
+	Timeout            time.Duration
+	SMTP               SMTPConfig
+	ProcessingAgeAlert time.Duration // 0 disables the processing backlog alert
+	NoticeRetention    time.Duration // 0 keeps the server notices
+}
+
+type ClamAVConfig struct {
+	Enabled      bool
+	Address      string
+	Timeout      time.Duration
+	FailOpen     bool
+	ContentTypes []string
+}
+
+// IPFilterConfig restricts the client IPs that reach the server, resolved through the trusted proxies.
+type IPFilterConfig struct {
+	Allow        []netip.Prefix // empty allows every address that is not denied
+	Deny         []netip.Prefix
+	ExemptHealth bool
+	Audit        bool
+}
+
+// Enabled reports whether any address is restricted.
+func (c IPFilterConfig) Enabled() bool {
+	return len(c.Allow) > 0 || len(c.Deny) > 0
+}
+
+type TranscriptionConfig struct {
+	AuthHeader string
+	Timeout    time.Duration
+}
+
+type IntegrityConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	Budget   int
+	MaxRate  uint64 // Bytes per second, 0 is unlimited
+	Pause    time.Duration
+}
+
+// AuthMethods are the ways of logging in with a password, see GetAuthMethods.
+type AuthMethods struct {
+	BasicAuth      bool // Basic credentials are accepted on the protected routes
+	TokenBasicAuth bool // POST /api/token accepts Basic credentials
+	TokenJSONLogin bool // POST /api/token accepts the username and password as JSON body
 }
 
 type JWTConfig struct {
 	AccessDuration  time.Duration
 	RefreshDuration time.Duration
 	Secret          string
+	PreviousSecret  string
+	SecretSource    string
+	SecretFile      string
+	RotationGrace   time.Duration
 }
 
 // --------------------
@@ -138,6 +419,24 @@ func (cfg *Config) GetServerConfig() (ServerConfig, error) {
 		return ServerConfig{}, err
 	}
 
+	maxJSONSize := cfg.Server.MaxJSONFileSize
+	if strings.TrimSpace(maxJSONSize) == "" {
+		maxJSONSize = DefaultMaxJSONFileSize
+	}
+	maxjsonsize_int, err := shared.ParseSize(maxJSONSize)
+	if err != nil {
+		return ServerConfig{}, fmt.Errorf("invalid max_json_file_size value '%s': %w", maxJSONSize, err)
+	}
+
+	idempotencyTTLStr := cfg.Server.IdempotencyKeyTTL
+	if strings.TrimSpace(idempotencyTTLStr) == "" {
+		idempotencyTTLStr = DefaultIdempotencyKeyTTL
+	}
+	idempotencyTTL, err := shared.ParseDuration(idempotencyTTLStr)
+	if err != nil {
+		return ServerConfig{}, fmt.Errorf("invalid idempotency_key_ttl value '%s': %w", idempotencyTTLStr, err)
+	}
+
 	// Parse n_ffmpeg_async
 	nAsync := 0
 	valAsync := strings.TrimSpace(strings.ToLower(cfg.Server.Processing.NFfmpegAsync))
@@ -179,17 +478,176 @@ func (cfg *Config) GetServerConfig() (ServerConfig, error) {
 		return ServerConfig{}, fmt.Errorf("invalid processing configuration: n_ffmpeg_total (%d) must be greater than or equal to n_ffmpeg_async (%d)", nTotal, nAsync)
 	}
 
+	healthCritical := cfg.Server.HealthCritical
+	if len(healthCritical) == 0 {
+		healthCritical = DefaultHealthCriticalChecks
+	}
+	for _, check := range healthCritical {
+		if check != "database" && check != "storage" && check != "ffmpeg" {
+			return ServerConfig{}, fmt.Errorf("invalid health_critical_checks value '%s': must be 'database', 'storage' or 'ffmpeg'", check)
+		}
+	}
+
+	// Routes served below route_prefix need no rewriting by the proxy, the frontend and links follow it
+	routePrefix, err := parseRoutePrefix(cfg.Server.RoutePrefix)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+	baseHref := cfg.Server.Basepath
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.Server.BaseURL), "/")
+	if routePrefix != "" {
+		baseHref = routePrefix + "/"
+		if baseURL == "" {
+			baseURL = routePrefix
+		}
+	}
+
+	trustedProxies, err := parseIPPrefixes("trusted_proxies", cfg.Server.TrustedProxies)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	anonymousRateLimit := DefaultAnonymousRateLimit
+	if cfg.Server.AnonymousRateLimit != nil {
+		anonymousRateLimit = *cfg.Server.AnonymousRateLimit
+	}
+	if anonymousRateLimit < 0 {
+		return ServerConfig{}, fmt.Errorf("invalid anonymous_rate_limit value '%d': must be 0 (unlimited) or positive", anonymousRateLimit)
+	}
+
+	timestampPolicy, maxFutureSkew, minTimestamp, err := parseTimestampBounds(cfg.Server.TimestampPolicy, cfg.Server.MaxFutureSkew, cfg.Server.MinTimestamp)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	maxConcurrentExports := DefaultMaxConcurrentExports
+	if cfg.Server.MaxConcurrentExports != nil {
+		maxConcurrentExports = *cfg.Server.MaxConcurrentExports
+	}
+	if maxConcurrentExports < 0 {
+		return ServerConfig{}, fmt.Errorf("invalid max_concurrent_exports value '%d': must be 0 (unlimited) or positive", maxConcurrentExports)
+	}
+	exportWriteTimeoutStr := cfg.Server.ExportWriteTimeout
+	if strings.TrimSpace(exportWriteTimeoutStr) == "" {
+		exportWriteTimeoutStr = DefaultExportWriteTimeout
+	}
+	exportWriteTimeout, err := shared.ParseDuration(exportWriteTimeoutStr)
+	if err != nil {
+		return ServerConfig{}, fmt.Errorf("invalid export_write_timeout value '%s': %w", exportWriteTimeoutStr, err)
+	}
+	shutdownDrainStr := cfg.Server.ShutdownDrain
+	if strings.TrimSpace(shutdownDrainStr) == "" {
+		shutdownDrainStr = DefaultShutdownDrain
+	}
+	shutdownDrain, err := shared.ParseDuration(shutdownDrainStr)
+	if err != nil {
+		return ServerConfig{}, fmt.Errorf("invalid shutdown_drain value '%s': %w", shutdownDrainStr, err)
+	}
+
+	asyncDeleteThreshold := DefaultAsyncDeleteThreshold
+	if cfg.Server.AsyncDeleteThreshold != nil {
+		asyncDeleteThreshold = *cfg.Server.AsyncDeleteThreshold
+	}
+	if asyncDeleteThreshold < 0 {
+		return ServerConfig{}, fmt.Errorf("invalid async_delete_threshold value '%d': must be 0 (only on request) or positive", asyncDeleteThreshold)
+	}
+
 	return ServerConfig{
-		Host:               cfg.Server.Host,
-		Port:               cfg.Server.Port,
-		Basepath:           cfg.Server.Basepath,
-		MaxSyncUploadSize:  maxsyncsize_int,
-		CorsAllowedOrigins: cfg.Server.CorsAllowedOrigins,
-		NFfmpegAsync:       nAsync,
-		NFfmpegTotal:       nTotal,
+		Host:                 cfg.Server.Host,
+		Port:                 cfg.Server.Port,
+		Basepath:             baseHref,
+		RoutePrefix:          routePrefix,
+		BaseURL:              baseURL,
+		MaxSyncUploadSize:    maxsyncsize_int,
+		MaxJSONFileSize:      maxjsonsize_int,
+		IdempotencyKeyTTL:    idempotencyTTL,
+		CorsAllowedOrigins:   cfg.Server.CorsAllowedOrigins,
+		HealthCritical:       healthCritical,
+		TrustedProxies:       trustedProxies,
+		AnonymousRateLimit:   anonymousRateLimit,
+		TimestampPolicy:      timestampPolicy,
+		MaxFutureSkew:        maxFutureSkew,
+		MinTimestamp:         minTimestamp,
+		StrictFileNames:      cfg.Server.StrictFileNames,
+		DisableFrontend:      cfg.Server.DisableFrontend,
+		MaxConcurrentExports: maxConcurrentExports,
+		ExportWriteTimeout:   exportWriteTimeout,
+		ShutdownDrain:        shutdownDrain,
+		AsyncDeleteThreshold: asyncDeleteThreshold,
+		NFfmpegAsync:         nAsync,
+		NFfmpegTotal:         nTotal,
 	}, nil
 }
 
+// parseTimestampBounds validates the upload timestamp checks and fills in their defaults.
+func parseTimestampBounds(policy, maxFutureSkew, minTimestamp string) (string, time.Duration, time.Time, error) {
+	policy = strings.ToLower(strings.TrimSpace(policy))
+	if policy == "" {
+		policy = DefaultTimestampPolicy
+	}
+	if policy != "reject" && policy != "clamp" {
+		return "", 0, time.Time{}, fmt.Errorf("invalid timestamp_policy value '%s': must be 'reject' or 'clamp'", policy)
+	}
+
+	if strings.TrimSpace(maxFutureSkew) == "" {
+		maxFutureSkew = DefaultMaxFutureSkew
+	}
+	skew, err := shared.ParseDuration(maxFutureSkew)
+	if err != nil {
+		return "", 0, time.Time{}, fmt.Errorf("invalid max_future_skew value '%s': %w", maxFutureSkew, err)
+	}
+	if skew < 0 {
+		return "", 0, time.Time{}, fmt.Errorf("invalid max_future_skew value '%s': must not be negative", maxFutureSkew)
+	}
+
+	minStr := strings.TrimSpace(minTimestamp)
+	if minStr == "" {
+		minStr = DefaultMinTimestamp
+	}
+	minTime, err := time.Parse(time.DateOnly, minStr)
+	if err != nil {
+		if minTime, err = time.Parse(time.RFC3339, minStr); err != nil {
+			return "", 0, time.Time{}, fmt.Errorf("invalid min_timestamp value '%s': must be a date like '2000-01-01' or an RFC 3339 time", minTimestamp)
+		}
+	}
+	return policy, skew, minTime, nil
+}
+
+// parseRoutePrefix normalizes the route_prefix option to "/prefix", or "" to serve at the root.
+func parseRoutePrefix(value string) (string, error) {
+	prefix := strings.Trim(strings.TrimSpace(value), "/")
+	if prefix == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(prefix, "?#{}% \t") {
+		return "", fmt.Errorf("invalid route_prefix value '%s': must be a plain URL path like '/mediahub'", value)
+	}
+	return "/" + prefix, nil
+}
+
+// parseIPPrefixes parses a list of IP addresses or CIDR ranges, option names the list in the errors.
+func parseIPPrefixes(option string, values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s value '%s': %w", option, value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value '%s': must be an IP address or a CIDR range", option, value)
+		}
+		ip = ip.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return prefixes, nil
+}
+
 func (cfg *Config) GetJWTConfig() (JWTConfig, error) {
 	accessDuration, err := shared.ParseDuration(cfg.Auth.JWT.AccessDuration)
 	if err != nil {
@@ -201,9 +659,333 @@ func (cfg *Config) GetJWTConfig() (JWTConfig, error) {
 		return JWTConfig{}, err
 	}
 
+	source := strings.ToLower(strings.TrimSpace(cfg.Auth.JWT.SecretSource))
+	switch source {
+	case "":
+		source = JWTSecretSourceConfig
+	case JWTSecretSourceConfig, JWTSecretSourceDB:
+	case JWTSecretSourceFile:
+		if strings.TrimSpace(cfg.Auth.JWT.SecretFile) == "" {
+			return JWTConfig{}, fmt.Errorf("secret_source \"file\" requires secret_file")
+		}
+	default:
+		return JWTConfig{}, fmt.Errorf("invalid secret_source '%s': expected \"config\", \"file\" or \"db\"", cfg.Auth.JWT.SecretSource)
+	}
+
+	graceStr := cfg.Auth.JWT.RotationGrace
+	if strings.TrimSpace(graceStr) == "" {
+		graceStr = DefaultJWTRotationGrace
+	}
+	rotationGrace, err := shared.ParseDuration(graceStr)
+	if err != nil {
+		return JWTConfig{}, fmt.Errorf("invalid rotation_grace value '%s': %w", graceStr, err)
+	}
+
 	return JWTConfig{
 		AccessDuration:  accessDuration,
 		RefreshDuration: refreshDuration,
 		Secret:          cfg.Auth.JWT.Secret,
+		PreviousSecret:  cfg.Auth.JWT.PreviousSecret,
+		SecretSource:    source,
+		SecretFile:      cfg.Auth.JWT.SecretFile,
+		RotationGrace:   rotationGrace,
+	}, nil
+}
+
+// GetAuthMethods returns the enabled password logins. Without basic_auth_enabled, POST /api/token stays the
+// only place accepting Basic credentials, unless token_json_login replaces them there as well.
+func (cfg *Config) GetAuthMethods() AuthMethods {
+	basicAuth := cfg.Auth.BasicAuthEnabled == nil || *cfg.Auth.BasicAuthEnabled
+	return AuthMethods{
+		BasicAuth:      basicAuth,
+		TokenBasicAuth: basicAuth || !cfg.Auth.TokenJSONLogin,
+		TokenJSONLogin: cfg.Auth.TokenJSONLogin,
+	}
+}
+
+// GetActivityInterval returns how often the last activity of a user is written at most. 0 disables the tracking
+// on the protected routes, logins through POST /api/token are recorded regardless.
+func (cfg *Config) GetActivityInterval() (time.Duration, error) {
+	intervalStr := cfg.Auth.ActivityInterval
+	if strings.TrimSpace(intervalStr) == "" {
+		intervalStr = DefaultActivityInterval
+	}
+	interval, err := shared.ParseDuration(intervalStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid activity_interval value '%s': %w", intervalStr, err)
+	}
+	return interval, nil
+}
+
+// GetIPFilterConfig returns the IP allowlist and denylist, an error names the first invalid value.
+func (cfg *Config) GetIPFilterConfig() (IPFilterConfig, error) {
+	allow, err := parseIPPrefixes("ip_allowlist", cfg.Security.IPAllowlist)
+	if err != nil {
+		return IPFilterConfig{}, err
+	}
+	deny, err := parseIPPrefixes("ip_denylist", cfg.Security.IPDenylist)
+	if err != nil {
+		return IPFilterConfig{}, err
+	}
+	return IPFilterConfig{
+		Allow:        allow,
+		Deny:         deny,
+		ExemptHealth: cfg.Security.IPFilterExemptHealth,
+		Audit:        cfg.Security.IPFilterAudit,
+	}, nil
+}
+
+func (cfg *Config) GetClamAVConfig() (ClamAVConfig, error) {
+	c := cfg.Security.ClamAV
+
+	address := strings.TrimSpace(c.Address)
+	if address == "" {
+		address = DefaultClamdAddress
+	}
+
+	timeoutStr := c.Timeout
+	if strings.TrimSpace(timeoutStr) == "" {
+		timeoutStr = DefaultScanTimeout
+	}
+	timeout, err := shared.ParseDuration(timeoutStr)
+	if err != nil {
+		return ClamAVConfig{}, fmt.Errorf("invalid clamav timeout value '%s': %w", timeoutStr, err)
+	}
+
+	contentTypes := c.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = []string{"file"}
+	}
+
+	return ClamAVConfig{
+		Enabled:      c.Enabled,
+		Address:      address,
+		Timeout:      timeout,
+		FailOpen:     c.FailOpen,
+		ContentTypes: contentTypes,
+	}, nil
+}
+
+func (cfg *Config) GetAlertsConfig() (AlertsConfig, error) {
+	c := cfg.Alerts
+
+	sink := strings.ToLower(strings.TrimSpace(c.Sink))
+	if sink == "" {
+		sink = DefaultAlertsSink
+	}
+
+	timeoutStr := c.Timeout
+	if strings.TrimSpace(timeoutStr) == "" {
+		timeoutStr = DefaultAlertsTimeout
+	}
+	timeout, err := shared.ParseDuration(timeoutStr)
+	if err != nil {
+		return AlertsConfig{}, fmt.Errorf("invalid alerts timeout value '%s': %w", timeoutStr, err)
+	}
+
+	processingAgeStr := c.ProcessingAgeAlert
+	if strings.TrimSpace(processingAgeStr) == "" {
+		processingAgeStr = DefaultProcessingAge
+	}
+	processingAge, err := shared.ParseDuration(processingAgeStr)
+	if err != nil {
+		return AlertsConfig{}, fmt.Errorf("invalid alerts processing_age_alert value '%s': %w", processingAgeStr, err)
+	}
+
+	noticeRetentionStr := c.NoticeRetention
+	if strings.TrimSpace(noticeRetentionStr) == "" {
+		noticeRetentionStr = DefaultNoticeRetention
+	}
+	noticeRetention, err := shared.ParseDuration(noticeRetentionStr)
+	if err != nil {
+		return AlertsConfig{}, fmt.Errorf("invalid alerts notice_retention value '%s': %w", noticeRetentionStr, err)
+	}
+
+	smtpCfg := c.SMTP
+	if smtpCfg.Port == 0 {
+		smtpCfg.Port = DefaultSMTPPort
+	}
+
+	switch sink {
+	case "log":
+	case "webhook":
+		if strings.TrimSpace(c.WebhookURL) == "" {
+			return AlertsConfig{}, fmt.Errorf("invalid alerts configuration: sink 'webhook' requires webhook_url")
+		}
+	case "smtp":
+		if smtpCfg.Host == "" || smtpCfg.From == "" || len(smtpCfg.To) == 0 {
+			return AlertsConfig{}, fmt.Errorf("invalid alerts configuration: sink 'smtp' requires smtp.host, smtp.from and smtp.to")
+		}
+	default:
+		return AlertsConfig{}, fmt.Errorf("invalid alerts sink '%s': must be 'log', 'webhook' or 'smtp'", c.Sink)
+	}
+
+	return AlertsConfig{
+		Sink:               sink,
+		WebhookURL:         strings.TrimSpace(c.WebhookURL),
+		Timeout:            timeout,
+		SMTP:               smtpCfg,
+		ProcessingAgeAlert: processingAge,
+		NoticeRetention:    noticeRetention,
+	}, nil
+}
+
+// GetVacuumThreshold returns the number of entries a housekeeping run has to delete before the
+// freed pages of the database file are released, 0 if disabled.
+func (cfg *Config) GetVacuumThreshold() (int, error) {
+	if cfg.Database.VacuumThreshold == nil {
+		return DefaultVacuumThreshold, nil
+	}
+	if *cfg.Database.VacuumThreshold < 0 {
+		return 0, fmt.Errorf("invalid vacuum threshold %d, expected 0 or more entries", *cfg.Database.VacuumThreshold)
+	}
+	return *cfg.Database.VacuumThreshold, nil
+}
+
+// GetCapacityLimits returns the number of databases and of entries per database that can be
+// created, 0 if unlimited.
+// The repository is set up from the database section alone, so it is a method of DatabaseConfig.
+func (dbCfg DatabaseConfig) GetCapacityLimits() (int, int, error) {
+	maxDatabases := DefaultMaxDatabases
+	if dbCfg.MaxDatabases != nil {
+		maxDatabases = *dbCfg.MaxDatabases
+	}
+	if maxDatabases < 0 {
+		return 0, 0, fmt.Errorf("invalid max_databases %d, expected 0 or more databases", maxDatabases)
+	}
+	if dbCfg.MaxEntriesPerDatabase < 0 {
+		return 0, 0, fmt.Errorf("invalid max_entries_per_database %d, expected 0 or more entries", dbCfg.MaxEntriesPerDatabase)
+	}
+	return maxDatabases, dbCfg.MaxEntriesPerDatabase, nil
+}
+
+// GetSortLocale returns the locale of the unicode sort collation, the root locale if none is configured.
+func (dbCfg DatabaseConfig) GetSortLocale() (language.Tag, error) {
+	if strings.TrimSpace(dbCfg.SortLocale) == "" {
+		return language.Und, nil
+	}
+	tag, err := language.Parse(dbCfg.SortLocale)
+	if err != nil {
+		return language.Und, fmt.Errorf("invalid sort_locale '%s', expected a BCP 47 language tag like 'de': %w", dbCfg.SortLocale, err)
+	}
+	return tag, nil
+}
+
+// GetDeleteConfirmationThresholds returns the entry count and the size in bytes from which on
+// deleting a database has to be confirmed, 0 disables the respective threshold.
+func (cfg *Config) GetDeleteConfirmationThresholds() (int, uint64, error) {
+	entries := DefaultConfirmDeleteEntries
+	if cfg.Database.ConfirmDeleteEntries != nil {
+		entries = *cfg.Database.ConfirmDeleteEntries
+	}
+	if entries < 0 {
+		return 0, 0, fmt.Errorf("invalid confirm_delete_entries %d, expected 0 or more entries", entries)
+	}
+
+	sizeStr := cfg.Database.ConfirmDeleteSize
+	if strings.TrimSpace(sizeStr) == "" {
+		sizeStr = DefaultConfirmDeleteSize
+	}
+	size, err := shared.ParseSize(sizeStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid confirm_delete_size value '%s': %w", sizeStr, err)
+	}
+	return entries, size, nil
+}
+
+// GetMaxSegmentDuration returns the longest audio segment the segment endpoint extracts.
+func (cfg *Config) GetMaxSegmentDuration() (time.Duration, error) {
+	durationStr := cfg.Media.MaxSegmentDuration
+	if strings.TrimSpace(durationStr) == "" {
+		durationStr = DefaultMaxSegmentDuration
+	}
+	maxDuration, err := shared.ParseDuration(durationStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max segment duration value '%s': %w", durationStr, err)
+	}
+	return maxDuration, nil
+}
+
+// GetFailedUploadRetention returns how long sources of failed asynchronous uploads are kept for retries, 0 if disabled.
+func (cfg *Config) GetFailedUploadRetention() (time.Duration, error) {
+	durationStr := cfg.Media.FailedUploadRetention
+	if strings.TrimSpace(durationStr) == "" {
+		durationStr = DefaultFailedUploadRetention
+	}
+	retention, err := shared.ParseDuration(durationStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid failed upload retention value '%s': %w", durationStr, err)
+	}
+	return retention, nil
+}
+
+// GetOperationLogSize returns the number of recent media operations kept in memory, 0 if disabled.
+func (cfg *Config) GetOperationLogSize() (int, error) {
+	if cfg.Media.OperationLogSize == nil {
+		return DefaultOperationLogSize, nil
+	}
+	if *cfg.Media.OperationLogSize < 0 {
+		return 0, fmt.Errorf("invalid operation log size %d, expected 0 or more operations", *cfg.Media.OperationLogSize)
+	}
+	return *cfg.Media.OperationLogSize, nil
+}
+
+// GetTranscriptionConfig returns the settings shared by the transcription services of all databases.
+func (cfg *Config) GetTranscriptionConfig() (TranscriptionConfig, error) {
+	c := cfg.Media.Transcription
+
+	timeoutStr := c.Timeout
+	if strings.TrimSpace(timeoutStr) == "" {
+		timeoutStr = DefaultTranscriptionTimeout
+	}
+	timeout, err := shared.ParseDuration(timeoutStr)
+	if err != nil {
+		return TranscriptionConfig{}, fmt.Errorf("invalid transcription timeout value '%s': %w", timeoutStr, err)
+	}
+
+	return TranscriptionConfig{
+		AuthHeader: strings.TrimSpace(c.AuthHeader),
+		Timeout:    timeout,
+	}, nil
+}
+
+// GetIntegrityConfig returns the settings of the periodic verification of stored files.
+func (cfg *Config) GetIntegrityConfig() (IntegrityConfig, error) {
+	c := cfg.Storage.Integrity
+
+	orDefault := func(value, def string) string {
+		if strings.TrimSpace(value) == "" {
+			return def
+		}
+		return value
+	}
+
+	interval, err := shared.ParseDuration(orDefault(c.Interval, DefaultIntegrityInterval))
+	if err != nil {
+		return IntegrityConfig{}, fmt.Errorf("invalid integrity interval value '%s': %w", c.Interval, err)
+	}
+	if c.Enabled && interval <= 0 {
+		return IntegrityConfig{}, fmt.Errorf("invalid integrity interval value '%s': must be positive", c.Interval)
+	}
+	maxRate, err := shared.ParseSize(orDefault(c.MaxRate, DefaultIntegrityMaxRate))
+	if err != nil {
+		return IntegrityConfig{}, fmt.Errorf("invalid integrity max_rate value '%s': %w", c.MaxRate, err)
+	}
+	pause, err := shared.ParseDuration(orDefault(c.Pause, DefaultIntegrityPause))
+	if err != nil {
+		return IntegrityConfig{}, fmt.Errorf("invalid integrity pause value '%s': %w", c.Pause, err)
+	}
+
+	budget := c.Budget
+	if budget <= 0 {
+		budget = DefaultIntegrityBudget
+	}
+
+	return IntegrityConfig{
+		Enabled:  c.Enabled,
+		Interval: interval,
+		Budget:   budget,
+		MaxRate:  maxRate,
+		Pause:    pause,
 	}, nil
 }
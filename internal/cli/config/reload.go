@@ -0,0 +1,77 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ReloadableKeys are the settings a reload applies to the running server. Changes of all other keys,
+// e.g. the port, the storage root or the database, need a restart.
+var ReloadableKeys = []string{
+	"database.vacuum_threshold",
+	"logging.level",
+	"logging.audit.enabled",
+	"logging.audit.retention",
+	"server.anonymous_rate_limit",
+	"server.max_sync_upload_size",
+	"server.max_json_file_size",
+	"storage.integrity.budget",
+	"storage.integrity.max_rate",
+	"storage.integrity.pause",
+}
+
+// ReloadReport lists the keys that differ between the running and the reloaded configuration.
+type ReloadReport struct {
+	Changed []string `json:"changed"` // applied to the running server
+	Ignored []string `json:"ignored"` // not reloadable, they keep their running value until a restart
+}
+
+// Merge compares the configuration with next, a freshly loaded one, and returns a copy with the
+// reloadable settings of next. The receiver is not modified.
+func (cfg *Config) Merge(next *Config) (*Config, ReloadReport) {
+	merged := *cfg
+	current := settingsByKey(reflect.ValueOf(&merged).Elem())
+	reloaded := settingsByKey(reflect.ValueOf(next).Elem())
+
+	report := ReloadReport{Changed: []string{}, Ignored: []string{}}
+	for key, value := range reloaded {
+		if reflect.DeepEqual(current[key].Interface(), value.Interface()) {
+			continue
+		}
+		if slices.Contains(ReloadableKeys, key) {
+			current[key].Set(value)
+			report.Changed = append(report.Changed, key)
+		} else {
+			report.Ignored = append(report.Ignored, key)
+		}
+	}
+	sort.Strings(report.Changed)
+	sort.Strings(report.Ignored)
+	return &merged, report
+}
+
+// settingsByKey maps the dotted TOML keys of a config struct, e.g. "logging.audit.enabled", to its fields.
+func settingsByKey(v reflect.Value) map[string]reflect.Value {
+	settings := map[string]reflect.Value{}
+	collectSettings("", v, settings)
+	return settings
+}
+
+func collectSettings(prefix string, v reflect.Value, settings map[string]reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			collectSettings(key+".", field, settings)
+			continue
+		}
+		settings[key] = field
+	}
+}
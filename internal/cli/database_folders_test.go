@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/notices"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/storage/localstorage"
+)
+
+func TestCheckDatabaseFolders(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	r := openTestRepository(t, filepath.Join(t.TempDir(), "mediahub.db"))
+	empty, err := r.CreateDatabase(ctx, repository.Database{Name: "empty", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	filled, err := r.CreateDatabase(ctx, repository.Database{Name: "filled", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	if _, err := r.CreateEntry(ctx, filled, repository.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream"}); err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	noticeService := notices.NewService(r, logger)
+
+	// 1. Missing folders are recreated, only the database with entries is reported
+	checkDatabaseFolders(ctx, r, store, noticeService, logger)
+	for _, db := range []repository.Database{empty, filled} {
+		if info, err := os.Stat(filepath.Join(store.RootPath, db.ID.String())); err != nil || !info.IsDir() {
+			t.Errorf("expected the folder of %s to be recreated, got %v", db.Name, err)
+		}
+	}
+	list, err := r.GetNotices(ctx, repository.NoticeFilter{})
+	if err != nil {
+		t.Fatalf("failed to get notices: %v", err)
+	}
+	if len(list) != 1 || list[0].DatabaseID != filled.ID || !strings.Contains(list[0].Message, "recreated empty") {
+		t.Fatalf("expected one notice about %s, got %+v", filled.Name, list)
+	}
+
+	// 2. Existing folders are left alone
+	checkDatabaseFolders(ctx, r, store, noticeService, logger)
+	if list, _ := r.GetNotices(ctx, repository.NoticeFilter{}); len(list) != 1 || list[0].Count != 1 {
+		t.Errorf("expected no new notice, got %+v", list)
+	}
+
+	// 3. A file in place of the folder is reported
+	if err := os.Remove(filepath.Join(store.RootPath, empty.ID.String())); err != nil {
+		t.Fatalf("failed to remove folder: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(store.RootPath, empty.ID.String()), nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	checkDatabaseFolders(ctx, r, store, noticeService, logger)
+	list, _ = r.GetNotices(ctx, repository.NoticeFilter{})
+	if len(list) != 2 || list[0].DatabaseID != empty.ID || !strings.Contains(list[0].Message, "not usable") {
+		t.Errorf("expected a notice about the unusable folder of %s, got %+v", empty.Name, list)
+	}
+}
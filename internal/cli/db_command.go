@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	dbh "mediahub_oss/internal/httpserver/databasehandler"
+	"mediahub_oss/internal/media/ffmpeg"
+	"mediahub_oss/internal/repository/cache"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDBCommand(globalOptions *GlobalOptions) *cobra.Command {
+	var opts vacuumOptions
+
+	dbCmd := &cobra.Command{
+		Use:   "db",
+		Short: "Database file maintenance",
+		Long:  `Maintain the SQLite database file and the database definitions. Use subcommand 'vacuum', 'export-def' or 'apply-def'.`,
+	}
+
+	vacuumCmd := &cobra.Command{
+		Use:   "vacuum",
+		Short: "Return the free pages of the database file to the file system",
+		Long: `Deleted entries leave free pages in the database file. Housekeeping releases them after runs
+that deleted more than database.vacuum_threshold entries, this command releases all of them at once.
+
+Files created before incremental vacuuming was enabled have to be rebuilt once with --full. The rebuild
+copies the whole file, so stop the server first. Before it starts, the database file is copied to a
+timestamped '.bak' file next to it and the copy is verified.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVacuum(globalOptions, opts)
+		},
+	}
+	vacuumCmd.Flags().BoolVar(&opts.full, "full", false, "Rebuild the whole file with VACUUM, converting it to incremental vacuuming")
+	vacuumCmd.Flags().BoolVar(&opts.noBackup, "no-backup", false, "Skip the automatic backup before a full vacuum")
+
+	var exportOpts exportDefOptions
+	exportDefCmd := &cobra.Command{
+		Use:   "export-def",
+		Short: "Write the definition of a database as portable JSON",
+		Long: `Writes the content type, config, housekeeping rules and custom fields of a database as JSON, the
+same document as GET /api/database/definition. Apply it to another environment with 'apply-def'.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExportDef(cmd.Context(), globalOptions, exportOpts)
+		},
+	}
+	exportDefCmd.Flags().StringVar(&exportOpts.name, "name", "", "Name of the database")
+	exportDefCmd.Flags().StringVar(&exportOpts.out, "out", "", "File to write, standard output if empty")
+	_ = exportDefCmd.MarkFlagRequired("name")
+
+	var applyOpts applyDefOptions
+	applyDefCmd := &cobra.Command{
+		Use:   "apply-def",
+		Short: "Create or update a database from a definition",
+		Long: `Creates the database of a definition written by 'export-def', validated like the API. With --update an
+existing database of that name is updated instead: missing custom fields are added, the flags of existing
+ones, the config and the housekeeping rules are replaced. Custom fields missing from the definition are
+kept. Another content type or custom field type is refused without changing anything.
+
+A running server may use the previous settings for up to 5 minutes, use the API to apply definitions to it.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runApplyDef(cmd.Context(), globalOptions, applyOpts)
+		},
+	}
+	applyDefCmd.Flags().StringVar(&applyOpts.in, "in", "", "Definition file to read, - for standard input")
+	applyDefCmd.Flags().BoolVar(&applyOpts.update, "update", false, "Update the database if it exists")
+	_ = applyDefCmd.MarkFlagRequired("in")
+
+	dbCmd.AddCommand(vacuumCmd, exportDefCmd, applyDefCmd)
+	return dbCmd
+}
+
+type exportDefOptions struct {
+	name string
+	out  string
+}
+
+type applyDefOptions struct {
+	in     string
+	update bool
+}
+
+type vacuumOptions struct {
+	full     bool
+	noBackup bool
+}
+
+func runVacuum(globalOptions *GlobalOptions, opts vacuumOptions) error {
+	ctx := context.Background()
+
+	repo, err := sqlite.NewRepository(globalOptions.Conf.Database.Source)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer repo.Close()
+
+	before, err := repo.GetFileStats(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Database file: %d bytes, %d free pages of %d bytes, auto_vacuum %s.\n", before.SizeBytes, before.FreelistPages, before.PageSize, before.AutoVacuum)
+
+	start := time.Now()
+	if opts.full {
+		// Back up the database, or let the user confirm that they have one
+		if opts.noBackup {
+			if !confirmManualBackup() {
+				return nil
+			}
+		} else {
+			backupPath, err := repo.Backup(ctx)
+			if err != nil {
+				return fmt.Errorf("pre-vacuum backup failed (use --no-backup to skip it): %w", err)
+			}
+			fmt.Printf("Backup created and verified: %s\n", backupPath)
+		}
+
+		if err := repo.Vacuum(ctx); err != nil {
+			return err
+		}
+	} else {
+		if before.AutoVacuum != "incremental" {
+			return fmt.Errorf("the database file does not support incremental vacuuming, convert it once with --full")
+		}
+		if _, err := repo.IncrementalVacuum(ctx, 0); err != nil {
+			return err
+		}
+	}
+
+	after, err := repo.GetFileStats(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Vacuum completed in %s: %d bytes (%d bytes released), %d free pages, auto_vacuum %s.\n",
+		time.Since(start).Round(time.Millisecond), after.SizeBytes, before.SizeBytes-min(after.SizeBytes, before.SizeBytes), after.FreelistPages, after.AutoVacuum)
+	return nil
+}
+
+func runExportDef(ctx context.Context, globalOptions *GlobalOptions, opts exportDefOptions) error {
+	cfg := globalOptions.Conf
+
+	repo, err := initRepository(cfg.Database, cache.New(repositoryCacheTTL, cfg.Database.CacheMaxEntries))
+	if err != nil {
+		return fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	defer repo.Close()
+	if err := handleInitialMigration(ctx, repo, globalOptions.Logger); err != nil {
+		return fmt.Errorf("failed to verify or apply database schema: %w", err)
+	}
+
+	databases, err := repo.GetDatabases(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list databases: %w", err)
+	}
+	for _, db := range databases {
+		if db.Name != opts.name {
+			continue
+		}
+		data, err := json.MarshalIndent(dbh.NewDefinition(db), "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		if opts.out == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(opts.out, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write definition: %w", err)
+		}
+		fmt.Printf("Definition of '%s' written to %s\n", db.Name, opts.out)
+		return nil
+	}
+	return fmt.Errorf("database '%s' not found", opts.name)
+}
+
+func runApplyDef(ctx context.Context, globalOptions *GlobalOptions, opts applyDefOptions) error {
+	cfg := globalOptions.Conf
+	logger := globalOptions.Logger
+
+	var in io.Reader = os.Stdin
+	if opts.in != "-" {
+		f, err := os.Open(opts.in)
+		if err != nil {
+			return fmt.Errorf("failed to open definition: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	// Typos in keys would silently fall back to defaults, so they are rejected
+	var def dbh.DatabaseDefinition
+	decoder := json.NewDecoder(in)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&def); err != nil {
+		return fmt.Errorf("invalid definition: %w", err)
+	}
+
+	repo, err := initRepository(cfg.Database, cache.New(repositoryCacheTTL, cfg.Database.CacheMaxEntries))
+	if err != nil {
+		return fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	defer repo.Close()
+	if err := handleInitialMigration(ctx, repo, logger); err != nil {
+		return fmt.Errorf("failed to verify or apply database schema: %w", err)
+	}
+
+	// Conversion targets are validated against the local FFmpeg, like the server does
+	converter, err := ffmpeg.NewFFMPEGConverter(cfg.Media.FFmpegPath, cfg.Media.FFprobePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to start media converter: %w", err)
+	}
+	defer converter.Shutdown(context.Background())
+	converter.SetPDFRenderer(cfg.Media.PDFRendererPath)
+
+	result, err := dbh.ApplyDefinition(ctx, repo, converter, def, opts.update)
+	if err != nil {
+		return err
+	}
+	switch {
+	case result.Created:
+		fmt.Printf("Created %s database '%s' (%s)\n", result.Database.ContentType, result.Database.Name, result.Database.ID)
+	case len(result.Changes) == 0:
+		fmt.Printf("Database '%s' already matches the definition.\n", result.Database.Name)
+	default:
+		fmt.Printf("Updated database '%s':\n", result.Database.Name)
+		for _, change := range result.Changes {
+			fmt.Printf("  %s\n", change)
+		}
+	}
+	for _, name := range result.KeptFields {
+		fmt.Printf("Kept custom field '%s', it is not part of the definition.\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"mediahub_oss/internal/media/ffmpeg"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDoctorCommand(globalOptions *GlobalOptions) *cobra.Command {
+
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Verify that the media toolchain works",
+		Long: `Runs a self-test of FFmpeg and FFprobe using tiny generated test files.
+		Each capability (conversions, waveform previews, metadata probing) is exercised
+		with the same invocations the upload paths use. This does not start the HTTP server.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor(globalOptions)
+		},
+	}
+
+	return doctorCmd
+}
+
+func runDoctor(globalOptions *GlobalOptions) error {
+	ctx := context.Background()
+	cfg := globalOptions.Conf
+
+	converter, err := ffmpeg.NewFFMPEGConverter(cfg.Media.FFmpegPath, cfg.Media.FFprobePath, globalOptions.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to start media converter: %w", err)
+	}
+	defer converter.Shutdown(ctx)
+	converter.SetPDFRenderer(cfg.Media.PDFRendererPath)
+
+	report := converter.SelfTest(ctx)
+
+	fmt.Printf("FFmpeg version:  %s\n", valueOrMissing(report.FFmpegVersion))
+	fmt.Printf("FFprobe version: %s\n", valueOrMissing(report.FFprobeVersion))
+	fmt.Printf("PDF renderer:    %s\n", valueOrMissing(converter.PDFRendererPath()))
+	fmt.Println("Capabilities:")
+
+	names := make([]string, 0, len(report.Capabilities))
+	for name := range report.Capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		result := report.Capabilities[name]
+		if result.OK {
+			fmt.Printf("  [ OK ] %-14s (%s)\n", name, result.Duration.Round(time.Millisecond))
+		} else {
+			fmt.Printf("  [FAIL] %-14s %s\n", name, result.Error)
+		}
+	}
+
+	if !report.AllOK() {
+		return fmt.Errorf("media self-test failed")
+	}
+
+	fmt.Println("All media capabilities are working.")
+	return nil
+}
+
+func valueOrMissing(s string) string {
+	if s == "" {
+		return "not found"
+	}
+	return s
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 
 	"github.com/BurntSushi/toml"
 	"golang.org/x/crypto/bcrypt"
@@ -44,27 +45,46 @@ func Apply(ctx context.Context, config *InitConfig, repo repository.Repository,
 					isIndexed = *cf.IsIndexed
 				}
 				customFields[i] = repository.CustomFieldDef{
-					ID:        i,
-					Name:      cf.Name,
-					Type:      cf.Type,
-					IsIndexed: isIndexed,
+					ID:          i,
+					Name:        cf.Name,
+					Type:        cf.Type,
+					IsIndexed:   isIndexed,
+					IsSensitive: cf.IsSensitive,
+					IsFulltext:  slices.Contains(dbInit.Config.FulltextFields, cf.Name),
+				}
+			}
+			for _, name := range dbInit.Config.FulltextFields {
+				if !slices.ContainsFunc(customFields, func(cf repository.CustomFieldDef) bool { return cf.Name == name }) {
+					logger.Warn("Full-text field is not a custom field of the init database, ignoring it", "database", dbInit.Name, "field", name)
 				}
 			}
 
+			conversionRules := make([]repository.ConversionRule, len(dbInit.Config.ConversionRules))
+			for i, rule := range dbInit.Config.ConversionRules {
+				conversionRules[i] = repository.ConversionRule{From: rule.From, To: rule.To}
+			}
+
 			db := repository.Database{
 				Name:        dbInit.Name,
 				ContentType: dbInit.ContentType,
 				NMaxQueued:  dbInit.NMaxQueued,
 				Config: repository.DatabaseConfig{
-					CreatePreview:  dbInit.Config.CreatePreview,
-					AutoConversion: dbInit.Config.AutoConversion,
+					CreatePreview:   dbInit.Config.CreatePreview,
+					AutoConversion:  dbInit.Config.AutoConversion,
+					PublicRead:      dbInit.Config.PublicRead,
+					ConversionRules: conversionRules,
+					Transcription:   repository.TranscriptionConfig(dbInit.Config.Transcription),
+					GeoFields:       repository.GeoFields(dbInit.Config.GeoFields),
 				},
-				Housekeeping: hk,
-				CustomFields: customFields,
+				Housekeeping:  hk,
+				CustomFields:  customFields,
+				CustomIndexes: dbInit.CustomIndexes,
 			}
 
 			createdDB, err := repo.CreateDatabase(ctx, db)
-			if err != nil {
+			if errors.Is(err, customerrors.ErrLimitReached) {
+				logger.Warn("Skipping init database, the database limit is reached", "database", dbInit.Name, "error", err)
+			} else if err != nil {
 				logger.Error("Failed to create init database", "database", dbInit.Name, "error", err)
 			} else {
 				logger.Info("Created database from init config", "database", dbInit.Name)
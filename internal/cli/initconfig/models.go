@@ -1,6 +1,7 @@
 package initconfig
 
 import (
+	"fmt"
 	"mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared"
 )
@@ -30,9 +31,10 @@ type InitUserPermission struct {
 }
 
 type InitCustomField struct {
-	Name      string `toml:"name"`
-	Type      string `toml:"type"`
-	IsIndexed *bool  `toml:"is_indexed"`
+	Name        string `toml:"name"`
+	Type        string `toml:"type"`
+	IsIndexed   *bool  `toml:"is_indexed"`
+	IsSensitive bool   `toml:"is_sensitive"`
 }
 
 // InitDatabase represents a database entry to be created.
@@ -43,12 +45,41 @@ type InitDatabase struct {
 	Config       InitDatabaseConfig `toml:"config"`
 	Housekeeping InitHousekeeping   `toml:"housekeeping"`
 	CustomFields []InitCustomField  `toml:"custom_fields"`
+
+	// Composite indexes, each a list of standard, media or custom field names
+	CustomIndexes [][]string `toml:"custom_indexes"`
 }
 
 // InitDatabaseConfig maps to the repository.DatabaseConfig.
 type InitDatabaseConfig struct {
 	CreatePreview  bool   `toml:"create_previews"` // Maps to "create_previews" or "create_preview" in TOML
 	AutoConversion string `toml:"auto_conversion"`
+	PublicRead     bool   `toml:"public_read"` // entries can be read without authentication
+
+	ConversionRules []InitConversionRule `toml:"conversion_rules"`
+	Transcription   InitTranscription    `toml:"transcription"`
+	FulltextFields  []string             `toml:"fulltext_fields"` // TEXT custom fields searchable with MATCH
+	GeoFields       InitGeoFields        `toml:"geo_fields"`      // REAL custom fields with the coordinates of the entries
+}
+
+// InitGeoFields maps to the repository.GeoFields.
+type InitGeoFields struct {
+	Lat string `toml:"lat"`
+	Lon string `toml:"lon"`
+}
+
+// InitTranscription maps to the repository.TranscriptionConfig.
+type InitTranscription struct {
+	Endpoint string `toml:"endpoint"`
+	Model    string `toml:"model"`
+	Field    string `toml:"field"`
+	Language string `toml:"language"`
+}
+
+// InitConversionRule maps to the repository.ConversionRule.
+type InitConversionRule struct {
+	From string `toml:"from"`
+	To   string `toml:"to"`
 }
 
 // InitHousekeeping uses strings for values that need parsing (e.g., "100G", "30d").
@@ -56,6 +87,10 @@ type InitHousekeeping struct {
 	Interval  string `toml:"interval"`
 	DiskSpace string `toml:"disk_space"`
 	MaxAge    string `toml:"max_age"`
+
+	DiskSpaceWarnPercent *int `toml:"disk_space_warn_percent"` // alert threshold in percent of disk_space, 0 disables it
+
+	AgeBasis string `toml:"age_basis"` // "capture" (default) or "ingestion", the time max_age is measured from
 }
 
 // GetHousekeeping converts the string-based TOML values into the required formats.
@@ -79,9 +114,28 @@ func (initdb *InitDatabase) GetHousekeeping() (repository.DatabaseHK, error) {
 		return repository.DatabaseHK{}, err
 	}
 
+	warnPercent := repository.DefaultDiskSpaceWarnPercent
+	if initdb.Housekeeping.DiskSpaceWarnPercent != nil {
+		warnPercent = *initdb.Housekeeping.DiskSpaceWarnPercent
+	}
+	if warnPercent < 0 || warnPercent > 100 {
+		return repository.DatabaseHK{}, fmt.Errorf("invalid disk_space_warn_percent %d, expected 0 to 100", warnPercent)
+	}
+
+	ageBasis := initdb.Housekeeping.AgeBasis
+	switch ageBasis {
+	case "":
+		ageBasis = repository.AgeBasisCapture
+	case repository.AgeBasisCapture, repository.AgeBasisIngestion:
+	default:
+		return repository.DatabaseHK{}, fmt.Errorf("invalid age_basis %q, expected %q or %q", ageBasis, repository.AgeBasisCapture, repository.AgeBasisIngestion)
+	}
+
 	return repository.DatabaseHK{
-		Interval:  interval,
-		DiskSpace: diskSpace,
-		MaxAge:    maxAge,
+		Interval:             interval,
+		DiskSpace:            diskSpace,
+		MaxAge:               maxAge,
+		DiskSpaceWarnPercent: warnPercent,
+		AgeBasis:             ageBasis,
 	}, nil
 }
@@ -3,56 +3,84 @@ package cli
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
 	"mediahub_oss/internal/repository/sqlite"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/pressly/goose/v3"
 	"github.com/spf13/cobra"
 )
 
 func NewMigrateCommand(globalOptions *GlobalOptions) *cobra.Command {
+	var opts migrateOptions
 
 	var migrateCmd = &cobra.Command{
 		Use:   "migrate",
 		Short: "Database migration tools",
-		Long:  `Manage database schema versions. Use subcommands 'up', 'down', or 'status'.`,
+		Long:  `Manage database schema versions. Use subcommands 'up', 'down', 'status', or 'tables'.`,
 	}
 
 	var upCmd = &cobra.Command{
 		Use:   "up",
 		Short: "Migrate the database to the most recent version",
+		Long: `Migrate the database to the most recent version.
+Before applying any migration, the database file is copied to a timestamped '.bak' file next to it and the copy is verified.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMigration("up", globalOptions)
+			return runMigration("up", globalOptions, opts)
 		},
 	}
+	upCmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only list the migrations that would be applied, without touching the database")
+	upCmd.Flags().BoolVar(&opts.noBackup, "no-backup", false, "Skip the automatic backup before migrating")
 
 	var downCmd = &cobra.Command{
 		Use:   "down",
 		Short: "Roll back the database by one version",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMigration("down", globalOptions)
+			return runMigration("down", globalOptions, opts)
 		},
 	}
 
 	var statusCmd = &cobra.Command{
 		Use:   "status",
-		Short: "Dump the migration status for the current DB",
+		Short: "Dump the migration status for the current DB, including pending migrations",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMigration("status", globalOptions)
+			return runMigration("status", globalOptions, opts)
 		},
 	}
 
+	var tablesCmd = &cobra.Command{
+		Use:   "tables",
+		Short: "Rebuild entry tables whose columns or constraints are out of date",
+		Long: `Compare the entry table of every database with the schema the current version creates, e.g. the allowed statuses,
+and rebuild the tables that differ. Each table is rebuilt in its own transaction, all entries are kept.
+The server does this at startup as well. Before rebuilding, the database file is backed up like by 'migrate up'.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReconcileTables(globalOptions, opts)
+		},
+	}
+	tablesCmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only list the differences, without touching the database")
+	tablesCmd.Flags().BoolVar(&opts.noBackup, "no-backup", false, "Skip the automatic backup before rebuilding")
+
 	// Add subcommands
 	migrateCmd.AddCommand(upCmd)
 	migrateCmd.AddCommand(downCmd)
 	migrateCmd.AddCommand(statusCmd)
+	migrateCmd.AddCommand(tablesCmd)
 
 	return migrateCmd
 }
 
-func runMigration(command string, globalOptions *GlobalOptions) error {
+type migrateOptions struct {
+	dryRun   bool
+	noBackup bool
+}
+
+func runMigration(command string, globalOptions *GlobalOptions, opts migrateOptions) error {
 	ctx := context.Background()
 	logger := globalOptions.Logger
 
@@ -75,23 +103,13 @@ func runMigration(command string, globalOptions *GlobalOptions) error {
 	// Handle the 'status' command immediately (no backup prompt needed)
 	if command == "status" {
 		fmt.Printf("Current database schema version: %s (internal: %d)\n", versionStr, version)
-		return nil
+		_, err := printPendingMigrations(ctx, repo)
+		return err
 	}
 
-	// Prompt the user for backup confirmation before proceeding with up/down
 	fmt.Printf("Current database version is %s.\n", versionStr)
-	fmt.Print("WARNING: Before proceeding, it is highly recommended to create a backup of your database.\nHave you created a backup? (y/N): ")
-
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
-	response = strings.ToLower(strings.TrimSpace(response))
 
-	if response != "y" && response != "yes" {
-		fmt.Println("Migration aborted by user.")
-		return nil
-	}
-
-	// 5. Execute the requested migration
+	// Execute the requested migration
 	switch command {
 	case "up":
 		if version != 0 && version < 2000 {
@@ -102,15 +120,42 @@ func runMigration(command string, globalOptions *GlobalOptions) error {
 			fmt.Println(errorMessage)
 			logger.Warn("Blocked unsupported migration attempt from v1.x to v2.x", "current_version", versionStr)
 			return fmt.Errorf("unsupported migration path: v1.x -> v2.x")
+		}
+
+		pending, err := printPendingMigrations(ctx, repo)
+		if err != nil || len(pending) == 0 {
+			return err
+		}
+		if opts.dryRun {
+			fmt.Println("Dry run: no changes were made to the database.")
+			return nil
+		}
+
+		// Back up the database, or let the user confirm that they have one
+		backupPath := ""
+		if opts.noBackup {
+			if !confirmManualBackup() {
+				return nil
+			}
 		} else {
-			logger.Info("Starting database migration (Up)...")
-			if err := repo.MigrateUp(ctx); err != nil {
-				return fmt.Errorf("migration up failed: %w", err)
+			backupPath, err = repo.Backup(ctx)
+			if err != nil {
+				return fmt.Errorf("pre-migration backup failed (use --no-backup to skip it): %w", err)
 			}
-			logger.Info("Migration (Up) completed successfully.")
+			fmt.Printf("Backup created and verified: %s\n", backupPath)
+		}
+
+		logger.Info("Starting database migration (Up)...")
+		if err := migrateUpWithTiming(ctx, repo); err != nil {
+			return migrationFailedError(ctx, repo, err, backupPath)
 		}
+		logger.Info("Migration (Up) completed successfully.")
 
 	case "down":
+		if !confirmManualBackup() {
+			return nil
+		}
+
 		logger.Info("Starting database rollback (Down)...")
 		if err := repo.MigrateDown(ctx); err != nil {
 			return fmt.Errorf("migration down failed: %w", err)
@@ -123,3 +168,152 @@ func runMigration(command string, globalOptions *GlobalOptions) error {
 
 	return nil
 }
+
+// runReconcileTables lists the out-of-date entry tables and rebuilds them unless it is a dry run.
+func runReconcileTables(globalOptions *GlobalOptions, opts migrateOptions) error {
+	ctx := context.Background()
+
+	// TODO, add PostgreSQL as possibility
+	repo, err := sqlite.NewRepository(globalOptions.Conf.Database.Source)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer repo.Close()
+
+	// The tables are compared with the schema of the current version only
+	version, err := repo.GetMigrationVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current database version: %w", err)
+	}
+	if err := migrations.CheckVersion(version); err != nil {
+		return err
+	}
+
+	results, err := repo.ReconcileEntryTables(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to compare entry tables: %w", err)
+	}
+	if len(results) == 0 {
+		fmt.Println("All entry tables are up to date.")
+		return nil
+	}
+	printTableReconciliations(results)
+	if opts.dryRun {
+		fmt.Println("Dry run: no changes were made to the database.")
+		return nil
+	}
+
+	if opts.noBackup {
+		if !confirmManualBackup() {
+			return nil
+		}
+	} else {
+		backupPath, err := repo.Backup(ctx)
+		if err != nil {
+			return fmt.Errorf("backup failed (use --no-backup to skip it): %w", err)
+		}
+		fmt.Printf("Backup created and verified: %s\n", backupPath)
+	}
+
+	results, err = repo.ReconcileEntryTables(ctx, false)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild entry tables: %w", err)
+	}
+	failed := 0
+	for _, result := range results {
+		if result.Rebuilt {
+			fmt.Printf("Rebuilt the entry table of %s\n", result.DatabaseName)
+		} else {
+			fmt.Printf("Failed to rebuild the entry table of %s: %s\n", result.DatabaseName, result.Error)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d entry tables could not be rebuilt", failed)
+	}
+	return nil
+}
+
+// printTableReconciliations lists the differences of the out-of-date entry tables.
+func printTableReconciliations(results []repository.EntryTableReconciliation) {
+	fmt.Printf("Out-of-date entry tables (%d):\n", len(results))
+	for _, result := range results {
+		fmt.Printf("  %s (%s)\n", result.DatabaseName, result.DatabaseID)
+		for _, difference := range result.Differences {
+			fmt.Printf("         %s\n", difference)
+		}
+		if result.Error != "" {
+			fmt.Printf("         %s\n", result.Error)
+		}
+	}
+}
+
+// confirmManualBackup prompts the user to confirm that they have created a backup themselves.
+func confirmManualBackup() bool {
+	fmt.Print("WARNING: Before proceeding, it is highly recommended to create a backup of your database.\nHave you created a backup? (y/N): ")
+
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.ToLower(strings.TrimSpace(response))
+
+	if response != "y" && response != "yes" {
+		fmt.Println("Aborted by user.")
+		return false
+	}
+	return true
+}
+
+// printPendingMigrations lists the migrations that 'migrate up' would apply.
+func printPendingMigrations(ctx context.Context, repo *sqlite.SQLiteRepository) ([]sqlite.PendingMigration, error) {
+	pending, err := repo.PendingMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending migrations: %w", err)
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("The database schema is up to date, no pending migrations.")
+		return nil, nil
+	}
+
+	fmt.Printf("Pending migrations (%d):\n", len(pending))
+	for _, m := range pending {
+		fmt.Printf("  %-6d %s\n", m.Version, m.Name)
+		if m.Summary != "" {
+			fmt.Printf("         %s\n", m.Summary)
+		}
+	}
+	return pending, nil
+}
+
+// migrateUpWithTiming applies the pending migrations one at a time and reports how long each took.
+func migrateUpWithTiming(ctx context.Context, repo *sqlite.SQLiteRepository) error {
+	for {
+		start := time.Now()
+		version, err := repo.MigrateUpByOne(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied migration %d in %s\n", version, time.Since(start).Round(time.Microsecond))
+	}
+}
+
+// migrationFailedError adds the backup location and restore instructions to a failed migration.
+func migrationFailedError(ctx context.Context, repo *sqlite.SQLiteRepository, err error, backupPath string) error {
+	if backupPath == "" {
+		return fmt.Errorf("migration up failed: %w", err)
+	}
+
+	dbPath, pathErr := repo.FilePath(ctx)
+	if pathErr != nil {
+		dbPath = "<database file>"
+	}
+	return fmt.Errorf("migration up failed: %w\n\n"+
+		"The database may be left in a partially migrated state. A verified backup from before the migration is available at:\n"+
+		"    %s\n"+
+		"To restore it, stop MediaHub, delete %s-wal and %s-shm if they exist, and run:\n"+
+		"    cp %s %s",
+		err, backupPath, dbPath, dbPath, backupPath, dbPath)
+}
@@ -94,11 +94,13 @@ func (s *RecoveryService) EntryStatusCorrection(ctx context.Context) error {
 			}
 
 			// 2. Delete entries where the file was never uploaded (Zombies)
+			deleteZombiesIDs = s.withoutHeld(ctx, db.ID, deleteZombiesIDs)
 			if len(deleteZombiesIDs) > 0 {
 				_, _ = s.repo.DeleteEntries(ctx, db.ID, deleteZombiesIDs)
 			}
 
 			// 3. Fix stuck deleting (Attempt storage cleanup, then remove DB entry)
+			deleteStuckIDs = s.withoutHeld(ctx, db.ID, deleteStuckIDs)
 			if len(deleteStuckIDs) > 0 {
 				_, _ = shared.DeleteMultipleSafe(ctx, s.repo, s.storage, db.ID, deleteStuckIDs)
 			}
@@ -45,6 +45,15 @@ func (s *RecoveryService) IntegrityCheck(ctx context.Context) error {
 			return err // Abort on error to prevent saving corrupted stats
 		}
 
+		// --- PHASE 2.3b: Storage -> DB (Find orphan originals of converted uploads) ---
+		orphanOriginalIDs, calculatedOriginalBytes, err := s.checkOrphanOriginalFiles(ctx, db, stats)
+		calculatedTotalBytes += calculatedOriginalBytes
+		if err != nil && !errors.Is(err, customerrors.ErrNotImplemented) {
+			fmt.Println() // Break the \r progress line
+			s.logger.Error("Failed walking original storage", "database_id", db.ID.String(), "database_name", db.Name, "error", err)
+			return err // Abort on error to prevent saving corrupted stats
+		}
+
 		// --- PHASE 2.4: entry DB --> database DB (verify entry count stat)
 		if entryCount != db.Stats.EntryCount {
 			fmt.Printf("Found discrepance in entry count. Stats: %v, Database: %v\n", db.Stats.EntryCount, entryCount)
@@ -55,8 +64,10 @@ func (s *RecoveryService) IntegrityCheck(ctx context.Context) error {
 		// --- PHASE 2.5: Action ---
 		if !s.dryRun {
 			// Delete the database entries for files that no longer exist on disk
+			missingFileIDs = s.withoutHeld(ctx, db.ID, missingFileIDs)
 			if len(missingFileIDs) > 0 {
 				_, _ = s.storage.DeleteMultiplePreviews(ctx, db.ID.String(), missingFileIDs)
+				_, _ = s.storage.DeleteMultipleOriginals(ctx, db.ID.String(), missingFileIDs)
 				_, _ = s.repo.DeleteEntries(ctx, db.ID, missingFileIDs)
 			}
 
@@ -70,6 +81,11 @@ func (s *RecoveryService) IntegrityCheck(ctx context.Context) error {
 				_, _ = s.storage.DeleteMultiplePreviews(ctx, db.ID.String(), orphanPreviewIDs)
 			}
 
+			// Delete kept originals that have no database record
+			if len(orphanOriginalIDs) > 0 {
+				_, _ = s.storage.DeleteMultipleOriginals(ctx, db.ID.String(), orphanOriginalIDs)
+			}
+
 			// --- PHASE 2.5: Sync Statistics ---
 			trueEntryCount := entryCount - uint64(len(missingFileIDs))
 
@@ -86,8 +102,8 @@ func (s *RecoveryService) IntegrityCheck(ctx context.Context) error {
 		}
 
 		// Print the final summary, including stats differences
-		fmt.Printf("\tSummary: %d missing files removed from DB, %d orphan media files and %d orphan previews removed from disk.\n",
-			len(missingFileIDs), len(orphanFileIDs), len(orphanPreviewIDs))
+		fmt.Printf("\tSummary: %d missing files removed from DB, %d orphan media files, %d orphan previews and %d orphan originals removed from disk.\n",
+			len(missingFileIDs), len(orphanFileIDs), len(orphanPreviewIDs), len(orphanOriginalIDs))
 	}
 
 	return nil
@@ -141,6 +157,16 @@ func (s *RecoveryService) checkMissingFiles(ctx context.Context, db repository.D
 					fmt.Println() // Break the \r progress line
 					s.logger.Error("Storage stat failed", "database_id", db.ID.String(), "database_name", db.Name, "id", entry.ID, "error", err)
 				} else {
+					// A kept original must exist as well, it is not removed automatically as it cannot be recreated
+					if entry.OriginalSize > 0 {
+						if rc, err := s.storage.ReadOriginal(ctx, db.ID.String(), entry.ID); err != nil {
+							fmt.Println() // Break the \r progress line
+							s.logger.Warn("Original file missing", "database_id", db.ID.String(), "database_name", db.Name, "id", entry.ID, "error", err)
+						} else {
+							rc.Close()
+						}
+					}
+
 					// File exists! Cross-check the recorded size against the actual physical size
 					if entry.Size != uint64(info.Size) {
 						fmt.Println() // Break the \r progress line
@@ -235,3 +261,41 @@ func (s *RecoveryService) checkOrphanPreviewFiles(ctx context.Context, db reposi
 	})
 	return orphanFileIDs, calculatedTotalBytes, err
 }
+
+// checkOrphanOriginalFiles walks the kept originals and returns those without an entry that references
+// an original, together with the accumulated size of the valid ones.
+func (s *RecoveryService) checkOrphanOriginalFiles(ctx context.Context, db repository.Database, stats repository.DatabaseStats) ([]int64, uint64, error) {
+	var orphanFileIDs []int64
+	var calculatedTotalBytes uint64 = 0
+
+	// number of entries for calculating progress
+	divisor := stats.EntryCount
+	if divisor == 0 {
+		divisor = 1
+	}
+
+	processedStorage := uint64(0)
+	err := s.storage.WalkOriginal(ctx, db.ID.String(), func(id int64, info storage.FileInfo) error {
+		processedStorage++
+		percent := (processedStorage * 100) / divisor
+		if percent > 99 {
+			percent = 99
+		} // Cap at 99% until finished
+		fmt.Printf("\r- Step 2: Integrity check: %d%% (Scanning Disk->DB Originals)...", percent)
+
+		entry, err := s.repo.GetEntry(ctx, db.ID, id)
+		if errors.Is(err, customerrors.ErrNotFound) || (err == nil && entry.OriginalSize == 0) {
+			orphanFileIDs = append(orphanFileIDs, id)
+		} else if err != nil {
+			fmt.Println() // Break the \r progress line
+			s.logger.Error("Database lookup failed during original file walk", "database_id", db.ID.String(), "database_name", db.Name, "id", id, "error", err)
+			// Return the error to abort!
+			return err
+		} else {
+			// Valid file! Add to our true physical size calculation
+			calculatedTotalBytes += uint64(info.Size)
+		}
+		return nil
+	})
+	return orphanFileIDs, calculatedTotalBytes, err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 
 	"mediahub_oss/internal/cli/config"
 	"mediahub_oss/internal/repository"
@@ -83,3 +84,25 @@ func (s *RecoveryService) GetMigrationVersion(ctx context.Context) (int, error)
 	}
 	return s.repo.GetMigrationVersion(ctx)
 }
+
+// withoutHeld removes the entries under legal hold from ids, they are kept even if they cannot be repaired.
+// The repository refuses to delete a batch that contains a held entry.
+func (s *RecoveryService) withoutHeld(ctx context.Context, dbID repository.ULID, ids []int64) []int64 {
+	held, err := s.repo.GetHeldEntries(ctx, dbID, ids)
+	if err != nil {
+		s.logger.Error("Failed to check the legal hold of entries", "database_id", dbID, "error", err)
+		return []int64{}
+	}
+	if len(held) == 0 {
+		return ids
+	}
+
+	fmt.Printf("\tKeeping %d entries under legal hold: %v\n", len(held), held)
+	kept := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !slices.Contains(held, id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
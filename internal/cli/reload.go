@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"mediahub_oss/internal/cli/config"
+	"mediahub_oss/internal/housekeeping"
+	"mediahub_oss/internal/httpserver/auth"
+	eh "mediahub_oss/internal/httpserver/entryhandler"
+	"mediahub_oss/internal/logging"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/shared"
+)
+
+// configReloader re-reads the configuration file on SIGHUP or POST /api/admin/reload_config and applies
+// the config.ReloadableKeys to the running services.
+type configReloader struct {
+	path   string
+	logger *slog.Logger
+
+	// The services read the reloadable settings through these, never through copies
+	level            *slog.LevelVar
+	auditor          *audit.Switch
+	houseKeeper      *housekeeping.HouseKeeper
+	uploadLimits     *eh.UploadLimits
+	anonymousLimiter *auth.IPRateLimiter
+
+	mu      sync.Mutex
+	current *config.Config
+}
+
+// Reload applies the changed reloadable settings and reports the changed keys that need a restart.
+// If a reloadable setting is invalid, nothing is applied.
+func (r *configReloader) Reload() (config.ReloadReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := config.LoadConfig(r.path, true)
+	if err != nil {
+		return config.ReloadReport{}, err
+	}
+	merged, report := r.current.Merge(next)
+
+	serverCfg, err := merged.GetServerConfig()
+	if err != nil {
+		return config.ReloadReport{}, fmt.Errorf("failed to parse server config: %w", err)
+	}
+	auditRetention, err := shared.ParseDuration(merged.Logging.Audit.Retention)
+	if err != nil {
+		return config.ReloadReport{}, fmt.Errorf("failed to parse audit retention duration: %w", err)
+	}
+	integrityCfg, err := merged.GetIntegrityConfig()
+	if err != nil {
+		return config.ReloadReport{}, fmt.Errorf("failed to parse integrity config: %w", err)
+	}
+	vacuumThreshold, err := merged.GetVacuumThreshold()
+	if err != nil {
+		return config.ReloadReport{}, err
+	}
+
+	r.level.Set(logging.ParseLevel(merged.Logging.Level))
+	r.auditor.SetEnabled(merged.Logging.Audit.Enabled)
+	r.houseKeeper.SetAuditRetention(auditRetention)
+	r.houseKeeper.SetIntegrityLimits(integrityCfg.Budget, integrityCfg.MaxRate, integrityCfg.Pause)
+	r.houseKeeper.SetVacuumThreshold(vacuumThreshold)
+	r.uploadLimits.Set(int64(serverCfg.MaxSyncUploadSize), int64(serverCfg.MaxJSONFileSize))
+	r.anonymousLimiter.SetLimit(serverCfg.AnonymousRateLimit)
+	r.current = merged
+
+	r.logger.Info("Configuration reloaded", "path", r.path, "changed", report.Changed)
+	if len(report.Ignored) > 0 {
+		r.logger.Warn("Changed configuration keys need a restart and were ignored", "keys", report.Ignored)
+	}
+	return report, nil
+}
+
+// ReloadConfig implements adminhandler.ConfigReloader.
+func (r *configReloader) ReloadConfig() ([]string, []string, error) {
+	report, err := r.Reload()
+	return report.Changed, report.Ignored, err
+}
+
+// watchSignals reloads the configuration on every SIGHUP until the context is cancelled.
+func (r *configReloader) watchSignals(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if _, err := r.Reload(); err != nil {
+				r.logger.Error("Failed to reload configuration, keeping the running one", "path", r.path, "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/cli/config"
+	"mediahub_oss/internal/housekeeping"
+	adh "mediahub_oss/internal/httpserver/adminhandler"
+	"mediahub_oss/internal/httpserver/auth"
+	eh "mediahub_oss/internal/httpserver/entryhandler"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/repository"
+)
+
+const reloadTestConfig = `
+[server]
+port = %PORT%
+max_sync_upload_size = "%SYNC%"
+anonymous_rate_limit = %ANON%
+
+[logging]
+level = "%LEVEL%"
+
+[logging.audit]
+type = "stdio"
+enabled = false
+retention = "7d"
+`
+
+func TestReloadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig := func(port, sync, level, anon string) {
+		content := strings.NewReplacer("%PORT%", port, "%SYNC%", sync, "%LEVEL%", level, "%ANON%", anon).Replace(reloadTestConfig)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+	}
+
+	writeConfig("8080", "2MB", "info", "0")
+	cfg, err := config.LoadConfig(path, true)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	var logs bytes.Buffer
+	level := new(slog.LevelVar)
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: level}))
+	limits := eh.NewUploadLimits(2<<20, 0)
+	anonymousLimiter := auth.NewIPRateLimiter(0, time.Minute)
+	reloader := &configReloader{
+		path:             path,
+		logger:           logger,
+		level:            level,
+		auditor:          audit.NewSwitch(false, "stdio", logger, nil, 0),
+		houseKeeper:      housekeeping.NewHouseKeeper(nil, nil, logger, 7*24*time.Hour),
+		uploadLimits:     limits,
+		anonymousLimiter: anonymousLimiter,
+		current:          cfg,
+	}
+	h := &adh.AdminHandler{Logger: logger, Auditor: audit.NewAlNoopLogger(), ConfigReloader: reloader}
+
+	reload := func() (int, adh.ConfigReloadResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/reload_config", nil)
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "admin", IsAdmin: true}))
+		rr := httptest.NewRecorder()
+		h.ReloadConfig(rr, req)
+		var resp adh.ConfigReloadResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return rr.Code, resp
+	}
+
+	logger.Debug("before reload")
+	if strings.Contains(logs.String(), "before reload") {
+		t.Fatal("expected no debug lines at level info")
+	}
+
+	// 1. Reloadable keys are applied, the port keeps its running value
+	writeConfig("9090", "4MB", "debug", "1")
+	code, resp := reload()
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if !slices.Equal(resp.Changed, []string{"logging.level", "server.anonymous_rate_limit", "server.max_sync_upload_size"}) || !slices.Equal(resp.Ignored, []string{"server.port"}) {
+		t.Errorf("unexpected report %+v", resp)
+	}
+
+	logger.Debug("after reload")
+	if !strings.Contains(logs.String(), "after reload") {
+		t.Error("expected debug lines after switching the level to debug")
+	}
+	if reloader.current.Server.Port != 8080 || reloader.current.Logging.Level != "debug" {
+		t.Errorf("expected the running port and the new level, got %d and %s", reloader.current.Server.Port, reloader.current.Logging.Level)
+	}
+
+	if ok, _ := anonymousLimiter.Allow("192.0.2.1"); !ok {
+		t.Error("expected the first anonymous request to be allowed")
+	}
+	if ok, _ := anonymousLimiter.Allow("192.0.2.1"); ok {
+		t.Error("expected the reloaded anonymous rate limit to apply")
+	}
+
+	// 2. An invalid reloadable value keeps the running configuration
+	writeConfig("9090", "lots", "info", "0")
+	if code, _ := reload(); code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for an invalid upload size, got %d", code)
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("expected the level to stay debug, got %s", level.Level())
+	}
+
+	// 3. Reloading an unchanged file still reports the ignored port
+	writeConfig("9090", "4MB", "debug", "1")
+	if code, resp := reload(); code != http.StatusOK || len(resp.Changed) != 0 || !slices.Equal(resp.Ignored, []string{"server.port"}) {
+		t.Errorf("expected only the ignored port, got %d %+v", code, resp)
+	}
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"mediahub_oss/internal/media/ffmpeg"
+	"mediahub_oss/internal/processing"
+	"mediahub_oss/internal/repository/cache"
+	"mediahub_oss/internal/seed"
+
+	"github.com/spf13/cobra"
+)
+
+func NewSeedCommand(globalOptions *GlobalOptions) *cobra.Command {
+	var opts seedOptions
+
+	seedCmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Fill a database with generated entries",
+		Long: `Generates entries for demos, load and smoke tests: noise or gradient JPEG/PNG images, WAV tones
+for audio databases and random bytes for file databases. They are uploaded through the same processing
+as the API, timestamps are spread over the last --days and custom fields are filled by generators:
+
+  --custom "ml_score=rand(0,1)" --custom "camera=choice(front,back)" --custom "frame=randint(1,500)" --custom "flagged=bool(0.1)"
+
+The database is created with these fields if it does not exist. Databases that already have entries are
+refused unless --append is given. The same --seed generates the same entries. This does not start the
+HTTP server.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSeed(cmd.Context(), globalOptions, opts)
+		},
+	}
+	seedCmd.Flags().StringVar(&opts.database, "database", "", "Name of the database, created if it does not exist")
+	seedCmd.Flags().StringVar(&opts.contentType, "content-type", "", "Content type of a new database (image, audio or file)")
+	seedCmd.Flags().IntVar(&opts.count, "count", 100, "Number of entries to generate")
+	seedCmd.Flags().StringVar(&opts.size, "size", "50KB..500KB", "Size or size range MIN..MAX of the generated files")
+	seedCmd.Flags().IntVar(&opts.days, "days", 30, "Spread the timestamps over this many days before now")
+	seedCmd.Flags().StringArrayVar(&opts.custom, "custom", nil, "Custom field generator, repeatable: "+seed.FieldSyntax)
+	seedCmd.Flags().BoolVar(&opts.previews, "previews", false, "Create a new database with previews enabled")
+	seedCmd.Flags().BoolVar(&opts.append, "append", false, "Add to a database that already has entries")
+	seedCmd.Flags().IntVar(&opts.concurrency, "concurrency", 4, "Number of entries processed at the same time")
+	seedCmd.Flags().Uint64Var(&opts.seed, "seed", 0, "Seed of the random generator, 0 picks one")
+	_ = seedCmd.MarkFlagRequired("database")
+
+	return seedCmd
+}
+
+type seedOptions struct {
+	database    string
+	contentType string
+	count       int
+	size        string
+	days        int
+	custom      []string
+	previews    bool
+	append      bool
+	concurrency int
+	seed        uint64
+}
+
+// seedProgressInterval is how often the progress of a seed is printed.
+const seedProgressInterval = 2 * time.Second
+
+func runSeed(ctx context.Context, globalOptions *GlobalOptions, opts seedOptions) error {
+	cfg := globalOptions.Conf
+	logger := globalOptions.Logger
+
+	sizes, err := seed.ParseSizeRange(opts.size)
+	if err != nil {
+		return err
+	}
+	var fields []seed.FieldSpec
+	for _, custom := range opts.custom {
+		field, err := seed.ParseFieldSpec(custom)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, field)
+	}
+	if opts.days < 0 {
+		return fmt.Errorf("--days must not be negative")
+	}
+	if opts.concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if opts.seed == 0 {
+		opts.seed = rand.Uint64()
+	}
+
+	// Interrupting stops generating, the entries created so far are kept
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	repo, err := initRepository(cfg.Database, cache.New(repositoryCacheTTL, cfg.Database.CacheMaxEntries))
+	if err != nil {
+		return fmt.Errorf("failed to initialize repository: %w", err)
+	}
+	defer repo.Close()
+	if err := handleInitialMigration(ctx, repo, logger); err != nil {
+		return fmt.Errorf("failed to verify or apply database schema: %w", err)
+	}
+
+	storageProvider, err := initStorage(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage provider: %w", err)
+	}
+	converter, err := ffmpeg.NewFFMPEGConverter(cfg.Media.FFmpegPath, cfg.Media.FFprobePath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to start media converter: %w", err)
+	}
+	defer converter.Shutdown(context.Background())
+	converter.SetPDFRenderer(cfg.Media.PDFRendererPath)
+
+	// Every upload gets a processing slot, so none of them is queued
+	proc, err := processing.NewProcessor(repo, storageProvider, converter, 1, opts.concurrency, logger)
+	if err != nil {
+		return fmt.Errorf("failed to initialize processing manager: %w", err)
+	}
+
+	fmt.Printf("Seeding %d entries into '%s' with seed %d\n", opts.count, opts.database, opts.seed)
+	start := time.Now()
+	var lastPrinted time.Time
+	result, err := seed.Run(ctx, repo, proc, seed.Options{
+		DatabaseName: opts.database,
+		ContentType:  opts.contentType,
+		Count:        opts.count,
+		Sizes:        sizes,
+		Window:       time.Duration(opts.days) * 24 * time.Hour,
+		Fields:       fields,
+		Previews:     opts.previews,
+		Append:       opts.append,
+		Concurrency:  opts.concurrency,
+		Seed:         opts.seed,
+		Progress: func(p seed.Progress) {
+			if time.Since(lastPrinted) < seedProgressInterval && p.Done+p.Failed < p.Total {
+				return
+			}
+			lastPrinted = time.Now()
+			fmt.Printf("  %d/%d entries, %.1f MiB, %d failed\n", p.Done, p.Total, float64(p.Bytes)/(1<<20), p.Failed)
+		},
+	})
+	if result.Created {
+		fmt.Printf("Created %s database '%s' (%s)\n", result.Database.ContentType, result.Database.Name, result.Database.ID)
+	}
+	if result.Done > 0 || result.Failed > 0 {
+		fmt.Printf("Created %d entries (%.1f MiB) in %s, %d failed.\n", result.Done, float64(result.Bytes)/(1<<20), time.Since(start).Round(time.Millisecond), result.Failed)
+	}
+	return err
+}
@@ -2,14 +2,18 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
 	"mediahub_oss/docs" // to get the version
+	"mediahub_oss/internal/alerts"
 	"mediahub_oss/internal/cli/config"
 	"mediahub_oss/internal/cli/initconfig"
+	"mediahub_oss/internal/grpcserver"
 	"mediahub_oss/internal/housekeeping"
 	"mediahub_oss/internal/httpserver"
+	adh "mediahub_oss/internal/httpserver/adminhandler"
 	ah "mediahub_oss/internal/httpserver/audithandler"
 	"mediahub_oss/internal/httpserver/auth"
 	dbh "mediahub_oss/internal/httpserver/databasehandler"
@@ -17,27 +21,42 @@ import (
 	ih "mediahub_oss/internal/httpserver/infohandler"
 	th "mediahub_oss/internal/httpserver/tokenhandler"
 	uh "mediahub_oss/internal/httpserver/userhandler"
+	"mediahub_oss/internal/logging"
 	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/maintenance"
+	"mediahub_oss/internal/media"
 	"mediahub_oss/internal/media/ffmpeg"
+	"mediahub_oss/internal/media/sprite"
+	"mediahub_oss/internal/notices"
 	"mediahub_oss/internal/processing"
 	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/cache"
 	"mediahub_oss/internal/repository/migrations"
 	"mediahub_oss/internal/repository/postgres"
 	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/scanner/clamav"
 	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
 	"mediahub_oss/internal/storage"
 	"mediahub_oss/internal/storage/localstorage"
 	"mediahub_oss/internal/storage/s3storage"
+	"mediahub_oss/internal/storagereport"
+	"mediahub_oss/internal/transcription/openai"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	// Aliased imports for your sub-handlers
 
+	"net"
 	"net/http"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 )
 
 func NewServeCommand(globalOptions *GlobalOptions, frontendFS fs.FS) *cobra.Command {
@@ -60,16 +79,26 @@ func registerFlags(cmd *cobra.Command) {
 	cmd.Flags().String("init_config", "", "Path to a TOML config file for one-time initialization.")
 	cmd.Flags().String("password", "", "Password for the 'admin' user.")
 	cmd.Flags().Bool("reset_pw", false, "If true, reset admin password on startup.")
+	cmd.Flags().Bool(acceptStorageMoveFlag, false, "Start with another storage root than the one recorded in the database and record it.")
 
 	// Server Settings
 	cmd.Flags().String("server-host", "0.0.0.0", "The host address to bind to.")
 	cmd.Flags().Int("server-port", 8080, "The HTTP port to bind to.")
 	cmd.Flags().String("server-basepath", "/", "The base path for reverse proxy.")
+	cmd.Flags().String("server-route-prefix", "", "Prefix all routes are served below, e.g. '/mediahub'.")
+	cmd.Flags().String("server-base-url", "", "External base URL or path used for generated links.")
 	cmd.Flags().String("server-max-sync-upload", "4MB", "RAM threshold for uploads.")
+	cmd.Flags().String("server-max-json-file-size", "32MB", "Largest file served as base64 JSON.")
+	cmd.Flags().String("server-idempotency-key-ttl", "24h", "How long upload results are replayed for a repeated Idempotency-Key.")
+	cmd.Flags().String("server-shutdown-drain", "60s", "How long a shutdown waits for running requests and background processing.")
 	cmd.Flags().StringSlice("server-cors-origins", []string{}, "Allowed CORS origins.")
+	cmd.Flags().StringSlice("server-health-critical-checks", []string{"database", "storage"}, "Readiness checks that make /health/ready fail (database, storage, ffmpeg).")
 	cmd.Flags().String("server-processing-n-ffmpeg-async", "auto", "Limit for asynchronous processors.")
 	cmd.Flags().String("server-processing-n-ffmpeg-total", "auto", "Limit for all conversion processors.")
 
+	// gRPC Settings
+	cmd.Flags().Int("grpc-port", 0, "The port of the gRPC API (0 disables it).")
+
 	// Database Settings
 	cmd.Flags().String("database-driver", "sqlite", "Database driver (sqlite or postgres).")
 	cmd.Flags().String("database-source", "mediahub.db", "Path to DB file or connection string.")
@@ -85,16 +114,25 @@ func registerFlags(cmd *cobra.Command) {
 	cmd.Flags().String("storage-s3-access-key", "", "S3 Access Key.")
 	cmd.Flags().String("storage-s3-secret-key", "", "S3 Secret Key.")
 	cmd.Flags().Bool("storage-s3-use-ssl", true, "Enable HTTPS for S3 connection.")
+	cmd.Flags().Bool("storage-integrity-enabled", false, "Periodically verify stored files against their content hash.")
+	cmd.Flags().String("storage-integrity-interval", "24h", "Time between two verification runs.")
+	cmd.Flags().Int("storage-integrity-budget", 1000, "Entries verified per database and run.")
+	cmd.Flags().String("storage-integrity-pause", "1s", "Sleep between two verified files.")
 
 	// Logging Settings
 	cmd.Flags().String("logging-level", "info", "Logging verbosity.")
 	cmd.Flags().String("logging-audit-type", "stdio", "Where to store audit logs.")
 	cmd.Flags().Bool("logging-audit-enabled", false, "Toggle audit logging.")
 	cmd.Flags().String("logging-audit-retention", "31d", "How long to keep audit logs.")
+	cmd.Flags().Int("logging-audit-queue-size", audit.DefaultQueueSize, "Audit events waiting to be written, more are dropped.")
 
 	// Media Settings
 	cmd.Flags().String("media-ffmpeg-path", "", "Path to FFmpeg executable.")
 	cmd.Flags().String("media-ffprobe-path", "", "Path to FFprobe executable.")
+	cmd.Flags().Bool("media-self-test", false, "Run the media self-test on startup.")
+	cmd.Flags().String("media-max-segment-duration", "10m", "Maximum length of extracted audio segments (e.g. '10m').")
+	cmd.Flags().String("media-failed-upload-retention", "1h", "How long the source of a failed async upload is kept for retries ('0' disables).")
+	cmd.Flags().String("media-transcription-timeout", "2m", "Upper bound for a single transcription of an audio entry.")
 
 	// Auth Settings
 	cmd.Flags().String("auth-jwt-access-duration", "5min", "Validity of the JWT.")
@@ -108,6 +146,11 @@ func registerFlags(cmd *cobra.Command) {
 	cmd.Flags().String("auth-oidc-client-secret", "", "OIDC Client Secret.")
 	cmd.Flags().String("auth-oidc-redirect-url", "", "OIDC Redirect callback URL.")
 
+	// Security Settings
+	cmd.Flags().Bool("security-clamav-enabled", false, "Scan uploads with ClamAV before storing them.")
+	cmd.Flags().String("security-clamav-address", "tcp://127.0.0.1:3310", "Address of the clamd daemon.")
+	cmd.Flags().String("security-clamav-timeout", "60s", "Upper bound for a single virus scan.")
+
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
 		// Convert standard flag "server-port" into Viper's nested format "server.port"
 		viperKey := strings.ReplaceAll(f.Name, "-", ".")
@@ -119,9 +162,12 @@ func registerFlags(cmd *cobra.Command) {
 type backgroundServices struct {
 	houseKeeper    *housekeeping.HouseKeeper
 	mediaConverter *ffmpeg.FfmpegConverter
-	auditLogger    audit.AuditLogger
+	auditLogger    *audit.Switch
 	authMiddleware *auth.AuthMiddleware
+	jwtKeys        *auth.Keyring
 	processor      *processing.Processor
+	maintenance    *maintenance.Mode
+	mediaLog       *media.OperationLog
 }
 
 func serve(globalOptions *GlobalOptions, frontendFS fs.FS) error {
@@ -135,14 +181,22 @@ func serve(globalOptions *GlobalOptions, frontendFS fs.FS) error {
 	logger.Info("Bootstrapping MediaHub server...")
 
 	// 1. Initialize repository and database schema.
-	repo, err := initDatabaseAndSchema(ctx, cfg.Database, logger)
+	repoCache := cache.New(repositoryCacheTTL, cfg.Database.CacheMaxEntries)
+	repo, err := initDatabaseAndSchema(ctx, cfg.Database, repoCache, logger)
 	if err != nil {
 		return err
 	}
 	// Because we return errors now, this defer will always execute safely.
 	defer repo.Close()
 
-	// 2. Initialize storage provider.
+	// 2. Verify the storage root and initialize storage provider.
+	if cfg.Storage.Type == "local" {
+		root, err := checkStorageRoot(ctx, repo, cfg.Database.Source, cfg.Storage.Local.Root, viper.GetBool(strings.ReplaceAll(acceptStorageMoveFlag, "-", ".")), logger)
+		if err != nil {
+			return err
+		}
+		cfg.Storage.Local.Root = root
+	}
 	storageProvider, err := initStorage(cfg.Storage)
 	if err != nil {
 		return fmt.Errorf("failed to initialize storage provider: %w", err)
@@ -159,20 +213,71 @@ func serve(globalOptions *GlobalOptions, frontendFS fs.FS) error {
 		return err
 	}
 
+	// Housekeeping runs until the shutdown stops it, see runServer
+	hkCtx, stopHousekeeping := context.WithCancel(ctx)
+	defer stopHousekeeping()
+	go svcs.houseKeeper.StartScheduler(hkCtx)
+
 	// 5. Build REST handlers.
-	handlers, err := buildHandlers(cfg, repo, storageProvider, svcs, logger, startTime)
+	handlers, err := buildHandlers(cfg, repo, repoCache, storageProvider, svcs, logger, startTime)
 	if err != nil {
 		return err
 	}
 
-	// 6. Setup router and start the HTTP server.
-	return startServer(cfg, handlers, svcs.authMiddleware, frontendFS, logger)
+	// Reloadable settings are applied on SIGHUP or POST /api/admin/reload_config
+	reloader := &configReloader{
+		path:             globalOptions.CfgFilePath,
+		logger:           logger,
+		level:            logging.Level(),
+		auditor:          svcs.auditLogger,
+		houseKeeper:      svcs.houseKeeper,
+		uploadLimits:     handlers.EntryHandler.Limits,
+		anonymousLimiter: svcs.authMiddleware.AnonymousLimiter,
+		current:          cfg,
+	}
+	handlers.AdminHandler.ConfigReloader = reloader
+	go reloader.watchSignals(ctx)
+
+	// 6. Setup router and serve until SIGINT or SIGTERM.
+	server, err := newHTTPServer(cfg, handlers, svcs.authMiddleware, frontendFS, logger)
+	if err != nil {
+		return err
+	}
+	grpcServer, grpcListener, err := newGRPCServer(cfg, repo, handlers, svcs, logger)
+	if err != nil {
+		return err
+	}
+	serverCfg, err := cfg.GetServerConfig()
+	if err != nil {
+		return fmt.Errorf("failed to parse server config: %w", err)
+	}
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The repository is closed by the deferred repo.Close once the server and the workers are done.
+	return runServer(signalCtx, server, grpcServer, grpcListener, svcs.processor, svcs.houseKeeper, stopHousekeeping, svcs.auditLogger, serverCfg.ShutdownDrain, logger)
 }
 
 // initDatabaseAndSchema initializes the repository connection, runs version check or auto-migration,
 // and ensures the initial admin user is configured.
-func initDatabaseAndSchema(ctx context.Context, dbCfg config.DatabaseConfig, logger *slog.Logger) (repository.Repository, error) {
-	repo, err := initRepository(dbCfg)
+func initDatabaseAndSchema(ctx context.Context, dbCfg config.DatabaseConfig, repoCache *cache.Cache, logger *slog.Logger) (repository.Repository, error) {
+	// A relative database path depends on the working directory, log where the database is
+	if dbCfg.Driver == "sqlite" {
+		source, err := absoluteSQLiteSource(dbCfg.Source)
+		if err != nil {
+			return nil, err
+		}
+		dbCfg.Source = source
+		if path := sqliteFilePath(source); path != "" {
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				logger.Warn("Database file not found, creating a new database", "path", path)
+			} else {
+				logger.Info("Using database", "path", path)
+			}
+		}
+	}
+
+	repo, err := initRepository(dbCfg, repoCache)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize repository: %w", err)
 	}
@@ -183,6 +288,9 @@ func initDatabaseAndSchema(ctx context.Context, dbCfg config.DatabaseConfig, log
 		return nil, fmt.Errorf("failed to verify or apply database schema: %w", err)
 	}
 
+	// Bring entry tables created by older versions up to date with the current schema.
+	reconcileEntryTables(ctx, repo, logger)
+
 	// Ensure the admin user setup or password reset logic is performed.
 	if err := EnsureAdminUser(ctx, repo, logger); err != nil {
 		repo.Close()
@@ -199,39 +307,166 @@ func initServices(ctx context.Context, cfg *config.Config, repo repository.Repos
 		return nil, fmt.Errorf("failed to parse audit retention duration: %w", err)
 	}
 
+	integrityCfg, err := cfg.GetIntegrityConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse integrity config: %w", err)
+	}
+
+	vacuumThreshold, err := cfg.GetVacuumThreshold()
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogger := audit.NewSwitch(cfg.Logging.Audit.Enabled, cfg.Logging.Audit.Type, logger, repo, cfg.Logging.Audit.QueueSize)
+
 	hk := housekeeping.NewHouseKeeper(repo, storageProvider, logger, auditRetention)
-	go hk.StartScheduler(ctx)
+	hk.Auditor = auditLogger
+	alertsCfg, err := cfg.GetAlertsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse alerts config: %w", err)
+	}
+	hk.Notifier = initAlertNotifier(alertsCfg, logger)
+	hk.ProcessingAgeAlert = alertsCfg.ProcessingAgeAlert
+	noticeService := notices.NewService(repo, logger)
+	hk.Notices = noticeService
+	hk.NoticeRetention = alertsCfg.NoticeRetention
+	hk.Integrity = housekeeping.IntegrityOptions{
+		Enabled:  integrityCfg.Enabled,
+		Interval: integrityCfg.Interval,
+		Budget:   integrityCfg.Budget,
+		MaxRate:  integrityCfg.MaxRate,
+		Pause:    integrityCfg.Pause,
+	}
+	hk.VacuumThreshold = vacuumThreshold
+
+	// Recreate database folders that went missing, e.g. after a wrong volume mount or a manual cleanup
+	checkDatabaseFolders(ctx, repo, storageProvider, noticeService, logger)
+
+	// Finish the removal of database folders interrupted by a crash or shutdown
+	go func() {
+		purged, err := storageProvider.PurgeRemovedDatabases(ctx)
+		if err != nil && !errors.Is(err, customerrors.ErrNotImplemented) {
+			logger.Error("Failed to purge the folders of deleted databases", "error", err)
+			noticeService.Record(ctx, repository.ServerNotice{
+				DedupKey: notices.Key(notices.SourceStartup, "purge_removed_databases"),
+				Source:   notices.SourceStartup,
+				Severity: repository.NoticeSeverityWarning,
+				Message:  fmt.Sprintf("Failed to purge the folders of deleted databases: %v", err),
+			})
+		} else if purged > 0 {
+			logger.Info("Purged the folders of deleted databases", "count", purged)
+		}
+	}()
 
 	converter, err := ffmpeg.NewFFMPEGConverter(cfg.Media.FFmpegPath, cfg.Media.FFprobePath, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start media converter: %w", err)
 	}
 
-	auditLogger := audit.NewAuditLogger(cfg.Logging.Audit.Enabled, cfg.Logging.Audit.Type, logger, repo)
-	authMiddleware := auth.NewAuthMiddleware(repo, cfg.Auth.JWT.Secret)
+	converter.SetPDFRenderer(cfg.Media.PDFRendererPath)
+
+	operationLogSize, err := cfg.GetOperationLogSize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse media config: %w", err)
+	}
+	mediaLog := media.NewOperationLog(operationLogSize)
+	if mediaLog != nil {
+		converter.SetOperationRecorder(mediaLog)
+	}
+
+	if cfg.Media.SelfTest {
+		report := converter.SelfTest(ctx)
+		if report.AllOK() {
+			logger.Info("Media self-test passed", "ffmpeg_version", report.FFmpegVersion, "ffprobe_version", report.FFprobeVersion)
+		} else {
+			logger.Warn("Media self-test reported failures, affected capabilities are disabled", "capabilities", converter.GetCapabilities())
+		}
+	}
+
+	jwtCfg, err := cfg.GetJWTConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT config: %w", err)
+	}
+	jwtKeys, err := initJWTKeyring(ctx, jwtCfg, repo, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize JWT secrets: %w", err)
+	}
+	authMiddleware := auth.NewAuthMiddleware(repo, jwtKeys)
 
 	serverCfg, err := cfg.GetServerConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse server config: %w", err)
 	}
+	authMiddleware.TrustedProxies = serverCfg.TrustedProxies
+	authMiddleware.BasicAuthDisabled = !cfg.GetAuthMethods().BasicAuth
+	activityInterval, err := cfg.GetActivityInterval()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse auth config: %w", err)
+	}
+	if activityInterval > 0 {
+		authMiddleware.Activity = auth.NewActivityTracker(repo, activityInterval)
+	}
+	// Created even without a limit, so that a reload can set one
+	authMiddleware.AnonymousLimiter = auth.NewIPRateLimiter(serverCfg.AnonymousRateLimit, time.Minute)
 
 	proc, err := processing.NewProcessor(repo, storageProvider, converter, serverCfg.NFfmpegAsync, serverCfg.NFfmpegTotal, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize processing manager: %w", err)
 	}
+	proc.Auditor = auditLogger
+	proc.Notices = noticeService
+
+	retention, err := cfg.GetFailedUploadRetention()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse media config: %w", err)
+	}
+	proc.RetainFailedUploads = retention
+
+	clamCfg, err := cfg.GetClamAVConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse clamav config: %w", err)
+	}
+	if clamCfg.Enabled {
+		clamScanner, err := clamav.NewClamAVScanner(clamCfg.Address, clamCfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize virus scanner: %w", err)
+		}
+		proc.Scanner = clamScanner
+		proc.ScanFailOpen = clamCfg.FailOpen
+		proc.ScanContentTypes = clamCfg.ContentTypes
+		logger.Info("Virus scanning enabled", "address", clamCfg.Address, "content_types", clamCfg.ContentTypes, "fail_open", clamCfg.FailOpen)
+	}
+
+	// Transcription stays inert unless a database configures a service
+	transcriptionCfg, err := cfg.GetTranscriptionConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transcription config: %w", err)
+	}
+	proc.Transcriber = openai.NewClient(transcriptionCfg.AuthHeader, transcriptionCfg.Timeout)
+
+	// Restored before the workers start, a restart during maintenance stays in maintenance
+	maintenanceMode := maintenance.NewMode(repo, logger, proc, proc, hk)
+	if err := maintenanceMode.Restore(ctx); err != nil {
+		return nil, err
+	}
+
 	go proc.StartQueueChecker(ctx)
+	go proc.StartTaskRunner(ctx)
 
 	return &backgroundServices{
 		houseKeeper:    hk,
 		mediaConverter: converter,
 		auditLogger:    auditLogger,
 		authMiddleware: authMiddleware,
+		jwtKeys:        jwtKeys,
 		processor:      proc,
+		maintenance:    maintenanceMode,
+		mediaLog:       mediaLog,
 	}, nil
 }
 
 // buildHandlers configures the Handler layer with dependency injection.
-func buildHandlers(cfg *config.Config, repo repository.Repository, storageProvider storage.StorageProvider, svcs *backgroundServices, logger *slog.Logger, startTime time.Time) (*httpserver.Handlers, error) {
+func buildHandlers(cfg *config.Config, repo repository.Repository, repoCache *cache.Cache, storageProvider storage.StorageProvider, svcs *backgroundServices, logger *slog.Logger, startTime time.Time) (*httpserver.Handlers, error) {
 	serverCfg, err := cfg.GetServerConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse server config: %w", err)
@@ -242,6 +477,33 @@ func buildHandlers(cfg *config.Config, repo repository.Repository, storageProvid
 		return nil, fmt.Errorf("failed to parse JWT config: %w", err)
 	}
 
+	maxSegmentDuration, err := cfg.GetMaxSegmentDuration()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse media config: %w", err)
+	}
+
+	confirmDeleteEntries, confirmDeleteSize, err := cfg.GetDeleteConfirmationThresholds()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+
+	ipFilterCfg, err := cfg.GetIPFilterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse security config: %w", err)
+	}
+	var ipFilter *httpserver.IPFilter
+	if ipFilterCfg.Enabled() {
+		ipFilter = &httpserver.IPFilter{
+			Allow:          ipFilterCfg.Allow,
+			Deny:           ipFilterCfg.Deny,
+			TrustedProxies: serverCfg.TrustedProxies,
+			ExemptHealth:   ipFilterCfg.ExemptHealth,
+			Auditor:        svcs.auditLogger,
+			AuditBlocked:   ipFilterCfg.Audit,
+		}
+		logger.Info("IP filter enabled", "allowlist", len(ipFilterCfg.Allow), "denylist", len(ipFilterCfg.Deny), "exempt_health", ipFilterCfg.ExemptHealth)
+	}
+
 	infoH := ih.NewInfoHandler(
 		logger,
 		svcs.auditLogger,
@@ -255,23 +517,64 @@ func buildHandlers(cfg *config.Config, repo repository.Repository, storageProvid
 		cfg.Logging.Audit.Enabled && cfg.Logging.Audit.Type == "database",
 	)
 	infoH.StartTime = startTime
+	limits := fieldLimits(cfg.Database)
+	capacity, err := capacityLimits(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	infoH.Limits = ih.LimitsConfig{
+		MaxCustomFields:       limits.MaxCount,
+		MaxFieldNameLength:    limits.MaxNameLength,
+		MaxDatabases:          capacity.MaxDatabases,
+		MaxEntriesPerDatabase: capacity.MaxEntriesPerDatabase,
+	}
+	infoH.Readiness = ih.NewReadinessChecker(repo, storageProvider, svcs.mediaConverter.IsFFmpegAvailable, serverCfg.HealthCritical)
+	infoH.Maintenance = svcs.maintenance
+	infoH.AuditDelivery = svcs.auditLogger
+	infoH.Backlog = repo
+	infoH.Notices = repo
+	infoH.Authenticator = svcs.authMiddleware
+	if ipFilter != nil {
+		infoH.IPFilter = ipFilter
+	}
+	authMethods := cfg.GetAuthMethods()
+	infoH.Auth = ih.NewAuthInfo(authMethods.BasicAuth, authMethods.TokenBasicAuth, authMethods.TokenJSONLogin, cfg.Auth.OIDC.Enabled)
 
 	return &httpserver.Handlers{
 		InfoHandler: *infoH,
 		EntryHandler: eh.EntryHandler{
-			Logger:                 logger,
-			Auditor:                svcs.auditLogger,
-			Repo:                   repo,
-			Storage:                storageProvider,
-			MaxSyncUploadSizeBytes: int64(serverCfg.MaxSyncUploadSize),
-			MediaConverter:         svcs.mediaConverter,
-			Processor:              svcs.processor,
+			Logger:             logger,
+			Auditor:            svcs.auditLogger,
+			Repo:               repo,
+			Storage:            storageProvider,
+			Limits:             eh.NewUploadLimits(int64(serverCfg.MaxSyncUploadSize), int64(serverCfg.MaxJSONFileSize)),
+			Exports:            eh.NewExportLimiter(serverCfg.MaxConcurrentExports, serverCfg.ExportWriteTimeout),
+			IdempotencyKeyTTL:  serverCfg.IdempotencyKeyTTL,
+			BaseURL:            serverCfg.BaseURL,
+			TrustedProxies:     serverCfg.TrustedProxies,
+			MaxSegmentDuration: maxSegmentDuration,
+			Sprites:            sprite.NewGenerator(sprite.DefaultCacheTTL),
+			PageLimits:         pageLimits(cfg.Database),
+			Capacity:           capacity,
+			Timestamps: eh.TimestampBounds{
+				Policy:        serverCfg.TimestampPolicy,
+				MaxFutureSkew: serverCfg.MaxFutureSkew,
+				MinTimestamp:  serverCfg.MinTimestamp,
+			},
+			StrictFileNames:      serverCfg.StrictFileNames,
+			MediaConverter:       svcs.mediaConverter,
+			Processor:            svcs.processor,
+			HouseKeeper:          svcs.houseKeeper,
+			AsyncDeleteThreshold: serverCfg.AsyncDeleteThreshold,
 		},
 		DatabaseHandler: dbh.DatabaseHandler{
-			Logger:      logger,
-			Auditor:     svcs.auditLogger,
-			Repo:        repo,
-			HouseKeeper: *svcs.houseKeeper,
+			Logger:         logger,
+			Auditor:        svcs.auditLogger,
+			Repo:           repo,
+			HouseKeeper:    svcs.houseKeeper,
+			MediaConverter: svcs.mediaConverter,
+			Storage:        storageProvider,
+			ConfirmDelete:  dbh.DeleteThresholds{Entries: uint64(confirmDeleteEntries), Bytes: confirmDeleteSize},
 		},
 		UserHandler: uh.UserHandler{
 			Logger:  logger,
@@ -282,38 +585,155 @@ func buildHandlers(cfg *config.Config, repo repository.Repository, storageProvid
 			Logger:          logger,
 			Auditor:         svcs.auditLogger,
 			Repo:            repo,
-			JWTSecret:       []byte(jwtCfg.Secret),
+			Keys:            svcs.jwtKeys,
 			AccessDuration:  jwtCfg.AccessDuration,
 			RefreshDuration: jwtCfg.RefreshDuration,
+
+			BasicAuthDisabled: !authMethods.TokenBasicAuth,
+			JSONLogin:         authMethods.TokenJSONLogin,
 		},
 		AuditHandler: ah.AuditHandler{
 			Logger: logger,
 			Repo:   repo,
 		},
+		AdminHandler: adh.AdminHandler{
+			Logger:             logger,
+			Auditor:            svcs.auditLogger,
+			Reporter:           storagereport.NewReporter(repo, storageProvider, logger, storagereport.DefaultCacheTTL),
+			JWTKeys:            svcs.jwtKeys,
+			JWTRotationGrace:   jwtCfg.RotationGrace,
+			Maintenance:        svcs.maintenance,
+			MediaLog:           svcs.mediaLog,
+			Cache:              repoCache,
+			Repo:               repo,
+			ProcessingAgeAlert: svcs.houseKeeper.ProcessingAgeAlert,
+			HouseKeeper:        svcs.houseKeeper,
+		},
+		Maintenance: svcs.maintenance,
+		IPFilter:    ipFilter,
 	}, nil
 }
 
-// startServer configures the routing engine and binds the HTTP listener.
-func startServer(cfg *config.Config, handlers *httpserver.Handlers, authMiddleware *auth.AuthMiddleware, frontendFS fs.FS, logger *slog.Logger) error {
+// newHTTPServer configures the routing engine of the HTTP server.
+func newHTTPServer(cfg *config.Config, handlers *httpserver.Handlers, authMiddleware *auth.AuthMiddleware, frontendFS fs.FS, logger *slog.Logger) (*http.Server, error) {
+	serverCfg, err := cfg.GetServerConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server config: %w", err)
+	}
+
 	var fileSystem http.FileSystem
-	if frontendFS != nil {
-		// TODO: Update <base href> to the MEDIAHUB_SERVER_BASEPATH
-		// or should we handle it later in SetupRouter?
+	if frontendFS != nil && !serverCfg.DisableFrontend {
 		fileSystem = http.FS(frontendFS)
 	}
 
-	mux := httpserver.SetupRouter(handlers, fileSystem, authMiddleware, cfg.Server.Basepath, cfg.Server.CorsAllowedOrigins)
+	mux := httpserver.SetupRouter(handlers, fileSystem, authMiddleware, serverCfg.Basepath, serverCfg.RoutePrefix, serverCfg.CorsAllowedOrigins)
 
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	logger.Info("Starting HTTP server", "address", addr)
+	logger.Info("Starting HTTP server", "address", addr, "route_prefix", serverCfg.RoutePrefix)
 
-	server := &http.Server{
+	return &http.Server{
 		Addr:    addr,
 		Handler: mux,
+	}, nil
+}
+
+// newGRPCServer binds the listener of the gRPC API, which shares the entry handler and the authentication
+// with the REST API. It returns nil if [grpc] port is not set.
+func newGRPCServer(cfg *config.Config, repo repository.Repository, handlers *httpserver.Handlers, svcs *backgroundServices, logger *slog.Logger) (*grpc.Server, net.Listener, error) {
+	if cfg.GRPC.Port == 0 {
+		return nil, nil, nil
 	}
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.GRPC.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen for the gRPC API: %w", err)
+	}
+	logger.Info("Starting gRPC server", "address", addr)
+
+	return grpcserver.NewGRPCServer(&grpcserver.Server{
+		Logger:      logger,
+		Repo:        repo,
+		Entries:     &handlers.EntryHandler,
+		Auth:        svcs.authMiddleware,
+		Maintenance: svcs.maintenance,
+		IPFilter:    handlers.IPFilter,
+	}), listener, nil
+}
+
+// runServer binds the HTTP listener, serves HTTP and the optional gRPC API until ctx is cancelled. It then
+// shuts down step by step within the drain time: it stops accepting requests, stops housekeeping and its jobs, waits
+// for the background processing and delivers the queued audit events. Entries whose processing is still
+// running afterwards are logged, so they can be checked.
+func runServer(ctx context.Context, server *http.Server, grpcServer *grpc.Server, grpcListener net.Listener, proc *processing.Processor, houseKeeper *housekeeping.HouseKeeper, stopHousekeeping func(), auditor *audit.Switch, drain time.Duration, logger *slog.Logger) error {
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	if grpcServer != nil {
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				serveErr <- fmt.Errorf("gRPC: %w", err)
+			}
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
 		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down, waiting for running requests and background processing", "drain", drain)
+	drainCtx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+
+	// Uploads still being received fail with 503 instead of starting work that might be cut off
+	proc.BeginDrain()
+
+	// 1. Stop accepting requests and calls and wait for the running ones
+	grpcStopped := make(chan struct{})
+	if grpcServer != nil {
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	}
+	if err := server.Shutdown(drainCtx); err != nil {
+		logger.Warn("Requests were still running at shutdown", "error", err)
+	}
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-drainCtx.Done():
+			logger.Warn("gRPC calls were still running at shutdown")
+			grpcServer.Stop()
+		}
+	}
+
+	// 2. Stop housekeeping and the running jobs, they store their progress per batch
+	stopHousekeeping()
+	if !houseKeeper.Wait(drainCtx) {
+		logger.Warn("Jobs were still running at shutdown, they resume after the restart")
+	}
+
+	// 3. Wait for the conversions and tasks
+	interrupted := proc.Drain(drainCtx)
+	if len(interrupted) > 0 {
+		entries := make([]string, len(interrupted))
+		for i, entry := range interrupted {
+			entries[i] = entry.String()
+		}
+		logger.Warn("Shutdown interrupted the processing of entries, check them after the restart", "count", len(interrupted), "entries", entries)
+	} else {
+		logger.Info("Background processing finished")
+	}
+
+	// 4. Deliver the audit events of the finished work
+	if err := auditor.Close(drainCtx); err != nil {
+		logger.Warn("Shutdown interrupted the delivery of audit events", "error", err)
+	} else {
+		logger.Info("Audit events delivered, shutdown complete")
 	}
 
 	return nil
@@ -332,6 +752,9 @@ func handleInitialMigration(ctx context.Context, repo repository.Repository, log
 		if err := repo.MigrateUp(ctx); err != nil {
 			return fmt.Errorf("initial migration failed: %w", err)
 		}
+		if err := repo.IntegrityCheck(ctx); err != nil && !errors.Is(err, customerrors.ErrNotImplemented) {
+			return fmt.Errorf("initial schema is not consistent: %w", err)
+		}
 		logger.Info("Initial database schema applied successfully.")
 	} else {
 		logger.Info("Existing database detected.", "version", repository.FormatVersion(version))
@@ -343,11 +766,114 @@ func handleInitialMigration(ctx context.Context, repo repository.Repository, log
 	return nil
 }
 
+// reconcileEntryTables rebuilds out-of-date entry tables at startup. A table that cannot be rebuilt does not
+// stop the server, it keeps working with its old constraints until 'mediahub migrate tables' is run.
+func reconcileEntryTables(ctx context.Context, repo repository.Repository, logger *slog.Logger) {
+	results, err := repo.ReconcileEntryTables(ctx, false)
+	if errors.Is(err, customerrors.ErrNotImplemented) {
+		return
+	}
+	if err != nil {
+		logger.Warn("Failed to reconcile entry tables", "error", err)
+		return
+	}
+	for _, result := range results {
+		if result.Rebuilt {
+			logger.Info("Rebuilt out-of-date entry table", "database", result.DatabaseName, "differences", result.Differences)
+		} else {
+			logger.Warn("Entry table is out of date", "database", result.DatabaseName, "differences", result.Differences, "error", result.Error)
+			notices.NewService(repo, logger).Record(ctx, repository.ServerNotice{
+				DedupKey:   notices.Key(notices.SourceStartup, "reconcile_entry_table", result.DatabaseID.String()),
+				Source:     notices.SourceStartup,
+				Severity:   repository.NoticeSeverityWarning,
+				Message:    fmt.Sprintf("The entry table of database '%s' is out of date and could not be rebuilt, run 'mediahub migrate tables': %v", result.DatabaseName, result.Error),
+				DatabaseID: result.DatabaseID,
+			})
+		}
+	}
+}
+
+// checkDatabaseFolders makes sure every database has its storage folder. A missing folder is recreated empty,
+// databases that still hold entries lost their files, which is reported with a warning and a server notice.
+func checkDatabaseFolders(ctx context.Context, repo repository.Repository, store storage.StorageProvider, noticeService *notices.Service, logger *slog.Logger) {
+	dbs, err := repo.GetDatabases(ctx)
+	if err != nil {
+		logger.Warn("Failed to list the databases to check their storage folders", "error", err)
+		return
+	}
+	for _, db := range dbs {
+		created, err := store.EnsureDatabase(ctx, db.ID.String())
+		if errors.Is(err, customerrors.ErrNotImplemented) {
+			return
+		}
+		var message string
+		switch {
+		case err != nil:
+			logger.Warn("Failed to check the storage folder of a database", "database", db.Name, "error", err)
+			message = fmt.Sprintf("The storage folder of database '%s' is not usable, uploads will fail: %v", db.Name, err)
+		case created && db.Stats.EntryCount > 0:
+			logger.Warn("Storage folder of a database was missing and has been recreated empty", "database", db.Name, "entries", db.Stats.EntryCount)
+			message = fmt.Sprintf("The storage folder of database '%s' was missing and has been recreated empty, the files of its %d entries are gone", db.Name, db.Stats.EntryCount)
+		case created:
+			logger.Debug("Created the storage folder of an empty database", "database", db.Name)
+			continue
+		default:
+			continue
+		}
+		noticeService.Record(ctx, repository.ServerNotice{
+			DedupKey:   notices.Key(notices.SourceStartup, "database_folder", db.ID.String()),
+			Source:     notices.SourceStartup,
+			Severity:   repository.NoticeSeverityWarning,
+			Message:    message,
+			DatabaseID: db.ID,
+		})
+	}
+}
+
+// pageLimits returns the configured page sizes of entry listings and searches.
+func pageLimits(dbCfg config.DatabaseConfig) repository.PageLimits {
+	return repository.PageLimits{Default: dbCfg.DefaultPageSize, Max: dbCfg.MaxPageSize}
+}
+
+// fieldLimits returns the configured limits of the custom fields of a database.
+func fieldLimits(dbCfg config.DatabaseConfig) repository.FieldLimits {
+	return repository.FieldLimits{MaxCount: dbCfg.MaxCustomFields, MaxNameLength: dbCfg.MaxFieldNameLength}.Resolved()
+}
+
+// capacityLimits returns the configured limits of the number of databases and entries.
+func capacityLimits(dbCfg config.DatabaseConfig) (repository.CapacityLimits, error) {
+	maxDatabases, maxEntries, err := dbCfg.GetCapacityLimits()
+	if err != nil {
+		return repository.CapacityLimits{}, err
+	}
+	return repository.CapacityLimits{MaxDatabases: maxDatabases, MaxEntriesPerDatabase: maxEntries}, nil
+}
+
+// repositoryCacheTTL is how long the repository caches users, databases and entries.
+const repositoryCacheTTL = 5 * time.Minute
+
 // initRepository sets up the database connection based on the configuration.
-func initRepository(dbCfg config.DatabaseConfig) (repository.Repository, error) {
+func initRepository(dbCfg config.DatabaseConfig, repoCache *cache.Cache) (repository.Repository, error) {
 	switch dbCfg.Driver {
 	case "sqlite":
-		return sqlite.NewRepository(dbCfg.Source)
+		capacity, err := capacityLimits(dbCfg)
+		if err != nil {
+			return nil, err
+		}
+		sortLocale, err := dbCfg.GetSortLocale()
+		if err != nil {
+			return nil, err
+		}
+		sqlite.SetSortLocale(sortLocale)
+		repo, err := sqlite.NewRepository(dbCfg.Source)
+		if err != nil {
+			return nil, err
+		}
+		repo.PageLimits = pageLimits(dbCfg)
+		repo.FieldLimits = fieldLimits(dbCfg)
+		repo.CapacityLimits = capacity
+		repo.Cache = repoCache
+		return repo, nil
 	case "postgres":
 		return postgres.NewRepository(dbCfg.Source)
 	default:
@@ -355,6 +881,48 @@ func initRepository(dbCfg config.DatabaseConfig) (repository.Repository, error)
 	}
 }
 
+// jwtSecretReloadInterval is how often database backed JWT secrets are reloaded to pick up rotations of other instances.
+const jwtSecretReloadInterval = time.Minute
+
+// initJWTKeyring loads the JWT secrets from the configured source.
+// Database backed secrets are created once if missing and reloaded in the background.
+func initJWTKeyring(ctx context.Context, jwtCfg config.JWTConfig, repo repository.Repository, logger *slog.Logger) (*auth.Keyring, error) {
+	switch jwtCfg.SecretSource {
+	case config.JWTSecretSourceFile:
+		content, err := os.ReadFile(jwtCfg.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwt secret file: %w", err)
+		}
+		var secrets []string
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				secrets = append(secrets, line)
+			}
+		}
+		return auth.NewKeyring(secrets...)
+
+	case config.JWTSecretSourceDB:
+		keys, err := auth.NewRepoKeyring(ctx, repo)
+		if err != nil {
+			return nil, err
+		}
+		go keys.StartReloading(ctx, jwtSecretReloadInterval, logger)
+		return keys, nil
+
+	default:
+		secret := jwtCfg.Secret
+		if secret == "" {
+			generated, err := auth.GenerateSecret()
+			if err != nil {
+				return nil, err
+			}
+			secret = generated
+			logger.Warn("No JWT secret configured, using a random one. Tokens are invalidated by a restart and not accepted by other instances; set auth.jwt.secret, secret_file or secret_source = \"db\"")
+		}
+		return auth.NewKeyring(secret, jwtCfg.PreviousSecret)
+	}
+}
+
 // initStorage sets up the file storage provider based on the configuration.
 func initStorage(storageCfg config.StorageConfig) (storage.StorageProvider, error) {
 	switch storageCfg.Type {
@@ -391,3 +959,25 @@ func processInitConfig(ctx context.Context, repo repository.Repository, logger *
 
 	return nil
 }
+
+// initAlertNotifier creates the sink that delivers alerts to administrators.
+func initAlertNotifier(alertsCfg config.AlertsConfig, logger *slog.Logger) alerts.Notifier {
+	switch alertsCfg.Sink {
+	case alerts.SinkWebhook:
+		logger.Info("Alerts are sent to a webhook")
+		return alerts.NewWebhookNotifier(alertsCfg.WebhookURL, alertsCfg.Timeout)
+	case alerts.SinkSMTP:
+		logger.Info("Alerts are sent by mail", "smtp_host", alertsCfg.SMTP.Host, "recipients", len(alertsCfg.SMTP.To))
+		return &alerts.SMTPNotifier{
+			Host:     alertsCfg.SMTP.Host,
+			Port:     alertsCfg.SMTP.Port,
+			Username: alertsCfg.SMTP.Username,
+			Password: alertsCfg.SMTP.Password,
+			From:     alertsCfg.SMTP.From,
+			To:       alertsCfg.SMTP.To,
+			Timeout:  alertsCfg.Timeout,
+		}
+	default:
+		return alerts.NewLogNotifier(logger)
+	}
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+)
+
+// acceptStorageMoveFlag lets the server start with another storage root than the recorded one.
+const acceptStorageMoveFlag = "accept-storage-move"
+
+// checkStorageRoot resolves the local storage root to an absolute path and verifies it before the server
+// starts. Relative paths depend on the working directory, so a server started from another directory would
+// otherwise silently use a new, empty storage root. The resolved root is recorded in the database on the
+// first start; if it changes and the new root is empty while the database has entries, the start is refused
+// unless acceptMove is set, which records the new root. The root must be writable and must not contain the
+// database file, or lie inside the database path. It returns the absolute storage root.
+func checkStorageRoot(ctx context.Context, repo repository.Repository, dbSource string, root string, acceptMove bool, logger *slog.Logger) (string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve storage root %s: %w", root, err)
+	}
+
+	// 1. The database must not be stored inside the storage root or vice versa
+	if dbPath := sqliteFilePath(dbSource); dbPath != "" {
+		absDB, err := filepath.Abs(dbPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve database path %s: %w", dbPath, err)
+		}
+		if isWithin(absRoot, absDB) {
+			return "", fmt.Errorf("the database %s is inside the storage root %s, move it out of the storage root", absDB, absRoot)
+		}
+		if isWithin(absDB, absRoot) {
+			return "", fmt.Errorf("the storage root %s is inside the database path %s", absRoot, absDB)
+		}
+	}
+
+	// 2. Compare with the storage root the entries were stored in
+	recorded, err := repo.GetSetting(ctx, repository.SettingStorageRoot)
+	switch {
+	case errors.Is(err, customerrors.ErrNotFound), errors.Is(err, customerrors.ErrNotImplemented):
+		recorded = ""
+	case err != nil:
+		return "", fmt.Errorf("failed to read the recorded storage root: %w", err)
+	}
+	if recorded != "" && recorded != absRoot && !acceptMove {
+		empty, err := isEmptyDir(absRoot)
+		if err != nil {
+			return "", fmt.Errorf("failed to read storage root %s: %w", absRoot, err)
+		}
+		entries, err := countEntries(ctx, repo)
+		if err != nil {
+			return "", err
+		}
+		if empty && entries > 0 {
+			return "", fmt.Errorf("refusing to start: the storage root %s is empty, but the %d entries of the database were stored in %s. "+
+				"Start MediaHub from its usual working directory or configure absolute paths; if the files were moved on purpose, start once with --%s",
+				absRoot, entries, recorded, acceptStorageMoveFlag)
+		}
+	}
+
+	// 3. The storage root must be writable
+	if err := os.MkdirAll(absRoot, 0755); err != nil {
+		return "", fmt.Errorf("failed to create storage root %s: %w", absRoot, err)
+	}
+	probe := localstorage.LocalStorage{RootPath: absRoot}
+	if err := probe.Probe(ctx); err != nil {
+		return "", fmt.Errorf("the storage root %s is not writable: %w", absRoot, err)
+	}
+
+	// 4. Record the storage root
+	if recorded != absRoot {
+		if err := repo.SetSetting(ctx, repository.SettingStorageRoot, absRoot); err != nil && !errors.Is(err, customerrors.ErrNotImplemented) {
+			return "", fmt.Errorf("failed to record the storage root: %w", err)
+		}
+		if recorded != "" {
+			logger.Warn("The storage root changed", "previous", recorded, "storage_root", absRoot)
+		}
+	}
+
+	logger.Info("Using storage root", "path", absRoot)
+	return absRoot, nil
+}
+
+// sqliteFilePath returns the file of a SQLite data source, "" for in-memory databases.
+func sqliteFilePath(source string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(source, "file:"), "?")
+	if path == "" || strings.HasPrefix(path, ":memory:") || strings.Contains(source, "mode=memory") {
+		return ""
+	}
+	return path
+}
+
+// absoluteSQLiteSource makes the file of a SQLite data source absolute, so the opened database does not
+// depend on the working directory.
+func absoluteSQLiteSource(source string) (string, error) {
+	path := sqliteFilePath(source)
+	if path == "" || filepath.IsAbs(path) {
+		return source, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve database path %s: %w", path, err)
+	}
+	return strings.Replace(source, path, abs, 1), nil
+}
+
+// isWithin reports whether path is parent or inside of it, both must be absolute.
+func isWithin(parent string, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isEmptyDir reports whether a directory is empty, a missing directory is empty.
+func isEmptyDir(path string) (bool, error) {
+	dir, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer dir.Close()
+
+	_, err = dir.Readdirnames(1)
+	if errors.Is(err, io.EOF) {
+		return true, nil
+	}
+	return false, err
+}
+
+// countEntries returns the number of entries in all databases.
+func countEntries(ctx context.Context, repo repository.Repository) (uint64, error) {
+	databases, err := repo.GetDatabases(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list databases: %w", err)
+	}
+	var total uint64
+	for _, db := range databases {
+		total += db.Stats.EntryCount
+	}
+	return total, nil
+}
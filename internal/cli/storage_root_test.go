@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+// openTestRepository opens a migrated SQLite database file with one entry.
+func openTestRepository(t *testing.T, path string) *sqlite.SQLiteRepository {
+	t.Helper()
+	r, err := sqlite.NewRepository(path)
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	return r
+}
+
+func TestCheckStorageRoot(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// The usual working directory holds the database and the storage root, configured relatively
+	home := t.TempDir()
+	t.Chdir(home)
+	r := openTestRepository(t, filepath.Join(home, "mediahub.db"))
+	db, err := r.CreateDatabase(ctx, repository.Database{Name: "cams", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	if _, err := r.CreateEntry(ctx, db, repository.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream"}); err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	// 1. The first start records the absolute storage root
+	root, err := checkStorageRoot(ctx, r, "mediahub.db", "storage_root", false, logger)
+	if err != nil {
+		t.Fatalf("expected the first start to succeed, got %v", err)
+	}
+	want := filepath.Join(home, "storage_root")
+	if root != want {
+		t.Errorf("expected storage root %s, got %s", want, root)
+	}
+	if recorded, _ := r.GetSetting(ctx, repository.SettingStorageRoot); recorded != want {
+		t.Errorf("expected %s to be recorded, got %q", want, recorded)
+	}
+	if err := os.WriteFile(filepath.Join(root, "file"), []byte("data"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := checkStorageRoot(ctx, r, "mediahub.db", "storage_root", false, logger); err != nil {
+		t.Errorf("expected a restart to succeed, got %v", err)
+	}
+
+	// 2. Started from another directory, the relative root is empty: the start is refused
+	elsewhere := t.TempDir()
+	t.Chdir(elsewhere)
+	_, err = checkStorageRoot(ctx, r, filepath.Join(home, "mediahub.db"), "storage_root", false, logger)
+	if err == nil {
+		t.Fatal("expected the start to be refused")
+	}
+	for _, part := range []string{filepath.Join(elsewhere, "storage_root"), want, "1 entries", "--accept-storage-move"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("expected the error to mention %q, got: %v", part, err)
+		}
+	}
+	if _, statErr := os.Stat(filepath.Join(elsewhere, "storage_root")); !os.IsNotExist(statErr) {
+		t.Errorf("expected the refused start not to create the storage root, got %v", statErr)
+	}
+
+	// 3. A moved, non-empty storage root is accepted and recorded
+	moved := filepath.Join(elsewhere, "moved")
+	if err := os.Rename(want, moved); err != nil {
+		t.Fatalf("failed to move the storage root: %v", err)
+	}
+	if _, err := checkStorageRoot(ctx, r, filepath.Join(home, "mediahub.db"), moved, false, logger); err != nil {
+		t.Errorf("expected the moved storage root to be accepted, got %v", err)
+	}
+	if recorded, _ := r.GetSetting(ctx, repository.SettingStorageRoot); recorded != moved {
+		t.Errorf("expected %s to be recorded, got %q", moved, recorded)
+	}
+
+	// 4. --accept-storage-move records an empty root as well
+	empty := filepath.Join(elsewhere, "empty")
+	if _, err := checkStorageRoot(ctx, r, filepath.Join(home, "mediahub.db"), empty, true, logger); err != nil {
+		t.Errorf("expected the override to succeed, got %v", err)
+	}
+	if recorded, _ := r.GetSetting(ctx, repository.SettingStorageRoot); recorded != empty {
+		t.Errorf("expected %s to be recorded, got %q", empty, recorded)
+	}
+}
+
+func TestCheckStorageRootNesting(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+	r := openTestRepository(t, filepath.Join(dir, "mediahub.db"))
+
+	for name, tc := range map[string]struct{ source, root string }{
+		"database in storage root": {filepath.Join(dir, "storage", "mediahub.db"), filepath.Join(dir, "storage")},
+		"storage root in database": {"file:" + filepath.Join(dir, "data") + "?cache=shared", filepath.Join(dir, "data", "storage")},
+		"same path":                {filepath.Join(dir, "data"), filepath.Join(dir, "data")},
+	} {
+		if _, err := checkStorageRoot(ctx, r, tc.source, tc.root, true, logger); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// Neighbours and in-memory databases are fine
+	for _, source := range []string{filepath.Join(dir, "mediahub.db"), ":memory:", "file::memory:?cache=shared"} {
+		if _, err := checkStorageRoot(ctx, r, source, filepath.Join(dir, "storage_root"), true, logger); err != nil {
+			t.Errorf("%s: expected no error, got %v", source, err)
+		}
+	}
+}
+
+func TestAbsoluteSQLiteSource(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	for source, want := range map[string]string{
+		"mediahub.db":                   filepath.Join(wd, "mediahub.db"),
+		"file:data/m.db?_pragma=x":      "file:" + filepath.Join(wd, "data/m.db") + "?_pragma=x",
+		"/var/lib/mediahub/mediahub.db": "/var/lib/mediahub/mediahub.db",
+		":memory:":                      ":memory:",
+	} {
+		if got, err := absoluteSQLiteSource(source); err != nil || got != want {
+			t.Errorf("absoluteSQLiteSource(%q) = %q, %v, want %q", source, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package exportmanifest describes the checksums of ZIP exports and verifies archives against them. The
+// checksums file is in the format of sha256sum, so unpacked exports can also be checked without MediaHub.
+package exportmanifest
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// Writer hashes a file of the archive while it is written, so the content is only read once.
+type Writer struct {
+	w    io.Writer
+	hash hash.Hash
+	file File
+}
+
+// NewWriter wraps the writer of the archive file at path.
+func NewWriter(w io.Writer, path string, entryID int64) *Writer {
+	return &Writer{w: w, hash: sha256.New(), file: File{EntryID: entryID, Path: path}}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.hash.Write(p[:n])
+	w.file.Size += int64(n)
+	return n, err
+}
+
+// File returns the listing of the bytes written so far.
+func (w *Writer) File() File {
+	file := w.file
+	file.SHA256 = hex.EncodeToString(w.hash.Sum(nil))
+	return file
+}
+
+// Checksums renders the files in the format of sha256sum: the hash, two spaces and the path.
+func (m Manifest) Checksums() []byte {
+	var buf bytes.Buffer
+	for _, file := range m.Files {
+		fmt.Fprintf(&buf, "%s  %s\n", file.SHA256, file.Path)
+	}
+	return buf.Bytes()
+}
+
+// OK reports whether every file of the archive matches the manifest.
+func (r Report) OK() bool {
+	for _, file := range r.Files {
+		if file.Status != StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyFile verifies the ZIP export at path, see Verify.
+func VerifyFile(path string) (Report, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+	return Verify(&zr.Reader)
+}
+
+// Verify checks every file of an archive against the manifest.json of the archive, which is the only
+// file that must be intact. The checksums file is compared with the manifest as well. An error is only
+// returned if the manifest cannot be read; damaged files are reported in the Report.
+func Verify(zr *zip.Reader) (Report, error) {
+	archived := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		archived[f.Name] = f
+	}
+
+	manifestFile, ok := archived[ManifestName]
+	if !ok {
+		return Report{}, fmt.Errorf("the archive has no %s, it was exported without include_checksums", ManifestName)
+	}
+	var report Report
+	if err := readJSON(manifestFile, &report.Manifest); err != nil {
+		return Report{}, fmt.Errorf("failed to read %s: %w", ManifestName, err)
+	}
+	if report.Manifest.Algorithm != Algorithm {
+		return Report{}, fmt.Errorf("unsupported checksum algorithm '%s'", report.Manifest.Algorithm)
+	}
+
+	listed := map[string]bool{ManifestName: true}
+	for _, file := range report.Manifest.Files {
+		listed[file.Path] = true
+		f, ok := archived[file.Path]
+		if !ok {
+			report.Files = append(report.Files, FileResult{File: file, Status: StatusMissing, Error: "not in the archive"})
+			continue
+		}
+		report.Files = append(report.Files, verifyFile(f, file))
+	}
+
+	if f, ok := archived[ChecksumsName]; ok {
+		listed[ChecksumsName] = true
+		report.Files = append(report.Files, verifyChecksums(f, report.Manifest))
+	} else {
+		report.Files = append(report.Files, FileResult{File: File{Path: ChecksumsName}, Status: StatusMissing, Error: "not in the archive"})
+	}
+
+	for _, f := range zr.File {
+		if !listed[f.Name] {
+			report.Files = append(report.Files, FileResult{File: File{Path: f.Name, Size: int64(f.UncompressedSize64)}, Status: StatusUnlisted, Error: "not listed in the manifest"})
+		}
+	}
+	return report, nil
+}
+
+// verifyFile hashes a file of the archive and compares it with its listing.
+func verifyFile(f *zip.File, want File) FileResult {
+	result := FileResult{File: want}
+	rc, err := f.Open()
+	if err != nil {
+		result.Status, result.Error = StatusUnreadable, err.Error()
+		return result
+	}
+	defer rc.Close()
+
+	w := NewWriter(io.Discard, want.Path, want.EntryID)
+	if _, err := io.Copy(w, rc); err != nil {
+		result.Status, result.Error = StatusUnreadable, err.Error()
+		return result
+	}
+	got := w.File()
+	switch {
+	case got.SHA256 != want.SHA256:
+		result.Status, result.Error = StatusMismatch, fmt.Sprintf("sha256 is %s", got.SHA256)
+	case got.Size != want.Size:
+		result.Status, result.Error = StatusMismatch, fmt.Sprintf("size is %d bytes", got.Size)
+	default:
+		result.Status = StatusOK
+	}
+	return result
+}
+
+// verifyChecksums compares the checksums file with the manifest, they are written from the same listing.
+func verifyChecksums(f *zip.File, manifest Manifest) FileResult {
+	result := FileResult{File: File{Path: ChecksumsName, Size: int64(f.UncompressedSize64)}}
+	rc, err := f.Open()
+	if err != nil {
+		result.Status, result.Error = StatusUnreadable, err.Error()
+		return result
+	}
+	defer rc.Close()
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		sum, path, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			result.Status, result.Error = StatusMismatch, fmt.Sprintf("invalid line %q", scanner.Text())
+			return result
+		}
+		sums[path] = sum
+	}
+	if err := scanner.Err(); err != nil {
+		result.Status, result.Error = StatusUnreadable, err.Error()
+		return result
+	}
+
+	if len(sums) != len(manifest.Files) {
+		result.Status, result.Error = StatusMismatch, fmt.Sprintf("lists %d files, the manifest %d", len(sums), len(manifest.Files))
+		return result
+	}
+	for _, file := range manifest.Files {
+		if sums[file.Path] != file.SHA256 {
+			result.Status, result.Error = StatusMismatch, fmt.Sprintf("the checksum of %s differs from the manifest", file.Path)
+			return result
+		}
+	}
+	result.Status = StatusOK
+	return result
+}
+
+func readJSON(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		return err
+	}
+	// reading to the end checks the CRC of the file
+	if _, err := io.Copy(io.Discard, rc); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
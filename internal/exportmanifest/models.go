@@ -0,0 +1,57 @@
+package exportmanifest
+
+import (
+	"time"
+
+	"mediahub_oss/pkg/models"
+)
+
+// Names of the integrity files at the root of a ZIP export with include_checksums.
+const (
+	ManifestName  = "manifest.json"
+	ChecksumsName = "checksums.sha256"
+)
+
+// Algorithm is the hash of the listed files.
+const Algorithm = "sha256"
+
+// Manifest lists every file of an export with its size and hash, and how the export was made.
+type Manifest struct {
+	CreatedAt    time.Time            `json:"created_at"`
+	DatabaseID   string               `json:"database_id"`
+	DatabaseName string               `json:"database_name"`
+	ExportedBy   string               `json:"exported_by"`
+	Request      models.ExportRequest `json:"request"`
+	Algorithm    string               `json:"algorithm"`
+	Files        []File               `json:"files"`
+}
+
+// File is a file of the archive. Files not belonging to an entry (entries.csv) have no entry ID.
+type File struct {
+	EntryID int64  `json:"entry_id,omitempty"`
+	Path    string `json:"path"` // path inside the archive
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"` // hex encoded
+}
+
+// Statuses of a verified file.
+const (
+	StatusOK         = "ok"
+	StatusMismatch   = "mismatch"   // the content differs from the manifest
+	StatusUnreadable = "unreadable" // the file cannot be decompressed, e.g. a failed ZIP CRC check
+	StatusMissing    = "missing"    // listed in the manifest, but not in the archive
+	StatusUnlisted   = "unlisted"   // in the archive, but not listed in the manifest
+)
+
+// FileResult is the outcome of verifying a single file.
+type FileResult struct {
+	File
+	Status string
+	Error  string // details unless the status is StatusOK
+}
+
+// Report is the outcome of verifying an archive against its manifest.
+type Report struct {
+	Manifest Manifest
+	Files    []FileResult // in the order of the manifest, followed by unlisted files
+}
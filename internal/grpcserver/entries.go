@@ -0,0 +1,219 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	eh "mediahub_oss/internal/httpserver/entryhandler"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/pkg/mediahubpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// UploadEntry creates an entry from the metadata of the first message and the chunks of the following ones.
+func (s *Server) UploadEntry(stream mediahubpb.EntryService_UploadEntryServer) error {
+	ctx := stream.Context()
+
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "the upload is empty, expected the metadata")
+	} else if err != nil {
+		return err
+	}
+	meta := first.GetMetadata()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "the first message of an upload must carry the metadata")
+	}
+
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	db, err := s.database(ctx, meta.GetDatabaseId(), repo.AccessCreate)
+	if err != nil {
+		return err
+	}
+
+	upload := eh.Upload{
+		Metadata: eh.PostPatchEntryRequest{
+			Timestamp:  math.MinInt64,
+			FileName:   meta.GetFilename(),
+			ExternalID: meta.ExternalId,
+		},
+		FileName:  meta.GetFilename(),
+		MimeType:  meta.GetMimeType(),
+		Size:      meta.GetSize(),
+		ClientIP:  clientIP(ctx),
+		UserAgent: userAgent(ctx),
+	}
+	if meta.Timestamp != nil {
+		upload.Metadata.Timestamp = meta.GetTimestamp()
+	}
+	if meta.GetCustomFields() != nil {
+		upload.Metadata.CustomFields = meta.GetCustomFields().AsMap()
+	}
+
+	entry, err := s.Entries.Ingest(ctx, db, upload, &chunkReader{stream: stream})
+	if err != nil {
+		return s.toStatus(err)
+	}
+	return stream.SendAndClose(toProtoEntry(db.ID, entry))
+}
+
+// GetEntryMeta returns the metadata of an entry.
+func (s *Server) GetEntryMeta(ctx context.Context, req *mediahubpb.GetEntryMetaRequest) (*mediahubpb.Entry, error) {
+	db, err := s.database(ctx, req.GetDatabaseId(), repo.AccessView)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.Entries.Lookup(ctx, db, req.GetId())
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+	return toProtoEntry(db.ID, entry), nil
+}
+
+// SearchEntries returns a page of the entries matching the filter.
+func (s *Server) SearchEntries(ctx context.Context, req *mediahubpb.SearchEntriesRequest) (*mediahubpb.SearchEntriesResponse, error) {
+	db, err := s.database(ctx, req.GetDatabaseId(), repo.AccessView)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.Entries.Search(ctx, db, toSearchRequest(req))
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+
+	resp := &mediahubpb.SearchEntriesResponse{Entries: make([]*mediahubpb.Entry, 0, len(entries))}
+	for _, entry := range entries {
+		resp.Entries = append(resp.Entries, toProtoEntry(db.ID, entry))
+	}
+	return resp, nil
+}
+
+// DeleteEntry deletes an entry with its files.
+func (s *Server) DeleteEntry(ctx context.Context, req *mediahubpb.DeleteEntryRequest) (*mediahubpb.DeleteEntryResponse, error) {
+	if err := s.checkWritable(); err != nil {
+		return nil, err
+	}
+	db, err := s.database(ctx, req.GetDatabaseId(), repo.AccessDelete)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Entries.Remove(ctx, db.ID, req.GetId()); err != nil {
+		return nil, s.toStatus(err)
+	}
+	return &mediahubpb.DeleteEntryResponse{}, nil
+}
+
+// chunkReader reads the file of an upload from the chunks of the stream messages.
+type chunkReader struct {
+	stream mediahubpb.EntryService_UploadEntryServer
+	buf    []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg, err := r.stream.Recv()
+		if err != nil {
+			return 0, err // io.EOF once the client closed the stream
+		}
+		if msg.GetMetadata() != nil {
+			return 0, status.Error(codes.InvalidArgument, "only the first message of an upload may carry the metadata")
+		}
+		r.buf = msg.GetChunk()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// clientIP returns the address of the caller without port.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+func userAgent(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("user-agent"); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func toSearchRequest(req *mediahubpb.SearchEntriesRequest) repo.SearchRequest {
+	searchReq := repo.SearchRequest{
+		Pagination: repo.Pagination{
+			Offset: int(req.GetOffset()),
+			Limit:  int(req.GetLimit()),
+		},
+		Now: time.Now(), // relative times of all conditions are evaluated at the same instant
+	}
+	if filter := req.GetFilter(); filter != nil {
+		searchReq.Filter = &repo.FilterGroup{Operator: filter.GetOperator()}
+		for _, c := range filter.GetConditions() {
+			searchReq.Filter.Conditions = append(searchReq.Filter.Conditions, repo.Condition{
+				Field:    c.GetField(),
+				Operator: c.GetOperator(),
+				Value:    c.GetValue().AsInterface(),
+			})
+		}
+	}
+	if sort := req.GetSort(); sort != nil {
+		searchReq.Sort = &repo.SortCriteria{Field: sort.GetField(), Direction: sort.GetDirection()}
+	}
+	return searchReq
+}
+
+// toProtoEntry maps an entry like the EntryResponse of the REST API.
+func toProtoEntry(dbID repo.ULID, entry repo.Entry) *mediahubpb.Entry {
+	resp := &mediahubpb.Entry{
+		DatabaseId:   dbID.String(),
+		Id:           entry.ID,
+		ExternalId:   entry.ExternalID,
+		Filename:     entry.FileName,
+		MimeType:     entry.MimeType,
+		Status:       repo.GetEntryStatusString(entry.Status),
+		ErrorReason:  entry.ErrorReason,
+		ErrorDetail:  entry.ErrorDetail,
+		Size:         entry.Size,
+		PreviewSize:  entry.PreviewSize,
+		Timestamp:    entry.Timestamp.UnixMilli(),
+		CreatedAt:    entry.CreatedAt.UnixMilli(),
+		UpdatedAt:    entry.UpdatedAt.UnixMilli(),
+		MediaFields:  toStruct(entry.MediaFields),
+		CustomFields: toStruct(entry.CustomFields),
+		LegalHold:    entry.LegalHold,
+	}
+	if !entry.ClientTimestamp.IsZero() {
+		clientTS := entry.ClientTimestamp.UnixMilli()
+		resp.ClientTimestamp = &clientTS
+	}
+	return resp
+}
+
+// toStruct converts media and custom fields, values without a JSON representation are left out.
+func toStruct(fields map[string]any) *structpb.Struct {
+	out := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(fields))}
+	for name, value := range fields {
+		if v, err := structpb.NewValue(value); err == nil {
+			out.Fields[name] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"mediahub_oss/internal/shared/customerrors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCodes maps the customerrors sentinels to the canonical gRPC codes, in the order they are checked.
+var errorCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{customerrors.ErrUnauthenticated, codes.Unauthenticated},
+	{customerrors.ErrPermissionDenied, codes.PermissionDenied},
+	{customerrors.ErrNotFound, codes.NotFound},
+	{customerrors.ErrValidation, codes.InvalidArgument},
+	{customerrors.ErrBadMimeType, codes.InvalidArgument},
+	{customerrors.ErrUnsupportedMedia, codes.InvalidArgument},
+	{customerrors.ErrInfected, codes.InvalidArgument},
+	{customerrors.ErrConflict, codes.AlreadyExists},
+	{customerrors.ErrLegalHold, codes.FailedPrecondition},
+	{customerrors.ErrLimitReached, codes.ResourceExhausted},
+	{customerrors.ErrQuotaExceeded, codes.ResourceExhausted},
+	{customerrors.ErrDependencies, codes.FailedPrecondition},
+	{customerrors.ErrUnavailable, codes.Unavailable},
+	{customerrors.ErrScannerUnavailable, codes.Unavailable},
+	{customerrors.ErrNotImplemented, codes.Unimplemented},
+	{context.Canceled, codes.Canceled},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+}
+
+// toStatus converts an error of the shared entry operations into a gRPC status error. Errors that are
+// already a status, e.g. of receiving the upload stream, are returned as they are. Unknown errors are
+// logged and reported as Internal without details.
+func (s *Server) toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	for _, mapping := range errorCodes {
+		if errors.Is(err, mapping.err) {
+			return status.Error(mapping.code, err.Error())
+		}
+	}
+	s.Logger.Error("gRPC call failed", "error", err)
+	return status.Error(codes.Internal, "internal server error")
+}
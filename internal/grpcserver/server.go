@@ -0,0 +1,135 @@
+// Package grpcserver serves the gRPC API of proto/mediahub/v1 next to the REST API. It is a thin layer over
+// the entry operations of the REST handlers, so both APIs share their checks, processing and audit log.
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+
+	"mediahub_oss/internal/httpserver"
+	"mediahub_oss/internal/httpserver/auth"
+	eh "mediahub_oss/internal/httpserver/entryhandler"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/maintenance"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/pkg/mediahubpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the EntryService of the gRPC API.
+type Server struct {
+	mediahubpb.UnimplementedEntryServiceServer
+
+	Logger      *slog.Logger
+	Repo        repo.Repository
+	Entries     *eh.EntryHandler // the entry operations shared with the REST API
+	Auth        *auth.AuthMiddleware
+	Maintenance *maintenance.Mode    // uploads and deletions are rejected while it is enabled, nil to ignore
+	IPFilter    *httpserver.IPFilter // the allowlist and denylist of the REST API, nil to allow every peer
+}
+
+// NewGRPCServer returns a gRPC server with the EntryService of s. Every call is checked against the IP
+// filter and authenticated with its "authorization" metadata before it reaches s.
+func NewGRPCServer(s *Server) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.filterUnary, s.authenticateUnary),
+		grpc.ChainStreamInterceptor(s.filterStream, s.authenticateStream),
+	)
+	mediahubpb.RegisterEntryServiceServer(server, s)
+	return server
+}
+
+// checkPeer rejects calls of peers the IP filter does not allow. The address of the peer is used as it is,
+// the gRPC API is not served behind the trusted proxies of the REST API.
+func (s *Server) checkPeer(ctx context.Context, fullMethod string) error {
+	if s.IPFilter == nil || s.IPFilter.AllowedCall(ctx, clientIP(ctx), "gRPC", fullMethod) {
+		return nil
+	}
+	return status.Error(codes.PermissionDenied, "forbidden")
+}
+
+func (s *Server) filterUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.checkPeer(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) filterStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.checkPeer(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// authenticate adds the user and the permission holder of the "authorization" metadata to ctx.
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	ctx, err := s.Auth.Authenticate(ctx, authorization)
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+	return ctx, nil
+}
+
+func (s *Server) authenticateUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authenticateStream(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream passes the authenticated context to the stream handlers.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// database checks the permission of the caller on a database and loads it. Callers without any right on the
+// database are not told whether it exists.
+func (s *Server) database(ctx context.Context, dbID string, perm repo.AccessGrant) (repo.Database, error) {
+	if dbID == "" {
+		return repo.Database{}, status.Error(codes.InvalidArgument, "missing database_id")
+	}
+	if holder := utils.GetPermissionHolderFromContext(ctx); !holder.HasPermission(repo.ULID(dbID), perm) {
+		return repo.Database{}, status.Errorf(codes.PermissionDenied, "you lack required rights on database '%s'", dbID)
+	}
+	db, err := s.Repo.GetDatabase(ctx, repo.ULID(dbID))
+	if err != nil {
+		return repo.Database{}, s.toStatus(err)
+	}
+	return db, nil
+}
+
+// checkWritable rejects writes while the maintenance mode is enabled.
+func (s *Server) checkWritable() error {
+	if s.Maintenance == nil {
+		return nil
+	}
+	if state := s.Maintenance.State(); state.Enabled {
+		return status.Error(codes.Unavailable, state.Message)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"testing"
+
+	"mediahub_oss/internal/httpserver"
+	"mediahub_oss/internal/httpserver/auth"
+	eh "mediahub_oss/internal/httpserver/entryhandler"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+	"mediahub_oss/pkg/mediahubpb"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// plainFileConverter stores uploads as they are, without media tools.
+type plainFileConverter struct {
+	media.MediaConverter
+}
+
+func (plainFileConverter) CanCreatePreview(string) bool { return false }
+
+func (plainFileConverter) CanConvert(string, string) media.ConversionCheck {
+	return media.ConversionCheck{}
+}
+
+func (plainFileConverter) ReadMediaFieldsFromStream(context.Context, io.ReadSeeker, string) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+
+func TestEntryService(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "grpc_test", ContentType: "file", Config: repo.DatabaseConfig{UniqueExternalID: true}, CustomFields: []repo.CustomFieldDef{{Name: "camera", Type: "TEXT"}}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	for username, roles := range map[string]repo.AccessGrant{
+		"grpc_uploader": repo.NewAccessGrant(true, true, true, true, false),
+		"grpc_viewer":   repo.NewAccessGrant(true, false, false, false, false),
+		"grpc_quota":    repo.NewAccessGrant(true, true, false, false, false),
+	} {
+		var quota uint64
+		if username == "grpc_quota" {
+			quota = 8
+		}
+		user, err := r.CreateUser(ctx, repo.User{Username: username, PasswordHash: string(hash), StorageQuota: quota})
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		if err := r.SetUserPermissions(ctx, repo.UserPermissions{UserID: user.ID, DatabaseID: db.ID, Roles: roles}); err != nil {
+			t.Fatalf("failed to set permissions: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	keys, err := auth.NewKeyring("test-secret")
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	server := NewGRPCServer(&Server{
+		Logger: logger,
+		Repo:   r,
+		Entries: &eh.EntryHandler{
+			Logger:         logger,
+			Auditor:        audit.NewAlNoopLogger(),
+			Repo:           r,
+			Storage:        store,
+			Limits:         eh.NewUploadLimits(1<<20, 0),
+			MediaConverter: plainFileConverter{},
+			Processor:      proc,
+		},
+		Auth: auth.NewAuthMiddleware(r, keys),
+	})
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer conn.Close()
+	client := mediahubpb.NewEntryServiceClient(conn)
+
+	as := func(username string) context.Context {
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":secret"))
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Basic "+credentials)
+	}
+	upload := func(ctx context.Context, meta *mediahubpb.UploadMetadata, chunks ...string) (*mediahubpb.Entry, error) {
+		stream, err := client.UploadEntry(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if meta != nil {
+			if err := stream.Send(&mediahubpb.UploadEntryRequest{Payload: &mediahubpb.UploadEntryRequest_Metadata{Metadata: meta}}); err != nil {
+				return nil, err
+			}
+		}
+		for _, chunk := range chunks {
+			if err := stream.Send(&mediahubpb.UploadEntryRequest{Payload: &mediahubpb.UploadEntryRequest_Chunk{Chunk: []byte(chunk)}}); err != nil {
+				return nil, err
+			}
+		}
+		return stream.CloseAndRecv()
+	}
+	expectCode := func(name string, err error, want codes.Code) {
+		t.Helper()
+		if got := status.Code(err); got != want {
+			t.Errorf("%s: expected %v, got %v", name, want, err)
+		}
+	}
+	externalID := "cam-1"
+	customFields, _ := structpb.NewStruct(map[string]any{"camera": "front"})
+	meta := &mediahubpb.UploadMetadata{DatabaseId: db.ID.String(), Filename: "notes.txt", MimeType: "text/plain", ExternalId: &externalID, CustomFields: customFields}
+
+	// 1. Calls need credentials and the right on the database
+	_, err = upload(ctx, meta, "data")
+	expectCode("upload without credentials", err, codes.Unauthenticated)
+	_, err = upload(as("grpc_viewer"), meta, "data")
+	expectCode("upload of a viewer", err, codes.PermissionDenied)
+
+	// 2. Invalid uploads
+	_, err = upload(as("grpc_uploader"), nil, "data")
+	expectCode("upload without metadata", err, codes.InvalidArgument)
+	_, err = upload(as("grpc_uploader"), &mediahubpb.UploadMetadata{DatabaseId: db.ID.String(), Filename: "short.txt", Size: 100}, "data")
+	expectCode("truncated upload", err, codes.InvalidArgument)
+	_, err = upload(as("grpc_uploader"), &mediahubpb.UploadMetadata{DatabaseId: db.ID.String(), Filename: "empty.txt"})
+	expectCode("empty upload", err, codes.InvalidArgument)
+	_, err = upload(as("grpc_quota"), &mediahubpb.UploadMetadata{DatabaseId: db.ID.String(), Filename: "large.txt"}, "more than eight bytes")
+	expectCode("upload over the storage quota", err, codes.ResourceExhausted)
+
+	// 3. An upload in chunks is stored like a REST upload
+	created, err := upload(as("grpc_uploader"), meta, "first chunk, ", "second chunk")
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if created.GetStatus() != "ready" || created.GetSize() != uint64(len("first chunk, second chunk")) || created.GetExternalId() != externalID || created.GetCustomFields().AsMap()["camera"] != "front" {
+		t.Errorf("unexpected entry: %v", created)
+	}
+	if data, err := store.Read(ctx, db.ID.String(), created.GetId(), 0, -1); err != nil {
+		t.Errorf("failed to read the stored file: %v", err)
+	} else {
+		stored, _ := io.ReadAll(data)
+		data.Close()
+		if string(stored) != "first chunk, second chunk" {
+			t.Errorf("expected the chunks to be stored in order, got %q", stored)
+		}
+	}
+	_, err = upload(as("grpc_uploader"), meta, "again")
+	expectCode("duplicate external id", err, codes.AlreadyExists)
+
+	// 4. Metadata and search
+	got, err := client.GetEntryMeta(as("grpc_viewer"), &mediahubpb.GetEntryMetaRequest{DatabaseId: db.ID.String(), Id: created.GetId()})
+	if err != nil || got.GetFilename() != "notes.txt" {
+		t.Errorf("expected the metadata of the entry, got %v: %v", got, err)
+	}
+	search := func(value string) []*mediahubpb.Entry {
+		t.Helper()
+		resp, err := client.SearchEntries(as("grpc_viewer"), &mediahubpb.SearchEntriesRequest{
+			DatabaseId: db.ID.String(),
+			Filter: &mediahubpb.Filter{Operator: "and", Conditions: []*mediahubpb.Condition{
+				{Field: "camera", Operator: "=", Value: structpb.NewStringValue(value)},
+				{Field: "created_at", Operator: ">=", Value: structpb.NewStringValue("now-1h")},
+			}},
+			Limit: 10,
+		})
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		return resp.GetEntries()
+	}
+	if found := search("front"); len(found) != 1 || found[0].GetId() != created.GetId() {
+		t.Errorf("expected the uploaded entry, got %v", found)
+	}
+	if found := search("rear"); len(found) != 0 {
+		t.Errorf("expected no entries of another camera, got %v", found)
+	}
+	_, err = client.SearchEntries(as("grpc_viewer"), &mediahubpb.SearchEntriesRequest{DatabaseId: db.ID.String(), Filter: &mediahubpb.Filter{
+		Operator: "and", Conditions: []*mediahubpb.Condition{{Field: "timestamp", Operator: ">", Value: structpb.NewStringValue("yesterday")}},
+	}})
+	expectCode("invalid relative time", err, codes.InvalidArgument)
+
+	// 5. Deletion
+	_, err = client.DeleteEntry(as("grpc_viewer"), &mediahubpb.DeleteEntryRequest{DatabaseId: db.ID.String(), Id: created.GetId()})
+	expectCode("delete of a viewer", err, codes.PermissionDenied)
+	if _, err := client.DeleteEntry(as("grpc_uploader"), &mediahubpb.DeleteEntryRequest{DatabaseId: db.ID.String(), Id: created.GetId()}); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	_, err = client.GetEntryMeta(as("grpc_viewer"), &mediahubpb.GetEntryMetaRequest{DatabaseId: db.ID.String(), Id: created.GetId()})
+	expectCode("metadata of a deleted entry", err, codes.NotFound)
+	if found := search("front"); len(found) != 0 {
+		t.Errorf("expected the deleted entry to be gone, got %v", found)
+	}
+}
+
+func TestIPFilterDeniesPeer(t *testing.T) {
+	ctx := context.Background()
+
+	// A denied peer is rejected before authentication, so the server needs no repository
+	filter := &httpserver.IPFilter{Deny: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}
+	server := NewGRPCServer(&Server{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), IPFilter: filter})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer conn.Close()
+	client := mediahubpb.NewEntryServiceClient(conn)
+
+	_, err = client.SearchEntries(ctx, &mediahubpb.SearchEntriesRequest{DatabaseId: "db"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("search: expected PermissionDenied, got %v", err)
+	}
+	_, err = client.DeleteEntry(ctx, &mediahubpb.DeleteEntryRequest{DatabaseId: "db", Id: 1})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("delete: expected PermissionDenied, got %v", err)
+	}
+	stream, err := client.UploadEntry(ctx)
+	if err == nil {
+		_, err = stream.CloseAndRecv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("upload: expected PermissionDenied, got %v", err)
+	}
+	if blocked := filter.Blocked(); blocked != 3 {
+		t.Errorf("expected 3 blocked calls, got %d", blocked)
+	}
+}
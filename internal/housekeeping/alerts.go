@@ -0,0 +1,101 @@
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mediahub_oss/internal/alerts"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+)
+
+// UsagePercent returns the disk usage of a database relative to its housekeeping disk_space limit.
+// The second value is false if the database has no limit.
+func UsagePercent(db repository.Database, usedBytes uint64) (float64, bool) {
+	if db.Housekeeping.DiskSpace == 0 {
+		return 0, false
+	}
+	return float64(usedBytes) * 100 / float64(db.Housekeeping.DiskSpace), true
+}
+
+// diskSpaceAlertDue reports whether the disk space alert of a database should be active at this usage.
+func diskSpaceAlertDue(db repository.Database, usedBytes uint64) bool {
+	if db.Housekeeping.DiskSpaceWarnPercent <= 0 {
+		return false
+	}
+	usage, limited := UsagePercent(db, usedBytes)
+	return limited && usage >= float64(db.Housekeeping.DiskSpaceWarnPercent)
+}
+
+// CheckDiskSpaceAlert sends a one-time alert when the usage of a database crosses its warning threshold.
+// The alert state is stored with the database once the alert was delivered, so the alert is not repeated
+// until the usage has dropped below the threshold again, and a failed delivery is retried by the next check.
+func (s *HouseKeeper) CheckDiskSpaceAlert(ctx context.Context, db repository.Database, usedBytes uint64) error {
+	due := diskSpaceAlertDue(db, usedBytes)
+	stats, err := s.Repo.GetDatabaseStats(ctx, db.ID)
+	if err != nil {
+		return err
+	}
+	if due == stats.DiskSpaceAlert {
+		return nil
+	}
+
+	usage, _ := UsagePercent(db, usedBytes)
+	if !due {
+		changed, err := s.Repo.SetDiskSpaceAlert(ctx, db.ID, false)
+		if err != nil {
+			return err
+		}
+		if changed {
+			s.Logger.Info("Disk space usage dropped below the warning threshold", "database_id", db.ID, "database_name", db.Name, "usage_percent", usage)
+		}
+		return nil
+	}
+
+	details := map[string]any{
+		"usage_percent":           usage,
+		"disk_space_warn_percent": db.Housekeeping.DiskSpaceWarnPercent,
+		"used_bytes":              usedBytes,
+		"disk_space_bytes":        db.Housekeeping.DiskSpace,
+	}
+	if s.Notifier != nil {
+		alert := alerts.Alert{
+			Kind:         "disk_space_warning",
+			DatabaseID:   db.ID.String(),
+			DatabaseName: db.Name,
+			Message: fmt.Sprintf("Database '%s' uses %s of its %s disk space limit (%.1f%%). Housekeeping deletes the oldest entries once the limit is exceeded.",
+				db.Name, shared.BytesToString(usedBytes), shared.BytesToString(db.Housekeeping.DiskSpace), usage),
+			Details: details,
+			Time:    time.Now(),
+		}
+		if err := s.Notifier.Notify(ctx, alert); err != nil {
+			return fmt.Errorf("failed to send disk space alert: %w", err)
+		}
+	}
+
+	changed, err := s.Repo.SetDiskSpaceAlert(ctx, db.ID, true)
+	if err != nil {
+		return err
+	}
+	if changed && s.Auditor != nil {
+		s.Auditor.Log(ctx, "database.disk_space_warning", "housekeeping", db.ID.String(), details)
+	}
+	return nil
+}
+
+// checkDiskSpaceAlerts checks the alerts of all databases against their current statistics,
+// so a threshold crossed by uploads is noticed before the next housekeeping run.
+func (s *HouseKeeper) checkDiskSpaceAlerts(ctx context.Context) {
+	dbs, err := s.Repo.GetDatabases(ctx)
+	if err != nil {
+		s.Logger.Error("Failed to fetch databases for disk space alerts", "error", err)
+		return
+	}
+	for _, db := range dbs {
+		if err := s.CheckDiskSpaceAlert(ctx, db, db.Stats.TotalDiskSpaceBytes); err != nil {
+			s.Logger.Error("Failed to check disk space alert", "database_id", db.ID, "database_name", db.Name, "error", err)
+			s.notice(ctx, "disk_space_alert", db, "Failed to check the disk space alert", err)
+		}
+	}
+}
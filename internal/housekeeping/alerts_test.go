@@ -0,0 +1,127 @@
+package housekeeping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/alerts"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+type recordingNotifier struct {
+	alerts []alerts.Alert
+	err    error // returned instead of recording the alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert alerts.Alert) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func TestDiskSpaceAlertHysteresis(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "alert_test",
+		ContentType:  "file",
+		Housekeeping: repo.DatabaseHK{DiskSpace: 1000, DiskSpaceWarnPercent: repo.DefaultDiskSpaceWarnPercent},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	notifier := &recordingNotifier{}
+	hk := NewHouseKeeper(r, &localstorage.LocalStorage{RootPath: t.TempDir()}, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+	hk.Notifier = notifier
+
+	// The alert fires once on crossing the threshold, and again only after usage dropped below it
+	steps := []struct {
+		used       uint64
+		wantActive bool
+		wantAlerts int
+	}{
+		{840, false, 0},
+		{850, true, 1},
+		{990, true, 1},
+		{860, true, 1},
+		{849, false, 1},
+		{700, false, 1},
+		{900, true, 2},
+	}
+	for _, step := range steps {
+		if err := hk.CheckDiskSpaceAlert(ctx, db, step.used); err != nil {
+			t.Fatalf("used %d: failed to check the alert: %v", step.used, err)
+		}
+		stats, err := r.GetDatabaseStats(ctx, db.ID)
+		if err != nil {
+			t.Fatalf("failed to get stats: %v", err)
+		}
+		if stats.DiskSpaceAlert != step.wantActive {
+			t.Errorf("used %d: expected the alert state %v, got %v", step.used, step.wantActive, stats.DiskSpaceAlert)
+		}
+		if len(notifier.alerts) != step.wantAlerts {
+			t.Errorf("used %d: expected %d alerts in total, got %d", step.used, step.wantAlerts, len(notifier.alerts))
+		}
+	}
+	if alert := notifier.alerts[0]; alert.Kind != "disk_space_warning" || alert.DatabaseName != "alert_test" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+
+	// A disabled threshold or limit never alerts, and clears an active alert
+	disabled := db
+	disabled.Housekeeping.DiskSpaceWarnPercent = 0
+	if err := hk.CheckDiskSpaceAlert(ctx, disabled, 1000); err != nil {
+		t.Fatalf("failed to check the alert: %v", err)
+	}
+	unlimited := db
+	unlimited.Housekeeping.DiskSpace = 0
+	if err := hk.CheckDiskSpaceAlert(ctx, unlimited, 1000); err != nil {
+		t.Fatalf("failed to check the alert: %v", err)
+	}
+	if stats, _ := r.GetDatabaseStats(ctx, db.ID); stats.DiskSpaceAlert || len(notifier.alerts) != 2 {
+		t.Errorf("expected no further alert and a cleared state, got %v with %d alerts", stats.DiskSpaceAlert, len(notifier.alerts))
+	}
+
+	// A failed delivery leaves the alert inactive, so the next check sends it
+	notifier.err = errors.New("webhook unreachable")
+	if err := hk.CheckDiskSpaceAlert(ctx, db, 900); err == nil {
+		t.Error("expected the failed delivery to be returned")
+	}
+	if stats, _ := r.GetDatabaseStats(ctx, db.ID); stats.DiskSpaceAlert {
+		t.Error("expected the alert to stay inactive after a failed delivery")
+	}
+	notifier.err = nil
+	if err := hk.CheckDiskSpaceAlert(ctx, db, 900); err != nil {
+		t.Fatalf("failed to check the alert: %v", err)
+	}
+	if stats, _ := r.GetDatabaseStats(ctx, db.ID); !stats.DiskSpaceAlert || len(notifier.alerts) != 3 {
+		t.Errorf("expected the alert to be sent by the next check, got %v with %d alerts", stats.DiskSpaceAlert, len(notifier.alerts))
+	}
+}
@@ -0,0 +1,107 @@
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mediahub_oss/internal/alerts"
+	"mediahub_oss/internal/repository"
+)
+
+// backlogAlertSampleSize is the number of stuck entry IDs listed in a processing alert.
+const backlogAlertSampleSize = 10
+
+// processingAlertDue reports whether the processing alert of a database should be active when its oldest
+// processing entry is age old. The alert is raised at the alert age and cleared only once no entry is older
+// than half of it, so a backlog hovering around the threshold does not alert again and again.
+func processingAlertDue(active bool, age, alertAge time.Duration) bool {
+	if alertAge <= 0 {
+		return false
+	}
+	if active {
+		return age >= alertAge/2
+	}
+	return age >= alertAge
+}
+
+// CheckProcessingBacklog sends a one-time alert for every database whose oldest processing entry is older
+// than ProcessingAgeAlert. Like the disk space alert, the state is stored with the database, so each backlog
+// is reported once by one instance.
+func (s *HouseKeeper) CheckProcessingBacklog(ctx context.Context, now time.Time) error {
+	dbs, err := s.Repo.GetDatabases(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch databases: %w", err)
+	}
+	backlog, err := s.Repo.GetProcessingBacklog(ctx, repository.BacklogFilter{SampleSize: backlogAlertSampleSize})
+	if err != nil {
+		return fmt.Errorf("failed to query processing backlog: %w", err)
+	}
+	byDatabase := make(map[repository.ULID]repository.ProcessingBacklog, len(backlog))
+	for _, b := range backlog {
+		byDatabase[b.DatabaseID] = b
+	}
+
+	for _, db := range dbs {
+		b, pending := byDatabase[db.ID]
+		due := pending && processingAlertDue(b.AlertActive, now.Sub(b.OldestSince), s.ProcessingAgeAlert)
+		if err := s.setProcessingAlert(ctx, db, b, due, now); err != nil {
+			s.Logger.Error("Failed to check processing alert", "database_id", db.ID, "database_name", db.Name, "error", err)
+			s.notice(ctx, "processing_alert", db, "Failed to check the processing backlog alert", err)
+		}
+	}
+	return nil
+}
+
+// setProcessingAlert records the alert state of a database and sends the alert if it was raised.
+func (s *HouseKeeper) setProcessingAlert(ctx context.Context, db repository.Database, b repository.ProcessingBacklog, due bool, now time.Time) error {
+	changed, err := s.Repo.SetProcessingAlert(ctx, db.ID, due)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	if !due {
+		s.Logger.Info("Processing backlog cleared", "database_id", db.ID, "database_name", db.Name, "processing", b.Count)
+		return nil
+	}
+
+	age := now.Sub(b.OldestSince).Round(time.Second)
+	details := map[string]any{
+		"processing":           b.Count,
+		"oldest_age_seconds":   int64(age.Seconds()),
+		"processing_age_alert": s.ProcessingAgeAlert.String(),
+		"sample_ids":           b.SampleIDs,
+	}
+	if s.Auditor != nil {
+		s.Auditor.Log(ctx, "database.processing_backlog_warning", "housekeeping", db.ID.String(), details)
+	}
+	if s.Notifier == nil {
+		return nil
+	}
+
+	alert := alerts.Alert{
+		Kind:         "processing_backlog_warning",
+		DatabaseID:   db.ID.String(),
+		DatabaseName: db.Name,
+		Message: fmt.Sprintf("Database '%s' has %d entries in processing, the oldest for %s (alert age %s). The conversion workers may be overloaded or stuck.",
+			db.Name, b.Count, age, s.ProcessingAgeAlert),
+		Details: details,
+		Time:    now,
+	}
+	if err := s.Notifier.Notify(ctx, alert); err != nil {
+		return fmt.Errorf("failed to send processing alert: %w", err)
+	}
+	return nil
+}
+
+// checkProcessingAlerts runs CheckProcessingBacklog for the scheduler, if the alert is enabled.
+func (s *HouseKeeper) checkProcessingAlerts(ctx context.Context) {
+	if s.ProcessingAgeAlert <= 0 {
+		return
+	}
+	if err := s.CheckProcessingBacklog(ctx, time.Now()); err != nil {
+		s.Logger.Error("Failed to check processing alerts", "error", err)
+	}
+}
@@ -0,0 +1,119 @@
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestProcessingBacklogAlert(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "backlog_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	if _, err := r.CreateDatabase(ctx, repo.Database{Name: "idle", ContentType: "file"}); err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	notifier := &recordingNotifier{}
+	hk := NewHouseKeeper(r, &localstorage.LocalStorage{RootPath: t.TempDir()}, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+	hk.Notifier = notifier
+	hk.ProcessingAgeAlert = 30 * time.Minute
+
+	// A single processing entry uploaded at start, its age grows with the time of the checks
+	start := time.Now()
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: start, MimeType: "application/octet-stream", Status: repo.EntryStatusProcessing})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if _, err := r.DB.ExecContext(ctx, fmt.Sprintf(`UPDATE "entries_%s" SET created_at = ? WHERE id = ?`, db.ID), start.UnixMilli(), entry.ID); err != nil {
+		t.Fatalf("failed to set upload time: %v", err)
+	}
+
+	// The alert fires once at the alert age and clears only when the backlog is younger than half of it
+	steps := []struct {
+		name       string
+		age        time.Duration
+		ready      bool // the entry finished processing before the check
+		wantActive bool
+		wantAlerts int
+	}{
+		{"young backlog", 10 * time.Minute, false, false, 0},
+		{"alert age reached", 30 * time.Minute, false, true, 1},
+		{"still stuck", 2 * time.Hour, false, true, 1},
+		{"processed", 2 * time.Hour, true, false, 1},
+		{"stuck again", 3 * time.Hour, false, true, 2},
+	}
+	for _, step := range steps {
+		status := repo.EntryStatusProcessing
+		if step.ready {
+			status = repo.EntryStatusReady
+		}
+		if err := r.UpdateEntryStatus(ctx, db.ID, entry.ID, status, "", ""); err != nil {
+			t.Fatalf("%s: failed to set entry status: %v", step.name, err)
+		}
+		if err := hk.CheckProcessingBacklog(ctx, start.Add(step.age)); err != nil {
+			t.Fatalf("%s: failed to check the backlog: %v", step.name, err)
+		}
+		backlog, err := r.GetProcessingBacklog(ctx, repo.BacklogFilter{})
+		if err != nil {
+			t.Fatalf("%s: failed to get backlog: %v", step.name, err)
+		}
+		active := len(backlog) > 0 && backlog[0].AlertActive
+		if active != step.wantActive {
+			t.Errorf("%s: expected the alert state %v, got %v", step.name, step.wantActive, active)
+		}
+		if len(notifier.alerts) != step.wantAlerts {
+			t.Errorf("%s: expected %d alerts in total, got %d", step.name, step.wantAlerts, len(notifier.alerts))
+		}
+	}
+	if alert := notifier.alerts[0]; alert.Kind != "processing_backlog_warning" || alert.DatabaseName != "backlog_test" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+
+	// Within the hysteresis band an active alert stays, a new one needs the full alert age
+	for _, c := range []struct {
+		active bool
+		age    time.Duration
+		want   bool
+	}{
+		{false, 29 * time.Minute, false},
+		{false, 30 * time.Minute, true},
+		{true, 15 * time.Minute, true},
+		{true, 14 * time.Minute, false},
+	} {
+		if got := processingAlertDue(c.active, c.age, 30*time.Minute); got != c.want {
+			t.Errorf("active %v at %s: expected %v, got %v", c.active, c.age, c.want, got)
+		}
+	}
+	if processingAlertDue(false, 24*time.Hour, 0) {
+		t.Error("expected no alert while the alert is disabled")
+	}
+}
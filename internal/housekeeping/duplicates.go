@@ -0,0 +1,144 @@
+package housekeeping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// duplicateScanBatch is the number of entries hashed between two progress updates of a duplicate scan.
+var duplicateScanBatch = 100
+
+// StartDuplicateScan creates a scan for the duplicates of a database and runs it in the background.
+// It returns ErrConflict if a scan of the database is already running.
+func (s *HouseKeeper) StartDuplicateScan(ctx context.Context, db repository.Database, username string) (repository.DuplicateScan, error) {
+	scan, err := s.Repo.CreateDuplicateScan(ctx, repository.DuplicateScan{DatabaseID: db.ID, CreatedBy: username})
+	if err != nil {
+		return scan, err
+	}
+
+	// The scan outlives the request, an interrupted scan is resumed on the next start
+	go func() {
+		if err := s.RunDuplicateScan(context.WithoutCancel(ctx), scan); err != nil {
+			s.Logger.Error("Duplicate scan failed", "database_id", db.ID, "scan", scan.ID, "error", err)
+		}
+	}()
+	return scan, nil
+}
+
+// ResumeDuplicateScans continues the scans that were running when the server stopped, one after the other.
+func (s *HouseKeeper) ResumeDuplicateScans(ctx context.Context) {
+	scans, err := s.Repo.GetRunningDuplicateScans(ctx)
+	if err != nil {
+		s.Logger.Error("Failed to fetch interrupted duplicate scans", "error", err)
+		return
+	}
+
+	for _, scan := range scans {
+		s.Logger.Info("Resuming duplicate scan", "database_id", scan.DatabaseID, "scan", scan.ID, "processed", scan.Processed, "total", scan.Total)
+		if err := s.RunDuplicateScan(ctx, scan); err != nil {
+			if errors.Is(err, customerrors.ErrLockNotAcquired) {
+				s.Logger.Debug("Skipping duplicate scan; locked by another instance", "database_id", scan.DatabaseID, "scan", scan.ID)
+			} else {
+				s.Logger.Error("Duplicate scan failed", "database_id", scan.DatabaseID, "scan", scan.ID, "error", err)
+			}
+		}
+	}
+}
+
+// RunDuplicateScan hashes the files of the entries covered by a scan, starting after its last
+// recorded entry. Stored content hashes are reused, missing ones are computed, paced like the
+// integrity check, and stored together with the progress after every batch. Files that cannot be
+// read are counted as failed and left out of the groups. If the context is cancelled, the scan stays
+// running, so it is resumed later. Other errors fail the scan.
+func (s *HouseKeeper) RunDuplicateScan(ctx context.Context, scan repository.DuplicateScan) error {
+	var lockName = "duplicates_" + scan.DatabaseID.String()
+
+	acquired, err := s.Repo.AcquireLock(ctx, lockName, s.InstanceID, 6*time.Hour)
+	if err != nil {
+		return fmt.Errorf("failed to check lock status: %w", err)
+	}
+	if !acquired {
+		return customerrors.ErrLockNotAcquired
+	}
+	defer func() {
+		if err := s.Repo.ReleaseLock(ctx, lockName, s.InstanceID); err != nil {
+			s.Logger.Error("Failed to release lock after duplicate scan", "scan", scan.ID, "error", err)
+		}
+	}()
+
+	if err := s.hashDuplicateScan(ctx, &scan); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		scan.Status = repository.DuplicateScanFailed
+		scan.Error = err.Error()
+		scan.FinishedAt = time.Now()
+		if updateErr := s.Repo.UpdateDuplicateScan(ctx, scan); updateErr != nil && !errors.Is(updateErr, customerrors.ErrNotFound) {
+			s.Logger.Error("Failed to record failed duplicate scan", "scan", scan.ID, "error", updateErr)
+		}
+		return err
+	}
+
+	scan.Status = repository.DuplicateScanDone
+	scan.FinishedAt = time.Now()
+	if err := s.Repo.UpdateDuplicateScan(ctx, scan); err != nil {
+		return fmt.Errorf("failed to record finished duplicate scan: %w", err)
+	}
+	s.Logger.Info("Duplicate scan completed", "database_id", scan.DatabaseID, "scan", scan.ID, "processed", scan.Processed, "failed", scan.Failed)
+	return nil
+}
+
+// hashDuplicateScan processes the remaining entries of a scan batch by batch.
+func (s *HouseKeeper) hashDuplicateScan(ctx context.Context, scan *repository.DuplicateScan) error {
+	for {
+		entries, err := s.Repo.GetEntriesToHash(ctx, scan.DatabaseID, scan.LastEntryID, scan.MaxEntryID, duplicateScanBatch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch entries to hash: %w", err)
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		hashes := make(map[int64]string)
+		for _, entry := range entries {
+			if entry.ContentHash != "" {
+				continue
+			}
+			if len(hashes) > 0 && s.integrity().Pause > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(s.integrity().Pause):
+				}
+			}
+
+			hash, err := s.hashStoredFile(ctx, scan.DatabaseID, entry.ID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if !errors.Is(err, os.ErrNotExist) && !errors.Is(err, customerrors.ErrNotFound) {
+					s.Logger.Warn("Failed to read file for duplicate scan", "database_id", scan.DatabaseID, "entry", entry.ID, "error", err)
+				}
+				scan.Failed++
+				continue
+			}
+			hashes[entry.ID] = hash
+		}
+
+		if err := s.Repo.StoreContentHashes(ctx, scan.DatabaseID, hashes); err != nil {
+			return fmt.Errorf("failed to store content hashes: %w", err)
+		}
+		scan.LastEntryID = entries[len(entries)-1].ID
+		scan.Processed += int64(len(entries))
+		if err := s.Repo.UpdateDuplicateScan(ctx, *scan); err != nil {
+			return fmt.Errorf("failed to record duplicate scan progress: %w", err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package housekeeping
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// countingStorage records the files read and cancels the scan when a given entry is read.
+type countingStorage struct {
+	*localstorage.LocalStorage
+	reads    []int64
+	cancelAt int64
+	cancel   context.CancelFunc
+}
+
+func (c *countingStorage) Read(ctx context.Context, dbID string, id int64, offset int64, length int64) (io.ReadCloser, error) {
+	c.reads = append(c.reads, id)
+	if id == c.cancelAt && c.cancel != nil {
+		c.cancel()
+		return nil, context.Canceled
+	}
+	return c.LocalStorage.Read(ctx, dbID, id, offset, length)
+}
+
+func TestDuplicateScan(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "duplicates_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	root := t.TempDir()
+	store := &countingStorage{LocalStorage: &localstorage.LocalStorage{RootPath: root}}
+	hk := NewHouseKeeper(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+
+	addEntry := func(content string, ts int64) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "file" + strconv.FormatInt(ts, 10) + ".bin",
+			Size:      uint64(len(content)),
+			Timestamp: time.UnixMilli(ts),
+			Status:    repo.EntryStatusReady,
+			MimeType:  "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader(content)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		return entry
+	}
+	filePath := func(id int64) string {
+		return filepath.Join(root, db.ID.String(), strconv.FormatInt(id/1000, 10), strconv.FormatInt(id, 10))
+	}
+
+	photo1 := addEntry("holiday photo", 3000)
+	other := addEntry("something else", 1000)
+	photo2 := addEntry("holiday photo", 2000)
+	report1 := addEntry("quarterly report", 4000)
+	report2 := addEntry("quarterly report", 5000)
+	missing := addEntry("holiday photo", 6000)
+	photo3 := addEntry("holiday photo", 7000)
+
+	// The hash of report1 was recorded by the integrity check, so its file is not needed anymore
+	sum := sha256.Sum256([]byte("quarterly report"))
+	if err := r.RecordEntryVerification(ctx, db.ID, report1.ID, hex.EncodeToString(sum[:]), ""); err != nil {
+		t.Fatalf("failed to record hash: %v", err)
+	}
+	if err := os.Remove(filePath(report1.ID)); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	if err := os.Remove(filePath(missing.ID)); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	duplicateScanBatch = 2
+	defer func() { duplicateScanBatch = 100 }()
+
+	// 1. Only one scan per database may run
+	scan, err := r.CreateDuplicateScan(ctx, repo.DuplicateScan{DatabaseID: db.ID, CreatedBy: "admin"})
+	if err != nil {
+		t.Fatalf("failed to create scan: %v", err)
+	}
+	if scan.Total != 7 || scan.MaxEntryID != photo3.ID {
+		t.Errorf("expected 7 entries up to %d, got %d up to %d", photo3.ID, scan.Total, scan.MaxEntryID)
+	}
+	if _, err := r.CreateDuplicateScan(ctx, repo.DuplicateScan{DatabaseID: db.ID}); !errors.Is(err, customerrors.ErrConflict) {
+		t.Errorf("expected ErrConflict for a second scan, got %v", err)
+	}
+
+	// 2. The scan is interrupted in its second batch, the first one is kept
+	runCtx, cancel := context.WithCancel(ctx)
+	store.cancelAt, store.cancel = photo2.ID, cancel
+	if err := hk.RunDuplicateScan(runCtx, scan); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the scan to be cancelled, got %v", err)
+	}
+	scan, err = r.GetDuplicateScan(ctx, scan.ID)
+	if err != nil {
+		t.Fatalf("failed to get scan: %v", err)
+	}
+	if scan.Status != repo.DuplicateScanRunning || scan.LastEntryID != other.ID || scan.Processed != 2 {
+		t.Errorf("expected the progress of the first batch, got %+v", scan)
+	}
+
+	// 3. Entries created after the start are not part of the scan
+	addEntry("holiday photo", 8000)
+
+	// 4. The restart resumes the scan after the first batch
+	store.reads, store.cancel = nil, nil
+	hk.ResumeDuplicateScans(ctx)
+	if want := []int64{photo2.ID, report2.ID, missing.ID, photo3.ID}; !slices.Equal(store.reads, want) {
+		t.Errorf("expected the remaining files without a hash to be read, got %v, want %v", store.reads, want)
+	}
+
+	scan, err = r.GetDuplicateScan(ctx, scan.ID)
+	if err != nil {
+		t.Fatalf("failed to get scan: %v", err)
+	}
+	if scan.Status != repo.DuplicateScanDone || scan.Processed != 7 || scan.Failed != 1 || scan.FinishedAt.IsZero() {
+		t.Errorf("expected the scan to be done with 1 failed entry, got %+v", scan)
+	}
+
+	// 5. The groups are ordered by their oldest entry, the missing file is left out
+	groups, err := r.GetDuplicateGroups(ctx, db.ID, scan.MaxEntryID)
+	if err != nil {
+		t.Fatalf("failed to get duplicate groups: %v", err)
+	}
+	var got [][]int64
+	for _, group := range groups {
+		var ids []int64
+		for _, entry := range group.Entries {
+			ids = append(ids, entry.ID)
+		}
+		got = append(got, ids)
+	}
+	want := [][]int64{{photo2.ID, photo1.ID, photo3.ID}, {report1.ID, report2.ID}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("expected groups %v, got %v", want, got)
+	}
+
+	// 6. A new scan may start once the previous one is done
+	if _, err := r.CreateDuplicateScan(ctx, repo.DuplicateScan{DatabaseID: db.ID}); err != nil {
+		t.Errorf("expected a new scan to start, got %v", err)
+	}
+}
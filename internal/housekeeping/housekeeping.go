@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"sync"
 	"time"
 
+	"mediahub_oss/internal/alerts"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/notices"
 	"mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared"
 	"mediahub_oss/internal/shared/customerrors"
@@ -21,8 +26,48 @@ type HouseKeeper struct {
 	Logger         *slog.Logger
 	InstanceID     string // Unique identifier for the pod/node
 	AuditRetention time.Duration
+
+	// Optional periodic verification of the stored files, see VerifyDBIntegrity
+	Integrity IntegrityOptions
+	Auditor   audit.AuditLogger
+
+	// Receives the disk space and processing backlog warnings, see CheckDiskSpaceAlert and CheckProcessingBacklog
+	Notifier alerts.Notifier
+
+	// Age of the oldest processing entry of a database that raises an alert, 0 disables it
+	ProcessingAgeAlert time.Duration
+
+	// Runs deleting more entries release the freed pages of the database file, 0 disables it, see vacuumAfterRun
+	VacuumThreshold int
+
+	// Receives the failures on single databases for the administrators, disabled if nil
+	Notices notices.Recorder
+	// How long server notices are kept after their latest repetition, 0 keeps them
+	NoticeRetention time.Duration
+
+	// Guards AuditRetention, Integrity, VacuumThreshold and the sweeps once the scheduler runs, see SetAuditRetention, SetIntegrityLimits and SetVacuumThreshold
+	mu         sync.RWMutex
+	paused     bool                  // scheduled runs are skipped, see SetPaused
+	sweeps     []Sweep               // expired rows removed by the global runs, see RegisterSweep
+	sweepStats map[string]SweepStats // counters of the sweeps since the start, see SweepStats
+
+	runCtx context.Context // context of the scheduler, stops the jobs on shutdown
+	jobs   sync.WaitGroup  // running jobs, see Wait
+}
+
+// HousekeepingReport summarizes the outcome of a housekeeping run on a single database.
+type HousekeepingReport struct {
+	EntriesDeleted int
+	SpaceFreed     uint64
+	EntriesSkipped int   // entries that were due but still queued or being processed
+	EntriesHeld    int   // entries that were due but are under legal hold
+	PagesReclaimed int64 // pages of the database file returned to the file system after the run
 }
 
+// settledStatuses are the entry statuses housekeeping may delete. Queued and processing entries
+// belong to a worker, deleting entries to another deletion in progress.
+var settledStatuses = []repository.EntryStatus{repository.EntryStatusReady, repository.EntryStatusError}
+
 // NewHouseKeeper creates a new Housekeeping Service.
 func NewHouseKeeper(repo repository.Repository, storage storage.StorageProvider, logger *slog.Logger, auditRetention time.Duration) *HouseKeeper {
 	// Use the hostname (Pod name in K8s) as the base instance ID.
@@ -33,18 +78,25 @@ func NewHouseKeeper(repo repository.Repository, storage storage.StorageProvider,
 	// Append startup timestamp to ensure uniqueness even if a pod restarts rapidly
 	instanceID := fmt.Sprintf("%s-%d", hostname, time.Now().Unix())
 
-	return &HouseKeeper{
+	hk := &HouseKeeper{
 		Repo:           repo,
 		Storage:        storage,
 		Logger:         logger,
 		InstanceID:     instanceID,
 		AuditRetention: auditRetention,
+		Notifier:       alerts.NewLogNotifier(logger),
 	}
+	hk.registerDefaultSweeps()
+	return hk
 }
 
 // StartScheduler launches a background goroutine that periodically checks all databases
 // to see if their housekeeping interval has passed.
 func (s *HouseKeeper) StartScheduler(ctx context.Context) {
+	s.mu.Lock()
+	s.runCtx = ctx
+	s.mu.Unlock()
+
 	ticker := time.NewTicker(5 * time.Minute) // Check every 5 minutes
 
 	go func() {
@@ -55,11 +107,103 @@ func (s *HouseKeeper) StartScheduler(ctx context.Context) {
 				ticker.Stop()
 				return
 			case <-ticker.C:
+				if s.isPaused() {
+					s.Logger.Debug("Housekeeping is paused, skipping scheduled run")
+					continue
+				}
 				s.runGlobalTasks(ctx)
 				s.runDBTasks(ctx)
+				s.checkDiskSpaceAlerts(ctx)
+				s.checkProcessingAlerts(ctx)
 			}
 		}
 	}()
+
+	// Verification runs take long, so they get their own loop
+	if s.integrity().Enabled {
+		go s.startIntegrityScheduler(ctx)
+	}
+
+	// Duplicate scans and jobs interrupted by the last shutdown continue where they stopped
+	go s.ResumeDuplicateScans(ctx)
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		s.ResumeJobs(ctx)
+	}()
+}
+
+// Wait waits until the running jobs stopped, after the context of the scheduler was cancelled, or until
+// ctx is done. It returns false if jobs were still running, they are resumed on the next start.
+func (s *HouseKeeper) Wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// SetAuditRetention changes how long audit logs are kept, effective with the next cleanup.
+func (s *HouseKeeper) SetAuditRetention(retention time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.AuditRetention = retention
+}
+
+// SetIntegrityLimits changes the budget and pacing of the verification runs, effective with the next run.
+// Enabling the verification or changing its interval needs a restart.
+func (s *HouseKeeper) SetIntegrityLimits(budget int, maxRate uint64, pause time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Integrity.Budget = budget
+	s.Integrity.MaxRate = maxRate
+	s.Integrity.Pause = pause
+}
+
+// SetVacuumThreshold changes how many entries a run has to delete before the database file is vacuumed, effective with the next run.
+func (s *HouseKeeper) SetVacuumThreshold(threshold int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.VacuumThreshold = threshold
+}
+
+// SetPaused skips the scheduled housekeeping and verification runs, e.g. during maintenance.
+// A run in progress is not interrupted.
+func (s *HouseKeeper) SetPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = paused
+}
+
+func (s *HouseKeeper) isPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.paused
+}
+
+func (s *HouseKeeper) auditRetention() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.AuditRetention
+}
+
+func (s *HouseKeeper) vacuumThreshold() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.VacuumThreshold
+}
+
+func (s *HouseKeeper) integrity() IntegrityOptions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Integrity
 }
 
 // runGlobalTasks handles maintenance that is not tied to a specific media database.
@@ -86,28 +230,40 @@ func (s *HouseKeeper) runGlobalTasks(ctx context.Context) {
 
 	// Execute global maintenance
 
-	// 1. Clean up expired refresh tokens
-	deletedCount, err := s.Repo.DeleteExpiredRefreshTokens(ctx)
-	if err != nil {
-		s.Logger.Error("Failed to clean up expired refresh tokens", "error", err)
-	} else if deletedCount > 0 {
-		s.Logger.Info("Cleaned up expired refresh tokens", "deleted_count", deletedCount)
+	// 1. Clean up the expired rows of the registered tables
+	report := s.RunSweeps(ctx)
+	s.Logger.Debug("Expired rows sweep finished", "deleted_count", report.Total(), "deleted", report.Deleted, "failed", len(report.Failed))
+	// Like the runs on databases, sweeps without anything to report are not audited
+	if s.Auditor != nil && (report.Total() > 0 || len(report.Failed) > 0) {
+		failed := make([]string, 0, len(report.Failed))
+		for name := range report.Failed {
+			failed = append(failed, name)
+		}
+		slices.Sort(failed)
+		s.Auditor.Log(ctx, "housekeeping.sweep", "housekeeping", "sweeps", map[string]any{
+			"deleted_count": report.Total(),
+			"deleted":       report.Deleted,
+			"failed":        failed,
+		})
 	}
 
-	// 1b. Clean up expired API keys
-	deletedKeysCount, err := s.Repo.DeleteExpiredAPIKeys(ctx)
+	// 1b. Fold the usage changes of the uploads and deletions into the per-user summary
+	foldedCount, err := s.Repo.AggregateUserUsage(ctx)
 	if err != nil {
-		s.Logger.Error("Failed to clean up expired API keys", "error", err)
-	} else if deletedKeysCount > 0 {
-		s.Logger.Info("Cleaned up expired API keys", "deleted_count", deletedKeysCount)
+		s.Logger.Error("Failed to aggregate user usage", "error", err)
+	} else if foldedCount > 0 {
+		s.Logger.Debug("Aggregated user usage", "changes", foldedCount)
 	}
 
 	// 2. Clean up old audit logs
-	if err := s.Repo.DeleteLogs(ctx, s.AuditRetention); err != nil {
+	if err := s.Repo.DeleteLogs(ctx, s.auditRetention()); err != nil {
 		s.Logger.Error("Failed to clean up old audit logs", "error", err)
 	} else {
 		s.Logger.Debug("Audit log cleanup routine executed successfully")
 	}
+
+	// 3. Clean up old server notices
+	s.pruneNotices(ctx)
 }
 
 func (s *HouseKeeper) runDBTasks(ctx context.Context) {
@@ -122,32 +278,45 @@ func (s *HouseKeeper) runDBTasks(ctx context.Context) {
 		s.Logger.Debug("Triggering scheduled housekeeping", "database_id", db.ID, "database_name", db.Name)
 
 		// Run synchronously to avoid spiking CPU/Disk I/O with concurrent sweeps
-		_, _, err := s.RunDBHousekeeping(ctx, db)
+		report, err := s.RunDBHousekeeping(ctx, db)
 		if err != nil {
 			if errors.Is(err, customerrors.ErrLockNotAcquired) {
 				s.Logger.Debug("Skipping scheduled housekeeping; locked by another instance", "database_id", db.ID, "database_name", db.Name)
 			} else {
 				s.Logger.Error("Scheduled housekeeping failed", "database_id", db.ID, "database_name", db.Name, "error", err)
+				s.notice(ctx, "run", db, "Scheduled housekeeping failed", err)
 			}
+			continue
+		}
+
+		// Runs without anything to do are not audited, they would bury the activity of the database
+		if s.Auditor != nil && (report.EntriesDeleted > 0 || report.EntriesSkipped > 0 || report.EntriesHeld > 0) {
+			s.Auditor.Log(ctx, "database.housekeeping", "housekeeping", db.ID.String(), map[string]any{
+				"name":            db.Name,
+				"entries_deleted": report.EntriesDeleted,
+				"entries_skipped": report.EntriesSkipped,
+				"entries_held":    report.EntriesHeld,
+				"space_freed":     report.SpaceFreed,
+				"pages_reclaimed": report.PagesReclaimed,
+			})
 		}
 	}
 }
 
 // RunDBHousekeeping executes the cleanup logic for a single database.
 // This can be called by the scheduler or manually via the API.
-func (s *HouseKeeper) RunDBHousekeeping(ctx context.Context, db repository.Database) (int, uint64, error) {
+func (s *HouseKeeper) RunDBHousekeeping(ctx context.Context, db repository.Database) (HousekeepingReport, error) {
 	var lockName = "hk_" + db.ID.String()
-	var totalDeleted int = 0
-	var totalFreed uint64 = 0
+	var report HousekeepingReport
 	var err error
 
 	// 1. Acquire Distributed Lock (30-minute TTL as a safety net for large deletions)
 	acquired, err := s.Repo.AcquireLock(ctx, lockName, s.InstanceID, 30*time.Minute)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to check lock status: %w", err)
+		return report, fmt.Errorf("failed to check lock status: %w", err)
 	}
 	if !acquired {
-		return 0, 0, customerrors.ErrLockNotAcquired
+		return report, customerrors.ErrLockNotAcquired
 	}
 
 	// Ensure lock is released regardless of panics or errors
@@ -159,28 +328,22 @@ func (s *HouseKeeper) RunDBHousekeeping(ctx context.Context, db repository.Datab
 
 	// If MaxAge is 0, this check is disabled.
 	if db.Housekeeping.MaxAge > 0 {
-		maxAgeDur := db.Housekeeping.MaxAge
-
 		// 1. Fetch the unified database time
 		dbTime, err := s.Repo.GetDBTime(ctx)
 		if err != nil {
 			s.Logger.Error("Housekeeper failed to get DB time for MaxAge cutoff", "error", err, "database", db.Name)
-			return totalDeleted, totalFreed, err // Or handle gracefully depending on your preference
+			return report, err // Or handle gracefully depending on your preference
 		}
 
-		// 2. Calculate cutoff using DB time and the MaxAge duration
-		cutoff := dbTime.Add(-maxAgeDur)
+		// 2. Calculate cutoff using DB time and the MaxAge duration, against the capture or ingestion time
+		cutoff, ageField := maxAgeCutoff(db.Housekeeping, dbTime)
 
 		for {
 			// We process in batches of 100 to prevent memory spikes.
-			entries, err := s.Repo.GetEntries(ctx, db.ID, repository.QueryOptions{
-				Limit:  100,
-				Offset: 0,
-				Order:  "asc",
-				TEnd:   cutoff,
-			})
+			entries, err := s.Repo.GetEntries(ctx, db.ID, maxAgeQuery(cutoff, ageField, 100, 0))
 			if err != nil {
 				s.Logger.Error("Housekeeper failed to fetch entries for MaxAge", "error", err, "database_id", db.ID, "database_name", db.Name)
+				s.notice(ctx, "max_age", db, "Housekeeping failed to fetch the entries older than max_age", err)
 				break
 			}
 
@@ -189,78 +352,156 @@ func (s *HouseKeeper) RunDBHousekeeping(ctx context.Context, db repository.Datab
 				break
 			}
 
-			delCount, freed, err := s.deleteEntriesBatch(ctx, db.ID, entries)
-			totalDeleted += delCount
-			totalFreed += freed
+			delCount, freed, skipped, err := s.deleteEntriesBatch(ctx, db.ID, entries)
+			report.EntriesDeleted += delCount
+			report.SpaceFreed += freed
+			report.EntriesSkipped += skipped
 
 			if err != nil {
 				s.Logger.Error("Housekeeper failed during MaxAge batch deletion", "error", err, "database_id", db.ID, "database_name", db.Name)
+				s.notice(ctx, "max_age", db, "Housekeeping failed to delete the entries older than max_age", err)
 				break
 			}
+			// Nothing could be deleted, the same batch would be fetched again
+			if delCount == 0 {
+				break
+			}
+		}
+
+		// Held entries are never fetched, they are only reported
+		held, err := s.Repo.CountHeldEntries(ctx, db.ID, ageField, cutoff)
+		if err != nil {
+			s.Logger.Error("Housekeeper failed to count the entries under legal hold", "error", err, "database_id", db.ID, "database_name", db.Name)
 		}
+		report.EntriesHeld = int(held)
 	}
 
 	// If DiskSpace is 0, this check is disabled.
 	if db.Housekeeping.DiskSpace > 0 {
 		// Calculate current space using the initial stats minus what we just freed
-		currentSpace := db.Stats.TotalDiskSpaceBytes - totalFreed
+		currentSpace := db.Stats.TotalDiskSpaceBytes - report.SpaceFreed
 		limit := db.Housekeeping.DiskSpace
 
 		for currentSpace > limit {
 			// Fetch the absolute oldest entries in the DB, regardless of age
-			entries, err := s.Repo.GetEntries(ctx, db.ID, repository.QueryOptions{
-				Limit:  100,
-				Offset: 0,
-				Order:  "asc",
-			})
+			entries, err := s.Repo.GetEntries(ctx, db.ID, diskSpaceQuery(100, 0))
 			if err != nil || len(entries) == 0 {
 				break // Cannot fetch or no entries left
 			}
 
 			// Accumulate just enough entries to dip below the limit
-			var slideEnd int = 0
-			var targetSpaceToFree uint64
-
-			for i, e := range entries {
-				targetSpaceToFree += e.Size
-				slideEnd = i + 1
-
-				// Check if this entry pushes us under the limit
-				if currentSpace-targetSpaceToFree <= limit {
-					break
-				}
-			}
+			slideEnd, _ := takeUntilBelow(entries, currentSpace, limit)
 
-			delCount, freed, err := s.deleteEntriesBatch(ctx, db.ID, entries[:slideEnd])
-			totalDeleted += delCount
-			totalFreed += freed
+			delCount, freed, skipped, err := s.deleteEntriesBatch(ctx, db.ID, entries[:slideEnd])
+			report.EntriesDeleted += delCount
+			report.SpaceFreed += freed
+			report.EntriesSkipped += skipped
 			currentSpace -= freed // Update our running total to know when to stop
 
 			if err != nil {
 				s.Logger.Error("Housekeeper failed during DiskSpace batch deletion", "error", err, "database_id", db.ID, "database_name", db.Name)
+				s.notice(ctx, "disk_space", db, "Housekeeping failed to delete entries above the disk_space limit", err)
+				break
+			}
+			if delCount == 0 {
 				break
 			}
 		}
+
+		// Entries under legal hold may keep the database above its limit
+		if currentSpace > limit {
+			held, err := s.Repo.CountHeldEntries(ctx, db.ID, "", time.Time{})
+			if err != nil {
+				s.Logger.Error("Housekeeper failed to count the entries under legal hold", "error", err, "database_id", db.ID, "database_name", db.Name)
+			}
+			if held > 0 {
+				s.Logger.Warn("Database stays above its disk space limit, entries under legal hold are not deleted", "database_id", db.ID, "database_name", db.Name, "held", held)
+			}
+			report.EntriesHeld = max(report.EntriesHeld, int(held))
+		}
 	}
 
+	// Warn before the limit is reached again; the stats were read before the run, minus what it freed
+	usedBytes := db.Stats.TotalDiskSpaceBytes - min(report.SpaceFreed, db.Stats.TotalDiskSpaceBytes)
+	if err := s.CheckDiskSpaceAlert(ctx, db, usedBytes); err != nil {
+		s.Logger.Error("Housekeeper failed to check the disk space alert", "error", err, "database_id", db.ID, "database_name", db.Name)
+		s.notice(ctx, "disk_space_alert", db, "Failed to check the disk space alert", err)
+	}
+
+	// Return the pages of the deleted rows to the file system
+	report.PagesReclaimed = s.vacuumAfterRun(ctx, db, report.EntriesDeleted)
+
 	// Update LastHkRun utilizing the new atomic database method to prevent stat overwrites
 	_, err = s.Repo.HouseKeepingWasCalled(ctx, db.ID)
 	if err != nil {
 		s.Logger.Error("Housekeeper failed to update LastHkRun", "error", err, "database_id", db.ID, "database_name", db.Name)
 	}
 
-	s.Logger.Info("Housekeeping completed", "database_id", db.ID.String(), "database_name", db.Name, "deleted", totalDeleted, "freed_bytes", totalFreed)
-	return totalDeleted, totalFreed, nil
+	s.Logger.Info("Housekeeping completed", "database_id", db.ID.String(), "database_name", db.Name, "deleted", report.EntriesDeleted, "freed_bytes", report.SpaceFreed, "skipped", report.EntriesSkipped, "held", report.EntriesHeld, "pages_reclaimed", report.PagesReclaimed)
+	return report, nil
+}
+
+// maxAgeCutoff returns the time up to which entries are due under the MaxAge rule at now,
+// and the entry column compared with it, the capture or ingestion time.
+func maxAgeCutoff(hk repository.DatabaseHK, now time.Time) (time.Time, string) {
+	return now.Add(-hk.MaxAge), hk.AgeField()
+}
+
+// maxAgeQuery selects the oldest entries due under the MaxAge rule.
+// Entries that are still being processed or under legal hold are not considered at all.
+func maxAgeQuery(cutoff time.Time, ageField string, limit, offset int) repository.QueryOptions {
+	return repository.QueryOptions{
+		Limit:     limit,
+		Offset:    offset,
+		Order:     "asc",
+		SortBy:    ageField,
+		TimeField: ageField,
+		TEnd:      cutoff,
+		Statuses:  settledStatuses,
+
+		ExcludeLegalHold: true,
+	}
+}
+
+// diskSpaceQuery selects the oldest entries regardless of age, for the DiskSpace rule.
+func diskSpaceQuery(limit, offset int) repository.QueryOptions {
+	return repository.QueryOptions{
+		Limit:    limit,
+		Offset:   offset,
+		Order:    "asc",
+		Statuses: settledStatuses,
+
+		ExcludeLegalHold: true,
+	}
+}
+
+// takeUntilBelow returns how many of the entries have to be deleted, oldest first, to bring
+// currentSpace down to limit, and the space they free. All of them if that is not enough.
+func takeUntilBelow(entries []repository.Entry, currentSpace, limit uint64) (int, uint64) {
+	var n int
+	var freed uint64
+	for i, e := range entries {
+		freed += e.Size + e.PreviewSize + e.OriginalSize
+		n = i + 1
+
+		// Check if this entry pushes us under the limit
+		if currentSpace-min(freed, currentSpace) <= limit {
+			break
+		}
+	}
+	return n, freed
 }
 
 // deleteEntriesBatch safely deletes a batch of entries from the DB and storage using a 2-Phase approach.
+// Entries that were picked up by a worker since they were fetched are skipped.
 // returns
 // - number of files deleted
 // - disk space that was freed
+// - number of entries skipped
 // - error if any
-func (s *HouseKeeper) deleteEntriesBatch(ctx context.Context, dbID repository.ULID, entries []repository.Entry) (int, uint64, error) {
+func (s *HouseKeeper) deleteEntriesBatch(ctx context.Context, dbID repository.ULID, entries []repository.Entry) (int, uint64, int, error) {
 	if len(entries) == 0 {
-		return 0, 0, nil
+		return 0, 0, 0, nil
 	}
 
 	// 1. Extract IDs
@@ -270,13 +511,13 @@ func (s *HouseKeeper) deleteEntriesBatch(ctx context.Context, dbID repository.UL
 	}
 
 	// 2. Delete the files and entries
-	deletedMeta, err := shared.DeleteMultipleSafe(ctx, s.Repo, s.Storage, dbID, ids)
+	deletedMeta, skipped, err := shared.DeleteSettledSafe(ctx, s.Repo, s.Storage, dbID, ids)
 
 	// 3. Calculate disk space freed
 	var freed uint64 = 0
 	for _, e := range deletedMeta {
-		freed += e.Filesize + e.PreviewSize
+		freed += e.Filesize + e.PreviewSize + e.OriginalSize
 	}
 
-	return len(deletedMeta), freed, err
+	return len(deletedMeta), freed, len(skipped), err
 }
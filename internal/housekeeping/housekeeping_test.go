@@ -0,0 +1,267 @@
+package housekeeping
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestRunDBHousekeepingSkipsProcessingEntries(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "hk_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db.Housekeeping.MaxAge = time.Hour
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	hk := NewHouseKeeper(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+
+	// Two entries older than max_age, one of them still owned by a worker
+	old := time.Now().Add(-2 * time.Hour)
+	addEntry := func(status repo.EntryStatus) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "file.bin",
+			Size:      4,
+			Timestamp: old,
+			Status:    status,
+			MimeType:  "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		return entry
+	}
+	ready := addEntry(repo.EntryStatusReady)
+	processing := addEntry(repo.EntryStatusProcessing)
+
+	report, err := hk.RunDBHousekeeping(ctx, db)
+	if err != nil {
+		t.Fatalf("housekeeping failed: %v", err)
+	}
+	if report.EntriesDeleted != 1 || report.SpaceFreed != 4 {
+		t.Errorf("expected 1 deleted entry freeing 4 bytes, got %+v", report)
+	}
+
+	if _, err := r.GetEntry(ctx, db.ID, ready.ID); err == nil {
+		t.Errorf("expected the old ready entry to be deleted")
+	}
+	got, err := r.GetEntry(ctx, db.ID, processing.ID)
+	if err != nil {
+		t.Fatalf("expected the processing entry to survive, got %v", err)
+	}
+	if got.Status != repo.EntryStatusProcessing {
+		t.Errorf("expected the processing entry to keep its status, got %v", got.Status)
+	}
+
+	// An entry that is claimed by a worker after it was fetched is skipped and counted
+	deleted, _, skipped, err := hk.deleteEntriesBatch(ctx, db.ID, []repo.Entry{got})
+	if err != nil {
+		t.Fatalf("batch deletion failed: %v", err)
+	}
+	if deleted != 0 || skipped != 1 {
+		t.Errorf("expected the processing entry to be skipped, got %d deleted and %d skipped", deleted, skipped)
+	}
+}
+
+func TestRunDBHousekeepingSkipsHeldEntries(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "hk_hold_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	hk := NewHouseKeeper(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+
+	addEntry := func(timestamp time.Time) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "file.bin", Size: 4, Timestamp: timestamp, MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		return entry
+	}
+
+	// The two oldest of five old entries are held, they would be fetched first
+	var held []int64
+	for i := range 5 {
+		entry := addEntry(time.Now().Add(-time.Duration(10-i) * time.Hour))
+		if i < 2 {
+			held = append(held, entry.ID)
+		}
+	}
+	if updated, err := r.SetLegalHold(ctx, db.ID, held, true); err != nil || len(updated) != 2 {
+		t.Fatalf("failed to hold entries: %v (%v)", updated, err)
+	}
+	recent := addEntry(time.Now())
+
+	// 1. MaxAge deletes the other old entries and reports the held ones
+	db.Housekeeping.MaxAge = time.Hour
+	report, err := hk.RunDBHousekeeping(ctx, db)
+	if err != nil {
+		t.Fatalf("housekeeping failed: %v", err)
+	}
+	if report.EntriesDeleted != 3 || report.EntriesHeld != 2 {
+		t.Errorf("expected 3 deleted and 2 held entries, got %+v", report)
+	}
+
+	// 2. DiskSpace deletes everything but the held entries, which keep the database above the limit
+	if db, err = r.GetDatabase(ctx, db.ID); err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	db.Housekeeping.DiskSpace = 1
+	report, err = hk.RunDBHousekeeping(ctx, db)
+	if err != nil {
+		t.Fatalf("housekeeping failed: %v", err)
+	}
+	if report.EntriesDeleted != 1 || report.EntriesHeld != 2 {
+		t.Errorf("expected 1 deleted and 2 held entries, got %+v", report)
+	}
+	if _, err := r.GetEntry(ctx, db.ID, recent.ID); err == nil {
+		t.Errorf("expected the recent entry to be deleted for space")
+	}
+	for _, id := range held {
+		entry, err := r.GetEntry(ctx, db.ID, id)
+		if err != nil || !entry.LegalHold || entry.Status != repo.EntryStatusReady {
+			t.Errorf("expected held entry %d to survive unchanged, got %+v (%v)", id, entry, err)
+		}
+		if _, err := store.Stat(ctx, db.ID.String(), id); err != nil {
+			t.Errorf("expected the file of held entry %d to survive: %v", id, err)
+		}
+	}
+
+	// 3. A batch that contains a held entry skips it
+	entry, _ := r.GetEntry(ctx, db.ID, held[0])
+	deleted, _, skipped, err := hk.deleteEntriesBatch(ctx, db.ID, []repo.Entry{entry})
+	if err != nil || deleted != 0 || skipped != 1 {
+		t.Errorf("expected the held entry to be skipped, got %d deleted and %d skipped (%v)", deleted, skipped, err)
+	}
+}
+
+func TestRunDBHousekeepingAgeBasis(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "hk_basis", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	hk := NewHouseKeeper(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+
+	// A backfilled recording (old capture time, ingested now) and a recent capture ingested long ago
+	addEntry := func(timestamp time.Time, createdAt time.Time) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "file.bin", Timestamp: timestamp, MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := r.DB.ExecContext(ctx, `UPDATE "entries_`+db.ID.String()+`" SET created_at = ? WHERE id = ?`, createdAt.UnixMilli(), entry.ID); err != nil {
+			t.Fatalf("failed to set created_at: %v", err)
+		}
+		return entry
+	}
+	now := time.Now()
+	backfilled := addEntry(now.Add(-48*time.Hour), now)
+	lateIngested := addEntry(now, now.Add(-48*time.Hour))
+
+	run := func(basis string) HousekeepingReport {
+		t.Helper()
+		db.Housekeeping.MaxAge = 24 * time.Hour
+		db.Housekeeping.AgeBasis = basis
+		updated, err := r.UpdateDatabase(ctx, db)
+		if err != nil {
+			t.Fatalf("failed to update database: %v", err)
+		}
+		stored, err := r.GetDatabase(ctx, updated.ID)
+		if err != nil || stored.Housekeeping.AgeBasis != basis {
+			t.Fatalf("expected the age basis %q to be stored, got %q (err %v)", basis, stored.Housekeeping.AgeBasis, err)
+		}
+		report, err := hk.RunDBHousekeeping(ctx, stored)
+		if err != nil {
+			t.Fatalf("housekeeping failed: %v", err)
+		}
+		return report
+	}
+
+	// 1. Based on the ingestion time, only the entry stored long ago is deleted
+	if report := run(repo.AgeBasisIngestion); report.EntriesDeleted != 1 {
+		t.Errorf("expected 1 deleted entry, got %+v", report)
+	}
+	if _, err := r.GetEntry(ctx, db.ID, lateIngested.ID); err == nil {
+		t.Error("expected the entry ingested long ago to be deleted")
+	}
+	if _, err := r.GetEntry(ctx, db.ID, backfilled.ID); err != nil {
+		t.Errorf("expected the backfilled entry to survive, got %v", err)
+	}
+
+	// 2. Based on the capture time, the backfilled entry is due
+	if report := run(repo.AgeBasisCapture); report.EntriesDeleted != 1 {
+		t.Errorf("expected 1 deleted entry, got %+v", report)
+	}
+	if _, err := r.GetEntry(ctx, db.ID, backfilled.ID); err == nil {
+		t.Error("expected the backfilled entry to be deleted")
+	}
+}
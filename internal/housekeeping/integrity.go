@@ -0,0 +1,195 @@
+package housekeeping
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// IntegrityOptions configures the periodic verification of stored files. Only the main files are
+// verified, previews can be regenerated.
+type IntegrityOptions struct {
+	Enabled  bool
+	Interval time.Duration // time between two verification runs
+	Budget   int           // entries verified per database and run
+	MaxRate  uint64        // read limit in bytes per second, 0 is unlimited
+	Pause    time.Duration // sleep between two files
+}
+
+// IntegrityReport summarizes a verification run on a single database.
+type IntegrityReport struct {
+	Verified  int // files that matched their hash, or got their first hash
+	Corrupted int // files that no longer match their hash or are missing
+	Failed    int // files that could not be read, they are retried in the next run
+}
+
+// startIntegrityScheduler verifies a budget of entries per database every interval, the first run
+// starts one interval after startup.
+func (s *HouseKeeper) startIntegrityScheduler(ctx context.Context) {
+	ticker := time.NewTicker(s.integrity().Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.isPaused() {
+				continue
+			}
+			s.runIntegrityTasks(ctx)
+		}
+	}
+}
+
+// runIntegrityTasks runs VerifyDBIntegrity on all databases, one after the other.
+func (s *HouseKeeper) runIntegrityTasks(ctx context.Context) {
+	dbs, err := s.Repo.GetDatabases(ctx)
+	if err != nil {
+		s.Logger.Error("Integrity check failed to fetch databases", "error", err)
+		return
+	}
+
+	for _, db := range dbs {
+		if _, err := s.VerifyDBIntegrity(ctx, db); err != nil {
+			if errors.Is(err, customerrors.ErrLockNotAcquired) {
+				s.Logger.Debug("Skipping integrity check; locked by another instance", "database_id", db.ID, "database_name", db.Name)
+			} else {
+				s.Logger.Error("Integrity check failed", "database_id", db.ID, "database_name", db.Name, "error", err)
+				s.notice(ctx, "integrity", db, "Integrity check failed", err)
+			}
+		}
+	}
+}
+
+// VerifyDBIntegrity re-hashes the stored files of the least recently verified entries of a database.
+// Entries without a hash get their first one, entries whose file changed or is missing are set to
+// error with the reason "corrupted".
+func (s *HouseKeeper) VerifyDBIntegrity(ctx context.Context, db repository.Database) (IntegrityReport, error) {
+	var lockName = "integrity_" + db.ID.String()
+	var report IntegrityReport
+
+	acquired, err := s.Repo.AcquireLock(ctx, lockName, s.InstanceID, 6*time.Hour)
+	if err != nil {
+		return report, fmt.Errorf("failed to check lock status: %w", err)
+	}
+	if !acquired {
+		return report, customerrors.ErrLockNotAcquired
+	}
+	defer func() {
+		if err := s.Repo.ReleaseLock(ctx, lockName, s.InstanceID); err != nil {
+			s.Logger.Error("Failed to release lock after integrity check", "database", db.Name, "error", err)
+		}
+	}()
+
+	opts := s.integrity()
+	entries, err := s.Repo.GetEntriesToVerify(ctx, db.ID, opts.Budget)
+	if err != nil {
+		return report, fmt.Errorf("failed to fetch entries to verify: %w", err)
+	}
+
+	for i, entry := range entries {
+		if i > 0 && opts.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			case <-time.After(opts.Pause):
+			}
+		}
+
+		hash, err := s.hashStoredFile(ctx, db.ID, entry.ID)
+		missing := errors.Is(err, os.ErrNotExist) || errors.Is(err, customerrors.ErrNotFound)
+		if err != nil && !missing {
+			if ctx.Err() != nil {
+				return report, ctx.Err()
+			}
+			s.Logger.Warn("Failed to read file for integrity check", "database_id", db.ID, "entry", entry.ID, "error", err)
+			report.Failed++
+			continue
+		}
+
+		if !missing && (entry.ContentHash == "" || entry.ContentHash == hash) {
+			if err := s.Repo.RecordEntryVerification(ctx, db.ID, entry.ID, hash, ""); err != nil && !errors.Is(err, customerrors.ErrNotFound) {
+				s.Logger.Error("Failed to record integrity check", "database_id", db.ID, "entry", entry.ID, "error", err)
+			}
+			report.Verified++
+			continue
+		}
+
+		// The expected hash is kept, so the entry can be compared again once the file was restored
+		err = s.Repo.RecordEntryVerification(ctx, db.ID, entry.ID, entry.ContentHash, repository.ErrorReasonCorrupted)
+		if errors.Is(err, customerrors.ErrNotFound) {
+			continue // deleted or reprocessed while it was read
+		}
+		if err != nil {
+			s.Logger.Error("Failed to flag corrupted entry", "database_id", db.ID, "entry", entry.ID, "error", err)
+			continue
+		}
+		report.Corrupted++
+
+		s.Logger.Warn("Stored file is corrupted", "database_id", db.ID, "database_name", db.Name, "entry", entry.ID, "missing", missing)
+		if s.Auditor != nil {
+			s.Auditor.Log(ctx, "entry.corrupted", "housekeeping", fmt.Sprintf("%s:%d", db.ID, entry.ID), map[string]any{
+				"database_name": db.Name,
+				"expected_hash": entry.ContentHash,
+				"actual_hash":   hash,
+				"missing":       missing,
+				"reason":        repository.ErrorReasonCorrupted,
+			})
+		}
+	}
+
+	s.Logger.Info("Integrity check completed", "database_id", db.ID.String(), "database_name", db.Name, "verified", report.Verified, "corrupted", report.Corrupted, "failed", report.Failed)
+	return report, nil
+}
+
+// hashStoredFile returns the hex SHA-256 of the main file of an entry, read at most at the configured rate.
+func (s *HouseKeeper) hashStoredFile(ctx context.Context, dbID repository.ULID, entryID int64) (string, error) {
+	file, err := s.Storage.Read(ctx, dbID.String(), entryID, 0, -1)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, &throttledReader{ctx: ctx, r: file, rate: s.integrity().MaxRate, start: time.Now()}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// throttledReader limits the average read rate to rate bytes per second, so verification does not starve uploads.
+type throttledReader struct {
+	ctx   context.Context
+	r     io.Reader
+	rate  uint64 // 0 is unlimited
+	start time.Time
+	read  uint64
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if t.rate == 0 || n == 0 {
+		return n, err
+	}
+	t.read += uint64(n)
+
+	// Sleep until the bytes read so far fit the rate
+	due := time.Duration(float64(t.read) / float64(t.rate) * float64(time.Second))
+	if wait := due - time.Since(t.start); wait > 0 {
+		select {
+		case <-t.ctx.Done():
+			return n, t.ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return n, err
+}
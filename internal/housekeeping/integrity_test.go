@@ -0,0 +1,127 @@
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestVerifyDBIntegrityDetectsCorruption(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "integrity_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	root := t.TempDir()
+	store := &localstorage.LocalStorage{RootPath: root}
+	hk := NewHouseKeeper(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+	hk.Integrity = IntegrityOptions{Enabled: true, Budget: 10, MaxRate: 1 << 20}
+
+	addEntry := func(content string) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "file.bin",
+			Size:      uint64(len(content)),
+			Timestamp: time.Now(),
+			Status:    repo.EntryStatusReady,
+			MimeType:  "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader(content)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		return entry
+	}
+	intact := addEntry("intact data")
+	rotten := addEntry("rotten data")
+	missing := addEntry("missing data")
+
+	// 1. The first run records the hashes
+	report, err := hk.VerifyDBIntegrity(ctx, db)
+	if err != nil {
+		t.Fatalf("integrity check failed: %v", err)
+	}
+	if report.Verified != 3 || report.Corrupted != 0 {
+		t.Errorf("expected 3 verified entries, got %+v", report)
+	}
+	stats, err := r.GetIntegrityStats(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("failed to get integrity stats: %v", err)
+	}
+	if stats.Verified != 3 || stats.Unverified != 0 || stats.OldestVerification.IsZero() {
+		t.Errorf("unexpected stats after the first run: %+v", stats)
+	}
+
+	// 2. Flip a byte of one file on disk and remove another one
+	filePath := func(id int64) string {
+		return filepath.Join(root, db.ID.String(), fmt.Sprintf("%d", id/1000), fmt.Sprintf("%d", id))
+	}
+	if err := os.WriteFile(filePath(rotten.ID), []byte("rotten dat4"), 0o600); err != nil {
+		t.Fatalf("failed to corrupt file: %v", err)
+	}
+	if err := os.Remove(filePath(missing.ID)); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	report, err = hk.VerifyDBIntegrity(ctx, db)
+	if err != nil {
+		t.Fatalf("integrity check failed: %v", err)
+	}
+	if report.Verified != 1 || report.Corrupted != 2 {
+		t.Errorf("expected 1 verified and 2 corrupted entries, got %+v", report)
+	}
+
+	for _, id := range []int64{rotten.ID, missing.ID} {
+		got, err := r.GetEntry(ctx, db.ID, id)
+		if err != nil {
+			t.Fatalf("failed to get entry: %v", err)
+		}
+		if got.Status != repo.EntryStatusError || got.ErrorReason != repo.ErrorReasonCorrupted {
+			t.Errorf("expected entry %d to be flagged as corrupted, got status %d reason %q", id, got.Status, got.ErrorReason)
+		}
+	}
+	if got, _ := r.GetEntry(ctx, db.ID, intact.ID); got.Status != repo.EntryStatusReady {
+		t.Errorf("expected the intact entry to stay ready, got status %d", got.Status)
+	}
+
+	// 3. The summary counts the flagged entries
+	stats, err = r.GetIntegrityStats(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("failed to get integrity stats: %v", err)
+	}
+	if stats.Verified != 1 || stats.Unverified != 0 || stats.Corrupted != 2 {
+		t.Errorf("unexpected stats after the second run: %+v", stats)
+	}
+}
@@ -0,0 +1,232 @@
+package housekeeping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// deletionJobBatch is the number of entries whose rows a deletion job removes in one transaction.
+var deletionJobBatch = 500
+
+// jobRetention is how long finished jobs and their payload are kept for their status to be queried.
+const jobRetention = 7 * 24 * time.Hour
+
+// StartDeletionJob creates a job deleting entries of a database and runs it in the background.
+// The IDs are deleted in the given order, so the progress tells which ones are done.
+func (s *HouseKeeper) StartDeletionJob(ctx context.Context, dbID repository.ULID, ids []int64, username string) (repository.Job, error) {
+	payload, err := json.Marshal(ids)
+	if err != nil {
+		return repository.Job{}, fmt.Errorf("failed to encode entry IDs: %w", err)
+	}
+	job, err := s.Repo.CreateJob(ctx, repository.Job{
+		Type:       repository.JobTypeDeleteEntries,
+		DatabaseID: dbID,
+		Payload:    string(payload),
+		Total:      int64(len(ids)),
+		CreatedBy:  username,
+	})
+	if err != nil {
+		return job, err
+	}
+
+	// The job outlives the request but not the server, an interrupted job is resumed on the next start
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		if err := s.RunJob(s.jobContext(ctx), job); err != nil {
+			s.Logger.Error("Job failed", "database_id", dbID, "job", job.ID, "type", job.Type, "error", err)
+		}
+	}()
+	return job, nil
+}
+
+// jobContext returns the context of the scheduler, which the shutdown cancels, or a context without the
+// cancellation of ctx if the scheduler is not running.
+func (s *HouseKeeper) jobContext(ctx context.Context) context.Context {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.runCtx != nil {
+		return s.runCtx
+	}
+	return context.WithoutCancel(ctx)
+}
+
+// ResumeJobs continues the jobs that were running when the server stopped, one after the other.
+func (s *HouseKeeper) ResumeJobs(ctx context.Context) {
+	jobs, err := s.Repo.GetRunningJobs(ctx)
+	if err != nil {
+		s.Logger.Error("Failed to fetch interrupted jobs", "error", err)
+		return
+	}
+
+	for _, job := range jobs {
+		s.Logger.Info("Resuming job", "database_id", job.DatabaseID, "job", job.ID, "type", job.Type, "processed", job.Processed, "total", job.Total)
+		if err := s.RunJob(ctx, job); err != nil {
+			if errors.Is(err, customerrors.ErrLockNotAcquired) {
+				s.Logger.Debug("Skipping job; locked by another instance", "database_id", job.DatabaseID, "job", job.ID)
+			} else {
+				s.Logger.Error("Job failed", "database_id", job.DatabaseID, "job", job.ID, "type", job.Type, "error", err)
+			}
+		}
+	}
+}
+
+// RunJob runs a job from its stored progress to the end and records one audit event with the final
+// counts. If the context is cancelled, the job stays running, so it is resumed later. Other errors
+// fail the job.
+func (s *HouseKeeper) RunJob(ctx context.Context, job repository.Job) error {
+	var lockName = "job_" + job.ID.String()
+
+	acquired, err := s.Repo.AcquireLock(ctx, lockName, s.InstanceID, 6*time.Hour)
+	if err != nil {
+		return fmt.Errorf("failed to check lock status: %w", err)
+	}
+	if !acquired {
+		return customerrors.ErrLockNotAcquired
+	}
+	defer func() {
+		if err := s.Repo.ReleaseLock(context.WithoutCancel(ctx), lockName, s.InstanceID); err != nil {
+			s.Logger.Error("Failed to release lock after job", "job", job.ID, "error", err)
+		}
+	}()
+
+	switch job.Type {
+	case repository.JobTypeDeleteEntries:
+		err = s.runDeletionJob(ctx, &job)
+	default:
+		err = fmt.Errorf("unknown job type '%s'", job.Type)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		job.Status = repository.JobFailed
+		job.Error = err.Error()
+		job.FinishedAt = time.Now()
+		job.ExpiresAt = job.FinishedAt.Add(jobRetention)
+		if updateErr := s.Repo.UpdateJob(ctx, job); updateErr != nil && !errors.Is(updateErr, customerrors.ErrNotFound) {
+			s.Logger.Error("Failed to record failed job", "job", job.ID, "error", updateErr)
+		}
+		s.auditJob(ctx, job)
+		return err
+	}
+
+	job.Status = repository.JobDone
+	job.FinishedAt = time.Now()
+	job.ExpiresAt = job.FinishedAt.Add(jobRetention)
+	if err := s.Repo.UpdateJob(ctx, job); err != nil {
+		return fmt.Errorf("failed to record finished job: %w", err)
+	}
+	s.auditJob(ctx, job)
+	s.Logger.Info("Job completed", "database_id", job.DatabaseID, "job", job.ID, "type", job.Type, "processed", job.Processed, "failed", job.Failed)
+	return nil
+}
+
+// auditJob records the outcome of a finished job, e.g. "job.delete_entries", as its creator.
+func (s *HouseKeeper) auditJob(ctx context.Context, job repository.Job) {
+	if s.Auditor == nil {
+		return
+	}
+	details := map[string]any{
+		"job":       job.ID.String(),
+		"status":    job.Status,
+		"total":     job.Total,
+		"processed": job.Processed,
+		"failed":    job.Failed,
+	}
+	for name, value := range job.Counters {
+		details[name] = value
+	}
+	s.Auditor.Log(ctx, "job."+job.Type, job.CreatedBy, job.DatabaseID.String(), details)
+}
+
+// runDeletionJob deletes the remaining entry IDs of a job batch by batch: the rows of a batch in one
+// transaction, then their files. Like housekeeping, entries under legal hold and entries still queued
+// or processing are skipped and counted as failed. The progress of a batch is stored in the transaction
+// deleting its rows, along with the files to remove, so a resumed job neither repeats a batch nor
+// leaves its files behind.
+func (s *HouseKeeper) runDeletionJob(ctx context.Context, job *repository.Job) error {
+	var ids []int64
+	if err := json.Unmarshal([]byte(job.Payload), &ids); err != nil {
+		return fmt.Errorf("invalid payload of deletion job: %w", err)
+	}
+	if job.Counters == nil {
+		job.Counters = map[string]int64{}
+	}
+
+	// The files of the last batch before an interruption
+	if len(job.PendingFiles) > 0 {
+		if err := s.removeJobFiles(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	for job.Processed < int64(len(ids)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch := ids[job.Processed:min(job.Processed+int64(deletionJobBatch), int64(len(ids)))]
+
+		held, err := s.Repo.GetHeldEntries(ctx, job.DatabaseID, batch)
+		if err != nil {
+			return fmt.Errorf("failed to check legal holds: %w", err)
+		}
+		var next repository.Job
+		progress := func(deleted []repository.DeletedEntryMeta, skipped []int64) repository.Job {
+			next = *job
+			next.Counters = maps.Clone(job.Counters)
+			var heldSkipped int64
+			for _, id := range skipped {
+				if slices.Contains(held, id) {
+					heldSkipped++
+				}
+			}
+			for _, meta := range deleted {
+				next.Counters["bytes_freed"] += int64(meta.Filesize + meta.PreviewSize + meta.OriginalSize)
+			}
+			next.Counters["deleted"] += int64(len(deleted))
+			next.Counters["missing"] += int64(len(batch) - len(deleted) - len(skipped))
+			next.Counters["held"] += heldSkipped
+			next.Counters["busy"] += int64(len(skipped)) - heldSkipped
+			next.Processed += int64(len(batch))
+			next.Failed += int64(len(skipped))
+			next.PendingFiles = deleted
+			return next
+		}
+		if _, _, err := s.Repo.DeleteSettledEntries(ctx, job.DatabaseID, batch, progress); err != nil {
+			return fmt.Errorf("failed to delete entries: %w", err)
+		}
+		*job = next
+
+		if err := s.removeJobFiles(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeJobFiles removes the pending files of a deletion job and stores the job without them. The rows
+// are already gone, so the files are removed even if the job is being stopped.
+func (s *HouseKeeper) removeJobFiles(ctx context.Context, job *repository.Job) error {
+	finishCtx := context.WithoutCancel(ctx)
+	fileErrors := shared.DeleteEntryFiles(finishCtx, s.Storage, job.DatabaseID, job.PendingFiles)
+	for id, fileErr := range fileErrors {
+		s.Logger.Warn("Entry deleted but its files could not be removed", "database_id", job.DatabaseID, "job", job.ID, "entry", id, "error", fileErr)
+	}
+
+	job.Counters["file_errors"] += int64(len(fileErrors))
+	job.PendingFiles = nil
+	if err := s.Repo.UpdateJob(finishCtx, *job); err != nil {
+		return fmt.Errorf("failed to record job progress: %w", err)
+	}
+	return nil
+}
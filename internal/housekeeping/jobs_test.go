@@ -0,0 +1,308 @@
+package housekeeping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// stoppingStorage cancels the context of a job once its first file was deleted, like a shutdown would.
+type stoppingStorage struct {
+	storage.StorageProvider
+	cancel context.CancelFunc
+}
+
+func (s *stoppingStorage) Delete(ctx context.Context, dbID string, id int64) error {
+	s.cancel()
+	return s.StorageProvider.Delete(ctx, dbID, id)
+}
+
+// recordingAuditor keeps the actions of the audit events.
+type recordingAuditor struct {
+	actions []string
+	details []map[string]any
+}
+
+func (a *recordingAuditor) Log(_ context.Context, action string, _ string, _ string, details map[string]any) {
+	a.actions = append(a.actions, action)
+	a.details = append(a.details, details)
+}
+
+func TestDeletionJobResume(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	defer func(batch int) { deletionJobBatch = batch }(deletionJobBatch)
+	deletionJobBatch = 2
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "bulk", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+
+	// 5 ready entries of 10 bytes, one of them held, and one entry still being processed
+	var ids []int64
+	for i, status := range []repo.EntryStatus{repo.EntryStatusReady, repo.EntryStatusReady, repo.EntryStatusReady, repo.EntryStatusProcessing, repo.EntryStatusReady, repo.EntryStatusReady} {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "file.bin",
+			Size:      10,
+			Timestamp: time.Now(),
+			Status:    status,
+			MimeType:  "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader(strings.Repeat("x", 10))); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if i == 2 {
+			if _, err := r.SetLegalHold(ctx, db.ID, []int64{entry.ID}, true); err != nil {
+				t.Fatalf("failed to hold entry: %v", err)
+			}
+		}
+		ids = append(ids, entry.ID)
+	}
+	ids = append(ids, 9999) // not existing
+
+	payload, _ := json.Marshal(ids)
+	job, err := r.CreateJob(ctx, repo.Job{
+		Type:       repo.JobTypeDeleteEntries,
+		DatabaseID: db.ID,
+		Payload:    string(payload),
+		Total:      int64(len(ids)),
+		CreatedBy:  "alice",
+	})
+	if err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	// 1. The first server stops after the first batch, the job stays running
+	stopCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	auditor := &recordingAuditor{}
+	hk1 := NewHouseKeeper(r, &stoppingStorage{StorageProvider: store, cancel: cancel}, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+	hk1.Auditor = auditor
+	if err := hk1.RunJob(stopCtx, job); err == nil {
+		t.Fatal("expected the stopped job to return the context error")
+	}
+
+	stopped, err := r.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if stopped.Status != repo.JobRunning || stopped.Processed != 2 || stopped.Counters["deleted"] != 2 {
+		t.Errorf("expected a running job with the first batch deleted, got %+v", stopped)
+	}
+	if len(auditor.actions) != 0 {
+		t.Errorf("expected no audit event for the stopped job, got %v", auditor.actions)
+	}
+
+	// 2. The next server resumes it from the stored progress
+	hk2 := NewHouseKeeper(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+	hk2.Auditor = auditor
+	hk2.ResumeJobs(ctx)
+
+	done, err := r.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if done.Status != repo.JobDone || done.Processed != 7 || done.Failed != 2 || done.FinishedAt.IsZero() {
+		t.Errorf("expected a done job of 7 entries with 2 failed, got %+v", done)
+	}
+	for name, want := range map[string]int64{"deleted": 4, "held": 1, "busy": 1, "missing": 1, "file_errors": 0, "bytes_freed": 40} {
+		if got := done.Counters[name]; got != want {
+			t.Errorf("expected %s to be %d, got %d", name, want, got)
+		}
+	}
+	if len(auditor.actions) != 1 || auditor.actions[0] != "job.delete_entries" || auditor.details[0]["deleted"] != int64(4) {
+		t.Errorf("expected a single job.delete_entries audit event, got %v %v", auditor.actions, auditor.details)
+	}
+
+	// 3. The held and the processing entry remain, with their files
+	for _, i := range []int{2, 3} {
+		if _, err := r.GetEntry(ctx, db.ID, ids[i]); err != nil {
+			t.Errorf("expected entry %d to remain: %v", ids[i], err)
+		}
+		if _, err := store.Stat(ctx, db.ID.String(), ids[i]); err != nil {
+			t.Errorf("expected the file of entry %d to remain: %v", ids[i], err)
+		}
+	}
+	if _, err := store.Stat(ctx, db.ID.String(), ids[0]); err == nil {
+		t.Errorf("expected the file of entry %d to be deleted", ids[0])
+	}
+	if jobs, err := r.GetRunningJobs(ctx); err != nil || len(jobs) != 0 {
+		t.Errorf("expected no running jobs, got %v (%v)", jobs, err)
+	}
+}
+
+// crashingStorage keeps the files, and crashingRepo fails the job updates, like a crash right after the
+// rows of a batch were deleted.
+type crashingStorage struct {
+	storage.StorageProvider
+}
+
+func (s crashingStorage) Delete(ctx context.Context, dbID string, id int64) error {
+	return nil
+}
+
+type crashingRepo struct {
+	*sqlite.SQLiteRepository
+}
+
+func (r crashingRepo) UpdateJob(ctx context.Context, job repo.Job) error {
+	return errors.New("server crashed")
+}
+
+func TestDeletionJobPendingFiles(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "pending", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+
+	var ids []int64
+	for range 3 {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "file.bin", Size: 10, Timestamp: time.Now(), Status: repo.EntryStatusReady, MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader(strings.Repeat("x", 10))); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	payload, _ := json.Marshal(ids)
+	job, err := r.CreateJob(ctx, repo.Job{Type: repo.JobTypeDeleteEntries, DatabaseID: db.ID, Payload: string(payload), Total: int64(len(ids)), CreatedBy: "alice"})
+	if err != nil {
+		t.Fatalf("failed to create job: %v", err)
+	}
+
+	// 1. The progress is stored with the deleted rows, the files to remove with it
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	crashed := NewHouseKeeper(crashingRepo{r}, crashingStorage{store}, logger, time.Hour)
+	if err := crashed.runDeletionJob(ctx, &job); err == nil {
+		t.Fatal("expected the crash to fail the job")
+	}
+	stored, err := r.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if stored.Status != repo.JobRunning || stored.Processed != 3 || stored.Counters["deleted"] != 3 || len(stored.PendingFiles) != 3 {
+		t.Fatalf("expected the deleted batch with its pending files, got %+v", stored)
+	}
+
+	// 2. The resumed job removes the files and finishes
+	hk := NewHouseKeeper(r, store, logger, time.Hour)
+	hk.ResumeJobs(ctx)
+
+	done, err := r.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("failed to get job: %v", err)
+	}
+	if done.Status != repo.JobDone || len(done.PendingFiles) != 0 || done.Counters["deleted"] != 3 || done.Counters["missing"] != 0 || done.Counters["file_errors"] != 0 {
+		t.Errorf("expected a done job without pending files, got %+v", done)
+	}
+	for _, id := range ids {
+		if _, err := store.Stat(ctx, db.ID.String(), id); err == nil {
+			t.Errorf("expected the file of entry %d to be deleted", id)
+		}
+	}
+
+	// 3. The finished job expires after the retention, the sweep keeps it until then
+	if want := done.FinishedAt.Add(jobRetention); !done.ExpiresAt.Equal(want) {
+		t.Errorf("expected the job to expire at %v, got %v", want, done.ExpiresAt)
+	}
+	if report := hk.RunSweeps(ctx); report.Deleted["jobs"] != 0 {
+		t.Errorf("expected the finished job to be kept, got %v", report.Deleted)
+	}
+	done.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := r.UpdateJob(ctx, done); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+	if report := hk.RunSweeps(ctx); report.Deleted["jobs"] != 1 {
+		t.Errorf("expected the expired job to be deleted, got %v", report.Deleted)
+	}
+}
+
+func TestDeletionJobShutdown(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "shutdown", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// A started job is awaited by Wait
+	hk := NewHouseKeeper(r, &localstorage.LocalStorage{RootPath: t.TempDir()}, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+	job, err := hk.StartDeletionJob(ctx, db.ID, []int64{1, 2}, "alice")
+	if err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if !hk.Wait(waitCtx) {
+		t.Fatal("expected the job to finish")
+	}
+	if done, err := r.GetJob(ctx, job.ID); err != nil || done.Status != repo.JobDone || done.Counters["missing"] != 2 {
+		t.Errorf("expected a done job with 2 missing entries, got %+v (%v)", done, err)
+	}
+}
@@ -0,0 +1,37 @@
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+
+	"mediahub_oss/internal/notices"
+	"mediahub_oss/internal/repository"
+)
+
+// notice records a failed step of the background work on a database for the administrators. Repetitions of
+// the same step on the database are counted in one notice until it is acknowledged.
+func (s *HouseKeeper) notice(ctx context.Context, step string, db repository.Database, message string, err error) {
+	if s.Notices == nil {
+		return
+	}
+	s.Notices.Record(ctx, repository.ServerNotice{
+		DedupKey:   notices.Key(notices.SourceHousekeeping, step, db.ID.String()),
+		Source:     notices.SourceHousekeeping,
+		Severity:   repository.NoticeSeverityError,
+		Message:    fmt.Sprintf("%s (database '%s'): %v", message, db.Name, err),
+		DatabaseID: db.ID,
+	})
+}
+
+// pruneNotices deletes the notices whose latest repetition is older than the NoticeRetention.
+func (s *HouseKeeper) pruneNotices(ctx context.Context) {
+	if s.NoticeRetention <= 0 {
+		return
+	}
+	deleted, err := s.Repo.DeleteNotices(ctx, s.NoticeRetention)
+	if err != nil {
+		s.Logger.Error("Failed to clean up old server notices", "error", err)
+	} else if deleted > 0 {
+		s.Logger.Info("Cleaned up old server notices", "deleted_count", deleted)
+	}
+}
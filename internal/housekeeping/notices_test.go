@@ -0,0 +1,95 @@
+package housekeeping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/alerts"
+	"mediahub_oss/internal/notices"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+type failingNotifier struct{}
+
+func (failingNotifier) Notify(ctx context.Context, alert alerts.Alert) error {
+	return errors.New("webhook unreachable")
+}
+
+func TestFailuresRecordNotices(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "notice_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	hk := NewHouseKeeper(r, &localstorage.LocalStorage{RootPath: t.TempDir()}, logger, time.Hour)
+	hk.Notifier = failingNotifier{}
+	hk.Notices = notices.NewService(r, logger)
+	hk.ProcessingAgeAlert = 30 * time.Minute
+	hk.NoticeRetention = 24 * time.Hour
+
+	start := time.Now()
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: start, MimeType: "application/octet-stream", Status: repo.EntryStatusProcessing})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if _, err := r.DB.ExecContext(ctx, fmt.Sprintf(`UPDATE "entries_%s" SET created_at = ? WHERE id = ?`, db.ID), start.UnixMilli(), entry.ID); err != nil {
+		t.Fatalf("failed to set upload time: %v", err)
+	}
+
+	// 1. An alert that cannot be delivered is recorded as a notice of the database
+	if err := hk.CheckProcessingBacklog(ctx, start.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to check the backlog: %v", err)
+	}
+	list, err := r.GetNotices(ctx, repo.NoticeFilter{Unacknowledged: true})
+	if err != nil {
+		t.Fatalf("failed to get notices: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected 1 notice, got %+v", list)
+	}
+	notice := list[0]
+	if notice.Source != notices.SourceHousekeeping || notice.Severity != repo.NoticeSeverityError || notice.DatabaseID != db.ID || notice.Count != 1 {
+		t.Errorf("unexpected notice %+v", notice)
+	}
+	if !strings.Contains(notice.Message, "notice_test") || !strings.Contains(notice.Message, "webhook unreachable") {
+		t.Errorf("expected the database and the cause in the message, got %q", notice.Message)
+	}
+
+	// 2. The global run removes the notices older than the retention
+	if _, err := r.DB.ExecContext(ctx, "UPDATE server_notices SET last_seen = ?", start.Add(-48*time.Hour).UnixMilli()); err != nil {
+		t.Fatalf("failed to age the notice: %v", err)
+	}
+	hk.runGlobalTasks(ctx)
+	if count, err := r.CountUnacknowledgedNotices(ctx); err != nil || count != 0 {
+		t.Errorf("expected the old notice to be removed, got %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,120 @@
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mediahub_oss/internal/repository"
+)
+
+// Length of the retention forecast, if not requested otherwise, and its upper bound.
+const (
+	DefaultForecastWeeks = 12
+	MaxForecastWeeks     = 104
+)
+
+const week = 7 * 24 * time.Hour
+
+// RetentionSimulation projects what housekeeping would delete under a set of rules, see SimulateRetention.
+type RetentionSimulation struct {
+	Now    time.Time // database time the projection starts at
+	Cutoff time.Time // entries up to it are due under MaxAge, zero if the rule is disabled
+
+	MaxAgeEntries int64 // entries the MaxAge rule would delete now
+	MaxAgeBytes   uint64
+	MaxAgeHeld    int64 // due entries that are kept because they are under legal hold
+
+	DiskSpaceUsed    uint64 // usage according to the database stats
+	DiskSpaceEntries int64  // entries the DiskSpace rule would delete after the MaxAge rule, oldest first
+	DiskSpaceBytes   uint64
+	DiskSpaceAfter   uint64 // usage after both rules, still above the limit if held or unsettled entries keep it there
+
+	Forecast []ForecastWeek // what the MaxAge rule deletes in each of the following weeks
+}
+
+// ForecastWeek is what the MaxAge rule deletes of the existing entries in the week starting at Start.
+type ForecastWeek struct {
+	Start   time.Time
+	Entries int64
+	Bytes   uint64
+}
+
+// SimulateRetention projects the effect of the housekeeping rules on a database without deleting anything.
+// The rules do not have to be those of the database, so changes can be assessed before they are made.
+// The cutoff and the selection of entries are those of RunDBHousekeeping. The forecast only covers the
+// MaxAge rule applied to the existing entries, future uploads and the DiskSpace rule they trigger are unknown.
+func (s *HouseKeeper) SimulateRetention(ctx context.Context, db repository.Database, rules repository.DatabaseHK, weeks int) (RetentionSimulation, error) {
+	var sim RetentionSimulation
+
+	now, err := s.Repo.GetDBTime(ctx)
+	if err != nil {
+		return sim, fmt.Errorf("failed to get the database time: %w", err)
+	}
+	sim.Now = now
+	sim.DiskSpaceUsed = db.Stats.TotalDiskSpaceBytes
+	sim.Forecast = make([]ForecastWeek, weeks)
+	for i := range sim.Forecast {
+		sim.Forecast[i].Start = now.Add(time.Duration(i) * week)
+	}
+
+	// 1. The MaxAge rule, the entries due now and those becoming due in each week, in one query
+	var ageField string
+	if rules.MaxAge > 0 {
+		sim.Cutoff, ageField = maxAgeCutoff(rules, now)
+		buckets, err := s.Repo.GetRetentionForecast(ctx, db.ID, repository.RetentionRequest{
+			TimeField: ageField,
+			Cutoff:    sim.Cutoff,
+			Weeks:     weeks,
+			Statuses:  settledStatuses,
+		})
+		if err != nil {
+			return sim, fmt.Errorf("failed to forecast the max age rule: %w", err)
+		}
+		sim.MaxAgeEntries, sim.MaxAgeBytes = buckets[0].Count, buckets[0].Bytes
+		for i, b := range buckets[1:] {
+			sim.Forecast[i].Entries, sim.Forecast[i].Bytes = b.Count, b.Bytes
+		}
+
+		if sim.MaxAgeHeld, err = s.Repo.CountHeldEntries(ctx, db.ID, ageField, sim.Cutoff); err != nil {
+			return sim, fmt.Errorf("failed to count the entries under legal hold: %w", err)
+		}
+	}
+
+	// 2. The DiskSpace rule on what the MaxAge rule leaves, walking the oldest entries as a run would delete them
+	sim.DiskSpaceAfter = sim.DiskSpaceUsed - min(sim.MaxAgeBytes, sim.DiskSpaceUsed)
+	if limit := rules.DiskSpace; limit > 0 {
+		for offset := 0; sim.DiskSpaceAfter > limit; {
+			entries, err := s.Repo.GetEntries(ctx, db.ID, diskSpaceQuery(100, offset))
+			if err != nil {
+				return sim, fmt.Errorf("failed to fetch the oldest entries: %w", err)
+			}
+			if len(entries) == 0 {
+				break
+			}
+			offset += len(entries)
+
+			// Entries due under MaxAge are already counted
+			remaining := entries[:0]
+			for _, e := range entries {
+				if ageField == "" || ageTime(e, ageField).After(sim.Cutoff) {
+					remaining = append(remaining, e)
+				}
+			}
+			n, freed := takeUntilBelow(remaining, sim.DiskSpaceAfter, limit)
+			sim.DiskSpaceEntries += int64(n)
+			sim.DiskSpaceBytes += freed
+			sim.DiskSpaceAfter -= min(freed, sim.DiskSpaceAfter)
+		}
+	}
+
+	return sim, nil
+}
+
+// ageTime returns the time of an entry the MaxAge rule compares with, see repository.DatabaseHK.AgeField.
+func ageTime(e repository.Entry, ageField string) time.Time {
+	if ageField == "created_at" {
+		return e.CreatedAt
+	}
+	return e.Timestamp
+}
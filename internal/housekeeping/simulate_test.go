@@ -0,0 +1,134 @@
+package housekeeping
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestSimulateRetention(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "retention", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	hk := NewHouseKeeper(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+
+	// Entries spread over several weeks, with a max_age of 30 days
+	day := 24 * time.Hour
+	now := time.Now()
+	for _, e := range []struct {
+		age    time.Duration
+		size   int
+		status repo.EntryStatus
+		held   bool
+	}{
+		{40 * day, 10, repo.EntryStatusReady, false},     // due now
+		{31 * day, 20, repo.EntryStatusReady, true},      // due now, but held
+		{31 * day, 5, repo.EntryStatusProcessing, false}, // due now, but still being processed
+		{27 * day, 30, repo.EntryStatusReady, false},     // due in the 1st week
+		{20 * day, 40, repo.EntryStatusError, false},     // due in the 2nd week
+		{19 * day, 50, repo.EntryStatusReady, false},     // due in the 2nd week
+		{5 * day, 60, repo.EntryStatusReady, false},      // due in the 4th week
+		{1 * day, 70, repo.EntryStatusReady, false},      // due after the forecast
+	} {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "file.bin",
+			Size:      uint64(e.size),
+			Timestamp: now.Add(-e.age),
+			Status:    e.status,
+			MimeType:  "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader(strings.Repeat("x", e.size))); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if e.held {
+			if _, err := r.SetLegalHold(ctx, db.ID, []int64{entry.ID}, true); err != nil {
+				t.Fatalf("failed to hold entry: %v", err)
+			}
+		}
+	}
+	if db, err = r.GetDatabase(ctx, db.ID); err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	rules := repo.DatabaseHK{MaxAge: 30 * day, DiskSpace: 200}
+
+	// 1. The max_age rule now and in the following weeks
+	sim, err := hk.SimulateRetention(ctx, db, rules, 4)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if sim.MaxAgeEntries != 1 || sim.MaxAgeBytes != 10 || sim.MaxAgeHeld != 1 {
+		t.Errorf("expected 1 entry of 10 bytes due now and 1 held, got %+v", sim)
+	}
+	want := []ForecastWeek{{Entries: 1, Bytes: 30}, {Entries: 2, Bytes: 90}, {}, {Entries: 1, Bytes: 60}}
+	if len(sim.Forecast) != len(want) {
+		t.Fatalf("expected %d forecast weeks, got %+v", len(want), sim.Forecast)
+	}
+	for i, w := range want {
+		got := sim.Forecast[i]
+		if got.Entries != w.Entries || got.Bytes != w.Bytes {
+			t.Errorf("week %d: expected %d entries of %d bytes, got %+v", i+1, w.Entries, w.Bytes, got)
+		}
+		if start := sim.Now.Add(time.Duration(i) * week); !got.Start.Equal(start) {
+			t.Errorf("week %d: expected it to start at %v, got %v", i+1, start, got.Start)
+		}
+	}
+
+	// 2. The disk_space rule deletes the oldest remaining entries until the usage is down to 200 bytes
+	if sim.DiskSpaceUsed != 285 || sim.DiskSpaceEntries != 3 || sim.DiskSpaceBytes != 120 || sim.DiskSpaceAfter != 155 {
+		t.Errorf("expected 3 entries of 120 bytes deleted down to 155 of 285 bytes, got %+v", sim)
+	}
+
+	// 3. Measured from the ingestion, nothing is due yet
+	ingestion, err := hk.SimulateRetention(ctx, db, repo.DatabaseHK{MaxAge: 30 * day, AgeBasis: repo.AgeBasisIngestion}, 4)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if ingestion.MaxAgeEntries != 0 || ingestion.Forecast[3].Entries != 0 || ingestion.DiskSpaceAfter != 285 {
+		t.Errorf("expected nothing to be due by the ingestion time, got %+v", ingestion)
+	}
+
+	// 4. Nothing was deleted, and a run with the same rules deletes what was projected
+	if entries, _ := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 100}); len(entries) != 8 {
+		t.Errorf("expected the simulation to keep all 8 entries, got %d", len(entries))
+	}
+	db.Housekeeping = rules
+	report, err := hk.RunDBHousekeeping(ctx, db)
+	if err != nil {
+		t.Fatalf("housekeeping failed: %v", err)
+	}
+	if int64(report.EntriesDeleted) != sim.MaxAgeEntries+sim.DiskSpaceEntries || report.SpaceFreed != sim.MaxAgeBytes+sim.DiskSpaceBytes {
+		t.Errorf("expected the run to delete %d entries of %d bytes, got %+v", sim.MaxAgeEntries+sim.DiskSpaceEntries, sim.MaxAgeBytes+sim.DiskSpaceBytes, report)
+	}
+}
@@ -0,0 +1,167 @@
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"mediahub_oss/internal/notices"
+	"mediahub_oss/internal/repository"
+)
+
+// defaultSweepBatchSize is the number of expired rows a table sweep deletes per statement.
+const defaultSweepBatchSize = 500
+
+// Sweep removes the expired rows of a table on every global housekeeping run. A table sweep names the table and
+// its expiry column, a sweep with Func runs the callback instead, e.g. when files belong to the rows.
+type Sweep struct {
+	Name         string // used in the logs, the report and the notices, the table if empty
+	Table        string
+	ExpiryColumn string // Unix milliseconds, rows with a NULL expiry are kept
+	BatchSize    int    // rows deleted per statement, defaultSweepBatchSize if 0
+	Func         func(ctx context.Context) (int64, error)
+}
+
+// SweepReport summarizes a run of the registered sweeps.
+type SweepReport struct {
+	Deleted map[string]int64 // rows deleted per sweep that succeeded
+	Failed  map[string]error
+}
+
+// SweepStats counts the runs of a sweep since the start of the server, see HouseKeeper.SweepStats.
+type SweepStats struct {
+	Runs      int64
+	Failures  int64
+	Deleted   int64 // rows deleted by all runs
+	LastRun   time.Time
+	LastError string // of the latest run, empty if it succeeded
+}
+
+// Total returns the number of rows deleted by all sweeps.
+func (r SweepReport) Total() int64 {
+	var total int64
+	for _, n := range r.Deleted {
+		total += n
+	}
+	return total
+}
+
+// RegisterSweep adds a sweep to the global housekeeping runs, replacing a registered sweep of the same name.
+func (s *HouseKeeper) RegisterSweep(sweep Sweep) {
+	if sweep.Name == "" {
+		sweep.Name = sweep.Table
+	}
+	if sweep.BatchSize <= 0 {
+		sweep.BatchSize = defaultSweepBatchSize
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, registered := range s.sweeps {
+		if registered.Name == sweep.Name {
+			s.sweeps[i] = sweep
+			return
+		}
+	}
+	s.sweeps = append(s.sweeps, sweep)
+}
+
+// SweepStats returns the counters of the sweeps that ran since the start, by sweep name.
+func (s *HouseKeeper) SweepStats() map[string]SweepStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := make(map[string]SweepStats, len(s.sweepStats))
+	for name, st := range s.sweepStats {
+		stats[name] = st
+	}
+	return stats
+}
+
+// recordSweep adds the outcome of a sweep run to its counters.
+func (s *HouseKeeper) recordSweep(name string, deleted int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sweepStats == nil {
+		s.sweepStats = map[string]SweepStats{}
+	}
+	st := s.sweepStats[name]
+	st.Runs++
+	st.LastRun = time.Now()
+	st.LastError = ""
+	if err != nil {
+		st.Failures++
+		st.LastError = err.Error()
+	} else {
+		st.Deleted += deleted
+	}
+	s.sweepStats[name] = st
+}
+
+// Sweeps returns the registered sweeps in the order they run.
+func (s *HouseKeeper) Sweeps() []Sweep {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Sweep(nil), s.sweeps...)
+}
+
+// registerDefaultSweeps registers the tables of the server whose rows expire.
+func (s *HouseKeeper) registerDefaultSweeps() {
+	s.RegisterSweep(Sweep{Table: "refresh_tokens", ExpiryColumn: "expiry"})
+	s.RegisterSweep(Sweep{Table: "api_keys", ExpiryColumn: "expires_at"})
+	s.RegisterSweep(Sweep{Table: "share_links", ExpiryColumn: "expires_at"})
+	s.RegisterSweep(Sweep{Table: "upload_grants", ExpiryColumn: "expires_at"})
+	s.RegisterSweep(Sweep{Table: "delete_confirmations", ExpiryColumn: "expires_at"})
+	s.RegisterSweep(Sweep{Table: "idempotency_keys", ExpiryColumn: "expires_at"})
+	s.RegisterSweep(Sweep{Table: "jobs", ExpiryColumn: "expires_at"}) // finished jobs, see jobRetention
+	s.RegisterSweep(Sweep{Name: "retained_uploads", Func: s.sweepRetainedUploads})
+}
+
+// RunSweeps runs every registered sweep and adds the outcome to the SweepStats. A failing sweep is logged and
+// recorded as notice, the others still run.
+func (s *HouseKeeper) RunSweeps(ctx context.Context) SweepReport {
+	report := SweepReport{Deleted: map[string]int64{}, Failed: map[string]error{}}
+	for _, sweep := range s.Sweeps() {
+		var deleted int64
+		var err error
+		if sweep.Func != nil {
+			deleted, err = sweep.Func(ctx)
+		} else {
+			deleted, err = s.Repo.DeleteExpiredRows(ctx, sweep.Table, sweep.ExpiryColumn, sweep.BatchSize)
+		}
+		s.recordSweep(sweep.Name, deleted, err)
+
+		if err != nil {
+			report.Failed[sweep.Name] = err
+			s.Logger.Error("Failed to clean up expired rows", "sweep", sweep.Name, "deleted_count", deleted, "error", err)
+			if s.Notices != nil {
+				s.Notices.Record(ctx, repository.ServerNotice{
+					DedupKey: notices.Key(notices.SourceHousekeeping, "sweep", sweep.Name),
+					Source:   notices.SourceHousekeeping,
+					Severity: repository.NoticeSeverityError,
+					Message:  fmt.Sprintf("Failed to clean up the expired rows of %s: %v", sweep.Name, err),
+				})
+			}
+			continue
+		}
+		report.Deleted[sweep.Name] = deleted
+		if deleted > 0 {
+			s.Logger.Info("Cleaned up expired rows", "sweep", sweep.Name, "deleted_count", deleted)
+		}
+	}
+	return report
+}
+
+// sweepRetainedUploads deletes the sources of failed uploads whose retry grace period has passed, with their files.
+func (s *HouseKeeper) sweepRetainedUploads(ctx context.Context) (int64, error) {
+	expiredUploads, err := s.Repo.DeleteExpiredRetainedUploads(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, u := range expiredUploads {
+		if err := os.Remove(u.Path); err != nil && !os.IsNotExist(err) {
+			s.Logger.Warn("Failed to delete retained upload", "database_id", u.DatabaseID, "entry", u.EntryID, "path", u.Path, "error", err)
+		}
+	}
+	return int64(len(expiredUploads)), nil
+}
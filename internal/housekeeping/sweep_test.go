@@ -0,0 +1,128 @@
+package housekeeping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/notices"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestExpiredRowSweeps(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// 1. The tables of the server are registered on construction
+	var names []string
+	for _, sweep := range NewHouseKeeper(r, &localstorage.LocalStorage{RootPath: t.TempDir()}, logger, time.Hour).Sweeps() {
+		names = append(names, sweep.Name)
+	}
+	want := []string{"refresh_tokens", "api_keys", "share_links", "upload_grants", "delete_confirmations", "idempotency_keys", "jobs", "retained_uploads"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected the sweeps %v, got %v", want, names)
+	}
+
+	// 2. Expired and live rows in two tables
+	user, err := r.CreateUser(ctx, repo.User{Username: "sweeper", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	for i, valid := range []time.Duration{-time.Hour, -time.Minute, -time.Second, time.Hour, time.Hour} {
+		if err := r.StoreRefreshToken(ctx, user.ID, fmt.Sprintf("token_%d", i), valid); err != nil {
+			t.Fatalf("failed to store refresh token: %v", err)
+		}
+	}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "sweep_test", ContentType: "image"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for i, valid := range []time.Duration{-time.Hour, -time.Minute, time.Hour} {
+		link := repo.ShareLink{TokenHash: fmt.Sprintf("link_%d", i), DatabaseID: db.ID, EntryID: 1, CreatedBy: "sweeper", ExpiresAt: time.Now().Add(valid)}
+		if _, err := r.CreateShareLink(ctx, link); err != nil {
+			t.Fatalf("failed to create share link: %v", err)
+		}
+	}
+
+	// 3. A failing sweep registered first does not stop the others, the small batch size needs several statements
+	hk := &HouseKeeper{Repo: r, Logger: logger, Notices: notices.NewService(r, logger)}
+	hk.RegisterSweep(Sweep{Name: "broken", Func: func(ctx context.Context) (int64, error) { return 0, errors.New("table is locked") }})
+	hk.RegisterSweep(Sweep{Table: "refresh_tokens", ExpiryColumn: "expiry", BatchSize: 2})
+	hk.RegisterSweep(Sweep{Table: "share_links", ExpiryColumn: "expires_at"})
+
+	report := hk.RunSweeps(ctx)
+	if report.Deleted["refresh_tokens"] != 3 || report.Deleted["share_links"] != 2 || report.Total() != 5 {
+		t.Errorf("expected 3 refresh tokens and 2 share links to be deleted, got %v", report.Deleted)
+	}
+	if _, failed := report.Failed["broken"]; !failed || len(report.Failed) != 1 {
+		t.Errorf("expected only the broken sweep to fail, got %v", report.Failed)
+	}
+
+	stats := hk.SweepStats()
+	if st := stats["refresh_tokens"]; st.Runs != 1 || st.Deleted != 3 || st.Failures != 0 || st.LastRun.IsZero() {
+		t.Errorf("unexpected refresh_tokens stats: %+v", st)
+	}
+	if st := stats["broken"]; st.Runs != 1 || st.Failures != 1 || st.LastError != "table is locked" {
+		t.Errorf("unexpected stats of the broken sweep: %+v", st)
+	}
+
+	count := func(table string) int {
+		t.Helper()
+		var n int
+		if err := r.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		return n
+	}
+	if n := count("refresh_tokens"); n != 2 {
+		t.Errorf("expected the 2 live refresh tokens to remain, got %d", n)
+	}
+	if n := count("share_links"); n != 1 {
+		t.Errorf("expected the live share link to remain, got %d", n)
+	}
+
+	open, err := r.GetNotices(ctx, repo.NoticeFilter{Unacknowledged: true})
+	if err != nil || len(open) != 1 || open[0].DedupKey != "housekeeping:sweep:broken" {
+		t.Errorf("expected a notice for the broken sweep, got %+v (%v)", open, err)
+	}
+
+	// 4. Registering a sweep again replaces it, a second run finds nothing to delete
+	hk.RegisterSweep(Sweep{Name: "broken", Func: func(ctx context.Context) (int64, error) { return 0, nil }})
+	if n := len(hk.Sweeps()); n != 3 {
+		t.Errorf("expected 3 sweeps, got %d", n)
+	}
+	if report := hk.RunSweeps(ctx); report.Total() != 0 || len(report.Failed) != 0 {
+		t.Errorf("expected an empty second run, got %v and %v", report.Deleted, report.Failed)
+	}
+	if st := hk.SweepStats()["broken"]; st.Runs != 2 || st.Failures != 1 || st.LastError != "" {
+		t.Errorf("expected the successful run to clear the last error, got %+v", st)
+	}
+	if st := hk.SweepStats()["refresh_tokens"]; st.Runs != 2 || st.Deleted != 3 {
+		t.Errorf("expected the deleted rows to add up over the runs, got %+v", st)
+	}
+}
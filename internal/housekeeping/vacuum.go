@@ -0,0 +1,43 @@
+package housekeeping
+
+import (
+	"context"
+	"errors"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// vacuumAfterRun releases the free pages of the database file once a run deleted more entries than
+// the VacuumThreshold. Deleted rows only put their pages on the freelist, the file keeps its size
+// until they are vacuumed. Returns the number of released pages.
+func (s *HouseKeeper) vacuumAfterRun(ctx context.Context, db repository.Database, deleted int) int64 {
+	threshold := s.vacuumThreshold()
+	if threshold <= 0 || deleted <= threshold {
+		return 0
+	}
+
+	stats, err := s.Repo.GetFileStats(ctx)
+	if err != nil {
+		if !errors.Is(err, customerrors.ErrNotImplemented) {
+			s.Logger.Error("Housekeeper failed to read the database file stats", "error", err, "database_id", db.ID, "database_name", db.Name)
+		}
+		return 0
+	}
+	if stats.FreelistPages == 0 {
+		return 0
+	}
+	if stats.AutoVacuum != "incremental" {
+		s.Logger.Warn("The database file does not support incremental vacuuming, run 'mediahub db vacuum --full' once to convert it", "free_pages", stats.FreelistPages)
+		return 0
+	}
+
+	// Sized to the pages that are free now, pages freed meanwhile are left for the next run
+	released, err := s.Repo.IncrementalVacuum(ctx, int64(stats.FreelistPages))
+	if err != nil {
+		s.Logger.Error("Housekeeper failed to vacuum the database file", "error", err, "database_id", db.ID, "database_name", db.Name)
+		s.notice(ctx, "vacuum", db, "Failed to vacuum the database file after housekeeping", err)
+		return 0
+	}
+	return released
+}
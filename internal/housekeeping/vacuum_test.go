@@ -0,0 +1,105 @@
+package housekeeping
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestRunDBHousekeepingVacuumsAboveThreshold(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(filepath.Join(t.TempDir(), "mediahub.db"))
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "hk_vacuum",
+		ContentType:  "file",
+		CustomFields: []repo.CustomFieldDef{{Name: "payload", Type: "TEXT"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db.Housekeeping.MaxAge = time.Hour
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	hk := NewHouseKeeper(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+	hk.VacuumThreshold = 100
+
+	addEntries := func(n int) {
+		t.Helper()
+		for range n {
+			entry, err := r.CreateEntry(ctx, db, repo.Entry{
+				FileName:     "file.bin",
+				Size:         4,
+				Timestamp:    time.Now().Add(-2 * time.Hour),
+				Status:       repo.EntryStatusReady,
+				MimeType:     "application/octet-stream",
+				CustomFields: map[string]any{"payload": strings.Repeat("x", 4000)},
+			})
+			if err != nil {
+				t.Fatalf("failed to create entry: %v", err)
+			}
+			if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+	}
+
+	// 1. Below the threshold the freed pages stay on the freelist
+	addEntries(50)
+	report, err := hk.RunDBHousekeeping(ctx, db)
+	if err != nil {
+		t.Fatalf("housekeeping failed: %v", err)
+	}
+	if report.EntriesDeleted != 50 || report.PagesReclaimed != 0 {
+		t.Errorf("expected 50 deleted entries without vacuum, got %+v", report)
+	}
+	stats, err := r.GetFileStats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get file stats: %v", err)
+	}
+	if stats.FreelistPages == 0 {
+		t.Fatalf("expected free pages after deleting entries, got %+v", stats)
+	}
+
+	// 2. Above it the run releases them
+	addEntries(150)
+	report, err = hk.RunDBHousekeeping(ctx, db)
+	if err != nil {
+		t.Fatalf("housekeeping failed: %v", err)
+	}
+	if report.EntriesDeleted != 150 || report.PagesReclaimed < 150 {
+		t.Errorf("expected 150 deleted entries and their pages reclaimed, got %+v", report)
+	}
+	after, err := r.GetFileStats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get file stats: %v", err)
+	}
+	if after.FreelistPages != 0 {
+		t.Errorf("expected a drained freelist, got %+v", after)
+	}
+}
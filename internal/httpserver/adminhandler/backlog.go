@@ -0,0 +1,82 @@
+package adminhandler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+)
+
+// Sample sizes of the stuck entry IDs listed per database.
+const (
+	defaultBacklogSample = 20
+	maxBacklogSample     = 100
+)
+
+// @Summary Get the processing backlog
+// @Description Lists the databases with entries still in `processing`: their number, the age of the oldest one and a sample of the oldest entry IDs, the database waiting longest first.
+// @Description `min_age` only counts entries processing for at least that long, to find the stuck ones. `alert_active` is set once the oldest entry exceeded alerts.processing_age_alert and the alert was sent.
+// @Tags admin
+// @Produce json
+// @Param   min_age  query  string  false  "Only entries uploaded at least this long ago, e.g. 10m"
+// @Param   sample   query  int     false  "Entry IDs listed per database (default 20, max 100)"
+// @Success 200 {object} ProcessingBacklogResponse "The backlog"
+// @Failure 400 {object} utils.ErrorResponse "Invalid parameter formats"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/processing_backlog [get]
+func (h *AdminHandler) GetProcessingBacklog(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	filter := repository.BacklogFilter{SampleSize: defaultBacklogSample}
+	if s := r.URL.Query().Get("min_age"); s != "" {
+		minAge, err := shared.ParseDuration(s)
+		if err != nil || minAge < 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'min_age' parameter, expected a duration like 10m.")
+			return
+		}
+		if minAge > 0 {
+			filter.UploadedBefore = now.Add(-minAge)
+		}
+	}
+	if s := r.URL.Query().Get("sample"); s != "" {
+		sample, err := strconv.Atoi(s)
+		if err != nil || sample < 0 || sample > maxBacklogSample {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'sample' parameter, expected 0 to 100.")
+			return
+		}
+		filter.SampleSize = sample
+	}
+
+	backlog, err := h.Repo.GetProcessingBacklog(r.Context(), filter)
+	if err != nil {
+		h.Logger.Error("Failed to query the processing backlog", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to query the processing backlog")
+		return
+	}
+
+	resp := ProcessingBacklogResponse{
+		GeneratedAt:     now.UnixMilli(),
+		AlertAgeSeconds: int64(h.ProcessingAgeAlert.Seconds()),
+		Databases:       make([]DatabaseBacklogResponse, 0, len(backlog)),
+	}
+	for _, b := range backlog {
+		resp.Total += b.Count
+		resp.Databases = append(resp.Databases, DatabaseBacklogResponse{
+			DatabaseID:       b.DatabaseID.String(),
+			DatabaseName:     b.DatabaseName,
+			Processing:       b.Count,
+			OldestSince:      b.OldestSince.UnixMilli(),
+			OldestAgeSeconds: int64(now.Sub(b.OldestSince).Seconds()),
+			SampleIDs:        b.SampleIDs,
+			AlertActive:      b.AlertActive,
+		})
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,61 @@
+package adminhandler
+
+import (
+	"net/http"
+	"slices"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository/cache"
+)
+
+// cacheNamespaces are the namespaces accepted by FlushCache.
+var cacheNamespaces = []string{cache.NamespaceUsers, cache.NamespaceDatabases, cache.NamespaceEntries}
+
+// @Summary Get the repository cache statistics
+// @Description Reports the size limit of the in-memory repository cache and, per namespace (users, databases, entries), the number of cached items and the hits, misses and evictions since the start or the last flush.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} CacheStatsResponse "The cache statistics"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/cache [get]
+func (h *AdminHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
+	resp := CacheStatsResponse{Namespaces: map[string]cache.Stats{}}
+	if h.Cache != nil {
+		resp.MaxEntries = h.Cache.MaxEntries()
+		resp.Namespaces = h.Cache.Stats()
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// @Summary Flush the repository cache
+// @Description Removes the cached items of a namespace, or of all namespaces, and resets their counters. Use it after changing users, groups or databases directly in the database file.
+// @Tags admin
+// @Produce json
+// @Param   namespace  query  string  false  "users, databases or entries, all namespaces if omitted"
+// @Success 200 {object} CacheFlushResponse "The cache was flushed"
+// @Failure 400 {object} utils.ErrorResponse "Unknown namespace"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/cache/flush [post]
+func (h *AdminHandler) FlushCache(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserFromContext(r.Context())
+
+	namespace := r.URL.Query().Get("namespace")
+	if namespace != "" && !slices.Contains(cacheNamespaces, namespace) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'namespace' parameter, expected users, databases or entries.")
+		return
+	}
+
+	resp := CacheFlushResponse{Namespace: namespace}
+	if h.Cache != nil {
+		resp.Removed = h.Cache.Flush(namespace)
+	}
+
+	h.Auditor.Log(r.Context(), "admin.cache_flush", user.Username, "cache", map[string]any{"namespace": namespace, "removed": resp.Removed})
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
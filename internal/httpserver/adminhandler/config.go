@@ -0,0 +1,36 @@
+package adminhandler
+
+import (
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+)
+
+// @Summary Reload the configuration
+// @Description Re-reads the configuration file, like a SIGHUP, and applies the settings that can change at runtime:
+// @Description `logging.level`, `logging.audit.enabled` and `retention`, `server.max_sync_upload_size` and `max_json_file_size`,
+// @Description and the `budget`, `max_rate` and `pause` of `storage.integrity`. Other changed keys, e.g. the port, the storage root
+// @Description or the database, are reported as ignored and need a restart.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} ConfigReloadResponse "The configuration was reloaded"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Failure 500 {object} utils.ErrorResponse "The configuration file is invalid, the running configuration is kept"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/reload_config [post]
+func (h *AdminHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserFromContext(r.Context())
+
+	changed, ignored, err := h.ConfigReloader.ReloadConfig()
+	if err != nil {
+		h.Logger.Error("Failed to reload configuration", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to reload the configuration, the running one is kept: "+err.Error())
+		return
+	}
+
+	h.Auditor.Log(r.Context(), "admin.config_reload", user.Username, "config", map[string]any{"changed": changed, "ignored": ignored})
+
+	utils.RespondWithJSON(w, http.StatusOK, ConfigReloadResponse{Changed: changed, Ignored: ignored})
+}
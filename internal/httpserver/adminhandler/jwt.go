@@ -0,0 +1,46 @@
+package adminhandler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Rotate the JWT secret
+// @Description Replaces the secret that signs new JWTs with a random one. Tokens signed with the previous secret are accepted for `auth.jwt.rotation_grace`, so nobody is logged out.
+// @Description Other instances pick up the new secret within a minute, or as soon as they see a token signed with it.
+// @Description Only available with `auth.jwt.secret_source = "db"`; with the other sources, the secrets are rotated by changing the configuration or the secret file.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} JWTRotationResponse "The secret was rotated"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Failure 409 {object} utils.ErrorResponse "The secret is not stored in the database"
+// @Failure 500 {object} utils.ErrorResponse "Failed to rotate the secret"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/jwt/rotate [post]
+func (h *AdminHandler) RotateJWTSecret(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserFromContext(r.Context())
+
+	rotatedAt := time.Now()
+	if err := h.JWTKeys.Rotate(r.Context(), h.JWTRotationGrace); err != nil {
+		if errors.Is(err, customerrors.ErrNotImplemented) {
+			utils.RespondWithError(w, http.StatusConflict, "The JWT secret can only be rotated at runtime with secret_source \"db\".")
+			return
+		}
+		h.Logger.Error("Failed to rotate JWT secret", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to rotate the JWT secret.")
+		return
+	}
+
+	h.Auditor.Log(r.Context(), "admin.jwt_rotate", user.Username, "jwt", map[string]any{"grace": h.JWTRotationGrace.String()})
+
+	utils.RespondWithJSON(w, http.StatusOK, JWTRotationResponse{
+		RotatedAt:          rotatedAt.UnixMilli(),
+		PreviousValidUntil: rotatedAt.Add(h.JWTRotationGrace).UnixMilli(),
+	})
+}
@@ -0,0 +1,70 @@
+package adminhandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/shared"
+)
+
+// maxDrainTimeout bounds how long a request waits for the running workers.
+const maxDrainTimeout = 10 * time.Minute
+
+// @Summary Enable or disable the maintenance mode
+// @Description While enabled, write requests (entry uploads, updates and deletions, database and field changes, bulk operations)
+// @Description are rejected with 503, the message and a Retry-After header; reads keep working. No new background work is started
+// @Description and scheduled housekeeping is paused. When enabling, the request waits up to `drain_timeout` (at most 10m) for the
+// @Description running workers to finish and reports how many are still running. The mode survives a restart.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body MaintenanceRequest true "The new maintenance mode"
+// @Success 200 {object} MaintenanceResponse "The maintenance mode was changed"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request body or drain timeout"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Failure 500 {object} utils.ErrorResponse "Failed to store the maintenance mode"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/maintenance [post]
+func (h *AdminHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserFromContext(r.Context())
+
+	var req MaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var drainTimeout time.Duration
+	if req.DrainTimeout != "" {
+		timeout, err := shared.ParseDuration(req.DrainTimeout)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid drain_timeout: "+err.Error())
+			return
+		}
+		drainTimeout = min(timeout, maxDrainTimeout)
+	}
+
+	state, err := h.Maintenance.Set(r.Context(), req.Enabled, req.Message)
+	if err != nil {
+		h.Logger.Error("Failed to change maintenance mode", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change the maintenance mode")
+		return
+	}
+
+	h.Auditor.Log(r.Context(), "admin.maintenance", user.Username, "maintenance", map[string]any{"enabled": state.Enabled, "message": state.Message})
+
+	resp := MaintenanceResponse{Enabled: state.Enabled, Message: state.Message}
+	if state.Enabled {
+		resp.Since = state.Since.UnixMilli()
+		resp.RunningWorkers = h.Maintenance.Drain(r.Context(), drainTimeout)
+		if resp.RunningWorkers > 0 {
+			h.Logger.Warn("Workers still running after the drain timeout", "running", resp.RunningWorkers, "drain_timeout", drainTimeout)
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,61 @@
+package adminhandler
+
+import (
+	"net/http"
+	"strconv"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/media"
+)
+
+// @Summary Get the recent media operations
+// @Description Lists the most recent FFmpeg and FFprobe runs (conversions, previews, probes), newest first: the command line with file paths replaced by placeholders, the duration, the exit status and the last 4 KB of stderr.
+// @Description The log is held in memory and keeps the last media.operation_log_size operations; the stderr of failed operations is also stored with the entry as error_detail.
+// @Tags admin
+// @Produce json
+// @Param   database_name  query  string  false  "Only operations of this database"
+// @Param   entry_id       query  int     false  "Only operations of this entry"
+// @Success 200 {object} MediaLogResponse "The recorded operations"
+// @Failure 400 {object} utils.ErrorResponse "Invalid parameter formats"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/media_log [get]
+func (h *AdminHandler) GetMediaLog(w http.ResponseWriter, r *http.Request) {
+	var entryID int64
+	if idStr := r.URL.Query().Get("entry_id"); idStr != "" {
+		parsed, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || parsed <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'entry_id' parameter, expected a positive number.")
+			return
+		}
+		entryID = parsed
+	}
+
+	ops := h.MediaLog.Find(r.URL.Query().Get("database_name"), entryID)
+
+	resp := MediaLogResponse{
+		Capacity:   h.MediaLog.Capacity(),
+		Operations: make([]MediaOperationResponse, 0, len(ops)),
+	}
+	for _, op := range ops {
+		resp.Operations = append(resp.Operations, mapToMediaOperationResponse(op))
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+func mapToMediaOperationResponse(op media.Operation) MediaOperationResponse {
+	return MediaOperationResponse{
+		Kind:         op.Kind,
+		DatabaseID:   op.DatabaseID,
+		DatabaseName: op.DatabaseName,
+		EntryID:      op.EntryID,
+		Command:      op.Command,
+		StartedAt:    op.StartedAt.UnixMilli(),
+		DurationMs:   op.Duration.Milliseconds(),
+		ExitCode:     op.ExitCode,
+		Error:        op.Error,
+		StderrTail:   op.StderrTail,
+	}
+}
@@ -0,0 +1,239 @@
+package adminhandler
+
+import (
+	"log/slog"
+	"time"
+
+	"mediahub_oss/internal/housekeeping"
+	"mediahub_oss/internal/httpserver/auth"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/maintenance"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/cache"
+	"mediahub_oss/internal/storagereport"
+)
+
+type AdminHandler struct {
+	Logger             *slog.Logger
+	Auditor            audit.AuditLogger
+	Reporter           *storagereport.Reporter
+	JWTKeys            *auth.Keyring
+	JWTRotationGrace   time.Duration // how long tokens signed with the previous secret are accepted
+	ConfigReloader     ConfigReloader
+	Maintenance        *maintenance.Mode
+	MediaLog           *media.OperationLog // nil if the log is disabled
+	Cache              *cache.Cache        // the repository cache
+	Repo               repository.Repository
+	ProcessingAgeAlert time.Duration // reported with the processing backlog, 0 if the alert is disabled
+	HouseKeeper        *housekeeping.HouseKeeper
+}
+
+// ConfigReloader re-reads the configuration file and applies the settings that can change at runtime.
+type ConfigReloader interface {
+	// ReloadConfig returns the applied keys and the changed keys that need a restart.
+	ReloadConfig() (changed []string, ignored []string, err error)
+}
+
+// StorageReportResponse is the outbound storage usage report.
+type StorageReportResponse struct {
+	GeneratedAt    int64                      `json:"generated_at"`  // Unix milliseconds
+	Volume         *VolumeResponse            `json:"volume"`        // null if the storage backend has no volume
+	DatabaseFile   *DatabaseFileResponse      `json:"database_file"` // null if the repository does not report it
+	Databases      []DatabaseUsageResponse    `json:"databases"`
+	ContentTypes   []ContentTypeUsageResponse `json:"content_types"`
+	LargestEntries []LargeEntryResponse       `json:"largest_entries"`
+}
+
+type VolumeResponse struct {
+	TotalBytes uint64 `json:"total_bytes"`
+	FreeBytes  uint64 `json:"free_bytes"`
+}
+
+// DatabaseFileResponse is the space used by the metadata database file. Free pages are released by
+// housekeeping runs above database.vacuum_threshold and by 'mediahub db vacuum'.
+type DatabaseFileResponse struct {
+	SizeBytes     uint64 `json:"size_bytes"`
+	PageSize      uint64 `json:"page_size"`
+	FreelistPages uint64 `json:"freelist_pages"`
+	AutoVacuum    string `json:"auto_vacuum"` // "none", "full" or "incremental"
+}
+
+type DatabaseUsageResponse struct {
+	ID            string               `json:"id"`
+	Name          string               `json:"name"`
+	ContentType   string               `json:"content_type"`
+	EntryCount    uint64               `json:"entry_count"`
+	TotalBytes    uint64               `json:"total_bytes"`
+	OriginalBytes uint64               `json:"original_bytes"`
+	Previews      PreviewUsageResponse `json:"previews"`
+}
+
+type PreviewUsageResponse struct {
+	Files     uint64 `json:"files"`
+	Bytes     uint64 `json:"bytes"`
+	ScannedAt int64  `json:"scanned_at"` // Unix milliseconds, older than generated_at if cached
+	Error     string `json:"error,omitempty"`
+}
+
+type ContentTypeUsageResponse struct {
+	ContentType   string `json:"content_type"`
+	Databases     int    `json:"databases"`
+	EntryCount    uint64 `json:"entry_count"`
+	TotalBytes    uint64 `json:"total_bytes"`
+	OriginalBytes uint64 `json:"original_bytes"`
+	PreviewBytes  uint64 `json:"preview_bytes"`
+}
+
+type LargeEntryResponse struct {
+	DatabaseID   string `json:"database_id"`
+	DatabaseName string `json:"database_name"`
+	ID           int64  `json:"id"`
+	FileName     string `json:"filename"`
+	MimeType     string `json:"mime_type"`
+	Size         uint64 `json:"filesize"`
+	PreviewSize  uint64 `json:"preview_filesize"`
+}
+
+// JWTRotationResponse reports a rotation of the JWT secret.
+type JWTRotationResponse struct {
+	RotatedAt          int64 `json:"rotated_at"`           // Unix milliseconds
+	PreviousValidUntil int64 `json:"previous_valid_until"` // Unix milliseconds, tokens of the previous secret are accepted until then
+}
+
+// ConfigReloadResponse reports the configuration keys that changed since the last (re)load.
+type ConfigReloadResponse struct {
+	Changed []string `json:"changed"` // applied to the running server
+	Ignored []string `json:"ignored"` // not reloadable, e.g. the port or the storage root, they need a restart
+}
+
+// MaintenanceRequest enables or disables the maintenance mode.
+type MaintenanceRequest struct {
+	Enabled      bool   `json:"enabled"`
+	DrainTimeout string `json:"drain_timeout"` // e.g. "60s", how long to wait for running workers when enabling, empty for not waiting
+	Message      string `json:"message"`       // returned to the rejected write requests
+}
+
+// MaintenanceResponse reports the maintenance mode after a change.
+type MaintenanceResponse struct {
+	Enabled        bool   `json:"enabled"`
+	Message        string `json:"message,omitempty"`
+	Since          int64  `json:"since,omitempty"` // Unix milliseconds
+	RunningWorkers int    `json:"running_workers"` // background workers still running after the drain timeout
+}
+
+// MediaLogResponse lists the recent media operations, newest first.
+type MediaLogResponse struct {
+	Capacity   int                      `json:"capacity"` // number of operations the log keeps, 0 if it is disabled
+	Operations []MediaOperationResponse `json:"operations"`
+}
+
+// MediaOperationResponse is a single FFmpeg or FFprobe run.
+type MediaOperationResponse struct {
+	Kind         string `json:"kind"` // conversion, preview or probe
+	DatabaseID   string `json:"database_id,omitempty"`
+	DatabaseName string `json:"database_name,omitempty"`
+	EntryID      int64  `json:"entry_id,omitempty"`
+	Command      string `json:"command"`    // file paths and URLs are replaced by <input> and <output>
+	StartedAt    int64  `json:"started_at"` // Unix milliseconds
+	DurationMs   int64  `json:"duration_ms"`
+	ExitCode     int    `json:"exit_code"` // -1 if the process was killed or could not be started
+	Error        string `json:"error,omitempty"`
+	StderrTail   string `json:"stderr_tail"` // the last 4 KB of stderr
+}
+
+// CacheStatsResponse reports the repository cache per namespace.
+type CacheStatsResponse struct {
+	MaxEntries int                    `json:"max_entries"` // items kept per namespace
+	Namespaces map[string]cache.Stats `json:"namespaces"`  // counters since the start or the last flush
+}
+
+// CacheFlushResponse reports a flush of the repository cache.
+type CacheFlushResponse struct {
+	Namespace string `json:"namespace,omitempty"` // empty if all namespaces were flushed
+	Removed   int    `json:"removed"`
+}
+
+// UsageResponse is the number and size of the stored entries per uploader or per database.
+type UsageResponse struct {
+	GroupBy string                `json:"group_by"` // "user" or "database"
+	From    *int64                `json:"from"`     // Unix milliseconds, null if the range is open
+	To      *int64                `json:"to"`
+	Usage   []UsageRecordResponse `json:"usage"`
+}
+
+// UsageRecordResponse is the usage of an uploader (grouped by user) or of a database.
+type UsageRecordResponse struct {
+	Username     string `json:"username,omitempty"`
+	DatabaseID   string `json:"database_id,omitempty"`
+	DatabaseName string `json:"database_name,omitempty"`
+	Entries      int64  `json:"entries"`
+	Bytes        int64  `json:"bytes"`                 // files, previews and kept originals
+	QuotaBytes   uint64 `json:"quota_bytes,omitempty"` // the storage quota of the user, omitted if unlimited
+}
+
+// ProcessingBacklogResponse lists the databases with entries in processing, the longest waiting first.
+type ProcessingBacklogResponse struct {
+	GeneratedAt     int64                     `json:"generated_at"`      // Unix milliseconds
+	Total           int64                     `json:"total"`             // processing entries in all databases
+	AlertAgeSeconds int64                     `json:"alert_age_seconds"` // alerts.processing_age_alert, 0 if disabled
+	Databases       []DatabaseBacklogResponse `json:"databases"`
+}
+
+// DatabaseBacklogResponse is the processing backlog of a database.
+type DatabaseBacklogResponse struct {
+	DatabaseID       string  `json:"database_id"`
+	DatabaseName     string  `json:"database_name"`
+	Processing       int64   `json:"processing"`
+	OldestSince      int64   `json:"oldest_since"` // upload time of the oldest processing entry, Unix milliseconds
+	OldestAgeSeconds int64   `json:"oldest_age_seconds"`
+	SampleIDs        []int64 `json:"sample_ids"`   // the oldest processing entries
+	AlertActive      bool    `json:"alert_active"` // the processing alert was sent and the backlog has not cleared since
+}
+
+// NoticesResponse lists server notices, the latest first.
+type NoticesResponse struct {
+	Unacknowledged int64            `json:"unacknowledged"` // open notices in total, regardless of the limit
+	Notices        []NoticeResponse `json:"notices"`
+}
+
+// NoticeResponse is a failure of background work, counted once per repetition until it is acknowledged.
+type NoticeResponse struct {
+	ID             int64  `json:"id"`
+	Source         string `json:"source"`   // "housekeeping", "processing" or "startup"
+	Severity       string `json:"severity"` // "warning" or "error"
+	Message        string `json:"message"`
+	DatabaseID     string `json:"database_id,omitempty"`
+	EntryID        int64  `json:"entry_id,omitempty"`
+	Count          int64  `json:"count"`
+	FirstSeen      int64  `json:"first_seen"` // Unix milliseconds
+	LastSeen       int64  `json:"last_seen"`
+	AcknowledgedAt int64  `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string `json:"acknowledged_by,omitempty"`
+}
+
+// AcknowledgeNoticesRequest lists the notices to acknowledge, all open notices if empty.
+type AcknowledgeNoticesRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// AcknowledgeNoticesResponse is the number of notices that were acknowledged.
+type AcknowledgeNoticesResponse struct {
+	Acknowledged int64 `json:"acknowledged"`
+}
+
+// SweepsResponse lists the sweeps of expired rows in the order they run.
+type SweepsResponse struct {
+	Sweeps []SweepResponse `json:"sweeps"`
+}
+
+// SweepResponse counts the runs of a sweep since the start of the server.
+type SweepResponse struct {
+	Name      string `json:"name"`
+	Table     string `json:"table,omitempty"` // empty for sweeps that also remove files, e.g. retained_uploads
+	Runs      int64  `json:"runs"`
+	Failures  int64  `json:"failures"`
+	Deleted   int64  `json:"deleted"`              // rows deleted by all runs
+	LastRun   int64  `json:"last_run,omitempty"`   // Unix milliseconds, omitted before the first run
+	LastError string `json:"last_error,omitempty"` // of the latest run
+}
@@ -0,0 +1,119 @@
+package adminhandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+)
+
+// Number of notices listed by GetNotices.
+const (
+	defaultNoticesLimit = 100
+	maxNoticesLimit     = 1000
+)
+
+// @Summary List the server notices
+// @Description Lists the failures of background work (housekeeping steps, media processing, alert delivery, startup checks) that would otherwise only appear in the log, the latest first.
+// @Description A repeated failure is counted on its open notice instead of being listed again. `unacknowledged=true` only lists the notices not acknowledged yet.
+// @Tags admin
+// @Produce json
+// @Param   unacknowledged  query  bool  false  "Only list the notices not acknowledged yet"
+// @Param   limit           query  int   false  "Maximum number of notices (default 100, max 1000)"
+// @Success 200 {object} NoticesResponse "The notices"
+// @Failure 400 {object} utils.ErrorResponse "Invalid parameter formats"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/notices [get]
+func (h *AdminHandler) GetNotices(w http.ResponseWriter, r *http.Request) {
+	filter := repository.NoticeFilter{Limit: defaultNoticesLimit}
+	if s := r.URL.Query().Get("unacknowledged"); s != "" {
+		unacknowledged, err := strconv.ParseBool(s)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'unacknowledged' parameter, expected true or false.")
+			return
+		}
+		filter.Unacknowledged = unacknowledged
+	}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxNoticesLimit {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'limit' parameter, expected 1 to 1000.")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	notices, err := h.Repo.GetNotices(r.Context(), filter)
+	if err != nil {
+		h.Logger.Error("Failed to query server notices", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to query the server notices")
+		return
+	}
+	unacknowledged, err := h.Repo.CountUnacknowledgedNotices(r.Context())
+	if err != nil {
+		h.Logger.Error("Failed to count server notices", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to query the server notices")
+		return
+	}
+
+	resp := NoticesResponse{Unacknowledged: unacknowledged, Notices: make([]NoticeResponse, 0, len(notices))}
+	for _, n := range notices {
+		notice := NoticeResponse{
+			ID:             n.ID,
+			Source:         n.Source,
+			Severity:       n.Severity,
+			Message:        n.Message,
+			DatabaseID:     n.DatabaseID.String(),
+			EntryID:        n.EntryID,
+			Count:          n.Count,
+			FirstSeen:      n.FirstSeen.UnixMilli(),
+			LastSeen:       n.LastSeen.UnixMilli(),
+			AcknowledgedBy: n.AcknowledgedBy,
+		}
+		if !n.AcknowledgedAt.IsZero() {
+			notice.AcknowledgedAt = n.AcknowledgedAt.UnixMilli()
+		}
+		resp.Notices = append(resp.Notices, notice)
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// @Summary Acknowledge server notices
+// @Description Marks the listed notices, or all open notices if `ids` is empty, as acknowledged. A failure repeating after it was acknowledged opens a new notice.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body AcknowledgeNoticesRequest true "The notices to acknowledge"
+// @Success 200 {object} AcknowledgeNoticesResponse "The notices were acknowledged"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request body"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/notices/ack [post]
+func (h *AdminHandler) AcknowledgeNotices(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserFromContext(r.Context())
+
+	var req AcknowledgeNoticesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	acknowledged, err := h.Repo.AcknowledgeNotices(r.Context(), req.IDs, user.Username)
+	if err != nil {
+		h.Logger.Error("Failed to acknowledge server notices", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to acknowledge the server notices")
+		return
+	}
+
+	h.Auditor.Log(r.Context(), "admin.notices_acknowledge", user.Username, "notices", map[string]any{"ids": req.IDs, "acknowledged": acknowledged})
+	utils.RespondWithJSON(w, http.StatusOK, AcknowledgeNoticesResponse{Acknowledged: acknowledged})
+}
@@ -0,0 +1,126 @@
+package adminhandler
+
+import (
+	"net/http"
+	"strconv"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/storagereport"
+)
+
+const (
+	defaultReportTopN = 10
+	maxReportTopN     = 100
+)
+
+// @Summary Get the storage usage report
+// @Description Reports the disk usage per database (originals vs. previews), per content type, the largest entries across all databases, the capacity of the storage volume and the size and free pages of the metadata database file.
+// @Description Preview sizes are measured by walking the preview folders and cached for a while; use refresh=true to measure again.
+// @Tags admin
+// @Produce json
+// @Param   top      query  int   false  "Number of largest entries to return (default 10, max 100)"
+// @Param   refresh  query  bool  false  "Bypass the cached preview measurements"
+// @Success 200 {object} StorageReportResponse "The storage report"
+// @Failure 400 {object} utils.ErrorResponse "Invalid parameter formats"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Failure 500 {object} utils.ErrorResponse "Failed to generate the report"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/storage_report [get]
+func (h *AdminHandler) GetStorageReport(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserFromContext(r.Context())
+
+	// 1. Parse query parameters
+	topN := defaultReportTopN
+	if topStr := r.URL.Query().Get("top"); topStr != "" {
+		parsed, err := strconv.Atoi(topStr)
+		if err != nil || parsed < 0 || parsed > maxReportTopN {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'top' parameter, expected a number between 0 and 100.")
+			return
+		}
+		topN = parsed
+	}
+
+	refresh := false
+	if refreshStr := r.URL.Query().Get("refresh"); refreshStr != "" {
+		parsed, err := strconv.ParseBool(refreshStr)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid 'refresh' parameter, expected a boolean.")
+			return
+		}
+		refresh = parsed
+	}
+
+	// 2. Generate the report
+	report, err := h.Reporter.Generate(r.Context(), topN, refresh)
+	if err != nil {
+		h.Logger.Error("Failed to generate storage report", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to generate storage report.")
+		return
+	}
+
+	h.Auditor.Log(r.Context(), "admin.storage_report", user.Username, "storage", map[string]any{"refresh": refresh})
+
+	utils.RespondWithJSON(w, http.StatusOK, mapToStorageReportResponse(report))
+}
+
+func mapToStorageReportResponse(report storagereport.Report) StorageReportResponse {
+	resp := StorageReportResponse{
+		GeneratedAt:    report.GeneratedAt.UnixMilli(),
+		Databases:      make([]DatabaseUsageResponse, 0, len(report.Databases)),
+		ContentTypes:   make([]ContentTypeUsageResponse, 0, len(report.ContentTypes)),
+		LargestEntries: make([]LargeEntryResponse, 0, len(report.LargestEntries)),
+	}
+
+	if report.Volume != nil {
+		resp.Volume = &VolumeResponse{
+			TotalBytes: report.Volume.TotalBytes,
+			FreeBytes:  report.Volume.FreeBytes,
+		}
+	}
+
+	if report.DatabaseFile != nil {
+		resp.DatabaseFile = &DatabaseFileResponse{
+			SizeBytes:     report.DatabaseFile.SizeBytes,
+			PageSize:      report.DatabaseFile.PageSize,
+			FreelistPages: report.DatabaseFile.FreelistPages,
+			AutoVacuum:    report.DatabaseFile.AutoVacuum,
+		}
+	}
+
+	for _, db := range report.Databases {
+		resp.Databases = append(resp.Databases, DatabaseUsageResponse{
+			ID:            db.DatabaseID.String(),
+			Name:          db.Name,
+			ContentType:   db.ContentType,
+			EntryCount:    db.EntryCount,
+			TotalBytes:    db.TotalBytes,
+			OriginalBytes: db.OriginalBytes,
+			Previews: PreviewUsageResponse{
+				Files:     db.Previews.Files,
+				Bytes:     db.Previews.Bytes,
+				ScannedAt: db.Previews.ScannedAt.UnixMilli(),
+				Error:     db.Previews.Error,
+			},
+		})
+	}
+
+	for _, ct := range report.ContentTypes {
+		resp.ContentTypes = append(resp.ContentTypes, ContentTypeUsageResponse(ct))
+	}
+
+	for _, e := range report.LargestEntries {
+		resp.LargestEntries = append(resp.LargestEntries, LargeEntryResponse{
+			DatabaseID:   e.DatabaseID.String(),
+			DatabaseName: e.DatabaseName,
+			ID:           e.ID,
+			FileName:     e.FileName,
+			MimeType:     e.MimeType,
+			Size:         e.Size,
+			PreviewSize:  e.PreviewSize,
+		})
+	}
+
+	return resp
+}
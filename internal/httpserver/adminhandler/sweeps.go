@@ -0,0 +1,43 @@
+package adminhandler
+
+import (
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+)
+
+// @Summary Get the expired row sweeps
+// @Description Lists the sweeps housekeeping runs on the tables with expiring rows (refresh tokens, API keys, share links, upload grants, ...) in the order they run, with the runs, failures and deleted rows since the start of the server and the outcome of the latest run.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} SweepsResponse "The sweeps"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/sweeps [get]
+func (h *AdminHandler) GetSweeps(w http.ResponseWriter, r *http.Request) {
+	resp := SweepsResponse{Sweeps: []SweepResponse{}}
+	if h.HouseKeeper == nil {
+		utils.RespondWithJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	stats := h.HouseKeeper.SweepStats()
+	for _, sweep := range h.HouseKeeper.Sweeps() {
+		st := stats[sweep.Name]
+		item := SweepResponse{
+			Name:      sweep.Name,
+			Table:     sweep.Table,
+			Runs:      st.Runs,
+			Failures:  st.Failures,
+			Deleted:   st.Deleted,
+			LastError: st.LastError,
+		}
+		if !st.LastRun.IsZero() {
+			item.LastRun = st.LastRun.UnixMilli()
+		}
+		resp.Sweeps = append(resp.Sweeps, item)
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
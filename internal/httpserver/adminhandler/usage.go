@@ -0,0 +1,124 @@
+package adminhandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Get the usage per user or database
+// @Description Counts the stored entries and their bytes (files, previews and kept originals) per uploader or per database, for the accounting of departments.
+// @Description `from` and `to` select the entries by the UTC day they were uploaded, deleted entries are no longer counted. Entries uploaded before the uploader was recorded are not attributed to a user.
+// @Description Grouped by user, the storage quota of the user is included.
+// @Tags admin
+// @Produce json
+// @Param   group_by  query  string  false  "user (default) or database"
+// @Param   from      query  string  false  "Uploaded on or after, Unix milliseconds or relative like now-30d"
+// @Param   to        query  string  false  "Uploaded before, Unix milliseconds or relative"
+// @Success 200 {object} UsageResponse "The usage"
+// @Failure 400 {object} utils.ErrorResponse "Invalid grouping or time"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires IsAdmin role)"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /admin/usage [get]
+func (h *AdminHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	now := time.Now()
+
+	filter := repository.UsageFilter{GroupBy: r.URL.Query().Get("group_by")}
+	if filter.GroupBy == "" {
+		filter.GroupBy = repository.UsageByUser
+	}
+	var err error
+	if filter.From, err = parseUsageTime(r, "from", now); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.To, err = parseUsageTime(r, "to", now); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	records, err := h.Repo.GetUserUsage(ctx, filter)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else {
+			h.Logger.Error("Failed to query the usage", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to query the usage")
+		}
+		return
+	}
+
+	resp := UsageResponse{GroupBy: filter.GroupBy, Usage: make([]UsageRecordResponse, 0, len(records))}
+	if !filter.From.IsZero() {
+		from := filter.From.UnixMilli()
+		resp.From = &from
+	}
+	if !filter.To.IsZero() {
+		to := filter.To.UnixMilli()
+		resp.To = &to
+	}
+
+	// The names of the databases and the quotas of the users complete the records
+	names := make(map[repository.ULID]string)
+	quotas := make(map[string]uint64)
+	if filter.GroupBy == repository.UsageByDatabase {
+		dbs, err := h.Repo.GetDatabases(ctx)
+		if err != nil {
+			h.Logger.Error("Failed to query the databases", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to query the databases")
+			return
+		}
+		for _, db := range dbs {
+			names[db.ID] = db.Name
+		}
+	} else {
+		users, err := h.Repo.GetUsers(ctx, nil)
+		if err != nil {
+			h.Logger.Error("Failed to query the users", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to query the users")
+			return
+		}
+		for _, user := range users {
+			quotas[user.Username] = user.StorageQuota
+		}
+	}
+
+	for _, record := range records {
+		resp.Usage = append(resp.Usage, UsageRecordResponse{
+			Username:     record.Username,
+			DatabaseID:   record.DatabaseID.String(),
+			DatabaseName: names[record.DatabaseID],
+			Entries:      record.Entries,
+			Bytes:        record.Bytes,
+			QuotaBytes:   quotas[record.Username],
+		})
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// parseUsageTime parses a time of the usage range, in Unix milliseconds or relative like "now-30d". It
+// returns the zero time if the parameter is missing.
+func parseUsageTime(r *http.Request, key string, now time.Time) (time.Time, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := repository.ParseRelativeTime(val, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"mediahub_oss/internal/repository"
+	"sync"
+	"time"
+)
+
+// ActivityTracker records the last login and activity of users without a database write per request.
+// Each user is written at most once per interval (logins and plain activity separately), and the writes
+// happen in a background worker, so authenticating a request never waits for the database.
+type ActivityTracker struct {
+	repo     repository.Repository
+	interval time.Duration
+	now      func() time.Time // replaced in tests
+	updates  chan activityUpdate
+
+	mu     sync.Mutex
+	queued map[activityKey]time.Time // when an update was last queued
+}
+
+type activityKey struct {
+	UserID repository.ULID
+	Login  bool
+}
+
+type activityUpdate struct {
+	activityKey
+	At time.Time
+}
+
+// NewActivityTracker creates an ActivityTracker and starts its background worker.
+func NewActivityTracker(repo repository.Repository, interval time.Duration) *ActivityTracker {
+	t := &ActivityTracker{
+		repo:     repo,
+		interval: interval,
+		now:      time.Now,
+		updates:  make(chan activityUpdate, 1000),
+		queued:   make(map[activityKey]time.Time),
+	}
+	go t.worker()
+	return t
+}
+
+// Touch records that the user made a request, which is a login if the request carried the password.
+// Requests within the interval after the last recorded one are skipped.
+func (t *ActivityTracker) Touch(userID repository.ULID, login bool) {
+	key := activityKey{UserID: userID, Login: login}
+	now := t.now()
+
+	t.mu.Lock()
+	if last, ok := t.queued[key]; ok && now.Sub(last) < t.interval {
+		t.mu.Unlock()
+		return
+	}
+	t.queued[key] = now
+	t.mu.Unlock()
+
+	select {
+	case t.updates <- activityUpdate{activityKey: key, At: now}:
+	default:
+		// Drop the update instead of blocking the request, the next one after the interval is recorded again
+		t.mu.Lock()
+		delete(t.queued, key)
+		t.mu.Unlock()
+		log.Printf("User activity channel full, dropping update for user %s", userID)
+	}
+}
+
+// worker writes the queued updates to the database.
+func (t *ActivityTracker) worker() {
+	for update := range t.updates {
+		var err error
+		if update.Login {
+			err = t.repo.RecordUserLogin(context.Background(), update.UserID, update.At)
+		} else {
+			err = t.repo.RecordUserActivity(context.Background(), update.UserID, update.At)
+		}
+		if err != nil {
+			log.Printf("Failed to record activity of user %s: %v", update.UserID, err)
+		}
+	}
+}
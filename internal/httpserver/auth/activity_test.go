@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/repository"
+)
+
+// recordingRepo passes the activity writes of the tracker to a channel.
+type recordingRepo struct {
+	repository.Repository
+	writes chan activityUpdate
+}
+
+func (r *recordingRepo) RecordUserLogin(ctx context.Context, id repository.ULID, at time.Time) error {
+	r.writes <- activityUpdate{activityKey: activityKey{UserID: id, Login: true}, At: at}
+	return nil
+}
+
+func (r *recordingRepo) RecordUserActivity(ctx context.Context, id repository.ULID, at time.Time) error {
+	r.writes <- activityUpdate{activityKey: activityKey{UserID: id}, At: at}
+	return nil
+}
+
+func TestActivityTrackerDebounce(t *testing.T) {
+	repo := &recordingRepo{writes: make(chan activityUpdate, 10)}
+	tracker := NewActivityTracker(repo, 5*time.Minute)
+	start := time.UnixMilli(1_700_000_000_000)
+	now := start
+	tracker.now = func() time.Time { return now }
+
+	tracker.Touch("alice", false)
+	now = start.Add(time.Minute)
+	tracker.Touch("alice", false) // within the interval
+	tracker.Touch("alice", true)  // logins are debounced separately
+	tracker.Touch("alice", true)
+	tracker.Touch("bob", false)
+	now = start.Add(5 * time.Minute)
+	tracker.Touch("alice", false) // the interval has passed
+
+	want := []activityUpdate{
+		{activityKey: activityKey{UserID: "alice"}, At: start},
+		{activityKey: activityKey{UserID: "alice", Login: true}, At: start.Add(time.Minute)},
+		{activityKey: activityKey{UserID: "bob"}, At: start.Add(time.Minute)},
+		{activityKey: activityKey{UserID: "alice"}, At: start.Add(5 * time.Minute)},
+	}
+	for i, w := range want {
+		select {
+		case got := <-repo.writes:
+			if got != w {
+				t.Errorf("write %d: expected %+v, got %+v", i, w, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("write %d: expected %+v, got nothing", i, w)
+		}
+	}
+	select {
+	case got := <-repo.writes:
+		t.Errorf("expected no further writes, got %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
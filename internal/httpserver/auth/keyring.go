@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minReloadInterval limits how often a token signed with an unknown secret triggers a reload.
+const minReloadInterval = 10 * time.Second
+
+// Keyring holds the secrets used to sign and validate JWTs. New tokens are signed with the newest secret,
+// tokens signed with any of the other secrets are still accepted. This allows rotating the secret
+// without invalidating the tokens that were issued shortly before.
+type Keyring struct {
+	repo repository.Repository // set if the secrets are stored in the database
+
+	mu         sync.RWMutex
+	secrets    [][]byte // newest first
+	lastReload time.Time
+}
+
+// NewKeyring returns a keyring with fixed secrets, the first one signs new tokens.
+// Empty secrets are ignored.
+func NewKeyring(secrets ...string) (*Keyring, error) {
+	k := &Keyring{}
+	for _, s := range secrets {
+		if s != "" {
+			k.secrets = append(k.secrets, []byte(s))
+		}
+	}
+	if len(k.secrets) == 0 {
+		return nil, fmt.Errorf("%w: no JWT secret configured", customerrors.ErrValidation)
+	}
+	return k, nil
+}
+
+// NewRepoKeyring returns a keyring whose secrets are stored in the database. If there is no current secret yet,
+// a random one is created; all instances starting concurrently end up with the same secret.
+func NewRepoKeyring(ctx context.Context, repo repository.Repository) (*Keyring, error) {
+	candidate, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repo.EnsureJWTSecret(ctx, candidate); err != nil {
+		return nil, fmt.Errorf("failed to initialize the jwt secret: %w", err)
+	}
+
+	k := &Keyring{repo: repo}
+	if err := k.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// GenerateSecret returns a random secret suitable for HS256.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate jwt secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SigningSecret returns the secret new tokens are signed with.
+func (k *Keyring) SigningSecret() []byte {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.secrets[0]
+}
+
+// Reload fetches the secrets from the database. It is a no-op for keyrings with fixed secrets.
+func (k *Keyring) Reload(ctx context.Context) error {
+	if k.repo == nil {
+		return nil
+	}
+	stored, err := k.repo.GetJWTSecrets(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load jwt secrets: %w", err)
+	}
+	if len(stored) == 0 {
+		return fmt.Errorf("%w: no jwt secret stored", customerrors.ErrNotFound)
+	}
+
+	secrets := make([][]byte, len(stored))
+	for i, s := range stored {
+		secrets[i] = []byte(s.Secret)
+	}
+
+	k.mu.Lock()
+	k.secrets = secrets
+	k.lastReload = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+// StartReloading periodically reloads database backed secrets, so a rotation done by another instance
+// is picked up. It blocks until the context is cancelled.
+func (k *Keyring) StartReloading(ctx context.Context, interval time.Duration, logger *slog.Logger) {
+	if k.repo == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := k.Reload(ctx); err != nil {
+				logger.Error("Failed to reload JWT secrets", "error", err)
+			}
+		}
+	}
+}
+
+// Rotate makes a new random secret the current one. The previous secrets are accepted for the grace period.
+// Only database backed keyrings can be rotated at runtime.
+func (k *Keyring) Rotate(ctx context.Context, grace time.Duration) error {
+	if k.repo == nil {
+		return fmt.Errorf("%w: rotating the jwt secret at runtime requires secret_source \"db\"", customerrors.ErrNotImplemented)
+	}
+	secret, err := GenerateSecret()
+	if err != nil {
+		return err
+	}
+	if err := k.repo.RotateJWTSecret(ctx, secret, grace); err != nil {
+		return fmt.Errorf("failed to rotate jwt secret: %w", err)
+	}
+	return k.Reload(ctx)
+}
+
+// Parse validates the signature of a token against all accepted secrets. If no secret matches and the
+// secrets are stored in the database, they are reloaded once, as the token may be signed with a secret
+// that another instance has just rotated in.
+func (k *Keyring) Parse(tokenString string) (*jwt.Token, error) {
+	token, err := k.parse(tokenString)
+	if err == nil || !errors.Is(err, jwt.ErrTokenSignatureInvalid) || k.repo == nil {
+		return token, err
+	}
+
+	k.mu.RLock()
+	recent := time.Since(k.lastReload) < minReloadInterval
+	k.mu.RUnlock()
+	if recent {
+		return token, err
+	}
+	if reloadErr := k.Reload(context.Background()); reloadErr != nil {
+		return token, err
+	}
+	return k.parse(tokenString)
+}
+
+func (k *Keyring) parse(tokenString string) (*jwt.Token, error) {
+	k.mu.RLock()
+	secrets := k.secrets
+	k.mu.RUnlock()
+
+	var token *jwt.Token
+	var err error
+	for _, secret := range secrets {
+		token, err = jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return secret, nil
+		})
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			return token, err
+		}
+	}
+	return token, err
+}
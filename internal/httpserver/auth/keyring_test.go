@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pressly/goose/v3"
+)
+
+func signToken(t *testing.T, secret []byte, userID repo.ULID) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": userID.String(),
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestJWTSecretRotation(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	user, err := r.CreateUser(ctx, repo.User{Username: "alice", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	status := func(am *AuthMiddleware, token string) int {
+		handler := am.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// 1. Configured secrets: the previous secret is still accepted, others are not
+	static, err := NewKeyring("new-secret", "old-secret")
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	am := NewAuthMiddleware(r, static)
+	if string(static.SigningSecret()) != "new-secret" {
+		t.Errorf("expected new tokens to be signed with the first secret, got %q", static.SigningSecret())
+	}
+	if code := status(am, signToken(t, []byte("old-secret"), user.ID)); code != http.StatusOK {
+		t.Errorf("expected a token of the previous secret to be accepted, got %d", code)
+	}
+	if code := status(am, signToken(t, []byte("other-secret"), user.ID)); code != http.StatusUnauthorized {
+		t.Errorf("expected a token of an unknown secret to be rejected, got %d", code)
+	}
+
+	// 2. Database secrets: two instances starting concurrently converge on the same secret
+	first, err := NewRepoKeyring(ctx, r)
+	if err != nil {
+		t.Fatalf("failed to create repo keyring: %v", err)
+	}
+	second, err := NewRepoKeyring(ctx, r)
+	if err != nil {
+		t.Fatalf("failed to create repo keyring: %v", err)
+	}
+	if string(first.SigningSecret()) != string(second.SigningSecret()) {
+		t.Fatal("expected both instances to share the secret")
+	}
+	oldToken := signToken(t, first.SigningSecret(), user.ID)
+
+	// 3. After a rotation by the first instance, tokens of both secrets are accepted by both
+	if err := first.Rotate(ctx, time.Hour); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	newToken := signToken(t, first.SigningSecret(), user.ID)
+	second.lastReload = time.Time{} // the second instance has not reloaded for a while
+	for name, k := range map[string]*Keyring{"rotating": first, "other": second} {
+		am := NewAuthMiddleware(r, k)
+		if code := status(am, oldToken); code != http.StatusOK {
+			t.Errorf("%s instance: expected the token of the previous secret to be accepted, got %d", name, code)
+		}
+		if code := status(am, newToken); code != http.StatusOK {
+			t.Errorf("%s instance: expected the token of the new secret to be accepted, got %d", name, code)
+		}
+	}
+	if string(second.SigningSecret()) != string(first.SigningSecret()) {
+		t.Error("expected the other instance to sign with the new secret after reloading")
+	}
+
+	// 4. Once the grace period has passed, the previous secret is rejected
+	if err := first.Rotate(ctx, -time.Second); err != nil {
+		t.Fatalf("failed to rotate: %v", err)
+	}
+	if code := status(NewAuthMiddleware(r, first), newToken); code != http.StatusUnauthorized {
+		t.Errorf("expected the token of a retired secret to be rejected, got %d", code)
+	}
+}
@@ -2,11 +2,15 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"mediahub_oss/internal/httpserver/utils"
 	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -18,8 +22,18 @@ import (
 // AuthMiddleware holds dependencies required for authentication/authorization.
 type AuthMiddleware struct {
 	Repo             repository.Repository
-	JWTSecret        []byte
+	Keys             *Keyring                 // secrets for validating JWTs
 	apiKeyUpdateChan chan APIKeyUpdateRequest // Buffered channel for debouncing and precision timing
+
+	// Basic credentials are rejected, only JWTs and API keys are accepted. Logging in then works through POST /api/token.
+	BasicAuthDisabled bool
+
+	// Records the last login and activity of the authenticated users, nil to record nothing
+	Activity *ActivityTracker
+
+	// Anonymous reads of public databases, see AllowAnonymous
+	AnonymousLimiter *IPRateLimiter // nil for unlimited, the limit of the running limiter can be changed
+	TrustedProxies   []netip.Prefix // proxies whose X-Forwarded-For header is honored for the client IP
 }
 
 // APIKeyUpdateRequest holds the exact timestamp the key was used for precise tracking.
@@ -29,10 +43,10 @@ type APIKeyUpdateRequest struct {
 }
 
 // NewAuthMiddleware creates a new AuthMiddleware service and starts background workers.
-func NewAuthMiddleware(repo repository.Repository, secret string) *AuthMiddleware {
+func NewAuthMiddleware(repo repository.Repository, keys *Keyring) *AuthMiddleware {
 	am := &AuthMiddleware{
 		Repo:             repo,
-		JWTSecret:        []byte(secret),
+		Keys:             keys,
 		apiKeyUpdateChan: make(chan APIKeyUpdateRequest, 5000), // Generous buffer
 	}
 
@@ -50,30 +64,106 @@ func (am *AuthMiddleware) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		schema, value, err := am.extractAuthCredentials(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			am.unauthorized(w, err.Error())
 			return
 		}
 
-		user, apiKey, err := am.authenticateRequest(schema, value)
-		if err != nil {
-			log.Printf("Auth failure: %v", err)
-			http.Error(w, "Unauthorized: Invalid credentials", http.StatusUnauthorized)
-			return
+		ctx, err := am.authenticate(r.Context(), schema, value)
+		switch {
+		case errors.Is(err, errBasicAuthDisabled):
+			am.unauthorized(w, "Unauthorized: Basic Auth is disabled, use a Bearer token from POST /api/token")
+		case errors.Is(err, customerrors.ErrPermissionDenied):
+			http.Error(w, "Forbidden: Account is disabled", http.StatusForbidden)
+		case err != nil:
+			am.unauthorized(w, "Unauthorized: Invalid credentials")
+		default:
+			next.ServeHTTP(w, r.WithContext(ctx))
 		}
+	})
+}
 
-		ctx := context.WithValue(r.Context(), utils.UserKey, &user)
+// Authenticate checks the value of an Authorization header like AuthMiddleware, for callers outside of
+// HTTP requests such as the gRPC API. It returns ctx with the user and the permission holder. Errors wrap
+// customerrors.ErrUnauthenticated for missing or invalid credentials and customerrors.ErrPermissionDenied
+// for disabled accounts.
+func (am *AuthMiddleware) Authenticate(ctx context.Context, authorization string) (context.Context, error) {
+	if authorization == "" {
+		return ctx, fmt.Errorf("%w: missing authorization", customerrors.ErrUnauthenticated)
+	}
+	schema, value, ok := strings.Cut(authorization, " ")
+	if !ok {
+		return ctx, fmt.Errorf("%w: invalid authorization format", customerrors.ErrUnauthenticated)
+	}
+	return am.authenticate(ctx, schema, value)
+}
+
+var errBasicAuthDisabled = fmt.Errorf("%w: Basic Auth is disabled", customerrors.ErrUnauthenticated)
+
+// authenticate validates the credentials, records the activity of the user and adds the user and the
+// permission holder to ctx.
+func (am *AuthMiddleware) authenticate(ctx context.Context, schema, value string) (context.Context, error) {
+	if schema == "Basic" && am.BasicAuthDisabled {
+		return ctx, errBasicAuthDisabled
+	}
+
+	user, apiKey, err := am.authenticateRequest(schema, value)
+	if err != nil {
+		log.Printf("Auth failure: %v", err)
+		return ctx, fmt.Errorf("%w: invalid credentials", customerrors.ErrUnauthenticated)
+	}
+	if user.Disabled {
+		return ctx, fmt.Errorf("%w: account is disabled", customerrors.ErrPermissionDenied)
+	}
+	if am.Activity != nil {
+		am.Activity.Touch(user.ID, schema == "Basic") // Basic credentials are a login on every request
+	}
 
-		isAPIKey := !apiKey.CreatedAt.IsZero()
-		if isAPIKey {
-			am.asyncUpdateAPIKeyLastUsed(apiKey.ID) // Now uses the worker channel
+	ctx = context.WithValue(ctx, utils.UserKey, &user)
+
+	isAPIKey := !apiKey.CreatedAt.IsZero()
+	if isAPIKey {
+		am.asyncUpdateAPIKeyLastUsed(apiKey.ID) // Now uses the worker channel
+	}
+
+	return am.cacheUserPermissions(ctx, user, apiKey, isAPIKey), nil
+}
+
+// AllowAnonymous authenticates requests with credentials like AuthMiddleware. Requests without any credentials
+// continue as anonymous caller (see utils.Anonymous), who may only read databases with public_read; the
+// permission middlewares and handlers check the flag. Anonymous requests are rate limited per client IP and
+// audit logged with the IP as actor.
+func (am *AuthMiddleware) AllowAnonymous(next http.Handler) http.Handler {
+	authenticated := am.AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.URL.Query().Get("token") != "" {
+			authenticated.ServeHTTP(w, r)
+			return
 		}
 
-		ctx = am.cacheUserPermissions(ctx, user, apiKey, isAPIKey)
+		clientIP := utils.ClientIP(r, am.TrustedProxies)
+		if am.AnonymousLimiter != nil {
+			if ok, retryAfter := am.AnonymousLimiter.Allow(clientIP); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())+1))
+				utils.RespondWithError(w, http.StatusTooManyRequests, "Too many requests without authentication, please try again later")
+				return
+			}
+		}
 
+		ctx := context.WithValue(r.Context(), utils.UserKey, &repository.User{Username: utils.AnonymousActor(clientIP)})
+		ctx = context.WithValue(ctx, utils.PermissionHolderKey, &utils.Anonymous{Repo: am.Repo})
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// unauthorized rejects a request with 401. Without Basic Auth, the challenge advertises Bearer tokens only;
+// otherwise no challenge is sent, so browsers do not show their login dialog.
+func (am *AuthMiddleware) unauthorized(w http.ResponseWriter, message string) {
+	if am.BasicAuthDisabled {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="mediahub"`)
+	}
+	http.Error(w, message, http.StatusUnauthorized)
+}
+
 // Extract either the Authorization header or the query parameter token. Returns the schema and value.
 func (am *AuthMiddleware) extractAuthCredentials(r *http.Request) (string, string, error) {
 	authHeader := r.Header.Get("Authorization")
@@ -126,7 +216,13 @@ func (am *AuthMiddleware) asyncUpdateAPIKeyLastUsed(keyID repository.ULID) {
 func (am *AuthMiddleware) cacheUserPermissions(ctx context.Context, user repository.User, apiKey repository.APIKey, isAPIKey bool) context.Context {
 	var holder utils.PermissionHolder
 
-	isEffectiveAdmin := user.IsAdmin
+	// Admin rights may also be granted through an admin group
+	isAdmin, err := repository.IsEffectiveAdmin(ctx, am.Repo, user)
+	if err != nil {
+		log.Printf("Failed to resolve group admin rights for user %s: %v", user.ID, err)
+	}
+
+	isEffectiveAdmin := isAdmin
 	if isAPIKey {
 		isEffectiveAdmin = isEffectiveAdmin && apiKey.Scope.HasAccess(repository.AccessAdmin)
 	}
@@ -140,7 +236,7 @@ func (am *AuthMiddleware) cacheUserPermissions(ctx context.Context, user reposit
 	}
 
 	if isAPIKey {
-		if user.IsAdmin {
+		if isAdmin {
 			holder = &utils.APIKeyOfAdmin{
 				UserULID: user.ID,
 				Scope:    apiKey.Scope,
@@ -194,6 +290,11 @@ func (am *AuthMiddleware) RequireDatabasePermission(perm repository.AccessGrant)
 
 			holder := utils.GetPermissionHolderFromContext(r.Context())
 			if !holder.HasPermission(repository.ULID(dbID), perm) {
+				// Anonymous callers are not told whether the database exists
+				if _, anonymous := holder.(*utils.Anonymous); anonymous {
+					http.Error(w, "Unauthorized: Missing Authorization header or query token", http.StatusUnauthorized)
+					return
+				}
 				http.Error(w, fmt.Sprintf("Forbidden: You lack required rights on database '%s'", dbID), http.StatusForbidden)
 				return
 			}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthMiddlewareDisabledUser(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user, err := r.CreateUser(ctx, repository.User{Username: "alice", PasswordHash: string(hash)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	am := NewAuthMiddleware(r, nil)
+	am.Activity = NewActivityTracker(r, time.Minute)
+	handler := am.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.SetBasicAuth("alice", "secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// 1. Basic credentials are a login, recorded in the background
+	if code := request(); code != http.StatusNoContent {
+		t.Fatalf("expected the request to pass, got %d", code)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, err := r.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("failed to get user: %v", err)
+		}
+		if !got.LastLoginAt.IsZero() && got.LastActivityAt.Equal(got.LastLoginAt) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the login to be recorded, got %+v", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 2. Disabled users are refused with 403
+	user.Disabled = true
+	if _, err := r.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("failed to disable user: %v", err)
+	}
+	if code := request(); code != http.StatusForbidden {
+		t.Errorf("expected 403 for the disabled user, got %d", code)
+	}
+}
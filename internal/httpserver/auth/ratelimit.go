@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// IPRateLimiter allows a number of requests per client IP and window. All counters are reset
+// together at the end of the window, so the memory is bounded by the clients of one window.
+// A limit of 0 allows all requests.
+type IPRateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+}
+
+func NewIPRateLimiter(limit int, window time.Duration) *IPRateLimiter {
+	return &IPRateLimiter{
+		limit:  limit,
+		window: window,
+		counts: make(map[string]int),
+	}
+}
+
+// SetLimit changes the allowed requests per window, e.g. on a configuration reload. It applies to the
+// current window, 0 allows all requests.
+func (l *IPRateLimiter) SetLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+}
+
+// Allow counts a request of the IP and reports whether it is within the limit.
+// If not, it also returns the time until the window resets.
+func (l *IPRateLimiter) Allow(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	if now.Sub(l.windowStart) >= l.window {
+		l.windowStart = now
+		clear(l.counts)
+	}
+
+	if l.counts[ip] >= l.limit {
+		return false, l.window - now.Sub(l.windowStart)
+	}
+	l.counts[ip]++
+	return true, 0
+}
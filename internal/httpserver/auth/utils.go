@@ -6,7 +6,6 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"mediahub_oss/internal/repository"
 	"strings"
 	"time"
@@ -17,12 +16,7 @@ import (
 
 // validateJWT parses the token string, validates the signature, and retrieves the user.
 func (am *AuthMiddleware) validateJWT(tokenString string) (repository.User, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return repository.User{}, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return am.JWTSecret, nil
-	})
+	token, err := am.Keys.Parse(tokenString)
 
 	if err != nil {
 		return repository.User{}, err
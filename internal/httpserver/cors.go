@@ -36,10 +36,10 @@ func CORSMiddleware(allowedOrigins []string) Middleware {
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 
 				// Allow headers necessary for JSON APIs, Auth, and Media Streaming
-				w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, Range")
+				w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, Range, If-None-Match, If-Match")
 
 				// Expose headers so the frontend JavaScript can read them (Crucial for streaming/chunking)
-				w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range, Accept-Ranges, Content-Disposition")
+				w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range, Accept-Ranges, Content-Disposition, X-Page-Limit-Clamped, ETag")
 
 				// Allow credentials (like cookies or Authorization headers) to be sent cross-origin
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -0,0 +1,273 @@
+package databasehandler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// activityKinds maps the event kinds of the activity feed to the audited actions they cover.
+// Reads (downloads, listings, searches) are not part of the feed.
+var activityKinds = map[string][]string{
+	"upload":       {"entry.post"},
+	"delete":       {"entry.delete", "entries.delete"},
+	"update":       {"entry.update", "entry.legal_hold.set", "entry.legal_hold.release"},
+	"housekeeping": {"database.housekeeping", "database.integrity", "database.duplicates.start"},
+	"config":       {"database.create", "database.update", "database.delete_requested"},
+	"export":       {"entries.export"},
+	"import":       {"entries.import"},
+	"alert":        {"database.disk_space_warning", "database.processing_backlog_warning", "entry.corrupted", "entry.infected"},
+}
+
+// @Summary Get the activity feed of a database
+// @Description Returns what happened in a database, newest first: uploads, deletions, metadata and legal hold changes, housekeeping runs
+// @Description with their report, integrity checks and duplicate scans, settings changes, exports, imports and alerts, each as `{time, kind, actor, summary, details}`.
+// @Description The feed is read from the audit log, so it only contains the events recorded while audit logging to the database was enabled.
+// @Description Scheduled housekeeping runs are listed if they deleted, skipped or held entries. Needs the admin role on the database.
+// @Description With `Accept: text/csv` the events are returned as CSV, the details as a JSON column.
+// @Tags database
+// @Produce json
+// @Produce text/csv
+// @Param    name    query  string  false  "Database name"
+// @Param    id      query  string  false  "Database ID, instead of the name"
+// @Param    from    query  int64   false  "Only events at or after this time (Unix milliseconds)"
+// @Param    to      query  int64   false  "Only events at or before this time (Unix milliseconds)"
+// @Param    kind    query  string  false  "Comma separated kinds: upload, delete, update, housekeeping, config, export, import, alert (default all)"
+// @Param    limit   query  int     false  "Number of events to return (default 100, at most 1000)"
+// @Param    offset  query  int     false  "Offset for pagination (default 0)"
+// @Param    order   query  string  false  "Sort order ('asc' or 'desc', default 'desc')"
+// @Success 200 {array} ActivityEventResponse
+// @Failure 400 {object} utils.ErrorResponse "Missing name or id, or invalid parameters"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/activity [get]
+func (h *DatabaseHandler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	name := query.Get("name")
+	id := query.Get("id")
+	if name == "" && id == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required query parameter: name")
+		return
+	}
+
+	// 1. Parse the filters
+	opts := repository.QueryOptions{Order: query.Get("order")}
+	var err error
+	for _, param := range []struct {
+		key string
+		dst *time.Time
+	}{{"from", &opts.TStart}, {"to", &opts.TEnd}} {
+		if value := query.Get(param.key); value != "" {
+			ms, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s: must be Unix milliseconds", param.key))
+				return
+			}
+			*param.dst = time.UnixMilli(ms)
+		}
+	}
+	if opts.Offset, err = parseOptionalInt(query.Get("offset")); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid offset")
+		return
+	}
+	limit, err := parseOptionalInt(query.Get("limit"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	opts.Limit, _ = repository.PageLimits{}.Apply(limit)
+	if err := opts.Validate(); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	actions, err := activityActions(query.Get("kind"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 2. Resolve the database, databases the user does not administrate are reported as not found
+	db, err := h.findDatabase(ctx, name, id)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to retrieve databases.", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve databases")
+		return
+	}
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	if !holder.IsGlobalAdmin() && !holder.HasPermission(db.ID, repository.AccessAdmin) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	// 3. Read the events from the audit log
+	logs, err := h.Repo.GetDatabaseLogs(ctx, db.ID, actions, opts)
+	if err != nil {
+		h.Logger.Error("Failed to retrieve database activity", "database_id", db.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve the activity.")
+		return
+	}
+
+	events := make([]ActivityEventResponse, len(logs))
+	for i, log := range logs {
+		events[i] = toActivityEvent(log)
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		writeActivityCSV(w, events)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, events)
+}
+
+// activityActions returns the audited actions of a comma separated list of kinds, all actions if it is empty.
+func activityActions(kinds string) ([]string, error) {
+	var actions []string
+	if kinds == "" {
+		for _, kindActions := range activityKinds {
+			actions = append(actions, kindActions...)
+		}
+		return actions, nil
+	}
+
+	for kind := range strings.SplitSeq(kinds, ",") {
+		kindActions, ok := activityKinds[strings.TrimSpace(kind)]
+		if !ok {
+			return nil, fmt.Errorf("invalid kind: %q (must be upload, delete, update, housekeeping, config, export, import or alert)", kind)
+		}
+		actions = append(actions, kindActions...)
+	}
+	return actions, nil
+}
+
+// toActivityEvent normalizes an audit log into an event of the feed.
+func toActivityEvent(log repository.AuditLog) ActivityEventResponse {
+	event := ActivityEventResponse{
+		Time:    log.Timestamp.UnixMilli(),
+		Actor:   log.Actor,
+		Details: log.Details,
+	}
+	for kind, actions := range activityKinds {
+		if slices.Contains(actions, log.Action) {
+			event.Kind = kind
+			break
+		}
+	}
+
+	// Entry events are logged for "<database_id>:<entry_id>"
+	entry := ""
+	if _, entryID, ok := strings.Cut(log.Resource, ":"); ok {
+		entry = "entry " + entryID
+		if event.Details == nil {
+			event.Details = map[string]any{}
+		}
+		event.Details["entry_id"], _ = strconv.ParseInt(entryID, 10, 64)
+	}
+
+	d := log.Details
+	switch log.Action {
+	case "entry.post":
+		event.Summary = fmt.Sprintf("Uploaded %s", entry)
+	case "entry.delete":
+		event.Summary = fmt.Sprintf("Deleted %s", entry)
+	case "entries.delete":
+		event.Summary = fmt.Sprintf("Deleted %v entries", d["count"])
+	case "entry.update":
+		event.Summary = fmt.Sprintf("Updated the metadata of %s", entry)
+	case "entry.legal_hold.set", "entry.legal_hold.release":
+		verb := "Placed"
+		if log.Action == "entry.legal_hold.release" {
+			verb = "Released"
+		}
+		ids, _ := d["ids"].([]any)
+		event.Summary = fmt.Sprintf("%s a legal hold on %d entries", verb, len(ids))
+	case "database.housekeeping":
+		event.Summary = fmt.Sprintf("Housekeeping deleted %v entries, freeing %v bytes", d["entries_deleted"], d["space_freed"])
+	case "database.integrity":
+		event.Summary = "Started an integrity check"
+	case "database.duplicates.start":
+		event.Summary = "Started a duplicate scan"
+	case "database.create":
+		event.Summary = "Created the database"
+	case "database.update":
+		if fields, ok := d["fields"].([]any); ok && len(fields) > 0 {
+			event.Summary = fmt.Sprintf("Changed the settings: %s", joinAny(fields))
+		} else {
+			event.Summary = "Changed the settings"
+		}
+	case "database.delete_requested":
+		event.Summary = "Requested the deletion of the database"
+	case "entries.export":
+		event.Summary = fmt.Sprintf("Exported %v entries", d["count"])
+	case "entries.import":
+		event.Summary = fmt.Sprintf("Imported entries (mode %v)", d["mode"])
+	case "database.disk_space_warning":
+		event.Summary = "Disk space warning"
+	case "database.processing_backlog_warning":
+		event.Summary = fmt.Sprintf("%v entries are processing for too long", d["processing"])
+	case "entry.corrupted":
+		event.Summary = fmt.Sprintf("The file of %s is corrupted", entry)
+	case "entry.infected":
+		event.Summary = fmt.Sprintf("The file of %s is infected", entry)
+	default:
+		event.Summary = log.Action
+	}
+	return event
+}
+
+// writeActivityCSV writes the events as CSV, with the details as JSON.
+func writeActivityCSV(w http.ResponseWriter, events []ActivityEventResponse) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	csvWriter := csv.NewWriter(w)
+	_ = csvWriter.Write([]string{"time", "kind", "actor", "summary", "details"})
+	for _, event := range events {
+		details, err := json.Marshal(event.Details)
+		if err != nil {
+			details = []byte("{}")
+		}
+		_ = csvWriter.Write([]string{
+			time.UnixMilli(event.Time).UTC().Format(time.RFC3339Nano),
+			event.Kind,
+			event.Actor,
+			event.Summary,
+			string(details),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func joinAny(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseOptionalInt parses a query parameter, 0 if it is empty.
+func parseOptionalInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
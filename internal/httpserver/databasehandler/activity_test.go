@@ -0,0 +1,156 @@
+package databasehandler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestGetActivity(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repository.Database{Name: "projects", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	h := &DatabaseHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+
+	// A week of mixed events, as logged by the handlers and housekeeping
+	auditor := audit.NewAlDatabase(r)
+	id := db.ID.String()
+	seed := []struct {
+		action, actor, resource string
+		details                 map[string]any
+	}{
+		{"database.create", "admin", id, map[string]any{"name": "projects"}},
+		{"entry.post", "alice", id + ":1", map[string]any{"database_name": "projects"}},
+		{"entry.read_meta", "bob", id + ":1", nil},
+		{"entry.post", "alice", id + ":2", map[string]any{"database_name": "projects"}},
+		{"database.update", "admin", id, map[string]any{"name": "projects", "fields": []string{"config", "housekeeping"}}},
+		{"entries.export", "bob", id, map[string]any{"count": 2, "format": "zip"}},
+		{"entry.delete", "alice", id + ":1", nil},
+		{"database.housekeeping", "housekeeping", id, map[string]any{"entries_deleted": 1, "space_freed": 512}},
+		{"user.create", "admin", "carol", nil},
+	}
+	for _, event := range seed {
+		auditor.Log(ctx, event.action, event.actor, event.resource, event.details)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	call := func(target, accept string, holder utils.PermissionHolder) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "lead"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, holder))
+		rec := httptest.NewRecorder()
+		h.GetActivity(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) []ActivityEventResponse {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var events []ActivityEventResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return events
+	}
+	admin := &utils.GlobalAdmin{}
+
+	// 1. The feed merges the events of the database, newest first, reads and other resources are left out
+	events := decode(call("/api/database/activity?name=projects", "", admin))
+	var kinds []string
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+	}
+	if want := []string{"housekeeping", "delete", "export", "config", "upload", "upload", "config"}; !slices.Equal(kinds, want) {
+		t.Fatalf("expected kinds %v, got %v", want, kinds)
+	}
+	if events[0].Actor != "housekeeping" || events[0].Summary != "Housekeeping deleted 1 entries, freeing 512 bytes" {
+		t.Errorf("unexpected housekeeping event: %+v", events[0])
+	}
+	if events[1].Summary != "Deleted entry 1" || events[1].Details["entry_id"] != float64(1) {
+		t.Errorf("unexpected delete event: %+v", events[1])
+	}
+	if events[3].Summary != "Changed the settings: config, housekeeping" {
+		t.Errorf("unexpected config event: %+v", events[3])
+	}
+
+	// 2. Filtered by kind and time, paginated
+	uploads := decode(call("/api/database/activity?id="+id+"&kind=upload&order=asc&limit=1", "", admin))
+	if len(uploads) != 1 || uploads[0].Actor != "alice" || uploads[0].Summary != "Uploaded entry 1" {
+		t.Errorf("expected the first upload, got %+v", uploads)
+	}
+	from := events[2].Time
+	recent := decode(call("/api/database/activity?name=projects&kind=delete,housekeeping&from="+strconv.FormatInt(from, 10), "", admin))
+	if len(recent) != 2 {
+		t.Errorf("expected the deletion and the housekeeping run, got %+v", recent)
+	}
+
+	// 3. CSV for reporting
+	rec := call("/api/database/activity?name=projects&kind=upload", "text/csv", admin)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected CSV, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	if len(rows) != 3 || !slices.Equal(rows[0], []string{"time", "kind", "actor", "summary", "details"}) || rows[1][2] != "alice" {
+		t.Errorf("unexpected CSV: %v", rows)
+	}
+
+	// 4. Invalid parameters, unknown databases and users without the admin role
+	for _, target := range []string{"/api/database/activity", "/api/database/activity?name=projects&kind=downloads", "/api/database/activity?name=projects&from=yesterday"} {
+		if rec := call(target, "", admin); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", target, rec.Code)
+		}
+	}
+	if rec := call("/api/database/activity?name=unknown", "", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown database, got %d", rec.Code)
+	}
+	viewer := &utils.APIKeyOfAdmin{Scope: repository.AccessView | repository.AccessDelete, Repo: r}
+	if rec := call("/api/database/activity?name=projects", "", viewer); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without the admin role, got %d", rec.Code)
+	}
+}
@@ -36,11 +36,13 @@ func (h *DatabaseHandler) GetFields(w http.ResponseWriter, r *http.Request) {
 	for i, f := range fields {
 		idVal := f.ID
 		isIndexedVal := f.IsIndexed
+		isSensitiveVal := f.IsSensitive
 		resp[i] = DatabaseCustomField{
-			ID:        &idVal,
-			Name:      f.Name,
-			Type:      f.Type,
-			IsIndexed: &isIndexedVal,
+			ID:          &idVal,
+			Name:        f.Name,
+			Type:        f.Type,
+			IsIndexed:   &isIndexedVal,
+			IsSensitive: &isSensitiveVal,
 		}
 	}
 
@@ -92,11 +94,13 @@ func (h *DatabaseHandler) AddField(w http.ResponseWriter, r *http.Request) {
 
 	idVal := added.ID
 	isIndexedVal := added.IsIndexed
+	isSensitiveVal := added.IsSensitive
 	resp := DatabaseCustomField{
-		ID:        &idVal,
-		Name:      added.Name,
-		Type:      added.Type,
-		IsIndexed: &isIndexedVal,
+		ID:          &idVal,
+		Name:        added.Name,
+		Type:        added.Type,
+		IsIndexed:   &isIndexedVal,
+		IsSensitive: &isSensitiveVal,
 	}
 
 	utils.RespondWithJSON(w, http.StatusCreated, resp)
@@ -119,20 +123,21 @@ func (h *DatabaseHandler) UpdateField(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var payload struct {
-		Name      *string `json:"name"`
-		IsIndexed *bool   `json:"is_indexed"`
+		Name        *string `json:"name"`
+		IsIndexed   *bool   `json:"is_indexed"`
+		IsSensitive *bool   `json:"is_sensitive"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	if payload.Name == nil && payload.IsIndexed == nil {
+	if payload.Name == nil && payload.IsIndexed == nil && payload.IsSensitive == nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Empty update payload")
 		return
 	}
 
-	updated, err := h.Repo.UpdateCustomField(ctx, repository.ULID(dbID), fieldID, payload.Name, payload.IsIndexed)
+	updated, err := h.Repo.UpdateCustomField(ctx, repository.ULID(dbID), fieldID, payload.Name, payload.IsIndexed, payload.IsSensitive)
 	if err != nil {
 		if errors.Is(err, customerrors.ErrNotFound) {
 			utils.RespondWithError(w, http.StatusNotFound, "Database or field not found.")
@@ -142,17 +147,23 @@ func (h *DatabaseHandler) UpdateField(w http.ResponseWriter, r *http.Request) {
 			utils.RespondWithError(w, http.StatusConflict, "The new field name is already in use by another field.")
 			return
 		}
+		if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update field: %v", err))
 		return
 	}
 
 	idVal := updated.ID
 	isIndexedVal := updated.IsIndexed
+	isSensitiveVal := updated.IsSensitive
 	resp := DatabaseCustomField{
-		ID:        &idVal,
-		Name:      updated.Name,
-		Type:      updated.Type,
-		IsIndexed: &isIndexedVal,
+		ID:          &idVal,
+		Name:        updated.Name,
+		Type:        updated.Type,
+		IsIndexed:   &isIndexedVal,
+		IsSensitive: &isSensitiveVal,
 	}
 
 	utils.RespondWithJSON(w, http.StatusOK, resp)
@@ -203,6 +214,10 @@ func (h *DatabaseHandler) DeleteField(w http.ResponseWriter, r *http.Request) {
 			utils.RespondWithError(w, http.StatusNotFound, "Database or field not found.")
 			return
 		}
+		if errors.Is(err, customerrors.ErrConflict) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
 		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete field: %v", err))
 		return
 	}
@@ -1,11 +1,16 @@
 package databasehandler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"mediahub_oss/internal/httpserver/utils"
 	"mediahub_oss/internal/repository"
@@ -118,8 +123,8 @@ func (h *DatabaseHandler) GetDatabases(w http.ResponseWriter, r *http.Request) {
 // @Produce  json
 // @Param    database  body  DatabaseCreatePayload  true  "Database Metadata"
 // @Success 201 {object} DatabaseResponse
-// @Failure 400 {object} utils.ErrorResponse "Invalid request payload or missing name"
-// @Failure 409 {object} utils.ErrorResponse "Database name already in use"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request payload, missing name or invalid housekeeping values"
+// @Failure 409 {object} utils.ErrorResponse "Database name already in use, or max_databases databases exist"
 // @Failure 500 {object} utils.ErrorResponse "Failed to create database or storage folder"
 // @Security BasicAuth
 // @Router /database [post]
@@ -133,25 +138,25 @@ func (h *DatabaseHandler) CreateDatabase(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if payload.Name == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Missing required field: name")
-		return
-	}
-	if payload.ContentType == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Missing required field: content_type")
-		return
-	}
-
 	user := utils.GetUserFromContext(ctx)
 
 	// Create the database
-	var database = payload.toModel()
+	database, err := newDatabase(h.MediaConverter, payload)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
+	// Only the database record is created, in a single transaction. The storage folders are created with the
+	// first file written and tolerated missing by reads, walks and RemoveDatabase, so there is nothing to roll back.
+	// Folders that go missing later are recreated by the startup check of the serve command.
 	createdDB, err := h.Repo.CreateDatabase(ctx, database)
 	if err != nil {
 		if errors.Is(err, customerrors.ErrDatabaseExists) {
 			utils.RespondWithError(w, http.StatusConflict, "Database name already in use.")
-		} else if errors.Is(err, customerrors.ErrInvalidName) {
+		} else if errors.Is(err, customerrors.ErrLimitReached) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+		} else if errors.Is(err, customerrors.ErrInvalidName) || errors.Is(err, customerrors.ErrValidation) {
 			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 		} else {
 			h.Logger.Error("Failed to create database.", "error", err)
@@ -172,14 +177,21 @@ func (h *DatabaseHandler) CreateDatabase(w http.ResponseWriter, r *http.Request)
 
 // @Summary Update database housekeeping rules or rename
 // @Description Updates the mutable configuration fields for a specific database, including its name.
+// @Description The body is merged onto the current settings: omitted keys, also inside `config` and `housekeeping`, keep their value.
+// @Description An explicit `null` resets a config flag or housekeeping rule to its default, a `null` object resets all of its keys.
+// @Description Housekeeping values can still be disabled with `"0"` or `"disabled"`. Nothing is changed if the merged result is invalid.
+// @Description Changing `config.fulltext_fields` rebuilds the full-text index, existing entries are indexed by a background task and only found by MATCH once it is done.
+// @Description `config.public_read` lets anyone list, search and download the entries without authentication, only global admins can change it.
 // @Tags database
 // @Accept   json
 // @Produce  json
 // @Param    database_id  path  string  true  "Database ID"
 // @Param    housekeeping  body  DatabaseUpdatePayload  true  "Configuration and Housekeeping Rules"
 // @Success 200 {object} DatabaseResponse
-// @Failure 400 {object} utils.ErrorResponse "Invalid request payload or missing id path parameter"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request payload, invalid housekeeping values or missing id path parameter"
+// @Failure 403 {object} utils.ErrorResponse "Changing config.public_read requires a global admin"
 // @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 409 {object} utils.ErrorResponse "Existing entries share an external_id while enabling unique_external_id"
 // @Failure 500 {object} utils.ErrorResponse "Failed to update database"
 // @Security BasicAuth
 // @Router /database/{database_id} [put]
@@ -206,24 +218,78 @@ func (h *DatabaseHandler) UpdateDatabase(w http.ResponseWriter, r *http.Request)
 
 	user := utils.GetUserFromContext(ctx)
 
-	var updates DatabaseUpdatePayload
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
 
-	// update database (Apply new name if provided)
-	if updates.Name != "" {
-		db.Name = updates.Name
+	// Merge the update onto the current values, nothing is stored unless the merged result is valid
+	merged, err := applyUpdate(db, body)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Publishing a database is up to the global admins, database admins may change everything else
+	if merged.Config.PublicRead != db.Config.PublicRead && !utils.GetPermissionHolderFromContext(ctx).IsGlobalAdmin() {
+		utils.RespondWithError(w, http.StatusForbidden, "Only global admins can change config.public_read")
+		return
+	}
+
+	// Only validate changed targets so unrelated updates are not blocked by a lost capability
+	if merged.Config.AutoConversion != db.Config.AutoConversion {
+		if err := validateAutoConversion(h.MediaConverter, db.ContentType, merged.Config.AutoConversion); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if !slices.Equal(merged.Config.ConversionRules, db.Config.ConversionRules) {
+		if err := validateConversionRules(h.MediaConverter, db.ContentType, merged.Config.ConversionRules); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if merged.Config.Transcription != db.Config.Transcription {
+		if err := validateTranscription(db.ContentType, merged.Config.Transcription, db.CustomFields); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if !reflect.DeepEqual(merged.Config.MetadataDefaults, db.Config.MetadataDefaults) {
+		if err := validateMetadataValues("metadata_defaults", merged.Config.MetadataDefaults, db.CustomFields); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if !reflect.DeepEqual(merged.Config.MetadataOverrides, db.Config.MetadataOverrides) {
+		if err := validateMetadataValues("metadata_overrides", merged.Config.MetadataOverrides, db.CustomFields); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if merged.Config.GeoFields != db.Config.GeoFields {
+		if err := repository.ValidateGeoFields(merged.Config.GeoFields, db.CustomFields); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if merged.Config.Waveform != db.Config.Waveform {
+		if err := validateWaveform(merged.Config.Waveform); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 	}
-	db.NMaxQueued = updates.NMaxQueued
-	db.Config = updates.getConfig()
-	db.Housekeeping = updates.getHK(db.Housekeeping.LastHkRun)
+	db = merged
 
 	updatedDB, err := h.Repo.UpdateDatabase(ctx, db)
 	if err != nil {
 		if errors.Is(err, customerrors.ErrDatabaseExists) {
 			utils.RespondWithError(w, http.StatusConflict, "Database name already in use.")
+		} else if errors.Is(err, customerrors.ErrConflict) {
+			utils.RespondWithError(w, http.StatusConflict, "Existing entries share an external_id, make them unique before enabling unique_external_id.")
+		} else if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
 		} else {
 			utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Error updating database: %v", err))
 		}
@@ -231,7 +297,10 @@ func (h *DatabaseHandler) UpdateDatabase(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Audit Log
-	h.Auditor.Log(ctx, "database.update", user.Username, updatedDB.ID.String(), nil)
+	h.Auditor.Log(ctx, "database.update", user.Username, updatedDB.ID.String(), map[string]any{
+		"name":   updatedDB.Name,
+		"fields": slices.Sorted(maps.Keys(body)),
+	})
 
 	resp := mapToDatabaseResponse(updatedDB)
 	utils.RespondWithJSON(w, http.StatusOK, resp)
@@ -239,12 +308,18 @@ func (h *DatabaseHandler) UpdateDatabase(w http.ResponseWriter, r *http.Request)
 
 // @Summary Delete a database
 // @Description Deletes a database, its entry table, and all of its associated entries and metadata.
+// @Description Databases with entries under legal hold are only deleted with `force=true` and the database name repeated in `confirm`.
 // @Tags database
 // @Produce  json
 // @Param    database_id  path  string  true  "Database ID"
+// @Param    force   query  bool    false  "Delete the database although entries are under legal hold, and without a confirmation token"
+// @Param    confirm query  string  false  "The name of the database, required with force if entries are under legal hold"
+// @Param    confirm_token query string false "The token returned by the first call for large databases"
 // @Success 200 {object} utils.MessageResponse "Success message"
+// @Success 202 {object} DeleteConfirmationResponse "The database is large, repeat the call with the confirmation token"
 // @Failure 400 {object} utils.ErrorResponse "Missing database_id path parameter"
 // @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 409 {object} utils.ErrorResponse "Entries are under legal hold, or the confirmation token is invalid or expired"
 // @Failure 500 {object} utils.ErrorResponse "Failed to delete database record or folder"
 // @Security BasicAuth
 // @Router /database/{database_id} [delete]
@@ -259,6 +334,56 @@ func (h *DatabaseHandler) DeleteDatabase(w http.ResponseWriter, r *http.Request)
 
 	user := utils.GetUserFromContext(ctx)
 
+	// Entries under legal hold must not disappear with their database by accident
+	db, err := h.Repo.GetDatabase(ctx, repository.ULID(id))
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		} else {
+			h.Logger.Error("Failed to get database.", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get database.")
+		}
+		return
+	}
+	held, err := h.Repo.CountHeldEntries(ctx, db.ID, "", time.Time{})
+	if err != nil {
+		h.Logger.Error("Failed to count the entries under legal hold.", "error", err, "database_id", id)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check the legal holds of the database.")
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
+	forced := force && r.URL.Query().Get("confirm") == db.Name
+	if held > 0 && !forced {
+		utils.RespondWithError(w, http.StatusConflict, fmt.Sprintf("Database has %d entries under legal hold. Release them first, or delete with force=true and confirm=<database name>.", held))
+		return
+	}
+
+	// Large databases are deleted in two steps: the first call returns a confirmation token
+	confirmToken := r.URL.Query().Get("confirm_token")
+	if confirmToken != "" {
+		ok, err := h.Repo.ConsumeDeleteConfirmation(ctx, db.ID, hashToken(confirmToken))
+		if err != nil {
+			h.Logger.Error("Failed to check the delete confirmation.", "error", err, "database_id", id)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check the confirmation token.")
+			return
+		}
+		if !ok {
+			utils.RespondWithError(w, http.StatusConflict, "The confirmation token is invalid or expired. Repeat the request without confirm_token to get a new one.")
+			return
+		}
+	} else if !force {
+		stats, err := h.Repo.GetDatabaseStats(ctx, db.ID)
+		if err != nil {
+			h.Logger.Error("Failed to get database stats.", "error", err, "database_id", id)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get database stats.")
+			return
+		}
+		if h.ConfirmDelete.needsConfirmation(stats) {
+			h.requestDeleteConfirmation(w, r, db, stats, user.Username)
+			return
+		}
+	}
+
 	if err := h.Repo.DeleteDatabase(ctx, repository.ULID(id)); err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
@@ -271,8 +396,29 @@ func (h *DatabaseHandler) DeleteDatabase(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The folders are renamed right away and removed in the background, a crash in between is
+	// finished by the cleanup on the next start
+	if err := h.Storage.RemoveDatabase(ctx, id); err != nil {
+		h.Logger.Error("Failed to remove the folders of the deleted database.", "error", err, "database_id", id)
+	} else {
+		go func() {
+			if _, err := h.Storage.PurgeRemovedDatabases(context.Background()); err != nil {
+				h.Logger.Error("Failed to purge the folders of deleted databases.", "error", err)
+			}
+		}()
+	}
+
 	// Audit Log
-	h.Auditor.Log(ctx, "database.delete", user.Username, id, nil)
+	details := map[string]any{"name": db.Name}
+	if held > 0 {
+		details["forced_legal_holds"] = held
+	}
+	if confirmToken != "" {
+		details["confirmed"] = true
+	} else if force {
+		details["forced"] = true
+	}
+	h.Auditor.Log(ctx, "database.delete", user.Username, id, details)
 
 	h.Logger.Info("Database deleted successfully.", "database_id", id)
 	utils.RespondWithJSON(w, http.StatusOK, utils.MessageResponse{
@@ -315,7 +461,7 @@ func (h *DatabaseHandler) TriggerHousekeeping(w http.ResponseWriter, r *http.Req
 	}
 
 	// 4. Execute Housekeeping Logic
-	deletedCount, spaceFreed, err := h.HouseKeeper.RunDBHousekeeping(ctx, db)
+	report, err := h.HouseKeeper.RunDBHousekeeping(ctx, db)
 	if errors.Is(err, customerrors.ErrLockNotAcquired) {
 		h.Logger.Error("Skipping housekeeping", "error", err, "database_id", db.ID, "database_name", db.Name)
 		utils.RespondWithError(w, http.StatusConflict, "Lock not acquired")
@@ -330,17 +476,23 @@ func (h *DatabaseHandler) TriggerHousekeeping(w http.ResponseWriter, r *http.Req
 	// 5. Audit Log the manual trigger
 	h.Auditor.Log(ctx, "database.housekeeping", user.Username, id, map[string]any{
 		"name":            db.Name,
-		"entries_deleted": deletedCount,
-		"space_freed":     spaceFreed,
+		"entries_deleted": report.EntriesDeleted,
+		"entries_skipped": report.EntriesSkipped,
+		"entries_held":    report.EntriesHeld,
+		"space_freed":     report.SpaceFreed,
+		"pages_reclaimed": report.PagesReclaimed,
 	})
 
 	// 6. Respond with the summary
 	resp := HousekeepingResponse{
 		DatabaseID:      id,
 		DatabaseName:    db.Name,
-		EntriesDeleted:  deletedCount,
-		SpaceFreedBytes: spaceFreed,
-		Message:         fmt.Sprintf("Housekeeping complete. %d entries deleted due to age or disk space limits.", deletedCount),
+		EntriesDeleted:  report.EntriesDeleted,
+		EntriesSkipped:  report.EntriesSkipped,
+		EntriesHeld:     report.EntriesHeld,
+		SpaceFreedBytes: report.SpaceFreed,
+		PagesReclaimed:  report.PagesReclaimed,
+		Message:         fmt.Sprintf("Housekeeping complete. %d entries deleted due to age or disk space limits.", report.EntriesDeleted),
 	}
 
 	utils.RespondWithJSON(w, http.StatusOK, resp)
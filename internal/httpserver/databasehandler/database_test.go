@@ -0,0 +1,534 @@
+package databasehandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// jpegOnlyConverter supports JPEG as the only conversion target.
+type jpegOnlyConverter struct {
+	media.MediaConverter
+}
+
+func (c *jpegOnlyConverter) GetOutputMimeTypes(contentType string) []string {
+	return []string{"image/jpeg"}
+}
+
+func TestUpdateDatabaseMerge(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repository.Database{
+		Name:         "merge_test",
+		ContentType:  "image",
+		NMaxQueued:   5,
+		Config:       repository.DatabaseConfig{CreatePreview: true, AutoConversion: "image/jpeg", KeepOriginal: true},
+		Housekeeping: repository.DatabaseHK{Interval: 2 * time.Hour, DiskSpace: 1 << 30, MaxAge: 30 * 24 * time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	h := &DatabaseHandler{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		MediaConverter: &jpegOnlyConverter{},
+	}
+	update := func(body string) (int, repository.Database) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/api/database/"+db.ID.String(), strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		h.UpdateDatabase(rec, req)
+
+		stored, err := r.GetDatabase(ctx, db.ID)
+		if err != nil {
+			t.Fatalf("failed to get database: %v", err)
+		}
+		return rec.Code, stored
+	}
+
+	// 1. A partial update keeps all other settings
+	code, got := update(`{"config": {"create_preview": false}}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if got.Config.CreatePreview || got.Config.AutoConversion != "image/jpeg" || !got.Config.KeepOriginal ||
+		got.NMaxQueued != 5 || got.Name != "merge_test" || got.Housekeeping.Interval != 2*time.Hour || got.Housekeeping.DiskSpace != 1<<30 {
+		t.Errorf("expected only create_preview to change, got %+v", got)
+	}
+
+	// 2. An explicit null resets a key to its default
+	code, got = update(`{"config": {"keep_original": null}, "housekeeping": {"max_age": null, "interval": "0"}}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if got.Config.KeepOriginal || got.Config.AutoConversion != "image/jpeg" {
+		t.Errorf("expected keep_original to be reset and auto_conversion kept, got %+v", got.Config)
+	}
+	if got.Housekeeping.MaxAge != 365*24*time.Hour || got.Housekeeping.Interval != 0 || got.Housekeeping.DiskSpace != 1<<30 {
+		t.Errorf("expected the default max_age, a disabled interval and the kept disk_space, got %+v", got.Housekeeping)
+	}
+
+	// The age basis of max_age is taken over alone
+	code, got = update(`{"housekeeping": {"age_basis": "ingestion"}}`)
+	if code != http.StatusOK || got.Housekeeping.AgeBasis != repository.AgeBasisIngestion || got.Housekeeping.MaxAge != 365*24*time.Hour {
+		t.Errorf("expected the ingestion age basis and the kept max_age, got %d %+v", code, got.Housekeeping)
+	}
+
+	// 3. An invalid merged result changes nothing
+	for _, body := range []string{
+		`{"name": "renamed", "housekeeping": {"disk_space": "lots"}}`,
+		`{"name": "renamed", "housekeeping": {"age_basis": "upload"}}`,
+		`{"name": "renamed", "config": {"auto_conversion": "image/avif"}}`,
+		`{"name": "renamed", "config": {"create_preview": "yes"}}`,
+		`{"name": "renamed", "n_max_queued": null}`,
+		`{"name": "renamed", "config": {"conversion_rules": [{"from": "image/png", "to": "image/avif"}]}}`,
+		`{"name": "renamed", "config": {"conversion_rules": [{"from": "audio/wav", "to": "image/jpeg"}]}}`,
+		`{"name": "renamed", "config": {"conversion_rules": [{"from": "image/png", "to": "image/jpeg"}, {"from": "image/png", "to": "image/jpeg"}]}}`,
+	} {
+		code, got = update(body)
+		if code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, code)
+		}
+		if got.Name != "merge_test" {
+			t.Errorf("%s: expected no change, got name %q", body, got.Name)
+		}
+	}
+
+	// 4. Conversion rules are stored and reset by null
+	code, got = update(`{"config": {"conversion_rules": [{"from": "image/png", "to": "image/jpeg"}]}}`)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if len(got.Config.ConversionRules) != 1 || got.Config.ConversionRules[0] != (repository.ConversionRule{From: "image/png", To: "image/jpeg"}) {
+		t.Errorf("expected the conversion rule to be stored, got %+v", got.Config.ConversionRules)
+	}
+	code, got = update(`{"config": {"conversion_rules": null}}`)
+	if code != http.StatusOK || len(got.Config.ConversionRules) != 0 {
+		t.Errorf("expected the conversion rules to be removed, got %d %+v", code, got.Config.ConversionRules)
+	}
+
+	// 5. Full-text fields are TEXT custom fields, kept by unrelated updates
+	for _, cf := range []repository.CustomFieldDef{{Name: "caption", Type: "TEXT"}, {Name: "score", Type: "INTEGER"}} {
+		if _, err := r.AddCustomField(ctx, db.ID, cf); err != nil {
+			t.Fatalf("failed to add custom field: %v", err)
+		}
+	}
+	for _, body := range []string{
+		`{"config": {"fulltext_fields": ["score"]}}`,
+		`{"config": {"fulltext_fields": ["missing"]}}`,
+	} {
+		if code, _ = update(body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, code)
+		}
+	}
+	code, got = update(`{"config": {"fulltext_fields": ["caption"]}}`)
+	if code != http.StatusOK || !slices.Equal(fulltextFieldNames(got.CustomFields), []string{"caption"}) {
+		t.Fatalf("expected caption to be a full-text field, got %d %+v", code, got.CustomFields)
+	}
+	code, got = update(`{"config": {"create_preview": true}}`)
+	if code != http.StatusOK || !slices.Equal(fulltextFieldNames(got.CustomFields), []string{"caption"}) {
+		t.Errorf("expected the full-text fields to be kept, got %d %+v", code, got.CustomFields)
+	}
+	code, got = update(`{"config": {"fulltext_fields": null}}`)
+	if code != http.StatusOK || len(fulltextFieldNames(got.CustomFields)) != 0 {
+		t.Errorf("expected the full-text fields to be removed, got %d %+v", code, got.CustomFields)
+	}
+
+	// 6. Metadata defaults and overrides name custom fields and match their types
+	for _, body := range []string{
+		`{"config": {"metadata_defaults": {"site": "north"}}}`,
+		`{"config": {"metadata_defaults": {"score": 1.5}}}`,
+		`{"config": {"metadata_overrides": {"caption": 3}}}`,
+		`{"config": {"metadata_overrides": {"timestamp": 0}}}`,
+	} {
+		if code, _ = update(body); code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, code)
+		}
+	}
+	code, got = update(`{"config": {"metadata_defaults": {"caption": "untitled", "score": 1}, "metadata_overrides": {"score": 2}}}`)
+	if code != http.StatusOK || got.Config.MetadataDefaults["caption"] != "untitled" || got.Config.MetadataOverrides["score"] != float64(2) {
+		t.Fatalf("expected the metadata values to be stored, got %d %+v", code, got.Config)
+	}
+	if resp := mapToDatabaseResponse(got); resp.Config.MetadataDefaults["score"] != float64(1) {
+		t.Errorf("expected the defaults in the response, got %+v", resp.Config)
+	}
+	code, got = update(`{"config": {"metadata_defaults": {"score": 4}}}`)
+	if code != http.StatusOK || len(got.Config.MetadataDefaults) != 1 || got.Config.MetadataDefaults["score"] != float64(4) {
+		t.Errorf("expected the defaults to be replaced, got %d %+v", code, got.Config.MetadataDefaults)
+	}
+	code, got = update(`{"config": {"metadata_defaults": null}}`)
+	if code != http.StatusOK || got.Config.MetadataDefaults != nil || got.Config.MetadataOverrides["score"] != float64(2) {
+		t.Errorf("expected the defaults to be removed and the overrides kept, got %d %+v", code, got.Config)
+	}
+}
+
+func TestCreateDatabaseMetadataDefaults(t *testing.T) {
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	h := &DatabaseHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	create := func(name, config string) *httptest.ResponseRecorder {
+		body := `{"name": "` + name + `", "content_type": "file", "custom_fields": [{"name": "site", "type": "TEXT"}], "config": ` + config + `}`
+		req := httptest.NewRequest(http.MethodPost, "/api/database", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		h.CreateDatabase(rec, req)
+		return rec
+	}
+
+	for _, config := range []string{`{"metadata_defaults": {"device_model": "x"}}`, `{"metadata_overrides": {"site": true}}`} {
+		if rec := create("rejected", config); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", config, rec.Code, rec.Body.String())
+		}
+	}
+	rec := create("edge", `{"metadata_defaults": {"site": "north"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp DatabaseResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Config.MetadataDefaults["site"] != "north" {
+		t.Errorf("expected the defaults in the response, got %+v", resp.Config)
+	}
+}
+
+func TestCreateDatabaseStorageResidue(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// A file as storage root, no folder can be created below it
+	brokenRoot := filepath.Join(t.TempDir(), "storage")
+	if err := os.WriteFile(brokenRoot, nil, 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	root := t.TempDir()
+	h := &DatabaseHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+		Storage: &localstorage.LocalStorage{RootPath: brokenRoot},
+	}
+	create := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/database", strings.NewReader(`{"name": "`+name+`", "content_type": "file"}`))
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		h.CreateDatabase(rec, req)
+		return rec
+	}
+
+	// 1. Creating a database does not touch the storage, even an unwritable one
+	rec := create("cameras")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 with an unwritable storage, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created DatabaseResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	// 2. A failed creation leaves neither a record nor folders
+	h.Storage = &localstorage.LocalStorage{RootPath: root}
+	if rec := create("cameras"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a duplicate name, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if dbs, err := r.GetDatabases(ctx); err != nil || len(dbs) != 1 {
+		t.Errorf("expected only the first database, got %d (err %v)", len(dbs), err)
+	}
+	if dirEntries, err := os.ReadDir(root); err != nil || len(dirEntries) != 0 {
+		t.Errorf("expected an empty storage, got %v (err %v)", dirEntries, err)
+	}
+
+	// 3. The first file creates the missing folder, removing the folders works with or without them
+	store := h.Storage
+	if _, err := store.Write(ctx, created.ID, 1, strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to write into a database without folder: %v", err)
+	}
+	for range 2 {
+		if err := store.RemoveDatabase(ctx, created.ID); err != nil {
+			t.Errorf("failed to remove the folders: %v", err)
+		}
+		if _, err := store.PurgeRemovedDatabases(ctx); err != nil {
+			t.Errorf("failed to purge the folders: %v", err)
+		}
+	}
+	if dirEntries, err := os.ReadDir(root); err != nil || len(dirEntries) != 0 {
+		t.Errorf("expected an empty storage after the removal, got %v (err %v)", dirEntries, err)
+	}
+}
+
+func TestUpdateDatabaseWaveform(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repository.Database{Name: "voice", ContentType: "audio"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	h := &DatabaseHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	update := func(config string) (*httptest.ResponseRecorder, repository.WaveformConfig) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPut, "/api/database/"+db.ID.String(), strings.NewReader(`{"config": `+config+`}`))
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		h.UpdateDatabase(rec, req)
+
+		stored, err := r.GetDatabase(ctx, db.ID)
+		if err != nil {
+			t.Fatalf("failed to get database: %v", err)
+		}
+		return rec, stored.Config.Waveform
+	}
+
+	// 1. The style is stored and returned
+	rec, got := update(`{"waveform_width": 600, "waveform_height": 90, "waveform_color": "#E0E0E0", "waveform_background": "transparent"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	want := repository.WaveformConfig{Width: 600, Height: 90, Color: "#E0E0E0", Background: "transparent"}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	var resp DatabaseResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Config.WaveformWidth != 600 || resp.Config.WaveformBackground != "transparent" {
+		t.Errorf("expected the style in the response, got %+v", resp.Config)
+	}
+
+	// 2. Malformed colors and absurd dimensions are rejected, the stored style is kept
+	for _, config := range []string{
+		`{"waveform_color": "red"}`,
+		`{"waveform_color": "#12345"}`,
+		`{"waveform_background": "#ggg"}`,
+		`{"waveform_width": 5}`,
+		`{"waveform_height": 20000}`,
+		`{"waveform_width": -200}`,
+	} {
+		rec, got := update(config)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", config, rec.Code)
+		}
+		if got != want {
+			t.Errorf("%s: expected the stored style to be kept, got %+v", config, got)
+		}
+	}
+
+	// 3. Null selects the default again
+	if rec, got = update(`{"waveform_width": null, "waveform_background": null}`); rec.Code != http.StatusOK || got.Width != 0 || got.Background != "" || got.Height != 90 {
+		t.Errorf("expected width and background to be reset, got %d %+v", rec.Code, got)
+	}
+}
+
+func TestDeleteDatabaseConfirmation(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	h := &DatabaseHandler{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor:       audit.NewAlNoopLogger(),
+		Repo:          r,
+		Storage:       store,
+		ConfirmDelete: DeleteThresholds{Entries: 2},
+	}
+	admin := &repository.User{Username: "admin"}
+
+	createDB := func(name string, entries int) repository.Database {
+		db, err := r.CreateDatabase(ctx, repository.Database{Name: name, ContentType: "file"})
+		if err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		for i := range entries {
+			ts := time.UnixMilli(int64(1000 * (i + 1)))
+			if _, err := r.CreateEntry(ctx, db, repository.Entry{FileName: "a.bin", Size: 4, Timestamp: ts, MimeType: "application/octet-stream"}); err != nil {
+				t.Fatalf("failed to create entry: %v", err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Join(store.RootPath, db.ID.String()), 0755); err != nil {
+			t.Fatalf("failed to create folder: %v", err)
+		}
+		return db
+	}
+	deleteDB := func(db repository.Database, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/database/"+db.ID.String()+query, nil)
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, admin))
+		rec := httptest.NewRecorder()
+		h.DeleteDatabase(rec, req)
+		return rec
+	}
+	waitForPurge := func(db repository.Database) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			_, errLive := os.Stat(filepath.Join(store.RootPath, db.ID.String()))
+			_, errRemoved := os.Stat(filepath.Join(store.RootPath, ".deleting-"+db.ID.String()))
+			if os.IsNotExist(errLive) && os.IsNotExist(errRemoved) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("folders of %s were not removed", db.Name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// 1. Small databases are deleted in a single step
+	small := createDB("small", 1)
+	if rec := deleteDB(small, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	waitForPurge(small)
+
+	// 2. Large databases return a confirmation token and a summary first
+	large := createDB("large", 3)
+	rec := deleteDB(large, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var confirmation DeleteConfirmationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &confirmation); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if confirmation.ConfirmToken == "" || confirmation.EntryCount != 3 || confirmation.DatabaseName != "large" ||
+		confirmation.OldestTimestamp == nil || *confirmation.OldestTimestamp != 1000 || *confirmation.NewestTimestamp != 3000 {
+		t.Fatalf("unexpected confirmation: %+v", confirmation)
+	}
+	if _, err := r.GetDatabase(ctx, large.ID); err != nil {
+		t.Fatalf("expected the database to still exist: %v", err)
+	}
+
+	// 3. A wrong token is refused, the right one deletes the database once
+	if rec := deleteDB(large, "?confirm_token=wrong"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a wrong token, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := deleteDB(large, "?confirm_token="+confirmation.ConfirmToken); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := r.GetDatabase(ctx, large.ID); err == nil {
+		t.Errorf("expected the database to be deleted")
+	}
+	waitForPurge(large)
+
+	// 4. Expired tokens are refused
+	expiring := createDB("expiring", 3)
+	if err := r.CreateDeleteConfirmation(ctx, repository.DeleteConfirmation{
+		DatabaseID: expiring.ID,
+		TokenHash:  hashToken("expired"),
+		ExpiresAt:  time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("failed to create confirmation: %v", err)
+	}
+	if rec := deleteDB(expiring, "?confirm_token=expired"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for an expired token, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n, err := r.DeleteExpiredRows(ctx, "delete_confirmations", "expires_at", 500); err != nil || n != 1 {
+		t.Errorf("expected 1 swept confirmation, got %d (err %v)", n, err)
+	}
+
+	// 5. force=true skips the confirmation
+	if rec := deleteDB(expiring, "?force=true"); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with force, got %d: %s", rec.Code, rec.Body.String())
+	}
+	waitForPurge(expiring)
+}
@@ -0,0 +1,450 @@
+package databasehandler
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// DefinitionVersion is the format of the database definitions written by NewDefinition.
+const DefinitionVersion = 1
+
+// DefinitionResult reports how a definition was applied.
+type DefinitionResult struct {
+	Database   repository.Database
+	Created    bool
+	Changes    []string // empty if the database already matched the definition
+	KeptFields []string // custom fields of the database missing from the definition
+}
+
+// @Summary Export the definition of a database
+// @Description Returns the portable definition of a database: content type, config, housekeeping rules and custom fields,
+// @Description without IDs, statistics or runtime state. It can be applied to another server with POST /database/definition.
+// @Tags database
+// @Produce  json
+// @Param    name  query  string  false  "Database name"
+// @Param    id    query  string  false  "Database ID, instead of the name"
+// @Success 200 {object} DatabaseDefinition
+// @Failure 400 {object} utils.ErrorResponse "Missing name or id"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Failed to retrieve databases"
+// @Security BasicAuth
+// @Router /database/definition [get]
+func (h *DatabaseHandler) GetDatabaseDefinition(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	name := r.URL.Query().Get("name")
+	id := r.URL.Query().Get("id")
+	if name == "" && id == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required query parameter: name")
+		return
+	}
+
+	db, err := h.findDatabase(ctx, name, id)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	} else if err != nil {
+		h.Logger.Error("Failed to retrieve databases.", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve databases")
+		return
+	}
+
+	// Databases the user has no role on are reported as not found
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	anyRole := repository.AccessView | repository.AccessCreate | repository.AccessEdit | repository.AccessDelete | repository.AccessAdmin
+	if !holder.IsGlobalAdmin() && !holder.HasPermission(db.ID, anyRole) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	user := utils.GetUserFromContext(ctx)
+	h.Auditor.Log(ctx, "database.export_definition", user.Username, db.ID.String(), map[string]any{
+		"name": db.Name,
+	})
+	utils.RespondWithJSON(w, http.StatusOK, NewDefinition(db))
+}
+
+// @Summary Apply a database definition
+// @Description Creates the database of a definition exported by GET /database/definition, validated like POST /database.
+// @Description With `update=true` an existing database of that name is brought in line with the definition instead:
+// @Description missing custom fields are added, the flags of existing ones, the config and the housekeeping rules are replaced.
+// @Description Custom fields missing from the definition are kept and reported. Destructive differences, another content type
+// @Description or another type of a custom field, are refused before anything is changed. Unknown keys are rejected.
+// @Tags database
+// @Accept   json
+// @Produce  json
+// @Param    update      query  bool                false  "Update the database if it exists"
+// @Param    definition  body   DatabaseDefinition  true   "Database definition"
+// @Success 200 {object} DefinitionApplyResponse "The existing database was updated"
+// @Success 201 {object} DefinitionApplyResponse "The database was created"
+// @Failure 400 {object} utils.ErrorResponse "Invalid definition"
+// @Failure 409 {object} utils.ErrorResponse "The database exists, the update would be destructive, or max_databases databases exist"
+// @Failure 500 {object} utils.ErrorResponse "Failed to create or update the database"
+// @Security BasicAuth
+// @Router /database/definition [post]
+func (h *DatabaseHandler) ApplyDatabaseDefinition(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	update, err := strconv.ParseBool(cmp.Or(r.URL.Query().Get("update"), "false"))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameter: update")
+		return
+	}
+
+	// Typos in keys would silently fall back to defaults, so they are rejected
+	var def DatabaseDefinition
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&def); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid definition: %v", err))
+		return
+	}
+
+	result, err := ApplyDefinition(ctx, h.Repo, h.MediaConverter, def, update)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrValidation) || errors.Is(err, customerrors.ErrInvalidName) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, customerrors.ErrConflict) || errors.Is(err, customerrors.ErrDatabaseExists) || errors.Is(err, customerrors.ErrLimitReached) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+		} else {
+			h.Logger.Error("Failed to apply database definition.", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to apply database definition. Error: %v", err))
+		}
+		return
+	}
+
+	user := utils.GetUserFromContext(ctx)
+	h.Auditor.Log(ctx, "database.apply_definition", user.Username, result.Database.ID.String(), map[string]any{
+		"name":    result.Database.Name,
+		"created": result.Created,
+		"changes": result.Changes,
+	})
+
+	resp := DefinitionApplyResponse{
+		Database:   mapToDatabaseResponse(result.Database),
+		Created:    result.Created,
+		Changes:    append([]string{}, result.Changes...),
+		KeptFields: append([]string{}, result.KeptFields...),
+	}
+	status := http.StatusOK
+	if result.Created {
+		status = http.StatusCreated
+	}
+	utils.RespondWithJSON(w, status, resp)
+}
+
+// NewDefinition returns the portable definition of a database. Housekeeping values are written
+// exactly, so applying the definition reproduces the database.
+func NewDefinition(db repository.Database) DatabaseDefinition {
+	resp := mapToDatabaseResponse(db)
+	for i := range resp.CustomFields {
+		resp.CustomFields[i].ID = nil
+	}
+	warnPercent := db.Housekeeping.DiskSpaceWarnPercent
+
+	return DatabaseDefinition{
+		Version: DefinitionVersion,
+		DatabaseCreatePayload: DatabaseCreatePayload{
+			Name:        db.Name,
+			ContentType: db.ContentType,
+			NMaxQueued:  db.NMaxQueued,
+			Config:      resp.Config,
+			Housekeeping: HousekeepingPayload{
+				Interval:             shared.DurationToString(db.Housekeeping.Interval),
+				DiskSpace:            exactSizeString(db.Housekeeping.DiskSpace),
+				MaxAge:               shared.DurationToString(db.Housekeeping.MaxAge),
+				AgeBasis:             resp.Housekeeping.AgeBasis,
+				DiskSpaceWarnPercent: &warnPercent,
+			},
+			CustomFields:  resp.CustomFields,
+			CustomIndexes: resp.CustomIndexes,
+		},
+	}
+}
+
+// exactSizeString formats a size in the largest unit that divides it, unlike the rounded shared.BytesToString.
+func exactSizeString(size uint64) string {
+	for _, unit := range []struct {
+		shift  uint
+		suffix string
+	}{{40, "T"}, {30, "G"}, {20, "M"}, {10, "K"}} {
+		if size != 0 && size%(1<<unit.shift) == 0 {
+			return fmt.Sprintf("%d%s", size>>unit.shift, unit.suffix)
+		}
+	}
+	return strconv.FormatUint(size, 10)
+}
+
+// ApplyDefinition creates the database of a definition. With update, an existing database of that
+// name is brought in line with the definition instead (see updateFromDefinition), without update it
+// fails with ErrDatabaseExists. Invalid definitions fail with ErrValidation. Conversion targets are
+// checked against the capabilities of the media converter.
+func ApplyDefinition(ctx context.Context, repo repository.Repository, mc media.MediaConverter, def DatabaseDefinition, update bool) (DefinitionResult, error) {
+	if def.Version != DefinitionVersion {
+		return DefinitionResult{}, fmt.Errorf("%w: unsupported definition_version %d, expected %d", customerrors.ErrValidation, def.Version, DefinitionVersion)
+	}
+
+	databases, err := repo.GetDatabases(ctx)
+	if err != nil {
+		return DefinitionResult{}, fmt.Errorf("failed to list databases: %w", err)
+	}
+	i := slices.IndexFunc(databases, func(db repository.Database) bool { return db.Name == def.Name })
+	if i < 0 {
+		database, err := newDatabase(mc, def.DatabaseCreatePayload)
+		if err != nil {
+			return DefinitionResult{}, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+		}
+		created, err := repo.CreateDatabase(ctx, database)
+		if err != nil {
+			return DefinitionResult{}, err
+		}
+		return DefinitionResult{Database: created, Created: true}, nil
+	}
+	if !update {
+		return DefinitionResult{}, fmt.Errorf("%w: database '%s' already exists, apply the definition as update to change it", customerrors.ErrDatabaseExists, def.Name)
+	}
+
+	current, err := repo.GetDatabase(ctx, databases[i].ID)
+	if err != nil {
+		return DefinitionResult{}, fmt.Errorf("failed to get database '%s': %w", def.Name, err)
+	}
+	return updateFromDefinition(ctx, repo, mc, current, def)
+}
+
+// updateFromDefinition adds the missing custom fields and composite indexes of the definition to the
+// database and replaces the flags of the existing fields, the config and the housekeeping rules. Custom
+// fields and indexes missing from the definition are kept. Another content type or custom field type fails with ErrConflict; like every
+// validation error it is returned before anything is stored.
+func updateFromDefinition(ctx context.Context, repo repository.Repository, mc media.MediaConverter, current repository.Database, def DatabaseDefinition) (DefinitionResult, error) {
+	if def.ContentType != current.ContentType {
+		return DefinitionResult{}, fmt.Errorf("%w: database '%s' holds %s entries, the definition %s; the content type cannot be changed", customerrors.ErrConflict, current.Name, current.ContentType, def.ContentType)
+	}
+	for _, cf := range def.CustomFields {
+		if !slices.Contains(customFieldTypes, strings.ToUpper(cf.Type)) {
+			return DefinitionResult{}, fmt.Errorf("%w: custom field '%s' has the unknown type '%s', expected one of %s", customerrors.ErrValidation, cf.Name, cf.Type, strings.Join(customFieldTypes, ", "))
+		}
+	}
+	// The full-text fields are applied to the merged fields below
+	payload := def.DatabaseCreatePayload
+	payload.Config.FulltextFields = nil
+	target, err := payload.toModel()
+	if err != nil {
+		return DefinitionResult{}, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+	}
+
+	type flagUpdate struct {
+		fieldID     int
+		isIndexed   *bool
+		isSensitive *bool
+	}
+	var changes, conflicts, kept []string
+	var added []repository.CustomFieldDef
+	var flagUpdates []flagUpdate
+	fields := slices.Clone(current.CustomFields)
+	for _, want := range target.CustomFields {
+		j := slices.IndexFunc(fields, func(cf repository.CustomFieldDef) bool { return cf.Name == want.Name })
+		if j < 0 {
+			want.ID = 0
+			added = append(added, want)
+			changes = append(changes, fmt.Sprintf("added custom field '%s' (%s)", want.Name, want.Type))
+			continue
+		}
+		have := &fields[j]
+		if !strings.EqualFold(have.Type, want.Type) {
+			conflicts = append(conflicts, fmt.Sprintf("custom field '%s' is %s, the definition has %s", have.Name, have.Type, want.Type))
+			continue
+		}
+		update := flagUpdate{fieldID: have.ID}
+		if have.IsIndexed != want.IsIndexed {
+			changes = append(changes, fmt.Sprintf("custom field '%s': is_indexed %t -> %t", have.Name, have.IsIndexed, want.IsIndexed))
+			have.IsIndexed, update.isIndexed = want.IsIndexed, &want.IsIndexed
+		}
+		if have.IsSensitive != want.IsSensitive {
+			changes = append(changes, fmt.Sprintf("custom field '%s': is_sensitive %t -> %t", have.Name, have.IsSensitive, want.IsSensitive))
+			have.IsSensitive, update.isSensitive = want.IsSensitive, &want.IsSensitive
+		}
+		if update.isIndexed != nil || update.isSensitive != nil {
+			flagUpdates = append(flagUpdates, update)
+		}
+	}
+	if len(conflicts) > 0 {
+		return DefinitionResult{}, fmt.Errorf("%w: the definition would change database '%s' destructively: %s", customerrors.ErrConflict, current.Name, strings.Join(conflicts, ", "))
+	}
+	for _, cf := range current.CustomFields {
+		if !slices.ContainsFunc(target.CustomFields, func(want repository.CustomFieldDef) bool { return want.Name == cf.Name }) {
+			kept = append(kept, cf.Name)
+		}
+	}
+
+	// Kept fields stay full-text fields, the definition decides for all others
+	fulltext := slices.Clone(def.Config.FulltextFields)
+	for _, cf := range current.CustomFields {
+		if cf.IsFulltext && slices.Contains(kept, cf.Name) && !slices.Contains(fulltext, cf.Name) {
+			fulltext = append(fulltext, cf.Name)
+		}
+	}
+
+	// Composite indexes are only ever added, like custom fields
+	var addedIndexes [][]string
+	for _, index := range target.CustomIndexes {
+		if !slices.ContainsFunc(current.CustomIndexes, func(have []string) bool { return slices.Equal(have, index) }) {
+			addedIndexes = append(addedIndexes, index)
+			changes = append(changes, fmt.Sprintf("added custom index (%s)", strings.Join(index, ", ")))
+		}
+	}
+
+	merged := current
+	merged.CustomIndexes = append(slices.Clone(current.CustomIndexes), addedIndexes...)
+	merged.NMaxQueued = cmp.Or(target.NMaxQueued, current.NMaxQueued)
+	merged.Config = target.Config
+	merged.Housekeeping = target.Housekeeping
+	merged.Housekeeping.LastHkRun = current.Housekeeping.LastHkRun
+	if merged.CustomFields, err = applyFulltextFields(append(fields, added...), fulltext); err != nil {
+		return DefinitionResult{}, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+	}
+	if err := validateMerged(mc, current, merged); err != nil {
+		return DefinitionResult{}, err
+	}
+
+	oldDef, newDef := NewDefinition(current), NewDefinition(merged)
+	if oldDef.NMaxQueued != newDef.NMaxQueued {
+		changes = append(changes, fmt.Sprintf("n_max_queued: %d -> %d", oldDef.NMaxQueued, newDef.NMaxQueued))
+	}
+	changes = append(changes, diffSection("config", oldDef.Config, newDef.Config)...)
+	changes = append(changes, diffSection("housekeeping", oldDef.Housekeeping, newDef.Housekeeping)...)
+	if len(changes) == 0 {
+		return DefinitionResult{Database: current, KeptFields: kept}, nil
+	}
+
+	for _, cf := range added {
+		if _, err := repo.AddCustomField(ctx, current.ID, cf); err != nil {
+			return DefinitionResult{}, fmt.Errorf("failed to add custom field '%s': %w", cf.Name, err)
+		}
+	}
+	for _, update := range flagUpdates {
+		if _, err := repo.UpdateCustomField(ctx, current.ID, update.fieldID, nil, update.isIndexed, update.isSensitive); err != nil {
+			return DefinitionResult{}, fmt.Errorf("failed to update custom field %d: %w", update.fieldID, err)
+		}
+	}
+	if len(addedIndexes) > 0 {
+		if _, err := repo.AddCustomIndexes(ctx, current.ID, addedIndexes); err != nil {
+			return DefinitionResult{}, fmt.Errorf("failed to add custom indexes: %w", err)
+		}
+	}
+
+	// Update from the stored database, which has the IDs of the added fields and the current stats
+	fresh, err := repo.GetDatabase(ctx, current.ID)
+	if err != nil {
+		return DefinitionResult{}, fmt.Errorf("failed to get database '%s': %w", current.Name, err)
+	}
+	fresh.NMaxQueued = merged.NMaxQueued
+	fresh.Config = merged.Config
+	fresh.Housekeeping = merged.Housekeeping
+	if fresh.CustomFields, err = applyFulltextFields(fresh.CustomFields, fulltext); err != nil {
+		return DefinitionResult{}, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+	}
+	updated, err := repo.UpdateDatabase(ctx, fresh)
+	if err != nil {
+		return DefinitionResult{}, err
+	}
+	return DefinitionResult{Database: updated, Changes: changes, KeptFields: kept}, nil
+}
+
+// validateMerged validates the merged database of an update like a new one. Like UpdateDatabase, only
+// changed conversion targets are checked, so unrelated updates are not blocked by a lost capability.
+func validateMerged(mc media.MediaConverter, current, merged repository.Database) error {
+	var mediaFields []string
+	if defs, err := media.GetMetadataFields(merged.ContentType); err == nil {
+		for _, f := range defs {
+			mediaFields = append(mediaFields, f.Name)
+		}
+	}
+	// The configured limits are enforced by the repository when the fields are added
+	unlimited := repository.FieldLimits{MaxCount: math.MaxInt, MaxNameLength: math.MaxInt}
+	if err := repository.ValidateCustomFields(merged.CustomFields, mediaFields, unlimited); err != nil {
+		return err
+	}
+	if err := repository.ValidateCustomIndexes(merged.CustomIndexes, repository.IndexFieldNames(mediaFields, merged.CustomFields)); err != nil {
+		return err
+	}
+	if err := repository.ValidateGeoFields(merged.Config.GeoFields, merged.CustomFields); err != nil {
+		return err
+	}
+
+	if merged.Config.AutoConversion != current.Config.AutoConversion {
+		if err := validateAutoConversion(mc, merged.ContentType, merged.Config.AutoConversion); err != nil {
+			return fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+		}
+	}
+	if !slices.Equal(merged.Config.ConversionRules, current.Config.ConversionRules) {
+		if err := validateConversionRules(mc, merged.ContentType, merged.Config.ConversionRules); err != nil {
+			return fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+		}
+	}
+	for _, validate := range []func() error{
+		func() error {
+			return validateTranscription(merged.ContentType, merged.Config.Transcription, merged.CustomFields)
+		},
+		func() error {
+			return validateMetadataValues("metadata_defaults", merged.Config.MetadataDefaults, merged.CustomFields)
+		},
+		func() error {
+			return validateMetadataValues("metadata_overrides", merged.Config.MetadataOverrides, merged.CustomFields)
+		},
+		func() error { return validateWaveform(merged.Config.Waveform) },
+	} {
+		if err := validate(); err != nil {
+			return fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+		}
+	}
+	return nil
+}
+
+// diffSection lists the keys of a definition section whose JSON values differ, as "section.key: old -> new".
+func diffSection(section string, before, after any) []string {
+	toMap := func(v any) map[string]json.RawMessage {
+		var m map[string]json.RawMessage
+		data, _ := json.Marshal(v)
+		_ = json.Unmarshal(data, &m)
+		return m
+	}
+	valueOf := func(m map[string]json.RawMessage, key string) []byte {
+		if v, ok := m[key]; ok {
+			return v
+		}
+		return []byte("null")
+	}
+	oldValues, newValues := toMap(before), toMap(after)
+
+	keys := slices.Collect(maps.Keys(oldValues))
+	for key := range newValues {
+		if _, ok := oldValues[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var changes []string
+	for _, key := range keys {
+		if a, b := valueOf(oldValues, key), valueOf(newValues, key); !bytes.Equal(a, b) {
+			changes = append(changes, fmt.Sprintf("%s.%s: %s -> %s", section, key, a, b))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,216 @@
+package databasehandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestDatabaseDefinition(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	h := &DatabaseHandler{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		MediaConverter: &jpegOnlyConverter{},
+	}
+	withAdmin := func(req *http.Request) *http.Request {
+		ctx := context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "tester"})
+		return req.WithContext(context.WithValue(ctx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+	}
+	export := func(name string) DatabaseDefinition {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.GetDatabaseDefinition(rec, withAdmin(httptest.NewRequest(http.MethodGet, "/api/database/definition?name="+name, nil)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 exporting %s, got %d: %s", name, rec.Code, rec.Body)
+		}
+		var def DatabaseDefinition
+		if err := json.NewDecoder(rec.Body).Decode(&def); err != nil {
+			t.Fatalf("failed to decode definition: %v", err)
+		}
+		return def
+	}
+	apply := func(def any, query string) (int, DefinitionApplyResponse) {
+		t.Helper()
+		body, ok := def.(string)
+		if !ok {
+			data, _ := json.Marshal(def)
+			body = string(data)
+		}
+		rec := httptest.NewRecorder()
+		h.ApplyDatabaseDefinition(rec, withAdmin(httptest.NewRequest(http.MethodPost, "/api/database/definition"+query, strings.NewReader(body))))
+		var resp DefinitionApplyResponse
+		_ = json.NewDecoder(rec.Body).Decode(&resp)
+		return rec.Code, resp
+	}
+
+	db, err := r.CreateDatabase(ctx, repository.Database{
+		Name:        "staging",
+		ContentType: "image",
+		NMaxQueued:  7,
+		Config: repository.DatabaseConfig{
+			CreatePreview:    true,
+			AutoConversion:   "image/jpeg",
+			ConversionRules:  []repository.ConversionRule{{From: "image/png", To: "image/jpeg"}},
+			MetadataDefaults: map[string]any{"camera": "front"},
+		},
+		Housekeeping: repository.DatabaseHK{Interval: 90 * time.Minute, DiskSpace: 1536 << 20, MaxAge: 0, DiskSpaceWarnPercent: 70, AgeBasis: repository.AgeBasisIngestion},
+		CustomFields: []repository.CustomFieldDef{
+			{Name: "camera", Type: "TEXT", IsIndexed: true, IsFulltext: true},
+			{Name: "score", Type: "REAL"},
+			{Name: "patient", Type: "TEXT", IsSensitive: true, IsFulltext: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// 1. Export, delete and re-import reproduce the definition
+	def := export("staging")
+	data, _ := json.Marshal(def)
+	for _, runtime := range []string{`"id"`, `"stats"`, `"last_hk_run"`, `"database_id"`} {
+		if strings.Contains(string(data), runtime) {
+			t.Errorf("expected no %s in the definition: %s", runtime, data)
+		}
+	}
+	if def.Version != DefinitionVersion || def.Housekeeping.DiskSpace != "1536M" || def.Housekeeping.MaxAge != "0" || def.Housekeeping.Interval != "1h 30min" {
+		t.Errorf("unexpected definition %s", data)
+	}
+	if err := r.DeleteDatabase(ctx, db.ID); err != nil {
+		t.Fatalf("failed to delete database: %v", err)
+	}
+	if code, resp := apply(def, ""); code != http.StatusCreated || !resp.Created {
+		t.Fatalf("expected the database to be created, got %d %+v", code, resp)
+	}
+	if got := export("staging"); !reflect.DeepEqual(got, def) {
+		t.Errorf("the re-imported definition differs:\ngot  %+v\nwant %+v", got, def)
+	}
+
+	// 2. An existing database is only changed on request, and not at all if it already matches
+	if code, _ := apply(def, ""); code != http.StatusConflict {
+		t.Errorf("expected 409 for an existing database, got %d", code)
+	}
+	if code, resp := apply(def, "?update=true"); code != http.StatusOK || resp.Created || len(resp.Changes) != 0 {
+		t.Errorf("expected no changes, got %d %+v", code, resp)
+	}
+
+	// 3. An update adds fields and replaces config and housekeeping, missing fields are kept
+	target := export("staging")
+	target.CustomFields = slices.DeleteFunc(target.CustomFields, func(cf DatabaseCustomField) bool { return cf.Name == "patient" })
+	indexed := true
+	target.CustomFields[1].IsIndexed = &indexed
+	target.CustomFields = append(target.CustomFields, DatabaseCustomField{Name: "site", Type: "TEXT"})
+	target.Config.CreatePreview = false
+	target.Config.FulltextFields = []string{"site"}
+	target.Housekeeping.MaxAge = "30d"
+	code, resp := apply(target, "?update=true")
+	if code != http.StatusOK {
+		t.Fatalf("expected 200 updating, got %d %+v", code, resp)
+	}
+	wantChanges := []string{
+		"custom field 'score': is_indexed false -> true",
+		"added custom field 'site' (TEXT)",
+		"config.create_preview: true -> false",
+		`config.fulltext_fields: ["camera","patient"] -> ["patient","site"]`,
+		`housekeeping.max_age: "0" -> "30d"`,
+	}
+	if !slices.Equal(resp.Changes, wantChanges) || !slices.Equal(resp.KeptFields, []string{"patient"}) {
+		t.Errorf("unexpected changes %q, kept %q", resp.Changes, resp.KeptFields)
+	}
+	stored, err := r.GetDatabase(ctx, repository.ULID(resp.Database.ID))
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	if stored.Config.CreatePreview || stored.Housekeeping.MaxAge != 30*24*time.Hour || stored.NMaxQueued != 7 || len(stored.CustomFields) != 4 {
+		t.Errorf("the update was not stored: %+v", stored)
+	}
+	if got := fulltextFieldNames(stored.CustomFields); !slices.Equal(got, []string{"patient", "site"}) {
+		t.Errorf("expected the kept and the new full-text field, got %v", got)
+	}
+
+	// 4. An update adds the missing composite indexes, also over the fields it adds
+	indexes := export("staging")
+	indexes.CustomFields = append(indexes.CustomFields, DatabaseCustomField{Name: "lane", Type: "INTEGER"})
+	indexes.CustomIndexes = [][]string{{"site", "timestamp"}, {"lane", "score"}}
+	code, resp = apply(indexes, "?update=true")
+	wantChanges = []string{"added custom field 'lane' (INTEGER)", "added custom index (site, timestamp)", "added custom index (lane, score)"}
+	if code != http.StatusOK || !slices.Equal(resp.Changes, wantChanges) {
+		t.Fatalf("expected the indexes to be added, got %d %+v", code, resp)
+	}
+	if got := export("staging").CustomIndexes; !slices.EqualFunc(got, indexes.CustomIndexes, slices.Equal) {
+		t.Errorf("expected the indexes %v in the definition, got %v", indexes.CustomIndexes, got)
+	}
+	if code, resp := apply(export("staging"), "?update=true"); code != http.StatusOK || len(resp.Changes) != 0 {
+		t.Errorf("expected no changes for existing indexes, got %d %+v", code, resp)
+	}
+
+	// 5. Destructive and invalid definitions change nothing
+	retyped := export("staging")
+	retyped.CustomFields[0].Type = "INTEGER"
+	retyped.Config.CreatePreview = true
+	if code, _ := apply(retyped, "?update=true"); code != http.StatusConflict {
+		t.Errorf("expected 409 for a type change, got %d", code)
+	}
+	moved := export("staging")
+	moved.ContentType = "audio"
+	if code, _ := apply(moved, "?update=true"); code != http.StatusConflict {
+		t.Errorf("expected 409 for another content type, got %d", code)
+	}
+	invalid := export("staging")
+	invalid.CustomFields = append(invalid.CustomFields, DatabaseCustomField{Name: "width", Type: "INTEGER"})
+	if code, _ := apply(invalid, "?update=true"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a field colliding with a media field, got %d", code)
+	}
+	unknown := export("staging")
+	unknown.Config.CreatePreview = true
+	unknown.CustomIndexes = append(unknown.CustomIndexes, []string{"site", "nope"})
+	if code, _ := apply(unknown, "?update=true"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an index over an unknown field, got %d", code)
+	}
+	if after, _ := r.GetDatabase(ctx, stored.ID); after.Config.CreatePreview || len(after.CustomFields) != 5 || len(after.CustomIndexes) != 2 || after.CustomFields[0].Type != "TEXT" {
+		t.Errorf("expected the database to be unchanged, got %+v", after)
+	}
+
+	for body, want := range map[string]int{
+		`{"definition_version": 1, "name": "typo", "content_type": "image", "custom_fields": [{"name": "a", "tpye": "TEXT"}]}`:    http.StatusBadRequest,
+		`{"definition_version": 2, "name": "future", "content_type": "image"}`:                                                    http.StatusBadRequest,
+		`{"definition_version": 1, "name": "bad_type", "content_type": "image", "custom_fields": [{"name": "a", "type": "TXT"}]}`: http.StatusBadRequest,
+	} {
+		if code, _ := apply(body, ""); code != want {
+			t.Errorf("expected %d for %s, got %d", want, body, code)
+		}
+	}
+}
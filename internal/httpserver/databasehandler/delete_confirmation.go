@@ -0,0 +1,83 @@
+package databasehandler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+)
+
+// deleteConfirmationTTL is how long the confirmation token of a database deletion stays valid.
+const deleteConfirmationTTL = 5 * time.Minute
+
+// needsConfirmation reports whether deleting a database with these stats has to be confirmed.
+func (t DeleteThresholds) needsConfirmation(stats repository.DatabaseStats) bool {
+	return (t.Entries > 0 && stats.EntryCount >= t.Entries) ||
+		(t.Bytes > 0 && stats.TotalDiskSpaceBytes >= t.Bytes)
+}
+
+// hashToken returns the SHA-256 hash of a confirmation token as a hex string.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// requestDeleteConfirmation stores a new confirmation token for the deletion of the database and
+// responds with 202, the token and a summary of what would be deleted.
+func (h *DatabaseHandler) requestDeleteConfirmation(w http.ResponseWriter, r *http.Request, db repository.Database, stats repository.DatabaseStats, username string) {
+	ctx := r.Context()
+
+	oldest, newest, err := h.Repo.GetEntryTimeRange(ctx, db.ID)
+	if err != nil {
+		h.Logger.Error("Failed to get the entry time range.", "error", err, "database_id", db.ID)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to summarize the database.")
+		return
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		h.Logger.Error("Failed to generate secure random bytes", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	now := time.Now()
+	expiresAt := now.Add(deleteConfirmationTTL)
+	if err := h.Repo.CreateDeleteConfirmation(ctx, repository.DeleteConfirmation{
+		DatabaseID: db.ID,
+		TokenHash:  hashToken(token),
+		CreatedBy:  username,
+		CreatedAt:  now,
+		ExpiresAt:  expiresAt,
+	}); err != nil {
+		h.Logger.Error("Failed to store the delete confirmation.", "error", err, "database_id", db.ID)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create the confirmation token.")
+		return
+	}
+
+	h.Auditor.Log(ctx, "database.delete_requested", username, db.ID.String(), map[string]any{
+		"name":                   db.Name,
+		"entry_count":            stats.EntryCount,
+		"total_disk_space_bytes": stats.TotalDiskSpaceBytes,
+	})
+
+	resp := DeleteConfirmationResponse{
+		Message:             "The database is large. Repeat the request with confirm_token within " + deleteConfirmationTTL.String() + " to delete it.",
+		ConfirmToken:        token,
+		ExpiresAt:           expiresAt.UnixMilli(),
+		DatabaseName:        db.Name,
+		EntryCount:          stats.EntryCount,
+		TotalDiskSpaceBytes: stats.TotalDiskSpaceBytes,
+	}
+	if !oldest.IsZero() {
+		oldestMs, newestMs := oldest.UnixMilli(), newest.UnixMilli()
+		resp.OldestTimestamp = &oldestMs
+		resp.NewestTimestamp = &newestMs
+	}
+	utils.RespondWithJSON(w, http.StatusAccepted, resp)
+}
@@ -0,0 +1,190 @@
+package databasehandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// Starting a duplicate scan and reading its report needs one of these roles, as the report is meant for the bulk deletion
+const duplicateScanRoles = repository.AccessDelete | repository.AccessAdmin
+
+// @Summary Start a duplicate scan of a database
+// @Description Starts a background job hashing the files of all ready entries of a database to find entries with identical content.
+// @Description Content hashes recorded by the integrity check are reused, missing ones are computed at the read rate of the integrity check and stored.
+// @Description The progress is stored, so a scan interrupted by a restart continues where it stopped. Poll the returned job with GET /database/duplicates?job={id}.
+// @Tags database
+// @Produce json
+// @Param    name  query  string  false  "Database name"
+// @Param    id    query  string  false  "Database ID, instead of the name"
+// @Success 202 {object} DuplicateScanResponse
+// @Failure 400 {object} utils.ErrorResponse "Missing name or id"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 409 {object} utils.ErrorResponse "A duplicate scan of the database is already running"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/duplicates [post]
+func (h *DatabaseHandler) StartDuplicateScan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+
+	name := r.URL.Query().Get("name")
+	id := r.URL.Query().Get("id")
+	if name == "" && id == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required query parameter: name")
+		return
+	}
+
+	db, err := h.findDatabase(ctx, name, id)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to retrieve databases.", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve databases")
+		return
+	}
+
+	// Databases the user has no suitable role on are reported as not found
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	if !holder.IsGlobalAdmin() && !holder.HasPermission(db.ID, duplicateScanRoles) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	scan, err := h.HouseKeeper.StartDuplicateScan(ctx, db, user.Username)
+	if errors.Is(err, customerrors.ErrConflict) {
+		utils.RespondWithError(w, http.StatusConflict, "A duplicate scan of this database is already running.")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to start duplicate scan", "database_id", db.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start the duplicate scan.")
+		return
+	}
+
+	h.Auditor.Log(ctx, "database.duplicates.start", user.Username, db.ID.String(), map[string]any{
+		"name":  db.Name,
+		"scan":  scan.ID.String(),
+		"total": scan.Total,
+	})
+
+	utils.RespondWithJSON(w, http.StatusAccepted, toDuplicateScanResponse(scan, db, nil))
+}
+
+// @Summary Get a duplicate scan
+// @Description Returns the progress of a duplicate scan. Once it is done, the groups of entries sharing a content hash are listed, oldest entry first,
+// @Description with the bytes reclaimable by deleting all but the oldest entry of each group. These entries are listed in `delete_ids`, which can be
+// @Description sent as `ids` to POST /database/{database_id}/entries/delete. Entries deleted since the scan are no longer listed.
+// @Tags database
+// @Produce json
+// @Param    job  query  string  true  "Scan ID, returned when the scan was started"
+// @Success 200 {object} DuplicateScanResponse
+// @Failure 400 {object} utils.ErrorResponse "Missing job"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 404 {object} utils.ErrorResponse "Scan not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/duplicates [get]
+func (h *DatabaseHandler) GetDuplicateScan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	job := r.URL.Query().Get("job")
+	if job == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required query parameter: job")
+		return
+	}
+
+	scan, err := h.Repo.GetDuplicateScan(ctx, repository.ULID(job))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, "Scan not found.")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to get duplicate scan", "scan", job, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get the duplicate scan.")
+		return
+	}
+
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	if !holder.IsGlobalAdmin() && !holder.HasPermission(scan.DatabaseID, duplicateScanRoles) {
+		utils.RespondWithError(w, http.StatusNotFound, "Scan not found.")
+		return
+	}
+
+	db, err := h.Repo.GetDatabase(ctx, scan.DatabaseID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Scan not found.")
+		return
+	}
+
+	var groups []repository.DuplicateGroup
+	if scan.Status == repository.DuplicateScanDone {
+		groups, err = h.Repo.GetDuplicateGroups(ctx, scan.DatabaseID, scan.MaxEntryID)
+		if err != nil {
+			h.Logger.Error("Failed to get duplicate groups", "database_id", scan.DatabaseID, "scan", scan.ID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get the duplicate groups.")
+			return
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, toDuplicateScanResponse(scan, db, groups))
+}
+
+// findDatabase returns the database with the given ID, or the given name if the ID is empty.
+func (h *DatabaseHandler) findDatabase(ctx context.Context, name string, id string) (repository.Database, error) {
+	dbs, err := h.Repo.GetDatabases(ctx)
+	if err != nil {
+		return repository.Database{}, err
+	}
+	for _, db := range dbs {
+		if (id != "" && db.ID.String() == id) || (id == "" && db.Name == name) {
+			return db, nil
+		}
+	}
+	return repository.Database{}, customerrors.ErrNotFound
+}
+
+// toDuplicateScanResponse maps a scan and its groups. The oldest entry of each group is kept, the others count as reclaimable.
+func toDuplicateScanResponse(scan repository.DuplicateScan, db repository.Database, groups []repository.DuplicateGroup) DuplicateScanResponse {
+	resp := DuplicateScanResponse{
+		ID:           scan.ID.String(),
+		DatabaseID:   db.ID.String(),
+		DatabaseName: db.Name,
+		Status:       scan.Status,
+		Total:        scan.Total,
+		Processed:    scan.Processed,
+		Failed:       scan.Failed,
+		Error:        scan.Error,
+		CreatedBy:    scan.CreatedBy,
+		CreatedAt:    scan.CreatedAt.UnixMilli(),
+	}
+	if !scan.FinishedAt.IsZero() {
+		finished := scan.FinishedAt.UnixMilli()
+		resp.FinishedAt = &finished
+	}
+
+	for _, group := range groups {
+		g := DuplicateGroupResponse{ContentHash: group.ContentHash}
+		for i, entry := range group.Entries {
+			g.Entries = append(g.Entries, DuplicateEntryResponse{
+				ID:        entry.ID,
+				Filename:  entry.FileName,
+				Timestamp: entry.Timestamp.UnixMilli(),
+				Filesize:  entry.Size,
+			})
+			if i > 0 {
+				resp.ReclaimableBytes += entry.Size
+				resp.DeleteIDs = append(resp.DeleteIDs, entry.ID)
+			}
+		}
+		resp.Groups = append(resp.Groups, g)
+	}
+	return resp
+}
@@ -0,0 +1,142 @@
+package databasehandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/housekeeping"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestDuplicateScanEndpoints(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	h := &DatabaseHandler{
+		Logger:      logger,
+		Auditor:     audit.NewAlNoopLogger(),
+		Repo:        r,
+		Storage:     store,
+		HouseKeeper: housekeeping.NewHouseKeeper(r, store, logger, time.Hour),
+	}
+
+	db, err := r.CreateDatabase(ctx, repository.Database{Name: "scans", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	var ids []int64
+	for i, content := range []string{"same bytes", "unique", "same bytes", "same bytes"} {
+		entry, err := r.CreateEntry(ctx, db, repository.Entry{FileName: "f.bin", Size: uint64(len(content)), Timestamp: time.UnixMilli(int64(1000 * (i + 1))), Status: repository.EntryStatusReady, MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader(content)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	call := func(handler http.HandlerFunc, method, target string, holder utils.PermissionHolder) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "admin"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, holder))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	admin := &utils.GlobalAdmin{}
+	viewer := &utils.APIKeyOfAdmin{Scope: repository.AccessView, Repo: r}
+
+	// 1. Viewers cannot start a scan, the database is unknown to them
+	if rec := call(h.StartDuplicateScan, http.MethodPost, "/api/database/duplicates?name=scans", viewer); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a viewer, got %d", rec.Code)
+	}
+
+	// 2. A second scan conflicts with the running one
+	if _, err := r.CreateDuplicateScan(ctx, repository.DuplicateScan{DatabaseID: db.ID, CreatedBy: "other"}); err != nil {
+		t.Fatalf("failed to create scan: %v", err)
+	}
+	if rec := call(h.StartDuplicateScan, http.MethodPost, "/api/database/duplicates?name=scans", admin); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 while a scan is running, got %d: %s", rec.Code, rec.Body.String())
+	}
+	h.HouseKeeper.ResumeDuplicateScans(ctx)
+
+	// 3. The scan runs in the background
+	rec := call(h.StartDuplicateScan, http.MethodPost, "/api/database/duplicates?name=scans", admin)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var started DuplicateScanResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if started.Status != repository.DuplicateScanRunning || started.Total != 4 || started.DatabaseName != "scans" {
+		t.Errorf("unexpected scan: %+v", started)
+	}
+
+	var report DuplicateScanResponse
+	deadline := time.Now().Add(5 * time.Second)
+	for report.Status != repository.DuplicateScanDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("scan did not finish: %+v", report)
+		}
+		time.Sleep(10 * time.Millisecond)
+		rec := call(h.GetDuplicateScan, http.MethodGet, "/api/database/duplicates?job="+started.ID, admin)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+	}
+
+	// 4. All but the oldest copy can be passed to the bulk deletion
+	if len(report.Groups) != 1 || len(report.Groups[0].Entries) != 3 || report.Groups[0].Entries[0].ID != ids[0] {
+		t.Errorf("expected a single group of three entries, got %+v", report.Groups)
+	}
+	if want := []int64{ids[2], ids[3]}; !slices.Equal(report.DeleteIDs, want) {
+		t.Errorf("expected delete_ids %v, got %v", want, report.DeleteIDs)
+	}
+	if report.ReclaimableBytes != 2*uint64(len("same bytes")) || report.Processed != 4 || report.FinishedAt == nil {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	// 5. The report is hidden from viewers, unknown scans are not found
+	if rec := call(h.GetDuplicateScan, http.MethodGet, "/api/database/duplicates?job="+started.ID, viewer); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a viewer, got %d", rec.Code)
+	}
+	if rec := call(h.GetDuplicateScan, http.MethodGet, "/api/database/duplicates?job=unknown", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown scan, got %d", rec.Code)
+	}
+}
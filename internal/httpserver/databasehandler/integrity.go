@@ -0,0 +1,60 @@
+package databasehandler
+
+import (
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+)
+
+// @Summary Get the integrity summary of a database
+// @Description Reports how many stored files were verified against their content hash, how many are not verified yet and how many were found corrupted.
+// @Tags database
+// @Produce json
+// @Param    database_id path string true "Database ID"
+// @Success 200 {object} IntegrityResponse
+// @Failure 400 {object} utils.ErrorResponse "Missing database_id path parameter"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/integrity [get]
+func (h *DatabaseHandler) GetIntegrity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+
+	id := r.PathValue("database_id")
+	if id == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required path parameter: database_id")
+		return
+	}
+
+	db, err := h.Repo.GetDatabase(ctx, repository.ULID(id))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	stats, err := h.Repo.GetIntegrityStats(ctx, db.ID)
+	if err != nil {
+		h.Logger.Error("Failed to get integrity stats", "database_id", db.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get the integrity summary.")
+		return
+	}
+
+	h.Auditor.Log(ctx, "database.integrity", user.Username, id, map[string]any{"name": db.Name})
+
+	resp := IntegrityResponse{
+		DatabaseID:   id,
+		DatabaseName: db.Name,
+		Verified:     stats.Verified,
+		Unverified:   stats.Unverified,
+		Corrupted:    stats.Corrupted,
+	}
+	if !stats.OldestVerification.IsZero() {
+		oldest := stats.OldestVerification.UnixMilli()
+		resp.OldestVerifiedAt = &oldest
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
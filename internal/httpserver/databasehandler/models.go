@@ -4,14 +4,28 @@ import (
 	"log/slog"
 	"mediahub_oss/internal/housekeeping"
 	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/media"
 	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/storage"
 )
 
 type DatabaseHandler struct {
-	Logger      *slog.Logger
-	Auditor     audit.AuditLogger
-	Repo        repository.Repository
-	HouseKeeper housekeeping.HouseKeeper
+	Logger         *slog.Logger
+	Auditor        audit.AuditLogger
+	Repo           repository.Repository
+	HouseKeeper    *housekeeping.HouseKeeper
+	MediaConverter media.MediaConverter
+	Storage        storage.StorageProvider
+
+	// Deleting a database at or above one of the thresholds needs a confirmation token
+	ConfirmDelete DeleteThresholds
+}
+
+// DeleteThresholds are the entry count and the size in bytes from which on deleting a database
+// has to be confirmed, 0 disables the respective threshold.
+type DeleteThresholds struct {
+	Entries uint64
+	Bytes   uint64
 }
 
 // DatabaseCreatePayload defines the required JSON payload for POST /api/database.
@@ -22,6 +36,24 @@ type DatabaseCreatePayload struct {
 	Config       ConfigPayload         `json:"config"`
 	Housekeeping HousekeepingPayload   `json:"housekeeping"`
 	CustomFields []DatabaseCustomField `json:"custom_fields"`
+
+	// Composite indexes, each a list of standard, media or custom field names, e.g. ["status", "timestamp"]
+	CustomIndexes [][]string `json:"custom_indexes,omitempty"`
+}
+
+// DatabaseDefinition is the portable definition of a database returned by GET /api/database/definition
+// and applied by POST /api/database/definition: the create payload without IDs, statistics or runtime state.
+type DatabaseDefinition struct {
+	Version int `json:"definition_version"` // format of the document, see DefinitionVersion
+	DatabaseCreatePayload
+}
+
+// DefinitionApplyResponse defines the JSON payload returned after applying a definition.
+type DefinitionApplyResponse struct {
+	Database   DatabaseResponse `json:"database"`
+	Created    bool             `json:"created"`
+	Changes    []string         `json:"changes"`     // applied differences, e.g. "added custom field 'camera' (TEXT)"
+	KeptFields []string         `json:"kept_fields"` // custom fields missing from the definition, they are never removed
 }
 
 type DatabaseCustomField struct {
@@ -29,9 +61,12 @@ type DatabaseCustomField struct {
 	Name      string `json:"name"`
 	Type      string `json:"type"`
 	IsIndexed *bool  `json:"is_indexed,omitempty"`
+	// IsSensitive hides the field from users who may only view entries.
+	IsSensitive *bool `json:"is_sensitive,omitempty"`
 }
 
-// DatabaseUpdatePayload defines the required JSON payload for PUT /api/database.
+// DatabaseUpdatePayload documents the JSON payload for PUT /api/database. All keys are optional,
+// the body is merged onto the current settings (see applyUpdate).
 type DatabaseUpdatePayload struct {
 	Name         string              `json:"name"`
 	NMaxQueued   int                 `json:"n_max_queued"`
@@ -41,16 +76,50 @@ type DatabaseUpdatePayload struct {
 
 // ConfigPayload defines the JSON structure for type-specific settings.
 type ConfigPayload struct {
-	CreatePreview  bool   `json:"create_preview"`
-	AutoConversion string `json:"auto_conversion"`
+	CreatePreview    bool   `json:"create_preview"`
+	AutoConversion   string `json:"auto_conversion"`
+	KeepOriginal     bool   `json:"keep_original"`      // keep the uploaded file next to the auto converted one
+	UniqueExternalID bool   `json:"unique_external_id"` // reject uploads and updates reusing an external_id
+	PublicRead       bool   `json:"public_read"`        // entries can be read without authentication, only global admins can change it
+
+	// Per-mime conversions, e.g. [{"from": "audio/wav", "to": "audio/flac"}], they take precedence over auto_conversion
+	ConversionRules []repository.ConversionRule `json:"conversion_rules"`
+
+	// Transcription of audio entries, e.g. {"endpoint": "http://whisper:8000", "model": "whisper-1", "field": "transcript"}, omitted if disabled
+	Transcription *repository.TranscriptionConfig `json:"transcription,omitempty"`
+
+	// TEXT custom fields kept in a full-text index and searchable with the MATCH operator, e.g. ["description", "notes"]
+	FulltextFields []string `json:"fulltext_fields"`
+
+	// REAL custom fields holding the coordinates of the entries, e.g. {"lat": "lat", "lon": "lon"}, for geo searches; omitted if not declared
+	GeoFields *repository.GeoFields `json:"geo_fields,omitempty"`
+
+	// Custom field values filled into uploads whose metadata lacks them, e.g. {"site": "north"}
+	MetadataDefaults map[string]any `json:"metadata_defaults"`
+	// Custom field values set on every upload, replacing those of the client
+	MetadataOverrides map[string]any `json:"metadata_overrides"`
+
+	// Waveform previews of audio entries: size in pixels (default 200x120, 16 to 2000), colors as hex like "#1e90ff",
+	// the background may also be "transparent", which stores the previews as PNG; omitted values select the defaults
+	WaveformWidth      int    `json:"waveform_width,omitempty"`
+	WaveformHeight     int    `json:"waveform_height,omitempty"`
+	WaveformColor      string `json:"waveform_color,omitempty"`
+	WaveformBackground string `json:"waveform_background,omitempty"`
 }
 
 // HousekeepingPayload defines the JSON structure for housekeeping rules.
 // These are strings in the API but converted to uint64 for the DB.
+// "0" or "disabled" switches a rule off, an empty string applies the default.
 type HousekeepingPayload struct {
 	Interval  string `json:"interval"`
 	DiskSpace string `json:"disk_space"`
 	MaxAge    string `json:"max_age"`
+
+	// The time max_age is measured from: "capture" (the entry timestamp, default) or "ingestion" (created_at)
+	AgeBasis string `json:"age_basis"`
+
+	// An alert is sent once the usage reaches this percentage of disk_space (default 85), 0 disables it
+	DiskSpaceWarnPercent *int `json:"disk_space_warn_percent"`
 }
 
 // HousekeepingResponse defines the JSON payload returned after triggering housekeeping.
@@ -58,20 +127,113 @@ type HousekeepingResponse struct {
 	DatabaseID      string `json:"database_id"`
 	DatabaseName    string `json:"database_name"`
 	EntriesDeleted  int    `json:"entries_deleted"`
+	EntriesSkipped  int    `json:"entries_skipped"` // due entries left alone because they were still being processed
+	EntriesHeld     int    `json:"entries_held"`    // due entries left alone because they are under legal hold
 	SpaceFreedBytes uint64 `json:"space_freed_bytes"`
+	PagesReclaimed  int64  `json:"pages_reclaimed"` // pages of the database file released after runs above database.vacuum_threshold
 	Message         string `json:"message"`
 }
 
+// IntegrityResponse summarizes the verification of the stored files of a database.
+type IntegrityResponse struct {
+	DatabaseID       string `json:"database_id"`
+	DatabaseName     string `json:"database_name"`
+	Verified         uint64 `json:"verified"`                     // ready entries whose file was verified at least once
+	Unverified       uint64 `json:"unverified"`                   // ready entries whose file was never verified
+	Corrupted        uint64 `json:"corrupted"`                    // entries set to error because their file changed or is missing
+	OldestVerifiedAt *int64 `json:"oldest_verified_at,omitempty"` // Unix milliseconds, least recent check of a verified entry
+}
+
+// DuplicateScanResponse reports the progress of a duplicate scan and, once it is done, the groups of
+// entries with identical files.
+type DuplicateScanResponse struct {
+	ID               string                   `json:"id"`
+	DatabaseID       string                   `json:"database_id"`
+	DatabaseName     string                   `json:"database_name"`
+	Status           string                   `json:"status"` // running, done or failed
+	Total            int64                    `json:"total"`  // ready entries at the start of the scan
+	Processed        int64                    `json:"processed"`
+	Failed           int64                    `json:"failed"` // entries whose file could not be read, they are not part of any group
+	Error            string                   `json:"error,omitempty"`
+	CreatedBy        string                   `json:"created_by"`
+	CreatedAt        int64                    `json:"created_at"`            // Unix milliseconds
+	FinishedAt       *int64                   `json:"finished_at,omitempty"` // Unix milliseconds
+	Groups           []DuplicateGroupResponse `json:"groups,omitempty"`
+	ReclaimableBytes uint64                   `json:"reclaimable_bytes"`    // freed if all but the oldest entry of each group were deleted
+	DeleteIDs        []int64                  `json:"delete_ids,omitempty"` // all but the oldest entry of each group, the ids of POST /database/{database_id}/entries/delete
+}
+
+// DuplicateGroupResponse lists the entries sharing a content hash, oldest first.
+type DuplicateGroupResponse struct {
+	ContentHash string                   `json:"content_hash"`
+	Entries     []DuplicateEntryResponse `json:"entries"`
+}
+
+type DuplicateEntryResponse struct {
+	ID        int64  `json:"id"`
+	Filename  string `json:"filename"`
+	Timestamp int64  `json:"timestamp"` // Unix milliseconds
+	Filesize  uint64 `json:"filesize"`
+}
+
+// RetentionSimulationResponse projects what housekeeping would delete under the simulated rules.
+// Nothing is deleted by the simulation.
+type RetentionSimulationResponse struct {
+	DatabaseID   string                     `json:"database_id"`
+	DatabaseName string                     `json:"database_name"`
+	Housekeeping DatabaseResponseHK         `json:"housekeeping"` // the simulated rules
+	Now          int64                      `json:"now"`          // Unix milliseconds, database time the projection starts at
+	MaxAge       RetentionMaxAgeResponse    `json:"max_age"`
+	DiskSpace    RetentionDiskSpaceResponse `json:"disk_space"`
+	Forecast     []RetentionForecastWeek    `json:"forecast"` // what max_age deletes of the existing entries in each following week
+}
+
+// RetentionMaxAgeResponse is the immediate effect of the max_age rule.
+type RetentionMaxAgeResponse struct {
+	Cutoff  *int64 `json:"cutoff,omitempty"` // Unix milliseconds, entries up to it are due, missing if the rule is disabled
+	Entries int64  `json:"entries"`
+	Bytes   uint64 `json:"bytes"`
+	Held    int64  `json:"held"` // due entries that are kept because they are under legal hold
+}
+
+// RetentionDiskSpaceResponse is the immediate effect of the disk_space rule, after the max_age rule.
+type RetentionDiskSpaceResponse struct {
+	UsedBytes  uint64 `json:"used_bytes"` // according to the database stats
+	Entries    int64  `json:"entries"`    // deleted in addition to the max_age rule, oldest first
+	Bytes      uint64 `json:"bytes"`
+	AfterBytes uint64 `json:"after_bytes"` // usage after both rules
+}
+
+type RetentionForecastWeek struct {
+	Start   int64  `json:"start"` // Unix milliseconds
+	Entries int64  `json:"entries"`
+	Bytes   uint64 `json:"bytes"`
+}
+
+// DeleteConfirmationResponse is returned by the first call of DELETE /api/database/{database_id} for
+// large databases. Repeating the call with confirm_token deletes the database.
+type DeleteConfirmationResponse struct {
+	Message             string `json:"message"`
+	ConfirmToken        string `json:"confirm_token"`
+	ExpiresAt           int64  `json:"expires_at"` // Unix milliseconds
+	DatabaseName        string `json:"database_name"`
+	EntryCount          uint64 `json:"entry_count"`
+	TotalDiskSpaceBytes uint64 `json:"total_disk_space_bytes"`
+	OldestTimestamp     *int64 `json:"oldest_timestamp,omitempty"` // Unix milliseconds
+	NewestTimestamp     *int64 `json:"newest_timestamp,omitempty"` // Unix milliseconds
+}
+
 // DatabaseResponse defines the JSON structure for outbound database data.
 type DatabaseResponse struct {
-	ID           string                `json:"id"`
-	Name         string                `json:"name"`
-	ContentType  string                `json:"content_type"`
-	NMaxQueued   int                   `json:"n_max_queued"`
-	Config       ConfigPayload         `json:"config"`
-	Housekeeping DatabaseResponseHK    `json:"housekeeping"`
-	CustomFields []DatabaseCustomField `json:"custom_fields"`
-	Stats        DatabaseResponseStats `json:"stats,omitempty"`
+	ID            string                `json:"id"`
+	Name          string                `json:"name"`
+	ContentType   string                `json:"content_type"`
+	NMaxQueued    int                   `json:"n_max_queued"`
+	Config        ConfigPayload         `json:"config"`
+	Housekeeping  DatabaseResponseHK    `json:"housekeeping"`
+	CustomFields  []DatabaseCustomField `json:"custom_fields"`
+	CustomIndexes [][]string            `json:"custom_indexes"`
+	Stats         DatabaseResponseStats `json:"stats,omitempty"`
 }
 
 // Using explicit types to send to the frontend
@@ -79,9 +241,29 @@ type DatabaseResponseHK struct {
 	Interval  string `json:"interval"`   // e.g."10min"
 	DiskSpace string `json:"disk_space"` // e.g. "10G"
 	MaxAge    string `json:"max_age"`    // e.g. "365d"
+	AgeBasis  string `json:"age_basis"`  // "capture" or "ingestion"
+
+	// The values as understood by the server, 0 if the rule is disabled
+	IntervalSeconds int64  `json:"interval_seconds"`
+	DiskSpaceBytes  uint64 `json:"disk_space_bytes"`
+	MaxAgeSeconds   int64  `json:"max_age_seconds"`
+
+	DiskSpaceWarnPercent int `json:"disk_space_warn_percent"` // 0 if disk space alerts are disabled
 }
 
 type DatabaseResponseStats struct {
-	EntryCount          uint64 `json:"entry_count"`
-	TotalDiskSpaceBytes uint64 `json:"total_disk_space_bytes"`
+	EntryCount          uint64   `json:"entry_count"` // all entries, including processing_count and failed ones
+	TotalDiskSpaceBytes uint64   `json:"total_disk_space_bytes"`
+	ProcessingCount     uint64   `json:"processing_count"` // queued or processing, their sizes are added once processing finished
+	UsagePercent        *float64 `json:"usage_percent"`    // of the housekeeping disk_space, null if it is disabled
+	AlertActive         bool     `json:"alert_active"`     // the disk space warning was sent and usage has not dropped below the threshold since
+}
+
+// ActivityEventResponse is an event of the activity feed of a database, normalized from the audit log.
+type ActivityEventResponse struct {
+	Time    int64          `json:"time"` // Unix milliseconds
+	Kind    string         `json:"kind"` // upload, delete, update, housekeeping, config, export, import or alert
+	Actor   string         `json:"actor"`
+	Summary string         `json:"summary"`
+	Details map[string]any `json:"details"` // as audited, plus entry_id for events of a single entry
 }
@@ -0,0 +1,129 @@
+package databasehandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"mediahub_oss/internal/housekeeping"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Simulate the housekeeping rules of a database
+// @Description Projects what housekeeping would delete, without deleting anything: the entries and bytes due now under `max_age`,
+// @Description what `disk_space` deletes in addition given the current stats, and what `max_age` deletes of the existing entries in each of the next weeks.
+// @Description The body holds hypothetical housekeeping rules in the format of PUT /database/{database_id}, omitted rules (or no body) keep their current value.
+// @Description The simulation uses the cutoff and the selection of the real cleanup: entries still being processed or under legal hold are not deleted.
+// @Description Requires the CanView and CanDelete roles, as it reveals the retention impact.
+// @Tags database
+// @Accept   json
+// @Produce  json
+// @Param    name   query  string               false  "Database name"
+// @Param    id     query  string               false  "Database ID, instead of the name"
+// @Param    weeks  query  int                  false  "Number of forecast weeks (default 12, max 104)"
+// @Param    rules  body   HousekeepingPayload  false  "Hypothetical housekeeping rules"
+// @Success 200 {object} RetentionSimulationResponse
+// @Failure 400 {object} utils.ErrorResponse "Missing name or id, invalid weeks or invalid housekeeping values"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView and CanDelete roles)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/housekeeping/simulate [post]
+func (h *DatabaseHandler) SimulateHousekeeping(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	name := query.Get("name")
+	id := query.Get("id")
+	if name == "" && id == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required query parameter: name")
+		return
+	}
+	weeks, err := parseOptionalInt(query.Get("weeks"))
+	if err != nil || weeks < 0 || weeks > housekeeping.MaxForecastWeeks {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid weeks: must be a number between 1 and %d", housekeeping.MaxForecastWeeks))
+		return
+	}
+	if weeks == 0 {
+		weeks = housekeeping.DefaultForecastWeeks
+	}
+
+	var rules json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	// 1. Resolve the database, databases the user cannot view are reported as not found
+	db, err := h.findDatabase(ctx, name, id)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to retrieve databases.", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve databases")
+		return
+	}
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	if !holder.IsGlobalAdmin() {
+		if !holder.HasPermission(db.ID, repository.AccessView) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+			return
+		}
+		if !holder.HasPermission(db.ID, repository.AccessDelete) {
+			utils.RespondWithError(w, http.StatusForbidden, "Forbidden: simulating housekeeping requires the CanView and CanDelete roles")
+			return
+		}
+	}
+
+	// 2. Merge the hypothetical rules onto the current ones, like an update of the database would
+	simulated := db
+	if len(rules) > 0 && !isNull(rules) {
+		if simulated, err = applyUpdate(db, map[string]json.RawMessage{"housekeeping": rules}); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// 3. Project the rules, nothing is deleted
+	sim, err := h.HouseKeeper.SimulateRetention(ctx, db, simulated.Housekeeping, weeks)
+	if err != nil {
+		h.Logger.Error("Housekeeping simulation failed", "error", err, "database_id", db.ID, "database_name", db.Name)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to simulate housekeeping.")
+		return
+	}
+
+	resp := RetentionSimulationResponse{
+		DatabaseID:   db.ID.String(),
+		DatabaseName: db.Name,
+		Housekeeping: mapToDatabaseResponse(simulated).Housekeeping,
+		Now:          sim.Now.UnixMilli(),
+		MaxAge: RetentionMaxAgeResponse{
+			Entries: sim.MaxAgeEntries,
+			Bytes:   sim.MaxAgeBytes,
+			Held:    sim.MaxAgeHeld,
+		},
+		DiskSpace: RetentionDiskSpaceResponse{
+			UsedBytes:  sim.DiskSpaceUsed,
+			Entries:    sim.DiskSpaceEntries,
+			Bytes:      sim.DiskSpaceBytes,
+			AfterBytes: sim.DiskSpaceAfter,
+		},
+		Forecast: make([]RetentionForecastWeek, len(sim.Forecast)),
+	}
+	if !sim.Cutoff.IsZero() {
+		cutoff := sim.Cutoff.UnixMilli()
+		resp.MaxAge.Cutoff = &cutoff
+	}
+	for i, week := range sim.Forecast {
+		resp.Forecast[i] = RetentionForecastWeek{Start: week.Start.UnixMilli(), Entries: week.Entries, Bytes: week.Bytes}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,127 @@
+package databasehandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/housekeeping"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestSimulateHousekeeping(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	h := &DatabaseHandler{
+		Logger:      logger,
+		Auditor:     audit.NewAlNoopLogger(),
+		Repo:        r,
+		Storage:     store,
+		HouseKeeper: housekeeping.NewHouseKeeper(r, store, logger, time.Hour),
+	}
+
+	day := 24 * time.Hour
+	db, err := r.CreateDatabase(ctx, repository.Database{Name: "retention", ContentType: "file", Housekeeping: repository.DatabaseHK{MaxAge: 365 * day}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for _, e := range []struct {
+		age  time.Duration
+		size uint64
+	}{{100 * day, 10}, {40 * day, 20}} {
+		if _, err := r.CreateEntry(ctx, db, repository.Entry{FileName: "f.bin", Size: e.size, Timestamp: time.Now().Add(-e.age), Status: repository.EntryStatusReady, MimeType: "application/octet-stream"}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+	}
+
+	call := func(target, body string, holder utils.PermissionHolder) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repository.User{Username: "owner"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, holder))
+		rec := httptest.NewRecorder()
+		h.SimulateHousekeeping(rec, req)
+		return rec
+	}
+	simulate := func(target, body string) RetentionSimulationResponse {
+		t.Helper()
+		rec := call(target, body, &utils.APIKeyOfAdmin{Scope: repository.AccessView | repository.AccessDelete})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp RetentionSimulationResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// 1. Both roles are required, the database is unknown to those who cannot view it
+	if rec := call("/api/database/housekeeping/simulate?name=retention", "", &utils.APIKeyOfAdmin{Scope: repository.AccessCreate}); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without CanView, got %d", rec.Code)
+	}
+	if rec := call("/api/database/housekeeping/simulate?name=retention", "", &utils.APIKeyOfAdmin{Scope: repository.AccessView}); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without CanDelete, got %d", rec.Code)
+	}
+
+	// 2. Without a body the current rules are simulated, nothing is due within 365 days
+	current := simulate("/api/database/housekeeping/simulate?name=retention", "")
+	if current.Housekeeping.MaxAge != "365d" || current.MaxAge.Entries != 0 || len(current.Forecast) != housekeeping.DefaultForecastWeeks {
+		t.Errorf("unexpected simulation of the current rules: %+v", current)
+	}
+
+	// 3. Shortening max_age to 90 days deletes the older entry now and the other one in the 8th week
+	proposed := simulate("/api/database/housekeeping/simulate?name=retention&weeks=8", `{"max_age": "90d"}`)
+	if proposed.Housekeeping.MaxAge != "90d" || proposed.MaxAge.Entries != 1 || proposed.MaxAge.Bytes != 10 || proposed.MaxAge.Cutoff == nil {
+		t.Errorf("expected 1 entry of 10 bytes due under 90d, got %+v", proposed)
+	}
+	if len(proposed.Forecast) != 8 || proposed.Forecast[7].Entries != 1 || proposed.Forecast[7].Bytes != 20 {
+		t.Errorf("expected the other entry to be due in the 8th week, got %+v", proposed.Forecast)
+	}
+	if proposed.DiskSpace.UsedBytes != 30 || proposed.DiskSpace.Entries != 0 || proposed.DiskSpace.AfterBytes != 20 {
+		t.Errorf("expected the disabled disk_space rule to delete nothing, got %+v", proposed.DiskSpace)
+	}
+	if entries, _ := r.GetEntries(ctx, db.ID, repository.QueryOptions{}); len(entries) != 2 {
+		t.Errorf("expected the simulation to keep both entries, got %d", len(entries))
+	}
+
+	// 4. Invalid rules and forecast lengths are rejected
+	for target, body := range map[string]string{
+		"/api/database/housekeeping/simulate?name=retention":           `{"max_age": "soon"}`,
+		"/api/database/housekeeping/simulate?name=retention&weeks=500": "",
+		"/api/database/housekeeping/simulate":                          "",
+	} {
+		if rec := call(target, body, &utils.GlobalAdmin{}); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected 400, got %d", target, body, rec.Code)
+		}
+	}
+}
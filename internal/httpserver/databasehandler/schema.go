@@ -0,0 +1,273 @@
+package databasehandler
+
+import (
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/repository"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is the subset of JSON Schema (draft 2020-12, as used by OpenAPI 3.1 components)
+// needed to describe the entries of a database.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"` // a type name, or a list of them for nullable values
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Examples             []any                  `json:"examples,omitempty"`
+
+	// Extensions for client generators
+	DatabaseID      string   `json:"x-database-id,omitempty"`
+	ContentType     string   `json:"x-content-type,omitempty"`
+	SearchOperators []string `json:"x-search-operators,omitempty"` // the operators accepted by the search for this field
+	Indexed         *bool    `json:"x-indexed,omitempty"`
+}
+
+// @Summary Get the entry schema of a database
+// @Description Returns a JSON Schema (draft 2020-12, usable as OpenAPI 3.1 component) of the entry object of a database:
+// @Description the standard fields, the media fields of its content type and its custom fields, each with the allowed
+// @Description search operators (`x-search-operators`), and an example entry. Sensitive custom fields are only
+// @Description included for users who may see them.
+// @Tags database
+// @Produce  json
+// @Param    database_id  path  string  true  "Database ID"
+// @Success 200 {object} JSONSchema
+// @Failure 400 {object} utils.ErrorResponse "Missing database_id path parameter"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires any role on the database)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Security BasicAuth
+// @Router /database/{database_id}/schema [get]
+func (h *DatabaseHandler) GetEntrySchema(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	dbID := r.PathValue("database_id")
+	if dbID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required path parameter: database_id")
+		return
+	}
+
+	db, err := h.Repo.GetDatabase(ctx, repository.ULID(dbID))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	// Any role passes the router, sensitive fields need CanEdit or DB Admin
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	showSensitive := holder.IsGlobalAdmin() || holder.HasPermission(db.ID, repository.AccessEdit|repository.AccessAdmin)
+	utils.RespondWithJSON(w, http.StatusOK, buildEntrySchema(db, showSensitive))
+}
+
+// buildEntrySchema describes the entry object returned for a database, matching the entry handler's EntryResponse.
+func buildEntrySchema(db repository.Database, showSensitive bool) *JSONSchema {
+	props := map[string]*JSONSchema{
+		"database_id":        {Type: "string", Description: "ULID of the database"},
+		"id":                 standardField("integer", "id", "Entry ID, unique within the database"),
+		"external_id":        standardField("string", "external_id", "Identifier assigned by the client, omitted if none"),
+		"legal_hold":         standardField("boolean", "legal_hold", "The entry is preserved for compliance and cannot be deleted until released"),
+		"filename":           standardField("string", "filename", ""),
+		"filesize":           standardField("integer", "filesize", "Size of the stored file in bytes"),
+		"content_version":    {Type: "string", Description: "Version of the stored file, changes whenever processing writes it. Sent as X-Content-Version and accepted as 'version' by the file download"},
+		"preview_filesize":   standardField("integer", "preview_filesize", "Size of the preview in bytes, 0 without preview"),
+		"original_filesize":  {Type: "integer", Minimum: ptr(0.0), Description: "Size of the kept original of a converted upload, omitted if none"},
+		"original_mime_type": {Type: "string", Description: "MIME type of the kept original, omitted if none"},
+		"status":             {Type: "string", Enum: entryStatuses(), Description: "Processing status. Filters compare the numeric status", SearchOperators: repository.OperatorsForType(repository.StandardFieldTypes["status"])},
+		"error_reason":       {Type: "string", Description: "Why processing failed, omitted if it did not"},
+		"error_detail":       {Type: "string", Description: "End of the output of the media tool that failed, omitted if there is none"},
+		"timestamp":          standardField("integer", "timestamp", "Unix time in milliseconds"),
+		"client_timestamp":   standardField("integer", "client_timestamp", "Timestamp sent by the client if it was out of bounds and replaced by the server time, omitted otherwise"),
+		"created_at":         standardField("integer", "created_at", "Unix time in milliseconds"),
+		"updated_at":         standardField("integer", "updated_at", "Unix time in milliseconds"),
+		"mime_type":          standardField("string", "mime_type", ""),
+		"uploaded_by":        nullable(standardField("string", "uploaded_by", "Username of the uploader, null for entries uploaded before it was recorded")),
+		"upload_source": {
+			Type:        []string{"object", "null"},
+			Description: "Where the entry was uploaded from, null for entries uploaded before it was recorded",
+			Properties: map[string]*JSONSchema{
+				"ip":         {Type: "string", SearchOperators: repository.OperatorsForType(repository.StandardFieldTypes["upload_ip"]), Description: "Searchable as upload_ip"},
+				"user_agent": {Type: "string", SearchOperators: repository.OperatorsForType(repository.StandardFieldTypes["upload_user_agent"]), Description: "Searchable as upload_user_agent"},
+			},
+		},
+		"media_fields":  mediaFieldsSchema(db.ContentType),
+		"custom_fields": customFieldsSchema(db.CustomFields, showSensitive),
+	}
+	if db.ContentType == "audio" {
+		props["transcription_status"] = &JSONSchema{
+			Type:            "string",
+			Enum:            []string{repository.TranscriptionPending, repository.TranscriptionDone, repository.TranscriptionFailed},
+			Description:     "Status of the transcription, omitted if the entry is not transcribed",
+			SearchOperators: repository.OperatorsForType(repository.StandardFieldTypes["transcription_status"]),
+		}
+	}
+	props["external_id"].MaxLength = ptr(255)
+	for _, name := range []string{"id", "filesize", "preview_filesize"} {
+		props[name].Minimum = ptr(0.0)
+	}
+
+	return &JSONSchema{
+		Schema:      jsonSchemaDialect,
+		Title:       db.Name,
+		Description: "Entry of the " + db.ContentType + " database " + db.Name,
+		Type:        "object",
+		Properties:  props,
+		Required: []string{
+			"database_id", "id", "filename", "filesize", "content_version", "preview_filesize", "status",
+			"timestamp", "created_at", "updated_at", "mime_type", "media_fields", "custom_fields",
+		},
+		Examples:    []any{exampleEntry(db, showSensitive)},
+		DatabaseID:  db.ID.String(),
+		ContentType: db.ContentType,
+	}
+}
+
+// mediaFieldsSchema describes the media fields extracted for a content type.
+func mediaFieldsSchema(contentType string) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}, AdditionalProperties: ptr(false)}
+	fields, _ := media.GetMetadataFields(contentType)
+	for _, f := range fields {
+		sqlType := repository.MediaFieldType(f.Type)
+		field := &JSONSchema{Type: jsonTypeOf(sqlType), SearchOperators: repository.OperatorsForType(sqlType)}
+		if f.Type == "uint8" || f.Type == "uint64" {
+			field.Minimum = ptr(0.0)
+		}
+		schema.Properties[f.Name] = field
+	}
+	return schema
+}
+
+// customFieldsSchema describes the custom fields, values that were never set are null.
+func customFieldsSchema(fields []repository.CustomFieldDef, showSensitive bool) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}, AdditionalProperties: ptr(false)}
+	for _, cf := range fields {
+		if cf.IsSensitive && !showSensitive {
+			continue
+		}
+		schema.Properties[cf.Name] = &JSONSchema{
+			Type:            []string{jsonTypeOf(cf.Type), "null"},
+			SearchOperators: repository.CustomFieldOperators(cf),
+			Indexed:         ptr(cf.IsIndexed),
+		}
+	}
+	return schema
+}
+
+// exampleEntry returns an entry as the API would return it for the database.
+func exampleEntry(db repository.Database, showSensitive bool) map[string]any {
+	mediaFields := map[string]any{}
+	fields, _ := media.GetMetadataFields(db.ContentType)
+	for _, f := range fields {
+		switch f.Name {
+		case "width":
+			mediaFields[f.Name] = 1920
+		case "height":
+			mediaFields[f.Name] = 1080
+		case "duration":
+			mediaFields[f.Name] = 12.5
+		case "channels":
+			mediaFields[f.Name] = 2
+		default:
+			mediaFields[f.Name] = exampleValue(repository.MediaFieldType(f.Type))
+		}
+	}
+
+	customFields := map[string]any{}
+	for _, cf := range db.CustomFields {
+		if cf.IsSensitive && !showSensitive {
+			continue
+		}
+		customFields[cf.Name] = exampleValue(cf.Type)
+	}
+
+	mimeType, fileName := "application/octet-stream", "example.bin"
+	switch db.ContentType {
+	case "image":
+		mimeType, fileName = "image/jpeg", "example.jpg"
+	case "audio":
+		mimeType, fileName = "audio/flac", "example.flac"
+	case "video":
+		mimeType, fileName = "video/mp4", "example.mp4"
+	}
+
+	return map[string]any{
+		"database_id":      db.ID.String(),
+		"id":               1,
+		"filename":         fileName,
+		"filesize":         1048576,
+		"content_version":  "3f2a9c1e5b7d4a60",
+		"preview_filesize": 20480,
+		"status":           repository.GetEntryStatusString(repository.EntryStatusReady),
+		"timestamp":        1700000000000,
+		"created_at":       1700000000000,
+		"updated_at":       1700000000000,
+		"mime_type":        mimeType,
+		"media_fields":     mediaFields,
+		"custom_fields":    customFields,
+		"uploaded_by":      "admin",
+		"upload_source":    map[string]any{"ip": "192.0.2.10", "user_agent": "curl/8.5.0"},
+	}
+}
+
+// standardField describes a searchable standard field.
+func standardField(jsonType string, name string, description string) *JSONSchema {
+	return &JSONSchema{
+		Type:            jsonType,
+		Description:     description,
+		SearchOperators: repository.OperatorsForType(repository.StandardFieldTypes[name]),
+	}
+}
+
+func nullable(s *JSONSchema) *JSONSchema {
+	if t, ok := s.Type.(string); ok {
+		s.Type = []string{t, "null"}
+	}
+	return s
+}
+
+// jsonTypeOf maps an SQL field type to its JSON type.
+func jsonTypeOf(sqlType string) string {
+	switch sqlType {
+	case "INTEGER":
+		return "integer"
+	case "REAL":
+		return "number"
+	case "BOOLEAN":
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+func exampleValue(sqlType string) any {
+	switch sqlType {
+	case "INTEGER":
+		return 42
+	case "REAL":
+		return 3.14
+	case "BOOLEAN":
+		return true
+	default:
+		return "text"
+	}
+}
+
+func entryStatuses() []string {
+	var statuses []string
+	for _, s := range repository.GetAllEntryStatuses() {
+		statuses = append(statuses, repository.GetEntryStatusString(s))
+	}
+	return statuses
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,63 @@
+package databasehandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mediahub_oss/internal/repository"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files")
+
+func TestEntrySchemaGolden(t *testing.T) {
+	fields := []repository.CustomFieldDef{
+		{ID: 0, Name: "description", Type: "TEXT", IsIndexed: true},
+		{ID: 1, Name: "rating", Type: "INTEGER"},
+		{ID: 2, Name: "latitude", Type: "REAL"},
+		{ID: 3, Name: "approved", Type: "BOOLEAN"},
+		{ID: 4, Name: "patient", Type: "TEXT", IsSensitive: true},
+	}
+
+	for _, contentType := range []string{"image", "audio", "file"} {
+		t.Run(contentType, func(t *testing.T) {
+			db := repository.Database{
+				ID:           "01HZX5K8J6Q9V3T2W1R0P7N4M5",
+				Name:         contentType + "_db",
+				ContentType:  contentType,
+				CustomFields: fields,
+			}
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(buildEntrySchema(db, false)); err != nil {
+				t.Fatalf("failed to encode schema: %v", err)
+			}
+			got := buf.Bytes()
+
+			golden := filepath.Join("testdata", "schema_"+contentType+".golden.json")
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatalf("failed to write golden file: %v", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("schema differs from %s (run with -update to accept):\n%s", golden, got)
+			}
+		})
+	}
+
+	// Sensitive fields are described for users who may see them
+	db := repository.Database{ContentType: "file", CustomFields: fields}
+	if _, ok := buildEntrySchema(db, true).Properties["custom_fields"].Properties["patient"]; !ok {
+		t.Error("expected the sensitive field in the schema")
+	}
+}
@@ -1,19 +1,229 @@
 package databasehandler
 
 import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"mediahub_oss/internal/housekeeping"
+	"mediahub_oss/internal/media"
 	"mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared"
-	"time"
+	"net/url"
+	"slices"
+	"strings"
 )
 
-// toModel parses the string-based API payload into the Repository model
-func (dbc DatabaseCreatePayload) toModel() repository.Database {
+// Housekeeping defaults applied to missing, empty or null values
+const (
+	defaultHKInterval  = "1h"
+	defaultHKDiskSpace = "100G"
+	defaultHKMaxAge    = "365d"
+)
+
+// validateAutoConversion checks the requested auto conversion target against the
+// capabilities of the media converter, not just the presence of the executables.
+func validateAutoConversion(mc media.MediaConverter, contentType string, target string) error {
+	if target == "" || mc == nil {
+		return nil
+	}
+	if !slices.Contains(mc.GetOutputMimeTypes(contentType), media.NormalizeMimeType(target)) {
+		return fmt.Errorf("auto conversion to '%s' is not supported by this server for content type '%s'", target, contentType)
+	}
+	return nil
+}
+
+// validateConversionRules checks that every rule converts a mime type of the content type to a
+// target the media converter supports, and that no mime type has more than one rule.
+func validateConversionRules(mc media.MediaConverter, contentType string, rules []repository.ConversionRule) error {
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		from := media.NormalizeMimeType(rule.From)
+		to := media.NormalizeMimeType(rule.To)
+		if from == "" || to == "" {
+			return fmt.Errorf("conversion rules need both 'from' and 'to'")
+		}
+		if ok, _ := media.IsMimeOfType(contentType, from); !ok {
+			return fmt.Errorf("conversion rule from '%s' does not match content type '%s'", rule.From, contentType)
+		}
+		if from == to {
+			return fmt.Errorf("conversion rule from '%s' converts to the same mime type", rule.From)
+		}
+		if seen[from] {
+			return fmt.Errorf("more than one conversion rule from '%s'", rule.From)
+		}
+		seen[from] = true
+		if mc != nil && !slices.Contains(mc.GetOutputMimeTypes(contentType), to) {
+			return fmt.Errorf("conversion to '%s' is not supported by this server for content type '%s'", rule.To, contentType)
+		}
+	}
+	return nil
+}
+
+// validateTranscription checks that transcription is only configured for audio databases, with an
+// http(s) endpoint, a model and an existing TEXT custom field receiving the text.
+func validateTranscription(contentType string, cfg repository.TranscriptionConfig, customFields []repository.CustomFieldDef) error {
+	if cfg == (repository.TranscriptionConfig{}) {
+		return nil
+	}
+	if contentType != "audio" {
+		return fmt.Errorf("transcription is only supported for audio databases")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("transcription endpoint '%s' must be an http or https URL", cfg.Endpoint)
+	}
+	if cfg.Model == "" {
+		return fmt.Errorf("transcription needs a model")
+	}
+	for _, cf := range customFields {
+		if cf.Name != cfg.Field {
+			continue
+		}
+		if !strings.EqualFold(cf.Type, "TEXT") {
+			return fmt.Errorf("transcription field '%s' must be of type TEXT", cfg.Field)
+		}
+		return nil
+	}
+	return fmt.Errorf("transcription field '%s' is not a custom field of the database", cfg.Field)
+}
+
+// validateMetadataValues checks the metadata defaults or overrides (named by key) against the custom
+// fields: every value must belong to a declared field and match its type.
+func validateMetadataValues(key string, values map[string]any, customFields []repository.CustomFieldDef) error {
+	for name, value := range values {
+		i := slices.IndexFunc(customFields, func(cf repository.CustomFieldDef) bool { return cf.Name == name })
+		if i < 0 {
+			return fmt.Errorf("%s: '%s' is not a custom field of the database", key, name)
+		}
+
+		var ok bool
+		switch strings.ToUpper(customFields[i].Type) {
+		case "TEXT":
+			_, ok = value.(string)
+		case "INTEGER":
+			num, isNum := value.(float64)
+			ok = isNum && num == float64(int64(num))
+		case "REAL":
+			_, ok = value.(float64)
+		case "BOOLEAN":
+			_, ok = value.(bool)
+		}
+		if !ok {
+			return fmt.Errorf("%s: '%s' must be of type %s", key, name, customFields[i].Type)
+		}
+	}
+	return nil
+}
+
+// validateWaveform rejects malformed waveform colors and absurd dimensions, so previews do not fail later.
+func validateWaveform(cfg repository.WaveformConfig) error {
+	return media.WaveformStyle(cfg).Validate()
+}
+
+// applyFulltextFields returns a copy of the custom fields where exactly the named ones are full-text
+// fields. The fields are copied because they may be shared with the repository cache.
+func applyFulltextFields(customFields []repository.CustomFieldDef, names []string) ([]repository.CustomFieldDef, error) {
+	for _, name := range names {
+		i := slices.IndexFunc(customFields, func(cf repository.CustomFieldDef) bool { return cf.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("full-text field '%s' is not a custom field of the database", name)
+		}
+		if !strings.EqualFold(customFields[i].Type, "TEXT") {
+			return nil, fmt.Errorf("full-text field '%s' must be of type TEXT", name)
+		}
+	}
+
+	fields := slices.Clone(customFields)
+	for i := range fields {
+		fields[i].IsFulltext = slices.Contains(names, fields[i].Name)
+	}
+	return fields, nil
+}
+
+// fulltextFieldNames returns the names of the full-text custom fields, never nil.
+func fulltextFieldNames(customFields []repository.CustomFieldDef) []string {
+	names := []string{}
+	for _, cf := range customFields {
+		if cf.IsFulltext {
+			names = append(names, cf.Name)
+		}
+	}
+	return names
+}
+
+// customFieldTypes are the accepted types of custom fields.
+var customFieldTypes = []string{"TEXT", "INTEGER", "REAL", "BOOLEAN"}
+
+// newDatabase validates the payload of a database to create and parses it into the repository model.
+// Conversion targets are checked against the capabilities of the media converter.
+func newDatabase(mc media.MediaConverter, payload DatabaseCreatePayload) (repository.Database, error) {
+	if payload.Name == "" {
+		return repository.Database{}, fmt.Errorf("Missing required field: name")
+	}
+	if payload.ContentType == "" {
+		return repository.Database{}, fmt.Errorf("Missing required field: content_type")
+	}
+	for _, cf := range payload.CustomFields {
+		if !slices.Contains(customFieldTypes, strings.ToUpper(cf.Type)) {
+			return repository.Database{}, fmt.Errorf("custom field '%s' has the unknown type '%s', expected one of %s", cf.Name, cf.Type, strings.Join(customFieldTypes, ", "))
+		}
+	}
+
+	if err := validateAutoConversion(mc, payload.ContentType, payload.Config.AutoConversion); err != nil {
+		return repository.Database{}, err
+	}
+	if err := validateConversionRules(mc, payload.ContentType, payload.Config.ConversionRules); err != nil {
+		return repository.Database{}, err
+	}
+
+	database, err := payload.toModel()
+	if err != nil {
+		return repository.Database{}, err
+	}
+	if err := validateTranscription(database.ContentType, database.Config.Transcription, database.CustomFields); err != nil {
+		return repository.Database{}, err
+	}
+	if err := validateMetadataValues("metadata_defaults", database.Config.MetadataDefaults, database.CustomFields); err != nil {
+		return repository.Database{}, err
+	}
+	if err := validateMetadataValues("metadata_overrides", database.Config.MetadataOverrides, database.CustomFields); err != nil {
+		return repository.Database{}, err
+	}
+	if err := validateWaveform(database.Config.Waveform); err != nil {
+		return repository.Database{}, err
+	}
+	if err := repository.ValidateGeoFields(database.Config.GeoFields, database.CustomFields); err != nil {
+		return repository.Database{}, err
+	}
+	return database, nil
+}
+
+// toModel parses the string-based API payload into the Repository model.
+// It fails if a housekeeping value cannot be parsed or a full-text field is not a TEXT custom field.
+func (dbc DatabaseCreatePayload) toModel() (repository.Database, error) {
+
+	hk, err := dbc.Housekeeping.toModel()
+	if err != nil {
+		return repository.Database{}, err
+	}
 
 	// convert from package internal model to repository model
 	customFields := make([]repository.CustomFieldDef, len(dbc.CustomFields))
 	for i, cf := range dbc.CustomFields {
 		customFields[i] = cf.toModel()
 	}
+	if customFields, err = applyFulltextFields(customFields, dbc.Config.FulltextFields); err != nil {
+		return repository.Database{}, err
+	}
+
+	var transcription repository.TranscriptionConfig
+	if dbc.Config.Transcription != nil {
+		transcription = *dbc.Config.Transcription
+	}
+	var geoFields repository.GeoFields
+	if dbc.Config.GeoFields != nil {
+		geoFields = *dbc.Config.GeoFields
+	}
 
 	// create return object (ID will be generated automatically by the repository)
 	return repository.Database{
@@ -21,16 +231,33 @@ func (dbc DatabaseCreatePayload) toModel() repository.Database {
 		ContentType: dbc.ContentType,
 		NMaxQueued:  dbc.NMaxQueued,
 		Config: repository.DatabaseConfig{
-			CreatePreview:  dbc.Config.CreatePreview,
-			AutoConversion: dbc.Config.AutoConversion,
+			CreatePreview:    dbc.Config.CreatePreview,
+			AutoConversion:   dbc.Config.AutoConversion,
+			KeepOriginal:     dbc.Config.KeepOriginal,
+			UniqueExternalID: dbc.Config.UniqueExternalID,
+			PublicRead:       dbc.Config.PublicRead,
+			ConversionRules:  dbc.Config.ConversionRules,
+			Transcription:    transcription,
+			GeoFields:        geoFields,
+
+			MetadataDefaults:  dbc.Config.MetadataDefaults,
+			MetadataOverrides: dbc.Config.MetadataOverrides,
+
+			Waveform: repository.WaveformConfig{
+				Width:      dbc.Config.WaveformWidth,
+				Height:     dbc.Config.WaveformHeight,
+				Color:      dbc.Config.WaveformColor,
+				Background: dbc.Config.WaveformBackground,
+			},
 		},
-		Housekeeping: dbc.Housekeeping.toModel(),
-		CustomFields: customFields,
+		Housekeeping:  hk,
+		CustomFields:  customFields,
+		CustomIndexes: dbc.CustomIndexes,
 		Stats: repository.DatabaseStats{
 			EntryCount:          0,
 			TotalDiskSpaceBytes: 0,
 		},
-	}
+	}, nil
 }
 
 func (cf DatabaseCustomField) toModel() repository.CustomFieldDef {
@@ -42,61 +269,215 @@ func (cf DatabaseCustomField) toModel() repository.CustomFieldDef {
 	if cf.IsIndexed != nil {
 		isIndexed = *cf.IsIndexed
 	}
+	isSensitive := false
+	if cf.IsSensitive != nil {
+		isSensitive = *cf.IsSensitive
+	}
 	return repository.CustomFieldDef{
-		ID:        id,
-		Name:      cf.Name,
-		Type:      cf.Type,
-		IsIndexed: isIndexed,
+		ID:          id,
+		Name:        cf.Name,
+		Type:        cf.Type,
+		IsIndexed:   isIndexed,
+		IsSensitive: isSensitive,
+	}
+}
+
+// applyUpdate merges a partial update body onto the current database. Keys that are absent keep
+// their current value. An explicit null resets a config flag or housekeeping rule to its default,
+// a null "config" or "housekeeping" object resets all of them. The result still has to be validated.
+func applyUpdate(db repository.Database, body map[string]json.RawMessage) (repository.Database, error) {
+	if raw, ok := body["name"]; ok && !isNull(raw) {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			return db, fmt.Errorf("invalid name: %w", err)
+		}
+		if name != "" {
+			db.Name = name
+		}
+	}
+
+	if raw, ok := body["n_max_queued"]; ok {
+		if isNull(raw) {
+			return db, fmt.Errorf("n_max_queued cannot be null")
+		}
+		if err := json.Unmarshal(raw, &db.NMaxQueued); err != nil {
+			return db, fmt.Errorf("invalid n_max_queued: %w", err)
+		}
+	}
+
+	if raw, ok := body["config"]; ok {
+		fields, err := decodeObject(raw, "config")
+		if err != nil {
+			return db, err
+		}
+		fulltextFields := fulltextFieldNames(db.CustomFields)
+		if isNull(raw) {
+			db.Config = repository.DatabaseConfig{}
+			fulltextFields = nil
+		}
+		for key, target := range map[string]any{
+			"create_preview":     &db.Config.CreatePreview,
+			"auto_conversion":    &db.Config.AutoConversion,
+			"keep_original":      &db.Config.KeepOriginal,
+			"unique_external_id": &db.Config.UniqueExternalID,
+			"public_read":        &db.Config.PublicRead,
+			"conversion_rules":   &db.Config.ConversionRules,
+			"transcription":      &db.Config.Transcription,
+			"fulltext_fields":    &fulltextFields,
+			"geo_fields":         &db.Config.GeoFields,
+			"metadata_defaults":  &db.Config.MetadataDefaults,
+			"metadata_overrides": &db.Config.MetadataOverrides,
+
+			"waveform_width":      &db.Config.Waveform.Width,
+			"waveform_height":     &db.Config.Waveform.Height,
+			"waveform_color":      &db.Config.Waveform.Color,
+			"waveform_background": &db.Config.Waveform.Background,
+		} {
+			if err := mergeField(fields, key, target); err != nil {
+				return db, fmt.Errorf("invalid config.%s: %w", key, err)
+			}
+		}
+		if db.CustomFields, err = applyFulltextFields(db.CustomFields, fulltextFields); err != nil {
+			return db, err
+		}
+	}
+
+	if raw, ok := body["housekeeping"]; ok {
+		fields, err := decodeObject(raw, "housekeeping")
+		if err != nil {
+			return db, err
+		}
+		var payload HousekeepingPayload
+		rules := map[string]any{"interval": &payload.Interval, "disk_space": &payload.DiskSpace, "max_age": &payload.MaxAge, "age_basis": &payload.AgeBasis, "disk_space_warn_percent": &payload.DiskSpaceWarnPercent}
+		for key, target := range rules {
+			if err := mergeField(fields, key, target); err != nil {
+				return db, fmt.Errorf("invalid housekeeping.%s: %w", key, err)
+			}
+		}
+
+		// Null and empty strings parse to the defaults, only the given rules are taken over
+		parsed, err := payload.toModel()
+		if err != nil {
+			return db, err
+		}
+		_, hasInterval := fields["interval"]
+		_, hasDiskSpace := fields["disk_space"]
+		_, hasMaxAge := fields["max_age"]
+		if hasInterval || isNull(raw) {
+			db.Housekeeping.Interval = parsed.Interval
+		}
+		if hasDiskSpace || isNull(raw) {
+			db.Housekeeping.DiskSpace = parsed.DiskSpace
+		}
+		if hasMaxAge || isNull(raw) {
+			db.Housekeeping.MaxAge = parsed.MaxAge
+		}
+		if _, ok := fields["age_basis"]; ok || isNull(raw) {
+			db.Housekeeping.AgeBasis = parsed.AgeBasis
+		}
+		if _, ok := fields["disk_space_warn_percent"]; ok || isNull(raw) {
+			db.Housekeeping.DiskSpaceWarnPercent = parsed.DiskSpaceWarnPercent
+		}
 	}
+
+	return db, nil
 }
 
-// Extract the config part from the payload and return the repository type
-func (upd DatabaseUpdatePayload) getConfig() repository.DatabaseConfig {
-	return repository.DatabaseConfig{
-		CreatePreview:  upd.Config.CreatePreview,
-		AutoConversion: upd.Config.AutoConversion,
+// decodeObject decodes a nested object of an update body, null decodes to an empty object.
+func decodeObject(raw json.RawMessage, name string) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("invalid %s: expected an object", name)
 	}
+	return fields, nil
 }
 
-// Extract the housekeeping part from the payload and return the repository type
-func (upd DatabaseUpdatePayload) getHK(lastHKRun time.Time) repository.DatabaseHK {
-	hk := upd.Housekeeping.toModel()
-	hk.LastHkRun = lastHKRun
-	return hk
+// mergeField decodes fields[key] into target if the key is present. An explicit null sets the zero value.
+func mergeField(fields map[string]json.RawMessage, key string, target any) error {
+	raw, ok := fields[key]
+	if !ok {
+		return nil
+	}
+	if isNull(raw) {
+		switch t := target.(type) {
+		case *bool:
+			*t = false
+		case *int:
+			*t = 0
+		case *string:
+			*t = ""
+		case *[]repository.ConversionRule:
+			*t = nil
+		case *[]string:
+			*t = nil
+		case *repository.TranscriptionConfig:
+			*t = repository.TranscriptionConfig{}
+		case *repository.GeoFields:
+			*t = repository.GeoFields{}
+		case *map[string]any:
+			*t = nil
+		}
+		return nil
+	}
+	if t, ok := target.(*map[string]any); ok {
+		// Replace the object instead of merging into it, the current one may be shared with the repository cache
+		*t = nil
+	}
+	return json.Unmarshal(raw, target)
+}
+
+func isNull(raw json.RawMessage) bool {
+	return string(raw) == "null"
 }
 
 // toModel parses the string-based API payload into the uint64-based Repository model, applying defaults.
-func (hk HousekeepingPayload) toModel() repository.DatabaseHK {
+// Invalid values are rejected with the accepted syntax, instead of silently disabling the rule.
+func (hk HousekeepingPayload) toModel() (repository.DatabaseHK, error) {
 	var dbHk repository.DatabaseHK
+	var err error
 
-	// Default: "1h"
 	intervalStr := hk.Interval
 	if intervalStr == "" {
-		intervalStr = "1h"
+		intervalStr = defaultHKInterval
 	}
-	if dur, err := shared.ParseDuration(intervalStr); err == nil {
-		dbHk.Interval = dur
+	if dbHk.Interval, err = shared.ParseDuration(intervalStr); err != nil {
+		return dbHk, fmt.Errorf("invalid housekeeping interval %q, expected %s", hk.Interval, shared.DurationSyntax)
 	}
 
-	// Default: "100G"
 	diskSpaceStr := hk.DiskSpace
 	if diskSpaceStr == "" {
-		diskSpaceStr = "100G"
+		diskSpaceStr = defaultHKDiskSpace
 	}
-	if size, err := shared.ParseSize(diskSpaceStr); err == nil {
-		dbHk.DiskSpace = size
+	if dbHk.DiskSpace, err = shared.ParseSize(diskSpaceStr); err != nil {
+		return dbHk, fmt.Errorf("invalid housekeeping disk_space %q, expected %s", hk.DiskSpace, shared.SizeSyntax)
 	}
 
-	// Default: "365d"
 	maxAgeStr := hk.MaxAge
 	if maxAgeStr == "" {
-		maxAgeStr = "365d"
+		maxAgeStr = defaultHKMaxAge
+	}
+	if dbHk.MaxAge, err = shared.ParseDuration(maxAgeStr); err != nil {
+		return dbHk, fmt.Errorf("invalid housekeeping max_age %q, expected %s", hk.MaxAge, shared.DurationSyntax)
 	}
-	if age, err := shared.ParseDuration(maxAgeStr); err == nil {
-		dbHk.MaxAge = age
+
+	switch hk.AgeBasis {
+	case "", repository.AgeBasisCapture:
+		dbHk.AgeBasis = repository.AgeBasisCapture
+	case repository.AgeBasisIngestion:
+		dbHk.AgeBasis = repository.AgeBasisIngestion
+	default:
+		return dbHk, fmt.Errorf("invalid housekeeping age_basis %q, expected %q or %q", hk.AgeBasis, repository.AgeBasisCapture, repository.AgeBasisIngestion)
+	}
+
+	dbHk.DiskSpaceWarnPercent = repository.DefaultDiskSpaceWarnPercent
+	if hk.DiskSpaceWarnPercent != nil {
+		dbHk.DiskSpaceWarnPercent = *hk.DiskSpaceWarnPercent
+	}
+	if dbHk.DiskSpaceWarnPercent < 0 || dbHk.DiskSpaceWarnPercent > 100 {
+		return dbHk, fmt.Errorf("invalid housekeeping disk_space_warn_percent %d, expected 0 (disabled) to 100", dbHk.DiskSpaceWarnPercent)
 	}
 
-	return dbHk
+	return dbHk, nil
 }
 
 func mapToDatabaseResponse(db repository.Database) DatabaseResponse {
@@ -106,14 +487,36 @@ func mapToDatabaseResponse(db repository.Database) DatabaseResponse {
 	for i, cf := range db.CustomFields {
 		idVal := cf.ID
 		isIndexedVal := cf.IsIndexed
+		isSensitiveVal := cf.IsSensitive
 		customFields[i] = DatabaseCustomField{
-			ID:        &idVal,
-			Name:      cf.Name,
-			Type:      cf.Type,
-			IsIndexed: &isIndexedVal,
+			ID:          &idVal,
+			Name:        cf.Name,
+			Type:        cf.Type,
+			IsIndexed:   &isIndexedVal,
+			IsSensitive: &isSensitiveVal,
 		}
 	}
 
+	customIndexes := [][]string{}
+	for _, index := range db.CustomIndexes {
+		customIndexes = append(customIndexes, slices.Clone(index))
+	}
+
+	var transcription *repository.TranscriptionConfig
+	if db.Config.Transcription != (repository.TranscriptionConfig{}) {
+		transcription = &db.Config.Transcription
+	}
+
+	var geoFields *repository.GeoFields
+	if db.Config.GeoFields.Enabled() {
+		geoFields = &db.Config.GeoFields
+	}
+
+	var usagePercent *float64
+	if usage, limited := housekeeping.UsagePercent(db, db.Stats.TotalDiskSpaceBytes); limited {
+		usagePercent = &usage
+	}
+
 	// create return object
 	return DatabaseResponse{
 		ID:          db.ID.String(),
@@ -121,18 +524,43 @@ func mapToDatabaseResponse(db repository.Database) DatabaseResponse {
 		ContentType: db.ContentType,
 		NMaxQueued:  db.NMaxQueued,
 		Config: ConfigPayload{
-			CreatePreview:  db.Config.CreatePreview,
-			AutoConversion: db.Config.AutoConversion,
+			CreatePreview:    db.Config.CreatePreview,
+			AutoConversion:   db.Config.AutoConversion,
+			KeepOriginal:     db.Config.KeepOriginal,
+			UniqueExternalID: db.Config.UniqueExternalID,
+			PublicRead:       db.Config.PublicRead,
+			ConversionRules:  db.Config.ConversionRules,
+			Transcription:    transcription,
+			FulltextFields:   fulltextFieldNames(db.CustomFields),
+			GeoFields:        geoFields,
+
+			MetadataDefaults:  db.Config.MetadataDefaults,
+			MetadataOverrides: db.Config.MetadataOverrides,
+
+			WaveformWidth:      db.Config.Waveform.Width,
+			WaveformHeight:     db.Config.Waveform.Height,
+			WaveformColor:      db.Config.Waveform.Color,
+			WaveformBackground: db.Config.Waveform.Background,
 		},
 		Housekeeping: DatabaseResponseHK{
-			Interval:  shared.DurationToString(db.Housekeeping.Interval),
-			DiskSpace: shared.BytesToString(db.Housekeeping.DiskSpace),
-			MaxAge:    shared.DurationToString(db.Housekeeping.MaxAge),
+			Interval:        shared.DurationToString(db.Housekeeping.Interval),
+			DiskSpace:       shared.BytesToString(db.Housekeeping.DiskSpace),
+			MaxAge:          shared.DurationToString(db.Housekeeping.MaxAge),
+			AgeBasis:        cmp.Or(db.Housekeeping.AgeBasis, repository.AgeBasisCapture),
+			IntervalSeconds: int64(db.Housekeeping.Interval.Seconds()),
+			DiskSpaceBytes:  db.Housekeeping.DiskSpace,
+			MaxAgeSeconds:   int64(db.Housekeeping.MaxAge.Seconds()),
+
+			DiskSpaceWarnPercent: db.Housekeeping.DiskSpaceWarnPercent,
 		},
-		CustomFields: customFields,
+		CustomFields:  customFields,
+		CustomIndexes: customIndexes,
 		Stats: DatabaseResponseStats{
 			EntryCount:          db.Stats.EntryCount,
 			TotalDiskSpaceBytes: db.Stats.TotalDiskSpaceBytes,
+			ProcessingCount:     db.Stats.ProcessingCount,
+			UsagePercent:        usagePercent,
+			AlertActive:         db.Stats.DiskSpaceAlert,
 		},
 	}
 }
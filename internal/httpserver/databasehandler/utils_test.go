@@ -0,0 +1,41 @@
+package databasehandler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/repository"
+)
+
+func TestHousekeepingPayloadToModel(t *testing.T) {
+	// Defaults for empty values, sentinels switch a rule off
+	hk, err := HousekeepingPayload{MaxAge: "disabled", DiskSpace: "0"}.toModel()
+	if err != nil {
+		t.Fatalf("expected a valid payload, got %v", err)
+	}
+	if hk.Interval != time.Hour || hk.DiskSpace != 0 || hk.MaxAge != 0 {
+		t.Errorf("expected the default interval and disabled rules, got %+v", hk)
+	}
+
+	// Invalid values are rejected with the accepted syntax instead of disabling the rule
+	invalid := []HousekeepingPayload{
+		{Interval: "soon"},
+		{DiskSpace: "ten gigs"},
+		{MaxAge: "30x"},
+	}
+	for _, payload := range invalid {
+		_, err := payload.toModel()
+		if err == nil || !strings.Contains(err.Error(), "expected") {
+			t.Errorf("%+v: expected an error with the accepted syntax, got %v", payload, err)
+		}
+	}
+
+	// The response carries the parsed values
+	resp := mapToDatabaseResponse(repository.Database{
+		Housekeeping: repository.DatabaseHK{Interval: 2 * time.Hour, DiskSpace: 10 << 30},
+	})
+	if resp.Housekeeping.IntervalSeconds != 7200 || resp.Housekeeping.DiskSpaceBytes != 10<<30 || resp.Housekeeping.MaxAgeSeconds != 0 {
+		t.Errorf("unexpected canonical values: %+v", resp.Housekeeping)
+	}
+}
@@ -0,0 +1,267 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCommentSize is the maximum length of a comment in bytes.
+const maxCommentSize = 4 << 10
+
+func mapToCommentResponse(comment repo.Comment) CommentResponse {
+	return CommentResponse{
+		ID:        comment.ID,
+		EntryID:   comment.EntryID,
+		Author:    comment.Author,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt.UnixMilli(),
+	}
+}
+
+// wantsCommentCount reports whether the client asked for the comment counts via ?include_comment_count=true.
+func wantsCommentCount(r *http.Request) bool {
+	include, _ := strconv.ParseBool(r.URL.Query().Get("include_comment_count"))
+	return include
+}
+
+// addCommentCounts sets the comment count of the entry responses, looked up in a single query.
+// The responses are left without counts if the lookup fails.
+func (h *EntryHandler) addCommentCounts(r *http.Request, dbID string, responses []EntryResponse) {
+	ids := make([]int64, len(responses))
+	for i := range responses {
+		ids[i] = responses[i].EntryID
+	}
+	counts, err := h.Repo.CountComments(r.Context(), repo.ULID(dbID), ids)
+	if err != nil {
+		h.Logger.Error("Failed to count comments", "database_id", dbID, "error", err)
+		return
+	}
+	for i := range responses {
+		count := counts[responses[i].EntryID]
+		responses[i].CommentCount = &count
+	}
+}
+
+// @Summary Comment on an entry
+// @Description Adds a comment (up to 4 KB of text) to an entry, e.g. for reviewers. The caller is recorded as author.
+// @Tags entry
+// @Accept json
+// @Produce json
+// @Param   database_id  path  string                true  "Database ID"
+// @Param   id           path  int64                 true  "Entry ID"
+// @Param   payload      body  CreateCommentRequest  true  "The comment"
+// @Success 201 {object} CommentResponse
+// @Failure 400 {object} utils.ErrorResponse "Invalid request, empty or too long comment"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/{database_id}/entry/{id}/comments [post]
+func (h *EntryHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	dbID := r.PathValue("database_id")
+	user := utils.GetUserFromContext(ctx)
+
+	// 1. Validate Input
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+
+	var payload CreateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	payload.Body = strings.TrimSpace(payload.Body)
+	if payload.Body == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "The comment must not be empty.")
+		return
+	}
+	if len(payload.Body) > maxCommentSize {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("The comment exceeds the maximum size of %d bytes.", maxCommentSize))
+		return
+	}
+	if !utf8.ValidString(payload.Body) {
+		utils.RespondWithError(w, http.StatusBadRequest, "The comment is not valid UTF-8.")
+		return
+	}
+
+	// 2. Store it (the repository checks that the entry exists)
+	comment, err := h.Repo.CreateComment(ctx, repo.ULID(dbID), repo.Comment{
+		EntryID: id,
+		Author:  user.Username,
+		Body:    payload.Body,
+	})
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database or entry not found.")
+		} else {
+			h.Logger.Error("Failed to create comment", "database_id", dbID, "entry", id, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	// 3. Audit & Response
+	h.Auditor.Log(ctx, "entry.comment_create", user.Username, fmt.Sprintf("%s:%d", dbID, id), map[string]any{
+		"comment_id": comment.ID,
+	})
+
+	utils.RespondWithJSON(w, http.StatusCreated, mapToCommentResponse(comment))
+}
+
+// @Summary List the comments of an entry
+// @Description Lists the comments of an entry, newest first.
+// @Tags entry
+// @Produce json
+// @Param   database_id  path   string  true   "Database ID"
+// @Param   id           path   int64   true   "Entry ID"
+// @Param   limit        query  int     false  "Number of comments to return (default 100, clamped to the maximum page size)"
+// @Param   offset       query  int     false  "Offset for pagination (default 0)"
+// @Success 200 {array} CommentResponse
+// @Failure 400 {object} utils.ErrorResponse "Invalid request"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/{database_id}/entry/{id}/comments [get]
+func (h *EntryHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+	dbID := r.PathValue("database_id")
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+	offset := parseQueryInt(r, "offset", 0)
+	if offset < 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid offset: must not be negative")
+		return
+	}
+	limit := h.pageLimit(w, parseQueryInt(r, "limit", 0))
+
+	comments, err := h.Repo.GetComments(r.Context(), repo.ULID(dbID), id, limit, offset)
+	if err != nil {
+		h.Logger.Error("Failed to get comments", "database_id", dbID, "entry", id, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	resp := make([]CommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		resp = append(resp, mapToCommentResponse(comment))
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// @Summary Delete a comment
+// @Description Deletes a comment of an entry. Only its author and admins of the database may delete it.
+// @Tags entry
+// @Produce json
+// @Param   database_id  path  string  true  "Database ID"
+// @Param   id           path  int64   true  "Entry ID"
+// @Param   comment_id   path  int64   true  "Comment ID"
+// @Success 200 {object} utils.MessageResponse "Success message"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Neither the author nor an admin"
+// @Failure 404 {object} utils.ErrorResponse "Comment not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/{database_id}/entry/{id}/comments/{comment_id} [delete]
+func (h *EntryHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	dbID := r.PathValue("database_id")
+	user := utils.GetUserFromContext(ctx)
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+	commentID, err := strconv.ParseInt(r.PathValue("comment_id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid comment ID format.")
+		return
+	}
+
+	// Comments of other entries are not found
+	comment, err := h.Repo.GetComment(ctx, repo.ULID(dbID), commentID)
+	if err == nil && comment.EntryID != id {
+		err = customerrors.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Comment not found.")
+		} else {
+			h.Logger.Error("Failed to get comment", "database_id", dbID, "comment_id", commentID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	if comment.Author != user.Username && !holder.HasPermission(repo.ULID(dbID), repo.AccessAdmin) {
+		utils.RespondWithError(w, http.StatusForbidden, "Forbidden: only the author or an admin may delete a comment.")
+		return
+	}
+
+	if err := h.Repo.DeleteComment(ctx, repo.ULID(dbID), commentID); err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Comment not found.")
+		} else {
+			h.Logger.Error("Failed to delete comment", "database_id", dbID, "comment_id", commentID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.Auditor.Log(ctx, "entry.comment_delete", user.Username, fmt.Sprintf("%s:%d", dbID, id), map[string]any{
+		"comment_id": commentID,
+		"author":     comment.Author,
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.MessageResponse{Message: fmt.Sprintf("Comment %d was deleted.", commentID)})
+}
+
+// exportComments adds the comments of an entry to an export as comments/<id>.json, newest first.
+// Nothing is added for entries without comments.
+func (h *EntryHandler) exportComments(ctx context.Context, archive *exportArchive, dbID string, entryID int64) {
+	comments, err := h.Repo.GetComments(ctx, repo.ULID(dbID), entryID, 0, 0)
+	if err != nil {
+		h.Logger.Warn("Failed to read comments for export", "id", entryID, "error", err)
+		return
+	}
+	if len(comments) == 0 {
+		return
+	}
+
+	resp := make([]CommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		resp = append(resp, mapToCommentResponse(comment))
+	}
+	zipFile, err := archive.create(fmt.Sprintf("comments/%d.json", entryID), entryID)
+	if err != nil {
+		h.Logger.Warn("Failed to create zip entry for comments", "id", entryID, "error", err)
+		return
+	}
+	if err := json.NewEncoder(zipFile).Encode(resp); err != nil {
+		h.Logger.Warn("Failed to write comments to zip", "id", entryID, "error", err)
+	}
+}
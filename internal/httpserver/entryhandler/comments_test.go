@@ -0,0 +1,168 @@
+package entryhandler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestComments(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "reviews", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream"})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	users := map[string]repo.User{}
+	for name, roles := range map[string]repo.AccessGrant{
+		"alice": repo.AccessView | repo.AccessEdit,
+		"bob":   repo.AccessView | repo.AccessEdit,
+		"carol": repo.AccessView,
+		"dave":  repo.AccessView | repo.AccessEdit | repo.AccessAdmin,
+	} {
+		user, err := r.CreateUser(ctx, repo.User{Username: name, PasswordHash: "x"})
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		if err := r.SetUserPermissions(ctx, repo.UserPermissions{UserID: user.ID, DatabaseID: db.ID, Roles: roles}); err != nil {
+			t.Fatalf("failed to set permissions: %v", err)
+		}
+		users[name] = user
+	}
+
+	call := func(handler http.HandlerFunc, method, target, body, username string, commentID string) *httptest.ResponseRecorder {
+		user := users[username]
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		req.SetPathValue("id", strconv.FormatInt(entry.ID, 10))
+		req.SetPathValue("comment_id", commentID)
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &user)
+		reqCtx = context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.UserPermissions{UserULID: user.ID, Scope: repo.NewAccessGrant(true, true, true, true, true), Repo: r})
+		rec := httptest.NewRecorder()
+		handler(rec, req.WithContext(reqCtx))
+		return rec
+	}
+	post := func(body, username string) *httptest.ResponseRecorder {
+		return call(h.CreateComment, http.MethodPost, "/comments", body, username, "")
+	}
+
+	// 1. Comments are validated
+	for name, body := range map[string]string{
+		"empty":    `{"body": "  "}`,
+		"too long": `{"body": "` + strings.Repeat("x", maxCommentSize+1) + `"}`,
+		"invalid":  `{"body": 1}`,
+	} {
+		if rec := post(body, "alice"); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+
+	// 2. Comments are created and listed newest first
+	var created []CommentResponse
+	for i, author := range []string{"alice", "bob", "alice"} {
+		rec := post(`{"body": "note `+strconv.Itoa(i)+`"}`, author)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var comment CommentResponse
+		json.Unmarshal(rec.Body.Bytes(), &comment)
+		created = append(created, comment)
+	}
+	if created[0].Author != "alice" || created[0].EntryID != entry.ID || created[0].Body != "note 0" {
+		t.Errorf("unexpected comment: %+v", created[0])
+	}
+
+	rec := call(h.GetComments, http.MethodGet, "/comments?limit=2&offset=1", "", "carol", "")
+	var listed []CommentResponse
+	json.Unmarshal(rec.Body.Bytes(), &listed)
+	if rec.Code != http.StatusOK || len(listed) != 2 || listed[0].Body != "note 1" || listed[1].Body != "note 0" {
+		t.Errorf("unexpected page %+v (%d)", listed, rec.Code)
+	}
+
+	// 3. The count is added on request only
+	rec = call(h.GetEntryMeta, http.MethodGet, "/entry?include_comment_count=true", "", "carol", "")
+	var meta EntryResponse
+	json.Unmarshal(rec.Body.Bytes(), &meta)
+	if meta.CommentCount == nil || *meta.CommentCount != 3 {
+		t.Errorf("expected a comment count of 3, got %v", meta.CommentCount)
+	}
+	rec = call(h.GetEntryMeta, http.MethodGet, "/entry", "", "carol", "")
+	if strings.Contains(rec.Body.String(), "comment_count") {
+		t.Errorf("expected no comment count, got %s", rec.Body.String())
+	}
+
+	// 4. Only the author or an admin may delete a comment
+	del := func(id int64, username string) int {
+		return call(h.DeleteComment, http.MethodDelete, "/comments", "", username, strconv.FormatInt(id, 10)).Code
+	}
+	if code := del(created[0].ID, "bob"); code != http.StatusForbidden {
+		t.Errorf("expected 403 for another user, got %d", code)
+	}
+	if code := del(created[0].ID, "alice"); code != http.StatusOK {
+		t.Errorf("expected the author to delete the comment, got %d", code)
+	}
+	if code := del(created[1].ID, "dave"); code != http.StatusOK {
+		t.Errorf("expected the database admin to delete the comment, got %d", code)
+	}
+	if code := del(created[1].ID, "dave"); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted comment, got %d", code)
+	}
+
+	// 5. The export adds the remaining comment as JSON
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	h.exportComments(ctx, newExportArchive(zw, false), db.ID.String(), entry.ID)
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(zr.File) != 1 || zr.File[0].Name != "comments/"+strconv.FormatInt(entry.ID, 10)+".json" {
+		t.Fatalf("unexpected export (err %v)", err)
+	}
+	f, _ := zr.File[0].Open()
+	var exported []CommentResponse
+	json.NewDecoder(f).Decode(&exported)
+	if len(exported) != 1 || exported[0].Body != "note 2" {
+		t.Errorf("unexpected exported comments: %+v", exported)
+	}
+}
@@ -3,15 +3,14 @@ package entryhandler
 import (
 	"archive/zip"
 	"context"
-	"encoding/base64"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	"mediahub_oss/internal/exportmanifest"
 	"mediahub_oss/internal/httpserver/utils"
-	"mediahub_oss/internal/processing"
 	repo "mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared"
 	"mediahub_oss/internal/shared/customerrors"
@@ -25,22 +24,38 @@ import (
 // @Summary Upload an entry
 // @Description Uploads a new entry to a specified database using multipart/form-data. The metadata part should be a JSON object containing the entry's timestamp, and any custom fields.
 // @Description The 'file' part's 'filename' in the Content-Disposition header will be extracted and saved.
+// @Description The parts may come in any order. The 'metadata' part is limited to 64 KB and may also be sent as a file attachment,
+// @Description unknown parts are skipped and reported in a `Warning` header.
 // @Description
 // @Description This endpoint uses a hybrid model:
 // @Description - **Small files (<= Configured Limit):** Processed synchronously. Returns `201 Created` with the full entry metadata.
 // @Description - **Large files (> Configured Limit):** Processed asynchronously. Returns `202 Accepted` with a partial response. The client should poll `GET /api/entry/meta` until the `status` field is 'ready'.
+// @Description
+// @Description With `response=minimal`, synchronous uploads return only `{id, timestamp, status}`, e.g. for high-rate sensor ingestion; asynchronous uploads are not affected.
+// @Description With `sync_preview=true`, the preview of a small image is generated before the `201` response, which then reports `has_preview`; it is rejected for other databases and for files above the sync upload size.
+// @Description Retries sent with the same `Idempotency-Key` header return the original response (marked with `Idempotent-Replayed: true`) instead of creating another entry.
+// @Description A replayed asynchronous upload that has finished processing returns the full entry with `200 OK`.
 // @Tags entry
 // @Accept  mpfd
 // @Produce  json
-// @Param   database_id  path  string  true  "Database ID"
+// @Param   database_id      path      string  true   "Database ID"
+// @Param   Idempotency-Key  header    string  false  "Client-chosen key (max. 255 characters) identifying retries of the same upload"
+// @Param   response         query     string  false  "'full' (default) or 'minimal' for the compact response of synchronous uploads"
+// @Param   sync_preview     query     bool    false  "Generate the preview of a small image before responding"
 // @Param   metadata      formData  string  true  "JSON metadata for the entry"
 // @Param   file          formData  file    true  "Entry file"
-// @Success 201 {object} EntryResponse "For small files (synchronous processing)"
+// @Success 200 {object} EntryResponse "Replay of an asynchronous upload that has finished processing"
+// @Success 201 {object} EntryResponse "For small files (synchronous processing), MinimalEntryResponse with response=minimal"
 // @Success 202 {object} PartialEntryResponse "For large files (asynchronous processing)"
-// @Failure 400 {object} utils.ErrorResponse "Invalid request"
-// @Failure 404 {object} utils.ErrorResponse "Database not found"
-// @Failure 415 {object} utils.ErrorResponse "Unsupported entry format"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request, an empty or truncated file, or sync_preview for a non-image database or a large file"
+// @Failure 404 {object} utils.ErrorResponse "Database not found, or the entry of a replayed upload was deleted"
+// @Failure 409 {object} ExternalIDConflictResponse "The external_id is already used (unique_external_id), the original request with this Idempotency-Key is still in progress, or the database holds max_entries_per_database entries"
+// @Failure 413 {object} utils.ErrorResponse "The 'metadata' part exceeds 64 KB, or the body the maximum upload size"
+// @Failure 415 {object} utils.ErrorResponse "Unsupported entry format, or the body is not multipart/form-data"
+// @Failure 422 {object} utils.ErrorResponse "File rejected by the virus scanner"
 // @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Failure 503 {object} utils.ErrorResponse "Queue full or virus scanner unreachable"
+// @Failure 507 {object} utils.ErrorResponse "The upload exceeds the storage quota of the user"
 // @Security BasicAuth
 // @Router /database/{database_id}/entry [post]
 func (h *EntryHandler) PostEntry(w http.ResponseWriter, r *http.Request) {
@@ -51,6 +66,12 @@ func (h *EntryHandler) PostEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts, err := parseUploadOptions(r)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Get user and db
 	user := utils.GetUserFromContext(r.Context())
 
@@ -65,80 +86,46 @@ func (h *EntryHandler) PostEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Read file into memory or store it on the file system
-	maxMemory := h.MaxSyncUploadSizeBytes
-	if maxMemory <= 0 {
-		maxMemory = 8 << 20
+	// Retries with a known Idempotency-Key get the original response
+	upload, ok := h.beginIdempotentUpload(w, r, db, user)
+	if !ok {
+		return
 	}
+	defer upload.release(r.Context())
 
-	if err := r.ParseMultipartForm(maxMemory); err != nil {
-		h.Logger.Warn("Failed to parse multipart form", "error", err)
-		utils.RespondWithError(w, http.StatusBadRequest, "Failed to parse multipart form.")
+	if !h.checkCapacity(w, db, 1) {
 		return
 	}
 
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Missing 'file' part in multipart form.")
+	form, ok := h.readUploadForm(w, r)
+	if !ok {
 		return
 	}
-	defer file.Close()
+	defer form.Close()
 
 	// Parse and validate metadata
-	metadataStr := r.FormValue("metadata")
+	metadataStr := form.metadata
 	if metadataStr == "" {
 		h.Logger.Warn("Missing 'metadata' part in multipart form")
 		utils.RespondWithError(w, http.StatusBadRequest, "Missing 'metadata' part in multipart form.")
 		return
 	}
 
-	entry_request, err := parseUploadMetadata(metadataStr)
+	entry_request, clientTimestamp, err := parseUploadMetadata(metadataStr, h.Timestamps, time.Now())
 	if err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Error parsing file metadata: "+err.Error())
 		return
 	}
 
-	err = validateCustomFields(entry_request.CustomFields, db.CustomFields)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Error validating custom fields: "+err.Error())
+	responseObj, status, ok := h.storeUpload(w, r, db, entry_request, clientTimestamp, form.file, form.header, opts, nil)
+	if !ok {
 		return
 	}
-
-	// Call processor
-	procReq := processing.EntryRequest{
-		Timestamp:    entry_request.Timestamp,
-		FileName:     entry_request.FileName,
-		CustomFields: entry_request.CustomFields,
-	}
-
-	originalMime := header.Header.Get("Content-Type")
-	originalName := header.Filename
-
-	entry, wasSync, err := h.Processor.ProcessEntry(r.Context(), db, procReq, file, originalMime, originalName)
-	if err != nil {
-		if errors.Is(err, customerrors.ErrUnavailable) {
-			utils.RespondWithError(w, http.StatusServiceUnavailable, "Service Unavailable: queue is full or processing capacity exhausted.")
-		} else if errors.Is(err, customerrors.ErrBadMimeType) {
-			utils.RespondWithError(w, http.StatusUnsupportedMediaType, err.Error())
-		} else {
-			h.Logger.Error("Processing failed", "error", err)
-			utils.RespondWithError(w, http.StatusInternalServerError, err.Error())
-		}
+	if err := upload.complete(r.Context(), responseObj.GetID(), status); err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Entry %d was created, but the result for the Idempotency-Key could not be stored. Retries with this key are rejected until it expires.", responseObj.GetID()))
 		return
 	}
 
-	var responseObj EntryWithID
-	status := http.StatusCreated
-	if wasSync {
-		responseObj = mapToEntryResponse(dbID, entry)
-	} else {
-		responseObj = mapToPartialEntryResponse(dbID, entry)
-		status = http.StatusAccepted
-	}
-
-	// Audit & Response
-	h.Auditor.Log(r.Context(), "entry.post", user.Username, fmt.Sprintf("%s:%d", dbID, responseObj.GetID()), map[string]any{"database_name": db.Name})
-
 	utils.RespondWithJSON(w, status, responseObj)
 }
 
@@ -151,7 +138,7 @@ func (h *EntryHandler) PostEntry(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} utils.MessageResponse "Success message"
 // @Failure 400 {object} utils.ErrorResponse "Invalid request"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
-// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden, or the entry is under legal hold"
 // @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
 // @Failure 500 {object} utils.ErrorResponse "Internal server error"
 // @Security BasicAuth
@@ -178,6 +165,8 @@ func (h *EntryHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if errors.Is(err, customerrors.ErrNotFound) {
 			utils.RespondWithError(w, http.StatusNotFound, "Database or entry not found.")
+		} else if errors.Is(err, customerrors.ErrLegalHold) {
+			utils.RespondWithError(w, http.StatusForbidden, "The entry is under legal hold and cannot be deleted.")
 		} else {
 			h.Logger.Error("Failed to safely delete entry", "database_id", dbID, "id", id, "error", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete the entry data. Error: %v", err))
@@ -194,23 +183,29 @@ func (h *EntryHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Get an entry file
 // @Description Retrieves a raw entry file. Supports Content Negotiation (JSON vs Binary) and HTTP Range Requests (Streaming).
+// @Description Every download carries the `content_version` of the entry as `X-Content-Version`; it changes when processing writes the file again, e.g. after a retry.
+// @Description Clients caching files send the version of their copy as `version` and get `409` once the file changed.
 // @Tags entry
 // @Produce octet-stream
 // @Produce json
 // @Param   database_id  path    string  true  "Database ID"
 // @Param   id      path    int64   true  "Entry ID"
 // @Param   Range   header  string  false "Byte range request (e.g., bytes=0-1023)"
+// @Param   variant query   string  false "'converted' (default) or 'original' for the kept original of a converted upload. Entries without original return their file for both"
+// @Param   version query   string  false "content_version of the entry the client expects, 409 if the file was written since"
 // @Success 200 {file} file "The full raw file data (default)"
 // @Success 200 {object} FileJSONResponse "Base64 encoded file data (if Accept: application/json)"
 // @Success 206 {file} file "Partial content (streaming response)"
-// @Failure 400 {object} utils.ErrorResponse "Invalid request or ID format"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request, ID format or variant"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
 // @Failure 403 {object} utils.ErrorResponse "Forbidden"
 // @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
-// @Failure 409 {object} utils.ErrorResponse "File is currently processing"
+// @Failure 406 {object} utils.ErrorResponse "File too large for the JSON representation"
+// @Failure 409 {object} utils.ErrorResponse "File is currently processing, or its content version differs from 'version'"
 // @Failure 416 {object} utils.ErrorResponse "Range Not Satisfiable"
 // @Failure 500 {object} utils.ErrorResponse "Internal server error"
 // @Header 200,206 {string} Accept-Ranges "bytes"
+// @Header 200,206,409 {string} X-Content-Version "content_version of the entry, changes whenever the stored file is written"
 // @Header 206 {string} Content-Range "bytes start-end/total"
 // @Security BasicAuth
 // @Security BearerAuth
@@ -230,6 +225,11 @@ func (h *EntryHandler) GetEntryFile(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
 		return
 	}
+	variant := r.URL.Query().Get("variant")
+	if variant != "" && variant != variantConverted && variant != variantOriginal {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid variant, use 'converted' or 'original'.")
+		return
+	}
 
 	// 2. Get Metadata (Crucial for File Size)
 	filemeta, err := h.Repo.GetEntry(r.Context(), repo.ULID(dbID), id)
@@ -248,8 +248,30 @@ func (h *EntryHandler) GetEntryFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A client asking for the version of its cached copy learns explicitly that the file changed
+	contentVersion := entryContentVersion(filemeta)
+	w.Header().Set(contentVersionHeader, contentVersion)
+	if version := r.URL.Query().Get("version"); version != "" && version != contentVersion {
+		utils.RespondWithError(w, http.StatusConflict, fmt.Sprintf("The file was changed, its current content version is '%s'.", contentVersion))
+		return
+	}
+
+	// The kept original is always sent as a full binary download
+	if variant == variantOriginal && filemeta.OriginalSize > 0 {
+		if h.streamOriginalFile(w, r, dbID, filemeta) {
+			h.Auditor.Log(r.Context(), "entry.download", user.Username, fmt.Sprintf("%s:%d", dbID, id), map[string]any{"variant": variantOriginal})
+		}
+		return
+	}
+
 	// Case A: JSON / Base64 Response
 	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		// Base64 inflates the payload by a third, large files must use the binary representation
+		if maxJSON := h.maxJSONFileSize(); maxJSON > 0 && int64(filemeta.Size) > maxJSON {
+			utils.RespondWithError(w, http.StatusNotAcceptable, fmt.Sprintf("File is too large for a JSON response (%d bytes, limit %d bytes). Request the binary representation instead (omit 'Accept: application/json').", filemeta.Size, maxJSON))
+			return
+		}
+
 		// Read full file (offset 0, length -1)
 		fileStream, err := h.Storage.Read(r.Context(), dbID, filemeta.ID, 0, -1)
 		if err != nil {
@@ -262,93 +284,33 @@ func (h *EntryHandler) GetEntryFile(w http.ResponseWriter, r *http.Request) {
 			filemeta.FileName = fmt.Sprintf("%d", id)
 		}
 
-		resp, err := encodeReaderAsJSON(fileStream, filemeta.FileName, filemeta.MimeType)
-		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to encode file. Error: %v", err))
-			return
-		}
-		utils.RespondWithJSON(w, http.StatusOK, resp)
+		h.respondWithReaderAsJSON(w, fileStream, filemeta.FileName, filemeta.MimeType)
 		return
 	}
 
-	// Determine Range (Streaming vs Full)
-	rangeHeader := r.Header.Get("Range")
-	fileSize := int64(filemeta.Size)
-
-	var offset int64 = 0
-	var length int64 = -1 // Read to end
-	isPartial := false
-
-	if rangeHeader != "" {
-		// Simple parser for "bytes=start-end"
-		ranges, err := parseRange(rangeHeader, fileSize)
-		if err != nil {
-			// 416 Range Not Satisfiable
-			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
-			utils.RespondWithError(w, http.StatusRequestedRangeNotSatisfiable, "Invalid Range Header")
-			return
-		}
-
-		// We only support the first range requested (multipart ranges are rare for this use case)
-		if len(ranges) > 0 {
-			isPartial = true
-			offset = ranges[0].start
-			length = ranges[0].length
-		}
-	}
-
-	// 3. Open Stream (Partial or Full)
-	fileStream, err := h.Storage.Read(r.Context(), dbID, filemeta.ID, offset, length)
-	if err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "File content not found.")
+	// Case B/C: Binary Response (Partial or Full)
+	if !h.streamEntryFile(w, r, dbID, filemeta) {
 		return
 	}
-	defer fileStream.Close()
-
-	// 4. Set Response Headers
-	w.Header().Set("Content-Type", filemeta.MimeType)
-	w.Header().Set("Accept-Ranges", "bytes") // Advertise support
-
-	if isPartial {
-		// Case B: 206 Partial Content
-		end := offset + length - 1
-		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, end, fileSize))
-		w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
-
-		// Spec: "inline" allows playback
-		if filemeta.FileName != "" {
-			w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filemeta.FileName))
-		}
-		w.WriteHeader(http.StatusPartialContent)
-
-	} else {
-		// Case C: 200 OK (Full Download)
-		w.Header().Set("Content-Length", strconv.FormatInt(fileSize, 10))
-
-		if filemeta.FileName != "" {
-			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filemeta.FileName))
-		}
-		w.WriteHeader(http.StatusOK)
-	}
 
 	// Auditor logging
 	h.Auditor.Log(r.Context(), "entry.download", user.Username, fmt.Sprintf("%s:%d", dbID, id), nil)
-
-	// 5. Stream Data
-	_, err = io.Copy(w, fileStream)
-	if err != nil {
-		// Stream interrupted
-		return
-	}
 }
 
 // @Summary Get entry metadata
 // @Description Retrieves all metadata for a single entry, including custom fields.
+// @Description The response carries a weak `ETag` that changes with any field of the entry (e.g. its `status`). Polling clients send it as `If-None-Match` and get `304 Not Modified` without body while the entry is unchanged.
+// @Description Responses with `include_comment_count` carry no ETag, as new comments do not change the entry.
 // @Tags entry
 // @Produce json
 // @Param   database_id  path  string  true  "Database ID"
 // @Param   id      path  int64   true  "Entry ID"
+// @Param   include_links query bool false "Add a _links block with the entry's URLs"
+// @Param   include_comment_count query bool false "Add the number of comments of the entry"
+// @Param   If-None-Match header string false "ETag of a previous response"
 // @Success 200 {object} EntryResponse "The full entry metadata object"
+// @Success 304 "The entry is unchanged"
+// @Header  200,304 {string} ETag "Weak ETag of the entry"
 // @Failure 400 {object} utils.ErrorResponse "Invalid request"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
 // @Failure 403 {object} utils.ErrorResponse "Forbidden"
@@ -384,24 +346,45 @@ func (h *EntryHandler) GetEntryMeta(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 3. Map to API Response Model!
-	responseObject := mapToEntryResponse(dbID, filemeta)
-
-	// 4. Set anti-caching headers before sending the JSON
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Header().Set("Pragma", "no-cache")
-	w.Header().Set("Expires", "0")
+	responseObject := mapToEntryResponse(dbID, h.redactEntry(r.Context(), dbID, filemeta))
+	etag := entryETag(responseObject)
+	if wantsLinks(r) {
+		responseObject.Links = buildEntryLinks(h.BaseURL, dbID, filemeta)
+	}
+	if wantsCommentCount(r) {
+		responses := []EntryResponse{responseObject}
+		h.addCommentCounts(r, dbID, responses)
+		responseObject = responses[0]
+		etag = ""
+	}
+
+	// 4. Set caching headers before sending the JSON, clients must revalidate with the ETag
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Header().Set("Pragma", "no-cache")
+		w.Header().Set("Expires", "0")
+	}
 
 	// 5. Auditor logging
 	h.Auditor.Log(r.Context(), "entry.read_meta", user.Username, fmt.Sprintf("%s:%d", dbID, id), nil)
 
-	// 6. Return the mapped response
+	// 6. Return the mapped response, or nothing if the client has it already
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	utils.RespondWithJSON(w, http.StatusOK, responseObject)
 }
 
 // @Summary Get an entry preview
-// @Description Retrieves a 200x200 WebP preview of an entry. Supports Content Negotiation via Accept header.
+// @Description Retrieves a 200x200 WebP preview of an entry. Waveforms of audio databases with a transparent `waveform_background` are PNG, the `Content-Type` follows the stored format.
+// @Description Supports Content Negotiation via Accept header.
 // @Tags entry
 // @Produce image/webp
+// @Produce image/png
 // @Produce json
 // @Param   database_id   path   string   true  "Database ID"
 // @Param   id       path   int64    true  "Entry ID"
@@ -430,60 +413,50 @@ func (h *EntryHandler) GetEntryPreview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 2. Read the preview file from storage
-	ioReader, err := h.Storage.ReadPreview(r.Context(), dbID, id)
+	preview, err := h.openPreview(r.Context(), dbID, id)
 	if err != nil {
 		utils.RespondWithError(w, http.StatusNotFound, "Preview not found")
 		return
 	}
-	defer ioReader.Close()
+	defer preview.Close()
 
 	// 3. Content Negotiation: Check if the client specifically requested JSON
 	acceptHeader := r.Header.Get("Accept")
 	if strings.Contains(acceptHeader, "application/json") {
-		// Read the binary data into memory
-		previewBytes, err := io.ReadAll(ioReader)
-		if err != nil {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to read preview data")
-			return
-		}
-
-		// Convert to Base64 and format as a Data URI
-		base64Data := base64.StdEncoding.EncodeToString(previewBytes)
-		dataURI := "data:image/webp;base64," + base64Data
-
-		// Construct and return the JSON response
-		jsonResp := FileJSONResponse{
-			Filename: fmt.Sprintf("%d_preview.webp", id),
-			MimeType: "image/webp",
-			Data:     dataURI,
-		}
-
-		utils.RespondWithJSON(w, http.StatusOK, jsonResp)
+		// Stream as Base64 Data URI inside the JSON response
+		h.respondWithReaderAsJSON(w, preview, fmt.Sprintf("%d_preview.%s", id, preview.Extension), preview.MimeType)
 		return
 	}
 
 	// 5. Default Response: Stream the raw binary image
-	w.Header().Set("Content-Type", "image/webp")
+	w.Header().Set("Content-Type", preview.MimeType)
 	w.WriteHeader(http.StatusOK)
 
-	if _, err := io.Copy(w, ioReader); err != nil {
+	if _, err := io.Copy(w, preview); err != nil {
 		h.Logger.Error("Failed to stream preview to client", "entry", id, "error", err)
 	}
 }
 
 // @Summary Update entry metadata
-// @Description Updates an entry's mutable metadata, including custom fields, the 'timestamp' and the 'filename'.
+// @Description Updates an entry's mutable metadata, including custom fields, the 'timestamp', the 'filename' and the 'external_id'.
+// @Description Any other key, e.g. 'width', 'duration' or 'status', or a key of 'custom_fields' that is no custom field of the database, is refused with `400` listing all offending keys in `fields`. Technical metadata is measured from the file.
+// @Description For optimistic concurrency, send the `ETag` of `GET /database/{database_id}/entry/{id}` as `If-Match`: the update is refused with `412` if the entry changed since.
+// @Description A new 'filename' must be a single path element of at most 255 bytes without control characters. Without extension it keeps the current one; the extension of another file type is replaced by the right one, or refused with `400` if `server.strict_filenames` is set.
 // @Tags entry
 // @Accept json
 // @Produce json
 // @Param   database_id   path   string                true  "Database ID"
 // @Param   id       path   int64                 true  "Entry ID"
+// @Param   If-Match header string                false "ETag the entry must still have"
 // @Param   updates  body   PostPatchEntryRequest  true  "JSON object with fields to update"
 // @Success 200 {object} EntryResponse "The full, updated entry metadata object"
-// @Failure 400 {object} utils.ErrorResponse "Invalid request"
+// @Header  200 {string} ETag "Weak ETag of the updated entry"
+// @Failure 400 {object} ImmutableFieldsResponse "Invalid request or filename, or fields that cannot be changed"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
 // @Failure 403 {object} utils.ErrorResponse "Forbidden"
 // @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
+// @Failure 409 {object} ExternalIDConflictResponse "The external_id is already used by another entry (unique_external_id)"
+// @Failure 412 {object} utils.ErrorResponse "The entry no longer matches If-Match"
 // @Failure 500 {object} utils.ErrorResponse "Internal server error"
 // @Security BasicAuth
 // @Router /database/{database_id}/entry/{id} [patch]
@@ -504,18 +477,28 @@ func (h *EntryHandler) PatchEntry(w http.ResponseWriter, r *http.Request) {
 
 	user := utils.GetUserFromContext(r.Context())
 
-	// 2. Decode the PATCH Request Body
+	// 2. Decode the PATCH Request Body, the keys are checked against the database below
 	var req = PostPatchEntryRequest{
 		FileName:     "",
 		Timestamp:    math.MinInt64,
 		CustomFields: nil,
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(body, &keys); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
-	defer r.Body.Close()
 
 	// 3. Fetch the Existing Entry and Database
 	db, err := h.Repo.GetDatabase(r.Context(), repo.ULID(dbID))
@@ -534,6 +517,20 @@ func (h *EntryHandler) PatchEntry(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Only the fields users own can be changed, technical metadata comes from the file
+	immutable, err := immutablePatchKeys(keys, db)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(immutable) > 0 {
+		utils.RespondWithJSON(w, http.StatusBadRequest, ImmutableFieldsResponse{
+			Error:  immutableFieldsMessage(immutable, db.ContentType),
+			Fields: immutable,
+		})
+		return
+	}
+
 	existingEntry, err := h.Repo.GetEntry(r.Context(), repo.ULID(dbID), id)
 	if err != nil {
 		if errors.Is(err, customerrors.ErrNotFound) {
@@ -545,19 +542,42 @@ func (h *EntryHandler) PatchEntry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 4. Apply Updates Safely (Ignoring Go zero-values)
+	// The client's copy must still be current, compared like the ETag of GetEntryMeta
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !etagMatches(ifMatch, entryETag(mapToEntryResponse(dbID, h.redactEntry(r.Context(), dbID, existingEntry)))) {
+			utils.RespondWithError(w, http.StatusPreconditionFailed, "The entry was modified, fetch it again before updating.")
+			return
+		}
+	}
+
+	// 4. Collect the Updates (Ignoring Go zero-values)
+	fields := make(map[string]any)
 
 	// Only update if the string is not empty
 	if req.FileName != "" {
-		existingEntry.FileName = req.FileName
+		fileName, err := renamedFileName(req.FileName, existingEntry, h.StrictFileNames)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		fields["filename"] = fileName
 	}
 
 	// Only update the timestamp if it was provided
 	if req.Timestamp != math.MinInt64 {
-		existingEntry.Timestamp = time.UnixMilli(req.Timestamp)
+		fields["timestamp"] = time.UnixMilli(req.Timestamp)
+	}
+
+	// An empty external ID removes it
+	if req.ExternalID != nil {
+		if err := validateExternalID(*req.ExternalID); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		fields["external_id"] = *req.ExternalID
 	}
 
-	// Merge Custom Fields after validation
+	// Add Custom Fields after validation
 	if req.CustomFields != nil {
 		err = validateCustomFields(req.CustomFields, db.CustomFields)
 		if err != nil {
@@ -565,17 +585,21 @@ func (h *EntryHandler) PatchEntry(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if existingEntry.CustomFields == nil {
-			existingEntry.CustomFields = make(map[string]any)
-		}
 		for key, value := range req.CustomFields {
-			existingEntry.CustomFields[key] = value
+			fields[key] = value
 		}
 	}
 
-	// 5. Save the Updated Entry back to the Database
-	updatedEntry, err := h.Repo.UpdateEntry(r.Context(), repo.ULID(dbID), existingEntry)
-	if err != nil {
+	// 5. Save the Updates, only user fields can be written
+	updatedEntry, err := h.Repo.UpdateEntryUserFields(r.Context(), repo.ULID(dbID), existingEntry.ID, fields)
+	if errors.Is(err, customerrors.ErrConflict) {
+		externalID, _ := fields["external_id"].(string)
+		h.respondWithExternalIDConflict(r.Context(), w, db, externalID)
+		return
+	} else if errors.Is(err, customerrors.ErrValidation) {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
 		h.Logger.Error("Failed to update entry metadata", "entry", id, "error", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to apply updates to database.")
 		return
@@ -585,23 +609,31 @@ func (h *EntryHandler) PatchEntry(w http.ResponseWriter, r *http.Request) {
 	h.Auditor.Log(r.Context(), "entry.update", user.Username, fmt.Sprintf("%s:%d", dbID, id), nil)
 
 	// 7. Map to API Response Model and Return
-	responseObject := mapToEntryResponse(dbID, updatedEntry)
+	responseObject := mapToEntryResponse(dbID, h.redactEntry(r.Context(), dbID, updatedEntry))
+	w.Header().Set("ETag", entryETag(responseObject))
 	utils.RespondWithJSON(w, http.StatusOK, responseObject)
 }
 
 // @Summary Bulk delete entries
-// @Description Deletes multiple entries in a single atomic transaction. Updates database statistics only once.
+// @Description Deletes multiple entries. All rows and the database statistics are removed in a single atomic transaction; files and previews are deleted only after the commit.
+// @Description IDs that do not exist are listed in `missing`. Files that could not be removed from storage are reported per ID in `file_errors`, the entries are deleted regardless.
+// @Description If any entry is under legal hold, nothing is deleted and the request fails with `403`, listing the held IDs in `held`.
+// @Description With `async=true`, or more IDs than `server.async_delete_threshold` (default 1000), a background job is started instead and `202` returns it.
+// @Description The job deletes the rows in transactions of 500 entries, then their files, and skips entries under legal hold or still being processed like housekeeping.
+// @Description Poll its progress with GET /jobs/{job_id}; a restart resumes it.
 // @Tags database
 // @Accept  json
 // @Produce json
 // @Param   database_id  path   string  true  "Database ID"
+// @Param   async   query  bool    false "Delete in a background job, also below the threshold"
 // @Param   body    body   BulkDeleteRequest true "JSON object containing a list of Entry IDs to delete"
 // @Success 200 {object} BulkDeleteResponse "Summary of the deletion operation"
+// @Success 202 {object} JobResponse "The deletion job was started"
 // @Failure 400 {object} utils.ErrorResponse "Invalid request, missing id, or empty IDs list"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
-// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanDelete role)"
+// @Failure 403 {object} BulkDeleteResponse "Forbidden (Requires CanDelete role), or entries are under legal hold"
 // @Failure 404 {object} utils.ErrorResponse "Database not found"
-// @Failure 500 {object} utils.ErrorResponse "Transaction failed"
+// @Failure 500 {object} BulkDeleteResponse "Transaction failed, no entry or file was deleted"
 // @Security BasicAuth
 // @Router /database/{database_id}/entries/delete [post]
 func (h *EntryHandler) DeleteEntries(w http.ResponseWriter, r *http.Request) {
@@ -615,14 +647,23 @@ func (h *EntryHandler) DeleteEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 2. Delete the files and entries
-	deletedMeta, err := shared.DeleteMultipleSafe(ctx, h.Repo, h.Storage, repo.ULID(dbID), req.IDs)
+	// Large lists would outlast proxy timeouts, they are deleted in the background
+	async, _ := strconv.ParseBool(r.URL.Query().Get("async"))
+	if h.HouseKeeper != nil && (async || (h.AsyncDeleteThreshold > 0 && len(req.IDs) > h.AsyncDeleteThreshold)) {
+		h.startDeletionJob(w, r, repo.ULID(dbID), req.IDs)
+		return
+	}
+
+	// 2. Delete the entries, then the files
+	result, err := shared.DeleteMultipleAtomic(ctx, h.Repo, h.Storage, repo.ULID(dbID), req.IDs)
 
 	// 3. Calculate disk space freed
 	var spaceFreed uint64 = 0
-	var deletedCount = len(deletedMeta)
-	for _, e := range deletedMeta {
-		spaceFreed += e.Filesize + e.PreviewSize
+	var deletedCount = len(result.Deleted)
+	deletedIDs := make([]int64, 0, deletedCount)
+	for _, e := range result.Deleted {
+		spaceFreed += e.Filesize + e.PreviewSize + e.OriginalSize
+		deletedIDs = append(deletedIDs, e.ID)
 	}
 
 	// Safely extract the error message if one exists
@@ -631,6 +672,10 @@ func (h *EntryHandler) DeleteEntries(w http.ResponseWriter, r *http.Request) {
 		errorMsg = err.Error()
 	}
 
+	for id, fileErr := range result.FileErrors {
+		h.Logger.Warn("Entry deleted but its files could not be removed", "database_id", dbID, "entry", id, "error", fileErr)
+	}
+
 	// 4. Respond
 	resp := BulkDeleteResponse{
 		DatabaseID:      dbID,
@@ -638,6 +683,9 @@ func (h *EntryHandler) DeleteEntries(w http.ResponseWriter, r *http.Request) {
 		SpaceFreedBytes: spaceFreed,
 		Message:         fmt.Sprintf("Successfully deleted %d entries.", deletedCount),
 		Errors:          errorMsg, // Safe to use now!
+		Deleted:         deletedIDs,
+		Missing:         result.Missing,
+		FileErrors:      result.FileErrors,
 	}
 
 	// check for internal status or user errors
@@ -647,32 +695,62 @@ func (h *EntryHandler) DeleteEntries(w http.ResponseWriter, r *http.Request) {
 			status = http.StatusInternalServerError
 		} else if errors.Is(err, customerrors.ErrDatabaseNotExisting) {
 			status = http.StatusNotFound
+		} else if errors.Is(err, customerrors.ErrLegalHold) {
+			status = http.StatusForbidden
+			resp.Message = "No entries were deleted, some are under legal hold."
+			resp.Held, _ = h.Repo.GetHeldEntries(ctx, repo.ULID(dbID), req.IDs)
 		} else {
 			// Optional: Fallback status for any other unexpected errors
 			status = http.StatusInternalServerError
 		}
 	}
 
-	h.Auditor.Log(r.Context(), "entries.delete", user.Username, dbID, map[string]any{"count": deletedCount})
+	h.Auditor.Log(r.Context(), "entries.delete", user.Username, dbID, map[string]any{
+		"count":   deletedCount,
+		"missing": len(result.Missing),
+	})
 	utils.RespondWithJSON(w, status, resp)
 }
 
+// startDeletionJob starts the background deletion of entries and responds with the job.
+func (h *EntryHandler) startDeletionJob(w http.ResponseWriter, r *http.Request, dbID repo.ULID, ids []int64) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+
+	job, err := h.HouseKeeper.StartDeletionJob(ctx, dbID, ids, user.Username)
+	if errors.Is(err, customerrors.ErrDatabaseNotExisting) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to start deletion job", "database_id", dbID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to start deletion job")
+		return
+	}
+
+	w.Header().Set("Location", h.BaseURL+"/api/jobs/"+job.ID.String())
+	utils.RespondWithJSON(w, http.StatusAccepted, toJobResponse(job))
+}
+
 // @Summary Get entries from a database (basic)
 // @Description Retrieves a paginated list of entries from a specific database. Only supports time-based filters.
 // @Tags database
 // @Produce json
 // @Param   database_id  path   string  true   "Database ID"
-// @Param   limit   query  int     false  "Number of entries to return (default 30)"
+// @Param   limit   query  int     false  "Number of entries to return (default 100), must be positive. Larger limits than the maximum page size (default 1000) are clamped and reported in the X-Page-Limit-Clamped header"
 // @Param   offset  query  int     false  "Offset for pagination (default 0)"
 // @Param   order   query  string  false  "Sort order ('asc' or 'desc', default 'desc')"
 // @Param   sort_by query  string  false  "The field to sort the results by ('timestamp', 'created_at', 'updated_at', 'id', default 'timestamp')"
 // @Param   time_field query string false  "The field that tstart and tend should filter against ('timestamp', 'created_at', 'updated_at', default 'timestamp')"
-// @Param   tstart  query  int64   false  "Start timestamp (Unix milliseconds)"
-// @Param   tend    query  int64   false  "End timestamp (Unix milliseconds)"
+// @Param   tstart  query  string  false  "Start timestamp (Unix milliseconds) or relative time: 'now' or 'now' with a signed offset in s, m, h, d or w, e.g. 'now-24h'"
+// @Param   tend    query  string  false  "End timestamp (Unix milliseconds) or relative time like 'now' or 'now-1h'"
+// @Param   include_links query bool false "Add a _links block with the URLs of each entry"
+// @Param   include_comment_count query bool false "Add the number of comments of each entry"
+// @Param   fields  query  string  false  "Comma-separated list of fields to return (the id is always included), all if empty"
 // @Success 200 {array} EntryResponse "Returns an array of entry metadata objects"
-// @Failure 400 {object} utils.ErrorResponse "Missing id param or invalid parameter formats"
+// @Failure 400 {object} utils.ErrorResponse "Missing id param, invalid parameter formats, a limit that is not positive or unknown field"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
-// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role)"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role, or sorting/selecting a sensitive field without CanEdit)"
 // @Failure 404 {object} utils.ErrorResponse "Database not found"
 // @Failure 500 {object} utils.ErrorResponse "Failed to retrieve entries"
 // @Security BasicAuth
@@ -682,21 +760,29 @@ func (h *EntryHandler) QueryEntries(w http.ResponseWriter, r *http.Request) {
 
 	user := utils.GetUserFromContext(r.Context())
 
-	limit := parseQueryInt(r, "limit", 30)
+	limit := parseQueryInt(r, "limit", 0)
+	if r.URL.Query().Has("limit") && limit <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid limit: must be positive, omit it for the default page size")
+		return
+	}
+	limit = h.pageLimit(w, limit)
 	offset := parseQueryInt(r, "offset", 0)
 
 	order := r.URL.Query().Get("order")
 	sortBy := r.URL.Query().Get("sort_by")
 	timeField := r.URL.Query().Get("time_field")
 
-	var tStart, tEnd time.Time
-	tStartQuery := parseQueryInt64(r, "tstart", math.MinInt64)
-	if tStartQuery != math.MinInt64 {
-		tStart = time.UnixMilli(tStartQuery)
+	// Relative times of both bounds are evaluated at the same instant
+	now := time.Now()
+	tStart, err := parseQueryTime(r, "tstart", now)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-	tEndQuery := parseQueryInt64(r, "tend", math.MaxInt64)
-	if tEndQuery != math.MaxInt64 {
-		tEnd = time.UnixMilli(tEndQuery)
+	tEnd, err := parseQueryTime(r, "tend", now)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	opts := repo.QueryOptions{
@@ -707,6 +793,7 @@ func (h *EntryHandler) QueryEntries(w http.ResponseWriter, r *http.Request) {
 		TimeField: timeField,
 		TStart:    tStart,
 		TEnd:      tEnd,
+		Fields:    parseQueryList(r, "fields"),
 	}
 
 	if err := opts.Validate(); err != nil {
@@ -714,17 +801,42 @@ func (h *EntryHandler) QueryEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sorting by or selecting a field the user may not read would reveal its values
+	if err := h.fieldRedaction(r.Context(), dbID).checkAccess(append([]string{opts.SortBy}, opts.Fields...)...); err != nil {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	// The custom fields are only needed to project the response
+	var customFields []repo.CustomFieldDef
+	if len(opts.Fields) > 0 {
+		db, err := h.Repo.GetDatabase(r.Context(), repo.ULID(dbID))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+			return
+		}
+		customFields = db.CustomFields
+	}
+
 	entries, err := h.Repo.GetEntries(r.Context(), repo.ULID(dbID), opts)
 	if err != nil {
+		if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.Logger.Error("Failed to query entries", "error", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve entries")
 		return
 	}
 
 	// Map DB models to API responses
-	results := make([]EntryResponse, 0, len(entries))
-	for _, entry := range entries {
-		results = append(results, mapToEntryResponse(dbID, entry))
+	responses := h.mapToEntryResponses(r.Context(), dbID, entries, wantsLinks(r))
+	if wantsCommentCount(r) {
+		h.addCommentCounts(r, dbID, responses)
+	}
+	var results any = responses
+	if len(opts.Fields) > 0 {
+		results = projectEntryResponses(responses, opts.Fields, customFields)
 	}
 
 	h.Auditor.Log(r.Context(), "entries.query", user.Username, dbID, nil)
@@ -733,15 +845,32 @@ func (h *EntryHandler) QueryEntries(w http.ResponseWriter, r *http.Request) {
 
 // @Summary Search for entries in a database (complex)
 // @Description Retrieves a list of entry metadata matching the complex, nested filter criteria provided in the request body.
+// @Description With `fields`, only the listed fields (plus the id) are selected and returned.
+// @Description TEXT fields accept `LIKE` with the pattern as given and the operators `starts_with`, `ends_with` and `contains`, which add the wildcards and match `%` and `_` literally.
+// @Description `equals_ci` is `=` ignoring the case, `contains_ci` an explicitly case-insensitive `contains`. Only the case of ASCII letters is ignored, `É` and `é` differ. `starts_with` can use an index on the field.
+// @Description The `MATCH` operator runs an FTS5 full-text query, e.g. `"backup AND disk*"`, on the custom fields listed in `config.fulltext_fields`.
+// @Description Sorting by `fts_rank` orders by the relevance of the first `MATCH` condition, `desc` returns the best matches first.
+// @Description Sorts on TEXT fields take a `collation`: `binary` (default, byte order), `nocase` (ignores the case of ASCII letters) or `unicode`
+// @Description (alphabetical in the locale of `database.sort_locale`, e.g. "Öl" next to "Ol"). Other fields refuse a collation with `400`.
+// @Description `geo` restricts the results to a `bbox` (`min_lat`, `min_lon`, `max_lat`, `max_lon`, edges included) or a `radius`
+// @Description (`center` with `lat` and `lon`, `meters`) on databases declaring `config.geo_fields`; sorting by `geo_distance` orders a radius search by the distance to its center, nearest first unless `desc`.
+// @Description Conditions on `timestamp`, `created_at`, `updated_at` and `client_timestamp` accept Unix milliseconds or relative times:
+// @Description `"now"` or `"now"` followed by a signed whole number of `s`, `m`, `h`, `d` (24 hours) or `w`, e.g. `"now-24h"` or `"now-7d"`.
+// @Description They are evaluated at the same instant for all conditions of the request.
+// @Description Sensitive custom fields are only returned to users with the CanEdit or CanAdmin role.
+// @Description Without `pagination.limit` 100 entries are returned, larger limits than the maximum page size (default 1000)
+// @Description are clamped and reported in the `X-Page-Limit-Clamped` header. A limit of 0 or below returns 400, it does not mean unlimited.
 // @Tags database
 // @Accept  json
 // @Produce json
 // @Param   database_id  path   string        true  "Database ID"
 // @Param   search  body   repository.SearchRequest  true  "JSON body defining filter, sort, and pagination logic"
+// @Param   include_links query bool false "Add a _links block with the URLs of each entry"
+// @Param   include_comment_count query bool false "Add the number of comments of each entry"
 // @Success 200 {array} EntryResponse "Returns an array of matching results (even if empty)"
-// @Failure 400 {object} utils.ErrorResponse "Missing id, invalid JSON, missing limit, or invalid filter/sort"
+// @Failure 400 {object} utils.ErrorResponse "Missing id, invalid JSON, negative offset, a limit that is not positive, or invalid filter/sort/fields"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
-// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role)"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role, or filtering on a sensitive field without CanEdit)"
 // @Failure 404 {object} utils.ErrorResponse "Database not found"
 // @Failure 500 {object} utils.ErrorResponse "Internal server error"
 // @Security BasicAuth
@@ -764,36 +893,66 @@ func (h *EntryHandler) SearchEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	searchReq := searchPayload.toModel()
+	if err := checkPagination(searchPayload.Pagination); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	searchReq, err := withGeoFields(db, searchRequestToModel(searchPayload))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	searchReq.Pagination.Limit = h.pageLimit(w, searchReq.Pagination.Limit)
+	if err := h.fieldRedaction(r.Context(), dbID).checkSearch(searchReq); err != nil {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
 	entries, err := h.Repo.SearchEntries(r.Context(), repo.ULID(dbID), searchReq, db.CustomFields)
 	if err != nil {
+		if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.Logger.Error("Search failed", "error", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
 	// Map DB models to API responses
-	results := make([]EntryResponse, 0, len(entries))
-	for _, entry := range entries {
-		results = append(results, mapToEntryResponse(dbID, entry))
+	responses := h.mapToEntryResponses(r.Context(), dbID, entries, wantsLinks(r))
+	if wantsCommentCount(r) {
+		h.addCommentCounts(r, dbID, responses)
+	}
+	var results any = responses
+	if len(searchReq.Fields) > 0 {
+		results = projectEntryResponses(responses, searchReq.Fields, db.CustomFields)
 	}
 
 	h.Auditor.Log(r.Context(), "entries.search", user.Username, dbID, nil)
 	utils.RespondWithJSON(w, http.StatusOK, results)
 }
 
-// @Summary Export entries as ZIP
-// @Description Streams a ZIP archive containing the files and metadata (CSV) for the specified entries using io.Pipe.
+// @Summary Export entries as ZIP or Parquet
+// @Description Streams a ZIP archive containing the files and metadata (CSV) for the specified entries directly to the response, one open file at a time.
+// @Description Only `server.max_concurrent_exports` exports run at the same time, further requests get `429` with a Retry-After header.
+// @Description An export is aborted if the client accepts no data for `server.export_write_timeout`.
+// @Description With `format: "parquet"` only the metadata is exported, as a single Parquet file with typed columns (timestamps as milliseconds, custom fields nullable).
+// @Description Sensitive custom fields are only exported for users with the CanEdit or CanAdmin role.
+// @Description With `include_comments: true` the ZIP holds the comments of each commented entry as comments/<id>.json.
+// @Description With `include_checksums: true` every file is hashed while it is streamed, and the ZIP ends with `checksums.sha256` (sha256sum format) and `manifest.json` (entry, path, size and SHA-256 of every file plus the export parameters), for verification with `mediahub verify-export`.
 // @Tags database
 // @Accept  json
 // @Produce application/zip
+// @Produce application/vnd.apache.parquet
 // @Param   database_id  path   string        true  "Database ID"
-// @Param   body    body   ExportRequest  true  "List of Entry IDs to export"
-// @Success 200 {file} file "ZIP Archive containing files and entries.csv"
-// @Failure 400 {object} utils.ErrorResponse "Missing id query parameter or empty IDs list"
+// @Param   body    body   ExportRequest  true  "List of Entry IDs to export and the format"
+// @Success 200 {file} file "ZIP Archive containing files and entries.csv, or the Parquet file"
+// @Failure 400 {object} utils.ErrorResponse "Missing id query parameter, empty IDs list or unknown format"
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
 // @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role)"
 // @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 429 {object} utils.ErrorResponse "Too many exports are running"
 // @Failure 500 {object} utils.ErrorResponse "ZIP streaming failed"
 // @Security BasicAuth
 // @Router /database/{database_id}/entries/export [post]
@@ -807,6 +966,13 @@ func (h *EntryHandler) ExportEntries(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request or empty IDs list")
 		return
 	}
+	if req.Format == "" {
+		req.Format = exportFormatZip
+	}
+	if req.Format != exportFormatZip && req.Format != exportFormatParquet {
+		utils.RespondWithError(w, http.StatusBadRequest, "Unknown format, expected 'zip' or 'parquet'")
+		return
+	}
 
 	// Verify database existence and fetch custom fields
 	db, err := h.Repo.GetDatabase(r.Context(), repo.ULID(dbID))
@@ -815,132 +981,174 @@ func (h *EntryHandler) ExportEntries(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only the custom fields the user may read are exported
+	redaction := h.fieldRedaction(r.Context(), dbID)
+	exportFields := redaction.fields(db.CustomFields)
+	auditDetails := map[string]any{"count": len(req.IDs), "include_originals": req.IncludeOriginals, "include_comments": req.IncludeComments, "format": req.Format}
+
+	// Each export holds a connection and open files until the client has downloaded it
+	if !h.Exports.tryAcquire() {
+		h.Logger.Warn("Export rejected, too many exports running", "database_id", dbID, "user", user.Username)
+		w.Header().Set("Retry-After", strconv.Itoa(int(exportRetryAfter.Seconds())))
+		utils.RespondWithError(w, http.StatusTooManyRequests, "Too many exports are running, retry later.")
+		return
+	}
+	defer h.Exports.release()
+
+	if req.Format == exportFormatParquet {
+		h.Auditor.Log(r.Context(), "entries.export", user.Username, dbID, auditDetails)
+		h.exportParquet(r.Context(), w, db, req, exportFields)
+		return
+	}
+
 	// Set headers for ZIP download
 	w.Header().Set("Content-Type", "application/zip")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s_export.zip\"", db.Name))
 
-	// Use io.Pipe to stream generation directly to the HTTP response
-	pr, pw := io.Pipe()
+	h.Auditor.Log(r.Context(), "entries.export", user.Username, dbID, auditDetails)
 
-	go func() {
-		defer pw.Close()
+	// Stream the archive directly to the response, a stalled client aborts it after the write timeout
+	ctx := r.Context()
+	start := time.Now()
+	out := h.newExportWriter(w)
+	archive := newExportArchive(zip.NewWriter(out), req.IncludeChecksums)
+	exported := 0
+	defer func() { h.logExport(ctx, dbID, exportFormatZip, start, out, exported) }()
+
+	// 1. Create CSV file inside ZIP
+	csvFile, err := archive.create("entries.csv", 0)
+	if err != nil {
+		h.Logger.Error("Failed to create CSV in zip", "error", err)
+		return
+	}
+
+	csvWriter := csv.NewWriter(csvFile)
+
+	// --- Build dynamic CSV Header ---
+	header := []string{"id", "filename", "timestamp", "filesize", "previewsize", "mime_type", "status", "external_id", "uploaded_by", "upload_ip", "upload_user_agent", "created_at", "updated_at"}
+	if req.IncludeOriginals {
+		header = append(header, "original_filesize", "original_mime_type")
+	}
+	for _, cf := range exportFields {
+		header = append(header, cf.Name)
+	}
+	_ = csvWriter.Write(header)
 
-		zipWriter := zip.NewWriter(pw)
-		defer zipWriter.Close()
+	// Keep track of valid entries so we don't have to query the DB twice
+	var validEntries []repo.Entry
 
-		// 1. Create CSV file inside ZIP
-		csvFile, err := zipWriter.Create("entries.csv")
+	// Pass 1: Fetch metadata and write all CSV rows
+	for _, id := range req.IDs {
+		// Fetch metadata
+		entry, err := h.Repo.GetEntry(ctx, repo.ULID(dbID), id)
 		if err != nil {
-			h.Logger.Error("Failed to create CSV in zip", "error", err)
-			pw.CloseWithError(err)
-			return
+			h.Logger.Warn("Skipping entry in export (not found)", "id", id)
+			continue
 		}
 
-		csvWriter := csv.NewWriter(csvFile)
-
-		// --- Build dynamic CSV Header ---
-		header := []string{"id", "filename", "timestamp", "filesize", "previewsize", "mime_type", "status"}
-		for _, cf := range db.CustomFields {
-			header = append(header, cf.Name)
+		validEntries = append(validEntries, entry)
+
+		// --- Build dynamic CSV Row ---
+		row := []string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.FileName,
+			entry.Timestamp.Format(time.RFC3339),
+			strconv.FormatUint(entry.Size, 10),
+			strconv.FormatUint(entry.PreviewSize, 10),
+			entry.MimeType,
+			strconv.Itoa(int(entry.Status)),
+			entry.ExternalID,
+			entry.Origin.UploadedBy,
+			entry.Origin.ClientIP,
+			entry.Origin.UserAgent,
+			entry.CreatedAt.Format(time.RFC3339),
+			entry.UpdatedAt.Format(time.RFC3339),
+		}
+		if req.IncludeOriginals {
+			row = append(row, strconv.FormatUint(entry.OriginalSize, 10), entry.OriginalMimeType)
 		}
-		_ = csvWriter.Write(header)
-
-		// Keep track of valid entries so we don't have to query the DB twice
-		var validEntries []repo.Entry
-
-		// Pass 1: Fetch metadata and write all CSV rows
-		for _, id := range req.IDs {
-			// Fetch metadata
-			entry, err := h.Repo.GetEntry(r.Context(), repo.ULID(dbID), id)
-			if err != nil {
-				h.Logger.Warn("Skipping entry in export (not found)", "id", id)
-				continue
-			}
 
-			validEntries = append(validEntries, entry)
-
-			// --- Build dynamic CSV Row ---
-			row := []string{
-				strconv.FormatInt(entry.ID, 10),
-				entry.FileName,
-				entry.Timestamp.Format(time.RFC3339),
-				strconv.FormatUint(entry.Size, 10),
-				strconv.FormatUint(entry.PreviewSize, 10),
-				entry.MimeType,
-				strconv.Itoa(int(entry.Status)),
+		// Append custom field values safely
+		for _, cf := range exportFields {
+			val, exists := entry.CustomFields[cf.Name]
+			if !exists || val == nil {
+				row = append(row, "") // Empty column if no value
+			} else {
+				row = append(row, fmt.Sprintf("%v", val))
 			}
+		}
 
-			// Append custom field values safely
-			for _, cf := range db.CustomFields {
-				val, exists := entry.CustomFields[cf.Name]
-				if !exists || val == nil {
-					row = append(row, "") // Empty column if no value
-				} else {
-					row = append(row, fmt.Sprintf("%v", val))
-				}
-			}
+		_ = csvWriter.Write(row)
+	}
 
-			_ = csvWriter.Write(row)
-		}
+	// Flush the CSV buffer to the zip file BEFORE creating new zip entries
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		h.Logger.Error("Failed to flush CSV", "error", err)
+	}
 
-		// Flush the CSV buffer to the zip file BEFORE creating new zip entries
-		csvWriter.Flush()
-		if err := csvWriter.Error(); err != nil {
-			h.Logger.Error("Failed to flush CSV", "error", err)
+	// Pass 2: Stream the files, previews and originals into the ZIP, one open file at a time
+	for _, entry := range validEntries {
+		if out.err != nil || ctx.Err() != nil {
+			return
 		}
 
-		// Pass 2: Stream the files into the ZIP
-		// Pass 2: Stream the files and previews into the ZIP
-		for _, entry := range validEntries {
-			// --- 1. Stream the Main File ---
-			// Fetch file stream from storage
-			fileStream, err := h.Storage.Read(r.Context(), dbID, entry.ID, 0, -1)
-			if err != nil {
+		// --- 1. Stream the Main File ---
+		err := writeFileToZip(archive, fmt.Sprintf("files/%d_%s", entry.ID, sanitizeFileName(entry.FileName)), entry.ID, func() (io.ReadCloser, error) {
+			return h.Storage.Read(ctx, dbID, entry.ID, 0, -1)
+		})
+		if err != nil {
+			if out.err == nil {
 				h.Logger.Warn("Failed to read file from storage for export", "id", entry.ID, "error", err)
-				continue // If the main file fails, we skip this entry entirely
 			}
-
-			// Create file inside ZIP
-			zipEntryPath := fmt.Sprintf("files/%d_%s", entry.ID, entry.FileName)
-			zipFile, err := zipWriter.Create(zipEntryPath)
-			if err != nil {
-				fileStream.Close()
-				h.Logger.Warn("Failed to create zip entry for file", "id", entry.ID, "error", err)
-				continue
+			continue // If the main file fails, we skip this entry entirely
+		}
+		exported++
+
+		// --- 2. Stream the Preview File (if it exists) ---
+		// We use the database metadata to quickly check if a preview was generated
+		if entry.PreviewSize > 0 {
+			preview, err := h.openPreview(ctx, dbID, entry.ID)
+			if err == nil {
+				err = writeFileToZip(archive, fmt.Sprintf("previews/%d.%s", entry.ID, preview.Extension), entry.ID, func() (io.ReadCloser, error) {
+					return preview, nil
+				})
 			}
+			if err != nil && out.err == nil {
+				h.Logger.Warn("Failed to read preview from storage for export", "id", entry.ID, "error", err)
+			}
+		}
 
-			// Stream content into ZIP
-			_, _ = io.Copy(zipFile, fileStream)
-			fileStream.Close()
-
-			// --- 2. Stream the Preview File (if it exists) ---
-			// We use the database metadata to quickly check if a preview was generated
-			if entry.PreviewSize > 0 {
-				previewStream, err := h.Storage.ReadPreview(r.Context(), dbID, entry.ID)
-				if err != nil {
-					h.Logger.Warn("Failed to read preview from storage for export", "id", entry.ID, "error", err)
-				} else {
-					// Create preview file inside ZIP
-					zipPreviewPath := fmt.Sprintf("previews/%d.webp", entry.ID)
-					zipPreviewFile, err := zipWriter.Create(zipPreviewPath)
-					if err != nil {
-						h.Logger.Warn("Failed to create zip entry for preview", "id", entry.ID, "error", err)
-					} else {
-						// Stream preview content into ZIP
-						_, _ = io.Copy(zipPreviewFile, previewStream)
-					}
-					previewStream.Close()
-				}
+		// --- 3. Stream the kept Original (if requested and kept) ---
+		if req.IncludeOriginals && entry.OriginalSize > 0 {
+			err := writeFileToZip(archive, fmt.Sprintf("originals/%d_%s", entry.ID, sanitizeFileName(originalFileName(entry))), entry.ID, func() (io.ReadCloser, error) {
+				return h.Storage.ReadOriginal(ctx, dbID, entry.ID)
+			})
+			if err != nil && out.err == nil {
+				h.Logger.Warn("Failed to read original from storage for export", "id", entry.ID, "error", err)
 			}
 		}
-	}()
 
-	h.Auditor.Log(r.Context(), "entries.export", user.Username, dbID, map[string]any{"count": len(req.IDs)})
+		// --- 4. Add the Comments (if requested and there are any) ---
+		if req.IncludeComments {
+			h.exportComments(ctx, archive, dbID, entry.ID)
+		}
+	}
 
-	// Stream the pipe reader directly to the response writer
-	if _, err := io.Copy(w, pr); err != nil {
-		h.Logger.Error("Failed to stream ZIP to client", "error", err)
+	manifest := exportmanifest.Manifest{
+		CreatedAt:    start.UTC(),
+		DatabaseID:   dbID,
+		DatabaseName: db.Name,
+		ExportedBy:   user.Username,
+		Request:      req,
+	}
+	if err := archive.close(manifest); err != nil {
+		if out.err == nil {
+			h.Logger.Error("Failed to finish ZIP export", "error", err)
+		}
+		return
 	}
+	out.finish()
 }
 
 // @Summary Bulk import entries
@@ -957,6 +1165,7 @@ func (h *EntryHandler) ExportEntries(w http.ResponseWriter, r *http.Request) {
 // @Failure 401 {object} utils.ErrorResponse "Unauthorized"
 // @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanCreate role)"
 // @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 409 {object} utils.ErrorResponse "The rows of entries.csv exceed max_entries_per_database"
 // @Failure 415 {object} utils.ErrorResponse "Unsupported Media Type (Not a ZIP archive)"
 // @Failure 500 {object} utils.ErrorResponse "Internal Server Error"
 // @Security BasicAuth
@@ -977,8 +1186,8 @@ func (h *EntryHandler) ImportEntries(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 2. Parse Multipart Form
-	// Use the configured MaxSyncUploadSizeBytes to limit memory consumption during parsing
-	if err := r.ParseMultipartForm(h.MaxSyncUploadSizeBytes); err != nil {
+	// Use the configured sync upload size to limit memory consumption during parsing
+	if err := r.ParseMultipartForm(h.maxSyncUploadSize()); err != nil {
 		h.Logger.Warn("Failed to parse multipart form for import", "error", err)
 		utils.RespondWithError(w, http.StatusBadRequest, "Failed to parse multipart form.")
 		return
@@ -1057,6 +1266,14 @@ func (h *EntryHandler) ImportEntries(w http.ResponseWriter, r *http.Request) {
 		destFile.Close()
 	}
 
+	// The whole batch has to fit, instead of importing it up to the limit. Unreadable archives are left to the job to report.
+	if h.Capacity.MaxEntriesPerDatabase > 0 {
+		if rows, err := countImportRows(tempFilePath); err == nil && !h.checkCapacity(w, db, rows) {
+			os.Remove(tempFilePath)
+			return
+		}
+	}
+
 	// 6. Launch Background Worker
 	// Pass context.Background() because the HTTP request context will cancel when we return the response
 	go h.processImportJob(context.Background(), db, user.Username, tempFilePath, importConfig)
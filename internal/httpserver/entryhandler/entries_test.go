@@ -0,0 +1,1467 @@
+package entryhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/image/draw"
+)
+
+func TestPageLimitClamp(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "paging", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for i := range 5 {
+		if _, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "f.bin", Status: repo.EntryStatusReady, Timestamp: time.UnixMilli(int64(i + 1)), MimeType: "application/octet-stream"}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+	}
+
+	limits := repo.PageLimits{Default: 2, Max: 3}
+	r.PageLimits = limits
+	h := &EntryHandler{
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor:    audit.NewAlNoopLogger(),
+		Repo:       r,
+		PageLimits: limits,
+	}
+	do := func(handler http.HandlerFunc, method, target, body string) (*httptest.ResponseRecorder, int) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		var entries []EntryResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &entries)
+		return rec, len(entries)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		handler   http.HandlerFunc
+		method    string
+		target    string
+		body      string
+		wantCode  int
+		wantCount int
+		clamped   string
+	}{
+		{"list default", h.QueryEntries, http.MethodGet, "/entries", "", http.StatusOK, 2, ""},
+		{"list within limit", h.QueryEntries, http.MethodGet, "/entries?limit=3", "", http.StatusOK, 3, ""},
+		{"list clamped", h.QueryEntries, http.MethodGet, "/entries?limit=1000000", "", http.StatusOK, 3, "3"},
+		{"list zero limit", h.QueryEntries, http.MethodGet, "/entries?limit=0", "", http.StatusBadRequest, 0, ""},
+		{"list negative offset", h.QueryEntries, http.MethodGet, "/entries?offset=-1", "", http.StatusBadRequest, 0, ""},
+		{"search default", h.SearchEntries, http.MethodPost, "/search", `{}`, http.StatusOK, 2, ""},
+		{"search clamped", h.SearchEntries, http.MethodPost, "/search", `{"pagination":{"limit":1000000}}`, http.StatusOK, 3, "3"},
+		{"search zero limit", h.SearchEntries, http.MethodPost, "/search", `{"pagination":{"limit":0}}`, http.StatusBadRequest, 0, ""},
+		{"search negative offset", h.SearchEntries, http.MethodPost, "/search", `{"pagination":{"offset":-5}}`, http.StatusBadRequest, 0, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, count := do(tc.handler, tc.method, tc.target, tc.body)
+			if rec.Code != tc.wantCode || count != tc.wantCount {
+				t.Errorf("expected %d with %d entries, got %d with %d: %s", tc.wantCode, tc.wantCount, rec.Code, count, rec.Body.String())
+			}
+			if got := rec.Header().Get(pageLimitClampedHeader); got != tc.clamped {
+				t.Errorf("expected clamp header %q, got %q", tc.clamped, got)
+			}
+		})
+	}
+}
+
+func TestPostEntryRejectsEmptyAndTruncatedUploads(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "truncation_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+	}
+
+	form := func(content string) ([]byte, string) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", `{"timestamp": 1700000000000}`)
+		part, _ := mw.CreateFormFile("file", "camera.jpg")
+		part.Write([]byte(content))
+		mw.Close()
+		return body.Bytes(), mw.FormDataContentType()
+	}
+	post := func(body []byte, contentType string, contentLength int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/entry", bytes.NewReader(body))
+		req.ContentLength = contentLength
+		req.Header.Set("Content-Type", contentType)
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "camera"}))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		return rec
+	}
+
+	// 1. An empty file part
+	empty, contentType := form("")
+	if rec := post(empty, contentType, int64(len(empty))); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "empty") {
+		t.Errorf("expected 400 for an empty file, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 2. The body ends before the announced length, inside the file part
+	full, contentType := form(strings.Repeat("jpeg", 1000))
+	if rec := post(full[:len(full)/2], contentType, int64(len(full))); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "truncated") {
+		t.Errorf("expected 400 for a truncated body, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Neither left an entry or a file behind
+	entries, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 100})
+	if err != nil {
+		t.Fatalf("failed to get entries: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+	filepath.WalkDir(store.RootPath, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			t.Errorf("expected no stored files, found %s", path)
+		}
+		return nil
+	})
+
+	// 3. A complete upload still works
+	if rec := post(full, contentType, int64(len(full))); rec.Code != http.StatusCreated {
+		t.Errorf("expected 201 for a complete upload, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPostEntryMalformedForms(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "form_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+	}
+
+	type part struct {
+		name, fileName, content string
+	}
+	const boundary = "mediahub-form-test"
+	const contentType = "multipart/form-data; boundary=" + boundary
+	form := func(parts ...part) string {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.SetBoundary(boundary)
+		for _, p := range parts {
+			if p.fileName != "" {
+				w, _ := mw.CreateFormFile(p.name, p.fileName)
+				w.Write([]byte(p.content))
+			} else {
+				mw.WriteField(p.name, p.content)
+			}
+		}
+		mw.Close()
+		return body.String()
+	}
+	post := func(body string, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/entry", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "camera"}))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		return rec
+	}
+
+	metadata := part{"metadata", "", `{"timestamp": 1700000000000}`}
+	file := part{"file", "scan.bin", "payload"}
+	for _, tc := range []struct {
+		name        string
+		body        string
+		contentType string
+		wantCode    int
+		wantMessage string
+	}{
+		{"not multipart", `{"timestamp": 1}`, "application/json", http.StatusUnsupportedMediaType, "multipart/form-data"},
+		{"missing boundary", form(metadata, file), "multipart/form-data", http.StatusBadRequest, "boundary"},
+		{"missing metadata", form(file), contentType, http.StatusBadRequest, "Missing 'metadata' part"},
+		{"missing file", form(metadata), contentType, http.StatusBadRequest, "Missing 'file' part"},
+		{"empty file", form(metadata, part{"file", "scan.bin", ""}), contentType, http.StatusBadRequest, "'file' part is empty"},
+		{"oversized metadata", form(part{"metadata", "", `{"x": "` + strings.Repeat("x", maxMetadataPartSize) + `"}`}, file), contentType, http.StatusRequestEntityTooLarge, "'metadata' part exceeds"},
+		{"two files", form(metadata, file, file), contentType, http.StatusBadRequest, "more than one 'file' part"},
+		{"malformed part", "--" + boundary + "\r\nno header line\r\n\r\nx\r\n--" + boundary + "--\r\n", contentType, http.StatusBadRequest, "Failed to parse multipart form"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := post(tc.body, tc.contentType)
+			if rec.Code != tc.wantCode || !strings.Contains(rec.Body.String(), tc.wantMessage) {
+				t.Errorf("expected %d with %q, got %d: %s", tc.wantCode, tc.wantMessage, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// The file may come first, the metadata as attachment, unknown parts are skipped with a warning
+	body := form(file, part{"thumbnail", "thumb.jpg", "jpeg"}, part{"metadata", "metadata.json", `{"timestamp": 1700000000000}`})
+	rec := post(body, contentType)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Values("Warning"); len(got) != 1 || !strings.Contains(got[0], "'thumbnail'") {
+		t.Errorf("expected a warning about the skipped part, got %q", got)
+	}
+
+	// Files above the sync upload size are spooled to disk, and removed unless the processor takes them over
+	h.Limits.Set(16, 0)
+	req := httptest.NewRequest(http.MethodPost, "/entry", strings.NewReader(form(metadata, part{"file", "large.bin", strings.Repeat("x", 100)})))
+	req.Header.Set("Content-Type", contentType)
+	parsed, ok := h.readUploadForm(httptest.NewRecorder(), req)
+	spooled, isFile := parsed.file.(*os.File)
+	if !ok || !isFile || parsed.header.Size != 100 || parsed.header.Filename != "large.bin" {
+		t.Fatalf("expected a spooled file of 100 bytes, got %T %+v", parsed.file, parsed.header)
+	}
+	parsed.Close()
+	if _, err := os.Stat(spooled.Name()); !os.IsNotExist(err) {
+		t.Errorf("expected the spooled file to be removed, got %v", err)
+	}
+}
+
+// TestPostEntryParallelUploads runs many uploads to the same database at once, which share their
+// database lookups. Run it with -race.
+func TestPostEntryParallelUploads(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "parallel_test", ContentType: "file", CustomFields: []repo.CustomFieldDef{
+		{Name: "camera", Type: "TEXT"},
+		{Name: "operator", Type: "TEXT", IsSensitive: true},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 64, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+	}
+
+	post := func() *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", `{"timestamp": 1700000000000, "custom_fields": {"camera": "gate", "operator": "alice"}}`)
+		part, _ := mw.CreateFormFile("file", "data.bin")
+		part.Write([]byte("payload"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/entry", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "camera"}))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		return rec
+	}
+
+	const uploads = 24
+	recs := make([]*httptest.ResponseRecorder, uploads)
+	var wg sync.WaitGroup
+	for i := range recs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recs[i] = post()
+		}()
+	}
+	wg.Wait()
+
+	// Every upload is created and redacted as if it ran alone
+	for _, rec := range recs {
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp EntryResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.CustomFields["camera"] != "gate" {
+			t.Errorf("expected the camera field, got %v", resp.CustomFields)
+		}
+		if _, ok := resp.CustomFields["operator"]; ok {
+			t.Errorf("expected the sensitive field to be hidden, got %v", resp.CustomFields)
+		}
+	}
+
+	entries, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 100})
+	if err != nil {
+		t.Fatalf("failed to get entries: %v", err)
+	}
+	if len(entries) != uploads {
+		t.Errorf("expected %d entries, got %d", uploads, len(entries))
+	}
+	if db, err = r.GetDatabase(ctx, db.ID); err != nil || db.Stats.EntryCount != uploads {
+		t.Errorf("expected an entry count of %d, got %d (err %v)", uploads, db.Stats.EntryCount, err)
+	}
+}
+
+func TestPostEntryTimestampPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "clock_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+		Timestamps: TimestampBounds{
+			Policy:        TimestampPolicyReject,
+			MaxFutureSkew: time.Hour,
+			MinTimestamp:  time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	post := func(timestamp int64) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", fmt.Sprintf(`{"timestamp": %d}`, timestamp))
+		part, _ := mw.CreateFormFile("file", "data.bin")
+		part.Write([]byte("payload"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/entry", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "camera"}))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		return rec
+	}
+	y2038 := time.Date(2038, 1, 19, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+	// 1. Reject mode refuses timestamps from broken clocks
+	for _, ts := range []int64{y2038, 0} {
+		if rec := post(ts); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "timestamp") {
+			t.Errorf("expected 400 for timestamp %d, got %d: %s", ts, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := post(1700000000000); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for a plausible timestamp, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 2. Clamp mode stores the server time and keeps the client's timestamp
+	h.Timestamps.Policy = TimestampPolicyClamp
+	before := time.Now()
+	rec := post(y2038)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 in clamp mode, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp EntryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ClientTS == nil || *resp.ClientTS != y2038 || resp.Timestamp < before.UnixMilli() || resp.Timestamp > time.Now().UnixMilli() {
+		t.Errorf("expected the server time and client_timestamp %d, got %d and %v", y2038, resp.Timestamp, resp.ClientTS)
+	}
+
+	stored, err := r.GetEntry(ctx, db.ID, resp.EntryID)
+	if err != nil {
+		t.Fatalf("failed to get entry: %v", err)
+	}
+	if stored.ClientTimestamp.UnixMilli() != y2038 || stored.Timestamp.UnixMilli() != resp.Timestamp {
+		t.Errorf("expected both timestamps to be stored, got %+v", stored)
+	}
+	if unclamped, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 10, TEnd: time.UnixMilli(1700000000000)}); err != nil || len(unclamped) != 1 || !unclamped[0].ClientTimestamp.IsZero() {
+		t.Errorf("expected the plausible upload without client timestamp, got %+v (err %v)", unclamped, err)
+	}
+}
+
+func TestPostEntryMinimalResponse(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "sensors",
+		ContentType:  "file",
+		CustomFields: []repo.CustomFieldDef{{Name: "sensor", Type: "TEXT"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+	}
+
+	post := func(query string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", `{"timestamp": 1700000000000, "custom_fields": {"sensor": "t-01"}}`)
+		part, _ := mw.CreateFormFile("file", "reading.bin")
+		part.Write([]byte("payload"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/entry"+query, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "sensor"}))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		return rec
+	}
+
+	// 1. The minimal response only has the id, timestamp and status
+	rec := post("?response=minimal")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var minimal map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &minimal); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(minimal) != 3 || minimal["id"] == nil || minimal["timestamp"] != float64(1700000000000) || minimal["status"] != "ready" {
+		t.Errorf("unexpected minimal response: %v", minimal)
+	}
+	stored, err := r.GetEntry(ctx, db.ID, int64(minimal["id"].(float64)))
+	if err != nil || stored.CustomFields["sensor"] != "t-01" || stored.Size != 7 {
+		t.Errorf("expected the entry to be stored completely, got %+v (err %v)", stored, err)
+	}
+
+	// 2. The default response is the full entry
+	for _, query := range []string{"", "?response=full"} {
+		rec := post(query)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var full EntryResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &full); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if full.DatabaseID != db.ID.String() || full.Size != 7 || full.CustomFields["sensor"] != "t-01" || full.Status != "ready" {
+			t.Errorf("unexpected full response for %q: %+v", query, full)
+		}
+	}
+
+	// 3. Unknown modes are rejected before anything is stored
+	if rec := post("?response=tiny"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown mode, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n, err := r.CountEntriesByStatus(ctx, db.ID, repo.EntryStatusReady); err != nil || n != 3 {
+		t.Errorf("expected 3 entries, got %d (err %v)", n, err)
+	}
+}
+
+func TestPostEntryCapacity(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "sensors", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+		Capacity:       repo.CapacityLimits{MaxEntriesPerDatabase: 2},
+	}
+
+	post := func() *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", `{"timestamp": 1700000000000}`)
+		part, _ := mw.CreateFormFile("file", "reading.bin")
+		part.Write([]byte("payload"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/entry", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "sensor"}))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		return rec
+	}
+
+	// 1. Uploads up to the limit are stored, the next one is rejected with the limit
+	var first EntryResponse
+	for i := range 2 {
+		rec := post()
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("upload %d: expected 201, got %d: %s", i+1, rec.Code, rec.Body.String())
+		}
+		if i == 0 {
+			json.Unmarshal(rec.Body.Bytes(), &first)
+		}
+	}
+	rec := post()
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "maximum is 2 entries") {
+		t.Fatalf("expected 409 with the limit, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 2. A deleted entry makes room for the next upload at once
+	if _, err := r.DeleteEntry(ctx, db.ID, first.GetID()); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if rec := post(); rec.Code != http.StatusCreated {
+		t.Errorf("expected 201 after a deletion, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n, err := r.CountEntriesByStatus(ctx, db.ID, repo.EntryStatusReady); err != nil || n != 2 {
+		t.Errorf("expected 2 entries, got %d (err %v)", n, err)
+	}
+}
+
+func TestPostEntryStorageQuota(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "sensors", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+	}
+
+	// Each upload stores 7 bytes, the quota holds exactly 3 of them
+	post := func(user repo.User) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", `{"timestamp": 1700000000000}`)
+		part, _ := mw.CreateFormFile("file", "reading.bin")
+		part.Write([]byte("payload"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/entry", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &user))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		return rec
+	}
+	sensor := repo.User{Username: "sensor", StorageQuota: 21}
+
+	// 1. Uploads up to the quota are stored, the quota itself included, the next one is rejected
+	var first EntryResponse
+	for i := range 3 {
+		rec := post(sensor)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("upload %d: expected 201, got %d: %s", i+1, rec.Code, rec.Body.String())
+		}
+		if i == 0 {
+			json.Unmarshal(rec.Body.Bytes(), &first)
+		}
+	}
+	rec := post(sensor)
+	if rec.Code != http.StatusInsufficientStorage || !strings.Contains(rec.Body.String(), "21 bytes are used") {
+		t.Fatalf("expected 507 with the used bytes, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 2. The quota is per user, and folding the usage changes keeps the stored bytes
+	if rec := post(repo.User{Username: "camera", StorageQuota: 7}); rec.Code != http.StatusCreated {
+		t.Errorf("expected 201 for another user, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := r.AggregateUserUsage(ctx); err != nil {
+		t.Fatalf("failed to aggregate usage: %v", err)
+	}
+	if rec := post(sensor); rec.Code != http.StatusInsufficientStorage {
+		t.Errorf("expected 507 after the aggregation, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 3. A deleted entry makes room for the next upload at once
+	if _, err := r.DeleteEntry(ctx, db.ID, first.GetID()); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if rec := post(sensor); rec.Code != http.StatusCreated {
+		t.Errorf("expected 201 after a deletion, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stored, err := r.GetUserStoredBytes(ctx, "sensor"); err != nil || stored != 21 {
+		t.Errorf("expected 21 stored bytes, got %d (err %v)", stored, err)
+	}
+}
+
+// imagePreviewConverter creates PNG previews in pure Go, so previews of images work without FFmpeg.
+type imagePreviewConverter struct {
+	plainFileConverter
+}
+
+func (imagePreviewConverter) CanCreatePreview(mimeType string) bool { return mimeType == "image/png" }
+
+func (imagePreviewConverter) ReadMediaFieldsFromStream(_ context.Context, in io.ReadSeeker, _ string) (map[string]any, error) {
+	cfg, err := png.DecodeConfig(in)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"width": cfg.Width, "height": cfg.Height}, nil
+}
+
+func (imagePreviewConverter) CreatePreviewFromStream(_ context.Context, in io.ReadSeeker, out io.Writer, _ string) error {
+	src, err := png.Decode(in)
+	if err != nil {
+		return err
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx()/4, src.Bounds().Dy()/4))
+	draw.NearestNeighbor.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return png.Encode(out, dst)
+}
+
+// previewCheckingRecorder records whether a preview was stored when the response status was written.
+type previewCheckingRecorder struct {
+	*httptest.ResponseRecorder
+	hasPreview            func() bool
+	previewBeforeResponse bool
+}
+
+func (rec *previewCheckingRecorder) WriteHeader(code int) {
+	rec.previewBeforeResponse = rec.hasPreview()
+	rec.ResponseRecorder.WriteHeader(code)
+}
+
+func TestPostEntrySyncPreview(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	images, err := r.CreateDatabase(ctx, repo.Database{Name: "captures", ContentType: "image", Config: repo.DatabaseConfig{CreatePreview: true}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	audio, err := r.CreateDatabase(ctx, repo.Database{Name: "recordings", ContentType: "audio", Config: repo.DatabaseConfig{CreatePreview: true}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for x := range 64 {
+		img.Set(x, x%48, color.RGBA{R: 0xff, A: 0xff})
+	}
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, imagePreviewConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(64<<10, 0),
+		MediaConverter: imagePreviewConverter{},
+		Processor:      proc,
+	}
+
+	previews := func(db repo.Database) int {
+		n := 0
+		store.WalkPreview(ctx, db.ID.String(), func(int64, storage.FileInfo) error {
+			n++
+			return nil
+		})
+		return n
+	}
+	post := func(db repo.Database, query string, mimeType string, data []byte) *previewCheckingRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", `{"timestamp": 1700000000000}`)
+		partHeader := textproto.MIMEHeader{}
+		partHeader.Set("Content-Disposition", `form-data; name="file"; filename="capture.png"`)
+		partHeader.Set("Content-Type", mimeType)
+		part, _ := mw.CreatePart(partHeader)
+		part.Write(data)
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/entry"+query, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "device"}))
+
+		before := previews(db)
+		rec := &previewCheckingRecorder{ResponseRecorder: httptest.NewRecorder(), hasPreview: func() bool { return previews(db) > before }}
+		h.PostEntry(rec, req)
+		return rec
+	}
+
+	// 1. The preview is stored before the response is written, the entry is ready right away
+	rec := post(images, "?sync_preview=true", "image/png", pngData.Bytes())
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !rec.previewBeforeResponse {
+		t.Error("expected the preview to exist before the response was written")
+	}
+	var resp EntryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "ready" || resp.PreviewSize == 0 || resp.HasPreview == nil || !*resp.HasPreview {
+		t.Errorf("expected a ready entry with preview, got status %s, preview size %d, has_preview %v", resp.Status, resp.PreviewSize, resp.HasPreview)
+	}
+	if tasks, err := r.GetDuePendingTasks(ctx, 10); err != nil || len(tasks) != 0 {
+		t.Errorf("expected no preview task, got %d (err %v)", len(tasks), err)
+	}
+
+	// 2. The minimal response reports the preview as well
+	rec = post(images, "?sync_preview=true&response=minimal", "image/png", pngData.Bytes())
+	var minimal map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &minimal); err != nil || minimal["has_preview"] != true || minimal["status"] != "ready" {
+		t.Errorf("expected a ready minimal response with has_preview, got %s (err %v)", rec.Body.String(), err)
+	}
+
+	// 3. By default the preview is generated after the response
+	rec = post(images, "", "image/png", pngData.Bytes())
+	var async EntryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &async); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if rec.Code != http.StatusCreated || async.Status != "processing" || async.HasPreview != nil {
+		t.Errorf("expected a processing entry without has_preview, got %d: %s", rec.Code, rec.Body.String())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for previews(images) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 4. Other databases and large files are rejected with a reason
+	for name, tc := range map[string]struct {
+		db       repo.Database
+		query    string
+		mimeType string
+		data     []byte
+		want     string
+	}{
+		"audio":         {audio, "?sync_preview=true", "audio/mpeg", []byte("ID3"), "image databases"},
+		"large file":    {images, "?sync_preview=true", "image/png", bytes.Repeat([]byte{0}, 128<<10), "max_sync_upload_size"},
+		"invalid value": {images, "?sync_preview=maybe", "image/png", pngData.Bytes(), "sync_preview"},
+	} {
+		rec := post(tc.db, tc.query, tc.mimeType, tc.data)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tc.want) {
+			t.Errorf("%s: expected 400 mentioning %q, got %d: %s", name, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestPatchEntryUserFields(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "patch_test",
+		ContentType:  "image",
+		CustomFields: []repo.CustomFieldDef{{Name: "camera", Type: "TEXT"}, {Name: "rating", Type: "INTEGER"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{
+		FileName:    "a.png",
+		Size:        100,
+		Timestamp:   time.UnixMilli(1000),
+		Status:      repo.EntryStatusReady,
+		MimeType:    "image/png",
+		MediaFields: map[string]any{"width": uint64(640), "height": uint64(480)},
+	})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/entry", strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		req.SetPathValue("id", fmt.Sprint(entry.ID))
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+		rec := httptest.NewRecorder()
+		h.PatchEntry(rec, req)
+		return rec
+	}
+
+	// 1. Filename, timestamp, external ID and custom fields are updated and returned
+	rec := patch(`{"filename":"b.png","timestamp":5000,"external_id":"img-1","custom_fields":{"camera":"Nikon","rating":4}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp EntryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.FileName != "b.png" || resp.Timestamp != 5000 || resp.ExternalID != "img-1" || resp.CustomFields["camera"] != "Nikon" || resp.CustomFields["rating"] != float64(4) {
+		t.Errorf("expected the updated fields, got %+v", resp)
+	}
+
+	// 2. The technical metadata is left alone, omitted fields keep their values
+	rec = patch(`{"custom_fields":{"rating":5}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got, err := r.GetEntry(ctx, db.ID, entry.ID)
+	if err != nil {
+		t.Fatalf("failed to get entry: %v", err)
+	}
+	if got.FileName != "b.png" || got.CustomFields["camera"] != "Nikon" || fmt.Sprint(got.CustomFields["rating"]) != "5" {
+		t.Errorf("expected the earlier updates to be kept, got %+v", got)
+	}
+	if got.Size != 100 || got.Status != repo.EntryStatusReady || got.MimeType != "image/png" || fmt.Sprint(got.MediaFields["width"]) != "640" {
+		t.Errorf("expected the technical metadata to be unchanged, got %+v", got)
+	}
+
+	// 3. Fields that are not custom fields of the database are rejected
+	for _, body := range []string{`{"custom_fields":{"width":1}}`, `{"custom_fields":{"status":"error"}}`} {
+		if rec := patch(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// TestPatchEntryImmutableFields patches the technical metadata of every content type, at the top level and
+// inside custom_fields, and expects all offending keys in the 400 response.
+func TestPatchEntryImmutableFields(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+
+	for _, tc := range []struct {
+		contentType string
+		mimeType    string
+		mediaFields map[string]any
+	}{
+		{"image", "image/png", map[string]any{"width": uint64(640), "height": uint64(480)}},
+		{"video", "video/mp4", map[string]any{"width": uint64(640), "height": uint64(480), "duration": 12.5}},
+		{"audio", "audio/mpeg", map[string]any{"duration": 12.5, "channels": uint8(2)}},
+		{"file", "application/octet-stream", map[string]any{}},
+	} {
+		t.Run(tc.contentType, func(t *testing.T) {
+			db, err := r.CreateDatabase(ctx, repo.Database{
+				Name:         "immutable_" + tc.contentType,
+				ContentType:  tc.contentType,
+				CustomFields: []repo.CustomFieldDef{{Name: "camera", Type: "TEXT"}},
+			})
+			if err != nil {
+				t.Fatalf("failed to create database: %v", err)
+			}
+			entry, err := r.CreateEntry(ctx, db, repo.Entry{
+				FileName:    "a.bin",
+				Size:        100,
+				Timestamp:   time.UnixMilli(1000),
+				Status:      repo.EntryStatusReady,
+				MimeType:    tc.mimeType,
+				MediaFields: tc.mediaFields,
+			})
+			if err != nil {
+				t.Fatalf("failed to create entry: %v", err)
+			}
+			patch := func(body string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPatch, "/entry", strings.NewReader(body))
+				req.SetPathValue("database_id", db.ID.String())
+				req.SetPathValue("id", fmt.Sprint(entry.ID))
+				reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+				req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+				rec := httptest.NewRecorder()
+				h.PatchEntry(rec, req)
+				return rec
+			}
+			expectRejected := func(body string, want ...string) {
+				t.Helper()
+				rec := patch(body)
+				if rec.Code != http.StatusBadRequest {
+					t.Errorf("expected 400 for %s, got %d: %s", body, rec.Code, rec.Body.String())
+					return
+				}
+				var resp ImmutableFieldsResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if fmt.Sprint(resp.Fields) != fmt.Sprint(want) || resp.Error == "" {
+					t.Errorf("expected the fields %v for %s, got %+v", want, body, resp)
+				}
+			}
+
+			// 1. Every media field of the content type and of the others, at the top level and as custom field
+			for _, field := range []string{"width", "height", "duration", "duration_sec", "channels"} {
+				expectRejected(fmt.Sprintf(`{%q: 12}`, field), field)
+				expectRejected(fmt.Sprintf(`{"custom_fields": {%q: 12}}`, field), "custom_fields."+field)
+			}
+
+			// 2. Standard fields derived from the file or set by processing
+			for _, field := range []string{"id", "filesize", "preview_filesize", "mime_type", "status", "created_at", "legal_hold"} {
+				expectRejected(fmt.Sprintf(`{%q: 1}`, field), field)
+			}
+
+			// 3. All offending keys are listed, sorted, and nothing is applied
+			expectRejected(`{"filename": "b.bin", "status": "error", "width": 1, "custom_fields": {"camera": "Nikon", "channels": 1}}`, "custom_fields.channels", "status", "width")
+			got, err := r.GetEntry(ctx, db.ID, entry.ID)
+			if err != nil {
+				t.Fatalf("failed to get entry: %v", err)
+			}
+			if got.FileName != "a.bin" || got.CustomFields["camera"] != nil || got.Status != repo.EntryStatusReady {
+				t.Errorf("expected the rejected patch not to change the entry, got %+v", got)
+			}
+			for name, value := range tc.mediaFields {
+				if fmt.Sprint(got.MediaFields[name]) != fmt.Sprint(value) {
+					t.Errorf("expected %s to stay %v, got %v", name, value, got.MediaFields[name])
+				}
+			}
+
+			// 4. Custom fields and the filename still update normally
+			rec := patch(`{"filename": "b.bin", "custom_fields": {"camera": "Nikon"}}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var resp EntryResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.FileName != "b.bin" || resp.CustomFields["camera"] != "Nikon" {
+				t.Errorf("expected the filename and custom field to be updated, got %+v", resp)
+			}
+		})
+	}
+}
+
+// TestEntryMetaETag polls an entry through its processing→ready transition with If-None-Match and
+// updates it with If-Match.
+func TestEntryMetaETag(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "etag_test", ContentType: "file", CustomFields: []repo.CustomFieldDef{{Name: "note", Type: "TEXT"}}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.UnixMilli(1000), Status: repo.EntryStatusProcessing, MimeType: "application/octet-stream"})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	do := func(handler http.HandlerFunc, method, target, header, value, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		req.SetPathValue("id", fmt.Sprint(entry.ID))
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	// 1. The processing entry has an ETag, polling with it returns 304 without body
+	rec := do(h.GetEntryMeta, http.MethodGet, "/entry", "", "", "")
+	processingTag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(processingTag, `W/"`) {
+		t.Fatalf("expected 200 with a weak ETag, got %d and %q", rec.Code, processingTag)
+	}
+	rec = do(h.GetEntryMeta, http.MethodGet, "/entry", "If-None-Match", processingTag, "")
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != processingTag {
+		t.Errorf("expected 304 without body while processing, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(h.GetEntryMeta, http.MethodGet, "/entry?include_links=true", "If-None-Match", processingTag, ""); rec.Code != http.StatusNotModified {
+		t.Errorf("expected the links not to change the ETag, got %d", rec.Code)
+	}
+
+	// 2. Once processing finished, the ETag changes and the poll gets the new state
+	if err := r.UpdateEntryStatus(ctx, db.ID, entry.ID, repo.EntryStatusReady, "", ""); err != nil {
+		t.Fatalf("failed to update the status: %v", err)
+	}
+	rec = do(h.GetEntryMeta, http.MethodGet, "/entry", "If-None-Match", processingTag, "")
+	readyTag := rec.Header().Get("ETag")
+	var meta EntryResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &meta) != nil || meta.Status != "ready" {
+		t.Fatalf("expected 200 with the ready entry, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if readyTag == "" || readyTag == processingTag {
+		t.Errorf("expected a new ETag after the transition, got %q", readyTag)
+	}
+	if rec := do(h.GetEntryMeta, http.MethodGet, "/entry", "If-None-Match", `"other", `+readyTag, ""); rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for a list containing the ETag, got %d", rec.Code)
+	}
+
+	// 3. PATCH with an outdated If-Match is refused, the current one is accepted and returns the next ETag
+	if rec := do(h.PatchEntry, http.MethodPatch, "/entry", "If-Match", processingTag, `{"custom_fields":{"note":"late"}}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected 412 for an outdated ETag, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = do(h.PatchEntry, http.MethodPatch, "/entry", "If-Match", readyTag, `{"custom_fields":{"note":"current"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the current ETag, got %d: %s", rec.Code, rec.Body.String())
+	}
+	patchedTag := rec.Header().Get("ETag")
+	if patchedTag == "" || patchedTag == readyTag {
+		t.Errorf("expected a new ETag after the update, got %q", patchedTag)
+	}
+	if rec := do(h.GetEntryMeta, http.MethodGet, "/entry", "If-None-Match", patchedTag, ""); rec.Code != http.StatusNotModified {
+		t.Errorf("expected the ETag of the PATCH response to match GET, got %d", rec.Code)
+	}
+	if rec := do(h.PatchEntry, http.MethodPatch, "/entry", "If-Match", "*", `{"custom_fields":{"note":"any"}}`); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for If-Match: *, got %d", rec.Code)
+	}
+}
+
+func TestEntryContentVersion(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "version_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Size: 3, Timestamp: time.Now(), Status: repo.EntryStatusReady, MimeType: "application/octet-stream"})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	writeFile := func(content string) {
+		t.Helper()
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader(content)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if err := r.UpdateEntryTechMetadata(ctx, db.ID, entry.ID, repo.EntryTechMetadata{Size: uint64(len(content)), MimeType: "application/octet-stream", FileWritten: true}); err != nil {
+			t.Fatalf("failed to update the metadata: %v", err)
+		}
+	}
+	writeFile("old")
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+		Storage: store,
+	}
+	do := func(handler http.HandlerFunc, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.SetPathValue("database_id", db.ID.String())
+		req.SetPathValue("id", fmt.Sprint(entry.ID))
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	metaVersion := func() string {
+		t.Helper()
+		var meta EntryResponse
+		if rec := do(h.GetEntryMeta, "/entry"); rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &meta) != nil {
+			t.Fatalf("failed to get the entry: %d %s", rec.Code, rec.Body.String())
+		}
+		return meta.ContentVersion
+	}
+
+	// 1. The metadata and the download carry the same version, downloading with it succeeds
+	oldVersion := metaVersion()
+	if oldVersion == "" {
+		t.Fatal("expected a content version in the metadata")
+	}
+	rec := do(h.GetEntryFile, "/file?version="+oldVersion)
+	if rec.Code != http.StatusOK || rec.Body.String() != "old" || rec.Header().Get(contentVersionHeader) != oldVersion {
+		t.Fatalf("expected the file with version %q, got %d %q (%q)", oldVersion, rec.Code, rec.Body.String(), rec.Header().Get(contentVersionHeader))
+	}
+
+	// 2. Metadata changes keep the version
+	if err := r.UpdateEntryTechMetadata(ctx, db.ID, entry.ID, repo.EntryTechMetadata{Size: 3, MimeType: "application/octet-stream"}); err != nil {
+		t.Fatalf("failed to update the metadata: %v", err)
+	}
+	if v := metaVersion(); v != oldVersion {
+		t.Errorf("expected the version to stay %q without a file write, got %q", oldVersion, v)
+	}
+
+	// 3. Writing the file again changes the version, the stale version is refused with the current one
+	writeFile("new!")
+	newVersion := metaVersion()
+	if newVersion == oldVersion {
+		t.Fatalf("expected a new version after the file was written, got %q", newVersion)
+	}
+	rec = do(h.GetEntryFile, "/file?version="+oldVersion)
+	if rec.Code != http.StatusConflict || rec.Header().Get(contentVersionHeader) != newVersion || !strings.Contains(rec.Body.String(), newVersion) {
+		t.Errorf("expected 409 naming version %q for the stale version, got %d %q", newVersion, rec.Code, rec.Body.String())
+	}
+	rec = do(h.GetEntryFile, "/file?version="+newVersion)
+	if rec.Code != http.StatusOK || rec.Body.String() != "new!" {
+		t.Errorf("expected the new file, got %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := do(h.GetEntryFile, "/file"); rec.Code != http.StatusOK || rec.Header().Get(contentVersionHeader) != newVersion {
+		t.Errorf("expected the download without version to succeed with the header, got %d", rec.Code)
+	}
+}
+
+func TestPostEntryMetadataDefaults(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// Numbers are float64 as in a decoded API request
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:        "defaults_test",
+		ContentType: "file",
+		Config: repo.DatabaseConfig{
+			MetadataDefaults:  map[string]any{"site": "north", "line": float64(3)},
+			MetadataOverrides: map[string]any{"device_model": "cam-x"},
+		},
+		CustomFields: []repo.CustomFieldDef{{Name: "site", Type: "TEXT"}, {Name: "device_model", Type: "TEXT"}, {Name: "line", Type: "INTEGER"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+	}
+	post := func(metadata string) repo.Entry {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", metadata)
+		part, _ := mw.CreateFormFile("file", "data.bin")
+		part.Write([]byte("payload"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/entry", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "camera"}))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp EntryResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		entry, err := r.GetEntry(ctx, db.ID, resp.EntryID)
+		if err != nil {
+			t.Fatalf("failed to get entry: %v", err)
+		}
+		return entry
+	}
+
+	// 1. Missing fields are filled from the defaults, the overrides are set
+	entry := post(`{}`)
+	if entry.CustomFields["site"] != "north" || fmt.Sprint(entry.CustomFields["line"]) != "3" || entry.CustomFields["device_model"] != "cam-x" {
+		t.Errorf("expected the defaults and overrides, got %+v", entry.CustomFields)
+	}
+
+	// 2. Values of the client take precedence over defaults, overrides over the client
+	entry = post(`{"custom_fields": {"site": "south", "line": 7, "device_model": "firmware-bug"}}`)
+	if entry.CustomFields["site"] != "south" || fmt.Sprint(entry.CustomFields["line"]) != "7" || entry.CustomFields["device_model"] != "cam-x" {
+		t.Errorf("expected the client values and the override, got %+v", entry.CustomFields)
+	}
+
+	// 3. Updates are not affected by the overrides
+	req := httptest.NewRequest(http.MethodPatch, "/entry", strings.NewReader(`{"custom_fields": {"device_model": "replaced"}}`))
+	req.SetPathValue("database_id", db.ID.String())
+	req.SetPathValue("id", fmt.Sprint(entry.ID))
+	reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+	req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+	rec := httptest.NewRecorder()
+	h.PatchEntry(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got, _ := r.GetEntry(ctx, db.ID, entry.ID); got.CustomFields["device_model"] != "replaced" {
+		t.Errorf("expected the update to keep its value, got %+v", got.CustomFields)
+	}
+}
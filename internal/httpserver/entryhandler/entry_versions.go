@@ -0,0 +1,91 @@
+package entryhandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+)
+
+// maxEntryVersionIDs caps the entries of a single versions request.
+const maxEntryVersionIDs = 5000
+
+// @Summary Get the versions of a set of entries
+// @Description Returns the version, filesize, mime type, preview availability and status of up to 5000 entries in one round trip, so a syncing client can tell which files to download again without a request per entry.
+// @Description The entries are listed in the requested order, with `null` for IDs that do not exist. The version changes with any change of an entry; only the database is read, no files.
+// @Description With `known` (entry ID -> version of the client's copy), only the entries whose version differs are listed, and the IDs that do not exist are returned in `missing`.
+// @Tags entry
+// @Accept  json
+// @Produce json
+// @Param   database_id  path  string                true  "Database ID"
+// @Param   body         body  EntryVersionsRequest  true  "Entry IDs (at most 5000) and optionally the known versions"
+// @Success 200 {object} EntryVersionsResponse
+// @Failure 400 {object} utils.ErrorResponse "Invalid JSON, no or too many IDs"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/entries/versions [post]
+func (h *EntryHandler) GetEntryVersions(w http.ResponseWriter, r *http.Request) {
+	dbID := r.PathValue("database_id")
+
+	// 1. Validate Input
+	var req EntryVersionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if len(req.IDs) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Provide the 'ids' of the entries.")
+		return
+	}
+	if len(req.IDs) > maxEntryVersionIDs {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d entries can be requested at once.", maxEntryVersionIDs))
+		return
+	}
+
+	db, err := h.Repo.GetDatabase(r.Context(), repo.ULID(dbID))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	// 2. One query for all entries
+	versions, err := h.Repo.GetEntryVersions(r.Context(), db.ID, req.IDs)
+	if err != nil {
+		h.Logger.Error("Failed to get entry versions", "database_id", dbID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	byID := make(map[int64]*EntryVersionResponse, len(versions))
+	for _, v := range versions {
+		byID[v.ID] = &EntryVersionResponse{
+			ID:         v.ID,
+			Version:    entryVersion(v),
+			Filesize:   v.Size,
+			MimeType:   v.MimeType,
+			HasPreview: v.PreviewSize > 0,
+			Status:     repo.GetEntryStatusString(v.Status),
+		}
+	}
+
+	// 3. Order as requested, in delta mode only what the client does not have
+	resp := EntryVersionsResponse{Entries: make([]*EntryVersionResponse, 0, len(req.IDs))}
+	for _, id := range req.IDs {
+		v, ok := byID[id]
+		if req.Known == nil {
+			resp.Entries = append(resp.Entries, v)
+			continue
+		}
+		if !ok {
+			resp.Missing = append(resp.Missing, id)
+		} else if known, has := req.Known[id]; !has || known != v.Version {
+			resp.Entries = append(resp.Entries, v)
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
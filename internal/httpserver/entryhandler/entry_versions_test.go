@@ -0,0 +1,147 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestGetEntryVersions(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "tablet", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	var ids []int64
+	for i := range 4 {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:    "file.bin",
+			Size:        uint64(10 * (i + 1)),
+			PreviewSize: uint64(i % 2),
+			Timestamp:   time.Now(),
+			Status:      repo.EntryStatusReady,
+			MimeType:    "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	call := func(dbID string, body any) (*httptest.ResponseRecorder, EntryVersionsResponse) {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/database/"+dbID+"/entries/versions", strings.NewReader(string(payload)))
+		req.SetPathValue("database_id", dbID)
+		rec := httptest.NewRecorder()
+		h.GetEntryVersions(rec, req)
+		var resp EntryVersionsResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp
+	}
+
+	// 1. All entries in the requested order, null for the missing one
+	rec, full := call(db.ID.String(), EntryVersionsRequest{IDs: []int64{ids[2], 9999, ids[0], ids[1], ids[3]}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(full.Entries) != 5 || full.Entries[1] != nil || len(full.Missing) != 0 {
+		t.Fatalf("expected 5 entries with a null for the missing ID, got %s", rec.Body)
+	}
+	first := full.Entries[0]
+	if first.ID != ids[2] || first.Filesize != 30 || first.MimeType != "application/octet-stream" || first.HasPreview || first.Status != "ready" || first.Version == "" {
+		t.Errorf("unexpected version of entry %d: %+v", ids[2], first)
+	}
+	if !full.Entries[3].HasPreview {
+		t.Errorf("expected entry %d to have a preview", ids[1])
+	}
+	known := make(map[int64]string)
+	for _, v := range full.Entries {
+		if v != nil {
+			known[v.ID] = v.Version
+		}
+	}
+
+	// 2. Unchanged entries keep their version
+	if _, again := call(db.ID.String(), EntryVersionsRequest{IDs: []int64{ids[2]}}); len(again.Entries) != 1 || again.Entries[0].Version != known[ids[2]] {
+		t.Errorf("expected the same version for an unchanged entry, got %+v", again.Entries)
+	}
+
+	// 3. Delta mode: one entry changed, one deleted, one unknown to the client
+	if err := r.UpdateEntryStatus(ctx, db.ID, ids[1], repo.EntryStatusError, "preview_failed", ""); err != nil {
+		t.Fatalf("failed to update entry: %v", err)
+	}
+	if _, err := r.DeleteEntry(ctx, db.ID, ids[3]); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	delete(known, ids[0])
+	rec, delta := call(db.ID.String(), EntryVersionsRequest{IDs: ids, Known: known})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var changed []int64
+	for _, v := range delta.Entries {
+		changed = append(changed, v.ID)
+	}
+	if len(changed) != 2 || changed[0] != ids[0] || changed[1] != ids[1] || delta.Entries[1].Status != "error" {
+		t.Errorf("expected entries %d and %d to have changed, got %s", ids[0], ids[1], rec.Body)
+	}
+	if len(delta.Missing) != 1 || delta.Missing[0] != ids[3] {
+		t.Errorf("expected entry %d to be missing, got %v", ids[3], delta.Missing)
+	}
+
+	// 4. Nothing changed returns an empty list
+	if _, none := call(db.ID.String(), EntryVersionsRequest{IDs: []int64{ids[2]}, Known: map[int64]string{ids[2]: known[ids[2]]}}); none.Entries == nil || len(none.Entries) != 0 {
+		t.Errorf("expected no changed entries, got %+v", none.Entries)
+	}
+
+	// 5. Invalid requests and unknown databases
+	tooMany := make([]int64, maxEntryVersionIDs+1)
+	for name, tc := range map[string]struct {
+		dbID string
+		body any
+		want int
+	}{
+		"no ids":   {db.ID.String(), EntryVersionsRequest{}, http.StatusBadRequest},
+		"too many": {db.ID.String(), EntryVersionsRequest{IDs: tooMany}, http.StatusBadRequest},
+		"invalid":  {db.ID.String(), "ids", http.StatusBadRequest},
+		"unknown":  {"missing", EntryVersionsRequest{IDs: ids}, http.StatusNotFound},
+	} {
+		if rec, _ := call(tc.dbID, tc.body); rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package entryhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Get entry metadata by external ID
+// @Description Retrieves the metadata of the entry carrying the given external ID, see `GET /database/{database_id}/entry/{id}`.
+// @Tags entry
+// @Produce json
+// @Param   database_id  path  string  true  "Database ID"
+// @Param   external_id  path  string  true  "External ID assigned by the client"
+// @Param   include_links query bool false "Add a _links block with the entry's URLs"
+// @Success 200 {object} EntryResponse "The full entry metadata object"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
+// @Failure 409 {object} utils.ErrorResponse "Several entries have this external ID"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/external/{external_id} [get]
+func (h *EntryHandler) GetEntryMetaByExternalID(w http.ResponseWriter, r *http.Request) {
+	if h.resolveExternalID(w, r) {
+		h.GetEntryMeta(w, r)
+	}
+}
+
+// @Summary Get an entry file by external ID
+// @Description Retrieves the file of the entry carrying the given external ID, see `GET /database/{database_id}/entry/{id}/file`.
+// @Tags entry
+// @Produce octet-stream
+// @Produce json
+// @Param   database_id  path    string  true  "Database ID"
+// @Param   external_id  path    string  true  "External ID assigned by the client"
+// @Param   Range   header  string  false "Byte range request (e.g., bytes=0-1023)"
+// @Param   variant query   string  false "'converted' (default) or 'original' for the kept original of a converted upload"
+// @Success 200 {file} file "The full raw file data (default)"
+// @Success 206 {file} file "Partial content (streaming response)"
+// @Failure 400 {object} utils.ErrorResponse "Invalid variant"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
+// @Failure 409 {object} utils.ErrorResponse "Several entries have this external ID, or the file is currently processing"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/{database_id}/external/{external_id}/file [get]
+func (h *EntryHandler) GetEntryFileByExternalID(w http.ResponseWriter, r *http.Request) {
+	if h.resolveExternalID(w, r) {
+		h.GetEntryFile(w, r)
+	}
+}
+
+// @Summary Get an entry preview by external ID
+// @Description Retrieves the preview of the entry carrying the given external ID, see `GET /database/{database_id}/entry/{id}/preview`.
+// @Tags entry
+// @Produce image/webp
+// @Produce image/png
+// @Produce json
+// @Param   database_id  path  string  true  "Database ID"
+// @Param   external_id  path  string  true  "External ID assigned by the client"
+// @Success 200 {file} file "The WebP preview image (default)"
+// @Success 200 {object} FileJSONResponse "Base64 encoded preview data (if Accept: application/json)"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database, entry, or preview not found"
+// @Failure 409 {object} utils.ErrorResponse "Several entries have this external ID"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/external/{external_id}/preview [get]
+func (h *EntryHandler) GetEntryPreviewByExternalID(w http.ResponseWriter, r *http.Request) {
+	if h.resolveExternalID(w, r) {
+		h.GetEntryPreview(w, r)
+	}
+}
+
+// resolveExternalID sets the {id} path value to the entry carrying the {external_id} path value,
+// so the lookups are served by the handlers of the entry endpoints. It responds itself on failure.
+func (h *EntryHandler) resolveExternalID(w http.ResponseWriter, r *http.Request) bool {
+	dbID := r.PathValue("database_id")
+	externalID := r.PathValue("external_id")
+
+	entry, err := h.Repo.GetEntryByExternalID(r.Context(), repo.ULID(dbID), externalID)
+	if err != nil {
+		switch {
+		case errors.Is(err, customerrors.ErrNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Database or entry not found.")
+		case errors.Is(err, customerrors.ErrConflict):
+			utils.RespondWithError(w, http.StatusConflict, "Several entries have this external_id, use the entry ID instead.")
+		default:
+			h.Logger.Error("Failed to look up external ID", "database_id", dbID, "external_id", externalID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to look up the entry.")
+		}
+		return false
+	}
+
+	r.SetPathValue("id", strconv.FormatInt(entry.ID, 10))
+	return true
+}
+
+// respondWithExternalIDConflict answers an upload or update whose external ID is already used
+// with 409 and the entry that has it.
+func (h *EntryHandler) respondWithExternalIDConflict(ctx context.Context, w http.ResponseWriter, db repo.Database, externalID string) {
+	dbID := db.ID.String()
+	message := fmt.Sprintf("The external_id '%s' is already used by another entry.", externalID)
+
+	existing, err := h.Repo.GetEntryByExternalID(ctx, db.ID, externalID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusConflict, message)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusConflict, ExternalIDConflictResponse{
+		Error: message,
+		Entry: mapToEntryResponse(dbID, redactEntryIn(ctx, db, existing)),
+	})
+}
@@ -0,0 +1,142 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestExternalID(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "external_ids", ContentType: "file", Config: repo.DatabaseConfig{UniqueExternalID: true}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	create := func(db repo.Database, externalID string) (repo.Entry, error) {
+		return r.CreateEntry(ctx, db, repo.Entry{
+			ExternalID: externalID,
+			FileName:   "rec.bin",
+			Status:     repo.EntryStatusReady,
+			Timestamp:  time.Now(),
+			MimeType:   "application/octet-stream",
+		})
+	}
+
+	first, err := create(db, "rec-0001")
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	second, err := create(db, "")
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if _, err := create(db, ""); err != nil {
+		t.Errorf("entries without external ID must not conflict, got %v", err)
+	}
+
+	// 1. Reusing an external ID is rejected by the repository
+	if _, err := create(db, "rec-0001"); !errors.Is(err, customerrors.ErrConflict) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	do := func(handler http.HandlerFunc, method, target string, pathValues map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		for key, value := range pathValues {
+			req.SetPathValue(key, value)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	// 2. The external ID resolves to the entry
+	rec := do(h.GetEntryMetaByExternalID, http.MethodGet, "/external/rec-0001", map[string]string{"external_id": "rec-0001"}, "")
+	var meta EntryResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &meta) != nil || meta.EntryID != first.ID || meta.ExternalID != "rec-0001" {
+		t.Errorf("expected entry %d, got %d: %s", first.ID, rec.Code, rec.Body.String())
+	}
+	if rec := do(h.GetEntryMetaByExternalID, http.MethodGet, "/external/unknown", map[string]string{"external_id": "unknown"}, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown external ID, got %d", rec.Code)
+	}
+
+	// 3. PATCH sets the external ID and returns the conflicting entry on reuse
+	id := map[string]string{"id": strconv.FormatInt(second.ID, 10)}
+	rec = do(h.PatchEntry, http.MethodPatch, "/entry", id, `{"external_id":"rec-0001"}`)
+	var conflict ExternalIDConflictResponse
+	if rec.Code != http.StatusConflict || json.Unmarshal(rec.Body.Bytes(), &conflict) != nil || conflict.Entry.EntryID != first.ID {
+		t.Errorf("expected 409 with entry %d, got %d: %s", first.ID, rec.Code, rec.Body.String())
+	}
+	if rec := do(h.PatchEntry, http.MethodPatch, "/entry", id, `{"external_id":"rec-0002"}`); rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(h.PatchEntry, http.MethodPatch, "/entry", id, `{"external_id":"`+strings.Repeat("x", maxExternalIDLength+1)+`"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a long external ID, got %d", rec.Code)
+	}
+
+	// 4. The external ID can be searched
+	results, err := r.SearchEntries(ctx, db.ID, repo.SearchRequest{
+		Filter:     &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "external_id", Operator: "LIKE", Value: "rec-%"}}},
+		Pagination: repo.Pagination{Limit: 10},
+	}, nil)
+	if err != nil || len(results) != 2 {
+		t.Errorf("expected two entries with an external ID, got %d (err %v)", len(results), err)
+	}
+
+	// 5. Without uniqueness, duplicates are accepted but cannot be looked up
+	db.Config.UniqueExternalID = false
+	if db, err = r.UpdateDatabase(ctx, db); err != nil {
+		t.Fatalf("failed to disable uniqueness: %v", err)
+	}
+	if _, err := create(db, "rec-0001"); err != nil {
+		t.Fatalf("expected a duplicate to be accepted, got %v", err)
+	}
+	if rec := do(h.GetEntryMetaByExternalID, http.MethodGet, "/external/rec-0001", map[string]string{"external_id": "rec-0001"}, ""); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for an ambiguous external ID, got %d", rec.Code)
+	}
+
+	// 6. Uniqueness cannot be enabled while duplicates exist
+	db.Config.UniqueExternalID = true
+	if _, err := r.UpdateDatabase(ctx, db); !errors.Is(err, customerrors.ErrConflict) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package entryhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Count the values of entry fields
+// @Description Returns the most frequent values of the requested fields with the number of entries having them, e.g. for the facets of a filter UI.
+// @Description `fields` takes standard, media and custom fields of type TEXT, INTEGER or BOOLEAN (at most 20); REAL fields are refused as nearly every entry has its own value.
+// @Description `limit` values are returned per field (default 20, max 100), the entries with other values are summed up in `other_count`, those without a value in `null_count`.
+// @Description The optional `filter` in the format of the search endpoint scopes the counts to the current query. The status is counted by name.
+// @Description Requires the CanView role; sensitive custom fields can only be counted or filtered with the CanEdit or CanAdmin role.
+// @Tags database
+// @Accept  json
+// @Produce json
+// @Param    database_id  path  string         true  "Database ID"
+// @Param    facets       body  FacetsRequest  true  "Fields, values per field and filter"
+// @Success 200 {object} FacetsResponse
+// @Failure 400 {object} utils.ErrorResponse "Invalid JSON, or invalid fields, limit or filter"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role, or counting or filtering a sensitive field without CanEdit)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/{database_id}/entries/facets [post]
+func (h *EntryHandler) GetEntryFacets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+
+	dbID := r.PathValue("database_id")
+
+	// 1. Validate Input
+	var payload FacetsRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	req := repo.FacetRequest{Fields: payload.Fields, Limit: payload.Limit}
+	if payload.Filter != nil {
+		req.Filter = searchRequestToModel(SearchRequestPayload{Filter: payload.Filter}).Filter
+	}
+
+	// 2. Get Database
+	db, err := h.Repo.GetDatabase(ctx, repo.ULID(dbID))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	// Counting or filtering a field the user may not read would reveal its values
+	redaction := redactionFor(ctx, db.ID.String(), db.CustomFields)
+	if err := redaction.checkAccess(req.Fields...); err != nil {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err := redaction.checkSearch(repo.SearchRequest{Filter: req.Filter}); err != nil {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	// 3. Count
+	facets, err := h.Repo.GetEntryFacets(ctx, db.ID, req, db.CustomFields)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.Logger.Error("Failed to count entry facets", "database_id", db.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// 4. Response
+	resp := FacetsResponse{
+		DatabaseID:   db.ID.String(),
+		DatabaseName: db.Name,
+		Total:        facets.Total,
+		Facets:       make([]FacetResponse, 0, len(facets.Fields)),
+	}
+	for _, f := range facets.Fields {
+		facet := FacetResponse{Field: f.Field, Values: make([]FacetValueResponse, 0, len(f.Values)), OtherCount: f.OtherCount, NullCount: f.NullCount}
+		for _, v := range f.Values {
+			value := v.Value
+			if status, ok := value.(int64); ok && f.Field == "status" {
+				value = repo.GetEntryStatusString(repo.EntryStatus(status))
+			}
+			facet.Values = append(facet.Values, FacetValueResponse{Value: value, Count: v.Count})
+		}
+		resp.Facets = append(resp.Facets, facet)
+	}
+
+	h.Auditor.Log(ctx, "entries.facets", user.Username, db.ID.String(), map[string]any{"fields": req.Fields})
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
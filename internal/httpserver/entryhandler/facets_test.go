@@ -0,0 +1,128 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestGetEntryFacets(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "cams",
+		ContentType:  "file",
+		CustomFields: []repo.CustomFieldDef{{Name: "sensor_id", Type: "TEXT"}, {Name: "patient", Type: "TEXT", IsSensitive: true}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for _, s := range []struct {
+		sensor string
+		status repo.EntryStatus
+	}{{"cam01", repo.EntryStatusReady}, {"cam01", repo.EntryStatusReady}, {"cam02", repo.EntryStatusError}} {
+		if _, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.jpg", Timestamp: time.Now(), MimeType: "image/jpeg", Status: s.status,
+			CustomFields: map[string]any{"sensor_id": s.sensor, "patient": "Jane Doe"}}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	viewer := &utils.APIKeyOfAdmin{Scope: repo.AccessView, Repo: r}
+	editor := &utils.APIKeyOfAdmin{Scope: repo.AccessView | repo.AccessEdit, Repo: r}
+
+	do := func(holder utils.PermissionHolder, dbID repo.ULID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/database/"+dbID.String()+"/entries/facets", strings.NewReader(body))
+		req.SetPathValue("database_id", dbID.String())
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, holder))
+		rec := httptest.NewRecorder()
+		h.GetEntryFacets(rec, req)
+		return rec
+	}
+
+	// 1. Viewers get the counts, the status by name
+	rec := do(viewer, db.ID, `{"fields": ["status", "sensor_id"], "limit": 1}`)
+	var resp FacetsResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil {
+		t.Fatalf("expected the facets, got %d: %s", rec.Code, rec.Body.String())
+	}
+	want := []FacetResponse{
+		{Field: "status", Values: []FacetValueResponse{{Value: "ready", Count: 2}}, OtherCount: 1},
+		{Field: "sensor_id", Values: []FacetValueResponse{{Value: "cam01", Count: 2}}, OtherCount: 1},
+	}
+	if resp.Total != 3 || resp.DatabaseName != "cams" || !reflect.DeepEqual(resp.Facets, want) {
+		t.Errorf("expected 3 entries with %+v, got %d with %+v", want, resp.Total, resp.Facets)
+	}
+
+	// 2. The filter scopes the counts
+	rec = do(viewer, db.ID,
+		`{"fields": ["sensor_id"], "filter": {"operator": "and", "conditions": [{"field": "status", "operator": "=", "value": 0}]}}`)
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil || resp.Total != 2 {
+		t.Errorf("expected the 2 ready entries, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 3. Sensitive fields need the edit role, for counting and for filtering
+	if rec := do(viewer, db.ID, `{"fields": ["patient"]}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for counting a sensitive field as viewer, got %d", rec.Code)
+	}
+	if rec := do(viewer, db.ID,
+		`{"fields": ["sensor_id"], "filter": {"operator": "and", "conditions": [{"field": "patient", "operator": "=", "value": "Jane Doe"}]}}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for filtering a sensitive field as viewer, got %d", rec.Code)
+	}
+	if rec := do(editor, db.ID, `{"fields": ["patient"]}`); rec.Code != http.StatusOK {
+		t.Errorf("expected editors to count sensitive fields, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 4. Invalid requests and unknown databases, the view role is checked by the router
+	for name, tc := range map[string]struct {
+		dbID   repo.ULID
+		body   string
+		holder utils.PermissionHolder
+		code   int
+	}{
+		"invalid json":   {db.ID, `{`, viewer, http.StatusBadRequest},
+		"no fields":      {db.ID, `{}`, viewer, http.StatusBadRequest},
+		"unknown field":  {db.ID, `{"fields": ["nope"]}`, viewer, http.StatusBadRequest},
+		"limit too high": {db.ID, `{"fields": ["status"], "limit": 101}`, viewer, http.StatusBadRequest},
+		"unknown db":     {"01ARZ3NDEKTSV4RRFFQ69G5FAV", `{"fields": ["status"]}`, viewer, http.StatusNotFound},
+	} {
+		if rec := do(tc.holder, tc.dbID, tc.body); rec.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", name, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+}
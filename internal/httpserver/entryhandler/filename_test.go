@@ -0,0 +1,172 @@
+package entryhandler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestPatchEntryFileName(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "photos", ContentType: "image"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	content := []byte("not really a jpeg")
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "photo.jpg", Size: uint64(len(content)), Status: repo.EntryStatusReady, Timestamp: time.Now(), MimeType: "image/jpeg", MediaFields: map[string]any{"width": uint64(640), "height": uint64(480)}})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if _, err := store.Write(ctx, db.ID.String(), entry.ID, bytes.NewReader(content)); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+		Storage: store,
+	}
+	do := func(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		req.SetPathValue("id", strconv.FormatInt(entry.ID, 10))
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	rename := func(name string) (*httptest.ResponseRecorder, string) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"filename": name})
+		rec := do(h.PatchEntry, http.MethodPatch, "/entry", string(body))
+		var resp EntryResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+		}
+		return rec, resp.FileName
+	}
+
+	// 1. Paths, control characters and long names are refused, the name stays
+	for _, name := range []string{"../../etc/passwd", `..\boot.ini`, "..", "a\x00b.jpg", "line\nbreak.jpg", strings.Repeat("x", 252) + ".jpg"} {
+		if rec, _ := rename(name); rec.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %q, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if stored, err := r.GetEntry(ctx, db.ID, entry.ID); err != nil || stored.FileName != "photo.jpg" {
+		t.Errorf("expected the name to be unchanged, got %q (%v)", stored.FileName, err)
+	}
+
+	// 2. The extension follows the stored file type, names without one keep the current extension
+	for name, want := range map[string]string{
+		"holiday.mp3":  "holiday.jpg",
+		"holiday":      "holiday.jpg",
+		"holiday.JPEG": "holiday.JPEG",
+		"v1.2 final":   "v1.2 final", // unknown extensions are kept
+	} {
+		if rec, got := rename(name); rec.Code != http.StatusOK || got != want {
+			t.Errorf("renaming to %q: expected %q, got %q (%d: %s)", name, want, got, rec.Code, rec.Body.String())
+		}
+	}
+
+	// 3. Strict names reject the contradicting extension instead
+	h.StrictFileNames = true
+	if rec, _ := rename("holiday.mp4"); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "image/jpeg") {
+		t.Errorf("expected 400 naming the file type, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec, got := rename("Grüße \"Sommer\".jpg"); rec.Code != http.StatusOK || got != "Grüße \"Sommer\".jpg" {
+		t.Fatalf("expected the rename to pass, got %q (%d: %s)", got, rec.Code, rec.Body.String())
+	}
+
+	// 4. Downloads and exports use the new name
+	rec := do(h.GetEntryFile, http.MethodGet, "/file", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the file, got %d", rec.Code)
+	}
+	want := `attachment; filename="Gr__e \"Sommer\".jpg"; filename*=UTF-8''Gr%C3%BC%C3%9Fe%20%22Sommer%22.jpg`
+	if cd := rec.Header().Get("Content-Disposition"); cd != want {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	body, _ := json.Marshal(ExportRequest{IDs: []int64{entry.ID}})
+	rec = do(h.ExportEntries, http.MethodPost, "/export", string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the export, got %d: %s", rec.Code, rec.Body.String())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("invalid archive: %v", err)
+	}
+	var names []string
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+	}
+	if path := "files/" + strconv.FormatInt(entry.ID, 10) + "_Grüße \"Sommer\".jpg"; !strings.Contains(strings.Join(names, "\n"), path) {
+		t.Errorf("expected %s in the archive, got %v", path, names)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	long := strings.Repeat("ä", 200) + ".flac" // 405 bytes
+	for name, want := range map[string]string{
+		"photo.jpg":              "photo.jpg",
+		"../../etc/passwd":       "passwd",
+		`C:\Users\me\a.png`:      "a.png",
+		"tab\there.txt":          "tabhere.txt",
+		"bad\xffutf8.bin":        "badutf8.bin",
+		"dir/..":                 "",
+		long:                     strings.Repeat("ä", 125) + ".flac",
+		strings.Repeat("x", 300): strings.Repeat("x", 255),
+	} {
+		got := sanitizeFileName(name)
+		if got != want {
+			t.Errorf("sanitizeFileName(%q) = %q, expected %q", name, got, want)
+		}
+		if got != "" && validateFileName(got) != nil {
+			t.Errorf("sanitized name %q does not validate: %v", got, validateFileName(got))
+		}
+	}
+	if cd := contentDisposition("inline", "../clip.mp4"); cd != `inline; filename="clip.mp4"` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	if cd := contentDisposition("attachment", ""); cd != "attachment" {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+}
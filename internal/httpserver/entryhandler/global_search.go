@@ -0,0 +1,252 @@
+package entryhandler
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// globalSearchConcurrency is the number of databases searched at the same time.
+const globalSearchConcurrency = 4
+
+// globalSortFields are the fields the merged results can be sorted by. They exist in every database
+// and compare the same way in all of them.
+var globalSortFields = map[string]func(a, b repo.Entry) int{
+	"id":         func(a, b repo.Entry) int { return cmp.Compare(a.ID, b.ID) },
+	"timestamp":  func(a, b repo.Entry) int { return a.Timestamp.Compare(b.Timestamp) },
+	"created_at": func(a, b repo.Entry) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"updated_at": func(a, b repo.Entry) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	"filesize":   func(a, b repo.Entry) int { return cmp.Compare(a.Size, b.Size) },
+	"filename":   func(a, b repo.Entry) int { return strings.Compare(a.FileName, b.FileName) },
+	"mime_type":  func(a, b repo.Entry) int { return strings.Compare(a.MimeType, b.MimeType) },
+	"status":     func(a, b repo.Entry) int { return cmp.Compare(a.Status, b.Status) },
+}
+
+// globalSearchHit is an entry found by the global search, with the database it belongs to.
+type globalSearchHit struct {
+	db    repo.Database
+	entry repo.Entry
+}
+
+// @Summary Search for entries in all databases
+// @Description Runs a search in every database the user may view (all databases for admins) and merges the results.
+// @Description The filter may use the standard fields of all databases (id, timestamp, filename, filesize, mime_type, status, ...).
+// @Description Conditions on media or custom fields are possible, databases without the field are skipped and listed in `skipped`.
+// @Description `databases` (names) and `content_types` restrict the searched databases.
+// @Description The results are sorted by `sort.field` (one of id, timestamp, created_at, updated_at, filesize, filename, mime_type, status,
+// @Description newest first by default) and capped at `pagination.limit` entries overall, `truncated` reports that more entries may match.
+// @Description `pagination.offset`, `fields` and `sort.collation` are not supported.
+// @Tags search
+// @Accept  json
+// @Produce json
+// @Param   search  body   GlobalSearchRequest  true  "Filter, sort, limit and the databases to search"
+// @Param   include_links query bool false "Add a _links block with the URLs of each entry"
+// @Success 200 {object} GlobalSearchResponse "The merged results, the skipped databases and whether the results were capped"
+// @Failure 400 {object} utils.ErrorResponse "Invalid JSON, an offset, fields or a collation, a limit that is not positive, or an invalid sort field"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /search/global [post]
+func (h *EntryHandler) GlobalSearch(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserFromContext(r.Context())
+
+	var payload GlobalSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if payload.Pagination.Offset != 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "The global search does not support an offset, narrow the filter instead")
+		return
+	}
+	if err := checkPagination(payload.Pagination); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(payload.Fields) > 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "The global search does not support fields")
+		return
+	}
+	// The results of the databases are merged in byte order, a collation would only sort within each database
+	if payload.Sort != nil && payload.Sort.Collation != "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "The global search does not support a sort collation")
+		return
+	}
+
+	searchReq := searchRequestToModel(payload.SearchRequestPayload)
+	if searchReq.Sort == nil {
+		searchReq.Sort = &repo.SortCriteria{Field: "timestamp", Direction: "desc"}
+	}
+	compare, ok := globalSortFields[searchReq.Sort.Field]
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid sort field '%s' for the global search", searchReq.Sort.Field))
+		return
+	}
+	descending := strings.EqualFold(searchReq.Sort.Direction, "desc")
+	// Every database gets the whole budget, any of them may hold all of the best matches
+	searchReq.Pagination.Limit = h.pageLimit(w, searchReq.Pagination.Limit)
+
+	databases, skipped, err := h.globalSearchDatabases(r.Context(), payload, searchReq)
+	if err != nil {
+		h.Logger.Error("Failed to list databases for the global search", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Search the databases with bounded concurrency
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		hits      []globalSearchHit
+		truncated bool
+		searchErr error
+	)
+	sem := make(chan struct{}, globalSearchConcurrency)
+	for _, db := range databases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// The databases were selected by withGeoFields, it cannot fail
+			dbReq, _ := withGeoFields(db, searchReq)
+			entries, err := h.Repo.SearchEntries(r.Context(), db.ID, dbReq, db.CustomFields)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case errors.Is(err, customerrors.ErrValidation):
+				skipped = append(skipped, GlobalSearchSkipped{Database: db.Name, Reason: err.Error()})
+			case err != nil:
+				searchErr = errors.Join(searchErr, fmt.Errorf("database %s: %w", db.Name, err))
+			default:
+				truncated = truncated || len(entries) >= searchReq.Pagination.Limit
+				for _, entry := range entries {
+					hits = append(hits, globalSearchHit{db: db, entry: entry})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if searchErr != nil {
+		h.Logger.Error("Global search failed", "error", searchErr)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Merge the results, ties are ordered by database name and entry ID so pages are stable
+	slices.SortFunc(hits, func(a, b globalSearchHit) int {
+		c := compare(a.entry, b.entry)
+		if descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+		return cmp.Or(strings.Compare(a.db.Name, b.db.Name), cmp.Compare(a.entry.ID, b.entry.ID))
+	})
+	if len(hits) > searchReq.Pagination.Limit {
+		hits, truncated = hits[:searchReq.Pagination.Limit], true
+	}
+	slices.SortFunc(skipped, func(a, b GlobalSearchSkipped) int { return strings.Compare(a.Database, b.Database) })
+
+	resp := GlobalSearchResponse{Results: make([]GlobalSearchResult, 0, len(hits)), Skipped: skipped, Truncated: truncated}
+	includeLinks := wantsLinks(r)
+	for _, hit := range hits {
+		dbID := hit.db.ID.String()
+		entry := mapToEntryResponse(dbID, redactionFor(r.Context(), dbID, hit.db.CustomFields).entry(hit.entry))
+		if includeLinks {
+			entry.Links = buildEntryLinks(h.BaseURL, dbID, hit.entry)
+		}
+		resp.Results = append(resp.Results, GlobalSearchResult{DatabaseName: hit.db.Name, EntryResponse: entry})
+	}
+	if resp.Skipped == nil {
+		resp.Skipped = []GlobalSearchSkipped{}
+	}
+
+	h.Auditor.Log(r.Context(), "entries.search_global", user.Username, "global", map[string]any{"databases": len(databases), "results": len(resp.Results)})
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// globalSearchDatabases selects the databases of a global search: those the user may view, matching the
+// requested names and content types. Databases without a field of the filter or without geo fields for a
+// geo search, or whose field the user may not read, are returned as skipped.
+func (h *EntryHandler) globalSearchDatabases(ctx context.Context, payload GlobalSearchRequest, searchReq repo.SearchRequest) ([]repo.Database, []GlobalSearchSkipped, error) {
+	all, err := h.Repo.GetDatabases(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	holder := utils.GetPermissionHolderFromContext(ctx)
+
+	var databases []repo.Database
+	var skipped []GlobalSearchSkipped
+	found := make(map[string]bool)
+	for _, db := range all {
+		if !holder.IsGlobalAdmin() && !holder.HasPermission(db.ID, repo.AccessView) {
+			continue
+		}
+		if len(payload.Databases) > 0 && !slices.Contains(payload.Databases, db.Name) {
+			continue
+		}
+		found[db.Name] = true
+		if len(payload.ContentTypes) > 0 && !slices.Contains(payload.ContentTypes, db.ContentType) {
+			continue
+		}
+
+		if field := missingSearchField(db, searchReq); field != "" {
+			skipped = append(skipped, GlobalSearchSkipped{Database: db.Name, Reason: fmt.Sprintf("the database has no field '%s'", field)})
+			continue
+		}
+		dbReq, err := withGeoFields(db, searchReq)
+		if err != nil {
+			skipped = append(skipped, GlobalSearchSkipped{Database: db.Name, Reason: "the database declares no geo fields"})
+			continue
+		}
+		if err := redactionFor(ctx, db.ID.String(), db.CustomFields).checkSearch(dbReq); err != nil {
+			skipped = append(skipped, GlobalSearchSkipped{Database: db.Name, Reason: err.Error()})
+			continue
+		}
+		databases = append(databases, db)
+	}
+
+	// Databases the user may not view are reported like unknown ones
+	for _, name := range payload.Databases {
+		if !found[name] {
+			skipped = append(skipped, GlobalSearchSkipped{Database: name, Reason: "database not found"})
+		}
+	}
+	return databases, skipped, nil
+}
+
+// missingSearchField returns the first field of the filter the database does not have, "" if it has all.
+func missingSearchField(db repo.Database, req repo.SearchRequest) string {
+	if req.Filter == nil {
+		return ""
+	}
+	mediaFields, _ := media.GetMetadataFields(db.ContentType)
+	for _, c := range req.Filter.Conditions {
+		if _, ok := repo.StandardFieldTypes[c.Field]; ok {
+			continue
+		}
+		if slices.ContainsFunc(mediaFields, func(f media.FieldDef) bool { return f.Name == c.Field }) {
+			continue
+		}
+		if slices.ContainsFunc(db.CustomFields, func(cf repo.CustomFieldDef) bool { return cf.Name == c.Field }) {
+			continue
+		}
+		return c.Field
+	}
+	return ""
+}
@@ -0,0 +1,197 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestGlobalSearch(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// Three databases of different content types, only two of them have the sensor field.
+	// Their entries interleave in time: cams at 0, 3, 6, mics at 1, 4, 7 and docs at 2, 5, 8.
+	sensor := []repo.CustomFieldDef{{Name: "sensor", Type: "TEXT"}}
+	base := time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC)
+	databases := map[string]repo.Database{}
+	for i, spec := range []struct {
+		name, contentType, mime string
+		mediaFields             map[string]any
+		fields                  []repo.CustomFieldDef
+	}{
+		{"cams", "image", "image/jpeg", map[string]any{"width": 640, "height": 480}, sensor},
+		{"mics", "audio", "audio/mpeg", map[string]any{"duration": 1.5, "channels": 1}, nil},
+		{"docs", "file", "text/plain", nil, sensor},
+	} {
+		db, err := r.CreateDatabase(ctx, repo.Database{Name: spec.name, ContentType: spec.contentType, CustomFields: spec.fields})
+		if err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		databases[spec.name] = db
+		for j := range 3 {
+			entry := repo.Entry{
+				FileName:    spec.name + ".bin",
+				Size:        uint64(100 * (i + 3*j)),
+				Timestamp:   base.Add(time.Duration(i+3*j) * time.Minute),
+				MimeType:    spec.mime,
+				MediaFields: spec.mediaFields,
+			}
+			if spec.fields != nil {
+				entry.CustomFields = map[string]any{"sensor": []string{"sensor-17", "sensor-4"}[j%2]}
+			}
+			if _, err := r.CreateEntry(ctx, db, entry); err != nil {
+				t.Fatalf("failed to create entry: %v", err)
+			}
+		}
+	}
+
+	admin, err := r.CreateUser(ctx, repo.User{Username: "admin", PasswordHash: "x", IsAdmin: true})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	viewer, err := r.CreateUser(ctx, repo.User{Username: "viewer", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := r.SetUserPermissions(ctx, repo.UserPermissions{UserID: viewer.ID, DatabaseID: databases["cams"].ID, Roles: repo.AccessView}); err != nil {
+		t.Fatalf("failed to set permissions: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	search := func(body string, user repo.User) (*httptest.ResponseRecorder, GlobalSearchResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/search/global", strings.NewReader(body))
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &user)
+		var holder utils.PermissionHolder = &utils.UserPermissions{UserULID: user.ID, Scope: repo.NewAccessGrant(true, true, true, true, true), Repo: r}
+		if user.IsAdmin {
+			holder = &utils.GlobalAdmin{UserULID: user.ID, Repo: r}
+		}
+		reqCtx = context.WithValue(reqCtx, utils.PermissionHolderKey, holder)
+		rec := httptest.NewRecorder()
+		h.GlobalSearch(rec, req.WithContext(reqCtx))
+
+		var resp GlobalSearchResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return rec, resp
+	}
+	names := func(resp GlobalSearchResponse) string {
+		var parts []string
+		for _, res := range resp.Results {
+			parts = append(parts, res.DatabaseName+"@"+time.UnixMilli(res.Timestamp).UTC().Format("4"))
+		}
+		return strings.Join(parts, ",")
+	}
+
+	// 1. The results of all databases are merged newest first and capped at the limit
+	rec, resp := search(`{"pagination": {"limit": 5}}`, admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got, want := names(resp), "docs@8,mics@7,cams@6,docs@5,mics@4"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if !resp.Truncated || len(resp.Skipped) != 0 {
+		t.Errorf("expected truncated results without skipped databases, got %+v", resp)
+	}
+	for _, res := range resp.Results {
+		if res.DatabaseID != databases[res.DatabaseName].ID.String() {
+			t.Errorf("result of %s has the database ID %s", res.DatabaseName, res.DatabaseID)
+		}
+	}
+
+	// 2. Sorting by another common field, all entries fit into the budget
+	_, resp = search(`{"sort": {"field": "filesize", "direction": "asc"}, "pagination": {"limit": 20}}`, admin)
+	if len(resp.Results) != 9 || resp.Truncated {
+		t.Fatalf("expected all 9 entries untruncated, got %d (truncated %v)", len(resp.Results), resp.Truncated)
+	}
+	for i := 1; i < len(resp.Results); i++ {
+		if resp.Results[i-1].Size > resp.Results[i].Size {
+			t.Errorf("results are not sorted by filesize: %d before %d", resp.Results[i-1].Size, resp.Results[i].Size)
+		}
+	}
+
+	// 3. Databases without a field of the filter are skipped with a note
+	_, resp = search(`{"filter": {"operator": "and", "conditions": [{"field": "sensor", "operator": "=", "value": "sensor-17"}]}}`, admin)
+	if got, want := names(resp), "docs@8,cams@6,docs@2,cams@0"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0].Database != "mics" || !strings.Contains(resp.Skipped[0].Reason, "sensor") {
+		t.Errorf("expected mics to be skipped for the sensor field, got %+v", resp.Skipped)
+	}
+
+	// 4. Databases are selected by name and content type
+	_, resp = search(`{"databases": ["mics", "docs", "nope"], "content_types": ["audio", "image"]}`, admin)
+	if got, want := names(resp), "mics@7,mics@4,mics@1"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0].Database != "nope" {
+		t.Errorf("expected the unknown database to be reported, got %+v", resp.Skipped)
+	}
+
+	// 5. Users only search the databases they may view, others look unknown
+	_, resp = search(`{"databases": ["cams", "mics"]}`, viewer)
+	if got, want := names(resp), "cams@6,cams@3,cams@0"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if len(resp.Skipped) != 1 || resp.Skipped[0].Database != "mics" || resp.Skipped[0].Reason != "database not found" {
+		t.Errorf("expected mics to be reported as not found, got %+v", resp.Skipped)
+	}
+
+	// 6. Geo searches skip the databases without geo fields
+	_, resp = search(`{"geo": {"bbox": {"min_lat": -90, "min_lon": -180, "max_lat": 90, "max_lon": 180}}}`, admin)
+	if len(resp.Results) != 0 || len(resp.Skipped) != 3 || !strings.Contains(resp.Skipped[0].Reason, "geo") {
+		t.Errorf("expected every database to be skipped for the geo search, got %+v", resp)
+	}
+
+	// 7. Invalid requests
+	for name, body := range map[string]string{
+		"invalid json":         `{`,
+		"offset":               `{"pagination": {"offset": 10}}`,
+		"fields":               `{"fields": ["filename"]}`,
+		"custom sort field":    `{"sort": {"field": "sensor", "direction": "asc"}}`,
+		"relevance sort field": `{"sort": {"field": "fts_rank", "direction": "desc"}}`,
+		"distance sort field":  `{"sort": {"field": "geo_distance", "direction": "asc"}}`,
+		"sort collation":       `{"sort": {"field": "filename", "direction": "asc", "collation": "unicode"}}`,
+	} {
+		if rec, _ := search(body, admin); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
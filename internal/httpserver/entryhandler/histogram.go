@@ -0,0 +1,97 @@
+package entryhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Count entries per time bucket
+// @Description Counts the entries with a timestamp in [tstart, tend) per hour, day or week, e.g. for a timeline.
+// @Description Buckets are aligned in UTC, weeks start on Monday 00:00 UTC; the first bucket contains tstart.
+// @Description Every bucket of the range is returned in order, empty ones with a count of 0. At most 2000 buckets are returned, larger ranges are refused.
+// @Description The optional `filter` is a JSON-encoded filter group in the format of the search endpoint.
+// @Tags database
+// @Produce json
+// @Param   database_id  path   string  true   "Database ID"
+// @Param   tstart  query  int64   true   "Start timestamp (Unix milliseconds, inclusive)"
+// @Param   tend    query  int64   true   "End timestamp (Unix milliseconds, exclusive)"
+// @Param   bucket  query  string  false  "Bucket size ('hour', 'day' or 'week', default 'day')"
+// @Param   filter  query  string  false  "JSON-encoded filter group, e.g. {\"operator\":\"and\",\"conditions\":[{\"field\":\"mime_type\",\"operator\":\"=\",\"value\":\"image/jpeg\"}]}"
+// @Success 200 {array} HistogramBucketResponse "The buckets of the range, oldest first"
+// @Failure 400 {object} utils.ErrorResponse "Missing or invalid range, bucket or filter, or too many buckets"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role, or filtering on a sensitive field without CanEdit)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/entries/histogram [get]
+func (h *EntryHandler) GetEntryHistogram(w http.ResponseWriter, r *http.Request) {
+	dbID := r.PathValue("database_id")
+	query := r.URL.Query()
+
+	user := utils.GetUserFromContext(r.Context())
+
+	// 1. Validate Input
+	req := repo.HistogramRequest{Bucket: query.Get("bucket")}
+	if req.Bucket == "" {
+		req.Bucket = repo.HistogramBucketDay
+	}
+	for _, param := range []struct {
+		key string
+		ts  *time.Time
+	}{{"tstart", &req.TStart}, {"tend", &req.TEnd}} {
+		ms, err := strconv.ParseInt(query.Get(param.key), 10, 64)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Missing or invalid %s: expected Unix milliseconds.", param.key))
+			return
+		}
+		*param.ts = time.UnixMilli(ms)
+	}
+	if raw := query.Get("filter"); raw != "" {
+		var filter FilterGroupPayload
+		if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid filter: expected a JSON filter group.")
+			return
+		}
+		req.Filter = searchRequestToModel(SearchRequestPayload{Filter: &filter}).Filter
+	}
+	if err := h.fieldRedaction(r.Context(), dbID).checkSearch(repo.SearchRequest{Filter: req.Filter}); err != nil {
+		utils.RespondWithError(w, http.StatusForbidden, err.Error())
+		return
+	}
+
+	// 2. Fetch database to get custom fields for filter validation
+	db, err := h.Repo.GetDatabase(r.Context(), repo.ULID(dbID))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	buckets, err := h.Repo.GetEntryHistogram(r.Context(), db.ID, req, db.CustomFields)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.Logger.Error("Failed to get entry histogram", "database_id", dbID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// 3. Response
+	resp := make([]HistogramBucketResponse, 0, len(buckets))
+	for _, b := range buckets {
+		resp = append(resp, HistogramBucketResponse{BucketStart: b.Start.UnixMilli(), Count: b.Count, Bytes: b.Bytes})
+	}
+
+	h.Auditor.Log(r.Context(), "entries.histogram", user.Username, dbID, map[string]any{"bucket": req.Bucket, "buckets": len(resp)})
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
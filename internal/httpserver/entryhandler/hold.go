@@ -0,0 +1,127 @@
+package entryhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// maxLegalHoldIDs limits the entries of a single hold or release request.
+const maxLegalHoldIDs = 1000
+
+// @Summary Put entries under legal hold
+// @Description Preserves entries for compliance: users cannot delete them (`403`), housekeeping skips them and their database can only be deleted with `force`.
+// @Description The reason is mandatory and recorded in the audit log. IDs that do not exist or are being deleted are listed in `missing`.
+// @Tags entry
+// @Accept  json
+// @Produce json
+// @Param   body  body  LegalHoldRequest  true  "The database, the entry IDs and the reason"
+// @Success 200 {object} LegalHoldResponse "The entries that are now held"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request, no IDs or missing reason"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires global admin)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /entry/hold [post]
+func (h *EntryHandler) SetLegalHold(w http.ResponseWriter, r *http.Request) {
+	h.changeLegalHold(w, r, true)
+}
+
+// @Summary Release entries from legal hold
+// @Description Clears the legal hold of entries, afterwards they can be deleted again. The reason is mandatory and recorded in the audit log.
+// @Tags entry
+// @Accept  json
+// @Produce json
+// @Param   body  body  LegalHoldRequest  true  "The database, the entry IDs and the reason"
+// @Success 200 {object} LegalHoldResponse "The entries that were released"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request, no IDs or missing reason"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires global admin)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /entry/hold [delete]
+func (h *EntryHandler) ReleaseLegalHold(w http.ResponseWriter, r *http.Request) {
+	h.changeLegalHold(w, r, false)
+}
+
+// changeLegalHold sets or clears the legal hold of the requested entries.
+func (h *EntryHandler) changeLegalHold(w http.ResponseWriter, r *http.Request, hold bool) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+
+	// 1. Validate Input
+	var req LegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.DatabaseID == "":
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing database_id")
+		return
+	case len(req.IDs) == 0:
+		utils.RespondWithError(w, http.StatusBadRequest, "Empty IDs list")
+		return
+	case len(req.IDs) > maxLegalHoldIDs:
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d IDs per request", maxLegalHoldIDs))
+		return
+	case req.Reason == "":
+		utils.RespondWithError(w, http.StatusBadRequest, "A reason is required")
+		return
+	}
+
+	db, err := h.Repo.GetDatabase(ctx, repo.ULID(req.DatabaseID))
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		} else {
+			h.Logger.Error("Failed to fetch database", "database_id", req.DatabaseID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch database.")
+		}
+		return
+	}
+
+	// 2. Update the entries
+	updated, err := h.Repo.SetLegalHold(ctx, db.ID, req.IDs, hold)
+	if err != nil {
+		h.Logger.Error("Failed to change legal hold", "database_id", req.DatabaseID, "hold", hold, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to change the legal hold.")
+		return
+	}
+	slices.Sort(updated)
+	missing := make([]int64, 0)
+	for _, id := range req.IDs {
+		if !slices.Contains(updated, id) && !slices.Contains(missing, id) {
+			missing = append(missing, id)
+		}
+	}
+
+	// 3. Audit & Response
+	action := "entry.legal_hold.release"
+	if hold {
+		action = "entry.legal_hold.set"
+	}
+	h.Auditor.Log(ctx, action, user.Username, req.DatabaseID, map[string]any{
+		"ids":     updated,
+		"missing": missing,
+		"reason":  req.Reason,
+	})
+
+	h.Logger.Info("Legal hold changed", "database_id", req.DatabaseID, "hold", hold, "count", len(updated))
+	utils.RespondWithJSON(w, http.StatusOK, LegalHoldResponse{
+		DatabaseID: req.DatabaseID,
+		LegalHold:  hold,
+		Updated:    updated,
+		Missing:    missing,
+	})
+}
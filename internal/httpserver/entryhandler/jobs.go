@@ -0,0 +1,73 @@
+package entryhandler
+
+import (
+	"errors"
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Get the progress of a background job
+// @Description Reports the progress of a job such as an asynchronous bulk deletion, also after a restart interrupted and resumed it.
+// @Description Jobs are visible to their creator, global admins and users with the CanDelete role on the database.
+// @Tags jobs
+// @Produce json
+// @Param   job_id  path  string  true  "Job ID"
+// @Success 200 {object} JobResponse
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 404 {object} utils.ErrorResponse "Job not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /jobs/{job_id} [get]
+func (h *EntryHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+
+	job, err := h.Repo.GetJob(ctx, repo.ULID(r.PathValue("job_id")))
+	if errors.Is(err, customerrors.ErrNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, "Job not found.")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to retrieve job", "job", r.PathValue("job_id"), "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve job")
+		return
+	}
+
+	// Jobs of others are reported as not found
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	if job.CreatedBy != user.Username && !holder.IsGlobalAdmin() && !holder.HasPermission(job.DatabaseID, repo.AccessDelete) {
+		utils.RespondWithError(w, http.StatusNotFound, "Job not found.")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, toJobResponse(job))
+}
+
+// toJobResponse maps a job to its API response.
+func toJobResponse(job repo.Job) JobResponse {
+	resp := JobResponse{
+		ID:         job.ID.String(),
+		Type:       job.Type,
+		DatabaseID: job.DatabaseID.String(),
+		Status:     job.Status,
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Failed:     job.Failed,
+		Counters:   job.Counters,
+		Error:      job.Error,
+		CreatedBy:  job.CreatedBy,
+		CreatedAt:  job.CreatedAt.UnixMilli(),
+		UpdatedAt:  job.UpdatedAt.UnixMilli(),
+	}
+	if resp.Counters == nil {
+		resp.Counters = map[string]int64{}
+	}
+	if !job.FinishedAt.IsZero() {
+		finished := job.FinishedAt.UnixMilli()
+		resp.FinishedAt = &finished
+	}
+	return resp
+}
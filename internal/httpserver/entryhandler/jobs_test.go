@@ -0,0 +1,139 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/housekeeping"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestAsyncDeleteEntries(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "bulk", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	var ids []int64
+	for range 3 {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "file.bin", Size: 10, Timestamp: time.Now(), Status: repo.EntryStatusReady, MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := &EntryHandler{
+		Logger:               logger,
+		Auditor:              audit.NewAlNoopLogger(),
+		Repo:                 r,
+		Storage:              store,
+		HouseKeeper:          housekeeping.NewHouseKeeper(r, store, logger, time.Hour),
+		AsyncDeleteThreshold: 2,
+	}
+	alice := &repo.User{Username: "alice"}
+	as := func(req *http.Request, user *repo.User, holder utils.PermissionHolder) *http.Request {
+		ctx := context.WithValue(req.Context(), utils.UserKey, user)
+		return req.WithContext(context.WithValue(ctx, utils.PermissionHolderKey, holder))
+	}
+	deleter := &utils.APIKeyOfAdmin{Scope: repo.AccessDelete}
+
+	// 1. More IDs than the threshold start a job
+	req := httptest.NewRequest(http.MethodPost, "/api/database/"+db.ID.String()+"/entries/delete", strings.NewReader(`{"ids": [1, 2, 3]}`))
+	req.SetPathValue("database_id", db.ID.String())
+	rec := httptest.NewRecorder()
+	h.DeleteEntries(rec, as(req, alice, deleter))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body)
+	}
+	var started JobResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if started.Type != repo.JobTypeDeleteEntries || started.Total != 3 || started.CreatedBy != "alice" || rec.Header().Get("Location") != "/api/jobs/"+started.ID {
+		t.Errorf("unexpected job %+v (Location %q)", started, rec.Header().Get("Location"))
+	}
+
+	// 2. Its creator polls it until it is done
+	getJob := func(user *repo.User, holder utils.PermissionHolder) (*httptest.ResponseRecorder, JobResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs/"+started.ID, nil)
+		req.SetPathValue("job_id", started.ID)
+		rec := httptest.NewRecorder()
+		h.GetJob(rec, as(req, user, holder))
+		var job JobResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &job)
+		return rec, job
+	}
+	var job JobResponse
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var rec *httptest.ResponseRecorder
+		if rec, job = getJob(alice, deleter); rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		if job.Status != repo.JobRunning {
+			break
+		}
+	}
+	if job.Status != repo.JobDone || job.Processed != 3 || job.Counters["deleted"] != 3 || job.Counters["bytes_freed"] != 30 || job.FinishedAt == nil {
+		t.Errorf("expected a done job of 3 deleted entries, got %+v", job)
+	}
+	if _, err := r.GetEntry(ctx, db.ID, ids[0]); err == nil {
+		t.Errorf("expected entry %d to be deleted", ids[0])
+	}
+
+	// 3. Other users only see it with CanDelete on the database
+	if rec, _ := getJob(&repo.User{Username: "bob"}, &utils.APIKeyOfAdmin{Scope: repo.AccessView}); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a viewer, got %d", rec.Code)
+	}
+	if rec, _ := getJob(&repo.User{Username: "bob"}, &utils.GlobalAdmin{}); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for an admin, got %d", rec.Code)
+	}
+
+	// 4. Unknown databases and jobs
+	req = httptest.NewRequest(http.MethodPost, "/api/database/missing/entries/delete?async=true", strings.NewReader(`{"ids": [1]}`))
+	req.SetPathValue("database_id", "missing")
+	rec = httptest.NewRecorder()
+	h.DeleteEntries(rec, as(req, alice, deleter))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown database, got %d: %s", rec.Code, rec.Body)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/jobs/missing", nil)
+	req.SetPathValue("job_id", "missing")
+	rec = httptest.NewRecorder()
+	h.GetJob(rec, as(req, alice, deleter))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown job, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,125 @@
+package entryhandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// UploadLimits holds the size limits of uploads and JSON responses, which a configuration reload
+// changes while requests are served.
+type UploadLimits struct {
+	maxSyncUploadSize atomic.Int64
+	maxJSONFileSize   atomic.Int64
+}
+
+// NewUploadLimits creates the limits in bytes, see Set.
+func NewUploadLimits(maxSyncUploadSize, maxJSONFileSize int64) *UploadLimits {
+	l := &UploadLimits{}
+	l.Set(maxSyncUploadSize, maxJSONFileSize)
+	return l
+}
+
+// Set changes the limits: uploads above maxSyncUploadSize are spooled to disk and processed asynchronously,
+// files above maxJSONFileSize are not served as base64 JSON (0 disables the limit).
+func (l *UploadLimits) Set(maxSyncUploadSize, maxJSONFileSize int64) {
+	l.maxSyncUploadSize.Store(maxSyncUploadSize)
+	l.maxJSONFileSize.Store(maxJSONFileSize)
+}
+
+// maxSyncUploadSize returns the current threshold for synchronous uploads, 0 if no limits are set.
+func (h *EntryHandler) maxSyncUploadSize() int64 {
+	if h.Limits == nil {
+		return 0
+	}
+	return h.Limits.maxSyncUploadSize.Load()
+}
+
+// maxJSONFileSize returns the largest file served as base64 JSON, 0 if unlimited.
+func (h *EntryHandler) maxJSONFileSize() int64 {
+	if h.Limits == nil {
+		return 0
+	}
+	return h.Limits.maxJSONFileSize.Load()
+}
+
+// checkCapacity writes a 409 response and returns false if n more entries do not fit into the database.
+// It goes by the stats of the database, so uploads into a full database are rejected before they are read.
+func (h *EntryHandler) checkCapacity(w http.ResponseWriter, db repo.Database, n int) bool {
+	if err := h.Capacity.CheckEntries(db.Stats.EntryCount, n); err != nil {
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+		return false
+	}
+	return true
+}
+
+// checkQuota fails with customerrors.ErrQuotaExceeded if an upload of size bytes exceeds the storage quota
+// of the user. The stored bytes include the changes housekeeping has not aggregated yet.
+func (h *EntryHandler) checkQuota(ctx context.Context, user *repo.User, size int64) error {
+	if user.StorageQuota == 0 {
+		return nil
+	}
+	stored, err := h.Repo.GetUserStoredBytes(ctx, user.Username)
+	if err != nil {
+		return fmt.Errorf("failed to check the storage quota of user %s: %w", user.Username, err)
+	}
+	if stored+uint64(max(size, 0)) > user.StorageQuota {
+		return fmt.Errorf("%w: the upload of %d bytes exceeds the storage quota of %d bytes, %d bytes are used", customerrors.ErrQuotaExceeded, size, user.StorageQuota, stored)
+	}
+	return nil
+}
+
+// exportRetryAfter is sent as Retry-After header with exports rejected by the ExportLimiter.
+const exportRetryAfter = 30 * time.Second
+
+// ExportLimiter caps the number of exports streamed at the same time. Each export holds a connection
+// and one open file after the other for as long as the client takes to download it.
+type ExportLimiter struct {
+	slots        chan struct{} // nil for an unlimited number of exports
+	writeTimeout time.Duration
+}
+
+// NewExportLimiter allows maxConcurrent exports at the same time (0 for unlimited). An export is aborted
+// if the client accepts no data for writeTimeout (0 disables the timeout).
+func NewExportLimiter(maxConcurrent int, writeTimeout time.Duration) *ExportLimiter {
+	l := &ExportLimiter{writeTimeout: writeTimeout}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	return l
+}
+
+// tryAcquire takes a slot for an export without waiting, it reports false if all slots are taken.
+func (l *ExportLimiter) tryAcquire() bool {
+	if l == nil || l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release returns the slot taken by tryAcquire.
+func (l *ExportLimiter) release() {
+	if l == nil || l.slots == nil {
+		return
+	}
+	<-l.slots
+}
+
+// exportWriteTimeout returns how long an export waits for a client that accepts no data, 0 without limiter.
+func (h *EntryHandler) exportWriteTimeout() time.Duration {
+	if h.Exports == nil {
+		return 0
+	}
+	return h.Exports.writeTimeout
+}
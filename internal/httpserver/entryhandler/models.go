@@ -2,97 +2,218 @@ package entryhandler
 
 import (
 	"log/slog"
+	"mediahub_oss/internal/housekeeping"
 	"mediahub_oss/internal/logging/audit"
 	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/media/sprite"
 	"mediahub_oss/internal/processing"
 	"mediahub_oss/internal/repository"
 	"mediahub_oss/internal/storage"
+	"mediahub_oss/pkg/models"
+	"net/netip"
+	"time"
 )
 
 type EntryHandler struct {
-	Logger                 *slog.Logger
-	Auditor                audit.AuditLogger
-	Repo                   repository.Repository
-	Storage                storage.StorageProvider
-	MaxSyncUploadSizeBytes int64
-	MediaConverter         media.MediaConverter
-	Processor              *processing.Processor
-}
+	Logger             *slog.Logger
+	Auditor            audit.AuditLogger
+	Repo               repository.Repository
+	Storage            storage.StorageProvider
+	Limits             *UploadLimits  // upload and JSON response sizes, changed by configuration reloads
+	Exports            *ExportLimiter // concurrent exports and the write timeout of their downloads, unlimited if nil
+	IdempotencyKeyTTL  time.Duration  // how long upload results are kept for replays
+	MediaConverter     media.MediaConverter
+	Processor          *processing.Processor
+	BaseURL            string         // external prefix for generated entry links, e.g. behind a reverse proxy
+	TrustedProxies     []netip.Prefix // proxies whose X-Forwarded-For header is honored for the upload origin
+	MaxSegmentDuration time.Duration  // longest audio segment that can be extracted (0 disables the limit)
+	Sprites            *sprite.Generator
+	PageLimits         repository.PageLimits     // default and maximum page size of listings and searches
+	Capacity           repository.CapacityLimits // entries per database, checked before an upload is read
+	Timestamps         TimestampBounds           // checks of the upload timestamps, the zero value accepts all
+	StrictFileNames    bool                      // renames to the extension of another file type fail instead of being corrected
 
-// metadata that can be added when sending a new entry
-type PostPatchEntryRequest struct {
-	Timestamp    int64          `json:"timestamp"`
-	FileName     string         `json:"filename"`
-	CustomFields map[string]any `json:"custom_fields"`
+	// Bulk deletions of more IDs run as background jobs of the house keeper, 0 only with async=true
+	HouseKeeper          *housekeeping.HouseKeeper
+	AsyncDeleteThreshold int
 }
 
+// metadata that can be added when sending a new entry, shared with the Go client
+type PostPatchEntryRequest = models.EntryMetadata
+
 type BulkDeleteRequest struct {
 	IDs []int64 `json:"ids"`
 }
 
 // ExportRequest defines the payload for the export endpoint.
-type ExportRequest struct {
-	IDs []int64 `json:"ids"`
+type ExportRequest = models.ExportRequest
+
+// PreviewExportRequest selects the previews of POST /database/previews/export.
+type PreviewExportRequest struct {
+	DatabaseID string  `json:"database_id"`
+	IDs        []int64 `json:"ids"`              // at most 5000
+	Format     string  `json:"format,omitempty"` // "zip" or "tar", taken from the Accept header if empty
+}
+
+// PreviewExportSkipped is an entry without preview in the archive of a preview export, listed in skipped.json.
+type PreviewExportSkipped struct {
+	ID     int64  `json:"id"`
+	Reason string `json:"reason"` // not_found, no_preview or read_failed
+}
+
+// EntryVersionsRequest lists the entries of POST /database/{database_id}/entries/versions. With known, only
+// the entries whose version differs from the one the client has are returned.
+type EntryVersionsRequest struct {
+	IDs   []int64          `json:"ids"`             // at most 5000
+	Known map[int64]string `json:"known,omitempty"` // entry ID -> version of the client's copy
+}
+
+// EntryVersionsResponse holds the versions in the requested order, null for IDs that do not exist. In delta
+// mode (with known), only the changed entries are listed and the IDs that do not exist are in missing.
+type EntryVersionsResponse struct {
+	Entries []*EntryVersionResponse `json:"entries"`
+	Missing []int64                 `json:"missing,omitempty"`
+}
+
+// EntryVersionResponse is what a syncing client needs to decide whether to download an entry again.
+type EntryVersionResponse struct {
+	ID         int64  `json:"id"`
+	Version    string `json:"version"` // changes with any change of the entry, including its metadata
+	Filesize   uint64 `json:"filesize"`
+	MimeType   string `json:"mime_type"`
+	HasPreview bool   `json:"has_preview"`
+	Status     string `json:"status"`
+}
+
+// SpriteRequest selects the entries of a sprite sheet, either by ID or by a search (exactly one of both).
+type SpriteRequest struct {
+	IDs    []int64               `json:"ids,omitempty"`
+	Search *SearchRequestPayload `json:"search,omitempty"` // pagination.limit defaults to 100, capped at 200
+}
+
+// SpriteResponse describes the grid of a sprite sheet. In the JSON envelope, Sprite holds the JPEG
+// as base64 data URI; in a multipart response the JPEG is sent as a separate part.
+type SpriteResponse struct {
+	Columns    int                          `json:"columns"`
+	Rows       int                          `json:"rows"`
+	CellWidth  int                          `json:"cell_width"`
+	CellHeight int                          `json:"cell_height"`
+	Cells      map[int64]SpriteCellResponse `json:"cells"` // entry ID -> top left corner of its cell
+	Sprite     string                       `json:"sprite,omitempty"`
 }
 
-// SearchRequestPayload defines the JSON structure for the complex search endpoint.
-type SearchRequestPayload struct {
-	Filter     *FilterGroupPayload  `json:"filter,omitempty"`
-	Sort       *SortCriteriaPayload `json:"sort,omitempty"`
-	Pagination PaginationPayload    `json:"pagination"`
+type SpriteCellResponse struct {
+	X           int  `json:"x"`
+	Y           int  `json:"y"`
+	Placeholder bool `json:"placeholder,omitempty"` // the entry has no preview
 }
 
-// FilterGroupPayload allows chaining multiple conditions together.
-type FilterGroupPayload struct {
-	Operator   string             `json:"operator"`   // e.g., "and", "or"
-	Conditions []ConditionPayload `json:"conditions"` // The individual rules
+// The search payloads are shared with the Go client.
+type (
+	SearchRequestPayload = models.SearchRequest
+	FilterGroupPayload   = models.FilterGroup
+	ConditionPayload     = models.Condition
+	SortCriteriaPayload  = models.SortCriteria
+	PaginationPayload    = models.Pagination
+)
+
+// HistogramBucketResponse is a bucket of GET /database/{database_id}/entries/histogram.
+type HistogramBucketResponse struct {
+	BucketStart int64  `json:"bucket_start"` // Unix milliseconds, aligned in UTC
+	Count       int64  `json:"count"`
+	Bytes       uint64 `json:"bytes"` // total file size of the entries
+}
+
+// FacetsRequest selects the fields of POST /database/{database_id}/entries/facets.
+type FacetsRequest struct {
+	Fields []string            `json:"fields"`           // TEXT, INTEGER and BOOLEAN fields, at most 20
+	Limit  int                 `json:"limit,omitempty"`  // values per field, default 20, max 100
+	Filter *FilterGroupPayload `json:"filter,omitempty"` // only count the entries matching it, in the format of the search
+}
+
+// FacetsResponse holds the value counts of every requested field, in the order of the request.
+type FacetsResponse struct {
+	DatabaseID   string          `json:"database_id"`
+	DatabaseName string          `json:"database_name"`
+	Total        int64           `json:"total"` // entries matching the filter
+	Facets       []FacetResponse `json:"facets"`
+}
+
+// FacetResponse lists the most frequent values of a field, the most frequent first.
+type FacetResponse struct {
+	Field      string               `json:"field"`
+	Values     []FacetValueResponse `json:"values"`
+	OtherCount int64                `json:"other_count"` // entries with a value beyond the limit
+	NullCount  int64                `json:"null_count"`  // entries without a value
 }
 
-// ConditionPayload represents a single query filter.
-type ConditionPayload struct {
-	Field    string `json:"field"`
-	Operator string `json:"operator"` // e.g., "=", ">", "<", "LIKE"
-	Value    any    `json:"value"`    // 'any' allows for strings, numbers, or booleans
+type FacetValueResponse struct {
+	Value any   `json:"value"` // string, number or boolean by the type of the field, the status as name
+	Count int64 `json:"count"`
 }
 
-// SortCriteriaPayload defines how the results should be ordered.
-type SortCriteriaPayload struct {
-	Field     string `json:"field"`
-	Direction string `json:"direction"` // "asc" or "desc"
+// GlobalSearchRequest is a search in all databases the user may view, optionally restricted by name and content type.
+type GlobalSearchRequest struct {
+	SearchRequestPayload
+	Databases    []string `json:"databases,omitempty"`     // names of the databases to search, all if empty
+	ContentTypes []string `json:"content_types,omitempty"` // only search databases of these content types, e.g. "image"
 }
 
-// PaginationPayload controls the subset of results returned.
-type PaginationPayload struct {
-	Offset int `json:"offset"`
-	Limit  int `json:"limit"`
+// GlobalSearchResponse holds the merged results of a global search.
+type GlobalSearchResponse struct {
+	Results   []GlobalSearchResult  `json:"results"`
+	Skipped   []GlobalSearchSkipped `json:"skipped"`   // databases that were not searched
+	Truncated bool                  `json:"truncated"` // the results were capped, more entries may match
 }
 
-// Returned in case of sync file handling or entry requests
-type EntryResponse struct {
-	DatabaseID   string         `json:"database_id"`
-	EntryID      int64          `json:"id"`
-	FileName     string         `json:"filename"`
-	Size         uint64         `json:"filesize"`
-	PreviewSize  uint64         `json:"preview_filesize"`
-	Status       string         `json:"status"`
-	Timestamp    int64          `json:"timestamp"`
-	CreatedAt    int64          `json:"created_at"`
-	UpdatedAt    int64          `json:"updated_at"`
-	MimeType     string         `json:"mime_type"`
-	MediaFields  map[string]any `json:"media_fields"`
-	CustomFields map[string]any `json:"custom_fields"`
+// GlobalSearchResult is an entry of a global search, tagged with the name of its database.
+type GlobalSearchResult struct {
+	DatabaseName string `json:"database_name"`
+	EntryResponse
 }
 
-// Returned in case of async file handling
-type PartialEntryResponse struct {
-	DatabaseID   string         `json:"database_id"`
-	EntryID      int64          `json:"id"`
-	Status       string         `json:"status"`
-	Timestamp    int64          `json:"timestamp"`
-	CreatedAt    int64          `json:"created_at"`
-	UpdatedAt    int64          `json:"updated_at"`
-	MimeType     string         `json:"mime_type"`
-	CustomFields map[string]any `json:"custom_fields"`
+// GlobalSearchSkipped is a database left out of a global search and why.
+type GlobalSearchSkipped struct {
+	Database string `json:"database"`
+	Reason   string `json:"reason"`
+}
+
+// The entry responses are shared with the Go client.
+type (
+	EntryResponse        = models.Entry        // returned in case of sync file handling or entry requests
+	PartialEntryResponse = models.PartialEntry // returned in case of async file handling
+	UploadSource         = models.UploadSource
+	EntryLinks           = models.EntryLinks
+)
+
+// MinimalEntryResponse is returned by synchronous uploads with ?response=minimal.
+type MinimalEntryResponse struct {
+	EntryID   int64  `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Status    string `json:"status"`
+
+	HasPreview *bool `json:"has_preview,omitempty"` // only with sync_preview=true
+}
+
+func (m MinimalEntryResponse) GetID() int64 { return m.EntryID }
+
+// ExternalIDConflictResponse is returned if an upload or update uses an external ID that belongs to another entry.
+type ExternalIDConflictResponse struct {
+	Error string        `json:"error"`
+	Entry EntryResponse `json:"entry"` // the entry that already has the external ID
+}
+
+// ImmutableFieldsResponse is returned with 400 if a PATCH of an entry sets fields users cannot change.
+type ImmutableFieldsResponse struct {
+	Error  string   `json:"error"`
+	Fields []string `json:"fields"` // the offending keys, custom_fields.<name> for keys inside custom_fields
+}
+
+// ProgressResponse is the processing progress of an asynchronously handled entry.
+type ProgressResponse struct {
+	Phase     string   `json:"phase"`             // queued, starting, converting, preview or finalizing
+	Percent   *float64 `json:"percent,omitempty"` // progress within the phase, omitted if it cannot be measured
+	UpdatedAt int64    `json:"updated_at"`
 }
 
 // FileJSONResponse is used when clients request a file via Accept: application/json.
@@ -105,11 +226,47 @@ type FileJSONResponse struct {
 
 // BulkDeleteResponse defines the success payload for a bulk delete operation.
 type BulkDeleteResponse struct {
-	DatabaseID      string `json:"database_id"`
-	DeletedCount    int    `json:"deleted_count"`
-	SpaceFreedBytes uint64 `json:"space_freed_bytes"`
-	Message         string `json:"message"`
-	Errors          string `json:"errors"`
+	DatabaseID      string           `json:"database_id"`
+	DeletedCount    int              `json:"deleted_count"`
+	SpaceFreedBytes uint64           `json:"space_freed_bytes"`
+	Message         string           `json:"message"`
+	Errors          string           `json:"errors"`
+	Deleted         []int64          `json:"deleted"`        // IDs whose entries were removed
+	Missing         []int64          `json:"missing"`        // requested IDs that did not exist
+	FileErrors      map[int64]string `json:"file_errors"`    // per-ID warnings for files left in storage
+	Held            []int64          `json:"held,omitempty"` // requested IDs under legal hold, nothing was deleted
+}
+
+// JobResponse is the state of a background job, e.g. an asynchronous bulk deletion.
+type JobResponse struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"` // e.g. delete_entries
+	DatabaseID string           `json:"database_id"`
+	Status     string           `json:"status"` // running, done or failed
+	Total      int64            `json:"total"`
+	Processed  int64            `json:"processed"`
+	Failed     int64            `json:"failed"`   // for deletions: entries skipped under legal hold or still being processed
+	Counters   map[string]int64 `json:"counters"` // for deletions: deleted, missing, held, busy, file_errors and bytes_freed
+	Error      string           `json:"error,omitempty"`
+	CreatedBy  string           `json:"created_by"`
+	CreatedAt  int64            `json:"created_at"`            // Unix milliseconds
+	UpdatedAt  int64            `json:"updated_at"`            // Unix milliseconds
+	FinishedAt *int64           `json:"finished_at,omitempty"` // Unix milliseconds
+}
+
+// LegalHoldRequest selects the entries of a database to put under or release from legal hold.
+type LegalHoldRequest struct {
+	DatabaseID string  `json:"database_id"`
+	IDs        []int64 `json:"ids"`
+	Reason     string  `json:"reason"` // mandatory, recorded in the audit log
+}
+
+// LegalHoldResponse reports the entries whose legal hold was set or cleared.
+type LegalHoldResponse struct {
+	DatabaseID string  `json:"database_id"`
+	LegalHold  bool    `json:"legal_hold"`
+	Updated    []int64 `json:"updated"`
+	Missing    []int64 `json:"missing"` // requested IDs that do not exist or are being deleted
 }
 
 // Helper for range parsing
@@ -131,13 +288,94 @@ type ImportResponse struct {
 	Message    string `json:"message"`
 }
 
+// CreateShareLinkRequest defines the payload for creating a share link.
+type CreateShareLinkRequest struct {
+	ExpiresAt    *int64 `json:"expires_at"`    // unix ms, defaults to 24 hours from now
+	MaxDownloads int64  `json:"max_downloads"` // 0 means unlimited
+	PreviewOnly  bool   `json:"preview_only"`
+}
+
+// ShareLinkResponse is the metadata of a share link. The token itself is never returned again.
+type ShareLinkResponse struct {
+	ID            string `json:"id"`
+	DatabaseID    string `json:"database_id"`
+	EntryID       int64  `json:"entry_id"`
+	PreviewOnly   bool   `json:"preview_only"`
+	MaxDownloads  int64  `json:"max_downloads"`
+	DownloadCount int64  `json:"download_count"`
+	CreatedBy     string `json:"created_by"`
+	CreatedAt     int64  `json:"created_at"`
+	ExpiresAt     int64  `json:"expires_at"`
+}
+
+// ShareLinkCreatedResponse includes the plaintext token and the public URL path. It is returned only once.
+type ShareLinkCreatedResponse struct {
+	ShareLinkResponse
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// CreateCommentRequest defines the payload for commenting on an entry.
+type CreateCommentRequest struct {
+	Body string `json:"body"` // up to 4 KB of text
+}
+
+// CommentResponse is a comment on an entry.
+type CommentResponse struct {
+	ID        int64  `json:"id"`
+	EntryID   int64  `json:"entry_id"`
+	Author    string `json:"author"`
+	Body      string `json:"body"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// CreateUploadGrantRequest defines the payload for issuing an upload grant.
+type CreateUploadGrantRequest struct {
+	DatabaseID  string         `json:"database_id"`
+	Metadata    map[string]any `json:"metadata,omitempty"` // template of the upload metadata, the upload cannot change its values
+	MaxFileSize int64          `json:"max_file_size"`      // bytes, required
+	ExpiresAt   *int64         `json:"expires_at"`         // unix ms, defaults to 1 hour from now, at most 7 days
+}
+
+// UploadGrantResponse is the metadata of an upload grant. The token itself is never returned again.
+type UploadGrantResponse struct {
+	ID          string         `json:"id"`
+	DatabaseID  string         `json:"database_id"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+	MaxFileSize int64          `json:"max_file_size"`
+	CreatedBy   string         `json:"created_by"`
+	CreatedAt   int64          `json:"created_at"`
+	ExpiresAt   int64          `json:"expires_at"`
+	ConsumedAt  *int64         `json:"consumed_at"` // null while the grant is unused
+}
+
+// UploadGrantCreatedResponse includes the plaintext token and the public upload URL path. It is returned only once.
+type UploadGrantCreatedResponse struct {
+	UploadGrantResponse
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// QueryPlanResponse is the SQL of a search request and the plan SQLite chooses for it.
+type QueryPlanResponse struct {
+	DatabaseID   string          `json:"database_id"`
+	DatabaseName string          `json:"database_name"`
+	SQL          string          `json:"sql"`
+	Args         []any           `json:"args"`
+	Plan         []QueryPlanStep `json:"plan"`
+}
+
+// QueryPlanStep is a row of EXPLAIN QUERY PLAN, e.g. "SEARCH entries USING INDEX ... (status=? AND timestamp>?)".
+// Steps nest through their parent, 0 is the top level.
+type QueryPlanStep struct {
+	ID     int    `json:"id"`
+	Parent int    `json:"parent"`
+	Detail string `json:"detail"`
+}
+
 // Interfaces
 
 // Define an interface that guarantees a GetID method
 type EntryWithID interface {
 	GetID() int64
 }
-
-// Add the method to both structs so they satisfy the interface
-func (e EntryResponse) GetID() int64        { return e.EntryID }
-func (p PartialEntryResponse) GetID() int64 { return p.EntryID }
@@ -0,0 +1,143 @@
+package entryhandler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Regenerate an entry preview
+// @Description Draws the preview of a ready entry again from its stored file, e.g. after the `waveform_*` settings of the database changed.
+// @Description Previews keep their format until they are regenerated: waveforms on a transparent background are stored as PNG, all other previews as WebP.
+// @Description Files larger than the synchronous upload limit are refused with `413`, their previews are only generated by the upload processing.
+// @Tags entry
+// @Produce json
+// @Param   database_id  path  string  true  "Database ID"
+// @Param   id           path  int64   true  "Entry ID"
+// @Success 200 {object} PartialEntryResponse "The entry with the size of the new preview"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request or ID format"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
+// @Failure 409 {object} utils.ErrorResponse "The entry is not ready or its type has no previews"
+// @Failure 413 {object} utils.ErrorResponse "The file is too large for a synchronous preview"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/entry/{id}/preview/regenerate [post]
+func (h *EntryHandler) RegenerateEntryPreview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	dbID := r.PathValue("database_id")
+	idStr := r.PathValue("id")
+	user := utils.GetUserFromContext(ctx)
+
+	// 1. Validate Input
+	if dbID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required path parameter: database_id")
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+
+	// 2. Get Database and Entry
+	db, err := h.Repo.GetDatabase(ctx, repo.ULID(dbID))
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		} else {
+			h.Logger.Error("Failed to fetch database", "database_id", dbID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch database.")
+		}
+		return
+	}
+	entry, err := h.Repo.GetEntry(ctx, db.ID, id)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Entry not found.")
+		} else {
+			h.Logger.Error("Failed to get entry", "database_id", dbID, "id", id, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get entry metadata.")
+		}
+		return
+	}
+	if entry.Status != repo.EntryStatusReady {
+		utils.RespondWithError(w, http.StatusConflict, fmt.Sprintf("Only ready entries get a new preview, the entry is '%s'.", repo.GetEntryStatusString(entry.Status)))
+		return
+	}
+	if h.MediaConverter == nil || !h.MediaConverter.CanCreatePreview(entry.MimeType) {
+		utils.RespondWithError(w, http.StatusConflict, fmt.Sprintf("This server cannot create previews of '%s' files.", entry.MimeType))
+		return
+	}
+	if limit := h.maxSyncUploadSize(); limit > 0 && entry.Size > uint64(limit) {
+		utils.RespondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Previews are only regenerated for files up to %d bytes.", limit))
+		return
+	}
+
+	// 3. Draw the preview into memory first, so a failure keeps the current one
+	source, err := h.Storage.Read(ctx, dbID, id, 0, -1)
+	if err != nil {
+		h.Logger.Error("Failed to read entry file for preview", "database_id", dbID, "id", id, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to read the stored file.")
+		return
+	}
+	defer source.Close()
+	seeker, ok := source.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(source)
+		if err != nil {
+			h.Logger.Error("Failed to read entry file for preview", "database_id", dbID, "id", id, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to read the stored file.")
+			return
+		}
+		seeker = bytes.NewReader(data)
+	}
+
+	var preview bytes.Buffer
+	styleCtx := media.WithWaveformStyle(ctx, media.WaveformStyle(db.Config.Waveform))
+	if err := h.MediaConverter.CreatePreviewFromStream(styleCtx, seeker, &preview, entry.MimeType); err != nil {
+		h.Logger.Error("Failed to regenerate preview", "database_id", dbID, "id", id, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to generate the preview.")
+		return
+	}
+	previewSize, err := h.Storage.WritePreview(ctx, dbID, id, &preview)
+	if err != nil {
+		h.Logger.Error("Failed to store regenerated preview", "database_id", dbID, "id", id, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to store the preview.")
+		return
+	}
+
+	// 4. Record the new size, a previous preview failure is resolved
+	entry.PreviewSize = uint64(previewSize)
+	err = h.Repo.UpdateEntryTechMetadata(ctx, db.ID, id, repo.EntryTechMetadata{
+		Size:             entry.Size,
+		PreviewSize:      entry.PreviewSize,
+		OriginalSize:     entry.OriginalSize,
+		MimeType:         entry.MimeType,
+		OriginalMimeType: entry.OriginalMimeType,
+	})
+	if err == nil && (entry.ErrorReason == processing.ErrorReasonPreviewFailed || entry.ErrorReason == processing.ErrorReasonDependencyMissing) {
+		entry.ErrorReason, entry.ErrorDetail = "", ""
+		err = h.Repo.UpdateEntryStatus(ctx, db.ID, id, entry.Status, "", "")
+	}
+	if err != nil {
+		h.Logger.Error("Failed to update entry after preview regeneration", "database_id", dbID, "id", id, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update the entry.")
+		return
+	}
+
+	// 5. Audit & Response
+	h.Auditor.Log(ctx, "entry.preview_regenerate", user.Username, fmt.Sprintf("%s:%d", dbID, id), map[string]any{"preview_size": entry.PreviewSize})
+
+	utils.RespondWithJSON(w, http.StatusOK, mapToPartialEntryResponse(dbID, h.redactEntry(ctx, dbID, entry)))
+}
@@ -0,0 +1,185 @@
+package entryhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/media/waveform"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/image/webp"
+)
+
+// waveformConverter draws the previews of WAV files in pure Go, in the waveform style of the context.
+type waveformConverter struct {
+	plainFileConverter
+}
+
+func (waveformConverter) CanCreatePreview(mimeType string) bool { return media.IsWAV(mimeType) }
+
+func (waveformConverter) CreatePreviewFromStream(ctx context.Context, in io.ReadSeeker, out io.Writer, _ string) error {
+	return waveform.Render(out, in, media.WaveformStyleFromContext(ctx))
+}
+
+// silentWAV returns 100 ms of silence, 8 bit mono PCM.
+func silentWAV() []byte {
+	samples := bytes.Repeat([]byte{128}, 800)
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(samples)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, []uint32{16})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 1})       // PCM, mono
+	binary.Write(&buf, binary.LittleEndian, []uint32{8000, 8000}) // sample and byte rate
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 8})       // block align, bits per sample
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(samples)))
+	buf.Write(samples)
+	return buf.Bytes()
+}
+
+func TestRegenerateEntryPreview(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "voice", ContentType: "audio", Config: repo.DatabaseConfig{CreatePreview: true}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	wav := silentWAV()
+	newEntry := func(status repo.EntryStatus) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:    "memo.wav",
+			Size:        uint64(len(wav)),
+			Status:      status,
+			Timestamp:   time.Now(),
+			MimeType:    "audio/wav",
+			MediaFields: map[string]any{"duration": 0.1, "channels": int64(1)},
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, bytes.NewReader(wav)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		return entry
+	}
+	entry := newEntry(repo.EntryStatusReady)
+	processing := newEntry(repo.EntryStatusProcessing)
+
+	h := &EntryHandler{
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		MediaConverter: waveformConverter{},
+	}
+	request := func(handler http.HandlerFunc, method, path string, id int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.SetPathValue("database_id", db.ID.String())
+		req.SetPathValue("id", strconv.FormatInt(id, 10))
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	regenerate := func(id int64) *httptest.ResponseRecorder {
+		return request(h.RegenerateEntryPreview, http.MethodPost, "/preview/regenerate", id)
+	}
+	preview := func(wantType string) image.Image {
+		t.Helper()
+		rec := request(h.GetEntryPreview, http.MethodGet, "/preview", entry.ID)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 for the preview, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Content-Type"); got != wantType {
+			t.Fatalf("expected the preview as %s, got %s", wantType, got)
+		}
+		decode := webp.Decode
+		if wantType == "image/png" {
+			decode = png.Decode
+		}
+		img, err := decode(rec.Body)
+		if err != nil {
+			t.Fatalf("failed to decode the %s preview: %v", wantType, err)
+		}
+		return img
+	}
+
+	// 1. The default style draws a WebP waveform
+	if rec := regenerate(entry.ID); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if size := preview("image/webp").Bounds().Size(); size != image.Pt(200, 120) {
+		t.Errorf("expected the default size, got %v", size)
+	}
+
+	// 2. A new style leaves the existing preview alone until it is regenerated
+	if db, err = r.GetDatabase(ctx, db.ID); err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	db.Config.Waveform = repo.WaveformConfig{Width: 64, Height: 32, Color: "#ffffff", Background: media.WaveformTransparent}
+	if _, err := r.UpdateDatabase(ctx, db); err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+	preview("image/webp")
+	if rec := regenerate(entry.ID); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	img := preview("image/png")
+	if size := img.Bounds().Size(); size != image.Pt(64, 32) {
+		t.Errorf("expected 64x32, got %v", size)
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("expected a transparent background, got alpha %d", a)
+	}
+	stored, err := r.GetEntry(ctx, db.ID, entry.ID)
+	if err != nil {
+		t.Fatalf("failed to get entry: %v", err)
+	}
+	info, err := store.StatPreview(ctx, db.ID.String(), entry.ID)
+	if err != nil {
+		t.Fatalf("failed to stat preview: %v", err)
+	}
+	if stored.PreviewSize == 0 || int64(stored.PreviewSize) != info.Size {
+		t.Errorf("expected the preview size %d on the entry, got %d", info.Size, stored.PreviewSize)
+	}
+
+	// 3. Entries still being processed are left to the processing
+	if rec := regenerate(processing.ID); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a processing entry, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,212 @@
+package entryhandler
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/storage"
+)
+
+const (
+	// maxPreviewExportIDs caps the entries of a single preview export.
+	maxPreviewExportIDs = 5000
+
+	previewExportFormatTar = "tar"
+	tarContentType         = "application/x-tar"
+
+	// previewExportSkippedFile is the last file of a preview export, listing the entries without preview.
+	previewExportSkippedFile = "skipped.json"
+)
+
+// previewArchive writes the files of a preview export, either as uncompressed ZIP or as TAR.
+type previewArchive interface {
+	add(name string, info storage.FileInfo, content io.Reader) error
+	close() error
+}
+
+// zipPreviewArchive stores the previews without compression, as WebP does not compress any further.
+type zipPreviewArchive struct{ w *zip.Writer }
+
+func (a zipPreviewArchive) add(name string, info storage.FileInfo, content io.Reader) error {
+	file, err := a.w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: info.LastModified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, content)
+	return err
+}
+
+func (a zipPreviewArchive) close() error { return a.w.Close() }
+
+// tarPreviewArchive needs the size of every file upfront, it is taken from storage.StatPreview.
+type tarPreviewArchive struct{ w *tar.Writer }
+
+func (a tarPreviewArchive) add(name string, info storage.FileInfo, content io.Reader) error {
+	if err := a.w.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: info.Size, ModTime: info.LastModified, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := io.CopyN(a.w, content, info.Size)
+	return err
+}
+
+func (a tarPreviewArchive) close() error { return a.w.Close() }
+
+// previewExportFormat picks the archive format from the request body, falling back to the Accept header.
+func previewExportFormat(r *http.Request, format string) (string, bool) {
+	if format == "" {
+		if strings.Contains(r.Header.Get("Accept"), tarContentType) {
+			return previewExportFormatTar, true
+		}
+		return exportFormatZip, true
+	}
+	return format, format == exportFormatZip || format == previewExportFormatTar
+}
+
+// @Summary Export the previews of entries
+// @Description Streams the previews of the given entries of a database as uncompressed ZIP or as TAR archive, named `<id>.webp` (`<id>.png` for transparent waveforms).
+// @Description The format is `format` of the body, or `application/x-tar` in the Accept header; ZIP is the default.
+// @Description Entries that do not exist, have no preview or whose preview cannot be read are skipped and listed with the reason in `skipped.json`, the last file of the archive.
+// @Description Requires the CanView role on the database. Counts towards `server.max_concurrent_exports` like the entry export.
+// @Tags database
+// @Accept  json
+// @Produce application/zip
+// @Produce application/x-tar
+// @Param   body  body  PreviewExportRequest  true  "Database, entry IDs (at most 5000) and format"
+// @Success 200 {file} file "ZIP or TAR archive of the previews and skipped.json"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request, empty or too many IDs, or unknown format"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 429 {object} utils.ErrorResponse "Too many exports are running"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/previews/export [post]
+func (h *EntryHandler) ExportPreviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+
+	// 1. Validate Input
+	var req PreviewExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DatabaseID == "" || len(req.IDs) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request, a database_id and ids are required")
+		return
+	}
+	if len(req.IDs) > maxPreviewExportIDs {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Too many IDs, at most %d previews can be exported at once", maxPreviewExportIDs))
+		return
+	}
+	format, ok := previewExportFormat(r, req.Format)
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, "Unknown format, expected 'zip' or 'tar'")
+		return
+	}
+
+	// 2. Databases the user may not view are reported as not found
+	holder := utils.GetPermissionHolderFromContext(ctx)
+	if !holder.IsGlobalAdmin() && !holder.HasPermission(repo.ULID(req.DatabaseID), repo.AccessView) {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found")
+		return
+	}
+	db, err := h.Repo.GetDatabase(ctx, repo.ULID(req.DatabaseID))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found")
+		return
+	}
+	dbID := db.ID.String()
+
+	// 3. Each export holds a connection and an open file until the client has downloaded it
+	if !h.Exports.tryAcquire() {
+		h.Logger.Warn("Preview export rejected, too many exports running", "database_id", dbID, "user", user.Username)
+		w.Header().Set("Retry-After", strconv.Itoa(int(exportRetryAfter.Seconds())))
+		utils.RespondWithError(w, http.StatusTooManyRequests, "Too many exports are running, retry later.")
+		return
+	}
+	defer h.Exports.release()
+
+	h.Auditor.Log(ctx, "entries.export_previews", user.Username, dbID, map[string]any{"count": len(req.IDs), "format": format})
+
+	// 4. Stream the archive, a stalled client aborts it after the write timeout
+	start := time.Now()
+	out := h.newExportWriter(w)
+	var archive previewArchive
+	if format == previewExportFormatTar {
+		w.Header().Set("Content-Type", tarContentType)
+		archive = tarPreviewArchive{tar.NewWriter(out)}
+	} else {
+		w.Header().Set("Content-Type", "application/zip")
+		archive = zipPreviewArchive{zip.NewWriter(out)}
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s_previews.%s\"", db.Name, format))
+
+	exported := 0
+	defer func() { h.logExport(ctx, dbID, "previews_"+format, start, out, exported) }()
+
+	skipped := []PreviewExportSkipped{}
+	for _, id := range req.IDs {
+		if out.err != nil || ctx.Err() != nil {
+			return
+		}
+		reason, err := h.addPreviewToArchive(ctx, archive, dbID, id)
+		if err != nil {
+			// The archive is broken once a file was only partially written
+			if out.err == nil {
+				h.Logger.Error("Failed to write preview to archive", "database_id", dbID, "id", id, "error", err)
+			}
+			return
+		}
+		if reason != "" {
+			skipped = append(skipped, PreviewExportSkipped{ID: id, Reason: reason})
+			continue
+		}
+		exported++
+	}
+
+	// 5. The skipped entries close the archive
+	skippedJSON, _ := json.Marshal(skipped)
+	if err := archive.add(previewExportSkippedFile, storage.FileInfo{Size: int64(len(skippedJSON)), LastModified: time.Now()}, bytes.NewReader(skippedJSON)); err != nil {
+		return
+	}
+	if err := archive.close(); err != nil {
+		if out.err == nil {
+			h.Logger.Error("Failed to finish preview export", "error", err)
+		}
+		return
+	}
+	out.finish()
+}
+
+// addPreviewToArchive writes the preview of an entry, or returns why it was skipped. The preview file is
+// closed before returning. An error means the archive could not be written and the export must stop.
+func (h *EntryHandler) addPreviewToArchive(ctx context.Context, archive previewArchive, dbID string, id int64) (string, error) {
+	entry, err := h.Repo.GetEntry(ctx, repo.ULID(dbID), id)
+	if err != nil {
+		return "not_found", nil
+	}
+	if entry.PreviewSize == 0 {
+		return "no_preview", nil
+	}
+
+	info, err := h.Storage.StatPreview(ctx, dbID, id)
+	if err != nil {
+		h.Logger.Warn("Skipping preview in export (stat failed)", "database_id", dbID, "id", id, "error", err)
+		return "read_failed", nil
+	}
+	source, err := h.openPreview(ctx, dbID, id)
+	if err != nil {
+		h.Logger.Warn("Skipping preview in export (read failed)", "database_id", dbID, "id", id, "error", err)
+		return "read_failed", nil
+	}
+	defer source.Close()
+
+	return "", archive.add(fmt.Sprintf("%d.%s", id, source.Extension), info, source)
+}
@@ -0,0 +1,193 @@
+package entryhandler
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestExportPreviews(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "previews", ContentType: "image"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+
+	// first and last have a preview, the preview of lost is missing in the storage, plain never had one
+	create := func(previewSize uint64) repo.Entry {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:    "a.png",
+			Timestamp:   time.Now(),
+			Status:      repo.EntryStatusReady,
+			MimeType:    "image/png",
+			PreviewSize: previewSize,
+			MediaFields: map[string]any{"width": uint64(1), "height": uint64(1)},
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		return entry
+	}
+	first, lost, plain, last := create(5), create(5), create(0), create(4)
+	previews := map[int64]string{first.ID: "first", last.ID: "last"}
+	for id, content := range previews {
+		if _, err := store.WritePreview(ctx, db.ID.String(), id, strings.NewReader(content)); err != nil {
+			t.Fatalf("failed to write preview: %v", err)
+		}
+	}
+	const unknown = 999
+
+	viewer, err := r.CreateUser(ctx, repo.User{Username: "viewer", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+		Storage: store,
+	}
+	export := func(body, accept string, holder utils.PermissionHolder) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/database/previews/export", strings.NewReader(body))
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, holder))
+		rec := httptest.NewRecorder()
+		h.ExportPreviews(rec, req)
+		return rec
+	}
+	ids := fmt.Sprintf(`[%d, %d, %d, %d, %d]`, first.ID, lost.ID, plain.ID, unknown, last.ID)
+	wantSkipped := []PreviewExportSkipped{{ID: lost.ID, Reason: "read_failed"}, {ID: plain.ID, Reason: "no_preview"}, {ID: unknown, Reason: "not_found"}}
+	checkSkipped := func(format string, data []byte) {
+		var skipped []PreviewExportSkipped
+		if err := json.Unmarshal(data, &skipped); err != nil {
+			t.Fatalf("%s: failed to decode skipped.json: %v", format, err)
+		}
+		if fmt.Sprint(skipped) != fmt.Sprint(wantSkipped) {
+			t.Errorf("%s: expected skipped %v, got %v", format, wantSkipped, skipped)
+		}
+	}
+
+	// 1. The ZIP stores the previews uncompressed, the missing preview does not abort the stream
+	rec := export(fmt.Sprintf(`{"database_id": %q, "ids": %s}`, db.ID, ids), "", &utils.GlobalAdmin{})
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a ZIP, got %d: %s", rec.Code, rec.Body.String())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to open the ZIP: %v", err)
+	}
+	var names []string
+	for _, file := range zr.File {
+		names = append(names, file.Name)
+		if file.Method != zip.Store {
+			t.Errorf("expected %s to be stored without compression", file.Name)
+		}
+		f, err := file.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(f)
+		f.Close()
+		if file.Name == previewExportSkippedFile {
+			checkSkipped("zip", content)
+		} else if want := previews[mustParseID(t, file.Name)]; string(content) != want {
+			t.Errorf("expected %s to contain %q, got %q", file.Name, want, content)
+		}
+	}
+	wantNames := fmt.Sprint([]string{fmt.Sprintf("%d.webp", first.ID), fmt.Sprintf("%d.webp", last.ID), previewExportSkippedFile})
+	if fmt.Sprint(names) != wantNames {
+		t.Errorf("expected the files %s, got %v", wantNames, names)
+	}
+
+	// 2. The TAR is selected by the Accept header and has the same contents
+	rec = export(fmt.Sprintf(`{"database_id": %q, "ids": %s}`, db.ID, ids), "application/x-tar", &utils.GlobalAdmin{})
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-tar" {
+		t.Fatalf("expected a TAR, got %d: %s", rec.Code, rec.Body.String())
+	}
+	tr := tar.NewReader(rec.Body)
+	names = nil
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to read the TAR: %v", err)
+		}
+		names = append(names, header.Name)
+		content, _ := io.ReadAll(tr)
+		if header.Name == previewExportSkippedFile {
+			checkSkipped("tar", content)
+		} else if want := previews[mustParseID(t, header.Name)]; string(content) != want {
+			t.Errorf("expected %s to contain %q, got %q", header.Name, want, content)
+		}
+	}
+	if fmt.Sprint(names) != wantNames {
+		t.Errorf("expected the files %s, got %v", wantNames, names)
+	}
+
+	// 3. Invalid requests and databases the user may not view
+	for name, body := range map[string]string{
+		"no ids":         fmt.Sprintf(`{"database_id": %q, "ids": []}`, db.ID),
+		"no database":    `{"ids": [1]}`,
+		"unknown format": fmt.Sprintf(`{"database_id": %q, "ids": [1], "format": "rar"}`, db.ID),
+		"too many ids":   fmt.Sprintf(`{"database_id": %q, "ids": [%s0]}`, db.ID, strings.Repeat("0,", maxPreviewExportIDs)),
+	} {
+		if rec := export(body, "", &utils.GlobalAdmin{}); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+	noAccess := &utils.UserPermissions{UserULID: viewer.ID, Scope: repo.NewAccessGrant(true, true, true, true, true), Repo: r}
+	if rec := export(fmt.Sprintf(`{"database_id": %q, "ids": %s}`, db.ID, ids), "", noAccess); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without the view role, got %d", rec.Code)
+	}
+}
+
+// mustParseID returns the entry ID of a preview file named <id>.webp.
+func mustParseID(t *testing.T, name string) int64 {
+	t.Helper()
+	var id int64
+	if _, err := fmt.Sscanf(name, "%d.webp", &id); err != nil {
+		t.Fatalf("unexpected file %s in the archive", name)
+	}
+	return id
+}
@@ -0,0 +1,74 @@
+package entryhandler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Get the processing progress of an entry
+// @Description Returns the current phase (`queued`, `starting`, `converting`, `preview`, `finalizing`) of an entry that is processed asynchronously, with the percentage within the phase if it can be measured (conversions with FFmpeg).
+// @Description Once the entry is `ready` or `error`, the endpoint returns `404`; fetch the entry metadata for the result.
+// @Tags entry
+// @Produce json
+// @Param   database_id  path  string  true  "Database ID"
+// @Param   id           path  int64   true  "Entry ID"
+// @Success 200 {object} ProgressResponse "The current progress"
+// @Failure 400 {object} utils.ErrorResponse "Invalid ID format"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role)"
+// @Failure 404 {object} utils.ErrorResponse "Entry not found or not being processed"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/entry/{id}/progress [get]
+func (h *EntryHandler) GetEntryProgress(w http.ResponseWriter, r *http.Request) {
+	dbID := repo.ULID(r.PathValue("database_id"))
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+
+	// Not audited: clients poll this endpoint while waiting for an upload
+	if progress, ok := h.Processor.Progress.Get(dbID, id); ok {
+		utils.RespondWithJSON(w, http.StatusOK, mapToProgressResponse(progress))
+		return
+	}
+
+	// No worker reported yet, the entry status tells whether one will
+	entry, err := h.Repo.GetEntry(r.Context(), dbID, id)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Entry not found.")
+		} else {
+			h.Logger.Error("Failed to get entry", "database_id", dbID, "id", id, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get entry metadata.")
+		}
+		return
+	}
+
+	switch entry.Status {
+	case repo.EntryStatusQueued:
+		utils.RespondWithJSON(w, http.StatusOK, ProgressResponse{Phase: processing.PhaseQueued, UpdatedAt: entry.UpdatedAt.UnixMilli()})
+	case repo.EntryStatusProcessing:
+		utils.RespondWithJSON(w, http.StatusOK, ProgressResponse{Phase: processing.PhaseStarting, UpdatedAt: time.Now().UnixMilli()})
+	default:
+		utils.RespondWithError(w, http.StatusNotFound, "The entry is not being processed.")
+	}
+}
+
+func mapToProgressResponse(p processing.Progress) ProgressResponse {
+	resp := ProgressResponse{Phase: p.Phase, UpdatedAt: p.UpdatedAt.UnixMilli()}
+	if p.Percent >= 0 {
+		percent := math.Round(p.Percent*10) / 10
+		resp.Percent = &percent
+	}
+	return resp
+}
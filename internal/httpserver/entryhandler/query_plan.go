@@ -0,0 +1,81 @@
+package entryhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Explain the query of an entry search
+// @Description Builds the SQL of a search request like POST /database/{database_id}/entries/search and returns it with SQLite's `EXPLAIN QUERY PLAN` output, without running it.
+// @Description A `SCAN` of the entry table reads every entry, a `SEARCH ... USING INDEX` only the matching ones; declare `custom_indexes` in the database definition for frequent filter combinations.
+// @Description Without a body the plan of listing the newest entries is returned.
+// @Tags database
+// @Accept  json
+// @Produce json
+// @Param    database_id  path  string                    true   "Database ID"
+// @Param    search       body  repository.SearchRequest  false  "The search request to explain"
+// @Success 200 {object} QueryPlanResponse
+// @Failure 400 {object} utils.ErrorResponse "Invalid JSON, invalid pagination, or invalid filter/sort/fields"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires global admin)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/query_plan [get]
+func (h *EntryHandler) GetQueryPlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	dbID := r.PathValue("database_id")
+
+	var searchPayload SearchRequestPayload
+	if err := json.NewDecoder(r.Body).Decode(&searchPayload); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if err := checkPagination(searchPayload.Pagination); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db, err := h.Repo.GetDatabase(ctx, repo.ULID(dbID))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	searchReq, err := withGeoFields(db, searchRequestToModel(searchPayload))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	plan, err := h.Repo.ExplainSearch(ctx, db.ID, searchReq, db.CustomFields)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.Logger.Error("Failed to explain search", "database_id", db.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	resp := QueryPlanResponse{
+		DatabaseID:   db.ID.String(),
+		DatabaseName: db.Name,
+		SQL:          plan.SQL,
+		Args:         plan.Args,
+		Plan:         make([]QueryPlanStep, len(plan.Steps)),
+	}
+	if resp.Args == nil {
+		resp.Args = []any{}
+	}
+	for i, step := range plan.Steps {
+		resp.Plan[i] = QueryPlanStep{ID: step.ID, Parent: step.Parent, Detail: step.Detail}
+	}
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,101 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestGetQueryPlan(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:          "detections",
+		ContentType:   "file",
+		CustomFields:  []repo.CustomFieldDef{{Name: "camera", Type: "TEXT"}},
+		CustomIndexes: [][]string{{"camera", "timestamp"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	call := func(dbID repo.ULID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/database/"+dbID.String()+"/query_plan", strings.NewReader(body))
+		req.SetPathValue("database_id", dbID.String())
+		rec := httptest.NewRecorder()
+		h.GetQueryPlan(rec, req)
+		return rec
+	}
+
+	// 1. The plan of a search uses the declared index, the SQL is returned with its arguments
+	rec := call(db.ID, `{"filter": {"operator": "and", "conditions": [{"field": "camera", "operator": "=", "value": "gate"}]}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp QueryPlanResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.DatabaseID != db.ID.String() || !strings.Contains(resp.SQL, "entries_"+db.ID.String()) || len(resp.Args) == 0 || resp.Args[0] != "gate" {
+		t.Errorf("unexpected query %+v", resp)
+	}
+	var details []string
+	for _, step := range resp.Plan {
+		details = append(details, step.Detail)
+	}
+	if plan := strings.Join(details, "\n"); !strings.Contains(plan, "USING INDEX idx_entries_"+db.ID.String()+"_ix_cf_0__timestamp") {
+		t.Errorf("expected the composite index to be used, got %q", plan)
+	}
+
+	// 2. Without a body the plan of the default listing is returned
+	if rec := call(db.ID, ""); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 without a body, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// 3. Invalid requests and unknown databases
+	for name, tc := range map[string]struct {
+		dbID repo.ULID
+		body string
+		want int
+	}{
+		"unknown database": {"01ARZ3NDEKTSV4RRFFQ69G5FAV", "", http.StatusNotFound},
+		"unknown field":    {db.ID, `{"filter": {"operator": "and", "conditions": [{"field": "nope", "operator": "=", "value": 1}]}}`, http.StatusBadRequest},
+		"invalid json":     {db.ID, "{", http.StatusBadRequest},
+	} {
+		if rec := call(tc.dbID, tc.body); rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package entryhandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Retry a failed upload
+// @Description Processes an entry in status `error` again, using the source file that was kept when its asynchronous processing failed.
+// @Description Sources are only kept for retryable failures (`error_reason` conversion_failed, storage_failed, dependency_missing or internal_error) and only for the configured grace period.
+// @Description The entry is queued and returned with `202 Accepted`; poll its metadata until the `status` field is 'ready' or 'error'.
+// @Tags entry
+// @Produce json
+// @Param   database_id  path  string  true  "Database ID"
+// @Param   id           path  int64   true  "Entry ID"
+// @Success 202 {object} PartialEntryResponse "The entry was queued for processing"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request or ID format"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
+// @Failure 409 {object} utils.ErrorResponse "The entry is not in status error"
+// @Failure 410 {object} utils.ErrorResponse "The source of the upload is no longer available, the file must be uploaded again"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Failure 503 {object} utils.ErrorResponse "Queue full"
+// @Security BasicAuth
+// @Router /database/{database_id}/entry/{id}/retry [post]
+func (h *EntryHandler) RetryEntry(w http.ResponseWriter, r *http.Request) {
+	dbID := r.PathValue("database_id")
+	idStr := r.PathValue("id")
+	user := utils.GetUserFromContext(r.Context())
+
+	// 1. Validate Input
+	if dbID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required path parameter: database_id")
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+
+	// 2. Get Database and Entry
+	db, err := h.Repo.GetDatabase(r.Context(), repo.ULID(dbID))
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		} else {
+			h.Logger.Error("Failed to fetch database", "database_id", dbID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch database.")
+		}
+		return
+	}
+	entry, err := h.Repo.GetEntry(r.Context(), db.ID, id)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Entry not found.")
+		} else {
+			h.Logger.Error("Failed to get entry", "database_id", dbID, "id", id, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get entry metadata.")
+		}
+		return
+	}
+
+	// 3. Queue it again
+	queued, err := h.Processor.RetryEntry(r.Context(), db, entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, customerrors.ErrConflict):
+			utils.RespondWithError(w, http.StatusConflict, fmt.Sprintf("Only entries in status 'error' can be retried, the entry is '%s'.", repo.GetEntryStatusString(entry.Status)))
+		case errors.Is(err, customerrors.ErrGone):
+			utils.RespondWithError(w, http.StatusGone, "The source of this upload is no longer available. Please delete the entry and upload the file again.")
+		case errors.Is(err, customerrors.ErrUnavailable):
+			utils.RespondWithError(w, http.StatusServiceUnavailable, "Service Unavailable: queue is full.")
+		default:
+			h.Logger.Error("Failed to retry entry", "database_id", dbID, "id", id, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retry the entry.")
+		}
+		return
+	}
+
+	// 4. Audit & Response
+	h.Auditor.Log(r.Context(), "entry.retry", user.Username, fmt.Sprintf("%s:%d", dbID, id), map[string]any{"error_reason": entry.ErrorReason})
+
+	utils.RespondWithJSON(w, http.StatusAccepted, mapToPartialEntryResponse(dbID, h.redactEntry(r.Context(), dbID, queued)))
+}
@@ -0,0 +1,207 @@
+package entryhandler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// @Summary Extract an audio segment
+// @Description Encodes a time range of an audio entry with FFmpeg and streams it without a temporary file.
+// @Description The duration is clamped to the end of the file and limited by the configured maximum segment duration.
+// @Tags entry
+// @Produce audio/opus
+// @Produce audio/flac
+// @Produce audio/wav
+// @Param   database_id  path   string  true   "Database ID"
+// @Param   id           path   int64   true   "Entry ID"
+// @Param   start        query  number  true   "Start of the segment in seconds"
+// @Param   duration     query  number  true   "Length of the segment in seconds"
+// @Param   format       query  string  false  "Output format: opus (default), flac or wav"
+// @Success 200 {file} file "The encoded segment"
+// @Failure 400 {object} utils.ErrorResponse "Invalid range or format, or not an audio database"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
+// @Failure 423 {object} utils.ErrorResponse "Entry is still processing"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Failure 501 {object} utils.ErrorResponse "FFmpeg is not available"
+// @Security BasicAuth
+// @Router /database/{database_id}/entry/{id}/segment [get]
+func (h *EntryHandler) GetEntrySegment(w http.ResponseWriter, r *http.Request) {
+	dbID := r.PathValue("database_id")
+	idStr := r.PathValue("id")
+	user := utils.GetUserFromContext(r.Context())
+
+	// 1. Validate Input
+	if dbID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required path parameter: database_id")
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+
+	query := r.URL.Query()
+	// ParseFloat accepts NaN and Inf, which would pass every comparison of clampSegment
+	start, err := strconv.ParseFloat(query.Get("start"), 64)
+	if err != nil || math.IsNaN(start) || math.IsInf(start, 0) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid or missing 'start' parameter (seconds).")
+		return
+	}
+	duration, err := strconv.ParseFloat(query.Get("duration"), 64)
+	if err != nil || math.IsNaN(duration) || math.IsInf(duration, 0) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid or missing 'duration' parameter (seconds).")
+		return
+	}
+	formatName := query.Get("format")
+	if formatName == "" {
+		formatName = "opus"
+	}
+	format, ok := media.AudioSegmentFormats[formatName]
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported format '%s'. Use opus, flac or wav.", formatName))
+		return
+	}
+
+	// 2. Get Database and Entry
+	db, err := h.Repo.GetDatabase(r.Context(), repo.ULID(dbID))
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		} else {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get database.")
+		}
+		return
+	}
+	if db.ContentType != "audio" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Segments can only be extracted from audio databases.")
+		return
+	}
+
+	entry, err := h.Repo.GetEntry(r.Context(), db.ID, id)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Entry not found.")
+		} else {
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get entry metadata.")
+		}
+		return
+	}
+	if entry.Status == repo.EntryStatusProcessing || entry.Status == repo.EntryStatusQueued {
+		utils.RespondWithError(w, http.StatusLocked, "Entry is currently being processed. Try again later.")
+		return
+	}
+
+	// 3. Validate the range against the stored duration
+	duration, err = clampSegment(start, duration, entryDuration(entry), h.MaxSegmentDuration.Seconds())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 4. Open the stored file, FFmpeg needs to seek in it
+	stream, err := h.Storage.Read(r.Context(), dbID, id, 0, -1)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to read file from storage.")
+		return
+	}
+	defer stream.Close()
+
+	seeker, ok := stream.(io.ReadSeeker)
+	if !ok {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Storage does not support seeking in files.")
+		return
+	}
+
+	// 5. Stream the encoded segment
+	w.Header().Set("Content-Type", format.MimeType)
+	w.Header().Set("Content-Disposition", contentDisposition("attachment", segmentFileName(entry, start, duration, format.Extension)))
+
+	out := &writeTracker{ResponseWriter: w}
+	if err := h.MediaConverter.ExtractAudioSegment(r.Context(), seeker, out, start, duration, formatName); err != nil {
+		if out.written {
+			// The status line is already sent, all we can do is log and cut the response short
+			h.Logger.Error("Failed to stream audio segment", "entry", id, "error", err)
+			return
+		}
+		if errors.Is(err, customerrors.ErrNotImplemented) {
+			utils.RespondWithError(w, http.StatusNotImplemented, "Segment extraction requires FFmpeg, which is not available on this server.")
+		} else {
+			h.Logger.Error("Failed to extract audio segment", "entry", id, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to extract audio segment.")
+		}
+		return
+	}
+
+	// 6. Auditor logging
+	h.Auditor.Log(r.Context(), "entry.segment", user.Username, fmt.Sprintf("%s:%d", dbID, id), map[string]any{
+		"start":    start,
+		"duration": duration,
+		"format":   formatName,
+	})
+}
+
+// clampSegment validates a requested segment and returns its duration, shortened to end with the file.
+// A total of 0 means the length of the file is unknown and skips the end check. A maximum of 0 disables the limit.
+func clampSegment(start, duration, total, maximum float64) (float64, error) {
+	if start < 0 {
+		return 0, errors.New("'start' must not be negative.")
+	}
+	if duration <= 0 {
+		return 0, errors.New("'duration' must be positive.")
+	}
+	if maximum > 0 && duration > maximum {
+		return 0, fmt.Errorf("'duration' exceeds the maximum segment duration of %g seconds.", maximum)
+	}
+	if total > 0 {
+		if start >= total {
+			return 0, fmt.Errorf("'start' is beyond the end of the file (%g seconds).", total)
+		}
+		duration = min(duration, total-start)
+	}
+	return duration, nil
+}
+
+// entryDuration returns the stored duration of an audio entry in seconds, or 0 if it is unknown.
+func entryDuration(entry repo.Entry) float64 {
+	switch v := entry.MediaFields["duration"].(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+// segmentFileName generates a download name like "talk_720-810.opus".
+func segmentFileName(entry repo.Entry, start, duration float64, ext string) string {
+	base := strings.TrimSuffix(entry.FileName, filepath.Ext(entry.FileName))
+	if base == "" {
+		base = strconv.FormatInt(entry.ID, 10)
+	}
+	return fmt.Sprintf("%s_%g-%g.%s", base, start, start+duration, ext)
+}
+
+// writeTracker records whether anything was sent, so errors can still be answered with a status code.
+type writeTracker struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (t *writeTracker) Write(p []byte) (int, error) {
+	t.written = true
+	return t.ResponseWriter.Write(p)
+}
@@ -0,0 +1,194 @@
+package entryhandler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestClampSegment(t *testing.T) {
+	tests := []struct {
+		name                          string
+		start, duration, total, limit float64
+		want                          float64
+		wantErr                       bool
+	}{
+		{name: "inside the file", start: 10, duration: 20, total: 100, limit: 600, want: 20},
+		{name: "ends exactly at the end", start: 80, duration: 20, total: 100, limit: 600, want: 20},
+		{name: "clamped to the end", start: 90, duration: 30, total: 100, limit: 600, want: 10},
+		{name: "unknown length is not clamped", start: 90, duration: 30, total: 0, limit: 600, want: 30},
+		{name: "no limit", start: 0, duration: 5000, total: 0, limit: 0, want: 5000},
+		{name: "start at the end", start: 100, duration: 10, total: 100, limit: 600, wantErr: true},
+		{name: "start beyond the end", start: 120, duration: 10, total: 100, limit: 600, wantErr: true},
+		{name: "negative start", start: -1, duration: 10, total: 100, limit: 600, wantErr: true},
+		{name: "zero duration", start: 0, duration: 0, total: 100, limit: 600, wantErr: true},
+		{name: "above the limit", start: 0, duration: 601, total: 1000, limit: 600, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := clampSegment(tt.start, tt.duration, tt.total, tt.limit)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got duration %g", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected duration %g, got %g", tt.want, got)
+			}
+		})
+	}
+}
+
+// segmentConverter records the requested range instead of running FFmpeg.
+type segmentConverter struct {
+	media.MediaConverter
+	unavailable   bool
+	start, length float64
+}
+
+func (c *segmentConverter) ExtractAudioSegment(ctx context.Context, in io.ReadSeeker, out io.Writer, start, duration float64, format string) error {
+	if c.unavailable {
+		return fmt.Errorf("ffmpeg is not available: %w", customerrors.ErrNotImplemented)
+	}
+	c.start, c.length = start, duration
+	_, err := out.Write([]byte("segment"))
+	return err
+}
+
+func TestGetEntrySegment(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	audioDB, err := r.CreateDatabase(ctx, repo.Database{Name: "segment_audio", ContentType: "audio"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	fileDB, err := r.CreateDatabase(ctx, repo.Database{Name: "segment_file", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	newEntry := func(db repo.Database, status repo.EntryStatus, fields map[string]any) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:    "talk.flac",
+			Size:        4,
+			Status:      status,
+			Timestamp:   time.Now(),
+			MimeType:    "audio/flac",
+			MediaFields: fields,
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		return entry
+	}
+	audioFields := map[string]any{"duration": 100.0, "channels": int64(2)}
+	ready := newEntry(audioDB, repo.EntryStatusReady, audioFields)
+	processing := newEntry(audioDB, repo.EntryStatusProcessing, audioFields)
+	file := newEntry(fileDB, repo.EntryStatusReady, nil)
+
+	converter := &segmentConverter{}
+	h := &EntryHandler{
+		Logger:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor:            audit.NewAlNoopLogger(),
+		Repo:               r,
+		Storage:            store,
+		MediaConverter:     converter,
+		MaxSegmentDuration: 10 * time.Minute,
+	}
+
+	get := func(db repo.Database, id int64, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/segment?"+query, nil)
+		req.SetPathValue("database_id", db.ID.String())
+		req.SetPathValue("id", fmt.Sprint(id))
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		h.GetEntrySegment(rec, req)
+		return rec
+	}
+
+	// Clamped at the end of the file
+	rec := get(audioDB, ready.ID, "start=90&duration=30&format=flac")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if converter.start != 90 || converter.length != 10 {
+		t.Errorf("expected segment 90+10s, got %g+%gs", converter.start, converter.length)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "audio/flac" {
+		t.Errorf("expected Content-Type audio/flac, got %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="talk_90-100.flac"`) {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+
+	cases := []struct {
+		name  string
+		db    repo.Database
+		id    int64
+		query string
+		want  int
+	}{
+		{"start NaN", audioDB, ready.ID, "start=NaN&duration=5", http.StatusBadRequest},
+		{"duration NaN", audioDB, ready.ID, "start=0&duration=NaN", http.StatusBadRequest},
+		{"duration Inf", audioDB, ready.ID, "start=0&duration=Inf", http.StatusBadRequest},
+		{"start beyond the end", audioDB, ready.ID, "start=100&duration=5", http.StatusBadRequest},
+		{"above the maximum", audioDB, ready.ID, "start=0&duration=601", http.StatusBadRequest},
+		{"unknown format", audioDB, ready.ID, "start=0&duration=5&format=mp3", http.StatusBadRequest},
+		{"non-audio database", fileDB, file.ID, "start=0&duration=5", http.StatusBadRequest},
+		{"still processing", audioDB, processing.ID, "start=0&duration=5", http.StatusLocked},
+		{"missing entry", audioDB, 9999, "start=0&duration=5", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if rec := get(tc.db, tc.id, tc.query); rec.Code != tc.want {
+			t.Errorf("%s: expected %d, got %d: %s", tc.name, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+
+	// No FFmpeg
+	converter.unavailable = true
+	if rec := get(audioDB, ready.ID, "start=0&duration=5"); rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected 501 without FFmpeg, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,323 @@
+package entryhandler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultShareLinkTTL is used when no expiry is provided on creation.
+const defaultShareLinkTTL = 24 * time.Hour
+
+func mapToShareLinkResponse(link repo.ShareLink) ShareLinkResponse {
+	return ShareLinkResponse{
+		ID:            link.ID.String(),
+		DatabaseID:    link.DatabaseID.String(),
+		EntryID:       link.EntryID,
+		PreviewOnly:   link.PreviewOnly,
+		MaxDownloads:  link.MaxDownloads,
+		DownloadCount: link.DownloadCount,
+		CreatedBy:     link.CreatedBy,
+		CreatedAt:     link.CreatedAt.UnixMilli(),
+		ExpiresAt:     link.ExpiresAt.UnixMilli(),
+	}
+}
+
+// hashToken returns the hex encoded SHA-256 hash of a share or upload grant token.
+func hashToken(token string) string {
+	hashBytes := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hashBytes[:])
+}
+
+// @Summary Create a share link
+// @Description Creates an expiring, unauthenticated access token for a single entry. The plaintext token is returned only once.
+// @Description Every access through the link (including range requests) counts towards max_downloads.
+// @Tags entry
+// @Accept json
+// @Produce json
+// @Param   database_id  path  string                  true  "Database ID"
+// @Param   id           path  int64                   true  "Entry ID"
+// @Param   payload      body  CreateShareLinkRequest  true  "Share link options"
+// @Success 201 {object} ShareLinkCreatedResponse
+// @Failure 400 {object} utils.ErrorResponse "Invalid request"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database or entry not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/{database_id}/entry/{id}/share [post]
+func (h *EntryHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	dbID := r.PathValue("database_id")
+	idStr := r.PathValue("id")
+	user := utils.GetUserFromContext(ctx)
+
+	// 1. Validate Input
+	if dbID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required path parameter: database_id")
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+
+	var payload CreateShareLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if payload.MaxDownloads < 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "max_downloads must not be negative")
+		return
+	}
+
+	expiresAt := time.Now().Add(defaultShareLinkTTL)
+	if payload.ExpiresAt != nil {
+		expiresAt = time.UnixMilli(*payload.ExpiresAt)
+		if !expiresAt.After(time.Now()) {
+			utils.RespondWithError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+	}
+
+	// 2. Make sure the entry exists
+	if _, err := h.Repo.GetEntry(ctx, repo.ULID(dbID), id); err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database or entry not found.")
+		} else {
+			h.Logger.Error("Failed to get entry for share link", "database_id", dbID, "entry", id, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get entry metadata.")
+		}
+		return
+	}
+
+	// 3. Generate the token (32 bytes of randomness, 64 hex characters)
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		h.Logger.Error("Failed to generate secure random bytes", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	link, err := h.Repo.CreateShareLink(ctx, repo.ShareLink{
+		ID:           repo.ULID(shared.GenerateULID()),
+		TokenHash:    hashToken(token),
+		DatabaseID:   repo.ULID(dbID),
+		EntryID:      id,
+		PreviewOnly:  payload.PreviewOnly,
+		MaxDownloads: payload.MaxDownloads,
+		CreatedBy:    user.Username,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		h.Logger.Error("Failed to create share link", "database_id", dbID, "entry", id, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// 4. Audit & Response
+	h.Auditor.Log(ctx, "entry.share_create", user.Username, fmt.Sprintf("%s:%d", dbID, id), map[string]any{
+		"share_id":      link.ID.String(),
+		"preview_only":  link.PreviewOnly,
+		"max_downloads": link.MaxDownloads,
+		"expires_at":    link.ExpiresAt.UnixMilli(),
+	})
+
+	utils.RespondWithJSON(w, http.StatusCreated, ShareLinkCreatedResponse{
+		ShareLinkResponse: mapToShareLinkResponse(link),
+		Token:             token,
+		URL:               utils.AbsoluteURL(r, h.BaseURL, "/share/"+token),
+	})
+}
+
+// @Summary List share links of an entry
+// @Description Lists all share links (including expired ones not yet removed by housekeeping) of an entry.
+// @Tags entry
+// @Produce json
+// @Param   database_id  path  string  true  "Database ID"
+// @Param   id           path  int64   true  "Entry ID"
+// @Success 200 {array} ShareLinkResponse
+// @Failure 400 {object} utils.ErrorResponse "Invalid request"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/{database_id}/entry/{id}/shares [get]
+func (h *EntryHandler) GetShareLinks(w http.ResponseWriter, r *http.Request) {
+	dbID := r.PathValue("database_id")
+	idStr := r.PathValue("id")
+
+	if dbID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required path parameter: database_id")
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+
+	links, err := h.Repo.GetShareLinks(r.Context(), repo.ULID(dbID), id)
+	if err != nil {
+		h.Logger.Error("Failed to get share links", "database_id", dbID, "entry", id, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	resp := make([]ShareLinkResponse, 0, len(links))
+	for _, link := range links {
+		resp = append(resp, mapToShareLinkResponse(link))
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// @Summary Revoke a share link
+// @Description Deletes a share link. The token stops working immediately.
+// @Tags entry
+// @Produce json
+// @Param   database_id  path  string  true  "Database ID"
+// @Param   id           path  int64   true  "Entry ID"
+// @Param   share_id     path  string  true  "Share link ID"
+// @Success 200 {object} utils.MessageResponse "Success message"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Share link not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /database/{database_id}/entry/{id}/share/{share_id} [delete]
+func (h *EntryHandler) DeleteShareLink(w http.ResponseWriter, r *http.Request) {
+	dbID := r.PathValue("database_id")
+	idStr := r.PathValue("id")
+	shareID := r.PathValue("share_id")
+	user := utils.GetUserFromContext(r.Context())
+
+	if dbID == "" || shareID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required path parameter: database_id or share_id")
+		return
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid ID format.")
+		return
+	}
+
+	if err := h.Repo.DeleteShareLink(r.Context(), repo.ULID(dbID), id, repo.ULID(shareID)); err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Share link not found.")
+		} else {
+			h.Logger.Error("Failed to delete share link", "share_id", shareID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.Auditor.Log(r.Context(), "entry.share_delete", user.Username, fmt.Sprintf("%s:%d", dbID, id), map[string]any{
+		"share_id": shareID,
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.MessageResponse{Message: fmt.Sprintf("Share link '%s' was revoked.", shareID)})
+}
+
+// @Summary Access a shared entry
+// @Description Serves the file (or the preview, for preview-only links) of a shared entry without authentication.
+// @Description Supports HTTP Range Requests for file links.
+// @Tags share
+// @Produce octet-stream
+// @Param   token  path    string  true   "Share token"
+// @Param   Range  header  string  false  "Byte range request (e.g., bytes=0-1023)"
+// @Success 200 {file} file "The file or preview data"
+// @Success 206 {file} file "Partial content (streaming response)"
+// @Failure 404 {object} utils.ErrorResponse "Share link not found, expired or exhausted"
+// @Failure 409 {object} utils.ErrorResponse "File is currently processing"
+// @Failure 416 {object} utils.ErrorResponse "Range Not Satisfiable"
+// @Router /share/{token} [get]
+func (h *EntryHandler) GetSharedEntry(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := r.PathValue("token")
+	const notFoundMsg = "Share link not found or expired."
+
+	// 1. Resolve the token by its hash
+	link, err := h.Repo.GetShareLinkByHash(ctx, hashToken(token))
+	if err != nil {
+		if !errors.Is(err, customerrors.ErrNotFound) {
+			h.Logger.Error("Failed to look up share link", "error", err)
+		}
+		utils.RespondWithError(w, http.StatusNotFound, notFoundMsg)
+		return
+	}
+	if !time.Now().Before(link.ExpiresAt) {
+		utils.RespondWithError(w, http.StatusNotFound, notFoundMsg)
+		return
+	}
+
+	dbID := link.DatabaseID.String()
+	resource := fmt.Sprintf("%s:%d", dbID, link.EntryID)
+	actor := "share:" + link.ID.String()
+
+	entry, err := h.Repo.GetEntry(ctx, link.DatabaseID, link.EntryID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, notFoundMsg)
+		return
+	}
+	if entry.Status == repo.EntryStatusProcessing {
+		utils.RespondWithError(w, http.StatusConflict, "File is currently being processed. Try again later.")
+		return
+	}
+
+	// 2. Count the access (atomic, respects max_downloads and expiry)
+	ok, err := h.Repo.ConsumeShareLink(ctx, link.ID)
+	if err != nil {
+		h.Logger.Error("Failed to update share link download count", "share_id", link.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, notFoundMsg)
+		return
+	}
+
+	h.Auditor.Log(ctx, "entry.share_access", actor, resource, map[string]any{
+		"share_id":     link.ID.String(),
+		"preview_only": link.PreviewOnly,
+	})
+
+	// 3. Serve the preview or the file
+	if link.PreviewOnly {
+		preview, err := h.openPreview(ctx, dbID, link.EntryID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusNotFound, "Preview not found")
+			return
+		}
+		defer preview.Close()
+
+		w.Header().Set("Content-Type", preview.MimeType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, preview); err != nil {
+			h.Logger.Error("Failed to stream shared preview to client", "share_id", link.ID, "error", err)
+		}
+		return
+	}
+
+	w.Header().Set(contentVersionHeader, entryContentVersion(entry))
+	h.streamEntryFile(w, r, dbID, entry)
+}
@@ -0,0 +1,173 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/media/sprite"
+	repo "mediahub_oss/internal/repository"
+)
+
+const defaultSpriteSearchLimit = 100
+
+// @Summary Get a sprite sheet of entry previews
+// @Description Composes the previews of up to 200 entries into a single JPEG grid, filled row by row in the given order.
+// @Description Entries are selected by `ids` or by a `search` (same format as the search endpoint, limit defaults to 100).
+// @Description Entries without a preview are rendered as gray placeholder cells. Sprites are cached for a few minutes per list of entries.
+// @Description The response is a JSON envelope with the sprite as base64 data URI, or with `Accept: multipart/mixed` a multipart body with the JSON layout followed by the JPEG.
+// @Tags entry
+// @Accept  json
+// @Produce json
+// @Produce multipart/mixed
+// @Param   database_id  path  string         true  "Database ID"
+// @Param   body         body  SpriteRequest  true  "Entries to include"
+// @Success 200 {object} SpriteResponse "The sprite layout and image"
+// @Failure 400 {object} utils.ErrorResponse "Invalid JSON, no entries, too many entries or a search limit that is not positive"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden (Requires CanView role, or filtering on a sensitive field without CanEdit)"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Router /database/{database_id}/entries/sprite [post]
+func (h *EntryHandler) GetEntriesSprite(w http.ResponseWriter, r *http.Request) {
+	dbID := r.PathValue("database_id")
+	user := utils.GetUserFromContext(r.Context())
+
+	// 1. Validate Input
+	var req SpriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if (len(req.IDs) > 0) == (req.Search != nil) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Provide either 'ids' or 'search'.")
+		return
+	}
+	if len(req.IDs) > sprite.MaxEntries {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("A sprite can contain at most %d entries.", sprite.MaxEntries))
+		return
+	}
+
+	db, err := h.Repo.GetDatabase(r.Context(), repo.ULID(dbID))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		return
+	}
+
+	// 2. Resolve the entries of a search
+	ids := req.IDs
+	if req.Search != nil {
+		if err := checkPagination(req.Search.Pagination); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit := defaultSpriteSearchLimit
+		if req.Search.Pagination.Limit != nil {
+			limit = min(*req.Search.Pagination.Limit, sprite.MaxEntries)
+		}
+		req.Search.Pagination.Limit = &limit
+
+		searchReq, err := withGeoFields(db, searchRequestToModel(*req.Search))
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := h.fieldRedaction(r.Context(), dbID).checkSearch(searchReq); err != nil {
+			utils.RespondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+
+		entries, err := h.Repo.SearchEntries(r.Context(), db.ID, searchReq, db.CustomFields)
+		if err != nil {
+			h.Logger.Error("Search for sprite failed", "database_id", dbID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		ids = make([]int64, len(entries))
+		for i, e := range entries {
+			ids[i] = e.ID
+		}
+	}
+	if len(ids) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "No entries to include in the sprite.")
+		return
+	}
+
+	// 3. Compose
+	openPreview := func(ctx context.Context, id int64) (io.ReadCloser, error) {
+		return h.Storage.ReadPreview(ctx, dbID, id)
+	}
+	s, err := h.Sprites.Generate(r.Context(), dbID, ids, openPreview)
+	if err != nil {
+		h.Logger.Error("Failed to generate sprite", "database_id", dbID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to generate sprite.")
+		return
+	}
+
+	h.Auditor.Log(r.Context(), "entries.sprite", user.Username, dbID, map[string]any{"count": len(ids)})
+
+	// 4. Respond, negotiated by Accept
+	layout := mapToSpriteResponse(s)
+	if strings.Contains(r.Header.Get("Accept"), "multipart/mixed") {
+		h.respondWithSpriteMultipart(w, layout, s.JPEG)
+		return
+	}
+	layout.Sprite = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(s.JPEG)
+	utils.RespondWithJSON(w, http.StatusOK, layout)
+}
+
+// respondWithSpriteMultipart sends the layout as JSON part followed by the JPEG part.
+func (h *EntryHandler) respondWithSpriteMultipart(w http.ResponseWriter, layout SpriteResponse, jpegData []byte) {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusOK)
+
+	err := func() error {
+		jsonHeader := textproto.MIMEHeader{}
+		jsonHeader.Set("Content-Type", "application/json")
+		part, err := mw.CreatePart(jsonHeader)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(part).Encode(layout); err != nil {
+			return err
+		}
+
+		imageHeader := textproto.MIMEHeader{}
+		imageHeader.Set("Content-Type", "image/jpeg")
+		imageHeader.Set("Content-Disposition", `attachment; filename="sprite.jpg"`)
+		part, err = mw.CreatePart(imageHeader)
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(jpegData); err != nil {
+			return err
+		}
+		return mw.Close()
+	}()
+	if err != nil {
+		h.Logger.Error("Failed to write multipart sprite response", "error", err)
+	}
+}
+
+func mapToSpriteResponse(s sprite.Sprite) SpriteResponse {
+	resp := SpriteResponse{
+		Columns:    s.Columns,
+		Rows:       s.Rows,
+		CellWidth:  s.CellWidth,
+		CellHeight: s.CellHeight,
+		Cells:      make(map[int64]SpriteCellResponse, len(s.Cells)),
+	}
+	for id, cell := range s.Cells {
+		resp.Cells[id] = SpriteCellResponse(cell)
+	}
+	return resp
+}
@@ -0,0 +1,120 @@
+package entryhandler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// The methods below serve other transports than the REST handlers, e.g. the gRPC API. They apply the same
+// checks, audit entries and field redaction as the handlers, but return errors wrapping the customerrors
+// sentinels instead of writing responses. The caller checks the permissions on the database.
+
+// Upload describes a file received by another transport than the multipart form of PostEntry.
+type Upload struct {
+	Metadata  PostPatchEntryRequest // Timestamp is math.MinInt64 if the client sent none
+	FileName  string                // name of the uploaded file, used to detect its type
+	MimeType  string                // content type sent by the client, detected if empty
+	Size      int64                 // announced size, uploads of another size are rejected as truncated; 0 if unknown
+	ClientIP  string
+	UserAgent string // truncated like the header of REST uploads
+}
+
+// Ingest reads the file of an upload from body and stores it like PostEntry. Files larger than the sync upload
+// size are spooled to disk and processed asynchronously, the status of the returned entry tells which.
+func (h *EntryHandler) Ingest(ctx context.Context, db repo.Database, upload Upload, body io.Reader) (repo.Entry, error) {
+	meta := upload.Metadata
+	clientTimestamp, err := resolveUploadTimestamp(&meta, h.Timestamps, time.Now())
+	if err != nil {
+		return repo.Entry{}, err
+	}
+	if err := h.Capacity.CheckEntries(db.Stats.EntryCount, 1); err != nil {
+		return repo.Entry{}, err
+	}
+
+	file, size, err := h.spoolFile(body)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to receive the file: %w", err)
+	}
+	header := &multipart.FileHeader{Filename: upload.FileName, Header: textproto.MIMEHeader{}, Size: size}
+	if upload.MimeType != "" {
+		header.Header.Set("Content-Type", upload.MimeType)
+	}
+	form := uploadForm{file: file, header: header}
+	defer form.Close()
+	if err := processing.CheckUploadSize(size, upload.Size); err != nil {
+		return repo.Entry{}, err
+	}
+
+	userAgent := upload.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	entry, _, err := h.ingest(ctx, db, ingestRequest{
+		metadata:        meta,
+		clientTimestamp: clientTimestamp,
+		file:            file,
+		header:          header,
+		origin:          repo.UploadOrigin{UploadedBy: utils.GetUserFromContext(ctx).Username, ClientIP: upload.ClientIP, UserAgent: userAgent},
+	})
+	if err != nil {
+		return repo.Entry{}, err
+	}
+	return redactEntryIn(ctx, db, entry), nil
+}
+
+// Lookup returns the metadata of an entry like GetEntryMeta.
+func (h *EntryHandler) Lookup(ctx context.Context, db repo.Database, id int64) (repo.Entry, error) {
+	entry, err := h.Repo.GetEntry(ctx, db.ID, id)
+	if err != nil {
+		return repo.Entry{}, err
+	}
+
+	h.Auditor.Log(ctx, "entry.read_meta", utils.GetUserFromContext(ctx).Username, fmt.Sprintf("%s:%d", db.ID, id), nil)
+	return redactEntryIn(ctx, db, entry), nil
+}
+
+// Search runs a search like SearchEntries. The limit is clamped to the configured page sizes, filters and
+// sorts on fields the user may not read fail with customerrors.ErrPermissionDenied.
+func (h *EntryHandler) Search(ctx context.Context, db repo.Database, req repo.SearchRequest) ([]repo.Entry, error) {
+	if req.Pagination.Offset < 0 {
+		return nil, fmt.Errorf("%w: invalid offset, must not be negative", customerrors.ErrValidation)
+	}
+	req.Pagination.Limit, _ = h.PageLimits.Apply(req.Pagination.Limit)
+
+	redaction := redactionFor(ctx, db.ID.String(), db.CustomFields)
+	if err := redaction.checkSearch(req); err != nil {
+		return nil, err
+	}
+
+	entries, err := h.Repo.SearchEntries(ctx, db.ID, req, db.CustomFields)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		entries[i] = redaction.entry(entry)
+	}
+
+	h.Auditor.Log(ctx, "entries.search", utils.GetUserFromContext(ctx).Username, db.ID.String(), nil)
+	return entries, nil
+}
+
+// Remove deletes an entry like DeleteEntry. Entries under legal hold fail with customerrors.ErrLegalHold.
+func (h *EntryHandler) Remove(ctx context.Context, dbID repo.ULID, id int64) error {
+	if _, err := shared.DeleteSafe(ctx, h.Repo, h.Storage, dbID, id); err != nil {
+		return err
+	}
+
+	h.Auditor.Log(ctx, "entry.delete", utils.GetUserFromContext(ctx).Username, fmt.Sprintf("%s:%d", dbID, id), nil)
+	h.Logger.Info("Entry deleted", "id", id, "database_id", dbID)
+	return nil
+}
@@ -0,0 +1,466 @@
+package entryhandler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+const (
+	// defaultUploadGrantTTL is used when no expiry is provided on creation.
+	defaultUploadGrantTTL = time.Hour
+	// maxUploadGrantTTL is the longest an upload grant may stay valid.
+	maxUploadGrantTTL = 7 * 24 * time.Hour
+	// uploadGrantFormOverhead is the room for the metadata part and the multipart framing on top of
+	// the max_file_size of a grant.
+	uploadGrantFormOverhead = 1 << 20
+)
+
+func mapToUploadGrantResponse(grant repo.UploadGrant) UploadGrantResponse {
+	resp := UploadGrantResponse{
+		ID:          grant.ID.String(),
+		DatabaseID:  grant.DatabaseID.String(),
+		MaxFileSize: grant.MaxFileSize,
+		CreatedBy:   grant.CreatedBy,
+		CreatedAt:   grant.CreatedAt.UnixMilli(),
+		ExpiresAt:   grant.ExpiresAt.UnixMilli(),
+	}
+	if grant.Metadata != "" {
+		_ = json.Unmarshal([]byte(grant.Metadata), &resp.Metadata)
+	}
+	if !grant.ConsumedAt.IsZero() {
+		consumedAt := grant.ConsumedAt.UnixMilli()
+		resp.ConsumedAt = &consumedAt
+	}
+	return resp
+}
+
+// @Summary Create an upload grant
+// @Description Issues a single-use token that allows one unauthenticated upload into a database via POST /upload/{grant},
+// @Description e.g. for field devices that should not hold credentials. Requires the create right on the database.
+// @Description The values of the metadata template are applied to the upload and cannot be changed by it.
+// @Description The upload is recorded with the creator of the grant as uploading user. The plaintext token is returned only once.
+// @Tags upload
+// @Accept json
+// @Produce json
+// @Param   payload  body  CreateUploadGrantRequest  true  "Upload grant options"
+// @Success 201 {object} UploadGrantCreatedResponse
+// @Failure 400 {object} utils.ErrorResponse "Invalid request or metadata template"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 403 {object} utils.ErrorResponse "Forbidden"
+// @Failure 404 {object} utils.ErrorResponse "Database not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /upload/grants [post]
+func (h *EntryHandler) CreateUploadGrant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+	holder := utils.GetPermissionHolderFromContext(ctx)
+
+	// 1. Validate Input
+	var payload CreateUploadGrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if payload.DatabaseID == "" {
+		utils.RespondWithError(w, http.StatusBadRequest, "Missing required field: database_id")
+		return
+	}
+	if !holder.HasPermission(repo.ULID(payload.DatabaseID), repo.AccessCreate) {
+		utils.RespondWithError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: You lack required rights on database '%s'", payload.DatabaseID))
+		return
+	}
+	if payload.MaxFileSize <= 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "max_file_size must be positive")
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(defaultUploadGrantTTL)
+	if payload.ExpiresAt != nil {
+		expiresAt = time.UnixMilli(*payload.ExpiresAt)
+		if !expiresAt.After(now) {
+			utils.RespondWithError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+		if expiresAt.After(now.Add(maxUploadGrantTTL)) {
+			utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("expires_at must not be more than %s in the future", maxUploadGrantTTL))
+			return
+		}
+	}
+
+	db, err := h.Repo.GetDatabase(ctx, repo.ULID(payload.DatabaseID))
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		} else {
+			h.Logger.Error("Failed to fetch database", "database_id", payload.DatabaseID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch database.")
+		}
+		return
+	}
+
+	template, err := validateUploadGrantTemplate(payload.Metadata, db)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid metadata template: "+err.Error())
+		return
+	}
+
+	// 2. Generate the token (32 bytes of randomness, 64 hex characters)
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		h.Logger.Error("Failed to generate secure random bytes", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	grant, err := h.Repo.CreateUploadGrant(ctx, repo.UploadGrant{
+		ID:          repo.ULID(shared.GenerateULID()),
+		TokenHash:   hashToken(token),
+		DatabaseID:  db.ID,
+		Metadata:    template,
+		MaxFileSize: payload.MaxFileSize,
+		UserID:      user.ID,
+		CreatedBy:   user.Username,
+		CreatedAt:   now,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		h.Logger.Error("Failed to create upload grant", "database_id", db.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// 3. Audit & Response
+	h.Auditor.Log(ctx, "upload_grant.create", user.Username, db.ID.String(), map[string]any{
+		"grant_id":      grant.ID.String(),
+		"max_file_size": grant.MaxFileSize,
+		"expires_at":    grant.ExpiresAt.UnixMilli(),
+		"client_ip":     utils.ClientIP(r, h.TrustedProxies),
+	})
+
+	utils.RespondWithJSON(w, http.StatusCreated, UploadGrantCreatedResponse{
+		UploadGrantResponse: mapToUploadGrantResponse(grant),
+		Token:               token,
+		URL:                 utils.AbsoluteURL(r, h.BaseURL, "/upload/"+token),
+	})
+}
+
+// @Summary List upload grants
+// @Description Lists the upload grants created by the caller (all grants for admins), including used and expired ones not yet removed by housekeeping.
+// @Tags upload
+// @Produce json
+// @Param   database_id  query  string  false  "Only grants of this database"
+// @Success 200 {array} UploadGrantResponse
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /upload/grants [get]
+func (h *EntryHandler) GetUploadGrants(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := utils.GetUserFromContext(ctx)
+
+	filter := repo.UploadGrantFilter{DatabaseID: repo.ULID(r.URL.Query().Get("database_id"))}
+	if !utils.GetPermissionHolderFromContext(ctx).IsGlobalAdmin() {
+		filter.UserID = user.ID
+	}
+
+	grants, err := h.Repo.GetUploadGrants(ctx, filter)
+	if err != nil {
+		h.Logger.Error("Failed to get upload grants", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	resp := make([]UploadGrantResponse, 0, len(grants))
+	for _, grant := range grants {
+		resp = append(resp, mapToUploadGrantResponse(grant))
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, resp)
+}
+
+// @Summary Revoke an upload grant
+// @Description Deletes an upload grant of the caller (any grant for admins). The token stops working immediately.
+// @Tags upload
+// @Produce json
+// @Param   grant_id  path  string  true  "Upload grant ID"
+// @Success 200 {object} utils.MessageResponse "Success message"
+// @Failure 401 {object} utils.ErrorResponse "Unauthorized"
+// @Failure 404 {object} utils.ErrorResponse "Upload grant not found"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Security BasicAuth
+// @Security BearerAuth
+// @Router /upload/grants/{grant_id} [delete]
+func (h *EntryHandler) DeleteUploadGrant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	grantID := r.PathValue("grant_id")
+	user := utils.GetUserFromContext(ctx)
+
+	// Others' grants are not found, unless the caller is an admin
+	var owner repo.ULID
+	if !utils.GetPermissionHolderFromContext(ctx).IsGlobalAdmin() {
+		owner = user.ID
+	}
+
+	if err := h.Repo.DeleteUploadGrant(ctx, repo.ULID(grantID), owner); err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Upload grant not found.")
+		} else {
+			h.Logger.Error("Failed to delete upload grant", "grant_id", grantID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.Auditor.Log(ctx, "upload_grant.delete", user.Username, grantID, nil)
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.MessageResponse{Message: fmt.Sprintf("Upload grant '%s' was revoked.", grantID)})
+}
+
+// @Summary Upload with a grant
+// @Description Uploads a single file without authentication, the grant token is the credential. The 'metadata' part is optional,
+// @Description the metadata template of the grant is applied to it; changing a value of the template returns 400.
+// @Description The grant is consumed by the first upload that passes validation, further uploads return 409.
+// @Tags upload
+// @Accept multipart/form-data
+// @Produce json
+// @Param   grant     path      string  true   "Upload grant token"
+// @Param   metadata  formData  string  false  "JSON metadata (e.g., {\"custom_fields\": {\"device\": \"cam-07\"}})"
+// @Param   file      formData  file    true   "The file to upload"
+// @Success 201 {object} EntryResponse "Entry created (synchronous processing)"
+// @Success 202 {object} PartialEntryResponse "Entry accepted (asynchronous processing)"
+// @Failure 400 {object} utils.ErrorResponse "Invalid request or metadata violating the template"
+// @Failure 403 {object} utils.ErrorResponse "The creator of the grant was disabled or lost the create right"
+// @Failure 404 {object} utils.ErrorResponse "Upload grant not found"
+// @Failure 409 {object} utils.ErrorResponse "Upload grant already used, or the database holds max_entries_per_database entries"
+// @Failure 410 {object} utils.ErrorResponse "Upload grant expired"
+// @Failure 413 {object} utils.ErrorResponse "File larger than the max_file_size of the grant"
+// @Failure 415 {object} utils.ErrorResponse "Unsupported Media Type"
+// @Failure 500 {object} utils.ErrorResponse "Internal server error"
+// @Failure 503 {object} utils.ErrorResponse "Service Unavailable"
+// @Failure 507 {object} utils.ErrorResponse "The upload exceeds the storage quota of the creator of the grant"
+// @Router /upload/{grant} [post]
+func (h *EntryHandler) PostGrantUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	clientIP := utils.ClientIP(r, h.TrustedProxies)
+
+	// 1. Resolve the token by its hash
+	grant, err := h.Repo.GetUploadGrantByHash(ctx, hashToken(r.PathValue("grant")))
+	if err != nil {
+		if !errors.Is(err, customerrors.ErrNotFound) {
+			h.Logger.Error("Failed to look up upload grant", "error", err)
+		}
+		utils.RespondWithError(w, http.StatusNotFound, "Upload grant not found.")
+		return
+	}
+	if !time.Now().Before(grant.ExpiresAt) {
+		utils.RespondWithError(w, http.StatusGone, "The upload grant has expired.")
+		return
+	}
+	if !grant.ConsumedAt.IsZero() {
+		utils.RespondWithError(w, http.StatusConflict, "The upload grant has already been used.")
+		return
+	}
+
+	// 2. The upload acts as the creator of the grant, who must still be enabled and have the create right
+	creator, err := h.Repo.GetUserByID(ctx, grant.UserID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Upload grant not found.")
+		return
+	}
+	if creator.Disabled {
+		utils.RespondWithError(w, http.StatusForbidden, "The creator of the upload grant is disabled.")
+		return
+	}
+	holder := h.grantCreatorPermissions(ctx, creator)
+	if !holder.HasPermission(grant.DatabaseID, repo.AccessCreate) {
+		utils.RespondWithError(w, http.StatusForbidden, "The creator of the upload grant lacks the create right on the database.")
+		return
+	}
+	ctx = context.WithValue(context.WithValue(ctx, utils.UserKey, &creator), utils.PermissionHolderKey, holder)
+	r = r.WithContext(ctx)
+
+	db, err := h.Repo.GetDatabase(ctx, grant.DatabaseID)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Database not found.")
+		} else {
+			h.Logger.Error("Failed to fetch database", "database_id", grant.DatabaseID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to fetch database.")
+		}
+		return
+	}
+
+	if !h.checkCapacity(w, db, 1) {
+		return
+	}
+
+	// 3. Read the upload, larger bodies are cut off before they are buffered
+	r.Body = http.MaxBytesReader(w, r.Body, grant.MaxFileSize+uploadGrantFormOverhead)
+	form, ok := h.readUploadForm(w, r)
+	if !ok {
+		return
+	}
+	defer form.Close()
+	if form.header.Size > grant.MaxFileSize {
+		utils.RespondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("The file exceeds the maximum size of %d bytes of the upload grant.", grant.MaxFileSize))
+		return
+	}
+
+	metadataStr, err := applyUploadGrantTemplate(form.metadata, grant.Metadata)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Error parsing file metadata: "+err.Error())
+		return
+	}
+	entryRequest, clientTimestamp, err := parseUploadMetadata(metadataStr, h.Timestamps, time.Now())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Error parsing file metadata: "+err.Error())
+		return
+	}
+
+	// 4. Consume the grant (atomic, of concurrent uploads only one gets here)
+	consumed, err := h.Repo.ConsumeUploadGrant(ctx, grant.ID)
+	if err != nil {
+		h.Logger.Error("Failed to consume upload grant", "grant_id", grant.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !consumed {
+		utils.RespondWithError(w, http.StatusConflict, "The upload grant has already been used.")
+		return
+	}
+
+	responseObj, status, ok := h.storeUpload(w, r, db, entryRequest, clientTimestamp, form.file, form.header, uploadOptions{}, map[string]any{"grant_id": grant.ID.String()})
+	if !ok {
+		// Nothing was stored, the device may try again
+		if err := h.Repo.ReleaseUploadGrant(context.WithoutCancel(ctx), grant.ID); err != nil {
+			h.Logger.Error("Failed to release upload grant", "grant_id", grant.ID, "error", err)
+		}
+		return
+	}
+
+	// 5. Audit & Response
+	h.Auditor.Log(ctx, "upload_grant.consume", creator.Username, fmt.Sprintf("%s:%d", db.ID, responseObj.GetID()), map[string]any{
+		"grant_id":  grant.ID.String(),
+		"client_ip": clientIP,
+	})
+
+	utils.RespondWithJSON(w, status, responseObj)
+}
+
+// grantCreatorPermissions returns the permissions of the creator of an upload grant, as if they
+// had logged in themselves.
+func (h *EntryHandler) grantCreatorPermissions(ctx context.Context, creator repo.User) utils.PermissionHolder {
+	isAdmin, err := repo.IsEffectiveAdmin(ctx, h.Repo, creator)
+	if err != nil {
+		h.Logger.Warn("Failed to resolve group admin rights of the upload grant creator", "user", creator.Username, "error", err)
+	}
+	if isAdmin {
+		return &utils.GlobalAdmin{UserULID: creator.ID, Repo: h.Repo}
+	}
+	return &utils.UserPermissions{
+		UserULID: creator.ID,
+		Scope:    repo.NewAccessGrant(true, true, true, true, true),
+		Repo:     h.Repo,
+	}
+}
+
+// validateUploadGrantTemplate checks the metadata template of a new grant like the metadata of an
+// upload and returns it as JSON, or an empty string if there is none.
+func validateUploadGrantTemplate(template map[string]any, db repo.Database) (string, error) {
+	if len(template) == 0 {
+		return "", nil
+	}
+
+	raw, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+
+	var metadata PostPatchEntryRequest
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&metadata); err != nil {
+		return "", err
+	}
+	if err := validateCustomFields(metadata.CustomFields, db.CustomFields); err != nil {
+		return "", err
+	}
+	if metadata.ExternalID != nil {
+		if err := validateExternalID(*metadata.ExternalID); err != nil {
+			return "", err
+		}
+	}
+
+	return string(raw), nil
+}
+
+// applyUploadGrantTemplate merges the metadata template of a grant into the 'metadata' part of an
+// upload. The values of the template are fixed: the upload may repeat them, but not change them.
+func applyUploadGrantTemplate(metadataStr string, template string) (string, error) {
+	if template == "" {
+		if metadataStr == "" {
+			return "{}", nil
+		}
+		return metadataStr, nil
+	}
+
+	metadata := map[string]any{}
+	if metadataStr != "" {
+		if err := json.Unmarshal([]byte(metadataStr), &metadata); err != nil {
+			return "", fmt.Errorf("%w: invalid JSON in 'metadata' part", customerrors.ErrValidation)
+		}
+	}
+	var fixed map[string]any
+	if err := json.Unmarshal([]byte(template), &fixed); err != nil {
+		return "", fmt.Errorf("invalid metadata template: %w", err)
+	}
+
+	for key, value := range fixed {
+		if key != "custom_fields" {
+			if current, ok := metadata[key]; ok && !reflect.DeepEqual(current, value) {
+				return "", fmt.Errorf("%w: '%s' is fixed by the upload grant", customerrors.ErrValidation, key)
+			}
+			metadata[key] = value
+			continue
+		}
+
+		fixedFields, _ := value.(map[string]any)
+		fields, ok := metadata["custom_fields"].(map[string]any)
+		if !ok {
+			if metadata["custom_fields"] != nil {
+				return "", fmt.Errorf("%w: 'custom_fields' must be an object", customerrors.ErrValidation)
+			}
+			fields = map[string]any{}
+		}
+		for name, fieldValue := range fixedFields {
+			if current, ok := fields[name]; ok && !reflect.DeepEqual(current, fieldValue) {
+				return "", fmt.Errorf("%w: custom field '%s' is fixed by the upload grant", customerrors.ErrValidation, name)
+			}
+			fields[name] = fieldValue
+		}
+		metadata["custom_fields"] = fields
+	}
+
+	merged, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
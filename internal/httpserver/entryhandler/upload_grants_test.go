@@ -0,0 +1,244 @@
+package entryhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestUploadGrants(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "field_devices",
+		ContentType:  "file",
+		CustomFields: []repo.CustomFieldDef{{Name: "device", Type: "TEXT"}, {Name: "site", Type: "TEXT"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	other, err := r.CreateDatabase(ctx, repo.Database{Name: "other", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	user, err := r.CreateUser(ctx, repo.User{Username: "provisioner", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := r.SetUserPermissions(ctx, repo.UserPermissions{UserID: user.ID, DatabaseID: db.ID, Roles: repo.AccessCreate}); err != nil {
+		t.Fatalf("failed to set permissions: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+	}
+
+	createGrant := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/upload/grants", strings.NewReader(body))
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &user)
+		reqCtx = context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.UserPermissions{UserULID: user.ID, Scope: repo.NewAccessGrant(true, true, true, true, true), Repo: r})
+		rec := httptest.NewRecorder()
+		h.CreateUploadGrant(rec, req.WithContext(reqCtx))
+		return rec
+	}
+	upload := func(token string, metadata string, payload []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if metadata != "" {
+			mw.WriteField("metadata", metadata)
+		}
+		part, _ := mw.CreateFormFile("file", "capture.bin")
+		part.Write(payload)
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/upload/"+token, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.SetPathValue("grant", token)
+		rec := httptest.NewRecorder()
+		h.PostGrantUpload(rec, req)
+		return rec
+	}
+
+	// 1. Grants are checked on creation
+	for name, body := range map[string]string{
+		"missing size":        `{"database_id": "` + db.ID.String() + `"}`,
+		"expiry too far":      `{"database_id": "` + db.ID.String() + `", "max_file_size": 16, "expires_at": ` + strconv.FormatInt(time.Now().Add(8*24*time.Hour).UnixMilli(), 10) + `}`,
+		"unknown field":       `{"database_id": "` + db.ID.String() + `", "max_file_size": 16, "metadata": {"custom_fields": {"nope": "x"}}}`,
+		"wrong field type":    `{"database_id": "` + db.ID.String() + `", "max_file_size": 16, "metadata": {"custom_fields": {"device": 7}}}`,
+		"unknown metadata":    `{"database_id": "` + db.ID.String() + `", "max_file_size": 16, "metadata": {"camera": "x"}}`,
+		"expiry in the past":  `{"database_id": "` + db.ID.String() + `", "max_file_size": 16, "expires_at": 1000}`,
+		"missing database id": `{"max_file_size": 16}`,
+	} {
+		if rec := createGrant(body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := createGrant(`{"database_id": "` + other.ID.String() + `", "max_file_size": 16}`); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 without the create right, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := createGrant(`{"database_id": "` + db.ID.String() + `", "max_file_size": 16, "metadata": {"custom_fields": {"device": "cam-07"}}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created UploadGrantCreatedResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.Token == "" || created.URL != "http://example.com/upload/"+created.Token || created.ConsumedAt != nil || created.CreatedBy != "provisioner" {
+		t.Fatalf("unexpected grant: %+v", created)
+	}
+
+	// 2. Uploads that violate the constraints fail and leave the grant usable
+	if rec := upload(created.Token, `{"custom_fields": {"device": "cam-99"}}`, []byte("payload")); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a changed template value, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := upload(created.Token, `{"custom_fields": {"site": 3}}`, []byte("payload")); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid custom field, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := upload(created.Token, "", bytes.Repeat([]byte("x"), 17)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a file above max_file_size, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := upload(created.Token, "", bytes.Repeat([]byte("x"), 2<<20)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a body above max_file_size, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := upload("unknown", "", []byte("payload")); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown grant, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 3. A valid upload (repeating a template value is fine) creates the entry as the creator
+	rec = upload(created.Token, `{"custom_fields": {"device": "cam-07", "site": "north"}}`, bytes.Repeat([]byte("x"), 16))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var entry EntryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if entry.CustomFields["device"] != "cam-07" || entry.CustomFields["site"] != "north" || entry.UploadedBy == nil || *entry.UploadedBy != "provisioner" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	// 4. The grant is single-use
+	if rec := upload(created.Token, "", []byte("again")); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a reused grant, got %d: %s", rec.Code, rec.Body.String())
+	}
+	grants, err := r.GetUploadGrants(ctx, repo.UploadGrantFilter{UserID: user.ID})
+	if err != nil || len(grants) != 1 || grants[0].ConsumedAt.IsZero() {
+		t.Errorf("expected the consumed grant, got %+v (err %v)", grants, err)
+	}
+
+	// 5. Expired grants are refused and swept
+	expired, err := r.CreateUploadGrant(ctx, repo.UploadGrant{
+		TokenHash:   hashToken("expired-token"),
+		DatabaseID:  db.ID,
+		MaxFileSize: 16,
+		UserID:      user.ID,
+		CreatedBy:   user.Username,
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("failed to create grant: %v", err)
+	}
+	if rec := upload("expired-token", "", []byte("late")); rec.Code != http.StatusGone {
+		t.Errorf("expected 410 for an expired grant, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ok, err := r.ConsumeUploadGrant(ctx, expired.ID); err != nil || ok {
+		t.Errorf("expected an expired grant not to be consumable, got %v (err %v)", ok, err)
+	}
+	if n, err := r.DeleteExpiredRows(ctx, "upload_grants", "expires_at", 500); err != nil || n != 1 {
+		t.Errorf("expected 1 swept grant, got %d (err %v)", n, err)
+	}
+
+	// 6. Revoked grants stop working, only the creator or an admin can revoke them
+	rec = createGrant(`{"database_id": "` + db.ID.String() + `", "max_file_size": 16}`)
+	var revoked UploadGrantCreatedResponse
+	json.Unmarshal(rec.Body.Bytes(), &revoked)
+
+	if err := r.DeleteUploadGrant(ctx, repo.ULID(revoked.ID), repo.ULID("someone-else")); err == nil {
+		t.Errorf("expected another user not to revoke the grant")
+	}
+	del := httptest.NewRequest(http.MethodDelete, "/api/upload/grants/"+revoked.ID, nil)
+	del.SetPathValue("grant_id", revoked.ID)
+	delCtx := context.WithValue(del.Context(), utils.UserKey, &user)
+	delCtx = context.WithValue(delCtx, utils.PermissionHolderKey, &utils.UserPermissions{UserULID: user.ID, Repo: r})
+	delRec := httptest.NewRecorder()
+	h.DeleteUploadGrant(delRec, del.WithContext(delCtx))
+	if delRec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", delRec.Code, delRec.Body.String())
+	}
+	if rec := upload(revoked.Token, "", []byte("payload")); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a revoked grant, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 7. Grants of a creator who was disabled since are refused
+	rec = createGrant(`{"database_id": "` + db.ID.String() + `", "max_file_size": 16}`)
+	var disabled UploadGrantCreatedResponse
+	json.Unmarshal(rec.Body.Bytes(), &disabled)
+	user.Disabled = true
+	if _, err := r.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("failed to disable user: %v", err)
+	}
+	if rec := upload(disabled.Token, "", []byte("payload")); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 after the creator was disabled, got %d: %s", rec.Code, rec.Body.String())
+	}
+	user.Disabled = false
+	if _, err := r.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("failed to enable user: %v", err)
+	}
+
+	// 8. The creator's rights are checked again on upload
+	rec = createGrant(`{"database_id": "` + db.ID.String() + `", "max_file_size": 16}`)
+	var orphaned UploadGrantCreatedResponse
+	json.Unmarshal(rec.Body.Bytes(), &orphaned)
+	if err := r.SetUserPermissions(ctx, repo.UserPermissions{UserID: user.ID, DatabaseID: db.ID}); err != nil {
+		t.Fatalf("failed to remove permissions: %v", err)
+	}
+	if rec := upload(orphaned.Token, "", []byte("payload")); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 after the creator lost the create right, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
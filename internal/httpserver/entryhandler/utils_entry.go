@@ -1,7 +1,13 @@
 package entryhandler
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
 	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
 )
 
 func mapToPartialEntryResponse(db_id string, entry repo.Entry) PartialEntryResponse {
@@ -10,7 +16,10 @@ func mapToPartialEntryResponse(db_id string, entry repo.Entry) PartialEntryRespo
 	return PartialEntryResponse{
 		DatabaseID:   db_id,
 		EntryID:      entry.ID,
+		ExternalID:   entry.ExternalID,
 		Status:       statusStr,
+		ErrorReason:  entry.ErrorReason,
+		ErrorDetail:  entry.ErrorDetail,
 		Timestamp:    entry.Timestamp.UnixMilli(),
 		CreatedAt:    entry.CreatedAt.UnixMilli(),
 		UpdatedAt:    entry.UpdatedAt.UnixMilli(),
@@ -23,28 +32,63 @@ func mapToPartialEntryResponse(db_id string, entry repo.Entry) PartialEntryRespo
 func mapToEntryResponse(db_id string, entry repo.Entry) EntryResponse {
 	statusStr := repo.GetEntryStatusString(entry.Status)
 
-	return EntryResponse{
-		DatabaseID:   db_id,
-		EntryID:      entry.ID,
-		FileName:     entry.FileName,
-		Size:         entry.Size,
-		PreviewSize:  entry.PreviewSize,
-		Status:       statusStr,
-		Timestamp:    entry.Timestamp.UnixMilli(),
-		CreatedAt:    entry.CreatedAt.UnixMilli(),
-		UpdatedAt:    entry.UpdatedAt.UnixMilli(),
-		MimeType:     entry.MimeType,
-		MediaFields:  entry.MediaFields,
-		CustomFields: entry.CustomFields,
+	resp := EntryResponse{
+		DatabaseID:     db_id,
+		EntryID:        entry.ID,
+		ExternalID:     entry.ExternalID,
+		FileName:       entry.FileName,
+		Size:           entry.Size,
+		ContentVersion: entryContentVersion(entry),
+		PreviewSize:    entry.PreviewSize,
+		OriginalSize:   entry.OriginalSize,
+		OriginalMime:   entry.OriginalMimeType,
+		Status:         statusStr,
+		ErrorReason:    entry.ErrorReason,
+		ErrorDetail:    entry.ErrorDetail,
+		Timestamp:      entry.Timestamp.UnixMilli(),
+		CreatedAt:      entry.CreatedAt.UnixMilli(),
+		UpdatedAt:      entry.UpdatedAt.UnixMilli(),
+		MimeType:       entry.MimeType,
+		MediaFields:    entry.MediaFields,
+		CustomFields:   entry.CustomFields,
+		Transcription:  entry.Transcription,
+		LegalHold:      entry.LegalHold,
 	}
+	if !entry.ClientTimestamp.IsZero() {
+		clientTS := entry.ClientTimestamp.UnixMilli()
+		resp.ClientTS = &clientTS
+	}
+	if entry.Origin.UploadedBy != "" {
+		resp.UploadedBy = &entry.Origin.UploadedBy
+	}
+	if entry.Origin.ClientIP != "" || entry.Origin.UserAgent != "" {
+		resp.UploadSource = &UploadSource{IP: entry.Origin.ClientIP, UserAgent: entry.Origin.UserAgent}
+	}
+	return resp
 }
 
-func (p SearchRequestPayload) toModel() repo.SearchRequest {
+// checkPagination rejects negative offsets and limits that are not positive. An omitted limit gets the
+// default page size, a limit of 0 is not read as unlimited.
+func checkPagination(p PaginationPayload) error {
+	if p.Offset < 0 {
+		return errors.New("Invalid offset: must not be negative")
+	}
+	if p.Limit != nil && *p.Limit <= 0 {
+		return errors.New("Invalid limit: must be positive, omit it for the default page size")
+	}
+	return nil
+}
+
+func searchRequestToModel(p SearchRequestPayload) repo.SearchRequest {
 	req := repo.SearchRequest{
 		Pagination: repo.Pagination{
 			Offset: p.Pagination.Offset,
-			Limit:  p.Pagination.Limit,
 		},
+		Fields: p.Fields,
+		Now:    time.Now(), // relative times of all conditions are evaluated at the same instant
+	}
+	if p.Pagination.Limit != nil {
+		req.Pagination.Limit = *p.Pagination.Limit
 	}
 
 	// Map the Filter if it exists
@@ -69,8 +113,95 @@ func (p SearchRequestPayload) toModel() repo.SearchRequest {
 		req.Sort = &repo.SortCriteria{
 			Field:     p.Sort.Field,
 			Direction: p.Sort.Direction,
+			Collation: p.Sort.Collation,
+		}
+	}
+
+	// The geo fields are those of the searched database, see withGeoFields
+	if p.Geo != nil {
+		req.Geo = &repo.GeoFilter{}
+		if b := p.Geo.BBox; b != nil {
+			req.Geo.BBox = &repo.GeoBBox{MinLat: b.MinLat, MinLon: b.MinLon, MaxLat: b.MaxLat, MaxLon: b.MaxLon}
+		}
+		if c := p.Geo.Radius; c != nil {
+			req.Geo.Radius = &repo.GeoRadius{Lat: c.Center.Lat, Lon: c.Center.Lon, Meters: c.Meters}
 		}
 	}
 
 	return req
 }
+
+// withGeoFields returns the search request for a database, with the geo fields of its config in the geo
+// filter. A geo filter on a database without geo fields is an ErrValidation.
+func withGeoFields(db repo.Database, req repo.SearchRequest) (repo.SearchRequest, error) {
+	if req.Geo == nil {
+		return req, nil
+	}
+	if !db.Config.GeoFields.Enabled() {
+		return req, fmt.Errorf("%w: database '%s' declares no geo fields (config.geo_fields) for geo searches", customerrors.ErrValidation, db.Name)
+	}
+	geo := *req.Geo
+	geo.Fields = db.Config.GeoFields
+	req.Geo = &geo
+	return req, nil
+}
+
+// projectEntryResponses reduces entry responses to the id and the requested fields.
+// Media and custom fields keep their nesting; requested fields that are NULL are returned as null.
+func projectEntryResponses(responses []EntryResponse, fields []string, customFields []repo.CustomFieldDef) []map[string]any {
+	results := make([]map[string]any, 0, len(responses))
+	for _, resp := range responses {
+		results = append(results, projectEntryResponse(resp, fields, customFields))
+	}
+	return results
+}
+
+func projectEntryResponse(resp EntryResponse, fields []string, customFields []repo.CustomFieldDef) map[string]any {
+	out := map[string]any{"id": resp.EntryID}
+	if resp.Links != nil {
+		out["_links"] = resp.Links
+	}
+	if resp.CommentCount != nil {
+		out["comment_count"] = *resp.CommentCount
+	}
+
+	for _, field := range fields {
+		switch field {
+		case "id":
+		case "timestamp":
+			out[field] = resp.Timestamp
+		case "created_at":
+			out[field] = resp.CreatedAt
+		case "updated_at":
+			out[field] = resp.UpdatedAt
+		case "filesize":
+			out[field] = resp.Size
+		case "preview_filesize":
+			out[field] = resp.PreviewSize
+		case "filename":
+			out[field] = resp.FileName
+		case "status":
+			out[field] = resp.Status
+		case "mime_type":
+			out[field] = resp.MimeType
+		case "external_id":
+			out[field] = resp.ExternalID
+		case "legal_hold":
+			out[field] = resp.LegalHold
+		case "client_timestamp":
+			out[field] = resp.ClientTS
+		default:
+			key, values := "media_fields", resp.MediaFields
+			if slices.ContainsFunc(customFields, func(cf repo.CustomFieldDef) bool { return cf.Name == field }) {
+				key, values = "custom_fields", resp.CustomFields
+			}
+			nested, ok := out[key].(map[string]any)
+			if !ok {
+				nested = make(map[string]any)
+				out[key] = nested
+			}
+			nested[field] = values[field]
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package entryhandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestEntryFieldProjection(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "projection_images",
+		ContentType:  "image",
+		CustomFields: []repo.CustomFieldDef{{Name: "camera", Type: "TEXT"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	if _, err := r.CreateEntry(ctx, db, repo.Entry{
+		FileName:     "cat.png",
+		Size:         42,
+		Status:       repo.EntryStatusReady,
+		Timestamp:    time.UnixMilli(1700000000000),
+		MimeType:     "image/png",
+		MediaFields:  map[string]any{"width": int64(640), "height": int64(480)},
+		CustomFields: map[string]any{"camera": "x100"},
+	}); err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	// 1. The repository only selects the requested columns
+	entries, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Fields: []string{"timestamp", "camera"}})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one entry, got %d (err %v)", len(entries), err)
+	}
+	if e := entries[0]; e.ID == 0 || e.FileName != "" || e.Size != 0 || len(e.MediaFields) != 0 || e.CustomFields["camera"] != "x100" {
+		t.Errorf("expected only id, timestamp and camera, got %+v", e)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	do := func(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"}))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	keysOf := func(rec *httptest.ResponseRecorder) []map[string]any {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var results []map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil || len(results) != 1 {
+			t.Fatalf("expected one result, got %s (err %v)", rec.Body.String(), err)
+		}
+		return results
+	}
+
+	// 2. Listing with ?fields= returns exactly the requested keys
+	results := keysOf(do(h.QueryEntries, http.MethodGet, "/entries?fields=filename,%20width", ""))
+	if keys := slices.Sorted(maps.Keys(results[0])); !slices.Equal(keys, []string{"filename", "id", "media_fields"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if media := results[0]["media_fields"].(map[string]any); len(media) != 1 || media["width"] != float64(640) {
+		t.Errorf("expected only the width, got %v", media)
+	}
+
+	// 3. Search with a fields array
+	results = keysOf(do(h.SearchEntries, http.MethodPost, "/search", `{"pagination":{"limit":10},"fields":["status","camera"]}`))
+	if keys := slices.Sorted(maps.Keys(results[0])); !slices.Equal(keys, []string{"custom_fields", "id", "status"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if custom := results[0]["custom_fields"].(map[string]any); len(custom) != 1 || custom["camera"] != "x100" {
+		t.Errorf("expected only the camera, got %v", custom)
+	}
+
+	// 4. Without fields, the full entry is returned
+	results = keysOf(do(h.QueryEntries, http.MethodGet, "/entries", ""))
+	if _, ok := results[0]["database_id"]; !ok {
+		t.Errorf("expected the full entry, got %v", results[0])
+	}
+
+	// 5. Unknown fields are rejected
+	if rec := do(h.QueryEntries, http.MethodGet, "/entries?fields=secret", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown field, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(h.SearchEntries, http.MethodPost, "/search", `{"pagination":{"limit":10},"fields":["id; DROP TABLE"]}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown field, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,60 @@
+package entryhandler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	repo "mediahub_oss/internal/repository"
+)
+
+// entryETag computes the weak ETag of an entry's metadata from its mapped response, before the _links block
+// and the comment count are added. Entries have no revision, so any change of a returned field (status,
+// sizes, custom fields, updated_at, ...) changes the ETag. The links only depend on these fields as well.
+func entryETag(resp EntryResponse) string {
+	resp.Links = nil
+	resp.CommentCount = nil
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// entryVersion derives the version of POST /database/{database_id}/entries/versions from the selected columns.
+// Every change of an entry bumps its updated_at, the sizes, mime type and status cover changes within the
+// same millisecond. Unlike the ETag it needs no custom fields, so it is not comparable with it.
+func entryVersion(v repo.EntryVersion) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d|%d|%d|%s|%d", v.UpdatedAt.UnixMilli(), v.Size, v.PreviewSize, v.MimeType, v.Status))
+	return hex.EncodeToString(sum[:8])
+}
+
+// contentVersionHeader carries the content version of every file download.
+const contentVersionHeader = "X-Content-Version"
+
+// entryContentVersion derives the content version of an entry's stored file from its content revision, which
+// every write of the file bumps. The creation time tells apart entries that reuse the ID of a deleted one,
+// e.g. after a database was restored from an export.
+func entryContentVersion(entry repo.Entry) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d|%d", entry.CreatedAt.UnixMilli(), entry.ContentRevision))
+	return hex.EncodeToString(sum[:8])
+}
+
+// etagMatches reports whether an If-None-Match or If-Match header lists the ETag. The comparison is weak,
+// as only weak ETags are issued, and "*" matches any existing entry.
+func etagMatches(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
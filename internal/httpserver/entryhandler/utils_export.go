@@ -0,0 +1,303 @@
+package entryhandler
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"mediahub_oss/internal/exportmanifest"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/parquet"
+	repo "mediahub_oss/internal/repository"
+)
+
+// Formats of the entry export.
+const (
+	exportFormatZip     = "zip"
+	exportFormatParquet = "parquet"
+)
+
+// parquetContentType is the media type of Parquet files registered with IANA.
+const parquetContentType = "application/vnd.apache.parquet"
+
+// parquetColumns derives the Parquet schema of an export from the standard fields (the columns of
+// entries.csv) and the exported custom fields, which are nullable.
+func parquetColumns(exportFields []repo.CustomFieldDef, includeOriginals bool) []parquet.Column {
+	columns := []parquet.Column{
+		{Name: "id", Type: parquet.Int64},
+		{Name: "filename", Type: parquet.String},
+		{Name: "timestamp", Type: parquet.Timestamp},
+		{Name: "filesize", Type: parquet.Int64},
+		{Name: "previewsize", Type: parquet.Int64},
+		{Name: "mime_type", Type: parquet.String},
+		{Name: "status", Type: parquet.Int64},
+		{Name: "external_id", Type: parquet.String, Optional: true},
+		{Name: "uploaded_by", Type: parquet.String, Optional: true},
+		{Name: "upload_ip", Type: parquet.String, Optional: true},
+		{Name: "upload_user_agent", Type: parquet.String, Optional: true},
+		{Name: "created_at", Type: parquet.Timestamp},
+		{Name: "updated_at", Type: parquet.Timestamp},
+	}
+	if includeOriginals {
+		columns = append(columns,
+			parquet.Column{Name: "original_filesize", Type: parquet.Int64, Optional: true},
+			parquet.Column{Name: "original_mime_type", Type: parquet.String, Optional: true},
+		)
+	}
+	standard := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		standard[col.Name] = true
+	}
+	for _, cf := range exportFields {
+		// Column names are unique, a custom field named like a standard field gets the "cf_" prefix
+		col := parquet.Column{Name: cf.Name, Type: parquet.String, Optional: true}
+		if standard[cf.Name] {
+			col.Name = "cf_" + cf.Name
+		}
+		switch cf.Type {
+		case "INTEGER":
+			col.Type = parquet.Int64
+		case "REAL":
+			col.Type = parquet.Double
+		case "BOOLEAN":
+			col.Type = parquet.Boolean
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+// parquetRow returns the values of an entry in the order of parquetColumns. Empty optional
+// standard fields are null, as are custom field values that do not fit the column type.
+func (h *EntryHandler) parquetRow(columns []parquet.Column, entry repo.Entry, exportFields []repo.CustomFieldDef, includeOriginals bool) []any {
+	row := []any{
+		entry.ID,
+		entry.FileName,
+		entry.Timestamp,
+		entry.Size,
+		entry.PreviewSize,
+		entry.MimeType,
+		int64(entry.Status),
+		nullIfEmpty(entry.ExternalID),
+		nullIfEmpty(entry.Origin.UploadedBy),
+		nullIfEmpty(entry.Origin.ClientIP),
+		nullIfEmpty(entry.Origin.UserAgent),
+		entry.CreatedAt,
+		entry.UpdatedAt,
+	}
+	if includeOriginals {
+		if entry.OriginalSize > 0 {
+			row = append(row, entry.OriginalSize, nullIfEmpty(entry.OriginalMimeType))
+		} else {
+			row = append(row, nil, nil)
+		}
+	}
+	for _, cf := range exportFields {
+		col := columns[len(row)]
+		v, err := col.Convert(entry.CustomFields[cf.Name])
+		if err != nil {
+			h.Logger.Warn("Exporting custom field value as null", "id", entry.ID, "field", cf.Name, "error", err)
+			v = nil
+		}
+		row = append(row, v)
+	}
+	return row
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// exportParquet streams the metadata of the requested entries as a single Parquet file. Entries that
+// do not exist are skipped, like in the ZIP export. Rows are written in row groups, so the memory
+// use does not grow with the number of entries.
+func (h *EntryHandler) exportParquet(ctx context.Context, w http.ResponseWriter, db repo.Database, req ExportRequest, exportFields []repo.CustomFieldDef) {
+	start := time.Now()
+	out := h.newExportWriter(w)
+	columns := parquetColumns(exportFields, req.IncludeOriginals)
+	pw, err := parquet.NewWriter(out, columns, parquet.DefaultRowGroupSize)
+	if err != nil {
+		h.Logger.Error("Failed to create Parquet writer", "database_id", db.ID, "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create the Parquet file.")
+		return
+	}
+
+	w.Header().Set("Content-Type", parquetContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s_export.parquet\"", db.Name))
+
+	exported := 0
+	defer func() { h.logExport(ctx, db.ID.String(), exportFormatParquet, start, out, exported) }()
+	for _, id := range req.IDs {
+		entry, err := h.Repo.GetEntry(ctx, db.ID, id)
+		if err != nil {
+			h.Logger.Warn("Skipping entry in export (not found)", "id", id)
+			continue
+		}
+		if err := pw.Write(h.parquetRow(columns, entry, exportFields, req.IncludeOriginals)); err != nil {
+			h.Logger.Error("Failed to stream Parquet to client", "error", err)
+			return
+		}
+		exported++
+	}
+	if err := pw.Close(); err != nil {
+		h.Logger.Error("Failed to stream Parquet to client", "error", err)
+		return
+	}
+	out.finish()
+}
+
+// exportWriter streams an export to the client. Every write extends the write deadline of the connection,
+// so a client that accepts no data within the timeout fails the write: checking the context is not enough,
+// as the kernel send buffer absorbs writes long after the client stopped reading. The first write error is
+// kept and returned by all later writes, so the export is aborted.
+type exportWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	timeout time.Duration
+	written int64
+	err     error
+}
+
+// newExportWriter wraps the response of an export, see exportWriter.
+func (h *EntryHandler) newExportWriter(w http.ResponseWriter) *exportWriter {
+	return &exportWriter{w: w, rc: http.NewResponseController(w), timeout: h.exportWriteTimeout()}
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	if e.timeout > 0 {
+		_ = e.rc.SetWriteDeadline(time.Now().Add(e.timeout)) // not supported by all writers, e.g. in tests
+	}
+	n, err := e.w.Write(p)
+	e.written += int64(n)
+	if err != nil {
+		e.err = err
+	}
+	return n, err
+}
+
+// finish clears the write deadline of a completed export, so it does not apply to the next request on the connection.
+func (e *exportWriter) finish() {
+	if e.timeout > 0 && e.err == nil {
+		_ = e.rc.SetWriteDeadline(time.Time{})
+	}
+}
+
+// exportArchive adds the files of a ZIP export. With checksums, every file is hashed while it is written,
+// in the same pass, and close appends the manifest and checksums listing all of them.
+type exportArchive struct {
+	zip       *zip.Writer
+	checksums bool
+	current   *exportmanifest.Writer // the file being written, listed on the next create or close
+	files     []exportmanifest.File
+}
+
+func newExportArchive(zipWriter *zip.Writer, checksums bool) *exportArchive {
+	return &exportArchive{zip: zipWriter, checksums: checksums}
+}
+
+// create adds a file to the archive, like zip.Writer.Create, which also ends the previous file. The
+// entryID is listed in the manifest, 0 for files not belonging to an entry.
+func (a *exportArchive) create(name string, entryID int64) (io.Writer, error) {
+	a.commit()
+	w, err := a.zip.Create(name)
+	if err != nil || !a.checksums {
+		return w, err
+	}
+	a.current = exportmanifest.NewWriter(w, name, entryID)
+	return a.current, nil
+}
+
+// discard leaves the current file out of the manifest, as it could not be written completely.
+func (a *exportArchive) discard() {
+	a.current = nil
+}
+
+func (a *exportArchive) commit() {
+	if a.current != nil {
+		a.files = append(a.files, a.current.File())
+		a.current = nil
+	}
+}
+
+// close writes the manifest and checksums (with checksums) and finishes the archive.
+func (a *exportArchive) close(manifest exportmanifest.Manifest) error {
+	a.commit()
+	if a.checksums {
+		manifest.Algorithm = exportmanifest.Algorithm
+		manifest.Files = a.files
+		if manifest.Files == nil {
+			manifest.Files = []exportmanifest.File{}
+		}
+		w, err := a.zip.Create(exportmanifest.ChecksumsName)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(manifest.Checksums()); err != nil {
+			return err
+		}
+		if w, err = a.zip.Create(exportmanifest.ManifestName); err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(manifest); err != nil {
+			return err
+		}
+	}
+	return a.zip.Close()
+}
+
+// writeFileToZip adds a stored file to the archive. The source is closed before returning, also on errors,
+// so an export holds at most one open file. An error of open is returned as is, the caller decides whether
+// the entry is skipped; a failed write to the client is reported by the exportWriter.
+func writeFileToZip(archive *exportArchive, name string, entryID int64, open func() (io.ReadCloser, error)) error {
+	source, err := open()
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	zipFile, err := archive.create(name, entryID)
+	if err != nil {
+		return fmt.Errorf("failed to create zip entry: %w", err)
+	}
+	if _, err := io.Copy(zipFile, source); err != nil {
+		archive.discard()
+		return fmt.Errorf("failed to copy file to zip: %w", err)
+	}
+	return nil
+}
+
+// exportAbortReason classifies why an export stopped early, for the log.
+func exportAbortReason(ctx context.Context, writeErr error) string {
+	switch {
+	case errors.Is(writeErr, os.ErrDeadlineExceeded):
+		return "client_stalled"
+	case ctx.Err() != nil:
+		return "client_gone"
+	default:
+		return "write_failed"
+	}
+}
+
+// logExport records the duration and size of an export, and the reason if it was aborted.
+func (h *EntryHandler) logExport(ctx context.Context, dbID string, format string, start time.Time, out *exportWriter, entries int) {
+	if out.err != nil || ctx.Err() != nil {
+		h.Logger.Warn("Export aborted", "database_id", dbID, "format", format, "reason", exportAbortReason(ctx, out.err),
+			"duration", time.Since(start), "bytes", out.written, "entries", entries, "error", out.err)
+		return
+	}
+	h.Logger.Info("Export finished", "database_id", dbID, "format", format, "duration", time.Since(start), "bytes", out.written, "entries", entries)
+}
@@ -0,0 +1,457 @@
+package entryhandler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"mediahub_oss/internal/exportmanifest"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/parquet"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestExportParquet(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:        "analytics",
+		ContentType: "file",
+		CustomFields: []repo.CustomFieldDef{
+			{Name: "count", Type: "INTEGER"},
+			{Name: "score", Type: "REAL"},
+			{Name: "flagged", Type: "BOOLEAN"},
+			{Name: "note", Type: "TEXT"},
+			{Name: "patient", Type: "TEXT", IsSensitive: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	var ids []int64
+	source := map[int64]repo.Entry{}
+	for i := range 5 {
+		fields := map[string]any{"count": int64(i * 10), "flagged": i%2 == 0, "patient": "Jane Doe"}
+		if i != 3 {
+			fields["score"] = float64(i) + 0.5
+			fields["note"] = strings.Repeat("n", i)
+		}
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:     "scan.bin",
+			Status:       repo.EntryStatusReady,
+			Timestamp:    time.UnixMilli(1700000000000 + int64(i)*1000),
+			MimeType:     "application/octet-stream",
+			Size:         uint64(100 + i),
+			ExternalID:   map[bool]string{true: "ext-1"}[i == 1],
+			CustomFields: fields,
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		ids = append(ids, entry.ID)
+		source[entry.ID] = entry
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	export := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/export", strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "analyst"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.APIKeyOfAdmin{Scope: repo.AccessView, Repo: r}))
+		rec := httptest.NewRecorder()
+		h.ExportEntries(rec, req)
+		return rec
+	}
+
+	// 1. The file holds one typed row per existing entry, without the sensitive field
+	body, _ := json.Marshal(ExportRequest{IDs: append(slices.Clone(ids), 999), Format: "parquet"})
+	rec := export(string(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != parquetContentType {
+		t.Errorf("expected content type %q, got %q", parquetContentType, ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, `filename="analytics_export.parquet"`) {
+		t.Errorf("expected a .parquet filename, got %q", cd)
+	}
+
+	file, err := parquet.Read(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("failed to read the export: %v", err)
+	}
+	index := map[string]int{}
+	for i, col := range file.Columns {
+		index[col.Name] = i
+	}
+	wantTypes := map[string]parquet.Type{"id": parquet.Int64, "timestamp": parquet.Timestamp, "filename": parquet.String,
+		"count": parquet.Int64, "score": parquet.Double, "flagged": parquet.Boolean, "note": parquet.String}
+	for name, typ := range wantTypes {
+		if i, ok := index[name]; !ok || file.Columns[i].Type != typ {
+			t.Errorf("expected column %s of type %s, got %+v", name, typ, file.Columns)
+		}
+	}
+	if _, ok := index["patient"]; ok {
+		t.Error("expected the sensitive field not to be exported to a viewer")
+	}
+	if len(file.Rows) != len(ids) {
+		t.Fatalf("expected %d rows, got %d", len(ids), len(file.Rows))
+	}
+
+	for _, row := range file.Rows {
+		want, ok := source[row[index["id"]].(int64)]
+		if !ok {
+			t.Fatalf("unexpected row %v", row)
+		}
+		if !row[index["timestamp"]].(time.Time).Equal(want.Timestamp) || row[index["filesize"]] != int64(want.Size) || row[index["filename"]] != want.FileName {
+			t.Errorf("entry %d: standard fields differ: %v", want.ID, row)
+		}
+		if ext := row[index["external_id"]]; (want.ExternalID == "" && ext != nil) || (want.ExternalID != "" && ext != want.ExternalID) {
+			t.Errorf("entry %d: expected external_id %q, got %v", want.ID, want.ExternalID, ext)
+		}
+		if row[index["count"]] != want.CustomFields["count"] || row[index["flagged"]] != want.CustomFields["flagged"] {
+			t.Errorf("entry %d: expected count %v and flagged %v, got %v and %v", want.ID, want.CustomFields["count"], want.CustomFields["flagged"], row[index["count"]], row[index["flagged"]])
+		}
+		if score, ok := want.CustomFields["score"]; ok {
+			if row[index["score"]] != score || row[index["note"]] != want.CustomFields["note"] {
+				t.Errorf("entry %d: expected score %v and note %v, got %v", want.ID, score, want.CustomFields["note"], row)
+			}
+		} else if row[index["score"]] != nil || row[index["note"]] != nil {
+			t.Errorf("entry %d: expected null score and note, got %v", want.ID, row)
+		}
+	}
+
+	// 2. Unknown formats are rejected
+	if rec := export(`{"ids":[1],"format":"xlsx"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown format, got %d", rec.Code)
+	}
+}
+
+// stalledResponse is a response whose client reads the first bytes of the body from a pipe and then stops.
+// Like a connection, it fails writes blocked past the write deadline set through http.ResponseController.
+type stalledResponse struct {
+	header   http.Header
+	pw       *io.PipeWriter
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func (s *stalledResponse) Header() http.Header { return s.header }
+func (s *stalledResponse) WriteHeader(int)     {}
+
+func (s *stalledResponse) SetWriteDeadline(deadline time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadline = deadline
+	return nil
+}
+
+func (s *stalledResponse) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	deadline := s.deadline
+	s.mu.Unlock()
+
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := s.pw.Write(p)
+		done <- result{n, err}
+	}()
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		expired = time.After(time.Until(deadline))
+	}
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-expired:
+		s.pw.CloseWithError(os.ErrDeadlineExceeded)
+		<-done
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+// closeTracker is a source file that records whether it was closed.
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestExportLimits(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "exports", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	var ids []int64
+	for range 4 {
+		content := bytes.Repeat([]byte{'x'}, 256*1024)
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "big.bin", Size: uint64(len(content)), Status: repo.EntryStatusReady, Timestamp: time.Now(), MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, bytes.NewReader(content)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+		Storage: store,
+		Exports: NewExportLimiter(1, 100*time.Millisecond),
+	}
+	body, _ := json.Marshal(ExportRequest{IDs: ids})
+	export := func(w http.ResponseWriter) {
+		req := httptest.NewRequest(http.MethodPost, "/export", bytes.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "analyst"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+		h.ExportEntries(w, req)
+	}
+
+	// 1. The client reads the first kilobyte and stalls
+	pr, pw := io.Pipe()
+	stalled := &stalledResponse{header: http.Header{}, pw: pw}
+	go func() {
+		_, _ = io.ReadFull(pr, make([]byte, 1024))
+	}()
+	start := time.Now()
+	finished := make(chan struct{})
+	go func() {
+		export(stalled)
+		close(finished)
+	}()
+
+	// 2. Meanwhile the only slot is taken
+	time.Sleep(20 * time.Millisecond)
+	rec := httptest.NewRecorder()
+	export(rec)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// 3. The stalled export is aborted after the write timeout and releases its slot
+	select {
+	case <-finished:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the stalled export was not aborted")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the export to end shortly after the write timeout, took %v", elapsed)
+	}
+	rec = httptest.NewRecorder()
+	export(rec)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 once the slot is free, got %d: %s", rec.Code, rec.Body.String())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil || len(archive.File) != 1+len(ids) {
+		t.Errorf("expected entries.csv and %d files, got %v (err %v)", len(ids), archive, err)
+	}
+
+	// 4. Source files are closed even if copying them fails
+	source := &closeTracker{Reader: iotest.ErrReader(errors.New("disk failure"))}
+	if err := writeFileToZip(newExportArchive(zip.NewWriter(io.Discard), true), "a.bin", 1, func() (io.ReadCloser, error) { return source, nil }); err == nil {
+		t.Error("expected the copy to fail")
+	}
+	if !source.closed {
+		t.Error("expected the source file to be closed")
+	}
+}
+
+func TestExportChecksums(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "archive", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	var ids []int64
+	for i := range 3 {
+		content := bytes.Repeat([]byte(fmt.Sprintf("content of file %d\n", i)), 1000)
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "doc.txt", Size: uint64(len(content)), PreviewSize: uint64(i % 2), Status: repo.EntryStatusReady, Timestamp: time.Now(), MimeType: "text/plain"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, bytes.NewReader(content)); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if entry.PreviewSize > 0 {
+			if _, err := store.WritePreview(ctx, db.ID.String(), entry.ID, strings.NewReader("preview")); err != nil {
+				t.Fatalf("failed to write preview: %v", err)
+			}
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+		Storage: store,
+	}
+	export := func(includeChecksums bool) []byte {
+		body, _ := json.Marshal(ExportRequest{IDs: ids, IncludeChecksums: includeChecksums})
+		req := httptest.NewRequest(http.MethodPost, "/export", bytes.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "auditor"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, &utils.GlobalAdmin{}))
+		rec := httptest.NewRecorder()
+		h.ExportEntries(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		return rec.Body.Bytes()
+	}
+	open := func(data []byte) *zip.Reader {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("failed to open the ZIP: %v", err)
+		}
+		return zr
+	}
+
+	// 1. The manifest lists entries.csv, the files and the preview, the checksums file has the same hashes
+	data := export(true)
+	report, err := exportmanifest.Verify(open(data))
+	if err != nil {
+		t.Fatalf("failed to verify the export: %v", err)
+	}
+	if !report.OK() {
+		t.Fatalf("expected the export to be intact, got %+v", report.Files)
+	}
+	m := report.Manifest
+	if len(m.Files) != 5 || m.Files[0].Path != "entries.csv" || m.DatabaseID != db.ID.String() || m.ExportedBy != "auditor" || !m.Request.IncludeChecksums {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+	for _, f := range open(data).File {
+		if f.Name != exportmanifest.ChecksumsName {
+			continue
+		}
+		rc, _ := f.Open()
+		sums, _ := io.ReadAll(rc)
+		rc.Close()
+		first := fmt.Sprintf("%s  %s", m.Files[0].SHA256, m.Files[0].Path)
+		if lines := strings.Split(strings.TrimSpace(string(sums)), "\n"); len(lines) != 5 || lines[0] != first {
+			t.Errorf("expected sha256sum lines, got %q", sums)
+		}
+	}
+
+	// 2. A byte flipped inside the second file is pinpointed
+	var damaged string
+	corrupted := slices.Clone(data)
+	for _, f := range open(data).File {
+		if strings.HasPrefix(f.Name, fmt.Sprintf("files/%d_", ids[1])) {
+			offset, err := f.DataOffset()
+			if err != nil {
+				t.Fatalf("failed to locate %s: %v", f.Name, err)
+			}
+			damaged = f.Name
+			corrupted[offset+int64(f.CompressedSize64)/2] ^= 0xff
+		}
+	}
+	path := t.TempDir() + "/export.zip"
+	if err := os.WriteFile(path, corrupted, 0o600); err != nil {
+		t.Fatalf("failed to write the archive: %v", err)
+	}
+	report, err = exportmanifest.VerifyFile(path)
+	if err != nil {
+		t.Fatalf("failed to verify the corrupted export: %v", err)
+	}
+	var failed []exportmanifest.FileResult
+	for _, file := range report.Files {
+		if file.Status != exportmanifest.StatusOK {
+			failed = append(failed, file)
+		}
+	}
+	if len(failed) != 1 || failed[0].Path != damaged || failed[0].EntryID != ids[1] {
+		t.Errorf("expected only %s to be reported, got %+v", damaged, failed)
+	}
+
+	// 3. Without include_checksums there is nothing to verify against
+	if _, err := exportmanifest.Verify(open(export(false))); err == nil {
+		t.Error("expected an export without checksums to fail the verification")
+	}
+}
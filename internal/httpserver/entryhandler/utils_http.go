@@ -2,11 +2,17 @@ package entryhandler
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // parseRange parses a standard HTTP Range header (e.g. "bytes=1000-2000")
@@ -78,32 +84,70 @@ func parseRange(header string, fileSize int64) ([]byteRange, error) {
 	return ranges, nil
 }
 
-// encodeReaderAsJSON reads data from an io.Reader stream, encodes it as Base64,
-// and returns it as a JSON object.
+// writeReaderAsJSON streams data from an io.Reader to w as a FileJSONResponse object,
+// base64 encoding it on the fly. Memory usage stays flat regardless of the file size.
 // This is used to support clients that cannot handle binary streams with auth headers.
-func encodeReaderAsJSON(reader io.Reader, filename, mimeType string) (FileJSONResponse, error) {
-	// 1. Read the stream into memory
-	// io.ReadAll reads from the reader until an error or EOF and returns the data it read.
-	data, err := io.ReadAll(reader)
+func writeReaderAsJSON(w io.Writer, reader io.Reader, filename, mimeType string) error {
+	filenameJSON, err := json.Marshal(filename)
 	if err != nil {
-		return FileJSONResponse{}, err
+		return err
 	}
+	mimeTypeJSON, err := json.Marshal(mimeType)
+	if err != nil {
+		return err
+	}
+	// Format strictly follows the Data URI scheme: data:[<mediatype>][;base64],<data>
+	// The closing quote is dropped so the base64 payload can be appended to the string.
+	dataPrefixJSON, err := json.Marshal(fmt.Sprintf("data:%s;base64,", mimeType))
+	if err != nil {
+		return err
+	}
+	dataPrefixJSON = dataPrefixJSON[:len(dataPrefixJSON)-1]
 
-	// 2. Encode to Base64
-	b64Str := base64.StdEncoding.EncodeToString(data)
+	// 1. Open the object: {"filename":...,"mime_type":...,"data":"data:<mime>;base64,
+	if _, err := fmt.Fprintf(w, `{"filename":%s,"mime_type":%s,"data":%s`, filenameJSON, mimeTypeJSON, dataPrefixJSON); err != nil {
+		return err
+	}
 
-	// 3. Construct the response object
-	resp := FileJSONResponse{
-		Filename: filename,
-		MimeType: mimeType,
-		// Format strictly follows the Data URI scheme: data:[<mediatype>][;base64],<data>
-		Data: fmt.Sprintf("data:%s;base64,%s", mimeType, b64Str),
+	// 2. Pipe the file through the encoder (the base64 alphabet needs no JSON escaping)
+	encoder := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(encoder, reader); err != nil {
+		return err
 	}
+	// Close flushes any partially written block and padding
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	// 3. Close the string and the object
+	_, err = io.WriteString(w, `"}`)
+	return err
+}
 
-	return resp, nil
+// respondWithReaderAsJSON sends a 200 response containing the reader's data as a streamed FileJSONResponse.
+// Errors after the headers were sent can only be logged, the client sees a truncated body.
+func (h *EntryHandler) respondWithReaderAsJSON(w http.ResponseWriter, reader io.Reader, filename, mimeType string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := writeReaderAsJSON(w, reader, filename, mimeType); err != nil {
+		h.Logger.Error("Failed to stream JSON file response", "filename", filename, "error", err)
+	}
 }
 
 // parseQueryInt safely parses an integer from query parameters, falling back to a default value.
+// pageLimitClampedHeader carries the applied limit if the requested one exceeded the maximum page size.
+const pageLimitClampedHeader = "X-Page-Limit-Clamped"
+
+// pageLimit applies the configured page sizes to a requested limit. A clamped limit is reported in a
+// header instead of failing the request, so clients notice that they need to page.
+func (h *EntryHandler) pageLimit(w http.ResponseWriter, limit int) int {
+	applied, clamped := h.PageLimits.Apply(limit)
+	if clamped {
+		w.Header().Set(pageLimitClampedHeader, strconv.Itoa(applied))
+	}
+	return applied
+}
+
 func parseQueryInt(r *http.Request, key string, defaultValue int) int {
 	if val := r.URL.Query().Get(key); val != "" {
 		if parsed, err := strconv.Atoi(val); err == nil {
@@ -113,12 +157,187 @@ func parseQueryInt(r *http.Request, key string, defaultValue int) int {
 	return defaultValue
 }
 
-// parseQueryInt64 safely parses a 64-bit integer from query parameters, falling back to a default value.
-func parseQueryInt64(r *http.Request, key string, defaultValue int64) int64 {
-	if val := r.URL.Query().Get(key); val != "" {
-		if parsed, err := strconv.ParseInt(val, 10, 64); err == nil {
-			return parsed
+// parseQueryList parses a comma-separated list from query parameters, skipping empty items.
+func parseQueryList(r *http.Request, key string) []string {
+	var items []string
+	for item := range strings.SplitSeq(r.URL.Query().Get(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
 	}
-	return defaultValue
+	return items
+}
+
+// parseQueryTime parses a time from query parameters, given in Unix milliseconds or as relative time
+// like "now-24h" evaluated at now (see repo.ParseRelativeTime). It returns the zero time if the parameter is missing.
+func parseQueryTime(r *http.Request, key string, now time.Time) (time.Time, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	t, err := repo.ParseRelativeTime(val, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return t, nil
+}
+
+// streamEntryFile writes the raw file of an entry to the response, honouring a single-range
+// Range header (206) or sending the full file (200). It returns false if an error response
+// was written instead of the file.
+func (h *EntryHandler) streamEntryFile(w http.ResponseWriter, r *http.Request, dbID string, filemeta repo.Entry) bool {
+	// Determine Range (Streaming vs Full)
+	rangeHeader := r.Header.Get("Range")
+	fileSize := int64(filemeta.Size)
+
+	var offset int64 = 0
+	var length int64 = -1 // Read to end
+	isPartial := false
+
+	if rangeHeader != "" {
+		// Simple parser for "bytes=start-end"
+		ranges, err := parseRange(rangeHeader, fileSize)
+		if err != nil {
+			// 416 Range Not Satisfiable
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
+			utils.RespondWithError(w, http.StatusRequestedRangeNotSatisfiable, "Invalid Range Header")
+			return false
+		}
+
+		// We only support the first range requested (multipart ranges are rare for this use case)
+		if len(ranges) > 0 {
+			isPartial = true
+			offset = ranges[0].start
+			length = ranges[0].length
+		}
+	}
+
+	// Open Stream (Partial or Full)
+	fileStream, err := h.Storage.Read(r.Context(), dbID, filemeta.ID, offset, length)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "File content not found.")
+		return false
+	}
+	defer fileStream.Close()
+
+	// Set Response Headers
+	w.Header().Set("Content-Type", filemeta.MimeType)
+	w.Header().Set("Accept-Ranges", "bytes") // Advertise support
+
+	if isPartial {
+		// 206 Partial Content
+		end := offset + length - 1
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, end, fileSize))
+		w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+
+		// Spec: "inline" allows playback
+		if filemeta.FileName != "" {
+			w.Header().Set("Content-Disposition", contentDisposition("inline", filemeta.FileName))
+		}
+		w.WriteHeader(http.StatusPartialContent)
+
+	} else {
+		// 200 OK (Full Download)
+		w.Header().Set("Content-Length", strconv.FormatInt(fileSize, 10))
+
+		if filemeta.FileName != "" {
+			w.Header().Set("Content-Disposition", contentDisposition("attachment", filemeta.FileName))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	// Stream Data
+	if _, err := io.Copy(w, fileStream); err != nil {
+		// Stream interrupted, headers are already sent
+		h.Logger.Debug("File stream interrupted", "entry", filemeta.ID, "error", err)
+	}
+	return true
+}
+
+// contentDisposition returns the Content-Disposition header of a download named after an entry. The name
+// is sanitized and quoted, names with non-ASCII characters get an ASCII fallback and the full name in
+// filename* (RFC 6266). Without a name only the disposition is returned.
+func contentDisposition(disposition, name string) string {
+	name = sanitizeFileName(name)
+	if name == "" {
+		return disposition
+	}
+
+	var fallback, encoded strings.Builder
+	ascii := true
+	for _, r := range name {
+		switch {
+		case r > unicode.MaxASCII:
+			fallback.WriteByte('_')
+			ascii = false
+		case r == '"':
+			fallback.WriteString(`\"`)
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	header := fmt.Sprintf(`%s; filename="%s"`, disposition, fallback.String())
+	if ascii {
+		return header
+	}
+	for _, b := range []byte(name) {
+		if 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || strings.IndexByte("!#$&+-.^_`|~", b) >= 0 {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return header + "; filename*=UTF-8''" + encoded.String()
+}
+
+// Values of the variant query parameter of the file endpoint
+const (
+	variantConverted = "converted"
+	variantOriginal  = "original"
+)
+
+// originalFileName is the entry's file name with the extension of its kept original.
+func originalFileName(entry repo.Entry) string {
+	return processing.ReplaceExtension(entry.FileName, processing.GetExtensionForMimeType(entry.OriginalMimeType))
+}
+
+// streamOriginalFile sends the kept original of a converted entry as full download. Returns false if it failed.
+func (h *EntryHandler) streamOriginalFile(w http.ResponseWriter, r *http.Request, dbID string, filemeta repo.Entry) bool {
+	originalStream, err := h.Storage.ReadOriginal(r.Context(), dbID, filemeta.ID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Original file content not found.")
+		return false
+	}
+	defer originalStream.Close()
+
+	w.Header().Set("Content-Type", filemeta.OriginalMimeType)
+	w.Header().Set("Content-Length", strconv.FormatUint(filemeta.OriginalSize, 10))
+	if name := originalFileName(filemeta); name != "" {
+		w.Header().Set("Content-Disposition", contentDisposition("attachment", name))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, originalStream); err != nil {
+		h.Logger.Debug("Original file stream interrupted", "entry", filemeta.ID, "error", err)
+	}
+	return true
+}
+
+// maxUserAgentLength caps the stored user agent, the header is client controlled.
+const maxUserAgentLength = 512
+
+// uploadOrigin records who sent an upload request and from where.
+func (h *EntryHandler) uploadOrigin(r *http.Request) repo.UploadOrigin {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return repo.UploadOrigin{
+		UploadedBy: utils.GetUserFromContext(r.Context()).Username,
+		ClientIP:   utils.ClientIP(r, h.TrustedProxies),
+		UserAgent:  userAgent,
+	}
 }
@@ -0,0 +1,116 @@
+package entryhandler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// countingWriter is an io spy that discards data and only records how much was written.
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+func TestWriteReaderAsJSONRoundTrip(t *testing.T) {
+	// Odd length to exercise base64 padding
+	data := make([]byte, 100_001)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("failed to generate test data: %v", err)
+	}
+
+	var buf bytes.Buffer
+	filename := `my "quoted" file.wav`
+	if err := writeReaderAsJSON(&buf, bytes.NewReader(data), filename, "audio/wav"); err != nil {
+		t.Fatalf("writeReaderAsJSON failed: %v", err)
+	}
+
+	var resp FileJSONResponse
+	if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+		t.Fatalf("response is not valid JSON: %v", err)
+	}
+	if resp.Filename != filename || resp.MimeType != "audio/wav" {
+		t.Errorf("unexpected metadata: filename=%q mime_type=%q", resp.Filename, resp.MimeType)
+	}
+
+	prefix := "data:audio/wav;base64,"
+	if !strings.HasPrefix(resp.Data, prefix) {
+		t.Fatalf("data URI has unexpected prefix: %.40s", resp.Data)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(resp.Data, prefix))
+	if err != nil {
+		t.Fatalf("failed to decode data URI: %v", err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Errorf("decoded data does not match the original (got %d bytes, want %d)", len(decoded), len(data))
+	}
+}
+
+func TestWriteReaderAsJSONBoundedMemory(t *testing.T) {
+	const fileSize = 32 << 20 // 32 MB
+
+	path := filepath.Join(t.TempDir(), "large.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	chunk := make([]byte, 1<<20)
+	for i := 0; i < fileSize/len(chunk); i++ {
+		if _, err := f.Write(chunk); err != nil {
+			t.Fatalf("failed to write temp file: %v", err)
+		}
+	}
+	f.Close()
+
+	var spy countingWriter
+	var before, after runtime.MemStats
+
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open temp file: %v", err)
+	}
+	err = writeReaderAsJSON(&spy, in, "large.bin", "application/octet-stream")
+	in.Close()
+	if err != nil {
+		t.Fatalf("writeReaderAsJSON failed: %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+
+	if want := int64(base64.StdEncoding.EncodedLen(fileSize)); spy.n < want {
+		t.Errorf("expected at least %d bytes of output, got %d", want, spy.n)
+	}
+
+	// Buffering the file (or its encoding) would allocate more than 32 MB
+	allocated := after.TotalAlloc - before.TotalAlloc
+	if allocated > 1<<20 {
+		t.Errorf("expected allocations to stay below 1 MB, got %d bytes", allocated)
+	}
+}
+
+func TestWriteReaderAsJSONPropagatesReadErrors(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("abc"), &failingReader{})
+	if err := writeReaderAsJSON(io.Discard, r, "f", "text/plain"); err == nil {
+		t.Errorf("expected read error to be returned")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, os.ErrClosed
+}
@@ -0,0 +1,183 @@
+package entryhandler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	defaultIdempotencyTTL    = 24 * time.Hour
+	idempotencyWaitTimeout   = time.Minute            // how long a retry waits for the original request to finish
+	idempotencyPollInterval  = 200 * time.Millisecond // how often a waiting retry checks the original request
+	idempotencyLease         = 30 * time.Second       // a key in progress that is not renewed, e.g. after a crash, is reclaimed
+)
+
+// idempotentUpload is an upload that reserved its Idempotency-Key. A nil *idempotentUpload
+// stands for an upload without a key, all methods are no-ops then.
+type idempotentUpload struct {
+	h          *EntryHandler
+	key        repo.IdempotencyKey
+	ttl        time.Duration
+	committed  bool // the entry exists, the key must not be released anymore
+	stopLease  context.CancelFunc
+	leaseEnded chan struct{}
+}
+
+// beginIdempotentUpload reserves the Idempotency-Key of the request, if any. If the key was used before,
+// it replays the original response (waiting for the original request if it is still running) and returns false.
+// Concurrent requests with the same key are serialized by the key's primary key, only one of them reserves it.
+func (h *EntryHandler) beginIdempotentUpload(w http.ResponseWriter, r *http.Request, db repo.Database, user *repo.User) (*idempotentUpload, bool) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return nil, true
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		utils.RespondWithError(w, http.StatusBadRequest, "Idempotency-Key must not be longer than 255 characters.")
+		return nil, false
+	}
+
+	ttl := h.IdempotencyKeyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+
+	deadline := time.Now().Add(idempotencyWaitTimeout)
+	for {
+		stored, reserved, err := h.Repo.ReserveIdempotencyKey(r.Context(), repo.IdempotencyKey{
+			DatabaseID: db.ID,
+			UserID:     user.ID,
+			Key:        key,
+			ExpiresAt:  time.Now().Add(idempotencyLease),
+		})
+		if err != nil {
+			h.Logger.Error("Failed to reserve idempotency key", "database_id", db.ID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to check the Idempotency-Key.")
+			return nil, false
+		}
+		if reserved {
+			upload := &idempotentUpload{h: h, key: stored, ttl: ttl}
+			upload.renewLease(r.Context())
+			return upload, true
+		}
+		if stored.StatusCode != 0 {
+			h.replayUpload(w, r, db, stored)
+			return nil, false
+		}
+
+		// The original request is still running. If it fails, it releases the key and this one takes over,
+		// if it crashed, its lease expires.
+		if time.Now().After(deadline) {
+			utils.RespondWithError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress.")
+			return nil, false
+		}
+		select {
+		case <-r.Context().Done():
+			return nil, false
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+}
+
+// renewLease extends the lease of the key until the upload completes or releases it.
+func (u *idempotentUpload) renewLease(ctx context.Context) {
+	ctx, u.stopLease = context.WithCancel(context.WithoutCancel(ctx))
+	u.leaseEnded = make(chan struct{})
+	go func() {
+		defer close(u.leaseEnded)
+		ticker := time.NewTicker(idempotencyLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			key := u.key
+			key.ExpiresAt = time.Now().Add(idempotencyLease)
+			if err := u.h.Repo.RenewIdempotencyKey(ctx, key); err != nil && ctx.Err() == nil {
+				u.h.Logger.Error("Failed to renew idempotency key", "database_id", key.DatabaseID, "error", err)
+			}
+		}
+	}()
+}
+
+// endLease stops renewing the lease, so it cannot overwrite the outcome of the upload.
+func (u *idempotentUpload) endLease() {
+	u.stopLease()
+	<-u.leaseEnded
+}
+
+// complete stores the outcome of the upload, so retries with the same key can replay it. The entry exists at
+// this point, so the key is never released afterwards: if the outcome cannot be stored, the key is kept in
+// progress until it expires, retries are rejected instead of creating a duplicate, and the error is returned.
+func (u *idempotentUpload) complete(ctx context.Context, entryID int64, statusCode int) error {
+	if u == nil {
+		return nil
+	}
+	u.committed = true
+	u.endLease()
+
+	ctx = context.WithoutCancel(ctx)
+	u.key.EntryID = entryID
+	u.key.StatusCode = statusCode
+	u.key.ExpiresAt = time.Now().Add(u.ttl)
+	err := u.h.Repo.CompleteIdempotencyKey(ctx, u.key)
+	if err == nil {
+		return nil
+	}
+	u.h.Logger.Error("Failed to store idempotency key result", "database_id", u.key.DatabaseID, "entry", entryID, "error", err)
+	if renewErr := u.h.Repo.RenewIdempotencyKey(ctx, u.key); renewErr != nil {
+		u.h.Logger.Error("Failed to keep idempotency key", "database_id", u.key.DatabaseID, "entry", entryID, "error", renewErr)
+	}
+	return err
+}
+
+// release frees the key of an upload that did not create an entry, so the client can retry it.
+func (u *idempotentUpload) release(ctx context.Context) {
+	if u == nil || u.committed {
+		return
+	}
+	u.endLease()
+	// The client may have gone away, the key must be released anyway
+	if err := u.h.Repo.DeleteIdempotencyKey(context.WithoutCancel(ctx), u.key.DatabaseID, u.key.UserID, u.key.Key); err != nil {
+		u.h.Logger.Error("Failed to release idempotency key", "database_id", u.key.DatabaseID, "error", err)
+	}
+}
+
+// replayUpload answers a retried upload with the entry created by the original request.
+// An asynchronous upload that has finished processing in the meantime returns the full entry with 200.
+func (h *EntryHandler) replayUpload(w http.ResponseWriter, r *http.Request, db repo.Database, stored repo.IdempotencyKey) {
+	entry, err := h.Repo.GetEntry(r.Context(), db.ID, stored.EntryID)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "The entry created by the original request no longer exists.")
+		} else {
+			h.Logger.Error("Failed to get entry for idempotent replay", "entry", stored.EntryID, "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get entry metadata.")
+		}
+		return
+	}
+
+	dbID := db.ID.String()
+	entry = redactEntryIn(r.Context(), db, entry)
+	w.Header().Set(idempotentReplayedHeader, "true")
+
+	stillProcessing := entry.Status == repo.EntryStatusProcessing || entry.Status == repo.EntryStatusQueued
+	switch {
+	case stored.StatusCode == http.StatusAccepted && stillProcessing:
+		utils.RespondWithJSON(w, http.StatusAccepted, mapToPartialEntryResponse(dbID, entry))
+	case stored.StatusCode == http.StatusAccepted:
+		utils.RespondWithJSON(w, http.StatusOK, mapToEntryResponse(dbID, entry))
+	default:
+		utils.RespondWithJSON(w, stored.StatusCode, mapToEntryResponse(dbID, entry))
+	}
+}
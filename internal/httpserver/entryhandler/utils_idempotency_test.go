@@ -0,0 +1,202 @@
+package entryhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"sync"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// plainFileConverter handles generic files, which need neither conversion nor previews.
+type plainFileConverter struct {
+	media.MediaConverter
+}
+
+func (plainFileConverter) CanCreatePreview(string) bool { return false }
+
+func (plainFileConverter) CanConvert(string, string) media.ConversionCheck {
+	return media.ConversionCheck{}
+}
+
+func (plainFileConverter) ReadMediaFieldsFromStream(context.Context, io.ReadSeeker, string) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+
+func TestPostEntryIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "idempotency_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	user, err := r.CreateUser(ctx, repo.User{Username: "uploader", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+
+	h := &EntryHandler{
+		Logger:         logger,
+		Auditor:        audit.NewAlNoopLogger(),
+		Repo:           r,
+		Storage:        store,
+		Limits:         NewUploadLimits(1<<20, 0),
+		MediaConverter: plainFileConverter{},
+		Processor:      proc,
+	}
+
+	post := func(key string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("metadata", `{"timestamp": 1700000000000}`)
+		partHeader := textproto.MIMEHeader{}
+		partHeader.Set("Content-Disposition", `form-data; name="file"; filename="data.bin"`)
+		partHeader.Set("Content-Type", "application/octet-stream")
+		part, _ := mw.CreatePart(partHeader)
+		part.Write([]byte("payload"))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/entry", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		req.SetPathValue("database_id", db.ID.String())
+		req = req.WithContext(context.WithValue(req.Context(), utils.UserKey, &user))
+		rec := httptest.NewRecorder()
+		h.PostEntry(rec, req)
+		return rec
+	}
+	entryID := func(rec *httptest.ResponseRecorder) int64 {
+		t.Helper()
+		var resp struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+		}
+		return resp.ID
+	}
+	countEntries := func() int {
+		entries, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 100})
+		if err != nil {
+			t.Fatalf("failed to get entries: %v", err)
+		}
+		return len(entries)
+	}
+
+	// 1. A retry replays the original response
+	first := post("upload-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", first.Code, first.Body.String())
+	}
+	retry := post("upload-1")
+	if retry.Code != http.StatusCreated || retry.Header().Get(idempotentReplayedHeader) != "true" {
+		t.Fatalf("expected a replayed 201, got %d (replayed %q): %s", retry.Code, retry.Header().Get(idempotentReplayedHeader), retry.Body.String())
+	}
+	if entryID(first) != entryID(retry) {
+		t.Errorf("expected the replay to return entry %d, got %d", entryID(first), entryID(retry))
+	}
+	if n := countEntries(); n != 1 {
+		t.Errorf("expected 1 entry after the retry, got %d", n)
+	}
+
+	// 2. Concurrent requests with the same key create a single entry
+	var wg sync.WaitGroup
+	codes := make([]int, 5)
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = post("upload-2").Code
+		}()
+	}
+	wg.Wait()
+	for _, code := range codes {
+		if code != http.StatusCreated {
+			t.Errorf("expected every concurrent request to get 201, got %v", codes)
+			break
+		}
+	}
+	if n := countEntries(); n != 2 {
+		t.Errorf("expected 2 entries after the concurrent requests, got %d", n)
+	}
+
+	// 3. Uploads without a key are never deduplicated
+	post("")
+	post("")
+	if n := countEntries(); n != 4 {
+		t.Errorf("expected 4 entries, got %d", n)
+	}
+
+	// 4. The key of a crashed request is reclaimed once its lease expired
+	if _, reserved, err := r.ReserveIdempotencyKey(ctx, repo.IdempotencyKey{DatabaseID: db.ID, UserID: user.ID, Key: "upload-3", ExpiresAt: time.Now().Add(-time.Second)}); err != nil || !reserved {
+		t.Fatalf("failed to reserve key: %v", err)
+	}
+	if rec := post("upload-3"); rec.Code != http.StatusCreated || rec.Header().Get(idempotentReplayedHeader) != "" {
+		t.Errorf("expected the orphaned key to be reclaimed with 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 5. A result that cannot be stored keeps the key, retries must not create another entry
+	h.Repo = failingCompleteRepo{r}
+	rec := post("upload-4")
+	h.Repo = r
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 if the result cannot be stored, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if stored, err := r.GetIdempotencyKey(ctx, db.ID, user.ID, "upload-4"); err != nil {
+		t.Errorf("expected the key to be kept: %v", err)
+	} else if stored.StatusCode != 0 || time.Until(stored.ExpiresAt) < time.Hour {
+		t.Errorf("expected the key to stay in progress until it expires, got status %d until %v", stored.StatusCode, stored.ExpiresAt)
+	}
+	if n := countEntries(); n != 6 {
+		t.Errorf("expected 6 entries, got %d", n)
+	}
+}
+
+// failingCompleteRepo fails to store the result of an idempotent upload.
+type failingCompleteRepo struct {
+	repo.Repository
+}
+
+func (failingCompleteRepo) CompleteIdempotencyKey(context.Context, repo.IdempotencyKey) error {
+	return errors.New("disk I/O error")
+}
@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strconv"
 	"time"
 
+	"mediahub_oss/internal/processing"
 	repo "mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared/customerrors"
 )
@@ -100,6 +102,38 @@ func (h *EntryHandler) processImportJob(ctx context.Context, db repo.Database, u
 // Helper Functions
 // -----------------------------------------------------------------------------
 
+// countImportRows returns the number of entries an import archive holds, the rows of its entries.csv.
+func countImportRows(zipPath string) (int, error) {
+	zr, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	i := slices.IndexFunc(zr.File, func(f *zip.File) bool { return f.Name == "entries.csv" })
+	if i < 0 {
+		return 0, errors.New("entries.csv not found in archive")
+	}
+	csvFile, err := zr.File[i].Open()
+	if err != nil {
+		return 0, err
+	}
+	defer csvFile.Close()
+
+	csvReader := csv.NewReader(csvFile)
+	csvReader.FieldsPerRecord = -1
+	rows := -1 // the header
+	for {
+		if _, err := csvReader.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+		rows++
+	}
+	return max(rows, 0), nil
+}
+
 // indexZipContents maps the ZIP contents for O(1) lookups and locates the entries.csv file.
 func (h *EntryHandler) indexZipContents(zr *zip.ReadCloser) (map[string]*zip.File, *zip.File, error) {
 	zipFiles := make(map[string]*zip.File)
@@ -119,6 +153,9 @@ func (h *EntryHandler) indexZipContents(zr *zip.ReadCloser) (map[string]*zip.Fil
 	return zipFiles, csvZipFile, nil
 }
 
+// optionalStandardHeaders may follow the standard headers of an export, they are not custom fields.
+var optionalStandardHeaders = []string{"external_id", "uploaded_by", "upload_ip", "upload_user_agent", "created_at", "updated_at", "original_filesize", "original_mime_type"}
+
 // validateCSVHeaders ensures the standard headers exist in the correct order.
 func (h *EntryHandler) validateCSVHeaders(headers []string) error {
 	expectedHeaders := []string{"id", "filename", "timestamp", "filesize", "previewsize", "mime_type", "status"}
@@ -142,6 +179,19 @@ func (h *EntryHandler) processImportRow(ctx context.Context, db repo.Database, r
 		return false, fmt.Errorf("invalid standard field format: %w", err)
 	}
 	originalCSVId := entry.ID
+	if i := slices.Index(headers, "external_id"); i >= 0 && i < len(row) {
+		entry.ExternalID = row[i]
+	}
+	// The origin of exported entries is kept, it is not the importing request
+	for header, value := range map[string]*string{
+		"uploaded_by":       &entry.Origin.UploadedBy,
+		"upload_ip":         &entry.Origin.ClientIP,
+		"upload_user_agent": &entry.Origin.UserAgent,
+	} {
+		if i := slices.Index(headers, header); i >= 0 && i < len(row) {
+			*value = row[i]
+		}
+	}
 
 	// 2. Determine Target ID & Mode Logic
 	if config.Mode == "skip" {
@@ -165,6 +215,9 @@ func (h *EntryHandler) processImportRow(ctx context.Context, db repo.Database, r
 	// 4. Locate Files in ZIP
 	mainZipPath := fmt.Sprintf("files/%d_%s", originalCSVId, entry.FileName)
 	previewZipPath := fmt.Sprintf("previews/%d.webp", originalCSVId)
+	if _, ok := zipFiles[previewZipPath]; !ok {
+		previewZipPath = fmt.Sprintf("previews/%d.png", originalCSVId) // transparent waveform
+	}
 
 	mainFileZipped, ok := zipFiles[mainZipPath]
 	if !ok {
@@ -182,13 +235,20 @@ func (h *EntryHandler) processImportRow(ctx context.Context, db repo.Database, r
 
 	// Spool the zipped content into the temp file
 	srcZipStream, _ := mainFileZipped.Open()
-	if _, err := io.Copy(tempMediaFile, srcZipStream); err != nil {
+	extracted, err := io.Copy(tempMediaFile, srcZipStream)
+	if err != nil {
 		srcZipStream.Close()
 		tempMediaFile.Close()
 		return false, fmt.Errorf("failed to extract file from zip to disk: %w", err)
 	}
 	srcZipStream.Close()
 
+	// Empty or truncated files are rejected like uploads, before an entry is created
+	if err := processing.CheckUploadSize(extracted, int64(mainFileZipped.UncompressedSize64)); err != nil {
+		tempMediaFile.Close()
+		return false, fmt.Errorf("%s: %w", mainZipPath, err)
+	}
+
 	// Sync to disk to ensure ffprobe can read it properly
 	tempMediaFile.Sync()
 
@@ -211,9 +271,14 @@ func (h *EntryHandler) processImportRow(ctx context.Context, db repo.Database, r
 	// 7. Write Main File to Storage
 	// Rewind the temp file so we can stream it to the final storage location
 	tempMediaFile.Seek(0, io.SeekStart)
-	_, err = h.Storage.Write(ctx, db.ID.String(), savedEntry.ID, tempMediaFile)
+	written, err := h.Storage.Write(ctx, db.ID.String(), savedEntry.ID, tempMediaFile)
 	tempMediaFile.Close() // Close the handle now that storage has consumed it
 
+	if err == nil {
+		if err = processing.CheckStoredSize(written, extracted, false); err != nil {
+			h.Storage.Delete(ctx, db.ID.String(), savedEntry.ID)
+		}
+	}
 	if err != nil {
 		h.Repo.DeleteEntry(ctx, db.ID, savedEntry.ID) // Rollback DB on storage failure
 		return false, fmt.Errorf("failed to write main file to storage: %w", err)
@@ -241,7 +306,7 @@ func (h *EntryHandler) parseStandardFields(row []string) (repo.Entry, error) {
 		return entry, fmt.Errorf("invalid ID: %s", row[0])
 	}
 
-	entry.FileName = row[1]
+	entry.FileName = sanitizeFileName(row[1]) // as in the paths of the export
 
 	if entry.Timestamp, err = time.Parse(time.RFC3339, row[2]); err != nil {
 		return entry, fmt.Errorf("invalid timestamp: %s", row[2])
@@ -274,6 +339,9 @@ func (h *EntryHandler) mapCustomFields(row []string, headers []string, dbFields
 		}
 
 		csvHeader := headers[i]
+		if slices.Contains(optionalStandardHeaders, csvHeader) {
+			continue
+		}
 		dbField := csvHeader
 
 		// Apply user-defined mapping if provided
@@ -0,0 +1,50 @@
+package entryhandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	repo "mediahub_oss/internal/repository"
+)
+
+// entryPath mirrors the entry routes registered in router.go ("/api/database/{database_id}/entry/{id}").
+const entryPath = "/api/database/%s/entry/%d"
+
+// wantsLinks reports whether the client asked for the _links block via ?include_links=true.
+func wantsLinks(r *http.Request) bool {
+	include, _ := strconv.ParseBool(r.URL.Query().Get("include_links"))
+	return include
+}
+
+// buildEntryLinks generates the URLs of an entry's endpoints, prefixed with the external base URL.
+// All handlers returning entries use this helper, so the links stay in sync with the router.
+func buildEntryLinks(baseURL string, dbID string, entry repo.Entry) *EntryLinks {
+	base := baseURL + fmt.Sprintf(entryPath, dbID, entry.ID)
+
+	links := &EntryLinks{
+		Meta:   base,
+		File:   base + "/file",
+		Shares: base + "/shares",
+	}
+	if entry.PreviewSize > 0 {
+		links.Preview = base + "/preview"
+	}
+	return links
+}
+
+// mapToEntryResponses maps a list of entries and adds their links if requested.
+// Custom fields the authenticated user may not read are removed.
+func (h *EntryHandler) mapToEntryResponses(ctx context.Context, dbID string, entries []repo.Entry, includeLinks bool) []EntryResponse {
+	redaction := h.fieldRedaction(ctx, dbID)
+	results := make([]EntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		resp := mapToEntryResponse(dbID, redaction.entry(entry))
+		if includeLinks {
+			resp.Links = buildEntryLinks(h.BaseURL, dbID, entry)
+		}
+		results = append(results, resp)
+	}
+	return results
+}
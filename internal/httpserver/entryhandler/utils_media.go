@@ -1,6 +1,8 @@
 package entryhandler
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -19,6 +21,8 @@ func getExtensionForMimeType(mimeType string) string {
 		"image/gif":  "gif",
 		"image/webp": "webp",
 		"image/avif": "avif",
+		"image/heic": "heic",
+		"image/heif": "heif",
 
 		// Audio
 		"audio/mpeg":      "mp3", // Note: audio/mpeg is usually an .mp3 file
@@ -88,11 +92,11 @@ func (h *EntryHandler) generateAndStorePreview(ctx context.Context, db repo.Data
 	pr, pw := io.Pipe()
 	errChan := make(chan error, 1)
 
-	// Run the preview generation in a background goroutine
+	// Run the preview generation in a background goroutine, audio is drawn in the waveform style of the database
+	styleCtx := media.WithWaveformStyle(ctx, media.WaveformStyle(db.Config.Waveform))
 	go func() {
 		defer pw.Close() // Signal EOF to the storage reader when generation completes
-		// NOTE: Updated interface method call to CreatePreviewFromStream
-		err := h.MediaConverter.CreatePreviewFromStream(ctx, inputSeeker, pw, mimeType)
+		err := h.MediaConverter.CreatePreviewFromStream(styleCtx, inputSeeker, pw, mimeType)
 		errChan <- err
 	}()
 
@@ -109,3 +113,29 @@ func (h *EntryHandler) generateAndStorePreview(ctx context.Context, db repo.Data
 
 	return uint64(previewSize), nil
 }
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// storedPreview is a preview read from the storage, with the format detected from its first bytes.
+// Previews are WebP, transparent waveforms are PNG; changing the style of a database leaves the
+// existing previews in their format until they are regenerated.
+type storedPreview struct {
+	*bufio.Reader
+	io.Closer
+	MimeType  string
+	Extension string
+}
+
+// openPreview opens the preview of an entry and detects its format.
+func (h *EntryHandler) openPreview(ctx context.Context, dbID string, id int64) (*storedPreview, error) {
+	rc, err := h.Storage.ReadPreview(ctx, dbID, id)
+	if err != nil {
+		return nil, err
+	}
+	preview := &storedPreview{Reader: bufio.NewReader(rc), Closer: rc, MimeType: "image/webp", Extension: "webp"}
+	if magic, _ := preview.Peek(len(pngSignature)); bytes.Equal(magic, pngSignature) {
+		preview.MimeType, preview.Extension = "image/png", "png"
+	}
+	return preview, nil
+}
@@ -0,0 +1,136 @@
+package entryhandler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// fieldRedaction hides the sensitive custom fields of a database from the authenticated user.
+// Every handler that returns entry metadata passes it through redactEntry (or mapToEntryResponses),
+// so viewers never see these values, independent of the endpoint.
+type fieldRedaction struct {
+	hideAll bool
+	hidden  map[string]bool
+}
+
+// canSeeSensitiveFields reports whether the authenticated user may read sensitive custom fields.
+// This requires the edit or admin role on the database (or being a global admin).
+func canSeeSensitiveFields(ctx context.Context, dbID string) bool {
+	holder, ok := ctx.Value(utils.PermissionHolderKey).(utils.PermissionHolder)
+	if !ok {
+		return false
+	}
+	return holder.IsGlobalAdmin() || holder.HasPermission(repo.ULID(dbID), repo.AccessEdit|repo.AccessAdmin)
+}
+
+// fieldRedaction determines which custom fields of a database are hidden from the authenticated user.
+// If the field definitions cannot be loaded, all custom fields are hidden.
+func (h *EntryHandler) fieldRedaction(ctx context.Context, dbID string) fieldRedaction {
+	if canSeeSensitiveFields(ctx, dbID) {
+		return fieldRedaction{}
+	}
+
+	fields, err := h.Repo.GetCustomFields(ctx, repo.ULID(dbID))
+	if err != nil {
+		h.Logger.Error("Failed to load custom fields for redaction", "database_id", dbID, "error", err)
+		return fieldRedaction{hideAll: true}
+	}
+	return redactionFor(ctx, dbID, fields)
+}
+
+// redactionFor is fieldRedaction for a caller that already holds the field definitions of the database.
+func redactionFor(ctx context.Context, dbID string, fields []repo.CustomFieldDef) fieldRedaction {
+	if canSeeSensitiveFields(ctx, dbID) {
+		return fieldRedaction{}
+	}
+
+	redaction := fieldRedaction{}
+	for _, cf := range fields {
+		if cf.IsSensitive {
+			if redaction.hidden == nil {
+				redaction.hidden = make(map[string]bool)
+			}
+			redaction.hidden[cf.Name] = true
+		}
+	}
+	return redaction
+}
+
+func (f fieldRedaction) active() bool {
+	return f.hideAll || len(f.hidden) > 0
+}
+
+// entry returns a copy of the entry without the hidden custom fields.
+func (f fieldRedaction) entry(entry repo.Entry) repo.Entry {
+	if !f.active() || entry.CustomFields == nil {
+		return entry
+	}
+	if f.hideAll {
+		entry.CustomFields = map[string]any{}
+		return entry
+	}
+	visible := maps.Clone(entry.CustomFields)
+	for name := range f.hidden {
+		delete(visible, name)
+	}
+	entry.CustomFields = visible
+	return entry
+}
+
+// fields returns the custom field definitions the user may read.
+func (f fieldRedaction) fields(defs []repo.CustomFieldDef) []repo.CustomFieldDef {
+	if !f.active() {
+		return defs
+	}
+	visible := make([]repo.CustomFieldDef, 0, len(defs))
+	for _, cf := range defs {
+		if !f.hideAll && !f.hidden[cf.Name] {
+			visible = append(visible, cf)
+		}
+	}
+	return visible
+}
+
+// checkAccess rejects filters, sorts and projections that reference a hidden field,
+// as they would reveal its values.
+func (f fieldRedaction) checkAccess(names ...string) error {
+	for _, name := range names {
+		if f.hidden[name] {
+			return fmt.Errorf("%w: the field '%s' is restricted to editors", customerrors.ErrPermissionDenied, name)
+		}
+	}
+	return nil
+}
+
+// checkSearch applies checkAccess to all fields a search request references.
+func (f fieldRedaction) checkSearch(req repo.SearchRequest) error {
+	names := append([]string{}, req.Fields...)
+	if req.Filter != nil {
+		for _, c := range req.Filter.Conditions {
+			names = append(names, c.Field)
+		}
+	}
+	if req.Sort != nil {
+		names = append(names, req.Sort.Field)
+	}
+	if req.Geo != nil {
+		names = append(names, req.Geo.Fields.Lat, req.Geo.Fields.Lon)
+	}
+	return f.checkAccess(names...)
+}
+
+// redactEntry removes the custom fields the authenticated user may not read from an entry.
+func (h *EntryHandler) redactEntry(ctx context.Context, dbID string, entry repo.Entry) repo.Entry {
+	return h.fieldRedaction(ctx, dbID).entry(entry)
+}
+
+// redactEntryIn is redactEntry for a database that was already loaded, e.g. by the upload,
+// so the custom field definitions are not looked up again.
+func redactEntryIn(ctx context.Context, db repo.Database, entry repo.Entry) repo.Entry {
+	return redactionFor(ctx, db.ID.String(), db.CustomFields).entry(entry)
+}
@@ -0,0 +1,160 @@
+package entryhandler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestSensitiveFieldRedaction(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:        "sensitive",
+		ContentType: "file",
+		CustomFields: []repo.CustomFieldDef{
+			{ID: 0, Name: "note", Type: "TEXT"},
+			{ID: 1, Name: "patient", Type: "TEXT", IsSensitive: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{
+		FileName:     "scan.bin",
+		Status:       repo.EntryStatusReady,
+		Timestamp:    time.Now(),
+		MimeType:     "application/octet-stream",
+		CustomFields: map[string]any{"note": "routine", "patient": "Jane Doe"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to store file: %v", err)
+	}
+
+	h := &EntryHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+		Storage: store,
+	}
+	viewer := &utils.APIKeyOfAdmin{Scope: repo.AccessView, Repo: r}
+	editor := &utils.APIKeyOfAdmin{Scope: repo.AccessView | repo.AccessEdit, Repo: r}
+
+	do := func(holder utils.PermissionHolder, handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetPathValue("database_id", db.ID.String())
+		req.SetPathValue("id", strconv.FormatInt(entry.ID, 10))
+		reqCtx := context.WithValue(req.Context(), utils.UserKey, &repo.User{Username: "tester"})
+		req = req.WithContext(context.WithValue(reqCtx, utils.PermissionHolderKey, holder))
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		name   string
+		holder utils.PermissionHolder
+		sees   bool
+	}{
+		{"viewer", viewer, false},
+		{"editor", editor, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// 1. Meta, listing and search
+			var meta EntryResponse
+			rec := do(tc.holder, h.GetEntryMeta, http.MethodGet, "/entry", "")
+			if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &meta) != nil {
+				t.Fatalf("expected the entry, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var listed, found []EntryResponse
+			if rec := do(tc.holder, h.QueryEntries, http.MethodGet, "/entries", ""); rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &listed) != nil || len(listed) != 1 {
+				t.Fatalf("expected one listed entry, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if rec := do(tc.holder, h.SearchEntries, http.MethodPost, "/search", `{"pagination":{"limit":10}}`); rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &found) != nil || len(found) != 1 {
+				t.Fatalf("expected one found entry, got %d: %s", rec.Code, rec.Body.String())
+			}
+			for name, fields := range map[string]map[string]any{"meta": meta.CustomFields, "list": listed[0].CustomFields, "search": found[0].CustomFields} {
+				if _, ok := fields["patient"]; ok != tc.sees {
+					t.Errorf("%s: expected the sensitive field to be returned: %v, got %v", name, tc.sees, fields)
+				}
+				if fields["note"] != "routine" {
+					t.Errorf("%s: expected the regular field, got %v", name, fields)
+				}
+			}
+
+			// 2. Filtering, sorting and selecting the sensitive field
+			wantCode := http.StatusForbidden
+			if tc.sees {
+				wantCode = http.StatusOK
+			}
+			filter := `{"filter":{"operator":"and","conditions":[{"field":"patient","operator":"=","value":"Jane Doe"}]},"pagination":{"limit":10}}`
+			if rec := do(tc.holder, h.SearchEntries, http.MethodPost, "/search", filter); rec.Code != wantCode {
+				t.Errorf("expected %d for a filter on the sensitive field, got %d: %s", wantCode, rec.Code, rec.Body.String())
+			}
+			if rec := do(tc.holder, h.QueryEntries, http.MethodGet, "/entries?fields=patient", ""); rec.Code != wantCode {
+				t.Errorf("expected %d when selecting the sensitive field, got %d: %s", wantCode, rec.Code, rec.Body.String())
+			}
+
+			// 3. The CSV of an export
+			rec = do(tc.holder, h.ExportEntries, http.MethodPost, "/export", `{"ids":[`+strconv.FormatInt(entry.ID, 10)+`]}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected the export, got %d: %s", rec.Code, rec.Body.String())
+			}
+			zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+			if err != nil {
+				t.Fatalf("failed to open export: %v", err)
+			}
+			csvFile, err := zr.Open("entries.csv")
+			if err != nil {
+				t.Fatalf("export has no CSV: %v", err)
+			}
+			rows, err := csv.NewReader(csvFile).ReadAll()
+			if err != nil || len(rows) != 2 {
+				t.Fatalf("expected header and one row, got %v (err %v)", rows, err)
+			}
+			csvText := strings.Join(rows[0], ",") + "\n" + strings.Join(rows[1], ",")
+			if strings.Contains(csvText, "patient") != tc.sees || strings.Contains(csvText, "Jane Doe") != tc.sees {
+				t.Errorf("expected the sensitive column to be exported: %v, got\n%s", tc.sees, csvText)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package entryhandler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Limits of the multipart form of uploads.
+const (
+	maxMetadataPartSize = 64 << 10 // bytes of the JSON 'metadata' part
+	maxSkippedParts     = 16       // unknown parts skipped before the form is rejected
+)
+
+// uploadForm is the multipart form of an upload.
+type uploadForm struct {
+	file     multipart.File        // the 'file' part, an *os.File if it was spooled to disk
+	header   *multipart.FileHeader // file name, part headers and size of the 'file' part
+	metadata string                // the 'metadata' part, empty if it is missing
+}
+
+// Close closes the file and removes it from disk unless the processor took it over.
+func (f uploadForm) Close() {
+	f.file.Close()
+	if spooled, ok := f.file.(*os.File); ok {
+		os.Remove(spooled.Name())
+	}
+}
+
+// memoryFile is a 'file' part kept in memory.
+type memoryFile struct {
+	*io.SectionReader
+}
+
+func (memoryFile) Close() error { return nil }
+
+// readUploadForm streams the multipart form of an upload part by part: the 'metadata' part is limited
+// to maxMetadataPartSize, the non-empty 'file' part is required and may come before or after it. Files
+// larger than the sync upload size are spooled to disk. Unknown parts are skipped and reported in a
+// Warning header. Writes the error response and returns false if the form is invalid.
+func (h *EntryHandler) readUploadForm(w http.ResponseWriter, r *http.Request) (uploadForm, bool) {
+	reader, err := r.MultipartReader()
+	if errors.Is(err, http.ErrMissingBoundary) {
+		utils.RespondWithError(w, http.StatusBadRequest, "The multipart/form-data Content-Type lacks the boundary parameter.")
+		return uploadForm{}, false
+	} else if err != nil {
+		utils.RespondWithError(w, http.StatusUnsupportedMediaType, "Uploads must be sent as multipart/form-data with a 'metadata' and a 'file' part.")
+		return uploadForm{}, false
+	}
+
+	var form uploadForm
+	var hasMetadata bool
+	var skipped int
+	fail := func(status int, message string) (uploadForm, bool) {
+		if form.file != nil {
+			form.Close()
+		}
+		utils.RespondWithError(w, status, message)
+		return uploadForm{}, false
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fail(h.formErrorResponse(err))
+		}
+
+		switch name := part.FormName(); name {
+		case "metadata":
+			// Clients that attach the metadata as a file are accepted as well
+			if hasMetadata {
+				return fail(http.StatusBadRequest, "The multipart form has more than one 'metadata' part.")
+			}
+			data, err := io.ReadAll(io.LimitReader(part, maxMetadataPartSize+1))
+			if err != nil {
+				return fail(h.formErrorResponse(err))
+			}
+			if len(data) > maxMetadataPartSize {
+				return fail(http.StatusRequestEntityTooLarge, fmt.Sprintf("The 'metadata' part exceeds the maximum of %d bytes.", maxMetadataPartSize))
+			}
+			form.metadata, hasMetadata = string(data), true
+		case "file":
+			if form.file != nil {
+				return fail(http.StatusBadRequest, "The multipart form has more than one 'file' part.")
+			}
+			file, size, err := h.spoolFile(part)
+			if err != nil {
+				return fail(h.formErrorResponse(err))
+			}
+			form.file = file
+			form.header = &multipart.FileHeader{Filename: part.FileName(), Header: part.Header, Size: size}
+		default:
+			if skipped++; skipped > maxSkippedParts {
+				return fail(http.StatusBadRequest, fmt.Sprintf("The multipart form has more than %d unknown parts.", maxSkippedParts))
+			}
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				return fail(h.formErrorResponse(err))
+			}
+			w.Header().Add("Warning", fmt.Sprintf("199 - %s", strconv.Quote(fmt.Sprintf("skipped unknown form part '%s'", name))))
+		}
+	}
+
+	if form.file == nil {
+		return fail(http.StatusBadRequest, "Missing 'file' part in multipart form.")
+	}
+	if form.header.Size == 0 {
+		return fail(http.StatusBadRequest, "The 'file' part is empty.")
+	}
+	return form, true
+}
+
+// spoolFile reads the file of an upload, e.g. the 'file' part of the form, into memory up to the sync
+// upload size and into a temporary file beyond it.
+func (h *EntryHandler) spoolFile(part io.Reader) (multipart.File, int64, error) {
+	maxMemory := h.maxSyncUploadSize()
+	if maxMemory <= 0 {
+		maxMemory = 8 << 20
+	}
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, part, maxMemory+1)
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	if n <= maxMemory {
+		return memoryFile{io.NewSectionReader(bytes.NewReader(buf.Bytes()), 0, n)}, n, nil
+	}
+
+	spooled, err := os.CreateTemp("", "mh-upload-*")
+	if err != nil {
+		return nil, 0, err
+	}
+	size, err := io.Copy(spooled, io.MultiReader(&buf, part))
+	if err == nil {
+		_, err = spooled.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spooled.Close()
+		os.Remove(spooled.Name())
+		return nil, 0, err
+	}
+	return spooled, size, nil
+}
+
+// formErrorResponse returns the status and message of an error reading the multipart form.
+func (h *EntryHandler) formErrorResponse(err error) (int, string) {
+	h.Logger.Warn("Failed to read multipart form", "error", err)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge, "The upload exceeds the maximum file size."
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return http.StatusBadRequest, "The upload is truncated: the request body ended inside the multipart form."
+	}
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return http.StatusInternalServerError, "Failed to spool the upload to disk."
+	}
+	return http.StatusBadRequest, fmt.Sprintf("Failed to parse multipart form: %v", err)
+}
+
+// Response modes of uploads, selected with ?response=.
+const (
+	responseModeFull    = "full"
+	responseModeMinimal = "minimal"
+)
+
+// uploadOptions are the query parameters of an upload.
+type uploadOptions struct {
+	minimal     bool // ?response=minimal, the compact response of synchronous uploads
+	syncPreview bool // ?sync_preview=true, the preview of a small image exists when the response is sent
+}
+
+// parseUploadOptions parses the query parameters of an upload.
+func parseUploadOptions(r *http.Request) (uploadOptions, error) {
+	var opts uploadOptions
+	switch mode := r.URL.Query().Get("response"); mode {
+	case "", responseModeFull:
+	case responseModeMinimal:
+		opts.minimal = true
+	default:
+		return uploadOptions{}, fmt.Errorf("invalid 'response' parameter %q, expected 'full' or 'minimal'", mode)
+	}
+
+	if val := r.URL.Query().Get("sync_preview"); val != "" {
+		syncPreview, err := strconv.ParseBool(val)
+		if err != nil {
+			return uploadOptions{}, fmt.Errorf("invalid 'sync_preview' parameter %q, expected 'true' or 'false'", val)
+		}
+		opts.syncPreview = syncPreview
+	}
+	return opts, nil
+}
+
+// prepareUpload applies the metadata defaults and overrides of the database and validates the parsed
+// metadata of an upload against the database. The returned request lacks the origin and size of the upload.
+// Every error is a validation error of the client.
+func (h *EntryHandler) prepareUpload(db repo.Database, entryRequest PostPatchEntryRequest, clientTimestamp time.Time, file io.ReadSeeker, syncPreview bool) (processing.EntryRequest, error) {
+	applyMetadataDefaults(&entryRequest, db.Config, db.CustomFields)
+	if err := validateCustomFields(entryRequest.CustomFields, db.CustomFields); err != nil {
+		return processing.EntryRequest{}, fmt.Errorf("Error validating custom fields: %w", err)
+	}
+
+	var externalID string
+	if entryRequest.ExternalID != nil {
+		externalID = *entryRequest.ExternalID
+	}
+	if err := validateExternalID(externalID); err != nil {
+		return processing.EntryRequest{}, err
+	}
+	if entryRequest.FileName != "" {
+		if err := validateFileName(entryRequest.FileName); err != nil {
+			return processing.EntryRequest{}, err
+		}
+	}
+	if syncPreview {
+		if err := h.checkSyncPreview(db, file); err != nil {
+			return processing.EntryRequest{}, err
+		}
+	}
+
+	return processing.EntryRequest{
+		Timestamp:       entryRequest.Timestamp,
+		ClientTimestamp: clientTimestamp,
+		FileName:        entryRequest.FileName,
+		ExternalID:      externalID,
+		CustomFields:    entryRequest.CustomFields,
+		SyncPreview:     syncPreview,
+	}, nil
+}
+
+// checkSyncPreview reports why a synchronous preview cannot be generated for an upload, nil if it can.
+// It is limited to images below the sync upload size, audio waveforms and large files take too long.
+func (h *EntryHandler) checkSyncPreview(db repo.Database, file io.ReadSeeker) error {
+	if db.ContentType != "image" {
+		return fmt.Errorf("sync_preview is only supported for image databases, not %s", db.ContentType)
+	}
+	if !db.Config.CreatePreview {
+		return errors.New("sync_preview needs a database with create_preview enabled")
+	}
+	if _, spooled := file.(*os.File); spooled {
+		return fmt.Errorf("sync_preview is only supported for files up to %d bytes (server.max_sync_upload_size)", h.maxSyncUploadSize())
+	}
+	return nil
+}
+
+// ingestRequest is an upload after its transport read the file and parsed the metadata.
+type ingestRequest struct {
+	metadata        PostPatchEntryRequest
+	clientTimestamp time.Time
+	file            multipart.File
+	header          *multipart.FileHeader // file name, Content-Type part header and size of the file
+	origin          repo.UploadOrigin
+	syncPreview     bool
+	auditDetails    map[string]any // added to the details of the entry.post audit event
+}
+
+// ingest is the upload path shared by the REST handlers and Ingest: it validates the metadata with
+// prepareUpload, checks the storage quota of the user in ctx, hands the file to the processor and audits the
+// new entry. It reports whether the entry was processed synchronously. Invalid metadata fails with
+// customerrors.ErrValidation, an exceeded quota with customerrors.ErrQuotaExceeded.
+func (h *EntryHandler) ingest(ctx context.Context, db repo.Database, req ingestRequest) (repo.Entry, bool, error) {
+	procReq, err := h.prepareUpload(db, req.metadata, req.clientTimestamp, req.file, req.syncPreview)
+	if err != nil {
+		return repo.Entry{}, false, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+	}
+	procReq.Origin = req.origin
+	procReq.Size = req.header.Size
+	if err := h.checkQuota(ctx, utils.GetUserFromContext(ctx), req.header.Size); err != nil {
+		return repo.Entry{}, false, err
+	}
+
+	originalMime := req.header.Header.Get("Content-Type")
+	originalName := sanitizeFileName(req.header.Filename)
+	entry, wasSync, err := h.Processor.ProcessEntry(ctx, db, procReq, req.file, originalMime, originalName)
+	if err != nil {
+		return repo.Entry{}, false, err
+	}
+
+	details := map[string]any{"database_name": db.Name}
+	if !req.clientTimestamp.IsZero() {
+		details["client_timestamp"] = req.clientTimestamp.UnixMilli()
+	}
+	maps.Copy(details, req.auditDetails)
+	h.Auditor.Log(ctx, "entry.post", req.origin.UploadedBy, fmt.Sprintf("%s:%d", db.ID, entry.ID), details)
+	return entry, wasSync, nil
+}
+
+// storeUpload stores an upload with ingest and maps its errors to the responses of the REST handlers.
+// It returns the entry response and its status code (201 for synchronous, 202 for asynchronous processing), or
+// writes the error response and returns false. With opts.minimal, synchronous uploads return a
+// MinimalEntryResponse; with opts.syncPreview, their response reports has_preview.
+func (h *EntryHandler) storeUpload(w http.ResponseWriter, r *http.Request, db repo.Database, entryRequest PostPatchEntryRequest, clientTimestamp time.Time, file multipart.File, header *multipart.FileHeader, opts uploadOptions, auditDetails map[string]any) (EntryWithID, int, bool) {
+	entry, wasSync, err := h.ingest(r.Context(), db, ingestRequest{
+		metadata:        entryRequest,
+		clientTimestamp: clientTimestamp,
+		file:            file,
+		header:          header,
+		origin:          h.uploadOrigin(r),
+		syncPreview:     opts.syncPreview,
+		auditDetails:    auditDetails,
+	})
+	if err != nil {
+		if errors.Is(err, customerrors.ErrUnavailable) {
+			utils.RespondWithError(w, http.StatusServiceUnavailable, "Service Unavailable: queue is full or processing capacity exhausted.")
+		} else if errors.Is(err, customerrors.ErrBadMimeType) || errors.Is(err, customerrors.ErrDependencies) {
+			utils.RespondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+		} else if errors.Is(err, customerrors.ErrValidation) {
+			utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		} else if errors.Is(err, customerrors.ErrInfected) {
+			utils.RespondWithError(w, http.StatusUnprocessableEntity, "The uploaded file was rejected by the virus scanner.")
+		} else if errors.Is(err, customerrors.ErrScannerUnavailable) {
+			utils.RespondWithError(w, http.StatusServiceUnavailable, "Service Unavailable: the virus scanner could not be reached.")
+		} else if errors.Is(err, customerrors.ErrLimitReached) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+		} else if errors.Is(err, customerrors.ErrQuotaExceeded) {
+			utils.RespondWithError(w, http.StatusInsufficientStorage, err.Error())
+		} else if errors.Is(err, customerrors.ErrConflict) {
+			var externalID string
+			if entryRequest.ExternalID != nil {
+				externalID = *entryRequest.ExternalID
+			}
+			h.respondWithExternalIDConflict(r.Context(), w, db, externalID)
+		} else {
+			h.Logger.Error("Processing failed", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		}
+		return nil, 0, false
+	}
+
+	// The processor returns the entry as it was stored, the response is built without reading it again
+	dbID := db.ID.String()
+	var hasPreview *bool
+	if opts.syncPreview {
+		created := entry.PreviewSize > 0
+		hasPreview = &created
+	}
+	if wasSync && opts.minimal {
+		return MinimalEntryResponse{EntryID: entry.ID, Timestamp: entry.Timestamp.UnixMilli(), Status: repo.GetEntryStatusString(entry.Status), HasPreview: hasPreview}, http.StatusCreated, true
+	}
+	if wasSync {
+		resp := mapToEntryResponse(dbID, redactEntryIn(r.Context(), db, entry))
+		resp.HasPreview = hasPreview
+		return resp, http.StatusCreated, true
+	}
+	return mapToPartialEntryResponse(dbID, redactEntryIn(r.Context(), db, entry)), http.StatusAccepted, true
+}
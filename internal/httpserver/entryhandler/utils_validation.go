@@ -2,15 +2,26 @@ package entryhandler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/processing"
 	"mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared/customerrors"
 )
 
 // parseUploadMetadata validates the request and parses the 'metadata' JSON part of the POST request.
-// It also assigns the current timestamp in case a timestamp was not provided.
-func parseUploadMetadata(metadataStr string) (PostPatchEntryRequest, error) {
+// It also assigns the current timestamp in case a timestamp was not provided, and checks a provided
+// one against the bounds. If the bounds clamp it, the client's timestamp is returned as well.
+func parseUploadMetadata(metadataStr string, bounds TimestampBounds, now time.Time) (PostPatchEntryRequest, time.Time, error) {
 	var entry = PostPatchEntryRequest{
 		FileName:  "",
 		Timestamp: math.MinInt64, // default, indicates missing timestamp
@@ -18,14 +29,164 @@ func parseUploadMetadata(metadataStr string) (PostPatchEntryRequest, error) {
 
 	// Parse Metadata
 	if metadataStr == "" {
-		return entry, fmt.Errorf("%w: missing 'metadata' part in multipart form", customerrors.ErrValidation)
+		return entry, time.Time{}, fmt.Errorf("%w: missing 'metadata' part in multipart form", customerrors.ErrValidation)
 	}
 
 	if err := json.Unmarshal([]byte(metadataStr), &entry); err != nil {
-		return entry, fmt.Errorf("%w: invalid JSON in 'metadata' part", customerrors.ErrValidation)
+		return entry, time.Time{}, fmt.Errorf("%w: invalid JSON in 'metadata' part", customerrors.ErrValidation)
+	}
+
+	clientTimestamp, err := resolveUploadTimestamp(&entry, bounds, now)
+	return entry, clientTimestamp, err
+}
+
+// resolveUploadTimestamp assigns the current time to metadata without timestamp (math.MinInt64) and checks a
+// provided one against the bounds. If the bounds clamp it, the client's timestamp is returned.
+func resolveUploadTimestamp(entry *PostPatchEntryRequest, bounds TimestampBounds, now time.Time) (time.Time, error) {
+	if entry.Timestamp == math.MinInt64 {
+		entry.Timestamp = now.UnixMilli()
+		return time.Time{}, nil
+	}
+
+	clientTimestamp := time.UnixMilli(entry.Timestamp)
+	if err := bounds.check(clientTimestamp, now); err != nil {
+		if bounds.Policy != TimestampPolicyClamp {
+			return time.Time{}, err
+		}
+		entry.Timestamp = now.UnixMilli()
+		return clientTimestamp, nil
+	}
+	return time.Time{}, nil
+}
+
+// applyMetadataDefaults fills the custom fields of an upload from the metadata defaults of the database
+// where the client sent none, and sets the metadata overrides regardless of what the client sent. Values of
+// fields that have been removed or renamed since are skipped. Updates of existing entries are not affected.
+func applyMetadataDefaults(entry *PostPatchEntryRequest, cfg repository.DatabaseConfig, defined []repository.CustomFieldDef) {
+	if len(cfg.MetadataDefaults) == 0 && len(cfg.MetadataOverrides) == 0 {
+		return
+	}
+	if entry.CustomFields == nil {
+		entry.CustomFields = make(map[string]any)
+	}
+	for _, field := range defined {
+		if value, ok := cfg.MetadataOverrides[field.Name]; ok {
+			entry.CustomFields[field.Name] = value
+		} else if _, sent := entry.CustomFields[field.Name]; !sent {
+			if value, ok := cfg.MetadataDefaults[field.Name]; ok {
+				entry.CustomFields[field.Name] = value
+			}
+		}
+	}
+}
+
+// Policies for upload timestamps outside the TimestampBounds.
+const (
+	TimestampPolicyReject = "reject" // the upload fails with 400
+	TimestampPolicyClamp  = "clamp"  // the server time is used, the client's timestamp is kept as client_timestamp
+)
+
+// TimestampBounds guards against devices with broken clocks, whose entries would sort to the top or
+// bottom of every listing and confuse the max_age housekeeping. The zero value accepts every timestamp.
+type TimestampBounds struct {
+	Policy        string        // TimestampPolicyReject or TimestampPolicyClamp, checks are disabled if empty
+	MaxFutureSkew time.Duration // how far a timestamp may be ahead of the server time
+	MinTimestamp  time.Time     // earliest accepted timestamp
+}
+
+// check returns a validation error if the timestamp is before MinTimestamp or more than MaxFutureSkew
+// after now. Both bounds are inclusive.
+func (b TimestampBounds) check(ts time.Time, now time.Time) error {
+	if b.Policy == "" {
+		return nil
+	}
+	if latest := now.Add(b.MaxFutureSkew); ts.After(latest) {
+		return fmt.Errorf("%w: timestamp %d is more than %s ahead of the server time", customerrors.ErrValidation, ts.UnixMilli(), b.MaxFutureSkew)
+	}
+	if ts.Before(b.MinTimestamp) {
+		return fmt.Errorf("%w: timestamp %d is before the earliest accepted timestamp %s", customerrors.ErrValidation, ts.UnixMilli(), b.MinTimestamp.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// maxExternalIDLength limits the identifiers assigned by clients.
+const maxExternalIDLength = 255
+
+// validateExternalID checks an external ID of the upload metadata or a PATCH request.
+func validateExternalID(externalID string) error {
+	if len(externalID) > maxExternalIDLength {
+		return fmt.Errorf("external_id must not be longer than %d characters", maxExternalIDLength)
+	}
+	return nil
+}
+
+// maxFileNameLength is the longest file name in bytes, the limit of common file systems.
+const maxFileNameLength = 255
+
+// validateFileName checks a file name given by a client in the upload metadata or a PATCH request.
+// Names are stored as they are and end up in downloads and export archives, so they must be a
+// single path element without control characters.
+func validateFileName(name string) error {
+	switch {
+	case len(name) > maxFileNameLength:
+		return fmt.Errorf("filename must not be longer than %d bytes", maxFileNameLength)
+	case strings.ContainsAny(name, `/\`):
+		return errors.New("filename must not contain path separators")
+	case name == "." || name == "..":
+		return fmt.Errorf("filename '%s' is not allowed", name)
+	case !utf8.ValidString(name) || strings.ContainsFunc(name, unicode.IsControl):
+		return errors.New("filename must not contain control characters")
+	}
+	return nil
+}
+
+// sanitizeFileName turns a name that was not validated, e.g. of the multipart header or of entries
+// stored by earlier versions, into one that passes validateFileName. Directories are removed, control
+// characters dropped and long names shortened before their extension. Empty if nothing is left.
+func sanitizeFileName(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(name, ""))
+	if name == "." || name == ".." {
+		return ""
 	}
+	if len(name) > maxFileNameLength {
+		ext := filepath.Ext(name)
+		if len(ext) > maxFileNameLength/4 {
+			ext = ""
+		}
+		base := name[:maxFileNameLength-len(ext)]
+		for !utf8.ValidString(base) { // do not cut a character in half
+			base = base[:len(base)-1]
+		}
+		name = base + ext
+	}
+	return name
+}
 
-	return entry, nil
+// renamedFileName validates the new file name of an entry. A name without extension keeps the
+// extension of the current name. An extension of another media type than the stored file is replaced
+// by the right one, or rejected if strict is set.
+func renamedFileName(name string, entry repository.Entry, strict bool) (string, error) {
+	if err := validateFileName(name); err != nil {
+		return "", err
+	}
+	if filepath.Ext(name) == "" {
+		name += filepath.Ext(entry.FileName)
+	}
+	if processing.ContradictsMimeType(name, entry.MimeType) {
+		if strict {
+			return "", fmt.Errorf("the extension of filename '%s' does not match the file type %s", name, entry.MimeType)
+		}
+		name = processing.ReplaceExtension(name, processing.GetExtensionForMimeType(entry.MimeType))
+	}
+	return name, validateFileName(name)
 }
 
 // ValidateCustomFields checks if the provided fields exist in the database schema
@@ -75,3 +236,51 @@ func validateCustomFields(provided map[string]any, defined []repository.CustomFi
 
 	return nil
 }
+
+// mutableEntryKeys are the top-level keys a PATCH of an entry may set.
+var mutableEntryKeys = []string{"filename", "timestamp", "external_id", "custom_fields"}
+
+// immutablePatchKeys returns the keys of a PATCH body that are no user-mutable fields of the database, in
+// sorted order: top-level keys besides mutableEntryKeys, e.g. width, status or duration, and keys of
+// custom_fields that are no custom fields of the database, reported as custom_fields.<name>. Technical
+// metadata is derived from the file, processing sets it with the typed repository methods.
+func immutablePatchKeys(body map[string]json.RawMessage, db repository.Database) ([]string, error) {
+	var keys []string
+	for key := range body {
+		if !slices.Contains(mutableEntryKeys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	var customFields map[string]json.RawMessage
+	if raw, ok := body["custom_fields"]; ok {
+		if err := json.Unmarshal(raw, &customFields); err != nil {
+			return nil, fmt.Errorf("%w: 'custom_fields' must be an object", customerrors.ErrValidation)
+		}
+	}
+	for key := range customFields {
+		if !slices.ContainsFunc(db.CustomFields, func(cf repository.CustomFieldDef) bool { return cf.Name == key }) {
+			keys = append(keys, "custom_fields."+key)
+		}
+	}
+
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// immutableFieldsMessage explains the rejection of the keys, naming the media fields of the content type.
+func immutableFieldsMessage(keys []string, contentType string) string {
+	message := fmt.Sprintf("These fields cannot be changed: %s. Only filename, timestamp, external_id and the custom fields of the database are mutable.", strings.Join(keys, ", "))
+	fields, _ := media.GetMetadataFields(contentType)
+	var technical []string
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, "custom_fields.")
+		if slices.ContainsFunc(fields, func(f media.FieldDef) bool { return f.Name == name }) {
+			technical = append(technical, name)
+		}
+	}
+	if len(technical) > 0 {
+		message += fmt.Sprintf(" %s of %s entries are measured from the file.", strings.Join(technical, ", "), contentType)
+	}
+	return message
+}
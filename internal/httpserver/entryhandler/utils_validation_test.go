@@ -0,0 +1,62 @@
+package entryhandler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+func TestParseUploadMetadataTimestampBounds(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	minTimestamp := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	latest := now.Add(time.Hour)
+
+	cases := []struct {
+		name      string
+		timestamp int64
+		valid     bool
+	}{
+		{"now", now.UnixMilli(), true},
+		{"latest accepted", latest.UnixMilli(), true},
+		{"just too far ahead", latest.UnixMilli() + 1, false},
+		{"2038", time.Date(2038, 1, 19, 0, 0, 0, 0, time.UTC).UnixMilli(), false},
+		{"earliest accepted", minTimestamp.UnixMilli(), true},
+		{"just too early", minTimestamp.UnixMilli() - 1, false},
+		{"1970", 0, false},
+	}
+
+	for _, policy := range []string{TimestampPolicyReject, TimestampPolicyClamp} {
+		bounds := TimestampBounds{Policy: policy, MaxFutureSkew: time.Hour, MinTimestamp: minTimestamp}
+		for _, tc := range cases {
+			metadata := fmt.Sprintf(`{"filename": "a.bin", "timestamp": %d}`, tc.timestamp)
+			req, clientTimestamp, err := parseUploadMetadata(metadata, bounds, now)
+
+			switch {
+			case tc.valid:
+				if err != nil || req.Timestamp != tc.timestamp || !clientTimestamp.IsZero() {
+					t.Errorf("%s/%s: expected the timestamp to be accepted, got %d, client %v (err %v)", policy, tc.name, req.Timestamp, clientTimestamp, err)
+				}
+			case policy == TimestampPolicyReject:
+				if !errors.Is(err, customerrors.ErrValidation) {
+					t.Errorf("%s/%s: expected a validation error, got %v", policy, tc.name, err)
+				}
+			default:
+				if err != nil || req.Timestamp != now.UnixMilli() || clientTimestamp.UnixMilli() != tc.timestamp {
+					t.Errorf("%s/%s: expected the server time and the client's timestamp, got %d, client %v (err %v)", policy, tc.name, req.Timestamp, clientTimestamp, err)
+				}
+			}
+		}
+	}
+
+	// A missing timestamp is the server time, the zero value of the bounds accepts everything
+	req, clientTimestamp, err := parseUploadMetadata(`{"filename": "a.bin"}`, TimestampBounds{Policy: TimestampPolicyReject}, now)
+	if err != nil || req.Timestamp != now.UnixMilli() || !clientTimestamp.IsZero() {
+		t.Errorf("expected the server time for a missing timestamp, got %d (err %v)", req.Timestamp, err)
+	}
+	if req, _, err := parseUploadMetadata(`{"timestamp": 0}`, TimestampBounds{}, now); err != nil || req.Timestamp != 0 {
+		t.Errorf("expected disabled bounds to accept 1970, got %d (err %v)", req.Timestamp, err)
+	}
+}
@@ -0,0 +1,63 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"mediahub_oss/internal/httpserver/utils"
+)
+
+// apiMethods are the methods used by the API. HEAD is implied by GET.
+var apiMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// apiFallbackPath is registered for every API method and catches requests no API route matched.
+const apiFallbackPath = "/api/"
+
+// addAPIFallbackRoutes answers unmatched API requests with structured errors instead of the SPA:
+// a 405 with an Allow header if the path exists for other methods, a 404 otherwise.
+// A single method-less "/api/" pattern would conflict with the frontend's "GET /" route,
+// so the fallback is registered once per method.
+func addAPIFallbackRoutes(mux *http.ServeMux) {
+	fallback := apiFallback(mux)
+	for _, method := range append([]string{http.MethodOptions}, apiMethods...) {
+		mux.Handle(method+" "+apiFallbackPath, fallback)
+	}
+}
+
+func apiFallback(mux *http.ServeMux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := allowedMethods(mux, r)
+		if len(allowed) == 0 {
+			utils.RespondWithError(w, http.StatusNotFound, fmt.Sprintf("Unknown API endpoint: %s", r.URL.Path))
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		utils.RespondWithError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method %s is not allowed for %s", r.Method, r.URL.Path))
+	}
+}
+
+// allowedMethods asks the mux which methods have a real route for the request path.
+func allowedMethods(mux *http.ServeMux, r *http.Request) []string {
+	var allowed []string
+	for _, method := range apiMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		if _, pattern := mux.Handler(probe); pattern == "" || strings.HasSuffix(pattern, " "+apiFallbackPath) {
+			continue
+		}
+		allowed = append(allowed, method)
+		if method == http.MethodGet {
+			allowed = append(allowed, http.MethodHead)
+		}
+	}
+	if len(allowed) > 0 {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	return allowed
+}
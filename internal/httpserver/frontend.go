@@ -0,0 +1,167 @@
+package httpserver
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache-Control of the frontend files. The Angular build puts a content hash into the names of all
+// other files, so a name never changes its content. index.html refers to them and is revalidated.
+const (
+	cacheControlAsset = "public, max-age=31536000, immutable"
+	cacheControlIndex = "no-cache"
+)
+
+// precompressed are the variants embedded next to a file, e.g. main.js.br, in order of preference.
+var precompressed = []struct{ encoding, ext string }{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// addFrontendRoutes serves the single-page app: existing files as cacheable assets, index.html for
+// everything else so deep links work. Missing files are not found instead of an empty app.
+// Without a file system (an API-only deployment) nothing is served.
+func addFrontendRoutes(mux *http.ServeMux, frontendFS http.FileSystem, indexFile string, basePath string) {
+	if frontendFS == nil {
+		return
+	}
+
+	// Angular requires the base href to end with a trailing slash
+	if !strings.HasSuffix(basePath, "/") {
+		basePath += "/"
+	}
+
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+
+		// If the path is empty, they want the root index
+		if name == "" || name == indexFile {
+			serveModifiedIndex(w, frontendFS, indexFile, basePath)
+			return
+		}
+
+		if serveAsset(w, r, frontendFS, name) {
+			return
+		}
+
+		// A path that looks like a file is a missing asset, the app would only hide the broken deployment
+		if strings.Contains(path.Base(name), ".") {
+			http.NotFound(w, r)
+			return
+		}
+
+		// It wasn't a physical file, so it's an Angular route.
+		// Serve the dynamically modified index.html.
+		serveModifiedIndex(w, frontendFS, indexFile, basePath)
+	})
+}
+
+// serveAsset serves a file of the frontend, or its pre-compressed variant if the client accepts it.
+// It returns false if there is no such file.
+func serveAsset(w http.ResponseWriter, r *http.Request, fs http.FileSystem, name string) bool {
+	file, ok := openFile(fs, name)
+	if !ok {
+		return false
+	}
+	defer file.Close()
+
+	header := w.Header()
+	header.Set("Cache-Control", cacheControlAsset)
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream" // not sniffed, the served bytes may be compressed
+	}
+	header.Set("Content-Type", contentType)
+
+	var content io.ReadSeeker = file
+	var encoding string
+	var varies bool
+	for _, variant := range precompressed {
+		compressed, ok := openFile(fs, name+variant.ext)
+		if !ok {
+			continue
+		}
+		defer compressed.Close()
+
+		// The response depends on the header as soon as there is a variant, also if it is not served
+		if !varies {
+			header.Add("Vary", "Accept-Encoding")
+			varies = true
+		}
+		if encoding == "" && acceptsEncoding(r.Header.Get("Accept-Encoding"), variant.encoding) {
+			content, encoding = compressed, variant.encoding
+		}
+	}
+	if encoding != "" {
+		header.Set("Content-Encoding", encoding)
+	}
+
+	// Embedded files have no modification time, the immutable names make it unnecessary
+	http.ServeContent(w, r, name, time.Time{}, content)
+	return true
+}
+
+// openFile opens a regular file of the frontend, directories are not files.
+func openFile(fs http.FileSystem, name string) (http.File, bool) {
+	file, err := fs.Open(name)
+	if err != nil {
+		return nil, false
+	}
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		file.Close()
+		return nil, false
+	}
+	return file, true
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows the content coding, a quality of 0 refuses it.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), encoding) {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		quality, err := strconv.ParseFloat(q, 64)
+		return err == nil && quality > 0
+	}
+	return false
+}
+
+// Helper function to dynamically modify and serve index.html
+func serveModifiedIndex(w http.ResponseWriter, fs http.FileSystem, indexFile, basePath string) {
+	file, err := fs.Open(indexFile)
+	if err != nil {
+		http.Error(w, "Index file not found", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	// Read the HTML into memory
+	htmlBytes, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read index file", http.StatusInternalServerError)
+		return
+	}
+
+	// Dynamically replace the base href
+	// We assume Angular built it with the standard <base href="/">
+	htmlStr := string(htmlBytes)
+	htmlStr = strings.Replace(htmlStr, `<base href="/">`, fmt.Sprintf(`<base href="%s">`, basePath), 1)
+
+	// Send it to the browser, it has to ask for the current version on every load to pick up new assets
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", cacheControlIndex)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(htmlStr))
+}
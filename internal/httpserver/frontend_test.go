@@ -0,0 +1,105 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFrontendRoutes(t *testing.T) {
+	files := fstest.MapFS{
+		"index.html":                {Data: []byte(`<html><head><base href="/"></head></html>`)},
+		"main-3F2A.js":              {Data: []byte("console.log('plain')")},
+		"main-3F2A.js.br":           {Data: []byte("brotli bytes")},
+		"main-3F2A.js.gz":           {Data: []byte("gzip bytes")},
+		"styles-9C1B.css":           {Data: []byte("body{}")},
+		"assets/logo-7E4D.svg":      {Data: []byte("<svg/>")},
+		"assets/logo-7E4D.svg.gz":   {Data: []byte("gzip svg")},
+		"assets/i18n/en-5B3C.json":  {Data: []byte("{}")},
+		"assets/fonts/roboto.woff2": {Data: []byte("font")},
+	}
+	mux := http.NewServeMux()
+	addFrontendRoutes(mux, http.FS(files), "index.html", "/app")
+
+	get := func(target, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 1. The app and its routes get the index, which is revalidated on every load
+	for _, target := range []string{"/", "/index.html", "/databases/foo", "/assets"} {
+		rec := get(target, "")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `<base href="/app/">`) {
+			t.Errorf("%s: expected the index, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+			t.Errorf("%s: expected Cache-Control no-cache, got %q", target, got)
+		}
+	}
+
+	// 2. Missing files are not found instead of a blank app
+	for _, target := range []string{"/assets/missing.js", "/favicon.ico", "/assets/i18n/de.json"} {
+		if rec := get(target, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", target, rec.Code)
+		}
+	}
+
+	// 3. Assets are cached for good and served pre-compressed where the client accepts it
+	for _, tc := range []struct {
+		target, acceptEncoding string
+		body, encoding, vary   string
+	}{
+		{"/main-3F2A.js", "gzip, deflate, br", "brotli bytes", "br", "Accept-Encoding"},
+		{"/main-3F2A.js", "gzip", "gzip bytes", "gzip", "Accept-Encoding"},
+		{"/main-3F2A.js", "br;q=0, gzip;q=0.8", "gzip bytes", "gzip", "Accept-Encoding"},
+		{"/main-3F2A.js", "", "console.log('plain')", "", "Accept-Encoding"},
+		{"/main-3F2A.js", "identity", "console.log('plain')", "", "Accept-Encoding"},
+		{"/assets/logo-7E4D.svg", "br", "<svg/>", "", "Accept-Encoding"},
+		{"/assets/logo-7E4D.svg", "BR, GZIP", "gzip svg", "gzip", "Accept-Encoding"},
+		{"/styles-9C1B.css", "gzip, br", "body{}", "", ""},
+	} {
+		rec := get(tc.target, tc.acceptEncoding)
+		name := tc.target + " with " + tc.acceptEncoding
+		if rec.Code != http.StatusOK || rec.Body.String() != tc.body {
+			t.Errorf("%s: expected %q, got %d: %q", name, tc.body, rec.Code, rec.Body.String())
+			continue
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != tc.encoding {
+			t.Errorf("%s: expected Content-Encoding %q, got %q", name, tc.encoding, got)
+		}
+		if got := rec.Header().Get("Vary"); got != tc.vary {
+			t.Errorf("%s: expected Vary %q, got %q", name, tc.vary, got)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+			t.Errorf("%s: expected an immutable Cache-Control, got %q", name, got)
+		}
+	}
+
+	// 4. The type is that of the original file, not of the compressed variant
+	for target, want := range map[string]string{
+		"/main-3F2A.js":             "text/javascript; charset=utf-8",
+		"/assets/logo-7E4D.svg":     "image/svg+xml",
+		"/assets/i18n/en-5B3C.json": "application/json",
+		"/styles-9C1B.css":          "text/css; charset=utf-8",
+	} {
+		if got := get(target, "gzip, br").Header().Get("Content-Type"); got != want {
+			t.Errorf("%s: expected Content-Type %q, got %q", target, want, got)
+		}
+	}
+
+	// 5. Without a frontend, e.g. for API-only deployments, nothing is served
+	apiOnly := http.NewServeMux()
+	addFrontendRoutes(apiOnly, nil, "index.html", "/")
+	rec := httptest.NewRecorder()
+	apiOnly.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/databases/foo", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without a frontend, got %d", rec.Code)
+	}
+}
@@ -1,12 +1,14 @@
 package httpserver
 
 import (
+	adh "mediahub_oss/internal/httpserver/adminhandler"
 	ah "mediahub_oss/internal/httpserver/audithandler"
 	dbh "mediahub_oss/internal/httpserver/databasehandler"
 	eh "mediahub_oss/internal/httpserver/entryhandler"
 	ih "mediahub_oss/internal/httpserver/infohandler"
 	th "mediahub_oss/internal/httpserver/tokenhandler"
 	uh "mediahub_oss/internal/httpserver/userhandler"
+	"mediahub_oss/internal/maintenance"
 )
 
 // container holding all other "subhandlers"
@@ -20,4 +22,10 @@ type Handlers struct {
 	UserHandler     uh.UserHandler
 	TokenHandler    th.TokenHandler
 	AuditHandler    ah.AuditHandler
+	AdminHandler    adh.AdminHandler
+
+	// Rejects the write routes while enabled, optional
+	Maintenance *maintenance.Mode
+	// Rejects the requests of clients outside of the allowed networks, optional
+	IPFilter *IPFilter
 }
@@ -0,0 +1,114 @@
+package infohandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/storage"
+)
+
+// Names of the readiness checks, as used in the response and the critical checks configuration.
+const (
+	CheckDatabase = "database"
+	CheckStorage  = "storage"
+	CheckFFmpeg   = "ffmpeg"
+)
+
+// AllChecks lists every readiness check.
+var AllChecks = []string{CheckDatabase, CheckStorage, CheckFFmpeg}
+
+const (
+	readinessCacheTTL = 2 * time.Second // aggressive probe intervals reuse the last result
+	readinessTimeout  = 5 * time.Second
+)
+
+// ReadinessChecker runs the dependency checks of /health/ready and caches their result briefly.
+type ReadinessChecker struct {
+	Repo            repository.Repository
+	Storage         storage.StorageProvider
+	FFmpegAvailable func() bool
+	Critical        []string // checks that fail the readiness, the others are only reported
+
+	mu        sync.Mutex
+	last      ReadinessResponse
+	checkedAt time.Time
+}
+
+func NewReadinessChecker(repo repository.Repository, store storage.StorageProvider, ffmpegAvailable func() bool, critical []string) *ReadinessChecker {
+	return &ReadinessChecker{
+		Repo:            repo,
+		Storage:         store,
+		FFmpegAvailable: ffmpegAvailable,
+		Critical:        critical,
+	}
+}
+
+// Check returns the cached result if it is recent enough, otherwise it runs all checks.
+// Concurrent probes wait for the running check instead of starting their own.
+func (c *ReadinessChecker) Check(ctx context.Context) ReadinessResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < readinessCacheTTL {
+		return c.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	resp := ReadinessResponse{Status: "ok", Checks: make(map[string]CheckResult, len(AllChecks))}
+	run := func(name string, check func(context.Context) error) {
+		start := time.Now()
+		err := check(ctx)
+		result := CheckResult{
+			OK:        err == nil,
+			Critical:  slices.Contains(c.Critical, name),
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if err != nil {
+			result.Error = err.Error()
+			if result.Critical {
+				resp.Status = "fail"
+			}
+		}
+		resp.Checks[name] = result
+	}
+
+	run(CheckDatabase, c.Repo.Ping)
+	run(CheckStorage, c.Storage.Probe)
+	run(CheckFFmpeg, func(context.Context) error {
+		if c.FFmpegAvailable == nil || !c.FFmpegAvailable() {
+			return fmt.Errorf("ffmpeg is not available")
+		}
+		return nil
+	})
+
+	c.checkedAt = time.Now()
+	resp.CheckedAt = c.checkedAt.UnixMilli()
+	c.last = resp
+	return resp
+}
+
+// LiveCheck confirms that the process is up, without looking at any dependency.
+func (h *InfoHandler) LiveCheck(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyCheck reports whether the instance can serve traffic: the database answers, the storage is
+// writable and optionally FFmpeg is available. It returns 503 if a critical check fails.
+func (h *InfoHandler) ReadyCheck(w http.ResponseWriter, r *http.Request) {
+	resp := h.Readiness.Check(r.Context())
+
+	status := http.StatusOK
+	if resp.Status != "ok" {
+		status = http.StatusServiceUnavailable
+		h.Logger.Warn("Readiness check failed", "checks", resp.Checks)
+	}
+	utils.RespondWithJSON(w, status, resp)
+}
@@ -0,0 +1,66 @@
+package infohandler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+)
+
+func TestReadyCheck(t *testing.T) {
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	store := &localstorage.LocalStorage{RootPath: filepath.Join(t.TempDir(), "unmounted")}
+	checker := NewReadinessChecker(r, store, func() bool { return false }, []string{CheckDatabase, CheckStorage})
+	h := &InfoHandler{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), Readiness: checker}
+
+	ready := func() (int, ReadinessResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ReadyCheck(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		var resp ReadinessResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rec.Code, resp
+	}
+
+	// 1. A missing storage root is a critical failure
+	code, resp := ready()
+	if code != http.StatusServiceUnavailable || resp.Status != "fail" {
+		t.Fatalf("expected 503 with status fail, got %d / %q", code, resp.Status)
+	}
+	if storage := resp.Checks[CheckStorage]; storage.OK || !storage.Critical || storage.Error == "" {
+		t.Errorf("expected a failed critical storage check with error, got %+v", storage)
+	}
+	if db := resp.Checks[CheckDatabase]; !db.OK {
+		t.Errorf("expected the database check to pass, got %+v", db)
+	}
+
+	// 2. The result is cached, even if the storage comes back immediately
+	store.RootPath = t.TempDir()
+	if code, cached := ready(); code != http.StatusServiceUnavailable || cached.CheckedAt != resp.CheckedAt {
+		t.Errorf("expected the cached result, got %d checked at %d", code, cached.CheckedAt)
+	}
+
+	// 3. Once the cache expired the instance is ready, a missing FFmpeg is only reported
+	checker.checkedAt = time.Now().Add(-readinessCacheTTL)
+	code, resp = ready()
+	if code != http.StatusOK || resp.Status != "ok" {
+		t.Fatalf("expected 200 with status ok, got %d / %q", code, resp.Status)
+	}
+	if ffmpeg := resp.Checks[CheckFFmpeg]; ffmpeg.OK || ffmpeg.Critical {
+		t.Errorf("expected a failed, non-critical ffmpeg check, got %+v", ffmpeg)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"mediahub_oss/internal/httpserver/utils"
 	"mediahub_oss/internal/logging/audit"
 	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/repository"
 )
 
 func NewInfoHandler(
@@ -35,6 +36,7 @@ func NewInfoHandler(
 		Version:      version,
 		StartTime:    time.Now(),
 		ConversionTo: convertTo,
+		Capabilities: mc.GetCapabilities(),
 		OIDC: OIDCConfig{
 			Enabled:           oidcEnabled,
 			LoginPageDisabled: loginPageDisabled,
@@ -45,10 +47,32 @@ func NewInfoHandler(
 		Features: FeaturesConfig{
 			AuditLogs: auditLogsStored,
 		},
+		Limits: LimitsConfig{
+			MaxCustomFields:    repository.DefaultMaxCustomFields,
+			MaxFieldNameLength: repository.DefaultMaxFieldNameLength,
+		},
 	}
 	return handler
 }
 
+// NewAuthInfo lists the enabled authentication methods for the InfoResponse.
+func NewAuthInfo(basicAuth, tokenBasicAuth, tokenJSONLogin, oidcEnabled bool) AuthInfo {
+	info := AuthInfo{Methods: []string{"bearer"}, TokenLogin: []string{}}
+	if basicAuth {
+		info.Methods = append(info.Methods, "basic")
+	}
+	if tokenBasicAuth {
+		info.TokenLogin = append(info.TokenLogin, "basic")
+	}
+	if tokenJSONLogin {
+		info.TokenLogin = append(info.TokenLogin, "json")
+	}
+	if oidcEnabled {
+		info.TokenLogin = append(info.TokenLogin, "oidc")
+	}
+	return info
+}
+
 // HealthCheck is a simple public endpoint to confirm the server is running.
 func (h *InfoHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -56,7 +80,7 @@ func (h *InfoHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Get server info
-// @Description Retrieves general information about the software, including version, uptime, and media tool availability.
+// @Description Retrieves general information about the software, including version, uptime, media tool availability, the active authentication methods, the maintenance mode, the audit events dropped because their queue was full, the number of entries in processing and, for callers with admin credentials, the number of server notices not acknowledged yet.
 // @Tags info
 // @Produce json
 // @Success 200 {object} InfoResponse "Returns general backend information"
@@ -70,10 +94,55 @@ func (h *InfoHandler) GetInfo(w http.ResponseWriter, r *http.Request) {
 		Version:      h.Version,
 		Uptime:       elapsed.String(), // Returns format like "1h5m30s"
 		ConversionTo: h.ConversionTo,
+		Capabilities: h.Capabilities,
 		OIDC:         h.OIDC,
+		Auth:         h.Auth,
 		Features:     h.Features,
+		Limits:       h.Limits,
+	}
+	if h.Maintenance != nil {
+		if state := h.Maintenance.State(); state.Enabled {
+			resp.Maintenance = MaintenanceInfo{Enabled: true, Message: state.Message, Since: state.Since.UnixMilli()}
+		}
+	}
+	if h.AuditDelivery != nil {
+		stats := h.AuditDelivery.Stats()
+		resp.Audit = AuditInfo{Queued: stats.Queued, DroppedEvents: stats.Dropped, SinkFailures: stats.SinkFailures}
+	}
+	if h.IPFilter != nil {
+		resp.IPFilter = IPFilterInfo{Enabled: true, BlockedRequests: h.IPFilter.Blocked()}
+	}
+	if h.Backlog != nil {
+		backlog, err := h.Backlog.GetProcessingBacklog(r.Context(), repository.BacklogFilter{})
+		if err != nil {
+			h.Logger.Warn("Failed to query the processing backlog", "error", err)
+		}
+		for _, b := range backlog {
+			resp.Backlog += b.Count
+		}
+	}
+	if h.Notices != nil && h.callerIsAdmin(r) {
+		count, err := h.Notices.CountUnacknowledgedNotices(r.Context())
+		if err != nil {
+			h.Logger.Warn("Failed to count the server notices", "error", err)
+		}
+		resp.Notices = &count
 	}
 
 	// h.Auditor.Log(r.Context(), "system.info", "anonymous", "server", nil) // this is public, not audit logging
 	utils.RespondWithJSON(w, http.StatusOK, resp)
 }
+
+// callerIsAdmin reports whether the request carries the credentials of a global admin. The endpoint is public,
+// missing or invalid credentials just leave out the admin details.
+func (h *InfoHandler) callerIsAdmin(r *http.Request) bool {
+	authorization := r.Header.Get("Authorization")
+	if h.Authenticator == nil || authorization == "" {
+		return false
+	}
+	ctx, err := h.Authenticator.Authenticate(r.Context(), authorization)
+	if err != nil {
+		return false
+	}
+	return utils.GetPermissionHolderFromContext(ctx).IsGlobalAdmin()
+}
@@ -0,0 +1,70 @@
+package infohandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mediahub_oss/internal/httpserver/utils"
+)
+
+type fixedNotices int64
+
+func (n fixedNotices) CountUnacknowledgedNotices(ctx context.Context) (int64, error) {
+	return int64(n), nil
+}
+
+// bearerAuthenticator accepts "Bearer admin" as admin and "Bearer user" as user without permissions.
+type bearerAuthenticator struct{}
+
+func (bearerAuthenticator) Authenticate(ctx context.Context, authorization string) (context.Context, error) {
+	switch authorization {
+	case "Bearer admin":
+		return context.WithValue(ctx, utils.PermissionHolderKey, &utils.GlobalAdmin{}), nil
+	case "Bearer user":
+		return context.WithValue(ctx, utils.PermissionHolderKey, &utils.Anonymous{}), nil
+	}
+	return ctx, errors.New("invalid credentials")
+}
+
+func TestGetInfoNotices(t *testing.T) {
+	h := &InfoHandler{
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Notices:       fixedNotices(3),
+		Authenticator: bearerAuthenticator{},
+	}
+	info := func(authorization string) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rec := httptest.NewRecorder()
+		h.GetInfo(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var resp map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// 1. Anonymous callers, callers with invalid credentials and non-admins do not see the count
+	for _, authorization := range []string{"", "Bearer invalid", "Bearer user"} {
+		if count, ok := info(authorization)["unacknowledged_notices"]; ok {
+			t.Errorf("expected no notice count for %q, got %v", authorization, count)
+		}
+	}
+
+	// 2. Admins do
+	if count := info("Bearer admin")["unacknowledged_notices"]; count != float64(3) {
+		t.Errorf("expected 3 notices for the admin, got %v", count)
+	}
+}
@@ -1,10 +1,13 @@
 package infohandler
 
 import (
+	"context"
 	"log/slog"
 	"time"
 
 	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/maintenance"
+	"mediahub_oss/internal/repository"
 )
 
 // OIDCConfig represents the nested OIDC settings in the InfoResponse.
@@ -16,19 +19,83 @@ type OIDCConfig struct {
 	RedirectURL       string `json:"oidc_redirect_url"`
 }
 
+// AuthInfo represents the active authentication methods in the InfoResponse, so clients can adapt their login.
+type AuthInfo struct {
+	Methods    []string `json:"methods"`     // accepted on the protected routes: "bearer" (JWTs and API keys), "basic"
+	TokenLogin []string `json:"token_login"` // accepted by POST /api/token: "basic", "json" (username and password), "oidc"
+}
+
 // FeaturesConfig represents the nested features settings in the InfoResponse.
 type FeaturesConfig struct {
 	AuditLogs bool `json:"audit_logs"`
 }
 
+// MaintenanceInfo represents the maintenance mode in the InfoResponse.
+type MaintenanceInfo struct {
+	Enabled bool   `json:"enabled"` // write requests are rejected with 503
+	Message string `json:"message,omitempty"`
+	Since   int64  `json:"since,omitempty"` // Unix milliseconds
+}
+
+// AuditInfo represents the background delivery of audit events in the InfoResponse.
+type AuditInfo struct {
+	Queued        int    `json:"queued"`         // events waiting to be written
+	DroppedEvents uint64 `json:"dropped_events"` // events lost since the start because the queue was full
+	SinkFailures  uint64 `json:"sink_failures"`  // events the audit logger failed to write
+}
+
+// IPFilterInfo represents the IP allowlist and denylist in the InfoResponse.
+type IPFilterInfo struct {
+	Enabled         bool   `json:"enabled"`
+	BlockedRequests uint64 `json:"blocked_requests"` // requests rejected since the start
+}
+
+// BlockedCounter reports the requests an IP filter rejected.
+type BlockedCounter interface {
+	Blocked() uint64
+}
+
+// BacklogReporter reports the entries waiting for processing.
+type BacklogReporter interface {
+	GetProcessingBacklog(ctx context.Context, filter repository.BacklogFilter) ([]repository.ProcessingBacklog, error)
+}
+
+// NoticeCounter reports the server notices not acknowledged yet.
+type NoticeCounter interface {
+	CountUnacknowledgedNotices(ctx context.Context) (int64, error)
+}
+
+// Authenticator resolves the caller of an Authorization header, see auth.AuthMiddleware.Authenticate.
+type Authenticator interface {
+	Authenticate(ctx context.Context, authorization string) (context.Context, error)
+}
+
+// LimitsConfig represents the limits of database definitions and of their number in the InfoResponse.
+type LimitsConfig struct {
+	MaxCustomFields       int `json:"max_custom_fields"`
+	MaxFieldNameLength    int `json:"max_field_name_length"`
+	MaxDatabases          int `json:"max_databases"`            // 0 for unlimited
+	MaxEntriesPerDatabase int `json:"max_entries_per_database"` // 0 for unlimited
+}
+
 type InfoHandler struct {
-	Logger       *slog.Logger
-	Auditor      audit.AuditLogger
-	Version      string
-	StartTime    time.Time
-	ConversionTo map[string][]string
-	OIDC         OIDCConfig
-	Features     FeaturesConfig
+	Logger        *slog.Logger
+	Auditor       audit.AuditLogger
+	Version       string
+	StartTime     time.Time
+	ConversionTo  map[string][]string
+	Capabilities  map[string]bool
+	OIDC          OIDCConfig
+	Auth          AuthInfo
+	Features      FeaturesConfig
+	Limits        LimitsConfig
+	Readiness     *ReadinessChecker
+	Maintenance   *maintenance.Mode // optional
+	AuditDelivery *audit.Switch     // optional, reports the queued and dropped audit events
+	IPFilter      BlockedCounter    // optional, set while the IP filter is enabled
+	Backlog       BacklogReporter   // optional, reports the processing backlog
+	Notices       NoticeCounter     // optional, reports the open server notices to admins
+	Authenticator Authenticator     // optional, identifies admins among the callers of the public endpoint
 }
 
 // InfoResponse defines the JSON structure for the /api/info endpoint.
@@ -37,6 +104,29 @@ type InfoResponse struct {
 	Version      string              `json:"version"`
 	Uptime       string              `json:"uptime"` // Changed to reflect elapsed duration
 	ConversionTo map[string][]string `json:"conversion_to"`
+	Capabilities map[string]bool     `json:"media_capabilities"`
 	OIDC         OIDCConfig          `json:"oidc"`
+	Auth         AuthInfo            `json:"auth"`
 	Features     FeaturesConfig      `json:"features"`
+	Limits       LimitsConfig        `json:"limits"`
+	Maintenance  MaintenanceInfo     `json:"maintenance"`
+	Audit        AuditInfo           `json:"audit"`
+	IPFilter     IPFilterInfo        `json:"ip_filter"`
+	Backlog      int64               `json:"processing_backlog"`               // entries in processing in all databases
+	Notices      *int64              `json:"unacknowledged_notices,omitempty"` // server notices not acknowledged yet, only for admins
+}
+
+// ReadinessResponse defines the JSON structure for the /health/ready endpoint.
+type ReadinessResponse struct {
+	Status    string                 `json:"status"` // "ok" or "fail"
+	Checks    map[string]CheckResult `json:"checks"`
+	CheckedAt int64                  `json:"checked_at"` // results are cached for a few seconds
+}
+
+// CheckResult is the outcome of a single readiness check.
+type CheckResult struct {
+	OK        bool    `json:"ok"`
+	Critical  bool    `json:"critical"` // a failing critical check makes the instance unready
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
 }
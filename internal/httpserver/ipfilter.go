@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+)
+
+// ipFilterAuditInterval is the least time between two audit events about blocked requests, so a scan
+// does not flood the audit log.
+const ipFilterAuditInterval = time.Minute
+
+// healthPaths are the probes of orchestrators that IPFilter.ExemptHealth lets through.
+var healthPaths = []string{"/health", "/health/live", "/health/ready"}
+
+// IPFilter rejects requests by the client IP, resolved through the trusted proxies. The denylist wins
+// over the allowlist, an empty allowlist allows every address that is not denied.
+type IPFilter struct {
+	Allow          []netip.Prefix
+	Deny           []netip.Prefix
+	TrustedProxies []netip.Prefix // proxies whose X-Forwarded-For header is honored for the client IP
+	ExemptHealth   bool           // the health endpoints are reachable from everywhere
+	Auditor        audit.AuditLogger
+	AuditBlocked   bool // log an audit event for blocked requests, at most once per ipFilterAuditInterval
+
+	blocked atomic.Uint64
+
+	mu             sync.Mutex
+	lastAudit      time.Time
+	unauditedSince uint64 // blocked requests since the last audit event
+}
+
+// Allowed reports whether a client address may reach the API. Addresses that cannot be parsed are only
+// allowed without allowlist.
+func (f *IPFilter) Allowed(ip netip.Addr) bool {
+	if !ip.IsValid() {
+		return len(f.Allow) == 0
+	}
+	ip = ip.Unmap()
+	contains := func(prefix netip.Prefix) bool { return prefix.Contains(ip) }
+	if slices.ContainsFunc(f.Deny, contains) {
+		return false
+	}
+	return len(f.Allow) == 0 || slices.ContainsFunc(f.Allow, contains)
+}
+
+// Blocked returns the number of rejected requests since the start.
+func (f *IPFilter) Blocked() uint64 {
+	return f.blocked.Load()
+}
+
+// IPFilterMiddleware rejects the requests of clients the filter does not allow with 403 and a minimal
+// body. prefix is the route prefix the routes are served below, for the health exemption. A nil filter
+// never rejects.
+func IPFilterMiddleware(f *IPFilter, prefix string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if f == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if f.ExemptHealth && slices.Contains(healthPaths, strings.TrimPrefix(r.URL.Path, prefix)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			clientIP := utils.ClientIP(r, f.TrustedProxies)
+			ip, _ := netip.ParseAddr(clientIP)
+			if !f.Allowed(ip) {
+				f.reject(r.Context(), clientIP, r.Method, r.URL.Path)
+				utils.RespondWithError(w, http.StatusForbidden, "Forbidden")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AllowedCall reports whether a client of another transport than HTTP, e.g. the gRPC API, may reach the
+// server. clientIP is the address of the peer, method and resource describe the call for the audit event.
+// Rejected calls are counted and audited like blocked requests.
+func (f *IPFilter) AllowedCall(ctx context.Context, clientIP, method, resource string) bool {
+	ip, _ := netip.ParseAddr(clientIP)
+	if f.Allowed(ip) {
+		return true
+	}
+	f.reject(ctx, clientIP, method, resource)
+	return false
+}
+
+// reject counts a blocked request and audits it, together with the requests blocked since the last event.
+func (f *IPFilter) reject(ctx context.Context, clientIP, method, resource string) {
+	f.blocked.Add(1)
+	if !f.AuditBlocked || f.Auditor == nil {
+		return
+	}
+
+	f.mu.Lock()
+	f.unauditedSince++
+	now := time.Now()
+	if now.Sub(f.lastAudit) < ipFilterAuditInterval {
+		f.mu.Unlock()
+		return
+	}
+	count := f.unauditedSince
+	f.lastAudit = now
+	f.unauditedSince = 0
+	f.mu.Unlock()
+
+	f.Auditor.Log(ctx, "security.ip_blocked", utils.AnonymousActor(clientIP), resource, map[string]any{
+		"client_ip": clientIP,
+		"method":    method,
+		"blocked":   count, // requests blocked since the previous event, this one included
+	})
+}
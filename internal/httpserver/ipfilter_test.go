@@ -0,0 +1,171 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+type blockedAuditor struct {
+	mu      sync.Mutex
+	details []map[string]any
+}
+
+func (a *blockedAuditor) Log(_ context.Context, action string, _ string, _ string, details map[string]any) {
+	if action != "security.ip_blocked" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.details = append(a.details, details)
+}
+
+func prefixes(t *testing.T, values ...string) []netip.Prefix {
+	t.Helper()
+	var out []netip.Prefix
+	for _, value := range values {
+		out = append(out, netip.MustParsePrefix(value))
+	}
+	return out
+}
+
+func TestIPFilter(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	tests := []struct {
+		name       string
+		filter     *IPFilter
+		prefix     string
+		path       string
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{name: "no filter", path: "/api/info", remoteAddr: "203.0.113.5:1234", want: http.StatusOK},
+		{
+			name:   "allowed network",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8")},
+			path:   "/api/info", remoteAddr: "10.1.2.3:1234", want: http.StatusOK,
+		},
+		{
+			name:   "outside of the allowlist",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8")},
+			path:   "/api/info", remoteAddr: "203.0.113.5:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "denylist wins over the allowlist",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8"), Deny: prefixes(t, "10.9.0.0/16")},
+			path:   "/api/info", remoteAddr: "10.9.1.1:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "denylist without allowlist",
+			filter: &IPFilter{Deny: prefixes(t, "203.0.113.0/24")},
+			path:   "/api/info", remoteAddr: "198.51.100.1:1234", want: http.StatusOK,
+		},
+		{
+			name:   "IPv4-mapped IPv6 address",
+			filter: &IPFilter{Deny: prefixes(t, "203.0.113.0/24")},
+			path:   "/api/info", remoteAddr: "[::ffff:203.0.113.5]:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "forwarded by a trusted proxy",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8"), TrustedProxies: prefixes(t, "192.168.0.1/32")},
+			path:   "/api/info", remoteAddr: "192.168.0.1:1234", forwarded: "10.1.2.3", want: http.StatusOK,
+		},
+		{
+			name:   "forwarded by a trusted proxy for a denied client",
+			filter: &IPFilter{Deny: prefixes(t, "203.0.113.0/24"), TrustedProxies: prefixes(t, "192.168.0.1/32")},
+			path:   "/api/info", remoteAddr: "192.168.0.1:1234", forwarded: "203.0.113.5", want: http.StatusForbidden,
+		},
+		{
+			name:   "spoofed header from an untrusted client",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8"), TrustedProxies: prefixes(t, "192.168.0.1/32")},
+			path:   "/api/info", remoteAddr: "203.0.113.5:1234", forwarded: "10.1.2.3", want: http.StatusForbidden,
+		},
+		{
+			name:   "health is filtered by default",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8")},
+			path:   "/health/ready", remoteAddr: "203.0.113.5:1234", want: http.StatusForbidden,
+		},
+		{
+			name:   "health exemption",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8"), ExemptHealth: true},
+			path:   "/health/live", remoteAddr: "203.0.113.5:1234", want: http.StatusOK,
+		},
+		{
+			name:   "health exemption below the route prefix",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8"), ExemptHealth: true},
+			prefix: "/mediahub", path: "/mediahub/health", remoteAddr: "203.0.113.5:1234", want: http.StatusOK,
+		},
+		{
+			name:   "health exemption covers only the probes",
+			filter: &IPFilter{Allow: prefixes(t, "10.0.0.0/8"), ExemptHealth: true},
+			path:   "/health/other", remoteAddr: "203.0.113.5:1234", want: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			IPFilterMiddleware(tt.filter, tt.prefix)(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.filter != nil {
+				wantBlocked := uint64(0)
+				if tt.want == http.StatusForbidden {
+					wantBlocked = 1
+				}
+				if got := tt.filter.Blocked(); got != wantBlocked {
+					t.Errorf("expected %d blocked requests, got %d", wantBlocked, got)
+				}
+			}
+		})
+	}
+}
+
+func TestIPFilterAuditRateLimit(t *testing.T) {
+	auditor := &blockedAuditor{}
+	filter := &IPFilter{Deny: prefixes(t, "203.0.113.0/24"), Auditor: auditor, AuditBlocked: true}
+	handler := IPFilterMiddleware(filter, "")(http.NotFoundHandler())
+
+	for range 5 {
+		req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+		req.RemoteAddr = "203.0.113.5:1234"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := filter.Blocked(); got != 5 {
+		t.Errorf("expected 5 blocked requests, got %d", got)
+	}
+	if len(auditor.details) != 1 {
+		t.Fatalf("expected a single audit event within the interval, got %d", len(auditor.details))
+	}
+	if got := auditor.details[0]["client_ip"]; got != "203.0.113.5" {
+		t.Errorf("expected the client IP in the audit event, got %v", got)
+	}
+
+	// The next event after the interval carries the requests blocked in between
+	filter.mu.Lock()
+	filter.lastAudit = filter.lastAudit.Add(-ipFilterAuditInterval)
+	filter.mu.Unlock()
+	req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+	req.RemoteAddr = "203.0.113.5:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(auditor.details) != 2 {
+		t.Fatalf("expected a second audit event after the interval, got %d", len(auditor.details))
+	}
+	if got := auditor.details[1]["blocked"]; got != uint64(5) {
+		t.Errorf("expected 5 blocked requests in the second event, got %v", got)
+	}
+}
@@ -0,0 +1,27 @@
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/maintenance"
+)
+
+// MaintenanceMiddleware rejects requests with 503 and the maintenance message while the maintenance
+// mode is enabled. It wraps the write routes, reads keep working. A nil mode never rejects.
+func MaintenanceMiddleware(mode *maintenance.Mode) Middleware {
+	return func(next http.Handler) http.Handler {
+		if mode == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if state := mode.State(); state.Enabled {
+				w.Header().Set("Retry-After", strconv.Itoa(int(maintenance.RetryAfter.Seconds())))
+				utils.RespondWithError(w, http.StatusServiceUnavailable, state.Message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,29 +1,38 @@
 package httpserver
 
 import (
-	"fmt"
-	"io"
 	"mediahub_oss/internal/httpserver/auth"
+	"mediahub_oss/internal/httpserver/utils"
 	repo "mediahub_oss/internal/repository"
 	"net/http"
-	"strings"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 // SetupRouter configures the main router using the Go Standard Library.
-func SetupRouter(h *Handlers, frontendFS http.FileSystem, am *auth.AuthMiddleware, basePath string, allowedOrigins []string) http.Handler {
+// basePath is the <base href> of the frontend. If prefix is set (e.g. "/mediahub"), all routes are
+// served below it, for reverse proxies that forward the prefix instead of stripping it.
+func SetupRouter(h *Handlers, frontendFS http.FileSystem, am *auth.AuthMiddleware, basePath string, prefix string, allowedOrigins []string) http.Handler {
 	mux := http.NewServeMux()
 
 	// --- 1. Public Endpoints ---
 	mux.HandleFunc("GET /health", h.InfoHandler.HealthCheck)
+	mux.HandleFunc("GET /health/live", h.InfoHandler.LiveCheck)
+	mux.HandleFunc("GET /health/ready", h.InfoHandler.ReadyCheck)
 	mux.HandleFunc("GET /api/info", h.InfoHandler.GetInfo)
 	mux.Handle("GET /swagger/", httpSwagger.WrapHandler)
+	mux.HandleFunc("GET /swagger/doc.json", swaggerDoc(h.EntryHandler.BaseURL, !am.BasicAuthDisabled, !h.TokenHandler.BasicAuthDisabled))
 
 	// --- 2. Public Token Endpoints ---
 	mux.HandleFunc("POST /api/token", h.TokenHandler.GetToken)
 	mux.HandleFunc("POST /api/token/refresh", h.TokenHandler.RefreshToken)
 
+	// --- 2b. Public Share Links (the token itself is the credential) ---
+	mux.HandleFunc("GET /share/{token}", h.EntryHandler.GetSharedEntry)
+
+	// --- 2c. Public Upload Grants (the single-use token itself is the credential) ---
+	mux.Handle("POST /upload/{grant}", Chain(h.EntryHandler.PostGrantUpload, MaintenanceMiddleware(h.Maintenance)))
+
 	// --- 3. Authenticated Routes (Logout & User Self-Management) ---
 	// Auth is required, but no specific role/permission.
 	// We use the Chain helper for clean stacking: Chain(Handler, Auth)
@@ -36,13 +45,32 @@ func SetupRouter(h *Handlers, frontendFS http.FileSystem, am *auth.AuthMiddlewar
 	// --- 4. Feature Routes ---
 	addAdminRoutes(mux, h, am)
 	addDatabaseRoutes(mux, h, am)
+	addAPIFallbackRoutes(mux)
 
 	// --- 5. Frontend (SPA) ---
 	addFrontendRoutes(mux, frontendFS, "index.html", basePath)
 
 	// --- 6. Global Middleware Wrap ---
-	// Wrap the entire router with the CORS middleware before returning
-	return CORSMiddleware(allowedOrigins)(mux)
+	// Wrap the entire router with the CORS middleware before returning, the external origin of
+	// each request is resolved first for the absolute URLs of the handlers. Clients the IP filter
+	// rejects are turned away before anything else.
+	var handler http.Handler = mux
+	if prefix != "" {
+		handler = mountUnderPrefix(mux, prefix)
+	}
+	handler = CORSMiddleware(allowedOrigins)(utils.OriginMiddleware(am.TrustedProxies)(handler))
+	return IPFilterMiddleware(h.IPFilter, prefix)(handler)
+}
+
+// mountUnderPrefix serves the router below prefix and redirects the un-prefixed root to the app.
+// Everything else outside of the prefix is not found.
+func mountUnderPrefix(h http.Handler, prefix string) http.Handler {
+	outer := http.NewServeMux()
+	outer.Handle(prefix+"/", http.StripPrefix(prefix, h))
+	outer.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, prefix+"/", http.StatusFound)
+	})
+	return outer
 }
 
 // addAdminRoutes configures global administrative routes.
@@ -59,13 +87,65 @@ func addAdminRoutes(mux *http.ServeMux, h *Handlers, am *auth.AuthMiddleware) {
 	mux.Handle("PATCH /api/user/{user_ulid}", ReqAdmin(h.UserHandler.UpdateUser))
 	mux.Handle("DELETE /api/user/{user_ulid}", ReqAdmin(h.UserHandler.DeleteUser))
 
+	// Group Management
+	mux.Handle("GET /api/groups", ReqAdmin(h.UserHandler.GetGroups))
+	mux.Handle("POST /api/group", ReqAdmin(h.UserHandler.CreateGroup))
+	mux.Handle("GET /api/group/{group_ulid}", ReqAdmin(h.UserHandler.GetGroup))
+	mux.Handle("PATCH /api/group/{group_ulid}", ReqAdmin(h.UserHandler.UpdateGroup))
+	mux.Handle("DELETE /api/group/{group_ulid}", ReqAdmin(h.UserHandler.DeleteGroup))
+	mux.Handle("PUT /api/user/{user_ulid}/groups/{group_ulid}", ReqAdmin(h.UserHandler.AddUserToGroup))
+	mux.Handle("DELETE /api/user/{user_ulid}/groups/{group_ulid}", ReqAdmin(h.UserHandler.RemoveUserFromGroup))
+
+	// Middleware Stack: Auth -> IsAdmin -> Not in Maintenance
+	ReqAdminWrite := func(hf http.HandlerFunc) http.Handler {
+		return Chain(hf, am.AuthMiddleware, am.RequireGlobalAdmin(), MaintenanceMiddleware(h.Maintenance))
+	}
+
 	// Global Database Creation and Deletion (Restricted to Admin)
-	mux.Handle("POST /api/database", ReqAdmin(h.DatabaseHandler.CreateDatabase))
-	mux.Handle("DELETE /api/database/{database_id}", ReqAdmin(h.DatabaseHandler.DeleteDatabase))
+	mux.Handle("POST /api/database", ReqAdminWrite(h.DatabaseHandler.CreateDatabase))
+	mux.Handle("DELETE /api/database/{database_id}", ReqAdminWrite(h.DatabaseHandler.DeleteDatabase))
+	mux.Handle("POST /api/database/definition", ReqAdminWrite(h.DatabaseHandler.ApplyDatabaseDefinition))
+
+	// Query Plans of Entry Searches (Restricted to Admin)
+	mux.Handle("GET /api/database/{database_id}/query_plan", ReqAdmin(h.EntryHandler.GetQueryPlan))
+
+	// Legal Hold of Entries (Restricted to Admin)
+	mux.Handle("POST /api/entry/hold", ReqAdminWrite(h.EntryHandler.SetLegalHold))
+	mux.Handle("DELETE /api/entry/hold", ReqAdminWrite(h.EntryHandler.ReleaseLegalHold))
 
 	// Audit Logs (Restricted to Admin)
 	mux.Handle("GET /api/audit", ReqAdmin(h.AuditHandler.GetLogs))
 
+	// Storage Usage Report (Restricted to Admin)
+	mux.Handle("GET /api/admin/storage_report", ReqAdmin(h.AdminHandler.GetStorageReport))
+
+	// Usage per User or Database (Restricted to Admin)
+	mux.Handle("GET /api/admin/usage", ReqAdmin(h.AdminHandler.GetUsage))
+
+	// Processing Backlog (Restricted to Admin)
+	mux.Handle("GET /api/admin/processing_backlog", ReqAdmin(h.AdminHandler.GetProcessingBacklog))
+
+	// Server Notices (Restricted to Admin)
+	mux.Handle("GET /api/admin/notices", ReqAdmin(h.AdminHandler.GetNotices))
+	mux.Handle("POST /api/admin/notices/ack", ReqAdmin(h.AdminHandler.AcknowledgeNotices))
+
+	// Recent Media Operations (Restricted to Admin)
+	mux.Handle("GET /api/admin/media_log", ReqAdmin(h.AdminHandler.GetMediaLog))
+
+	// JWT Secret Rotation (Restricted to Admin)
+	mux.Handle("POST /api/admin/jwt/rotate", ReqAdmin(h.AdminHandler.RotateJWTSecret))
+
+	// Configuration Reload (Restricted to Admin)
+	mux.Handle("POST /api/admin/reload_config", ReqAdmin(h.AdminHandler.ReloadConfig))
+
+	// Repository Cache (Restricted to Admin)
+	mux.Handle("GET /api/admin/cache", ReqAdmin(h.AdminHandler.GetCacheStats))
+	mux.Handle("POST /api/admin/cache/flush", ReqAdmin(h.AdminHandler.FlushCache))
+	mux.Handle("GET /api/admin/sweeps", ReqAdmin(h.AdminHandler.GetSweeps))
+
+	// Maintenance Mode (Restricted to Admin)
+	mux.Handle("POST /api/admin/maintenance", ReqAdmin(h.AdminHandler.SetMaintenance))
+
 	// API Keys Management (Admin only)
 	mux.Handle("GET /api/users/keys", ReqAdmin(h.UserHandler.GetAllAPIKeys))
 
@@ -78,6 +158,9 @@ func addAdminRoutes(mux *http.ServeMux, h *Handlers, am *auth.AuthMiddleware) {
 	mux.Handle("GET /api/user/{user_ulid}/keys/{key_ulid}", ReqSelfOrAdmin(h.UserHandler.GetAPIKey))
 	mux.Handle("PATCH /api/user/{user_ulid}/keys/{key_ulid}", ReqSelfOrAdmin(h.UserHandler.UpdateAPIKey))
 	mux.Handle("DELETE /api/user/{user_ulid}/keys/{key_ulid}", ReqSelfOrAdmin(h.UserHandler.DeleteAPIKey))
+
+	// Group Memberships (Self or Admin)
+	mux.Handle("GET /api/user/{user_ulid}/groups", ReqSelfOrAdmin(h.UserHandler.GetUserGroups))
 }
 
 // addDatabaseRoutes configures database routes AND nested entry routes.
@@ -86,102 +169,95 @@ func addDatabaseRoutes(mux *http.ServeMux, h *Handlers, am *auth.AuthMiddleware)
 	ReqPerm := func(perm repo.AccessGrant, h http.HandlerFunc) http.Handler {
 		return Chain(h, am.AuthMiddleware, am.RequireDatabasePermission(perm))
 	}
-	// 1. Global Database List (Any Authenticated User)
-	mux.Handle("GET /api/databases", Chain(h.DatabaseHandler.GetDatabases, am.AuthMiddleware))
+	// Stack: Auth -> Check Permission for {database_id} -> Not in Maintenance
+	// Write operations are rejected while the maintenance mode is enabled
+	ReqWrite := func(perm repo.AccessGrant, hf http.HandlerFunc) http.Handler {
+		return Chain(hf, am.AuthMiddleware, am.RequireDatabasePermission(perm), MaintenanceMiddleware(h.Maintenance))
+	}
+	// Stack: Auth or Anonymous -> Check View Permission for {database_id}
+	// Anonymous callers pass for databases with public_read only
+	ReqPublicRead := func(hf http.HandlerFunc) http.Handler {
+		return Chain(hf, am.AllowAnonymous, am.RequireDatabasePermission(repo.AccessView))
+	}
+	// 1. Global Database List (Any Authenticated User, anonymous callers see the public databases)
+	mux.Handle("GET /api/databases", Chain(h.DatabaseHandler.GetDatabases, am.AllowAnonymous))
+	mux.Handle("GET /api/database/definition", Chain(h.DatabaseHandler.GetDatabaseDefinition, am.AuthMiddleware)) // access is checked by the handler
+
+	// Duplicate Scans (CanDelete or DB Admin on the database, checked by the handler)
+	mux.Handle("POST /api/database/duplicates", Chain(h.DatabaseHandler.StartDuplicateScan, am.AuthMiddleware, MaintenanceMiddleware(h.Maintenance)))
+	mux.Handle("GET /api/database/duplicates", Chain(h.DatabaseHandler.GetDuplicateScan, am.AuthMiddleware))
+	mux.Handle("GET /api/database/activity", Chain(h.DatabaseHandler.GetActivity, am.AuthMiddleware))
+
+	// Retention Simulation (CanView and CanDelete on the database, checked by the handler, nothing is deleted)
+	mux.Handle("POST /api/database/housekeeping/simulate", Chain(h.DatabaseHandler.SimulateHousekeeping, am.AuthMiddleware))
+
+	// Background Jobs (own jobs, CanDelete on the database or global admin, checked by the handler)
+	mux.Handle("GET /api/jobs/{job_id}", Chain(h.EntryHandler.GetJob, am.AuthMiddleware))
+
+	// Preview Export (CanView on the database, checked by the handler)
+	mux.Handle("POST /api/database/previews/export", Chain(h.EntryHandler.ExportPreviews, am.AuthMiddleware))
+
+	// Global Search (Any Authenticated User, in the databases the user may view)
+	mux.Handle("POST /api/search/global", Chain(h.EntryHandler.GlobalSearch, am.AuthMiddleware))
+
+	// Upload Grants (CanCreate on the database of the grant, checked by the handler; own grants, all for admins)
+	mux.Handle("POST /api/upload/grants", Chain(h.EntryHandler.CreateUploadGrant, am.AuthMiddleware, MaintenanceMiddleware(h.Maintenance)))
+	mux.Handle("GET /api/upload/grants", Chain(h.EntryHandler.GetUploadGrants, am.AuthMiddleware))
+	mux.Handle("DELETE /api/upload/grants/{grant_id}", Chain(h.EntryHandler.DeleteUploadGrant, am.AuthMiddleware, MaintenanceMiddleware(h.Maintenance)))
 
 	// 2. Database Admin Operations (Global Admin or DB Admin)
-	mux.Handle("PUT /api/database/{database_id}", ReqPerm(repo.AccessAdmin, h.DatabaseHandler.UpdateDatabase))
-	mux.Handle("POST /api/database/{database_id}/field", ReqPerm(repo.AccessAdmin, h.DatabaseHandler.AddField))
-	mux.Handle("PATCH /api/database/{database_id}/field/{field_id}", ReqPerm(repo.AccessAdmin, h.DatabaseHandler.UpdateField))
-	mux.Handle("DELETE /api/database/{database_id}/field/{field_id}", ReqPerm(repo.AccessAdmin, h.DatabaseHandler.DeleteField))
+	mux.Handle("PUT /api/database/{database_id}", ReqWrite(repo.AccessAdmin, h.DatabaseHandler.UpdateDatabase))
+	mux.Handle("POST /api/database/{database_id}/field", ReqWrite(repo.AccessAdmin, h.DatabaseHandler.AddField))
+	mux.Handle("PATCH /api/database/{database_id}/field/{field_id}", ReqWrite(repo.AccessAdmin, h.DatabaseHandler.UpdateField))
+	mux.Handle("DELETE /api/database/{database_id}/field/{field_id}", ReqWrite(repo.AccessAdmin, h.DatabaseHandler.DeleteField))
 
 	// 3. Database View Operations (CanView / CanCreate / CanEdit / CanDelete/ CanAdmin)
 	// Covers getting DB stats, searching entries, and viewing specific entries
 	mux.Handle("GET /api/database/{database_id}", ReqPerm(repo.AccessView|repo.AccessCreate|repo.AccessEdit|repo.AccessDelete|repo.AccessAdmin, h.DatabaseHandler.GetDatabase))
 	mux.Handle("GET /api/database/{database_id}/fields", ReqPerm(repo.AccessView|repo.AccessCreate|repo.AccessEdit|repo.AccessDelete|repo.AccessAdmin, h.DatabaseHandler.GetFields))
+	mux.Handle("GET /api/database/{database_id}/schema", ReqPerm(repo.AccessView|repo.AccessCreate|repo.AccessEdit|repo.AccessDelete|repo.AccessAdmin, h.DatabaseHandler.GetEntrySchema))
+	mux.Handle("GET /api/database/{database_id}/integrity", ReqPerm(repo.AccessView|repo.AccessCreate|repo.AccessEdit|repo.AccessDelete|repo.AccessAdmin, h.DatabaseHandler.GetIntegrity))
 
 	// Bulk Operations (List/Search/Export/Import)
-	mux.Handle("GET /api/database/{database_id}/entries", ReqPerm(repo.AccessView, h.EntryHandler.QueryEntries))
-	mux.Handle("POST /api/database/{database_id}/entries/search", ReqPerm(repo.AccessView, h.EntryHandler.SearchEntries))
+	mux.Handle("GET /api/database/{database_id}/entries", ReqPublicRead(h.EntryHandler.QueryEntries))
+	mux.Handle("POST /api/database/{database_id}/entries/search", ReqPublicRead(h.EntryHandler.SearchEntries))
+	mux.Handle("GET /api/database/{database_id}/entries/histogram", ReqPublicRead(h.EntryHandler.GetEntryHistogram))
 	mux.Handle("POST /api/database/{database_id}/entries/export", ReqPerm(repo.AccessView, h.EntryHandler.ExportEntries))
-	mux.Handle("POST /api/database/{database_id}/entries/import", ReqPerm(repo.AccessCreate, h.EntryHandler.ImportEntries))
+	mux.Handle("POST /api/database/{database_id}/entries/facets", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryFacets))
+	mux.Handle("POST /api/database/{database_id}/entries/sprite", ReqPerm(repo.AccessView, h.EntryHandler.GetEntriesSprite))
+	mux.Handle("POST /api/database/{database_id}/entries/versions", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryVersions))
+	mux.Handle("POST /api/database/{database_id}/entries/import", ReqWrite(repo.AccessCreate, h.EntryHandler.ImportEntries))
 
 	// Single Entry Read Operations
-	mux.Handle("GET /api/database/{database_id}/entry/{id}", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryMeta))
-	mux.Handle("GET /api/database/{database_id}/entry/{id}/file", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryFile))
-	mux.Handle("GET /api/database/{database_id}/entry/{id}/preview", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryPreview))
+	mux.Handle("GET /api/database/{database_id}/entry/{id}", ReqPublicRead(h.EntryHandler.GetEntryMeta))
+	mux.Handle("GET /api/database/{database_id}/entry/{id}/file", ReqPublicRead(h.EntryHandler.GetEntryFile))
+	mux.Handle("GET /api/database/{database_id}/entry/{id}/preview", ReqPublicRead(h.EntryHandler.GetEntryPreview))
+	mux.Handle("GET /api/database/{database_id}/entry/{id}/segment", ReqPerm(repo.AccessView, h.EntryHandler.GetEntrySegment))
+	mux.Handle("GET /api/database/{database_id}/entry/{id}/progress", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryProgress))
+	mux.Handle("GET /api/database/{database_id}/external/{external_id}", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryMetaByExternalID))
+	mux.Handle("GET /api/database/{database_id}/external/{external_id}/file", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryFileByExternalID))
+	mux.Handle("GET /api/database/{database_id}/external/{external_id}/preview", ReqPerm(repo.AccessView, h.EntryHandler.GetEntryPreviewByExternalID))
+
+	// Share Links (CanView may share what it can read)
+	mux.Handle("POST /api/database/{database_id}/entry/{id}/share", ReqWrite(repo.AccessView, h.EntryHandler.CreateShareLink))
+	mux.Handle("GET /api/database/{database_id}/entry/{id}/shares", ReqPerm(repo.AccessView, h.EntryHandler.GetShareLinks))
+	mux.Handle("DELETE /api/database/{database_id}/entry/{id}/share/{share_id}", ReqWrite(repo.AccessView, h.EntryHandler.DeleteShareLink))
+
+	// Comments (CanView reads them, CanEdit writes them; only the author or an admin deletes one, checked by the handler)
+	mux.Handle("GET /api/database/{database_id}/entry/{id}/comments", ReqPerm(repo.AccessView, h.EntryHandler.GetComments))
+	mux.Handle("POST /api/database/{database_id}/entry/{id}/comments", ReqWrite(repo.AccessEdit, h.EntryHandler.CreateComment))
+	mux.Handle("DELETE /api/database/{database_id}/entry/{id}/comments/{comment_id}", ReqWrite(repo.AccessEdit, h.EntryHandler.DeleteComment))
 
 	// 4. Database Write Operations (CanCreate / CanEdit)
-	mux.Handle("POST /api/database/{database_id}/entry", ReqPerm(repo.AccessCreate, h.EntryHandler.PostEntry))
-	mux.Handle("PATCH /api/database/{database_id}/entry/{id}", ReqPerm(repo.AccessEdit, h.EntryHandler.PatchEntry))
+	mux.Handle("POST /api/database/{database_id}/entry", ReqWrite(repo.AccessCreate, h.EntryHandler.PostEntry))
+	mux.Handle("PATCH /api/database/{database_id}/entry/{id}", ReqWrite(repo.AccessEdit, h.EntryHandler.PatchEntry))
+	mux.Handle("POST /api/database/{database_id}/entry/{id}/retry", ReqWrite(repo.AccessCreate, h.EntryHandler.RetryEntry))
+	mux.Handle("POST /api/database/{database_id}/entry/{id}/preview/regenerate", ReqWrite(repo.AccessEdit, h.EntryHandler.RegenerateEntryPreview))
 
 	// 5. Database Delete Operations (CanDelete)
-	mux.Handle("POST /api/database/{database_id}/housekeeping", ReqPerm(repo.AccessDelete, h.DatabaseHandler.TriggerHousekeeping))
-	mux.Handle("POST /api/database/{database_id}/entries/delete", ReqPerm(repo.AccessDelete, h.EntryHandler.DeleteEntries))
-	mux.Handle("DELETE /api/database/{database_id}/entry/{id}", ReqPerm(repo.AccessDelete, h.EntryHandler.DeleteEntry))
-}
-
-func addFrontendRoutes(mux *http.ServeMux, frontendFS http.FileSystem, indexFile string, basePath string) {
-	fileServer := http.FileServer(frontendFS)
-
-	// Angular requires the base href to end with a trailing slash
-	if !strings.HasSuffix(basePath, "/") {
-		basePath += "/"
-	}
-
-	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
-		path := strings.TrimPrefix(r.URL.Path, "/")
-
-		// If the path is empty, they want the root index
-		if path == "" {
-			serveModifiedIndex(w, frontendFS, indexFile, basePath)
-			return
-		}
-
-		// Check if it exists AND is not a directory
-		file, err := frontendFS.Open(path)
-		if err == nil {
-			stat, statErr := file.Stat()
-			file.Close()
-
-			if statErr == nil && !stat.IsDir() {
-				// It's a real file (like .js, .css, .png). Serve it normally.
-				fileServer.ServeHTTP(w, r)
-				return
-			}
-		}
-
-		// It wasn't a physical file, so it's an Angular route.
-		// Serve the dynamically modified index.html.
-		serveModifiedIndex(w, frontendFS, indexFile, basePath)
-	})
-}
-
-// Helper function to dynamically modify and serve index.html
-func serveModifiedIndex(w http.ResponseWriter, fs http.FileSystem, indexFile, basePath string) {
-	file, err := fs.Open(indexFile)
-	if err != nil {
-		http.Error(w, "Index file not found", http.StatusInternalServerError)
-		return
-	}
-	defer file.Close()
-
-	// Read the HTML into memory
-	htmlBytes, err := io.ReadAll(file)
-	if err != nil {
-		http.Error(w, "Failed to read index file", http.StatusInternalServerError)
-		return
-	}
-
-	// Dynamically replace the base href
-	// We assume Angular built it with the standard <base href="/">
-	htmlStr := string(htmlBytes)
-	htmlStr = strings.Replace(htmlStr, `<base href="/">`, fmt.Sprintf(`<base href="%s">`, basePath), 1)
-
-	// Send it to the browser
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(htmlStr))
+	mux.Handle("POST /api/database/{database_id}/housekeeping", ReqWrite(repo.AccessDelete, h.DatabaseHandler.TriggerHousekeeping))
+	mux.Handle("POST /api/database/{database_id}/entries/delete", ReqWrite(repo.AccessDelete, h.EntryHandler.DeleteEntries))
+	mux.Handle("DELETE /api/database/{database_id}/entry/{id}", ReqWrite(repo.AccessDelete, h.EntryHandler.DeleteEntry))
 }
 
 // --- Middleware Helpers ---
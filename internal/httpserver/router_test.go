@@ -0,0 +1,933 @@
+package httpserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "mediahub_oss/docs"
+	"mediahub_oss/internal/httpserver"
+	adh "mediahub_oss/internal/httpserver/adminhandler"
+	"mediahub_oss/internal/httpserver/auth"
+	dbh "mediahub_oss/internal/httpserver/databasehandler"
+	eh "mediahub_oss/internal/httpserver/entryhandler"
+	ih "mediahub_oss/internal/httpserver/infohandler"
+	th "mediahub_oss/internal/httpserver/tokenhandler"
+	uh "mediahub_oss/internal/httpserver/userhandler"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/maintenance"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestEntryLinksResolve checks that every generated link points to a working endpoint,
+// including when the server sits behind a reverse proxy under a base URL.
+func TestEntryLinksResolve(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if _, err := r.CreateUser(ctx, repo.User{Username: "links_admin", PasswordHash: string(hash), IsAdmin: true}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "links_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{
+		FileName:    "file.bin",
+		Size:        9,
+		PreviewSize: 7,
+		Timestamp:   time.Now(),
+		MimeType:    "application/octet-stream",
+	})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("file-data"))
+	store.WritePreview(ctx, db.ID.String(), entry.ID, strings.NewReader("preview"))
+
+	const baseURL = "/mediahub"
+	h := &httpserver.Handlers{
+		EntryHandler: eh.EntryHandler{
+			Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Auditor: audit.NewAlNoopLogger(),
+			Repo:    r,
+			Storage: store,
+			BaseURL: baseURL,
+		},
+	}
+	router := httpserver.SetupRouter(h, http.Dir(t.TempDir()), auth.NewAuthMiddleware(r, testKeyring(t)), "/", "", nil)
+	// The reverse proxy strips the base URL before forwarding
+	proxy := http.StripPrefix(baseURL, router)
+
+	get := func(target string, method string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetBasicAuth("links_admin", "secret")
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 1. All entry endpoints return the same links
+	prefix := baseURL + "/api/database/" + db.ID.String()
+	var fromList, fromSearch []eh.EntryResponse
+	var fromMeta eh.EntryResponse
+	for _, tc := range []struct {
+		method, target, body string
+		into                 any
+	}{
+		{"GET", prefix + "/entries?include_links=true", "", &fromList},
+		{"POST", prefix + "/entries/search?include_links=true", `{"pagination":{"limit":10}}`, &fromSearch},
+		{"GET", prefix + "/entry/" + strconv.FormatInt(entry.ID, 10) + "?include_links=true", "", &fromMeta},
+	} {
+		rec := get(tc.target, tc.method, tc.body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d: %s", tc.method, tc.target, rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), tc.into); err != nil {
+			t.Fatalf("failed to decode response of %s: %v", tc.target, err)
+		}
+	}
+	if len(fromList) != 1 || len(fromSearch) != 1 || fromMeta.Links == nil {
+		t.Fatalf("expected links in every response, got %+v / %+v / %+v", fromList, fromSearch, fromMeta)
+	}
+	links := *fromMeta.Links
+	if *fromList[0].Links != links || *fromSearch[0].Links != links {
+		t.Errorf("expected identical links, got %+v / %+v / %+v", *fromList[0].Links, *fromSearch[0].Links, links)
+	}
+
+	// 2. Each link reaches its endpoint (the SPA fallback would also answer 200, so check the bodies)
+	if rec := get(links.File, "GET", ""); rec.Code != http.StatusOK || rec.Body.String() != "file-data" {
+		t.Errorf("file link %s: got %d %q", links.File, rec.Code, rec.Body.String())
+	}
+	if rec := get(links.Preview, "GET", ""); rec.Code != http.StatusOK || rec.Body.String() != "preview" {
+		t.Errorf("preview link %s: got %d %q", links.Preview, rec.Code, rec.Body.String())
+	}
+	var meta eh.EntryResponse
+	if rec := get(links.Meta, "GET", ""); rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &meta) != nil || meta.EntryID != entry.ID {
+		t.Errorf("meta link %s: got %d %q", links.Meta, rec.Code, rec.Body.String())
+	}
+	if rec := get(links.Shares, "GET", ""); rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "[") {
+		t.Errorf("shares link %s: got %d %q", links.Shares, rec.Code, rec.Body.String())
+	}
+
+	// 3. Without the query parameter, the block is omitted
+	if rec := get(prefix+"/entries", "GET", ""); strings.Contains(rec.Body.String(), "_links") {
+		t.Errorf("expected no _links without include_links, got %s", rec.Body.String())
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	router := httpserver.SetupRouter(&httpserver.Handlers{}, http.Dir(t.TempDir()), auth.NewAuthMiddleware(nil, testKeyring(t)), "/", "", nil)
+
+	tests := []struct {
+		method, path string
+		wantCode     int
+		wantAllow    string
+	}{
+		{"PUT", "/api/database/01ARZ3NDEKTSV4RRFFQ69G5FAV/entry", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{"PUT", "/api/database", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{"GET", "/api/user", http.StatusMethodNotAllowed, "POST, OPTIONS"},
+		{"POST", "/api/user/01ARZ3NDEKTSV4RRFFQ69G5FAV", http.StatusMethodNotAllowed, "GET, HEAD, PATCH, DELETE, OPTIONS"},
+		{"OPTIONS", "/api/database", http.StatusNoContent, "POST, OPTIONS"},
+		{"GET", "/api/does-not-exist", http.StatusNotFound, ""},
+		{"DELETE", "/api/does-not-exist", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.wantCode {
+			t.Errorf("%s %s: expected %d, got %d", tc.method, tc.path, tc.wantCode, rec.Code)
+		}
+		if allow := rec.Header().Get("Allow"); allow != tc.wantAllow {
+			t.Errorf("%s %s: expected Allow %q, got %q", tc.method, tc.path, tc.wantAllow, allow)
+		}
+		if tc.wantCode == http.StatusNoContent {
+			continue
+		}
+
+		// Errors use the standard ErrorResponse shape
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+			t.Errorf("%s %s: expected a JSON error body, got %q", tc.method, tc.path, rec.Body.String())
+		}
+	}
+}
+
+// TestSwaggerDocOrigin checks that the swagger UI sends its requests to the address the client used,
+// taken from the forwarding headers only if they come from a trusted proxy.
+func TestSwaggerDocOrigin(t *testing.T) {
+	am := auth.NewAuthMiddleware(nil, testKeyring(t))
+	am.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	h := &httpserver.Handlers{EntryHandler: eh.EntryHandler{BaseURL: "/mediahub"}}
+	router := httpserver.SetupRouter(h, http.Dir(t.TempDir()), am, "/mediahub/", "/mediahub", nil)
+
+	for _, tc := range []struct {
+		name, remoteAddr                 string
+		wantHost, wantScheme, wantPrefix string
+	}{
+		{"trusted proxy", "10.0.0.1:5000", "media.example.com", "https", "/mediahub/api"},
+		{"untrusted peer", "203.0.113.7:5000", "backend:8080", "http", "/mediahub/api"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "http://backend:8080/mediahub/swagger/doc.json", nil)
+		req.RemoteAddr = tc.remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "media.example.com")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var doc struct {
+			Host     string   `json:"host"`
+			Schemes  []string `json:"schemes"`
+			BasePath string   `json:"basePath"`
+		}
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &doc) != nil {
+			t.Fatalf("%s: expected the swagger document, got %d: %.200s", tc.name, rec.Code, rec.Body.String())
+		}
+		if doc.Host != tc.wantHost || !slices.Equal(doc.Schemes, []string{tc.wantScheme}) || doc.BasePath != tc.wantPrefix {
+			t.Errorf("%s: expected %s://%s%s, got %+v", tc.name, tc.wantScheme, tc.wantHost, tc.wantPrefix, doc)
+		}
+	}
+}
+
+// TestRoutePrefix checks that every route group is served below the configured prefix
+// and that nothing answers outside of it, except the redirect of the root.
+func TestRoutePrefix(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if _, err := r.CreateUser(ctx, repo.User{Username: "prefix_admin", PasswordHash: string(hash), IsAdmin: true}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "prefix_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	frontendDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(frontendDir, "index.html"), []byte(`<html><head><base href="/"></head></html>`), 0o600); err != nil {
+		t.Fatalf("failed to write index: %v", err)
+	}
+
+	const prefix = "/mediahub"
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := &httpserver.Handlers{
+		EntryHandler:    eh.EntryHandler{Logger: logger, Auditor: audit.NewAlNoopLogger(), Repo: r, BaseURL: prefix},
+		DatabaseHandler: dbh.DatabaseHandler{Logger: logger, Auditor: audit.NewAlNoopLogger(), Repo: r},
+		UserHandler:     uh.UserHandler{Logger: logger, Auditor: audit.NewAlNoopLogger(), Repo: r},
+	}
+	router := httpserver.SetupRouter(h, http.Dir(frontendDir), auth.NewAuthMiddleware(r, testKeyring(t)), prefix+"/", prefix, nil)
+
+	tests := []struct {
+		name, path string
+		wantCode   int
+		wantBody   string
+	}{
+		{"public", prefix + "/health", http.StatusOK, "OK"},
+		{"authenticated", prefix + "/api/me", http.StatusOK, `"prefix_admin"`},
+		{"admin", prefix + "/api/users", http.StatusOK, `"prefix_admin"`},
+		{"database", prefix + "/api/databases", http.StatusOK, `"prefix_test"`},
+		{"entry", prefix + "/api/database/" + db.ID.String() + "/entries", http.StatusOK, "[]"},
+		{"api fallback", prefix + "/api/does-not-exist", http.StatusNotFound, ""},
+		{"frontend", prefix + "/", http.StatusOK, `<base href="/mediahub/">`},
+		{"frontend route", prefix + "/databases", http.StatusOK, `<base href="/mediahub/">`},
+		{"root redirect", "/", http.StatusFound, ""},
+		{"outside the prefix", "/api/me", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.SetBasicAuth("prefix_admin", "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.wantCode {
+			t.Errorf("%s: GET %s: expected %d, got %d: %s", tc.name, tc.path, tc.wantCode, rec.Code, rec.Body.String())
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), tc.wantBody) {
+			t.Errorf("%s: GET %s: expected body containing %q, got %q", tc.name, tc.path, tc.wantBody, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if location := rec.Header().Get("Location"); location != prefix+"/" {
+		t.Errorf("expected a redirect to %s/, got %q", prefix, location)
+	}
+}
+
+func testKeyring(t *testing.T) *auth.Keyring {
+	t.Helper()
+	keys, err := auth.NewKeyring("test-secret")
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	return keys
+}
+
+// fakeWorkers stands in for the processor, it counts the running workers and records the pauses.
+type fakeWorkers struct {
+	running atomic.Int32
+	paused  atomic.Bool
+}
+
+func (f *fakeWorkers) ActiveWorkers() int    { return int(f.running.Load()) }
+func (f *fakeWorkers) SetPaused(paused bool) { f.paused.Store(paused) }
+
+// TestMaintenanceMode checks that the maintenance mode rejects the write routes but not the reads,
+// that enabling it waits for the running workers, and that it survives a restart.
+func TestMaintenanceMode(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if _, err := r.CreateUser(ctx, repo.User{Username: "maintenance_admin", PasswordHash: string(hash), IsAdmin: true}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "maintenance_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "file.bin", Timestamp: time.Now(), MimeType: "application/octet-stream"})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	workers := &fakeWorkers{}
+	mode := maintenance.NewMode(r, logger, workers, workers)
+	h := &httpserver.Handlers{
+		InfoHandler:     ih.InfoHandler{Maintenance: mode},
+		EntryHandler:    eh.EntryHandler{Logger: logger, Auditor: audit.NewAlNoopLogger(), Repo: r},
+		DatabaseHandler: dbh.DatabaseHandler{Logger: logger, Auditor: audit.NewAlNoopLogger(), Repo: r},
+		AdminHandler:    adh.AdminHandler{Logger: logger, Auditor: audit.NewAlNoopLogger(), Maintenance: mode},
+		Maintenance:     mode,
+	}
+	router := httpserver.SetupRouter(h, http.Dir(t.TempDir()), auth.NewAuthMiddleware(r, testKeyring(t)), "/", "", nil)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetBasicAuth("maintenance_admin", "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	setMaintenance := func(body string) adh.MaintenanceResponse {
+		rec := do("POST", "/api/admin/maintenance", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 from the maintenance endpoint, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp adh.MaintenanceResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode maintenance response: %v", err)
+		}
+		return resp
+	}
+
+	prefix := "/api/database/" + db.ID.String()
+	entryPath := prefix + "/entry/" + strconv.FormatInt(entry.ID, 10)
+	writes := []struct{ method, target, body string }{
+		{"POST", prefix + "/entry", ""},
+		{"PATCH", entryPath, `{"filename":"renamed.bin"}`},
+		{"DELETE", entryPath, ""},
+		{"POST", prefix + "/entries/delete", `{"ids":[1]}`},
+		{"POST", prefix + "/entries/import", ""},
+		{"PUT", prefix, `{"name":"renamed"}`},
+		{"POST", prefix + "/field", `{"name":"extra","type":"TEXT"}`},
+		{"POST", "/api/database", `{"name":"another","content_type":"file"}`},
+		{"DELETE", prefix, ""},
+	}
+	reads := []struct{ method, target, body string }{
+		{"GET", "/api/databases", ""},
+		{"GET", prefix, ""},
+		{"GET", entryPath, ""},
+		{"GET", prefix + "/entries", ""},
+		{"POST", prefix + "/entries/search", `{"pagination":{"limit":10}}`},
+	}
+
+	// 1. Enabling waits for the running workers and pauses the background work
+	workers.running.Store(2)
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		workers.running.Add(-1)
+		time.Sleep(150 * time.Millisecond)
+		workers.running.Add(-1)
+	}()
+	resp := setMaintenance(`{"enabled": true, "drain_timeout": "5s", "message": "snapshot in progress"}`)
+	if !resp.Enabled || resp.Message != "snapshot in progress" || resp.Since == 0 || resp.RunningWorkers != 0 {
+		t.Errorf("expected maintenance with all workers drained, got %+v", resp)
+	}
+	if !workers.paused.Load() {
+		t.Error("expected the background work to be paused")
+	}
+
+	// 2. Writes are rejected with the message and Retry-After, reads keep working
+	for _, tc := range writes {
+		rec := do(tc.method, tc.target, tc.body)
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "snapshot in progress") {
+			t.Errorf("%s %s: expected 503 with the message, got %d: %s", tc.method, tc.target, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("%s %s: expected Retry-After 60, got %q", tc.method, tc.target, rec.Header().Get("Retry-After"))
+		}
+	}
+	for _, tc := range reads {
+		if rec := do(tc.method, tc.target, tc.body); rec.Code != http.StatusOK {
+			t.Errorf("%s %s: expected 200 during maintenance, got %d: %s", tc.method, tc.target, rec.Code, rec.Body.String())
+		}
+	}
+	if _, err := r.GetEntry(ctx, db.ID, entry.ID); err != nil {
+		t.Errorf("expected the entry to survive the rejected delete, got %v", err)
+	}
+
+	var info ih.InfoResponse
+	if rec := do("GET", "/api/info", ""); json.Unmarshal(rec.Body.Bytes(), &info) != nil || !info.Maintenance.Enabled || info.Maintenance.Message != "snapshot in progress" {
+		t.Errorf("expected the maintenance flag in the info, got %s", rec.Body.String())
+	}
+
+	// 3. A worker outliving the drain timeout is reported, enabling again keeps the start time
+	workers.running.Store(1)
+	again := setMaintenance(`{"enabled": true, "drain_timeout": "1s", "message": "still snapshotting"}`)
+	if again.RunningWorkers != 1 || again.Since != resp.Since || again.Message != "still snapshotting" {
+		t.Errorf("expected one running worker and the original start time, got %+v", again)
+	}
+	workers.running.Store(0)
+
+	// 4. A restart stays in maintenance
+	restarted := maintenance.NewMode(r, logger, nil)
+	if err := restarted.Restore(ctx); err != nil {
+		t.Fatalf("failed to restore maintenance mode: %v", err)
+	}
+	if state := restarted.State(); !state.Enabled || state.Message != "still snapshotting" || state.Since.UnixMilli() != resp.Since {
+		t.Errorf("expected the persisted maintenance mode, got %+v", state)
+	}
+
+	// 5. Disabling accepts writes again and resumes the background work
+	if resp := setMaintenance(`{"enabled": false}`); resp.Enabled || resp.Since != 0 {
+		t.Errorf("expected maintenance to be disabled, got %+v", resp)
+	}
+	if workers.paused.Load() {
+		t.Error("expected the background work to be resumed")
+	}
+	if rec := do("PATCH", entryPath, `{"filename":"renamed.bin"}`); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 after maintenance, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("GET", "/api/info", ""); strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Errorf("expected the maintenance flag to be cleared, got %s", rec.Body.String())
+	}
+}
+
+// recordingAuditor keeps all audit events.
+type recordingAuditor struct {
+	mu      sync.Mutex
+	actors  []string
+	actions []string
+	details []map[string]any
+}
+
+func (a *recordingAuditor) Log(ctx context.Context, action string, actor string, resource string, details map[string]any) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.actors = append(a.actors, actor)
+	a.actions = append(a.actions, action)
+	a.details = append(a.details, details)
+}
+
+// TestPublicRead checks that callers without credentials can read databases with public_read, and nothing else.
+func TestPublicRead(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if _, err := r.CreateUser(ctx, repo.User{Username: "public_admin", PasswordHash: string(hash), IsAdmin: true}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	dbAdmin, err := r.CreateUser(ctx, repo.User{Username: "public_db_admin", PasswordHash: string(hash)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	var public, private repo.Database
+	var publicEntry, privateEntry repo.Entry
+	for _, target := range []struct {
+		db    *repo.Database
+		entry *repo.Entry
+		name  string
+	}{{&public, &publicEntry, "public_test"}, {&private, &privateEntry, "private_test"}} {
+		if *target.db, err = r.CreateDatabase(ctx, repo.Database{Name: target.name, ContentType: "file"}); err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		if *target.entry, err = r.CreateEntry(ctx, *target.db, repo.Entry{FileName: "file.bin", Size: 9, PreviewSize: 7, Timestamp: time.Now(), MimeType: "application/octet-stream"}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		store.Write(ctx, target.db.ID.String(), target.entry.ID, strings.NewReader("file-data"))
+		store.WritePreview(ctx, target.db.ID.String(), target.entry.ID, strings.NewReader("preview"))
+	}
+	if err := r.SetUserPermissions(ctx, repo.UserPermissions{UserID: dbAdmin.ID, DatabaseID: public.ID, Roles: repo.NewAccessGrant(true, true, true, true, true)}); err != nil {
+		t.Fatalf("failed to set permissions: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	auditor := &recordingAuditor{}
+	h := &httpserver.Handlers{
+		EntryHandler:    eh.EntryHandler{Logger: logger, Auditor: auditor, Repo: r, Storage: store},
+		DatabaseHandler: dbh.DatabaseHandler{Logger: logger, Auditor: auditor, Repo: r, Storage: store},
+	}
+	am := auth.NewAuthMiddleware(r, testKeyring(t))
+	am.AnonymousLimiter = auth.NewIPRateLimiter(1000, time.Minute)
+	router := httpserver.SetupRouter(h, http.Dir(t.TempDir()), am, "/", "", nil)
+
+	do := func(method, target, body, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = "203.0.113.7:4711"
+		if username != "" {
+			req.SetBasicAuth(username, "secret")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	reads := func(db repo.Database, entry repo.Entry) []struct{ method, target, body string } {
+		prefix := "/api/database/" + db.ID.String()
+		entryPath := prefix + "/entry/" + strconv.FormatInt(entry.ID, 10)
+		return []struct{ method, target, body string }{
+			{"GET", prefix + "/entries", ""},
+			{"POST", prefix + "/entries/search", `{"pagination":{"limit":10}}`},
+			{"GET", entryPath, ""},
+			{"GET", entryPath + "/file", ""},
+			{"GET", entryPath + "/preview", ""},
+		}
+	}
+
+	// 1. Only global admins may publish a database
+	if rec := do("PUT", "/api/database/"+public.ID.String(), `{"config": {"public_read": true}}`, "public_db_admin"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a database admin changing public_read, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("PUT", "/api/database/"+public.ID.String(), `{"config": {"public_read": true}}`, "public_admin"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"public_read":true`) {
+		t.Fatalf("expected the admin to publish the database, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("PUT", "/api/database/"+public.ID.String(), `{"name": "public_renamed"}`, "public_db_admin"); rec.Code != http.StatusOK {
+		t.Errorf("expected a database admin to keep changing other settings, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 2. Anonymous reads of the public database work, of the private one they need authentication
+	for _, tc := range reads(public, publicEntry) {
+		if rec := do(tc.method, tc.target, tc.body, ""); rec.Code != http.StatusOK {
+			t.Errorf("public %s %s: expected 200, got %d: %s", tc.method, tc.target, rec.Code, rec.Body.String())
+		}
+	}
+	for _, tc := range reads(private, privateEntry) {
+		if rec := do(tc.method, tc.target, tc.body, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("private %s %s: expected 401, got %d: %s", tc.method, tc.target, rec.Code, rec.Body.String())
+		}
+		if rec := do(tc.method, tc.target, tc.body, "public_admin"); rec.Code != http.StatusOK {
+			t.Errorf("private %s %s: expected 200 with credentials, got %d: %s", tc.method, tc.target, rec.Code, rec.Body.String())
+		}
+	}
+
+	// 3. Writes and other reads of the public database still need authentication
+	publicPrefix := "/api/database/" + public.ID.String()
+	for _, tc := range []struct{ method, target string }{
+		{"POST", publicPrefix + "/entry"},
+		{"PATCH", publicPrefix + "/entry/" + strconv.FormatInt(publicEntry.ID, 10)},
+		{"DELETE", publicPrefix + "/entry/" + strconv.FormatInt(publicEntry.ID, 10)},
+		{"POST", publicPrefix + "/entries/delete"},
+		{"POST", publicPrefix + "/entries/export"},
+		{"GET", publicPrefix},
+	} {
+		if rec := do(tc.method, tc.target, "{}", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: expected 401 without credentials, got %d", tc.method, tc.target, rec.Code)
+		}
+	}
+	if rec := do("GET", publicPrefix+"/entries", "", "nobody"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected invalid credentials to be rejected, got %d", rec.Code)
+	}
+
+	// 4. The database list shows anonymous callers the public database only
+	rec := do("GET", "/api/databases", "", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), public.ID.String()) || strings.Contains(rec.Body.String(), private.ID.String()) {
+		t.Errorf("expected only the public database, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 5. Anonymous reads are audit logged with the client IP
+	auditor.mu.Lock()
+	anonymous := 0
+	for _, actor := range auditor.actors {
+		if actor == "anonymous:203.0.113.7" {
+			anonymous++
+		}
+	}
+	auditor.mu.Unlock()
+	if anonymous < len(reads(public, publicEntry)) {
+		t.Errorf("expected the anonymous reads in the audit log, got actors %v", auditor.actors)
+	}
+
+	// 6. Anonymous traffic is rate limited per client IP, authenticated traffic is not
+	am.AnonymousLimiter = auth.NewIPRateLimiter(2, time.Minute)
+	for i := range 3 {
+		rec := do("GET", publicPrefix+"/entries", "", "")
+		if want := http.StatusOK; i == 2 {
+			if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+				t.Errorf("expected 429 with Retry-After once the limit is reached, got %d", rec.Code)
+			}
+		} else if rec.Code != want {
+			t.Errorf("request %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	if rec := do("GET", publicPrefix+"/entries", "", "public_admin"); rec.Code != http.StatusOK {
+		t.Errorf("expected authenticated requests to bypass the limit, got %d", rec.Code)
+	}
+}
+
+// TestLegalHold checks that held entries survive all user deletions until they are released.
+func TestLegalHold(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if _, err := r.CreateUser(ctx, repo.User{Username: "hold_admin", PasswordHash: string(hash), IsAdmin: true}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	dbAdmin, err := r.CreateUser(ctx, repo.User{Username: "hold_db_admin", PasswordHash: string(hash)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "hold_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	var ids []int64
+	for range 3 {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "file.bin", Size: 4, Timestamp: time.Now(), MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data"))
+		ids = append(ids, entry.ID)
+	}
+	if err := r.SetUserPermissions(ctx, repo.UserPermissions{UserID: dbAdmin.ID, DatabaseID: db.ID, Roles: repo.NewAccessGrant(true, true, true, true, true)}); err != nil {
+		t.Fatalf("failed to set permissions: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	auditor := &recordingAuditor{}
+	h := &httpserver.Handlers{
+		EntryHandler:    eh.EntryHandler{Logger: logger, Auditor: auditor, Repo: r, Storage: store},
+		DatabaseHandler: dbh.DatabaseHandler{Logger: logger, Auditor: auditor, Repo: r, Storage: store},
+	}
+	router := httpserver.SetupRouter(h, http.Dir(t.TempDir()), auth.NewAuthMiddleware(r, testKeyring(t)), "/", "", nil)
+
+	do := func(method, target, body, username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.SetBasicAuth(username, "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	prefix := "/api/database/" + db.ID.String()
+	entryPath := func(id int64) string { return prefix + "/entry/" + strconv.FormatInt(id, 10) }
+	held := ids[0]
+
+	// 1. Only global admins may hold entries, and only with a reason
+	holdBody := fmt.Sprintf(`{"database_id": %q, "ids": [%d, 999], "reason": "case 4711"}`, db.ID, held)
+	if rec := do("POST", "/api/entry/hold", holdBody, "hold_db_admin"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a database admin, got %d", rec.Code)
+	}
+	if rec := do("POST", "/api/entry/hold", fmt.Sprintf(`{"database_id": %q, "ids": [%d], "reason": " "}`, db.ID, held), "hold_admin"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a reason, got %d", rec.Code)
+	}
+	rec := do("POST", "/api/entry/hold", holdBody, "hold_admin")
+	var holdResp eh.LegalHoldResponse
+	json.NewDecoder(rec.Body).Decode(&holdResp)
+	if rec.Code != http.StatusOK || !holdResp.LegalHold || !slices.Equal(holdResp.Updated, []int64{held}) || !slices.Equal(holdResp.Missing, []int64{999}) {
+		t.Fatalf("expected the entry to be held and 999 missing, got %d %+v", rec.Code, holdResp)
+	}
+	auditor.mu.Lock()
+	last := len(auditor.actions) - 1
+	if auditor.actions[last] != "entry.legal_hold.set" || auditor.details[last]["reason"] != "case 4711" {
+		t.Errorf("expected the hold to be audited with its reason, got %s %v", auditor.actions[last], auditor.details[last])
+	}
+	auditor.mu.Unlock()
+
+	// 2. The hold is part of the metadata and searchable
+	if rec := do("GET", entryPath(held), "", "hold_db_admin"); !strings.Contains(rec.Body.String(), `"legal_hold":true`) {
+		t.Errorf("expected legal_hold in the metadata, got %s", rec.Body.String())
+	}
+	rec = do("POST", prefix+"/entries/search", `{"filter": {"operator": "and", "conditions": [{"field": "legal_hold", "operator": "=", "value": true}]}}`, "hold_db_admin")
+	var found []eh.EntryResponse
+	json.NewDecoder(rec.Body).Decode(&found)
+	if rec.Code != http.StatusOK || len(found) != 1 || found[0].EntryID != held {
+		t.Errorf("expected the held entry in the search, got %d %+v", rec.Code, found)
+	}
+
+	// 3. User deletions are refused
+	if rec := do("DELETE", entryPath(held), "", "hold_db_admin"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 deleting a held entry, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = do("POST", prefix+"/entries/delete", fmt.Sprintf(`{"ids": [%d, %d]}`, ids[1], held), "hold_db_admin")
+	var bulk eh.BulkDeleteResponse
+	json.NewDecoder(rec.Body).Decode(&bulk)
+	if rec.Code != http.StatusForbidden || bulk.DeletedCount != 0 || !slices.Equal(bulk.Held, []int64{held}) {
+		t.Errorf("expected the bulk delete to be refused with the held ID, got %d %+v", rec.Code, bulk)
+	}
+	if _, err := r.GetEntry(ctx, db.ID, ids[1]); err != nil {
+		t.Errorf("expected the refused batch to keep all entries, got %v", err)
+	}
+
+	// 4. The database is only deleted with force and its name
+	if rec := do("DELETE", prefix, "", "hold_admin"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 deleting a database with held entries, got %d", rec.Code)
+	}
+	if rec := do("DELETE", prefix+"?force=true&confirm=wrong", "", "hold_admin"); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 with a wrong confirmation, got %d", rec.Code)
+	}
+
+	// 5. Released entries can be deleted again
+	if rec := do("DELETE", "/api/entry/hold", fmt.Sprintf(`{"database_id": %q, "ids": [%d], "reason": "case closed"}`, db.ID, held), "hold_admin"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the release to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("DELETE", entryPath(held), "", "hold_db_admin"); rec.Code != http.StatusOK {
+		t.Errorf("expected the released entry to be deleted, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Holding again and forcing the deletion removes the database
+	do("POST", "/api/entry/hold", fmt.Sprintf(`{"database_id": %q, "ids": [%d], "reason": "case 4712"}`, db.ID, ids[1]), "hold_admin")
+	if rec := do("DELETE", prefix+"?force=true&confirm=hold_test", "", "hold_admin"); rec.Code != http.StatusOK {
+		t.Errorf("expected the forced deletion to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestBasicAuthDisabled checks the JWT-only operation: Basic credentials are rejected on the protected routes,
+// POST /api/token accepts them unless the JSON login replaces them, and the swagger document follows.
+func TestBasicAuthDisabled(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if _, err := r.CreateUser(ctx, repo.User{Username: "jwt_user", PasswordHash: string(hash)}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tc := range []struct {
+		name                      string
+		basicAuth, tokenJSONLogin bool
+		wantMe, wantBasicToken    int
+		wantJSONToken             int
+	}{
+		{"basic auth enabled", true, false, http.StatusOK, http.StatusOK, http.StatusUnauthorized},
+		{"basic auth only at the token endpoint", false, false, http.StatusUnauthorized, http.StatusOK, http.StatusUnauthorized},
+		{"basic auth fully disabled", false, true, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusOK},
+	} {
+		tokenBasicAuth := tc.basicAuth || !tc.tokenJSONLogin
+		keys := testKeyring(t)
+		h := &httpserver.Handlers{
+			InfoHandler: ih.InfoHandler{Auth: ih.NewAuthInfo(tc.basicAuth, tokenBasicAuth, tc.tokenJSONLogin, false)},
+			UserHandler: uh.UserHandler{Logger: logger, Auditor: audit.NewAlNoopLogger(), Repo: r},
+			TokenHandler: th.TokenHandler{
+				Logger: logger, Auditor: audit.NewAlNoopLogger(), Repo: r, Keys: keys, AccessDuration: time.Minute, RefreshDuration: time.Hour,
+				BasicAuthDisabled: !tokenBasicAuth, JSONLogin: tc.tokenJSONLogin,
+			},
+		}
+		am := auth.NewAuthMiddleware(r, keys)
+		am.BasicAuthDisabled = !tc.basicAuth
+		router := httpserver.SetupRouter(h, http.Dir(t.TempDir()), am, "/", "", nil)
+
+		do := func(method, target, body string, basic bool, bearer string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, strings.NewReader(body))
+			if body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if basic {
+				req.SetBasicAuth("jwt_user", "secret")
+			}
+			if bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+bearer)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			return rec
+		}
+
+		// 1. Basic credentials on a protected route, the challenge only advertises Bearer without Basic Auth
+		rec := do(http.MethodGet, "/api/me", "", true, "")
+		if rec.Code != tc.wantMe {
+			t.Errorf("%s: expected %d for Basic Auth on /api/me, got %d", tc.name, tc.wantMe, rec.Code)
+		}
+		if challenge := rec.Header().Get("WWW-Authenticate"); !tc.basicAuth && !strings.HasPrefix(challenge, "Bearer") {
+			t.Errorf("%s: expected a Bearer challenge, got %q", tc.name, challenge)
+		}
+
+		// 2. The token endpoint, with Basic Auth and with the JSON body
+		if rec := do(http.MethodPost, "/api/token", "", true, ""); rec.Code != tc.wantBasicToken {
+			t.Errorf("%s: expected %d for Basic Auth on /api/token, got %d: %s", tc.name, tc.wantBasicToken, rec.Code, rec.Body.String())
+		}
+		rec = do(http.MethodPost, "/api/token", `{"username":"jwt_user","password":"secret"}`, false, "")
+		if rec.Code != tc.wantJSONToken {
+			t.Errorf("%s: expected %d for the JSON login, got %d: %s", tc.name, tc.wantJSONToken, rec.Code, rec.Body.String())
+		}
+		if tc.tokenJSONLogin {
+			if rec := do(http.MethodPost, "/api/token", `{"username":"jwt_user","password":"wrong"}`, false, ""); rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s: expected 401 for a wrong password, got %d", tc.name, rec.Code)
+			}
+			if rec := do(http.MethodPost, "/api/token", `{"username":"jwt_user","password":"secret"}`, true, ""); rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400 for two methods, got %d", tc.name, rec.Code)
+			}
+		}
+
+		// 3. The obtained JWT works in every mode
+		var tokens th.TokenResponse
+		if tokenBasicAuth {
+			rec = do(http.MethodPost, "/api/token", "", true, "")
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil || tokens.AccessToken == "" {
+			t.Fatalf("%s: expected a token pair, got %s", tc.name, rec.Body.String())
+		}
+		if rec := do(http.MethodGet, "/api/me", "", false, tokens.AccessToken); rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 with the JWT, got %d", tc.name, rec.Code)
+		}
+
+		// 4. The info endpoint advertises the active methods
+		var info ih.InfoResponse
+		if err := json.Unmarshal(do(http.MethodGet, "/api/info", "", false, "").Body.Bytes(), &info); err != nil {
+			t.Fatalf("%s: failed to decode info: %v", tc.name, err)
+		}
+		if slices.Contains(info.Auth.Methods, "basic") != tc.basicAuth || slices.Contains(info.Auth.TokenLogin, "basic") != tokenBasicAuth || slices.Contains(info.Auth.TokenLogin, "json") != tc.tokenJSONLogin {
+			t.Errorf("%s: unexpected auth methods %+v", tc.name, info.Auth)
+		}
+
+		// 5. The swagger document only offers Basic Auth where it is accepted
+		rec = do(http.MethodGet, "/swagger/doc.json", "", false, "")
+		var doc struct {
+			Paths               map[string]map[string]struct{ Security []map[string][]string }
+			SecurityDefinitions map[string]any
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("%s: failed to decode the swagger document: %v", tc.name, err)
+		}
+		if _, ok := doc.SecurityDefinitions["BasicAuth"]; ok != tokenBasicAuth {
+			t.Errorf("%s: expected the BasicAuth definition %v, got %v", tc.name, tokenBasicAuth, ok)
+		}
+		for path, operations := range doc.Paths {
+			for method, op := range operations {
+				for _, requirement := range op.Security {
+					if _, ok := requirement["BasicAuth"]; ok && !tc.basicAuth && !(path == "/api/token" && tokenBasicAuth) {
+						t.Errorf("%s: %s %s still requires BasicAuth", tc.name, method, path)
+					}
+				}
+			}
+		}
+	}
+}
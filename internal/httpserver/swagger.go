@@ -0,0 +1,93 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"mediahub_oss/internal/httpserver/utils"
+
+	"github.com/swaggo/swag"
+)
+
+// swaggerDoc serves the OpenAPI document of the swagger UI with the scheme, host and path the client used,
+// so "Try it out" sends its requests through the reverse proxy instead of to the backend address.
+// baseURL is the external prefix of the server (server.base_url or the route prefix), see utils.AbsoluteURL.
+// The security of the operations follows whether Basic Auth is accepted on the protected routes and by the token endpoint.
+func swaggerDoc(baseURL string, basicAuth, tokenBasicAuth bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		spec, ok := swag.GetSwagger(swag.Name).(*swag.Spec)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		external, err := url.Parse(utils.AbsoluteURL(r, baseURL, ""))
+		if err != nil {
+			http.Error(w, "Invalid base URL", http.StatusInternalServerError)
+			return
+		}
+
+		doc := *spec
+		doc.Host = external.Host
+		doc.Schemes = []string{external.Scheme}
+		doc.BasePath = strings.TrimRight(external.Path, "/") + "/api"
+
+		content := doc.ReadDoc()
+		if !basicAuth || !tokenBasicAuth {
+			if content, err = withoutBasicAuth(content, tokenBasicAuth); err != nil {
+				http.Error(w, "Invalid swagger document", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		io.WriteString(w, content)
+	}
+}
+
+// withoutBasicAuth replaces the BasicAuth security of the operations by BearerAuth. The token endpoint keeps
+// it if tokenBasicAuth is set, otherwise the definition is removed as well.
+func withoutBasicAuth(content string, tokenBasicAuth bool) (string, error) {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return "", err
+	}
+
+	paths, _ := doc["paths"].(map[string]any)
+	for path, item := range paths {
+		operations, _ := item.(map[string]any)
+		for _, op := range operations {
+			operation, _ := op.(map[string]any)
+			security, _ := operation["security"].([]any)
+			if len(security) == 0 || (tokenBasicAuth && path == "/api/token") {
+				continue
+			}
+
+			var replaced []any
+			bearer := false
+			for _, requirement := range security {
+				req, _ := requirement.(map[string]any)
+				if _, ok := req["BasicAuth"]; ok {
+					req = map[string]any{"BearerAuth": []any{}}
+				}
+				if _, ok := req["BearerAuth"]; ok {
+					if bearer {
+						continue
+					}
+					bearer = true
+				}
+				replaced = append(replaced, req)
+			}
+			operation["security"] = replaced
+		}
+	}
+
+	if definitions, ok := doc["securityDefinitions"].(map[string]any); ok && !tokenBasicAuth {
+		delete(definitions, "BasicAuth")
+	}
+
+	out, err := json.Marshal(doc)
+	return string(out), err
+}
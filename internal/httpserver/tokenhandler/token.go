@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"time"
 
+	"mediahub_oss/internal/httpserver/auth"
 	"mediahub_oss/internal/httpserver/utils"
 	"mediahub_oss/internal/logging/audit"
 	"mediahub_oss/internal/repository"
@@ -18,9 +19,13 @@ type TokenHandler struct {
 	Logger          *slog.Logger
 	Auditor         audit.AuditLogger
 	Repo            repository.Repository
-	JWTSecret       []byte
+	Keys            *auth.Keyring // new tokens are signed with the newest secret
 	AccessDuration  time.Duration
 	RefreshDuration time.Duration
+
+	// Password logins, see config.AuthMethods
+	BasicAuthDisabled bool // Basic credentials are refused
+	JSONLogin         bool // the username and password may be sent as JSON body
 }
 
 // TokenResponse defines the JSON payload for successful token generation.
@@ -29,6 +34,12 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// PasswordTokenRequest defines the JSON payload for logging in without Basic Auth.
+type PasswordTokenRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
 // TokenRequest defines the JSON payload for refreshing or logging out.
 type TokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
@@ -36,38 +47,56 @@ type TokenRequest struct {
 
 // @Summary Get a token pair
 // @Description Obtains an internal JWT Access/Refresh token pair.
-// @Description Supports two authentication methods:
-// @Description 1. Local Authentication: Send standard Basic Auth headers.
-// @Description 2. OIDC Token Exchange (commercial version only): Send a JSON body containing a valid external JWT (`idp_token`).
-// @Description Providing both methods in a single request will result in a 400 Bad Request.
+// @Description Supports three authentication methods:
+// @Description 1. Local Authentication: Send standard Basic Auth headers. Refused if both `auth.basic_auth_enabled` is false and `auth.token_json_login` is enabled.
+// @Description 2. Local Authentication with a JSON body containing `username` and `password`, if `auth.token_json_login` is enabled.
+// @Description 3. OIDC Token Exchange (commercial version only): Send a JSON body containing a valid external JWT (`idp_token`).
+// @Description The accepted methods are listed as `auth.token_login` by GET /api/info. Providing several methods in a single request will result in a 400 Bad Request.
 // @Tags token
 // @Accept json
 // @Produce json
-// @Param body body OidcTokenRequest false "OIDC Identity Provider Token (required if not using Basic Auth)"
+// @Param body body PasswordTokenRequest false "Username and password (if enabled), or an OIDC Identity Provider Token (OidcTokenRequest)"
 // @Success 200 {object} TokenResponse "Returns access and refresh tokens"
 // @Failure 400 {object} utils.ErrorResponse "Ambiguous authentication request"
-// @Failure 401 {object} utils.ErrorResponse "Invalid credentials, invalid OIDC token, or missing authentication"
+// @Failure 401 {object} utils.ErrorResponse "Invalid credentials, invalid OIDC token, missing or disabled authentication"
+// @Failure 403 {object} utils.ErrorResponse "The account is disabled"
 // @Failure 500 {object} utils.ErrorResponse "Internal server error or OIDC not available"
 // @Security BasicAuth
 // @Router /api/token [post]
 func (h *TokenHandler) GetToken(w http.ResponseWriter, r *http.Request) {
 
 	username, password, hasBasicAuth := r.BasicAuth()
+	passwordReq, hasPasswordAuth := h.checkPasswordLogin(r)
 	oidcReq, hasOIDCAuth := checkOIDC(r)
 	var user repository.User
 	var err error
 
-	// Requires either basic auth or OIDC auth
-	if hasBasicAuth && hasOIDCAuth {
-		h.Logger.Warn("Login attempt failed: ambiguous request (both Basic Auth and OIDC provided)")
+	// Requires exactly one of basic auth, the password body or OIDC auth
+	methods := 0
+	for _, has := range []bool{hasBasicAuth, hasPasswordAuth, hasOIDCAuth} {
+		if has {
+			methods++
+		}
+	}
+	if methods > 1 {
+		h.Logger.Warn("Login attempt failed: ambiguous request (several authentication methods provided)")
 		utils.RespondWithError(w, http.StatusBadRequest, "Ambiguous authentication request")
 		return
-	} else if !(hasBasicAuth || hasOIDCAuth) {
+	} else if methods == 0 {
 		utils.RespondWithError(w, http.StatusUnauthorized, "Missing authentication credentials")
 		return
 	}
 
-	if hasBasicAuth {
+	if hasBasicAuth && h.BasicAuthDisabled {
+		h.Logger.Warn("Login attempt failed: Basic Auth is disabled", "username", username)
+		utils.RespondWithError(w, http.StatusUnauthorized, "Basic Auth is disabled, send the username and password as JSON body")
+		return
+	}
+	if hasPasswordAuth {
+		username, password = passwordReq.Username, passwordReq.Password
+	}
+
+	if hasBasicAuth || hasPasswordAuth {
 		user, err = h.handleBasicAuth(r, username, password)
 		if errors.Is(err, customerrors.ErrNotFound) {
 			h.Logger.Warn("Login attempt failed: user not found", "username", username)
@@ -97,6 +126,12 @@ func (h *TokenHandler) GetToken(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if user.Disabled {
+		h.Logger.Warn("Login attempt failed: account is disabled", "username", user.Username)
+		utils.RespondWithError(w, http.StatusForbidden, "Account is disabled")
+		return
+	}
+
 	// Generate and return tokens
 	accessToken, refreshToken, err := h.generateTokens(r, user.ID)
 	if err != nil {
@@ -104,6 +139,7 @@ func (h *TokenHandler) GetToken(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to generate tokens")
 		return
 	}
+	h.recordLogin(r, user.ID)
 
 	h.Auditor.Log(r.Context(), "auth.login", user.Username, "token", nil)
 
@@ -122,6 +158,7 @@ func (h *TokenHandler) GetToken(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} TokenResponse "Returns new access and refresh tokens"
 // @Failure 400 {object} utils.ErrorResponse "Invalid request body"
 // @Failure 401 {object} utils.ErrorResponse "Invalid or expired refresh token"
+// @Failure 403 {object} utils.ErrorResponse "The account is disabled"
 // @Router /api/token/refresh [post]
 func (h *TokenHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	var req TokenRequest
@@ -147,6 +184,21 @@ func (h *TokenHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	// Delete the old token (Token Rotation)
 	_ = h.Repo.DeleteRefreshToken(r.Context(), tokenHash)
 
+	// Disabling revokes the refresh tokens, but accounts may also be disabled directly in the database
+	user, err := h.Repo.GetUserByID(r.Context(), userID)
+	if errors.Is(err, customerrors.ErrNotFound) {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
+		return
+	} else if err != nil {
+		h.Logger.Error("Failed to get the user of the refresh token", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to verify refresh token")
+		return
+	}
+	if user.Disabled {
+		utils.RespondWithError(w, http.StatusForbidden, "Account is disabled")
+		return
+	}
+
 	// Generate a fresh pair of tokens
 	accessToken, newRefreshToken, err := h.generateTokens(r, userID)
 	if err != nil {
@@ -154,6 +206,7 @@ func (h *TokenHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to generate tokens")
 		return
 	}
+	h.recordLogin(r, userID)
 
 	h.Auditor.Log(r.Context(), "auth.refresh", fmt.Sprintf("user_id:%s", userID.String()), "token", nil)
 
@@ -0,0 +1,105 @@
+package tokenhandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/auth"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestGetTokenDisabledUser(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user, err := r.CreateUser(ctx, repo.User{Username: "alice", PasswordHash: string(hash)})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	keys, err := auth.NewKeyring("secret")
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	h := &TokenHandler{
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor:         audit.NewAlNoopLogger(),
+		Repo:            r,
+		Keys:            keys,
+		AccessDuration:  time.Minute,
+		RefreshDuration: time.Hour,
+	}
+	login := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/token", nil)
+		req.SetBasicAuth("alice", "secret")
+		rec := httptest.NewRecorder()
+		h.GetToken(rec, req)
+		return rec
+	}
+	refresh := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", strings.NewReader(`{"refresh_token": "`+token+`"}`))
+		rec := httptest.NewRecorder()
+		h.RefreshToken(rec, req)
+		return rec.Code
+	}
+
+	// 1. The login is recorded when the tokens are issued
+	rec := login()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the login to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var tokens TokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("failed to decode tokens: %v", err)
+	}
+	got, err := r.GetUserByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if got.LastLoginAt.IsZero() || got.LastActivityAt.IsZero() {
+		t.Errorf("expected the login to be recorded, got %+v", got)
+	}
+
+	// 2. Disabled users neither get new tokens nor refresh their existing ones
+	user.Disabled = true
+	if _, err := r.UpdateUser(ctx, user); err != nil {
+		t.Fatalf("failed to disable user: %v", err)
+	}
+	if rec := login(); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for the disabled user, got %d", rec.Code)
+	}
+	if code := refresh(tokens.RefreshToken); code != http.StatusForbidden {
+		t.Errorf("expected the refresh to fail with 403, got %d", code)
+	}
+}
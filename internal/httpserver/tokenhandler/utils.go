@@ -1,10 +1,13 @@
 package tokenhandler
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"io"
 	"mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared/customerrors"
 	"net/http"
@@ -23,7 +26,7 @@ func (h *TokenHandler) generateTokens(r *http.Request, userID repository.ULID) (
 		"iat": time.Now().Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString(h.JWTSecret)
+	accessToken, err := token.SignedString(h.Keys.SigningSecret())
 	if err != nil {
 		return "", "", err
 	}
@@ -47,6 +50,13 @@ func (h *TokenHandler) generateTokens(r *http.Request, userID repository.ULID) (
 	return accessToken, refreshToken, nil
 }
 
+// recordLogin sets the last login of the user. A failure is only logged, the tokens are issued already.
+func (h *TokenHandler) recordLogin(r *http.Request, userID repository.ULID) {
+	if err := h.Repo.RecordUserLogin(r.Context(), userID, time.Now()); err != nil {
+		h.Logger.Warn("Failed to record the login", "user_id", userID, "error", err)
+	}
+}
+
 // hashToken takes a plaintext refresh token and returns its SHA-256 hash as a hex string.
 func hashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
@@ -72,3 +82,22 @@ func (h *TokenHandler) handleBasicAuth(r *http.Request, username, password strin
 
 	return user, nil
 }
+
+// checkPasswordLogin reads the username and password from a JSON body, if the JSON login is enabled.
+func (h *TokenHandler) checkPasswordLogin(r *http.Request) (PasswordTokenRequest, bool) {
+	var req PasswordTokenRequest
+	if !h.JSONLogin || r.Header.Get("Content-Type") != "application/json" {
+		return req, false
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil || len(bodyBytes) == 0 {
+		return req, false
+	}
+	// Restore the body so it can be read again by the OIDC check
+	r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+	if err := json.Unmarshal(bodyBytes, &req); err != nil || req.Username == "" {
+		return req, false
+	}
+	return req, true
+}
@@ -0,0 +1,63 @@
+package tokenhandler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/auth"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pressly/goose/v3"
+)
+
+func TestGenerateTokensSignsWithNewestSecret(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	user, err := r.CreateUser(ctx, repo.User{Username: "alice", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	keys, err := auth.NewKeyring("new-secret", "old-secret")
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	h := &TokenHandler{Repo: r, Keys: keys, AccessDuration: time.Minute, RefreshDuration: time.Hour}
+
+	accessToken, _, err := h.generateTokens(httptest.NewRequest(http.MethodPost, "/api/token", nil), user.ID)
+	if err != nil {
+		t.Fatalf("failed to generate tokens: %v", err)
+	}
+
+	// The token validates with the newest secret alone, and through the keyring of the middleware
+	if _, err := jwt.Parse(accessToken, func(*jwt.Token) (any, error) { return []byte("new-secret"), nil }); err != nil {
+		t.Errorf("expected the token to be signed with the newest secret: %v", err)
+	}
+	if _, err := jwt.Parse(accessToken, func(*jwt.Token) (any, error) { return []byte("old-secret"), nil }); err == nil {
+		t.Error("expected the token not to be signed with the previous secret")
+	}
+	if _, err := keys.Parse(accessToken); err != nil {
+		t.Errorf("expected the keyring to accept the token: %v", err)
+	}
+}
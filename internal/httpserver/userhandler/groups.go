@@ -0,0 +1,588 @@
+package userhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+	"net/http"
+	"slices"
+)
+
+func mapToGroupResponse(group repo.Group, members []repo.ULID) GroupResponse {
+	if members == nil {
+		members = []repo.ULID{}
+	}
+	return GroupResponse{
+		ID:        group.ID,
+		Name:      group.Name,
+		CanView:   group.Roles.HasAccess(repo.AccessView),
+		CanCreate: group.Roles.HasAccess(repo.AccessCreate),
+		CanEdit:   group.Roles.HasAccess(repo.AccessEdit),
+		CanDelete: group.Roles.HasAccess(repo.AccessDelete),
+		IsAdmin:   group.IsAdmin,
+		Members:   members,
+	}
+}
+
+// groupAuditDetails returns the roles and admin flag of a group for its audit events.
+func groupAuditDetails(group repo.Group) map[string]any {
+	return map[string]any{
+		"group_id":   group.ID.String(),
+		"can_view":   group.Roles.HasAccess(repo.AccessView),
+		"can_create": group.Roles.HasAccess(repo.AccessCreate),
+		"can_edit":   group.Roles.HasAccess(repo.AccessEdit),
+		"can_delete": group.Roles.HasAccess(repo.AccessDelete),
+		"is_admin":   group.IsAdmin,
+	}
+}
+
+// setGroupRole sets or clears a role of a group if the flag is given.
+func setGroupRole(roles *repo.AccessGrant, role repo.AccessGrant, flag *bool) {
+	if flag == nil {
+		return
+	}
+	if *flag {
+		*roles |= role
+	} else {
+		*roles &^= role
+	}
+}
+
+// getGroupMemberships returns the groups of a user in their short form.
+func (h *UserHandler) getGroupMemberships(ctx context.Context, userID repo.ULID) []GroupMembership {
+	memberships := []GroupMembership{}
+	groups, err := h.Repo.GetUserGroups(ctx, userID)
+	if err != nil {
+		h.Logger.Warn("Failed to fetch groups for user", "user_id", userID, "error", err)
+		return memberships
+	}
+	for _, g := range groups {
+		memberships = append(memberships, GroupMembership{ID: g.ID, Name: g.Name, IsAdmin: g.IsAdmin})
+	}
+	return memberships
+}
+
+// isGroupAdmin reports whether the user is an admin through at least one of their groups.
+func (h *UserHandler) isGroupAdmin(ctx context.Context, userID repo.ULID) bool {
+	for _, g := range h.getGroupMemberships(ctx, userID) {
+		if g.IsAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// keepsAnAdmin checks whether at least one global admin remains if the given users lose
+// the admin rights granted by the group. Users that are admins on their own or through
+// another admin group are not affected.
+func (h *UserHandler) keepsAnAdmin(ctx context.Context, groupID repo.ULID, userIDs []repo.ULID) (bool, error) {
+	adminCount, err := h.Repo.CountAdminUsers(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var losing int64
+	for _, userID := range userIDs {
+		user, err := h.Repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		if user.IsAdmin {
+			continue
+		}
+		groups, err := h.Repo.GetUserGroups(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		adminElsewhere := false
+		for _, g := range groups {
+			if g.ID != groupID && g.IsAdmin {
+				adminElsewhere = true
+				break
+			}
+		}
+		if !adminElsewhere {
+			losing++
+		}
+	}
+
+	return adminCount-losing >= 1, nil
+}
+
+// parseGroupULID extracts and validates a ULID path parameter, writing a 400 response if invalid.
+func parseGroupULID(w http.ResponseWriter, r *http.Request, name string) (repo.ULID, bool) {
+	idStr := r.PathValue(name)
+	if idStr == "" || !shared.IsValidULID(idStr) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid or missing path parameter: "+name)
+		return "", false
+	}
+	return repo.ULID(idStr), true
+}
+
+// GetGroups godoc
+// @Summary      Retrieve all groups
+// @Description  Retrieves all groups with their roles and members. Requires the global IsAdmin role.
+// @Tags         User
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Success      200  {array}   userhandler.GroupResponse "List of groups"
+// @Failure      401  {object}  utils.ErrorResponse "Authentication failed"
+// @Failure      403  {object}  utils.ErrorResponse "Forbidden: User lacks IsAdmin role"
+// @Router       /groups [get]
+func (h *UserHandler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	groups, err := h.Repo.GetGroups(ctx)
+	if err != nil {
+		h.Logger.Error("Failed to retrieve groups", "error", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve group list")
+		return
+	}
+
+	response := make([]GroupResponse, 0, len(groups))
+	for _, g := range groups {
+		members, err := h.Repo.GetGroupMembers(ctx, g.ID)
+		if err != nil {
+			h.Logger.Warn("Failed to fetch members of group", "group_id", g.ID, "error", err)
+		}
+		response = append(response, mapToGroupResponse(g, members))
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// GetGroup godoc
+// @Summary      Retrieve a group
+// @Description  Retrieves a group with its roles and members. Requires the global IsAdmin role.
+// @Tags         User
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        group_ulid path string true "Group ULID"
+// @Success      200 {object} userhandler.GroupResponse "Group record"
+// @Failure      400 {object} utils.ErrorResponse "Invalid group ULID"
+// @Failure      401 {object} utils.ErrorResponse "Authentication failed"
+// @Failure      403 {object} utils.ErrorResponse "Forbidden"
+// @Failure      404 {object} utils.ErrorResponse "Group not found"
+// @Router       /group/{group_ulid} [get]
+func (h *UserHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	groupID, ok := parseGroupULID(w, r, "group_ulid")
+	if !ok {
+		return
+	}
+
+	group, err := h.Repo.GetGroup(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Group not found")
+		} else {
+			h.Logger.Error("Failed to retrieve group", "error", err, "group_id", groupID)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	members, err := h.Repo.GetGroupMembers(ctx, groupID)
+	if err != nil {
+		h.Logger.Error("Failed to retrieve group members", "error", err, "group_id", groupID)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, mapToGroupResponse(group, members))
+}
+
+// CreateGroup godoc
+// @Summary      Create a new group
+// @Description  Creates a new group. The can_* roles of a group apply to its members on every database. Requires the global IsAdmin role.
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        payload body userhandler.CreateGroupPayload true "New group details and roles"
+// @Success      201  {object}  userhandler.GroupResponse "Group successfully created"
+// @Failure      400  {object}  utils.ErrorResponse "Invalid JSON body or missing name"
+// @Failure      401  {object}  utils.ErrorResponse "Authentication failed"
+// @Failure      403  {object}  utils.ErrorResponse "Forbidden"
+// @Failure      409  {object}  utils.ErrorResponse "Group already exists"
+// @Failure      500  {object}  utils.ErrorResponse "Internal server error"
+// @Router       /group [post]
+func (h *UserHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminUser := utils.GetUserFromContext(ctx)
+
+	var payload CreateGroupPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if payload.Name == "" || len(payload.Name) > 64 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Name is required and must be at most 64 characters")
+		return
+	}
+
+	group, err := h.Repo.CreateGroup(ctx, repo.Group{
+		Name:    payload.Name,
+		Roles:   repo.NewAccessGrant(payload.CanView, payload.CanCreate, payload.CanEdit, payload.CanDelete, false),
+		IsAdmin: payload.IsAdmin,
+	})
+	if err != nil {
+		if errors.Is(err, customerrors.ErrGroupExists) {
+			utils.RespondWithError(w, http.StatusConflict, "Group already exists")
+		} else {
+			h.Logger.Error("Failed to create group", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to create group")
+		}
+		return
+	}
+
+	h.Auditor.Log(ctx, "group.create", adminUser.Username, group.Name, groupAuditDetails(group))
+
+	utils.RespondWithJSON(w, http.StatusCreated, mapToGroupResponse(group, nil))
+}
+
+// UpdateGroup godoc
+// @Summary      Update a group
+// @Description  Updates the name, roles or admin flag of a group. Omitted flags are kept. Requires the global IsAdmin role.
+// @Tags         User
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        group_ulid path string true "Group ULID"
+// @Param        payload body userhandler.UpdateGroupPayload true "Fields to update"
+// @Success      200 {object} userhandler.GroupResponse "Group successfully updated"
+// @Failure      400 {object} utils.ErrorResponse "Invalid group ULID or JSON body"
+// @Failure      401 {object} utils.ErrorResponse "Authentication failed"
+// @Failure      403 {object} utils.ErrorResponse "Forbidden"
+// @Failure      404 {object} utils.ErrorResponse "Group not found"
+// @Failure      409 {object} utils.ErrorResponse "Name taken or last admin would be removed"
+// @Failure      500 {object} utils.ErrorResponse "Internal server error"
+// @Router       /group/{group_ulid} [patch]
+func (h *UserHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminUser := utils.GetUserFromContext(ctx)
+
+	groupID, ok := parseGroupULID(w, r, "group_ulid")
+	if !ok {
+		return
+	}
+
+	var payload UpdateGroupPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON body")
+		return
+	}
+	if len(payload.Name) > 64 {
+		utils.RespondWithError(w, http.StatusBadRequest, "Name must be at most 64 characters")
+		return
+	}
+
+	group, err := h.Repo.GetGroup(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Group not found")
+		} else {
+			h.Logger.Error("Failed to retrieve group", "error", err, "group_id", groupID)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	members, err := h.Repo.GetGroupMembers(ctx, groupID)
+	if err != nil {
+		h.Logger.Error("Failed to retrieve group members", "error", err, "group_id", groupID)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	groupChanged := false
+	if payload.Name != "" && payload.Name != group.Name {
+		group.Name = payload.Name
+		groupChanged = true
+	}
+
+	if payload.IsAdmin != nil && *payload.IsAdmin != group.IsAdmin {
+		// make sure we dont remove the last admin user
+		if group.IsAdmin {
+			keeps, err := h.keepsAnAdmin(ctx, groupID, members)
+			if err != nil {
+				h.Logger.Error("Failed to count admin users", "error", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Repository error while making sure we dont remove last admin user")
+				return
+			}
+			if !keeps {
+				utils.RespondWithError(w, http.StatusConflict, "Cannot remove last admin user")
+				return
+			}
+		}
+		group.IsAdmin = *payload.IsAdmin
+		groupChanged = true
+	}
+
+	roles := group.Roles
+	setGroupRole(&roles, repo.AccessView, payload.CanView)
+	setGroupRole(&roles, repo.AccessCreate, payload.CanCreate)
+	setGroupRole(&roles, repo.AccessEdit, payload.CanEdit)
+	setGroupRole(&roles, repo.AccessDelete, payload.CanDelete)
+	if roles != group.Roles {
+		group.Roles = roles
+		groupChanged = true
+	}
+
+	if groupChanged {
+		if _, err := h.Repo.UpdateGroup(ctx, group); err != nil {
+			if errors.Is(err, customerrors.ErrNotFound) {
+				utils.RespondWithError(w, http.StatusNotFound, "Group not found")
+			} else if errors.Is(err, customerrors.ErrGroupExists) {
+				utils.RespondWithError(w, http.StatusConflict, "Group already exists")
+			} else {
+				h.Logger.Error("Failed to update group", "error", err, "group_id", groupID)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Failed to update group")
+			}
+			return
+		}
+	}
+
+	h.Auditor.Log(ctx, "group.update", adminUser.Username, group.Name, groupAuditDetails(group))
+
+	utils.RespondWithJSON(w, http.StatusOK, mapToGroupResponse(group, members))
+}
+
+// DeleteGroup godoc
+// @Summary      Delete a group
+// @Description  Deletes a group. Its memberships are removed as well. Requires the global IsAdmin role.
+// @Tags         User
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        group_ulid path string true "Group ULID"
+// @Success      200 {object} utils.MessageResponse "Group successfully deleted"
+// @Failure      400 {object} utils.ErrorResponse "Invalid group ULID"
+// @Failure      401 {object} utils.ErrorResponse "Authentication failed"
+// @Failure      403 {object} utils.ErrorResponse "Forbidden"
+// @Failure      404 {object} utils.ErrorResponse "Group not found"
+// @Failure      409 {object} utils.ErrorResponse "Cannot delete the group of the last remaining admin user"
+// @Failure      500 {object} utils.ErrorResponse "Internal server error"
+// @Router       /group/{group_ulid} [delete]
+func (h *UserHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminUser := utils.GetUserFromContext(ctx)
+
+	groupID, ok := parseGroupULID(w, r, "group_ulid")
+	if !ok {
+		return
+	}
+
+	group, err := h.Repo.GetGroup(ctx, groupID)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Group not found")
+		} else {
+			h.Logger.Error("Failed to retrieve group", "error", err, "group_id", groupID)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	if group.IsAdmin {
+		members, err := h.Repo.GetGroupMembers(ctx, groupID)
+		if err == nil {
+			var keeps bool
+			keeps, err = h.keepsAnAdmin(ctx, groupID, members)
+			if err == nil && !keeps {
+				utils.RespondWithError(w, http.StatusConflict, "Cannot delete the group of the last remaining admin user")
+				return
+			}
+		}
+		if err != nil {
+			h.Logger.Error("Failed to count admin users", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Repository error while checking admin count")
+			return
+		}
+	}
+
+	if err := h.Repo.DeleteGroup(ctx, groupID); err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Group not found")
+		} else {
+			h.Logger.Error("Failed to delete group", "error", err, "group_id", groupID)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to delete group")
+		}
+		return
+	}
+
+	h.Auditor.Log(ctx, "group.delete", adminUser.Username, group.Name, map[string]any{
+		"deleted_id": groupID.String(),
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.MessageResponse{
+		Message: "Group '" + group.Name + "' (ID: " + groupID.String() + ") was successfully deleted.",
+	})
+}
+
+// GetUserGroups godoc
+// @Summary      Retrieve the groups of a user
+// @Description  Lists the groups a user is a member of. Requires admin or self ownership.
+// @Tags         User
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        user_ulid path string true "User ULID"
+// @Success      200 {array}  userhandler.GroupMembership "Group memberships"
+// @Failure      400 {object} utils.ErrorResponse "Invalid user ULID"
+// @Failure      401 {object} utils.ErrorResponse "Authentication failed"
+// @Failure      403 {object} utils.ErrorResponse "Forbidden"
+// @Router       /user/{user_ulid}/groups [get]
+func (h *UserHandler) GetUserGroups(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseGroupULID(w, r, "user_ulid")
+	if !ok {
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, h.getGroupMemberships(r.Context(), userID))
+}
+
+// AddUserToGroup godoc
+// @Summary      Add a user to a group
+// @Description  Adds a user to a group. Adding an existing membership has no effect. Requires the global IsAdmin role.
+// @Tags         User
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        user_ulid  path string true "User ULID"
+// @Param        group_ulid path string true "Group ULID"
+// @Success      200 {array}  userhandler.GroupMembership "The user's group memberships"
+// @Failure      400 {object} utils.ErrorResponse "Invalid ULID"
+// @Failure      401 {object} utils.ErrorResponse "Authentication failed"
+// @Failure      403 {object} utils.ErrorResponse "Forbidden"
+// @Failure      404 {object} utils.ErrorResponse "User or group not found"
+// @Router       /user/{user_ulid}/groups/{group_ulid} [put]
+func (h *UserHandler) AddUserToGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminUser := utils.GetUserFromContext(ctx)
+
+	userID, ok := parseGroupULID(w, r, "user_ulid")
+	if !ok {
+		return
+	}
+	groupID, ok := parseGroupULID(w, r, "group_ulid")
+	if !ok {
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(ctx, userID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	group, err := h.Repo.GetGroup(ctx, groupID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	if err := h.Repo.AddUserToGroup(ctx, userID, groupID); err != nil {
+		h.Logger.Error("Failed to add user to group", "error", err, "user_id", userID, "group_id", groupID)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to add user to group")
+		return
+	}
+
+	h.Auditor.Log(ctx, "group.add_member", adminUser.Username, user.Username, map[string]any{
+		"group_id":   groupID.String(),
+		"group_name": group.Name,
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, h.getGroupMemberships(ctx, userID))
+}
+
+// RemoveUserFromGroup godoc
+// @Summary      Remove a user from a group
+// @Description  Removes a user from a group. Requires the global IsAdmin role.
+// @Tags         User
+// @Produce      json
+// @Security     BasicAuth
+// @Security     BearerAuth
+// @Param        user_ulid  path string true "User ULID"
+// @Param        group_ulid path string true "Group ULID"
+// @Success      200 {array}  userhandler.GroupMembership "The user's remaining group memberships"
+// @Failure      400 {object} utils.ErrorResponse "Invalid ULID"
+// @Failure      401 {object} utils.ErrorResponse "Authentication failed"
+// @Failure      403 {object} utils.ErrorResponse "Forbidden"
+// @Failure      404 {object} utils.ErrorResponse "User, group or membership not found"
+// @Failure      409 {object} utils.ErrorResponse "Cannot remove last admin user"
+// @Router       /user/{user_ulid}/groups/{group_ulid} [delete]
+func (h *UserHandler) RemoveUserFromGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	adminUser := utils.GetUserFromContext(ctx)
+
+	userID, ok := parseGroupULID(w, r, "user_ulid")
+	if !ok {
+		return
+	}
+	groupID, ok := parseGroupULID(w, r, "group_ulid")
+	if !ok {
+		return
+	}
+
+	user, err := h.Repo.GetUserByID(ctx, userID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	group, err := h.Repo.GetGroup(ctx, groupID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusNotFound, "Group not found")
+		return
+	}
+
+	members, err := h.Repo.GetGroupMembers(ctx, groupID)
+	if err != nil {
+		h.Logger.Error("Failed to retrieve group members", "error", err, "group_id", groupID)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !slices.Contains(members, userID) {
+		utils.RespondWithError(w, http.StatusNotFound, "User is not a member of this group")
+		return
+	}
+
+	// make sure we dont remove the last admin user
+	if group.IsAdmin {
+		keeps, err := h.keepsAnAdmin(ctx, groupID, []repo.ULID{userID})
+		if err != nil {
+			h.Logger.Error("Failed to count admin users", "error", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Repository error while making sure we dont remove last admin user")
+			return
+		}
+		if !keeps {
+			utils.RespondWithError(w, http.StatusConflict, "Cannot remove last admin user")
+			return
+		}
+	}
+
+	if err := h.Repo.RemoveUserFromGroup(ctx, userID, groupID); err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "User is not a member of this group")
+		} else {
+			h.Logger.Error("Failed to remove user from group", "error", err, "user_id", userID, "group_id", groupID)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Failed to remove user from group")
+		}
+		return
+	}
+
+	h.Auditor.Log(ctx, "group.remove_member", adminUser.Username, user.Username, map[string]any{
+		"group_id":   groupID.String(),
+		"group_name": group.Name,
+	})
+
+	utils.RespondWithJSON(w, http.StatusOK, h.getGroupMemberships(ctx, userID))
+}
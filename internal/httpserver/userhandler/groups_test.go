@@ -0,0 +1,210 @@
+package userhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mediahub_oss/internal/httpserver/userhandler"
+	"mediahub_oss/internal/httpserver/utils"
+	"mediahub_oss/internal/logging/audit"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func newTestHandler(t *testing.T) (*userhandler.UserHandler, *sqlite.SQLiteRepository) {
+	t.Helper()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	h := &userhandler.UserHandler{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auditor: audit.NewAlNoopLogger(),
+		Repo:    r,
+	}
+	return h, r
+}
+
+// serve runs a handler with the given path values and an admin in the request context.
+func serve(hf http.HandlerFunc, admin repo.User, method, body string, pathValues map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	for k, v := range pathValues {
+		req.SetPathValue(k, v)
+	}
+	ctx := context.WithValue(req.Context(), utils.UserKey, &admin)
+	ctx = context.WithValue(ctx, utils.PermissionHolderKey, utils.PermissionHolder(&utils.GlobalAdmin{UserULID: admin.ID}))
+	rec := httptest.NewRecorder()
+	hf(rec, req.WithContext(ctx))
+	return rec
+}
+
+func TestGroupHandlers(t *testing.T) {
+	ctx := context.Background()
+	h, r := newTestHandler(t)
+
+	admin, err := r.CreateUser(ctx, repo.User{Username: "admin", PasswordHash: "x", IsAdmin: true})
+	if err != nil {
+		t.Fatalf("failed to create admin: %v", err)
+	}
+	member, err := r.CreateUser(ctx, repo.User{Username: "member", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create member: %v", err)
+	}
+	db1, err := r.CreateDatabase(ctx, repo.Database{Name: "group_db1", ContentType: "image"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db2, err := r.CreateDatabase(ctx, repo.Database{Name: "group_db2", ContentType: "image"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// 1. Create a group viewing every database
+	rec := serve(h.CreateGroup, admin, http.MethodPost, `{"name":"editors","can_view":true}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var group userhandler.GroupResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &group); err != nil {
+		t.Fatalf("failed to decode group: %v", err)
+	}
+	if !group.CanView || group.CanCreate || group.CanEdit || group.CanDelete || group.IsAdmin {
+		t.Errorf("expected only the view role, got %+v", group)
+	}
+
+	// Duplicate names are rejected
+	rec = serve(h.CreateGroup, admin, http.MethodPost, `{"name":"editors"}`, nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for duplicate group, got %d", rec.Code)
+	}
+
+	// 2. Add the member and give them a direct edit permission on db1
+	if err := r.SetUserPermissions(ctx, repo.UserPermissions{UserID: member.ID, DatabaseID: db1.ID, Roles: repo.AccessEdit}); err != nil {
+		t.Fatalf("failed to set user permissions: %v", err)
+	}
+	paths := map[string]string{"user_ulid": member.ID.String(), "group_ulid": group.ID.String()}
+	rec = serve(h.AddUserToGroup, admin, http.MethodPut, "", paths)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on add member, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 3. Effective permissions are the union of own and group roles, the group roles apply to every database
+	holder := &utils.UserPermissions{UserULID: member.ID, Scope: repo.NewAccessGrant(true, true, true, true, true), Repo: r}
+	if !holder.HasPermission(db1.ID, repo.AccessView) || !holder.HasPermission(db1.ID, repo.AccessEdit) {
+		t.Errorf("expected view (group) and edit (own) on db1")
+	}
+	if !holder.HasPermission(db2.ID, repo.AccessView) || holder.HasPermission(db2.ID, repo.AccessEdit) {
+		t.Errorf("expected only view (group) on db2")
+	}
+
+	// Updating a role keeps the omitted ones
+	rec = serve(h.UpdateGroup, admin, http.MethodPatch, `{"can_delete":true}`, map[string]string{"group_ulid": group.ID.String()})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on update, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &group); err != nil {
+		t.Fatalf("failed to decode group: %v", err)
+	}
+	if !group.CanView || !group.CanDelete || group.CanEdit {
+		t.Errorf("expected the view and delete roles, got %+v", group)
+	}
+	holder = &utils.UserPermissions{UserULID: member.ID, Scope: repo.NewAccessGrant(true, true, true, true, true), Repo: r}
+	if !holder.HasPermission(db2.ID, repo.AccessDelete) {
+		t.Errorf("expected the updated group role to apply on db2")
+	}
+
+	// 4. GET /users lists the membership
+	rec = serve(h.GetUsers, admin, http.MethodGet, "", nil)
+	var users []userhandler.UserResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &users); err != nil {
+		t.Fatalf("failed to decode users: %v", err)
+	}
+	for _, u := range users {
+		if u.ID == member.ID && (len(u.Groups) != 1 || u.Groups[0].Name != "editors") {
+			t.Errorf("expected member to be listed in 'editors', got %+v", u.Groups)
+		}
+	}
+
+	// 5. Deleting the group removes the membership
+	rec = serve(h.DeleteGroup, admin, http.MethodDelete, "", map[string]string{"group_ulid": group.ID.String()})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d: %s", rec.Code, rec.Body.String())
+	}
+	groups, err := r.GetUserGroups(ctx, member.ID)
+	if err != nil || len(groups) != 0 {
+		t.Errorf("expected no groups after deletion, got %v (err: %v)", groups, err)
+	}
+}
+
+func TestGroupHandlersKeepLastAdmin(t *testing.T) {
+	ctx := context.Background()
+	h, r := newTestHandler(t)
+
+	// The only admin is an admin through a group
+	user, err := r.CreateUser(ctx, repo.User{Username: "group_admin", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	group, err := r.CreateGroup(ctx, repo.Group{Name: "admins", IsAdmin: true})
+	if err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	if err := r.AddUserToGroup(ctx, user.ID, group.ID); err != nil {
+		t.Fatalf("failed to add user to group: %v", err)
+	}
+
+	isAdmin, err := repo.IsEffectiveAdmin(ctx, r, user)
+	if err != nil || !isAdmin {
+		t.Fatalf("expected user to be an effective admin (err: %v)", err)
+	}
+
+	paths := map[string]string{"user_ulid": user.ID.String(), "group_ulid": group.ID.String()}
+	if rec := serve(h.RemoveUserFromGroup, user, http.MethodDelete, "", paths); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when removing the last admin from the group, got %d", rec.Code)
+	}
+	if rec := serve(h.DeleteGroup, user, http.MethodDelete, "", paths); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when deleting the group of the last admin, got %d", rec.Code)
+	}
+	if rec := serve(h.UpdateGroup, user, http.MethodPatch, `{"is_admin":false}`, paths); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when revoking admin from the group, got %d", rec.Code)
+	}
+
+	// Removing a user who is not a member is not found, even from an admin group
+	outsider, err := r.CreateUser(ctx, repo.User{Username: "outsider", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	outsiderPaths := map[string]string{"user_ulid": outsider.ID.String(), "group_ulid": group.ID.String()}
+	if rec := serve(h.RemoveUserFromGroup, user, http.MethodDelete, "", outsiderPaths); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when removing a non-member, got %d", rec.Code)
+	}
+
+	// With a second, direct admin the removal is allowed
+	if _, err := r.CreateUser(ctx, repo.User{Username: "direct_admin", PasswordHash: "x", IsAdmin: true}); err != nil {
+		t.Fatalf("failed to create admin: %v", err)
+	}
+	if rec := serve(h.RemoveUserFromGroup, user, http.MethodDelete, "", paths); rec.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
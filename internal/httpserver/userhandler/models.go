@@ -30,10 +30,12 @@ type CreateUserPayload struct {
 
 // UpdateUserPayload defines the expected JSON body for PATCH /api/user.
 type UpdateUserPayload struct {
-	Username    string               `json:"username"`
-	Password    string               `json:"password"`
-	IsAdmin     *bool                `json:"is_admin"`
-	Permissions []DatabasePermission `json:"permissions"`
+	Username     string               `json:"username"`
+	Password     string               `json:"password"`
+	IsAdmin      *bool                `json:"is_admin"`
+	Disabled     *bool                `json:"disabled"`            // disabling also revokes the refresh tokens
+	StorageQuota *uint64              `json:"storage_quota_bytes"` // bytes the uploads of the user may take in all databases, 0 for unlimited
+	Permissions  []DatabasePermission `json:"permissions"`
 }
 
 // UserResponse is the JSON structure returned by the /api/me and /api/users endpoints.
@@ -42,7 +44,51 @@ type UserResponse struct {
 	Username         string               `json:"username"`
 	IsAdmin          bool                 `json:"is_admin"`
 	IsServiceAccount bool                 `json:"is_service_account"`
+	Disabled         bool                 `json:"disabled"`
+	LastLoginAt      *int64               `json:"last_login_at"`       // UNIX epoch in milliseconds, null if never
+	LastActivityAt   *int64               `json:"last_activity_at"`    // UNIX epoch in milliseconds, null if never
+	StorageQuota     uint64               `json:"storage_quota_bytes"` // 0 for unlimited
 	Permissions      []DatabasePermission `json:"permissions"`
+	Groups           []GroupMembership    `json:"groups"`
+}
+
+// GroupMembership is the short form of a group listed on a user.
+type GroupMembership struct {
+	ID      repository.ULID `json:"id"`
+	Name    string          `json:"name"`
+	IsAdmin bool            `json:"is_admin"`
+}
+
+// CreateGroupPayload defines the expected JSON body for POST /api/group.
+type CreateGroupPayload struct {
+	Name      string `json:"name"`
+	CanView   bool   `json:"can_view"` // the can_* roles apply to every database
+	CanCreate bool   `json:"can_create"`
+	CanEdit   bool   `json:"can_edit"`
+	CanDelete bool   `json:"can_delete"`
+	IsAdmin   bool   `json:"is_admin"`
+}
+
+// UpdateGroupPayload defines the expected JSON body for PATCH /api/group/{group_ulid}.
+type UpdateGroupPayload struct {
+	Name      string `json:"name"`
+	CanView   *bool  `json:"can_view"`
+	CanCreate *bool  `json:"can_create"`
+	CanEdit   *bool  `json:"can_edit"`
+	CanDelete *bool  `json:"can_delete"`
+	IsAdmin   *bool  `json:"is_admin"`
+}
+
+// GroupResponse is the JSON structure returned by the group endpoints.
+type GroupResponse struct {
+	ID        repository.ULID   `json:"id"`
+	Name      string            `json:"name"`
+	CanView   bool              `json:"can_view"` // the can_* roles apply to every database
+	CanCreate bool              `json:"can_create"`
+	CanEdit   bool              `json:"can_edit"`
+	CanDelete bool              `json:"can_delete"`
+	IsAdmin   bool              `json:"is_admin"`
+	Members   []repository.ULID `json:"members"`
 }
 
 // DatabasePermission defines the boolean flags for a user's rights on a specific database.
@@ -8,7 +8,9 @@ import (
 	"mediahub_oss/internal/shared"
 	"mediahub_oss/internal/shared/customerrors"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -38,7 +40,12 @@ func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
 		Username:         user.Username,
 		IsAdmin:          isAdmin,
 		IsServiceAccount: user.IsServiceAccount,
+		Disabled:         user.Disabled,
+		LastLoginAt:      nullableMillis(user.LastLoginAt),
+		LastActivityAt:   nullableMillis(user.LastActivityAt),
+		StorageQuota:     user.StorageQuota,
 		Permissions:      []DatabasePermission{}, // Default to empty array
+		Groups:           h.getGroupMemberships(ctx, user.ID),
 	}
 
 	// 3. If the user is an admin, they bypass specific permission checks
@@ -152,7 +159,10 @@ func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Security     BasicAuth
 // @Security     BearerAuth
+// @Param        is_service_account query bool   false "Only service accounts (true) or only other users (false)"
+// @Param        inactive_since     query string false "Only users without login or activity for this duration (e.g. 90d), including users never seen"
 // @Success      200  {array}   userhandler.UserResponse "List of users"
+// @Failure      400  {object}  utils.ErrorResponse "Invalid query parameter"
 // @Failure      401  {object}  utils.ErrorResponse "Authentication failed"
 // @Failure      403  {object}  utils.ErrorResponse "Forbidden: User lacks IsAdmin role"
 // @Router       /users [get]
@@ -171,6 +181,17 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 		isServiceAccountFilter = &val
 	}
 
+	// Optional inactive_since filter for finding accounts to clean up
+	var inactiveBefore time.Time
+	if inactiveSinceStr := r.URL.Query().Get("inactive_since"); inactiveSinceStr != "" {
+		inactiveSince, err := shared.ParseDuration(inactiveSinceStr)
+		if err != nil || inactiveSince <= 0 {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid inactive_since query parameter: must be a positive duration like 90d")
+			return
+		}
+		inactiveBefore = time.Now().Add(-inactiveSince)
+	}
+
 	// 2. Fetch all users from the database
 	dbUsers, err := h.Repo.GetUsers(ctx, isServiceAccountFilter)
 	if err != nil {
@@ -178,6 +199,11 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve user list")
 		return
 	}
+	if !inactiveBefore.IsZero() {
+		dbUsers = slices.DeleteFunc(dbUsers, func(u repo.User) bool {
+			return lastSeen(u).After(inactiveBefore)
+		})
+	}
 
 	// 3. Initialize our response array
 	var response = make([]UserResponse, len(dbUsers))
@@ -189,7 +215,12 @@ func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
 			Username:         u.Username,
 			IsAdmin:          u.IsAdmin,
 			IsServiceAccount: u.IsServiceAccount,
+			Disabled:         u.Disabled,
+			LastLoginAt:      nullableMillis(u.LastLoginAt),
+			LastActivityAt:   nullableMillis(u.LastActivityAt),
+			StorageQuota:     u.StorageQuota,
 			Permissions:      []DatabasePermission{}, // Default to empty
+			Groups:           h.getGroupMemberships(ctx, u.ID),
 		}
 
 		// 4. Admin users implicitly have all rights, so we leave their permissions array empty
@@ -337,6 +368,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		IsAdmin:          createdUser.IsAdmin,
 		IsServiceAccount: createdUser.IsServiceAccount,
 		Permissions:      appliedPermissions,
+		Groups:           []GroupMembership{},
 	}
 
 	// 7. Log the action
@@ -352,6 +384,8 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 // UpdateUser godoc
 // @Summary      Update an existing user
 // @Description  Updates an existing user's global status, password, or database permissions. Permissions act as an Upsert/Replace operation. Requires the global IsAdmin role.
+// @Description  Disabled users fail every login with 403 and their refresh tokens are revoked, but the account and its data are kept.
+// @Description  storage_quota_bytes limits the bytes of the entries the user uploaded in all databases; uploads beyond it fail with 507, 0 removes the limit.
 // @Tags         User
 // @Accept       json
 // @Produce      json
@@ -364,7 +398,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 // @Failure      401     {object} utils.ErrorResponse "Authentication failed"
 // @Failure      403     {object} utils.ErrorResponse "Forbidden: Admin user not retrieved"
 // @Failure      404     {object} utils.ErrorResponse "User not found"
-// @Failure      409     {object} utils.ErrorResponse "Cannot remove last admin user"
+// @Failure      409     {object} utils.ErrorResponse "Cannot remove last admin user or disable your own account"
 // @Failure      500     {object} utils.ErrorResponse "Internal server error"
 // @Router       /user [patch]
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
@@ -424,7 +458,8 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	if payload.IsAdmin != nil {
 		// make sure we dont remove the last admin user
-		if existingUser.IsAdmin && !(*payload.IsAdmin) {
+		// (users that are admins through a group stay admins)
+		if existingUser.IsAdmin && !(*payload.IsAdmin) && !h.isGroupAdmin(ctx, userID) {
 			adminCount, err := h.Repo.CountAdminUsers(ctx)
 			if err != nil {
 				h.Logger.Error("Failed to count admin users", "error", err)
@@ -440,6 +475,23 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		userChanged = true
 	}
 
+	disabling := false
+	if payload.Disabled != nil && *payload.Disabled != existingUser.Disabled {
+		// there is always an enabled admin left, the one making the request
+		if *payload.Disabled && existingUser.ID == adminUser.ID {
+			utils.RespondWithError(w, http.StatusConflict, "Cannot disable your own account")
+			return
+		}
+		disabling = *payload.Disabled
+		existingUser.Disabled = *payload.Disabled
+		userChanged = true
+	}
+
+	if payload.StorageQuota != nil && *payload.StorageQuota != existingUser.StorageQuota {
+		existingUser.StorageQuota = *payload.StorageQuota
+		userChanged = true
+	}
+
 	if userChanged {
 		if _, err := h.Repo.UpdateUser(ctx, existingUser); err != nil {
 			h.Logger.Error("Failed to update user record", "error", err, "user_id", userID)
@@ -448,6 +500,15 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Access tokens are refused from now on as the user is looked up on each request, refresh tokens are revoked
+	if disabling {
+		if err := h.Repo.DeleteAllRefreshTokensForUser(ctx, userID); err != nil {
+			h.Logger.Error("Failed to revoke the refresh tokens of the disabled user", "error", err, "user_id", userID)
+			utils.RespondWithError(w, http.StatusInternalServerError, "User disabled, but failed to revoke the refresh tokens")
+			return
+		}
+	}
+
 	// 5. Process permission updates (Upsert or Delete)
 	if len(payload.Permissions) > 0 {
 		for _, perm := range payload.Permissions {
@@ -489,11 +550,26 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		Username:         existingUser.Username,
 		IsAdmin:          existingUser.IsAdmin,
 		IsServiceAccount: existingUser.IsServiceAccount,
+		Disabled:         existingUser.Disabled,
+		LastLoginAt:      nullableMillis(existingUser.LastLoginAt),
+		LastActivityAt:   nullableMillis(existingUser.LastActivityAt),
+		StorageQuota:     existingUser.StorageQuota,
 		Permissions:      finalPermissions,
+		Groups:           h.getGroupMemberships(ctx, existingUser.ID),
 	}
 
 	// 7. Log the action
-	h.Auditor.Log(ctx, "user.update", adminUser.Username, existingUser.Username, nil)
+	var details map[string]any
+	if payload.Disabled != nil {
+		details = map[string]any{"disabled": existingUser.Disabled}
+	}
+	if payload.StorageQuota != nil {
+		if details == nil {
+			details = map[string]any{}
+		}
+		details["storage_quota_bytes"] = existingUser.StorageQuota
+	}
+	h.Auditor.Log(ctx, "user.update", adminUser.Username, existingUser.Username, details)
 
 	utils.RespondWithJSON(w, http.StatusOK, response)
 }
@@ -539,8 +615,14 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 3. Prevent deletion of the last remaining admin user
-	if userToDelete.IsAdmin {
+	// 3. Prevent deletion of the last remaining admin user (directly or through a group)
+	isAdmin, err := repo.IsEffectiveAdmin(ctx, h.Repo, userToDelete)
+	if err != nil {
+		h.Logger.Error("Failed to resolve admin status", "error", err, "user_id", userID)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Repository error while checking admin count")
+		return
+	}
+	if isAdmin {
 		adminCount, err := h.Repo.CountAdminUsers(ctx)
 		if err != nil {
 			h.Logger.Error("Failed to count admin users", "error", err)
@@ -639,7 +721,12 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		Username:         user.Username,
 		IsAdmin:          user.IsAdmin,
 		IsServiceAccount: user.IsServiceAccount,
+		Disabled:         user.Disabled,
+		LastLoginAt:      nullableMillis(user.LastLoginAt),
+		LastActivityAt:   nullableMillis(user.LastActivityAt),
+		StorageQuota:     user.StorageQuota,
 		Permissions:      finalPermissions,
+		Groups:           h.getGroupMemberships(ctx, user.ID),
 	}
 
 	h.Auditor.Log(ctx, "user.get", adminUser.Username, user.Username, map[string]any{
@@ -650,3 +737,20 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	utils.RespondWithJSON(w, http.StatusOK, response)
 }
+
+// nullableMillis converts a timestamp to UNIX milliseconds, nil for the zero time.
+func nullableMillis(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+	val := t.UnixMilli()
+	return &val
+}
+
+// lastSeen returns the latest login or activity of a user, the zero time if the user was never seen.
+func lastSeen(user repo.User) time.Time {
+	if user.LastLoginAt.After(user.LastActivityAt) {
+		return user.LastLoginAt
+	}
+	return user.LastActivityAt
+}
@@ -0,0 +1,109 @@
+package userhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver/userhandler"
+	"mediahub_oss/internal/httpserver/utils"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+func TestUserActivityAndDisable(t *testing.T) {
+	ctx := context.Background()
+	h, r := newTestHandler(t)
+
+	admin, err := r.CreateUser(ctx, repo.User{Username: "admin", PasswordHash: "x", IsAdmin: true})
+	if err != nil {
+		t.Fatalf("failed to create admin: %v", err)
+	}
+	stale, err := r.CreateUser(ctx, repo.User{Username: "stale", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if _, err := r.CreateUser(ctx, repo.User{Username: "never", PasswordHash: "x"}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := r.RecordUserLogin(ctx, admin.ID, time.Now()); err != nil {
+		t.Fatalf("failed to record login: %v", err)
+	}
+	staleLogin := time.Now().Add(-100 * 24 * time.Hour)
+	if err := r.RecordUserLogin(ctx, stale.ID, staleLogin); err != nil {
+		t.Fatalf("failed to record login: %v", err)
+	}
+	// older timestamps do not move the activity backwards
+	if err := r.RecordUserActivity(ctx, stale.ID, staleLogin.Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to record activity: %v", err)
+	}
+
+	listUsers := func(query string) map[string]userhandler.UserResponse {
+		t.Helper()
+		rec := serveQuery(h.GetUsers, admin, query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /api/users?%s: expected 200, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+		var users []userhandler.UserResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &users); err != nil {
+			t.Fatalf("failed to decode users: %v", err)
+		}
+		byName := make(map[string]userhandler.UserResponse)
+		for _, u := range users {
+			byName[u.Username] = u
+		}
+		return byName
+	}
+
+	// 1. The timestamps are listed, users never seen have null
+	users := listUsers("")
+	if got := users["stale"]; got.LastLoginAt == nil || *got.LastLoginAt != staleLogin.UnixMilli() || got.LastActivityAt == nil || *got.LastActivityAt != staleLogin.UnixMilli() {
+		t.Errorf("expected the stale login in both timestamps, got %+v", got)
+	}
+	if got := users["never"]; got.LastLoginAt != nil || got.LastActivityAt != nil {
+		t.Errorf("expected null timestamps for the user never seen, got %+v", got)
+	}
+
+	// 2. inactive_since lists the candidates for cleanup
+	users = listUsers("inactive_since=90d")
+	if _, ok := users["admin"]; len(users) != 2 || ok {
+		t.Errorf("expected stale and never to be inactive, got %v", users)
+	}
+	if rec := serveQuery(h.GetUsers, admin, "inactive_since=soon"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid duration, got %d", rec.Code)
+	}
+
+	// 3. Disabling revokes the refresh tokens, admins cannot disable themselves
+	if err := r.StoreRefreshToken(ctx, stale.ID, "hash", time.Hour); err != nil {
+		t.Fatalf("failed to store refresh token: %v", err)
+	}
+	rec := serve(h.UpdateUser, admin, http.MethodPatch, `{"disabled": true}`, map[string]string{"user_ulid": stale.ID.String()})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the user to be disabled, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated userhandler.UserResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil || !updated.Disabled {
+		t.Errorf("expected the response to show the user disabled, got %s", rec.Body.String())
+	}
+	if _, err := r.ValidateRefreshToken(ctx, "hash"); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected the refresh token to be revoked, got %v", err)
+	}
+	rec = serve(h.UpdateUser, admin, http.MethodPatch, `{"disabled": true}`, map[string]string{"user_ulid": admin.ID.String()})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 when disabling yourself, got %d", rec.Code)
+	}
+}
+
+// serveQuery runs a GET handler with the given query string and an admin in the request context.
+func serveQuery(hf http.HandlerFunc, admin repo.User, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	ctx := context.WithValue(req.Context(), utils.UserKey, &admin)
+	ctx = context.WithValue(ctx, utils.PermissionHolderKey, utils.PermissionHolder(&utils.GlobalAdmin{UserULID: admin.ID}))
+	rec := httptest.NewRecorder()
+	hf(rec, req.WithContext(ctx))
+	return rec
+}
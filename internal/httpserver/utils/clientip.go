@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP returns the address of the client that sent the request. The X-Forwarded-For header is only
+// honored if the request comes from one of the trusted proxies: its hops are walked from the right and
+// the first address that is not a trusted proxy is the client. Without trusted proxies the header is
+// ignored, as any client can set it.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	remote := remoteAddr(r.RemoteAddr)
+	if !remote.IsValid() {
+		return r.RemoteAddr
+	}
+	if !isTrustedProxy(remote, trustedProxies) {
+		return remote.String()
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break // a malformed hop cannot be trusted, the last valid address is the client
+		}
+		client = hop.Unmap()
+		if !isTrustedProxy(client, trustedProxies) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// remoteAddr parses the host of a "host:port" remote address, the zero value if it is not an IP.
+func remoteAddr(addr string) netip.Addr {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return ip.Unmap()
+}
+
+func isTrustedProxy(ip netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.5/32")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		trusted    []netip.Prefix
+		want       string
+	}{
+		{"no proxy", "203.0.113.7:5000", nil, trusted, "203.0.113.7"},
+		{"untrusted peer spoofing the header", "203.0.113.7:5000", []string{"1.2.3.4"}, trusted, "203.0.113.7"},
+		{"no trusted proxies configured", "10.0.0.1:5000", []string{"1.2.3.4"}, nil, "10.0.0.1"},
+		{"trusted proxy", "10.0.0.1:5000", []string{"198.51.100.9"}, trusted, "198.51.100.9"},
+		{"chain of trusted proxies", "10.0.0.1:5000", []string{"198.51.100.9, 192.168.1.5"}, trusted, "198.51.100.9"},
+		{"spoofed hop left of the client", "10.0.0.1:5000", []string{"1.2.3.4, 198.51.100.9"}, trusted, "198.51.100.9"},
+		{"several headers", "10.0.0.1:5000", []string{"1.2.3.4", "198.51.100.9"}, trusted, "198.51.100.9"},
+		{"malformed hop", "10.0.0.1:5000", []string{"198.51.100.9, garbage"}, trusted, "10.0.0.1"},
+		{"only trusted hops", "10.0.0.1:5000", []string{"10.0.0.2"}, trusted, "10.0.0.2"},
+		{"ipv6 peer", "[2001:db8::1]:5000", []string{"1.2.3.4"}, trusted, "2001:db8::1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/database/x/entry", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, value := range tc.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := ClientIP(req, tc.trusted); got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
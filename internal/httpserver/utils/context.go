@@ -11,6 +11,7 @@ type ContextKey string
 const (
 	UserKey             ContextKey = "user"
 	PermissionHolderKey ContextKey = "permholder"
+	OriginKey           ContextKey = "origin"
 )
 
 // GetUserFromContext is a helper to safely retrieve the strongly-typed User object.
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// Origin is the scheme and host a client used to reach the server, e.g. https://media.example.com.
+type Origin struct {
+	Scheme string
+	Host   string
+}
+
+func (o Origin) String() string {
+	return o.Scheme + "://" + o.Host
+}
+
+// RequestOrigin derives the origin of a request from the connection and its Host header. Behind a proxy
+// these name the proxy's upstream (e.g. http://backend:8080), so if the request comes from one of the
+// trusted proxies, the X-Forwarded-Proto and X-Forwarded-Host headers take precedence, falling back to the
+// proto and host of the Forwarded header (RFC 7239). Requests from other addresses cannot change the
+// origin, as any client can set these headers.
+func RequestOrigin(r *http.Request, trustedProxies []netip.Prefix) Origin {
+	origin := Origin{Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		origin.Scheme = "https"
+	}
+
+	remote := remoteAddr(r.RemoteAddr)
+	if !remote.IsValid() || !isTrustedProxy(remote, trustedProxies) {
+		return origin
+	}
+
+	proto, host := firstValue(r.Header.Get("X-Forwarded-Proto")), firstValue(r.Header.Get("X-Forwarded-Host"))
+	if proto == "" && host == "" {
+		proto, host = parseForwarded(r.Header.Get("Forwarded"))
+	}
+	if proto = strings.ToLower(proto); proto == "http" || proto == "https" {
+		origin.Scheme = proto
+	}
+	if validHost(host) {
+		origin.Host = host
+	}
+	return origin
+}
+
+// OriginMiddleware stores the origin of every request in its context, see RequestOrigin.
+func OriginMiddleware(trustedProxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), OriginKey, RequestOrigin(r, trustedProxies))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// originOf returns the origin stored by OriginMiddleware. Without the middleware, the forwarding headers
+// are ignored.
+func originOf(r *http.Request) Origin {
+	if origin, ok := r.Context().Value(OriginKey).(Origin); ok {
+		return origin
+	}
+	return RequestOrigin(r, nil)
+}
+
+// AbsoluteURL returns the absolute URL of path for the client of a request. baseURL is the configured
+// external prefix (server.base_url): an absolute base URL is used as is, a relative one, like a base path,
+// is prefixed with the origin of the request.
+func AbsoluteURL(r *http.Request, baseURL string, path string) string {
+	if u, err := url.Parse(baseURL); err == nil && u.Scheme != "" && u.Host != "" {
+		return baseURL + path
+	}
+	return originOf(r).String() + baseURL + path
+}
+
+// firstValue returns the first of comma-separated header values, the one set by the proxy nearest to the client.
+func firstValue(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(first)
+}
+
+// parseForwarded returns the proto and host of the first element of a Forwarded header,
+// e.g. `for=198.51.100.9;proto=https;host="media.example.com"`.
+func parseForwarded(header string) (proto string, host string) {
+	for _, pair := range strings.Split(firstValue(header), ";") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		switch strings.ToLower(key) {
+		case "proto":
+			proto = value
+		case "host":
+			host = value
+		}
+	}
+	return proto, host
+}
+
+// validHost reports whether host is a plain "host[:port]", without user info, path or query.
+func validHost(host string) bool {
+	if host == "" || strings.ContainsAny(host, "/\\@?# ") {
+		return false
+	}
+	u, err := url.Parse("http://" + host)
+	return err == nil && u.Host == host
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRequestOrigin(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		tls        bool
+		want       string
+	}{
+		{"direct request", "203.0.113.7:5000", nil, false, "http://backend:8080"},
+		{"direct tls request", "203.0.113.7:5000", nil, true, "https://backend:8080"},
+		{"untrusted peer spoofing the headers", "203.0.113.7:5000", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"}, false, "http://backend:8080"},
+		{"untrusted peer spoofing forwarded", "203.0.113.7:5000", map[string]string{"Forwarded": "proto=https;host=evil.example"}, false, "http://backend:8080"},
+		{"trusted proxy", "10.0.0.1:5000", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "media.example.com"}, false, "https://media.example.com"},
+		{"trusted proxy with port", "10.0.0.1:5000", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "media.example.com:8443"}, false, "https://media.example.com:8443"},
+		{"only the scheme", "10.0.0.1:5000", map[string]string{"X-Forwarded-Proto": "HTTPS"}, false, "https://backend:8080"},
+		{"chained values", "10.0.0.1:5000", map[string]string{"X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "media.example.com, proxy2"}, false, "https://media.example.com"},
+		{"forwarded header", "10.0.0.1:5000", map[string]string{"Forwarded": `for=198.51.100.9;proto=https;host="media.example.com", for=10.0.0.2`}, false, "https://media.example.com"},
+		{"x-forwarded takes precedence", "10.0.0.1:5000", map[string]string{"X-Forwarded-Host": "media.example.com", "Forwarded": "host=other.example"}, false, "http://media.example.com"},
+		{"invalid values are ignored", "10.0.0.1:5000", map[string]string{"X-Forwarded-Proto": "javascript", "X-Forwarded-Host": "evil.example/path"}, false, "http://backend:8080"},
+		{"user info is ignored", "10.0.0.1:5000", map[string]string{"X-Forwarded-Host": "user@evil.example"}, false, "http://backend:8080"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://backend:8080/api/info", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			if got := RequestOrigin(req, trusted).String(); got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAbsoluteURL(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")}
+	forwarded := map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "media.example.com"}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		baseURL    string
+		want       string
+	}{
+		{"trusted proxy", "10.0.0.1:5000", "", "https://media.example.com/share/abc"},
+		{"trusted proxy with base path", "10.0.0.1:5000", "/mediahub", "https://media.example.com/mediahub/share/abc"},
+		{"untrusted peer", "10.0.0.2:5000", "/mediahub", "http://backend:8080/mediahub/share/abc"},
+		{"configured absolute base url", "10.0.0.1:5000", "https://files.example.org/hub", "https://files.example.org/hub/share/abc"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			handler := OriginMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = AbsoluteURL(r, tc.baseURL, "/share/abc")
+			}))
+			req := httptest.NewRequest(http.MethodGet, "http://backend:8080/api/share", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for key, value := range forwarded {
+				req.Header.Set(key, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	// Without the middleware the forwarding headers are never honored
+	req := httptest.NewRequest(http.MethodGet, "http://backend:8080/api/share", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-Host", "media.example.com")
+	if got := AbsoluteURL(req, "", "/share/abc"); got != "http://backend:8080/share/abc" {
+		t.Errorf("expected the request host without the middleware, got %s", got)
+	}
+}
@@ -13,10 +13,11 @@ type PermissionHolder interface {
 	GetAllPermissions(ctx context.Context) (map[repository.ULID]repository.AccessGrant, error)
 }
 
-// There are three types of permission holders:
+// There are four types of permission holders:
 // **GlobalAdmin**: has full access to all databases and actions.
 // **APIKeyOfAdmin**: has access limited only by the scope of the API key
-// **UserPermissions**: has access limited by the specific database permissions and a potential API key scope
+// **UserPermissions**: has access limited by the specific database permissions, the global roles of the groups and a potential API key scope
+// **Anonymous**: a caller without credentials, may only view databases with public_read
 
 type GlobalAdmin struct {
 	UserULID repository.ULID
@@ -118,6 +119,21 @@ func (u *UserPermissions) loadPermissions(ctx context.Context) {
 	for _, p := range perms {
 		permsMap[p.DatabaseID] = p.Roles
 	}
+
+	// Effective permissions: OR the direct roles with the roles of all groups, which apply to every database
+	var groupRoles repository.AccessGrant
+	if groups, err := u.Repo.GetUserGroups(ctx, u.UserULID); err == nil {
+		for _, g := range groups {
+			groupRoles |= g.Roles
+		}
+	}
+	if groupRoles != 0 {
+		if dbs, err := u.Repo.GetDatabases(ctx); err == nil {
+			for _, db := range dbs {
+				permsMap[db.ID] |= groupRoles
+			}
+		}
+	}
 	u.permissions = permsMap
 	u.loaded = true
 }
@@ -143,3 +159,55 @@ func (u *UserPermissions) GetAllPermissions(ctx context.Context) (map[repository
 	}
 	return filtered, nil
 }
+
+// A caller without credentials on a route allowing anonymous reads, it may only view databases with public_read
+type Anonymous struct {
+	Repo repository.Repository
+
+	public map[repository.ULID]bool // looked up databases, true if public_read is set
+}
+
+// AnonymousActor is the audit actor and username of anonymous callers, it carries their client IP.
+func AnonymousActor(clientIP string) string {
+	return "anonymous:" + clientIP
+}
+
+func (a *Anonymous) IsGlobalAdmin() bool {
+	return false
+}
+
+func (a *Anonymous) isPublic(ctx context.Context, database repository.ULID) bool {
+	if public, ok := a.public[database]; ok {
+		return public
+	}
+	db, err := a.Repo.GetDatabase(ctx, database)
+	public := err == nil && db.Config.PublicRead
+	if a.public == nil {
+		a.public = make(map[repository.ULID]bool)
+	}
+	a.public[database] = public
+	return public
+}
+
+func (a *Anonymous) HasPermission(database repository.ULID, ag repository.AccessGrant) bool {
+	// Only the view bit is ever granted, and only on public databases
+	return (ag&repository.AccessView) != 0 && a.isPublic(context.Background(), database)
+}
+
+func (a *Anonymous) GetUserULID() repository.ULID {
+	return ""
+}
+
+func (a *Anonymous) GetAllPermissions(ctx context.Context) (map[repository.ULID]repository.AccessGrant, error) {
+	dbs, err := a.Repo.GetDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	perms := make(map[repository.ULID]repository.AccessGrant)
+	for _, db := range dbs {
+		if db.Config.PublicRead {
+			perms[db.ID] = repository.AccessView
+		}
+	}
+	return perms, nil
+}
@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of a Dispatcher: the events it queues and the workers delivering them.
+const (
+	DefaultQueueSize = 1000
+	DefaultWorkers   = 2
+)
+
+// droppedWarningInterval is the least time between two warnings about dropped events.
+const droppedWarningInterval = time.Minute
+
+// Stats reports the delivery of audit events since the start.
+type Stats struct {
+	Queued       int    // events waiting for delivery
+	Dropped      uint64 // events dropped because the queue was full or the dispatcher closed
+	SinkFailures uint64 // deliveries to a sink that panicked
+}
+
+type event struct {
+	ctx      context.Context
+	action   string
+	actor    string
+	resource string
+	details  map[string]any
+}
+
+// Dispatcher is an AuditLogger that queues events and delivers them to its sinks in the background, so a
+// slow sink does not delay the request that logs. The events of an actor are delivered by the same worker,
+// in the order they were logged. If the queue is full, events are dropped and counted instead of blocking.
+type Dispatcher struct {
+	sinks  []AuditLogger
+	queues []chan event
+	logger *slog.Logger
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex // Close must not close a queue Log is sending to
+	closed bool
+
+	dropped      atomic.Uint64
+	sinkFailures atomic.Uint64
+	lastWarning  atomic.Int64 // Unix nanoseconds of the last warning about dropped events
+}
+
+// NewDispatcher starts the workers delivering to the sinks. The queue is shared evenly by the workers,
+// values <= 0 select DefaultQueueSize and DefaultWorkers.
+func NewDispatcher(logger *slog.Logger, queueSize, workers int, sinks ...AuditLogger) *Dispatcher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	d := &Dispatcher{sinks: sinks, logger: logger, queues: make([]chan event, workers)}
+	for i := range d.queues {
+		d.queues[i] = make(chan event, max(1, queueSize/workers))
+		d.wg.Add(1)
+		go d.run(d.queues[i])
+	}
+	return d
+}
+
+// Log queues the event and returns immediately. The sinks receive a context that is not cancelled with
+// the request, but keeps its values.
+func (d *Dispatcher) Log(ctx context.Context, action string, actor string, resource string, details map[string]any) {
+	e := event{ctx: context.WithoutCancel(ctx), action: action, actor: actor, resource: resource, details: details}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.drop(e)
+		return
+	}
+	select {
+	case d.queues[d.shard(actor)] <- e:
+	default:
+		d.drop(e)
+	}
+}
+
+// Close stops accepting events and waits until the queued ones are delivered or ctx is done.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, queue := range d.queues {
+			close(queue)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d audit events were not delivered: %w", d.Stats().Queued, ctx.Err())
+	}
+}
+
+// Stats reports the queued, dropped and failed events.
+func (d *Dispatcher) Stats() Stats {
+	stats := Stats{Dropped: d.dropped.Load(), SinkFailures: d.sinkFailures.Load()}
+	for _, queue := range d.queues {
+		stats.Queued += len(queue)
+	}
+	return stats
+}
+
+// shard returns the queue of an actor, so its events stay in order.
+func (d *Dispatcher) shard(actor string) int {
+	h := fnv.New32a()
+	h.Write([]byte(actor))
+	return int(h.Sum32() % uint32(len(d.queues)))
+}
+
+// drop counts a dropped event and warns at most once per droppedWarningInterval.
+func (d *Dispatcher) drop(e event) {
+	dropped := d.dropped.Add(1)
+
+	now := time.Now().UnixNano()
+	last := d.lastWarning.Load()
+	if now-last < int64(droppedWarningInterval) || !d.lastWarning.CompareAndSwap(last, now) {
+		return
+	}
+	d.logger.Warn("Audit queue is full or closed, dropping audit events", "action", e.action, "actor", e.actor, "dropped_total", dropped)
+}
+
+func (d *Dispatcher) run(queue <-chan event) {
+	defer d.wg.Done()
+	for e := range queue {
+		for _, sink := range d.sinks {
+			d.deliver(sink, e)
+		}
+	}
+}
+
+// deliver passes the event to a sink. A panicking sink is counted and logged, the other sinks and later
+// events are not affected.
+func (d *Dispatcher) deliver(sink AuditLogger, e event) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.sinkFailures.Add(1)
+			d.logger.Error("Audit sink failed", "sink", fmt.Sprintf("%T", sink), "action", e.action, "actor", e.actor, "panic", r)
+		}
+	}()
+	sink.Log(e.ctx, e.action, e.actor, e.resource, e.details)
+}
@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink keeps the actions it received, optionally waiting for release before each of them.
+type recordingSink struct {
+	mu      sync.Mutex
+	actions map[string][]string // actor -> actions
+	release chan struct{}
+}
+
+func (s *recordingSink) Log(_ context.Context, action string, actor string, _ string, _ map[string]any) {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.actions == nil {
+		s.actions = make(map[string][]string)
+	}
+	s.actions[actor] = append(s.actions[actor], action)
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, actions := range s.actions {
+		n += len(actions)
+	}
+	return n
+}
+
+type panickingSink struct{}
+
+func (panickingSink) Log(context.Context, string, string, string, map[string]any) {
+	panic("sink is broken")
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestDispatcherOverflow(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	d := NewDispatcher(discardLogger(), 2, 1, sink)
+
+	// The worker blocks on the first event, 2 more fill the queue, the rest is dropped without blocking
+	d.Log(context.Background(), "event.0", "alice", "", nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for d.Stats().Queued != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	start := time.Now()
+	for i := 1; i < 6; i++ {
+		d.Log(context.Background(), fmt.Sprintf("event.%d", i), "alice", "", nil)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Log not to block, took %v", elapsed)
+	}
+	if stats := d.Stats(); stats.Queued != 2 || stats.Dropped != 3 {
+		t.Errorf("expected 2 queued and 3 dropped events, got %+v", stats)
+	}
+
+	close(sink.release)
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if got := sink.actions["alice"]; len(got) != 3 || got[0] != "event.0" || got[1] != "event.1" || got[2] != "event.2" {
+		t.Errorf("expected the first 3 events in order, got %v", got)
+	}
+
+	// Events after the close are dropped as well
+	d.Log(context.Background(), "late", "alice", "", nil)
+	if stats := d.Stats(); stats.Dropped != 4 {
+		t.Errorf("expected the late event to be dropped, got %+v", stats)
+	}
+}
+
+func TestDispatcherFlushOnClose(t *testing.T) {
+	sink := &recordingSink{}
+	d := NewDispatcher(discardLogger(), 1000, 4, sink)
+
+	actors := []string{"alice", "bob", "carol"}
+	for i := range 300 {
+		d.Log(context.Background(), fmt.Sprintf("event.%d", i), actors[i%len(actors)], "", nil)
+	}
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if got := sink.count(); got != 300 {
+		t.Fatalf("expected all 300 events to be delivered, got %d", got)
+	}
+	// The events of an actor keep their order
+	for i, actor := range actors {
+		for j, action := range sink.actions[actor] {
+			if want := fmt.Sprintf("event.%d", i+j*len(actors)); action != want {
+				t.Fatalf("expected %s as event %d of %s, got %s", want, j, actor, action)
+			}
+		}
+	}
+}
+
+func TestDispatcherCloseTimeout(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	defer close(sink.release)
+	d := NewDispatcher(discardLogger(), 10, 1, sink)
+
+	d.Log(context.Background(), "stuck", "alice", "", nil)
+	d.Log(context.Background(), "waiting", "alice", "", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Close(ctx); err == nil {
+		t.Error("expected an error when the queue cannot be delivered in time")
+	}
+}
+
+func TestDispatcherPanickingSink(t *testing.T) {
+	sink := &recordingSink{}
+	d := NewDispatcher(discardLogger(), 10, 1, panickingSink{}, sink)
+
+	for i := range 3 {
+		d.Log(context.Background(), fmt.Sprintf("event.%d", i), "alice", "", nil)
+	}
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if got := sink.count(); got != 3 {
+		t.Errorf("expected the other sink to receive all 3 events, got %d", got)
+	}
+	if stats := d.Stats(); stats.SinkFailures != 3 || stats.Dropped != 0 {
+		t.Errorf("expected 3 sink failures, got %+v", stats)
+	}
+}
+
+func TestDispatcherKeepsContextValues(t *testing.T) {
+	type key struct{}
+	var got any
+	sink := sinkFunc(func(ctx context.Context) { got = ctx.Value(key{}) })
+	d := NewDispatcher(discardLogger(), 10, 1, sink)
+
+	// The request ends before the event is delivered
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "request-1"))
+	d.Log(ctx, "event", "alice", "", nil)
+	cancel()
+
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if got != "request-1" {
+		t.Errorf("expected the context value of the request, got %v", got)
+	}
+}
+
+type sinkFunc func(ctx context.Context)
+
+func (f sinkFunc) Log(ctx context.Context, _ string, _ string, _ string, _ map[string]any) {
+	if ctx.Err() != nil {
+		panic("the context of the sink was cancelled")
+	}
+	f(ctx)
+}
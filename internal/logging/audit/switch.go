@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"mediahub_oss/internal/repository"
+	"sync/atomic"
+)
+
+// Switch is an AuditLogger that can be turned on and off while the server runs. The events are delivered
+// to the logger of the configured type in the background, see Dispatcher.
+type Switch struct {
+	dispatcher *Dispatcher
+	enabled    atomic.Bool
+}
+
+// NewSwitch creates the logger of the given type, which only receives events while the switch is enabled.
+// Up to queueSize events wait for their delivery, DefaultQueueSize if it is <= 0.
+func NewSwitch(enabled bool, ltype string, logger *slog.Logger, repo repository.Repository, queueSize int) *Switch {
+	s := &Switch{dispatcher: NewDispatcher(logger, queueSize, DefaultWorkers, NewAuditLogger(true, ltype, logger, repo))}
+	s.enabled.Store(enabled)
+	return s
+}
+
+func (s *Switch) Log(ctx context.Context, action string, actor string, resource string, details map[string]any) {
+	if s.enabled.Load() {
+		s.dispatcher.Log(ctx, action, actor, resource, details)
+	}
+}
+
+// SetEnabled turns audit logging on or off.
+func (s *Switch) SetEnabled(enabled bool) {
+	s.enabled.Store(enabled)
+}
+
+// Enabled reports whether events are logged.
+func (s *Switch) Enabled() bool {
+	return s.enabled.Load()
+}
+
+// Stats reports the delivery of the events.
+func (s *Switch) Stats() Stats {
+	return s.dispatcher.Stats()
+}
+
+// Close delivers the queued events, at most until ctx is done. Later events are dropped.
+func (s *Switch) Close(ctx context.Context) error {
+	return s.dispatcher.Close(ctx)
+}
@@ -6,25 +6,16 @@ import (
 	"strings"
 )
 
+// level is shared by all loggers created with NewLogger, so SetLevel changes it at runtime.
+var level slog.LevelVar
+
 // NewLogger initializes the logger with a specific level.
 func NewLogger(levelStr string) *slog.Logger {
-	var level slog.Level
-
-	// Map strings to slog Levels.
-	switch strings.ToLower(levelStr) {
-	case "debug":
-		level = slog.LevelDebug
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
+	level.Set(ParseLevel(levelStr))
 
 	// Create the Handler Options with the chosen level
 	opts := &slog.HandlerOptions{
-		Level: level,
+		Level: &level,
 		// Optional: AddSource: true, // Uncomment if you want file:line number in logs
 	}
 
@@ -32,3 +23,22 @@ func NewLogger(levelStr string) *slog.Logger {
 
 	return slog.New(handler)
 }
+
+// Level returns the level of the loggers created with NewLogger.
+func Level() *slog.LevelVar {
+	return &level
+}
+
+// ParseLevel maps "debug", "warn" and "error" to their slog levels, anything else is info.
+func ParseLevel(levelStr string) slog.Level {
+	switch strings.ToLower(levelStr) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
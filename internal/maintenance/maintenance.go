@@ -0,0 +1,139 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"mediahub_oss/internal/repository"
+)
+
+// DefaultMessage is returned to rejected write requests if maintenance was enabled without a message.
+const DefaultMessage = "The server is in maintenance, please try again later"
+
+// RetryAfter is sent as Retry-After header with the rejected write requests.
+const RetryAfter = 60 * time.Second
+
+// drainPollInterval is how often Drain checks the running background work.
+const drainPollInterval = 100 * time.Millisecond
+
+// Pauser is a background service that stops starting new work while maintenance is enabled.
+type Pauser interface {
+	SetPaused(paused bool)
+}
+
+// WorkerCounter reports the background work still running, e.g. the processing.Processor.
+type WorkerCounter interface {
+	ActiveWorkers() int
+}
+
+// Mode is the maintenance mode of the server. While it is enabled, write requests are rejected
+// (see httpserver.MaintenanceMiddleware) and the background services are paused, so e.g. a snapshot
+// of the database and the storage is consistent. The state is persisted, a restart stays in maintenance.
+type Mode struct {
+	Repo    repository.Repository
+	Logger  *slog.Logger
+	Workers WorkerCounter // optional, Drain returns at once without it
+	Pausers []Pauser
+
+	mu    sync.RWMutex
+	state repository.MaintenanceMode
+}
+
+func NewMode(repo repository.Repository, logger *slog.Logger, workers WorkerCounter, pausers ...Pauser) *Mode {
+	return &Mode{
+		Repo:    repo,
+		Logger:  logger,
+		Workers: workers,
+		Pausers: pausers,
+	}
+}
+
+// Restore applies the persisted state, it is called once on startup before the background services run.
+func (m *Mode) Restore(ctx context.Context) error {
+	state, err := m.Repo.GetMaintenanceMode(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load maintenance mode: %w", err)
+	}
+	m.mu.Lock()
+	m.setState(state)
+	m.mu.Unlock()
+
+	if state.Enabled {
+		m.Logger.Warn("Server is in maintenance, write requests are rejected until it is disabled", "since", state.Since, "message", state.Message)
+	}
+	return nil
+}
+
+// State returns the current maintenance state.
+func (m *Mode) State() repository.MaintenanceMode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Set enables or disables maintenance. Enabling it again only replaces the message.
+// The state is persisted first, if that fails the running state is kept.
+func (m *Mode) Set(ctx context.Context, enabled bool, message string) (repository.MaintenanceMode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := repository.MaintenanceMode{}
+	if enabled {
+		state.Enabled = true
+		state.Message = strings.TrimSpace(message)
+		if state.Message == "" {
+			state.Message = DefaultMessage
+		}
+		state.Since = m.state.Since
+		if !m.state.Enabled {
+			state.Since = time.Now()
+		}
+	}
+
+	if err := m.Repo.SetMaintenanceMode(ctx, state); err != nil {
+		return m.state, fmt.Errorf("failed to store maintenance mode: %w", err)
+	}
+	m.setState(state)
+
+	m.Logger.Info("Maintenance mode changed", "enabled", state.Enabled, "message", state.Message)
+	return state, nil
+}
+
+// setState applies the state to the running server, the caller holds mu.
+func (m *Mode) setState(state repository.MaintenanceMode) {
+	m.state = state
+	for _, p := range m.Pausers {
+		p.SetPaused(state.Enabled)
+	}
+}
+
+// Drain waits until the background work has finished, the timeout passed or the context is cancelled.
+// It returns the number of workers still running.
+func (m *Mode) Drain(ctx context.Context, timeout time.Duration) int {
+	if m.Workers == nil {
+		return 0
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		running := m.Workers.ActiveWorkers()
+		if running == 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return running
+		case <-deadline.C:
+			return m.Workers.ActiveWorkers()
+		case <-ticker.C:
+		}
+	}
+}
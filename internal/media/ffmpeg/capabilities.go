@@ -20,18 +20,38 @@ func (c *FfmpegConverter) GetOutputMimeTypes(contentType string) []string {
 	return outputs
 }
 
-// CanCreatePreview determines if FFmpeg can generate a visual preview for this file.
+// CanCreatePreview determines if a visual preview can be generated for this file. Text files and
+// WAV waveforms are rendered without FFmpeg, PDFs need a PDF renderer.
 func (c *FfmpegConverter) CanCreatePreview(inputMimeType string) bool {
+	normalized := media.NormalizeMimeType(inputMimeType)
+
+	if media.IsText(normalized) {
+		return true
+	}
+	if media.IsPDF(normalized) {
+		return c.capabilities[media.CapabilityPDFRender]
+	}
+	if media.IsWAV(normalized) {
+		return true // drawn in Go without a working waveform filter
+	}
+
 	if !c.IsFFmpegAvailable() {
 		return false
 	}
 
-	normalized := media.NormalizeMimeType(inputMimeType)
+	// Audio previews rely on the waveform filter, which may have failed the self-test
+	if strings.HasPrefix(normalized, "audio/") {
+		return c.capabilities[media.CapabilityWaveform]
+	}
+
+	// HEIC needs the HEVC decoder, which not every build has
+	if media.IsHEIC(normalized) {
+		return c.capabilities[media.CapabilityHEICDecode]
+	}
 
 	// Return the evaluation directly
 	return strings.HasPrefix(normalized, "image/") ||
-		strings.HasPrefix(normalized, "video/") ||
-		strings.HasPrefix(normalized, "audio/")
+		strings.HasPrefix(normalized, "video/")
 }
 
 // CanConvert checks if a conversion is possible based on our supportedConversions map.
@@ -44,7 +64,7 @@ func (c *FfmpegConverter) CanConvert(inputMimeType string, outputMimeType string
 	canConvert := false
 
 	// check if we can convert
-	if c.IsFFmpegAvailable() {
+	if c.IsFFmpegAvailable() && (!media.IsHEIC(normInput) || c.capabilities[media.CapabilityHEICDecode]) {
 		contentType, _ := media.GetContentType(normInput)
 
 		if contentType != "file" {
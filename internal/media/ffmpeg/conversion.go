@@ -3,6 +3,7 @@ package ffmpeg
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -19,6 +20,7 @@ type ConversionProfile struct {
 }
 
 // ConvertFile transcodes a large file using pure disk-to-disk direct I/O.
+// If ctx carries a media.ProgressFunc, the progress is reported through it.
 func (c *FfmpegConverter) ConvertFile(ctx context.Context, inputPath string, outputPath string, inputMimeType, targetMimeType string) error {
 	ffmpegPath, err := c.GetFFmpegPath()
 	if err != nil {
@@ -27,14 +29,35 @@ func (c *FfmpegConverter) ConvertFile(ctx context.Context, inputPath string, out
 
 	normTarget := media.NormalizeMimeType(targetMimeType)
 
-	// -y to overwrite existing output files automatically, -i to read direct from disk
-	args := []string{"-y", "-i", inputPath}
-
 	// Get the required codec and format arguments (isStream = false)
 	formatArgs, err := c.buildConversionArgs(normTarget)
 	if err != nil {
 		return err
 	}
+
+	report := media.ProgressFromContext(ctx)
+	if report != nil && !c.progressUnsupported.Load() {
+		err := c.runFileConversion(ctx, ffmpegPath, inputPath, outputPath, targetMimeType, formatArgs, report)
+		if !errors.Is(err, errProgressUnsupported) {
+			return err
+		}
+		// Very old FFmpeg builds: convert without progress, the caller only sees the phase
+		c.logger.Warn("FFmpeg does not support -progress, conversion progress will not be reported")
+		c.progressUnsupported.Store(true)
+	}
+	return c.runFileConversion(ctx, ffmpegPath, inputPath, outputPath, targetMimeType, formatArgs, nil)
+}
+
+// runFileConversion executes a disk-to-disk conversion, parsing '-progress' output if report is set.
+func (c *FfmpegConverter) runFileConversion(ctx context.Context, ffmpegPath, inputPath, outputPath, targetMimeType string, formatArgs []string, report media.ProgressFunc) error {
+	var args []string
+	if report != nil {
+		// Machine readable progress on stdout, which is otherwise unused for file outputs
+		args = append(args, "-progress", "pipe:1", "-nostats")
+	}
+
+	// -y to overwrite existing output files automatically, -i to read direct from disk
+	args = append(args, "-y", "-i", inputPath)
 	args = append(args, formatArgs...)
 
 	// Specify the final output path
@@ -43,13 +66,42 @@ func (c *FfmpegConverter) ConvertFile(ctx context.Context, inputPath string, out
 	// Bind the FFmpeg process to the provided context to prevent zombie processes
 	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
 
-	var stderr bytes.Buffer
+	// The sniffer picks up the input duration from the banner, needed to turn the output time into a fraction
+	var stderr durationSniffer
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	started := time.Now()
+	var progressDone chan struct{}
+	if report != nil {
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return fmt.Errorf("failed to create progress pipe: %w", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("ffmpeg conversion error: %w", err)
+		}
+		progressDone = make(chan struct{})
+		go func() {
+			defer close(progressDone)
+			parseProgress(stdout, stderr.duration.Load, report)
+		}()
+	} else if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ffmpeg conversion error: %w", err)
+	}
+
+	// All reads from the progress pipe must be done before Wait closes it
+	if progressDone != nil {
+		<-progressDone
+	}
+	if err := cmd.Wait(); err != nil {
+		if report != nil && isProgressUnsupported(stderr.String()) {
+			return errProgressUnsupported
+		}
 		c.logger.Error("FFmpeg file conversion failed", "error", err, "stderr", stderr.String(), "target", targetMimeType)
+		err = c.recordCommand(ctx, media.OperationConversion, cmd, started, err, stderr.String(), map[string]string{inputPath: "<input>", outputPath: "<output>"})
 		return fmt.Errorf("ffmpeg conversion error: %w", err)
 	}
+	c.recordCommand(ctx, media.OperationConversion, cmd, started, nil, stderr.String(), map[string]string{inputPath: "<input>", outputPath: "<output>"})
 
 	return nil
 }
@@ -98,7 +150,10 @@ func (c *FfmpegConverter) ConvertStream(ctx context.Context, inputData io.ReadSe
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	started := time.Now()
+	err = cmd.Run()
+	err = c.recordCommand(ctx, media.OperationConversion, cmd, started, err, stderr.String(), map[string]string{fullURL: "<input>", tmpPath: "<output>"})
+	if err != nil {
 		c.logger.Error("FFmpeg stream conversion failed", "error", err, "stderr", stderr.String(), "target", targetMimeType)
 		return fmt.Errorf("ffmpeg conversion error: %w", err)
 	}
@@ -4,18 +4,24 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"mediahub_oss/internal/media"
 	"mediahub_oss/internal/shared/customerrors"
 	"os"
 	"os/exec"
 	"strings"
+	"sync/atomic"
 )
 
 type FfmpegConverter struct {
 	ffmpegPath           string
 	ffprobePath          string
+	pdfRendererPath      string // pdftoppm or mutool, "" if PDFs get no preview
 	logger               *slog.Logger
 	supportedConversions map[string]ConversionProfile
+	capabilities         map[string]bool
 	localServer          *LocalStreamServer
+	progressUnsupported  atomic.Bool // set once FFmpeg rejected '-progress'
+	recorder             media.OperationRecorder
 }
 
 // Updated signature: now returns a pointer and an error
@@ -95,6 +101,7 @@ func NewFFMPEGConverter(ffmpegConfiguredPath string, ffprobeConfiguredPath strin
 
 	// Probe FFmpeg and set up hardware acceleration
 	converter.initConversions()
+	converter.initCapabilities()
 
 	return converter, nil
 }
@@ -0,0 +1,35 @@
+package ffmpeg
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// detectHEICDecode reports whether FFmpeg can decode HEIC/HEIF images. The container is read by
+// the mov demuxer of every build, the HEVC decoder is optional.
+func (c *FfmpegConverter) detectHEICDecode() bool {
+	if c.ffmpegPath == "" {
+		return false
+	}
+	out, err := exec.Command(c.ffmpegPath, "-hide_banner", "-decoders").Output()
+	if err != nil {
+		c.logger.Warn("Failed to probe ffmpeg decoders, HEIC uploads will be rejected", "error", err)
+		return false
+	}
+	if !hasDecoder(string(out), "hevc") {
+		c.logger.Warn("FFmpeg has no HEVC decoder, HEIC uploads will be rejected")
+		return false
+	}
+	return true
+}
+
+// hasDecoder checks the output of 'ffmpeg -decoders' for a decoder, e.g. " V....D hevc   HEVC ...".
+func hasDecoder(decoders string, name string) bool {
+	for _, line := range strings.Split(decoders, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package ffmpeg
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"mediahub_oss/internal/media"
+)
+
+func TestHEICDecodeDetection(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+
+	decoders := " V....D hevc                 HEVC (High Efficiency Video Coding)\n" +
+		" V....D mjpeg                MJPEG (Motion JPEG)\n"
+	for name, tc := range map[string]struct {
+		output string
+		want   bool
+	}{
+		"with hevc":    {output: " ------\n" + decoders, want: true},
+		"without hevc": {output: " ------\n V....D mjpeg                MJPEG (Motion JPEG)\n"},
+		"similar name": {output: " V....D hevc_cuvid           Nvidia CUVID HEVC decoder\n"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			fakeFFmpeg := filepath.Join(dir, "ffmpeg")
+			if err := os.WriteFile(fakeFFmpeg, []byte("#!/bin/sh\ncat <<'EOF'\n"+tc.output+"EOF\n"), 0755); err != nil {
+				t.Fatalf("failed to write fake ffmpeg: %v", err)
+			}
+			c := &FfmpegConverter{ffmpegPath: fakeFFmpeg, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			if got := c.detectHEICDecode(); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHEICConversionNeedsCapability(t *testing.T) {
+	for _, supported := range []bool{true, false} {
+		c := &FfmpegConverter{
+			ffmpegPath:   "/usr/bin/ffmpeg",
+			capabilities: map[string]bool{media.CapabilityHEICDecode: supported},
+			supportedConversions: map[string]ConversionProfile{
+				"image/jpeg": {ContentType: "image", Args: []string{"-c:v", "mjpeg", "-f", "image2"}},
+			},
+		}
+
+		if check := c.CanConvert("image/heic", "image/jpeg"); check.CanConvert != supported {
+			t.Errorf("heic_decode=%v: expected CanConvert %v, got %+v", supported, supported, check)
+		}
+		if got := c.CanCreatePreview("image/heif"); got != supported {
+			t.Errorf("heic_decode=%v: expected CanCreatePreview %v, got %v", supported, supported, got)
+		}
+		if check := c.CanConvert("image/png", "image/jpeg"); !check.CanConvert {
+			t.Errorf("heic_decode=%v: expected other images to convert, got %+v", supported, check)
+		}
+	}
+}
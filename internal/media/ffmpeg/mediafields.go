@@ -67,7 +67,10 @@ func (c *FfmpegConverter) runFFprobe(ctx context.Context, inputSource string, co
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	started := time.Now()
+	err = cmd.Run()
+	err = c.recordCommand(ctx, media.OperationProbe, cmd, started, err, stderr.String(), map[string]string{inputSource: "<input>"})
+	if err != nil {
 		c.logger.Error("ffprobe extraction failed", "error", err, "stderr", stderr.String(), "source", inputSource)
 		return nil, fmt.Errorf("ffprobe error: %w", err)
 	}
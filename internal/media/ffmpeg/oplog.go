@@ -0,0 +1,84 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"mediahub_oss/internal/media"
+)
+
+// SetOperationRecorder sets the recorder that receives a record of every conversion, preview and
+// probe. It must be called before the converter is used.
+func (c *FfmpegConverter) SetOperationRecorder(recorder media.OperationRecorder) {
+	c.recorder = recorder
+}
+
+// recordCommand reports a finished command to the operation recorder and turns a failure into a
+// *media.CommandError carrying the end of stderr. The file paths and URLs in redact are replaced
+// by '<input>', '<output>', ... in the recorded command line and stderr.
+func (c *FfmpegConverter) recordCommand(ctx context.Context, kind string, cmd *exec.Cmd, started time.Time, runErr error, stderr string, redact map[string]string) error {
+	replacer := newRedactor(redact)
+	tail := replacer.Replace(media.StderrTail(stderr))
+
+	exitCode := 0
+	if runErr != nil {
+		exitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(runErr, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+
+	if c.recorder != nil {
+		args := make([]string, len(cmd.Args))
+		args[0] = filepath.Base(cmd.Args[0])
+		for i, arg := range cmd.Args[1:] {
+			args[i+1] = replacer.Replace(arg)
+		}
+
+		target := media.OperationTargetFromContext(ctx)
+		op := media.Operation{
+			Kind:         kind,
+			DatabaseID:   target.DatabaseID,
+			DatabaseName: target.DatabaseName,
+			EntryID:      target.EntryID,
+			Command:      strings.Join(args, " "),
+			StartedAt:    started,
+			Duration:     time.Since(started),
+			ExitCode:     exitCode,
+			StderrTail:   tail,
+		}
+		if runErr != nil {
+			op.Error = runErr.Error()
+		}
+		c.recorder.RecordOperation(op)
+	}
+
+	if runErr == nil {
+		return nil
+	}
+	return &media.CommandError{Kind: kind, ExitCode: exitCode, StderrTail: tail, Err: runErr}
+}
+
+// newRedactor replaces each key of redact by its value. Longer keys go first, so a path is not
+// partially replaced by one of its prefixes.
+func newRedactor(redact map[string]string) *strings.Replacer {
+	keys := make([]string, 0, len(redact))
+	for k := range redact {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b string) int { return len(b) - len(a) })
+
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k, redact[k])
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,84 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"mediahub_oss/internal/media"
+)
+
+func TestConversionFailureIsRecorded(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+
+	// A fake ffmpeg that fails like the real one on a broken input ($3 is the path after -y -i)
+	dir := t.TempDir()
+	fakeFFmpeg := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\n" +
+		"echo \"$3: Invalid data found when processing input\" >&2\n" +
+		"exit 1\n"
+	if err := os.WriteFile(fakeFFmpeg, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg: %v", err)
+	}
+	input := filepath.Join(dir, "broken.png")
+	if err := os.WriteFile(input, []byte("not a png"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	oplog := media.NewOperationLog(2)
+	c := &FfmpegConverter{
+		ffmpegPath: fakeFFmpeg,
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		supportedConversions: map[string]ConversionProfile{
+			"image/jpeg": {ContentType: "image", Args: []string{"-c:v", "mjpeg", "-f", "image2"}},
+		},
+	}
+	c.SetOperationRecorder(oplog)
+
+	ctx := media.WithOperationTarget(context.Background(), media.OperationTarget{DatabaseID: "01DB", DatabaseName: "photos", EntryID: 42})
+	err := c.ConvertFile(ctx, input, filepath.Join(dir, "out.jpg"), "image/png", "image/jpeg")
+
+	var cmdErr *media.CommandError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("expected a CommandError, got %v", err)
+	}
+	if cmdErr.ExitCode != 1 || cmdErr.StderrTail != "<input>: Invalid data found when processing input\n" {
+		t.Errorf("unexpected error: %+v", cmdErr)
+	}
+
+	ops := oplog.Find("photos", 42)
+	if len(ops) != 1 {
+		t.Fatalf("expected 1 recorded operation, got %d", len(ops))
+	}
+	op := ops[0]
+	if op.Kind != media.OperationConversion || op.DatabaseID != "01DB" || op.ExitCode != 1 || op.Error == "" {
+		t.Errorf("unexpected record: %+v", op)
+	}
+	if op.Command != "ffmpeg -y -i <input> -c:v mjpeg -f image2 <output>" {
+		t.Errorf("expected a redacted command line, got %q", op.Command)
+	}
+	if strings.Contains(op.StderrTail, dir) {
+		t.Errorf("expected the stderr tail without paths, got %q", op.StderrTail)
+	}
+	if ops := oplog.Find("photos", 7); len(ops) != 0 {
+		t.Errorf("expected no record of another entry, got %+v", ops)
+	}
+
+	// The log keeps the newest records only
+	for _, id := range []int64{43, 44} {
+		ctx := media.WithOperationTarget(context.Background(), media.OperationTarget{DatabaseName: "photos", EntryID: id})
+		c.ConvertFile(ctx, input, filepath.Join(dir, "out.jpg"), "image/png", "image/jpeg")
+	}
+	ops = oplog.Find("", 0)
+	if len(ops) != 2 || ops[0].EntryID != 44 || ops[1].EntryID != 43 {
+		t.Errorf("expected the records of 44 and 43, got %+v", ops)
+	}
+}
@@ -0,0 +1,114 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// pdfRenderSize is the longer side of the rendered page, the preview is scaled down from it.
+const pdfRenderSize = 2 * maxPreviewWidth
+
+// SetPDFRenderer enables PDF previews with pdftoppm (poppler) or mutool (MuPDF). The configured
+// path is used if it exists, otherwise both tools are looked up in the PATH. The rendered page is
+// turned into the preview by FFmpeg, so both are needed. It must be called before the converter
+// is used.
+func (c *FfmpegConverter) SetPDFRenderer(configuredPath string) {
+	c.pdfRendererPath = ""
+	if configuredPath != "" {
+		if _, err := os.Stat(configuredPath); err == nil {
+			c.pdfRendererPath = configuredPath
+		} else {
+			c.logger.Warn("Configured pdf_renderer_path not found, falling back to system PATH.", "config_path", configuredPath)
+		}
+	}
+	for _, name := range []string{"pdftoppm", "mutool"} {
+		if c.pdfRendererPath != "" {
+			break
+		}
+		if path, err := exec.LookPath(name); err == nil {
+			c.pdfRendererPath = path
+		}
+	}
+
+	switch {
+	case c.pdfRendererPath == "":
+		c.logger.Info("No PDF renderer (pdftoppm or mutool) found, PDFs get no preview")
+	case !c.IsFFmpegAvailable():
+		c.logger.Warn("PDF previews need FFmpeg, PDFs get no preview", "pdf_renderer", c.pdfRendererPath)
+	default:
+		c.logger.Info("PDF previews enabled", "pdf_renderer", c.pdfRendererPath)
+	}
+	c.capabilities[media.CapabilityPDFRender] = c.pdfRendererPath != "" && c.IsFFmpegAvailable()
+}
+
+// PDFRendererPath returns the path of the PDF renderer, "" if there is none.
+func (c *FfmpegConverter) PDFRendererPath() string {
+	return c.pdfRendererPath
+}
+
+// pdfRenderArgs returns the arguments rendering the first page of a PDF as PNG to stdout.
+func pdfRenderArgs(rendererPath string, inputPath string) []string {
+	size := fmt.Sprint(pdfRenderSize)
+	if strings.HasPrefix(strings.ToLower(filepath.Base(rendererPath)), "mutool") {
+		return []string{"draw", "-q", "-F", "png", "-o", "-", "-w", size, "-h", size, inputPath, "1"}
+	}
+	return []string{"-png", "-f", "1", "-l", "1", "-singlefile", "-scale-to", size, inputPath}
+}
+
+// generatePDFPreview renders the first page of a PDF file and writes its WebP preview.
+func (c *FfmpegConverter) generatePDFPreview(ctx context.Context, inputPath string, outputWriter io.Writer) error {
+	if !c.capabilities[media.CapabilityPDFRender] {
+		return fmt.Errorf("no PDF renderer is available: %w", customerrors.ErrNotImplemented)
+	}
+
+	cmd := exec.CommandContext(ctx, c.pdfRendererPath, pdfRenderArgs(c.pdfRendererPath, inputPath)...)
+	var page, stderr bytes.Buffer
+	cmd.Stdout = &page
+	cmd.Stderr = &stderr
+
+	started := time.Now()
+	err := cmd.Run()
+	err = c.recordCommand(ctx, media.OperationPreview, cmd, started, err, stderr.String(), map[string]string{inputPath: "<input>"})
+	if err != nil {
+		c.logger.Error("PDF page rendering failed", "error", err, "stderr", stderr.String(), "source", inputPath)
+		return fmt.Errorf("pdf rendering error: %w", err)
+	}
+	if page.Len() == 0 {
+		return fmt.Errorf("pdf rendering error: %s produced no image", filepath.Base(c.pdfRendererPath))
+	}
+
+	return c.CreatePreviewFromStream(ctx, bytes.NewReader(page.Bytes()), outputWriter, "image/png")
+}
+
+// generatePDFPreviewFromStream writes the PDF to a temporary file, the renderers need to seek it.
+func (c *FfmpegConverter) generatePDFPreviewFromStream(ctx context.Context, inputData io.Reader, outputWriter io.Writer) error {
+	if !c.capabilities[media.CapabilityPDFRender] {
+		return fmt.Errorf("no PDF renderer is available: %w", customerrors.ErrNotImplemented)
+	}
+
+	tmp, err := os.CreateTemp("", "mediahub-preview-*.pdf")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, inputData)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	return c.generatePDFPreview(ctx, tmp.Name(), outputWriter)
+}
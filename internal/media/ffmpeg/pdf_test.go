@@ -0,0 +1,135 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"mediahub_oss/internal/shared/customerrors"
+
+	"golang.org/x/image/webp"
+)
+
+// samplePDF builds a one-page PDF with a line of text and a correct cross-reference table.
+func samplePDF() []byte {
+	content := "BT /F1 24 Tf 72 720 Td (MediaHub preview test) Tj ET"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestPDFRenderArgs(t *testing.T) {
+	for renderer, want := range map[string][]string{
+		"/usr/bin/pdftoppm": {"-png", "-f", "1", "-l", "1", "-singlefile", "-scale-to", "400", "in.pdf"},
+		"/opt/mupdf/mutool": {"draw", "-q", "-F", "png", "-o", "-", "-w", "400", "-h", "400", "in.pdf", "1"},
+	} {
+		if got := pdfRenderArgs(renderer, "in.pdf"); !slices.Equal(got, want) {
+			t.Errorf("%s: expected %v, got %v", renderer, want, got)
+		}
+	}
+}
+
+func TestDocumentPreviewsWithoutTools(t *testing.T) {
+	c := &FfmpegConverter{
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		capabilities: map[string]bool{},
+	}
+	t.Setenv("PATH", t.TempDir()) // neither pdftoppm nor mutool
+	c.SetPDFRenderer("")
+
+	for mime, want := range map[string]bool{
+		"text/plain":       true,
+		"text/csv":         true,
+		"application/json": true,
+		"application/pdf":  false,
+		"application/zip":  false,
+		"image/png":        false, // needs FFmpeg
+		"audio/wav":        true,  // waveform drawn in Go
+		"audio/mpeg":       false, // needs FFmpeg
+	} {
+		if got := c.CanCreatePreview(mime); got != want {
+			t.Errorf("%s: expected CanCreatePreview %v, got %v", mime, want, got)
+		}
+	}
+
+	// Text previews are rendered without FFmpeg, from memory and from disk
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("line 1\nline 2\n"), 0644); err != nil {
+		t.Fatalf("failed to write text file: %v", err)
+	}
+	for name, create := range map[string]func(w io.Writer) error{
+		"stream": func(w io.Writer) error {
+			return c.CreatePreviewFromStream(context.Background(), strings.NewReader("line 1\nline 2\n"), w, "text/plain")
+		},
+		"file": func(w io.Writer) error {
+			return c.CreatePreviewFromFile(context.Background(), path, w, "text/plain")
+		},
+	} {
+		var out bytes.Buffer
+		if err := create(&out); err != nil {
+			t.Fatalf("%s: failed to create text preview: %v", name, err)
+		}
+		if _, err := webp.Decode(&out); err != nil {
+			t.Errorf("%s: expected a WebP preview: %v", name, err)
+		}
+	}
+
+	// PDFs are a missing dependency, so the entry is settled without preview
+	err := c.CreatePreviewFromStream(context.Background(), bytes.NewReader(samplePDF()), io.Discard, "application/pdf")
+	if !errors.Is(err, customerrors.ErrNotImplemented) {
+		t.Errorf("expected ErrNotImplemented without a PDF renderer, got %v", err)
+	}
+}
+
+// TestPDFPreview renders a real PDF, it needs FFmpeg and pdftoppm or mutool in the PATH.
+func TestPDFPreview(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c, err := NewFFMPEGConverter("", "", logger)
+	if err != nil {
+		t.Fatalf("failed to create converter: %v", err)
+	}
+	defer c.Shutdown(context.Background())
+	c.SetPDFRenderer("")
+	if !c.CanCreatePreview("application/pdf") {
+		t.Skip("FFmpeg or a PDF renderer (pdftoppm, mutool) is not installed")
+	}
+
+	var out bytes.Buffer
+	if err := c.CreatePreviewFromStream(context.Background(), bytes.NewReader(samplePDF()), &out, "application/pdf"); err != nil {
+		t.Fatalf("failed to create PDF preview: %v", err)
+	}
+	img, err := webp.Decode(&out)
+	if err != nil {
+		t.Fatalf("expected a WebP preview: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() > maxPreviewWidth || b.Dy() > maxPreviewHeight || b.Dx() < maxPreviewWidth/2 {
+		t.Errorf("unexpected preview size %v", b)
+	}
+}
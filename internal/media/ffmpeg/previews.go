@@ -5,10 +5,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"time"
 
 	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/media/textpreview"
+	"mediahub_oss/internal/media/waveform"
+	"mediahub_oss/internal/shared/customerrors"
 )
 
 const maxPreviewHeight = 200
@@ -16,13 +20,41 @@ const maxPreviewWidth = 200
 
 // CreatePreviewFromFile generates a WebP preview directly from a file on disk.
 // This is heavily optimized for large files and ensures WebM/MP4 index seeking works natively.
+// Audio previews are drawn in the waveform style of ctx, transparent ones are PNG.
 func (c *FfmpegConverter) CreatePreviewFromFile(ctx context.Context, filepath string, outputWriter io.Writer, inputMimeType string) error {
+	switch {
+	case c.useWaveformFallback(inputMimeType):
+		f, err := os.Open(filepath)
+		if err != nil {
+			return fmt.Errorf("failed to open audio file for preview: %w", err)
+		}
+		defer f.Close()
+		return waveform.Render(outputWriter, f, media.WaveformStyleFromContext(ctx))
+	case media.IsText(inputMimeType):
+		f, err := os.Open(filepath)
+		if err != nil {
+			return fmt.Errorf("failed to open text file for preview: %w", err)
+		}
+		defer f.Close()
+		return textpreview.Render(outputWriter, f)
+	case media.IsPDF(inputMimeType):
+		return c.generatePDFPreview(ctx, filepath, outputWriter)
+	}
 	return c.generatePreview(ctx, filepath, outputWriter, inputMimeType)
 }
 
 // CreatePreviewFromStream generates a WebP preview purely in-memory using the LocalStreamServer.
 // It bypasses physical disk writes while retaining the ability for FFmpeg to safely seek the stream.
 func (c *FfmpegConverter) CreatePreviewFromStream(ctx context.Context, inputData io.ReadSeeker, outputWriter io.Writer, inputMimeType string) error {
+	switch {
+	case c.useWaveformFallback(inputMimeType):
+		return waveform.Render(outputWriter, inputData, media.WaveformStyleFromContext(ctx))
+	case media.IsText(inputMimeType):
+		return textpreview.Render(outputWriter, inputData)
+	case media.IsPDF(inputMimeType):
+		return c.generatePDFPreviewFromStream(ctx, inputData, outputWriter)
+	}
+
 	// Register the stream with the local loopback server with a short Time-To-Live.
 	id, fullURL, err := c.localServer.Register(inputData, 2*time.Minute)
 	if err != nil {
@@ -40,7 +72,7 @@ func (c *FfmpegConverter) CreatePreviewFromStream(ctx context.Context, inputData
 func (c *FfmpegConverter) generatePreview(ctx context.Context, inputSource string, outputWriter io.Writer, inputMimeType string) error {
 	ffmpegPath, err := c.GetFFmpegPath()
 	if err != nil {
-		return fmt.Errorf("ffmpeg is not available: %w", err)
+		return fmt.Errorf("ffmpeg is not available: %w: %v", customerrors.ErrNotImplemented, err)
 	}
 
 	contentType, err := media.GetContentType(inputMimeType)
@@ -50,6 +82,7 @@ func (c *FfmpegConverter) generatePreview(ctx context.Context, inputSource strin
 
 	var filterArgs []string
 	var preInputArgs []string
+	outputCodec := "libwebp"
 
 	switch contentType {
 	case "image", "video":
@@ -64,14 +97,14 @@ func (c *FfmpegConverter) generatePreview(ctx context.Context, inputSource strin
 			"-vf", fmt.Sprintf("crop=min(iw\\,2.5*ih):min(ih\\,2.5*iw),scale='%d:%d':force_original_aspect_ratio=decrease", maxPreviewWidth, maxPreviewHeight),
 		}
 	case "audio":
-		// Generate a 200x120 waveform image (using a pleasant blue color)
-		filterArgs = []string{
-			"-filter_complex", "showwavespic=s=200x120:colors=#1E90FF",
-			"-frames:v", "1",
+		style := media.WaveformStyleFromContext(ctx)
+		filterArgs = []string{"-filter_complex", waveformFilter(style), "-frames:v", "1"}
+		if style.Transparent() {
+			outputCodec = "png" // keeps the alpha channel in every viewer
 		}
 	case "file":
-		// Standard files do not support previews
-		return fmt.Errorf("preview generation is not supported for generic files")
+		// Text files and PDFs are handled by the callers, other files do not support previews
+		return fmt.Errorf("preview generation is not supported for %s files", inputMimeType)
 	default:
 		return fmt.Errorf("unknown content type for preview: %s", contentType)
 	}
@@ -82,8 +115,8 @@ func (c *FfmpegConverter) generatePreview(ctx context.Context, inputSource strin
 	args = append(args, "-i", inputSource)
 	args = append(args, filterArgs...)
 
-	// Force the output format to WebP using image2pipe to ensure it can be piped safely without seeking
-	args = append(args, "-c:v", "libwebp", "-f", "image2pipe", "pipe:1")
+	// Force the output format (WebP, PNG for transparent waveforms) using image2pipe to ensure it can be piped safely without seeking
+	args = append(args, "-c:v", outputCodec, "-f", "image2pipe", "pipe:1")
 
 	// Bind the FFmpeg process to the provided context
 	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
@@ -95,7 +128,10 @@ func (c *FfmpegConverter) generatePreview(ctx context.Context, inputSource strin
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	started := time.Now()
+	err = cmd.Run()
+	err = c.recordCommand(ctx, media.OperationPreview, cmd, started, err, stderr.String(), map[string]string{inputSource: "<input>"})
+	if err != nil {
 		c.logger.Error("FFmpeg preview generation failed",
 			"error", err,
 			"stderr", stderr.String(),
@@ -107,3 +143,20 @@ func (c *FfmpegConverter) generatePreview(ctx context.Context, inputSource strin
 
 	return nil
 }
+
+// waveformFilter draws the waveform of the audio input in the given style. Without background,
+// showwavespic leaves the image transparent; a background is a color source laid under it.
+func waveformFilter(style media.WaveformStyle) string {
+	waves := fmt.Sprintf("showwavespic=s=%dx%d:colors=%s", style.Width, style.Height, style.Color)
+	if style.Background == "" || style.Transparent() {
+		return waves
+	}
+	return fmt.Sprintf("%s[waves];color=c=%s:s=%dx%d[bg];[bg][waves]overlay=shortest=1:format=auto,format=yuv420p",
+		waves, style.Background, style.Width, style.Height)
+}
+
+// useWaveformFallback reports whether a WAV preview is drawn in Go, as FFmpeg is missing or
+// failed the waveform self-test.
+func (c *FfmpegConverter) useWaveformFallback(inputMimeType string) bool {
+	return media.IsWAV(inputMimeType) && (!c.IsFFmpegAvailable() || !c.capabilities[media.CapabilityWaveform])
+}
@@ -0,0 +1,79 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// errProgressUnsupported is returned if FFmpeg does not know the '-progress' option.
+var errProgressUnsupported = errors.New("ffmpeg does not support -progress")
+
+// durationRegex matches the input duration FFmpeg prints in its banner, e.g. "Duration: 01:02:03.45,".
+var durationRegex = regexp.MustCompile(`Duration: (\d+):(\d{2}):(\d{2}(?:\.\d+)?)`)
+
+// durationSniffer collects FFmpeg's stderr and remembers the first input duration it contains.
+// Write is only called by the goroutine copying stderr, the duration may be read concurrently.
+type durationSniffer struct {
+	stderr   bytes.Buffer
+	duration atomic.Int64 // microseconds, 0 while unknown
+}
+
+func (s *durationSniffer) Write(p []byte) (int, error) {
+	n, err := s.stderr.Write(p)
+	if s.duration.Load() == 0 {
+		if m := durationRegex.FindSubmatch(s.stderr.Bytes()); m != nil {
+			hours, _ := strconv.Atoi(string(m[1]))
+			minutes, _ := strconv.Atoi(string(m[2]))
+			seconds, _ := strconv.ParseFloat(string(m[3]), 64)
+			d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+			s.duration.Store(d.Microseconds())
+		}
+	}
+	return n, err
+}
+
+func (s *durationSniffer) String() string {
+	return s.stderr.String()
+}
+
+// parseProgress reads the key=value blocks written by '-progress' and reports the output time
+// as fraction of the input duration. Nothing is reported while the duration is unknown
+// (e.g. for streams without a duration), apart from the final 'progress=end'.
+func parseProgress(r io.Reader, duration func() int64, report func(float64)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		// out_time_ms is in microseconds as well, older FFmpeg versions only write that one
+		case "out_time_us", "out_time_ms":
+			outTime, err := strconv.ParseInt(value, 10, 64)
+			total := duration()
+			if err != nil || outTime < 0 || total <= 0 {
+				continue
+			}
+			report(min(1, float64(outTime)/float64(total)))
+		case "progress":
+			if value == "end" {
+				report(1)
+			}
+		}
+	}
+	// Drain, so FFmpeg never blocks on a full pipe
+	io.Copy(io.Discard, r)
+}
+
+// isProgressUnsupported checks if FFmpeg rejected the '-progress' option.
+func isProgressUnsupported(stderr string) bool {
+	return strings.Contains(stderr, "Unrecognized option 'progress'")
+}
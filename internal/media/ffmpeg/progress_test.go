@@ -0,0 +1,49 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseProgress(t *testing.T) {
+	// Banner on stderr, split across writes like a pipe would deliver it
+	var sniffer durationSniffer
+	fmt.Fprint(&sniffer, "Input #0, wav, from 'in.wav':\n  Durat")
+	fmt.Fprint(&sniffer, "ion: 00:01:40.00, bitrate: 1411 kb/s\n")
+	if got := sniffer.duration.Load(); got != 100_000_000 {
+		t.Fatalf("expected a duration of 100s, got %dus", got)
+	}
+
+	// Fake '-progress pipe:1' output: an older block with out_time_ms only, a newer one and the end
+	stream := strings.Join([]string{
+		"total_size=1024",
+		"out_time_ms=25000000",
+		"progress=continue",
+		"total_size=4096",
+		"out_time_us=75000000",
+		"out_time=00:01:15.000000",
+		"progress=continue",
+		"out_time_us=N/A",
+		"progress=end",
+	}, "\n")
+
+	var reported []float64
+	parseProgress(strings.NewReader(stream), sniffer.duration.Load, func(f float64) { reported = append(reported, f) })
+
+	expected := []float64{0.25, 0.75, 1}
+	if fmt.Sprint(reported) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, reported)
+	}
+
+	// Without a known duration only the end is reported
+	reported = nil
+	parseProgress(strings.NewReader(stream), func() int64 { return 0 }, func(f float64) { reported = append(reported, f) })
+	if fmt.Sprint(reported) != "[1]" {
+		t.Errorf("expected only the end to be reported, got %v", reported)
+	}
+
+	if !isProgressUnsupported("Unrecognized option 'progress'.\nError splitting the argument list: Option not found") {
+		t.Errorf("expected the missing option to be detected")
+	}
+}
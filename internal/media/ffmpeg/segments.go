@@ -0,0 +1,67 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// segmentFormatArgs holds the codec and muxer arguments for every format in media.AudioSegmentFormats.
+// All of them can be written to a non-seekable pipe.
+var segmentFormatArgs = map[string][]string{
+	"opus": {"-c:a", "libopus", "-f", "opus"},
+	"flac": {"-c:a", "flac", "-f", "flac"},
+	"wav":  {"-c:a", "pcm_s16le", "-f", "wav"},
+}
+
+// ExtractAudioSegment encodes the given time range of the input and pipes it directly to the output,
+// without a temporary file. Start and duration are given in seconds.
+func (c *FfmpegConverter) ExtractAudioSegment(ctx context.Context, inputData io.ReadSeeker, outputWriter io.Writer, start, duration float64, format string) error {
+	ffmpegPath, err := c.GetFFmpegPath()
+	if err != nil {
+		return fmt.Errorf("ffmpeg is not available: %w", customerrors.ErrNotImplemented)
+	}
+
+	formatArgs, ok := segmentFormatArgs[format]
+	if !ok {
+		return fmt.Errorf("unsupported segment format: %s", format)
+	}
+
+	// Register the stream with the local loopback server, so FFmpeg can seek in it.
+	id, fullURL, err := c.localServer.Register(inputData, 30*time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to register stream: %w", err)
+	}
+	defer c.localServer.Unregister(id)
+
+	// -ss and -t before -i seek in the input instead of decoding everything up to the start
+	args := []string{
+		"-v", "error",
+		"-ss", strconv.FormatFloat(start, 'f', -1, 64),
+		"-t", strconv.FormatFloat(duration, 'f', -1, 64),
+		"-i", fullURL,
+		"-vn", // drop embedded cover art
+	}
+	args = append(args, formatArgs...)
+	args = append(args, "pipe:1")
+
+	// Bind the FFmpeg process to the provided context to prevent zombie processes
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd.Stdout = outputWriter
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		c.logger.Error("FFmpeg segment extraction failed", "error", err, "stderr", stderr.String(), "format", format)
+		return fmt.Errorf("ffmpeg segment extraction error: %w", err)
+	}
+
+	return nil
+}
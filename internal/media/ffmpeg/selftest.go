@@ -0,0 +1,200 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+	"time"
+
+	"mediahub_oss/internal/media"
+)
+
+// SelfTest exercises the same conversion, preview and probe invocations the upload paths use
+// on tiny in-memory test files. Capabilities that fail are disabled on the converter, so
+// GetOutputMimeTypes and CanCreatePreview reflect what the server can actually perform.
+// It must be called before the converter is shared with request handlers.
+func (c *FfmpegConverter) SelfTest(ctx context.Context) media.SelfTestReport {
+	report := media.SelfTestReport{
+		FFmpegVersion:  readToolVersion(c.ffmpegPath),
+		FFprobeVersion: readToolVersion(c.ffprobePath),
+		Capabilities:   make(map[string]media.CapabilityResult),
+	}
+
+	pngData, err := generateTestPNG()
+	if err != nil {
+		c.logger.Error("Self-test: failed to generate test image", "error", err)
+	}
+	wavData := generateTestWAV()
+
+	// 1. Conversions (identical to the sync upload path)
+	report.Capabilities[media.CapabilityJPEGConvert] = c.runCheck(ctx, func(ctx context.Context) error {
+		return c.ConvertStream(ctx, bytes.NewReader(pngData), io.Discard, "image/png", "image/jpeg")
+	})
+	report.Capabilities[media.CapabilityOpusEncode] = c.runCheck(ctx, func(ctx context.Context) error {
+		return c.ConvertStream(ctx, bytes.NewReader(wavData), io.Discard, "audio/wav", "audio/opus")
+	})
+	report.Capabilities[media.CapabilityFLACEncode] = c.runCheck(ctx, func(ctx context.Context) error {
+		return c.ConvertStream(ctx, bytes.NewReader(wavData), io.Discard, "audio/wav", "audio/flac")
+	})
+
+	// 2. Audio waveform preview
+	report.Capabilities[media.CapabilityWaveform] = c.runCheck(ctx, func(ctx context.Context) error {
+		var out bytes.Buffer
+		if err := c.CreatePreviewFromStream(ctx, bytes.NewReader(wavData), &out, "audio/wav"); err != nil {
+			return err
+		}
+		if out.Len() == 0 {
+			return fmt.Errorf("ffmpeg produced an empty preview")
+		}
+		return nil
+	})
+
+	// 3. Metadata extraction
+	report.Capabilities[media.CapabilityProbe] = c.runCheck(ctx, func(ctx context.Context) error {
+		if !c.IsFFprobeAvailable() {
+			return fmt.Errorf("ffprobe is not available")
+		}
+		fields, err := c.ReadMediaFieldsFromStream(ctx, bytes.NewReader(wavData), "audio")
+		if err != nil {
+			return err
+		}
+		if ch, _ := fields["channels"].(uint8); ch != 1 {
+			return fmt.Errorf("unexpected probe result: %v", fields)
+		}
+		return nil
+	})
+
+	c.applySelfTestReport(report)
+	return report
+}
+
+// runCheck executes a single self-test check with a timeout and measures its duration.
+func (c *FfmpegConverter) runCheck(ctx context.Context, check func(ctx context.Context) error) media.CapabilityResult {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := media.CapabilityResult{
+		OK:       err == nil,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// applySelfTestReport disables conversions and previews that failed the self-test.
+func (c *FfmpegConverter) applySelfTestReport(report media.SelfTestReport) {
+	conversionTargets := map[string]string{
+		media.CapabilityJPEGConvert: "image/jpeg",
+		media.CapabilityOpusEncode:  "audio/opus",
+		media.CapabilityFLACEncode:  "audio/flac",
+	}
+
+	for name, result := range report.Capabilities {
+		c.capabilities[name] = result.OK
+		if result.OK {
+			continue
+		}
+		c.logger.Warn("Media self-test failed, disabling capability", "capability", name, "error", result.Error)
+		if target, ok := conversionTargets[name]; ok {
+			delete(c.supportedConversions, target)
+		}
+	}
+}
+
+// GetCapabilities returns a copy of the capability map. Before a self-test has run,
+// it only reflects whether the required executables were found.
+func (c *FfmpegConverter) GetCapabilities() map[string]bool {
+	caps := make(map[string]bool, len(c.capabilities))
+	for name, ok := range c.capabilities {
+		caps[name] = ok
+	}
+	return caps
+}
+
+// initCapabilities sets the capability map based on executable presence only.
+func (c *FfmpegConverter) initCapabilities() {
+	hasFFmpeg := c.IsFFmpegAvailable()
+	c.capabilities = map[string]bool{
+		media.CapabilityJPEGConvert: hasFFmpeg,
+		media.CapabilityOpusEncode:  hasFFmpeg,
+		media.CapabilityFLACEncode:  hasFFmpeg,
+		media.CapabilityWaveform:    hasFFmpeg,
+		media.CapabilityProbe:       c.IsFFprobeAvailable(),
+		media.CapabilityHEICDecode:  c.detectHEICDecode(),
+		media.CapabilityPDFRender:   false, // see SetPDFRenderer
+	}
+}
+
+// readToolVersion parses the version from the first line of `<tool> -version`,
+// e.g. "ffmpeg version 6.1.1 Copyright ..." -> "6.1.1".
+func readToolVersion(path string) string {
+	if path == "" {
+		return ""
+	}
+	out, err := exec.Command(path, "-version").Output()
+	if err != nil {
+		return ""
+	}
+	firstLine, _, _ := strings.Cut(string(out), "\n")
+	fields := strings.Fields(firstLine)
+	if len(fields) >= 3 && fields[1] == "version" {
+		return fields[2]
+	}
+	return strings.TrimSpace(firstLine)
+}
+
+// generateTestPNG encodes a small gradient image.
+func generateTestPNG() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 16), G: uint8(y * 16), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// generateTestWAV builds a half-second 440 Hz mono 16-bit PCM WAV file.
+func generateTestWAV() []byte {
+	const sampleRate = 8000
+	const numSamples = sampleRate / 2
+
+	samples := make([]int16, numSamples)
+	for i := range samples {
+		samples[i] = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+	}
+	dataSize := uint32(numSamples * 2)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36)+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))           // fmt chunk size
+	binary.Write(&buf, binary.LittleEndian, uint16(1))            // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(1))            // channels
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))   // sample rate
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2)) // byte rate
+	binary.Write(&buf, binary.LittleEndian, uint16(2))            // block align
+	binary.Write(&buf, binary.LittleEndian, uint16(16))           // bits per sample
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, samples)
+
+	return buf.Bytes()
+}
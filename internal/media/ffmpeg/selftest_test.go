@@ -0,0 +1,119 @@
+package ffmpeg
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"mediahub_oss/internal/media"
+)
+
+// fakeFFmpeg answers -version and writes a dummy result to the temporary output file or to
+// stdout for previews. Invocations containing failOn exit with an error instead.
+func fakeFFmpeg(failOn string) string {
+	script := "#!/bin/sh\ncase \"$*\" in\n*-version*) echo 'ffmpeg version 6.1.1 Copyright (c) the FFmpeg developers'; exit 0 ;;\n"
+	if failOn != "" {
+		script += "*" + failOn + "*) echo \"Unknown encoder '" + failOn + "'\" >&2; exit 1 ;;\n"
+	}
+	return script + "esac\nfor last; do :; done\ncase \"$last\" in\npipe:1) printf preview ;;\n*.tmp) printf converted > \"$last\" ;;\nesac\n"
+}
+
+const fakeFFprobe = "#!/bin/sh\ncase \"$*\" in\n*-version*) echo 'ffprobe version 6.1.1 Copyright (c) the FFmpeg developers'; exit 0 ;;\nesac\n" +
+	"echo '{\"streams\": [{\"codec_type\": \"audio\", \"channels\": 1, \"duration\": \"0.5\"}], \"format\": {\"duration\": \"0.5\"}}'\n"
+
+func newFakeConverter(t *testing.T, ffmpegScript, ffprobeScript string) *FfmpegConverter {
+	t.Helper()
+	dir := t.TempDir()
+	writeTool := func(name, script string) string {
+		if script == "" {
+			return "" // not found
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			t.Fatalf("failed to write fake %s: %v", name, err)
+		}
+		return path
+	}
+	c := &FfmpegConverter{
+		ffmpegPath:  writeTool("ffmpeg", ffmpegScript),
+		ffprobePath: writeTool("ffprobe", ffprobeScript),
+		logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	server, err := NewLocalStreamServer(c.logger)
+	if err != nil {
+		t.Fatalf("failed to start the stream server: %v", err)
+	}
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+	c.localServer = server
+
+	c.initConversions()
+	c.initCapabilities()
+	return c
+}
+
+func TestSelfTest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ffmpeg is a shell script")
+	}
+
+	// 1. Working tools report every capability and their versions
+	c := newFakeConverter(t, fakeFFmpeg(""), fakeFFprobe)
+	report := c.SelfTest(context.Background())
+	if !report.AllOK() {
+		t.Errorf("expected all checks to pass, got %+v", report.Capabilities)
+	}
+	if report.FFmpegVersion != "6.1.1" || report.FFprobeVersion != "6.1.1" {
+		t.Errorf("expected version 6.1.1, got ffmpeg %q and ffprobe %q", report.FFmpegVersion, report.FFprobeVersion)
+	}
+	if got := c.GetOutputMimeTypes("audio"); !slices.Equal(got, []string{"audio/flac", "audio/opus"}) {
+		t.Errorf("expected flac and opus outputs, got %v", got)
+	}
+
+	// 2. A failing encoder is reported and its conversion disabled, the others stay
+	c = newFakeConverter(t, fakeFFmpeg("libopus"), fakeFFprobe)
+	report = c.SelfTest(context.Background())
+	if result := report.Capabilities[media.CapabilityOpusEncode]; result.OK || !strings.Contains(result.Error, "ffmpeg conversion error") {
+		t.Errorf("expected the opus check to fail with the conversion error, got %+v", result)
+	}
+	if report.AllOK() {
+		t.Error("expected the report to contain a failure")
+	}
+	for _, name := range []string{media.CapabilityJPEGConvert, media.CapabilityFLACEncode, media.CapabilityWaveform, media.CapabilityProbe} {
+		if !report.Capabilities[name].OK {
+			t.Errorf("expected %s to pass, got %+v", name, report.Capabilities[name])
+		}
+	}
+	if got := c.GetOutputMimeTypes("audio"); !slices.Equal(got, []string{"audio/flac"}) {
+		t.Errorf("expected only the flac output, got %v", got)
+	}
+	if c.GetCapabilities()[media.CapabilityOpusEncode] {
+		t.Error("expected opus_encode to be disabled")
+	}
+
+	// 3. Missing tools fail every check that needs them, WAV previews fall back to Go
+	c = newFakeConverter(t, "", "")
+	report = c.SelfTest(context.Background())
+	if report.FFmpegVersion != "" || report.FFprobeVersion != "" {
+		t.Errorf("expected no versions, got ffmpeg %q and ffprobe %q", report.FFmpegVersion, report.FFprobeVersion)
+	}
+	for name, want := range map[string]string{
+		media.CapabilityJPEGConvert: "ffmpeg is not available",
+		media.CapabilityOpusEncode:  "ffmpeg is not available",
+		media.CapabilityFLACEncode:  "ffmpeg is not available",
+		media.CapabilityProbe:       "ffprobe is not available",
+	} {
+		if result := report.Capabilities[name]; result.OK || !strings.Contains(result.Error, want) {
+			t.Errorf("expected %s to fail with %q, got %+v", name, want, result)
+		}
+	}
+	if !report.Capabilities[media.CapabilityWaveform].OK {
+		t.Errorf("expected the Go waveform to pass, got %+v", report.Capabilities[media.CapabilityWaveform])
+	}
+}
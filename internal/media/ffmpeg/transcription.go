@@ -0,0 +1,51 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// DownsampleForTranscription converts the input to mono 16 kHz WAV and pipes it directly to the output.
+// Speech recognition models resample to this rate anyway, so the upload to the service gets much smaller.
+func (c *FfmpegConverter) DownsampleForTranscription(ctx context.Context, inputData io.ReadSeeker, outputWriter io.Writer) error {
+	ffmpegPath, err := c.GetFFmpegPath()
+	if err != nil {
+		return fmt.Errorf("ffmpeg is not available: %w", customerrors.ErrNotImplemented)
+	}
+
+	id, fullURL, err := c.localServer.Register(inputData, 30*time.Minute)
+	if err != nil {
+		return fmt.Errorf("failed to register stream: %w", err)
+	}
+	defer c.localServer.Unregister(id)
+
+	args := []string{
+		"-v", "error",
+		"-i", fullURL,
+		"-vn",
+		"-ac", "1",
+		"-ar", "16000",
+		"-c:a", "pcm_s16le",
+		"-f", "wav",
+		"pipe:1",
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd.Stdout = outputWriter
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		c.logger.Error("FFmpeg downsampling for transcription failed", "error", err, "stderr", stderr.String())
+		return fmt.Errorf("ffmpeg downsampling error: %w", err)
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ type MediaConverter interface {
 	GetOutputMimeTypes(contentType string) []string
 	CanCreatePreview(inputMimeType string) bool
 	CanConvert(inputMimeType string, outputMimeType string) ConversionCheck
+	GetCapabilities() map[string]bool
 
 	// --- File Conversion ---
 	// ConvertStream: For small files in RAM. Uses HTTP loopback for input, pipes to output.
@@ -31,4 +32,12 @@ type MediaConverter interface {
 
 	// CreatePreviewFromFile: Reads direct from disk. Pipes WEBP bytes to output.
 	CreatePreviewFromFile(ctx context.Context, filepath string, outputWriter io.Writer, inputMimeType string) error
+
+	// --- Segment Extraction ---
+	// ExtractAudioSegment: Uses HTTP loopback. Streams the encoded segment (seconds) directly to output.
+	ExtractAudioSegment(ctx context.Context, inputData io.ReadSeeker, outputWriter io.Writer, start, duration float64, format string) error
+
+	// --- Transcription ---
+	// DownsampleForTranscription: Uses HTTP loopback. Streams the audio as mono 16 kHz WAV, the input rate of speech recognition models.
+	DownsampleForTranscription(ctx context.Context, inputData io.ReadSeeker, outputWriter io.Writer) error
 }
@@ -1,5 +1,7 @@
 package media
 
+import "time"
+
 // FieldDef defines a media-specific metadata field and its database type.
 type FieldDef struct {
 	Name string
@@ -17,6 +19,8 @@ var imageMimeTypes = []string{
 	"image/webp",
 	"image/gif",
 	"image/avif",
+	"image/heic", // accepted only if FFmpeg can decode it, always converted, see HEICTargetMimeType
+	"image/heif",
 }
 
 var videoMimeTypes = []string{
@@ -39,3 +43,65 @@ var audioMimeTypes = []string{
 	"audio/mp4",
 	"audio/m4a",
 }
+
+// Capability names reported by the media self-test.
+const (
+	CapabilityJPEGConvert = "jpeg_convert"
+	CapabilityOpusEncode  = "opus_encode"
+	CapabilityFLACEncode  = "flac_encode"
+	CapabilityWaveform    = "waveform"
+	CapabilityProbe       = "probe"
+	CapabilityHEICDecode  = "heic_decode"
+	CapabilityPDFRender   = "pdf_render"
+)
+
+// textMimeTypes are the mime types besides text/* whose previews show their first lines.
+var textMimeTypes = []string{
+	"application/json",
+	"application/x-ndjson",
+	"application/xml",
+	"application/yaml",
+	"application/x-yaml",
+	"application/toml",
+}
+
+// HEICTargetMimeType is what HEIC/HEIF uploads are converted to if the database does not convert
+// them to another format, browsers cannot display HEIC.
+const HEICTargetMimeType = "image/jpeg"
+
+// CapabilityResult holds the outcome of a single self-test check.
+type CapabilityResult struct {
+	OK       bool
+	Error    string
+	Duration time.Duration
+}
+
+// SelfTestReport summarizes the self-test of the media toolchain.
+type SelfTestReport struct {
+	FFmpegVersion  string
+	FFprobeVersion string
+	Capabilities   map[string]CapabilityResult
+}
+
+// AllOK returns true if every capability check passed.
+func (r SelfTestReport) AllOK() bool {
+	for _, c := range r.Capabilities {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// SegmentFormat describes an output format for extracted audio segments.
+type SegmentFormat struct {
+	MimeType  string
+	Extension string
+}
+
+// AudioSegmentFormats lists the formats audio segments can be extracted to, keyed by the format name used in requests.
+var AudioSegmentFormats = map[string]SegmentFormat{
+	"opus": {MimeType: "audio/opus", Extension: "opus"},
+	"flac": {MimeType: "audio/flac", Extension: "flac"},
+	"wav":  {MimeType: "audio/wav", Extension: "wav"},
+}
@@ -0,0 +1,150 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Kinds of the media operations recorded by converters.
+const (
+	OperationConversion = "conversion"
+	OperationPreview    = "preview"
+	OperationProbe      = "probe"
+)
+
+// StderrTailSize is how much of the end of the stderr of a media tool is kept.
+const StderrTailSize = 4 << 10
+
+// Operation is the record of a single run of an external media tool.
+type Operation struct {
+	Kind         string
+	DatabaseID   string
+	DatabaseName string
+	EntryID      int64  // 0 if the operation was not run for an entry
+	Command      string // command line with file paths and URLs replaced by placeholders
+	StartedAt    time.Time
+	Duration     time.Duration
+	ExitCode     int // -1 if the process did not exit on its own, e.g. killed by a cancelled context
+	Error        string
+	StderrTail   string
+}
+
+// OperationRecorder receives a record of every media operation. Converters call it synchronously,
+// implementations must be cheap and safe for concurrent use.
+type OperationRecorder interface {
+	RecordOperation(op Operation)
+}
+
+// OperationTarget identifies the entry media operations are run for.
+type OperationTarget struct {
+	DatabaseID   string
+	DatabaseName string
+	EntryID      int64
+}
+
+type operationTargetKey struct{}
+
+// WithOperationTarget attaches the entry being processed to ctx, converters add it to their records.
+func WithOperationTarget(ctx context.Context, target OperationTarget) context.Context {
+	return context.WithValue(ctx, operationTargetKey{}, target)
+}
+
+// OperationTargetFromContext returns the entry attached to ctx, the zero value if there is none.
+func OperationTargetFromContext(ctx context.Context) OperationTarget {
+	target, _ := ctx.Value(operationTargetKey{}).(OperationTarget)
+	return target
+}
+
+// CommandError is returned by converters if an external media tool failed. It carries the end of
+// the tool's stderr, which usually names the actual problem.
+type CommandError struct {
+	Kind       string
+	ExitCode   int
+	StderrTail string
+	Err        error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s failed (exit status %d): %v", e.Kind, e.ExitCode, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// StderrTail returns the last StderrTailSize bytes of stderr.
+func StderrTail(stderr string) string {
+	if len(stderr) <= StderrTailSize {
+		return stderr
+	}
+	return stderr[len(stderr)-StderrTailSize:]
+}
+
+// OperationLog keeps the most recent media operations in a ring buffer of fixed capacity. It is
+// safe for concurrent use. A nil *OperationLog records nothing.
+type OperationLog struct {
+	mu      sync.Mutex
+	records []Operation
+	next    int  // index the next record is written to
+	full    bool // the buffer wrapped around at least once
+}
+
+// NewOperationLog returns a log of the last capacity operations, nil if capacity is 0 or less.
+func NewOperationLog(capacity int) *OperationLog {
+	if capacity <= 0 {
+		return nil
+	}
+	return &OperationLog{records: make([]Operation, capacity)}
+}
+
+// RecordOperation adds op to the log, replacing the oldest record once the log is full.
+func (l *OperationLog) RecordOperation(op Operation) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records[l.next] = op
+	l.next = (l.next + 1) % len(l.records)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Find returns the recorded operations of a database and entry, newest first. An empty database
+// name or an entry id of 0 match all databases or entries.
+func (l *OperationLog) Find(databaseName string, entryID int64) []Operation {
+	found := []Operation{}
+	if l == nil {
+		return found
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := l.next
+	if l.full {
+		n = len(l.records)
+	}
+	for i := 1; i <= n; i++ {
+		op := l.records[(l.next-i+len(l.records))%len(l.records)]
+		if databaseName != "" && op.DatabaseName != databaseName {
+			continue
+		}
+		if entryID != 0 && op.EntryID != entryID {
+			continue
+		}
+		found = append(found, op)
+	}
+	return found
+}
+
+// Capacity returns the number of operations the log keeps, 0 if it is disabled.
+func (l *OperationLog) Capacity() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.records)
+}
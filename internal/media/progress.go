@@ -0,0 +1,20 @@
+package media
+
+import "context"
+
+// ProgressFunc receives the completed fraction (0 to 1) of a long running conversion.
+type ProgressFunc func(fraction float64)
+
+type progressKey struct{}
+
+// WithProgress attaches a progress callback to ctx. Converters that can measure their progress
+// report it through the callback, others ignore it.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ProgressFromContext returns the progress callback of ctx, or nil if there is none.
+func ProgressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
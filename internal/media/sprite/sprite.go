@@ -0,0 +1,180 @@
+// Package sprite composes the previews of several entries into a single JPEG sprite sheet,
+// so galleries can render many thumbnails with one request.
+package sprite
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	MaxEntries      = 200
+	DefaultCellSize = 100 // previews are at most 200x200, half of that is enough for scrubbing
+	DefaultCacheTTL = 5 * time.Minute
+	jpegQuality     = 80
+)
+
+var (
+	placeholderColor = color.RGBA{R: 128, G: 128, B: 128, A: 255} // cells of entries without a preview
+	backgroundColor  = color.RGBA{R: 32, G: 32, B: 32, A: 255}    // letterbox of previews that are not square
+)
+
+// PreviewOpener returns the preview of an entry. Any error renders the entry as a placeholder cell.
+type PreviewOpener func(ctx context.Context, id int64) (io.ReadCloser, error)
+
+// Cell is the position of an entry's thumbnail within the sprite.
+type Cell struct {
+	X           int
+	Y           int
+	Placeholder bool // the entry has no (readable) preview
+}
+
+// Sprite is a composed sprite sheet.
+type Sprite struct {
+	JPEG       []byte
+	Columns    int
+	Rows       int
+	CellWidth  int
+	CellHeight int
+	Cells      map[int64]Cell
+}
+
+// Generator composes sprites and caches them by the list of entries they contain.
+type Generator struct {
+	CellSize int
+
+	sprites *cache.Cache
+}
+
+// NewGenerator creates a generator whose sprites are cached for cacheTTL.
+func NewGenerator(cacheTTL time.Duration) *Generator {
+	return &Generator{
+		CellSize: DefaultCellSize,
+		sprites:  cache.New(cacheTTL, 2*cacheTTL),
+	}
+}
+
+// Generate composes the previews of ids into a grid, in the given order and row by row.
+// The scope (e.g. the database ID) is part of the cache key. Previews are decoded one at a time,
+// so memory is bounded by the sprite itself plus a single preview.
+func (g *Generator) Generate(ctx context.Context, scope string, ids []int64, open PreviewOpener) (Sprite, error) {
+	if len(ids) == 0 {
+		return Sprite{}, fmt.Errorf("no entries given")
+	}
+	if len(ids) > MaxEntries {
+		return Sprite{}, fmt.Errorf("too many entries: %d (max %d)", len(ids), MaxEntries)
+	}
+
+	cellSize := g.CellSize
+	if cellSize <= 0 {
+		cellSize = DefaultCellSize
+	}
+
+	key := cacheKey(scope, cellSize, ids)
+	if cached, ok := g.sprites.Get(key); ok {
+		return cached.(Sprite), nil
+	}
+
+	columns, rows := gridSize(len(ids))
+
+	sprite := Sprite{
+		Columns:    columns,
+		Rows:       rows,
+		CellWidth:  cellSize,
+		CellHeight: cellSize,
+		Cells:      make(map[int64]Cell, len(ids)),
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, columns*cellSize, rows*cellSize))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{backgroundColor}, image.Point{}, draw.Src)
+
+	for i, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return Sprite{}, err
+		}
+
+		cellRect := image.Rect(0, 0, cellSize, cellSize).Add(image.Pt((i%columns)*cellSize, (i/columns)*cellSize))
+		cell := Cell{X: cellRect.Min.X, Y: cellRect.Min.Y}
+
+		preview, err := decodePreview(ctx, open, id)
+		if err != nil {
+			cell.Placeholder = true
+			draw.Draw(canvas, cellRect, &image.Uniform{placeholderColor}, image.Point{}, draw.Src)
+		} else {
+			draw.ApproxBiLinear.Scale(canvas, fitInto(preview.Bounds(), cellRect), preview, preview.Bounds(), draw.Src, nil)
+		}
+
+		// An ID listed twice keeps its first cell
+		if _, seen := sprite.Cells[id]; !seen {
+			sprite.Cells[id] = cell
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return Sprite{}, fmt.Errorf("failed to encode sprite: %w", err)
+	}
+	sprite.JPEG = buf.Bytes()
+
+	g.sprites.SetDefault(key, sprite)
+	return sprite, nil
+}
+
+func decodePreview(ctx context.Context, open PreviewOpener, id int64) (image.Image, error) {
+	rc, err := open(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	img, _, err := image.Decode(rc)
+	return img, err
+}
+
+// gridSize returns a grid that is as square as possible, with more columns than rows if it cannot be.
+func gridSize(n int) (columns, rows int) {
+	columns = int(math.Ceil(math.Sqrt(float64(n))))
+	rows = (n + columns - 1) / columns
+	return columns, rows
+}
+
+// fitInto scales src to fit into cell, keeping its aspect ratio, and centers it.
+func fitInto(src, cell image.Rectangle) image.Rectangle {
+	w, h := src.Dx(), src.Dy()
+	if w <= 0 || h <= 0 {
+		return image.Rectangle{}
+	}
+	cw, ch := cell.Dx(), cell.Dy()
+	if w*ch > h*cw {
+		h, w = max(1, h*cw/w), cw
+	} else {
+		w, h = max(1, w*ch/h), ch
+	}
+	offset := image.Pt(cell.Min.X+(cw-w)/2, cell.Min.Y+(ch-h)/2)
+	return image.Rect(0, 0, w, h).Add(offset)
+}
+
+func cacheKey(scope string, cellSize int, ids []int64) string {
+	h := sha256.New()
+	h.Write([]byte(scope + "/" + strconv.Itoa(cellSize)))
+	for _, id := range ids {
+		h.Write([]byte{','})
+		h.Write([]byte(strconv.FormatInt(id, 10)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,144 @@
+package sprite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+
+	// Fixture previews: solid colors in different shapes and formats, entry 3 has no preview
+	solid := func(w, h int, c color.Color) image.Image {
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		return img
+	}
+	encodePNG := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		png.Encode(&buf, img)
+		return buf.Bytes()
+	}
+	encodeJPEG := func(img image.Image) []byte {
+		var buf bytes.Buffer
+		jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+		return buf.Bytes()
+	}
+
+	red := color.RGBA{R: 255, A: 255}
+	green := color.RGBA{G: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	previews := map[int64][]byte{
+		1: encodePNG(solid(200, 200, red)),
+		2: encodeJPEG(solid(200, 100, green)), // wide, letterboxed
+		4: encodePNG(solid(50, 50, blue)),     // small, scaled up
+		5: encodePNG(solid(120, 200, white)),  // tall, pillarboxed
+	}
+
+	opened := 0
+	open := func(ctx context.Context, id int64) (io.ReadCloser, error) {
+		opened++
+		data, ok := previews[id]
+		if !ok {
+			return nil, errors.New("preview not found")
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	g := NewGenerator(time.Minute)
+	g.CellSize = 40
+	ids := []int64{1, 2, 3, 4, 5}
+
+	s, err := g.Generate(ctx, "db", ids, open)
+	if err != nil {
+		t.Fatalf("failed to generate sprite: %v", err)
+	}
+
+	// 1. Layout: 5 entries fill a 3x2 grid row by row
+	if s.Columns != 3 || s.Rows != 2 || s.CellWidth != 40 || s.CellHeight != 40 {
+		t.Fatalf("expected a 3x2 grid of 40px cells, got %dx%d of %dx%d", s.Columns, s.Rows, s.CellWidth, s.CellHeight)
+	}
+	expectedCells := map[int64]Cell{
+		1: {X: 0, Y: 0},
+		2: {X: 40, Y: 0},
+		3: {X: 80, Y: 0, Placeholder: true},
+		4: {X: 0, Y: 40},
+		5: {X: 40, Y: 40},
+	}
+	for id, want := range expectedCells {
+		if got := s.Cells[id]; got != want {
+			t.Errorf("entry %d: expected cell %+v, got %+v", id, want, got)
+		}
+	}
+
+	// 2. Pixels: each cell shows its preview, the letterbox and the unused cell show the background
+	img, err := jpeg.Decode(bytes.NewReader(s.JPEG))
+	if err != nil {
+		t.Fatalf("failed to decode sprite: %v", err)
+	}
+	if img.Bounds().Dx() != 120 || img.Bounds().Dy() != 80 {
+		t.Fatalf("expected a 120x80 sprite, got %v", img.Bounds())
+	}
+	pixels := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"entry 1", 20, 20, red},
+		{"entry 2", 60, 20, green},
+		{"entry 2 letterbox", 60, 3, backgroundColor},
+		{"entry 3 placeholder", 100, 20, placeholderColor},
+		{"entry 4", 20, 60, blue},
+		{"entry 5", 60, 60, white},
+		{"entry 5 pillarbox", 43, 60, backgroundColor},
+		{"unused cell", 100, 60, backgroundColor},
+	}
+	for _, p := range pixels {
+		if got := img.At(p.x, p.y); !similar(got, p.want) {
+			t.Errorf("%s: expected %v at (%d,%d), got %v", p.name, p.want, p.x, p.y, got)
+		}
+	}
+
+	// 3. The same list is served from the cache
+	opened = 0
+	if _, err := g.Generate(ctx, "db", ids, open); err != nil {
+		t.Fatalf("failed to generate cached sprite: %v", err)
+	}
+	if opened != 0 {
+		t.Errorf("expected the cached sprite, but %d previews were opened", opened)
+	}
+	if _, err := g.Generate(ctx, "other_db", ids, open); err != nil || opened != len(ids) {
+		t.Errorf("expected another scope to compose a new sprite, opened %d previews (err %v)", opened, err)
+	}
+
+	// 4. Limits
+	if _, err := g.Generate(ctx, "db", make([]int64, MaxEntries+1), open); err == nil {
+		t.Errorf("expected an error for more than %d entries", MaxEntries)
+	}
+}
+
+// similar compares colors with a tolerance for JPEG artifacts.
+func similar(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(a uint32, b uint8) int {
+		d := int(a>>8) - int(b)
+		if d < 0 {
+			return -d
+		}
+		return d
+	}
+	return diff(r, want.R) < 24 && diff(g, want.G) < 24 && diff(b, want.B) < 24
+}
@@ -0,0 +1,101 @@
+// Package textpreview renders the beginning of plain-text files (logs, CSV, JSON, ...) as preview
+// images. It is pure Go, so text previews work without FFmpeg.
+package textpreview
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"mediahub_oss/internal/media/vp8l"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// MaxLines is the number of lines shown in a preview.
+	MaxLines = 20
+	// Width of a preview in pixels, the height depends on the number of lines.
+	Width = 200
+
+	padding  = 4
+	tabWidth = 4
+	maxRead  = 64 << 10 // bytes read at most, e.g. from files with very long lines
+)
+
+var (
+	background = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	foreground = color.NRGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+)
+
+// Render writes a WebP image of the first MaxLines lines of r. Lines longer than the preview are
+// cut off, invalid UTF-8 and characters the font lacks are shown as replacement characters.
+func Render(w io.Writer, r io.Reader) error {
+	lines, err := readLines(io.LimitReader(r, maxRead))
+	if err != nil {
+		return err
+	}
+
+	face := basicfont.Face7x13
+	lineHeight := face.Height
+	height := 2*padding + max(len(lines), 1)*lineHeight
+
+	img := image.NewPaletted(image.Rect(0, 0, Width, height), color.Palette{background, foreground})
+	drawer := font.Drawer{Dst: img, Src: image.NewUniform(foreground), Face: face}
+	for i, line := range lines {
+		drawer.Dot = fixed.P(padding, padding+i*lineHeight+face.Ascent)
+		drawer.DrawString(line)
+	}
+
+	return vp8l.Encode(w, img)
+}
+
+// readLines returns the first MaxLines lines, cut to the characters that fit into the preview.
+func readLines(r io.Reader) ([]string, error) {
+	maxChars := (Width - 2*padding) / basicfont.Face7x13.Advance
+
+	var lines []string
+	br := bufio.NewReader(r)
+	for len(lines) < MaxLines {
+		raw, err := br.ReadString('\n')
+		if raw != "" {
+			lines = append(lines, printable(strings.TrimRight(raw, "\r\n"), maxChars))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lines, nil
+}
+
+// printable expands tabs, drops other control characters and cuts the line to maxChars characters.
+func printable(line string, maxChars int) string {
+	var sb strings.Builder
+	n := 0
+	for len(line) > 0 && n < maxChars {
+		r, size := utf8.DecodeRuneInString(line)
+		line = line[size:]
+		switch {
+		case r == '\t':
+			for spaces := tabWidth - n%tabWidth; spaces > 0 && n < maxChars; spaces-- {
+				sb.WriteByte(' ')
+				n++
+			}
+			continue
+		case unicode.IsControl(r):
+			continue
+		}
+		sb.WriteRune(r) // utf8.RuneError is drawn as the replacement character
+		n++
+	}
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package textpreview
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"mediahub_oss/internal/media/vp8l"
+
+	"golang.org/x/image/webp"
+)
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, err := webp.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode the preview: %v", err)
+	}
+	return img
+}
+
+// colors counts the pixels per color of an image.
+func colors(img image.Image) map[color.NRGBA]int {
+	counts := make(map[color.NRGBA]int)
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			counts[color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)]++
+		}
+	}
+	return counts
+}
+
+func TestRender(t *testing.T) {
+	var lines []string
+	for i := range 30 {
+		lines = append(lines, fmt.Sprintf("2026-10-16T12:00:%02dZ\tINFO\tsensor %d reading ok, value within range", i, i))
+	}
+
+	var out bytes.Buffer
+	if err := Render(&out, strings.NewReader(strings.Join(lines, "\r\n"))); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	if !bytes.HasPrefix(out.Bytes(), []byte("RIFF")) || string(out.Bytes()[8:16]) != "WEBPVP8L" {
+		t.Fatalf("expected a lossless WebP file, got % x", out.Bytes()[:16])
+	}
+
+	img := decode(t, out.Bytes())
+	if b := img.Bounds(); b.Dx() != Width || b.Dy() != 2*padding+MaxLines*13 {
+		t.Errorf("expected %dx%d, got %v", Width, 2*padding+MaxLines*13, b)
+	}
+	counts := colors(img)
+	if len(counts) != 2 || counts[foreground] == 0 || counts[background] <= counts[foreground] {
+		t.Errorf("expected dark text on a white background, got %v", counts)
+	}
+	// Nothing is drawn into the padding
+	for x := range Width {
+		if c := color.NRGBAModel.Convert(img.At(x, 0)); c != background {
+			t.Fatalf("expected the padding to be empty at %d,0, got %v", x, c)
+		}
+	}
+}
+
+func TestRenderShortAndBinaryInput(t *testing.T) {
+	for name, input := range map[string]string{
+		"empty":       "",
+		"single line": "hello",
+		"binary":      "\x00\x01\xff\xfe%PDF\x1b[31m",
+		"long line":   strings.Repeat("x", 200<<10),
+	} {
+		var out bytes.Buffer
+		if err := Render(&out, strings.NewReader(input)); err != nil {
+			t.Fatalf("%s: failed to render: %v", name, err)
+		}
+		img := decode(t, out.Bytes())
+		if img.Bounds().Dx() != Width || img.Bounds().Dy() != 2*padding+13 {
+			t.Errorf("%s: expected a single line preview, got %v", name, img.Bounds())
+		}
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	for in, want := range map[string]string{
+		"a\tb":           "a   b",
+		"ab\tc":          "ab  c",
+		"bell\a!":        "bell!",
+		"caf\xe9":        "caf�",
+		"0123456789abcd": "012345",
+	} {
+		if got := printable(in, 6); got != want {
+			t.Errorf("printable(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWriteWebPSingleColor(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 3, 5), color.Palette{foreground})
+	var out bytes.Buffer
+	if err := vp8l.Encode(&out, img); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	decoded := decode(t, out.Bytes())
+	if counts := colors(decoded); len(counts) != 1 || counts[foreground] != 15 {
+		t.Errorf("expected 15 foreground pixels, got %v", counts)
+	}
+}
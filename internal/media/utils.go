@@ -72,6 +72,23 @@ func GetMetadataFields(contentType string) ([]FieldDef, error) {
 	}
 }
 
+// IsHEIC reports whether the mime type is a HEIC/HEIF image.
+func IsHEIC(mimeType string) bool {
+	normType := NormalizeMimeType(mimeType)
+	return normType == "image/heic" || normType == "image/heif"
+}
+
+// IsText reports whether the mime type is plain text, e.g. logs, CSV or JSON.
+func IsText(mimeType string) bool {
+	normType := NormalizeMimeType(mimeType)
+	return strings.HasPrefix(normType, "text/") || slices.Contains(textMimeTypes, normType)
+}
+
+// IsPDF reports whether the mime type is a PDF document.
+func IsPDF(mimeType string) bool {
+	return NormalizeMimeType(mimeType) == "application/pdf"
+}
+
 // convert mime aliases into a common type
 func NormalizeMimeType(mime string) string {
 	switch mime {
@@ -85,6 +102,10 @@ func NormalizeMimeType(mime string) string {
 		return "audio/mpeg"
 	case "audio/x-flac":
 		return "audio/flac"
+	case "image/heic-sequence":
+		return "image/heic"
+	case "image/heif-sequence":
+		return "image/heif"
 	default:
 		return mime
 	}
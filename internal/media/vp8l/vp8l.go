@@ -0,0 +1,142 @@
+// Package vp8l writes two-color images as lossless WebP files in pure Go, for previews rendered without FFmpeg.
+package vp8l
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Encode writes an image of at most two colors as a lossless WebP (VP8L) file. Every channel
+// uses a prefix code of one or two symbols, so each pixel takes at most three bits and no
+// entropy coding is needed. The decoders of browsers, libwebp and golang.org/x/image read it.
+func Encode(w io.Writer, img *image.Paletted) error {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return fmt.Errorf("invalid image size %dx%d", width, height)
+	}
+	if len(img.Palette) < 1 || len(img.Palette) > 2 {
+		return fmt.Errorf("expected a palette of one or two colors, got %d", len(img.Palette))
+	}
+
+	// ARGB of the palette entries, a single color is repeated
+	var argb [2][4]uint8 // alpha, red, green, blue
+	var alphaUsed uint32
+	for i := range argb {
+		c := color.NRGBAModel.Convert(img.Palette[min(i, len(img.Palette)-1)]).(color.NRGBA)
+		argb[i] = [4]uint8{c.A, c.R, c.G, c.B}
+		if c.A != 0xff {
+			alphaUsed = 1
+		}
+	}
+
+	var bw bitWriter
+	bw.write(0x2f, 8) // signature
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(alphaUsed, 1) // hint for decoders, the alpha values are always coded
+	bw.write(0, 3)         // version
+	bw.write(0, 1)         // no transforms
+	bw.write(0, 1)         // no color cache
+	bw.write(0, 1)         // no meta prefix codes
+
+	// Prefix codes of green, red, blue and alpha, then distance, which is never used
+	var codes [4]simpleCode
+	for i, channel := range []int{2, 1, 3, 0} {
+		codes[i] = newSimpleCode(argb[0][channel], argb[1][channel])
+		codes[i].writeHeader(&bw)
+	}
+	newSimpleCode(0, 0).writeHeader(&bw)
+
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			idx := min(int(img.ColorIndexAt(x, y)), 1)
+			for i, channel := range []int{2, 1, 3, 0} {
+				codes[i].writeSymbol(&bw, argb[idx][channel])
+			}
+		}
+	}
+	data := bw.bytes()
+
+	chunkSize := len(data)
+	padding := chunkSize % 2
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+chunkSize+padding))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(chunkSize))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padding != 0 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// simpleCode is a VP8L "simple" prefix code of one symbol (zero bits) or two symbols (one bit).
+type simpleCode struct {
+	symbols []uint8 // ascending, the canonical code of symbols[i] is i
+}
+
+func newSimpleCode(a, b uint8) simpleCode {
+	switch {
+	case a == b:
+		return simpleCode{symbols: []uint8{a}}
+	case a < b:
+		return simpleCode{symbols: []uint8{a, b}}
+	default:
+		return simpleCode{symbols: []uint8{b, a}}
+	}
+}
+
+func (c simpleCode) writeHeader(bw *bitWriter) {
+	bw.write(1, 1) // simple code
+	bw.write(uint32(len(c.symbols)-1), 1)
+	bw.write(1, 1) // the first symbol has 8 bits
+	for _, s := range c.symbols {
+		bw.write(uint32(s), 8)
+	}
+}
+
+func (c simpleCode) writeSymbol(bw *bitWriter, s uint8) {
+	if len(c.symbols) == 2 {
+		if s == c.symbols[1] {
+			bw.write(1, 1)
+		} else {
+			bw.write(0, 1)
+		}
+	}
+}
+
+// bitWriter packs bits least significant bit first, as VP8L reads them.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (bw *bitWriter) write(v uint32, n uint) {
+	bw.acc |= uint64(v) << bw.nbits
+	bw.nbits += n
+	for bw.nbits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nbits -= 8
+	}
+}
+
+func (bw *bitWriter) bytes() []byte {
+	if bw.nbits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nbits = 0, 0
+	}
+	return bw.buf
+}
@@ -0,0 +1,118 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// WaveformTransparent is the background of waveforms drawn without one, they are stored as PNG.
+const WaveformTransparent = "transparent"
+
+// Bounds of the waveform dimensions in pixels.
+const (
+	MinWaveformSize = 16
+	MaxWaveformSize = 2000
+)
+
+// WaveformStyle is the look of audio previews. Zero values select the defaults of
+// DefaultWaveformStyle, an empty background keeps the WebP previews of earlier versions.
+type WaveformStyle struct {
+	Width      int
+	Height     int
+	Color      string // "#rrggbb" or "#rgb"
+	Background string // "#rrggbb", "#rgb", WaveformTransparent or empty
+}
+
+// DefaultWaveformStyle is the waveform of databases that do not configure one.
+var DefaultWaveformStyle = WaveformStyle{Width: 200, Height: 120, Color: "#1E90FF"}
+
+// WithDefaults fills the unset fields from DefaultWaveformStyle.
+func (s WaveformStyle) WithDefaults() WaveformStyle {
+	if s.Width == 0 {
+		s.Width = DefaultWaveformStyle.Width
+	}
+	if s.Height == 0 {
+		s.Height = DefaultWaveformStyle.Height
+	}
+	if s.Color == "" {
+		s.Color = DefaultWaveformStyle.Color
+	}
+	return s
+}
+
+// Validate rejects malformed colors and dimensions outside [MinWaveformSize, MaxWaveformSize].
+func (s WaveformStyle) Validate() error {
+	if s.Width != 0 && (s.Width < MinWaveformSize || s.Width > MaxWaveformSize) {
+		return fmt.Errorf("waveform_width must be between %d and %d pixels", MinWaveformSize, MaxWaveformSize)
+	}
+	if s.Height != 0 && (s.Height < MinWaveformSize || s.Height > MaxWaveformSize) {
+		return fmt.Errorf("waveform_height must be between %d and %d pixels", MinWaveformSize, MaxWaveformSize)
+	}
+	if s.Color != "" {
+		if _, err := ParseHexColor(s.Color); err != nil {
+			return fmt.Errorf("invalid waveform_color: %w", err)
+		}
+	}
+	if s.Background != "" && s.Background != WaveformTransparent {
+		if _, err := ParseHexColor(s.Background); err != nil {
+			return fmt.Errorf("invalid waveform_background: %w", err)
+		}
+	}
+	return nil
+}
+
+// Transparent reports whether the waveform is drawn without background, which needs PNG.
+func (s WaveformStyle) Transparent() bool {
+	return s.Background == WaveformTransparent
+}
+
+// PreviewMimeType is the format of the waveform previews of the style.
+func (s WaveformStyle) PreviewMimeType() string {
+	if s.Transparent() {
+		return "image/png"
+	}
+	return "image/webp"
+}
+
+// ParseHexColor parses an opaque color in the form "#rrggbb" or "#rgb".
+func ParseHexColor(s string) (color.NRGBA, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || (len(hex) != 3 && len(hex) != 6) {
+		return color.NRGBA{}, fmt.Errorf("'%s' is not a hex color like #1e90ff", s)
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("'%s' is not a hex color like #1e90ff", s)
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+type waveformStyleKey struct{}
+
+// WithWaveformStyle attaches the waveform style of a database to ctx, the audio previews
+// created with ctx are drawn in it.
+func WithWaveformStyle(ctx context.Context, style WaveformStyle) context.Context {
+	return context.WithValue(ctx, waveformStyleKey{}, style)
+}
+
+// WaveformStyleFromContext returns the waveform style of ctx with defaults applied.
+func WaveformStyleFromContext(ctx context.Context) WaveformStyle {
+	style, _ := ctx.Value(waveformStyleKey{}).(WaveformStyle)
+	return style.WithDefaults()
+}
+
+// IsWAV reports whether the mime type is an uncompressed WAV file, which previews are drawn
+// from without FFmpeg if needed.
+func IsWAV(mimeType string) bool {
+	switch NormalizeMimeType(mimeType) {
+	case "audio/wav", "audio/x-wav", "audio/wave", "audio/vnd.wave":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+// Package waveform draws the previews of uncompressed WAV files in pure Go, so audio previews
+// work without FFmpeg. The look follows the waveform style of the database, like the previews
+// drawn by FFmpeg.
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"slices"
+
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/media/vp8l"
+)
+
+// WAV format tags.
+const (
+	formatPCM        = 1
+	formatFloat      = 3
+	formatExtensible = 0xfffe
+)
+
+// wavFormat is the fmt chunk of a WAV file.
+type wavFormat struct {
+	tag           uint16
+	channels      uint16
+	blockAlign    uint16
+	bitsPerSample uint16
+}
+
+// Render draws the waveform of the WAV file r in the given style and writes it to w, as PNG if
+// the style is transparent, as WebP otherwise. The samples of all channels are drawn on top of
+// each other, every column shows the range of the samples it covers.
+func Render(w io.Writer, r io.Reader, style media.WaveformStyle) error {
+	style = style.WithDefaults()
+	if err := style.Validate(); err != nil {
+		return err
+	}
+	fg, err := media.ParseHexColor(style.Color)
+	if err != nil {
+		return err
+	}
+	bg := color.NRGBA{} // transparent
+	if style.Background != "" && !style.Transparent() {
+		if bg, err = media.ParseHexColor(style.Background); err != nil {
+			return err
+		}
+	}
+
+	lows, highs, err := readPeaks(bufio.NewReader(r), style.Width)
+	if err != nil {
+		return err
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, style.Width, style.Height), color.Palette{bg, fg})
+	half := float64(style.Height) / 2
+	for x := range style.Width {
+		top := int(math.Floor(half - highs[x]*half))
+		bottom := int(math.Ceil(half - lows[x]*half))
+		top = min(max(top, 0), style.Height-1)
+		bottom = min(max(bottom, top+1), style.Height) // silence is drawn as a line
+		for y := top; y < bottom; y++ {
+			img.SetColorIndex(x, y, 1)
+		}
+	}
+
+	if style.Transparent() {
+		return png.Encode(w, img)
+	}
+	return vp8l.Encode(w, img)
+}
+
+// readPeaks returns the lowest and highest sample (-1 to 1) of each of the columns.
+func readPeaks(r *bufio.Reader, columns int) (lows, highs []float64, err error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, nil, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, nil, errors.New("not a WAV file")
+	}
+
+	var format *wavFormat
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, nil, fmt.Errorf("WAV file has no data chunk: %w", err)
+		}
+		id, size := string(header[0:4]), binary.LittleEndian.Uint32(header[4:8])
+		switch id {
+		case "fmt ":
+			if format, err = readFormat(r, size); err != nil {
+				return nil, nil, err
+			}
+		case "data":
+			if format == nil {
+				return nil, nil, errors.New("WAV data chunk precedes the fmt chunk")
+			}
+			return readSamples(r, *format, size, columns)
+		default:
+			if _, err := r.Discard(int(size + size%2)); err != nil {
+				return nil, nil, fmt.Errorf("failed to skip WAV chunk '%s': %w", id, err)
+			}
+		}
+	}
+}
+
+func readFormat(r *bufio.Reader, size uint32) (*wavFormat, error) {
+	if size < 16 {
+		return nil, fmt.Errorf("WAV fmt chunk too short (%d bytes)", size)
+	}
+	data := make([]byte, size+size%2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read WAV fmt chunk: %w", err)
+	}
+	format := &wavFormat{
+		tag:           binary.LittleEndian.Uint16(data[0:2]),
+		channels:      binary.LittleEndian.Uint16(data[2:4]),
+		blockAlign:    binary.LittleEndian.Uint16(data[12:14]),
+		bitsPerSample: binary.LittleEndian.Uint16(data[14:16]),
+	}
+	if format.tag == formatExtensible && size >= 26 {
+		format.tag = binary.LittleEndian.Uint16(data[24:26]) // the first bytes of the sub format GUID
+	}
+
+	switch {
+	case format.tag == formatPCM && slices.Contains([]uint16{8, 16, 24, 32}, format.bitsPerSample):
+	case format.tag == formatFloat && format.bitsPerSample == 32:
+	default:
+		return nil, fmt.Errorf("unsupported WAV encoding (format %d, %d bits)", format.tag, format.bitsPerSample)
+	}
+	if format.channels == 0 || int(format.blockAlign) < int(format.channels)*int(format.bitsPerSample/8) {
+		return nil, fmt.Errorf("invalid WAV block size %d for %d channels", format.blockAlign, format.channels)
+	}
+	return format, nil
+}
+
+// readSamples reads the data chunk, a truncated chunk is drawn as far as it was written.
+func readSamples(r *bufio.Reader, format wavFormat, size uint32, columns int) (lows, highs []float64, err error) {
+	lows, highs = make([]float64, columns), make([]float64, columns)
+	frames := int64(size) / int64(format.blockAlign)
+	if frames == 0 {
+		return lows, highs, nil
+	}
+
+	sampleSize := int(format.bitsPerSample / 8)
+	frame := make([]byte, format.blockAlign)
+	column, seen := -1, false
+	for i := range frames {
+		if _, err := io.ReadFull(r, frame); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, nil, fmt.Errorf("failed to read WAV samples: %w", err)
+		}
+		if c := int(i * int64(columns) / frames); c != column {
+			column, seen = c, false
+		}
+		for ch := range int(format.channels) {
+			v := decodeSample(frame[ch*sampleSize:(ch+1)*sampleSize], format)
+			if !seen {
+				lows[column], highs[column], seen = v, v, true
+				continue
+			}
+			lows[column], highs[column] = min(lows[column], v), max(highs[column], v)
+		}
+	}
+	return lows, highs, nil
+}
+
+// decodeSample scales a sample to -1 to 1.
+func decodeSample(b []byte, format wavFormat) float64 {
+	var v float64
+	switch {
+	case format.tag == formatFloat:
+		v = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case len(b) == 1: // 8 bit samples are unsigned
+		v = (float64(b[0]) - 128) / 128
+	case len(b) == 2:
+		v = float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case len(b) == 3:
+		v = float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+	case len(b) == 4:
+		v = float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+	if math.IsNaN(v) {
+		return 0
+	}
+	return min(max(v, -1), 1)
+}
@@ -0,0 +1,163 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"mediahub_oss/internal/media"
+
+	"golang.org/x/image/webp"
+)
+
+// fixtureWAV returns half a second of a 440 Hz sine at half scale, 16 bit PCM.
+func fixtureWAV(channels int) []byte {
+	const sampleRate = 8000
+	const frames = sampleRate / 2
+
+	samples := make([]int16, 0, frames*channels)
+	for i := range frames {
+		v := int16(16384 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+		for range channels {
+			samples = append(samples, v)
+		}
+	}
+	dataSize := uint32(len(samples) * 2)
+	blockAlign := uint16(channels * 2)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+8+4)+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(formatPCM))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate)*uint32(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, blockAlign)
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("LIST") // chunks before the data are skipped
+	binary.Write(&buf, binary.LittleEndian, uint32(4))
+	buf.WriteString("INFO")
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+func nrgba(img image.Image, x, y int) color.NRGBA {
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+func TestRender(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	black := color.NRGBA{A: 0xff}
+
+	for _, tc := range []struct {
+		name       string
+		style      media.WaveformStyle
+		channels   int
+		format     string
+		width      int
+		height     int
+		background color.NRGBA
+		wave       color.NRGBA
+	}{
+		{"defaults", media.WaveformStyle{}, 1, "webp", 200, 120, color.NRGBA{}, color.NRGBA{R: 0x1e, G: 0x90, B: 0xff, A: 0xff}},
+		{"opaque", media.WaveformStyle{Width: 320, Height: 80, Color: "#f00", Background: "#000000"}, 2, "webp", 320, 80, black, red},
+		{"transparent", media.WaveformStyle{Width: 64, Height: 32, Color: "#ff0000", Background: media.WaveformTransparent}, 1, "png", 64, 32, color.NRGBA{}, red},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Render(&out, bytes.NewReader(fixtureWAV(tc.channels)), tc.style); err != nil {
+				t.Fatalf("failed to render: %v", err)
+			}
+
+			var img image.Image
+			var err error
+			if tc.format == "png" {
+				img, err = png.Decode(&out)
+			} else {
+				img, err = webp.Decode(&out)
+			}
+			if err != nil {
+				t.Fatalf("expected a %s image: %v", tc.format, err)
+			}
+			if got := img.Bounds().Size(); got != image.Pt(tc.width, tc.height) {
+				t.Fatalf("expected %dx%d, got %v", tc.width, tc.height, got)
+			}
+
+			// The sine reaches half the height above and below the center, the rest is background
+			for _, y := range []int{0, tc.height - 1} {
+				if got := nrgba(img, tc.width/2, y); got.A != tc.background.A || (got.A != 0 && got != tc.background) {
+					t.Errorf("expected the background %v at row %d, got %v", tc.background, y, got)
+				}
+			}
+			top, bottom := tc.height, 0
+			for x := range tc.width {
+				if got := nrgba(img, x, tc.height/2); got != tc.wave {
+					t.Fatalf("expected the waveform %v at the center of column %d, got %v", tc.wave, x, got)
+				}
+				for y := range tc.height {
+					if nrgba(img, x, y) == tc.wave {
+						top, bottom = min(top, y), max(bottom, y+1)
+					}
+				}
+			}
+			if top != tc.height/4 || bottom != tc.height*3/4 {
+				t.Errorf("expected the waveform between rows %d and %d, got %d and %d", tc.height/4, tc.height*3/4, top, bottom)
+			}
+		})
+	}
+}
+
+func TestRenderSilence(t *testing.T) {
+	wav := fixtureWAV(1)
+	data := bytes.Index(wav, []byte("data")) + 8
+	clear(wav[data:])
+
+	var out bytes.Buffer
+	style := media.WaveformStyle{Width: 40, Height: 20, Color: "#ffffff", Background: media.WaveformTransparent}
+	if err := Render(&out, bytes.NewReader(wav), style); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	img, err := png.Decode(&out)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	// Silence is a line at the center, the rest stays transparent
+	for y := range 20 {
+		want := uint8(0)
+		if y == 10 {
+			want = 0xff
+		}
+		if got := nrgba(img, 10, y).A; got != want {
+			t.Errorf("row %d: expected alpha %d, got %d", y, want, got)
+		}
+	}
+}
+
+func TestRenderRejects(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		style media.WaveformStyle
+		err   string
+	}{
+		"not a WAV":   {"ID3\x04not audio at all", media.WaveformStyle{}, "not a WAV file"},
+		"bad color":   {string(fixtureWAV(1)), media.WaveformStyle{Color: "blue"}, "waveform_color"},
+		"tiny":        {string(fixtureWAV(1)), media.WaveformStyle{Width: 4}, "waveform_width"},
+		"no data":     {string(fixtureWAV(1)[:36]), media.WaveformStyle{}, "no data chunk"},
+		"compressed":  {strings.Replace(string(fixtureWAV(1)), "\x01\x00\x01\x00", "\x55\x00\x01\x00", 1), media.WaveformStyle{}, "unsupported WAV encoding"},
+		"huge height": {string(fixtureWAV(1)), media.WaveformStyle{Height: 100000}, "waveform_height"},
+	} {
+		err := Render(&bytes.Buffer{}, strings.NewReader(tc.input), tc.style)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package notices
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// Sources of the notices recorded by the server.
+const (
+	SourceHousekeeping = "housekeeping"
+	SourceProcessing   = "processing"
+	SourceStartup      = "startup"
+)
+
+// Recorder keeps the warnings and errors of background work for administrators, next to the log.
+type Recorder interface {
+	Record(ctx context.Context, notice repository.ServerNotice)
+}
+
+// Key joins the parts of a deduplication key, e.g. the source, the kind of failure and the database.
+// Failures with the same key are counted in one notice, so it must not contain changing details like an entry ID.
+func Key(parts ...string) string {
+	return strings.Join(parts, ":")
+}
+
+// Service stores the notices in the repository. Recording never fails the caller, an error is only logged.
+type Service struct {
+	Repo   repository.Repository
+	Logger *slog.Logger
+}
+
+func NewService(repo repository.Repository, logger *slog.Logger) *Service {
+	return &Service{Repo: repo, Logger: logger}
+}
+
+func (s *Service) Record(ctx context.Context, notice repository.ServerNotice) {
+	// Failures during a shutdown are recorded as well
+	ctx = context.WithoutCancel(ctx)
+	if _, err := s.Repo.RecordNotice(ctx, notice); err != nil && !errors.Is(err, customerrors.ErrNotImplemented) {
+		s.Logger.Warn("Failed to record server notice", "key", notice.DedupKey, "message", notice.Message, "error", err)
+	}
+}
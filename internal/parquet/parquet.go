@@ -0,0 +1,161 @@
+// Package parquet writes flat tables as Apache Parquet files, e.g. the entry metadata of an export.
+// It covers the subset of the format the exports need: one level of required or optional columns
+// of 64-bit integers, doubles, booleans, UTF-8 strings and millisecond timestamps, PLAIN encoded
+// and uncompressed. Read decodes the same subset, it is used to verify written files.
+package parquet
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// magic starts and ends every Parquet file.
+const magic = "PAR1"
+
+// DefaultRowGroupSize is the number of rows buffered before a row group is written.
+const DefaultRowGroupSize = 10000
+
+// Type is the type of a column.
+type Type int
+
+const (
+	Int64     Type = iota // INT64
+	Double                // DOUBLE
+	Boolean               // BOOLEAN
+	String                // BYTE_ARRAY annotated as UTF8 string
+	Timestamp             // INT64 annotated as timestamp in milliseconds (UTC)
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int64:
+		return "int64"
+	case Double:
+		return "double"
+	case Boolean:
+		return "boolean"
+	case String:
+		return "string"
+	case Timestamp:
+		return "timestamp"
+	default:
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+}
+
+// Column describes a column of the table. Optional columns accept nil values.
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool
+}
+
+// Identifiers of the Parquet format (parquet.thrift).
+const (
+	physicalBoolean   = 0
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+
+	pageTypeData = 0
+)
+
+func (t Type) physical() int32 {
+	switch t {
+	case Double:
+		return physicalDouble
+	case Boolean:
+		return physicalBoolean
+	case String:
+		return physicalByteArray
+	default:
+		return physicalInt64
+	}
+}
+
+// Convert converts a value to the Go type stored for the column: int64, float64, bool or string,
+// or nil for an optional column without value. Writer.Write applies it to every value.
+func (c Column) Convert(v any) (any, error) {
+	if v == nil {
+		if !c.Optional {
+			return nil, fmt.Errorf("parquet: column %q is required", c.Name)
+		}
+		return nil, nil
+	}
+
+	switch c.Type {
+	case Int64, Timestamp:
+		switch n := v.(type) {
+		case int64:
+			return n, nil
+		case int:
+			return int64(n), nil
+		case int32:
+			return int64(n), nil
+		case uint64:
+			if n > math.MaxInt64 {
+				break
+			}
+			return int64(n), nil
+		case float64:
+			if n != math.Trunc(n) || math.Abs(n) > 1<<53 {
+				break
+			}
+			return int64(n), nil
+		case bool:
+			if n {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case time.Time:
+			if c.Type == Timestamp {
+				return n.UnixMilli(), nil
+			}
+		}
+	case Double:
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case float32:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		case int:
+			return float64(n), nil
+		}
+	case Boolean:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case int64:
+			// SQLite stores booleans as 0 or 1
+			if b == 0 || b == 1 {
+				return b == 1, nil
+			}
+		case int:
+			if b == 0 || b == 1 {
+				return b == 1, nil
+			}
+		}
+	case String:
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case []byte:
+			return string(s), nil
+		}
+	}
+	return nil, fmt.Errorf("parquet: column %q (%s) cannot hold %T %v", c.Name, c.Type, v, v)
+}
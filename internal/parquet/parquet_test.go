@@ -0,0 +1,121 @@
+package parquet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+	columns := []Column{
+		{Name: "id", Type: Int64},
+		{Name: "timestamp", Type: Timestamp},
+		{Name: "name", Type: String},
+		{Name: "score", Type: Double, Optional: true},
+		{Name: "flag", Type: Boolean, Optional: true},
+		{Name: "note", Type: String, Optional: true},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, columns, 4)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+
+	base := time.UnixMilli(1700000000123).UTC()
+	var want [][]any
+	for i := range 11 {
+		row := []any{int64(i), base.Add(time.Duration(i) * time.Minute), "entry ü", nil, nil, nil}
+		if i%2 == 0 {
+			row[3] = float64(i) / 4
+		}
+		if i%3 != 0 {
+			row[4] = i%3 == 1
+		}
+		if i > 6 {
+			row[5] = string(rune('a' + i))
+		}
+		want = append(want, row)
+		if err := w.Write(row); err != nil {
+			t.Fatalf("failed to write row %d: %v", i, err)
+		}
+	}
+
+	// Rejected rows are not written
+	if err := w.Write([]any{nil, base, "x", nil, nil, nil}); err == nil {
+		t.Error("expected an error for a null in a required column")
+	}
+	if err := w.Write([]any{int64(1), base, 42, nil, nil, nil}); err == nil {
+		t.Error("expected an error for a number in a string column")
+	}
+	if err := w.Write([]any{int64(1)}); err == nil {
+		t.Error("expected an error for a short row")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != magic || string(data[len(data)-4:]) != magic {
+		t.Fatalf("expected the file to start and end with %q", magic)
+	}
+
+	file, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if !reflect.DeepEqual(file.Columns, columns) {
+		t.Errorf("expected columns %+v, got %+v", columns, file.Columns)
+	}
+	if !reflect.DeepEqual(file.Rows, want) {
+		t.Errorf("expected rows\n%v\ngot\n%v", want, file.Rows)
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{{Name: "id", Type: Int64}}, 0)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	file, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(file.Columns) != 1 || len(file.Rows) != 0 {
+		t.Errorf("expected one column without rows, got %+v (err %v)", file, err)
+	}
+
+	if _, err := NewWriter(&buf, []Column{{Name: "a"}, {Name: "a"}}, 0); err == nil {
+		t.Error("expected an error for duplicate columns")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		col  Column
+		in   any
+		want any
+	}{
+		{Column{Type: Int64}, 7, int64(7)},
+		{Column{Type: Int64}, float64(7), int64(7)},
+		{Column{Type: Boolean}, int64(1), true},
+		{Column{Type: Boolean}, int64(0), false},
+		{Column{Type: Double}, int64(3), float64(3)},
+		{Column{Type: String}, []byte("x"), "x"},
+		{Column{Type: Timestamp}, time.UnixMilli(5), int64(5)},
+	}
+	for _, tc := range cases {
+		got, err := tc.col.Convert(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("%s %v: expected %v, got %v (err %v)", tc.col.Type, tc.in, tc.want, got, err)
+		}
+	}
+
+	for _, in := range []any{1.5, int64(2), "1"} {
+		if _, err := (Column{Type: Boolean}).Convert(in); err == nil {
+			t.Errorf("expected an error for %v in a boolean column", in)
+		}
+	}
+}
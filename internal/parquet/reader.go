@@ -0,0 +1,248 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// File is a Parquet file decoded by Read. Values are int64, float64, bool, string or time.Time
+// (UTC) by column type, and nil for nulls.
+type File struct {
+	Columns []Column
+	Rows    [][]any
+}
+
+var errCorrupt = errors.New("parquet: corrupt file")
+
+// Read decodes a whole Parquet file written by Writer, or another file within the same subset
+// of the format (flat, PLAIN encoded, uncompressed data pages).
+func Read(r io.ReaderAt, size int64) (File, error) {
+	if size < int64(2*len(magic)+4) {
+		return File{}, errCorrupt
+	}
+	tail := make([]byte, 4+len(magic))
+	if _, err := r.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return File{}, fmt.Errorf("parquet: %w", err)
+	}
+	if string(tail[4:]) != magic {
+		return File{}, errCorrupt
+	}
+	footerSize := int64(binary.LittleEndian.Uint32(tail))
+	if footerSize > size-int64(len(tail)+len(magic)) {
+		return File{}, errCorrupt
+	}
+	footer := make([]byte, footerSize)
+	if _, err := r.ReadAt(footer, size-int64(len(tail))-footerSize); err != nil {
+		return File{}, fmt.Errorf("parquet: %w", err)
+	}
+	meta, err := (&compactReader{buf: footer}).readStruct(0)
+	if err != nil {
+		return File{}, err
+	}
+
+	// 1. Schema
+	var file File
+	schema := meta.list(2)
+	if len(schema) < 2 {
+		return File{}, fmt.Errorf("parquet: file has no columns")
+	}
+	for _, e := range schema[1:] {
+		elem, ok := e.(thriftStruct)
+		if !ok || elem.int(5) != 0 {
+			return File{}, fmt.Errorf("parquet: nested schemas are not supported")
+		}
+		col := Column{Name: elem.str(4), Optional: elem.int(3) == repetitionOptional}
+		switch elem.int(1) {
+		case physicalBoolean:
+			col.Type = Boolean
+		case physicalDouble:
+			col.Type = Double
+		case physicalByteArray:
+			col.Type = String
+		case physicalInt64:
+			col.Type = Int64
+			if _, ok := elem[6]; ok && elem.int(6) == convertedTimestampMillis {
+				col.Type = Timestamp
+			}
+		default:
+			return File{}, fmt.Errorf("parquet: column %q has an unsupported type", col.Name)
+		}
+		file.Columns = append(file.Columns, col)
+	}
+
+	// 2. Row groups, column by column
+	for _, g := range meta.list(4) {
+		group, _ := g.(thriftStruct)
+		chunks := group.list(1)
+		numRows := int(group.int(3))
+		if len(chunks) != len(file.Columns) || numRows < 0 {
+			return File{}, errCorrupt
+		}
+
+		rows := make([][]any, numRows)
+		for i := range rows {
+			rows[i] = make([]any, len(file.Columns))
+		}
+		for i, ch := range chunks {
+			chunk, _ := ch.(thriftStruct)
+			md := chunk.child(3)
+			if md.int(4) != codecUncompressed {
+				return File{}, fmt.Errorf("parquet: compressed columns are not supported")
+			}
+			chunkSize := md.int(7)
+			if md.int(9) < 0 || chunkSize < 0 || md.int(9)+chunkSize > size {
+				return File{}, errCorrupt
+			}
+			data := make([]byte, chunkSize)
+			if _, err := r.ReadAt(data, md.int(9)); err != nil {
+				return File{}, fmt.Errorf("parquet: %w", err)
+			}
+			values, err := decodeChunk(file.Columns[i], data, numRows)
+			if err != nil {
+				return File{}, err
+			}
+			for row, v := range values {
+				rows[row][i] = v
+			}
+		}
+		file.Rows = append(file.Rows, rows...)
+	}
+
+	return file, nil
+}
+
+// decodeChunk decodes the data pages of a column chunk.
+func decodeChunk(c Column, data []byte, numRows int) ([]any, error) {
+	values := make([]any, 0, numRows)
+	for len(values) < numRows {
+		reader := &compactReader{buf: data}
+		header, err := reader.readStruct(0)
+		if err != nil {
+			return nil, err
+		}
+		pageSize := int(header.int(3))
+		data = data[reader.pos:]
+		if header.int(1) != pageTypeData || pageSize > len(data) || pageSize < 0 {
+			return nil, fmt.Errorf("parquet: column %q has an unsupported page", c.Name)
+		}
+		dph := header.child(5)
+		if dph.int(2) != encodingPlain {
+			return nil, fmt.Errorf("parquet: column %q is not PLAIN encoded", c.Name)
+		}
+
+		page, err := decodePage(c, data[:pageSize], int(dph.int(1)))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, page...)
+		data = data[pageSize:]
+	}
+	if len(values) != numRows {
+		return nil, errCorrupt
+	}
+	return values, nil
+}
+
+func decodePage(c Column, data []byte, numValues int) ([]any, error) {
+	defined := make([]bool, numValues)
+	for i := range defined {
+		defined[i] = true
+	}
+	if c.Optional {
+		if len(data) < 4 {
+			return nil, errCorrupt
+		}
+		n := int(binary.LittleEndian.Uint32(data))
+		if n > len(data)-4 {
+			return nil, errCorrupt
+		}
+		if err := decodeDefinitionLevels(data[4:4+n], defined); err != nil {
+			return nil, err
+		}
+		data = data[4+n:]
+	}
+
+	values := make([]any, numValues)
+	in := bytes.NewReader(data)
+	bit := 0
+	for i := range values {
+		if !defined[i] {
+			continue
+		}
+		switch c.Type {
+		case Boolean:
+			if bit/8 >= len(data) {
+				return nil, errCorrupt
+			}
+			values[i] = data[bit/8]&(1<<(bit%8)) != 0
+			bit++
+		case Double:
+			var v uint64
+			if err := binary.Read(in, binary.LittleEndian, &v); err != nil {
+				return nil, errCorrupt
+			}
+			values[i] = math.Float64frombits(v)
+		case String:
+			var n uint32
+			if err := binary.Read(in, binary.LittleEndian, &n); err != nil || int(n) > in.Len() {
+				return nil, errCorrupt
+			}
+			s := make([]byte, n)
+			in.Read(s)
+			values[i] = string(s)
+		default:
+			var v int64
+			if err := binary.Read(in, binary.LittleEndian, &v); err != nil {
+				return nil, errCorrupt
+			}
+			if c.Type == Timestamp {
+				values[i] = time.UnixMilli(v).UTC()
+			} else {
+				values[i] = v
+			}
+		}
+	}
+	return values, nil
+}
+
+// decodeDefinitionLevels decodes RLE hybrid levels of bit width 1 into defined.
+func decodeDefinitionLevels(data []byte, defined []bool) error {
+	pos := 0
+	for i := 0; i < len(defined); {
+		header, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return errCorrupt
+		}
+		pos += n
+		if header&1 == 0 {
+			// RLE run: the count, then the level in one byte
+			count := int(header >> 1)
+			if pos >= len(data) || count > len(defined)-i {
+				return errCorrupt
+			}
+			level := data[pos] != 0
+			pos++
+			for range count {
+				defined[i] = level
+				i++
+			}
+			continue
+		}
+		// Bit-packed run of groups of 8 levels
+		count := int(header>>1) * 8
+		if pos+count/8 > len(data) {
+			return errCorrupt
+		}
+		for j := 0; j < count && i < len(defined); j++ {
+			defined[i] = data[pos+j/8]&(1<<(j%8)) != 0
+			i++
+		}
+		pos += count / 8
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Type IDs of the Thrift compact protocol, the encoding of the Parquet metadata.
+const (
+	compactStop   = 0
+	compactTrue   = 1
+	compactFalse  = 2
+	compactByte   = 3
+	compactI16    = 4
+	compactI32    = 5
+	compactI64    = 6
+	compactDouble = 7
+	compactBinary = 8
+	compactList   = 9
+	compactSet    = 10
+	compactMap    = 11
+	compactStruct = 12
+)
+
+// Limits of the decoder, so a corrupt footer cannot exhaust the memory.
+const (
+	maxThriftDepth  = 32
+	maxThriftLength = 1 << 28
+)
+
+// compactWriter encodes Thrift structs with the compact protocol. Fields are written in the
+// order of their IDs, as the field headers hold the delta to the previous ID.
+type compactWriter struct {
+	buf    []byte
+	lastID []int16 // last field ID per open struct
+}
+
+func newCompactWriter() *compactWriter {
+	return &compactWriter{lastID: []int16{0}}
+}
+
+func (c *compactWriter) varint(v uint64) {
+	c.buf = binary.AppendUvarint(c.buf, v)
+}
+
+func (c *compactWriter) zigzag(v int64) {
+	c.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (c *compactWriter) field(id int16, typ byte) {
+	last := &c.lastID[len(c.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.buf = append(c.buf, byte(delta)<<4|typ)
+	} else {
+		c.buf = append(c.buf, typ)
+		c.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.field(id, compactI32)
+	c.zigzag(int64(v))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.field(id, compactI64)
+	c.zigzag(v)
+}
+
+func (c *compactWriter) bool(id int16, v bool) {
+	if v {
+		c.field(id, compactTrue)
+	} else {
+		c.field(id, compactFalse)
+	}
+}
+
+func (c *compactWriter) string(id int16, v string) {
+	c.field(id, compactBinary)
+	c.varint(uint64(len(v)))
+	c.buf = append(c.buf, v...)
+}
+
+// beginStruct opens a struct field, endStruct closes it again.
+func (c *compactWriter) beginStruct(id int16) {
+	c.field(id, compactStruct)
+	c.lastID = append(c.lastID, 0)
+}
+
+func (c *compactWriter) endStruct() {
+	c.buf = append(c.buf, compactStop)
+	c.lastID = c.lastID[:len(c.lastID)-1]
+}
+
+// list writes the header of a list field, followed by its size elements.
+func (c *compactWriter) list(id int16, elemType byte, size int) {
+	c.field(id, compactList)
+	if size < 15 {
+		c.buf = append(c.buf, byte(size)<<4|elemType)
+	} else {
+		c.buf = append(c.buf, 0xf0|elemType)
+		c.varint(uint64(size))
+	}
+}
+
+// beginElement opens a struct element of a list, endStruct closes it.
+func (c *compactWriter) beginElement() {
+	c.lastID = append(c.lastID, 0)
+}
+
+func (c *compactWriter) i32Element(v int32) {
+	c.zigzag(int64(v))
+}
+
+func (c *compactWriter) stringElement(v string) {
+	c.varint(uint64(len(v)))
+	c.buf = append(c.buf, v...)
+}
+
+// finish closes the top-level struct and returns the encoding.
+func (c *compactWriter) finish() []byte {
+	c.buf = append(c.buf, compactStop)
+	return c.buf
+}
+
+// thriftStruct is a decoded Thrift struct by field ID. Values are int64, float64, bool, []byte,
+// []any or thriftStruct.
+type thriftStruct map[int16]any
+
+func (s thriftStruct) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStruct) str(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s thriftStruct) child(id int16) thriftStruct {
+	v, _ := s[id].(thriftStruct)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []any {
+	v, _ := s[id].([]any)
+	return v
+}
+
+var errCompactTruncated = errors.New("parquet: truncated metadata")
+
+// compactReader decodes Thrift structs of the compact protocol without knowing their definition.
+type compactReader struct {
+	buf []byte
+	pos int
+}
+
+func (c *compactReader) byte() (byte, error) {
+	if c.pos >= len(c.buf) {
+		return 0, errCompactTruncated
+	}
+	b := c.buf[c.pos]
+	c.pos++
+	return b, nil
+}
+
+func (c *compactReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(c.buf[c.pos:])
+	if n <= 0 {
+		return 0, errCompactTruncated
+	}
+	c.pos += n
+	return v, nil
+}
+
+func (c *compactReader) zigzag() (int64, error) {
+	v, err := c.varint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (c *compactReader) readStruct(depth int) (thriftStruct, error) {
+	if depth > maxThriftDepth {
+		return nil, fmt.Errorf("parquet: metadata nested too deeply")
+	}
+	s := thriftStruct{}
+	var lastID int16
+	for {
+		header, err := c.byte()
+		if err != nil {
+			return nil, err
+		}
+		if header == compactStop {
+			return s, nil
+		}
+		typ := header & 0x0f
+		if delta := int16(header >> 4); delta != 0 {
+			lastID += delta
+		} else {
+			id, err := c.zigzag()
+			if err != nil {
+				return nil, err
+			}
+			lastID = int16(id)
+		}
+		switch typ {
+		case compactTrue, compactFalse:
+			s[lastID] = typ == compactTrue
+		default:
+			v, err := c.readValue(typ, depth)
+			if err != nil {
+				return nil, err
+			}
+			s[lastID] = v
+		}
+	}
+}
+
+func (c *compactReader) readValue(typ byte, depth int) (any, error) {
+	switch typ {
+	case compactTrue, compactFalse:
+		// Booleans in lists are a byte each
+		b, err := c.byte()
+		return b == compactTrue, err
+	case compactByte:
+		b, err := c.byte()
+		return int64(int8(b)), err
+	case compactI16, compactI32, compactI64:
+		return c.zigzag()
+	case compactDouble:
+		if c.pos+8 > len(c.buf) {
+			return nil, errCompactTruncated
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(c.buf[c.pos:]))
+		c.pos += 8
+		return v, nil
+	case compactBinary:
+		n, err := c.varint()
+		if err != nil {
+			return nil, err
+		}
+		if n > maxThriftLength || c.pos+int(n) > len(c.buf) {
+			return nil, errCompactTruncated
+		}
+		v := c.buf[c.pos : c.pos+int(n)]
+		c.pos += int(n)
+		return v, nil
+	case compactList, compactSet:
+		header, err := c.byte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = c.varint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > maxThriftLength {
+			return nil, errCompactTruncated
+		}
+		elems := make([]any, 0, min(size, 1024))
+		for range size {
+			v, err := c.readValue(header&0x0f, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, v)
+		}
+		return elems, nil
+	case compactStruct:
+		return c.readStruct(depth + 1)
+	case compactMap:
+		return nil, fmt.Errorf("parquet: maps in metadata are not supported")
+	default:
+		return nil, fmt.Errorf("parquet: unknown metadata type %d", typ)
+	}
+}
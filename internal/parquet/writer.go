@@ -0,0 +1,336 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Writer streams rows into a Parquet file. Rows are buffered until a row group is full, so the memory
+// use depends on the row group size and not on the number of rows. Close writes the footer.
+type Writer struct {
+	out          io.Writer
+	columns      []Column
+	rowGroupSize int
+
+	offset    int64   // bytes written so far
+	buffered  [][]any // values of the current row group per column
+	rows      int     // rows in the current row group
+	rowGroups []rowGroup
+	numRows   int64
+	started   bool
+	closed    bool
+	err       error
+}
+
+type rowGroup struct {
+	columns   []columnChunk
+	numRows   int64
+	totalSize int64
+}
+
+type columnChunk struct {
+	numValues int64
+	offset    int64 // of the page header
+	size      int64 // page header and data
+}
+
+// NewWriter returns a Writer of a table with the given columns. A rowGroupSize of 0 or less
+// uses DefaultRowGroupSize.
+func NewWriter(out io.Writer, columns []Column, rowGroupSize int) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: no columns")
+	}
+	seen := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		if c.Name == "" || seen[c.Name] {
+			return nil, fmt.Errorf("parquet: invalid or duplicate column name %q", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	return &Writer{
+		out:          out,
+		columns:      columns,
+		rowGroupSize: rowGroupSize,
+		buffered:     make([][]any, len(columns)),
+	}, nil
+}
+
+// Write adds a row with one value per column, in the order of the columns.
+func (w *Writer) Write(row []any) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.closed {
+		return errors.New("parquet: write after close")
+	}
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("parquet: row has %d values, expected %d", len(row), len(w.columns))
+	}
+
+	// Validate the whole row first, so a rejected row leaves the columns aligned
+	values := make([]any, len(row))
+	for i, c := range w.columns {
+		v, err := c.Convert(row[i])
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	for i, v := range values {
+		w.buffered[i] = append(w.buffered[i], v)
+	}
+	w.rows++
+
+	if w.rows >= w.rowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the footer. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	footer := w.footer()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, magic...)
+	return w.write(footer)
+}
+
+func (w *Writer) write(p []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	n, err := w.out.Write(p)
+	w.offset += int64(n)
+	if err != nil {
+		w.err = fmt.Errorf("parquet: %w", err)
+	}
+	return w.err
+}
+
+// start writes the leading magic bytes.
+func (w *Writer) start() error {
+	if w.started {
+		return w.err
+	}
+	w.started = true
+	return w.write([]byte(magic))
+}
+
+// flush writes the buffered rows as a row group with one data page per column.
+func (w *Writer) flush() error {
+	if w.rows == 0 {
+		return w.err
+	}
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	group := rowGroup{numRows: int64(w.rows)}
+	for i, c := range w.columns {
+		page := encodePage(c, w.buffered[i])
+		header := pageHeader(len(page), w.rows)
+
+		chunk := columnChunk{numValues: int64(w.rows), offset: w.offset, size: int64(len(header) + len(page))}
+		if err := w.write(header); err != nil {
+			return err
+		}
+		if err := w.write(page); err != nil {
+			return err
+		}
+		group.columns = append(group.columns, chunk)
+		group.totalSize += chunk.size
+		w.buffered[i] = w.buffered[i][:0]
+	}
+
+	w.rowGroups = append(w.rowGroups, group)
+	w.numRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+// encodePage encodes the values of a column as the data of a PLAIN data page (version 1), preceded
+// by the definition levels of optional columns.
+func encodePage(c Column, values []any) []byte {
+	var buf []byte
+	if c.Optional {
+		levels := encodeDefinitionLevels(values)
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(levels)))
+		buf = append(buf, levels...)
+	}
+
+	switch c.Type {
+	case Boolean:
+		// Bit-packed, least significant bit first
+		var current byte
+		n := 0
+		for _, v := range values {
+			if v == nil {
+				continue
+			}
+			if v.(bool) {
+				current |= 1 << (n % 8)
+			}
+			n++
+			if n%8 == 0 {
+				buf = append(buf, current)
+				current = 0
+			}
+		}
+		if n%8 != 0 {
+			buf = append(buf, current)
+		}
+	case Double:
+		for _, v := range values {
+			if v != nil {
+				buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.(float64)))
+			}
+		}
+	case String:
+		for _, v := range values {
+			if v != nil {
+				s := v.(string)
+				buf = binary.LittleEndian.AppendUint32(buf, uint32(len(s)))
+				buf = append(buf, s...)
+			}
+		}
+	default:
+		for _, v := range values {
+			if v != nil {
+				buf = binary.LittleEndian.AppendUint64(buf, uint64(v.(int64)))
+			}
+		}
+	}
+	return buf
+}
+
+// encodeDefinitionLevels encodes 1 for every value and 0 for every null with the RLE hybrid
+// encoding (bit width 1), as one run per sequence of equal levels.
+func encodeDefinitionLevels(values []any) []byte {
+	var buf []byte
+	for i := 0; i < len(values); {
+		defined := values[i] != nil
+		j := i + 1
+		for j < len(values) && (values[j] != nil) == defined {
+			j++
+		}
+		buf = binary.AppendUvarint(buf, uint64(j-i)<<1)
+		if defined {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		i = j
+	}
+	return buf
+}
+
+// pageHeader encodes the PageHeader of an uncompressed data page.
+func pageHeader(size int, numValues int) []byte {
+	c := newCompactWriter()
+	c.i32(1, pageTypeData)
+	c.i32(2, int32(size)) // uncompressed_page_size
+	c.i32(3, int32(size)) // compressed_page_size
+	c.beginStruct(5)      // data_page_header
+	c.i32(1, int32(numValues))
+	c.i32(2, encodingPlain)
+	c.i32(3, encodingRLE) // definition levels
+	c.i32(4, encodingRLE) // repetition levels
+	c.endStruct()
+	return c.finish()
+}
+
+// footer encodes the FileMetaData.
+func (w *Writer) footer() []byte {
+	c := newCompactWriter()
+	c.i32(1, 1) // version
+
+	// The schema is a root element followed by the columns
+	c.list(2, compactStruct, len(w.columns)+1)
+	c.beginElement()
+	c.string(4, "schema")
+	c.i32(5, int32(len(w.columns)))
+	c.endStruct()
+	for _, col := range w.columns {
+		c.beginElement()
+		c.i32(1, col.Type.physical())
+		if col.Optional {
+			c.i32(3, repetitionOptional)
+		} else {
+			c.i32(3, repetitionRequired)
+		}
+		c.string(4, col.Name)
+		switch col.Type {
+		case String:
+			c.i32(6, convertedUTF8)
+			c.beginStruct(10) // logicalType
+			c.beginStruct(1)  // STRING
+			c.endStruct()
+			c.endStruct()
+		case Timestamp:
+			c.i32(6, convertedTimestampMillis)
+			c.beginStruct(10) // logicalType
+			c.beginStruct(8)  // TIMESTAMP
+			c.bool(1, true)   // isAdjustedToUTC
+			c.beginStruct(2)  // unit
+			c.beginStruct(1)  // MILLIS
+			c.endStruct()
+			c.endStruct()
+			c.endStruct()
+			c.endStruct()
+		}
+		c.endStruct()
+	}
+
+	c.i64(3, w.numRows)
+
+	c.list(4, compactStruct, len(w.rowGroups))
+	for _, group := range w.rowGroups {
+		c.beginElement()
+		c.list(1, compactStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			col := w.columns[i]
+			c.beginElement()
+			c.i64(2, chunk.offset) // file_offset
+			c.beginStruct(3)       // meta_data
+			c.i32(1, col.Type.physical())
+			c.list(2, compactI32, 2)
+			c.i32Element(encodingPlain)
+			c.i32Element(encodingRLE)
+			c.list(3, compactBinary, 1)
+			c.stringElement(col.Name)
+			c.i32(4, codecUncompressed)
+			c.i64(5, chunk.numValues)
+			c.i64(6, chunk.size) // total_uncompressed_size
+			c.i64(7, chunk.size) // total_compressed_size
+			c.i64(9, chunk.offset)
+			c.endStruct()
+			c.endStruct()
+		}
+		c.i64(2, group.totalSize)
+		c.i64(3, group.numRows)
+		c.endStruct()
+	}
+
+	c.string(6, "mediahub")
+	return c.finish()
+}
@@ -30,12 +30,13 @@ func (p *Processor) handleLargeFileAsync(
 	}
 	p.Logger.Debug("Claimed large file for async processing", "from", httpTempPath, "to", workerTempPath)
 
-	createdEntry, err := p.createPreliminaryEntry(ctx, db, req, plan, repo.EntryStatusProcessing, false)
+	createdEntry, _, err := p.createPreliminaryEntry(ctx, db, req, plan, repo.EntryStatusProcessing, false)
 	if err != nil {
 		os.Remove(workerTempPath)
 		return repo.Entry{}, err
 	}
 	p.Logger.Debug("Created partial entry in database", "entry", createdEntry.ID)
+	createdEntry.Size = uint64(req.Size) // the worker checks the spooled file against it
 
 	go func() {
 		defer func() {
@@ -0,0 +1,42 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+const (
+	fulltextBackfillBatchSize = 1000 // entries indexed per transaction
+	fulltextBackfillBatches   = 50   // batches per task run, a follow-up task continues after that
+)
+
+// runFulltextBackfillTask copies entries that existed before the full-text index of a database was
+// built into it. The task stays well below the lease: after fulltextBackfillBatches it hands over to
+// a new task, so other due tasks get their turn in between.
+func (p *Processor) runFulltextBackfillTask(ctx context.Context, task repo.PendingTask) error {
+	for range fulltextBackfillBatches {
+		done, err := p.Repo.BackfillFulltext(ctx, task.DatabaseID, fulltextBackfillBatchSize)
+		if err != nil {
+			if errors.Is(err, customerrors.ErrNotFound) {
+				return errTaskObsolete
+			}
+			return err
+		}
+		if done {
+			p.Logger.Info("TaskRunner: Full-text index is complete", "database", task.DatabaseID)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	if err := p.Repo.ScheduleFulltextBackfill(ctx, task.DatabaseID); err != nil {
+		return fmt.Errorf("failed to schedule the next full-text backfill: %w", err)
+	}
+	return nil
+}
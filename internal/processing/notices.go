@@ -0,0 +1,25 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+
+	"mediahub_oss/internal/notices"
+	repo "mediahub_oss/internal/repository"
+)
+
+// noticeFailure records the failed processing of an entry for the administrators. The failures of a
+// database are counted in one notice per reason, which names the latest entry.
+func (p *Processor) noticeFailure(ctx context.Context, db repo.Database, entryID int64, reason string, err error) {
+	if p.Notices == nil {
+		return
+	}
+	p.Notices.Record(ctx, repo.ServerNotice{
+		DedupKey:   notices.Key(notices.SourceProcessing, reason, db.ID.String()),
+		Source:     notices.SourceProcessing,
+		Severity:   repo.NoticeSeverityError,
+		Message:    fmt.Sprintf("Processing of entry %d in database '%s' failed (%s): %v", entryID, db.Name, reason, err),
+		DatabaseID: db.ID,
+		EntryID:    entryID,
+	})
+}
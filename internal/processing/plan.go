@@ -26,6 +26,76 @@ type ProcessingPlan struct {
 	FinalFileName string
 }
 
+// ConversionPlan describes how a file of a given mime type is converted before it is stored.
+// The ffmpeg arguments for the target are chosen by the media converter.
+type ConversionPlan struct {
+	WantsConversion bool // a rule or the auto conversion applies to the file
+	NeedsConversion bool // the target differs from the original mime type
+	CanConvert      bool // the converter supports the conversion
+
+	TargetMimeType  string // the requested mime type
+	ResultMimeType  string // the mime type of the stored file
+	OutputExtension string // the file extension of the result, empty if the file is kept as is
+}
+
+// Convert reports whether the file is run through the converter.
+func (p ConversionPlan) Convert() bool {
+	return p.NeedsConversion && p.CanConvert
+}
+
+// ConversionPlanner decides which conversion applies to an upload. Per-mime conversion rules of
+// the database take precedence over its auto conversion target. HEIC/HEIF images, which browsers
+// cannot display, are always converted: to JPEG unless another supported target is configured.
+type ConversionPlanner struct {
+	Converter media.MediaConverter
+}
+
+// Plan returns the conversion plan for a file of the original mime type in a database of the content type.
+func (p ConversionPlanner) Plan(contentType string, originalMimeType string, cfg repo.DatabaseConfig) ConversionPlan {
+	originalMimeType = media.NormalizeMimeType(originalMimeType)
+	plan := ConversionPlan{TargetMimeType: originalMimeType, ResultMimeType: originalMimeType}
+
+	target := ""
+	if rule, ok := MatchConversionRule(contentType, originalMimeType, cfg.ConversionRules); ok {
+		target = media.NormalizeMimeType(rule.To)
+	} else if cfg.AutoConversion != "" {
+		target = media.NormalizeMimeType(cfg.AutoConversion)
+	}
+	if media.IsHEIC(originalMimeType) && (target == "" || media.IsHEIC(target) || !p.Converter.CanConvert(originalMimeType, target).CanConvert) {
+		target = media.HEICTargetMimeType
+	}
+	if target == "" {
+		return plan
+	}
+
+	plan.WantsConversion = true
+	plan.TargetMimeType = target
+
+	check := p.Converter.CanConvert(originalMimeType, target)
+	plan.NeedsConversion = check.NeedsConversion
+	plan.CanConvert = check.CanConvert
+	if check.CanConvert {
+		plan.ResultMimeType = target
+	}
+	if plan.Convert() {
+		plan.OutputExtension = GetExtensionForMimeType(target)
+	}
+	return plan
+}
+
+// MatchConversionRule returns the rule converting files of the mime type, if any.
+func MatchConversionRule(contentType string, mimeType string, rules []repo.ConversionRule) (repo.ConversionRule, bool) {
+	mimeType = media.NormalizeMimeType(mimeType)
+	for _, rule := range rules {
+		if media.NormalizeMimeType(rule.From) == mimeType {
+			if ok, _ := media.IsMimeOfType(contentType, mimeType); ok {
+				return rule, true
+			}
+		}
+	}
+	return repo.ConversionRule{}, false
+}
+
 // DetermineConversionPlan evaluates if a file needs conversion based on the database configuration.
 func DetermineConversionPlan(mc media.MediaConverter, db repo.Database, originalMimeType string, originalFileName string, userFileName string) (ProcessingPlan, error) {
 	originalMimeType = media.NormalizeMimeType(originalMimeType)
@@ -38,113 +108,78 @@ func DetermineConversionPlan(mc media.MediaConverter, db repo.Database, original
 		return ProcessingPlan{InitMimeType: originalMimeType}, err
 	}
 
-	wantsConversion := (db.Config.AutoConversion != "")
-	targetMimeType := originalMimeType
-	resultMimeType := originalMimeType
-
-	var convCheck media.ConversionCheck
-	if wantsConversion {
-		targetMimeType = db.Config.AutoConversion
-
-		// check capabilities
-		convCheck = mc.CanConvert(originalMimeType, db.Config.AutoConversion)
-		if convCheck.CanConvert {
-			resultMimeType = targetMimeType
-		}
-	}
-
-	canGenPreview := mc.CanCreatePreview(originalMimeType)
-
 	// derive file name
-	finalFileName := originalFileName
+	fileName := originalFileName
 	if userFileName != "" {
-		finalFileName = userFileName
-		if filepath.Ext(finalFileName) == "" {
+		fileName = userFileName
+		if filepath.Ext(fileName) == "" {
 			originalExt := filepath.Ext(originalFileName)
-			finalFileName = finalFileName + originalExt
+			fileName = fileName + originalExt
 		}
 	}
-	if convCheck.NeedsConversion && convCheck.CanConvert {
-		newExtension := GetExtensionForMimeType(db.Config.AutoConversion)
-		finalFileName = ReplaceExtension(finalFileName, newExtension)
-	}
 
-	return ProcessingPlan{
-		WantsConversion: wantsConversion,
-		NeedsConversion: convCheck.NeedsConversion,
-		CanConvert:      convCheck.CanConvert,
-		WantsPreview:    db.Config.CreatePreview,
-		CanGenPreview:   canGenPreview,
-		InitMimeType:    originalMimeType,
-		TargetMimeType:  targetMimeType,
-		ResultMimeType:  resultMimeType,
-		FinalFileName:   finalFileName,
-	}, nil
+	plan := buildProcessingPlan(mc, db, originalMimeType, fileName)
+	if media.IsHEIC(originalMimeType) && !(plan.NeedsConversion && plan.CanConvert) {
+		return plan, fmt.Errorf("%w: %s uploads need an FFmpeg build with HEVC decoding support", customerrors.ErrDependencies, originalMimeType)
+	}
+	return plan, nil
 }
 
 // DeterminePlanForEntry determines the processing plan for a queued/processing database entry.
 func DeterminePlanForEntry(mc media.MediaConverter, db repo.Database, entry repo.Entry) ProcessingPlan {
-	originalMimeType := entry.MimeType
-	wantsConversion := (db.Config.AutoConversion != "")
-	targetMimeType := originalMimeType
-	resultMimeType := originalMimeType
-
-	var convCheck media.ConversionCheck
-	if wantsConversion {
-		targetMimeType = db.Config.AutoConversion
-		convCheck = mc.CanConvert(originalMimeType, db.Config.AutoConversion)
-		if convCheck.CanConvert {
-			resultMimeType = targetMimeType
-		}
-	}
+	return buildProcessingPlan(mc, db, entry.MimeType, entry.FileName)
+}
 
-	canGenPreview := mc.CanCreatePreview(originalMimeType)
+// buildProcessingPlan combines the conversion plan and the preview capabilities for a file.
+func buildProcessingPlan(mc media.MediaConverter, db repo.Database, originalMimeType string, fileName string) ProcessingPlan {
+	conv := ConversionPlanner{Converter: mc}.Plan(db.ContentType, originalMimeType, db.Config)
 
-	// Derive final file name
-	finalFileName := entry.FileName
-	if convCheck.NeedsConversion && convCheck.CanConvert {
-		newExtension := GetExtensionForMimeType(db.Config.AutoConversion)
-		finalFileName = ReplaceExtension(finalFileName, newExtension)
+	finalFileName := fileName
+	if conv.Convert() {
+		finalFileName = ReplaceExtension(finalFileName, conv.OutputExtension)
 	}
 
 	return ProcessingPlan{
-		WantsConversion: wantsConversion,
-		NeedsConversion: convCheck.NeedsConversion,
-		CanConvert:      convCheck.CanConvert,
+		WantsConversion: conv.WantsConversion,
+		NeedsConversion: conv.NeedsConversion,
+		CanConvert:      conv.CanConvert,
 		WantsPreview:    db.Config.CreatePreview,
-		CanGenPreview:   canGenPreview,
+		CanGenPreview:   mc.CanCreatePreview(originalMimeType),
 		InitMimeType:    originalMimeType,
-		TargetMimeType:  targetMimeType,
-		ResultMimeType:  resultMimeType,
+		TargetMimeType:  conv.TargetMimeType,
+		ResultMimeType:  conv.ResultMimeType,
 		FinalFileName:   finalFileName,
 	}
 }
 
+// mimeToExtension maps the MIME types of the media databases to their preferred file extension.
+var mimeToExtension = map[string]string{
+	// Images
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+	"image/avif": "avif",
+	"image/heic": "heic",
+	"image/heif": "heif",
+
+	// Audio
+	"audio/mpeg":      "mp3",
+	"audio/wav":       "wav",
+	"audio/flac":      "flac",
+	"audio/x-flac":    "flac",
+	"audio/opus":      "opus",
+	"audio/ogg":       "ogg",
+	"application/ogg": "ogg",
+
+	// Video
+	"video/mp4":  "mp4",
+	"video/webm": "webm",
+	"video/ogg":  "ogv",
+}
+
 // GetExtensionForMimeType returns the preferred file extension for a given MIME type (e.g., ".opus")
 func GetExtensionForMimeType(mimeType string) string {
-	var mimeToExtension = map[string]string{
-		// Images
-		"image/jpeg": "jpg",
-		"image/png":  "png",
-		"image/gif":  "gif",
-		"image/webp": "webp",
-		"image/avif": "avif",
-
-		// Audio
-		"audio/mpeg":      "mp3",
-		"audio/wav":       "wav",
-		"audio/flac":      "flac",
-		"audio/x-flac":    "flac",
-		"audio/opus":      "opus",
-		"audio/ogg":       "ogg",
-		"application/ogg": "ogg",
-
-		// Video
-		"video/mp4":  "mp4",
-		"video/webm": "webm",
-		"video/ogg":  "ogv",
-	}
-
 	if ext, ok := mimeToExtension[mimeType]; ok {
 		return "." + ext
 	}
@@ -159,6 +194,28 @@ func GetExtensionForMimeType(mimeType string) string {
 	return ""
 }
 
+// ContradictsMimeType reports whether the extension of fileName is the extension of another media type
+// than mimeType, e.g. "photo.mp3" for a JPEG. Unknown extensions and untyped files never contradict.
+func ContradictsMimeType(fileName string, mimeType string) bool {
+	mimeType = media.NormalizeMimeType(mimeType)
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		return false
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	if ext == "" || "."+ext == GetExtensionForMimeType(mimeType) {
+		return false
+	}
+	for _, known := range mimeToExtension {
+		if ext == known {
+			return true
+		}
+	}
+	return false
+}
+
 // ReplaceExtension replaces the extension of a filename with a new one.
 func ReplaceExtension(filename string, newExt string) string {
 	if filename == "" {
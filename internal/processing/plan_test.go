@@ -0,0 +1,161 @@
+package processing
+
+import (
+	"errors"
+	"testing"
+
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// planConverter supports the listed conversions only.
+type planConverter struct {
+	media.MediaConverter
+	supported map[string][]string
+}
+
+func (c *planConverter) CanConvert(in, out string) media.ConversionCheck {
+	if in == out {
+		return media.ConversionCheck{NeedsConversion: false, CanConvert: true}
+	}
+	for _, target := range c.supported[in] {
+		if target == out {
+			return media.ConversionCheck{NeedsConversion: true, CanConvert: true}
+		}
+	}
+	return media.ConversionCheck{NeedsConversion: true, CanConvert: false}
+}
+
+func (c *planConverter) CanCreatePreview(string) bool { return true }
+
+func TestConversionPlanner(t *testing.T) {
+	planner := ConversionPlanner{Converter: &planConverter{supported: map[string][]string{
+		"audio/wav":  {"audio/flac", "audio/opus"},
+		"audio/mpeg": {"audio/opus"},
+		"image/png":  {"image/jpeg", "image/webp"},
+	}}}
+	wavToFlac := []repo.ConversionRule{{From: "audio/wav", To: "audio/flac"}}
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		mime        string
+		cfg         repo.DatabaseConfig
+		want        ConversionPlan
+	}{
+		{
+			name: "no conversion configured", contentType: "audio", mime: "audio/wav",
+			want: ConversionPlan{TargetMimeType: "audio/wav", ResultMimeType: "audio/wav"},
+		},
+		{
+			name: "auto conversion", contentType: "audio", mime: "audio/mpeg",
+			cfg:  repo.DatabaseConfig{AutoConversion: "audio/opus"},
+			want: ConversionPlan{WantsConversion: true, NeedsConversion: true, CanConvert: true, TargetMimeType: "audio/opus", ResultMimeType: "audio/opus", OutputExtension: ".opus"},
+		},
+		{
+			name: "rule wins over auto conversion", contentType: "audio", mime: "audio/wav",
+			cfg:  repo.DatabaseConfig{AutoConversion: "audio/opus", ConversionRules: wavToFlac},
+			want: ConversionPlan{WantsConversion: true, NeedsConversion: true, CanConvert: true, TargetMimeType: "audio/flac", ResultMimeType: "audio/flac", OutputExtension: ".flac"},
+		},
+		{
+			name: "other mime types fall back to auto conversion", contentType: "audio", mime: "audio/mpeg",
+			cfg:  repo.DatabaseConfig{AutoConversion: "audio/opus", ConversionRules: wavToFlac},
+			want: ConversionPlan{WantsConversion: true, NeedsConversion: true, CanConvert: true, TargetMimeType: "audio/opus", ResultMimeType: "audio/opus", OutputExtension: ".opus"},
+		},
+		{
+			name: "other mime types are kept without auto conversion", contentType: "audio", mime: "audio/mpeg",
+			cfg:  repo.DatabaseConfig{ConversionRules: wavToFlac},
+			want: ConversionPlan{TargetMimeType: "audio/mpeg", ResultMimeType: "audio/mpeg"},
+		},
+		{
+			name: "aliases match rules", contentType: "image", mime: "image/png",
+			cfg:  repo.DatabaseConfig{ConversionRules: []repo.ConversionRule{{From: "image/png", To: "image/jpg"}}},
+			want: ConversionPlan{WantsConversion: true, NeedsConversion: true, CanConvert: true, TargetMimeType: "image/jpeg", ResultMimeType: "image/jpeg", OutputExtension: ".jpg"},
+		},
+		{
+			name: "already the target", contentType: "audio", mime: "audio/opus",
+			cfg:  repo.DatabaseConfig{AutoConversion: "audio/opus"},
+			want: ConversionPlan{WantsConversion: true, CanConvert: true, TargetMimeType: "audio/opus", ResultMimeType: "audio/opus"},
+		},
+		{
+			name: "unsupported conversion keeps the original", contentType: "audio", mime: "audio/mpeg",
+			cfg:  repo.DatabaseConfig{AutoConversion: "audio/flac"},
+			want: ConversionPlan{WantsConversion: true, NeedsConversion: true, TargetMimeType: "audio/flac", ResultMimeType: "audio/mpeg"},
+		},
+		{
+			name: "rules of another content type are ignored", contentType: "image", mime: "image/png",
+			cfg:  repo.DatabaseConfig{ConversionRules: wavToFlac},
+			want: ConversionPlan{TargetMimeType: "image/png", ResultMimeType: "image/png"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := planner.Plan(tc.contentType, tc.mime, tc.cfg); got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+
+	// The processing plan renames converted files
+	db := repo.Database{ContentType: "audio", Config: repo.DatabaseConfig{ConversionRules: wavToFlac}}
+	plan, err := DetermineConversionPlan(planner.Converter, db, "audio/wav", "take.wav", "")
+	if err != nil {
+		t.Fatalf("failed to determine plan: %v", err)
+	}
+	if plan.FinalFileName != "take.flac" || plan.ResultMimeType != "audio/flac" {
+		t.Errorf("expected take.flac as audio/flac, got %s as %s", plan.FinalFileName, plan.ResultMimeType)
+	}
+}
+
+func TestHEICPlan(t *testing.T) {
+	db := repo.Database{ContentType: "image"}
+
+	// Converted to JPEG without any conversion configured
+	supported := &planConverter{supported: map[string][]string{"image/heic": {"image/jpeg"}}}
+	plan, err := DetermineConversionPlan(supported, db, "image/heic", "IMG_0001.HEIC", "")
+	if err != nil {
+		t.Fatalf("failed to determine plan: %v", err)
+	}
+	if !plan.WantsConversion || plan.ResultMimeType != "image/jpeg" || plan.FinalFileName != "IMG_0001.jpg" {
+		t.Errorf("expected IMG_0001.jpg as image/jpeg, got %+v", plan)
+	}
+
+	// A configured target wins, an unsupported one falls back to JPEG
+	db.Config.AutoConversion = "image/webp"
+	if plan := (ConversionPlanner{Converter: supported}).Plan("image", "image/heic", db.Config); plan.ResultMimeType != "image/jpeg" {
+		t.Errorf("expected the fallback to image/jpeg, got %+v", plan)
+	}
+	both := &planConverter{supported: map[string][]string{"image/heic": {"image/jpeg", "image/webp"}}}
+	if plan := (ConversionPlanner{Converter: both}).Plan("image", "image/heic", db.Config); plan.ResultMimeType != "image/webp" {
+		t.Errorf("expected image/webp, got %+v", plan)
+	}
+
+	// Rejected if FFmpeg cannot decode HEIC
+	_, err = DetermineConversionPlan(&planConverter{}, repo.Database{ContentType: "image"}, "image/heic", "IMG_0001.HEIC", "")
+	if !errors.Is(err, customerrors.ErrDependencies) {
+		t.Errorf("expected ErrDependencies, got %v", err)
+	}
+}
+
+func TestContradictsMimeType(t *testing.T) {
+	for _, tc := range []struct {
+		fileName string
+		mimeType string
+		want     bool
+	}{
+		{"photo.mp3", "image/jpeg", true},
+		{"photo.jpg", "image/jpeg", false},
+		{"photo.JPEG", "image/jpg", false},
+		{"song.ogg", "application/ogg", false},
+		{"song.flac", "audio/x-flac", false},
+		{"clip.webm", "video/mp4", true},
+		{"report.pdf", "image/png", false}, // unknown extensions are left alone
+		{"notes", "audio/mpeg", false},
+		{"archive.png", "application/octet-stream", false},
+		{"scan.jpg", "application/pdf", true},
+	} {
+		if got := ContradictsMimeType(tc.fileName, tc.mimeType); got != tc.want {
+			t.Errorf("ContradictsMimeType(%q, %q) = %v, expected %v", tc.fileName, tc.mimeType, got, tc.want)
+		}
+	}
+}
@@ -7,17 +7,27 @@ import (
 	"log/slog"
 	"os"
 	"sync"
+	"time"
 
+	"mediahub_oss/internal/logging/audit"
 	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/notices"
 	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/scanner"
 	"mediahub_oss/internal/shared/customerrors"
 	"mediahub_oss/internal/storage"
+	"mediahub_oss/internal/transcription"
 )
 
 type EntryRequest struct {
-	Timestamp    int64
-	FileName     string
-	CustomFields map[string]any
+	Timestamp       int64
+	ClientTimestamp time.Time // the client's timestamp if Timestamp replaced it, zero otherwise
+	FileName        string
+	ExternalID      string
+	CustomFields    map[string]any
+	Origin          repo.UploadOrigin // who uploaded the entry and from where
+	Size            int64             // size of the file announced by the client, 0 if unknown
+	SyncPreview     bool              // generate the preview before returning, only for small files
 }
 
 type Processor struct {
@@ -28,9 +38,32 @@ type Processor struct {
 	NFfmpegTotal   int
 	Logger         *slog.Logger
 
+	// Optional virus scanning, disabled if Scanner is nil
+	Scanner          scanner.Scanner
+	ScanFailOpen     bool     // accept files if the scanner is unreachable
+	ScanContentTypes []string // content types of the databases whose uploads are scanned
+	Auditor          audit.AuditLogger
+
+	// Sources of failed asynchronous uploads are kept this long so the entry can be retried, disabled if 0
+	RetainFailedUploads time.Duration
+
+	// Progress of the entries processed by asynchronous workers
+	Progress *ProgressRegistry
+
+	// Optional transcription of audio entries, disabled if Transcriber is nil
+	Transcriber transcription.Transcriber
+
+	// Receives the terminal failures of asynchronous processing for the administrators, disabled if nil
+	Notices notices.Recorder
+
 	mu          sync.Mutex
 	activeAsync int
 	activeTotal int
+	paused      bool // no new queue workers or tasks are started, see SetPaused
+	draining    bool // no new work is accepted at all, see BeginDrain
+
+	jobs    sync.WaitGroup       // reserved slots and other background work, awaited by Drain
+	running map[RunningEntry]int // entries being processed in the background, see trackEntry
 }
 
 func NewProcessor(
@@ -48,6 +81,7 @@ func NewProcessor(
 		NFfmpegAsync:   nFfmpegAsync,
 		NFfmpegTotal:   nFfmpegTotal,
 		Logger:         logger,
+		Progress:       NewProgressRegistry(progressTTL),
 	}, nil
 }
 
@@ -60,9 +94,9 @@ func (p *Processor) ProcessEntry(
 	originalMimeType string,
 	originalFileName string,
 ) (repo.Entry, bool, error) {
-	procPlan, err := DetermineConversionPlan(p.MediaConverter, db, originalMimeType, originalFileName, req.FileName)
-	if err != nil {
-		return repo.Entry{}, false, err
+	if p.isDraining() {
+		p.Logger.Warn("Upload rejected: The server is shutting down", "database_id", db.ID.String())
+		return repo.Entry{}, false, customerrors.ErrUnavailable
 	}
 
 	var isLarge bool
@@ -72,6 +106,39 @@ func (p *Processor) ProcessEntry(
 		diskFile = f
 	}
 
+	// Empty uploads never create an entry, nor do small uploads that differ from the announced size.
+	// Spooled files are compared with it by the worker, which marks mismatches as truncated_upload.
+	size, err := streamSize(file)
+	if err != nil {
+		return repo.Entry{}, false, err
+	}
+	announced := req.Size
+	if isLarge {
+		announced = 0
+	}
+	if err := CheckUploadSize(size, announced); err != nil {
+		return repo.Entry{}, false, err
+	}
+
+	procPlan, err := DetermineConversionPlan(p.MediaConverter, db, originalMimeType, originalFileName, req.FileName)
+	if err != nil {
+		return repo.Entry{}, false, err
+	}
+
+	if isLarge && req.SyncPreview {
+		return repo.Entry{}, false, fmt.Errorf("%w: sync_preview is only supported for files processed synchronously", customerrors.ErrValidation)
+	}
+
+	// Uploads are scanned once, in memory or in the spool file, before anything is written to storage
+	if p.wantsScan(db) {
+		if err := p.scanContent(ctx, db, file, fmt.Sprintf("%s:%s", db.ID, originalFileName)); err != nil {
+			return repo.Entry{}, !isLarge, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return repo.Entry{}, !isLarge, fmt.Errorf("failed to seek file after scanning: %w", err)
+		}
+	}
+
 	if isLarge {
 		// Path A: Large File, Asynchronous
 		if p.tryReserveAsyncSlot() {
@@ -90,16 +157,18 @@ func (p *Processor) ProcessEntry(
 		}
 
 		if int(queuedCount) < db.NMaxQueued {
-			p.Logger.Debug("Concurrency limit reached, queueing large file", "database_id", db.ID.String(), "active_async", p.activeAsync, "active_total", p.activeTotal, "queued_count", queuedCount, "max_queued", db.NMaxQueued)
+			p.Logger.Debug("Concurrency limit reached, queueing large file", "database_id", db.ID.String(), "active_async", p.activeAsyncWorkers(), "active_total", p.ActiveWorkers(), "queued_count", queuedCount, "max_queued", db.NMaxQueued)
 			entry, err := p.queueLargeFile(ctx, diskFile, db, req, procPlan)
 			if err != nil {
 				return repo.Entry{}, false, err
 			}
-			p.tryAcquireAndSpawn(context.Background(), db, entry)
+			if entry.Status == repo.EntryStatusQueued {
+				p.tryAcquireAndSpawn(context.Background(), db, entry)
+			}
 			return entry, false, nil
 		}
 
-		p.Logger.Warn("Upload rejected: Concurrency limit reached and queue is full", "database_id", db.ID.String(), "active_async", p.activeAsync, "active_total", p.activeTotal, "queued_count", queuedCount, "max_queued", db.NMaxQueued)
+		p.Logger.Warn("Upload rejected: Concurrency limit reached and queue is full", "database_id", db.ID.String(), "active_async", p.activeAsyncWorkers(), "active_total", p.ActiveWorkers(), "queued_count", queuedCount, "max_queued", db.NMaxQueued)
 		return repo.Entry{}, false, customerrors.ErrUnavailable
 	}
 
@@ -124,7 +193,7 @@ func (p *Processor) ProcessEntry(
 	}
 
 	if int(queuedCount) < db.NMaxQueued {
-		p.Logger.Debug("Concurrency limit reached, queueing small file", "database_id", db.ID.String(), "active_total", p.activeTotal, "queued_count", queuedCount, "max_queued", db.NMaxQueued)
+		p.Logger.Debug("Concurrency limit reached, queueing small file", "database_id", db.ID.String(), "active_total", p.ActiveWorkers(), "queued_count", queuedCount, "max_queued", db.NMaxQueued)
 		entry, err := p.queueSmallFile(ctx, file, db, req, procPlan)
 		if err != nil {
 			return repo.Entry{}, false, err
@@ -133,7 +202,7 @@ func (p *Processor) ProcessEntry(
 		return entry, false, nil
 	}
 
-	p.Logger.Warn("Upload rejected: Concurrency limit reached and queue is full", "database_id", db.ID, "active_total", p.activeTotal, "queued_count", queuedCount, "max_queued", db.NMaxQueued)
+	p.Logger.Warn("Upload rejected: Concurrency limit reached and queue is full", "database_id", db.ID, "active_total", p.ActiveWorkers(), "queued_count", queuedCount, "max_queued", db.NMaxQueued)
 	return repo.Entry{}, false, customerrors.ErrUnavailable
 }
 
@@ -141,11 +210,12 @@ func (p *Processor) ProcessEntry(
 func (p *Processor) tryReserveAsyncSlot() bool {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.activeAsync >= p.NFfmpegAsync || p.activeTotal >= p.NFfmpegTotal {
+	if p.draining || p.activeAsync >= p.NFfmpegAsync || p.activeTotal >= p.NFfmpegTotal {
 		return false
 	}
 	p.activeAsync++
 	p.activeTotal++
+	p.jobs.Add(1)
 	return true
 }
 
@@ -155,16 +225,18 @@ func (p *Processor) releaseAsyncSlot() {
 	p.activeAsync--
 	p.activeTotal--
 	p.mu.Unlock()
+	p.jobs.Done()
 }
 
 // tryReserveSyncSlot checks limits and reserves a slot for a synchronous/small conversion.
 func (p *Processor) tryReserveSyncSlot() bool {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.activeTotal >= p.NFfmpegTotal {
+	if p.draining || p.activeTotal >= p.NFfmpegTotal {
 		return false
 	}
 	p.activeTotal++
+	p.jobs.Add(1)
 	return true
 }
 
@@ -173,4 +245,37 @@ func (p *Processor) releaseSyncSlot() {
 	p.mu.Lock()
 	p.activeTotal--
 	p.mu.Unlock()
+	p.jobs.Done()
+}
+
+// SetPaused stops starting queue workers and pending tasks, e.g. during maintenance. Running ones finish,
+// queued entries and due tasks wait until the processor is resumed.
+func (p *Processor) SetPaused(paused bool) {
+	p.mu.Lock()
+	p.paused = paused
+	p.mu.Unlock()
+
+	if !paused {
+		go p.TriggerQueueWorkersIfPossible(context.Background())
+	}
+}
+
+func (p *Processor) isPaused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// ActiveWorkers returns the number of conversions and tasks currently holding a processing slot.
+func (p *Processor) ActiveWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.activeTotal
+}
+
+// activeAsyncWorkers returns the number of running asynchronous conversions.
+func (p *Processor) activeAsyncWorkers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.activeAsync
 }
@@ -0,0 +1,67 @@
+package processing
+
+import (
+	"fmt"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// Phases of an asynchronously processed entry, as reported by the progress registry.
+const (
+	PhaseQueued     = "queued"
+	PhaseStarting   = "starting" // claimed by a worker that has not reported yet
+	PhaseConverting = "converting"
+	PhasePreview    = "preview"
+	PhaseFinalizing = "finalizing"
+)
+
+const (
+	progressTTL        = 30 * time.Minute // refreshed by every update, only hit if a worker never finishes
+	maxTrackedProgress = 10000
+)
+
+// Progress is a snapshot of an entry that is being processed.
+type Progress struct {
+	Phase     string
+	Percent   float64 // progress within the phase (0-100), -1 if it cannot be measured
+	UpdatedAt time.Time
+}
+
+// ProgressRegistry keeps the progress of running workers in memory. It is safe for concurrent use,
+// entries are removed when processing ends or expire after progressTTL without update.
+type ProgressRegistry struct {
+	items *cache.Cache
+}
+
+func NewProgressRegistry(ttl time.Duration) *ProgressRegistry {
+	return &ProgressRegistry{items: cache.New(ttl, ttl)}
+}
+
+// Set records the phase and percentage of an entry. New entries are dropped once the registry is full.
+func (r *ProgressRegistry) Set(dbID repo.ULID, entryID int64, phase string, percent float64) {
+	key := progressKey(dbID, entryID)
+	if _, tracked := r.items.Get(key); !tracked && r.items.ItemCount() >= maxTrackedProgress {
+		return
+	}
+	r.items.SetDefault(key, Progress{Phase: phase, Percent: percent, UpdatedAt: time.Now()})
+}
+
+// Get returns the progress of an entry, false if it is not being processed.
+func (r *ProgressRegistry) Get(dbID repo.ULID, entryID int64) (Progress, bool) {
+	item, ok := r.items.Get(progressKey(dbID, entryID))
+	if !ok {
+		return Progress{}, false
+	}
+	return item.(Progress), true
+}
+
+func (r *ProgressRegistry) Remove(dbID repo.ULID, entryID int64) {
+	r.items.Delete(progressKey(dbID, entryID))
+}
+
+func progressKey(dbID repo.ULID, entryID int64) string {
+	return fmt.Sprintf("%s:%d", dbID, entryID)
+}
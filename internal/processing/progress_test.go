@@ -0,0 +1,112 @@
+package processing
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// steppedConverter reports each fraction it receives on steps and waits for the test before continuing.
+type steppedConverter struct {
+	media.MediaConverter
+	steps  chan float64
+	resume chan struct{}
+}
+
+func (c *steppedConverter) CanCreatePreview(string) bool { return false }
+
+func (c *steppedConverter) CanConvert(string, string) media.ConversionCheck {
+	return media.ConversionCheck{NeedsConversion: true, CanConvert: true}
+}
+
+func (c *steppedConverter) ConvertFile(ctx context.Context, inputPath, outputPath, inputMimeType, targetMimeType string) error {
+	report := media.ProgressFromContext(ctx)
+	for fraction := range c.steps {
+		report(fraction)
+		c.resume <- struct{}{}
+	}
+	return os.WriteFile(outputPath, []byte("converted"), 0o644)
+}
+
+func TestWorkerReportsProgress(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "progress_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db.Config.AutoConversion = "application/x-converted"
+
+	converter := &steppedConverter{steps: make(chan float64), resume: make(chan struct{})}
+	p, _ := NewProcessor(r, store, converter, 1, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{
+		FileName:  "file.bin",
+		Status:    repo.EntryStatusProcessing,
+		Timestamp: time.Now(),
+		MimeType:  "application/octet-stream",
+	})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	source, err := os.CreateTemp(t.TempDir(), "upload-*")
+	if err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+	source.Close()
+
+	plan := DeterminePlanForEntry(converter, db, entry)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.runConversionAndFinalize(ctx, db, entry, source.Name(), plan)
+	}()
+
+	// Every reported fraction shows up as percentage of the converting phase
+	for _, fraction := range []float64{0.1, 0.5} {
+		converter.steps <- fraction
+		<-converter.resume
+
+		progress, ok := p.Progress.Get(db.ID, entry.ID)
+		if !ok || progress.Phase != PhaseConverting || progress.Percent != fraction*100 {
+			t.Errorf("expected converting at %.0f%%, got %+v (tracked: %v)", fraction*100, progress, ok)
+		}
+	}
+	close(converter.steps)
+	<-done
+
+	// Once processing ended, the entry is no longer tracked
+	if progress, ok := p.Progress.Get(db.ID, entry.ID); ok {
+		t.Errorf("expected the progress to be removed, got %+v", progress)
+	}
+	if got, _ := r.GetEntry(ctx, db.ID, entry.ID); got.Status != repo.EntryStatusReady {
+		t.Errorf("expected the entry to be ready, got %v", got.Status)
+	}
+}
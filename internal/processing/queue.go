@@ -30,7 +30,7 @@ func (p *Processor) queueLargeFile(
 		return repo.Entry{}, fmt.Errorf("failed to claim temp file: %w", err)
 	}
 
-	createdEntry, err := p.createPreliminaryEntry(ctx, db, req, plan, repo.EntryStatusQueued, false)
+	createdEntry, _, err := p.createPreliminaryEntry(ctx, db, req, plan, repo.EntryStatusQueued, false)
 	if err != nil {
 		os.Remove(workerTempPath)
 		return repo.Entry{}, err
@@ -43,6 +43,12 @@ func (p *Processor) queueLargeFile(
 	}
 	defer f.Close()
 
+	spooledSize, err := streamSize(f)
+	if err != nil {
+		os.Remove(workerTempPath)
+		return repo.Entry{}, err
+	}
+
 	fileSize, err := p.Storage.Write(ctx, db.ID.String(), createdEntry.ID, f)
 	if err != nil {
 		os.Remove(workerTempPath)
@@ -50,11 +56,25 @@ func (p *Processor) queueLargeFile(
 	}
 	os.Remove(workerTempPath)
 
+	// The spooled file must have the announced size, the staged copy that of the spooled file
 	createdEntry.Size = uint64(fileSize)
-	finalEntry, err := p.Repo.UpdateEntry(ctx, db.ID, createdEntry)
-	if err != nil {
+	truncated := CheckUploadSize(spooledSize, req.Size)
+	if truncated == nil {
+		truncated = CheckStoredSize(fileSize, spooledSize, false)
+	}
+	if truncated != nil {
+		p.Logger.Error("Queued upload is truncated", "entry", createdEntry.ID, "error", truncated)
+		_ = p.Storage.Delete(ctx, db.ID.String(), createdEntry.ID)
+		createdEntry.Status = repo.EntryStatusError
+		createdEntry.ErrorReason = ErrorReasonTruncatedUpload
+	}
+	if err := p.saveProcessedEntry(ctx, db.ID, createdEntry, true); err != nil {
 		return repo.Entry{}, fmt.Errorf("failed to update queued entry size: %w", err)
 	}
+	finalEntry, err := p.Repo.GetEntry(ctx, db.ID, createdEntry.ID)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to get queued entry: %w", err)
+	}
 
 	p.Logger.Debug("Successfully queued large file for async processing", "database_id", db.ID.String(), "entry_id", finalEntry.ID, "filename", finalEntry.FileName)
 	return finalEntry, nil
@@ -67,31 +87,44 @@ func (p *Processor) queueSmallFile(
 	req EntryRequest,
 	plan ProcessingPlan,
 ) (repo.Entry, error) {
-	createdEntry, err := p.createPreliminaryEntry(ctx, db, req, plan, repo.EntryStatusQueued, false)
+	createdEntry, _, err := p.createPreliminaryEntry(ctx, db, req, plan, repo.EntryStatusQueued, false)
 	if err != nil {
 		return repo.Entry{}, err
 	}
 
-	if _, err := file.Seek(0, io.SeekStart); err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to seek file: %w", err)
+	size, err := streamSize(file)
+	if err != nil {
+		return repo.Entry{}, err
 	}
 
 	fileSize, err := p.Storage.Write(ctx, db.ID.String(), createdEntry.ID, file)
+	if err == nil {
+		if err = CheckStoredSize(fileSize, size, false); err != nil {
+			p.discardEntry(ctx, db, createdEntry.ID)
+		}
+	}
 	if err != nil {
 		return repo.Entry{}, fmt.Errorf("failed to write file to storage: %w", err)
 	}
 
 	createdEntry.Size = uint64(fileSize)
-	finalEntry, err := p.Repo.UpdateEntry(ctx, db.ID, createdEntry)
-	if err != nil {
+	if err := p.saveProcessedEntry(ctx, db.ID, createdEntry, true); err != nil {
 		return repo.Entry{}, fmt.Errorf("failed to update queued entry size: %w", err)
 	}
+	finalEntry, err := p.Repo.GetEntry(ctx, db.ID, createdEntry.ID)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to get queued entry: %w", err)
+	}
 
 	p.Logger.Debug("Successfully queued small file for processing", "database_id", db.ID.String(), "entry_id", finalEntry.ID, "filename", finalEntry.FileName)
 	return finalEntry, nil
 }
 
 func (p *Processor) findNextQueuedEntry(ctx context.Context) (repo.Entry, repo.Database, bool, error) {
+	if p.isPaused() {
+		return repo.Entry{}, repo.Database{}, false, nil // the queue is picked up again once resumed
+	}
+
 	databases, err := p.Repo.GetDatabases(ctx)
 	if err != nil {
 		return repo.Entry{}, repo.Database{}, false, err
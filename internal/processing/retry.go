@@ -0,0 +1,162 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// conversionErrorReason distinguishes a missing converter from a file it could not convert.
+func conversionErrorReason(err error) string {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, customerrors.ErrNotImplemented) || errors.Is(err, customerrors.ErrDependencies) {
+		return ErrorReasonDependencyMissing
+	}
+	return ErrorReasonConversionFailed
+}
+
+// errorDetail returns the end of the stderr of a failed media tool, kept with the entry next to its
+// error_reason, or "" if err did not come from one.
+func errorDetail(err error) string {
+	var cmdErr *media.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.StderrTail
+	}
+	return ""
+}
+
+// isRetryable reports whether processing may succeed when it is run again on the same source.
+func isRetryable(reason string) bool {
+	switch reason {
+	case ErrorReasonConversionFailed, ErrorReasonStorageFailed, ErrorReasonDependencyMissing, ErrorReasonInternal:
+		return true
+	default:
+		return false
+	}
+}
+
+// retainUpload moves the source of a failed upload out of the worker's cleanup and records it,
+// so RetryEntry can process it again until the grace period has passed.
+// Returns false if the source was not retained and must be removed by the caller.
+func (p *Processor) retainUpload(ctx context.Context, dbID repo.ULID, entryID int64, sourcePath string) bool {
+	retainedFile, err := os.CreateTemp(os.TempDir(), "mh-retained-*")
+	if err != nil {
+		p.Logger.Warn("Worker: Failed to create file to retain failed upload", "entry", entryID, "error", err)
+		return false
+	}
+	retainedPath := retainedFile.Name()
+	retainedFile.Close()
+
+	if err := os.Rename(sourcePath, retainedPath); err != nil {
+		p.Logger.Warn("Worker: Failed to retain failed upload", "entry", entryID, "error", err)
+		os.Remove(retainedPath)
+		return false
+	}
+
+	now := time.Now()
+	err = p.Repo.SaveRetainedUpload(ctx, repo.RetainedUpload{
+		DatabaseID: dbID,
+		EntryID:    entryID,
+		Path:       retainedPath,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(p.RetainFailedUploads),
+	})
+	if err != nil {
+		p.Logger.Warn("Worker: Failed to record retained upload", "entry", entryID, "error", err)
+		os.Remove(retainedPath)
+		return true // the source is gone either way
+	}
+
+	p.Logger.Debug("Worker: Retained source of failed upload", "entry", entryID, "path", retainedPath, "expires_at", now.Add(p.RetainFailedUploads))
+	return true
+}
+
+// RetryEntry processes a failed entry again, using the source retained when it failed.
+// The entry is claimed by changing its status from error to processing, so concurrent retries stage the source
+// only once. The source is staged in storage and the entry is queued, like an upload that hit the concurrency limits.
+// Returns customerrors.ErrConflict if the entry did not fail, customerrors.ErrGone if its source is no longer
+// available and customerrors.ErrUnavailable if the queue of the database is full. The entry keeps its error then.
+func (p *Processor) RetryEntry(ctx context.Context, db repo.Database, entry repo.Entry) (repo.Entry, error) {
+	// 1. Claim the failed entry
+	claimed, err := p.Repo.ClaimFailedEntry(ctx, db.ID, entry.ID)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to claim entry: %w", err)
+	}
+	if !claimed {
+		return repo.Entry{}, customerrors.ErrConflict
+	}
+
+	queuedEntry, err := p.stageRetry(ctx, db, entry)
+	if err != nil {
+		// Restore the error, so the entry can be retried again
+		if restoreErr := p.Repo.UpdateEntryStatus(context.WithoutCancel(ctx), db.ID, entry.ID, repo.EntryStatusError, entry.ErrorReason, entry.ErrorDetail); restoreErr != nil {
+			p.Logger.Error("Failed to restore status of entry after failed retry", "entry", entry.ID, "error", restoreErr)
+		}
+		return repo.Entry{}, err
+	}
+
+	p.tryAcquireAndSpawn(context.Background(), db, queuedEntry)
+	return queuedEntry, nil
+}
+
+// stageRetry writes the retained source of a claimed entry to storage and queues the entry.
+func (p *Processor) stageRetry(ctx context.Context, db repo.Database, entry repo.Entry) (repo.Entry, error) {
+	// 2. Find the retained source
+	retained, err := p.Repo.GetRetainedUpload(ctx, db.ID, entry.ID)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			return repo.Entry{}, customerrors.ErrGone
+		}
+		return repo.Entry{}, fmt.Errorf("failed to get retained upload: %w", err)
+	}
+
+	source, err := os.Open(retained.Path)
+	if err != nil {
+		// Retained by another instance, or removed from the temp dir in the meantime
+		p.Logger.Warn("Retained upload is missing", "entry", entry.ID, "path", retained.Path, "error", err)
+		return repo.Entry{}, customerrors.ErrGone
+	}
+	defer source.Close()
+
+	// 3. Respect the queue limit of the database
+	queuedCount, err := p.Repo.CountEntriesByStatus(ctx, db.ID, repo.EntryStatusQueued)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to count queued entries: %w", err)
+	}
+	if int(queuedCount) >= db.NMaxQueued {
+		return repo.Entry{}, customerrors.ErrUnavailable
+	}
+
+	// 4. Stage the source in storage and queue the entry
+	fileSize, err := p.Storage.Write(ctx, db.ID.String(), entry.ID, source)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to write retained upload to storage: %w", err)
+	}
+
+	entry.Status = repo.EntryStatusQueued
+	entry.ErrorReason = ""
+	entry.ErrorDetail = ""
+	entry.Size = uint64(fileSize)
+	if err := p.saveProcessedEntry(ctx, db.ID, entry, true); err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to queue entry for retry: %w", err)
+	}
+	queuedEntry, err := p.Repo.GetEntry(ctx, db.ID, entry.ID)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to get queued entry: %w", err)
+	}
+
+	// 5. The staged copy is the source from now on
+	if err := p.Repo.DeleteRetainedUpload(ctx, db.ID, entry.ID); err != nil {
+		p.Logger.Warn("Failed to delete retained upload record", "entry", entry.ID, "error", err)
+	}
+	source.Close()
+	os.Remove(retained.Path)
+
+	return queuedEntry, nil
+}
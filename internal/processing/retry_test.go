@@ -0,0 +1,184 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// flakyConverter converts by copying the file, failing as long as fail is set.
+type flakyConverter struct {
+	media.MediaConverter
+	fail atomic.Bool
+}
+
+func (c *flakyConverter) CanCreatePreview(string) bool { return false }
+
+func (c *flakyConverter) CanConvert(string, string) media.ConversionCheck {
+	return media.ConversionCheck{NeedsConversion: true, CanConvert: true}
+}
+
+func (c *flakyConverter) ConvertFile(ctx context.Context, inputPath, outputPath, inputMimeType, targetMimeType string) error {
+	if c.fail.Load() {
+		return &media.CommandError{Kind: media.OperationConversion, ExitCode: 1, StderrTail: "<input>: Invalid data found when processing input\n", Err: errors.New("exit status 1")}
+	}
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, data, 0o644)
+}
+
+func TestRetryFailedUpload(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "retry_test", ContentType: "file", NMaxQueued: 5})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db.Config.AutoConversion = "application/x-converted"
+
+	converter := &flakyConverter{}
+	converter.fail.Store(true)
+	p, _ := NewProcessor(r, store, converter, 1, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.RetainFailedUploads = time.Hour
+
+	plan := DeterminePlanForEntry(converter, db, repo.Entry{FileName: "file.bin", MimeType: "application/octet-stream"})
+	newFailedUpload := func() repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "file.bin",
+			Status:    repo.EntryStatusProcessing,
+			Timestamp: time.Now(),
+			MimeType:  "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		source, err := os.CreateTemp(t.TempDir(), "upload-*")
+		if err != nil {
+			t.Fatalf("failed to create source: %v", err)
+		}
+		source.WriteString("data")
+		source.Close()
+
+		p.runConversionAndFinalize(ctx, db, entry, source.Name(), plan)
+
+		failed, err := r.GetEntry(ctx, db.ID, entry.ID)
+		if err != nil {
+			t.Fatalf("failed to get entry: %v", err)
+		}
+		return failed
+	}
+
+	// 1. A failed conversion stores the reason and keeps the source
+	failed := newFailedUpload()
+	if failed.Status != repo.EntryStatusError || failed.ErrorReason != ErrorReasonConversionFailed {
+		t.Fatalf("expected status error with reason %q, got %d / %q", ErrorReasonConversionFailed, failed.Status, failed.ErrorReason)
+	}
+	if failed.ErrorDetail != "<input>: Invalid data found when processing input\n" {
+		t.Errorf("expected the stderr of the converter as error detail, got %q", failed.ErrorDetail)
+	}
+	if _, err := r.GetRetainedUpload(ctx, db.ID, failed.ID); err != nil {
+		t.Fatalf("expected the source to be retained, got %v", err)
+	}
+
+	// 2. Retrying queues the entry, which then finishes processing
+	converter.fail.Store(false)
+	queued, err := p.RetryEntry(ctx, db, failed)
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if queued.Status != repo.EntryStatusQueued || queued.ErrorReason != "" || queued.ErrorDetail != "" {
+		t.Errorf("expected a queued entry without error reason, got %d / %q / %q", queued.Status, queued.ErrorReason, queued.ErrorDetail)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := r.GetEntry(ctx, db.ID, failed.ID)
+		if err != nil {
+			t.Fatalf("failed to get entry: %v", err)
+		}
+		if got.Status == repo.EntryStatusReady {
+			if got.MimeType != "application/x-converted" || got.Size != 4 {
+				t.Errorf("expected the converted 4 byte file, got %q with %d bytes", got.MimeType, got.Size)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("entry did not become ready, status %d / %q", got.Status, got.ErrorReason)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if _, err := r.GetRetainedUpload(ctx, db.ID, failed.ID); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected the retained upload to be removed after the retry, got %v", err)
+	}
+
+	// 3. Without a retained source the retry is refused
+	converter.fail.Store(true)
+	gone := newFailedUpload()
+	retained, _ := r.GetRetainedUpload(ctx, db.ID, gone.ID)
+	os.Remove(retained.Path)
+	if _, err := p.RetryEntry(ctx, db, gone); !errors.Is(err, customerrors.ErrGone) {
+		t.Errorf("expected ErrGone for a missing source, got %v", err)
+	}
+	if got, err := r.GetEntry(ctx, db.ID, gone.ID); err != nil || got.Status != repo.EntryStatusError || got.ErrorReason != ErrorReasonConversionFailed {
+		t.Errorf("expected the entry to keep its error after the refused retry, got %+v (%v)", got, err)
+	}
+
+	// 4. Of two concurrent retries of the same entry only one stages the source
+	twice := newFailedUpload()
+	converter.fail.Store(false)
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := p.RetryEntry(ctx, db, twice)
+			errs <- err
+		}()
+	}
+	var succeeded, conflicts int
+	for range 2 {
+		switch err := <-errs; {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, customerrors.ErrConflict):
+			conflicts++
+		default:
+			t.Errorf("unexpected retry error: %v", err)
+		}
+	}
+	if succeeded != 1 || conflicts != 1 {
+		t.Errorf("expected one retry and one conflict, got %d and %d", succeeded, conflicts)
+	}
+}
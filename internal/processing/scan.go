@@ -0,0 +1,45 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// wantsScan reports whether uploads to the given database have to be scanned.
+func (p *Processor) wantsScan(db repo.Database) bool {
+	return p.Scanner != nil && slices.Contains(p.ScanContentTypes, db.ContentType)
+}
+
+// scanContent runs the virus scanner on an upload. It returns ErrInfected if malware was found,
+// and ErrScannerUnavailable if the scan failed and the scanner is configured to fail closed.
+// resource identifies the upload in logs and audit events.
+func (p *Processor) scanContent(ctx context.Context, db repo.Database, content io.Reader, resource string) error {
+	result, err := p.Scanner.Scan(ctx, content)
+	if err != nil {
+		if p.ScanFailOpen {
+			p.Logger.Warn("Virus scan failed, accepting file (fail-open)", "resource", resource, "error", err)
+			return nil
+		}
+		p.Logger.Error("Virus scan failed, rejecting file (fail-closed)", "resource", resource, "error", err)
+		return fmt.Errorf("%w: %v", customerrors.ErrScannerUnavailable, err)
+	}
+
+	if result.Infected {
+		p.Logger.Warn("Infected upload rejected", "resource", resource, "signature", result.Signature)
+		if p.Auditor != nil {
+			p.Auditor.Log(ctx, "entry.infected", "scanner", resource, map[string]any{
+				"database_name": db.Name,
+				"signature":     result.Signature,
+				"reason":        "infected",
+			})
+		}
+		return customerrors.ErrInfected
+	}
+
+	return nil
+}
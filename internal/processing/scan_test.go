@@ -0,0 +1,120 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/scanner"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// countingScanner counts the scans and reports content containing "EICAR" as infected.
+type countingScanner struct {
+	mu    sync.Mutex
+	scans int
+}
+
+func (s *countingScanner) Scan(ctx context.Context, content io.Reader) (scanner.Result, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return scanner.Result{}, err
+	}
+	s.mu.Lock()
+	s.scans++
+	s.mu.Unlock()
+	if strings.Contains(string(data), "EICAR") {
+		return scanner.Result{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return scanner.Result{}, nil
+}
+
+func (s *countingScanner) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scans
+}
+
+func TestScanBeforeStorage(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "scanned", ContentType: "file", NMaxQueued: 5})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	scan := &countingScanner{}
+	// Without worker slots every upload is queued
+	p, _ := NewProcessor(r, store, plainConverter{}, 0, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.Scanner = scan
+	p.ScanContentTypes = []string{"file"}
+
+	// 1. An infected large upload is rejected before it is staged in storage
+	spooled, err := os.CreateTemp(t.TempDir(), "upload-*")
+	if err != nil {
+		t.Fatalf("failed to create spooled file: %v", err)
+	}
+	spooled.WriteString("EICAR")
+	if _, _, err := p.ProcessEntry(ctx, db, EntryRequest{FileName: "bad.bin"}, spooled, "application/octet-stream", "bad.bin"); !errors.Is(err, customerrors.ErrInfected) {
+		t.Fatalf("expected ErrInfected, got %v", err)
+	}
+	if entries, _ := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 10}); len(entries) != 0 {
+		t.Fatalf("expected no entries, got %d", len(entries))
+	}
+	if dirs, _ := os.ReadDir(store.RootPath); len(dirs) != 0 {
+		t.Errorf("expected nothing in storage, found %d items", len(dirs))
+	}
+
+	// 2. A queued small upload is scanned once, not again by the queue worker
+	entry, _, err := p.ProcessEntry(ctx, db, EntryRequest{FileName: "good.bin", Size: 4}, strings.NewReader("good"), "application/octet-stream", "good.bin")
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if entry.Status != repo.EntryStatusQueued {
+		t.Fatalf("expected a queued entry, got status %v", entry.Status)
+	}
+
+	p.mu.Lock()
+	p.NFfmpegAsync, p.NFfmpegTotal = 1, 1
+	p.mu.Unlock()
+	p.StartQueueChecker(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for entry.Status != repo.EntryStatusReady && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		entry, _ = r.GetEntry(ctx, db.ID, entry.ID)
+	}
+	if entry.Status != repo.EntryStatusReady {
+		t.Fatalf("expected the queued entry to be ready, got status %v (%q)", entry.Status, entry.ErrorReason)
+	}
+	if n := scan.count(); n != 2 {
+		t.Errorf("expected one scan per upload (2), got %d", n)
+	}
+}
@@ -0,0 +1,93 @@
+package processing
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	repo "mediahub_oss/internal/repository"
+)
+
+// RunningEntry identifies an entry whose processing runs in the background.
+type RunningEntry struct {
+	DatabaseID repo.ULID
+	EntryID    int64
+}
+
+func (e RunningEntry) String() string {
+	return fmt.Sprintf("%s/%d", e.DatabaseID, e.EntryID)
+}
+
+// BeginDrain stops accepting new work: uploads fail with customerrors.ErrUnavailable, and neither queued
+// entries nor pending tasks are started anymore. Work already running continues, see Drain.
+func (p *Processor) BeginDrain() {
+	p.mu.Lock()
+	p.draining = true
+	p.mu.Unlock()
+}
+
+func (p *Processor) isDraining() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.draining
+}
+
+// Drain stops accepting new work and waits until the running conversions and tasks are finished or ctx
+// is done. It returns the entries whose processing was still running, ordered by database and id, nil if
+// all work finished. Interrupted entries keep their status and are picked up again on the next start
+// only if they were queued.
+func (p *Processor) Drain(ctx context.Context) []RunningEntry {
+	p.BeginDrain()
+
+	done := make(chan struct{})
+	go func() {
+		p.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.SortedFunc(maps.Keys(p.running), func(a, b RunningEntry) int {
+		return cmp.Or(cmp.Compare(a.DatabaseID, b.DatabaseID), cmp.Compare(a.EntryID, b.EntryID))
+	})
+}
+
+// startJob counts background work that is not bound to a processing slot, so Drain waits for it.
+// It returns false once the processor drains, the work must not be started then.
+func (p *Processor) startJob() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.draining {
+		return false
+	}
+	p.jobs.Add(1)
+	return true
+}
+
+// trackEntry records that an entry is being processed until the returned function is called.
+func (p *Processor) trackEntry(dbID repo.ULID, entryID int64) func() {
+	key := RunningEntry{DatabaseID: dbID, EntryID: entryID}
+
+	p.mu.Lock()
+	if p.running == nil {
+		p.running = make(map[RunningEntry]int)
+	}
+	p.running[key]++
+	p.mu.Unlock()
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.running[key]--; p.running[key] <= 0 {
+			delete(p.running, key)
+		}
+	}
+}
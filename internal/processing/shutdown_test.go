@@ -0,0 +1,135 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// slowConverter converts like convertingConverter, but only once release is closed.
+type slowConverter struct {
+	convertingConverter
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *slowConverter) ConvertFile(ctx context.Context, inputPath, outputPath, inMime, outMime string) error {
+	c.started <- struct{}{}
+	<-c.release
+	return c.convertingConverter.ConvertFile(ctx, inputPath, outputPath, inMime, outMime)
+}
+
+func TestDrain(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "draining", ContentType: "file", NMaxQueued: 5})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	plan := ProcessingPlan{
+		WantsConversion: true, NeedsConversion: true, CanConvert: true,
+		InitMimeType: "text/plain", TargetMimeType: "application/octet-stream", ResultMimeType: "application/octet-stream",
+	}
+
+	// startSlowUpload starts the asynchronous processing of an upload and waits until its conversion runs.
+	startSlowUpload := func(p *Processor, conv *slowConverter) repo.Entry {
+		t.Helper()
+		spooled, err := os.CreateTemp(t.TempDir(), "upload-*")
+		if err != nil {
+			t.Fatalf("failed to create spooled file: %v", err)
+		}
+		spooled.WriteString("original")
+		if !p.tryReserveAsyncSlot() {
+			t.Fatal("expected a free async slot")
+		}
+		entry, err := p.handleLargeFileAsync(ctx, spooled, db, EntryRequest{FileName: "slow.txt"}, plan)
+		if err != nil {
+			t.Fatalf("failed to start processing: %v", err)
+		}
+		select {
+		case <-conv.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the conversion did not start")
+		}
+		return entry
+	}
+	newProcessor := func() (*Processor, *slowConverter) {
+		conv := &slowConverter{started: make(chan struct{}, 1), release: make(chan struct{})}
+		p, _ := NewProcessor(r, store, conv, 1, 2, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		return p, conv
+	}
+
+	// 1. A conversion finishing within the drain time completes, and uploads are rejected meanwhile
+	p, conv := newProcessor()
+	entry := startSlowUpload(p, conv)
+	drained := make(chan []RunningEntry, 1)
+	go func() {
+		drainCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		drained <- p.Drain(drainCtx)
+	}()
+	for !p.isDraining() {
+		time.Sleep(time.Millisecond)
+	}
+	if _, _, err := p.ProcessEntry(ctx, db, EntryRequest{FileName: "late.txt"}, strings.NewReader("late"), "text/plain", "late.txt"); !errors.Is(err, customerrors.ErrUnavailable) {
+		t.Errorf("expected uploads to be unavailable while draining, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(conv.release)
+
+	if interrupted := <-drained; len(interrupted) != 0 {
+		t.Errorf("expected no interrupted entries, got %v", interrupted)
+	}
+	if entry, _ = r.GetEntry(ctx, db.ID, entry.ID); entry.Status != repo.EntryStatusReady {
+		t.Errorf("expected the entry to be ready after the drain, got %v", entry.Status)
+	}
+
+	// 2. A conversion outlasting the drain time is reported
+	p, conv = newProcessor()
+	entry = startSlowUpload(p, conv)
+	drainCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	interrupted := p.Drain(drainCtx)
+	if want := []RunningEntry{{DatabaseID: db.ID, EntryID: entry.ID}}; !slices.Equal(interrupted, want) {
+		t.Errorf("expected %v to be interrupted, got %v", want, interrupted)
+	}
+	if entry, _ = r.GetEntry(ctx, db.ID, entry.ID); entry.Status != repo.EntryStatusProcessing {
+		t.Errorf("expected the interrupted entry to be processing, got %v", entry.Status)
+	}
+
+	// The worker still needs the repository, let it finish before it is closed
+	close(conv.release)
+	if interrupted := p.Drain(ctx); len(interrupted) != 0 {
+		t.Errorf("expected the worker to finish, got %v", interrupted)
+	}
+}
@@ -16,15 +16,33 @@ func (p *Processor) handleSmallFileSync(
 	req EntryRequest,
 	plan ProcessingPlan,
 ) (repo.Entry, error) {
-	createdEntry, err := p.createPreliminaryEntry(ctx, db, req, plan, repo.EntryStatusProcessing, true)
+	// The preview is generated in the background after the response. Its task is persisted with the
+	// entry, so the task runner completes it if the process stops before the goroutine finishes.
+	// With SyncPreview it is generated before the entry is finalized instead, without a task.
+	wantsPreview := plan.WantsPreview && plan.CanGenPreview
+	inlinePreview := wantsPreview && req.SyncPreview
+	var taskTypes []string
+	if wantsPreview && !inlinePreview {
+		taskTypes = append(taskTypes, repo.TaskTypePreview)
+	}
+
+	size, err := streamSize(file)
 	if err != nil {
 		return repo.Entry{}, err
 	}
 
+	createdEntry, tasks, err := p.createPreliminaryEntry(ctx, db, req, plan, repo.EntryStatusProcessing, true, taskTypes...)
+	if err != nil {
+		return repo.Entry{}, err
+	}
+
+	ctx = withOperationTarget(ctx, db, createdEntry.ID)
+
 	cleanupOnError := func(uploadErr error) {
 		p.Logger.Error("Upload failed", "entry", createdEntry.ID, "error", uploadErr)
 		createdEntry.Status = repo.EntryStatusError
-		_, _ = p.Repo.UpdateEntry(ctx, db.ID, createdEntry)
+		createdEntry.ErrorDetail = errorDetail(uploadErr)
+		_ = p.Repo.UpdateEntryStatus(ctx, db.ID, createdEntry.ID, createdEntry.Status, createdEntry.ErrorReason, createdEntry.ErrorDetail)
 	}
 
 	if _, err := file.Seek(0, io.SeekStart); err != nil {
@@ -40,9 +58,14 @@ func (p *Processor) handleSmallFileSync(
 	}
 
 	var streamToUpload io.ReadSeeker = file
-	if plan.WantsConversion && plan.NeedsConversion {
+	converted := plan.WantsConversion && plan.NeedsConversion
+	if converted {
 		if !plan.CanConvert {
-			return repo.Entry{}, fmt.Errorf("cannot convert %v to the database mime type %v", plan.InitMimeType, db.Config.AutoConversion)
+			// The preliminary entry is counted in the stats already, it must not stay processing
+			err := fmt.Errorf("cannot convert %v to the target mime type %v", plan.InitMimeType, plan.TargetMimeType)
+			createdEntry.ErrorReason = ErrorReasonDependencyMissing
+			cleanupOnError(err)
+			return repo.Entry{}, err
 		}
 
 		if _, err := streamToUpload.Seek(0, io.SeekStart); err != nil {
@@ -58,6 +81,15 @@ func (p *Processor) handleSmallFileSync(
 		}
 
 		streamToUpload = bytes.NewReader(convertedBuffer.Bytes())
+
+		// e.g. HEIC images, which not every ffprobe can read, are probed as the converted JPEG
+		if metaErr != nil {
+			if meta, err := p.MediaConverter.ReadMediaFieldsFromStream(ctx, streamToUpload, db.ContentType); err == nil {
+				createdEntry.MediaFields = meta
+			} else {
+				p.Logger.Warn("could not extract metadata from converted file", "entryID", createdEntry.ID, "error", err)
+			}
+		}
 	}
 
 	if _, err := streamToUpload.Seek(0, io.SeekStart); err != nil {
@@ -70,42 +102,74 @@ func (p *Processor) handleSmallFileSync(
 		cleanupOnError(err)
 		return repo.Entry{}, fmt.Errorf("failed to write to storage provider: %w", err)
 	}
+	// A short write leaves nothing behind, the client gets an error and can upload again
+	if err := CheckStoredSize(fileSize, size, converted); err != nil {
+		p.discardEntry(ctx, db, createdEntry.ID)
+		return repo.Entry{}, fmt.Errorf("failed to store upload: %w", err)
+	}
 	createdEntry.Size = uint64(fileSize)
 
-	if plan.WantsPreview && plan.CanGenPreview {
-		streamToUpload.Seek(0, io.SeekStart)
-		fileBytes, err := io.ReadAll(streamToUpload)
+	// The converted file is the entry's file, the original is kept on request
+	if converted && db.Config.KeepOriginal {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			cleanupOnError(err)
+			return repo.Entry{}, fmt.Errorf("failed to seek original file: %w", err)
+		}
+		if err := p.storeOriginal(ctx, db, &createdEntry, file, plan.InitMimeType); err != nil {
+			cleanupOnError(err)
+			return repo.Entry{}, err
+		}
+	}
+
+	var fileBytes []byte
+	if inlinePreview {
+		if _, err := streamToUpload.Seek(0, io.SeekStart); err != nil {
+			cleanupOnError(err)
+			return repo.Entry{}, fmt.Errorf("failed to seek file stream before preview generation: %w", err)
+		}
+		previewSize, err := p.generateAndStorePreview(ctx, db, createdEntry.ID, func(ctx context.Context, w io.Writer) error {
+			return p.MediaConverter.CreatePreviewFromStream(ctx, streamToUpload, w, createdEntry.MimeType)
+		})
 		if err != nil {
-			p.Logger.Error("Failed to read file into memory for preview generation", "entry", createdEntry.ID, "error", err)
-			createdEntry.Status = repo.EntryStatusReady
+			p.resolvePreviewFailure(ctx, db, &createdEntry, err)
 		} else {
-			createdEntry.Status = repo.EntryStatusProcessing
-
-			go func(bgEntry repo.Entry) {
-				var err error
-				var previewSize uint64 = 0
-
-				reader := bytes.NewReader(fileBytes)
-				if previewSize, err = p.generateAndStorePreview(context.Background(), db, bgEntry.ID, reader, plan.TargetMimeType); err != nil {
-					p.Logger.Error("Async preview generation failed", "entry", bgEntry.ID, "error", err)
-				}
-
-				bgEntry.Status = repo.EntryStatusReady
-				bgEntry.PreviewSize = previewSize
-
-				if _, err := p.Repo.UpdateEntry(context.Background(), db.ID, bgEntry); err != nil {
-					p.Logger.Error("Failed to update status to ready after async preview", "entry", bgEntry.ID, "error", err)
-				}
-			}(createdEntry)
+			createdEntry.Status = repo.EntryStatusReady
+			createdEntry.PreviewSize = previewSize
+		}
+	} else if wantsPreview {
+		streamToUpload.Seek(0, io.SeekStart)
+		if fileBytes, err = io.ReadAll(streamToUpload); err != nil {
+			// The task runner generates the preview from storage instead
+			p.Logger.Error("Failed to read file into memory for preview generation", "entry", createdEntry.ID, "error", err)
+			fileBytes = nil
 		}
+		createdEntry.Status = repo.EntryStatusProcessing
 	} else {
 		createdEntry.Status = repo.EntryStatusReady
 	}
 
-	finalEntry, err := p.Repo.UpdateEntry(ctx, db.ID, createdEntry)
-	if err != nil {
+	if err := p.saveProcessedEntry(ctx, db.ID, createdEntry, true); err != nil {
 		return repo.Entry{}, fmt.Errorf("failed to finalize entry metadata: %w", err)
 	}
+	finalEntry, err := p.Repo.GetEntry(ctx, db.ID, createdEntry.ID)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to get finalized entry: %w", err)
+	}
+	p.scheduleTranscription(ctx, db, finalEntry)
+
+	// Started only after the entry is finalized, so the goroutine cannot be overwritten by the update above.
+	// During a shutdown the task is left to the task runner of the next start.
+	if wantsPreview && fileBytes != nil && len(tasks) == 1 && p.startJob() {
+		go func(bgEntry repo.Entry, task repo.PendingTask) {
+			defer p.jobs.Done()
+			defer p.trackEntry(db.ID, bgEntry.ID)()
+			err := p.finalizePreview(context.Background(), db, bgEntry, bytes.NewReader(fileBytes))
+			if err != nil {
+				p.Logger.Error("Async preview generation failed", "entry", bgEntry.ID, "error", err)
+			}
+			p.settleTask(context.Background(), task, err)
+		}(finalEntry, tasks[0])
+	}
 
 	return finalEntry, nil
 }
@@ -0,0 +1,248 @@
+package processing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+const (
+	taskLease        = 5 * time.Minute  // time a task may run before another runner picks it up again
+	taskPollInterval = 10 * time.Second // how often the runner looks for due tasks
+	taskBatchSize    = 20
+	taskMaxAttempts  = 5
+	taskBaseBackoff  = 10 * time.Second // doubled after every failed attempt
+)
+
+// Error reasons stored on entries whose processing or post-processing failed
+const (
+	ErrorReasonPreviewFailed     = "preview_failed"
+	ErrorReasonStorageFailed     = "storage_failed"
+	ErrorReasonConversionFailed  = "conversion_failed"
+	ErrorReasonDependencyMissing = "dependency_missing" // e.g. FFmpeg or a codec is not available
+	ErrorReasonInternal          = "internal_error"
+	ErrorReasonTruncatedUpload   = "truncated_upload" // the received or stored file is shorter than announced
+)
+
+// errTaskObsolete signals that a task has nothing left to do, e.g. because the entry was deleted.
+var errTaskObsolete = errors.New("task is obsolete")
+
+// errSourceMissing signals that the stored file of an entry could not be read.
+var errSourceMissing = errors.New("stored file could not be read")
+
+// StartTaskRunner re-enqueues the pending tasks left over from a previous run and then
+// periodically executes due tasks until the context is cancelled.
+func (p *Processor) StartTaskRunner(ctx context.Context) {
+	if released, err := p.Repo.ReleasePendingTasks(ctx); err != nil {
+		p.Logger.Error("TaskRunner: Failed to re-enqueue pending tasks", "error", err)
+	} else if released > 0 {
+		p.Logger.Info("TaskRunner: Re-enqueued unfinished tasks from a previous run", "count", released)
+	}
+
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+
+	for {
+		p.runDueTasks(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDueTasks executes the currently due tasks one after another. Each task holds a processing slot,
+// so the runner never exceeds the FFmpeg limits shared with uploads.
+func (p *Processor) runDueTasks(ctx context.Context) {
+	if p.isPaused() {
+		return
+	}
+
+	tasks, err := p.Repo.GetDuePendingTasks(ctx, taskBatchSize)
+	if err != nil {
+		p.Logger.Error("TaskRunner: Failed to get due tasks", "error", err)
+		return
+	}
+
+	for _, task := range tasks {
+		if p.isPaused() {
+			return
+		}
+		if !p.tryReserveSyncSlot() {
+			p.Logger.Debug("TaskRunner: Concurrency limits reached, postponing remaining tasks")
+			return
+		}
+
+		claimed, err := p.Repo.ClaimPendingTask(ctx, task.ID, taskLease)
+		if err != nil || !claimed {
+			if err != nil {
+				p.Logger.Error("TaskRunner: Failed to claim task", "task", task.ID, "error", err)
+			}
+			p.releaseSyncSlot()
+			continue
+		}
+
+		p.settleTask(ctx, task, p.runTask(ctx, task))
+		p.releaseSyncSlot()
+	}
+}
+
+// runTask executes a single task loaded from the pending_tasks table.
+func (p *Processor) runTask(ctx context.Context, task repo.PendingTask) error {
+	defer p.trackEntry(task.DatabaseID, task.EntryID)()
+
+	switch task.Type {
+	case repo.TaskTypePreview:
+		return p.runPreviewTask(ctx, task)
+	case repo.TaskTypeTranscription:
+		return p.runTranscriptionTask(ctx, task)
+	case repo.TaskTypeFulltextBackfill:
+		return p.runFulltextBackfillTask(ctx, task)
+	default:
+		p.Logger.Warn("TaskRunner: Dropping task of unknown type", "task", task.ID, "type", task.Type)
+		return errTaskObsolete
+	}
+}
+
+// runPreviewTask generates the preview of an entry from its stored file. Entries whose first attempt failed
+// are already ready without preview, the task retries it.
+func (p *Processor) runPreviewTask(ctx context.Context, task repo.PendingTask) error {
+	db, entry, err := p.getTaskEntry(ctx, task, repo.EntryStatusProcessing, repo.EntryStatusReady)
+	if err != nil {
+		return err
+	}
+	if entry.Status == repo.EntryStatusReady && entry.ErrorReason != ErrorReasonPreviewFailed {
+		return errTaskObsolete
+	}
+
+	stream, err := p.Storage.Read(ctx, db.ID.String(), entry.ID, 0, -1)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSourceMissing, err)
+	}
+	data, err := io.ReadAll(stream)
+	stream.Close()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSourceMissing, err)
+	}
+
+	return p.finalizePreview(ctx, db, entry, bytes.NewReader(data))
+}
+
+// finalizePreview generates and stores the preview of an entry and marks the entry ready. A failed preview
+// does not keep the entry processing while it is retried: the entry is settled without preview right away,
+// see resolvePreviewFailure, and the error is returned for a retry. A missing FFmpeg is not retried.
+func (p *Processor) finalizePreview(ctx context.Context, db repo.Database, entry repo.Entry, content io.ReadSeeker) error {
+	ctx = withOperationTarget(ctx, db, entry.ID)
+	wasProcessing := entry.Status == repo.EntryStatusProcessing
+	previewSize, previewErr := p.generateAndStorePreview(ctx, db, entry.ID, func(ctx context.Context, w io.Writer) error {
+		return p.MediaConverter.CreatePreviewFromStream(ctx, content, w, entry.MimeType)
+	})
+	if previewErr != nil {
+		p.resolvePreviewFailure(ctx, db, &entry, previewErr)
+	} else {
+		entry.Status = repo.EntryStatusReady
+		entry.ErrorReason = ""
+		entry.ErrorDetail = ""
+		entry.PreviewSize = previewSize
+	}
+
+	if err := p.saveProcessedEntry(ctx, db.ID, entry, false); err != nil {
+		return fmt.Errorf("failed to update entry after preview generation: %w", err)
+	}
+	if wasProcessing {
+		p.scheduleTranscription(ctx, db, entry)
+	}
+	if previewErr != nil && entry.ErrorReason == ErrorReasonPreviewFailed {
+		return previewErr
+	}
+	return nil
+}
+
+// getTaskEntry loads the database and entry of a task. It returns errTaskObsolete
+// if either is gone or the entry does not have one of the statuses the task expects anymore.
+func (p *Processor) getTaskEntry(ctx context.Context, task repo.PendingTask, statuses ...repo.EntryStatus) (repo.Database, repo.Entry, error) {
+	db, err := p.Repo.GetDatabase(ctx, task.DatabaseID)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			return repo.Database{}, repo.Entry{}, errTaskObsolete
+		}
+		return repo.Database{}, repo.Entry{}, err
+	}
+
+	entry, err := p.Repo.GetEntry(ctx, db.ID, task.EntryID)
+	if err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			return repo.Database{}, repo.Entry{}, errTaskObsolete
+		}
+		return repo.Database{}, repo.Entry{}, err
+	}
+
+	if !slices.Contains(statuses, entry.Status) {
+		return repo.Database{}, repo.Entry{}, errTaskObsolete
+	}
+	return db, entry, nil
+}
+
+// settleTask marks a task done, schedules a retry with exponential backoff, or gives up after
+// taskMaxAttempts and records the failure on the entry.
+func (p *Processor) settleTask(ctx context.Context, task repo.PendingTask, taskErr error) {
+	if taskErr == nil || errors.Is(taskErr, errTaskObsolete) {
+		if err := p.Repo.CompletePendingTask(ctx, task.ID); err != nil {
+			p.Logger.Error("TaskRunner: Failed to complete task", "task", task.ID, "error", err)
+		}
+		return
+	}
+
+	attempts := task.Attempts + 1
+	if attempts < taskMaxAttempts {
+		backoff := taskBaseBackoff << (attempts - 1)
+		p.Logger.Warn("TaskRunner: Task failed, scheduling retry", "task", task.ID, "type", task.Type, "entry", task.EntryID, "attempt", attempts, "backoff", backoff, "error", taskErr)
+		if err := p.Repo.RetryPendingTask(ctx, task.ID, backoff, taskErr.Error()); err != nil {
+			p.Logger.Error("TaskRunner: Failed to schedule retry", "task", task.ID, "error", err)
+		}
+		return
+	}
+
+	p.Logger.Error("TaskRunner: Task failed permanently", "task", task.ID, "type", task.Type, "entry", task.EntryID, "attempts", attempts, "error", taskErr)
+	p.failTaskEntry(ctx, task, taskErr)
+	if err := p.Repo.CompletePendingTask(ctx, task.ID); err != nil {
+		p.Logger.Error("TaskRunner: Failed to remove failed task", "task", task.ID, "error", err)
+	}
+}
+
+// failTaskEntry records why the post-processing of an entry failed. If the stored file is
+// still readable, the entry stays usable and only lacks the result of the task.
+func (p *Processor) failTaskEntry(ctx context.Context, task repo.PendingTask, taskErr error) {
+	if task.Type == repo.TaskTypeFulltextBackfill {
+		return // not bound to an entry, the index stays incomplete until the fields are changed again
+	}
+	if task.Type == repo.TaskTypeTranscription {
+		db, entry, err := p.getTaskEntry(ctx, task, repo.EntryStatusReady)
+		if err == nil {
+			p.recordTranscription(ctx, db, entry.ID, repo.TranscriptionFailed, -1, "")
+		}
+		return
+	}
+
+	db, entry, err := p.getTaskEntry(ctx, task, repo.EntryStatusProcessing)
+	if err != nil {
+		return
+	}
+
+	p.resolvePreviewFailure(ctx, db, &entry, taskErr)
+
+	if err := p.saveProcessedEntry(ctx, task.DatabaseID, entry, false); err != nil {
+		p.Logger.Error("TaskRunner: Failed to record task failure on entry", "entry", entry.ID, "error", err)
+		return
+	}
+	p.scheduleTranscription(ctx, db, entry)
+}
@@ -0,0 +1,158 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// fakeConverter only implements preview generation, optionally failing every attempt.
+type fakeConverter struct {
+	media.MediaConverter
+	fail bool
+}
+
+func (c *fakeConverter) CreatePreviewFromStream(ctx context.Context, in io.ReadSeeker, out io.Writer, mimeType string) error {
+	if c.fail {
+		return errors.New("preview generation failed")
+	}
+	_, err := out.Write([]byte("preview"))
+	return err
+}
+
+func TestPendingTaskRecovery(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "task_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	converter := &fakeConverter{}
+	p, _ := NewProcessor(r, store, converter, 1, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// newCrashedUpload simulates an upload whose process stopped before the preview goroutine finished:
+	// the entry is still processing and its task row was inserted manually.
+	newCrashedUpload := func(withFile bool) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "file.bin",
+			Size:      4,
+			Status:    repo.EntryStatusProcessing,
+			Timestamp: time.Now(),
+			MimeType:  "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if withFile {
+			if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+		// Still leased by the (now dead) request
+		_, err = r.DB.ExecContext(ctx,
+			`INSERT INTO pending_tasks (database_id, entry_id, task_type, next_attempt_at) VALUES (?, ?, ?, ?)`,
+			db.ID.String(), entry.ID, repo.TaskTypePreview, time.Now().Add(time.Hour).UnixMilli())
+		if err != nil {
+			t.Fatalf("failed to insert pending task: %v", err)
+		}
+		return entry
+	}
+
+	// restart runs what StartTaskRunner does on startup, without the polling loop
+	restart := func() {
+		if _, err := r.ReleasePendingTasks(ctx); err != nil {
+			t.Fatalf("failed to release tasks: %v", err)
+		}
+		p.runDueTasks(ctx)
+	}
+
+	countTasks := func() int {
+		var n int
+		r.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM pending_tasks").Scan(&n)
+		return n
+	}
+
+	// 1. Recovery completes the preview and removes the task
+	entry := newCrashedUpload(true)
+	restart()
+
+	got, err := r.GetEntry(ctx, db.ID, entry.ID)
+	if err != nil {
+		t.Fatalf("failed to get entry: %v", err)
+	}
+	if got.Status != repo.EntryStatusReady || got.PreviewSize != uint64(len("preview")) {
+		t.Errorf("expected ready entry with preview, got status %d and preview size %d", got.Status, got.PreviewSize)
+	}
+	if n := countTasks(); n != 0 {
+		t.Errorf("expected the task to be completed, %d remain", n)
+	}
+
+	// 2. Failing tasks are retried with backoff and give up after the limit
+	converter.fail = true
+	entry = newCrashedUpload(true)
+	restart()
+
+	tasks, _ := r.GetDuePendingTasks(ctx, 10)
+	if len(tasks) != 0 {
+		t.Errorf("expected the failed task to be scheduled in the future, got %+v", tasks)
+	}
+	if _, err := r.ReleasePendingTasks(ctx); err != nil {
+		t.Fatalf("failed to release tasks: %v", err)
+	}
+	tasks, _ = r.GetDuePendingTasks(ctx, 10)
+	if len(tasks) != 1 || tasks[0].Attempts != 1 || tasks[0].LastError == "" {
+		t.Fatalf("expected one task with a recorded attempt, got %+v", tasks)
+	}
+
+	for i := 1; i < taskMaxAttempts; i++ {
+		restart()
+	}
+	got, _ = r.GetEntry(ctx, db.ID, entry.ID)
+	if got.Status != repo.EntryStatusReady || got.ErrorReason != ErrorReasonPreviewFailed {
+		t.Errorf("expected ready entry with reason %q, got status %d and reason %q", ErrorReasonPreviewFailed, got.Status, got.ErrorReason)
+	}
+	if n := countTasks(); n != 0 {
+		t.Errorf("expected the task to be dropped after %d attempts, %d remain", taskMaxAttempts, n)
+	}
+
+	// 3. A missing source file marks the entry as failed
+	converter.fail = false
+	entry = newCrashedUpload(false)
+	for i := 0; i < taskMaxAttempts; i++ {
+		restart()
+	}
+	got, _ = r.GetEntry(ctx, db.ID, entry.ID)
+	if got.Status != repo.EntryStatusError || got.ErrorReason != ErrorReasonStorageFailed {
+		t.Errorf("expected error entry with reason %q, got status %d and reason %q", ErrorReasonStorageFailed, got.Status, got.ErrorReason)
+	}
+}
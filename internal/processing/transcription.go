@@ -0,0 +1,154 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/transcription"
+)
+
+// scheduleTranscription creates the transcription task of an audio entry that just became ready.
+// It does nothing unless a transcriber is set up and the database configures transcription.
+func (p *Processor) scheduleTranscription(ctx context.Context, db repo.Database, entry repo.Entry) {
+	if p.Transcriber == nil || db.ContentType != "audio" || !db.Config.Transcription.Enabled() || entry.Status != repo.EntryStatusReady {
+		return
+	}
+	if _, err := p.Repo.ScheduleTranscription(ctx, db.ID, entry.ID); err != nil && !errors.Is(err, customerrors.ErrNotFound) {
+		p.Logger.Error("Failed to schedule transcription", "entry", entry.ID, "error", err)
+	}
+}
+
+// runTranscriptionTask sends the stored file of an entry to the transcription service of its database
+// and writes the text into the configured custom field. If FFmpeg is available, the file is
+// downsampled to mono 16 kHz first.
+func (p *Processor) runTranscriptionTask(ctx context.Context, task repo.PendingTask) error {
+	db, entry, err := p.getTaskEntry(ctx, task, repo.EntryStatusReady)
+	if err != nil {
+		return err
+	}
+
+	// The configuration may have changed since the task was created
+	cfg := db.Config.Transcription
+	field, ok := transcriptionField(db, cfg)
+	if p.Transcriber == nil || !ok {
+		p.Logger.Warn("TaskRunner: Transcription is not configured anymore", "database", db.Name, "entry", entry.ID)
+		p.recordTranscription(ctx, db, entry.ID, repo.TranscriptionFailed, -1, "")
+		return errTaskObsolete
+	}
+
+	source, err := p.copyStoredFile(ctx, db, entry.ID)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(source.Name())
+	defer source.Close()
+
+	req := transcription.Request{
+		Endpoint: cfg.Endpoint,
+		Model:    cfg.Model,
+		Language: cfg.Language,
+		FileName: entry.FileName,
+	}
+	audio, err := p.downsampleForTranscription(ctx, source)
+	if err != nil {
+		return err
+	}
+	if audio != nil {
+		defer os.Remove(audio.Name())
+		defer audio.Close()
+		req.FileName = strings.TrimSuffix(entry.FileName, filepath.Ext(entry.FileName)) + ".wav"
+	} else {
+		audio = source
+	}
+
+	text, err := p.Transcriber.Transcribe(ctx, req, audio)
+	if err != nil {
+		return err
+	}
+
+	if err := p.Repo.RecordTranscription(ctx, db.ID, entry.ID, repo.TranscriptionDone, field.ID, text); err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			return errTaskObsolete
+		}
+		return fmt.Errorf("failed to store transcription: %w", err)
+	}
+	return nil
+}
+
+// downsampleForTranscription converts the source to mono 16 kHz WAV in a temporary file.
+// It returns nil without FFmpeg, the source is then sent as it is.
+func (p *Processor) downsampleForTranscription(ctx context.Context, source *os.File) (*os.File, error) {
+	out, err := os.CreateTemp(os.TempDir(), "mh-transcription-*.wav")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	err = p.MediaConverter.DownsampleForTranscription(ctx, source, out)
+	if err == nil {
+		_, err = out.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		if errors.Is(err, customerrors.ErrNotImplemented) {
+			if _, err := source.Seek(0, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("failed to seek source file: %w", err)
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// copyStoredFile copies the stored file of an entry into a temporary file, so it can be read more than once.
+func (p *Processor) copyStoredFile(ctx context.Context, db repo.Database, entryID int64) (*os.File, error) {
+	stream, err := p.Storage.Read(ctx, db.ID.String(), entryID, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errSourceMissing, err)
+	}
+	defer stream.Close()
+
+	tmp, err := os.CreateTemp(os.TempDir(), "mh-transcription-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	if _, err := io.Copy(tmp, stream); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("%w: %v", errSourceMissing, err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to seek temp file: %w", err)
+	}
+	return tmp, nil
+}
+
+// recordTranscription stores the transcription status of an entry, logging failures.
+func (p *Processor) recordTranscription(ctx context.Context, db repo.Database, entryID int64, status string, field int, text string) {
+	if err := p.Repo.RecordTranscription(ctx, db.ID, entryID, status, field, text); err != nil && !errors.Is(err, customerrors.ErrNotFound) {
+		p.Logger.Error("Failed to record transcription status", "entry", entryID, "status", status, "error", err)
+	}
+}
+
+// transcriptionField returns the TEXT custom field receiving the transcription text.
+func transcriptionField(db repo.Database, cfg repo.TranscriptionConfig) (repo.CustomFieldDef, bool) {
+	if db.ContentType != "audio" || !cfg.Enabled() {
+		return repo.CustomFieldDef{}, false
+	}
+	for _, cf := range db.CustomFields {
+		if cf.Name == cfg.Field && strings.EqualFold(cf.Type, "TEXT") {
+			return cf, true
+		}
+	}
+	return repo.CustomFieldDef{}, false
+}
@@ -0,0 +1,176 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+	"mediahub_oss/internal/transcription/openai"
+
+	"github.com/pressly/goose/v3"
+)
+
+// downsampleConverter replaces the audio with a marker, or reports a missing FFmpeg.
+type downsampleConverter struct {
+	media.MediaConverter
+	unavailable bool
+}
+
+func (c *downsampleConverter) DownsampleForTranscription(ctx context.Context, in io.ReadSeeker, out io.Writer) error {
+	if c.unavailable {
+		return fmt.Errorf("ffmpeg is not available: %w", customerrors.ErrNotImplemented)
+	}
+	_, err := out.Write([]byte("mono16k"))
+	return err
+}
+
+func TestTranscriptionTask(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// The fake service answers with the uploaded content, or fails while failing is set
+	var failing atomic.Bool
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if failing.Load() {
+			http.Error(w, "model is loading", http.StatusServiceUnavailable)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		fmt.Fprintf(w, `{"text": "%s %s %s %s"}`, r.FormValue("model"), r.FormValue("language"), header.Filename, content)
+	}))
+	defer server.Close()
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	fields := []repo.CustomFieldDef{{Name: "transcript", Type: "TEXT"}}
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "transcribed",
+		ContentType:  "audio",
+		CustomFields: fields,
+		Config: repo.DatabaseConfig{Transcription: repo.TranscriptionConfig{
+			Endpoint: server.URL, Model: "whisper-1", Field: "transcript", Language: "en",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	plainDB, err := r.CreateDatabase(ctx, repo.Database{Name: "plain", ContentType: "audio", CustomFields: fields})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	converter := &downsampleConverter{}
+	p, _ := NewProcessor(r, store, converter, 1, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.Transcriber = openai.NewClient("Bearer secret", 10*time.Second)
+
+	newReadyEntry := func(db repo.Database) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:    "talk.flac",
+			Size:        4,
+			Status:      repo.EntryStatusReady,
+			Timestamp:   time.Now(),
+			MimeType:    "audio/flac",
+			MediaFields: map[string]any{"duration": 1.0, "channels": int64(2)},
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		p.scheduleTranscription(ctx, db, entry)
+		return entry
+	}
+	expect := func(name string, db repo.Database, id int64, status string, text any) {
+		t.Helper()
+		got, err := r.GetEntry(ctx, db.ID, id)
+		if err != nil {
+			t.Fatalf("%s: failed to get entry: %v", name, err)
+		}
+		if got.Transcription != status || got.CustomFields["transcript"] != text {
+			t.Errorf("%s: expected status %q and text %v, got %q and %v", name, status, text, got.Transcription, got.CustomFields["transcript"])
+		}
+	}
+
+	// 1. The downsampled audio is transcribed into the custom field
+	entry := newReadyEntry(db)
+	expect("scheduled", db, entry.ID, repo.TranscriptionPending, nil)
+	p.runDueTasks(ctx)
+	expect("downsampled", db, entry.ID, repo.TranscriptionDone, "whisper-1 en talk.wav mono16k")
+
+	// 2. Without FFmpeg, the stored file is sent as it is
+	converter.unavailable = true
+	entry = newReadyEntry(db)
+	p.runDueTasks(ctx)
+	expect("without ffmpeg", db, entry.ID, repo.TranscriptionDone, "whisper-1 en talk.flac data")
+
+	// 3. Databases without transcription are left alone
+	before := requests.Load()
+	entry = newReadyEntry(plainDB)
+	p.runDueTasks(ctx)
+	expect("not configured", plainDB, entry.ID, "", nil)
+	if requests.Load() != before {
+		t.Error("expected no request for a database without transcription")
+	}
+
+	// 4. Failures are retried with backoff and marked failed after the limit
+	failing.Store(true)
+	entry = newReadyEntry(db)
+	for i := 0; i < taskMaxAttempts; i++ {
+		if _, err := r.ReleasePendingTasks(ctx); err != nil {
+			t.Fatalf("failed to release tasks: %v", err)
+		}
+		p.runDueTasks(ctx)
+		if i == 0 {
+			tasks, _ := r.GetDuePendingTasks(ctx, 10)
+			if len(tasks) != 0 {
+				t.Errorf("expected the failed task to be scheduled in the future, got %+v", tasks)
+			}
+			expect("retrying", db, entry.ID, repo.TranscriptionPending, nil)
+		}
+	}
+	expect("failed", db, entry.ID, repo.TranscriptionFailed, nil)
+	var n int
+	r.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM pending_tasks").Scan(&n)
+	if n != 0 {
+		t.Errorf("expected the task to be dropped after %d attempts, %d remain", taskMaxAttempts, n)
+	}
+}
@@ -0,0 +1,82 @@
+package processing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// CheckUploadSize validates the size of a received file before an entry is created for it.
+// Empty files are rejected, as are files whose size differs from the announced size (if it is known, i.e. > 0).
+// Both errors wrap customerrors.ErrValidation.
+func CheckUploadSize(size int64, announced int64) error {
+	if size == 0 {
+		return fmt.Errorf("%w: the uploaded file is empty", customerrors.ErrValidation)
+	}
+	if announced > 0 && size != announced {
+		return fmt.Errorf("%w: the upload is truncated, received %d of %d bytes", customerrors.ErrValidation, size, announced)
+	}
+	return nil
+}
+
+// CheckStoredSize validates the size of a file written to the storage. Converted files only need to be non-empty,
+// unconverted ones must have the size of the source.
+func CheckStoredSize(written int64, source int64, converted bool) error {
+	if written == 0 {
+		return fmt.Errorf("the stored file is empty")
+	}
+	if !converted && written != source {
+		return fmt.Errorf("the stored file has %d of %d bytes", written, source)
+	}
+	return nil
+}
+
+// streamSize returns the size of a stream and rewinds it.
+func streamSize(file io.Seeker) (int64, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine upload size: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to rewind upload: %w", err)
+	}
+	return size, nil
+}
+
+// checkSpooledSize compares a file spooled to disk with the size the entry expects (if it is known, i.e. > 0).
+func checkSpooledSize(path string, expected uint64) error {
+	if expected == 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat spooled file: %w", err)
+	}
+	if uint64(info.Size()) != expected {
+		return fmt.Errorf("spooled file has %d of %d bytes", info.Size(), expected)
+	}
+	return nil
+}
+
+// checkStoredFile compares the size written to the storage with the local file it was streamed from.
+func (p *Processor) checkStoredFile(path string, written int64, converted bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat stored file: %w", err)
+	}
+	return CheckStoredSize(written, info.Size(), converted)
+}
+
+// discardEntry removes an entry and its stored file, so a rejected upload leaves nothing behind.
+func (p *Processor) discardEntry(ctx context.Context, db repo.Database, entryID int64) {
+	if err := p.Storage.Delete(ctx, db.ID.String(), entryID); err != nil {
+		p.Logger.Warn("Failed to delete file of discarded entry", "entry", entryID, "error", err)
+	}
+	if _, err := p.Repo.DeleteEntry(ctx, db.ID, entryID); err != nil {
+		p.Logger.Warn("Failed to delete discarded entry", "entry", entryID, "error", err)
+	}
+}
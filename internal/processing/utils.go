@@ -6,6 +6,7 @@ import (
 	"io"
 	"time"
 
+	"mediahub_oss/internal/media"
 	repo "mediahub_oss/internal/repository"
 )
 
@@ -16,12 +17,16 @@ func (p *Processor) createPreliminaryEntry(
 	plan ProcessingPlan,
 	status repo.EntryStatus,
 	useResultMimeType bool,
-) (repo.Entry, error) {
+	taskTypes ...string,
+) (repo.Entry, []repo.PendingTask, error) {
 	var err error
 
 	partialEntry := repo.Entry{}
 	partialEntry.FileName = plan.FinalFileName
+	partialEntry.ExternalID = entryMetadata.ExternalID
+	partialEntry.Origin = entryMetadata.Origin
 	partialEntry.Timestamp = time.UnixMilli(entryMetadata.Timestamp)
+	partialEntry.ClientTimestamp = entryMetadata.ClientTimestamp
 	if useResultMimeType {
 		partialEntry.MimeType = plan.ResultMimeType
 	} else {
@@ -31,43 +36,112 @@ func (p *Processor) createPreliminaryEntry(
 
 	partialEntry.MediaFields, err = DefaultMediaFields(db.ContentType)
 	if err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to create default media fields: %w", err)
+		return repo.Entry{}, nil, fmt.Errorf("failed to create default media fields: %w", err)
 	}
 
 	partialEntry.CustomFields = entryMetadata.CustomFields
 
-	createdEntry, err := p.Repo.CreateEntry(ctx, db, partialEntry)
+	// Post-processing tasks are persisted together with the entry, so they survive a restart
+	createdEntry, tasks, err := p.Repo.CreateEntryWithTasks(ctx, db, partialEntry, taskTypes, taskLease)
 	if err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to create partial database entry: %w", err)
+		return repo.Entry{}, nil, fmt.Errorf("failed to create partial database entry: %w", err)
 	}
 
-	return createdEntry, nil
+	return createdEntry, tasks, nil
 }
 
-func (p *Processor) generateAndStorePreview(
-	ctx context.Context,
-	db repo.Database,
-	entryID int64,
-	inputSeeker io.ReadSeeker,
-	mimeType string,
-) (uint64, error) {
+// withOperationTarget attaches the entry to ctx, so the media operations run for it are recorded with
+// its database and id.
+func withOperationTarget(ctx context.Context, db repo.Database, entryID int64) context.Context {
+	return media.WithOperationTarget(ctx, media.OperationTarget{DatabaseID: db.ID.String(), DatabaseName: db.Name, EntryID: entryID})
+}
+
+// generateAndStorePreview streams the output of generate into the preview storage of an entry.
+// It returns the size of the stored preview and does not update the entry. The context passed to
+// generate carries the waveform style of the database.
+func (p *Processor) generateAndStorePreview(ctx context.Context, db repo.Database, entryID int64, generate func(ctx context.Context, w io.Writer) error) (uint64, error) {
 	pr, pw := io.Pipe()
 	errChan := make(chan error, 1)
 
+	styleCtx := media.WithWaveformStyle(ctx, media.WaveformStyle(db.Config.Waveform))
 	go func() {
-		defer pw.Close()
-		err := p.MediaConverter.CreatePreviewFromStream(ctx, inputSeeker, pw, mimeType)
+		err := generate(styleCtx, pw)
+		pw.CloseWithError(err) // unblocks the storage if generation stops early
 		errChan <- err
 	}()
 
 	previewSize, err := p.Storage.WritePreview(ctx, db.ID.String(), entryID, pr)
+	pr.Close() // unblocks the generator if the storage stops early
+	genErr := <-errChan
+	if genErr != nil {
+		_ = p.Storage.DeletePreview(ctx, db.ID.String(), entryID)
+		return 0, fmt.Errorf("failed to generate preview: %w", genErr)
+	}
 	if err != nil {
 		return 0, fmt.Errorf("failed to save preview to storage: %w", err)
 	}
 
-	if genErr := <-errChan; genErr != nil {
-		return 0, fmt.Errorf("failed to generate preview: %w", genErr)
+	return uint64(previewSize), nil
+}
+
+// storeOriginal keeps the original of a converted upload next to the converted file and records it on the entry.
+func (p *Processor) storeOriginal(ctx context.Context, db repo.Database, entry *repo.Entry, original io.Reader, mimeType string) error {
+	size, err := p.Storage.WriteOriginal(ctx, db.ID.String(), entry.ID, original)
+	if err != nil {
+		_ = p.Storage.DeleteOriginal(ctx, db.ID.String(), entry.ID)
+		return fmt.Errorf("failed to store original file: %w", err)
+	}
+	entry.OriginalSize = uint64(size)
+	entry.OriginalMimeType = mimeType
+	return nil
+}
+
+// saveProcessedEntry records what processing changed on an entry: its files and media fields first,
+// then its status, so an entry never turns ready before its sizes are recorded. User fields such as
+// the filename or the custom fields are not written. fileWritten bumps the content version of the entry,
+// set it whenever the stored file was written, not for the preview alone.
+func (p *Processor) saveProcessedEntry(ctx context.Context, dbID repo.ULID, entry repo.Entry, fileWritten bool) error {
+	err := p.Repo.UpdateEntryTechMetadata(ctx, dbID, entry.ID, repo.EntryTechMetadata{
+		Size:             entry.Size,
+		PreviewSize:      entry.PreviewSize,
+		OriginalSize:     entry.OriginalSize,
+		MimeType:         entry.MimeType,
+		OriginalMimeType: entry.OriginalMimeType,
+		MediaFields:      entry.MediaFields,
+		FileWritten:      fileWritten,
+	})
+	if err != nil {
+		return err
 	}
+	return p.Repo.UpdateEntryStatus(ctx, dbID, entry.ID, entry.Status, entry.ErrorReason, entry.ErrorDetail)
+}
 
-	return uint64(previewSize), nil
+// resolvePreviewFailure decides the state of an entry whose preview could not be generated.
+// The stored file is checked explicitly: if it cannot be opened, the entry is marked as failed,
+// otherwise it stays ready without a preview and the reason is kept in error_reason.
+func (p *Processor) resolvePreviewFailure(ctx context.Context, db repo.Database, entry *repo.Entry, previewErr error) {
+	entry.PreviewSize = 0
+
+	stored, err := p.Storage.Read(ctx, db.ID.String(), entry.ID, 0, -1)
+	if err != nil {
+		p.Logger.Error("Stored file is not readable after preview failure", "entry", entry.ID, "preview_error", previewErr, "error", err)
+		entry.Status = repo.EntryStatusError
+		entry.ErrorReason = ErrorReasonStorageFailed
+		entry.ErrorDetail = ""
+		return
+	}
+	stored.Close()
+
+	entry.Status = repo.EntryStatusReady
+	entry.ErrorReason = previewErrorReason(previewErr)
+	entry.ErrorDetail = errorDetail(previewErr)
+	p.Logger.Warn("Entry is ready without preview", "entry", entry.ID, "reason", entry.ErrorReason, "error", previewErr)
+}
+
+// previewErrorReason distinguishes a missing FFmpeg from a file it could not create a preview of.
+func previewErrorReason(err error) string {
+	if conversionErrorReason(err) == ErrorReasonDependencyMissing {
+		return ErrorReasonDependencyMissing
+	}
+	return ErrorReasonPreviewFailed
 }
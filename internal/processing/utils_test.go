@@ -0,0 +1,162 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// previewConverter creates previews from streams and files, failing with err if set.
+type previewConverter struct {
+	media.MediaConverter
+	err error
+}
+
+func (c *previewConverter) CreatePreviewFromStream(ctx context.Context, in io.ReadSeeker, out io.Writer, mimeType string) error {
+	return c.preview(out)
+}
+
+func (c *previewConverter) CreatePreviewFromFile(ctx context.Context, path string, out io.Writer, mimeType string) error {
+	return c.preview(out)
+}
+
+func (c *previewConverter) preview(out io.Writer) error {
+	if c.err != nil {
+		out.Write([]byte("partial"))
+		return c.err
+	}
+	_, err := out.Write([]byte("preview"))
+	return err
+}
+
+func TestPreviewFailureHandling(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "preview_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	converter := &previewConverter{}
+	p, _ := NewProcessor(r, store, converter, 1, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	newEntry := func(withFile bool) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "image.png",
+			Size:      4,
+			Status:    repo.EntryStatusProcessing,
+			Timestamp: time.Now(),
+			MimeType:  "image/png",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if withFile {
+			if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+		return entry
+	}
+	expect := func(name string, id int64, status repo.EntryStatus, reason string, previewSize uint64) {
+		t.Helper()
+		got, err := r.GetEntry(ctx, db.ID, id)
+		if err != nil {
+			t.Fatalf("%s: failed to get entry: %v", name, err)
+		}
+		if got.Status != status || got.ErrorReason != reason || got.PreviewSize != previewSize {
+			t.Errorf("%s: expected status %d, reason %q and preview size %d, got %d, %q and %d",
+				name, status, reason, previewSize, got.Status, got.ErrorReason, got.PreviewSize)
+		}
+	}
+
+	// 1. Success
+	entry := newEntry(true)
+	if err := p.finalizePreview(ctx, db, entry, strings.NewReader("data")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expect("success", entry.ID, repo.EntryStatusReady, "", uint64(len("preview")))
+
+	// 2. FFmpeg missing: settled right away, the entry is usable without preview
+	converter.err = fmt.Errorf("ffmpeg is not available: %w", customerrors.ErrNotImplemented)
+	entry = newEntry(true)
+	if err := p.finalizePreview(ctx, db, entry, strings.NewReader("data")); err != nil {
+		t.Fatalf("expected no retry without FFmpeg, got %v", err)
+	}
+	expect("ffmpeg missing", entry.ID, repo.EntryStatusReady, ErrorReasonDependencyMissing, 0)
+
+	// 3. Corrupt image: the entry is ready without preview at once, no partial preview is kept and the
+	// preview is retried
+	converter.err = errors.New("invalid data found when processing input")
+	entry = newEntry(true)
+	previewErr := p.finalizePreview(ctx, db, entry, strings.NewReader("data"))
+	if previewErr == nil {
+		t.Fatalf("expected the error to be returned for a retry")
+	}
+	if _, err := store.ReadPreview(ctx, db.ID.String(), entry.ID); err == nil {
+		t.Errorf("expected the partial preview to be removed")
+	}
+	expect("corrupt image", entry.ID, repo.EntryStatusReady, ErrorReasonPreviewFailed, 0)
+
+	converter.err = nil
+	if err := p.runPreviewTask(ctx, repo.PendingTask{Type: repo.TaskTypePreview, DatabaseID: db.ID, EntryID: entry.ID}); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	expect("retried preview", entry.ID, repo.EntryStatusReady, "", uint64(len("preview")))
+	if err := p.runPreviewTask(ctx, repo.PendingTask{Type: repo.TaskTypePreview, DatabaseID: db.ID, EntryID: entry.ID}); !errors.Is(err, errTaskObsolete) {
+		t.Errorf("expected the task of an entry with preview to be obsolete, got %v", err)
+	}
+	converter.err = previewErr
+
+	// 4. Unreadable file: the entry fails instead of being ready
+	entry = newEntry(false)
+	p.resolvePreviewFailure(ctx, db, &entry, previewErr)
+	if entry.Status != repo.EntryStatusError || entry.ErrorReason != ErrorReasonStorageFailed {
+		t.Errorf("unreadable file: expected error with %q, got %d with %q", ErrorReasonStorageFailed, entry.Status, entry.ErrorReason)
+	}
+
+	// 5. The worker of asynchronous uploads applies the same rules
+	entry = newEntry(false)
+	source, err := os.CreateTemp(t.TempDir(), "upload-*")
+	if err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+	source.WriteString("data")
+	source.Close()
+
+	plan := ProcessingPlan{WantsPreview: true, CanGenPreview: true, InitMimeType: "image/png", TargetMimeType: "image/png", ResultMimeType: "image/png"}
+	p.runConversionAndFinalize(ctx, db, entry, source.Name(), plan)
+	expect("worker with corrupt image", entry.ID, repo.EntryStatusReady, ErrorReasonPreviewFailed, 0)
+}
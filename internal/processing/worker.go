@@ -2,12 +2,14 @@ package processing
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 
 	"mediahub_oss/internal/media"
 	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
 )
 
 // StartQueueChecker scans for hanging queued entries on startup and processes them.
@@ -21,6 +23,10 @@ func (p *Processor) StartQueueChecker(ctx context.Context) {
 	}
 
 	for _, db := range databases {
+		if p.isPaused() {
+			p.Logger.Info("QueueChecker: Processing is paused, stopping initial queue scan.")
+			return
+		}
 		queuedEntries, err := p.Repo.GetEntriesByStatus(ctx, db.ID, repo.EntryStatusQueued)
 		if err != nil {
 			p.Logger.Error("QueueChecker: Failed to get queued entries", "database_id", db.ID.String(), "error", err)
@@ -37,7 +43,7 @@ func (p *Processor) StartQueueChecker(ctx context.Context) {
 }
 
 func (p *Processor) tryAcquireAndSpawn(ctx context.Context, db repo.Database, entry repo.Entry) bool {
-	if !p.tryReserveAsyncSlot() {
+	if p.isPaused() || !p.tryReserveAsyncSlot() {
 		return false
 	}
 
@@ -70,7 +76,9 @@ func (p *Processor) runWorkerForClaimedEntry(ctx context.Context, db repo.Databa
 	if err != nil {
 		p.Logger.Error("Worker: Failed to create temp file for queued entry", "entry", entry.ID, "error", err)
 		entry.Status = repo.EntryStatusError
-		_, _ = p.Repo.UpdateEntry(ctx, db.ID, entry)
+		entry.ErrorReason = ErrorReasonInternal
+		_ = p.Repo.UpdateEntryStatus(ctx, db.ID, entry.ID, entry.Status, entry.ErrorReason, entry.ErrorDetail)
+		p.noticeFailure(ctx, db, entry.ID, ErrorReasonInternal, err)
 		return
 	}
 	tempFilePath := tempFile.Name()
@@ -81,7 +89,9 @@ func (p *Processor) runWorkerForClaimedEntry(ctx context.Context, db repo.Databa
 		p.Logger.Error("Worker: Failed to read queued file from storage", "entry", entry.ID, "error", err)
 		tempFile.Close()
 		entry.Status = repo.EntryStatusError
-		_, _ = p.Repo.UpdateEntry(ctx, db.ID, entry)
+		entry.ErrorReason = ErrorReasonStorageFailed
+		_ = p.Repo.UpdateEntryStatus(ctx, db.ID, entry.ID, entry.Status, entry.ErrorReason, entry.ErrorDetail)
+		p.noticeFailure(ctx, db, entry.ID, ErrorReasonStorageFailed, err)
 		return
 	}
 
@@ -92,7 +102,9 @@ func (p *Processor) runWorkerForClaimedEntry(ctx context.Context, db repo.Databa
 	if err != nil {
 		p.Logger.Error("Worker: Failed to copy queued file to temp path", "entry", entry.ID, "error", err)
 		entry.Status = repo.EntryStatusError
-		_, _ = p.Repo.UpdateEntry(ctx, db.ID, entry)
+		entry.ErrorReason = ErrorReasonStorageFailed
+		_ = p.Repo.UpdateEntryStatus(ctx, db.ID, entry.ID, entry.Status, entry.ErrorReason, entry.ErrorDetail)
+		p.noticeFailure(ctx, db, entry.ID, ErrorReasonStorageFailed, err)
 		return
 	}
 
@@ -107,6 +119,10 @@ func (p *Processor) runWorkerForClaimedEntry(ctx context.Context, db repo.Databa
 func (p *Processor) runQueueWorkerLoop(ctx context.Context, initialDB repo.Database) {
 	db := initialDB
 	for {
+		// Entries left queued are picked up again on the next start
+		if p.isDraining() {
+			break
+		}
 		nextEntry, nextDB, found, err := p.findNextQueuedEntry(ctx)
 		if err != nil {
 			p.Logger.Error("Worker: Failed to scan for next queued entry", "error", err)
@@ -131,7 +147,9 @@ func (p *Processor) runQueueWorkerLoop(ctx context.Context, initialDB repo.Datab
 		if err != nil {
 			p.Logger.Error("Worker: Failed to create temp file for claimed entry", "entry", nextEntry.ID, "error", err)
 			nextEntry.Status = repo.EntryStatusError
-			_, _ = p.Repo.UpdateEntry(ctx, db.ID, nextEntry)
+			nextEntry.ErrorReason = ErrorReasonInternal
+			_ = p.Repo.UpdateEntryStatus(ctx, db.ID, nextEntry.ID, nextEntry.Status, nextEntry.ErrorReason, nextEntry.ErrorDetail)
+			p.noticeFailure(ctx, db, nextEntry.ID, ErrorReasonInternal, err)
 			continue
 		}
 		tempFilePath := tempFile.Name()
@@ -142,7 +160,9 @@ func (p *Processor) runQueueWorkerLoop(ctx context.Context, initialDB repo.Datab
 			tempFile.Close()
 			os.Remove(tempFilePath)
 			nextEntry.Status = repo.EntryStatusError
-			_, _ = p.Repo.UpdateEntry(ctx, db.ID, nextEntry)
+			nextEntry.ErrorReason = ErrorReasonStorageFailed
+			_ = p.Repo.UpdateEntryStatus(ctx, db.ID, nextEntry.ID, nextEntry.Status, nextEntry.ErrorReason, nextEntry.ErrorDetail)
+			p.noticeFailure(ctx, db, nextEntry.ID, ErrorReasonStorageFailed, err)
 			continue
 		}
 
@@ -154,7 +174,9 @@ func (p *Processor) runQueueWorkerLoop(ctx context.Context, initialDB repo.Datab
 			p.Logger.Error("Worker: Failed to copy claimed file to temp path", "entry", nextEntry.ID, "error", err)
 			os.Remove(tempFilePath)
 			nextEntry.Status = repo.EntryStatusError
-			_, _ = p.Repo.UpdateEntry(ctx, db.ID, nextEntry)
+			nextEntry.ErrorReason = ErrorReasonStorageFailed
+			_ = p.Repo.UpdateEntryStatus(ctx, db.ID, nextEntry.ID, nextEntry.Status, nextEntry.ErrorReason, nextEntry.ErrorDetail)
+			p.noticeFailure(ctx, db, nextEntry.ID, ErrorReasonStorageFailed, err)
 			continue
 		}
 
@@ -176,18 +198,35 @@ func (p *Processor) runConversionAndFinalize(
 	p.Logger.Debug("Worker: Starting conversion and finalize", "entry", entry.ID)
 
 	var processErr error
+	var failReason string = ErrorReasonInternal
 	var meta map[string]any = map[string]any{}
 	var fileSize int64 = 0
 
 	currentPath := originalTempPath
 	cleanupPaths := []string{originalTempPath}
 
+	defer p.trackEntry(db.ID, entry.ID)()
+	defer p.Progress.Remove(db.ID, entry.ID)
+	ctx = withOperationTarget(ctx, db, entry.ID)
+
 	defer func() {
 		if processErr != nil {
-			p.Logger.Error("Worker: FAILED processing", "entry", entry.ID, "error", processErr)
+			p.Logger.Error("Worker: FAILED processing", "entry", entry.ID, "reason", failReason, "error", processErr)
 			entry.Status = repo.EntryStatusError
-			if _, updateErr := p.Repo.UpdateEntry(ctx, db.ID, entry); updateErr != nil {
-				p.Logger.Error("Worker: CRITICAL: Failed to set status error", "entry", entry.ID, "error", updateErr)
+			entry.ErrorReason = failReason
+			entry.ErrorDetail = errorDetail(processErr)
+
+			// Keep the source, so the entry can be retried without uploading it again
+			if p.RetainFailedUploads > 0 && isRetryable(failReason) && p.retainUpload(ctx, db.ID, entry.ID, originalTempPath) {
+				cleanupPaths = cleanupPaths[1:]
+			}
+			if updateErr := p.Repo.UpdateEntryStatus(ctx, db.ID, entry.ID, entry.Status, entry.ErrorReason, entry.ErrorDetail); errors.Is(updateErr, customerrors.ErrNotFound) {
+				p.Logger.Warn("Worker: Entry was deleted while processing", "entry", entry.ID)
+			} else {
+				if updateErr != nil {
+					p.Logger.Error("Worker: CRITICAL: Failed to set status error", "entry", entry.ID, "error", updateErr)
+				}
+				p.noticeFailure(ctx, db, entry.ID, failReason, processErr)
 			}
 		}
 		for _, path := range cleanupPaths {
@@ -195,9 +234,18 @@ func (p *Processor) runConversionAndFinalize(
 		}
 	}()
 
+	// The spooled file must have the size the entry expects (announced by the client, or of the staged copy)
+	if err := checkSpooledSize(originalTempPath, entry.Size); err != nil {
+		_ = p.Storage.Delete(ctx, db.ID.String(), entry.ID)
+		failReason = ErrorReasonTruncatedUpload
+		processErr = err
+		return
+	}
+
 	if plan.WantsConversion && plan.NeedsConversion {
 		if !plan.CanConvert {
-			processErr = fmt.Errorf("cannot convert %v to the database mime type %v", plan.InitMimeType, db.Config.AutoConversion)
+			failReason = ErrorReasonDependencyMissing
+			processErr = fmt.Errorf("cannot convert %v to the target mime type %v", plan.InitMimeType, plan.TargetMimeType)
 			return
 		}
 
@@ -209,8 +257,13 @@ func (p *Processor) runConversionAndFinalize(
 		convertedTempPath := convertedTempFile.Name()
 		convertedTempFile.Close()
 
-		err = p.MediaConverter.ConvertFile(ctx, currentPath, convertedTempPath, plan.InitMimeType, plan.TargetMimeType)
+		p.Progress.Set(db.ID, entry.ID, PhaseConverting, -1)
+		progressCtx := media.WithProgress(ctx, func(fraction float64) {
+			p.Progress.Set(db.ID, entry.ID, PhaseConverting, fraction*100)
+		})
+		err = p.MediaConverter.ConvertFile(progressCtx, currentPath, convertedTempPath, plan.InitMimeType, plan.TargetMimeType)
 		if err != nil {
+			failReason = conversionErrorReason(err)
 			processErr = fmt.Errorf("conversion to file failed: %w", err)
 			return
 		}
@@ -226,25 +279,15 @@ func (p *Processor) runConversionAndFinalize(
 		}
 	}
 
+	var previewErr error
 	if plan.WantsPreview && plan.CanGenPreview {
-		pr, pw := io.Pipe()
-		errChan := make(chan error, 1)
-
-		go func() {
-			defer pw.Close()
-			err := p.MediaConverter.CreatePreviewFromFile(ctx, currentPath, pw, plan.TargetMimeType)
-			errChan <- err
-		}()
-
-		if previewSize, err := p.Storage.WritePreview(ctx, db.ID.String(), entry.ID, pr); err != nil {
-			p.Logger.Error("Worker: Failed to save preview to storage", "entry", entry.ID, "error", err)
-		} else if genErr := <-errChan; genErr != nil {
-			p.Logger.Error("Worker: Failed to generate preview", "entry", entry.ID, "error", genErr)
-		} else {
-			entry.PreviewSize = uint64(previewSize)
-		}
+		p.Progress.Set(db.ID, entry.ID, PhasePreview, -1)
+		entry.PreviewSize, previewErr = p.generateAndStorePreview(ctx, db, entry.ID, func(ctx context.Context, w io.Writer) error {
+			return p.MediaConverter.CreatePreviewFromFile(ctx, currentPath, w, plan.TargetMimeType)
+		})
 	}
 
+	p.Progress.Set(db.ID, entry.ID, PhaseFinalizing, -1)
 	finalFile, err := os.Open(currentPath)
 	if err != nil {
 		processErr = fmt.Errorf("failed to open final file for storage: %w", err)
@@ -255,19 +298,59 @@ func (p *Processor) runConversionAndFinalize(
 	finalFile.Close()
 
 	if err != nil {
+		failReason = ErrorReasonStorageFailed
 		processErr = fmt.Errorf("failed to stream file to storage: %w", err)
 		return
 	}
+	if err := p.checkStoredFile(currentPath, fileSize, currentPath != originalTempPath); err != nil {
+		_ = p.Storage.Delete(ctx, db.ID.String(), entry.ID)
+		failReason = ErrorReasonTruncatedUpload
+		processErr = err
+		return
+	}
+
+	// The converted file is the entry's file, the original is kept on request
+	if currentPath != originalTempPath && db.Config.KeepOriginal {
+		originalFile, err := os.Open(originalTempPath)
+		if err != nil {
+			processErr = fmt.Errorf("failed to open original file for storage: %w", err)
+			return
+		}
+		err = p.storeOriginal(ctx, db, &entry, originalFile, plan.InitMimeType)
+		originalFile.Close()
+		if err != nil {
+			failReason = ErrorReasonStorageFailed
+			processErr = err
+			return
+		}
+	}
 
 	entry.Status = repo.EntryStatusReady
 	entry.Size = uint64(fileSize)
 	entry.MimeType = plan.ResultMimeType
 	entry.MediaFields = meta
+	if previewErr != nil {
+		p.resolvePreviewFailure(ctx, db, &entry, previewErr)
+		if entry.Status == repo.EntryStatusError {
+			failReason = entry.ErrorReason
+			processErr = fmt.Errorf("stored file is not readable: %w", previewErr)
+			return
+		}
+	}
 
-	if _, err := p.Repo.UpdateEntry(ctx, db.ID, entry); err != nil {
+	if err := p.saveProcessedEntry(ctx, db.ID, entry, true); err != nil {
+		if errors.Is(err, customerrors.ErrNotFound) {
+			// The entry was deleted while we were working on it, remove what we stored for it
+			p.Logger.Warn("Worker: Entry was deleted while processing, discarding its files", "entry", entry.ID)
+			_ = p.Storage.Delete(ctx, db.ID.String(), entry.ID)
+			_ = p.Storage.DeletePreview(ctx, db.ID.String(), entry.ID)
+			_ = p.Storage.DeleteOriginal(ctx, db.ID.String(), entry.ID)
+			return
+		}
 		processErr = fmt.Errorf("failed to update final database stats: %w", err)
 		return
 	}
+	p.scheduleTranscription(ctx, db, entry)
 
 	p.Logger.Info("Worker: Successfully processed large entry", "entry", entry.ID)
 }
@@ -275,7 +358,7 @@ func (p *Processor) runConversionAndFinalize(
 // TriggerQueueWorkersIfPossible scans for any queued entries across all databases
 // and spawns background workers for them if concurrency limits allow.
 func (p *Processor) TriggerQueueWorkersIfPossible(ctx context.Context) {
-	for {
+	for !p.isDraining() {
 		entry, db, found, err := p.findNextQueuedEntry(ctx)
 		if err != nil {
 			p.Logger.Error("TriggerQueueWorkers: Failed to scan for next queued entry", "error", err)
@@ -0,0 +1,220 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/media"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// convertingConverter writes a fixed content as the result of every conversion.
+type convertingConverter struct {
+	media.MediaConverter
+}
+
+func (c *convertingConverter) ConvertFile(ctx context.Context, inputPath, outputPath, inMime, outMime string) error {
+	return os.WriteFile(outputPath, []byte("converted!"), 0o600)
+}
+
+func TestKeepOriginal(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	p, _ := NewProcessor(r, store, &convertingConverter{}, 1, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	plan := ProcessingPlan{
+		WantsConversion: true, NeedsConversion: true, CanConvert: true,
+		InitMimeType: "text/plain", TargetMimeType: "application/octet-stream", ResultMimeType: "application/octet-stream",
+	}
+
+	upload := func(db repo.Database) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:  "notes.txt",
+			Status:    repo.EntryStatusProcessing,
+			Timestamp: time.Now(),
+			MimeType:  plan.InitMimeType,
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		source, err := os.CreateTemp(t.TempDir(), "upload-*")
+		if err != nil {
+			t.Fatalf("failed to create source: %v", err)
+		}
+		source.WriteString("original")
+		source.Close()
+
+		p.runConversionAndFinalize(ctx, db, entry, source.Name(), plan)
+		entry, err = r.GetEntry(ctx, db.ID, entry.ID)
+		if err != nil {
+			t.Fatalf("failed to get entry: %v", err)
+		}
+		return entry
+	}
+
+	// 1. Without keep_original only the converted file is stored
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "convert_only", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry := upload(db)
+	if entry.OriginalSize != 0 {
+		t.Errorf("expected no original, got %d bytes", entry.OriginalSize)
+	}
+	if _, err := store.ReadOriginal(ctx, db.ID.String(), entry.ID); err == nil {
+		t.Errorf("expected no stored original")
+	}
+
+	// 2. With keep_original both files are stored and counted
+	db, err = r.CreateDatabase(ctx, repo.Database{Name: "keep_original", ContentType: "file", Config: repo.DatabaseConfig{KeepOriginal: true}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	entry = upload(db)
+	if entry.Status != repo.EntryStatusReady || entry.Size != uint64(len("converted!")) ||
+		entry.OriginalSize != uint64(len("original")) || entry.OriginalMimeType != plan.InitMimeType {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	original, err := store.ReadOriginal(ctx, db.ID.String(), entry.ID)
+	if err != nil {
+		t.Fatalf("failed to read original: %v", err)
+	}
+	content, _ := io.ReadAll(original)
+	original.Close()
+	if string(content) != "original" {
+		t.Errorf("expected the original content, got %q", content)
+	}
+
+	db, err = r.GetDatabase(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	if want := entry.Size + entry.OriginalSize; db.Stats.TotalDiskSpaceBytes != want {
+		t.Errorf("expected %d bytes in the stats, got %d", want, db.Stats.TotalDiskSpaceBytes)
+	}
+
+	// 3. Deleting the entry removes the original as well
+	meta, err := shared.DeleteSafe(ctx, r, store, db.ID, entry.ID)
+	if err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if meta.OriginalSize != entry.OriginalSize {
+		t.Errorf("expected the original size in the deletion meta, got %d", meta.OriginalSize)
+	}
+	if _, err := store.ReadOriginal(ctx, db.ID.String(), entry.ID); err == nil {
+		t.Errorf("expected the original to be removed")
+	}
+	if db, _ = r.GetDatabase(ctx, db.ID); db.Stats.TotalDiskSpaceBytes != 0 {
+		t.Errorf("expected empty stats, got %d bytes", db.Stats.TotalDiskSpaceBytes)
+	}
+}
+
+// plainConverter handles generic files, which need neither conversion nor previews.
+type plainConverter struct {
+	media.MediaConverter
+}
+
+func (plainConverter) CanCreatePreview(string) bool { return false }
+
+func (plainConverter) CanConvert(string, string) media.ConversionCheck {
+	return media.ConversionCheck{}
+}
+
+func (plainConverter) ReadMediaFieldsFromStream(context.Context, io.ReadSeeker, string) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+
+func TestTruncatedUploads(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "truncated", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	p, _ := NewProcessor(r, store, plainConverter{}, 1, 4, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// 1. In-memory uploads are rejected before an entry is created
+	for _, tc := range []struct {
+		name    string
+		content string
+		size    int64
+	}{
+		{"empty", "", 0},
+		{"shorter than announced", "short", 100},
+	} {
+		_, _, err := p.ProcessEntry(ctx, db, EntryRequest{FileName: "a.bin", Size: tc.size}, strings.NewReader(tc.content), "application/octet-stream", "a.bin")
+		if !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("%s: expected a validation error, got %v", tc.name, err)
+		}
+	}
+	if entries, _ := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 10}); len(entries) != 0 {
+		t.Fatalf("expected no entries, got %d", len(entries))
+	}
+
+	// 2. A spooled file that differs from the announced size fails in the worker
+	spooled, err := os.CreateTemp(t.TempDir(), "upload-*")
+	if err != nil {
+		t.Fatalf("failed to create spooled file: %v", err)
+	}
+	spooled.WriteString("partial")
+	entry, wasSync, err := p.ProcessEntry(ctx, db, EntryRequest{FileName: "b.bin", Size: 4096}, spooled, "application/octet-stream", "b.bin")
+	if err != nil || wasSync {
+		t.Fatalf("expected an asynchronous upload, got sync=%v: %v", wasSync, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for entry.Status != repo.EntryStatusError && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		entry, _ = r.GetEntry(ctx, db.ID, entry.ID)
+	}
+	if entry.Status != repo.EntryStatusError || entry.ErrorReason != ErrorReasonTruncatedUpload {
+		t.Errorf("expected the entry to fail with %s, got status %v (%q)", ErrorReasonTruncatedUpload, entry.Status, entry.ErrorReason)
+	}
+	if _, err := store.Read(ctx, db.ID.String(), entry.ID, 0, -1); err == nil {
+		t.Error("expected no stored file for the truncated upload")
+	}
+}
@@ -0,0 +1,194 @@
+// Package cache implements the in-memory cache of the repository: a least recently used cache with
+// expiring items, bounded per namespace. Keys are "<namespace>:<key>" (see Key), so the cached users,
+// databases or entries can be flushed independently and are counted separately.
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Namespaces of the repository cache.
+const (
+	NamespaceUsers     = "users"
+	NamespaceDatabases = "databases"
+	NamespaceEntries   = "entries"
+)
+
+// DefaultMaxEntries is the number of items kept per namespace if no limit is configured.
+const DefaultMaxEntries = 10000
+
+// Expirations accepted by Set, as in go-cache.
+const (
+	DefaultExpiration time.Duration = 0  // the default TTL of the cache
+	NoExpiration      time.Duration = -1 // the item is only removed by eviction, Delete or Flush
+)
+
+// Key joins a namespace and the parts of a key, e.g. Key(NamespaceUsers, "groups", id).
+func Key(namespace string, parts ...string) string {
+	return namespace + ":" + strings.Join(parts, ":")
+}
+
+// namespaceOf returns the namespace of a key, "" if it has none.
+func namespaceOf(key string) string {
+	namespace, _, found := strings.Cut(key, ":")
+	if !found {
+		return ""
+	}
+	return namespace
+}
+
+// Stats are the counters of a namespace since the start or its last flush.
+type Stats struct {
+	Entries   int    `json:"entries"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"` // items removed to stay within the size limit
+}
+
+type item struct {
+	key       string
+	value     any
+	expiresAt time.Time // zero if the item does not expire
+}
+
+type namespace struct {
+	items map[string]*list.Element
+	order *list.List // most recently used first
+	stats Stats
+}
+
+// Cache is safe for concurrent use.
+type Cache struct {
+	mu         sync.Mutex
+	defaultTTL time.Duration
+	maxEntries int
+	namespaces map[string]*namespace
+	now        func() time.Time
+}
+
+// New returns a cache whose items expire after defaultTTL unless Set is given another TTL. Every
+// namespace holds at most maxEntries items, DefaultMaxEntries if maxEntries is 0 or less.
+func New(defaultTTL time.Duration, maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Cache{
+		defaultTTL: defaultTTL,
+		maxEntries: maxEntries,
+		namespaces: make(map[string]*namespace),
+		now:        time.Now,
+	}
+}
+
+// MaxEntries returns the number of items kept per namespace.
+func (c *Cache) MaxEntries() int {
+	return c.maxEntries
+}
+
+func (c *Cache) namespace(name string) *namespace {
+	ns, ok := c.namespaces[name]
+	if !ok {
+		ns = &namespace{items: make(map[string]*list.Element), order: list.New()}
+		c.namespaces[name] = ns
+	}
+	return ns
+}
+
+// Get returns the item of a key and marks it as recently used. Expired items are not found.
+func (c *Cache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ns := c.namespace(namespaceOf(key))
+	el, ok := ns.items[key]
+	if !ok {
+		ns.stats.Misses++
+		return nil, false
+	}
+	it := el.Value.(*item)
+	if !it.expiresAt.IsZero() && c.now().After(it.expiresAt) {
+		ns.remove(el)
+		ns.stats.Misses++
+		return nil, false
+	}
+	ns.order.MoveToFront(el)
+	ns.stats.Hits++
+	return it.value, true
+}
+
+// Set adds or replaces the item of a key. If the namespace is full, its least recently used item is evicted.
+func (c *Cache) Set(key string, value any, ttl time.Duration) {
+	if ttl == DefaultExpiration {
+		ttl = c.defaultTTL
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ns := c.namespace(namespaceOf(key))
+	if el, ok := ns.items[key]; ok {
+		it := el.Value.(*item)
+		it.value, it.expiresAt = value, expiresAt
+		ns.order.MoveToFront(el)
+		return
+	}
+
+	ns.items[key] = ns.order.PushFront(&item{key: key, value: value, expiresAt: expiresAt})
+	for ns.order.Len() > c.maxEntries {
+		ns.remove(ns.order.Back())
+		ns.stats.Evictions++
+	}
+}
+
+// Delete removes the item of a key, if any.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ns := c.namespace(namespaceOf(key))
+	if el, ok := ns.items[key]; ok {
+		ns.remove(el)
+	}
+}
+
+// Flush removes all items of a namespace and resets its counters, all namespaces if name is empty.
+// It returns the number of removed items.
+func (c *Cache) Flush(name string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for nsName, ns := range c.namespaces {
+		if name == "" || nsName == name {
+			removed += ns.order.Len()
+			delete(c.namespaces, nsName)
+		}
+	}
+	return removed
+}
+
+// Stats returns the counters of all namespaces that were used.
+func (c *Cache) Stats() map[string]Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make(map[string]Stats, len(c.namespaces))
+	for name, ns := range c.namespaces {
+		s := ns.stats
+		s.Entries = ns.order.Len()
+		stats[name] = s
+	}
+	return stats
+}
+
+func (ns *namespace) remove(el *list.Element) {
+	ns.order.Remove(el)
+	delete(ns.items, el.Value.(*item).key)
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEvictionOrder(t *testing.T) {
+	c := New(NoExpiration, 3)
+
+	for _, id := range []string{"a", "b", "c"} {
+		c.Set(Key(NamespaceUsers, id), id, DefaultExpiration)
+	}
+	// "a" becomes the most recently used, "b" is evicted first
+	if _, ok := c.Get(Key(NamespaceUsers, "a")); !ok {
+		t.Fatalf("expected a hit for a")
+	}
+	c.Set(Key(NamespaceUsers, "d"), "d", DefaultExpiration)
+	c.Set(Key(NamespaceUsers, "e"), "e", DefaultExpiration)
+
+	for id, want := range map[string]bool{"a": true, "b": false, "c": false, "d": true, "e": true} {
+		if _, ok := c.Get(Key(NamespaceUsers, id)); ok != want {
+			t.Errorf("%s: expected cached=%v", id, want)
+		}
+	}
+
+	// Replacing an item does not evict and other namespaces have their own limit
+	c.Set(Key(NamespaceUsers, "a"), "a2", DefaultExpiration)
+	for _, id := range []string{"x", "y", "z"} {
+		c.Set(Key(NamespaceEntries, id), id, DefaultExpiration)
+	}
+	if v, ok := c.Get(Key(NamespaceUsers, "a")); !ok || v != "a2" {
+		t.Errorf("expected the replaced value, got %v (found %v)", v, ok)
+	}
+	stats := c.Stats()
+	if stats[NamespaceUsers].Entries != 3 || stats[NamespaceUsers].Evictions != 2 {
+		t.Errorf("unexpected users stats: %+v", stats[NamespaceUsers])
+	}
+	if stats[NamespaceEntries].Entries != 3 || stats[NamespaceEntries].Evictions != 0 {
+		t.Errorf("unexpected entries stats: %+v", stats[NamespaceEntries])
+	}
+}
+
+func TestFlushNamespace(t *testing.T) {
+	c := New(NoExpiration, 0)
+	if c.MaxEntries() != DefaultMaxEntries {
+		t.Errorf("expected the default limit, got %d", c.MaxEntries())
+	}
+
+	c.Set(Key(NamespaceUsers, "groups", "1"), 1, DefaultExpiration)
+	c.Set(Key(NamespaceUsers, "groups", "2"), 2, DefaultExpiration)
+	c.Set(Key(NamespaceDatabases, "custom_fields", "1"), 3, DefaultExpiration)
+	c.Get(Key(NamespaceUsers, "groups", "1"))
+
+	if n := c.Flush(NamespaceUsers); n != 2 {
+		t.Errorf("expected 2 flushed items, got %d", n)
+	}
+	if _, ok := c.Get(Key(NamespaceUsers, "groups", "1")); ok {
+		t.Errorf("expected the users namespace to be empty")
+	}
+	if _, ok := c.Get(Key(NamespaceDatabases, "custom_fields", "1")); !ok {
+		t.Errorf("expected the databases namespace to be kept")
+	}
+	// The counters of the flushed namespace start again
+	if s := c.Stats()[NamespaceUsers]; s.Hits != 0 || s.Misses != 1 || s.Entries != 0 {
+		t.Errorf("unexpected users stats after flush: %+v", s)
+	}
+
+	if n := c.Flush(""); n != 1 {
+		t.Errorf("expected 1 flushed item, got %d", n)
+	}
+	if stats := c.Stats(); len(stats) != 0 {
+		t.Errorf("expected no stats after flushing all namespaces, got %+v", stats)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := New(time.Minute, 10)
+	c.now = func() time.Time { return now }
+
+	key := Key(NamespaceDatabases, "custom_fields", "7")
+	c.Get(key) // miss
+	c.Set(key, "fields", DefaultExpiration)
+	c.Get(key) // hit
+	c.Get(key) // hit
+	c.Set(Key(NamespaceDatabases, "pinned"), "x", NoExpiration)
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get(key); ok { // expired: miss
+		t.Errorf("expected the item to expire")
+	}
+	if _, ok := c.Get(Key(NamespaceDatabases, "pinned")); !ok { // hit
+		t.Errorf("expected the item without expiration to be kept")
+	}
+	c.Delete(Key(NamespaceDatabases, "pinned"))
+
+	want := Stats{Entries: 0, Hits: 3, Misses: 2, Evictions: 0}
+	if got := c.Stats()[NamespaceDatabases]; got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	c := New(NoExpiration, 50)
+
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				key := Key(NamespaceEntries, strconv.Itoa((g*500+i)%200))
+				c.Set(key, i, DefaultExpiration)
+				c.Get(key)
+				if i%10 == 0 {
+					c.Delete(key)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := c.Stats()[NamespaceEntries]
+	if s.Entries > 50 {
+		t.Errorf("expected at most 50 items, got %d", s.Entries)
+	}
+	if s.Hits+s.Misses != 8*500 {
+		t.Errorf("expected %d lookups, got %d", 8*500, s.Hits+s.Misses)
+	}
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"fmt"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// CapacityLimits bounds the number of databases and of entries per database. Zero values are unlimited.
+type CapacityLimits struct {
+	MaxDatabases          int
+	MaxEntriesPerDatabase int
+}
+
+// CheckDatabases returns an error wrapping ErrLimitReached if there is no room for another database next to count existing ones.
+func (l CapacityLimits) CheckDatabases(count int) error {
+	if l.MaxDatabases > 0 && count >= l.MaxDatabases {
+		return fmt.Errorf("%w: %d databases exist, the maximum is %d", customerrors.ErrLimitReached, count, l.MaxDatabases)
+	}
+	return nil
+}
+
+// CheckEntries returns an error wrapping ErrLimitReached if n more entries do not fit into a database holding count entries.
+func (l CapacityLimits) CheckEntries(count uint64, n int) error {
+	if l.MaxEntriesPerDatabase <= 0 || count+uint64(n) <= uint64(l.MaxEntriesPerDatabase) {
+		return nil
+	}
+	if n > 1 {
+		return fmt.Errorf("%w: the database holds %d entries, %d more exceed the maximum of %d entries per database", customerrors.ErrLimitReached, count, n, l.MaxEntriesPerDatabase)
+	}
+	return fmt.Errorf("%w: the database holds %d entries, the maximum is %d entries per database", customerrors.ErrLimitReached, count, l.MaxEntriesPerDatabase)
+}
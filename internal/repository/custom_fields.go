@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// Limits of the custom fields of a database, if not configured otherwise.
+const (
+	DefaultMaxCustomFields    = 64
+	DefaultMaxFieldNameLength = 64
+)
+
+// reservedFieldNames are the entry fields and response keys besides StandardFieldTypes that custom fields must not shadow.
+var reservedFieldNames = []string{
+	"database_id", "error_reason", "error_detail", "original_filesize", "original_mime_type", "content_hash", "last_verified_at",
+	"upload_source", "media_fields", "custom_fields", "transcription_status", "content_version", SortFieldFulltextRank, SortFieldGeoDistance,
+}
+
+// FieldLimits bounds the custom fields of a database. Zero values fall back to
+// DefaultMaxCustomFields and DefaultMaxFieldNameLength.
+type FieldLimits struct {
+	MaxCount      int // custom fields per database
+	MaxNameLength int // characters of a field name
+}
+
+// Resolved returns the limits with the defaults applied.
+func (l FieldLimits) Resolved() FieldLimits {
+	if l.MaxCount <= 0 {
+		l.MaxCount = DefaultMaxCustomFields
+	}
+	if l.MaxNameLength <= 0 {
+		l.MaxNameLength = DefaultMaxFieldNameLength
+	}
+	return l
+}
+
+// ValidateCustomFields checks the custom fields of a database definition: their number, and that
+// every name is valid (see ValidateCustomFieldName) and unique, ignoring case, and that only TEXT
+// fields are full-text indexed. The error names the offending field and wraps ErrValidation.
+func ValidateCustomFields(fields []CustomFieldDef, mediaFields []string, limits FieldLimits) error {
+	limits = limits.Resolved()
+	if len(fields) > limits.MaxCount {
+		return fmt.Errorf("%w: %d custom fields exceed the maximum of %d", customerrors.ErrValidation, len(fields), limits.MaxCount)
+	}
+
+	seen := make(map[string]string, len(fields))
+	for _, cf := range fields {
+		if err := ValidateCustomFieldName(cf.Name, mediaFields, limits); err != nil {
+			return err
+		}
+		if other, ok := seen[strings.ToLower(cf.Name)]; ok {
+			return fmt.Errorf("%w: custom field '%s' collides with custom field '%s'", customerrors.ErrValidation, cf.Name, other)
+		}
+		seen[strings.ToLower(cf.Name)] = cf.Name
+		if cf.IsFulltext && !strings.EqualFold(cf.Type, "TEXT") {
+			return fmt.Errorf("%w: custom field '%s' of type %s cannot be full-text indexed, only TEXT fields can", customerrors.ErrValidation, cf.Name, cf.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateCustomFieldName checks that a custom field name is not empty, not longer than the limit and
+// does not collide, ignoring case, with a standard field or one of the media fields of the database.
+func ValidateCustomFieldName(name string, mediaFields []string, limits FieldLimits) error {
+	limits = limits.Resolved()
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("%w: custom field name cannot be empty", customerrors.ErrValidation)
+	}
+	if n := len([]rune(name)); n > limits.MaxNameLength {
+		return fmt.Errorf("%w: custom field name '%s' has %d characters, the maximum is %d", customerrors.ErrValidation, name, n, limits.MaxNameLength)
+	}
+
+	for standard := range StandardFieldTypes {
+		if strings.EqualFold(name, standard) {
+			return fmt.Errorf("%w: custom field '%s' collides with the standard field '%s'", customerrors.ErrValidation, name, standard)
+		}
+	}
+	for _, reserved := range reservedFieldNames {
+		if strings.EqualFold(name, reserved) {
+			return fmt.Errorf("%w: custom field '%s' collides with the standard field '%s'", customerrors.ErrValidation, name, reserved)
+		}
+	}
+	for _, mediaField := range mediaFields {
+		if strings.EqualFold(name, mediaField) {
+			return fmt.Errorf("%w: custom field '%s' collides with the media field '%s'", customerrors.ErrValidation, name, mediaField)
+		}
+	}
+	return nil
+}
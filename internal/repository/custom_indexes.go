@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// Limits of the composite indexes of a database, every index slows down the writes of its entries.
+const (
+	MaxCustomIndexes      = 16
+	MaxCustomIndexColumns = 8
+)
+
+// ValidateCustomIndexes checks the composite indexes of a database definition: at most MaxCustomIndexes
+// indexes of 2 to MaxCustomIndexColumns distinct fields each, none declared twice. Every field must be one
+// of fieldNames, the standard, media and custom fields of the database. The error wraps ErrValidation.
+func ValidateCustomIndexes(indexes [][]string, fieldNames []string) error {
+	if len(indexes) > MaxCustomIndexes {
+		return fmt.Errorf("%w: %d custom indexes exceed the maximum of %d", customerrors.ErrValidation, len(indexes), MaxCustomIndexes)
+	}
+	for i, index := range indexes {
+		if len(index) < 2 || len(index) > MaxCustomIndexColumns {
+			return fmt.Errorf("%w: custom index (%s) must have 2 to %d fields, index single custom fields with is_indexed", customerrors.ErrValidation, strings.Join(index, ", "), MaxCustomIndexColumns)
+		}
+		for j, field := range index {
+			if !slices.Contains(fieldNames, field) {
+				return fmt.Errorf("%w: custom index (%s): '%s' is not a standard, media or custom field of the database", customerrors.ErrValidation, strings.Join(index, ", "), field)
+			}
+			if slices.Contains(index[:j], field) {
+				return fmt.Errorf("%w: custom index (%s) has the field '%s' twice", customerrors.ErrValidation, strings.Join(index, ", "), field)
+			}
+		}
+		if slices.ContainsFunc(indexes[:i], func(other []string) bool { return slices.Equal(other, index) }) {
+			return fmt.Errorf("%w: custom index (%s) is declared twice", customerrors.ErrValidation, strings.Join(index, ", "))
+		}
+	}
+	return nil
+}
+
+// IndexFieldNames returns the fields composite indexes can use: the standard fields, the given media
+// fields of the content type and the custom fields.
+func IndexFieldNames(mediaFields []string, customFields []CustomFieldDef) []string {
+	names := make([]string, 0, len(StandardFieldTypes)+len(mediaFields)+len(customFields))
+	for name := range StandardFieldTypes {
+		names = append(names, name)
+	}
+	names = append(names, mediaFields...)
+	for _, cf := range customFields {
+		names = append(names, cf.Name)
+	}
+	return names
+}
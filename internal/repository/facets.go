@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"fmt"
+	"slices"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// Limits of a facet request.
+const (
+	DefaultFacetLimit = 20 // values per field if the request sets no limit
+	MaxFacetLimit     = 100
+	MaxFacetFields    = 20
+)
+
+// FacetFieldTypes are the SQL types of the fields values can be counted for. REAL fields are left out, nearly
+// every entry has its own value, a histogram or range filter fits them better.
+var FacetFieldTypes = []string{"TEXT", "INTEGER", "BOOLEAN"}
+
+// FacetRequest counts the values of Fields among the entries matching Filter.
+type FacetRequest struct {
+	Fields []string
+	Limit  int // values per field, DefaultFacetLimit if 0
+	Filter *FilterGroup
+}
+
+// Validate checks the number of fields and the limit and fills in the default limit. The fields themselves are
+// checked by the repository against the fields of the database.
+func (r *FacetRequest) Validate() error {
+	if len(r.Fields) == 0 {
+		return fmt.Errorf("%w: at least one field is required", customerrors.ErrValidation)
+	}
+	if len(r.Fields) > MaxFacetFields {
+		return fmt.Errorf("%w: at most %d fields can be counted at once", customerrors.ErrValidation, MaxFacetFields)
+	}
+	for i, field := range r.Fields {
+		if slices.Contains(r.Fields[:i], field) {
+			return fmt.Errorf("%w: field '%s' is listed twice", customerrors.ErrValidation, field)
+		}
+	}
+	if r.Limit < 0 || r.Limit > MaxFacetLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", customerrors.ErrValidation, MaxFacetLimit)
+	}
+	if r.Limit == 0 {
+		r.Limit = DefaultFacetLimit
+	}
+	return nil
+}
+
+// Facets are the value counts of a FacetRequest.
+type Facets struct {
+	Total  int64   // entries matching the filter
+	Fields []Facet // in the order of the request
+}
+
+// Facet holds the most frequent values of a field, the most frequent first. Values beyond the limit are summed
+// up in OtherCount, entries without a value in NullCount.
+type Facet struct {
+	Field      string
+	Values     []FacetValue
+	OtherCount int64
+	NullCount  int64
+}
+
+// FacetValue is a value of a field and the number of entries that have it. Values are strings, int64 or bool
+// by the type of the field.
+type FacetValue struct {
+	Value any
+	Count int64
+}
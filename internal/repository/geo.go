@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// SortFieldGeoDistance sorts by the distance to the center of the radius of the geo filter, ascending is
+// the nearest first.
+const SortFieldGeoDistance = "geo_distance"
+
+// EarthRadiusMeters is the mean radius of the earth used for distances.
+const EarthRadiusMeters = 6371008.8
+
+// MaxGeoRadiusMeters is the largest radius of a geo filter, half the circumference of the earth.
+const MaxGeoRadiusMeters = math.Pi * EarthRadiusMeters
+
+// GeoFields names the REAL custom fields holding the latitude and longitude of the entries, in degrees.
+type GeoFields struct {
+	Lat string `json:"lat"`
+	Lon string `json:"lon"`
+}
+
+// Enabled reports whether the database declares geo fields.
+func (g GeoFields) Enabled() bool {
+	return g.Lat != "" || g.Lon != ""
+}
+
+// ValidateGeoFields checks that the geo fields are two distinct REAL custom fields. Unset fields are valid.
+func ValidateGeoFields(g GeoFields, customFields []CustomFieldDef) error {
+	if !g.Enabled() {
+		return nil
+	}
+	if g.Lat == "" || g.Lon == "" {
+		return fmt.Errorf("%w: geo_fields needs both lat and lon", customerrors.ErrValidation)
+	}
+	if g.Lat == g.Lon {
+		return fmt.Errorf("%w: geo_fields lat and lon must be different fields", customerrors.ErrValidation)
+	}
+	for _, name := range []string{g.Lat, g.Lon} {
+		i := slices.IndexFunc(customFields, func(cf CustomFieldDef) bool { return cf.Name == name })
+		if i < 0 {
+			return fmt.Errorf("%w: geo field '%s' is not a custom field of the database", customerrors.ErrValidation, name)
+		}
+		if !strings.EqualFold(customFields[i].Type, "REAL") {
+			return fmt.Errorf("%w: geo field '%s' must be of type REAL", customerrors.ErrValidation, name)
+		}
+	}
+	return nil
+}
+
+// GeoFilter restricts a search to the entries within a bounding box or within a radius around a point.
+// Entries without coordinates never match.
+type GeoFilter struct {
+	Fields GeoFields // the geo fields of the database, set from its config
+	BBox   *GeoBBox
+	Radius *GeoRadius
+}
+
+// GeoBBox is a bounding box in degrees, its edges are included. A MinLon greater than MaxLon crosses
+// the antimeridian.
+type GeoBBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// GeoRadius is a circle of Meters around a point.
+type GeoRadius struct {
+	Lat    float64
+	Lon    float64
+	Meters float64
+}
+
+// Validate checks that the filter has exactly one of a bounding box and a radius with coordinates in range.
+func (f GeoFilter) Validate() error {
+	if (f.BBox == nil) == (f.Radius == nil) {
+		return fmt.Errorf("%w: geo needs either bbox or radius", customerrors.ErrValidation)
+	}
+	if b := f.BBox; b != nil {
+		if err := validateCoordinates("bbox min", b.MinLat, b.MinLon); err != nil {
+			return err
+		}
+		if err := validateCoordinates("bbox max", b.MaxLat, b.MaxLon); err != nil {
+			return err
+		}
+		if b.MinLat > b.MaxLat {
+			return fmt.Errorf("%w: geo bbox min_lat %v is above max_lat %v", customerrors.ErrValidation, b.MinLat, b.MaxLat)
+		}
+	}
+	if c := f.Radius; c != nil {
+		if err := validateCoordinates("radius center", c.Lat, c.Lon); err != nil {
+			return err
+		}
+		if !(c.Meters > 0 && c.Meters <= MaxGeoRadiusMeters) {
+			return fmt.Errorf("%w: geo radius meters must be above 0 and at most %.0f", customerrors.ErrValidation, MaxGeoRadiusMeters)
+		}
+	}
+	return nil
+}
+
+func validateCoordinates(name string, lat, lon float64) error {
+	if !(lat >= -90 && lat <= 90) || !(lon >= -180 && lon <= 180) {
+		return fmt.Errorf("%w: geo %s (%v, %v) is out of range, latitudes are -90 to 90 and longitudes -180 to 180", customerrors.ErrValidation, name, lat, lon)
+	}
+	return nil
+}
+
+// BBox returns a bounding box containing the circle, the prefilter of radius searches. Circles that reach
+// a pole span all longitudes.
+func (c GeoRadius) BBox() GeoBBox {
+	dLat := c.Meters / EarthRadiusMeters * 180 / math.Pi
+	box := GeoBBox{MinLat: c.Lat - dLat, MaxLat: c.Lat + dLat, MinLon: -180, MaxLon: 180}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		box.MinLat, box.MaxLat = max(box.MinLat, -90), min(box.MaxLat, 90)
+		return box
+	}
+
+	// The widest longitude span is at the latitude of the tangent point, not at the center
+	ratio := math.Sin(c.Meters/EarthRadiusMeters) / math.Cos(c.Lat*math.Pi/180)
+	if ratio >= 1 {
+		return box
+	}
+	dLon := math.Asin(ratio) * 180 / math.Pi
+	box.MinLon, box.MaxLon = c.Lon-dLon, c.Lon+dLon
+	if box.MinLon < -180 {
+		box.MinLon += 360
+	}
+	if box.MaxLon > 180 {
+		box.MaxLon -= 360
+	}
+	return box
+}
+
+// HaversineMeters returns the great-circle distance between two points in degrees.
+func HaversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusMeters * math.Asin(math.Sqrt(min(a, 1)))
+}
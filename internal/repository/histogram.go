@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// Bucket sizes of entry histograms. Buckets are aligned in UTC, weeks start on Monday 00:00 UTC.
+const (
+	HistogramBucketHour = "hour"
+	HistogramBucketDay  = "day"
+	HistogramBucketWeek = "week"
+)
+
+// MaxHistogramBuckets bounds the number of buckets of one histogram.
+const MaxHistogramBuckets = 2000
+
+// weekOffsetMs shifts week buckets to Monday, the Unix epoch was a Thursday.
+const weekOffsetMs = 4 * 24 * int64(time.Hour/time.Millisecond)
+
+// HistogramRequest counts the entries matching Filter per bucket of their timestamp.
+type HistogramRequest struct {
+	Bucket string // HistogramBucketHour, HistogramBucketDay or HistogramBucketWeek
+	TStart time.Time
+	TEnd   time.Time // exclusive
+	Filter *FilterGroup
+}
+
+// HistogramBucket is the number and total file size of the entries in [Start, Start+bucket size).
+type HistogramBucket struct {
+	Start time.Time
+	Count int64
+	Bytes uint64
+}
+
+// HistogramBucketMillis returns the length of a bucket in milliseconds and the offset of its
+// alignment from the Unix epoch, false for unknown buckets.
+func HistogramBucketMillis(bucket string) (size, offset int64, ok bool) {
+	switch bucket {
+	case HistogramBucketHour:
+		return int64(time.Hour / time.Millisecond), 0, true
+	case HistogramBucketDay:
+		return 24 * int64(time.Hour/time.Millisecond), 0, true
+	case HistogramBucketWeek:
+		return 7 * 24 * int64(time.Hour/time.Millisecond), weekOffsetMs, true
+	}
+	return 0, 0, false
+}
+
+// Buckets validates the request and returns the start of the first bucket, which contains TStart,
+// and the number of buckets up to TEnd. More than MaxHistogramBuckets are an ErrValidation.
+func (h HistogramRequest) Buckets() (time.Time, int, error) {
+	size, offset, ok := HistogramBucketMillis(h.Bucket)
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("%w: invalid bucket '%s' (must be hour, day or week)", customerrors.ErrValidation, h.Bucket)
+	}
+	if h.TStart.IsZero() || h.TEnd.IsZero() {
+		return time.Time{}, 0, fmt.Errorf("%w: tstart and tend are required", customerrors.ErrValidation)
+	}
+	if !h.TEnd.After(h.TStart) {
+		return time.Time{}, 0, fmt.Errorf("%w: tend must be after tstart", customerrors.ErrValidation)
+	}
+
+	start := floorBucket(h.TStart.UnixMilli(), size, offset)
+	end := h.TEnd.UnixMilli()
+	if end/size-start/size > MaxHistogramBuckets { // checked first, absurd ranges overflow the exact count
+		return time.Time{}, 0, fmt.Errorf("%w: the range spans more than %d buckets of a %s", customerrors.ErrValidation, MaxHistogramBuckets, h.Bucket)
+	}
+	count := (end - start + size - 1) / size
+	if count > MaxHistogramBuckets {
+		return time.Time{}, 0, fmt.Errorf("%w: the range spans more than %d buckets of a %s", customerrors.ErrValidation, MaxHistogramBuckets, h.Bucket)
+	}
+	return time.UnixMilli(start).UTC(), int(count), nil
+}
+
+// floorBucket returns the start of the bucket containing ms, also before the epoch.
+func floorBucket(ms, size, offset int64) int64 {
+	rel := (ms - offset) % size
+	if rel < 0 {
+		rel += size
+	}
+	return ms - rel
+}
+
+// RetentionRequest groups the entries without legal hold by the week in which they become due under
+// a MaxAge rule, see GetRetentionForecast.
+type RetentionRequest struct {
+	TimeField string        // timestamp or created_at, the column the rule compares with
+	Cutoff    time.Time     // entries with TimeField up to Cutoff are due now
+	Weeks     int           // number of weeks after Cutoff, at most MaxHistogramBuckets
+	Statuses  []EntryStatus // only count entries with one of these statuses, all if empty
+}
+
+// Validate checks the request.
+func (r RetentionRequest) Validate() error {
+	if r.TimeField != "timestamp" && r.TimeField != "created_at" {
+		return fmt.Errorf("%w: invalid time field '%s' (must be timestamp or created_at)", customerrors.ErrValidation, r.TimeField)
+	}
+	if r.Weeks < 0 || r.Weeks > MaxHistogramBuckets {
+		return fmt.Errorf("%w: weeks must be between 0 and %d", customerrors.ErrValidation, MaxHistogramBuckets)
+	}
+	return nil
+}
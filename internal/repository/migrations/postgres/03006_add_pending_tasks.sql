@@ -0,0 +1,21 @@
+-- Migration: Add Pending Tasks
+-- Description: Persists post-processing steps of entries so they survive restarts.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS pending_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    database_id VARCHAR(26) NOT NULL,
+    entry_id BIGINT NOT NULL,
+    task_type TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at BIGINT NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at BIGINT NOT NULL DEFAULT CAST(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP) * 1000 AS BIGINT),
+
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_tasks_next_attempt ON pending_tasks(next_attempt_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS pending_tasks;
@@ -0,0 +1,26 @@
+-- Migration: Add Share Links Table
+-- Description: Creates the share_links table for expiring, unauthenticated per-entry access tokens.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS share_links (
+    id VARCHAR(26) PRIMARY KEY NOT NULL, -- ULID
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 hash of the share token
+    database_id VARCHAR(26) NOT NULL,
+    entry_id INTEGER NOT NULL,
+
+    preview_only BOOLEAN NOT NULL DEFAULT 0,
+    max_downloads INTEGER NOT NULL DEFAULT 0, -- 0 means unlimited
+    download_count INTEGER NOT NULL DEFAULT 0,
+
+    created_by VARCHAR(64) NOT NULL, -- username of the creator (for auditing)
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    expires_at INTEGER NOT NULL,
+
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_entry ON share_links(database_id, entry_id);
+CREATE INDEX IF NOT EXISTS idx_share_links_expires_at ON share_links(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS share_links;
@@ -0,0 +1,28 @@
+-- Migration: Add User Groups
+-- Description: Creates groups with global view, create, edit, delete and admin flags, and the user/group membership table.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS groups (
+    id VARCHAR(26) PRIMARY KEY NOT NULL, -- ULID
+    name VARCHAR(64) UNIQUE NOT NULL CHECK(length(name) > 0 AND length(name) <= 64),
+    -- Roles of the members on every database
+    can_view BOOLEAN NOT NULL DEFAULT 0,
+    can_create BOOLEAN NOT NULL DEFAULT 0,
+    can_edit BOOLEAN NOT NULL DEFAULT 0,
+    can_delete BOOLEAN NOT NULL DEFAULT 0,
+    is_admin BOOLEAN NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS user_groups (
+    user_id VARCHAR(26) NOT NULL,
+    group_id VARCHAR(26) NOT NULL,
+    PRIMARY KEY (user_id, group_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_groups_group_id ON user_groups(group_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS groups;
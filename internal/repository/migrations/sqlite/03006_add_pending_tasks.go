@@ -0,0 +1,96 @@
+// Migration: Add Pending Tasks & Entry Error Reasons
+// Description: Persists post-processing steps of entries so they survive restarts.
+//
+// Up changes:
+//   - Creates the 'pending_tasks' table.
+//   - Adds the 'error_reason' text column to the dynamic 'entries_{db_id}' tables.
+//
+// Down changes:
+//   - Drops the 'error_reason' column and the 'pending_tasks' table.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03006, down03006)
+}
+
+func up03006(ctx context.Context, tx *sql.Tx) error {
+	createTable := `CREATE TABLE IF NOT EXISTS pending_tasks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    database_id VARCHAR(26) NOT NULL,
+    entry_id INTEGER NOT NULL,
+    task_type TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);`
+	if _, err := tx.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("failed to create pending_tasks table: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_pending_tasks_next_attempt ON pending_tasks(next_attempt_at);`); err != nil {
+		return fmt.Errorf("failed to create pending_tasks index: %w", err)
+	}
+
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN error_reason TEXT NOT NULL DEFAULT '';`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to add error_reason column for db %s: %w", dbID, err)
+		}
+	}
+
+	return nil
+}
+
+func down03006(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN error_reason;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to drop error_reason column for db %s: %w", dbID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS pending_tasks;`); err != nil {
+		return fmt.Errorf("failed to drop pending_tasks table: %w", err)
+	}
+	return nil
+}
+
+// queryDatabaseIDs returns the IDs of all databases, i.e. the suffixes of the dynamic entry tables.
+func queryDatabaseIDs(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM databases")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var dbIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan database ID: %w", err)
+		}
+		dbIDs = append(dbIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating database rows: %w", err)
+	}
+	return dbIDs, nil
+}
@@ -0,0 +1,24 @@
+-- Migration: Add Idempotency Keys Table
+-- Description: Remembers the outcome of uploads sent with an Idempotency-Key header, so retried uploads do not create duplicates.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    database_id VARCHAR(26) NOT NULL,
+    user_id VARCHAR(26) NOT NULL,
+    idempotency_key TEXT NOT NULL,
+
+    entry_id INTEGER NOT NULL DEFAULT 0,
+    status_code INTEGER NOT NULL DEFAULT 0, -- 0 while the original request is in progress
+
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    expires_at INTEGER NOT NULL,
+
+    PRIMARY KEY (database_id, user_id, idempotency_key),
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,21 @@
+-- Migration: Add Retained Uploads Table
+-- Description: Tracks the source files of failed asynchronous uploads that are kept for a grace period, so the entry can be retried without uploading it again.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS retained_uploads (
+    database_id VARCHAR(26) NOT NULL,
+    entry_id INTEGER NOT NULL,
+
+    path TEXT NOT NULL, -- local file of the instance that processed the upload
+
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    expires_at INTEGER NOT NULL,
+
+    PRIMARY KEY (database_id, entry_id),
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_retained_uploads_expires_at ON retained_uploads(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS retained_uploads;
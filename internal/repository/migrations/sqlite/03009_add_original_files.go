@@ -0,0 +1,63 @@
+// Migration: Add Original Files
+// Description: Databases can keep the original of converted uploads next to the converted file.
+//
+// Up changes:
+//   - Adds the 'keep_original' flag to the 'databases' table.
+//   - Adds the 'original_filesize' and 'original_mime_type' columns to the dynamic 'entries_{db_id}' tables.
+//
+// Down changes:
+//   - Drops the added columns. Stored originals are not removed from the storage.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03009, down03009)
+}
+
+func up03009(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE databases ADD COLUMN keep_original BOOLEAN NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add keep_original column: %w", err)
+	}
+
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		for _, column := range []string{"original_filesize INTEGER NOT NULL DEFAULT 0", "original_mime_type TEXT NOT NULL DEFAULT ''"} {
+			alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN %s;`, dbID, column)
+			if _, err := tx.ExecContext(ctx, alter); err != nil {
+				return fmt.Errorf("failed to add original columns for db %s: %w", dbID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func down03009(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		for _, column := range []string{"original_filesize", "original_mime_type"} {
+			alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN %s;`, dbID, column)
+			if _, err := tx.ExecContext(ctx, alter); err != nil {
+				return fmt.Errorf("failed to drop original columns for db %s: %w", dbID, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE databases DROP COLUMN keep_original;`); err != nil {
+		return fmt.Errorf("failed to drop keep_original column: %w", err)
+	}
+	return nil
+}
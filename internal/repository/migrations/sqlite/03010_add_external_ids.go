@@ -0,0 +1,69 @@
+// Migration: Add External IDs
+// Description: Entries can carry an identifier assigned by the client, optionally unique per database.
+//
+// Up changes:
+//   - Adds the 'unique_external_id' flag to the 'databases' table.
+//   - Adds the 'external_id' text column and its index to the dynamic 'entries_{db_id}' tables.
+//
+// Down changes:
+//   - Drops the indexes of the external ID, the added columns and the flag.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03010, down03010)
+}
+
+func up03010(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE databases ADD COLUMN unique_external_id BOOLEAN NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add unique_external_id column: %w", err)
+	}
+
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN external_id TEXT NOT NULL DEFAULT '';`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to add external_id column for db %s: %w", dbID, err)
+		}
+		index := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_external_id" ON "entries_%s"(external_id);`, dbID, dbID)
+		if _, err := tx.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("failed to create external_id index for db %s: %w", dbID, err)
+		}
+	}
+
+	return nil
+}
+
+func down03010(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		for _, index := range []string{"external_id", "external_id_unique"} {
+			drop := fmt.Sprintf(`DROP INDEX IF EXISTS "idx_entries_%s_%s";`, dbID, index)
+			if _, err := tx.ExecContext(ctx, drop); err != nil {
+				return fmt.Errorf("failed to drop external_id index for db %s: %w", dbID, err)
+			}
+		}
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN external_id;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to drop external_id column for db %s: %w", dbID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE databases DROP COLUMN unique_external_id;`); err != nil {
+		return fmt.Errorf("failed to drop unique_external_id column: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Migration: Add Integrity Checks
+// Description: Stored files are verified periodically against a content hash to detect silent corruption.
+//
+// Up changes:
+//   - Adds the 'content_hash' and 'last_verified_at' columns to the dynamic 'entries_{db_id}' tables.
+//   - Adds an index on 'last_verified_at', so the least recently verified entries are found quickly.
+//
+// Down changes:
+//   - Drops the index and the added columns.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03011, down03011)
+}
+
+func up03011(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		for _, column := range []string{"content_hash TEXT NOT NULL DEFAULT ''", "last_verified_at BIGINT NOT NULL DEFAULT 0"} {
+			alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN %s;`, dbID, column)
+			if _, err := tx.ExecContext(ctx, alter); err != nil {
+				return fmt.Errorf("failed to add integrity column for db %s: %w", dbID, err)
+			}
+		}
+		index := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_verified" ON "entries_%s"(last_verified_at);`, dbID, dbID)
+		if _, err := tx.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("failed to create last_verified_at index for db %s: %w", dbID, err)
+		}
+	}
+
+	return nil
+}
+
+func down03011(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		drop := fmt.Sprintf(`DROP INDEX IF EXISTS "idx_entries_%s_verified";`, dbID)
+		if _, err := tx.ExecContext(ctx, drop); err != nil {
+			return fmt.Errorf("failed to drop last_verified_at index for db %s: %w", dbID, err)
+		}
+		for _, column := range []string{"content_hash", "last_verified_at"} {
+			alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN %s;`, dbID, column)
+			if _, err := tx.ExecContext(ctx, alter); err != nil {
+				return fmt.Errorf("failed to drop %s column for db %s: %w", column, dbID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+// Migration: Add Sensitive Custom Fields
+// Description: Custom fields can be marked as sensitive so that they are hidden from users who may only view entries.
+//
+// Up changes:
+//   - Adds the 'is_sensitive' flag to the 'database_custom_fields' table.
+//
+// Down changes:
+//   - Drops the 'is_sensitive' flag.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03012, down03012)
+}
+
+func up03012(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE database_custom_fields ADD COLUMN is_sensitive BOOLEAN NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add is_sensitive column: %w", err)
+	}
+	return nil
+}
+
+func down03012(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE database_custom_fields DROP COLUMN is_sensitive;`); err != nil {
+		return fmt.Errorf("failed to drop is_sensitive column: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+-- Migration: Add JWT Secrets Table
+-- Description: Stores the secrets for signing JWTs when `auth.jwt.secret_source` is "db", so all instances share them.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS jwt_secrets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    secret TEXT NOT NULL,
+
+    created_at INTEGER NOT NULL,
+    retires_at INTEGER NOT NULL DEFAULT 0 -- 0 for the current secret, otherwise the end of its grace period
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS jwt_secrets;
@@ -0,0 +1,64 @@
+// Migration: Add Upload Origin
+// Description: Entries record who uploaded them and from where, to trace the source of an upload.
+//
+// Up changes:
+//   - Adds the nullable 'uploaded_by', 'upload_ip' and 'upload_user_agent' columns to the dynamic 'entries_{db_id}' tables.
+//     Existing entries keep NULL, their origin is unknown.
+//   - Adds an index on 'uploaded_by', so the entries of an uploader are found quickly.
+//
+// Down changes:
+//   - Drops the index and the added columns.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03014, down03014)
+}
+
+func up03014(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		for _, column := range []string{"uploaded_by TEXT", "upload_ip TEXT", "upload_user_agent TEXT"} {
+			alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN %s;`, dbID, column)
+			if _, err := tx.ExecContext(ctx, alter); err != nil {
+				return fmt.Errorf("failed to add upload origin column for db %s: %w", dbID, err)
+			}
+		}
+		index := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_uploaded_by" ON "entries_%s"(uploaded_by);`, dbID, dbID)
+		if _, err := tx.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("failed to create uploaded_by index for db %s: %w", dbID, err)
+		}
+	}
+
+	return nil
+}
+
+func down03014(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		drop := fmt.Sprintf(`DROP INDEX IF EXISTS "idx_entries_%s_uploaded_by";`, dbID)
+		if _, err := tx.ExecContext(ctx, drop); err != nil {
+			return fmt.Errorf("failed to drop uploaded_by index for db %s: %w", dbID, err)
+		}
+		for _, column := range []string{"uploaded_by", "upload_ip", "upload_user_agent"} {
+			alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN %s;`, dbID, column)
+			if _, err := tx.ExecContext(ctx, alter); err != nil {
+				return fmt.Errorf("failed to drop %s column for db %s: %w", column, dbID, err)
+			}
+		}
+	}
+	return nil
+}
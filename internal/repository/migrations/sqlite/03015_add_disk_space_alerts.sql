@@ -0,0 +1,12 @@
+-- Migration: Add Disk Space Alerts
+-- Description: Databases warn before housekeeping starts deleting entries to stay below disk_space.
+--
+-- +goose Up
+-- An alert is sent once usage reaches this percentage of hk_disk_space, 0 disables it
+ALTER TABLE databases ADD COLUMN hk_disk_space_warn_percent INTEGER NOT NULL DEFAULT 85;
+-- Set while usage is above the warning threshold, so the alert is not repeated until it dropped below
+ALTER TABLE databases ADD COLUMN disk_space_alert_active BOOLEAN NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE databases DROP COLUMN disk_space_alert_active;
+ALTER TABLE databases DROP COLUMN hk_disk_space_warn_percent;
@@ -0,0 +1,9 @@
+-- Migration: Add Conversion Rules
+-- Description: Databases can convert uploads per source mime type, e.g. only WAV to FLAC, next to the single auto_conversion target.
+--
+-- +goose Up
+-- JSON array of {"from": "<mime>", "to": "<mime>"}, matched before auto_conversion
+ALTER TABLE databases ADD COLUMN conversion_rules TEXT NOT NULL DEFAULT '[]';
+
+-- +goose Down
+ALTER TABLE databases DROP COLUMN conversion_rules;
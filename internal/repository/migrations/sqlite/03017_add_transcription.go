@@ -0,0 +1,60 @@
+// Migration: Add Transcription
+// Description: Audio databases can send their entries to an external transcription service and store the text in a custom field.
+//
+// Up changes:
+//   - Adds the 'transcription' column to the 'databases' table, the JSON transcription config or empty if disabled.
+//   - Adds the nullable 'transcription_status' column to the dynamic 'entries_{db_id}' tables.
+//     Entries that are not transcribed keep NULL.
+//
+// Down changes:
+//   - Drops the added columns.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03017, down03017)
+}
+
+func up03017(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE databases ADD COLUMN transcription TEXT NOT NULL DEFAULT '';`); err != nil {
+		return fmt.Errorf("failed to add transcription column: %w", err)
+	}
+
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN transcription_status TEXT;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to add transcription_status column for db %s: %w", dbID, err)
+		}
+	}
+
+	return nil
+}
+
+func down03017(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN transcription_status;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to drop transcription_status column for db %s: %w", dbID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE databases DROP COLUMN transcription;`); err != nil {
+		return fmt.Errorf("failed to drop transcription column: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// Migration: Add Full-Text Search
+// Description: TEXT custom fields can be kept in an FTS5 table per database and searched with MATCH.
+//
+// Up changes:
+//   - Adds the 'is_fulltext' flag to the 'database_custom_fields' table, off for all existing fields.
+//   - Adds the nullable 'fulltext_backfill_cursor' column to the 'databases' table, the id up to which
+//     existing entries were copied into the FTS5 table, NULL if nothing is left to copy.
+//
+// The 'entries_{db_id}_fts' tables and their triggers are created by the repository once fields are
+// marked as full-text, existing databases have none until then.
+//
+// Down changes:
+//   - Drops the 'entries_{db_id}_fts' tables and their triggers, and the added columns.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03018, down03018)
+}
+
+func up03018(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE database_custom_fields ADD COLUMN is_fulltext BOOLEAN NOT NULL DEFAULT 0;`); err != nil {
+		return fmt.Errorf("failed to add is_fulltext column: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE databases ADD COLUMN fulltext_backfill_cursor INTEGER;`); err != nil {
+		return fmt.Errorf("failed to add fulltext_backfill_cursor column: %w", err)
+	}
+	return nil
+}
+
+func down03018(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		for _, trigger := range []string{"ai", "ad", "au"} {
+			drop := fmt.Sprintf(`DROP TRIGGER IF EXISTS "entries_%s_fts_%s";`, dbID, trigger)
+			if _, err := tx.ExecContext(ctx, drop); err != nil {
+				return fmt.Errorf("failed to drop full-text trigger for db %s: %w", dbID, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS "entries_%s_fts";`, dbID)); err != nil {
+			return fmt.Errorf("failed to drop full-text table for db %s: %w", dbID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE databases DROP COLUMN fulltext_backfill_cursor;`); err != nil {
+		return fmt.Errorf("failed to drop fulltext_backfill_cursor column: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE database_custom_fields DROP COLUMN is_fulltext;`); err != nil {
+		return fmt.Errorf("failed to drop is_fulltext column: %w", err)
+	}
+	return nil
+}
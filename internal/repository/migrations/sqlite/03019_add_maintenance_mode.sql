@@ -0,0 +1,14 @@
+-- Migration: Add Maintenance Mode
+-- Description: Persists the maintenance mode, so a restart during maintenance stays in maintenance.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS maintenance_mode (
+    id INTEGER PRIMARY KEY CHECK (id = 1), -- a single row, created when maintenance is enabled the first time
+    enabled BOOLEAN NOT NULL DEFAULT 0,
+    message TEXT NOT NULL DEFAULT '',
+
+    since INTEGER NOT NULL DEFAULT 0 -- when maintenance was enabled, 0 while it is off
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS maintenance_mode;
@@ -0,0 +1,9 @@
+-- Migration: Add Public Read Access
+-- Description: Databases can be read without authentication, e.g. to publish a curated collection.
+--
+-- +goose Up
+-- Entries of the database can be listed, searched and downloaded anonymously, writes still need authentication
+ALTER TABLE databases ADD COLUMN public_read BOOLEAN NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE databases DROP COLUMN public_read;
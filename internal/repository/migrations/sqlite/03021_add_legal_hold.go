@@ -0,0 +1,59 @@
+// Migration: Add Legal Hold
+// Description: Entries can be put under legal hold, which preserves them regardless of housekeeping and user deletions.
+//
+// Up changes:
+//   - Adds the 'legal_hold' flag to the dynamic 'entries_{db_id}' tables, off for all existing entries.
+//   - Adds a partial index on the held entries, so deletions and housekeeping find them quickly.
+//
+// Down changes:
+//   - Drops the index and the added column.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03021, down03021)
+}
+
+func up03021(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN legal_hold BOOLEAN NOT NULL DEFAULT 0;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to add legal_hold column for db %s: %w", dbID, err)
+		}
+		index := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_legal_hold" ON "entries_%s"(timestamp) WHERE legal_hold = 1;`, dbID, dbID)
+		if _, err := tx.ExecContext(ctx, index); err != nil {
+			return fmt.Errorf("failed to create legal_hold index for db %s: %w", dbID, err)
+		}
+	}
+
+	return nil
+}
+
+func down03021(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		drop := fmt.Sprintf(`DROP INDEX IF EXISTS "idx_entries_%s_legal_hold";`, dbID)
+		if _, err := tx.ExecContext(ctx, drop); err != nil {
+			return fmt.Errorf("failed to drop legal_hold index for db %s: %w", dbID, err)
+		}
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN legal_hold;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to drop legal_hold column for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Migration: Add Client Timestamps
+// Description: Upload timestamps outside the configured bounds can be replaced by the server time, keeping the original.
+//
+// Up changes:
+//   - Adds the nullable 'client_timestamp' column to the dynamic 'entries_{db_id}' tables, NULL for all existing entries.
+//
+// Down changes:
+//   - Drops the added column.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03022, down03022)
+}
+
+func up03022(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN client_timestamp BIGINT;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to add client_timestamp column for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
+
+func down03022(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN client_timestamp;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to drop client_timestamp column for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
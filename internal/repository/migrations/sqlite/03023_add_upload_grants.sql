@@ -0,0 +1,27 @@
+-- Migration: Add Upload Grants Table
+-- Description: Creates the upload_grants table for single-use, unauthenticated upload tokens handed to field devices.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS upload_grants (
+    id VARCHAR(26) PRIMARY KEY NOT NULL, -- ULID
+    token_hash TEXT UNIQUE NOT NULL, -- SHA-256 hash of the grant token
+    database_id VARCHAR(26) NOT NULL,
+
+    metadata TEXT NOT NULL DEFAULT '', -- JSON template of the upload metadata, its values cannot be overridden
+    max_file_size INTEGER NOT NULL,
+
+    user_id VARCHAR(26) NOT NULL, -- the creator, recorded as the uploading user
+    created_by VARCHAR(64) NOT NULL, -- username of the creator (for auditing)
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    expires_at INTEGER NOT NULL,
+    consumed_at INTEGER, -- set once by the upload, NULL while the grant is unused
+
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_grants_database ON upload_grants(database_id);
+CREATE INDEX IF NOT EXISTS idx_upload_grants_expires_at ON upload_grants(expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS upload_grants;
@@ -0,0 +1,17 @@
+-- Migration: Add Delete Confirmations Table
+-- Description: Creates the delete_confirmations table for the tokens that confirm the deletion of a large database.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS delete_confirmations (
+    database_id VARCHAR(26) PRIMARY KEY NOT NULL, -- one pending confirmation per database, a new one replaces it
+    token_hash TEXT NOT NULL, -- SHA-256 hash of the confirmation token
+
+    created_by VARCHAR(64) NOT NULL, -- username of the requester (for auditing)
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    expires_at INTEGER NOT NULL,
+
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS delete_confirmations;
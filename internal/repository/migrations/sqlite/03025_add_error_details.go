@@ -0,0 +1,49 @@
+// Migration: Add Error Details
+// Description: Failed entries keep the end of the output of the media tool next to their error_reason.
+//
+// Up changes:
+//   - Adds the 'error_detail' text column to the dynamic 'entries_{db_id}' tables, empty for all existing entries.
+//
+// Down changes:
+//   - Drops the added column.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03025, down03025)
+}
+
+func up03025(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN error_detail TEXT NOT NULL DEFAULT '';`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to add error_detail column for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
+
+func down03025(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN error_detail;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to drop error_detail column for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
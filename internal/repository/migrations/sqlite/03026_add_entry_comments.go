@@ -0,0 +1,64 @@
+// Migration: Add Entry Comments
+// Description: Reviewers can leave comments on entries.
+//
+// Up changes:
+//   - Creates the 'entries_{db_id}_comments' table of every database, with an index on the entry and a
+//     trigger on the 'entries_{db_id}' table that deletes the comments of deleted entries.
+//
+// Down changes:
+//   - Drops the comments tables and their triggers.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03026, down03026)
+}
+
+func up03026(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		statements := []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "entries_%s_comments" (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	entry_id INTEGER NOT NULL,
+	author TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_at BIGINT NOT NULL
+);`, dbID),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_comments_entry" ON "entries_%s_comments"(entry_id, id);`, dbID, dbID),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS "entries_%s_comments_ad" AFTER DELETE ON "entries_%s" BEGIN DELETE FROM "entries_%s_comments" WHERE entry_id = old.id; END;`, dbID, dbID, dbID),
+		}
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to create comments table for db %s: %w", dbID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func down03026(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TRIGGER IF EXISTS "entries_%s_comments_ad";`, dbID)); err != nil {
+			return fmt.Errorf("failed to drop comments trigger for db %s: %w", dbID, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS "entries_%s_comments";`, dbID)); err != nil {
+			return fmt.Errorf("failed to drop comments table for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+-- Migration: Add Settings Table
+-- Description: Creates the settings table for values the server records about its installation, e.g. the storage root in use.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY NOT NULL,
+    value TEXT NOT NULL,
+
+    updated_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS settings;
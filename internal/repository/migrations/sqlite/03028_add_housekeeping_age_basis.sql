@@ -0,0 +1,9 @@
+-- Migration: Add Housekeeping Age Basis
+-- Description: The max_age rule of housekeeping can be based on the ingestion time (created_at) instead of the capture time (timestamp).
+--
+-- +goose Up
+-- 'capture' compares the client supplied timestamp, 'ingestion' the server side created_at
+ALTER TABLE databases ADD COLUMN hk_age_basis TEXT NOT NULL DEFAULT 'capture';
+
+-- +goose Down
+ALTER TABLE databases DROP COLUMN hk_age_basis;
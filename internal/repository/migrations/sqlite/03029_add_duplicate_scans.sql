@@ -0,0 +1,29 @@
+-- Migration: Add Duplicate Scans Table
+-- Description: Creates the duplicate_scans table for the background jobs reporting entries with identical files. The progress is stored, so a scan continues after a restart.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS duplicate_scans (
+    id VARCHAR(26) PRIMARY KEY NOT NULL, -- ULID
+    database_id VARCHAR(26) NOT NULL,
+    status TEXT NOT NULL, -- running, done or failed
+
+    max_entry_id INTEGER NOT NULL, -- entries created after the start are not scanned
+    last_entry_id INTEGER NOT NULL DEFAULT 0, -- all entries up to this ID were hashed
+    total INTEGER NOT NULL DEFAULT 0, -- ready entries up to max_entry_id at the start
+    processed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0, -- entries whose file could not be read
+    error TEXT NOT NULL DEFAULT '',
+
+    created_by VARCHAR(64) NOT NULL, -- username of the creator (for auditing)
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    updated_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    finished_at INTEGER, -- NULL while the scan is running
+
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);
+
+-- A single scan may run per database
+CREATE UNIQUE INDEX IF NOT EXISTS idx_duplicate_scans_running ON duplicate_scans(database_id) WHERE status = 'running';
+
+-- +goose Down
+DROP TABLE IF EXISTS duplicate_scans;
@@ -0,0 +1,8 @@
+-- Migration: Add Audit Log Resource Index
+-- Description: Indexes the audit logs by resource, so the activity feed of a database reads only the events of the database and its entries.
+--
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_audit_logs_resource ON audit_logs(resource, timestamp);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_audit_logs_resource;
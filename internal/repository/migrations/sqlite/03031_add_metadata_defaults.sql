@@ -0,0 +1,11 @@
+-- Migration: Add Upload Metadata Defaults
+-- Description: Databases can fill custom fields missing from the upload metadata, or force their values.
+--
+-- +goose Up
+-- JSON objects of custom field name to value, defaults apply to absent fields, overrides to all uploads
+ALTER TABLE databases ADD COLUMN metadata_defaults TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE databases ADD COLUMN metadata_overrides TEXT NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE databases DROP COLUMN metadata_overrides;
+ALTER TABLE databases DROP COLUMN metadata_defaults;
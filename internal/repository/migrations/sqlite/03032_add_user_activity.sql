@@ -0,0 +1,14 @@
+-- Migration: Add User Activity
+-- Description: Records when users last logged in and used the API, and lets admins disable accounts.
+--
+-- +goose Up
+-- UNIX epoch in milliseconds, NULL if never
+ALTER TABLE users ADD COLUMN last_login_at INTEGER;
+ALTER TABLE users ADD COLUMN last_activity_at INTEGER;
+-- Disabled users are refused by every login method, but keep their data and permissions
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN disabled;
+ALTER TABLE users DROP COLUMN last_activity_at;
+ALTER TABLE users DROP COLUMN last_login_at;
@@ -0,0 +1,15 @@
+-- Migration: Add Waveform Style
+-- Description: Audio databases can set the size and colors of their waveform previews.
+--
+-- +goose Up
+-- 0 and empty strings select the defaults, an empty background keeps the WebP previews drawn so far
+ALTER TABLE databases ADD COLUMN waveform_width INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE databases ADD COLUMN waveform_height INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE databases ADD COLUMN waveform_color TEXT NOT NULL DEFAULT '';
+ALTER TABLE databases ADD COLUMN waveform_background TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE databases DROP COLUMN waveform_background;
+ALTER TABLE databases DROP COLUMN waveform_color;
+ALTER TABLE databases DROP COLUMN waveform_height;
+ALTER TABLE databases DROP COLUMN waveform_width;
@@ -0,0 +1,30 @@
+-- Migration: Add Jobs Table
+-- Description: Creates the jobs table for background operations on a database, such as asynchronous bulk deletions. The input and progress are stored, so a job continues after a restart.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS jobs (
+    id VARCHAR(26) PRIMARY KEY NOT NULL, -- ULID
+    type TEXT NOT NULL, -- e.g. delete_entries
+    database_id VARCHAR(26) NOT NULL,
+    status TEXT NOT NULL, -- running, done or failed
+
+    payload TEXT NOT NULL DEFAULT '', -- JSON input of the job type, e.g. the entry IDs to delete
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0, -- items of the payload handled so far, in order
+    failed INTEGER NOT NULL DEFAULT 0,
+    counters TEXT NOT NULL DEFAULT '{}', -- JSON object of results specific to the job type, e.g. bytes_freed
+    error TEXT NOT NULL DEFAULT '',
+
+    created_by VARCHAR(64) NOT NULL, -- username of the creator (for auditing)
+    created_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    updated_at INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER)),
+    finished_at INTEGER, -- NULL while the job is running
+
+    FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);
+
+-- Running jobs are resumed on startup
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+
+-- +goose Down
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,9 @@
+-- Migration: Add Geo Fields
+-- Description: Databases can declare the REAL custom fields holding the coordinates of their entries.
+--
+-- +goose Up
+-- JSON like {"lat": "lat", "lon": "lon"}, empty if the database declares no geo fields
+ALTER TABLE databases ADD COLUMN geo_fields TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE databases DROP COLUMN geo_fields;
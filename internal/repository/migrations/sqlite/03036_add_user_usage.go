@@ -0,0 +1,85 @@
+// Migration: Add User Usage
+// Description: Entries and bytes stored per uploader and database are aggregated for the accounting of
+// departments, and users can get a storage quota.
+//
+// Up changes:
+//   - Adds the 'storage_quota_bytes' column to 'users', 0 is unlimited.
+//   - Creates the 'user_usage' summary table, the entries and bytes per uploader, database and UTC day of upload.
+//   - Creates the 'user_usage_changes' table, the changes of the usage since housekeeping last folded them into
+//     the summary.
+//   - Fills the summary from the existing entries that record their uploader.
+//
+// Down changes:
+//   - Drops the tables and the column.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03036, down03036)
+}
+
+func up03036(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`ALTER TABLE users ADD COLUMN storage_quota_bytes INTEGER NOT NULL DEFAULT 0;`,
+		`CREATE TABLE IF NOT EXISTS user_usage (
+	username TEXT NOT NULL,
+	database_id VARCHAR(26) NOT NULL,
+	day INTEGER NOT NULL, -- start of the UTC day of the upload, in unix milliseconds
+	entries INTEGER NOT NULL DEFAULT 0,
+	bytes INTEGER NOT NULL DEFAULT 0, -- files, previews and kept originals
+	PRIMARY KEY (username, database_id, day),
+	FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);`,
+		`CREATE TABLE IF NOT EXISTS user_usage_changes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL,
+	database_id VARCHAR(26) NOT NULL,
+	day INTEGER NOT NULL,
+	entries INTEGER NOT NULL, -- negative for deletions
+	bytes INTEGER NOT NULL,
+	FOREIGN KEY (database_id) REFERENCES databases(id) ON DELETE CASCADE
+);`,
+		// The stored bytes of an uploader are read on every upload for the quota
+		`CREATE INDEX IF NOT EXISTS idx_user_usage_changes_username ON user_usage_changes(username);`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create user usage tables: %w", err)
+		}
+	}
+
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		fill := fmt.Sprintf(`INSERT INTO user_usage (username, database_id, day, entries, bytes)
+SELECT uploaded_by, ?, (created_at / 86400000) * 86400000, COUNT(*), SUM(filesize + preview_filesize + original_filesize)
+FROM "entries_%s" WHERE uploaded_by IS NOT NULL GROUP BY uploaded_by, (created_at / 86400000);`, dbID)
+		if _, err := tx.ExecContext(ctx, fill, dbID); err != nil {
+			return fmt.Errorf("failed to fill user usage for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
+
+func down03036(ctx context.Context, tx *sql.Tx) error {
+	statements := []string{
+		`DROP TABLE IF EXISTS user_usage_changes;`,
+		`DROP TABLE IF EXISTS user_usage;`,
+		`ALTER TABLE users DROP COLUMN storage_quota_bytes;`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to drop user usage tables: %w", err)
+		}
+	}
+	return nil
+}
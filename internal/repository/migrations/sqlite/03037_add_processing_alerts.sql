@@ -0,0 +1,9 @@
+-- Migration: Add Processing Alerts
+-- Description: Databases warn when entries have been processing for longer than the configured age.
+--
+-- +goose Up
+-- Set while the oldest processing entry is older than the alert age, so the alert is not repeated until the backlog cleared
+ALTER TABLE databases ADD COLUMN processing_alert_active BOOLEAN NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE databases DROP COLUMN processing_alert_active;
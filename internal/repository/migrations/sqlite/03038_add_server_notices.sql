@@ -0,0 +1,27 @@
+-- Migration: Add Server Notices Table
+-- Description: Creates the server_notices table for the warnings and errors of background work (housekeeping, processing workers, startup), shown to administrators until they acknowledge them.
+--
+-- +goose Up
+CREATE TABLE IF NOT EXISTS server_notices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    dedup_key TEXT NOT NULL, -- repetitions of an unacknowledged notice with the same key increment its count
+    source TEXT NOT NULL, -- e.g. housekeeping, processing, startup
+    severity TEXT NOT NULL, -- warning or error
+    message TEXT NOT NULL, -- of the latest repetition
+    database_id VARCHAR(26), -- optional, kept after the database is deleted
+    entry_id INTEGER, -- optional, of the latest repetition
+
+    count INTEGER NOT NULL DEFAULT 1,
+    first_seen INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    acknowledged_at INTEGER, -- NULL until an administrator acknowledges the notice
+    acknowledged_by VARCHAR(64)
+);
+
+-- At most one open notice per key, a repetition after the acknowledgement opens a new one
+CREATE UNIQUE INDEX IF NOT EXISTS idx_server_notices_open_key ON server_notices(dedup_key) WHERE acknowledged_at IS NULL;
+-- Pruned by the time of their latest repetition
+CREATE INDEX IF NOT EXISTS idx_server_notices_last_seen ON server_notices(last_seen);
+
+-- +goose Down
+DROP TABLE IF EXISTS server_notices;
@@ -0,0 +1,49 @@
+// Migration: Add Content Revision
+// Description: Entries count the writes of their stored file, so clients can tell a replaced or re-converted file apart.
+//
+// Up changes:
+//   - Adds the 'content_revision' integer column to the dynamic 'entries_{db_id}' tables, 0 for all existing entries.
+//
+// Down changes:
+//   - Drops the added column.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03039, down03039)
+}
+
+func up03039(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" ADD COLUMN content_revision INTEGER NOT NULL DEFAULT 0;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to add content_revision column for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
+
+func down03039(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		alter := fmt.Sprintf(`ALTER TABLE "entries_%s" DROP COLUMN content_revision;`, dbID)
+		if _, err := tx.ExecContext(ctx, alter); err != nil {
+			return fmt.Errorf("failed to drop content_revision column for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
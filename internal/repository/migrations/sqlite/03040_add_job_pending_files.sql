@@ -0,0 +1,12 @@
+-- Migration: Add Job Pending Files
+-- Description: Deletion jobs record the files of a batch in the transaction deleting its rows, so a resumed job removes them, and finished jobs expire.
+--
+-- +goose Up
+-- JSON array of the deleted entries whose files have not been removed yet
+ALTER TABLE jobs ADD COLUMN pending_files TEXT NOT NULL DEFAULT '[]';
+-- Unix milliseconds after which housekeeping deletes a finished job, NULL while the job is running
+ALTER TABLE jobs ADD COLUMN expires_at INTEGER;
+
+-- +goose Down
+ALTER TABLE jobs DROP COLUMN expires_at;
+ALTER TABLE jobs DROP COLUMN pending_files;
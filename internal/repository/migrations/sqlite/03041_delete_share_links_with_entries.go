@@ -0,0 +1,54 @@
+// Migration: Delete Share Links With Entries
+// Description: Share links no longer outlive the entry they belong to.
+//
+// Up changes:
+//   - Deletes the share links of entries that no longer exist.
+//   - Creates a trigger on every 'entries_{db_id}' table that deletes the share links of deleted entries.
+//
+// Down changes:
+//   - Drops the triggers.
+package sqlitemigrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(up03041, down03041)
+}
+
+func up03041(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		statements := []string{
+			fmt.Sprintf(`DELETE FROM share_links WHERE database_id = '%s' AND entry_id NOT IN (SELECT id FROM "entries_%s");`, dbID, dbID),
+			fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS "entries_%s_share_links_ad" AFTER DELETE ON "entries_%s" BEGIN DELETE FROM share_links WHERE database_id = '%s' AND entry_id = old.id; END;`, dbID, dbID, dbID),
+		}
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to create share links trigger for db %s: %w", dbID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func down03041(ctx context.Context, tx *sql.Tx) error {
+	dbIDs, err := queryDatabaseIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, dbID := range dbIDs {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TRIGGER IF EXISTS "entries_%s_share_links_ad";`, dbID)); err != nil {
+			return fmt.Errorf("failed to drop share links trigger for db %s: %w", dbID, err)
+		}
+	}
+	return nil
+}
@@ -19,46 +19,257 @@ type Database struct {
 	Housekeeping DatabaseHK
 	CustomFields []CustomFieldDef
 	Stats        DatabaseStats
+
+	// Composite indexes of the entry table, each a list of standard, media or custom field names
+	CustomIndexes [][]string
 }
 
 type DatabaseConfig struct {
-	CreatePreview  bool
-	AutoConversion string
+	CreatePreview    bool
+	AutoConversion   string
+	KeepOriginal     bool // store the original of converted uploads next to the converted file
+	UniqueExternalID bool // reject entries whose external ID is already used in the database
+	PublicRead       bool // entries can be listed, searched and downloaded without authentication
+
+	// Conversions of single source mime types, they take precedence over AutoConversion
+	ConversionRules []ConversionRule
+
+	// Transcription of audio entries by an external service, disabled if the endpoint is empty
+	Transcription TranscriptionConfig
+
+	// Custom field values of uploads: defaults fill fields missing from the metadata, overrides replace
+	// the values of the client. Both are keyed by the field name and never apply to updates.
+	MetadataDefaults  map[string]any
+	MetadataOverrides map[string]any
+
+	// Look of the waveform previews of audio entries, zero values select the defaults
+	Waveform WaveformConfig
+
+	// Custom fields holding the coordinates of the entries, for geo searches
+	GeoFields GeoFields
+}
+
+// ConversionRule converts uploads of one mime type to another.
+type ConversionRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// WaveformConfig is the size and colors of waveform previews. The colors are hex like "#1e90ff",
+// the background may also be "transparent", which stores the previews as PNG instead of WebP.
+type WaveformConfig struct {
+	Width      int
+	Height     int
+	Color      string
+	Background string
+}
+
+// TranscriptionConfig sends ready audio entries to an OpenAI-compatible transcription service
+// (POST /v1/audio/transcriptions) and stores the returned text in a TEXT custom field.
+type TranscriptionConfig struct {
+	Endpoint string `json:"endpoint"`           // base URL of the service, e.g. "http://whisper:8000"
+	Model    string `json:"model"`              // model name sent with each request
+	Field    string `json:"field"`              // name of the TEXT custom field receiving the text
+	Language string `json:"language,omitempty"` // optional language hint, e.g. "en"
+}
+
+// Enabled reports whether transcription is configured.
+func (c TranscriptionConfig) Enabled() bool {
+	return c.Endpoint != "" && c.Field != ""
 }
 
+// DefaultDiskSpaceWarnPercent is the disk space warning threshold of databases that do not set one.
+const DefaultDiskSpaceWarnPercent = 85
+
 // Struct for housekeeping settings
 type DatabaseHK struct {
 	Interval  time.Duration
 	DiskSpace uint64
 	MaxAge    time.Duration
 	LastHkRun time.Time // timestamp of the last housekeeping run, used to determine when the next run should occur
+
+	DiskSpaceWarnPercent int // an alert is sent once the usage reaches this percentage of DiskSpace, 0 disables it
+
+	AgeBasis string // the time MaxAge is measured from, AgeBasisCapture (default) or AgeBasisIngestion
 }
 
+// Time bases of the housekeeping MaxAge rule.
+const (
+	AgeBasisCapture   = "capture"   // the timestamp of the entry, supplied by the client
+	AgeBasisIngestion = "ingestion" // created_at, the time the server stored the entry
+)
+
+// AgeField returns the entry column the MaxAge rule compares with.
+func (hk DatabaseHK) AgeField() string {
+	if hk.AgeBasis == AgeBasisIngestion {
+		return "created_at"
+	}
+	return "timestamp"
+}
+
+// DatabaseStats are the statistics of a database. EntryCount counts every entry row from the moment it is
+// committed, including queued, processing and failed entries, until the row is deleted. TotalDiskSpaceBytes
+// is the sum of the sizes recorded on the rows, processing adds them once it stored the files.
 type DatabaseStats struct {
 	EntryCount          uint64
 	TotalDiskSpaceBytes uint64
+	ProcessingCount     uint64 // queued or processing entries, part of EntryCount, read live by GetDatabase, GetDatabases and GetDatabaseStats
+	DiskSpaceAlert      bool   // usage reached the warning threshold and the alert was sent, reset once it drops below
+}
+
+// ErrorReasonCorrupted is the error reason of entries whose stored file no longer matches its content hash.
+const ErrorReasonCorrupted = "corrupted"
+
+// IntegrityStats summarizes the integrity checks of the stored files of a database.
+type IntegrityStats struct {
+	Verified           uint64    // ready entries that were verified at least once
+	Unverified         uint64    // ready entries that were never verified
+	Corrupted          uint64    // entries flagged as corrupted
+	OldestVerification time.Time // least recent check of a verified entry, zero if none was verified
+}
+
+// Statuses of a duplicate scan.
+const (
+	DuplicateScanRunning = "running"
+	DuplicateScanDone    = "done"
+	DuplicateScanFailed  = "failed"
+)
+
+// DuplicateScan is a background job hashing the files of a database to find entries with identical
+// content. Its progress is stored, so an interrupted scan continues where it stopped.
+type DuplicateScan struct {
+	ID          ULID
+	DatabaseID  ULID
+	Status      string
+	MaxEntryID  int64 // entries created after the start are not scanned
+	LastEntryID int64 // all entries up to this ID were hashed
+	Total       int64 // ready entries up to MaxEntryID at the start
+	Processed   int64
+	Failed      int64  // entries whose file could not be read, they are not part of any group
+	Error       string // why a failed scan stopped
+	CreatedBy   string // username of the creator
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	FinishedAt  time.Time // zero while the scan is running
+}
+
+// Types of background jobs.
+const (
+	JobTypeDeleteEntries = "delete_entries" // deletes the entry IDs of the payload, a JSON array
+)
+
+// Statuses of a background job.
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job is a background operation on a database. Its input and progress are stored, so an interrupted
+// job continues where it stopped.
+type Job struct {
+	ID         ULID
+	Type       string // one of the JobType constants
+	DatabaseID ULID
+	Status     string
+	Payload    string           // JSON input of the job type
+	Total      int64            // items of the payload
+	Processed  int64            // items handled so far, in the order of the payload
+	Failed     int64            // handled items the job could not complete
+	Counters   map[string]int64 // results specific to the job type, e.g. bytes_freed
+	Error      string           // why a failed job stopped
+	CreatedBy  string           // username of the creator
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt time.Time // zero while the job is running
+	ExpiresAt  time.Time // when housekeeping deletes the finished job, zero while it is running
+
+	// Entries whose rows the job deleted but whose files are not removed yet
+	PendingFiles []DeletedEntryMeta
+}
+
+// JobProgress returns a job with the outcome of a batch applied, given the entries whose rows were deleted
+// and the IDs that were skipped. DeleteSettledEntries stores it in the transaction that deletes the rows.
+type JobProgress func(deleted []DeletedEntryMeta, skipped []int64) Job
+
+// DuplicateGroup lists the ready entries sharing a content hash, oldest first.
+type DuplicateGroup struct {
+	ContentHash string
+	Entries     []Entry
+}
+
+// FileStats describes the space used by the metadata database file. Pages freed by deletions
+// stay in the file on the freelist until they are reused or vacuumed.
+type FileStats struct {
+	SizeBytes     uint64 // page count times page size
+	PageSize      uint64
+	PageCount     uint64
+	FreelistPages uint64
+	AutoVacuum    string // "none", "full" or "incremental"
+}
+
+// EntryTableReconciliation describes how the entry table of a database differs from the table the current
+// code creates for its content type and custom fields, see ReconcileEntryTables.
+type EntryTableReconciliation struct {
+	DatabaseID   ULID
+	DatabaseName string
+	Differences  []string // e.g. a changed column definition or a CHECK constraint that is no longer created
+	Rebuilt      bool
+	Error        string // why the table could not be rebuilt, empty otherwise
 }
 
 // CustomFieldDef defines a custom metadata field for a database.
 type CustomFieldDef struct {
-	ID        int
-	Name      string
-	Type      string
-	IsIndexed bool
+	ID          int
+	Name        string
+	Type        string
+	IsIndexed   bool
+	IsSensitive bool
+	IsFulltext  bool // TEXT fields only, kept in the FTS5 table of the database and searchable with MATCH
 }
 
 type Entry struct {
-	ID           int64
-	FileName     string
-	Size         uint64
-	PreviewSize  uint64
-	Timestamp    time.Time // The zero value (time.Time{}) indicates a missing timestamp
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	MimeType     string
-	Status       EntryStatus    // "processing" 0x01 or "ready" 0x00 for now
-	MediaFields  map[string]any // contains fields that are related to the filetype, e.g., image size
-	CustomFields map[string]any
+	ID               int64
+	ExternalID       string // identifier assigned by the client, empty if none
+	FileName         string
+	Size             uint64
+	PreviewSize      uint64
+	Timestamp        time.Time // The zero value (time.Time{}) indicates a missing timestamp
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	MimeType         string
+	Status           EntryStatus // "processing" 0x01 or "ready" 0x00 for now
+	ErrorReason      string      // why processing failed, empty if it did not
+	ErrorDetail      string      // end of the stderr of the failed media tool, empty if there is none
+	OriginalSize     uint64      // size of the kept original of a converted upload, 0 if none was kept
+	OriginalMimeType string
+	ContentHash      string         // hex SHA-256 of the stored file, recorded on its first integrity check
+	ContentRevision  int64          // counts the writes of the stored file, see EntryTechMetadata.FileWritten
+	LastVerifiedAt   time.Time      // last integrity check of the stored file, zero if never verified
+	Origin           UploadOrigin   // who uploaded the entry and from where, empty for entries from before it was recorded
+	Transcription    string         // transcription status (pending, done or failed), empty if the entry is not transcribed
+	LegalHold        bool           // preserved for compliance, neither users nor housekeeping may delete it
+	ClientTimestamp  time.Time      // timestamp sent by the client if it was out of bounds and replaced by Timestamp, zero otherwise
+	MediaFields      map[string]any // contains fields that are related to the filetype, e.g., image size
+	CustomFields     map[string]any
+}
+
+// EntryTechMetadata is what processing found out about the stored files of an entry, see UpdateEntryTechMetadata.
+type EntryTechMetadata struct {
+	Size             uint64
+	PreviewSize      uint64
+	OriginalSize     uint64
+	MimeType         string
+	OriginalMimeType string
+	MediaFields      map[string]any // only media fields of the content type of the database
+	FileWritten      bool           // the stored file was (re)written, its content revision is bumped and its hash cleared
+}
+
+// UploadOrigin describes where an entry was uploaded from. Empty values are stored as NULL.
+type UploadOrigin struct {
+	UploadedBy string // username of the uploader
+	ClientIP   string // the client address, resolved through trusted proxies
+	UserAgent  string
 }
 
 type User struct {
@@ -67,6 +278,10 @@ type User struct {
 	IsAdmin          bool
 	PasswordHash     string
 	IsServiceAccount bool
+	Disabled         bool      // every login is refused
+	LastLoginAt      time.Time // Uses time.Time{} for never logged in
+	LastActivityAt   time.Time // Uses time.Time{} for never active, updated at most once per activity interval
+	StorageQuota     uint64    // bytes the entries uploaded by the user may take in all databases, 0 for unlimited
 }
 
 type APIKey struct {
@@ -81,6 +296,142 @@ type APIKey struct {
 	LastUsedAt time.Time // Uses time.Time{} for never used
 }
 
+// Group bundles global roles that apply to all of its members on every database, and an admin flag.
+// A user's effective permissions are the OR of their own and all of their groups' permissions.
+type Group struct {
+	ID      ULID
+	Name    string
+	Roles   AccessGrant // view, create, edit and delete on every database
+	IsAdmin bool
+}
+
+// ShareLink grants unauthenticated, expiring access to a single entry.
+// Only the SHA-256 hash of the token is stored.
+type ShareLink struct {
+	ID            ULID
+	TokenHash     string
+	DatabaseID    ULID
+	EntryID       int64
+	PreviewOnly   bool
+	MaxDownloads  int64 // 0 means unlimited
+	DownloadCount int64
+	CreatedBy     string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+}
+
+// Comment is a note left by a reviewer on an entry. Comments are stored per database and removed
+// with their entry.
+type Comment struct {
+	ID        int64
+	EntryID   int64
+	Author    string // username of the author
+	Body      string
+	CreatedAt time.Time
+}
+
+// UploadGrant allows a single unauthenticated upload into a database, e.g. by a field device that
+// should not hold credentials. Only the SHA-256 hash of the token is stored.
+type UploadGrant struct {
+	ID          ULID
+	TokenHash   string
+	DatabaseID  ULID
+	Metadata    string // JSON template of the upload metadata, empty if there is none
+	MaxFileSize int64
+	UserID      ULID   // the creator, whose rights the upload uses
+	CreatedBy   string // username of the creator
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	ConsumedAt  time.Time // zero while the grant is unused
+}
+
+// DeleteConfirmation is the pending, second step of the deletion of a large database.
+// Only the SHA-256 hash of the token is stored.
+type DeleteConfirmation struct {
+	DatabaseID ULID
+	TokenHash  string
+	CreatedBy  string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// UploadGrantFilter narrows the listed upload grants, empty fields match all.
+type UploadGrantFilter struct {
+	DatabaseID ULID
+	UserID     ULID
+}
+
+// IdempotencyKey remembers the outcome of an upload sent with an Idempotency-Key header.
+// Keys are scoped per database and user. A StatusCode of 0 marks a request that is still in progress, its
+// ExpiresAt is a short lease the request renews while it runs, so the key of a crashed request is reclaimed soon.
+type IdempotencyKey struct {
+	DatabaseID ULID
+	UserID     ULID
+	Key        string
+	EntryID    int64
+	StatusCode int
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// JWTSecret is a secret for signing JWTs shared by all instances through the database.
+// The current secret has a zero RetiresAt, a rotated one is still accepted until RetiresAt.
+type JWTSecret struct {
+	ID        int64
+	Secret    string
+	CreatedAt time.Time
+	RetiresAt time.Time
+}
+
+// MaintenanceMode is the persisted maintenance state, so a restart during maintenance stays in maintenance.
+// Since is the zero time while maintenance is off.
+type MaintenanceMode struct {
+	Enabled bool
+	Message string // returned to the rejected write requests
+	Since   time.Time
+}
+
+// SettingStorageRoot is the setting holding the absolute storage root the entries are stored in,
+// recorded on the first start so a server started with another root is noticed.
+const SettingStorageRoot = "storage_root"
+
+// RetainedUpload is the source file of a failed asynchronous upload, kept for a grace period so
+// the entry can be retried. The file lives on the local disk of the instance that processed it.
+type RetainedUpload struct {
+	DatabaseID ULID
+	EntryID    int64
+	Path       string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// Task types of the post-processing steps that are persisted as pending tasks
+const (
+	TaskTypePreview          = "preview"
+	TaskTypeTranscription    = "transcription"
+	TaskTypeFulltextBackfill = "fulltext_backfill" // database wide, the EntryID is 0
+)
+
+// Transcription statuses of audio entries
+const (
+	TranscriptionPending = "pending"
+	TranscriptionDone    = "done"
+	TranscriptionFailed  = "failed"
+)
+
+// PendingTask is a post-processing step of an entry that has to survive restarts.
+// The row is removed once the task is done.
+type PendingTask struct {
+	ID            int64
+	DatabaseID    ULID
+	EntryID       int64
+	Type          string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
+
 // defines a role that a user has in a specific database (CanView, CanCreate, CanEdit, CanDelete)
 type UserPermissions struct {
 	UserID     ULID
@@ -99,6 +450,9 @@ type SearchRequest struct {
 	Filter     *FilterGroup
 	Sort       *SortCriteria
 	Pagination Pagination
+	Fields     []string   // only select these fields (the id is always included), all if empty
+	Now        time.Time  // relative times in the filter are evaluated at it, the current time if zero
+	Geo        *GeoFilter // only entries within the area, combined with the filter by "and"
 }
 
 // FilterGroup allows chaining multiple conditions together.
@@ -110,21 +464,74 @@ type FilterGroup struct {
 // Condition represents a single query filter.
 type Condition struct {
 	Field    string
-	Operator string // e.g., "=", ">", "<", "LIKE"
-	Value    any    // 'any' allows for strings, numbers, or booleans
+	Operator string // e.g., "=", ">", "<", "LIKE", "starts_with", "MATCH" on full-text fields
+	Value    any    // 'any' allows for strings, numbers, or booleans; relative times like "now-24h" on TimestampFields
 }
 
 // SortCriteria defines how the results should be ordered.
 type SortCriteria struct {
-	Field     string
+	Field     string // or SortFieldFulltextRank or SortFieldGeoDistance
 	Direction string // "asc" or "desc"
+	Collation string // CollationBinary (if empty), CollationNoCase or CollationUnicode, only for TEXT fields
+}
+
+// QueryPlan is the query of a search and the plan of the database for it.
+type QueryPlan struct {
+	SQL   string
+	Args  []any
+	Steps []QueryPlanStep
+}
+
+// QueryPlanStep is a row of EXPLAIN QUERY PLAN, e.g. "SEARCH entries USING INDEX ... (status=?)".
+// Steps form a tree by their ID and the ID of their parent, 0 for the top level.
+type QueryPlanStep struct {
+	ID     int
+	Parent int
+	Detail string
 }
 
 // returned upon deleting an entry from the database
 type DeletedEntryMeta struct {
+	ID           int64
+	Filesize     uint64
+	PreviewSize  uint64
+	OriginalSize uint64
+}
+
+// EntryVersion holds the columns of an entry that tell a client whether its copy of the files is current.
+type EntryVersion struct {
+	ID          int64
+	Size        uint64
+	PreviewSize uint64
+	MimeType    string
+	Status      EntryStatus
+	UpdatedAt   time.Time
+}
+
+// LargestEntry is a row of the storage report's ranking of the largest files across all databases.
+type LargestEntry struct {
+	DatabaseID  ULID
 	ID          int64
-	Filesize    uint64
+	FileName    string
+	Size        uint64
 	PreviewSize uint64
+	MimeType    string
+}
+
+// ProcessingBacklog is the backlog of a database, its entries still being processed.
+type ProcessingBacklog struct {
+	DatabaseID   ULID
+	DatabaseName string
+	Count        int64
+	OldestSince  time.Time // upload time of the oldest processing entry
+	SampleIDs    []int64   // the IDs of the oldest processing entries, up to the sample size of the filter
+	AlertActive  bool      // the processing alert was sent and the backlog has not cleared since
+}
+
+// BacklogFilter selects the processing entries of GetProcessingBacklog.
+type BacklogFilter struct {
+	UploadedBefore time.Time // only entries uploaded before, zero for all
+	SampleSize     int       // entry IDs listed per database, 0 for none
 }
 
 type AuditLog struct {
@@ -0,0 +1,32 @@
+package repository
+
+import "time"
+
+// Severities of server notices.
+const (
+	NoticeSeverityWarning = "warning"
+	NoticeSeverityError   = "error"
+)
+
+// ServerNotice is a warning or error of background work for administrators. Repetitions with the same
+// DedupKey update the open notice instead of adding one, until it is acknowledged.
+type ServerNotice struct {
+	ID             int64
+	DedupKey       string
+	Source         string // e.g. "housekeeping", "processing", "startup"
+	Severity       string // NoticeSeverityWarning or NoticeSeverityError
+	Message        string // of the latest repetition
+	DatabaseID     ULID   // optional
+	EntryID        int64  // optional, 0 if none
+	Count          int64
+	FirstSeen      time.Time
+	LastSeen       time.Time
+	AcknowledgedAt time.Time // zero while unacknowledged
+	AcknowledgedBy string
+}
+
+// NoticeFilter selects the notices returned by GetNotices, latest repetition first.
+type NoticeFilter struct {
+	Unacknowledged bool // only the open notices
+	Limit          int  // 0 for all
+}
@@ -55,6 +55,18 @@ func (r PostgresRepository) GetDatabaseStats(ctx context.Context, dbID repo.ULID
 	return repo.DatabaseStats{}, customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) GetEntryTimeRange(ctx context.Context, dbID repo.ULID) (time.Time, time.Time, error) {
+	return time.Time{}, time.Time{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CreateDeleteConfirmation(ctx context.Context, confirmation repo.DeleteConfirmation) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ConsumeDeleteConfirmation(ctx context.Context, dbID repo.ULID, tokenHash string) (bool, error) {
+	return false, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) HouseKeepingRequired(ctx context.Context) ([]repo.Database, error) {
 	return nil, customerrors.ErrNotImplemented
 }
@@ -63,6 +75,23 @@ func (r PostgresRepository) HouseKeepingWasCalled(ctx context.Context, dbID repo
 	return time.Time{}, customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) SetDiskSpaceAlert(ctx context.Context, dbID repo.ULID, active bool) (bool, error) {
+	// CONSIDERATION: UPDATE databases SET disk_space_alert_active = $1 WHERE id = $2 AND disk_space_alert_active <> $1;
+	// the state changed if a row was affected.
+	return false, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) SetProcessingAlert(ctx context.Context, dbID repo.ULID, active bool) (bool, error) {
+	// CONSIDERATION: Same as SetDiskSpaceAlert on processing_alert_active.
+	return false, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteExpiredRows(ctx context.Context, table, expiryColumn string, batchSize int) (int64, error) {
+	// CONSIDERATION: DELETE FROM table WHERE ctid IN (SELECT ctid FROM table WHERE expiry < $1 LIMIT $2), repeated
+	// until fewer than batchSize rows were deleted.
+	return 0, customerrors.ErrNotImplemented
+}
+
 // Entry
 func (r PostgresRepository) CreateEntry(ctx context.Context, db repo.Database, entry repo.Entry) (repo.Entry, error) {
 	// TRANSACTION REQUIRED:
@@ -74,24 +103,39 @@ func (r PostgresRepository) CreateEntry(ctx context.Context, db repo.Database, e
 	return repo.Entry{}, customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) CreateEntryWithTasks(ctx context.Context, db repo.Database, entry repo.Entry, taskTypes []string, lease time.Duration) (repo.Entry, []repo.PendingTask, error) {
+	// Same transaction as CreateEntry, additionally inserting one pending_tasks row per task type
+	return repo.Entry{}, nil, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) GetEntry(ctx context.Context, dbID repo.ULID, id int64) (repo.Entry, error) {
 	return repo.Entry{}, customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) GetEntryByExternalID(ctx context.Context, dbID repo.ULID, externalID string) (repo.Entry, error) {
+	return repo.Entry{}, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) GetEntries(ctx context.Context, dbID repo.ULID, opts repo.QueryOptions) ([]repo.Entry, error) {
 	return nil, customerrors.ErrNotImplemented
 }
 
-func (r PostgresRepository) UpdateEntry(ctx context.Context, dbID repo.ULID, entry repo.Entry) (repo.Entry, error) {
+func (r PostgresRepository) UpdateEntryStatus(ctx context.Context, dbID repo.ULID, entryID int64, status repo.EntryStatus, reason, detail string) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) UpdateEntryTechMetadata(ctx context.Context, dbID repo.ULID, entryID int64, meta repo.EntryTechMetadata) error {
 	// TRANSACTION REQUIRED:
 	// 1. Begin SQL Transaction.
-	// 2. Query the current size (filesize + previewsize) of the entry *before* updating.
-	// 3. Calculate the delta: Delta = (NewFileSize + NewPreviewSize) - (OldFileSize + OldPreviewSize).
-	// 4. Update the entry row with new data (update the updated_at timestamp).
-	// 5. Atomically apply the delta to the main database stats:
-	//    UPDATE databases SET total_disk_space_bytes = total_disk_space_bytes + $delta WHERE name = $name;
-	// 6. Commit Transaction.
-	// an entry with timestamp math.MinInt64 should create one with current server time
+	// 2. Query the current sizes (filesize + preview_filesize + original_filesize) of the entry *before* updating.
+	// 3. Update the sizes, mime types and media fields (update the updated_at timestamp).
+	// 4. Atomically apply the delta of the sizes to the main database stats:
+	//    UPDATE databases SET total_disk_space_bytes = total_disk_space_bytes + $delta WHERE id = $id;
+	// 5. Commit Transaction.
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) UpdateEntryUserFields(ctx context.Context, dbID repo.ULID, entryID int64, fields map[string]any) (repo.Entry, error) {
 	return repo.Entry{}, customerrors.ErrNotImplemented
 }
 
@@ -100,6 +144,12 @@ func (r PostgresRepository) UpdateEntriesStatus(ctx context.Context, dbID repo.U
 	return customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) MarkEntriesDeleting(ctx context.Context, dbID repo.ULID, entryIDs []int64) ([]int64, error) {
+	// CONSIDERATION: `UPDATE ... SET status = $deleting WHERE id = ANY($1) AND status IN ($ready, $error) RETURNING id`
+	// checks and marks the entries in one atomic statement.
+	return nil, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) DeleteEntry(ctx context.Context, dbID repo.ULID, id int64) (repo.DeletedEntryMeta, error) {
 	// TRANSACTION REQUIRED:
 	// 1. Begin SQL Transaction.
@@ -116,6 +166,21 @@ func (r PostgresRepository) DeleteEntries(ctx context.Context, dbID repo.ULID, e
 	return nil, customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) GetEntryVersions(ctx context.Context, dbID repo.ULID, entryIDs []int64) ([]repo.EntryVersion, error) {
+	// CONSIDERATION: SELECT ... WHERE id = ANY($1) takes the IDs as one array parameter instead of a placeholder each
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteSettledEntries(ctx context.Context, dbID repo.ULID, entryIDs []int64, progress repo.JobProgress) ([]repo.DeletedEntryMeta, []int64, error) {
+	// CONSIDERATION: DELETE ... RETURNING of the settled rows, then SELECT id ... = ANY($1) for the skipped ones and the job update, in one transaction
+	return nil, nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ExplainSearch(ctx context.Context, dbID repo.ULID, req repo.SearchRequest, customFields []repo.CustomFieldDef) (repo.QueryPlan, error) {
+	// CONSIDERATION: EXPLAIN (FORMAT JSON) returns the plan as one document instead of rows.
+	return repo.QueryPlan{}, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) SearchEntries(ctx context.Context, dbID repo.ULID, req repo.SearchRequest, customFields []repo.CustomFieldDef) ([]repo.Entry, error) {
 	// CONSIDERATION: You must whitelist the 'field' and 'operator' strings to prevent SQL injection.
 	// Ensure you use a query builder (like Squirrel) and double-quote the dynamic table name.
@@ -151,6 +216,14 @@ func (r PostgresRepository) GetUserByUsername(ctx context.Context, username stri
 	return repo.User{}, customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) RecordUserLogin(ctx context.Context, id repo.ULID, at time.Time) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) RecordUserActivity(ctx context.Context, id repo.ULID, at time.Time) error {
+	return customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) SetUserPermissions(ctx context.Context, permissions repo.UserPermissions) error {
 	// CONSIDERATION: This acts as an Upsert. In Postgres, you can use:
 	// INSERT INTO database_permissions ... ON CONFLICT (user_id, database_name) DO UPDATE SET ...
@@ -178,10 +251,6 @@ func (r PostgresRepository) DeleteRefreshToken(ctx context.Context, tokenHash st
 	return customerrors.ErrNotImplemented
 }
 
-func (r PostgresRepository) DeleteExpiredRefreshTokens(ctx context.Context) (int64, error) {
-	return 0, customerrors.ErrNotImplemented
-}
-
 func (r PostgresRepository) DeleteAllRefreshTokensForUser(ctx context.Context, userID repo.ULID) error {
 	return customerrors.ErrNotImplemented
 }
@@ -194,6 +263,10 @@ func (r PostgresRepository) GetLogs(ctx context.Context, opts repository.QueryOp
 	return nil, customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) GetDatabaseLogs(ctx context.Context, dbID repository.ULID, actions []string, opts repository.QueryOptions) ([]repository.AuditLog, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) DeleteLogs(ctx context.Context, maxAge time.Duration) error {
 	return customerrors.ErrNotImplemented
 }
@@ -231,10 +304,36 @@ func (r PostgresRepository) MigrateDown(ctx context.Context) error {
 	return customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) Ping(ctx context.Context) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetFileStats(ctx context.Context) (repository.FileStats, error) {
+	return repository.FileStats{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) IncrementalVacuum(ctx context.Context, pages int64) (int64, error) {
+	// CONSIDERATION: autovacuum reclaims space in PostgreSQL, VACUUM FULL would return it to the OS.
+	return 0, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) IntegrityCheck(ctx context.Context) error {
+	// CONSIDERATION: PostgreSQL has no direct equivalent, amcheck would be the closest.
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ReconcileEntryTables(ctx context.Context, dryRun bool) ([]repository.EntryTableReconciliation, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) ClaimQueuedEntry(ctx context.Context, dbID repo.ULID, entryID int64) (bool, error) {
 	return false, customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) ClaimFailedEntry(ctx context.Context, dbID repo.ULID, entryID int64) (bool, error) {
+	return false, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) GetEntriesByStatus(ctx context.Context, dbID repo.ULID, status repository.EntryStatus) ([]repository.Entry, error) {
 	return nil, customerrors.ErrNotImplemented
 }
@@ -247,7 +346,7 @@ func (r PostgresRepository) AddCustomField(ctx context.Context, dbID repo.ULID,
 	return repository.CustomFieldDef{}, customerrors.ErrNotImplemented
 }
 
-func (r PostgresRepository) UpdateCustomField(ctx context.Context, dbID repo.ULID, fieldID int, name *string, isIndexed *bool) (repository.CustomFieldDef, error) {
+func (r PostgresRepository) UpdateCustomField(ctx context.Context, dbID repo.ULID, fieldID int, name *string, isIndexed *bool, isSensitive *bool) (repository.CustomFieldDef, error) {
 	return repository.CustomFieldDef{}, customerrors.ErrNotImplemented
 }
 
@@ -255,11 +354,52 @@ func (r PostgresRepository) DeleteCustomField(ctx context.Context, dbID repo.ULI
 	return customerrors.ErrNotImplemented
 }
 
+func (r PostgresRepository) AddCustomIndexes(ctx context.Context, dbID repo.ULID, indexes [][]string) ([][]string, error) {
+	// CONSIDERATION: Use CREATE INDEX CONCURRENTLY outside of a transaction, the entry table stays writable.
+	return nil, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) GetCustomFields(ctx context.Context, dbID repo.ULID) ([]repository.CustomFieldDef, error) {
 	return nil, customerrors.ErrNotImplemented
 }
 
 // API Key stubs
+func (r PostgresRepository) CreateGroup(ctx context.Context, group repo.Group) (repo.Group, error) {
+	return repo.Group{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetGroup(ctx context.Context, id repo.ULID) (repo.Group, error) {
+	return repo.Group{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetGroups(ctx context.Context) ([]repo.Group, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) UpdateGroup(ctx context.Context, group repo.Group) (repo.Group, error) {
+	return repo.Group{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteGroup(ctx context.Context, id repo.ULID) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) AddUserToGroup(ctx context.Context, userID repo.ULID, groupID repo.ULID) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) RemoveUserFromGroup(ctx context.Context, userID repo.ULID, groupID repo.ULID) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetUserGroups(ctx context.Context, userID repo.ULID) ([]repo.Group, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetGroupMembers(ctx context.Context, groupID repo.ULID) ([]repo.ULID, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
 func (r PostgresRepository) CreateAPIKey(ctx context.Context, apiKey repo.APIKey) (repo.APIKey, error) {
 	return repo.APIKey{}, customerrors.ErrNotImplemented
 }
@@ -292,10 +432,317 @@ func (r PostgresRepository) DeleteAPIKey(ctx context.Context, id repo.ULID) erro
 	return customerrors.ErrNotImplemented
 }
 
-func (r PostgresRepository) DeleteExpiredAPIKeys(ctx context.Context) (int64, error) {
+func (r PostgresRepository) UpdateAPIKeyLastUsed(ctx context.Context, id repo.ULID, lastUsed time.Duration) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CreateComment(ctx context.Context, dbID repo.ULID, comment repo.Comment) (repo.Comment, error) {
+	return repo.Comment{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetComment(ctx context.Context, dbID repo.ULID, id int64) (repo.Comment, error) {
+	return repo.Comment{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetComments(ctx context.Context, dbID repo.ULID, entryID int64, limit int, offset int) ([]repo.Comment, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CountComments(ctx context.Context, dbID repo.ULID, entryIDs []int64) (map[int64]int64, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteComment(ctx context.Context, dbID repo.ULID, id int64) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CreateShareLink(ctx context.Context, link repo.ShareLink) (repo.ShareLink, error) {
+	return repo.ShareLink{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetShareLinkByHash(ctx context.Context, tokenHash string) (repo.ShareLink, error) {
+	return repo.ShareLink{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetShareLinks(ctx context.Context, dbID repo.ULID, entryID int64) ([]repo.ShareLink, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteShareLink(ctx context.Context, dbID repo.ULID, entryID int64, id repo.ULID) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ConsumeShareLink(ctx context.Context, id repo.ULID) (bool, error) {
+	// CONSIDERATION: use UPDATE ... RETURNING to make the check-and-increment a single round trip
+	return false, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CreateUploadGrant(ctx context.Context, grant repo.UploadGrant) (repo.UploadGrant, error) {
+	return repo.UploadGrant{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetUploadGrantByHash(ctx context.Context, tokenHash string) (repo.UploadGrant, error) {
+	return repo.UploadGrant{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetUploadGrants(ctx context.Context, filter repo.UploadGrantFilter) ([]repo.UploadGrant, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteUploadGrant(ctx context.Context, id repo.ULID, userID repo.ULID) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ConsumeUploadGrant(ctx context.Context, id repo.ULID) (bool, error) {
+	return false, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ReleaseUploadGrant(ctx context.Context, id repo.ULID) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetEntryHistogram(ctx context.Context, dbID repo.ULID, req repo.HistogramRequest, customFields []repo.CustomFieldDef) ([]repo.HistogramBucket, error) {
+	// CONSIDERATION: date_bin() over to_timestamp(timestamp / 1000.0) in UTC, with the week origin on a Monday.
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetRetentionForecast(ctx context.Context, dbID repo.ULID, req repo.RetentionRequest) ([]repo.HistogramBucket, error) {
+	// CONSIDERATION: Same CASE over the time field as SQLite, bigint division truncates as well.
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetEntryFacets(ctx context.Context, dbID repo.ULID, req repo.FacetRequest, customFields []repo.CustomFieldDef) (repo.Facets, error) {
+	// CONSIDERATION: Same GROUP BY per field in a REPEATABLE READ transaction, COUNT(col) for the null counts.
+	return repo.Facets{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetLargestEntries(ctx context.Context, limit int) ([]repo.LargestEntry, error) {
+	// CONSIDERATION: Same UNION ALL of per-table "ORDER BY filesize DESC LIMIT n" subqueries as SQLite.
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetProcessingBacklog(ctx context.Context, filter repo.BacklogFilter) ([]repo.ProcessingBacklog, error) {
+	// CONSIDERATION: Same UNION ALL of per-table COUNT(*), MIN(created_at) and sample subqueries as SQLite.
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetEntriesToVerify(ctx context.Context, dbID repo.ULID, limit int) ([]repo.Entry, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) RecordEntryVerification(ctx context.Context, dbID repo.ULID, entryID int64, contentHash, errorReason string) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetIntegrityStats(ctx context.Context, dbID repo.ULID) (repo.IntegrityStats, error) {
+	// CONSIDERATION: COUNT(*) FILTER (WHERE ...) instead of SUM(CASE ...)
+	return repo.IntegrityStats{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CreateDuplicateScan(ctx context.Context, scan repo.DuplicateScan) (repo.DuplicateScan, error) {
+	// CONSIDERATION: Same partial unique index on (database_id) WHERE status = 'running'
+	return repo.DuplicateScan{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetDuplicateScan(ctx context.Context, id repo.ULID) (repo.DuplicateScan, error) {
+	return repo.DuplicateScan{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetRunningDuplicateScans(ctx context.Context) ([]repo.DuplicateScan, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) UpdateDuplicateScan(ctx context.Context, scan repo.DuplicateScan) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetEntriesToHash(ctx context.Context, dbID repo.ULID, afterID, maxID int64, limit int) ([]repo.Entry, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) StoreContentHashes(ctx context.Context, dbID repo.ULID, hashes map[int64]string) error {
+	// CONSIDERATION: A single UPDATE ... FROM (VALUES ...) instead of one statement per entry
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetDuplicateGroups(ctx context.Context, dbID repo.ULID, maxID int64) ([]repo.DuplicateGroup, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CreateJob(ctx context.Context, job repo.Job) (repo.Job, error) {
+	// CONSIDERATION: payload and counters as JSONB
+	return repo.Job{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetJob(ctx context.Context, id repo.ULID) (repo.Job, error) {
+	return repo.Job{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetRunningJobs(ctx context.Context) ([]repo.Job, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) UpdateJob(ctx context.Context, job repo.Job) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ScheduleTranscription(ctx context.Context, dbID repo.ULID, entryID int64) (repo.PendingTask, error) {
+	return repo.PendingTask{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) RecordTranscription(ctx context.Context, dbID repo.ULID, entryID int64, status string, field int, text string) error {
+	return customerrors.ErrNotImplemented
+}
+
+// Legal Hold
+
+func (r PostgresRepository) SetLegalHold(ctx context.Context, dbID repo.ULID, entryIDs []int64, hold bool) ([]int64, error) {
+	// CONSIDERATION: UPDATE ... SET legal_hold = $1 WHERE id = ANY($2) RETURNING id
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetHeldEntries(ctx context.Context, dbID repo.ULID, entryIDs []int64) ([]int64, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CountHeldEntries(ctx context.Context, dbID repo.ULID, timeField string, before time.Time) (int64, error) {
 	return 0, customerrors.ErrNotImplemented
 }
 
-func (r PostgresRepository) UpdateAPIKeyLastUsed(ctx context.Context, id repo.ULID, lastUsed time.Duration) error {
+// Full-Text Search
+
+func (r PostgresRepository) BackfillFulltext(ctx context.Context, dbID repo.ULID, batchSize int) (bool, error) {
+	return false, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ScheduleFulltextBackfill(ctx context.Context, dbID repo.ULID) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ReserveIdempotencyKey(ctx context.Context, key repo.IdempotencyKey) (repo.IdempotencyKey, bool, error) {
+	// CONSIDERATION: INSERT ... ON CONFLICT DO NOTHING, then SELECT the existing row if nothing was inserted.
+	return repo.IdempotencyKey{}, false, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetIdempotencyKey(ctx context.Context, dbID repo.ULID, userID repo.ULID, key string) (repo.IdempotencyKey, error) {
+	return repo.IdempotencyKey{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) RenewIdempotencyKey(ctx context.Context, key repo.IdempotencyKey) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CompleteIdempotencyKey(ctx context.Context, key repo.IdempotencyKey) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteIdempotencyKey(ctx context.Context, dbID repo.ULID, userID repo.ULID, key string) error {
 	return customerrors.ErrNotImplemented
 }
+
+func (r PostgresRepository) GetUserStoredBytes(ctx context.Context, username string) (uint64, error) {
+	return 0, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetUserUsage(ctx context.Context, filter repo.UsageFilter) ([]repo.UsageRecord, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) AggregateUserUsage(ctx context.Context) (int64, error) {
+	return 0, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) RecordNotice(ctx context.Context, notice repo.ServerNotice) (repo.ServerNotice, error) {
+	// CONSIDERATION: INSERT ... ON CONFLICT (dedup_key) WHERE acknowledged_at IS NULL DO UPDATE SET count = server_notices.count + 1, ...
+	// works the same with the partial unique index.
+	return repo.ServerNotice{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetNotices(ctx context.Context, filter repo.NoticeFilter) ([]repo.ServerNotice, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CountUnacknowledgedNotices(ctx context.Context) (int64, error) {
+	return 0, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) AcknowledgeNotices(ctx context.Context, ids []int64, username string) (int64, error) {
+	return 0, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteNotices(ctx context.Context, maxAge time.Duration) (int64, error) {
+	return 0, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) EnsureJWTSecret(ctx context.Context, secret string) (bool, error) {
+	// CONSIDERATION: INSERT ... SELECT ... WHERE NOT EXISTS under a SERIALIZABLE transaction or an advisory lock.
+	return false, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetJWTSecrets(ctx context.Context) ([]repo.JWTSecret, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) RotateJWTSecret(ctx context.Context, secret string, grace time.Duration) error {
+	return customerrors.ErrNotImplemented
+}
+
+// Maintenance Mode
+
+func (r PostgresRepository) GetMaintenanceMode(ctx context.Context) (repo.MaintenanceMode, error) {
+	return repo.MaintenanceMode{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) SetMaintenanceMode(ctx context.Context, mode repo.MaintenanceMode) error {
+	// CONSIDERATION: INSERT ... ON CONFLICT (id) DO UPDATE SET enabled = EXCLUDED.enabled, ...
+	return customerrors.ErrNotImplemented
+}
+
+// Settings
+
+func (r PostgresRepository) GetSetting(ctx context.Context, key string) (string, error) {
+	return "", customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) SetSetting(ctx context.Context, key string, value string) error {
+	// CONSIDERATION: INSERT ... ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) SaveRetainedUpload(ctx context.Context, upload repo.RetainedUpload) error {
+	// CONSIDERATION: INSERT ... ON CONFLICT (database_id, entry_id) DO UPDATE SET path = EXCLUDED.path, ...
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetRetainedUpload(ctx context.Context, dbID repo.ULID, entryID int64) (repo.RetainedUpload, error) {
+	return repo.RetainedUpload{}, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteRetainedUpload(ctx context.Context, dbID repo.ULID, entryID int64) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) DeleteExpiredRetainedUploads(ctx context.Context) ([]repo.RetainedUpload, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) GetDuePendingTasks(ctx context.Context, limit int) ([]repo.PendingTask, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ClaimPendingTask(ctx context.Context, id int64, lease time.Duration) (bool, error) {
+	// CONSIDERATION: SELECT ... FOR UPDATE SKIP LOCKED would allow several instances to share the tasks
+	return false, customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) CompletePendingTask(ctx context.Context, id int64) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) RetryPendingTask(ctx context.Context, id int64, backoff time.Duration, lastError string) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (r PostgresRepository) ReleasePendingTasks(ctx context.Context) (int64, error) {
+	return 0, customerrors.ErrNotImplemented
+}
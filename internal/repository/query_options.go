@@ -6,6 +6,39 @@ import (
 	"time"
 )
 
+// Page sizes of listings and searches, if not configured otherwise.
+const (
+	DefaultPageSize    = 100
+	DefaultMaxPageSize = 1000
+)
+
+// PageLimits bounds the number of entries returned by one listing or search.
+// Zero values fall back to DefaultPageSize and DefaultMaxPageSize.
+type PageLimits struct {
+	Default int // applied if no limit is given
+	Max     int // larger limits are clamped to it
+}
+
+// Apply returns the limit to use for a requested limit, and whether the requested limit was clamped.
+// A missing (zero or negative) limit gets the default page size.
+func (p PageLimits) Apply(limit int) (int, bool) {
+	maxSize := p.Max
+	if maxSize <= 0 {
+		maxSize = DefaultMaxPageSize
+	}
+	defaultSize := p.Default
+	if defaultSize <= 0 {
+		defaultSize = DefaultPageSize
+	}
+	if limit <= 0 {
+		return min(defaultSize, maxSize), false
+	}
+	if limit > maxSize {
+		return maxSize, true
+	}
+	return limit, false
+}
+
 // QueryOptions defines generic parameters for pagination, sorting, and time-based filtering.
 type QueryOptions struct {
 	Limit     int
@@ -15,15 +48,19 @@ type QueryOptions struct {
 	TimeField string // e.g., "timestamp", "created_at", "updated_at"
 	TStart    time.Time
 	TEnd      time.Time
+	Statuses  []EntryStatus // only return entries with one of these statuses, all if empty
+	Fields    []string      // only select these fields (the id is always included), all if empty
+
+	ExcludeLegalHold bool // skip entries under legal hold, e.g. for housekeeping
 }
 
 // Validate checks query options, assigns defaults for missing values, and returns an error if any parameter is invalid.
 func (o *QueryOptions) Validate() error {
 	if o.Limit <= 0 {
-		o.Limit = 30
+		o.Limit = DefaultPageSize
 	}
 	if o.Offset < 0 {
-		o.Offset = 0
+		return fmt.Errorf("invalid offset: %d (must not be negative)", o.Offset)
 	}
 
 	if o.Order == "" {
@@ -26,48 +26,123 @@ type Repository interface {
 	UpdateDatabase(ctx context.Context, db Database) (Database, error)
 	DeleteDatabase(ctx context.Context, dbID ULID) error
 	GetDatabaseStats(ctx context.Context, dbID ULID) (DatabaseStats, error)
+	GetEntryTimeRange(ctx context.Context, dbID ULID) (time.Time, time.Time, error) // oldest and newest entry timestamp, zero if the database is empty
+
+	// Delete Confirmations
+	CreateDeleteConfirmation(ctx context.Context, confirmation DeleteConfirmation) error      // replaces the pending confirmation of the database
+	ConsumeDeleteConfirmation(ctx context.Context, dbID ULID, tokenHash string) (bool, error) // atomically removes a matching, unexpired confirmation, returns false if there is none
 
 	// Custom Fields
 	AddCustomField(ctx context.Context, dbID ULID, field CustomFieldDef) (CustomFieldDef, error)
-	UpdateCustomField(ctx context.Context, dbID ULID, fieldID int, name *string, isIndexed *bool) (CustomFieldDef, error)
+	UpdateCustomField(ctx context.Context, dbID ULID, fieldID int, name *string, isIndexed *bool, isSensitive *bool) (CustomFieldDef, error)
 	DeleteCustomField(ctx context.Context, dbID ULID, fieldID int) error
 	GetCustomFields(ctx context.Context, dbID ULID) ([]CustomFieldDef, error)
+	AddCustomIndexes(ctx context.Context, dbID ULID, indexes [][]string) ([][]string, error) // creates the missing composite indexes one at a time, returns the created ones
 
 	// Housekeeping
-	HouseKeepingRequired(ctx context.Context) ([]Database, error)            // return all databases where the last housekeeping run was longer ago than the provided interval
-	HouseKeepingWasCalled(ctx context.Context, dbID ULID) (time.Time, error) // set the LastHkRun to now (server timestamp), used by housekeeping to track when the last run was
+	HouseKeepingRequired(ctx context.Context) ([]Database, error)                                    // return all databases where the last housekeeping run was longer ago than the provided interval
+	HouseKeepingWasCalled(ctx context.Context, dbID ULID) (time.Time, error)                         // set the LastHkRun to now (server timestamp), used by housekeeping to track when the last run was
+	SetDiskSpaceAlert(ctx context.Context, dbID ULID, active bool) (bool, error)                     // records the alert state, true if it changed
+	SetProcessingAlert(ctx context.Context, dbID ULID, active bool) (bool, error)                    // records the processing backlog alert state, true if it changed
+	DeleteExpiredRows(ctx context.Context, table, expiryColumn string, batchSize int) (int64, error) // deletes the rows whose expiry (Unix milliseconds) has passed, batchSize rows per statement
 
 	// Entry
 	// Deleting or creating entries will also update the database statistics
 	CreateEntry(ctx context.Context, db Database, entry Entry) (Entry, error)
+	CreateEntryWithTasks(ctx context.Context, db Database, entry Entry, taskTypes []string, lease time.Duration) (Entry, []PendingTask, error) // pending tasks are created in the same transaction and become due after the lease
 	GetEntry(ctx context.Context, dbID ULID, id int64) (Entry, error)
+	GetEntryByExternalID(ctx context.Context, dbID ULID, externalID string) (Entry, error) // ErrConflict if several entries share the external ID
 	GetEntries(ctx context.Context, dbID ULID, opts QueryOptions) ([]Entry, error)
-	UpdateEntry(ctx context.Context, dbID ULID, entry Entry) (Entry, error)
+	GetEntryVersions(ctx context.Context, dbID ULID, entryIDs []int64) ([]EntryVersion, error)                        // the existing entries of the given IDs by ID, without custom fields
+	UpdateEntryStatus(ctx context.Context, dbID ULID, entryID int64, status EntryStatus, reason, detail string) error // empty reason and detail clear them
+	UpdateEntryTechMetadata(ctx context.Context, dbID ULID, entryID int64, meta EntryTechMetadata) error              // sizes, mime types and media fields, adjusts the database size
+	UpdateEntryUserFields(ctx context.Context, dbID ULID, entryID int64, fields map[string]any) (Entry, error)        // custom fields by name plus filename, timestamp and external_id, ErrValidation for other keys
 	UpdateEntriesStatus(ctx context.Context, dbID ULID, entryIDs []int64, status EntryStatus) error
+	MarkEntriesDeleting(ctx context.Context, dbID ULID, entryIDs []int64) ([]int64, error) // only ready or errored entries without legal hold are marked, returns the marked IDs
 	ClaimQueuedEntry(ctx context.Context, dbID ULID, entryID int64) (bool, error)
+	ClaimFailedEntry(ctx context.Context, dbID ULID, entryID int64) (bool, error) // errored to processing, false if the entry did not fail
 	GetEntriesByStatus(ctx context.Context, dbID ULID, status EntryStatus) ([]Entry, error)
 	CountEntriesByStatus(ctx context.Context, dbID ULID, status EntryStatus) (int64, error)
-	DeleteEntry(ctx context.Context, dbID ULID, id int64) (DeletedEntryMeta, error)
-	DeleteEntries(ctx context.Context, dbID ULID, entryIDs []int64) ([]DeletedEntryMeta, error)
+	DeleteEntry(ctx context.Context, dbID ULID, id int64) (DeletedEntryMeta, error)                                                   // ErrLegalHold if the entry is held
+	DeleteEntries(ctx context.Context, dbID ULID, entryIDs []int64) ([]DeletedEntryMeta, error)                                       // ErrLegalHold and nothing deleted if any entry is held
+	DeleteSettledEntries(ctx context.Context, dbID ULID, entryIDs []int64, progress JobProgress) ([]DeletedEntryMeta, []int64, error) // in one transaction, skips queued, processing and held entries
 	SearchEntries(ctx context.Context, dbID ULID, req SearchRequest, customFields []CustomFieldDef) ([]Entry, error)
+	ExplainSearch(ctx context.Context, dbID ULID, req SearchRequest, customFields []CustomFieldDef) (QueryPlan, error)                // the query of SearchEntries and its plan, without running it
+	GetEntryHistogram(ctx context.Context, dbID ULID, req HistogramRequest, customFields []CustomFieldDef) ([]HistogramBucket, error) // all buckets of the range in order, empty ones included
+	GetRetentionForecast(ctx context.Context, dbID ULID, req RetentionRequest) ([]HistogramBucket, error)                             // Weeks+1 buckets: the entries due at Cutoff, then those due in each following week
+	GetEntryFacets(ctx context.Context, dbID ULID, req FacetRequest, customFields []CustomFieldDef) (Facets, error)                   // the most frequent values of each field among the entries matching the filter
+	GetLargestEntries(ctx context.Context, limit int) ([]LargestEntry, error)                                                         // across all databases, largest file first
+	GetProcessingBacklog(ctx context.Context, filter BacklogFilter) ([]ProcessingBacklog, error)                                      // the databases with processing entries, oldest backlog first
+
+	// Legal Hold
+	SetLegalHold(ctx context.Context, dbID ULID, entryIDs []int64, hold bool) ([]int64, error)          // sets or clears the hold, returns the IDs of the existing entries
+	GetHeldEntries(ctx context.Context, dbID ULID, entryIDs []int64) ([]int64, error)                   // the given entries that are under legal hold
+	CountHeldEntries(ctx context.Context, dbID ULID, timeField string, before time.Time) (int64, error) // entries under legal hold with timeField (timestamp if empty) up to before, all if zero
+
+	// Comments
+	CreateComment(ctx context.Context, dbID ULID, comment Comment) (Comment, error)
+	GetComment(ctx context.Context, dbID ULID, id int64) (Comment, error)
+	GetComments(ctx context.Context, dbID ULID, entryID int64, limit int, offset int) ([]Comment, error) // newest first
+	CountComments(ctx context.Context, dbID ULID, entryIDs []int64) (map[int64]int64, error)             // entries without comments are missing from the map
+	DeleteComment(ctx context.Context, dbID ULID, id int64) error
+
+	// Full-Text Search
+	BackfillFulltext(ctx context.Context, dbID ULID, batchSize int) (bool, error) // indexes the next batch of entries older than the full-text index, true once none are left
+	ScheduleFulltextBackfill(ctx context.Context, dbID ULID) error                // creates a task continuing the backfill
+
+	// Integrity
+	GetEntriesToVerify(ctx context.Context, dbID ULID, limit int) ([]Entry, error)                                // ready entries, never verified ones first, then the least recently verified
+	RecordEntryVerification(ctx context.Context, dbID ULID, entryID int64, contentHash, errorReason string) error // stores the hash and check time, a non-empty reason sets the entry to error
+	GetIntegrityStats(ctx context.Context, dbID ULID) (IntegrityStats, error)
+
+	// Duplicate Scans
+	CreateDuplicateScan(ctx context.Context, scan DuplicateScan) (DuplicateScan, error) // sets the entry range and total, ErrConflict if a scan of the database is running
+	GetDuplicateScan(ctx context.Context, id ULID) (DuplicateScan, error)
+	GetRunningDuplicateScans(ctx context.Context) ([]DuplicateScan, error)
+	UpdateDuplicateScan(ctx context.Context, scan DuplicateScan) error                                 // stores the status and progress
+	GetEntriesToHash(ctx context.Context, dbID ULID, afterID, maxID int64, limit int) ([]Entry, error) // ready entries with afterID < id <= maxID, by ID
+	StoreContentHashes(ctx context.Context, dbID ULID, hashes map[int64]string) error                  // records the first hash of ready entries in one transaction
+	GetDuplicateGroups(ctx context.Context, dbID ULID, maxID int64) ([]DuplicateGroup, error)          // ready entries up to maxID sharing a content hash
+
+	// Jobs
+	CreateJob(ctx context.Context, job Job) (Job, error)
+	GetJob(ctx context.Context, id ULID) (Job, error)
+	GetRunningJobs(ctx context.Context) ([]Job, error) // oldest first
+	UpdateJob(ctx context.Context, job Job) error      // stores the status, progress, counters and pending files
+
+	// Transcription
+	ScheduleTranscription(ctx context.Context, dbID ULID, entryID int64) (PendingTask, error)                       // sets a ready entry to pending and creates its transcription task
+	RecordTranscription(ctx context.Context, dbID ULID, entryID int64, status string, field int, text string) error // stores the status, and the text in the custom field unless field is negative
 
 	// User
 	CreateUser(ctx context.Context, user User) (User, error)
-	CountAdminUsers(ctx context.Context) (int64, error)
+	CountAdminUsers(ctx context.Context) (int64, error) // counts effective admins (own flag or admin group)
 	DeleteUser(ctx context.Context, id ULID) error
 	UpdateUser(ctx context.Context, user User) (User, error)
 	GetUsers(ctx context.Context, isServiceAccount *bool) ([]User, error)
 	GetUserByID(ctx context.Context, id ULID) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
+	RecordUserLogin(ctx context.Context, id ULID, at time.Time) error          // sets the last login and activity, timestamps never move backwards
+	RecordUserActivity(ctx context.Context, id ULID, at time.Time) error       // sets the last activity, timestamps never move backwards
 	SetUserPermissions(ctx context.Context, permissions UserPermissions) error // create or update or delete (in case of empty Roles)
 	GetUserPermissions(ctx context.Context, userID ULID, dbID ULID) (UserPermissions, error)
 	GetAllUserPermissions(ctx context.Context, userID ULID) ([]UserPermissions, error)
 
+	// Group
+	CreateGroup(ctx context.Context, group Group) (Group, error)
+	GetGroup(ctx context.Context, id ULID) (Group, error)
+	GetGroups(ctx context.Context) ([]Group, error)
+	UpdateGroup(ctx context.Context, group Group) (Group, error)
+	DeleteGroup(ctx context.Context, id ULID) error // memberships are removed as well
+	AddUserToGroup(ctx context.Context, userID ULID, groupID ULID) error
+	RemoveUserFromGroup(ctx context.Context, userID ULID, groupID ULID) error
+	GetUserGroups(ctx context.Context, userID ULID) ([]Group, error)
+	GetGroupMembers(ctx context.Context, groupID ULID) ([]ULID, error)
+
 	// Token
 	StoreRefreshToken(ctx context.Context, userID ULID, tokenHash string, validDuration time.Duration) error // TODO adapt implementations
 	ValidateRefreshToken(ctx context.Context, tokenHash string) (ULID, error)
 	DeleteRefreshToken(ctx context.Context, tokenHash string) error
-	DeleteExpiredRefreshTokens(ctx context.Context) (int64, error)
 	DeleteAllRefreshTokensForUser(ctx context.Context, userID ULID) error
 
 	// API Key
@@ -79,13 +154,74 @@ type Repository interface {
 	GetAllAPIKeys(ctx context.Context) ([]APIKey, error)
 	UpdateAPIKey(ctx context.Context, apiKey APIKey) (APIKey, error)
 	DeleteAPIKey(ctx context.Context, id ULID) error
-	DeleteExpiredAPIKeys(ctx context.Context) (int64, error)
 	UpdateAPIKeyLastUsed(ctx context.Context, id ULID, lastUsed time.Duration) error // duration is elapsed time since usage. TIme is calculated on the server side to avoid client time sync issues.
 
+	// Share Links
+	CreateShareLink(ctx context.Context, link ShareLink) (ShareLink, error)
+	GetShareLinkByHash(ctx context.Context, tokenHash string) (ShareLink, error)
+	GetShareLinks(ctx context.Context, dbID ULID, entryID int64) ([]ShareLink, error)
+	DeleteShareLink(ctx context.Context, dbID ULID, entryID int64, id ULID) error
+	ConsumeShareLink(ctx context.Context, id ULID) (bool, error) // atomically increments the download count, returns false if the link is expired or exhausted
+
+	// Upload Grants
+	CreateUploadGrant(ctx context.Context, grant UploadGrant) (UploadGrant, error)
+	GetUploadGrantByHash(ctx context.Context, tokenHash string) (UploadGrant, error)
+	GetUploadGrants(ctx context.Context, filter UploadGrantFilter) ([]UploadGrant, error)
+	DeleteUploadGrant(ctx context.Context, id ULID, userID ULID) error // an empty userID deletes the grant of any user
+	ConsumeUploadGrant(ctx context.Context, id ULID) (bool, error)     // atomically marks the grant as used, returns false if it is used or expired
+	ReleaseUploadGrant(ctx context.Context, id ULID) error             // makes a consumed grant usable again after a failed upload
+
+	// Idempotency Keys
+	ReserveIdempotencyKey(ctx context.Context, key IdempotencyKey) (IdempotencyKey, bool, error) // returns false and the stored key if it already exists and has not expired
+	GetIdempotencyKey(ctx context.Context, dbID ULID, userID ULID, key string) (IdempotencyKey, error)
+	RenewIdempotencyKey(ctx context.Context, key IdempotencyKey) error    // extends the lease of a key in progress to key.ExpiresAt
+	CompleteIdempotencyKey(ctx context.Context, key IdempotencyKey) error // stores the entry ID, status code and expiry of the finished request
+	DeleteIdempotencyKey(ctx context.Context, dbID ULID, userID ULID, key string) error
+
+	// JWT Secrets
+	EnsureJWTSecret(ctx context.Context, secret string) (bool, error)              // stores the secret as current unless there is one, returns true if it was stored
+	GetJWTSecrets(ctx context.Context) ([]JWTSecret, error)                        // the current and the secrets still in their grace period, newest first
+	RotateJWTSecret(ctx context.Context, secret string, grace time.Duration) error // the current secret is accepted for the grace period, the new one becomes current
+
+	// Maintenance Mode
+	GetMaintenanceMode(ctx context.Context) (MaintenanceMode, error) // the zero value if maintenance was never enabled
+	SetMaintenanceMode(ctx context.Context, mode MaintenanceMode) error
+
+	// Settings
+	GetSetting(ctx context.Context, key string) (string, error) // ErrNotFound if the setting was never stored
+	SetSetting(ctx context.Context, key string, value string) error
+
+	// Retained Uploads
+	SaveRetainedUpload(ctx context.Context, upload RetainedUpload) error // replaces a previous upload retained for the same entry
+	GetRetainedUpload(ctx context.Context, dbID ULID, entryID int64) (RetainedUpload, error)
+	DeleteRetainedUpload(ctx context.Context, dbID ULID, entryID int64) error
+	DeleteExpiredRetainedUploads(ctx context.Context) ([]RetainedUpload, error) // returns the removed rows, so their files can be deleted
+
+	// Pending Tasks
+	GetDuePendingTasks(ctx context.Context, limit int) ([]PendingTask, error)
+	ClaimPendingTask(ctx context.Context, id int64, lease time.Duration) (bool, error) // postpones a due task by the lease, returns false if it is not due (anymore)
+	CompletePendingTask(ctx context.Context, id int64) error
+	RetryPendingTask(ctx context.Context, id int64, backoff time.Duration, lastError string) error // increments the attempts and schedules the next one
+	ReleasePendingTasks(ctx context.Context) (int64, error)                                        // makes all tasks due immediately, used on startup
+
+	// User Usage
+	// Creating, updating and deleting entries record the change of the usage of their uploader
+	GetUserStoredBytes(ctx context.Context, username string) (uint64, error) // bytes of the entries uploaded by the user in all databases, including changes not aggregated yet
+	GetUserUsage(ctx context.Context, filter UsageFilter) ([]UsageRecord, error)
+	AggregateUserUsage(ctx context.Context) (int64, error) // folds the recorded changes into the summary, returns the number of folded changes
+
+	// Server Notices
+	RecordNotice(ctx context.Context, notice ServerNotice) (ServerNotice, error) // adds the notice, or counts a repetition of the open notice with the same key
+	GetNotices(ctx context.Context, filter NoticeFilter) ([]ServerNotice, error)
+	CountUnacknowledgedNotices(ctx context.Context) (int64, error)
+	AcknowledgeNotices(ctx context.Context, ids []int64, username string) (int64, error) // all open notices if ids is empty, returns the number acknowledged
+	DeleteNotices(ctx context.Context, maxAge time.Duration) (int64, error)              // notices whose latest repetition is older
+
 	// Logging
 	LogAudit(ctx context.Context, log AuditLog) error
 	GetLogs(ctx context.Context, opts QueryOptions) ([]AuditLog, error)
-	DeleteLogs(ctx context.Context, maxAge time.Duration) error // delete all logs where the timestamp (checked again server time) is too old // TODO adapt implementations
+	GetDatabaseLogs(ctx context.Context, dbID ULID, actions []string, opts QueryOptions) ([]AuditLog, error) // logs of the database and its entries, all actions if empty
+	DeleteLogs(ctx context.Context, maxAge time.Duration) error                                              // delete all logs where the timestamp (checked again server time) is too old // TODO adapt implementations
 
 	// Distributed Locking
 	AcquireLock(ctx context.Context, lockName string, ownerID string, ttl time.Duration) (bool, error)
@@ -95,6 +231,15 @@ type Repository interface {
 	GetMigrationVersion(ctx context.Context) (int, error) // integer is 1000*major version + minor version
 	MigrateUp(ctx context.Context) error
 	MigrateDown(ctx context.Context) error
+	IntegrityCheck(ctx context.Context) error
+	ReconcileEntryTables(ctx context.Context, dryRun bool) ([]EntryTableReconciliation, error) // rebuilds the entry tables that differ from the current schema (only reports them with dryRun), returns the differing tables
+
+	// Space Reclamation
+	GetFileStats(ctx context.Context) (FileStats, error)
+	IncrementalVacuum(ctx context.Context, pages int64) (int64, error) // returns up to pages free pages to the file system (all if 0), returns the number released
+
+	// Ping runs a trivial query to verify that the database answers.
+	Ping(ctx context.Context) error
 }
 
 func UserExists(ctx context.Context, s Repository, username string) (bool, error) {
@@ -108,6 +253,23 @@ func UserExists(ctx context.Context, s Repository, username string) (bool, error
 	return true, nil
 }
 
+// IsEffectiveAdmin reports whether the user is a global admin, either directly or through an admin group.
+func IsEffectiveAdmin(ctx context.Context, s Repository, user User) (bool, error) {
+	if user.IsAdmin {
+		return true, nil
+	}
+	groups, err := s.GetUserGroups(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, g := range groups {
+		if g.IsAdmin {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // formatVersion converts the packed integer (e.g., 2001) into a readable string (e.g., "2.1")
 func FormatVersion(v int) string {
 	major := v / 1000
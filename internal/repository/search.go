@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// SearchOperators are the filter operators accepted by SearchEntries, in any letter case.
+// LIKE takes the pattern as given, the text match operators below add the wildcards themselves.
+// MATCH is a full-text query and only accepted on custom fields with IsFulltext.
+var SearchOperators = []string{"=", "!=", ">", ">=", "<", "<=", "LIKE",
+	OperatorStartsWith, OperatorEndsWith, OperatorContains, OperatorContainsCI, OperatorEqualsCI, "MATCH"}
+
+// Text match operators. The value is matched literally, % and _ are no wildcards. Like LIKE, starts_with,
+// ends_with and contains ignore the case of ASCII letters only. contains_ci and equals_ci are case-insensitive
+// by definition, also for ASCII only: other letters, e.g. "É" and "é", are different.
+const (
+	OperatorStartsWith = "starts_with"
+	OperatorEndsWith   = "ends_with"
+	OperatorContains   = "contains"
+	OperatorContainsCI = "contains_ci"
+	OperatorEqualsCI   = "equals_ci"
+)
+
+// SortFieldFulltextRank sorts by the relevance of the first MATCH condition, descending is the best match first.
+const SortFieldFulltextRank = "fts_rank"
+
+// Collations of a sort on a TEXT field. Binary, the default, compares the bytes, so "Z" comes before "a" and
+// "Öl" after "z". Nocase ignores the case of ASCII letters only. Unicode sorts alphabetically by the rules of
+// the configured sort locale, accented and lowercase letters next to their base letters.
+const (
+	CollationBinary  = "binary"
+	CollationNoCase  = "nocase"
+	CollationUnicode = "unicode"
+)
+
+// StandardFieldTypes maps the standard entry fields that can be filtered, sorted and selected to their SQL type.
+// Timestamps are stored as unix milliseconds, the status as its numeric value.
+var StandardFieldTypes = map[string]string{
+	"id":                "INTEGER",
+	"timestamp":         "INTEGER",
+	"created_at":        "INTEGER",
+	"updated_at":        "INTEGER",
+	"filesize":          "INTEGER",
+	"preview_filesize":  "INTEGER",
+	"filename":          "TEXT",
+	"status":            "INTEGER",
+	"mime_type":         "TEXT",
+	"external_id":       "TEXT",
+	"uploaded_by":       "TEXT",
+	"upload_ip":         "TEXT",
+	"upload_user_agent": "TEXT",
+
+	"transcription_status": "TEXT",
+	"legal_hold":           "BOOLEAN",
+	"client_timestamp":     "INTEGER",
+}
+
+// TimestampFields are the standard fields holding Unix milliseconds. Filters on them also accept
+// relative times such as "now-24h", see ParseRelativeTime.
+var TimestampFields = []string{"timestamp", "created_at", "updated_at", "client_timestamp"}
+
+// relativeTimePattern is the grammar of relative times: "now", optionally followed by a signed offset.
+var relativeTimePattern = regexp.MustCompile(`^now(?:([+-])(\d{1,9})([smhdw]))?$`)
+
+var relativeTimeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseRelativeTime evaluates a relative time at now: "now", or "now" followed by a signed whole number of
+// s(econds), m(inutes), h(ours), d(ays of 24 hours) or w(eeks), e.g. "now-24h" or "now+7d".
+// Anything else is an ErrValidation.
+func ParseRelativeTime(expr string, now time.Time) (time.Time, error) {
+	match := relativeTimePattern.FindStringSubmatch(expr)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("%w: invalid relative time '%s' (expected now or an offset like now-24h, now-7d or now+30m, units s, m, h, d and w)", customerrors.ErrValidation, expr)
+	}
+	if match[1] == "" {
+		return now, nil
+	}
+
+	n, _ := strconv.ParseInt(match[2], 10, 64)
+	unit := relativeTimeUnits[match[3]]
+	if n > math.MaxInt64/int64(unit) {
+		return time.Time{}, fmt.Errorf("%w: relative time '%s' is out of range", customerrors.ErrValidation, expr)
+	}
+	offset := time.Duration(n) * unit
+	if match[1] == "-" {
+		offset = -offset
+	}
+	return now.Add(offset), nil
+}
+
+// TimeFilterValue resolves the value of a condition on one of the TimestampFields: a relative time is
+// evaluated at now and returned as Unix milliseconds, numbers and numeric strings are returned unchanged.
+func TimeFilterValue(value any, now time.Time) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	if _, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+		return value, nil
+	}
+	t, err := ParseRelativeTime(s, now)
+	if err != nil {
+		return nil, err
+	}
+	return t.UnixMilli(), nil
+}
+
+// MediaFieldType returns the SQL type of a media field of the given Go type (see media.GetMetadataFields).
+func MediaFieldType(goType string) string {
+	switch goType {
+	case "float64":
+		return "REAL"
+	case "bool":
+		return "BOOLEAN"
+	case "string":
+		return "TEXT"
+	default:
+		return "INTEGER"
+	}
+}
+
+// IsOperatorAllowedForType reports whether a filter operator is meaningful on a field of the SQL type, for
+// clients that build queries. Numbers are compared and ordered, text is compared, ordered by its bytes and
+// matched with LIKE and the text match operators, booleans are only compared. SearchEntries only rejects the
+// text match operators on other types than TEXT, the comparisons and LIKE are accepted on every field.
+// MATCH depends on the field, not only its type, see CustomFieldOperators.
+func IsOperatorAllowedForType(op string, fieldType string) bool {
+	switch strings.ToUpper(op) {
+	case "=", "!=":
+		return true
+	case ">", ">=", "<", "<=":
+		return fieldType == "INTEGER" || fieldType == "REAL" || fieldType == "TEXT"
+	case "LIKE", "STARTS_WITH", "ENDS_WITH", "CONTAINS", "CONTAINS_CI", "EQUALS_CI":
+		return fieldType == "TEXT"
+	default:
+		return false
+	}
+}
+
+// OperatorsForType returns the filter operators allowed on a field of the SQL type.
+func OperatorsForType(fieldType string) []string {
+	var ops []string
+	for _, op := range SearchOperators {
+		if IsOperatorAllowedForType(op, fieldType) {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// CustomFieldOperators returns the filter operators allowed on a custom field, including MATCH on full-text fields.
+func CustomFieldOperators(cf CustomFieldDef) []string {
+	ops := OperatorsForType(cf.Type)
+	if cf.IsFulltext {
+		ops = append(ops, "MATCH")
+	}
+	return ops
+}
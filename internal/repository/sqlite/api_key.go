@@ -164,6 +164,7 @@ func (r *SQLiteRepository) GetAPIKeyWithOwnerByHash(ctx context.Context, keyHash
 		"ak.scope_view", "ak.scope_create", "ak.scope_edit", "ak.scope_delete", "ak.scope_admin",
 		"ak.created_at", "ak.expires_at", "ak.last_used_at",
 		"u.id", "u.username", "u.password_hash", "u.is_admin", "u.is_service_account",
+		"u.disabled", "u.last_login_at", "u.last_activity_at", "u.storage_quota_bytes",
 	).
 		From("api_keys ak").
 		Join("users u ON ak.user_id = u.id").
@@ -178,13 +179,14 @@ func (r *SQLiteRepository) GetAPIKeyWithOwnerByHash(ctx context.Context, keyHash
 	var user repo.User
 	var keyIDStr, userIDStr, uIDStr string
 	var createdAtVal int64
-	var expiresAtNull, lastUsedAtNull sql.NullInt64
+	var expiresAtNull, lastUsedAtNull, lastLoginAtNull, lastActivityAtNull sql.NullInt64
 
 	err = r.DB.QueryRowContext(ctx, query, args...).Scan(
 		&keyIDStr, &userIDStr, &key.Name, &key.KeyHash, &key.KeyHint,
 		&scopeView, &scopeCreate, &scopeEdit, &scopeDelete, &scopeAdmin,
 		&createdAtVal, &expiresAtNull, &lastUsedAtNull,
 		&uIDStr, &user.Username, &user.PasswordHash, &user.IsAdmin, &user.IsServiceAccount,
+		&user.Disabled, &lastLoginAtNull, &lastActivityAtNull, &user.StorageQuota,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -205,6 +207,12 @@ func (r *SQLiteRepository) GetAPIKeyWithOwnerByHash(ctx context.Context, keyHash
 	}
 
 	user.ID = repo.ULID(uIDStr)
+	if lastLoginAtNull.Valid {
+		user.LastLoginAt = time.UnixMilli(lastLoginAtNull.Int64)
+	}
+	if lastActivityAtNull.Valid {
+		user.LastActivityAt = time.UnixMilli(lastActivityAtNull.Int64)
+	}
 
 	return key, user, nil
 }
@@ -397,28 +405,6 @@ func (r *SQLiteRepository) DeleteAPIKey(ctx context.Context, id repo.ULID) error
 	return nil
 }
 
-// DeleteExpiredAPIKeys purges all API keys that have passed their expiration date.
-func (r *SQLiteRepository) DeleteExpiredAPIKeys(ctx context.Context) (int64, error) {
-	query, args, err := r.Builder.Delete("api_keys").
-		Where("expires_at IS NOT NULL AND expires_at < ?", time.Now().UnixMilli()).
-		ToSql()
-	if err != nil {
-		return 0, fmt.Errorf("failed to build delete expired api_keys query: %w", err)
-	}
-
-	res, err := r.DB.ExecContext(ctx, query, args...)
-	if err != nil {
-		return 0, fmt.Errorf("failed to delete expired api_keys: %w", err)
-	}
-
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to retrieve rows affected: %w", err)
-	}
-
-	return rowsAffected, nil
-}
-
 // UpdateAPIKeyLastUsed updates only the last_used_at field for the API Key.
 // TODO update to use Duration instead of time
 func (r *SQLiteRepository) UpdateAPIKeyLastUsed(ctx context.Context, id repo.ULID, lastUsed time.Duration) error {
@@ -185,8 +185,8 @@ func TestAPIKeysRepository(t *testing.T) {
 		t.Errorf("expected last_used_at timestamp %v, got %v", now.Unix(), lastUsedRetrieved.LastUsedAt.Unix())
 	}
 
-	// 13. Test DeleteExpiredAPIKeys
-	deletedCount, err := r.DeleteExpiredAPIKeys(ctx)
+	// 13. Expired keys are removed by the sweep of the api_keys table
+	deletedCount, err := r.DeleteExpiredRows(ctx, "api_keys", "expires_at", 500)
 	if err != nil {
 		t.Fatalf("failed to delete expired keys: %v", err)
 	}
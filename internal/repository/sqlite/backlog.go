@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+)
+
+// GetProcessingBacklog returns the databases with processing entries, the database whose oldest entry waits
+// longest first. Every entry table contributes one grouped row with a sample of its oldest entries, which are
+// merged with UNION ALL in batches like GetLargestEntries.
+func (r *SQLiteRepository) GetProcessingBacklog(ctx context.Context, filter repo.BacklogFilter) ([]repo.ProcessingBacklog, error) {
+	dbIDs, err := r.getDatabaseIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	backlog := []repo.ProcessingBacklog{}
+	for batch := range slices.Chunk(dbIDs, largestEntriesBatchSize) {
+		rows, err := r.queryProcessingBacklog(ctx, batch, filter)
+		if err != nil {
+			return nil, err
+		}
+		backlog = append(backlog, rows...)
+	}
+
+	slices.SortStableFunc(backlog, func(a, b repo.ProcessingBacklog) int {
+		return a.OldestSince.Compare(b.OldestSince)
+	})
+	return backlog, nil
+}
+
+func (r *SQLiteRepository) queryProcessingBacklog(ctx context.Context, dbIDs []string, filter repo.BacklogFilter) ([]repo.ProcessingBacklog, error) {
+	if len(dbIDs) == 0 {
+		return nil, nil
+	}
+
+	where := "status = ?"
+	whereArgs := []any{repo.EntryStatusProcessing}
+	if !filter.UploadedBefore.IsZero() {
+		where += " AND created_at < ?"
+		whereArgs = append(whereArgs, filter.UploadedBefore.UnixMilli())
+	}
+
+	parts := make([]string, 0, len(dbIDs))
+	var args []any
+	for _, dbID := range dbIDs {
+		sample := "NULL"
+		var sampleArgs []any
+		if filter.SampleSize > 0 {
+			sample = fmt.Sprintf(`(SELECT group_concat(id) FROM (SELECT id FROM "entries_%s" WHERE %s ORDER BY created_at, id LIMIT ?))`, dbID, where)
+			sampleArgs = append(slices.Clone(whereArgs), filter.SampleSize)
+		}
+		parts = append(parts, fmt.Sprintf(
+			`SELECT ? AS database_id, COUNT(*) AS processing, MIN(created_at) AS oldest, %s AS sample FROM "entries_%s" WHERE %s`,
+			sample, dbID, where))
+		args = append(args, dbID)
+		args = append(args, sampleArgs...)
+		args = append(args, whereArgs...)
+	}
+	query := `SELECT b.database_id, d.name, d.processing_alert_active, b.processing, b.oldest, b.sample FROM (` +
+		strings.Join(parts, " UNION ALL ") +
+		`) AS b JOIN databases d ON d.id = b.database_id WHERE b.processing > 0`
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query processing backlog: %w", err)
+	}
+	defer rows.Close()
+
+	var backlog []repo.ProcessingBacklog
+	for rows.Next() {
+		var b repo.ProcessingBacklog
+		var dbID string
+		var oldest int64
+		var sample sql.NullString
+		if err := rows.Scan(&dbID, &b.DatabaseName, &b.AlertActive, &b.Count, &oldest, &sample); err != nil {
+			return nil, fmt.Errorf("failed to scan processing backlog: %w", err)
+		}
+		b.DatabaseID = repo.ULID(dbID)
+		b.OldestSince = time.UnixMilli(oldest)
+		b.SampleIDs = []int64{}
+		if sample.Valid {
+			for _, s := range strings.Split(sample.String, ",") {
+				id, err := strconv.ParseInt(s, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse processing backlog sample: %w", err)
+				}
+				b.SampleIDs = append(b.SampleIDs, id)
+			}
+		}
+		backlog = append(backlog, b)
+	}
+
+	return backlog, rows.Err()
+}
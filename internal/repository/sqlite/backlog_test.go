@@ -0,0 +1,108 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestProcessingBacklog(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	now := time.Now()
+	var dbs []repo.Database
+	for _, name := range []string{"idle", "busy", "stuck"} {
+		db, err := r.CreateDatabase(ctx, repo.Database{Name: name, ContentType: "file"})
+		if err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		dbs = append(dbs, db)
+	}
+	// seed creates an entry uploaded age ago
+	seed := func(db repo.Database, status repo.EntryStatus, age time.Duration) int64 {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: now, MimeType: "application/octet-stream", Status: status})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		query := fmt.Sprintf(`UPDATE "entries_%s" SET created_at = ? WHERE id = ?`, db.ID)
+		if _, err := r.DB.ExecContext(ctx, query, now.Add(-age).UnixMilli(), entry.ID); err != nil {
+			t.Fatalf("failed to age entry: %v", err)
+		}
+		return entry.ID
+	}
+
+	seed(dbs[0], repo.EntryStatusReady, time.Hour)
+	seed(dbs[0], repo.EntryStatusError, time.Hour)
+	busy := seed(dbs[1], repo.EntryStatusProcessing, 5*time.Minute)
+	seed(dbs[1], repo.EntryStatusProcessing, time.Minute)
+	seed(dbs[1], repo.EntryStatusQueued, 2*time.Hour)
+	stuck1 := seed(dbs[2], repo.EntryStatusProcessing, 3*time.Hour)
+	stuck2 := seed(dbs[2], repo.EntryStatusProcessing, 2*time.Hour)
+	seed(dbs[2], repo.EntryStatusProcessing, 10*time.Second)
+
+	// 1. Only processing entries count, the database waiting longest comes first with its oldest entries
+	backlog, err := r.GetProcessingBacklog(ctx, repo.BacklogFilter{SampleSize: 2})
+	if err != nil {
+		t.Fatalf("failed to get backlog: %v", err)
+	}
+	if len(backlog) != 2 {
+		t.Fatalf("expected the backlog of 2 databases, got %+v", backlog)
+	}
+	if b := backlog[0]; b.DatabaseID != dbs[2].ID || b.DatabaseName != "stuck" || b.Count != 3 || !slices.Equal(b.SampleIDs, []int64{stuck1, stuck2}) || b.AlertActive {
+		t.Errorf("unexpected backlog of the stuck database: %+v", b)
+	}
+	if age := now.Sub(backlog[0].OldestSince); age < 3*time.Hour-time.Second || age > 3*time.Hour+time.Second {
+		t.Errorf("expected the oldest entry 3h ago, got %s", age)
+	}
+	if b := backlog[1]; b.DatabaseID != dbs[1].ID || b.Count != 2 || len(b.SampleIDs) != 2 || b.SampleIDs[0] != busy {
+		t.Errorf("unexpected backlog of the busy database: %+v", b)
+	}
+
+	// 2. The age filter finds the stuck entries, without sample only the numbers are read
+	backlog, err = r.GetProcessingBacklog(ctx, repo.BacklogFilter{UploadedBefore: now.Add(-30 * time.Minute)})
+	if err != nil {
+		t.Fatalf("failed to get backlog: %v", err)
+	}
+	if len(backlog) != 1 || backlog[0].DatabaseID != dbs[2].ID || backlog[0].Count != 2 || len(backlog[0].SampleIDs) != 0 {
+		t.Errorf("expected the 2 stuck entries, got %+v", backlog)
+	}
+
+	// 3. The alert state is reported and only changes once
+	if changed, err := r.SetProcessingAlert(ctx, dbs[2].ID, true); err != nil || !changed {
+		t.Fatalf("expected the alert state to change, got %v (err %v)", changed, err)
+	}
+	if changed, err := r.SetProcessingAlert(ctx, dbs[2].ID, true); err != nil || changed {
+		t.Errorf("expected the alert state to stay, got %v (err %v)", changed, err)
+	}
+	backlog, err = r.GetProcessingBacklog(ctx, repo.BacklogFilter{})
+	if err != nil {
+		t.Fatalf("failed to get backlog: %v", err)
+	}
+	if !backlog[0].AlertActive || backlog[1].AlertActive {
+		t.Errorf("expected the alert of the stuck database only, got %+v", backlog)
+	}
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// IntegrityCheck runs 'PRAGMA integrity_check' and returns an error listing the reported problems.
+func (r *SQLiteRepository) IntegrityCheck(ctx context.Context) error {
+	return integrityCheck(ctx, r.DB)
+}
+
+// FilePath returns the path of the database file, or an empty string for in-memory databases.
+// The connection knows the file it operates on, regardless of how the DSN was written.
+func (r *SQLiteRepository) FilePath(ctx context.Context) (string, error) {
+	var seq int
+	var name, dbPath string
+	if err := r.DB.QueryRowContext(ctx, "PRAGMA database_list").Scan(&seq, &name, &dbPath); err != nil {
+		return "", fmt.Errorf("failed to determine database file: %w", err)
+	}
+	return dbPath, nil
+}
+
+// Backup copies the database file (plus its -wal and -shm files, if present) to a timestamped
+// '.bak' file next to it and verifies the copy with an integrity check. It returns the backup path.
+func (r *SQLiteRepository) Backup(ctx context.Context) (string, error) {
+	dbPath, err := r.FilePath(ctx)
+	if err != nil {
+		return "", err
+	}
+	if dbPath == "" {
+		return "", fmt.Errorf("in-memory databases cannot be backed up")
+	}
+
+	// Move the WAL content into the main file, so the copy does not depend on it
+	if _, err := r.DB.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return "", fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.bak", dbPath, time.Now().Format("20060102-150405"))
+	if err := copyFile(dbPath, backupPath); err != nil {
+		return "", fmt.Errorf("failed to copy database file: %w", err)
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(dbPath + suffix); err != nil {
+			continue
+		}
+		if err := copyFile(dbPath+suffix, backupPath+suffix); err != nil {
+			return "", fmt.Errorf("failed to copy %s file: %w", suffix, err)
+		}
+	}
+
+	// Verify the copy
+	backupDB, err := sql.Open("sqlite", backupPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer backupDB.Close()
+	if err := integrityCheck(ctx, backupDB); err != nil {
+		return "", fmt.Errorf("backup %s is not usable: %w", backupPath, err)
+	}
+
+	return backupPath, nil
+}
+
+func integrityCheck(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("failed to run integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return fmt.Errorf("failed to scan integrity check result: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to run integrity check: %w", err)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// O_EXCL: never overwrite an earlier backup
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
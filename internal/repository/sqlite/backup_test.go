@@ -0,0 +1,85 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestBackupAndPendingMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	dbPath := filepath.Join(t.TempDir(), "mediahub.db")
+	r, err := sqlite.NewRepository(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.UpTo(r.DB, "sqlite", migrations.RequiredVersion-1); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// 1. The last migration is pending and described by its header
+	pending, err := r.PendingMigrations(ctx)
+	if err != nil {
+		t.Fatalf("failed to list pending migrations: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Version != migrations.RequiredVersion {
+		t.Fatalf("expected only migration %d to be pending, got %+v", migrations.RequiredVersion, pending)
+	}
+	if pending[0].Summary == "" || pending[0].Name == "" {
+		t.Errorf("expected the migration with a summary, got %+v", pending[0])
+	}
+
+	// 2. The backup is a verified copy next to the database
+	backupPath, err := r.Backup(ctx)
+	if err != nil {
+		t.Fatalf("backup failed: %v", err)
+	}
+	if filepath.Dir(backupPath) != filepath.Dir(dbPath) || !strings.HasSuffix(backupPath, ".bak") {
+		t.Errorf("expected a .bak file next to the database, got %s", backupPath)
+	}
+
+	backup, err := sqlite.NewRepository(backupPath)
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer backup.Close()
+	if version, err := backup.GetMigrationVersion(ctx); err != nil || version != migrations.RequiredVersion-1 {
+		t.Errorf("expected the backup at version %d, got %d (err %v)", migrations.RequiredVersion-1, version, err)
+	}
+
+	// 3. Applying one by one finishes with ErrNoNextVersion
+	if version, err := r.MigrateUpByOne(ctx); err != nil || version != migrations.RequiredVersion {
+		t.Fatalf("expected to apply migration %d, got %d (err %v)", migrations.RequiredVersion, version, err)
+	}
+	if _, err := r.MigrateUpByOne(ctx); !errors.Is(err, goose.ErrNoNextVersion) {
+		t.Errorf("expected ErrNoNextVersion, got %v", err)
+	}
+	if err := r.IntegrityCheck(ctx); err != nil {
+		t.Errorf("expected a consistent database, got %v", err)
+	}
+
+	// 4. In-memory databases cannot be backed up
+	mem, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer mem.Close()
+	if _, err := mem.Backup(ctx); err == nil {
+		t.Errorf("expected an error for an in-memory database")
+	}
+}
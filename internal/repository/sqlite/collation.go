@@ -0,0 +1,65 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	sqlitedriver "modernc.org/sqlite"
+)
+
+// sortCollators holds the collators of the sort locale. A collator must not be used concurrently, every
+// comparison takes one from the pool.
+var sortCollators atomic.Pointer[sync.Pool]
+
+func init() {
+	SetSortLocale(language.Und)
+	// Collations registered with the driver are created on every connection the pool opens
+	sqlitedriver.MustRegisterCollationUtf8(repo.CollationUnicode, compareUnicode)
+}
+
+// SetSortLocale sets the locale of the unicode collation, the root locale (language.Und) by default. It applies
+// to all repositories of the process and is meant to be set once on startup, before searches run.
+func SetSortLocale(tag language.Tag) {
+	sortCollators.Store(&sync.Pool{New: func() any { return collate.New(tag) }})
+}
+
+// compareUnicode compares by the collation rules of the sort locale. Strings the rules consider equal are
+// ordered by their bytes, so the order is total and stable across pages.
+func compareUnicode(left, right string) int {
+	pool := sortCollators.Load()
+	c := pool.Get().(*collate.Collator)
+	defer pool.Put(c)
+	if result := c.CompareString(left, right); result != 0 {
+		return result
+	}
+	return strings.Compare(left, right)
+}
+
+// sortCollation returns the COLLATE clause of a sort on a field of the SQL type, empty for the default binary
+// order. Collations are only accepted for TEXT fields.
+func sortCollation(collation, fieldType string) (string, error) {
+	if collation == "" {
+		return "", nil
+	}
+	if fieldType != "TEXT" {
+		return "", fmt.Errorf("%w: collation '%s' is only allowed for sorting by a TEXT field", customerrors.ErrValidation, collation)
+	}
+	switch strings.ToLower(collation) {
+	case repo.CollationBinary:
+		return "", nil
+	case repo.CollationNoCase:
+		return " COLLATE NOCASE", nil
+	case repo.CollationUnicode:
+		return " COLLATE " + repo.CollationUnicode, nil
+	default:
+		return "", fmt.Errorf("%w: invalid collation '%s', use '%s', '%s' or '%s'", customerrors.ErrValidation,
+			collation, repo.CollationBinary, repo.CollationNoCase, repo.CollationUnicode)
+	}
+}
@@ -0,0 +1,114 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/text/language"
+)
+
+func TestSortCollation(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "words",
+		ContentType:  "file",
+		CustomFields: []repo.CustomFieldDef{{Name: "description", Type: "TEXT"}, {Name: "pages", Type: "INTEGER"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	descriptions := map[int64]string{}
+	for _, word := range []string{"zebra", "Öl", "apfel", "Banane", "Zucker", "Äpfel", "oma"} {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "word.txt", Timestamp: time.Now(), MimeType: "text/plain",
+			CustomFields: map[string]any{"description": word, "pages": int64(len(word))}})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		descriptions[entry.ID] = word
+	}
+
+	sorted := func(sort repo.SortCriteria) ([]string, error) {
+		t.Helper()
+		entries, err := r.SearchEntries(ctx, db.ID, repo.SearchRequest{Sort: &sort, Pagination: repo.Pagination{Limit: 100}}, db.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		words := []string{}
+		for _, e := range entries {
+			words = append(words, descriptions[e.ID])
+		}
+		return words, nil
+	}
+
+	// 1. Binary compares the bytes, nocase folds ASCII letters only, unicode sorts alphabetically
+	tests := []struct {
+		collation, direction string
+		want                 []string
+	}{
+		{"", "asc", []string{"Banane", "Zucker", "apfel", "oma", "zebra", "Äpfel", "Öl"}},
+		{repo.CollationBinary, "asc", []string{"Banane", "Zucker", "apfel", "oma", "zebra", "Äpfel", "Öl"}},
+		{repo.CollationNoCase, "asc", []string{"apfel", "Banane", "oma", "zebra", "Zucker", "Äpfel", "Öl"}},
+		{"NOCASE", "asc", []string{"apfel", "Banane", "oma", "zebra", "Zucker", "Äpfel", "Öl"}},
+		{repo.CollationUnicode, "asc", []string{"apfel", "Äpfel", "Banane", "Öl", "oma", "zebra", "Zucker"}},
+		{repo.CollationUnicode, "desc", []string{"Zucker", "zebra", "oma", "Öl", "Banane", "Äpfel", "apfel"}},
+	}
+	for _, tc := range tests {
+		got, err := sorted(repo.SortCriteria{Field: "description", Direction: tc.direction, Collation: tc.collation})
+		if err != nil {
+			t.Fatalf("%q %s: failed to search: %v", tc.collation, tc.direction, err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%q %s: expected %v, got %v", tc.collation, tc.direction, tc.want, got)
+		}
+	}
+
+	// 2. The sort locale changes the unicode order, Swedish sorts Ä and Ö after Z
+	sqlite.SetSortLocale(language.Swedish)
+	defer sqlite.SetSortLocale(language.Und)
+	got, err := sorted(repo.SortCriteria{Field: "description", Direction: "asc", Collation: repo.CollationUnicode})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if want := []string{"apfel", "Banane", "oma", "zebra", "Zucker", "Äpfel", "Öl"}; !slices.Equal(got, want) {
+		t.Errorf("expected the Swedish order %v, got %v", want, got)
+	}
+
+	// 3. Collations are refused for fields other than TEXT fields and for unknown names
+	invalid := []repo.SortCriteria{
+		{Field: "pages", Direction: "asc", Collation: repo.CollationNoCase},
+		{Field: "timestamp", Direction: "asc", Collation: repo.CollationUnicode},
+		{Field: repo.SortFieldFulltextRank, Direction: "desc", Collation: repo.CollationUnicode},
+		{Field: "description", Direction: "asc", Collation: "icu"},
+	}
+	for _, sort := range invalid {
+		if _, err := sorted(sort); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("%s collate %q: expected a validation error, got %v", sort.Field, sort.Collation, err)
+		}
+	}
+}
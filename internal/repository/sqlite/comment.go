@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// The comments of a database are kept in the table "entries_<id>_comments". A trigger on the entries
+// table removes the comments of deleted entries, the table itself is dropped with the database.
+
+// commentsTableName returns the quoted name of the comments table of a database.
+func commentsTableName(dbID string) string {
+	return fmt.Sprintf(`"entries_%s_comments"`, dbID)
+}
+
+var commentColumns = []string{"id", "entry_id", "author", "body", "created_at"}
+
+// createCommentsTable creates the comments table of a database and the trigger deleting the comments
+// of deleted entries.
+func createCommentsTable(ctx context.Context, tx *sql.Tx, dbID string) error {
+	tableName := commentsTableName(dbID)
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	entry_id INTEGER NOT NULL,
+	author TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_at BIGINT NOT NULL
+)`, tableName),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_comments_entry" ON %s(entry_id, id)`, dbID, tableName),
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS "entries_%s_comments_ad" AFTER DELETE ON "entries_%s" BEGIN DELETE FROM %s WHERE entry_id = old.id; END`, dbID, dbID, tableName),
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create comments table: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateComment stores a comment on an existing entry, ErrNotFound if the entry does not exist.
+// The check and the insert are a single statement, so a concurrently deleted entry leaves no comment behind.
+func (r *SQLiteRepository) CreateComment(ctx context.Context, dbID repo.ULID, comment repo.Comment) (repo.Comment, error) {
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = time.Now()
+	}
+
+	entryExists := r.Builder.Select("1").
+		From(fmt.Sprintf(`"entries_%s"`, dbID)).
+		Where(squirrel.Eq{"id": comment.EntryID})
+	query, args, err := r.Builder.Insert(commentsTableName(dbID.String())).
+		Columns("entry_id", "author", "body", "created_at").
+		Select(r.Builder.Select().
+			Column("?", comment.EntryID).
+			Column("?", comment.Author).
+			Column("?", comment.Body).
+			Column("?", comment.CreatedAt.UnixMilli()).
+			Where(squirrel.Expr("EXISTS (?)", entryExists))).
+		ToSql()
+	if err != nil {
+		return repo.Comment{}, fmt.Errorf("failed to build insert comment query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return repo.Comment{}, fmt.Errorf("failed to insert comment: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return repo.Comment{}, fmt.Errorf("failed to verify rows affected: %w", err)
+	} else if n == 0 {
+		return repo.Comment{}, customerrors.ErrNotFound
+	}
+
+	if comment.ID, err = res.LastInsertId(); err != nil {
+		return repo.Comment{}, fmt.Errorf("failed to get comment id: %w", err)
+	}
+	return comment, nil
+}
+
+// GetComment retrieves a single comment of a database.
+func (r *SQLiteRepository) GetComment(ctx context.Context, dbID repo.ULID, id int64) (repo.Comment, error) {
+	query, args, err := r.Builder.Select(commentColumns...).
+		From(commentsTableName(dbID.String())).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return repo.Comment{}, fmt.Errorf("failed to build get comment query: %w", err)
+	}
+
+	comment, err := scanComment(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.Comment{}, customerrors.ErrNotFound
+		}
+		return repo.Comment{}, fmt.Errorf("failed to execute get comment query: %w", err)
+	}
+	return comment, nil
+}
+
+// GetComments retrieves a page of the comments of an entry, newest first.
+func (r *SQLiteRepository) GetComments(ctx context.Context, dbID repo.ULID, entryID int64, limit int, offset int) ([]repo.Comment, error) {
+	builder := r.Builder.Select(commentColumns...).
+		From(commentsTableName(dbID.String())).
+		Where(squirrel.Eq{"entry_id": entryID}).
+		OrderBy("id DESC")
+	if limit > 0 {
+		builder = builder.Limit(uint64(limit))
+	}
+	if offset > 0 {
+		builder = builder.Offset(uint64(offset))
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get comments query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get comments query: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []repo.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("comment row iteration error: %w", err)
+	}
+
+	return comments, nil
+}
+
+// CountComments returns the number of comments of the given entries in a single query.
+func (r *SQLiteRepository) CountComments(ctx context.Context, dbID repo.ULID, entryIDs []int64) (map[int64]int64, error) {
+	counts := make(map[int64]int64)
+	if len(entryIDs) == 0 {
+		return counts, nil
+	}
+
+	query, args, err := r.Builder.Select("entry_id", "COUNT(*)").
+		From(commentsTableName(dbID.String())).
+		Where(squirrel.Eq{"entry_id": entryIDs}).
+		GroupBy("entry_id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build count comments query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute count comments query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID, count int64
+		if err := rows.Scan(&entryID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan comment count: %w", err)
+		}
+		counts[entryID] = count
+	}
+	return counts, rows.Err()
+}
+
+// DeleteComment removes a single comment of a database.
+func (r *SQLiteRepository) DeleteComment(ctx context.Context, dbID repo.ULID, id int64) error {
+	query, args, err := r.Builder.Delete(commentsTableName(dbID.String())).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete comment query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute delete comment query: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// scanComment scans a single comments row (in commentColumns order).
+func scanComment(row scanner) (repo.Comment, error) {
+	var comment repo.Comment
+	var createdAt int64
+	if err := row.Scan(&comment.ID, &comment.EntryID, &comment.Author, &comment.Body, &createdAt); err != nil {
+		return repo.Comment{}, err
+	}
+	comment.CreatedAt = time.UnixMilli(createdAt)
+	return comment, nil
+}
@@ -0,0 +1,116 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestCommentsRepository(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "comment_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	var entries []repo.Entry
+	for range 2 {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	// 1. Comments are stored on existing entries only
+	for _, body := range []string{"first", "second", "third"} {
+		if _, err := r.CreateComment(ctx, db.ID, repo.Comment{EntryID: entries[0].ID, Author: "alice", Body: body}); err != nil {
+			t.Fatalf("failed to create comment: %v", err)
+		}
+	}
+	other, err := r.CreateComment(ctx, db.ID, repo.Comment{EntryID: entries[1].ID, Author: "bob", Body: "other"})
+	if err != nil || other.ID == 0 || other.CreatedAt.IsZero() {
+		t.Fatalf("unexpected comment %+v (err %v)", other, err)
+	}
+	if _, err := r.CreateComment(ctx, db.ID, repo.Comment{EntryID: 999, Author: "alice", Body: "lost"}); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing entry, got %v", err)
+	}
+
+	// 2. Pages are returned newest first
+	page, err := r.GetComments(ctx, db.ID, entries[0].ID, 2, 0)
+	if err != nil || len(page) != 2 || page[0].Body != "third" || page[1].Body != "second" {
+		t.Fatalf("unexpected first page %+v (err %v)", page, err)
+	}
+	page, err = r.GetComments(ctx, db.ID, entries[0].ID, 2, 2)
+	if err != nil || len(page) != 1 || page[0].Body != "first" || page[0].Author != "alice" {
+		t.Fatalf("unexpected second page %+v (err %v)", page, err)
+	}
+
+	counts, err := r.CountComments(ctx, db.ID, []int64{entries[0].ID, entries[1].ID, 999})
+	if err != nil || counts[entries[0].ID] != 3 || counts[entries[1].ID] != 1 || len(counts) != 2 {
+		t.Errorf("unexpected counts %v (err %v)", counts, err)
+	}
+
+	// 3. Single comments are deleted, the comments of deleted entries go with them
+	if err := r.DeleteComment(ctx, db.ID, other.ID); err != nil {
+		t.Fatalf("failed to delete comment: %v", err)
+	}
+	if _, err := r.GetComment(ctx, db.ID, other.ID); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected the deleted comment to be gone, got %v", err)
+	}
+	if err := r.DeleteComment(ctx, db.ID, other.ID); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a deleted comment, got %v", err)
+	}
+	if _, err := r.DeleteEntry(ctx, db.ID, entries[0].ID); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if counts, err := r.CountComments(ctx, db.ID, []int64{entries[0].ID}); err != nil || len(counts) != 0 {
+		t.Errorf("expected the comments of the deleted entry to be gone, got %v (err %v)", counts, err)
+	}
+
+	// 4. The migration creates the table for existing databases
+	if err := goose.DownTo(r.DB, "sqlite", 3025); err != nil {
+		t.Fatalf("failed to migrate down: %v", err)
+	}
+	if _, err := r.GetComments(ctx, db.ID, entries[1].ID, 0, 0); err == nil {
+		t.Errorf("expected the comments table to be dropped")
+	}
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to migrate up: %v", err)
+	}
+	if _, err := r.CreateComment(ctx, db.ID, repo.Comment{EntryID: entries[1].ID, Author: "bob", Body: "again"}); err != nil {
+		t.Errorf("failed to create comment after the migration: %v", err)
+	}
+
+	// 5. The table is dropped with the database
+	if err := r.DeleteDatabase(ctx, db.ID); err != nil {
+		t.Fatalf("failed to delete database: %v", err)
+	}
+	var n int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name LIKE '%comments%'`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("expected no comment tables, indexes or triggers left, got %d (err %v)", n, err)
+	}
+}
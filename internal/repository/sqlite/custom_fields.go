@@ -2,11 +2,14 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/cache"
 	"mediahub_oss/internal/shared/customerrors"
 
 	"github.com/Masterminds/squirrel"
@@ -26,14 +29,19 @@ func (r *SQLiteRepository) GetCustomFields(ctx context.Context, dbID repo.ULID)
 	return r.getCustomFields(ctx, r.DB, dbID)
 }
 
+// customFieldsCacheKey is the cache key of the custom fields of a database.
+func customFieldsCacheKey(dbID repo.ULID) string {
+	return cache.Key(cache.NamespaceDatabases, "custom_fields", dbID.String())
+}
+
 // getCustomFields retrieves all custom fields for a specific database with cache backing.
 func (r *SQLiteRepository) getCustomFields(ctx context.Context, q Queryer, dbID repo.ULID) ([]repo.CustomFieldDef, error) {
-	cacheKey := "cf:" + dbID.String()
+	cacheKey := customFieldsCacheKey(dbID)
 	if val, found := r.Cache.Get(cacheKey); found {
 		return val.([]repo.CustomFieldDef), nil
 	}
 
-	query, args, err := r.Builder.Select("field_id", "name", "type", "is_indexed").
+	query, args, err := r.Builder.Select("field_id", "name", "type", "is_indexed", "is_sensitive", "is_fulltext").
 		From("database_custom_fields").
 		Where(squirrel.Eq{"database_id": dbID.String()}).
 		OrderBy("field_id").
@@ -51,7 +59,7 @@ func (r *SQLiteRepository) getCustomFields(ctx context.Context, q Queryer, dbID
 	var fields []repo.CustomFieldDef
 	for rows.Next() {
 		var cf repo.CustomFieldDef
-		if err := rows.Scan(&cf.ID, &cf.Name, &cf.Type, &cf.IsIndexed); err != nil {
+		if err := rows.Scan(&cf.ID, &cf.Name, &cf.Type, &cf.IsIndexed, &cf.IsSensitive, &cf.IsFulltext); err != nil {
 			return nil, err
 		}
 		fields = append(fields, cf)
@@ -72,18 +80,14 @@ func (r *SQLiteRepository) getCustomFields(ctx context.Context, q Queryer, dbID
 // AddCustomField adds a new custom field to an existing database.
 func (r *SQLiteRepository) AddCustomField(ctx context.Context, dbID repo.ULID, field repo.CustomFieldDef) (repo.CustomFieldDef, error) {
 	// Check if database exists
-	var exists bool
-	err := r.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM databases WHERE id = ?)", dbID.String()).Scan(&exists)
+	contentType, err := r.getContentType(ctx, dbID)
 	if err != nil {
-		return repo.CustomFieldDef{}, fmt.Errorf("failed to check database existence: %w", err)
-	}
-	if !exists {
-		return repo.CustomFieldDef{}, customerrors.ErrNotFound
+		return repo.CustomFieldDef{}, err
 	}
 
 	// Validate name
-	if field.Name == "" {
-		return repo.CustomFieldDef{}, fmt.Errorf("%w: field name cannot be empty", customerrors.ErrValidation)
+	if err := repo.ValidateCustomFieldName(field.Name, r.mediaFieldNames(contentType), r.FieldLimits); err != nil {
+		return repo.CustomFieldDef{}, err
 	}
 
 	// Validate type
@@ -92,6 +96,9 @@ func (r *SQLiteRepository) AddCustomField(ctx context.Context, dbID repo.ULID, f
 		return repo.CustomFieldDef{}, fmt.Errorf("%w: unsupported custom field type '%s'", customerrors.ErrValidation, field.Type)
 	}
 
+	// Full-text fields are chosen with the database config
+	field.IsFulltext = false
+
 	// Load existing fields
 	existingFields, err := r.getCustomFields(ctx, r.DB, dbID)
 	if err != nil {
@@ -104,6 +111,9 @@ func (r *SQLiteRepository) AddCustomField(ctx context.Context, dbID repo.ULID, f
 			return repo.CustomFieldDef{}, customerrors.ErrConflict
 		}
 	}
+	if maxCount := r.FieldLimits.Resolved().MaxCount; len(existingFields) >= maxCount {
+		return repo.CustomFieldDef{}, fmt.Errorf("%w: cannot add field '%s', the database already has the maximum of %d custom fields", customerrors.ErrValidation, field.Name, maxCount)
+	}
 
 	// Find the next available ID between 0 and 254
 	usedIDs := make(map[int]bool)
@@ -131,8 +141,8 @@ func (r *SQLiteRepository) AddCustomField(ctx context.Context, dbID repo.ULID, f
 
 	// 1. Insert into database_custom_fields
 	query, args, err := r.Builder.Insert("database_custom_fields").
-		Columns("database_id", "field_id", "name", "type", "is_indexed").
-		Values(dbID.String(), field.ID, field.Name, datatype, field.IsIndexed).
+		Columns("database_id", "field_id", "name", "type", "is_indexed", "is_sensitive").
+		Values(dbID.String(), field.ID, field.Name, datatype, field.IsIndexed, field.IsSensitive).
 		ToSql()
 	if err != nil {
 		return repo.CustomFieldDef{}, err
@@ -165,21 +175,18 @@ func (r *SQLiteRepository) AddCustomField(ctx context.Context, dbID repo.ULID, f
 	}
 
 	// Invalidate cache
-	r.Cache.Delete("cf:" + dbID.String())
+	r.Cache.Delete(customFieldsCacheKey(dbID))
+	r.forgetDatabase(dbID)
 
 	return field, nil
 }
 
 // UpdateCustomField updates an existing custom field.
-func (r *SQLiteRepository) UpdateCustomField(ctx context.Context, dbID repo.ULID, fieldID int, name *string, isIndexed *bool) (repo.CustomFieldDef, error) {
+func (r *SQLiteRepository) UpdateCustomField(ctx context.Context, dbID repo.ULID, fieldID int, name *string, isIndexed *bool, isSensitive *bool) (repo.CustomFieldDef, error) {
 	// Check if database exists
-	var exists bool
-	err := r.DB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM databases WHERE id = ?)", dbID.String()).Scan(&exists)
+	contentType, err := r.getContentType(ctx, dbID)
 	if err != nil {
-		return repo.CustomFieldDef{}, fmt.Errorf("failed to check database existence: %w", err)
-	}
-	if !exists {
-		return repo.CustomFieldDef{}, customerrors.ErrNotFound
+		return repo.CustomFieldDef{}, err
 	}
 
 	// Load existing fields
@@ -204,8 +211,8 @@ func (r *SQLiteRepository) UpdateCustomField(ctx context.Context, dbID repo.ULID
 	newName := targetField.Name
 	if name != nil {
 		newName = *name
-		if newName == "" {
-			return repo.CustomFieldDef{}, fmt.Errorf("%w: name cannot be empty", customerrors.ErrValidation)
+		if err := repo.ValidateCustomFieldName(newName, r.mediaFieldNames(contentType), r.FieldLimits); err != nil {
+			return repo.CustomFieldDef{}, err
 		}
 		// Check name uniqueness if changed
 		if !strings.EqualFold(newName, targetField.Name) {
@@ -222,8 +229,13 @@ func (r *SQLiteRepository) UpdateCustomField(ctx context.Context, dbID repo.ULID
 		newIsIndexed = *isIndexed
 	}
 
+	newIsSensitive := targetField.IsSensitive
+	if isSensitive != nil {
+		newIsSensitive = *isSensitive
+	}
+
 	// If no changes, return early
-	if newName == targetField.Name && newIsIndexed == targetField.IsIndexed {
+	if newName == targetField.Name && newIsIndexed == targetField.IsIndexed && newIsSensitive == targetField.IsSensitive {
 		return *targetField, nil
 	}
 
@@ -252,10 +264,17 @@ func (r *SQLiteRepository) UpdateCustomField(ctx context.Context, dbID repo.ULID
 		}
 	}
 
+	if newName != targetField.Name {
+		if err := renameGeoField(ctx, tx, dbID.String(), targetField.Name, newName); err != nil {
+			return repo.CustomFieldDef{}, err
+		}
+	}
+
 	// Update record
 	query, args, err := r.Builder.Update("database_custom_fields").
 		Set("name", newName).
 		Set("is_indexed", newIsIndexed).
+		Set("is_sensitive", newIsSensitive).
 		Where(squirrel.Eq{"database_id": dbID.String(), "field_id": fieldID}).
 		ToSql()
 	if err != nil {
@@ -274,13 +293,16 @@ func (r *SQLiteRepository) UpdateCustomField(ctx context.Context, dbID repo.ULID
 	}
 
 	// Invalidate cache
-	r.Cache.Delete("cf:" + dbID.String())
+	r.Cache.Delete(customFieldsCacheKey(dbID))
+	r.forgetDatabase(dbID)
 
 	updatedField := repo.CustomFieldDef{
-		ID:        fieldID,
-		Name:      newName,
-		Type:      targetField.Type,
-		IsIndexed: newIsIndexed,
+		ID:          fieldID,
+		Name:        newName,
+		Type:        targetField.Type,
+		IsIndexed:   newIsIndexed,
+		IsSensitive: newIsSensitive,
+		IsFulltext:  targetField.IsFulltext,
 	}
 	return updatedField, nil
 }
@@ -303,10 +325,11 @@ func (r *SQLiteRepository) DeleteCustomField(ctx context.Context, dbID repo.ULID
 		return err
 	}
 
-	var found bool
+	var found, isFulltext bool
+	var name string
 	for _, f := range existingFields {
 		if f.ID == fieldID {
-			found = true
+			found, isFulltext, name = true, f.IsFulltext, f.Name
 			break
 		}
 	}
@@ -314,6 +337,15 @@ func (r *SQLiteRepository) DeleteCustomField(ctx context.Context, dbID repo.ULID
 		return customerrors.ErrNotFound
 	}
 
+	// The coordinates of geo searches cannot go away underneath them
+	geo, err := getGeoFields(ctx, r.DB, dbID.String())
+	if err != nil {
+		return err
+	}
+	if name == geo.Lat || name == geo.Lon {
+		return fmt.Errorf("%w: field '%s' is a geo field of the database, remove it from config.geo_fields first", customerrors.ErrConflict, name)
+	}
+
 	// Begin transaction
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -326,8 +358,16 @@ func (r *SQLiteRepository) DeleteCustomField(ctx context.Context, dbID repo.ULID
 	if _, err := tx.ExecContext(ctx, dropIndexSQL); err != nil {
 		return fmt.Errorf("failed to drop index: %w", err)
 	}
+	if err := dropCustomIndexesOf(ctx, tx, dbID.String(), fmt.Sprintf("%s%d", customFieldsPrefix, fieldID)); err != nil {
+		return err
+	}
 
-	// 2. Drop column from entries table
+	// 2. Drop column from entries table, the full-text triggers referencing it go first
+	if isFulltext {
+		if err := dropFulltextIndex(ctx, tx, dbID.String()); err != nil {
+			return err
+		}
+	}
 	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
 	dropColSQL := fmt.Sprintf(`ALTER TABLE %s DROP COLUMN "%s%d"`, tableName, customFieldsPrefix, fieldID)
 	if _, err := tx.ExecContext(ctx, dropColSQL); err != nil {
@@ -346,12 +386,42 @@ func (r *SQLiteRepository) DeleteCustomField(ctx context.Context, dbID repo.ULID
 		return fmt.Errorf("failed to delete custom field record: %w", err)
 	}
 
+	// 4. Index the remaining full-text fields
+	if isFulltext {
+		if err := r.rebuildFulltextIndex(ctx, tx, dbID.String()); err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 
 	// Invalidate cache
-	r.Cache.Delete("cf:" + dbID.String())
+	r.Cache.Delete(customFieldsCacheKey(dbID))
+	r.forgetDatabase(dbID)
 
 	return nil
 }
+
+// getContentType returns the content type of a database, or ErrNotFound if it does not exist.
+func (r *SQLiteRepository) getContentType(ctx context.Context, dbID repo.ULID) (string, error) {
+	var contentType string
+	err := r.DB.QueryRowContext(ctx, "SELECT content_type FROM databases WHERE id = ?", dbID.String()).Scan(&contentType)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", customerrors.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to check database existence: %w", err)
+	}
+	return contentType, nil
+}
+
+// mediaFieldNames returns the names of the media fields of a content type.
+func (r *SQLiteRepository) mediaFieldNames(contentType string) []string {
+	names := make([]string, len(r.MediaFields[contentType]))
+	for i, f := range r.MediaFields[contentType] {
+		names[i] = f.Name
+	}
+	return names
+}
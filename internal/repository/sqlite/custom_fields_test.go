@@ -0,0 +1,94 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestCustomFieldRules(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	r.FieldLimits = repo.FieldLimits{MaxCount: 3, MaxNameLength: 12}
+
+	fields := func(names ...string) []repo.CustomFieldDef {
+		defs := make([]repo.CustomFieldDef, len(names))
+		for i, name := range names {
+			defs[i] = repo.CustomFieldDef{Name: name, Type: "TEXT"}
+		}
+		return defs
+	}
+
+	for i, tc := range []struct {
+		name        string
+		contentType string
+		fields      []repo.CustomFieldDef
+		wantErr     string // part of the error naming the offending field
+	}{
+		{"standard column", "file", fields("Timestamp"), "'Timestamp' collides with the standard field 'timestamp'"},
+		{"status column", "file", fields("note", "STATUS"), "'STATUS' collides with the standard field 'status'"},
+		{"response key", "file", fields("error_reason"), "'error_reason' collides with the standard field 'error_reason'"},
+		{"media field", "image", fields("Width"), "'Width' collides with the media field 'width'"},
+		{"duplicate", "file", fields("note", "Note"), "'Note' collides with custom field 'note'"},
+		{"too many", "file", fields("a", "b", "c", "d"), "4 custom fields exceed the maximum of 3"},
+		{"too long", "file", fields("long_description"), "'long_description' has 16 characters, the maximum is 12"},
+		{"empty", "file", fields(""), "name cannot be empty"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := r.CreateDatabase(ctx, repo.Database{Name: fmt.Sprintf("rejected_%d", i), ContentType: tc.contentType, CustomFields: tc.fields})
+			if !errors.Is(err, customerrors.ErrValidation) || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected a validation error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// A previously valid definition still passes, media field names are only reserved for their content type
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "valid", ContentType: "file", CustomFields: []repo.CustomFieldDef{
+		{Name: "width", Type: "INTEGER"},
+		{Name: "note", Type: "TEXT"},
+	}})
+	if err != nil {
+		t.Fatalf("expected the definition to be accepted, got %v", err)
+	}
+
+	// Adding and renaming fields follow the same rules
+	if _, err := r.AddCustomField(ctx, db.ID, repo.CustomFieldDef{Name: "Filename", Type: "TEXT"}); !errors.Is(err, customerrors.ErrValidation) {
+		t.Errorf("expected a validation error for a standard field name, got %v", err)
+	}
+	if _, err := r.AddCustomField(ctx, db.ID, repo.CustomFieldDef{Name: "NOTE", Type: "TEXT"}); !errors.Is(err, customerrors.ErrConflict) {
+		t.Errorf("expected a conflict for an existing field name, got %v", err)
+	}
+	if _, err := r.AddCustomField(ctx, db.ID, repo.CustomFieldDef{Name: "rating", Type: "INTEGER"}); err != nil {
+		t.Fatalf("failed to add field: %v", err)
+	}
+	if _, err := r.AddCustomField(ctx, db.ID, repo.CustomFieldDef{Name: "extra", Type: "TEXT"}); err == nil || !strings.Contains(err.Error(), "maximum of 3") {
+		t.Errorf("expected the field count limit, got %v", err)
+	}
+	newName := "mime_type"
+	if _, err := r.UpdateCustomField(ctx, db.ID, 1, &newName, nil, nil); !errors.Is(err, customerrors.ErrValidation) {
+		t.Errorf("expected a validation error when renaming to a standard field name, got %v", err)
+	}
+}
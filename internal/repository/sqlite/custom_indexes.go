@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// Composite indexes are named after their columns, e.g. "idx_entries_<id>_ix_status__timestamp". No column
+// name contains the separator, so the name is deterministic, differs for every column list and gives the
+// columns back. Renaming a custom field keeps its column, and with it the index.
+const (
+	customIndexInfix     = "_ix_"
+	customIndexSeparator = "__"
+)
+
+// customIndexName returns the name of the composite index over the columns of an entry table.
+func customIndexName(dbID string, columns []string) string {
+	return fmt.Sprintf("idx_entries_%s%s%s", dbID, customIndexInfix, strings.Join(columns, customIndexSeparator))
+}
+
+// buildCustomIndexSQL returns the statement creating the composite index over the columns.
+func buildCustomIndexSQL(dbID string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = fmt.Sprintf(`"%s"`, column)
+	}
+	return fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s" ON "entries_%s"(%s);`, customIndexName(dbID, columns), dbID, strings.Join(quoted, ", "))
+}
+
+// customIndexColumns validates the composite indexes of a database and returns their columns.
+func (r *SQLiteRepository) customIndexColumns(contentType string, customFields []repo.CustomFieldDef, indexes [][]string) ([][]string, error) {
+	if err := repo.ValidateCustomIndexes(indexes, repo.IndexFieldNames(r.mediaFieldNames(contentType), customFields)); err != nil {
+		return nil, err
+	}
+	columns := make([][]string, len(indexes))
+	for i, index := range indexes {
+		columns[i] = make([]string, len(index))
+		for j, field := range index {
+			columns[i][j] = field
+			for _, cf := range customFields {
+				if cf.Name == field {
+					columns[i][j] = fmt.Sprintf("%s%d", customFieldsPrefix, cf.ID)
+					break
+				}
+			}
+		}
+	}
+	return columns, nil
+}
+
+// readCustomIndexes returns the columns of the composite indexes of the entry tables by database ID,
+// in the order they were created. With a dbID only the indexes of that database are read.
+func readCustomIndexes(ctx context.Context, q Queryer, dbID string) (map[string][][]string, error) {
+	query := `SELECT tbl_name, name FROM sqlite_master WHERE type = 'index' AND name LIKE 'idx\_entries\_%\_ix\_%' ESCAPE '\'`
+	var args []any
+	if dbID != "" {
+		query += " AND tbl_name = ?"
+		args = append(args, "entries_"+dbID)
+	}
+	rows, err := q.QueryContext(ctx, query+" ORDER BY rowid", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read custom indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][][]string)
+	for rows.Next() {
+		var table, name string
+		if err := rows.Scan(&table, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan custom index: %w", err)
+		}
+		id := strings.TrimPrefix(table, "entries_")
+		columns, ok := strings.CutPrefix(name, "idx_entries_"+id+customIndexInfix)
+		if !ok {
+			continue
+		}
+		indexes[id] = append(indexes[id], strings.Split(columns, customIndexSeparator))
+	}
+	return indexes, rows.Err()
+}
+
+// customIndexFields maps the columns of composite indexes to the field names of the database.
+func customIndexFields(columns [][]string, customFields []repo.CustomFieldDef) [][]string {
+	indexes := make([][]string, len(columns))
+	for i, index := range columns {
+		indexes[i] = make([]string, len(index))
+		for j, column := range index {
+			indexes[i][j] = column
+			if fieldID, ok := strings.CutPrefix(column, customFieldsPrefix); ok {
+				id, _ := strconv.Atoi(fieldID)
+				if k := slices.IndexFunc(customFields, func(cf repo.CustomFieldDef) bool { return cf.ID == id }); k >= 0 {
+					indexes[i][j] = customFields[k].Name
+				}
+			}
+		}
+	}
+	return indexes
+}
+
+// getCustomIndexes returns the composite indexes of a database by field name.
+func (r *SQLiteRepository) getCustomIndexes(ctx context.Context, q Queryer, dbID repo.ULID, customFields []repo.CustomFieldDef) ([][]string, error) {
+	columns, err := readCustomIndexes(ctx, q, dbID.String())
+	if err != nil {
+		return nil, err
+	}
+	return customIndexFields(columns[dbID.String()], customFields), nil
+}
+
+// AddCustomIndexes creates the composite indexes of a database that do not exist yet. Each index is created
+// in a transaction of its own, so the entries of a large database can be written between them.
+func (r *SQLiteRepository) AddCustomIndexes(ctx context.Context, dbID repo.ULID, indexes [][]string) ([][]string, error) {
+	contentType, err := r.getContentType(ctx, dbID)
+	if err != nil {
+		return nil, err
+	}
+	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := r.getCustomIndexes(ctx, r.DB, dbID, customFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing [][]string
+	for _, index := range indexes {
+		if !slices.ContainsFunc(existing, func(have []string) bool { return slices.Equal(have, index) }) {
+			missing = append(missing, index)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	if len(existing)+len(missing) > repo.MaxCustomIndexes {
+		return nil, fmt.Errorf("%w: %d more custom indexes exceed the maximum of %d per database", customerrors.ErrValidation, len(missing), repo.MaxCustomIndexes)
+	}
+	columns, err := r.customIndexColumns(contentType, customFields, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	var added [][]string
+	for i, index := range columns {
+		err := r.WithTx(ctx, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, buildCustomIndexSQL(dbID.String(), index))
+			return err
+		})
+		if err != nil {
+			return added, fmt.Errorf("failed to create custom index (%s): %w", strings.Join(missing[i], ", "), err)
+		}
+		added = append(added, missing[i])
+	}
+	r.forgetDatabase(dbID)
+	return added, nil
+}
+
+// dropCustomIndexesOf drops the composite indexes of an entry table that contain the column.
+func dropCustomIndexesOf(ctx context.Context, tx *sql.Tx, dbID string, column string) error {
+	indexes, err := readCustomIndexes(ctx, tx, dbID)
+	if err != nil {
+		return err
+	}
+	for _, columns := range indexes[dbID] {
+		if !slices.Contains(columns, column) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP INDEX IF EXISTS "%s"`, customIndexName(dbID, columns))); err != nil {
+			return fmt.Errorf("failed to drop custom index: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestCustomIndexes(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:          "detections",
+		ContentType:   "file",
+		CustomFields:  []repo.CustomFieldDef{{Name: "is_vehicle", Type: "BOOLEAN"}, {Name: "ml_score", Type: "REAL"}, {Name: "camera", Type: "TEXT"}},
+		CustomIndexes: [][]string{{"status", "timestamp"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	explain := func() repo.QueryPlan {
+		t.Helper()
+		req := repo.SearchRequest{Filter: &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{
+			{Field: "is_vehicle", Operator: "=", Value: true},
+			{Field: "ml_score", Operator: ">", Value: 0.9},
+		}}}
+		plan, err := r.ExplainSearch(ctx, db.ID, req, db.CustomFields)
+		if err != nil {
+			t.Fatalf("failed to explain search: %v", err)
+		}
+		return plan
+	}
+	details := func(plan repo.QueryPlan) string {
+		var lines []string
+		for _, step := range plan.Steps {
+			lines = append(lines, step.Detail)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	// 1. The index of the definition is created with the database and reported by name
+	got, err := r.GetDatabase(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	if len(got.CustomIndexes) != 1 || !slices.Equal(got.CustomIndexes[0], []string{"status", "timestamp"}) {
+		t.Errorf("expected the index (status, timestamp), got %v", got.CustomIndexes)
+	}
+
+	// 2. Without a composite index the custom fields are scanned
+	before := explain()
+	if !strings.Contains(before.SQL, "cf_0") || !strings.Contains(details(before), "SCAN") {
+		t.Fatalf("expected a SCAN before declaring the index, got %q for %s", details(before), before.SQL)
+	}
+
+	// 3. Declaring the index turns it into a SEARCH, the name is derived from the columns
+	added, err := r.AddCustomIndexes(ctx, db.ID, [][]string{{"status", "timestamp"}, {"is_vehicle", "ml_score"}})
+	if err != nil {
+		t.Fatalf("failed to add custom index: %v", err)
+	}
+	if len(added) != 1 || !slices.Equal(added[0], []string{"is_vehicle", "ml_score"}) {
+		t.Errorf("expected only the missing index to be added, got %v", added)
+	}
+	after := details(explain())
+	if want := "SEARCH entries_" + db.ID.String() + " USING INDEX idx_entries_" + db.ID.String() + "_ix_cf_0__cf_1"; !strings.Contains(after, want) {
+		t.Errorf("expected %q after declaring the index, got %q", want, after)
+	}
+	if added, err := r.AddCustomIndexes(ctx, db.ID, [][]string{{"is_vehicle", "ml_score"}}); err != nil || len(added) != 0 {
+		t.Errorf("expected adding an existing index to do nothing, got %v, %v", added, err)
+	}
+
+	// 4. Renaming a custom field keeps its index
+	name := "vehicle"
+	if _, err := r.UpdateCustomField(ctx, db.ID, 0, &name, nil, nil); err != nil {
+		t.Fatalf("failed to rename custom field: %v", err)
+	}
+	databases, err := r.GetDatabases(ctx)
+	if err != nil || len(databases) != 1 {
+		t.Fatalf("failed to get databases: %v", err)
+	}
+	if want := [][]string{{"status", "timestamp"}, {"vehicle", "ml_score"}}; !slices.EqualFunc(databases[0].CustomIndexes, want, slices.Equal) {
+		t.Errorf("expected the indexes %v, got %v", want, databases[0].CustomIndexes)
+	}
+
+	// 5. Invalid indexes are rejected before anything is created
+	for _, indexes := range [][][]string{
+		{{"ml_score"}},
+		{{"ml_score", "unknown"}},
+		{{"ml_score", "ml_score"}},
+		{{"camera", "ml_score"}, {"camera", "ml_score"}},
+	} {
+		if _, err := r.AddCustomIndexes(ctx, db.ID, indexes); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("%v: expected a validation error, got %v", indexes, err)
+		}
+	}
+
+	// 6. Deleting a custom field drops the indexes that contain it
+	if err := r.DeleteCustomField(ctx, db.ID, 1); err != nil {
+		t.Fatalf("failed to delete custom field: %v", err)
+	}
+	if got, err = r.GetDatabase(ctx, db.ID); err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	if len(got.CustomIndexes) != 1 || !slices.Equal(got.CustomIndexes[0], []string{"status", "timestamp"}) {
+		t.Errorf("expected only the index (status, timestamp) to remain, got %v", got.CustomIndexes)
+	}
+}
@@ -8,10 +8,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
 	repo "mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared"
 	"mediahub_oss/internal/shared/customerrors"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 )
@@ -22,6 +25,9 @@ func (r *SQLiteRepository) CreateDatabase(ctx context.Context, db repo.Database)
 	if !safeNameRegex.MatchString(db.Name) {
 		return repo.Database{}, fmt.Errorf("%w: database name contains invalid characters", customerrors.ErrInvalidName)
 	}
+	if err := repo.ValidateCustomFields(db.CustomFields, r.mediaFieldNames(db.ContentType), r.FieldLimits); err != nil {
+		return repo.Database{}, err
+	}
 
 	// Generate ULID if not provided by the handler
 	if db.ID == "" {
@@ -32,6 +38,26 @@ func (r *SQLiteRepository) CreateDatabase(ctx context.Context, db repo.Database)
 	if !db.Housekeeping.LastHkRun.IsZero() {
 		hkLastRunMs = db.Housekeeping.LastHkRun.UnixMilli()
 	}
+	conversionRules, err := encodeConversionRules(db.Config.ConversionRules)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	transcription, err := encodeTranscription(db.Config.Transcription)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	metadataDefaults, err := encodeMetadataValues(db.Config.MetadataDefaults)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	metadataOverrides, err := encodeMetadataValues(db.Config.MetadataOverrides)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	geoFields, err := encodeGeoFields(db.Config.GeoFields)
+	if err != nil {
+		return repo.Database{}, err
+	}
 
 	// Assign sequential IDs for custom fields if not set or just force sequential
 	for i := range db.CustomFields {
@@ -44,6 +70,13 @@ func (r *SQLiteRepository) CreateDatabase(ctx context.Context, db repo.Database)
 		return repo.Database{}, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
 	}
 	indexSQLs := BuildIndexesSQL(db.ID.String(), db.CustomFields)
+	customIndexes, err := r.customIndexColumns(db.ContentType, db.CustomFields, db.CustomIndexes)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	for _, columns := range customIndexes {
+		indexSQLs = append(indexSQLs, buildCustomIndexSQL(db.ID.String(), columns))
+	}
 
 	// 2. Execute within a transaction
 	tx, err := r.DB.BeginTx(ctx, nil)
@@ -52,9 +85,20 @@ func (r *SQLiteRepository) CreateDatabase(ctx context.Context, db repo.Database)
 	}
 	defer tx.Rollback()
 
+	// Count within the transaction, so concurrent creations cannot both take the last slot
+	if r.CapacityLimits.MaxDatabases > 0 {
+		var count int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM databases").Scan(&count); err != nil {
+			return repo.Database{}, fmt.Errorf("failed to count databases: %w", err)
+		}
+		if err := r.CapacityLimits.CheckDatabases(count); err != nil {
+			return repo.Database{}, err
+		}
+	}
+
 	// Insert metadata into the main databases table (without custom_fields column)
 	query, args, err := r.Builder.Insert("databases").
-		Columns("id", "name", "content_type", "hk_interval", "hk_disk_space", "hk_max_age", "create_preview", "auto_conversion", "n_max_queued", "hk_last_run").
+		Columns("id", "name", "content_type", "hk_interval", "hk_disk_space", "hk_max_age", "create_preview", "auto_conversion", "keep_original", "unique_external_id", "n_max_queued", "hk_last_run", "hk_disk_space_warn_percent", "conversion_rules", "transcription", "public_read", "hk_age_basis", "metadata_defaults", "metadata_overrides", "waveform_width", "waveform_height", "waveform_color", "waveform_background", "geo_fields").
 		Values(
 			db.ID,
 			db.Name,
@@ -64,8 +108,22 @@ func (r *SQLiteRepository) CreateDatabase(ctx context.Context, db repo.Database)
 			db.Housekeeping.MaxAge.Milliseconds(), // Converted to ms
 			db.Config.CreatePreview,
 			db.Config.AutoConversion,
+			db.Config.KeepOriginal,
+			db.Config.UniqueExternalID,
 			db.NMaxQueued,
 			hkLastRunMs,
+			db.Housekeeping.DiskSpaceWarnPercent,
+			conversionRules,
+			transcription,
+			db.Config.PublicRead,
+			db.Housekeeping.AgeBasis,
+			metadataDefaults,
+			metadataOverrides,
+			db.Config.Waveform.Width,
+			db.Config.Waveform.Height,
+			db.Config.Waveform.Color,
+			db.Config.Waveform.Background,
+			geoFields,
 		).
 		ToSql()
 	if err != nil {
@@ -84,8 +142,8 @@ func (r *SQLiteRepository) CreateDatabase(ctx context.Context, db repo.Database)
 	for _, cf := range db.CustomFields {
 		datatype := strings.ToUpper(cf.Type)
 		cfQuery, cfArgs, err := r.Builder.Insert("database_custom_fields").
-			Columns("database_id", "field_id", "name", "type", "is_indexed").
-			Values(db.ID, cf.ID, cf.Name, datatype, cf.IsIndexed).
+			Columns("database_id", "field_id", "name", "type", "is_indexed", "is_sensitive", "is_fulltext").
+			Values(db.ID, cf.ID, cf.Name, datatype, cf.IsIndexed, cf.IsSensitive, cf.IsFulltext).
 			ToSql()
 		if err != nil {
 			return repo.Database{}, fmt.Errorf("failed to build custom field insert query: %w", err)
@@ -106,6 +164,31 @@ func (r *SQLiteRepository) CreateDatabase(ctx context.Context, db repo.Database)
 			return repo.Database{}, fmt.Errorf("failed to create index: %w", err)
 		}
 	}
+	if db.Config.UniqueExternalID {
+		if err := setExternalIDUniqueness(ctx, tx, db.ID.String(), true); err != nil {
+			return repo.Database{}, err
+		}
+	}
+	if err := setGeoIndex(ctx, tx, db.ID.String(), db.Config.GeoFields, db.CustomFields); err != nil {
+		return repo.Database{}, err
+	}
+
+	// The table is empty, so the full-text index needs no backfill
+	var fulltextIDs []int
+	for _, cf := range db.CustomFields {
+		if cf.IsFulltext {
+			fulltextIDs = append(fulltextIDs, cf.ID)
+		}
+	}
+	if err := createFulltextIndex(ctx, tx, db.ID.String(), fulltextIDs); err != nil {
+		return repo.Database{}, err
+	}
+	if err := createCommentsTable(ctx, tx, db.ID.String()); err != nil {
+		return repo.Database{}, err
+	}
+	if err := createShareLinksTrigger(ctx, tx, db.ID.String()); err != nil {
+		return repo.Database{}, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return repo.Database{}, fmt.Errorf("failed to commit transaction: %w", err)
@@ -115,8 +198,41 @@ func (r *SQLiteRepository) CreateDatabase(ctx context.Context, db repo.Database)
 }
 
 // GetDatabase retrieves a single database configuration by its ULID.
+// Concurrent lookups of the same database share one query (see dbLookups), every caller
+// still returns as soon as its own context is done.
 func (r *SQLiteRepository) GetDatabase(ctx context.Context, dbID repo.ULID) (repo.Database, error) {
-	query, args, err := r.Builder.Select("id", "name", "content_type", "hk_interval", "hk_disk_space", "hk_max_age", "create_preview", "auto_conversion", "n_max_queued", "hk_last_run", "entry_count", "total_disk_space_bytes").
+	// The shared query must not fail because the caller that started it went away
+	lookup := r.dbLookups.DoChan(dbID.String(), func() (any, error) {
+		return r.getDatabase(context.WithoutCancel(ctx), dbID)
+	})
+
+	select {
+	case <-ctx.Done():
+		return repo.Database{}, ctx.Err()
+	case res := <-lookup:
+		if res.Err != nil {
+			return repo.Database{}, res.Err
+		}
+		db := res.Val.(repo.Database)
+		if res.Shared {
+			// The custom fields are shared through the cache anyway, the conversion rules and metadata values are not
+			db.Config.ConversionRules = slices.Clone(db.Config.ConversionRules)
+			db.Config.MetadataDefaults = maps.Clone(db.Config.MetadataDefaults)
+			db.Config.MetadataOverrides = maps.Clone(db.Config.MetadataOverrides)
+		}
+		return db, nil
+	}
+}
+
+// forgetDatabase makes lookups that start after a write to a database run a new query,
+// instead of sharing one that may have read the database before the write.
+func (r *SQLiteRepository) forgetDatabase(dbID repo.ULID) {
+	r.dbLookups.Forget(dbID.String())
+}
+
+// getDatabase reads a database configuration, GetDatabase without the coalescing.
+func (r *SQLiteRepository) getDatabase(ctx context.Context, dbID repo.ULID) (repo.Database, error) {
+	query, args, err := r.Builder.Select("id", "name", "content_type", "hk_interval", "hk_disk_space", "hk_max_age", "create_preview", "auto_conversion", "keep_original", "unique_external_id", "n_max_queued", "hk_last_run", "entry_count", "total_disk_space_bytes", "hk_disk_space_warn_percent", "disk_space_alert_active", "conversion_rules", "transcription", "public_read", "hk_age_basis", "metadata_defaults", "metadata_overrides", "waveform_width", "waveform_height", "waveform_color", "waveform_background", "geo_fields").
 		From("databases").
 		Where(squirrel.Eq{"id": dbID.String()}).
 		ToSql()
@@ -129,6 +245,11 @@ func (r *SQLiteRepository) GetDatabase(ctx context.Context, dbID repo.ULID) (rep
 	if err != nil {
 		return repo.Database{}, err
 	}
+	processing, err := r.processingCounts(ctx, []string{db.ID.String()})
+	if err != nil {
+		return repo.Database{}, err
+	}
+	db.Stats.ProcessingCount = processing[db.ID.String()]
 
 	// Load custom fields
 	cfs, err := r.getCustomFields(ctx, r.DB, db.ID)
@@ -137,12 +258,16 @@ func (r *SQLiteRepository) GetDatabase(ctx context.Context, dbID repo.ULID) (rep
 	}
 	db.CustomFields = cfs
 
+	if db.CustomIndexes, err = r.getCustomIndexes(ctx, r.DB, db.ID, cfs); err != nil {
+		return repo.Database{}, err
+	}
+
 	return db, nil
 }
 
 // GetDatabases retrieves all available database configurations.
 func (r *SQLiteRepository) GetDatabases(ctx context.Context) ([]repo.Database, error) {
-	query, args, err := r.Builder.Select("id", "name", "content_type", "hk_interval", "hk_disk_space", "hk_max_age", "create_preview", "auto_conversion", "n_max_queued", "hk_last_run", "entry_count", "total_disk_space_bytes").
+	query, args, err := r.Builder.Select("id", "name", "content_type", "hk_interval", "hk_disk_space", "hk_max_age", "create_preview", "auto_conversion", "keep_original", "unique_external_id", "n_max_queued", "hk_last_run", "entry_count", "total_disk_space_bytes", "hk_disk_space_warn_percent", "disk_space_alert_active", "conversion_rules", "transcription", "public_read", "hk_age_basis", "metadata_defaults", "metadata_overrides", "waveform_width", "waveform_height", "waveform_color", "waveform_background", "geo_fields").
 		From("databases").
 		ToSql()
 	if err != nil {
@@ -174,7 +299,7 @@ func (r *SQLiteRepository) GetDatabases(ctx context.Context) ([]repo.Database, e
 	}
 
 	// Fetch all custom fields and group them by database ID
-	cfQuery, cfArgs, err := r.Builder.Select("database_id", "field_id", "name", "type", "is_indexed").
+	cfQuery, cfArgs, err := r.Builder.Select("database_id", "field_id", "name", "type", "is_indexed", "is_sensitive", "is_fulltext").
 		From("database_custom_fields").
 		OrderBy("database_id", "field_id").
 		ToSql()
@@ -192,7 +317,7 @@ func (r *SQLiteRepository) GetDatabases(ctx context.Context) ([]repo.Database, e
 	for cfRows.Next() {
 		var dbID string
 		var cf repo.CustomFieldDef
-		if err := cfRows.Scan(&dbID, &cf.ID, &cf.Name, &cf.Type, &cf.IsIndexed); err != nil {
+		if err := cfRows.Scan(&dbID, &cf.ID, &cf.Name, &cf.Type, &cf.IsIndexed, &cf.IsSensitive, &cf.IsFulltext); err != nil {
 			return nil, fmt.Errorf("failed to scan custom field: %w", err)
 		}
 		cfMap[dbID] = append(cfMap[dbID], cf)
@@ -201,24 +326,63 @@ func (r *SQLiteRepository) GetDatabases(ctx context.Context) ([]repo.Database, e
 		return nil, fmt.Errorf("custom fields row iteration error: %w", err)
 	}
 
+	// Composite indexes are read from the schema, one query for all entry tables
+	customIndexes, err := readCustomIndexes(ctx, r.DB, "")
+	if err != nil {
+		return nil, err
+	}
+
+	dbIDs := make([]string, 0, len(databases))
+	for _, db := range databases {
+		dbIDs = append(dbIDs, db.ID.String())
+	}
+	processing, err := r.processingCounts(ctx, dbIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	for i := range databases {
+		databases[i].Stats.ProcessingCount = processing[databases[i].ID.String()]
 		if cfs, ok := cfMap[databases[i].ID.String()]; ok {
 			databases[i].CustomFields = cfs
 		} else {
 			databases[i].CustomFields = []repo.CustomFieldDef{}
 		}
+		databases[i].CustomIndexes = customIndexFields(customIndexes[databases[i].ID.String()], databases[i].CustomFields)
 	}
 
 	return databases, nil
 }
 
-// UpdateDatabase updates the mutable configuration fields of a database, including its name.
+// UpdateDatabase updates the mutable configuration fields of a database, including its name, and the
+// IsFulltext flags of the given custom fields. Changing them rebuilds the full-text index and queues the
+// backfill of the existing entries.
 func (r *SQLiteRepository) UpdateDatabase(ctx context.Context, db repo.Database) (repo.Database, error) {
 
 	var hkLastRunMs int64 = 0
 	if !db.Housekeeping.LastHkRun.IsZero() {
 		hkLastRunMs = db.Housekeeping.LastHkRun.UnixMilli()
 	}
+	conversionRules, err := encodeConversionRules(db.Config.ConversionRules)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	transcription, err := encodeTranscription(db.Config.Transcription)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	metadataDefaults, err := encodeMetadataValues(db.Config.MetadataDefaults)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	metadataOverrides, err := encodeMetadataValues(db.Config.MetadataOverrides)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	geoFields, err := encodeGeoFields(db.Config.GeoFields)
+	if err != nil {
+		return repo.Database{}, err
+	}
 
 	query, args, err := r.Builder.Update("databases").
 		Set("name", db.Name).                                        // We can now safely update the name!
@@ -226,8 +390,22 @@ func (r *SQLiteRepository) UpdateDatabase(ctx context.Context, db repo.Database)
 		Set("hk_disk_space", db.Housekeeping.DiskSpace).
 		Set("hk_max_age", db.Housekeeping.MaxAge.Milliseconds()). // Converted to ms
 		Set("hk_last_run", hkLastRunMs).
+		Set("hk_disk_space_warn_percent", db.Housekeeping.DiskSpaceWarnPercent).
+		Set("hk_age_basis", db.Housekeeping.AgeBasis).
 		Set("create_preview", db.Config.CreatePreview).
 		Set("auto_conversion", db.Config.AutoConversion).
+		Set("keep_original", db.Config.KeepOriginal).
+		Set("unique_external_id", db.Config.UniqueExternalID).
+		Set("conversion_rules", conversionRules).
+		Set("transcription", transcription).
+		Set("public_read", db.Config.PublicRead).
+		Set("metadata_defaults", metadataDefaults).
+		Set("metadata_overrides", metadataOverrides).
+		Set("waveform_width", db.Config.Waveform.Width).
+		Set("waveform_height", db.Config.Waveform.Height).
+		Set("waveform_color", db.Config.Waveform.Color).
+		Set("waveform_background", db.Config.Waveform.Background).
+		Set("geo_fields", geoFields).
 		Set("n_max_queued", db.NMaxQueued).
 		Set("entry_count", db.Stats.EntryCount).
 		Set("total_disk_space_bytes", db.Stats.TotalDiskSpaceBytes).
@@ -237,14 +415,54 @@ func (r *SQLiteRepository) UpdateDatabase(ctx context.Context, db repo.Database)
 		return repo.Database{}, fmt.Errorf("failed to build update query: %w", err)
 	}
 
-	res, err := r.DB.ExecContext(ctx, query, args...)
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
+		return repo.Database{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var wasUnique bool
+	var oldGeoFields string
+	if err := tx.QueryRowContext(ctx, "SELECT unique_external_id, geo_fields FROM databases WHERE id = ?", db.ID).Scan(&wasUnique, &oldGeoFields); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.Database{}, customerrors.ErrNotFound
+		}
+		return repo.Database{}, fmt.Errorf("failed to query database config: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
 		return repo.Database{}, fmt.Errorf("failed to execute update: %w", err)
 	}
 
-	rowsAffected, _ := res.RowsAffected()
-	if rowsAffected == 0 {
-		return repo.Database{}, customerrors.ErrNotFound
+	// Enabling the uniqueness fails if existing entries already share an external ID
+	if wasUnique != db.Config.UniqueExternalID {
+		if err := setExternalIDUniqueness(ctx, tx, db.ID.String(), db.Config.UniqueExternalID); err != nil {
+			return repo.Database{}, err
+		}
+	}
+
+	if oldGeoFields != geoFields {
+		if err := setGeoIndex(ctx, tx, db.ID.String(), db.Config.GeoFields, db.CustomFields); err != nil {
+			return repo.Database{}, err
+		}
+	}
+
+	fulltextChanged, err := r.updateFulltextFlags(ctx, tx, db)
+	if err != nil {
+		return repo.Database{}, err
+	}
+	if fulltextChanged {
+		if err := r.rebuildFulltextIndex(ctx, tx, db.ID.String()); err != nil {
+			return repo.Database{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repo.Database{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	r.forgetDatabase(db.ID)
+	if fulltextChanged {
+		r.Cache.Delete(customFieldsCacheKey(db.ID))
 	}
 
 	return r.GetDatabase(ctx, db.ID)
@@ -258,7 +476,13 @@ func (r *SQLiteRepository) DeleteDatabase(ctx context.Context, dbID repo.ULID) e
 	}
 	defer tx.Rollback()
 
-	// Drop the dynamic entry table using the ULID
+	// Drop the dynamic entry table using the ULID, its triggers go with it
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, fulltextTableName(dbID.String()))); err != nil {
+		return fmt.Errorf("failed to drop full-text table: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, commentsTableName(dbID.String()))); err != nil {
+		return fmt.Errorf("failed to drop comments table: %w", err)
+	}
 	dropTableSQL := fmt.Sprintf(`DROP TABLE IF EXISTS "entries_%s"`, dbID.String())
 	if _, err := tx.ExecContext(ctx, dropTableSQL); err != nil {
 		return fmt.Errorf("failed to drop dynamic table: %w", err)
@@ -283,13 +507,14 @@ func (r *SQLiteRepository) DeleteDatabase(ctx context.Context, dbID repo.ULID) e
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	r.forgetDatabase(dbID)
 
 	return nil
 }
 
 // GetDatabaseStats retrieves live statistics for a specific database by its ID.
 func (r *SQLiteRepository) GetDatabaseStats(ctx context.Context, dbID repo.ULID) (repo.DatabaseStats, error) {
-	query, args, err := r.Builder.Select("entry_count", "total_disk_space_bytes").
+	query, args, err := r.Builder.Select("entry_count", "total_disk_space_bytes", "disk_space_alert_active").
 		From("databases").
 		Where(squirrel.Eq{"id": dbID.String()}).
 		ToSql()
@@ -298,13 +523,37 @@ func (r *SQLiteRepository) GetDatabaseStats(ctx context.Context, dbID repo.ULID)
 	}
 
 	var stats repo.DatabaseStats
-	err = r.DB.QueryRowContext(ctx, query, args...).Scan(&stats.EntryCount, &stats.TotalDiskSpaceBytes)
+	err = r.DB.QueryRowContext(ctx, query, args...).Scan(&stats.EntryCount, &stats.TotalDiskSpaceBytes, &stats.DiskSpaceAlert)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return repo.DatabaseStats{}, customerrors.ErrNotFound
 		}
 		return repo.DatabaseStats{}, fmt.Errorf("failed to query database stats: %w", err)
 	}
+	processing, err := r.processingCounts(ctx, []string{dbID.String()})
+	if err != nil {
+		return repo.DatabaseStats{}, err
+	}
+	stats.ProcessingCount = processing[dbID.String()]
 
 	return stats, nil
 }
+
+// GetEntryTimeRange returns the oldest and the newest entry timestamp of a database, both zero if it has no entries.
+func (r *SQLiteRepository) GetEntryTimeRange(ctx context.Context, dbID repo.ULID) (time.Time, time.Time, error) {
+	query, args, err := r.Builder.Select("MIN(timestamp)", "MAX(timestamp)").
+		From(fmt.Sprintf(`"entries_%s"`, dbID.String())).
+		ToSql()
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to build entry time range query: %w", err)
+	}
+
+	var oldest, newest sql.NullInt64
+	if err := r.DB.QueryRowContext(ctx, query, args...).Scan(&oldest, &newest); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to query entry time range: %w", err)
+	}
+	if !oldest.Valid {
+		return time.Time{}, time.Time{}, nil
+	}
+	return time.UnixMilli(oldest.Int64), time.UnixMilli(newest.Int64), nil
+}
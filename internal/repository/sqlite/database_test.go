@@ -0,0 +1,182 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func newDatabaseTestRepo(tb testing.TB) (*sqlite.SQLiteRepository, repo.Database) {
+	tb.Helper()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		tb.Fatalf("failed to create repo: %v", err)
+	}
+	tb.Cleanup(func() { r.Close() })
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		tb.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	goose.SetLogger(goose.NopLogger())
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		tb.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(context.Background(), repo.Database{
+		Name:         "lookup_test",
+		ContentType:  "image",
+		Config:       repo.DatabaseConfig{ConversionRules: []repo.ConversionRule{{From: "image/png", To: "image/webp"}}},
+		CustomFields: []repo.CustomFieldDef{{Name: "camera", Type: "TEXT"}},
+	})
+	if err != nil {
+		tb.Fatalf("failed to create database: %v", err)
+	}
+	return r, db
+}
+
+func TestGetDatabaseCoalesced(t *testing.T) {
+	ctx := context.Background()
+	r, db := newDatabaseTestRepo(t)
+
+	want, err := r.GetDatabaseUncoalesced(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+
+	// 1. Concurrent lookups return the same database as a single one
+	results := make([]repo.Database, 32)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := r.GetDatabase(ctx, db.ID)
+			if err != nil {
+				t.Errorf("failed to get database: %v", err)
+			}
+			results[i] = got
+		}()
+	}
+	wg.Wait()
+	for _, got := range results {
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	}
+
+	// A caller changing its copy does not change the others
+	results[0].Config.ConversionRules[0].To = "image/avif"
+	for _, got := range results[1:] {
+		if got.Config.ConversionRules[0].To != "image/webp" {
+			t.Fatalf("a shared lookup leaked a change to another caller: %+v", got.Config.ConversionRules)
+		}
+	}
+
+	// 2. Lookups after a write see it
+	db.Config.ConversionRules = []repo.ConversionRule{{From: "image/png", To: "image/jpeg"}}
+	if _, err := r.UpdateDatabase(ctx, db); err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+	if got, err := r.GetDatabase(ctx, db.ID); err != nil || got.Config.ConversionRules[0].To != "image/jpeg" {
+		t.Errorf("expected the updated conversion rule, got %+v (err %v)", got.Config.ConversionRules, err)
+	}
+
+	// 3. A cancelled caller returns at once
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := r.GetDatabase(cancelled, db.ID); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// BenchmarkGetDatabase compares the lookups of many concurrent uploads with and without coalescing,
+// e.g. go test -bench GetDatabase -benchmem ./internal/repository/sqlite/
+func BenchmarkGetDatabase(b *testing.B) {
+	ctx := context.Background()
+	r, db := newDatabaseTestRepo(b)
+
+	lookups := map[string]func(context.Context, repo.ULID) (repo.Database, error){
+		"coalesced": r.GetDatabase,
+		"direct":    r.GetDatabaseUncoalesced,
+	}
+	for _, name := range []string{"direct", "coalesced"} {
+		lookup := lookups[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetParallelism(8)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := lookup(ctx, db.ID); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestCapacityLimits(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newDatabaseTestRepo(t)
+	r.CapacityLimits = repo.CapacityLimits{MaxDatabases: 2, MaxEntriesPerDatabase: 2}
+
+	// 1. The last database up to the limit is created, the next one is not
+	second, err := r.CreateDatabase(ctx, repo.Database{Name: "second", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("expected the 2nd database to be created, got %v", err)
+	}
+	_, err = r.CreateDatabase(ctx, repo.Database{Name: "third", ContentType: "file"})
+	if !errors.Is(err, customerrors.ErrLimitReached) || !strings.Contains(err.Error(), "2 databases exist") {
+		t.Fatalf("expected ErrLimitReached with the count, got %v", err)
+	}
+
+	// 2. Deleting a database makes room for another one
+	if err := r.DeleteDatabase(ctx, second.ID); err != nil {
+		t.Fatalf("failed to delete database: %v", err)
+	}
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "third", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("expected the database to be created after a deletion, got %v", err)
+	}
+
+	// 3. The same for the entries of a database
+	entry := repo.Entry{FileName: "a.bin", MimeType: "application/octet-stream", Status: repo.EntryStatusReady, Timestamp: time.Now()}
+	first, err := r.CreateEntry(ctx, db, entry)
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if _, err := r.CreateEntry(ctx, db, entry); err != nil {
+		t.Fatalf("expected the 2nd entry to be created, got %v", err)
+	}
+	_, err = r.CreateEntry(ctx, db, entry)
+	if !errors.Is(err, customerrors.ErrLimitReached) || !strings.Contains(err.Error(), "maximum is 2 entries") {
+		t.Fatalf("expected ErrLimitReached with the limit, got %v", err)
+	}
+	if _, err := r.DeleteEntry(ctx, db.ID, first.ID); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if _, err := r.CreateEntry(ctx, db, entry); err != nil {
+		t.Errorf("expected the entry to be created after a deletion, got %v", err)
+	}
+
+	// 4. Without limits there are none
+	r.CapacityLimits = repo.CapacityLimits{}
+	if _, err := r.CreateEntry(ctx, db, entry); err != nil {
+		t.Errorf("expected the entry to be created without limits, got %v", err)
+	}
+}
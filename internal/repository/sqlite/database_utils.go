@@ -1,11 +1,14 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	repo "mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared/customerrors"
+	"slices"
 	"strings"
 	"time"
 )
@@ -19,6 +22,7 @@ type scanner interface {
 func scanDatabaseRow(s scanner) (repo.Database, error) {
 	var db repo.Database
 	var intervalMs, maxAgeMs, HKLastRun int64 // Intermediate variables for millisecond values
+	var conversionRules, transcription, metadataDefaults, metadataOverrides, geoFields string
 
 	// Make sure ID is the first scanned column matching the modified Select queries
 	err := s.Scan(
@@ -30,10 +34,25 @@ func scanDatabaseRow(s scanner) (repo.Database, error) {
 		&maxAgeMs, // Scan into intermediate variable
 		&db.Config.CreatePreview,
 		&db.Config.AutoConversion,
+		&db.Config.KeepOriginal,
+		&db.Config.UniqueExternalID,
 		&db.NMaxQueued,
 		&HKLastRun,
 		&db.Stats.EntryCount,
 		&db.Stats.TotalDiskSpaceBytes,
+		&db.Housekeeping.DiskSpaceWarnPercent,
+		&db.Stats.DiskSpaceAlert,
+		&conversionRules,
+		&transcription,
+		&db.Config.PublicRead,
+		&db.Housekeeping.AgeBasis,
+		&metadataDefaults,
+		&metadataOverrides,
+		&db.Config.Waveform.Width,
+		&db.Config.Waveform.Height,
+		&db.Config.Waveform.Color,
+		&db.Config.Waveform.Background,
+		&geoFields,
 	)
 
 	if err != nil {
@@ -49,10 +68,157 @@ func scanDatabaseRow(s scanner) (repo.Database, error) {
 	if HKLastRun > 0 {
 		db.Housekeeping.LastHkRun = time.UnixMilli(HKLastRun)
 	}
+	if db.Config.ConversionRules, err = decodeConversionRules(conversionRules); err != nil {
+		return repo.Database{}, err
+	}
+	if db.Config.Transcription, err = decodeTranscription(transcription); err != nil {
+		return repo.Database{}, err
+	}
+	if db.Config.MetadataDefaults, err = decodeMetadataValues(metadataDefaults); err != nil {
+		return repo.Database{}, err
+	}
+	if db.Config.MetadataOverrides, err = decodeMetadataValues(metadataOverrides); err != nil {
+		return repo.Database{}, err
+	}
+	if db.Config.GeoFields, err = decodeGeoFields(geoFields); err != nil {
+		return repo.Database{}, err
+	}
 
 	return db, nil
 }
 
+// encodeConversionRules serializes the conversion rules for the conversion_rules column.
+func encodeConversionRules(rules []repo.ConversionRule) (string, error) {
+	if len(rules) == 0 {
+		return "[]", nil
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode conversion rules: %w", err)
+	}
+	return string(b), nil
+}
+
+// decodeConversionRules parses the conversion_rules column.
+func decodeConversionRules(s string) ([]repo.ConversionRule, error) {
+	if s == "" || s == "[]" {
+		return nil, nil
+	}
+	var rules []repo.ConversionRule
+	if err := json.Unmarshal([]byte(s), &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode conversion rules: %w", err)
+	}
+	return rules, nil
+}
+
+// encodeTranscription serializes the transcription config for the transcription column, empty if disabled.
+func encodeTranscription(cfg repo.TranscriptionConfig) (string, error) {
+	if cfg == (repo.TranscriptionConfig{}) {
+		return "", nil
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode transcription config: %w", err)
+	}
+	return string(b), nil
+}
+
+// decodeTranscription parses the transcription column.
+func decodeTranscription(s string) (repo.TranscriptionConfig, error) {
+	var cfg repo.TranscriptionConfig
+	if s == "" {
+		return cfg, nil
+	}
+	if err := json.Unmarshal([]byte(s), &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to decode transcription config: %w", err)
+	}
+	return cfg, nil
+}
+
+// encodeGeoFields serializes the geo fields for the geo_fields column, empty if the database declares none.
+func encodeGeoFields(g repo.GeoFields) (string, error) {
+	if !g.Enabled() {
+		return "", nil
+	}
+	b, err := json.Marshal(g)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode geo fields: %w", err)
+	}
+	return string(b), nil
+}
+
+// decodeGeoFields parses the geo_fields column.
+func decodeGeoFields(s string) (repo.GeoFields, error) {
+	var g repo.GeoFields
+	if s == "" {
+		return g, nil
+	}
+	if err := json.Unmarshal([]byte(s), &g); err != nil {
+		return g, fmt.Errorf("failed to decode geo fields: %w", err)
+	}
+	return g, nil
+}
+
+// encodeMetadataValues serializes the metadata defaults or overrides for their column.
+func encodeMetadataValues(values map[string]any) (string, error) {
+	if len(values) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode metadata values: %w", err)
+	}
+	return string(b), nil
+}
+
+// decodeMetadataValues parses the metadata_defaults and metadata_overrides columns. Numbers are
+// decoded as float64, like the metadata of an upload.
+func decodeMetadataValues(s string) (map[string]any, error) {
+	if s == "" || s == "{}" {
+		return nil, nil
+	}
+	var values map[string]any
+	if err := json.Unmarshal([]byte(s), &values); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata values: %w", err)
+	}
+	return values, nil
+}
+
+// processingCounts returns the queued and processing entries of the given databases by ID, in one UNION ALL
+// per batch of entry tables. The count is read live from the status index instead of kept in the stats, so it
+// cannot drift from the listing.
+func (r *SQLiteRepository) processingCounts(ctx context.Context, dbIDs []string) (map[string]uint64, error) {
+	counts := make(map[string]uint64, len(dbIDs))
+	for batch := range slices.Chunk(dbIDs, largestEntriesBatchSize) {
+		parts := make([]string, 0, len(batch))
+		args := make([]any, 0, 3*len(batch))
+		for _, dbID := range batch {
+			parts = append(parts, fmt.Sprintf(`SELECT ? AS database_id, COUNT(*) FROM "entries_%s" WHERE status IN (?, ?)`, dbID))
+			args = append(args, dbID, repo.EntryStatusQueued, repo.EntryStatusProcessing)
+		}
+
+		rows, err := r.DB.QueryContext(ctx, strings.Join(parts, " UNION ALL "), args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count processing entries: %w", err)
+		}
+		for rows.Next() {
+			var dbID string
+			var count uint64
+			if err := rows.Scan(&dbID, &count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan processing count: %w", err)
+			}
+			counts[dbID] = count
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read processing counts: %w", err)
+		}
+	}
+	return counts, nil
+}
+
 // BuildDynamicTableSchema generates the CREATE TABLE statement using the database ID.
 func (r *SQLiteRepository) BuildDynamicTableSchema(dbID, contentType string, customFields []repo.CustomFieldDef) (string, error) {
 	tableName := fmt.Sprintf(`"entries_%s"`, dbID)
@@ -66,6 +232,20 @@ func (r *SQLiteRepository) BuildDynamicTableSchema(dbID, contentType string, cus
 	sb.WriteString("\tfilesize INTEGER NOT NULL,\n")
 	sb.WriteString("\tpreview_filesize INTEGER NOT NULL,\n")
 	sb.WriteString("\tfilename TEXT NOT NULL DEFAULT '',\n")
+	sb.WriteString("\terror_reason TEXT NOT NULL DEFAULT '',\n")
+	sb.WriteString("\terror_detail TEXT NOT NULL DEFAULT '',\n")
+	sb.WriteString("\toriginal_filesize INTEGER NOT NULL DEFAULT 0,\n")
+	sb.WriteString("\toriginal_mime_type TEXT NOT NULL DEFAULT '',\n")
+	sb.WriteString("\texternal_id TEXT NOT NULL DEFAULT '',\n")
+	sb.WriteString("\tcontent_hash TEXT NOT NULL DEFAULT '',\n")
+	sb.WriteString("\tlast_verified_at BIGINT NOT NULL DEFAULT 0,\n")
+	sb.WriteString("\tuploaded_by TEXT,\n")
+	sb.WriteString("\tupload_ip TEXT,\n")
+	sb.WriteString("\tupload_user_agent TEXT,\n")
+	sb.WriteString("\ttranscription_status TEXT,\n")
+	sb.WriteString("\tlegal_hold BOOLEAN NOT NULL DEFAULT 0,\n")
+	sb.WriteString("\tclient_timestamp BIGINT,\n")
+	sb.WriteString("\tcontent_revision INTEGER NOT NULL DEFAULT 0,\n")
 
 	// 1. Add Status constraint
 	var statusStrs []string
@@ -114,6 +294,10 @@ func BuildIndexesSQL(dbID string, customFields []repo.CustomFieldDef) []string {
 	sqls = append(sqls, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_status" ON %s(status);`, dbID, tableName))
 	sqls = append(sqls, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_created" ON %s(created_at);`, dbID, tableName))
 	sqls = append(sqls, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_updated" ON %s(updated_at);`, dbID, tableName))
+	sqls = append(sqls, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_external_id" ON %s(external_id);`, dbID, tableName))
+	sqls = append(sqls, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_verified" ON %s(last_verified_at);`, dbID, tableName))
+	sqls = append(sqls, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_uploaded_by" ON %s(uploaded_by);`, dbID, tableName))
+	sqls = append(sqls, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_entries_%s_legal_hold" ON %s(timestamp) WHERE legal_hold = 1;`, dbID, tableName))
 
 	for _, cf := range customFields {
 		if cf.IsIndexed {
@@ -123,3 +307,24 @@ func BuildIndexesSQL(dbID string, customFields []repo.CustomFieldDef) []string {
 
 	return sqls
 }
+
+// setExternalIDUniqueness creates or drops the unique index of the external ID. The index is partial,
+// so entries without an external ID do not conflict with each other.
+func setExternalIDUniqueness(ctx context.Context, tx *sql.Tx, dbID string, unique bool) error {
+	indexName := fmt.Sprintf(`"idx_entries_%s_external_id_unique"`, dbID)
+	if !unique {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP INDEX IF EXISTS %s;`, indexName)); err != nil {
+			return fmt.Errorf("failed to drop unique external_id index: %w", err)
+		}
+		return nil
+	}
+
+	createSQL := fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS %s ON "entries_%s"(external_id) WHERE external_id != '';`, indexName, dbID)
+	if _, err := tx.ExecContext(ctx, createSQL); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("%w: existing entries share an external_id", customerrors.ErrConflict)
+		}
+		return fmt.Errorf("failed to create unique external_id index: %w", err)
+	}
+	return nil
+}
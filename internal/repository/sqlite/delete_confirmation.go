@@ -0,0 +1,63 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// CreateDeleteConfirmation stores the pending confirmation of a database deletion. A previous
+// confirmation of the same database is replaced, its token stops working.
+func (r *SQLiteRepository) CreateDeleteConfirmation(ctx context.Context, confirmation repo.DeleteConfirmation) error {
+	if confirmation.CreatedAt.IsZero() {
+		confirmation.CreatedAt = time.Now()
+	}
+
+	query, args, err := r.Builder.Insert("delete_confirmations").
+		Columns("database_id", "token_hash", "created_by", "created_at", "expires_at").
+		Values(
+			confirmation.DatabaseID.String(), confirmation.TokenHash, confirmation.CreatedBy,
+			confirmation.CreatedAt.UnixMilli(), confirmation.ExpiresAt.UnixMilli(),
+		).
+		Suffix(`ON CONFLICT(database_id) DO UPDATE SET
+			token_hash = excluded.token_hash,
+			created_by = excluded.created_by,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at`).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert delete_confirmation query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert delete_confirmation: %w", err)
+	}
+	return nil
+}
+
+// ConsumeDeleteConfirmation removes the confirmation of a database if the token matches and has not
+// expired. The single conditional DELETE makes sure a token confirms at most one deletion.
+func (r *SQLiteRepository) ConsumeDeleteConfirmation(ctx context.Context, dbID repo.ULID, tokenHash string) (bool, error) {
+	query, args, err := r.Builder.Delete("delete_confirmations").
+		Where(squirrel.Eq{"database_id": dbID.String(), "token_hash": tokenHash}).
+		Where("expires_at > ?", time.Now().UnixMilli()).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build consume delete_confirmation query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute consume delete_confirmation query: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to verify rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
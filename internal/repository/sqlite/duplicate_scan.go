@@ -0,0 +1,299 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+var duplicateScanColumns = []string{
+	"id", "database_id", "status",
+	"max_entry_id", "last_entry_id", "total", "processed", "failed", "error",
+	"created_by", "created_at", "updated_at", "finished_at",
+}
+
+// CreateDuplicateScan stores a new running scan. The scan covers the entries existing at its start:
+// MaxEntryID and Total are set from the entries table in the same transaction. The partial unique
+// index on running scans turns a second scan of the same database into ErrConflict.
+func (r *SQLiteRepository) CreateDuplicateScan(ctx context.Context, scan repo.DuplicateScan) (repo.DuplicateScan, error) {
+	if scan.ID == "" {
+		scan.ID = repo.ULID(shared.GenerateULID())
+	}
+	now := time.Now()
+	scan.Status = repo.DuplicateScanRunning
+	scan.CreatedAt, scan.UpdatedAt = now, now
+	scan.LastEntryID, scan.Processed, scan.Failed = 0, 0, 0
+
+	tableName := fmt.Sprintf(`"entries_%s"`, scan.DatabaseID.String())
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		query, args, err := r.Builder.Select("COALESCE(MAX(id), 0)", "COUNT(*)").
+			From(tableName).
+			Where(squirrel.Eq{"status": repo.EntryStatusReady}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build scan range query: %w", err)
+		}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&scan.MaxEntryID, &scan.Total); err != nil {
+			return fmt.Errorf("failed to query scan range: %w", err)
+		}
+
+		query, args, err = r.Builder.Insert("duplicate_scans").
+			Columns(duplicateScanColumns...).
+			Values(
+				scan.ID.String(), scan.DatabaseID.String(), scan.Status,
+				scan.MaxEntryID, scan.LastEntryID, scan.Total, scan.Processed, scan.Failed, scan.Error,
+				scan.CreatedBy, now.UnixMilli(), now.UnixMilli(), nil,
+			).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build insert duplicate_scan query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return fmt.Errorf("%w: a duplicate scan of the database is running", customerrors.ErrConflict)
+			}
+			return fmt.Errorf("failed to insert duplicate_scan: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return repo.DuplicateScan{}, err
+	}
+	return scan, nil
+}
+
+// GetDuplicateScan retrieves a scan by its ID.
+func (r *SQLiteRepository) GetDuplicateScan(ctx context.Context, id repo.ULID) (repo.DuplicateScan, error) {
+	query, args, err := r.Builder.Select(duplicateScanColumns...).
+		From("duplicate_scans").
+		Where(squirrel.Eq{"id": id.String()}).
+		ToSql()
+	if err != nil {
+		return repo.DuplicateScan{}, fmt.Errorf("failed to build get duplicate_scan query: %w", err)
+	}
+
+	scan, err := scanDuplicateScan(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.DuplicateScan{}, customerrors.ErrNotFound
+		}
+		return repo.DuplicateScan{}, fmt.Errorf("failed to execute get duplicate_scan query: %w", err)
+	}
+	return scan, nil
+}
+
+// GetRunningDuplicateScans retrieves the scans that have not finished, oldest first.
+func (r *SQLiteRepository) GetRunningDuplicateScans(ctx context.Context) ([]repo.DuplicateScan, error) {
+	query, args, err := r.Builder.Select(duplicateScanColumns...).
+		From("duplicate_scans").
+		Where(squirrel.Eq{"status": repo.DuplicateScanRunning}).
+		OrderBy("created_at ASC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get running duplicate_scans query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get running duplicate_scans query: %w", err)
+	}
+	defer rows.Close()
+
+	scans := []repo.DuplicateScan{}
+	for rows.Next() {
+		scan, err := scanDuplicateScan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate_scan row: %w", err)
+		}
+		scans = append(scans, scan)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("duplicate_scan row iteration error: %w", err)
+	}
+	return scans, nil
+}
+
+// UpdateDuplicateScan stores the status and progress of a scan.
+func (r *SQLiteRepository) UpdateDuplicateScan(ctx context.Context, scan repo.DuplicateScan) error {
+	var finishedAt any
+	if !scan.FinishedAt.IsZero() {
+		finishedAt = scan.FinishedAt.UnixMilli()
+	}
+
+	query, args, err := r.Builder.Update("duplicate_scans").
+		Set("status", scan.Status).
+		Set("last_entry_id", scan.LastEntryID).
+		Set("processed", scan.Processed).
+		Set("failed", scan.Failed).
+		Set("error", scan.Error).
+		Set("updated_at", time.Now().UnixMilli()).
+		Set("finished_at", finishedAt).
+		Where(squirrel.Eq{"id": scan.ID.String()}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update duplicate_scan query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute update duplicate_scan query: %w", err)
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return customerrors.ErrNotFound // removed along with its database
+	}
+	return nil
+}
+
+// GetEntriesToHash returns up to limit ready entries with afterID < id <= maxID in ID order, the next
+// batch of a duplicate scan.
+func (r *SQLiteRepository) GetEntriesToHash(ctx context.Context, dbID repo.ULID, afterID, maxID int64, limit int) ([]repo.Entry, error) {
+	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query, args, err := r.Builder.Select("*").
+		From(tableName).
+		Where(squirrel.Eq{"status": repo.EntryStatusReady}).
+		Where(squirrel.Gt{"id": afterID}).
+		Where(squirrel.LtOrEq{"id": maxID}).
+		OrderBy("id ASC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build entries to hash query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries to hash: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := r.scanEntryRows(rows, customFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan entries to hash: %w", err)
+	}
+	return entries, nil
+}
+
+// StoreContentHashes records the hashes of a batch of entries in one transaction. Like the first
+// integrity check, it counts as a verification. Entries that got a hash in the meantime, or are no
+// longer ready, are left alone.
+func (r *SQLiteRepository) StoreContentHashes(ctx context.Context, dbID repo.ULID, hashes map[int64]string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	now := time.Now().UnixMilli()
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		for entryID, hash := range hashes {
+			query, args, err := r.Builder.Update(tableName).
+				Set("content_hash", hash).
+				Set("last_verified_at", now).
+				Where(squirrel.Eq{"id": entryID, "status": repo.EntryStatusReady, "content_hash": ""}).
+				ToSql()
+			if err != nil {
+				return fmt.Errorf("failed to build store content hash query: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("failed to store content hash: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetDuplicateGroups returns the ready entries up to maxID that share their content hash with another
+// one, grouped by hash. Groups and their entries are ordered oldest first.
+func (r *SQLiteRepository) GetDuplicateGroups(ctx context.Context, dbID repo.ULID, maxID int64) ([]repo.DuplicateGroup, error) {
+	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	scanned := squirrel.And{
+		squirrel.Eq{"status": repo.EntryStatusReady},
+		squirrel.NotEq{"content_hash": ""},
+		squirrel.LtOrEq{"id": maxID},
+	}
+	duplicated := r.Builder.Select("content_hash").
+		From(tableName).
+		Where(scanned).
+		GroupBy("content_hash").
+		Having("COUNT(*) > 1")
+
+	query, args, err := r.Builder.Select("*").
+		From(tableName).
+		Where(scanned).
+		Where(duplicated.Prefix("content_hash IN (").Suffix(")")).
+		OrderBy("timestamp ASC", "id ASC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build duplicate groups query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate groups: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := r.scanEntryRows(rows, customFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan duplicate groups: %w", err)
+	}
+
+	// The groups are ordered by their oldest entry
+	groups := []repo.DuplicateGroup{}
+	index := map[string]int{}
+	for _, entry := range entries {
+		i, ok := index[entry.ContentHash]
+		if !ok {
+			i = len(groups)
+			index[entry.ContentHash] = i
+			groups = append(groups, repo.DuplicateGroup{ContentHash: entry.ContentHash})
+		}
+		groups[i].Entries = append(groups[i].Entries, entry)
+	}
+	return groups, nil
+}
+
+// scanDuplicateScan scans a single duplicate_scans row (in duplicateScanColumns order).
+func scanDuplicateScan(row interface{ Scan(dest ...any) error }) (repo.DuplicateScan, error) {
+	var scan repo.DuplicateScan
+	var idStr, dbIDStr string
+	var createdAtVal, updatedAtVal int64
+	var finishedAtVal sql.NullInt64
+
+	err := row.Scan(
+		&idStr, &dbIDStr, &scan.Status,
+		&scan.MaxEntryID, &scan.LastEntryID, &scan.Total, &scan.Processed, &scan.Failed, &scan.Error,
+		&scan.CreatedBy, &createdAtVal, &updatedAtVal, &finishedAtVal,
+	)
+	if err != nil {
+		return repo.DuplicateScan{}, err
+	}
+
+	scan.ID = repo.ULID(idStr)
+	scan.DatabaseID = repo.ULID(dbIDStr)
+	scan.CreatedAt = time.UnixMilli(createdAtVal)
+	scan.UpdatedAt = time.UnixMilli(updatedAtVal)
+	if finishedAtVal.Valid {
+		scan.FinishedAt = time.UnixMilli(finishedAtVal.Int64)
+	}
+	return scan, nil
+}
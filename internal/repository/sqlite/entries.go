@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -17,13 +19,20 @@ import (
 
 // CreateEntry inserts a new entry into the database's specific table and updates global stats.
 func (r *SQLiteRepository) CreateEntry(ctx context.Context, db repo.Database, entry repo.Entry) (repo.Entry, error) {
+	entry, _, err := r.CreateEntryWithTasks(ctx, db, entry, nil, 0)
+	return entry, err
+}
+
+// CreateEntryWithTasks works like CreateEntry, but also inserts the given pending tasks in the same transaction.
+// The tasks become due after the lease, giving the uploading request time to run them itself.
+func (r *SQLiteRepository) CreateEntryWithTasks(ctx context.Context, db repo.Database, entry repo.Entry, taskTypes []string, lease time.Duration) (repo.Entry, []repo.PendingTask, error) {
 	// Verify mime type matching DB's content type
 	isValidMime, err := media.IsMimeOfType(db.ContentType, entry.MimeType)
 	if !isValidMime {
-		return repo.Entry{}, customerrors.ErrBadMimeType
+		return repo.Entry{}, nil, customerrors.ErrBadMimeType
 	}
 	if err != nil {
-		return repo.Entry{}, err
+		return repo.Entry{}, nil, err
 	}
 
 	// Establish timing (SQLite case, with single client, we take client time)
@@ -36,14 +45,25 @@ func (r *SQLiteRepository) CreateEntry(ctx context.Context, db repo.Database, en
 	// Map standard columns
 	// Squirrel's SetMap is perfect for our highly dynamic schema
 	insertData := map[string]any{
-		"timestamp":        entryTime.UnixMilli(),
-		"created_at":       now.UnixMilli(),
-		"updated_at":       now.UnixMilli(),
-		"filesize":         entry.Size,
-		"preview_filesize": entry.PreviewSize,
-		"filename":         entry.FileName,
-		"status":           entry.Status,
-		"mime_type":        entry.MimeType,
+		"timestamp":          entryTime.UnixMilli(),
+		"created_at":         now.UnixMilli(),
+		"updated_at":         now.UnixMilli(),
+		"filesize":           entry.Size,
+		"preview_filesize":   entry.PreviewSize,
+		"filename":           entry.FileName,
+		"status":             entry.Status,
+		"mime_type":          entry.MimeType,
+		"error_reason":       entry.ErrorReason,
+		"error_detail":       entry.ErrorDetail,
+		"original_filesize":  entry.OriginalSize,
+		"original_mime_type": entry.OriginalMimeType,
+		"external_id":        entry.ExternalID,
+		"uploaded_by":        nullIfEmpty(entry.Origin.UploadedBy),
+		"upload_ip":          nullIfEmpty(entry.Origin.ClientIP),
+		"upload_user_agent":  nullIfEmpty(entry.Origin.UserAgent),
+	}
+	if !entry.ClientTimestamp.IsZero() {
+		insertData["client_timestamp"] = entry.ClientTimestamp.UnixMilli()
 	}
 
 	// Conditionally append the explicit ID if provided.
@@ -68,61 +88,104 @@ func (r *SQLiteRepository) CreateEntry(ctx context.Context, db repo.Database, en
 		}
 	}
 
-	// Begin Transaction
-	tx, err := r.DB.BeginTx(ctx, nil)
-	if err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Insert the Entry using the db.ID
-	tableName := fmt.Sprintf(`"entries_%s"`, db.ID)
-	insertQuery, args, err := r.Builder.Insert(tableName).SetMap(insertData).ToSql()
-	if err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to build insert query: %w", err)
-	}
+	// Insert the entry, update the stats and queue the tasks in one transaction
+	var tasks []repo.PendingTask
+	err = r.WithTx(ctx, func(tx *sql.Tx) error {
+		// The stats are exact, entries deleted a moment ago make room at once
+		if r.CapacityLimits.MaxEntriesPerDatabase > 0 {
+			var count uint64
+			if err := tx.QueryRowContext(ctx, "SELECT entry_count FROM databases WHERE id = ?", db.ID).Scan(&count); err != nil {
+				return fmt.Errorf("failed to read the entry count: %w", err)
+			}
+			if err := r.CapacityLimits.CheckEntries(count, 1); err != nil {
+				return err
+			}
+		}
 
-	res, err := tx.ExecContext(ctx, insertQuery, args...)
-	if err != nil {
-		// If entry.ID > 0 and already exists, SQLite throws a UNIQUE constraint failed error right here.
-		return repo.Entry{}, fmt.Errorf("failed to insert entry: %w", err)
-	}
+		// Insert the Entry using the db.ID
+		tableName := fmt.Sprintf(`"entries_%s"`, db.ID)
+		insertQuery, args, err := r.Builder.Insert(tableName).SetMap(insertData).ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build insert query: %w", err)
+		}
 
-	// Only fetch the LastInsertId if we let SQLite generate it
-	if entry.ID <= 0 {
-		insertedID, err := res.LastInsertId()
+		res, err := tx.ExecContext(ctx, insertQuery, args...)
 		if err != nil {
-			return repo.Entry{}, fmt.Errorf("failed to retrieve insert ID: %w", err)
+			// If entry.ID > 0 and already exists, SQLite throws a UNIQUE constraint failed error right here.
+			if isExternalIDConflict(err) {
+				return fmt.Errorf("%w: external_id '%s' is already used", customerrors.ErrConflict, entry.ExternalID)
+			}
+			return fmt.Errorf("failed to insert entry: %w", err)
 		}
-		entry.ID = insertedID
-	}
 
-	// Atomically update parent Database stats using db.ID
-	// Calculate total size delta (main file + preview)
-	totalSizeDelta := entry.Size + entry.PreviewSize
+		// Only fetch the LastInsertId if we let SQLite generate it
+		if entry.ID <= 0 {
+			insertedID, err := res.LastInsertId()
+			if err != nil {
+				return fmt.Errorf("failed to retrieve insert ID: %w", err)
+			}
+			entry.ID = insertedID
+		}
 
-	statsQuery, statsArgs, err := r.Builder.Update("databases").
-		Set("entry_count", squirrel.Expr("entry_count + 1")).
-		Set("total_disk_space_bytes", squirrel.Expr("total_disk_space_bytes + ?", totalSizeDelta)).
-		Where(squirrel.Eq{"id": db.ID}).
-		ToSql()
-	if err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to build stats update query: %w", err)
-	}
+		// Atomically update parent Database stats using db.ID
+		// Calculate total size delta (main file + preview + kept original)
+		totalSizeDelta := entry.Size + entry.PreviewSize + entry.OriginalSize
 
-	if _, err := tx.ExecContext(ctx, statsQuery, statsArgs...); err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to update database stats: %w", err)
-	}
+		statsQuery, statsArgs, err := r.Builder.Update("databases").
+			Set("entry_count", squirrel.Expr("entry_count + 1")).
+			Set("total_disk_space_bytes", squirrel.Expr("total_disk_space_bytes + ?", totalSizeDelta)).
+			Where(squirrel.Eq{"id": db.ID}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build stats update query: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, statsQuery, statsArgs...); err != nil {
+			return fmt.Errorf("failed to update database stats: %w", err)
+		}
+		if err := r.recordUsageChanges(ctx, tx, db.ID, []usageChange{{
+			username: entry.Origin.UploadedBy, day: usageDay(now.UnixMilli()), entries: 1, bytes: int64(totalSizeDelta),
+		}}); err != nil {
+			return err
+		}
 
-	// Commit
-	if err := tx.Commit(); err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to commit transaction: %w", err)
+		// Insert the post-processing tasks of the entry
+		for _, taskType := range taskTypes {
+			task := repo.PendingTask{
+				DatabaseID:    db.ID,
+				EntryID:       entry.ID,
+				Type:          taskType,
+				NextAttemptAt: now.Add(lease),
+				CreatedAt:     now,
+			}
+			taskQuery, taskArgs, err := r.Builder.Insert("pending_tasks").
+				Columns("database_id", "entry_id", "task_type", "next_attempt_at", "created_at").
+				Values(task.DatabaseID.String(), task.EntryID, task.Type, task.NextAttemptAt.UnixMilli(), task.CreatedAt.UnixMilli()).
+				ToSql()
+			if err != nil {
+				return fmt.Errorf("failed to build insert pending_task query: %w", err)
+			}
+			res, err := tx.ExecContext(ctx, taskQuery, taskArgs...)
+			if err != nil {
+				return fmt.Errorf("failed to insert pending_task: %w", err)
+			}
+			if task.ID, err = res.LastInsertId(); err != nil {
+				return fmt.Errorf("failed to retrieve pending_task ID: %w", err)
+			}
+			tasks = append(tasks, task)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return repo.Entry{}, nil, err
 	}
+	r.forgetDatabase(db.ID)
 
 	entry.CreatedAt = now
 	entry.UpdatedAt = now
 
-	return entry, nil
+	return entry, tasks, nil
 }
 
 // GetEntry retrieves a single entry by its ID using a dynamic row scanner.
@@ -152,14 +215,63 @@ func (r *SQLiteRepository) GetEntry(ctx context.Context, dbID repo.ULID, id int6
 	return entry, nil
 }
 
+// GetEntryByExternalID retrieves the entry carrying the given external ID.
+// It returns ErrConflict if several entries share it, which is possible without unique_external_id.
+func (r *SQLiteRepository) GetEntryByExternalID(ctx context.Context, dbID repo.ULID, externalID string) (repo.Entry, error) {
+	if externalID == "" {
+		return repo.Entry{}, customerrors.ErrNotFound
+	}
+
+	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
+	if err != nil {
+		return repo.Entry{}, err
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query, args, err := r.Builder.Select("*").From(tableName).Where(squirrel.Eq{"external_id": externalID}).Limit(2).ToSql()
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to build query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to query entry: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := r.scanEntryRows(rows, customFields)
+	if err != nil {
+		return repo.Entry{}, fmt.Errorf("failed to scan entry: %w", err)
+	}
+	switch len(entries) {
+	case 0:
+		return repo.Entry{}, customerrors.ErrNotFound
+	case 1:
+		return entries[0], nil
+	default:
+		return repo.Entry{}, fmt.Errorf("%w: several entries have the external_id '%s'", customerrors.ErrConflict, externalID)
+	}
+}
+
 // GetEntries retrieves a paginated list of entries, optionally filtered by a time range.
 func (r *SQLiteRepository) GetEntries(ctx context.Context, dbID repo.ULID, opts repo.QueryOptions) ([]repo.Entry, error) {
+	opts.Limit, _ = r.PageLimits.Apply(opts.Limit)
 	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+	}
+
+	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := r.selectColumns(opts.Fields, customFields)
+	if err != nil {
 		return nil, err
 	}
 
 	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
-	builder := r.Builder.Select("*").From(tableName)
+	builder := r.Builder.Select(columns...).From(tableName)
 
 	// Apply time filters only if they differ from the absolute minimum/maximum
 	if !opts.TStart.IsZero() && opts.TStart.After(time.Unix(0, 0)) {
@@ -168,21 +280,20 @@ func (r *SQLiteRepository) GetEntries(ctx context.Context, dbID repo.ULID, opts
 	if !opts.TEnd.IsZero() && opts.TEnd.After(time.Unix(0, 0)) {
 		builder = builder.Where(squirrel.LtOrEq{opts.TimeField: opts.TEnd.UnixMilli()})
 	}
+	if len(opts.Statuses) > 0 {
+		builder = builder.Where(squirrel.Eq{"status": opts.Statuses})
+	}
+	if opts.ExcludeLegalHold {
+		builder = builder.Where(squirrel.Eq{"legal_hold": false})
+	}
 
 	builder = builder.OrderBy(fmt.Sprintf("%s %s", opts.SortBy, strings.ToUpper(opts.Order)))
 
-	if opts.Limit > 0 {
-		builder = builder.Limit(uint64(opts.Limit))
-	}
+	builder = builder.Limit(uint64(opts.Limit))
 	if opts.Offset > 0 {
 		builder = builder.Offset(uint64(opts.Offset))
 	}
 
-	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
-	if err != nil {
-		return nil, err
-	}
-
 	query, args, err := builder.ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build query: %w", err)
@@ -202,109 +313,173 @@ func (r *SQLiteRepository) GetEntries(ctx context.Context, dbID repo.ULID, opts
 	return entries, nil
 }
 
-// UpdateEntry modifies an existing entry's metadata and safely adjusts the parent database's size statistics.
-func (r *SQLiteRepository) UpdateEntry(ctx context.Context, dbID repo.ULID, entry repo.Entry) (repo.Entry, error) {
-	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
-
-	var entryTime time.Time
-	if !entry.Timestamp.IsZero() {
-		entryTime = entry.Timestamp
-	}
+// UpdateEntryStatus sets the processing status of an entry, with the reason and detail of a failure
+// (both empty to clear them).
+func (r *SQLiteRepository) UpdateEntryStatus(ctx context.Context, dbID repo.ULID, entryID int64, status repo.EntryStatus, reason, detail string) error {
+	return r.updateEntryColumns(ctx, dbID, entryID, map[string]any{
+		"status":       status,
+		"error_reason": reason,
+		"error_detail": detail,
+	})
+}
 
-	// 1. Begin SQL Transaction
-	tx, err := r.DB.BeginTx(ctx, nil)
+// UpdateEntryTechMetadata records what processing found out about the stored files of an entry: the
+// sizes, the mime types and the media fields. Media fields that are not columns of the content type of
+// the database are rejected with ErrValidation, media fields missing from meta are left unchanged.
+// If the stored file was written, its content revision is bumped with the new sizes in the same update
+// and the hash of the integrity check is cleared, as it belongs to the replaced file.
+func (r *SQLiteRepository) UpdateEntryTechMetadata(ctx context.Context, dbID repo.ULID, entryID int64, meta repo.EntryTechMetadata) error {
+	db, err := r.GetDatabase(ctx, dbID)
 	if err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to begin transaction: %w", err)
+		return err
 	}
-	defer tx.Rollback()
 
-	customFields, err := r.getCustomFields(ctx, tx, dbID)
-	if err != nil {
-		return repo.Entry{}, err
+	columns := map[string]any{
+		"filesize":           meta.Size,
+		"preview_filesize":   meta.PreviewSize,
+		"original_filesize":  meta.OriginalSize,
+		"mime_type":          meta.MimeType,
+		"original_mime_type": meta.OriginalMimeType,
 	}
-
-	// 2. Query the current size of the entry before updating
-	var oldSize, oldPreviewSize uint64
-	queryOld, argsOld, err := r.Builder.Select("filesize", "preview_filesize").
-		From(tableName).
-		Where(squirrel.Eq{"id": entry.ID}).
-		ToSql()
-	if err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to build select old sizes query: %w", err)
+	if meta.FileWritten {
+		columns["content_revision"] = squirrel.Expr("content_revision + 1")
+		columns["content_hash"] = ""
+		columns["last_verified_at"] = 0
 	}
-
-	err = tx.QueryRowContext(ctx, queryOld, argsOld...).Scan(&oldSize, &oldPreviewSize)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return repo.Entry{}, customerrors.ErrNotFound
+	mediaFields := r.mediaFieldNames(db.ContentType)
+	for key, value := range meta.MediaFields {
+		if !slices.Contains(mediaFields, key) {
+			return fmt.Errorf("%w: '%s' is not a media field of content type %s", customerrors.ErrValidation, key, db.ContentType)
 		}
-		return repo.Entry{}, fmt.Errorf("failed to query old sizes: %w", err)
-	}
-
-	// 3. Update the entry row with new data
-	now := time.Now().UnixMilli()
-	updateData := map[string]any{
-		"timestamp":        entryTime.UnixMilli(),
-		"updated_at":       now,
-		"filesize":         entry.Size,
-		"preview_filesize": entry.PreviewSize,
-		"filename":         entry.FileName,
-		"status":           entry.Status,
-		"mime_type":        entry.MimeType,
+		columns[key] = value
 	}
+	return r.updateEntryColumns(ctx, dbID, entryID, columns)
+}
 
-	for key, value := range entry.MediaFields {
-		updateData[key] = value
+// UpdateEntryUserFields applies the changes of a user to an entry. The keys of fields are the mutable
+// standard fields (filename and external_id as string, timestamp as time.Time) and the names of the
+// custom fields of the database. Any other key is rejected with ErrValidation, so a custom field sharing
+// its name with a standard or media column can never overwrite that column. The updated entry is returned.
+func (r *SQLiteRepository) UpdateEntryUserFields(ctx context.Context, dbID repo.ULID, entryID int64, fields map[string]any) (repo.Entry, error) {
+	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
+	if err != nil {
+		return repo.Entry{}, err
 	}
-	cfNameToID := make(map[string]int)
+	cfNameToID := make(map[string]int, len(customFields))
 	for _, cf := range customFields {
 		cfNameToID[cf.Name] = cf.ID
 	}
-	for key, value := range entry.CustomFields {
-		if id, ok := cfNameToID[key]; ok {
-			updateData[fmt.Sprintf("%s%d", customFieldsPrefix, id)] = value
-		} else {
-			updateData[customFieldsPrefix+key] = value
+
+	columns := make(map[string]any, len(fields))
+	for key, value := range fields {
+		switch key {
+		case "filename", "external_id":
+			s, ok := value.(string)
+			if !ok {
+				return repo.Entry{}, fmt.Errorf("%w: '%s' must be a string", customerrors.ErrValidation, key)
+			}
+			columns[key] = s
+		case "timestamp":
+			ts, ok := value.(time.Time)
+			if !ok {
+				return repo.Entry{}, fmt.Errorf("%w: 'timestamp' must be a time", customerrors.ErrValidation)
+			}
+			columns[key] = ts.UnixMilli()
+		default:
+			id, ok := cfNameToID[key]
+			if !ok {
+				return repo.Entry{}, fmt.Errorf("%w: '%s' is neither a custom field nor a mutable standard field", customerrors.ErrValidation, key)
+			}
+			columns[fmt.Sprintf("%s%d", customFieldsPrefix, id)] = value
 		}
 	}
 
-	updateQuery, argsUpdate, err := r.Builder.Update(tableName).
-		SetMap(updateData).
-		Where(squirrel.Eq{"id": entry.ID}).
-		ToSql()
-	if err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to build update query: %w", err)
+	if err := r.updateEntryColumns(ctx, dbID, entryID, columns); err != nil {
+		return repo.Entry{}, err
 	}
+	return r.GetEntry(ctx, dbID, entryID)
+}
 
-	if _, err = tx.ExecContext(ctx, updateQuery, argsUpdate...); err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to update entry: %w", err)
-	}
+// updateEntryColumns writes the given columns of an entry and bumps its updated_at. Changed file sizes
+// are applied to the size statistics of the database in the same transaction. The column names are not
+// checked, only the typed update methods above may call it.
+func (r *SQLiteRepository) updateEntryColumns(ctx context.Context, dbID repo.ULID, entryID int64, columns map[string]any) error {
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	sizeColumns := []string{"filesize", "preview_filesize", "original_filesize"}
+
+	// 1. Run the update in a transaction
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		// 2. Query the current sizes of the entry before updating, and its uploader for the usage
+		var oldSizes [3]uint64
+		var uploadedBy sql.NullString
+		var createdAt int64
+		queryOld, argsOld, err := r.Builder.Select(append(sizeColumns, "uploaded_by", "created_at")...).
+			From(tableName).
+			Where(squirrel.Eq{"id": entryID}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build select old sizes query: %w", err)
+		}
 
-	// 4. Calculate the delta and atomically apply it to the main database stats
-	delta := (int64(entry.Size) + int64(entry.PreviewSize)) - (int64(oldSize) + int64(oldPreviewSize))
+		err = tx.QueryRowContext(ctx, queryOld, argsOld...).Scan(&oldSizes[0], &oldSizes[1], &oldSizes[2], &uploadedBy, &createdAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return customerrors.ErrNotFound
+			}
+			return fmt.Errorf("failed to query old sizes: %w", err)
+		}
 
-	if delta != 0 {
-		statsQuery, statsArgs, err := r.Builder.Update("databases").
-			Set("total_disk_space_bytes", squirrel.Expr("total_disk_space_bytes + ?", delta)).
-			Where(squirrel.Eq{"id": dbID.String()}).
+		// 3. Update the entry row with new data
+		updateData := maps.Clone(columns)
+		updateData["updated_at"] = time.Now().UnixMilli()
+
+		updateQuery, argsUpdate, err := r.Builder.Update(tableName).
+			SetMap(updateData).
+			Where(squirrel.Eq{"id": entryID}).
 			ToSql()
 		if err != nil {
-			return repo.Entry{}, fmt.Errorf("failed to build stats update query: %w", err)
+			return fmt.Errorf("failed to build update query: %w", err)
 		}
 
-		if _, err := tx.ExecContext(ctx, statsQuery, statsArgs...); err != nil {
-			return repo.Entry{}, fmt.Errorf("failed to update database stats: %w", err)
+		if _, err = tx.ExecContext(ctx, updateQuery, argsUpdate...); err != nil {
+			if isExternalIDConflict(err) {
+				return fmt.Errorf("%w: external_id '%s' is already used", customerrors.ErrConflict, columns["external_id"])
+			}
+			return fmt.Errorf("failed to update entry: %w", err)
 		}
-	}
 
-	// 5. Commit Transaction
-	if err := tx.Commit(); err != nil {
-		return repo.Entry{}, fmt.Errorf("failed to commit transaction: %w", err)
-	}
+		// 4. Calculate the delta of the written sizes and atomically apply it to the main database stats
+		var delta int64
+		for i, column := range sizeColumns {
+			if size, ok := columns[column].(uint64); ok {
+				delta += int64(size) - int64(oldSizes[i])
+			}
+		}
 
-	entry.UpdatedAt = time.UnixMilli(now)
+		if delta != 0 {
+			statsQuery, statsArgs, err := r.Builder.Update("databases").
+				Set("total_disk_space_bytes", squirrel.Expr("total_disk_space_bytes + ?", delta)).
+				Where(squirrel.Eq{"id": dbID.String()}).
+				ToSql()
+			if err != nil {
+				return fmt.Errorf("failed to build stats update query: %w", err)
+			}
 
-	return entry, nil
+			if _, err := tx.ExecContext(ctx, statsQuery, statsArgs...); err != nil {
+				return fmt.Errorf("failed to update database stats: %w", err)
+			}
+			if err := r.recordUsageChanges(ctx, tx, dbID, []usageChange{{username: uploadedBy.String, day: usageDay(createdAt), bytes: delta}}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.forgetDatabase(dbID)
+	return nil
 }
 
 // UpdateEntriesStatus efficiently modifies the async processing status of multiple entries at once.
@@ -335,58 +510,111 @@ func (r *SQLiteRepository) UpdateEntriesStatus(ctx context.Context, dbID repo.UL
 	if rowsAffected == 0 {
 		return customerrors.ErrNotFound
 	}
+	r.forgetDatabase(dbID) // the processing count of the stats changed
 
 	return nil
 }
 
-// DeleteEntry removes a single entry and atomically decrements the parent database's statistics.
-func (r *SQLiteRepository) DeleteEntry(ctx context.Context, dbID repo.ULID, id int64) (repo.DeletedEntryMeta, error) {
-	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
-
-	// 1. Begin SQL Transaction
-	tx, err := r.DB.BeginTx(ctx, nil)
-	if err != nil {
-		return repo.DeletedEntryMeta{}, fmt.Errorf("failed to begin transaction: %w", err)
+// MarkEntriesDeleting marks the given entries as deleting, skipping entries that are not ready or errored
+// (e.g. still being processed by a worker). The status is checked and changed in a single statement,
+// so a worker cannot claim an entry in between. Returns the IDs of the marked entries.
+func (r *SQLiteRepository) MarkEntriesDeleting(ctx context.Context, dbID repo.ULID, entryIDs []int64) ([]int64, error) {
+	if len(entryIDs) == 0 {
+		return []int64{}, nil
 	}
-	defer tx.Rollback()
 
-	// 2. Delete the row and retrieve its sizes using RETURNING
-	deleteQuery, deleteArgs, err := r.Builder.Delete(tableName).
-		Where(squirrel.Eq{"id": id}).
-		Suffix("RETURNING id, filesize, preview_filesize").
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query, args, err := r.Builder.Update(tableName).
+		Set("status", repo.EntryStatusDeleting).
+		Set("updated_at", time.Now().UnixMilli()).
+		Where(squirrel.Eq{"id": entryIDs}).
+		Where(squirrel.Eq{"status": []repo.EntryStatus{repo.EntryStatusReady, repo.EntryStatusError}}).
+		Where(squirrel.Eq{"legal_hold": false}).
+		Suffix("RETURNING id").
 		ToSql()
 	if err != nil {
-		return repo.DeletedEntryMeta{}, fmt.Errorf("failed to build delete query: %w", err)
+		return nil, fmt.Errorf("failed to build mark deleting query: %w", err)
 	}
 
-	var meta repo.DeletedEntryMeta
-	err = tx.QueryRowContext(ctx, deleteQuery, deleteArgs...).Scan(&meta.ID, &meta.Filesize, &meta.PreviewSize)
+	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return repo.DeletedEntryMeta{}, customerrors.ErrNotFound
-		}
-		return repo.DeletedEntryMeta{}, fmt.Errorf("failed to execute delete and retrieve sizes: %w", err)
+		return nil, fmt.Errorf("failed to mark entries as deleting: %w", err)
 	}
+	defer rows.Close()
 
-	// 3. Atomically decrement the parent database stats
-	totalDeletedSize := meta.Filesize + meta.PreviewSize
-	statsQuery, statsArgs, err := r.Builder.Update("databases").
-		Set("entry_count", squirrel.Expr("MAX(0, entry_count - 1)")).
-		Set("total_disk_space_bytes", squirrel.Expr("MAX(0, total_disk_space_bytes - ?)", totalDeletedSize)).
-		Where(squirrel.Eq{"id": dbID.String()}).
-		ToSql()
-	if err != nil {
-		return repo.DeletedEntryMeta{}, fmt.Errorf("failed to build stats update query: %w", err)
+	marked := make([]int64, 0, len(entryIDs))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan marked entry id: %w", err)
+		}
+		marked = append(marked, id)
 	}
-
-	if _, err := tx.ExecContext(ctx, statsQuery, statsArgs...); err != nil {
-		return repo.DeletedEntryMeta{}, fmt.Errorf("failed to update database stats: %w", err)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read marked entries: %w", err)
 	}
 
-	// 4. Commit Transaction
-	if err := tx.Commit(); err != nil {
-		return repo.DeletedEntryMeta{}, fmt.Errorf("failed to commit transaction: %w", err)
+	return marked, nil
+}
+
+// DeleteEntry removes a single entry and atomically decrements the parent database's statistics.
+func (r *SQLiteRepository) DeleteEntry(ctx context.Context, dbID repo.ULID, id int64) (repo.DeletedEntryMeta, error) {
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+
+	// 1. Run the deletion in a transaction
+	var meta repo.DeletedEntryMeta
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		// 2. Refuse entries under legal hold
+		held, err := r.getHeldEntries(ctx, tx, dbID, []int64{id})
+		if err != nil {
+			return err
+		}
+		if len(held) > 0 {
+			return fmt.Errorf("%w: entry %d", customerrors.ErrLegalHold, id)
+		}
+
+		// 3. Delete the row and retrieve its sizes using RETURNING
+		deleteQuery, deleteArgs, err := r.Builder.Delete(tableName).
+			Where(squirrel.Eq{"id": id}).
+			Suffix("RETURNING id, filesize, preview_filesize, original_filesize, uploaded_by, created_at").
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build delete query: %w", err)
+		}
+
+		var uploadedBy sql.NullString
+		var createdAt int64
+		err = tx.QueryRowContext(ctx, deleteQuery, deleteArgs...).Scan(&meta.ID, &meta.Filesize, &meta.PreviewSize, &meta.OriginalSize, &uploadedBy, &createdAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return customerrors.ErrNotFound
+			}
+			return fmt.Errorf("failed to execute delete and retrieve sizes: %w", err)
+		}
+
+		// 4. Atomically decrement the parent database stats
+		totalDeletedSize := meta.Filesize + meta.PreviewSize + meta.OriginalSize
+		statsQuery, statsArgs, err := r.Builder.Update("databases").
+			Set("entry_count", squirrel.Expr("MAX(0, entry_count - 1)")).
+			Set("total_disk_space_bytes", squirrel.Expr("MAX(0, total_disk_space_bytes - ?)", totalDeletedSize)).
+			Where(squirrel.Eq{"id": dbID.String()}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build stats update query: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, statsQuery, statsArgs...); err != nil {
+			return fmt.Errorf("failed to update database stats: %w", err)
+		}
+
+		return r.recordUsageChanges(ctx, tx, dbID, []usageChange{{
+			username: uploadedBy.String, day: usageDay(createdAt), entries: -1, bytes: -int64(totalDeletedSize),
+		}})
+	})
+	if err != nil {
+		return repo.DeletedEntryMeta{}, err
 	}
+	r.forgetDatabase(dbID)
 
 	return meta, nil
 }
@@ -397,19 +625,98 @@ func (r *SQLiteRepository) DeleteEntries(ctx context.Context, dbID repo.ULID, en
 		return nil, customerrors.ErrNotFound
 	}
 
+	// 1. Run the deletion in a transaction
+	var deletedMetas []repo.DeletedEntryMeta
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		// 2. Refuse the whole batch if any entry is under legal hold
+		held, err := r.getHeldEntries(ctx, tx, dbID, entryIDs)
+		if err != nil {
+			return err
+		}
+		if len(held) > 0 {
+			return fmt.Errorf("%w: entries %v", customerrors.ErrLegalHold, held)
+		}
+
+		deletedMetas, err = r.deleteEntryRows(ctx, tx, dbID, squirrel.Eq{"id": entryIDs})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.forgetDatabase(dbID)
+
+	return deletedMetas, nil
+}
+
+// DeleteSettledEntries removes the rows of the entries that are ready or errored and not under legal hold
+// in one transaction, like DeleteEntries without refusing the batch. The IDs of the entries that still
+// exist afterwards, queued, processing or held, are returned as skipped. If progress is set, the job it
+// returns is stored in the same transaction, so the progress of a job never misses deleted rows.
+func (r *SQLiteRepository) DeleteSettledEntries(ctx context.Context, dbID repo.ULID, entryIDs []int64, progress repo.JobProgress) ([]repo.DeletedEntryMeta, []int64, error) {
+	if len(entryIDs) == 0 {
+		return []repo.DeletedEntryMeta{}, []int64{}, nil
+	}
+
 	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	var deletedMetas []repo.DeletedEntryMeta
+	skipped := []int64{}
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		deletedMetas, err = r.deleteEntryRows(ctx, tx, dbID, squirrel.And{
+			squirrel.Eq{"id": entryIDs},
+			squirrel.Eq{"status": []repo.EntryStatus{repo.EntryStatusReady, repo.EntryStatusError}},
+			squirrel.Eq{"legal_hold": false},
+		})
+		if err != nil {
+			return err
+		}
 
-	// 1. Begin SQL Transaction
-	tx, err := r.DB.BeginTx(ctx, nil)
+		query, args, err := r.Builder.Select("id").From(tableName).Where(squirrel.Eq{"id": entryIDs}).OrderBy("id").ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build skipped entries query: %w", err)
+		}
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to query skipped entries: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("failed to scan skipped entry id: %w", err)
+			}
+			skipped = append(skipped, id)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		if deletedMetas == nil {
+			deletedMetas = []repo.DeletedEntryMeta{}
+		}
+		if progress == nil {
+			return nil
+		}
+		return r.updateJob(ctx, tx, progress(deletedMetas, skipped))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, nil, err
 	}
-	defer tx.Rollback()
+	r.forgetDatabase(dbID)
+
+	return deletedMetas, skipped, nil
+}
 
-	// 2. Delete the rows and retrieve their sizes using RETURNING
+// deleteEntryRows deletes the matching rows of an entry table within a transaction and decrements the
+// statistics of the database and the usage of the uploaders by them. It returns the sizes of the deleted entries.
+func (r *SQLiteRepository) deleteEntryRows(ctx context.Context, tx *sql.Tx, dbID repo.ULID, where squirrel.Sqlizer) ([]repo.DeletedEntryMeta, error) {
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+
+	// Delete the rows and retrieve their sizes using RETURNING
 	deleteQuery, deleteArgs, err := r.Builder.Delete(tableName).
-		Where(squirrel.Eq{"id": entryIDs}).
-		Suffix("RETURNING id, filesize, preview_filesize").
+		Where(where).
+		Suffix("RETURNING id, filesize, preview_filesize, original_filesize, uploaded_by, created_at").
 		ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("failed to build bulk delete query: %w", err)
@@ -423,16 +730,18 @@ func (r *SQLiteRepository) DeleteEntries(ctx context.Context, dbID repo.ULID, en
 
 	var deletedMetas []repo.DeletedEntryMeta
 	var totalDeletedSize uint64
-	var deletedCount int
-
+	var usage []usageChange
 	for rows.Next() {
 		var meta repo.DeletedEntryMeta
-		if err := rows.Scan(&meta.ID, &meta.Filesize, &meta.PreviewSize); err != nil {
+		var uploadedBy sql.NullString
+		var createdAt int64
+		if err := rows.Scan(&meta.ID, &meta.Filesize, &meta.PreviewSize, &meta.OriginalSize, &uploadedBy, &createdAt); err != nil {
 			return nil, fmt.Errorf("failed to scan deleted entry meta: %w", err)
 		}
 		deletedMetas = append(deletedMetas, meta)
-		totalDeletedSize += meta.Filesize + meta.PreviewSize
-		deletedCount++
+		size := meta.Filesize + meta.PreviewSize + meta.OriginalSize
+		totalDeletedSize += size
+		usage = append(usage, usageChange{username: uploadedBy.String, day: usageDay(createdAt), entries: -1, bytes: -int64(size)})
 	}
 
 	if err := rows.Err(); err != nil {
@@ -440,17 +749,14 @@ func (r *SQLiteRepository) DeleteEntries(ctx context.Context, dbID repo.ULID, en
 	}
 	rows.Close() // close read lock immediately instead of waiting on defer
 
-	// If no rows were actually deleted (e.g., IDs didn't exist), we can safely commit and return
-	if deletedCount == 0 {
-		if err := tx.Commit(); err != nil {
-			return nil, fmt.Errorf("failed to commit empty transaction: %w", err)
-		}
+	// If no rows were actually deleted (e.g., IDs didn't exist), there are no stats to update
+	if len(deletedMetas) == 0 {
 		return deletedMetas, nil
 	}
 
-	// 3. Atomically decrement the parent database stats in one operation
+	// Atomically decrement the parent database stats in one operation
 	statsQuery, statsArgs, err := r.Builder.Update("databases").
-		Set("entry_count", squirrel.Expr("MAX(0, entry_count - ?)", deletedCount)).
+		Set("entry_count", squirrel.Expr("MAX(0, entry_count - ?)", len(deletedMetas))).
 		Set("total_disk_space_bytes", squirrel.Expr("MAX(0, total_disk_space_bytes - ?)", totalDeletedSize)).
 		Where(squirrel.Eq{"id": dbID.String()}).
 		ToSql()
@@ -461,93 +767,254 @@ func (r *SQLiteRepository) DeleteEntries(ctx context.Context, dbID repo.ULID, en
 	if _, err := tx.ExecContext(ctx, statsQuery, statsArgs...); err != nil {
 		return nil, fmt.Errorf("failed to update database stats: %w", err)
 	}
-
-	// 4. Commit Transaction
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	if err := r.recordUsageChanges(ctx, tx, dbID, usage); err != nil {
+		return nil, err
 	}
-
 	return deletedMetas, nil
 }
 
 // SearchEntries retrieves entries matching complex nested filter criteria.
 func (r *SQLiteRepository) SearchEntries(ctx context.Context, dbID repo.ULID, req repo.SearchRequest, customFields []repo.CustomFieldDef) ([]repo.Entry, error) {
-	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
-	builder := r.Builder.Select("*").From(tableName)
+	builder, usesMatch, err := r.buildSearchQuery(dbID, req, customFields)
+	if err != nil {
+		return nil, err
+	}
 
-	// 1. Build Filter Conditions securely
-	if req.Filter != nil && len(req.Filter.Conditions) > 0 {
-		var andExpr squirrel.And
-		var orExpr squirrel.Or
-		isOr := strings.ToLower(req.Filter.Operator) == "or"
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build search query: %w", err)
+	}
 
-		for _, cond := range req.Filter.Conditions {
-			safeField, err := r.validateAndFormatSearchField(cond.Field, customFields)
-			if err != nil {
-				return nil, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
-			}
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		if usesMatch && isFulltextQueryError(err) {
+			return nil, fmt.Errorf("%w: invalid full-text query: %v", customerrors.ErrValidation, err)
+		}
+		return nil, fmt.Errorf("failed to execute search query: %w", err)
+	}
+	defer rows.Close()
 
-			if !isValidOperator(cond.Operator) {
-				return nil, fmt.Errorf("%w: invalid operator '%s'", customerrors.ErrValidation, cond.Operator)
-			}
+	entries, err := r.scanEntryRows(rows, customFields)
+	if err != nil {
+		if usesMatch && isFulltextQueryError(err) {
+			return nil, fmt.Errorf("%w: invalid full-text query: %v", customerrors.ErrValidation, err)
+		}
+		return nil, fmt.Errorf("failed to scan search results: %w", err)
+	}
 
-			// Safely assemble the SQL condition using squirrel.Expr
-			expr := squirrel.Expr(fmt.Sprintf("%s %s ?", safeField, cond.Operator), cond.Value)
-			if isOr {
-				orExpr = append(orExpr, expr)
-			} else {
-				andExpr = append(andExpr, expr)
-			}
+	return entries, nil
+}
+
+// ExplainSearch builds the query of a search request like SearchEntries and returns it with the plan
+// SQLite chooses for it, without running it.
+func (r *SQLiteRepository) ExplainSearch(ctx context.Context, dbID repo.ULID, req repo.SearchRequest, customFields []repo.CustomFieldDef) (repo.QueryPlan, error) {
+	builder, usesMatch, err := r.buildSearchQuery(dbID, req, customFields)
+	if err != nil {
+		return repo.QueryPlan{}, err
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return repo.QueryPlan{}, fmt.Errorf("failed to build search query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		if usesMatch && isFulltextQueryError(err) {
+			return repo.QueryPlan{}, fmt.Errorf("%w: invalid full-text query: %v", customerrors.ErrValidation, err)
 		}
+		return repo.QueryPlan{}, fmt.Errorf("failed to explain search query: %w", err)
+	}
+	defer rows.Close()
 
-		if isOr {
-			builder = builder.Where(orExpr)
+	plan := repo.QueryPlan{SQL: query, Args: args, Steps: []repo.QueryPlanStep{}}
+	for rows.Next() {
+		var step repo.QueryPlanStep
+		var notUsed int
+		if err := rows.Scan(&step.ID, &step.Parent, &notUsed, &step.Detail); err != nil {
+			return repo.QueryPlan{}, fmt.Errorf("failed to scan query plan: %w", err)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return repo.QueryPlan{}, fmt.Errorf("query plan iteration error: %w", err)
+	}
+	return plan, nil
+}
+
+// buildSearchQuery validates a search request and builds its query. It also reports whether the query
+// contains a MATCH condition, whose value is a full-text query that may only fail when executed.
+func (r *SQLiteRepository) buildSearchQuery(dbID repo.ULID, req repo.SearchRequest, customFields []repo.CustomFieldDef) (squirrel.SelectBuilder, bool, error) {
+	if req.Pagination.Offset < 0 {
+		return squirrel.SelectBuilder{}, false, fmt.Errorf("%w: invalid offset %d (must not be negative)", customerrors.ErrValidation, req.Pagination.Offset)
+	}
+	req.Pagination.Limit, _ = r.PageLimits.Apply(req.Pagination.Limit)
+
+	columns, err := r.selectColumns(req.Fields, customFields)
+	if err != nil {
+		return squirrel.SelectBuilder{}, false, err
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+
+	// 1. Build Filter Conditions securely
+	now := req.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	where, rankColumn, rankQuery, err := r.buildWhereExpr(dbID, req.Filter, customFields, now)
+	if err != nil {
+		return squirrel.SelectBuilder{}, false, err
+	}
+
+	// The geo area applies to all entries the filter matches
+	var distance squirrel.Sqlizer
+	if req.Geo != nil {
+		var geoExpr squirrel.Sqlizer
+		if geoExpr, distance, err = geoCondition(req.Geo, customFields); err != nil {
+			return squirrel.SelectBuilder{}, false, err
+		}
+		if where != nil {
+			where = squirrel.And{where, geoExpr}
 		} else {
-			builder = builder.Where(andExpr)
+			where = geoExpr
 		}
 	}
 
 	// 2. Build Sorting securely
+	var orderBy string
+	var orderByArgs []any
+	sortByRank := req.Sort != nil && req.Sort.Field == repo.SortFieldFulltextRank
 	if req.Sort != nil && req.Sort.Field != "" {
-		safeField, err := r.validateAndFormatSearchField(req.Sort.Field, customFields)
-		if err != nil {
-			return nil, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
-		}
-
 		dir := "DESC"
 		if strings.ToLower(req.Sort.Direction) == "asc" {
 			dir = "ASC"
 		}
-		builder = builder.OrderBy(fmt.Sprintf("%s %s", safeField, dir))
+
+		if req.Sort.Collation != "" && (req.Sort.Field == repo.SortFieldGeoDistance || sortByRank) {
+			return squirrel.SelectBuilder{}, false, fmt.Errorf("%w: collation is only allowed for sorting by a TEXT field", customerrors.ErrValidation)
+		}
+
+		if req.Sort.Field == repo.SortFieldGeoDistance {
+			if distance == nil {
+				return squirrel.SelectBuilder{}, false, fmt.Errorf("%w: sorting by '%s' needs a geo radius", customerrors.ErrValidation, repo.SortFieldGeoDistance)
+			}
+			distanceSQL, args, err := distance.ToSql()
+			if err != nil {
+				return squirrel.SelectBuilder{}, false, fmt.Errorf("failed to build geo distance: %w", err)
+			}
+			orderBy, orderByArgs = distanceSQL+" "+geoSortDirection(req.Sort.Direction), args
+		} else if sortByRank {
+			if rankColumn == "" {
+				return squirrel.SelectBuilder{}, false, fmt.Errorf("%w: sorting by '%s' needs a MATCH condition", customerrors.ErrValidation, repo.SortFieldFulltextRank)
+			}
+			orderBy = "fts_hits.fts_rank " + dir
+		} else {
+			safeField, err := r.validateAndFormatSearchField(req.Sort.Field, customFields)
+			if err != nil {
+				return squirrel.SelectBuilder{}, false, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+			}
+			collation, err := sortCollation(req.Sort.Collation, r.searchFieldType(req.Sort.Field, customFields))
+			if err != nil {
+				return squirrel.SelectBuilder{}, false, err
+			}
+			orderBy = fmt.Sprintf("%s%s %s", safeField, collation, dir)
+		}
 	} else {
-		builder = builder.OrderBy("timestamp DESC")
+		orderBy = "timestamp DESC"
 	}
 
-	// 3. Build Pagination
-	if req.Pagination.Limit > 0 {
-		builder = builder.Limit(uint64(req.Pagination.Limit))
+	// The rank of the matching entries is joined, higher is more relevant. Entries only found by other
+	// conditions of an "or" filter have no rank and come last when sorting descending.
+	if sortByRank && columns[0] == "*" {
+		columns = []string{tableName + ".*"}
+	}
+	builder := r.Builder.Select(columns...).From(tableName)
+	if sortByRank {
+		builder = builder.LeftJoin(fmt.Sprintf(`(SELECT rowid AS fts_rowid, -rank AS fts_rank FROM %s WHERE %s MATCH ?) AS fts_hits ON fts_hits.fts_rowid = %s.id`,
+			fulltextTableName(dbID.String()), rankColumn, tableName), rankQuery)
+	}
+	if where != nil {
+		builder = builder.Where(where)
 	}
+	builder = builder.OrderByClause(orderBy, orderByArgs...)
+
+	// 3. Build Pagination
+	builder = builder.Limit(uint64(req.Pagination.Limit))
 	if req.Pagination.Offset > 0 {
 		builder = builder.Offset(uint64(req.Pagination.Offset))
 	}
 
-	query, args, err := builder.ToSql()
-	if err != nil {
-		return nil, fmt.Errorf("failed to build search query: %w", err)
-	}
+	return builder, rankColumn != "", nil
+}
 
-	rows, err := r.DB.QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute search query: %w", err)
+// buildWhereExpr validates the filter conditions against the field whitelist and combines them, nil if
+// there are none. The first MATCH condition is returned as the column and query of the fts_rank sort field.
+// Relative times on timestamp fields are evaluated at now.
+func (r *SQLiteRepository) buildWhereExpr(dbID repo.ULID, filter *repo.FilterGroup, customFields []repo.CustomFieldDef, now time.Time) (squirrel.Sqlizer, string, any, error) {
+	if filter == nil || len(filter.Conditions) == 0 {
+		return nil, "", nil, nil
 	}
-	defer rows.Close()
 
-	entries, err := r.scanEntryRows(rows, customFields)
-	if err != nil {
-		return nil, fmt.Errorf("failed to scan search results: %w", err)
+	var andExpr squirrel.And
+	var orExpr squirrel.Or
+	var rankColumn string
+	var rankQuery any
+	isOr := strings.ToLower(filter.Operator) == "or"
+
+	for _, cond := range filter.Conditions {
+		safeField, err := r.validateAndFormatSearchField(cond.Field, customFields)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+		}
+
+		if !isValidOperator(cond.Operator) {
+			return nil, "", nil, fmt.Errorf("%w: invalid operator '%s'", customerrors.ErrValidation, cond.Operator)
+		}
+
+		var expr squirrel.Sqlizer
+		if strings.EqualFold(cond.Operator, "MATCH") {
+			if !isFulltextField(cond.Field, customFields) {
+				return nil, "", nil, fmt.Errorf("%w: operator 'MATCH' can only be used on full-text fields (config.fulltext_fields), not on '%s'", customerrors.ErrValidation, cond.Field)
+			}
+			if query, ok := cond.Value.(string); !ok || strings.TrimSpace(query) == "" {
+				return nil, "", nil, fmt.Errorf("%w: operator 'MATCH' on field '%s' needs a non-empty text query", customerrors.ErrValidation, cond.Field)
+			}
+			expr = fulltextCondition(dbID.String(), safeField, cond.Value)
+			if rankColumn == "" {
+				rankColumn, rankQuery = safeField, cond.Value
+			}
+		} else {
+			value := cond.Value
+			if slices.Contains(repo.TimestampFields, cond.Field) {
+				if value, err = repo.TimeFilterValue(value, now); err != nil {
+					return nil, "", nil, fmt.Errorf("%w (field '%s')", err, cond.Field)
+				}
+			}
+			if isTextMatchOperator(cond.Operator) {
+				if fieldType := r.searchFieldType(cond.Field, customFields); fieldType != "TEXT" {
+					return nil, "", nil, fmt.Errorf("%w: operator '%s' can only be used on TEXT fields, not on '%s' of type %s", customerrors.ErrValidation, cond.Operator, cond.Field, fieldType)
+				}
+				if expr, err = textMatchCondition(safeField, cond.Operator, value); err != nil {
+					return nil, "", nil, fmt.Errorf("%w (field '%s')", err, cond.Field)
+				}
+			} else {
+				// Safely assemble the SQL condition using squirrel.Expr
+				expr = squirrel.Expr(fmt.Sprintf("%s %s ?", safeField, cond.Operator), value)
+			}
+		}
+
+		if isOr {
+			orExpr = append(orExpr, expr)
+		} else {
+			andExpr = append(andExpr, expr)
+		}
 	}
 
-	return entries, nil
+	if isOr {
+		return orExpr, rankColumn, rankQuery, nil
+	}
+	return andExpr, rankColumn, rankQuery, nil
 }
 
 // ClaimQueuedEntry atomically claims a queued entry by changing its status to processing.
@@ -566,6 +1033,22 @@ func (r *SQLiteRepository) ClaimQueuedEntry(ctx context.Context, dbID repo.ULID,
 	return rows == 1, nil
 }
 
+// ClaimFailedEntry atomically claims an errored entry for a retry by changing its status to processing.
+func (r *SQLiteRepository) ClaimFailedEntry(ctx context.Context, dbID repo.ULID, entryID int64) (bool, error) {
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query := fmt.Sprintf(`UPDATE %s SET status = ?, updated_at = ? WHERE id = ? AND status = ?`, tableName)
+	now := time.Now().UnixMilli()
+	res, err := r.DB.ExecContext(ctx, query, repo.EntryStatusProcessing, now, entryID, repo.EntryStatusError)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute claim update: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve rows affected: %w", err)
+	}
+	return rows == 1, nil
+}
+
 // GetEntriesByStatus retrieves entries matching a status, ordered by ID ascending (oldest first).
 func (r *SQLiteRepository) GetEntriesByStatus(ctx context.Context, dbID repo.ULID, status repo.EntryStatus) ([]repo.Entry, error) {
 	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
@@ -0,0 +1,456 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestEntryUploadOrigin(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "origin_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	origin := repo.UploadOrigin{UploadedBy: "camera-07", ClientIP: "198.51.100.9", UserAgent: "cam-firmware/1.2"}
+	uploaded, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream", Origin: origin})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	// An entry without a recorded origin, like the entries from before the migration
+	unknown, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "b.bin", Timestamp: time.Now(), MimeType: "application/octet-stream"})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	// 1. The origin is returned with the entry, and stays NULL if unknown
+	got, err := r.GetEntry(ctx, db.ID, uploaded.ID)
+	if err != nil {
+		t.Fatalf("failed to get entry: %v", err)
+	}
+	if got.Origin != origin {
+		t.Errorf("expected origin %+v, got %+v", origin, got.Origin)
+	}
+	var nulls int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM "entries_`+db.ID.String()+`" WHERE id = ? AND uploaded_by IS NULL AND upload_ip IS NULL AND upload_user_agent IS NULL`, unknown.ID).Scan(&nulls); err != nil || nulls != 1 {
+		t.Errorf("expected NULL origin columns for an unknown origin, got %d (err %v)", nulls, err)
+	}
+
+	// 2. Searching by the uploader
+	found, err := r.SearchEntries(ctx, db.ID, repo.SearchRequest{
+		Filter:     &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "uploaded_by", Operator: "=", Value: "camera-07"}}},
+		Pagination: repo.Pagination{Limit: 10},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to search entries: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != uploaded.ID {
+		t.Errorf("expected only the uploaded entry, got %+v", found)
+	}
+}
+
+func TestPageLimits(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "paging", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for i := range 5 {
+		if _, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "f.bin", Timestamp: time.UnixMilli(int64(i + 1)), MimeType: "application/octet-stream"}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+	}
+	r.PageLimits = repo.PageLimits{Default: 2, Max: 3}
+
+	// Callers that skip the handler still get the default and the maximum applied to the query
+	for _, tc := range []struct{ limit, want int }{{0, 2}, {-1, 2}, {1, 1}, {3, 3}, {1000000, 3}} {
+		listed, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: tc.limit})
+		if err != nil || len(listed) != tc.want {
+			t.Errorf("list limit %d: expected %d entries, got %d (err %v)", tc.limit, tc.want, len(listed), err)
+		}
+		found, err := r.SearchEntries(ctx, db.ID, repo.SearchRequest{Pagination: repo.Pagination{Limit: tc.limit}}, nil)
+		if err != nil || len(found) != tc.want {
+			t.Errorf("search limit %d: expected %d entries, got %d (err %v)", tc.limit, tc.want, len(found), err)
+		}
+	}
+
+	if _, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Offset: -1}); !errors.Is(err, customerrors.ErrValidation) {
+		t.Errorf("expected a validation error for a negative offset, got %v", err)
+	}
+	if _, err := r.SearchEntries(ctx, db.ID, repo.SearchRequest{Pagination: repo.Pagination{Offset: -1}}, nil); !errors.Is(err, customerrors.ErrValidation) {
+		t.Errorf("expected a validation error for a negative offset, got %v", err)
+	}
+}
+
+func TestEntryServerTimestamps(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newDatabaseTestRepo(t)
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "ingestion_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// 1. A backfilled recording: the capture time is old, created_at is the server clock
+	capture := time.Now().Add(-30 * 24 * time.Hour).Truncate(time.Millisecond)
+	before := time.Now().Truncate(time.Millisecond)
+	created, err := r.CreateEntry(ctx, db, repo.Entry{
+		FileName:  "a.bin",
+		Timestamp: capture,
+		MimeType:  "application/octet-stream",
+		CreatedAt: capture, // ignored, the server sets it
+	})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	got, err := r.GetEntry(ctx, db.ID, created.ID)
+	if err != nil {
+		t.Fatalf("failed to get entry: %v", err)
+	}
+	if !got.Timestamp.Equal(capture) {
+		t.Errorf("expected the timestamp %v, got %v", capture, got.Timestamp)
+	}
+	if got.CreatedAt.Before(before) || got.CreatedAt.After(time.Now()) || !got.UpdatedAt.Equal(got.CreatedAt) {
+		t.Errorf("expected server side created_at and updated_at, got %v and %v", got.CreatedAt, got.UpdatedAt)
+	}
+
+	// 2. Updates, like the finalization of a worker, bump updated_at and keep created_at
+	previous := got
+	for i := range 3 {
+		time.Sleep(2 * time.Millisecond)
+		if err := r.UpdateEntryTechMetadata(ctx, db.ID, created.ID, repo.EntryTechMetadata{Size: uint64(i + 1), MimeType: previous.MimeType}); err != nil {
+			t.Fatalf("failed to update entry: %v", err)
+		}
+		if err := r.UpdateEntryStatus(ctx, db.ID, created.ID, repo.EntryStatusReady, "", ""); err != nil {
+			t.Fatalf("failed to update entry: %v", err)
+		}
+		got, err := r.GetEntry(ctx, db.ID, created.ID)
+		if err != nil {
+			t.Fatalf("failed to get entry: %v", err)
+		}
+		if !got.CreatedAt.Equal(previous.CreatedAt) {
+			t.Errorf("update %d: expected created_at to stay %v, got %v", i, previous.CreatedAt, got.CreatedAt)
+		}
+		if !got.UpdatedAt.After(previous.UpdatedAt) {
+			t.Errorf("update %d: expected updated_at after %v, got %v", i, previous.UpdatedAt, got.UpdatedAt)
+		}
+		previous = got
+	}
+
+	// 3. Both are filterable and sortable
+	found, err := r.SearchEntries(ctx, db.ID, repo.SearchRequest{
+		Filter:     &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "created_at", Operator: ">=", Value: before.UnixMilli()}}},
+		Sort:       &repo.SortCriteria{Field: "updated_at", Direction: "desc"},
+		Pagination: repo.Pagination{Limit: 10},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to search entries: %v", err)
+	}
+	if len(found) != 1 || found[0].ID != created.ID {
+		t.Errorf("expected the backfilled entry by its ingestion time, got %+v", found)
+	}
+}
+
+func TestSearchRelativeTimes(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newDatabaseTestRepo(t)
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "relative_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// Entries just inside and just outside of the last 24 hours and 7 days of a frozen clock
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	for name, ts := range map[string]time.Time{
+		"inside_day":   now.Add(-24*time.Hour + time.Second),
+		"outside_day":  now.Add(-24*time.Hour - time.Second),
+		"outside_week": now.Add(-7*24*time.Hour - time.Second),
+		"future":       now.Add(time.Minute),
+	} {
+		if _, err := r.CreateEntry(ctx, db, repo.Entry{FileName: name + ".bin", Timestamp: ts, MimeType: "application/octet-stream"}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+	}
+
+	search := func(conditions ...repo.Condition) ([]string, error) {
+		return searchNames(ctx, r, db.ID, now, conditions...)
+	}
+
+	for _, tc := range []struct {
+		conditions []repo.Condition
+		want       []string
+	}{
+		{[]repo.Condition{{Field: "timestamp", Operator: ">=", Value: "now-24h"}, {Field: "timestamp", Operator: "<=", Value: "now"}}, []string{"inside_day"}},
+		{[]repo.Condition{{Field: "timestamp", Operator: ">=", Value: "now-1d"}}, []string{"future", "inside_day"}},
+		{[]repo.Condition{{Field: "timestamp", Operator: ">=", Value: "now-1w"}, {Field: "timestamp", Operator: "<", Value: "now-86400s"}}, []string{"outside_day"}},
+		{[]repo.Condition{{Field: "timestamp", Operator: ">", Value: "now+30s"}}, []string{"future"}},
+		{[]repo.Condition{{Field: "timestamp", Operator: "<", Value: fmt.Sprint(now.Add(-7 * 24 * time.Hour).UnixMilli())}}, []string{"outside_week"}},
+	} {
+		got, err := search(tc.conditions...)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("%v: expected %v, got %v (%v)", tc.conditions, tc.want, got, err)
+		}
+	}
+
+	// Without a clock the current time is used, the entries were just created
+	if got, err := searchNames(ctx, r, db.ID, time.Time{}, repo.Condition{Field: "created_at", Operator: ">=", Value: "now-1h"}); err != nil || len(got) != 4 {
+		t.Errorf("expected all entries to be created in the last hour, got %v (%v)", got, err)
+	}
+
+	for _, value := range []string{"now-", "now-24", "now - 24h", "now-1y", "today", "NOW", "now-1.5h", "now-9999999999w", "now-999999999w"} {
+		if _, err := search(repo.Condition{Field: "timestamp", Operator: ">", Value: value}); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("expected a validation error for %q, got %v", value, err)
+		}
+	}
+	if _, err := search(repo.Condition{Field: "filename", Operator: "=", Value: "now-24h"}); err != nil {
+		t.Errorf("expected relative times to be plain text on other fields, got %v", err)
+	}
+}
+
+// searchNames returns the file names without extension of the entries matching all conditions, newest first.
+func searchNames(ctx context.Context, r *sqlite.SQLiteRepository, dbID repo.ULID, now time.Time, conditions ...repo.Condition) ([]string, error) {
+	entries, err := r.SearchEntries(ctx, dbID, repo.SearchRequest{
+		Filter:     &repo.FilterGroup{Operator: "and", Conditions: conditions},
+		Sort:       &repo.SortCriteria{Field: "timestamp", Direction: "desc"},
+		Pagination: repo.Pagination{Limit: 10},
+		Now:        now,
+	}, nil)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.FileName, ".bin"))
+	}
+	return names, err
+}
+
+func TestEntryTypedUpdates(t *testing.T) {
+	ctx := context.Background()
+	r, db := newDatabaseTestRepo(t)
+
+	// A custom field created before standard and media names were reserved, shadowing the width column
+	if _, err := r.DB.ExecContext(ctx, `UPDATE database_custom_fields SET name = 'width' WHERE database_id = ? AND name = 'camera'`, db.ID.String()); err != nil {
+		t.Fatalf("failed to rename custom field: %v", err)
+	}
+	r.Cache.Flush("")
+
+	created, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.png", Timestamp: time.UnixMilli(1000), MimeType: "image/png", Size: 10, Status: repo.EntryStatusProcessing, MediaFields: map[string]any{"width": uint64(0), "height": uint64(0)}})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+
+	// 1. Processing records the technical metadata, the size delta is applied to the stats
+	if err := r.UpdateEntryTechMetadata(ctx, db.ID, created.ID, repo.EntryTechMetadata{
+		Size:        40,
+		PreviewSize: 5,
+		MimeType:    "image/webp",
+		MediaFields: map[string]any{"width": uint64(640), "height": uint64(480)},
+	}); err != nil {
+		t.Fatalf("failed to update technical metadata: %v", err)
+	}
+	if err := r.UpdateEntryStatus(ctx, db.ID, created.ID, repo.EntryStatusReady, "", ""); err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	stats, err := r.GetDatabase(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	if stats.Stats.TotalDiskSpaceBytes != 45 {
+		t.Errorf("expected 45 bytes in the stats, got %d", stats.Stats.TotalDiskSpaceBytes)
+	}
+
+	// 2. The shadowing custom field is written to its own column, the media width is untouched
+	got, err := r.UpdateEntryUserFields(ctx, db.ID, created.ID, map[string]any{"width": "Nikon", "filename": "b.png"})
+	if err != nil {
+		t.Fatalf("failed to update user fields: %v", err)
+	}
+	if got.CustomFields["width"] != "Nikon" || got.FileName != "b.png" {
+		t.Errorf("expected the custom field and filename to be updated, got %+v", got)
+	}
+	if fmt.Sprint(got.MediaFields["width"]) != "640" || got.Status != repo.EntryStatusReady || got.MimeType != "image/webp" || got.Size != 40 {
+		t.Errorf("expected the technical metadata to be untouched, got %+v", got)
+	}
+
+	// 3. Keys that are neither custom fields nor mutable standard fields are rejected
+	for _, key := range []string{"status", "filesize", "mime_type", "height", "cf_1"} {
+		if _, err := r.UpdateEntryUserFields(ctx, db.ID, created.ID, map[string]any{key: 1}); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("expected ErrValidation for %q, got %v", key, err)
+		}
+	}
+	if _, err := r.UpdateEntryUserFields(ctx, db.ID, created.ID, map[string]any{"timestamp": int64(5)}); !errors.Is(err, customerrors.ErrValidation) {
+		t.Errorf("expected ErrValidation for a timestamp that is not a time, got %v", err)
+	}
+
+	// 4. Media fields of other content types and standard columns are no media fields
+	for _, key := range []string{"status", "duration", "filename"} {
+		err := r.UpdateEntryTechMetadata(ctx, db.ID, created.ID, repo.EntryTechMetadata{Size: 40, MediaFields: map[string]any{key: 1}})
+		if !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("expected ErrValidation for media field %q, got %v", key, err)
+		}
+	}
+
+	// 5. Unknown entries are not found
+	if err := r.UpdateEntryStatus(ctx, db.ID, created.ID+100, repo.EntryStatusReady, "", ""); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDatabaseStatsLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "stats_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// The stats read right after every step match the rows of the listing, through every way of reading them
+	assertStats := func(step string, entries, bytes, processing uint64) {
+		t.Helper()
+		want := repo.DatabaseStats{EntryCount: entries, TotalDiskSpaceBytes: bytes, ProcessingCount: processing}
+		stats, err := r.GetDatabaseStats(ctx, db.ID)
+		if err != nil {
+			t.Fatalf("%s: failed to get stats: %v", step, err)
+		}
+		got, err := r.GetDatabase(ctx, db.ID)
+		if err != nil {
+			t.Fatalf("%s: failed to get database: %v", step, err)
+		}
+		all, err := r.GetDatabases(ctx)
+		if err != nil || len(all) != 1 {
+			t.Fatalf("%s: failed to get databases: %v", step, err)
+		}
+		for source, s := range map[string]repo.DatabaseStats{"GetDatabaseStats": stats, "GetDatabase": got.Stats, "GetDatabases": all[0].Stats} {
+			if s != want {
+				t.Errorf("%s: expected %+v from %s, got %+v", step, want, source, s)
+			}
+		}
+		listed, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 100})
+		if err != nil {
+			t.Fatalf("%s: failed to list entries: %v", step, err)
+		}
+		if uint64(len(listed)) != entries {
+			t.Errorf("%s: expected %d listed entries, got %d", step, entries, len(listed))
+		}
+	}
+	// preliminary creates the row of an upload before its processing, like the processor does
+	preliminary := func() repo.Entry {
+		t.Helper()
+		entry, _, err := r.CreateEntryWithTasks(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream", Status: repo.EntryStatusProcessing}, nil, 0)
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		return entry
+	}
+	finalize := func(entry repo.Entry, size, previewSize uint64) {
+		t.Helper()
+		if err := r.UpdateEntryTechMetadata(ctx, db.ID, entry.ID, repo.EntryTechMetadata{Size: size, PreviewSize: previewSize, MimeType: "application/octet-stream"}); err != nil {
+			t.Fatalf("failed to update entry: %v", err)
+		}
+		if err := r.UpdateEntryStatus(ctx, db.ID, entry.ID, repo.EntryStatusReady, "", ""); err != nil {
+			t.Fatalf("failed to update entry status: %v", err)
+		}
+	}
+
+	// 1. Preliminary to final: counted from the commit of the row, the sizes follow when processing finished
+	first := preliminary()
+	assertStats("preliminary", 1, 0, 1)
+	finalize(first, 100, 10)
+	assertStats("final", 1, 110, 0)
+
+	// 2. Preliminary to error: the failed entry stays listed and counted, without sizes
+	failed := preliminary()
+	assertStats("second preliminary", 2, 110, 1)
+	if err := r.UpdateEntryStatus(ctx, db.ID, failed.ID, repo.EntryStatusError, "conversion_failed", "exit status 1"); err != nil {
+		t.Fatalf("failed to update entry status: %v", err)
+	}
+	assertStats("error", 2, 110, 0)
+
+	// 3. Single delete of the failed entry and of a processing one
+	if _, err := r.DeleteEntry(ctx, db.ID, failed.ID); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	assertStats("single delete", 1, 110, 0)
+	deletedWhileProcessing := preliminary()
+	if _, err := r.DeleteEntry(ctx, db.ID, deletedWhileProcessing.ID); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	assertStats("delete while processing", 1, 110, 0)
+
+	// 4. Bulk delete
+	second := preliminary()
+	finalize(second, 200, 0)
+	third := preliminary()
+	finalize(third, 300, 30)
+	assertStats("more uploads", 3, 640, 0)
+	if _, err := r.DeleteEntries(ctx, db.ID, []int64{first.ID, second.ID}); err != nil {
+		t.Fatalf("failed to delete entries: %v", err)
+	}
+	assertStats("bulk delete", 1, 330, 0)
+
+	// 5. Housekeeping deletes the settled entries and skips the processing one
+	pending := preliminary()
+	_, skipped, err := r.DeleteSettledEntries(ctx, db.ID, []int64{third.ID, pending.ID}, nil)
+	if err != nil {
+		t.Fatalf("failed to delete settled entries: %v", err)
+	}
+	if !slices.Equal(skipped, []int64{pending.ID}) {
+		t.Errorf("expected the processing entry to be skipped, got %v", skipped)
+	}
+	assertStats("housekeeping", 1, 0, 1)
+	finalize(pending, 50, 0)
+	assertStats("final after housekeeping", 1, 50, 0)
+}
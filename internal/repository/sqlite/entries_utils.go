@@ -5,9 +5,12 @@ import (
 	"fmt"
 	repo "mediahub_oss/internal/repository"
 	"mediahub_oss/internal/shared/customerrors"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Masterminds/squirrel"
 )
 
 // entryScanner holds pre-allocated slices and pre-computed field names
@@ -106,6 +109,39 @@ func (s entryScanner) scan(rows *sql.Rows) (repo.Entry, error) {
 			entry.Status = repo.EntryStatus(asInt64(val))
 		case "mime_type":
 			entry.MimeType = asString(val)
+		case "error_reason":
+			entry.ErrorReason = asString(val)
+		case "error_detail":
+			entry.ErrorDetail = asString(val)
+		case "original_filesize":
+			entry.OriginalSize = uint64(asInt64(val))
+		case "original_mime_type":
+			entry.OriginalMimeType = asString(val)
+		case "external_id":
+			entry.ExternalID = asString(val)
+		case "uploaded_by":
+			entry.Origin.UploadedBy = asString(val)
+		case "upload_ip":
+			entry.Origin.ClientIP = asString(val)
+		case "upload_user_agent":
+			entry.Origin.UserAgent = asString(val)
+		case "transcription_status":
+			entry.Transcription = asString(val)
+		case "legal_hold":
+			entry.LegalHold = asBool(val)
+		case "client_timestamp":
+			if val != nil {
+				entry.ClientTimestamp = time.UnixMilli(asInt64(val))
+			}
+		case "content_hash":
+			entry.ContentHash = asString(val)
+		case "content_revision":
+			entry.ContentRevision = asInt64(val)
+		case "last_verified_at":
+			tsMs := asInt64(val)
+			if tsMs > 0 {
+				entry.LastVerifiedAt = time.UnixMilli(tsMs)
+			}
 		default:
 			// We MUST convert []byte to string here to prevent Base64 JSON encoding!
 			if b, ok := val.([]byte); ok {
@@ -138,6 +174,14 @@ func asInt64(val any) int64 {
 	return 0
 }
 
+// asBool is a safe type-assertion helper for SQLite boolean scans, stored as 0 or 1
+func asBool(val any) bool {
+	if b, ok := val.(bool); ok {
+		return b
+	}
+	return asInt64(val) != 0
+}
+
 // Helper to safely extract a string from the database interface
 func asString(val any) string {
 	switch v := val.(type) {
@@ -150,6 +194,14 @@ func asString(val any) string {
 	}
 }
 
+// nullIfEmpty stores empty strings as NULL, for optional columns where "unknown" differs from "empty".
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
 // Scan a single row
 func (r *SQLiteRepository) scanEntryRow(rows *sql.Rows, customFields []repo.CustomFieldDef) (repo.Entry, error) {
 	scanner, err := newEntryScanner(rows, customFields)
@@ -190,11 +242,7 @@ func (r *SQLiteRepository) scanEntryRows(rows *sql.Rows, customFields []repo.Cus
 // validateAndFormatSearchField prevents SQL injection by ensuring a field name exists.
 func (r *SQLiteRepository) validateAndFormatSearchField(field string, customFields []repo.CustomFieldDef) (string, error) {
 	// 1. Whitelist Standard Fields
-	standardFields := map[string]bool{
-		"id": true, "timestamp": true, "created_at": true, "updated_at": true,
-		"filesize": true, "preview_filesize": true, "filename": true, "status": true, "mime_type": true,
-	}
-	if standardFields[field] {
+	if _, ok := repo.StandardFieldTypes[field]; ok {
 		return fmt.Sprintf(`"%s"`, field), nil
 	}
 
@@ -217,10 +265,126 @@ func (r *SQLiteRepository) validateAndFormatSearchField(field string, customFiel
 	return "", fmt.Errorf("field '%s' is not allowed or does not exist", field)
 }
 
+// searchFieldType returns the SQL type of a field accepted by validateAndFormatSearchField.
+func (r *SQLiteRepository) searchFieldType(field string, customFields []repo.CustomFieldDef) string {
+	if fieldType, ok := repo.StandardFieldTypes[field]; ok {
+		return fieldType
+	}
+	for _, fields := range r.MediaFields {
+		for _, mediaField := range fields {
+			if mediaField.Name == field {
+				return repo.MediaFieldType(mediaField.SQLiteType)
+			}
+		}
+	}
+	for _, cf := range customFields {
+		if cf.Name == field {
+			return cf.Type
+		}
+	}
+	return ""
+}
+
+// isFulltextField reports whether field is a custom field kept in the full-text index.
+func isFulltextField(field string, customFields []repo.CustomFieldDef) bool {
+	for _, cf := range customFields {
+		if cf.Name == field {
+			return cf.IsFulltext
+		}
+	}
+	return false
+}
+
+// selectColumns returns the columns to select for a field projection, or "*" if no fields are given.
+// Fields are validated with the same whitelist as filters, the id is always selected.
+func (r *SQLiteRepository) selectColumns(fields []string, customFields []repo.CustomFieldDef) ([]string, error) {
+	if len(fields) == 0 {
+		return []string{"*"}, nil
+	}
+
+	columns := []string{`"id"`}
+	for _, field := range fields {
+		column, err := r.validateAndFormatSearchField(field, customFields)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+		}
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+// isExternalIDConflict reports whether err is a violation of the unique external ID index.
+func isExternalIDConflict(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed") && strings.Contains(err.Error(), ".external_id")
+}
+
 // isValidOperator checks if the requested SQL operator is whitelisted.
 func isValidOperator(op string) bool {
-	valid := map[string]bool{
-		"=": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true, "LIKE": true,
+	return slices.ContainsFunc(repo.SearchOperators, func(allowed string) bool { return strings.EqualFold(allowed, op) })
+}
+
+// likeEscaper escapes the LIKE wildcards and the escape character, for patterns with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// isTextMatchOperator reports whether op is one of the text match operators, see repo.OperatorStartsWith.
+func isTextMatchOperator(op string) bool {
+	switch strings.ToLower(op) {
+	case repo.OperatorStartsWith, repo.OperatorEndsWith, repo.OperatorContains, repo.OperatorContainsCI, repo.OperatorEqualsCI:
+		return true
+	default:
+		return false
+	}
+}
+
+// textMatchCondition builds the condition of a text match operator on the column. The value is escaped, so
+// it matches literally.
+func textMatchCondition(column, op string, value any) (squirrel.Sqlizer, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: operator '%s' needs a text value", customerrors.ErrValidation, op)
+	}
+	pattern := likeEscaper.Replace(s)
+
+	switch strings.ToLower(op) {
+	case repo.OperatorStartsWith:
+		like := squirrel.Expr(column+` LIKE ? ESCAPE '\'`, pattern+"%")
+		lower, upper, ok := likePrefixRange(s)
+		if !ok {
+			return like, nil
+		}
+		// The range lets SQLite search an index of the column, the LIKE picks the matches from it
+		return squirrel.And{squirrel.Expr(column+" >= ?", lower), squirrel.Expr(column+" < ?", upper), like}, nil
+	case repo.OperatorEndsWith:
+		return squirrel.Expr(column+` LIKE ? ESCAPE '\'`, "%"+pattern), nil
+	case repo.OperatorContains:
+		return squirrel.Expr(column+` LIKE ? ESCAPE '\'`, "%"+pattern+"%"), nil
+	case repo.OperatorContainsCI:
+		return squirrel.Expr("LOWER("+column+`) LIKE LOWER(?) ESCAPE '\'`, "%"+pattern+"%"), nil
+	case repo.OperatorEqualsCI:
+		return squirrel.Expr(column+" = ? COLLATE NOCASE", s), nil
+	default:
+		return nil, fmt.Errorf("%w: invalid text match operator '%s'", customerrors.ErrValidation, op)
+	}
+}
+
+// likePrefixRange returns the range of texts that can start with the prefix as LIKE compares it, false for an
+// empty prefix. LIKE ignores the case of ASCII letters, which sort upper case first: the range starts at the
+// prefix in upper case and ends before the prefix in lower case with its last byte incremented. UTF-8 never
+// uses the byte 0xFF, so the increment does not overflow.
+func likePrefixRange(prefix string) (string, string, bool) {
+	if prefix == "" {
+		return "", "", false
+	}
+	lower, upper := []byte(prefix), []byte(prefix)
+	for i, c := range lower {
+		if 'a' <= c && c <= 'z' {
+			lower[i] = c - 'a' + 'A'
+		} else if 'A' <= c && c <= 'Z' {
+			upper[i] = c - 'A' + 'a'
+		}
 	}
-	return valid[strings.ToUpper(op)]
+	upper[len(upper)-1]++
+	return string(lower), string(upper), true
 }
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// GetEntryVersions returns the sizes, mime type, status and updated_at of the existing entries of the
+// given IDs, ordered by ID. Only these columns are selected, so a few thousand entries are one cheap query.
+func (r *SQLiteRepository) GetEntryVersions(ctx context.Context, dbID repo.ULID, entryIDs []int64) ([]repo.EntryVersion, error) {
+	versions := make([]repo.EntryVersion, 0, len(entryIDs))
+	if len(entryIDs) == 0 {
+		return versions, nil
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query, args, err := r.Builder.Select("id", "filesize", "preview_filesize", "mime_type", "status", "updated_at").
+		From(tableName).
+		Where(squirrel.Eq{"id": entryIDs}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build entry versions query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry versions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v repo.EntryVersion
+		var updatedAt int64
+		if err := rows.Scan(&v.ID, &v.Size, &v.PreviewSize, &v.MimeType, &v.Status, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan entry version: %w", err)
+		}
+		v.UpdatedAt = time.UnixMilli(updatedAt)
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read entry versions: %w", err)
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,21 @@
+package sqlite
+
+import (
+	"context"
+
+	repo "mediahub_oss/internal/repository"
+)
+
+// SearchQuery exposes the query built by SearchEntries, e.g. to check its plan.
+func (r *SQLiteRepository) SearchQuery(dbID repo.ULID, req repo.SearchRequest, customFields []repo.CustomFieldDef) (string, []any, error) {
+	builder, _, err := r.buildSearchQuery(dbID, req, customFields)
+	if err != nil {
+		return "", nil, err
+	}
+	return builder.ToSql()
+}
+
+// GetDatabaseUncoalesced is GetDatabase without sharing the query with concurrent lookups.
+func (r *SQLiteRepository) GetDatabaseUncoalesced(ctx context.Context, dbID repo.ULID) (repo.Database, error) {
+	return r.getDatabase(ctx, dbID)
+}
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// GetEntryFacets counts the values of the requested fields among the entries matching the filter: a single
+// query counts the matching entries and the values of every field, then one GROUP BY per field returns its
+// most frequent values. The null and other counts follow from the first query. All queries run in one
+// transaction, so the counts agree even while entries are uploaded.
+func (r *SQLiteRepository) GetEntryFacets(ctx context.Context, dbID repo.ULID, req repo.FacetRequest, customFields []repo.CustomFieldDef) (repo.Facets, error) {
+	if err := req.Validate(); err != nil {
+		return repo.Facets{}, err
+	}
+
+	// 1. Only fields of the whitelist with few distinct values can be counted
+	columns := make([]string, len(req.Fields))
+	fieldTypes := make([]string, len(req.Fields))
+	for i, field := range req.Fields {
+		column, err := r.validateAndFormatSearchField(field, customFields)
+		if err != nil {
+			return repo.Facets{}, fmt.Errorf("%w: %v", customerrors.ErrValidation, err)
+		}
+		fieldType := r.searchFieldType(field, customFields)
+		if fieldType == "REAL" {
+			return repo.Facets{}, fmt.Errorf("%w: field '%s' is a REAL field, its values are nearly all distinct and cannot be counted", customerrors.ErrValidation, field)
+		}
+		if !slices.Contains(repo.FacetFieldTypes, fieldType) {
+			return repo.Facets{}, fmt.Errorf("%w: values of the %s field '%s' cannot be counted", customerrors.ErrValidation, fieldType, field)
+		}
+		columns[i], fieldTypes[i] = column, fieldType
+	}
+
+	where, matchColumn, _, err := r.buildWhereExpr(dbID, req.Filter, customFields, time.Now())
+	if err != nil {
+		return repo.Facets{}, err
+	}
+	table := fmt.Sprintf(`"entries_%s"`, dbID.String())
+
+	facets := repo.Facets{Fields: make([]repo.Facet, len(req.Fields))}
+	err = r.WithTx(ctx, func(tx *sql.Tx) error {
+		// 2. The matching entries and the entries with a value of each field
+		counts := r.Builder.Select("COUNT(*)").From(table)
+		for _, column := range columns {
+			counts = counts.Column("COUNT(" + column + ")")
+		}
+		if where != nil {
+			counts = counts.Where(where)
+		}
+		query, args, err := counts.ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build facet count query: %w", err)
+		}
+		withValue := make([]int64, len(columns))
+		dest := []any{&facets.Total}
+		for i := range withValue {
+			dest = append(dest, &withValue[i])
+		}
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+			return fmt.Errorf("failed to count facet values: %w", err)
+		}
+
+		// 3. The most frequent values of each field, ties in the order of the values
+		for i, column := range columns {
+			builder := r.Builder.Select(column, "COUNT(*) AS facet_count").
+				From(table).
+				Where(column + " IS NOT NULL")
+			if where != nil {
+				builder = builder.Where(where)
+			}
+			builder = builder.GroupBy(column).OrderBy("facet_count DESC", column).Limit(uint64(req.Limit))
+
+			values, err := queryFacetValues(ctx, tx, builder, fieldTypes[i])
+			if err != nil {
+				return err
+			}
+			facet := repo.Facet{Field: req.Fields[i], Values: values, NullCount: facets.Total - withValue[i], OtherCount: withValue[i]}
+			for _, v := range values {
+				facet.OtherCount -= v.Count
+			}
+			facets.Fields[i] = facet
+		}
+		return nil
+	})
+	if err != nil {
+		if matchColumn != "" && isFulltextQueryError(err) {
+			return repo.Facets{}, fmt.Errorf("%w: invalid full-text query: %v", customerrors.ErrValidation, err)
+		}
+		return repo.Facets{}, err
+	}
+	return facets, nil
+}
+
+// queryFacetValues runs the GROUP BY of a facet and converts the values to the type of the field.
+func queryFacetValues(ctx context.Context, tx *sql.Tx, builder squirrel.SelectBuilder, fieldType string) ([]repo.FacetValue, error) {
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build facet query: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query facet values: %w", err)
+	}
+	defer rows.Close()
+
+	values := []repo.FacetValue{}
+	for rows.Next() {
+		var value any
+		var count int64
+		if err := rows.Scan(&value, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan facet value: %w", err)
+		}
+		switch fieldType {
+		case "BOOLEAN":
+			value = asBool(value)
+		case "INTEGER":
+			value = asInt64(value)
+		default:
+			value = asString(value)
+		}
+		values = append(values, repo.FacetValue{Value: value, Count: count})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read facet values: %w", err)
+	}
+	return values, nil
+}
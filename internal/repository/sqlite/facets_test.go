@@ -0,0 +1,136 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestEntryFacets(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:        "sensors",
+		ContentType: "file",
+		CustomFields: []repo.CustomFieldDef{
+			{Name: "sensor_id", Type: "TEXT"},
+			{Name: "lane", Type: "INTEGER"},
+			{Name: "is_vehicle", Type: "BOOLEAN"},
+			{Name: "score", Type: "REAL"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// cam01 x4, cam02 x3, cam03 x2, cam04 x1 and 2 entries without sensor
+	seed := []struct {
+		sensor  any
+		lane    any
+		vehicle any
+		status  repo.EntryStatus
+	}{
+		{"cam01", int64(1), true, repo.EntryStatusReady},
+		{"cam01", int64(1), true, repo.EntryStatusReady},
+		{"cam01", int64(2), false, repo.EntryStatusReady},
+		{"cam01", nil, nil, repo.EntryStatusError},
+		{"cam02", int64(1), true, repo.EntryStatusReady},
+		{"cam02", int64(2), true, repo.EntryStatusReady},
+		{"cam02", int64(2), nil, repo.EntryStatusProcessing},
+		{"cam03", int64(3), false, repo.EntryStatusReady},
+		{"cam03", nil, false, repo.EntryStatusReady},
+		{"cam04", int64(3), true, repo.EntryStatusReady},
+		{nil, int64(1), nil, repo.EntryStatusReady},
+		{nil, nil, nil, repo.EntryStatusError},
+	}
+	for _, s := range seed {
+		fields := map[string]any{}
+		for name, value := range map[string]any{"sensor_id": s.sensor, "lane": s.lane, "is_vehicle": s.vehicle} {
+			if value != nil {
+				fields[name] = value
+			}
+		}
+		if _, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "frame.jpg", Timestamp: time.Now(), MimeType: "image/jpeg",
+			Status: s.status, CustomFields: fields}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+	}
+
+	// 1. Top values with counts, the rest as other_count and the entries without value as null_count
+	facets, err := r.GetEntryFacets(ctx, db.ID, repo.FacetRequest{Fields: []string{"sensor_id", "lane", "is_vehicle", "status"}, Limit: 2}, db.CustomFields)
+	if err != nil {
+		t.Fatalf("failed to get facets: %v", err)
+	}
+	if facets.Total != int64(len(seed)) {
+		t.Errorf("expected %d matching entries, got %d", len(seed), facets.Total)
+	}
+	want := []repo.Facet{
+		{Field: "sensor_id", Values: []repo.FacetValue{{Value: "cam01", Count: 4}, {Value: "cam02", Count: 3}}, OtherCount: 3, NullCount: 2},
+		{Field: "lane", Values: []repo.FacetValue{{Value: int64(1), Count: 4}, {Value: int64(2), Count: 3}}, OtherCount: 2, NullCount: 3},
+		{Field: "is_vehicle", Values: []repo.FacetValue{{Value: true, Count: 5}, {Value: false, Count: 3}}, OtherCount: 0, NullCount: 4},
+		{Field: "status", Values: []repo.FacetValue{{Value: int64(repo.EntryStatusReady), Count: 9}, {Value: int64(repo.EntryStatusError), Count: 2}}, OtherCount: 1, NullCount: 0},
+	}
+	if !reflect.DeepEqual(facets.Fields, want) {
+		t.Errorf("expected facets\n%+v\ngot\n%+v", want, facets.Fields)
+	}
+
+	// 2. The filter scopes the counts, ties are ordered by value
+	filter := &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "is_vehicle", Operator: "=", Value: true}}}
+	facets, err = r.GetEntryFacets(ctx, db.ID, repo.FacetRequest{Fields: []string{"sensor_id"}, Filter: filter}, db.CustomFields)
+	if err != nil {
+		t.Fatalf("failed to get filtered facets: %v", err)
+	}
+	wantScoped := repo.Facet{Field: "sensor_id", Values: []repo.FacetValue{{Value: "cam01", Count: 2}, {Value: "cam02", Count: 2}, {Value: "cam04", Count: 1}}}
+	if facets.Total != 5 || !reflect.DeepEqual(facets.Fields, []repo.Facet{wantScoped}) {
+		t.Errorf("expected 5 entries with %+v, got %d with %+v", wantScoped, facets.Total, facets.Fields)
+	}
+
+	// 3. An empty result counts nothing
+	empty := &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "sensor_id", Operator: "=", Value: "cam99"}}}
+	facets, err = r.GetEntryFacets(ctx, db.ID, repo.FacetRequest{Fields: []string{"sensor_id"}, Filter: empty}, db.CustomFields)
+	if err != nil {
+		t.Fatalf("failed to get empty facets: %v", err)
+	}
+	if facets.Total != 0 || len(facets.Fields[0].Values) != 0 || facets.Fields[0].OtherCount != 0 || facets.Fields[0].NullCount != 0 {
+		t.Errorf("expected no counts, got %+v", facets)
+	}
+
+	// 4. REAL and unknown fields, duplicates, no fields and invalid limits are refused
+	invalid := []repo.FacetRequest{
+		{Fields: []string{"score"}},
+		{Fields: []string{"unknown"}},
+		{Fields: []string{"lane", "lane"}},
+		{},
+		{Fields: []string{"lane"}, Limit: repo.MaxFacetLimit + 1},
+		{Fields: []string{"lane"}, Limit: -1},
+	}
+	for _, req := range invalid {
+		if _, err := r.GetEntryFacets(ctx, db.ID, req, db.CustomFields); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("%+v: expected a validation error, got %v", req, err)
+		}
+	}
+}
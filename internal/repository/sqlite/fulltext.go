@@ -0,0 +1,277 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// The full-text index of a database is the FTS5 table "entries_<id>_fts" with one column per full-text
+// custom field, named like the column in the entries table. Its rowid is the entry ID. Triggers on the
+// entries table keep it in sync, entries that existed before a field was added to it are copied by the
+// fulltext_backfill task, see BackfillFulltext.
+
+// fulltextTableName returns the quoted name of the FTS5 table of a database.
+func fulltextTableName(dbID string) string {
+	return fmt.Sprintf(`"entries_%s_fts"`, dbID)
+}
+
+// fulltextFieldIDs returns the IDs of the full-text custom fields of a database, read through q so
+// uncommitted flag changes of a transaction are seen.
+func (r *SQLiteRepository) fulltextFieldIDs(ctx context.Context, q Queryer, dbID string) ([]int, error) {
+	query, args, err := r.Builder.Select("field_id").
+		From("database_custom_fields").
+		Where(squirrel.Eq{"database_id": dbID, "is_fulltext": true}).
+		OrderBy("field_id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build full-text fields query: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query full-text fields: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan full-text field: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// dropFulltextIndex removes the FTS5 table of a database and its triggers. The triggers have to go
+// before a custom field column they reference can be dropped.
+func dropFulltextIndex(ctx context.Context, tx *sql.Tx, dbID string) error {
+	for _, trigger := range []string{"ai", "ad", "au"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TRIGGER IF EXISTS "entries_%s_fts_%s"`, dbID, trigger)); err != nil {
+			return fmt.Errorf("failed to drop full-text trigger: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s`, fulltextTableName(dbID))); err != nil {
+		return fmt.Errorf("failed to drop full-text table: %w", err)
+	}
+	return nil
+}
+
+// createFulltextIndex creates the empty FTS5 table of the given custom fields and the triggers that
+// copy inserted, updated and deleted entries. Nothing is created without fields.
+func createFulltextIndex(ctx context.Context, tx *sql.Tx, dbID string, fieldIDs []int) error {
+	if len(fieldIDs) == 0 {
+		return nil
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID)
+	ftsName := fulltextTableName(dbID)
+	columns := make([]string, len(fieldIDs))
+	newValues := make([]string, len(fieldIDs))
+	for i, id := range fieldIDs {
+		columns[i] = fmt.Sprintf(`"%s%d"`, customFieldsPrefix, id)
+		newValues[i] = "new." + columns[i]
+	}
+	columnList := strings.Join(columns, ", ")
+	insertNew := fmt.Sprintf(`INSERT INTO %s(rowid, %s) VALUES (new.id, %s);`, ftsName, columnList, strings.Join(newValues, ", "))
+	deleteOld := fmt.Sprintf(`DELETE FROM %s WHERE rowid = old.id;`, ftsName)
+
+	statements := []string{
+		fmt.Sprintf(`CREATE VIRTUAL TABLE %s USING fts5(%s)`, ftsName, columnList),
+		fmt.Sprintf(`CREATE TRIGGER "entries_%s_fts_ai" AFTER INSERT ON %s BEGIN %s END`, dbID, tableName, insertNew),
+		fmt.Sprintf(`CREATE TRIGGER "entries_%s_fts_ad" AFTER DELETE ON %s BEGIN %s END`, dbID, tableName, deleteOld),
+		fmt.Sprintf(`CREATE TRIGGER "entries_%s_fts_au" AFTER UPDATE OF %s ON %s BEGIN %s %s END`, dbID, columnList, tableName, deleteOld, insertNew),
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create full-text index: %w", err)
+		}
+	}
+	return nil
+}
+
+// rebuildFulltextIndex recreates the full-text index of a database after its full-text fields changed.
+// The new index starts empty, so if it has fields, the existing entries are queued for the backfill.
+func (r *SQLiteRepository) rebuildFulltextIndex(ctx context.Context, tx *sql.Tx, dbID string) error {
+	fieldIDs, err := r.fulltextFieldIDs(ctx, tx, dbID)
+	if err != nil {
+		return err
+	}
+	if err := dropFulltextIndex(ctx, tx, dbID); err != nil {
+		return err
+	}
+	if err := createFulltextIndex(ctx, tx, dbID, fieldIDs); err != nil {
+		return err
+	}
+
+	var cursor any // NULL, nothing to backfill
+	if len(fieldIDs) > 0 {
+		cursor = 0
+	}
+	query, args, err := r.Builder.Update("databases").
+		Set("fulltext_backfill_cursor", cursor).
+		Where(squirrel.Eq{"id": dbID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build backfill cursor query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to reset backfill cursor: %w", err)
+	}
+
+	if len(fieldIDs) > 0 {
+		return r.insertFulltextBackfillTask(ctx, tx, dbID)
+	}
+	return nil
+}
+
+// updateFulltextFlags stores the IsFulltext flags of the custom fields of db that differ from the stored
+// ones and reports whether any changed. Fields that do not exist are ignored.
+func (r *SQLiteRepository) updateFulltextFlags(ctx context.Context, tx *sql.Tx, db repo.Database) (bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT field_id, type, is_fulltext FROM database_custom_fields WHERE database_id = ?", db.ID.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to query custom fields: %w", err)
+	}
+	stored := make(map[int]repo.CustomFieldDef)
+	for rows.Next() {
+		var cf repo.CustomFieldDef
+		if err := rows.Scan(&cf.ID, &cf.Type, &cf.IsFulltext); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		stored[cf.ID] = cf
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("custom fields row iteration error: %w", err)
+	}
+
+	changed := false
+	for _, cf := range db.CustomFields {
+		current, ok := stored[cf.ID]
+		if !ok || current.IsFulltext == cf.IsFulltext {
+			continue
+		}
+		if cf.IsFulltext && !strings.EqualFold(current.Type, "TEXT") {
+			return false, fmt.Errorf("%w: custom field '%s' of type %s cannot be full-text indexed, only TEXT fields can", customerrors.ErrValidation, cf.Name, current.Type)
+		}
+		query, args, err := r.Builder.Update("database_custom_fields").
+			Set("is_fulltext", cf.IsFulltext).
+			Where(squirrel.Eq{"database_id": db.ID.String(), "field_id": cf.ID}).
+			ToSql()
+		if err != nil {
+			return false, fmt.Errorf("failed to build full-text flag query: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return false, fmt.Errorf("failed to update full-text flag: %w", err)
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// insertFulltextBackfillTask creates a due fulltext_backfill task for a database.
+func (r *SQLiteRepository) insertFulltextBackfillTask(ctx context.Context, q Queryer, dbID string) error {
+	now := time.Now().UnixMilli()
+	query, args, err := r.Builder.Insert("pending_tasks").
+		Columns("database_id", "entry_id", "task_type", "next_attempt_at", "created_at").
+		Values(dbID, 0, repo.TaskTypeFulltextBackfill, now, now).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert pending_task query: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert pending_task: %w", err)
+	}
+	return nil
+}
+
+// ScheduleFulltextBackfill creates a task that continues the backfill of the full-text index of a database.
+func (r *SQLiteRepository) ScheduleFulltextBackfill(ctx context.Context, dbID repo.ULID) error {
+	return r.insertFulltextBackfillTask(ctx, r.DB, dbID.String())
+}
+
+// BackfillFulltext copies the next batchSize entries that existed before the full-text index was built
+// into it. It returns true once all entries are indexed, and ErrNotFound if the database is gone.
+// Entries written meanwhile are indexed by the triggers and skipped.
+func (r *SQLiteRepository) BackfillFulltext(ctx context.Context, dbID repo.ULID, batchSize int) (bool, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var cursor sql.NullInt64
+	err = tx.QueryRowContext(ctx, "SELECT fulltext_backfill_cursor FROM databases WHERE id = ?", dbID.String()).Scan(&cursor)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, customerrors.ErrNotFound
+		}
+		return false, fmt.Errorf("failed to query backfill cursor: %w", err)
+	}
+	if !cursor.Valid {
+		return true, nil
+	}
+
+	fieldIDs, err := r.fulltextFieldIDs(ctx, tx, dbID.String())
+	if err != nil {
+		return false, err
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	var batchEnd sql.NullInt64
+	batchQuery := fmt.Sprintf(`SELECT MAX(id) FROM (SELECT id FROM %s WHERE id > ? ORDER BY id LIMIT ?)`, tableName)
+	if err := tx.QueryRowContext(ctx, batchQuery, cursor.Int64, batchSize).Scan(&batchEnd); err != nil {
+		return false, fmt.Errorf("failed to query backfill batch: %w", err)
+	}
+
+	done := !batchEnd.Valid || len(fieldIDs) == 0
+	if !done {
+		columns := make([]string, len(fieldIDs))
+		for i, id := range fieldIDs {
+			columns[i] = fmt.Sprintf(`"%s%d"`, customFieldsPrefix, id)
+		}
+		columnList := strings.Join(columns, ", ")
+		ftsName := fulltextTableName(dbID.String())
+		insert := fmt.Sprintf(`INSERT INTO %s(rowid, %s) SELECT id, %s FROM %s AS e WHERE id > ? AND id <= ? AND NOT EXISTS (SELECT 1 FROM %s WHERE rowid = e.id)`,
+			ftsName, columnList, columnList, tableName, ftsName)
+		if _, err := tx.ExecContext(ctx, insert, cursor.Int64, batchEnd.Int64); err != nil {
+			return false, fmt.Errorf("failed to backfill full-text index: %w", err)
+		}
+	}
+
+	var next any = batchEnd.Int64
+	if done {
+		next = nil
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE databases SET fulltext_backfill_cursor = ? WHERE id = ?", next, dbID.String()); err != nil {
+		return false, fmt.Errorf("failed to update backfill cursor: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return done, nil
+}
+
+// fulltextCondition translates a MATCH condition on a full-text custom field into a rowid lookup in the
+// FTS5 table, so the query uses the full-text index instead of scanning the entries.
+func fulltextCondition(dbID string, column string, value any) squirrel.Sqlizer {
+	return squirrel.Expr(fmt.Sprintf(`"id" IN (SELECT rowid FROM %s WHERE %s MATCH ?)`, fulltextTableName(dbID), column), value)
+}
+
+// isFulltextQueryError reports whether err is caused by the syntax of a MATCH query.
+func isFulltextQueryError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "fts5:") || strings.Contains(msg, "unterminated string") || strings.Contains(msg, "no such column")
+}
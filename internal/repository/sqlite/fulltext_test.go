@@ -0,0 +1,213 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestFulltextSearch(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "fts_test", ContentType: "file", CustomFields: []repo.CustomFieldDef{
+		{Name: "description", Type: "TEXT"},
+		{Name: "notes", Type: "TEXT"},
+		{Name: "rating", Type: "INTEGER"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// Entries from before the full-text index, they have to be backfilled
+	words := []string{"harbor", "glacier", "meadow", "canyon", "volcano"}
+	for i := range 40 {
+		description := fmt.Sprintf("%s near the %s", words[i%len(words)], words[(i*3+1)%len(words)])
+		if _, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream",
+			CustomFields: map[string]any{"description": description, "notes": "harbor", "rating": i}}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+	}
+
+	search := func(conds []repo.Condition, sort *repo.SortCriteria) ([]repo.Entry, error) {
+		db, err := r.GetDatabase(ctx, db.ID)
+		if err != nil {
+			t.Fatalf("failed to get database: %v", err)
+		}
+		return r.SearchEntries(ctx, db.ID, repo.SearchRequest{
+			Filter:     &repo.FilterGroup{Operator: "and", Conditions: conds},
+			Sort:       sort,
+			Pagination: repo.Pagination{Limit: 1000},
+		}, db.CustomFields)
+	}
+	ids := func(entries []repo.Entry) []int64 {
+		var ids []int64
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	// 1. MATCH is rejected until the field is a full-text field
+	if _, err := search([]repo.Condition{{Field: "description", Operator: "MATCH", Value: "harbor"}}, nil); !errors.Is(err, customerrors.ErrValidation) {
+		t.Fatalf("expected a validation error for MATCH on a field without full-text index, got %v", err)
+	}
+
+	// 2. Enabling the field builds the index and queues the backfill
+	db.CustomFields = slices.Clone(db.CustomFields)
+	db.CustomFields[0].IsFulltext = true
+	if db, err = r.UpdateDatabase(ctx, db); err != nil {
+		t.Fatalf("failed to enable full-text search: %v", err)
+	}
+	if !db.CustomFields[0].IsFulltext {
+		t.Fatal("expected description to be a full-text field")
+	}
+	tasks, err := r.GetDuePendingTasks(ctx, 10)
+	if err != nil || len(tasks) != 1 || tasks[0].Type != repo.TaskTypeFulltextBackfill || tasks[0].DatabaseID != db.ID {
+		t.Fatalf("expected one backfill task, got %+v (err %v)", tasks, err)
+	}
+
+	// Entries written meanwhile are indexed by the triggers
+	added, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "b.bin", Timestamp: time.Now(), MimeType: "application/octet-stream",
+		CustomFields: map[string]any{"description": "glacier glacier glacier"}})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if found, err := search([]repo.Condition{{Field: "description", Operator: "MATCH", Value: "glacier"}}, nil); err != nil || !slices.Equal(ids(found), []int64{added.ID}) {
+		t.Fatalf("expected only the new entry before the backfill, got %v (err %v)", ids(found), err)
+	}
+
+	batches := 0
+	for done := false; !done; batches++ {
+		if done, err = r.BackfillFulltext(ctx, db.ID, 7); err != nil {
+			t.Fatalf("failed to backfill: %v", err)
+		}
+	}
+	if batches < 6 {
+		t.Errorf("expected the backfill to take several batches, took %d", batches)
+	}
+
+	// 3. Updates and deletes are synced
+	if _, err := r.UpdateEntryUserFields(ctx, db.ID, 1, map[string]any{"description": "volcano"}); err != nil {
+		t.Fatalf("failed to update entry: %v", err)
+	}
+	if _, err := r.DeleteEntry(ctx, db.ID, 2); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+
+	// 4. MATCH finds the same entries as LIKE
+	for _, word := range words {
+		matched, err := search([]repo.Condition{{Field: "description", Operator: "MATCH", Value: word}}, nil)
+		if err != nil {
+			t.Fatalf("failed to search with MATCH: %v", err)
+		}
+		liked, err := search([]repo.Condition{{Field: "description", Operator: "LIKE", Value: "%" + word + "%"}}, nil)
+		if err != nil {
+			t.Fatalf("failed to search with LIKE: %v", err)
+		}
+		if len(matched) == 0 || !slices.Equal(ids(matched), ids(liked)) {
+			t.Errorf("%s: MATCH found %v, LIKE found %v", word, ids(matched), ids(liked))
+		}
+	}
+
+	// Combined with other conditions
+	matched, err := search([]repo.Condition{{Field: "description", Operator: "MATCH", Value: "meadow"}, {Field: "rating", Operator: "<", Value: 20}}, nil)
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	liked, _ := search([]repo.Condition{{Field: "description", Operator: "LIKE", Value: "%meadow%"}, {Field: "rating", Operator: "<", Value: 20}}, nil)
+	if len(matched) == 0 || !slices.Equal(ids(matched), ids(liked)) {
+		t.Errorf("combined: MATCH found %v, LIKE found %v", ids(matched), ids(liked))
+	}
+
+	// 5. Sorting by relevance puts the entry repeating the word first
+	ranked, err := search([]repo.Condition{{Field: "description", Operator: "MATCH", Value: "glacier"}}, &repo.SortCriteria{Field: repo.SortFieldFulltextRank, Direction: "desc"})
+	if err != nil {
+		t.Fatalf("failed to sort by fts_rank: %v", err)
+	}
+	if len(ranked) < 2 || ranked[0].ID != added.ID || ranked[0].CustomFields["description"] != "glacier glacier glacier" {
+		t.Errorf("expected the entry repeating the word first, got %+v", ranked)
+	}
+
+	// 6. The MATCH condition is answered by the full-text index, not by scanning the entries
+	query, args, err := r.SearchQuery(db.ID, repo.SearchRequest{
+		Filter:     &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "description", Operator: "MATCH", Value: "harbor"}}},
+		Sort:       &repo.SortCriteria{Field: "id"},
+		Pagination: repo.Pagination{Limit: 10},
+	}, db.CustomFields)
+	if err != nil {
+		t.Fatalf("failed to build search query: %v", err)
+	}
+	rows, err := r.DB.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		t.Fatalf("failed to explain query: %v", err)
+	}
+	var plan []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			t.Fatalf("failed to scan query plan: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	rows.Close()
+	joined := strings.Join(plan, "\n")
+	if !strings.Contains(joined, "VIRTUAL TABLE INDEX") || !strings.Contains(joined, "USING INTEGER PRIMARY KEY") {
+		t.Errorf("expected a lookup through the full-text index, got plan:\n%s", joined)
+	}
+
+	// 7. Validation
+	invalid := []struct {
+		name  string
+		conds []repo.Condition
+		sort  *repo.SortCriteria
+	}{
+		{"not a full-text field", []repo.Condition{{Field: "notes", Operator: "MATCH", Value: "harbor"}}, nil},
+		{"not a text field", []repo.Condition{{Field: "rating", Operator: "MATCH", Value: "1"}}, nil},
+		{"standard field", []repo.Condition{{Field: "filename", Operator: "MATCH", Value: "a"}}, nil},
+		{"empty query", []repo.Condition{{Field: "description", Operator: "MATCH", Value: " "}}, nil},
+		{"syntax error", []repo.Condition{{Field: "description", Operator: "MATCH", Value: `"harbor`}}, nil},
+		{"rank without MATCH", []repo.Condition{{Field: "description", Operator: "LIKE", Value: "%harbor%"}}, &repo.SortCriteria{Field: repo.SortFieldFulltextRank}},
+	}
+	for _, tc := range invalid {
+		if _, err := search(tc.conds, tc.sort); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("%s: expected a validation error, got %v", tc.name, err)
+		}
+	}
+
+	// 8. Deleting the full-text field removes the index
+	if err := r.DeleteCustomField(ctx, db.ID, db.CustomFields[0].ID); err != nil {
+		t.Fatalf("failed to delete the full-text field: %v", err)
+	}
+	var tables int
+	if err := r.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name LIKE 'entries_%_fts%'").Scan(&tables); err != nil || tables != 0 {
+		t.Errorf("expected the full-text table and triggers to be gone, found %d (err %v)", tables, err)
+	}
+}
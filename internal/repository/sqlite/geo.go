@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// setGeoIndex replaces the composite index of the geo fields, which serves the range conditions of geo
+// searches. It is dropped if the database declares no geo fields.
+func setGeoIndex(ctx context.Context, tx *sql.Tx, dbID string, geo repo.GeoFields, customFields []repo.CustomFieldDef) error {
+	if err := repo.ValidateGeoFields(geo, customFields); err != nil {
+		return err
+	}
+	indexName := fmt.Sprintf(`"idx_entries_%s_geo"`, dbID)
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP INDEX IF EXISTS %s;`, indexName)); err != nil {
+		return fmt.Errorf("failed to drop geo index: %w", err)
+	}
+	if !geo.Enabled() {
+		return nil
+	}
+
+	lat, lon := geoColumns(geo, customFields)
+	createSQL := fmt.Sprintf(`CREATE INDEX %s ON "entries_%s"(%s, %s);`, indexName, dbID, lat, lon)
+	if _, err := tx.ExecContext(ctx, createSQL); err != nil {
+		return fmt.Errorf("failed to create geo index: %w", err)
+	}
+	return nil
+}
+
+// geoColumns returns the quoted entry table columns of the geo fields, which must be valid custom fields.
+func geoColumns(geo repo.GeoFields, customFields []repo.CustomFieldDef) (lat string, lon string) {
+	for _, cf := range customFields {
+		switch cf.Name {
+		case geo.Lat:
+			lat = fmt.Sprintf(`"%s%d"`, customFieldsPrefix, cf.ID)
+		case geo.Lon:
+			lon = fmt.Sprintf(`"%s%d"`, customFieldsPrefix, cf.ID)
+		}
+	}
+	return lat, lon
+}
+
+// getGeoFields returns the geo fields declared by a database.
+func getGeoFields(ctx context.Context, q Queryer, dbID string) (repo.GeoFields, error) {
+	var s string
+	if err := q.QueryRowContext(ctx, "SELECT geo_fields FROM databases WHERE id = ?", dbID).Scan(&s); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.GeoFields{}, customerrors.ErrNotFound
+		}
+		return repo.GeoFields{}, fmt.Errorf("failed to query geo fields: %w", err)
+	}
+	return decodeGeoFields(s)
+}
+
+// renameGeoField follows the rename of a custom field in the geo fields of the database. The index
+// refers to the column, which keeps its name.
+func renameGeoField(ctx context.Context, tx *sql.Tx, dbID string, oldName, newName string) error {
+	geo, err := getGeoFields(ctx, tx, dbID)
+	if err != nil {
+		return err
+	}
+	switch oldName {
+	case geo.Lat:
+		geo.Lat = newName
+	case geo.Lon:
+		geo.Lon = newName
+	default:
+		return nil
+	}
+	encoded, err := encodeGeoFields(geo)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE databases SET geo_fields = ? WHERE id = ?", encoded, dbID); err != nil {
+		return fmt.Errorf("failed to rename geo field: %w", err)
+	}
+	return nil
+}
+
+// geoCondition validates a geo filter and returns its condition. For a radius it also returns the distance
+// to its center in meters, the expression of the SortFieldGeoDistance sort field; nil for a bounding box.
+func geoCondition(geo *repo.GeoFilter, customFields []repo.CustomFieldDef) (squirrel.Sqlizer, squirrel.Sqlizer, error) {
+	if err := geo.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if !geo.Fields.Enabled() {
+		return nil, nil, fmt.Errorf("%w: geo searches need the geo fields of the database (config.geo_fields)", customerrors.ErrValidation)
+	}
+	if err := repo.ValidateGeoFields(geo.Fields, customFields); err != nil {
+		return nil, nil, err
+	}
+	lat, lon := geoColumns(geo.Fields, customFields)
+
+	if geo.BBox != nil {
+		return bboxCondition(lat, lon, *geo.BBox), nil, nil
+	}
+
+	// The bounding box of the circle narrows the candidates by the index, the distance decides
+	c := geo.Radius
+	distance := squirrel.Expr(fmt.Sprintf(
+		`(2 * %[3]f * asin(sqrt(min(1, power(sin(radians(%[1]s - ?) / 2), 2) + cos(radians(?)) * cos(radians(%[1]s)) * power(sin(radians(%[2]s - ?) / 2), 2)))))`,
+		lat, lon, repo.EarthRadiusMeters), c.Lat, c.Lat, c.Lon)
+	within, args, err := distance.ToSql()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build geo distance: %w", err)
+	}
+	cond := squirrel.And{
+		bboxCondition(lat, lon, c.BBox()),
+		squirrel.Expr(within+" <= ?", append(args, c.Meters)...),
+	}
+	return cond, distance, nil
+}
+
+// bboxCondition matches the entries within the box, including its edges. A box whose MinLon is greater
+// than its MaxLon crosses the antimeridian.
+func bboxCondition(lat, lon string, box repo.GeoBBox) squirrel.Sqlizer {
+	cond := squirrel.And{squirrel.Expr(fmt.Sprintf("%s BETWEEN ? AND ?", lat), box.MinLat, box.MaxLat)}
+	switch {
+	case box.MinLon <= -180 && box.MaxLon >= 180:
+		cond = append(cond, squirrel.Expr(fmt.Sprintf("%s IS NOT NULL", lon)))
+	case box.MinLon > box.MaxLon:
+		cond = append(cond, squirrel.Expr(fmt.Sprintf("(%[1]s >= ? OR %[1]s <= ?)", lon), box.MinLon, box.MaxLon))
+	default:
+		cond = append(cond, squirrel.Expr(fmt.Sprintf("%s BETWEEN ? AND ?", lon), box.MinLon, box.MaxLon))
+	}
+	return cond
+}
+
+// geoSortDirection is the direction of the SortFieldGeoDistance sort field, the nearest first unless "desc".
+func geoSortDirection(direction string) string {
+	if strings.EqualFold(direction, "desc") {
+		return "DESC"
+	}
+	return "ASC"
+}
@@ -0,0 +1,215 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestGeoSearch(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	geoFields := repo.GeoFields{Lat: "lat", Lon: "lon"}
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "sightings",
+		ContentType:  "file",
+		Config:       repo.DatabaseConfig{GeoFields: geoFields},
+		CustomFields: []repo.CustomFieldDef{{Name: "lat", Type: "REAL"}, {Name: "lon", Type: "REAL"}, {Name: "place", Type: "TEXT"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// 1. The geo fields are stored and indexed together
+	if got, err := r.GetDatabase(ctx, db.ID); err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	} else if got.Config.GeoFields != geoFields {
+		t.Errorf("expected the geo fields %+v, got %+v", geoFields, got.Config.GeoFields)
+	}
+	var indexes int
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?`, "idx_entries_"+db.ID.String()+"_geo").Scan(&indexes); err != nil {
+		t.Fatalf("failed to query indexes: %v", err)
+	}
+	if indexes != 1 {
+		t.Errorf("expected the geo index, found %d", indexes)
+	}
+
+	// 5000 m along the meridian is 0.04497 degrees of latitude
+	places := []struct {
+		name     string
+		lat, lon any
+	}{
+		{"center", 48.0, 11.0},
+		{"north inside", 48.0449, 11.0},
+		{"north outside", 48.0451, 11.0},
+		{"east inside", 48.0, 11.0671},
+		{"east outside", 48.0, 11.0675},
+		{"corner", 48.04, 11.05}, // within the bounding box of the circle, 5800 m away
+		{"edge", 49.0, 12.0},
+		{"west of antimeridian", 0.0, 179.9},
+		{"east of antimeridian", 0.0, -179.9},
+		{"unknown", nil, nil},
+	}
+	names := make(map[int64]string)
+	for _, p := range places {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream",
+			CustomFields: map[string]any{"lat": p.lat, "lon": p.lon, "place": p.name}})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		names[entry.ID] = p.name
+	}
+
+	search := func(geo repo.GeoFilter, sort *repo.SortCriteria) ([]string, error) {
+		t.Helper()
+		entries, err := r.SearchEntries(ctx, db.ID, repo.SearchRequest{Geo: &geo, Sort: sort, Pagination: repo.Pagination{Limit: 100}}, db.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		var found []string
+		for _, e := range entries {
+			found = append(found, names[e.ID])
+		}
+		return found, nil
+	}
+	sorted := func(s []string) []string {
+		s = slices.Clone(s)
+		slices.Sort(s)
+		return s
+	}
+
+	// 2. The edges of a bounding box are included
+	got, err := search(repo.GeoFilter{Fields: geoFields, BBox: &repo.GeoBBox{MinLat: 48.0, MinLon: 11.0, MaxLat: 49.0, MaxLon: 12.0}}, nil)
+	if err != nil {
+		t.Fatalf("bbox search failed: %v", err)
+	}
+	want := []string{"center", "corner", "east inside", "east outside", "edge", "north inside", "north outside"}
+	if !slices.Equal(sorted(got), want) {
+		t.Errorf("expected %v in the bounding box, got %v", want, sorted(got))
+	}
+
+	// 3. A radius matches by the great-circle distance, not by its bounding box
+	radius := repo.GeoRadius{Lat: 48.0, Lon: 11.0, Meters: 5000}
+	for _, p := range places[:6] {
+		inside := repo.HaversineMeters(radius.Lat, radius.Lon, p.lat.(float64), p.lon.(float64)) <= radius.Meters
+		if inside != (p.name == "center" || p.name == "north inside" || p.name == "east inside") {
+			t.Fatalf("test data of %q does not match its distance", p.name)
+		}
+	}
+	got, err = search(repo.GeoFilter{Fields: geoFields, Radius: &radius}, nil)
+	if err != nil {
+		t.Fatalf("radius search failed: %v", err)
+	}
+	want = []string{"center", "east inside", "north inside"}
+	if !slices.Equal(sorted(got), want) {
+		t.Errorf("expected %v within 5000 m, got %v", want, sorted(got))
+	}
+
+	// 4. The distance sorts the nearest first, or the farthest with desc
+	radius.Meters = 10000
+	got, err = search(repo.GeoFilter{Fields: geoFields, Radius: &radius}, &repo.SortCriteria{Field: repo.SortFieldGeoDistance})
+	if err != nil {
+		t.Fatalf("sorted radius search failed: %v", err)
+	}
+	want = []string{"center", "east inside", "north inside", "north outside", "east outside", "corner"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected the order %v, got %v", want, got)
+	}
+	got, err = search(repo.GeoFilter{Fields: geoFields, Radius: &radius}, &repo.SortCriteria{Field: repo.SortFieldGeoDistance, Direction: "desc"})
+	if err != nil {
+		t.Fatalf("sorted radius search failed: %v", err)
+	}
+	if len(got) == 0 || got[0] != "corner" {
+		t.Errorf("expected the farthest entry first, got %v", got)
+	}
+	if _, err := search(repo.GeoFilter{Fields: geoFields, BBox: &repo.GeoBBox{MinLat: 48, MinLon: 11, MaxLat: 49, MaxLon: 12}},
+		&repo.SortCriteria{Field: repo.SortFieldGeoDistance}); !errors.Is(err, customerrors.ErrValidation) {
+		t.Errorf("expected a validation error for the distance sort without radius, got %v", err)
+	}
+
+	// 5. Boxes and circles cross the antimeridian
+	want = []string{"east of antimeridian", "west of antimeridian"}
+	got, err = search(repo.GeoFilter{Fields: geoFields, BBox: &repo.GeoBBox{MinLat: -1, MinLon: 179, MaxLat: 1, MaxLon: -179}}, nil)
+	if err != nil {
+		t.Fatalf("antimeridian bbox search failed: %v", err)
+	}
+	if !slices.Equal(sorted(got), want) {
+		t.Errorf("expected %v in the box across the antimeridian, got %v", want, sorted(got))
+	}
+	got, err = search(repo.GeoFilter{Fields: geoFields, Radius: &repo.GeoRadius{Lat: 0, Lon: 180, Meters: 50000}}, nil)
+	if err != nil {
+		t.Fatalf("antimeridian radius search failed: %v", err)
+	}
+	if !slices.Equal(sorted(got), want) {
+		t.Errorf("expected %v in the circle across the antimeridian, got %v", want, sorted(got))
+	}
+
+	// 6. Invalid filters and filters without geo fields are rejected
+	for name, geo := range map[string]repo.GeoFilter{
+		"without geo fields": {Radius: &repo.GeoRadius{Lat: 48, Lon: 11, Meters: 1000}},
+		"without shape":      {Fields: geoFields},
+		"latitude range":     {Fields: geoFields, Radius: &repo.GeoRadius{Lat: 91, Lon: 11, Meters: 1000}},
+		"negative radius":    {Fields: geoFields, Radius: &repo.GeoRadius{Lat: 48, Lon: 11, Meters: -1}},
+		"inverted latitudes": {Fields: geoFields, BBox: &repo.GeoBBox{MinLat: 49, MinLon: 11, MaxLat: 48, MaxLon: 12}},
+	} {
+		if _, err := search(geo, nil); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("%s: expected a validation error, got %v", name, err)
+		}
+	}
+
+	// 7. A renamed geo field stays a geo field, a geo field cannot be deleted
+	latID := db.CustomFields[0].ID
+	renamed := "latitude"
+	if _, err := r.UpdateCustomField(ctx, db.ID, latID, &renamed, nil, nil); err != nil {
+		t.Fatalf("failed to rename the geo field: %v", err)
+	}
+	updated, err := r.GetDatabase(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	if updated.Config.GeoFields.Lat != renamed {
+		t.Errorf("expected the renamed latitude field, got %+v", updated.Config.GeoFields)
+	}
+	if err := r.DeleteCustomField(ctx, db.ID, latID); !errors.Is(err, customerrors.ErrConflict) {
+		t.Errorf("expected a conflict when deleting a geo field, got %v", err)
+	}
+
+	// 8. Removing the geo fields drops the index
+	updated.Config.GeoFields = repo.GeoFields{}
+	if _, err := r.UpdateDatabase(ctx, updated); err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+	if err := r.DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?`, "idx_entries_"+db.ID.String()+"_geo").Scan(&indexes); err != nil {
+		t.Fatalf("failed to query indexes: %v", err)
+	}
+	if indexes != 0 {
+		t.Errorf("expected the geo index to be dropped, found %d", indexes)
+	}
+	if err := r.DeleteCustomField(ctx, db.ID, latID); err != nil {
+		t.Errorf("expected the former geo field to be deletable, got %v", err)
+	}
+}
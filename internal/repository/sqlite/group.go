@@ -0,0 +1,320 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/cache"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// groupColumns are the columns of a group in the order scanGroup reads them.
+var groupColumns = []string{"id", "name", "can_view", "can_create", "can_edit", "can_delete", "is_admin"}
+
+// userGroupsCacheKey is the cache key of the groups (incl. roles) of a single user.
+// It is read on every authenticated request and must be invalidated whenever a group
+// or its memberships change.
+func userGroupsCacheKey(userID repo.ULID) string {
+	return cache.Key(cache.NamespaceUsers, "groups", userID.String())
+}
+
+// invalidateGroupMembersCache drops the cached groups of all members of a group.
+func (r *SQLiteRepository) invalidateGroupMembersCache(ctx context.Context, groupID repo.ULID) error {
+	members, err := r.GetGroupMembers(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	r.invalidateUserGroupsCache(members)
+	return nil
+}
+
+// invalidateUserGroupsCache drops the cached groups of the given users.
+func (r *SQLiteRepository) invalidateUserGroupsCache(userIDs []repo.ULID) {
+	for _, userID := range userIDs {
+		r.Cache.Delete(userGroupsCacheKey(userID))
+	}
+}
+
+// CreateGroup inserts a new group with its roles.
+func (r *SQLiteRepository) CreateGroup(ctx context.Context, group repo.Group) (repo.Group, error) {
+	group.ID = repo.ULID(shared.GenerateULID())
+
+	query, args, err := r.Builder.Insert("groups").
+		Columns(groupColumns...).
+		Values(
+			group.ID.String(), group.Name,
+			group.Roles.HasAccess(repo.AccessView),
+			group.Roles.HasAccess(repo.AccessCreate),
+			group.Roles.HasAccess(repo.AccessEdit),
+			group.Roles.HasAccess(repo.AccessDelete),
+			group.IsAdmin,
+		).
+		ToSql()
+	if err != nil {
+		return repo.Group{}, fmt.Errorf("failed to build insert group query: %w", err)
+	}
+
+	_, err = r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return repo.Group{}, customerrors.ErrGroupExists
+		}
+		return repo.Group{}, fmt.Errorf("failed to insert group: %w", err)
+	}
+
+	return group, nil
+}
+
+// GetGroup retrieves a single group.
+func (r *SQLiteRepository) GetGroup(ctx context.Context, id repo.ULID) (repo.Group, error) {
+	query, args, err := r.Builder.Select(groupColumns...).
+		From("groups").
+		Where(squirrel.Eq{"id": id.String()}).
+		ToSql()
+	if err != nil {
+		return repo.Group{}, fmt.Errorf("failed to build get group query: %w", err)
+	}
+
+	group, err := scanGroup(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.Group{}, customerrors.ErrNotFound
+		}
+		return repo.Group{}, fmt.Errorf("failed to scan group: %w", err)
+	}
+	return group, nil
+}
+
+// GetGroups retrieves all groups.
+func (r *SQLiteRepository) GetGroups(ctx context.Context) ([]repo.Group, error) {
+	query, args, err := r.Builder.Select(groupColumns...).
+		From("groups").
+		OrderBy("name").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get groups query: %w", err)
+	}
+
+	return r.queryGroups(ctx, query, args)
+}
+
+// UpdateGroup modifies the name, roles and admin flag of a group.
+func (r *SQLiteRepository) UpdateGroup(ctx context.Context, group repo.Group) (repo.Group, error) {
+	query, args, err := r.Builder.Update("groups").
+		Set("name", group.Name).
+		Set("can_view", group.Roles.HasAccess(repo.AccessView)).
+		Set("can_create", group.Roles.HasAccess(repo.AccessCreate)).
+		Set("can_edit", group.Roles.HasAccess(repo.AccessEdit)).
+		Set("can_delete", group.Roles.HasAccess(repo.AccessDelete)).
+		Set("is_admin", group.IsAdmin).
+		Where(squirrel.Eq{"id": group.ID.String()}).
+		ToSql()
+	if err != nil {
+		return repo.Group{}, fmt.Errorf("failed to build update group query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return repo.Group{}, customerrors.ErrGroupExists
+		}
+		return repo.Group{}, fmt.Errorf("failed to update group: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return repo.Group{}, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repo.Group{}, customerrors.ErrNotFound
+	}
+
+	if err := r.invalidateGroupMembersCache(ctx, group.ID); err != nil {
+		return repo.Group{}, fmt.Errorf("failed to invalidate group cache: %w", err)
+	}
+
+	return group, nil
+}
+
+// DeleteGroup removes a group.
+// Note: Due to ON DELETE CASCADE, this automatically clears its memberships.
+func (r *SQLiteRepository) DeleteGroup(ctx context.Context, id repo.ULID) error {
+	// The memberships are gone after the delete, so the members are looked up first
+	members, err := r.GetGroupMembers(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get group members: %w", err)
+	}
+
+	query, args, err := r.Builder.Delete("groups").
+		Where(squirrel.Eq{"id": id.String()}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete group query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return customerrors.ErrNotFound
+	}
+
+	// Invalidate after the delete, a request in between would cache the group again
+	r.invalidateUserGroupsCache(members)
+	return nil
+}
+
+// AddUserToGroup adds a membership. Adding an existing membership is a no-op.
+func (r *SQLiteRepository) AddUserToGroup(ctx context.Context, userID repo.ULID, groupID repo.ULID) error {
+	query, args, err := r.Builder.Insert("user_groups").
+		Columns("user_id", "group_id").
+		Values(userID.String(), groupID.String()).
+		Suffix("ON CONFLICT (user_id, group_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build add user to group query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return customerrors.ErrNotFound
+		}
+		return fmt.Errorf("failed to add user to group: %w", err)
+	}
+
+	r.Cache.Delete(userGroupsCacheKey(userID))
+	return nil
+}
+
+// RemoveUserFromGroup deletes a membership.
+func (r *SQLiteRepository) RemoveUserFromGroup(ctx context.Context, userID repo.ULID, groupID repo.ULID) error {
+	query, args, err := r.Builder.Delete("user_groups").
+		Where(squirrel.Eq{"user_id": userID.String(), "group_id": groupID.String()}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build remove user from group query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to remove user from group: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return customerrors.ErrNotFound
+	}
+
+	r.Cache.Delete(userGroupsCacheKey(userID))
+	return nil
+}
+
+// GetUserGroups retrieves all groups (incl. roles) a user is a member of, with cache backing.
+func (r *SQLiteRepository) GetUserGroups(ctx context.Context, userID repo.ULID) ([]repo.Group, error) {
+	cacheKey := userGroupsCacheKey(userID)
+	if val, found := r.Cache.Get(cacheKey); found {
+		return val.([]repo.Group), nil
+	}
+
+	query, args, err := r.Builder.Select("g.id", "g.name", "g.can_view", "g.can_create", "g.can_edit", "g.can_delete", "g.is_admin").
+		From("groups g").
+		Join("user_groups ug ON ug.group_id = g.id").
+		Where(squirrel.Eq{"ug.user_id": userID.String()}).
+		OrderBy("g.name").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get user groups query: %w", err)
+	}
+
+	groups, err := r.queryGroups(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Cache.Set(cacheKey, groups, 5*time.Minute)
+	return groups, nil
+}
+
+// GetGroupMembers retrieves the IDs of all members of a group.
+func (r *SQLiteRepository) GetGroupMembers(ctx context.Context, groupID repo.ULID) ([]repo.ULID, error) {
+	query, args, err := r.Builder.Select("user_id").
+		From("user_groups").
+		Where(squirrel.Eq{"group_id": groupID.String()}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get group members query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query group members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []repo.ULID{}
+	for rows.Next() {
+		var idStr string
+		if err := rows.Scan(&idStr); err != nil {
+			return nil, fmt.Errorf("failed to scan group member row: %w", err)
+		}
+		members = append(members, repo.ULID(idStr))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return members, nil
+}
+
+// queryGroups runs a query selecting the groupColumns.
+func (r *SQLiteRepository) queryGroups(ctx context.Context, query string, args []any) ([]repo.Group, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []repo.Group{}
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group row: %w", err)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return groups, nil
+}
+
+// scanGroup scans a single groups row (in groupColumns order).
+func scanGroup(row interface{ Scan(dest ...any) error }) (repo.Group, error) {
+	var group repo.Group
+	var idStr string
+	var canView, canCreate, canEdit, canDelete bool
+	if err := row.Scan(&idStr, &group.Name, &canView, &canCreate, &canEdit, &canDelete, &group.IsAdmin); err != nil {
+		return repo.Group{}, err
+	}
+	group.ID = repo.ULID(idStr)
+	group.Roles = repo.NewAccessGrant(canView, canCreate, canEdit, canDelete, false)
+	return group, nil
+}
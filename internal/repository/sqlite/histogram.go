@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// GetEntryHistogram counts the entries with a timestamp in [TStart, TEnd) matching the filter per bucket,
+// in a single GROUP BY over the bucket start. Buckets without entries are filled in, so the result holds
+// every bucket of the range in order.
+func (r *SQLiteRepository) GetEntryHistogram(ctx context.Context, dbID repo.ULID, req repo.HistogramRequest, customFields []repo.CustomFieldDef) ([]repo.HistogramBucket, error) {
+	first, count, err := req.Buckets()
+	if err != nil {
+		return nil, err
+	}
+	size, offset, _ := repo.HistogramBucketMillis(req.Bucket)
+
+	where, matchColumn, _, err := r.buildWhereExpr(dbID, req.Filter, customFields, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// The modulo of SQLite keeps the sign, adding the size once more floors timestamps before the epoch
+	builder := r.Builder.Select().
+		Column(squirrel.Expr("timestamp - (((timestamp - ?) % ?) + ?) % ? AS bucket", offset, size, size, size)).
+		Columns("COUNT(*)", "COALESCE(SUM(filesize), 0)").
+		From(fmt.Sprintf(`"entries_%s"`, dbID.String())).
+		Where(squirrel.GtOrEq{"timestamp": req.TStart.UnixMilli()}).
+		Where(squirrel.Lt{"timestamp": req.TEnd.UnixMilli()})
+	if where != nil {
+		builder = builder.Where(where)
+	}
+	query, args, err := builder.GroupBy("bucket").OrderBy("bucket").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build histogram query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		if matchColumn != "" && isFulltextQueryError(err) {
+			return nil, fmt.Errorf("%w: invalid full-text query: %v", customerrors.ErrValidation, err)
+		}
+		return nil, fmt.Errorf("failed to query entry histogram: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]repo.HistogramBucket, count)
+	for i := range buckets {
+		buckets[i].Start = first.Add(time.Duration(int64(i)*size) * time.Millisecond)
+	}
+	for rows.Next() {
+		var start, n int64
+		var bytes uint64
+		if err := rows.Scan(&start, &n, &bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan histogram bucket: %w", err)
+		}
+		if i := (start - first.UnixMilli()) / size; i >= 0 && i < int64(count) {
+			buckets[i].Count, buckets[i].Bytes = n, bytes
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if matchColumn != "" && isFulltextQueryError(err) {
+			return nil, fmt.Errorf("%w: invalid full-text query: %v", customerrors.ErrValidation, err)
+		}
+		return nil, fmt.Errorf("failed to read entry histogram: %w", err)
+	}
+	return buckets, nil
+}
+
+// GetRetentionForecast sums the entries without legal hold by the week in which they become due, in a single
+// GROUP BY. The first bucket holds the entries with a time field up to the cutoff, bucket i those in
+// (cutoff + i-1 weeks, cutoff + i weeks]. Buckets without entries are filled in.
+func (r *SQLiteRepository) GetRetentionForecast(ctx context.Context, dbID repo.ULID, req repo.RetentionRequest) ([]repo.HistogramBucket, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	size, _, _ := repo.HistogramBucketMillis(repo.HistogramBucketWeek)
+	cutoff := req.Cutoff.UnixMilli()
+	field := req.TimeField
+
+	// Times after the cutoff are positive offsets, the integer division floors them
+	builder := r.Builder.Select().
+		Column(squirrel.Expr(fmt.Sprintf("CASE WHEN %[1]s <= ? THEN 0 ELSE (%[1]s - ? - 1) / ? + 1 END AS bucket", field), cutoff, cutoff, size)).
+		Columns("COUNT(*)", "COALESCE(SUM(filesize + preview_filesize + original_filesize), 0)").
+		From(fmt.Sprintf(`"entries_%s"`, dbID.String())).
+		Where(squirrel.LtOrEq{field: cutoff + int64(req.Weeks)*size}).
+		Where(squirrel.Eq{"legal_hold": false})
+	if len(req.Statuses) > 0 {
+		builder = builder.Where(squirrel.Eq{"status": req.Statuses})
+	}
+	query, args, err := builder.GroupBy("bucket").OrderBy("bucket").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build retention forecast query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retention forecast: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]repo.HistogramBucket, req.Weeks+1)
+	for i := 1; i < len(buckets); i++ {
+		buckets[i].Start = time.UnixMilli(cutoff + int64(i-1)*size).UTC()
+	}
+	for rows.Next() {
+		var i, n int64
+		var bytes uint64
+		if err := rows.Scan(&i, &n, &bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan retention forecast bucket: %w", err)
+		}
+		if i >= 0 && i < int64(len(buckets)) {
+			buckets[i].Count, buckets[i].Bytes = n, bytes
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read retention forecast: %w", err)
+	}
+	return buckets, nil
+}
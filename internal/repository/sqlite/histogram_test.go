@@ -0,0 +1,159 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestGetEntryHistogram(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "timeline", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	utc := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatalf("bad time %s: %v", s, err)
+		}
+		return ts
+	}
+	for _, e := range []struct {
+		ts   string
+		size uint64
+	}{
+		{"2026-03-01T06:00:00Z", 1},       // before the range
+		{"2026-03-01T23:59:59.999Z", 10},  // last millisecond of Sunday
+		{"2026-03-02T00:00:00Z", 20},      // first millisecond of Monday
+		{"2026-03-03T00:30:00+02:00", 40}, // local midnight is still Monday in UTC
+		{"2026-03-04T00:00:00Z", 80},      // the end of the range is exclusive
+		{"1969-12-31T23:00:00Z", 5},       // before the epoch
+		{"1970-01-01T00:00:00Z", 7000},    // the epoch starts a bucket
+	} {
+		if _, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Size: e.size, Timestamp: utc(e.ts), MimeType: "application/octet-stream"}); err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+	}
+
+	type bucket struct {
+		start string
+		count int64
+		bytes uint64
+	}
+	for _, tc := range []struct {
+		name   string
+		req    repo.HistogramRequest
+		expect []bucket
+	}{
+		{
+			name: "days in UTC",
+			req:  repo.HistogramRequest{Bucket: repo.HistogramBucketDay, TStart: utc("2026-03-01T12:00:00Z"), TEnd: utc("2026-03-04T00:00:00Z")},
+			expect: []bucket{
+				{"2026-03-01T00:00:00Z", 1, 10},
+				{"2026-03-02T00:00:00Z", 2, 60},
+				{"2026-03-03T00:00:00Z", 0, 0},
+			},
+		},
+		{
+			name: "weeks start on Monday",
+			req:  repo.HistogramRequest{Bucket: repo.HistogramBucketWeek, TStart: utc("2026-03-01T12:00:00+02:00"), TEnd: utc("2026-03-04T00:00:00Z")},
+			expect: []bucket{
+				{"2026-02-23T00:00:00Z", 1, 10},
+				{"2026-03-02T00:00:00Z", 2, 60},
+			},
+		},
+		{
+			name: "filtered hours",
+			req: repo.HistogramRequest{
+				Bucket: repo.HistogramBucketHour, TStart: utc("2026-03-02T00:00:00Z"), TEnd: utc("2026-03-03T00:00:00Z"),
+				Filter: &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "filesize", Operator: ">", Value: 30}}},
+			},
+			expect: []bucket{{"2026-03-02T22:00:00Z", 1, 40}},
+		},
+		{
+			name: "before the epoch",
+			req:  repo.HistogramRequest{Bucket: repo.HistogramBucketDay, TStart: utc("1969-12-31T12:00:00Z"), TEnd: utc("1970-01-01T00:00:01Z")},
+			expect: []bucket{
+				{"1969-12-31T00:00:00Z", 1, 5},
+				{"1970-01-01T00:00:00Z", 1, 7000},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buckets, err := r.GetEntryHistogram(ctx, db.ID, tc.req, nil)
+			if err != nil {
+				t.Fatalf("failed to get histogram: %v", err)
+			}
+			if tc.name == "filtered hours" {
+				// The other hours are filled in empty
+				if len(buckets) != 24 {
+					t.Fatalf("expected 24 buckets, got %d", len(buckets))
+				}
+				for i, b := range buckets {
+					if !b.Start.Equal(utc("2026-03-02T00:00:00Z").Add(time.Duration(i) * time.Hour)) {
+						t.Errorf("bucket %d starts at %v", i, b.Start)
+					}
+					if i != 22 && (b.Count != 0 || b.Bytes != 0) {
+						t.Errorf("expected bucket %d to be empty, got %+v", i, b)
+					}
+				}
+				buckets = buckets[22:23]
+			}
+			if len(buckets) != len(tc.expect) {
+				t.Fatalf("expected %d buckets, got %+v", len(tc.expect), buckets)
+			}
+			for i, want := range tc.expect {
+				got := buckets[i]
+				if !got.Start.Equal(utc(want.start)) || got.Start.Location() != time.UTC || got.Count != want.count || got.Bytes != want.bytes {
+					t.Errorf("bucket %d: expected %+v, got %v %d %d", i, want, got.Start, got.Count, got.Bytes)
+				}
+			}
+		})
+	}
+
+	// Invalid buckets, ranges and filters are validation errors
+	for name, req := range map[string]repo.HistogramRequest{
+		"unknown bucket": {Bucket: "month", TStart: utc("2026-03-01T00:00:00Z"), TEnd: utc("2026-03-02T00:00:00Z")},
+		"no start":       {Bucket: repo.HistogramBucketDay, TEnd: utc("2026-03-02T00:00:00Z")},
+		"reversed":       {Bucket: repo.HistogramBucketDay, TStart: utc("2026-03-02T00:00:00Z"), TEnd: utc("2026-03-01T00:00:00Z")},
+		"too many":       {Bucket: repo.HistogramBucketHour, TStart: utc("2026-01-01T00:00:00Z"), TEnd: utc("2026-01-01T00:00:00Z").Add(2001 * time.Hour)},
+		"absurd range":   {Bucket: repo.HistogramBucketWeek, TStart: time.UnixMilli(-1 << 60), TEnd: time.UnixMilli(1 << 60)},
+		"unknown field": {
+			Bucket: repo.HistogramBucketDay, TStart: utc("2026-03-01T00:00:00Z"), TEnd: utc("2026-03-02T00:00:00Z"),
+			Filter: &repo.FilterGroup{Conditions: []repo.Condition{{Field: "nope", Operator: "=", Value: 1}}},
+		},
+	} {
+		if _, err := r.GetEntryHistogram(ctx, db.ID, req, nil); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("%s: expected a validation error, got %v", name, err)
+		}
+	}
+	exact := repo.HistogramRequest{Bucket: repo.HistogramBucketHour, TStart: utc("2026-01-01T00:00:00Z"), TEnd: utc("2026-01-01T00:00:00Z").Add(2000 * time.Hour)}
+	if buckets, err := r.GetEntryHistogram(ctx, db.ID, exact, nil); err != nil || len(buckets) != repo.MaxHistogramBuckets {
+		t.Errorf("expected %d buckets, got %d (%v)", repo.MaxHistogramBuckets, len(buckets), err)
+	}
+}
@@ -20,8 +20,8 @@ func (r *SQLiteRepository) HouseKeepingRequired(ctx context.Context) ([]repo.Dat
 
 	query, args, err := r.Builder.Select(
 		"id", "name", "content_type", "hk_interval", "hk_disk_space", "hk_max_age",
-		"create_preview", "auto_conversion", "n_max_queued", "hk_last_run",
-		"entry_count", "total_disk_space_bytes").
+		"create_preview", "auto_conversion", "keep_original", "unique_external_id", "n_max_queued", "hk_last_run",
+		"entry_count", "total_disk_space_bytes", "hk_disk_space_warn_percent", "disk_space_alert_active", "conversion_rules", "transcription", "public_read", "hk_age_basis", "metadata_defaults", "metadata_overrides", "waveform_width", "waveform_height", "waveform_color", "waveform_background", "geo_fields").
 		From("databases").
 		Where("hk_interval > 0 AND hk_last_run + hk_interval <= CAST(unixepoch('subsec') * 1000 AS INTEGER)").
 		ToSql()
@@ -82,6 +82,89 @@ func (r *SQLiteRepository) HouseKeepingWasCalled(ctx context.Context, dbID repo.
 	if rowsAffected == 0 {
 		return time.Time{}, customerrors.ErrNotFound
 	}
+	r.forgetDatabase(dbID)
 
 	return now, nil
 }
+
+// SetDiskSpaceAlert records whether the disk space alert of a database is active. It returns true only if the
+// state changed, so of several instances checking the same database only one sends the alert.
+func (r *SQLiteRepository) SetDiskSpaceAlert(ctx context.Context, dbID repo.ULID, active bool) (bool, error) {
+	query, args, err := r.Builder.Update("databases").
+		Set("disk_space_alert_active", active).
+		Where(squirrel.Eq{"id": dbID.String()}).
+		Where(squirrel.NotEq{"disk_space_alert_active": active}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build disk space alert update query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update disk space alert: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve rows affected: %w", err)
+	}
+	r.forgetDatabase(dbID)
+	return rowsAffected > 0, nil
+}
+
+// SetProcessingAlert records whether the processing backlog alert of a database is active. Like SetDiskSpaceAlert
+// it returns true only if the state changed.
+func (r *SQLiteRepository) SetProcessingAlert(ctx context.Context, dbID repo.ULID, active bool) (bool, error) {
+	query, args, err := r.Builder.Update("databases").
+		Set("processing_alert_active", active).
+		Where(squirrel.Eq{"id": dbID.String()}).
+		Where(squirrel.NotEq{"processing_alert_active": active}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build processing alert update query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update processing alert: %w", err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// DeleteExpiredRows deletes the rows of a table whose expiry column (Unix milliseconds) is before the start of the
+// call. Every statement deletes at most batchSize rows, so the write lock is released between the batches and
+// uploads and logins are not blocked by a large backlog. Rows with a NULL expiry never expire.
+func (r *SQLiteRepository) DeleteExpiredRows(ctx context.Context, table, expiryColumn string, batchSize int) (int64, error) {
+	// The names are part of the query
+	if !safeNameRegex.MatchString(table) || !safeNameRegex.MatchString(expiryColumn) {
+		return 0, fmt.Errorf("%w: invalid table '%s' or expiry column '%s'", customerrors.ErrValidation, table, expiryColumn)
+	}
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("%w: the batch size must be positive", customerrors.ErrValidation)
+	}
+
+	cutoff := time.Now().UnixMilli()
+	query := fmt.Sprintf(`DELETE FROM "%[1]s" WHERE rowid IN (SELECT rowid FROM "%[1]s" WHERE "%[2]s" < ? LIMIT ?)`, table, expiryColumn)
+
+	var deleted int64
+	for {
+		res, err := r.DB.ExecContext(ctx, query, cutoff, batchSize)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete expired rows of %s: %w", table, err)
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to retrieve rows affected: %w", err)
+		}
+		deleted += rowsAffected
+		if rowsAffected < int64(batchSize) {
+			return deleted, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var idempotencyKeyColumns = []string{
+	"database_id", "user_id", "idempotency_key",
+	"entry_id", "status_code", "created_at", "expires_at",
+}
+
+// ReserveIdempotencyKey inserts a key marking a request in progress. If the key already exists
+// and has not expired, nothing is inserted and the stored key is returned with false. A key in progress
+// whose lease expired is reclaimed like an expired result.
+func (r *SQLiteRepository) ReserveIdempotencyKey(ctx context.Context, key repo.IdempotencyKey) (repo.IdempotencyKey, bool, error) {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	key.EntryID = 0
+	key.StatusCode = 0
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return repo.IdempotencyKey{}, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	scope := squirrel.Eq{"database_id": key.DatabaseID.String(), "user_id": key.UserID.String(), "idempotency_key": key.Key}
+
+	// 1. An expired key may be reused right away, housekeeping might not have swept it yet
+	delQuery, delArgs, err := r.Builder.Delete("idempotency_keys").
+		Where(scope).
+		Where(squirrel.Lt{"expires_at": time.Now().UnixMilli()}).
+		ToSql()
+	if err != nil {
+		return repo.IdempotencyKey{}, false, fmt.Errorf("failed to build delete expired idempotency_key query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, delQuery, delArgs...); err != nil {
+		return repo.IdempotencyKey{}, false, fmt.Errorf("failed to delete expired idempotency_key: %w", err)
+	}
+
+	// 2. Insert unless the key exists, the primary key decides which request wins
+	insQuery, insArgs, err := r.Builder.Insert("idempotency_keys").
+		Columns(idempotencyKeyColumns...).
+		Values(
+			key.DatabaseID.String(), key.UserID.String(), key.Key,
+			key.EntryID, key.StatusCode, key.CreatedAt.UnixMilli(), key.ExpiresAt.UnixMilli(),
+		).
+		Suffix("ON CONFLICT (database_id, user_id, idempotency_key) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return repo.IdempotencyKey{}, false, fmt.Errorf("failed to build insert idempotency_key query: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, insQuery, insArgs...)
+	if err != nil {
+		return repo.IdempotencyKey{}, false, fmt.Errorf("failed to insert idempotency_key: %w", err)
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return repo.IdempotencyKey{}, false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if inserted > 0 {
+		if err := tx.Commit(); err != nil {
+			return repo.IdempotencyKey{}, false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return key, true, nil
+	}
+
+	// 3. Somebody else owns the key, return their state
+	selQuery, selArgs, err := r.Builder.Select(idempotencyKeyColumns...).
+		From("idempotency_keys").
+		Where(scope).
+		ToSql()
+	if err != nil {
+		return repo.IdempotencyKey{}, false, fmt.Errorf("failed to build get idempotency_key query: %w", err)
+	}
+	existing, err := scanIdempotencyKey(tx.QueryRowContext(ctx, selQuery, selArgs...))
+	if err != nil {
+		return repo.IdempotencyKey{}, false, fmt.Errorf("failed to get existing idempotency_key: %w", err)
+	}
+
+	return existing, false, tx.Commit()
+}
+
+// GetIdempotencyKey retrieves a key that has not expired yet.
+func (r *SQLiteRepository) GetIdempotencyKey(ctx context.Context, dbID repo.ULID, userID repo.ULID, key string) (repo.IdempotencyKey, error) {
+	query, args, err := r.Builder.Select(idempotencyKeyColumns...).
+		From("idempotency_keys").
+		Where(squirrel.Eq{"database_id": dbID.String(), "user_id": userID.String(), "idempotency_key": key}).
+		Where(squirrel.GtOrEq{"expires_at": time.Now().UnixMilli()}).
+		ToSql()
+	if err != nil {
+		return repo.IdempotencyKey{}, fmt.Errorf("failed to build get idempotency_key query: %w", err)
+	}
+
+	k, err := scanIdempotencyKey(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.IdempotencyKey{}, customerrors.ErrNotFound
+		}
+		return repo.IdempotencyKey{}, fmt.Errorf("failed to execute get idempotency_key query: %w", err)
+	}
+
+	return k, nil
+}
+
+// RenewIdempotencyKey extends the lease of a key whose request is still in progress to key.ExpiresAt.
+// It returns ErrNotFound if the key is gone or already completed.
+func (r *SQLiteRepository) RenewIdempotencyKey(ctx context.Context, key repo.IdempotencyKey) error {
+	query, args, err := r.Builder.Update("idempotency_keys").
+		Set("expires_at", key.ExpiresAt.UnixMilli()).
+		Where(squirrel.Eq{"database_id": key.DatabaseID.String(), "user_id": key.UserID.String(), "idempotency_key": key.Key, "status_code": 0}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build renew idempotency_key query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to renew idempotency_key: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// CompleteIdempotencyKey stores the outcome of the request that reserved the key, which is replayed until
+// key.ExpiresAt.
+func (r *SQLiteRepository) CompleteIdempotencyKey(ctx context.Context, key repo.IdempotencyKey) error {
+	query, args, err := r.Builder.Update("idempotency_keys").
+		Set("entry_id", key.EntryID).
+		Set("status_code", key.StatusCode).
+		Set("expires_at", key.ExpiresAt.UnixMilli()).
+		Where(squirrel.Eq{"database_id": key.DatabaseID.String(), "user_id": key.UserID.String(), "idempotency_key": key.Key}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build complete idempotency_key query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update idempotency_key: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey removes a key, e.g. to release it after the original request failed.
+func (r *SQLiteRepository) DeleteIdempotencyKey(ctx context.Context, dbID repo.ULID, userID repo.ULID, key string) error {
+	query, args, err := r.Builder.Delete("idempotency_keys").
+		Where(squirrel.Eq{"database_id": dbID.String(), "user_id": userID.String(), "idempotency_key": key}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete idempotency_key query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete idempotency_key: %w", err)
+	}
+	return nil
+}
+
+func scanIdempotencyKey(row interface{ Scan(dest ...any) error }) (repo.IdempotencyKey, error) {
+	var k repo.IdempotencyKey
+	var dbIDStr, userIDStr string
+	var createdAtVal, expiresAtVal int64
+
+	err := row.Scan(&dbIDStr, &userIDStr, &k.Key, &k.EntryID, &k.StatusCode, &createdAtVal, &expiresAtVal)
+	if err != nil {
+		return repo.IdempotencyKey{}, err
+	}
+
+	k.DatabaseID = repo.ULID(dbIDStr)
+	k.UserID = repo.ULID(userIDStr)
+	k.CreatedAt = time.UnixMilli(createdAtVal)
+	k.ExpiresAt = time.UnixMilli(expiresAtVal)
+
+	return k, nil
+}
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// GetEntriesToVerify returns up to limit ready entries for the integrity check. Entries that were
+// never verified come first (last_verified_at is 0), then the ones verified the longest time ago.
+func (r *SQLiteRepository) GetEntriesToVerify(ctx context.Context, dbID repo.ULID, limit int) ([]repo.Entry, error) {
+	customFields, err := r.getCustomFields(ctx, r.DB, dbID)
+	if err != nil {
+		return nil, err
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query, args, err := r.Builder.Select("*").
+		From(tableName).
+		Where(squirrel.Eq{"status": repo.EntryStatusReady}).
+		OrderBy("last_verified_at ASC", "id ASC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build entries to verify query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entries to verify: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := r.scanEntryRows(rows, customFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan entries to verify: %w", err)
+	}
+	return entries, nil
+}
+
+// RecordEntryVerification stores the content hash and the time of the check. If errorReason is set,
+// the entry is set to error as well. Only ready entries are updated, so an entry that is deleted or
+// reprocessed in the meantime is left alone and ErrNotFound is returned.
+func (r *SQLiteRepository) RecordEntryVerification(ctx context.Context, dbID repo.ULID, entryID int64, contentHash, errorReason string) error {
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	now := time.Now().UnixMilli()
+
+	builder := r.Builder.Update(tableName).
+		Set("content_hash", contentHash).
+		Set("last_verified_at", now)
+	if errorReason != "" {
+		builder = builder.
+			Set("status", repo.EntryStatusError).
+			Set("error_reason", errorReason).
+			Set("updated_at", now)
+	}
+
+	query, args, err := builder.
+		Where(squirrel.Eq{"id": entryID, "status": repo.EntryStatusReady}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build record verification query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to record verification: %w", err)
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
+
+// GetIntegrityStats counts the verified, unverified and corrupted entries of a database.
+func (r *SQLiteRepository) GetIntegrityStats(ctx context.Context, dbID repo.ULID) (repo.IntegrityStats, error) {
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query, args, err := r.Builder.Select(
+		"COALESCE(SUM(CASE WHEN status = ? AND last_verified_at > 0 THEN 1 ELSE 0 END), 0)",
+		"COALESCE(SUM(CASE WHEN status = ? AND last_verified_at = 0 THEN 1 ELSE 0 END), 0)",
+		"COALESCE(SUM(CASE WHEN status = ? AND error_reason = ? THEN 1 ELSE 0 END), 0)",
+		"MIN(CASE WHEN status = ? AND last_verified_at > 0 THEN last_verified_at END)",
+	).From(tableName).ToSql()
+	if err != nil {
+		return repo.IntegrityStats{}, fmt.Errorf("failed to build integrity stats query: %w", err)
+	}
+	args = append(args, repo.EntryStatusReady, repo.EntryStatusReady, repo.EntryStatusError, repo.ErrorReasonCorrupted, repo.EntryStatusReady)
+
+	var stats repo.IntegrityStats
+	var oldest sql.NullInt64
+	err = r.DB.QueryRowContext(ctx, query, args...).Scan(&stats.Verified, &stats.Unverified, &stats.Corrupted, &oldest)
+	if err != nil {
+		return repo.IntegrityStats{}, fmt.Errorf("failed to query integrity stats: %w", err)
+	}
+	if oldest.Valid {
+		stats.OldestVerification = time.UnixMilli(oldest.Int64)
+	}
+	return stats, nil
+}
@@ -0,0 +1,209 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+var jobColumns = []string{
+	"id", "type", "database_id", "status",
+	"payload", "total", "processed", "failed", "counters", "error",
+	"created_by", "created_at", "updated_at", "finished_at",
+	"expires_at", "pending_files",
+}
+
+// CreateJob stores a new running job.
+func (r *SQLiteRepository) CreateJob(ctx context.Context, job repo.Job) (repo.Job, error) {
+	if job.ID == "" {
+		job.ID = repo.ULID(shared.GenerateULID())
+	}
+	now := time.Now()
+	job.Status = repo.JobRunning
+	job.CreatedAt, job.UpdatedAt = now, now
+	job.Processed, job.Failed = 0, 0
+	job.PendingFiles = nil
+	if job.Counters == nil {
+		job.Counters = map[string]int64{}
+	}
+	counters, err := json.Marshal(job.Counters)
+	if err != nil {
+		return repo.Job{}, fmt.Errorf("failed to encode job counters: %w", err)
+	}
+
+	query, args, err := r.Builder.Insert("jobs").
+		Columns(jobColumns...).
+		Values(
+			job.ID.String(), job.Type, job.DatabaseID.String(), job.Status,
+			job.Payload, job.Total, job.Processed, job.Failed, string(counters), job.Error,
+			job.CreatedBy, now.UnixMilli(), now.UnixMilli(), nil,
+			nil, "[]",
+		).
+		ToSql()
+	if err != nil {
+		return repo.Job{}, fmt.Errorf("failed to build insert job query: %w", err)
+	}
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return repo.Job{}, customerrors.ErrDatabaseNotExisting
+		}
+		return repo.Job{}, fmt.Errorf("failed to insert job: %w", err)
+	}
+	return job, nil
+}
+
+// GetJob retrieves a job by its ID.
+func (r *SQLiteRepository) GetJob(ctx context.Context, id repo.ULID) (repo.Job, error) {
+	query, args, err := r.Builder.Select(jobColumns...).
+		From("jobs").
+		Where(squirrel.Eq{"id": id.String()}).
+		ToSql()
+	if err != nil {
+		return repo.Job{}, fmt.Errorf("failed to build get job query: %w", err)
+	}
+
+	job, err := scanJob(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.Job{}, customerrors.ErrNotFound
+		}
+		return repo.Job{}, fmt.Errorf("failed to execute get job query: %w", err)
+	}
+	return job, nil
+}
+
+// GetRunningJobs retrieves the jobs that have not finished, oldest first.
+func (r *SQLiteRepository) GetRunningJobs(ctx context.Context) ([]repo.Job, error) {
+	query, args, err := r.Builder.Select(jobColumns...).
+		From("jobs").
+		Where(squirrel.Eq{"status": repo.JobRunning}).
+		OrderBy("created_at ASC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get running jobs query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get running jobs query: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []repo.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job row: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("job row iteration error: %w", err)
+	}
+	return jobs, nil
+}
+
+// UpdateJob stores the status, progress, counters and pending files of a job. The payload never changes.
+func (r *SQLiteRepository) UpdateJob(ctx context.Context, job repo.Job) error {
+	return r.updateJob(ctx, r.DB, job)
+}
+
+// updateJob is UpdateJob on a Queryer, so a deletion job stores its progress in the transaction deleting the rows.
+func (r *SQLiteRepository) updateJob(ctx context.Context, q Queryer, job repo.Job) error {
+	var finishedAt, expiresAt any
+	if !job.FinishedAt.IsZero() {
+		finishedAt = job.FinishedAt.UnixMilli()
+	}
+	if !job.ExpiresAt.IsZero() {
+		expiresAt = job.ExpiresAt.UnixMilli()
+	}
+	if job.Counters == nil {
+		job.Counters = map[string]int64{}
+	}
+	counters, err := json.Marshal(job.Counters)
+	if err != nil {
+		return fmt.Errorf("failed to encode job counters: %w", err)
+	}
+	if job.PendingFiles == nil {
+		job.PendingFiles = []repo.DeletedEntryMeta{}
+	}
+	pendingFiles, err := json.Marshal(job.PendingFiles)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending files of job: %w", err)
+	}
+
+	query, args, err := r.Builder.Update("jobs").
+		Set("status", job.Status).
+		Set("processed", job.Processed).
+		Set("failed", job.Failed).
+		Set("counters", string(counters)).
+		Set("error", job.Error).
+		Set("updated_at", time.Now().UnixMilli()).
+		Set("finished_at", finishedAt).
+		Set("expires_at", expiresAt).
+		Set("pending_files", string(pendingFiles)).
+		Where(squirrel.Eq{"id": job.ID.String()}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update job query: %w", err)
+	}
+
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute update job query: %w", err)
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return customerrors.ErrNotFound // removed along with its database
+	}
+	return nil
+}
+
+// scanJob scans a single jobs row (in jobColumns order).
+func scanJob(row interface{ Scan(dest ...any) error }) (repo.Job, error) {
+	var job repo.Job
+	var idStr, dbIDStr, counters, pendingFiles string
+	var createdAtVal, updatedAtVal int64
+	var finishedAtVal, expiresAtVal sql.NullInt64
+
+	err := row.Scan(
+		&idStr, &job.Type, &dbIDStr, &job.Status,
+		&job.Payload, &job.Total, &job.Processed, &job.Failed, &counters, &job.Error,
+		&job.CreatedBy, &createdAtVal, &updatedAtVal, &finishedAtVal,
+		&expiresAtVal, &pendingFiles,
+	)
+	if err != nil {
+		return repo.Job{}, err
+	}
+
+	job.ID = repo.ULID(idStr)
+	job.DatabaseID = repo.ULID(dbIDStr)
+	if err := json.Unmarshal([]byte(counters), &job.Counters); err != nil {
+		return repo.Job{}, fmt.Errorf("failed to decode job counters: %w", err)
+	}
+	if job.Counters == nil {
+		job.Counters = map[string]int64{}
+	}
+	if err := json.Unmarshal([]byte(pendingFiles), &job.PendingFiles); err != nil {
+		return repo.Job{}, fmt.Errorf("failed to decode pending files of job: %w", err)
+	}
+	job.CreatedAt = time.UnixMilli(createdAtVal)
+	job.UpdatedAt = time.UnixMilli(updatedAtVal)
+	if finishedAtVal.Valid {
+		job.FinishedAt = time.UnixMilli(finishedAtVal.Int64)
+	}
+	if expiresAtVal.Valid {
+		job.ExpiresAt = time.UnixMilli(expiresAtVal.Int64)
+	}
+	return job, nil
+}
@@ -0,0 +1,113 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// EnsureJWTSecret stores the secret as the current one unless a current secret exists.
+// The check and the insert are one statement, so concurrently starting instances converge on the same secret.
+func (r *SQLiteRepository) EnsureJWTSecret(ctx context.Context, secret string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx,
+		`INSERT INTO jwt_secrets (secret, created_at, retires_at)
+		 SELECT ?, ?, 0 WHERE NOT EXISTS (SELECT 1 FROM jwt_secrets WHERE retires_at = 0)`,
+		secret, time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert jwt secret: %w", err)
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return inserted > 0, nil
+}
+
+// GetJWTSecrets returns the current secret and the rotated secrets that are still accepted, newest first.
+func (r *SQLiteRepository) GetJWTSecrets(ctx context.Context) ([]repo.JWTSecret, error) {
+	query, args, err := r.Builder.Select("id", "secret", "created_at", "retires_at").
+		From("jwt_secrets").
+		Where(squirrel.Or{squirrel.Eq{"retires_at": 0}, squirrel.Gt{"retires_at": time.Now().UnixMilli()}}).
+		OrderBy("id DESC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get jwt secrets query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get jwt secrets query: %w", err)
+	}
+	defer rows.Close()
+
+	var secrets []repo.JWTSecret
+	for rows.Next() {
+		var s repo.JWTSecret
+		var createdAtVal, retiresAtVal int64
+		if err := rows.Scan(&s.ID, &s.Secret, &createdAtVal, &retiresAtVal); err != nil {
+			return nil, fmt.Errorf("failed to scan jwt secret: %w", err)
+		}
+		s.CreatedAt = time.UnixMilli(createdAtVal)
+		if retiresAtVal > 0 {
+			s.RetiresAt = time.UnixMilli(retiresAtVal)
+		}
+		secrets = append(secrets, s)
+	}
+	return secrets, rows.Err()
+}
+
+// RotateJWTSecret makes the secret the current one. The previous current secret is accepted for the grace period,
+// secrets whose grace period has passed are removed.
+func (r *SQLiteRepository) RotateJWTSecret(ctx context.Context, secret string, grace time.Duration) error {
+	now := time.Now()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Remove secrets that are no longer accepted
+	delQuery, delArgs, err := r.Builder.Delete("jwt_secrets").
+		Where(squirrel.And{squirrel.Gt{"retires_at": 0}, squirrel.LtOrEq{"retires_at": now.UnixMilli()}}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete retired jwt secrets query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, delQuery, delArgs...); err != nil {
+		return fmt.Errorf("failed to delete retired jwt secrets: %w", err)
+	}
+
+	// 2. Retire the current secret after the grace period
+	retireQuery, retireArgs, err := r.Builder.Update("jwt_secrets").
+		Set("retires_at", now.Add(grace).UnixMilli()).
+		Where(squirrel.Eq{"retires_at": 0}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build retire jwt secret query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, retireQuery, retireArgs...); err != nil {
+		return fmt.Errorf("failed to retire jwt secret: %w", err)
+	}
+
+	// 3. Insert the new current secret
+	insQuery, insArgs, err := r.Builder.Insert("jwt_secrets").
+		Columns("secret", "created_at", "retires_at").
+		Values(secret, now.UnixMilli(), 0).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert jwt secret query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, insQuery, insArgs...); err != nil {
+		return fmt.Errorf("failed to insert jwt secret: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
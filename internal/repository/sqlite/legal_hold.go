@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// SetLegalHold sets or clears the legal hold of entries. Entries that are being deleted are left alone.
+// Returns the IDs of the entries that were updated, missing IDs are not an error.
+func (r *SQLiteRepository) SetLegalHold(ctx context.Context, dbID repo.ULID, entryIDs []int64, hold bool) ([]int64, error) {
+	if len(entryIDs) == 0 {
+		return []int64{}, nil
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query, args, err := r.Builder.Update(tableName).
+		Set("legal_hold", hold).
+		Set("updated_at", time.Now().UnixMilli()).
+		Where(squirrel.Eq{"id": entryIDs}).
+		Where(squirrel.NotEq{"status": repo.EntryStatusDeleting}).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build legal hold query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set legal hold: %w", err)
+	}
+	defer rows.Close()
+
+	updated := make([]int64, 0, len(entryIDs))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan held entry id: %w", err)
+		}
+		updated = append(updated, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read held entries: %w", err)
+	}
+
+	return updated, nil
+}
+
+// GetHeldEntries returns the IDs of the given entries that are under legal hold.
+func (r *SQLiteRepository) GetHeldEntries(ctx context.Context, dbID repo.ULID, entryIDs []int64) ([]int64, error) {
+	return r.getHeldEntries(ctx, r.DB, dbID, entryIDs)
+}
+
+// getHeldEntries is GetHeldEntries on a Queryer, so deletions can check the hold within their transaction.
+func (r *SQLiteRepository) getHeldEntries(ctx context.Context, q Queryer, dbID repo.ULID, entryIDs []int64) ([]int64, error) {
+	held := make([]int64, 0)
+	if len(entryIDs) == 0 {
+		return held, nil
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	query, args, err := r.Builder.Select("id").
+		From(tableName).
+		Where(squirrel.Eq{"id": entryIDs}).
+		Where(squirrel.Eq{"legal_hold": true}).
+		OrderBy("id").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build held entries query: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get held entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan held entry id: %w", err)
+		}
+		held = append(held, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read held entries: %w", err)
+	}
+
+	return held, nil
+}
+
+// CountHeldEntries counts the entries under legal hold whose timeField (timestamp, created_at or updated_at,
+// timestamp if empty) is up to before, all of them if before is zero.
+func (r *SQLiteRepository) CountHeldEntries(ctx context.Context, dbID repo.ULID, timeField string, before time.Time) (int64, error) {
+	opts := repo.QueryOptions{TimeField: timeField}
+	if err := opts.Validate(); err != nil {
+		return 0, err
+	}
+
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	builder := r.Builder.Select("COUNT(*)").
+		From(tableName).
+		Where(squirrel.Eq{"legal_hold": true})
+	if !before.IsZero() {
+		builder = builder.Where(squirrel.LtOrEq{opts.TimeField: before.UnixMilli()})
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build held entries count query: %w", err)
+	}
+
+	var count int64
+	if err := r.DB.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count held entries: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,87 @@
+package sqlite_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestLegalHold(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "hold_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	var ids []int64
+	for i := range 4 {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.Now().Add(-time.Duration(4-i) * time.Hour), MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	// 1. Holding reports the existing entries only
+	updated, err := r.SetLegalHold(ctx, db.ID, []int64{ids[0], ids[2], 999}, true)
+	if err != nil || !slices.Equal(updated, []int64{ids[0], ids[2]}) {
+		t.Fatalf("expected two held entries, got %v (%v)", updated, err)
+	}
+	if entry, err := r.GetEntry(ctx, db.ID, ids[0]); err != nil || !entry.LegalHold {
+		t.Errorf("expected the entry to be held, got %+v (%v)", entry, err)
+	}
+	if held, err := r.GetHeldEntries(ctx, db.ID, ids); err != nil || !slices.Equal(held, []int64{ids[0], ids[2]}) {
+		t.Errorf("expected the held entries, got %v (%v)", held, err)
+	}
+
+	// 2. Counting, with and without a timestamp bound
+	if count, err := r.CountHeldEntries(ctx, db.ID, "", time.Time{}); err != nil || count != 2 {
+		t.Errorf("expected 2 held entries, got %d (%v)", count, err)
+	}
+	if count, err := r.CountHeldEntries(ctx, db.ID, "", time.Now().Add(-150*time.Minute)); err != nil || count != 1 {
+		t.Errorf("expected 1 held entry older than 150 minutes, got %d (%v)", count, err)
+	}
+
+	// 3. The hold is searchable and can be excluded from listings
+	found, err := r.SearchEntries(ctx, db.ID, repo.SearchRequest{
+		Filter:     &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "legal_hold", Operator: "=", Value: true}}},
+		Pagination: repo.Pagination{Limit: 10},
+	}, nil)
+	if err != nil || len(found) != 2 {
+		t.Errorf("expected 2 entries searching legal_hold = true, got %d (%v)", len(found), err)
+	}
+	listed, err := r.GetEntries(ctx, db.ID, repo.QueryOptions{Limit: 10, ExcludeLegalHold: true})
+	if err != nil || len(listed) != 2 || listed[0].LegalHold || listed[1].LegalHold {
+		t.Errorf("expected the 2 entries without hold, got %+v (%v)", listed, err)
+	}
+
+	// 4. Releasing clears the flag
+	if updated, err := r.SetLegalHold(ctx, db.ID, ids, false); err != nil || len(updated) != 4 {
+		t.Errorf("expected all entries to be released, got %v (%v)", updated, err)
+	}
+	if count, err := r.CountHeldEntries(ctx, db.ID, "", time.Time{}); err != nil || count != 0 {
+		t.Errorf("expected no held entries, got %d (%v)", count, err)
+	}
+}
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -79,6 +80,62 @@ func (r *SQLiteRepository) GetLogs(ctx context.Context, opts repository.QueryOpt
 	}
 	defer rows.Close()
 
+	return scanAuditLogs(rows)
+}
+
+// GetDatabaseLogs retrieves a paginated list of the audit logs of a database and its entries, optionally
+// filtered by a time range and by actions (all if empty). Entry events are logged with the resource
+// "<database_id>:<entry_id>", database events with the database ID.
+func (r *SQLiteRepository) GetDatabaseLogs(ctx context.Context, dbID repository.ULID, actions []string, opts repository.QueryOptions) ([]repository.AuditLog, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	builder := r.Builder.Select("id", "timestamp", "action", "actor", "resource", "details").
+		From("audit_logs").
+		Where(squirrel.Or{
+			squirrel.Eq{"resource": dbID.String()},
+			// "<database_id>:" <= resource < "<database_id>;" matches the entries, using the resource index
+			squirrel.And{squirrel.GtOrEq{"resource": dbID.String() + ":"}, squirrel.Lt{"resource": dbID.String() + ";"}},
+		})
+
+	if len(actions) > 0 {
+		builder = builder.Where(squirrel.Eq{"action": actions})
+	}
+	if !opts.TStart.IsZero() && opts.TStart.After(time.Unix(0, 0)) {
+		builder = builder.Where(squirrel.GtOrEq{"timestamp": opts.TStart.UnixMilli()})
+	}
+	if !opts.TEnd.IsZero() && opts.TEnd.After(time.Unix(0, 0)) {
+		builder = builder.Where(squirrel.LtOrEq{"timestamp": opts.TEnd.UnixMilli()})
+	}
+
+	// The ID breaks ties between events of the same millisecond
+	if strings.ToLower(opts.Order) == "asc" {
+		builder = builder.OrderBy("timestamp ASC", "id ASC")
+	} else {
+		builder = builder.OrderBy("timestamp DESC", "id DESC")
+	}
+	builder = builder.Limit(uint64(opts.Limit))
+	if opts.Offset > 0 {
+		builder = builder.Offset(uint64(opts.Offset))
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get database logs query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAuditLogs(rows)
+}
+
+// scanAuditLogs reads audit_logs rows selected as id, timestamp, action, actor, resource, details.
+func scanAuditLogs(rows *sql.Rows) ([]repository.AuditLog, error) {
 	var logs []repository.AuditLog
 	var logTimestamp int64
 	for rows.Next() {
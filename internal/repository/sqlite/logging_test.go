@@ -0,0 +1,71 @@
+package sqlite_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+)
+
+func TestGetDatabaseLogs(t *testing.T) {
+	ctx := context.Background()
+	r, db := newDatabaseTestRepo(t)
+	other, err := r.CreateDatabase(ctx, repo.Database{Name: "other", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	logs := []repo.AuditLog{
+		{Action: "database.create", Actor: "admin", Resource: db.ID.String()},
+		{Action: "entry.post", Actor: "alice", Resource: db.ID.String() + ":1"},
+		{Action: "entry.download", Actor: "bob", Resource: db.ID.String() + ":1"},
+		{Action: "entry.post", Actor: "carol", Resource: other.ID.String() + ":1"},
+		{Action: "user.create", Actor: "admin", Resource: "alice"},
+		{Action: "entries.delete", Actor: "alice", Resource: db.ID.String(), Details: map[string]any{"count": 2}},
+	}
+	for _, log := range logs {
+		if err := r.LogAudit(ctx, log); err != nil {
+			t.Fatalf("failed to log: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	actionsOf := func(logs []repo.AuditLog) []string {
+		var actions []string
+		for _, log := range logs {
+			actions = append(actions, log.Action)
+		}
+		return actions
+	}
+
+	// 1. Only the events of the database and its entries, newest first
+	got, err := r.GetDatabaseLogs(ctx, db.ID, nil, repo.QueryOptions{})
+	if err != nil {
+		t.Fatalf("failed to get logs: %v", err)
+	}
+	if want := []string{"entries.delete", "entry.download", "entry.post", "database.create"}; !slices.Equal(actionsOf(got), want) {
+		t.Errorf("expected %v, got %v", want, actionsOf(got))
+	}
+	if got[0].Details["count"] != float64(2) {
+		t.Errorf("expected the details to be decoded, got %v", got[0].Details)
+	}
+
+	// 2. Filtered by action, oldest first and paginated
+	got, err = r.GetDatabaseLogs(ctx, db.ID, []string{"database.create", "entry.post", "entries.delete"}, repo.QueryOptions{Order: "asc", Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("failed to get logs: %v", err)
+	}
+	if want := []string{"entry.post", "entries.delete"}; !slices.Equal(actionsOf(got), want) {
+		t.Errorf("expected %v, got %v", want, actionsOf(got))
+	}
+
+	// 3. Filtered by time
+	got, err = r.GetDatabaseLogs(ctx, db.ID, nil, repo.QueryOptions{TStart: got[1].Timestamp})
+	if err != nil {
+		t.Fatalf("failed to get logs: %v", err)
+	}
+	if want := []string{"entries.delete"}; !slices.Equal(actionsOf(got), want) {
+		t.Errorf("expected %v, got %v", want, actionsOf(got))
+	}
+}
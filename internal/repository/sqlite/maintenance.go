@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"time"
+)
+
+// GetMaintenanceMode returns the persisted maintenance state, the zero value if it was never enabled.
+func (r *SQLiteRepository) GetMaintenanceMode(ctx context.Context) (repo.MaintenanceMode, error) {
+	query, args, err := r.Builder.Select("enabled", "message", "since").
+		From("maintenance_mode").
+		Where("id = 1").
+		ToSql()
+	if err != nil {
+		return repo.MaintenanceMode{}, fmt.Errorf("failed to build get maintenance mode query: %w", err)
+	}
+
+	var mode repo.MaintenanceMode
+	var sinceVal int64
+	err = r.DB.QueryRowContext(ctx, query, args...).Scan(&mode.Enabled, &mode.Message, &sinceVal)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repo.MaintenanceMode{}, nil
+	}
+	if err != nil {
+		return repo.MaintenanceMode{}, fmt.Errorf("failed to get maintenance mode: %w", err)
+	}
+	if sinceVal > 0 {
+		mode.Since = time.UnixMilli(sinceVal)
+	}
+	return mode, nil
+}
+
+// SetMaintenanceMode stores the maintenance state, replacing the previous one.
+func (r *SQLiteRepository) SetMaintenanceMode(ctx context.Context, mode repo.MaintenanceMode) error {
+	var sinceVal int64
+	if !mode.Since.IsZero() {
+		sinceVal = mode.Since.UnixMilli()
+	}
+	_, err := r.DB.ExecContext(ctx,
+		`INSERT INTO maintenance_mode (id, enabled, message, since) VALUES (1, ?, ?, ?)
+		 ON CONFLICT (id) DO UPDATE SET enabled = excluded.enabled, message = excluded.message, since = excluded.since`,
+		mode.Enabled, mode.Message, sinceVal,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store maintenance mode: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"path"
+	"strings"
 
 	// Adjust this import path to point to where your EmbedFS is located
 	"mediahub_oss/internal/repository/migrations"
@@ -48,3 +51,68 @@ func (r *SQLiteRepository) MigrateDown(ctx context.Context) error {
 	}
 	return nil
 }
+
+// PendingMigration describes a migration that has not been applied yet.
+type PendingMigration struct {
+	Version int64
+	Name    string // file name of the migration
+	Summary string // the description from the migration header
+}
+
+// PendingMigrations lists the migrations that MigrateUp would apply, in order.
+func (r *SQLiteRepository) PendingMigrations(ctx context.Context) ([]PendingMigration, error) {
+	goose.SetDialect("sqlite3")
+
+	current, err := goose.GetDBVersionContext(ctx, r.DB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database version: %w", err)
+	}
+
+	all, err := goose.CollectMigrations("sqlite", 0, goose.MaxVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+
+	var pending []PendingMigration
+	for _, m := range all {
+		if m.Version <= current {
+			continue
+		}
+		name := path.Base(m.Source)
+		summary := "Go migration (see source for details)"
+		if path.Ext(m.Source) == ".sql" {
+			summary = migrationSummary(m.Source)
+		}
+		pending = append(pending, PendingMigration{Version: m.Version, Name: name, Summary: summary})
+	}
+	return pending, nil
+}
+
+// MigrateUpByOne applies the next pending migration and returns its version.
+// It returns goose.ErrNoNextVersion once the database is up to date.
+func (r *SQLiteRepository) MigrateUpByOne(ctx context.Context) (int64, error) {
+	goose.SetDialect("sqlite3")
+
+	if err := goose.UpByOneContext(ctx, r.DB, "sqlite"); err != nil {
+		return 0, err
+	}
+	version, err := goose.GetDBVersionContext(ctx, r.DB)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database version: %w", err)
+	}
+	return version, nil
+}
+
+// migrationSummary reads the '-- Description:' header line of an embedded SQL migration.
+func migrationSummary(source string) string {
+	data, err := fs.ReadFile(migrations.EmbedFS, source)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if desc, ok := strings.CutPrefix(strings.TrimSpace(line), "-- Description:"); ok {
+			return strings.TrimSpace(desc)
+		}
+	}
+	return ""
+}
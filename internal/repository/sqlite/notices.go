@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var noticeColumns = []string{
+	"id", "dedup_key", "source", "severity", "message", "database_id", "entry_id",
+	"count", "first_seen", "last_seen", "acknowledged_at", "acknowledged_by",
+}
+
+// RecordNotice adds a notice, or counts a repetition of the unacknowledged notice with the same key. The
+// message, severity and references of a repetition replace the stored ones.
+func (r *SQLiteRepository) RecordNotice(ctx context.Context, notice repo.ServerNotice) (repo.ServerNotice, error) {
+	if notice.DedupKey == "" || notice.Source == "" {
+		return repo.ServerNotice{}, fmt.Errorf("%w: a notice needs a key and a source", customerrors.ErrValidation)
+	}
+	if notice.LastSeen.IsZero() {
+		notice.LastSeen = time.Now()
+	}
+
+	var dbID, entryID any
+	if notice.DatabaseID != "" {
+		dbID = notice.DatabaseID.String()
+	}
+	if notice.EntryID != 0 {
+		entryID = notice.EntryID
+	}
+	seen := notice.LastSeen.UnixMilli()
+
+	// The conflict target names the partial index, acknowledged notices never conflict
+	query, args, err := r.Builder.Insert("server_notices").
+		Columns("dedup_key", "source", "severity", "message", "database_id", "entry_id", "count", "first_seen", "last_seen").
+		Values(notice.DedupKey, notice.Source, notice.Severity, notice.Message, dbID, entryID, 1, seen, seen).
+		Suffix(`ON CONFLICT (dedup_key) WHERE acknowledged_at IS NULL DO UPDATE SET
+			count = count + 1, last_seen = MAX(last_seen, excluded.last_seen), source = excluded.source, severity = excluded.severity,
+			message = excluded.message, database_id = excluded.database_id, entry_id = excluded.entry_id
+			RETURNING ` + strings.Join(noticeColumns, ", ")).
+		ToSql()
+	if err != nil {
+		return repo.ServerNotice{}, fmt.Errorf("failed to build record notice query: %w", err)
+	}
+
+	stored, err := scanNotice(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return repo.ServerNotice{}, fmt.Errorf("failed to record notice: %w", err)
+	}
+	return stored, nil
+}
+
+// GetNotices returns the notices matching the filter, the latest repetition first.
+func (r *SQLiteRepository) GetNotices(ctx context.Context, filter repo.NoticeFilter) ([]repo.ServerNotice, error) {
+	builder := r.Builder.Select(noticeColumns...).
+		From("server_notices").
+		OrderBy("last_seen DESC", "id DESC")
+	if filter.Unacknowledged {
+		builder = builder.Where(squirrel.Eq{"acknowledged_at": nil})
+	}
+	if filter.Limit > 0 {
+		builder = builder.Limit(uint64(filter.Limit))
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get notices query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notices: %w", err)
+	}
+	defer rows.Close()
+
+	notices := []repo.ServerNotice{}
+	for rows.Next() {
+		notice, err := scanNotice(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notice: %w", err)
+		}
+		notices = append(notices, notice)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read notices: %w", err)
+	}
+	return notices, nil
+}
+
+// CountUnacknowledgedNotices returns the number of open notices.
+func (r *SQLiteRepository) CountUnacknowledgedNotices(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM server_notices WHERE acknowledged_at IS NULL").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count notices: %w", err)
+	}
+	return count, nil
+}
+
+// AcknowledgeNotices marks the open notices with the IDs, or all open notices if ids is empty, as acknowledged
+// by the user. Already acknowledged and unknown IDs are skipped.
+func (r *SQLiteRepository) AcknowledgeNotices(ctx context.Context, ids []int64, username string) (int64, error) {
+	where := squirrel.And{squirrel.Eq{"acknowledged_at": nil}}
+	if len(ids) > 0 {
+		where = append(where, squirrel.Eq{"id": ids})
+	}
+	query, args, err := r.Builder.Update("server_notices").
+		Set("acknowledged_at", time.Now().UnixMilli()).
+		Set("acknowledged_by", username).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build acknowledge notices query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acknowledge notices: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// DeleteNotices removes the notices, acknowledged or not, whose latest repetition is older than maxAge.
+func (r *SQLiteRepository) DeleteNotices(ctx context.Context, maxAge time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-maxAge).UnixMilli()
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM server_notices WHERE last_seen < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old notices: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func scanNotice(row interface{ Scan(dest ...any) error }) (repo.ServerNotice, error) {
+	var notice repo.ServerNotice
+	var dbID, acknowledgedBy sql.NullString
+	var entryID, acknowledgedAt sql.NullInt64
+	var firstSeen, lastSeen int64
+
+	err := row.Scan(
+		&notice.ID, &notice.DedupKey, &notice.Source, &notice.Severity, &notice.Message, &dbID, &entryID,
+		&notice.Count, &firstSeen, &lastSeen, &acknowledgedAt, &acknowledgedBy,
+	)
+	if err != nil {
+		return repo.ServerNotice{}, err
+	}
+
+	notice.DatabaseID = repo.ULID(dbID.String)
+	notice.EntryID = entryID.Int64
+	notice.FirstSeen = time.UnixMilli(firstSeen)
+	notice.LastSeen = time.UnixMilli(lastSeen)
+	if acknowledgedAt.Valid {
+		notice.AcknowledgedAt = time.UnixMilli(acknowledgedAt.Int64)
+	}
+	notice.AcknowledgedBy = acknowledgedBy.String
+	return notice, nil
+}
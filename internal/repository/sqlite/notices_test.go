@@ -0,0 +1,96 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestServerNotices(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	start := time.Now().Add(-time.Hour)
+	record := func(key, message string, at time.Time) repo.ServerNotice {
+		t.Helper()
+		notice, err := r.RecordNotice(ctx, repo.ServerNotice{DedupKey: key, Source: "housekeeping", Severity: repo.NoticeSeverityError, Message: message, LastSeen: at})
+		if err != nil {
+			t.Fatalf("failed to record notice: %v", err)
+		}
+		return notice
+	}
+
+	// 1. Repetitions of an open notice are counted, the latest message is kept
+	first := record("housekeeping:run:a", "failed once", start)
+	again := record("housekeeping:run:a", "failed again", start.Add(time.Minute))
+	other := record("housekeeping:run:b", "other database", start.Add(2*time.Minute))
+	if again.ID != first.ID || again.Count != 2 || again.Message != "failed again" {
+		t.Errorf("expected the repetition to be counted on notice %d, got %+v", first.ID, again)
+	}
+	if !again.FirstSeen.Equal(first.FirstSeen) || !again.LastSeen.After(again.FirstSeen) {
+		t.Errorf("expected the first and latest occurrence, got %v and %v", again.FirstSeen, again.LastSeen)
+	}
+	if other.ID == first.ID {
+		t.Errorf("expected a separate notice for another key")
+	}
+
+	// 2. Acknowledged notices are kept, a repetition opens a new notice
+	if n, err := r.AcknowledgeNotices(ctx, []int64{first.ID, 999}, "admin"); err != nil || n != 1 {
+		t.Fatalf("expected 1 acknowledged notice, got %d (%v)", n, err)
+	}
+	reopened := record("housekeeping:run:a", "failed after the acknowledgement", start.Add(3*time.Minute))
+	if reopened.ID == first.ID || reopened.Count != 1 {
+		t.Errorf("expected a new notice after the acknowledgement, got %+v", reopened)
+	}
+	if count, err := r.CountUnacknowledgedNotices(ctx); err != nil || count != 2 {
+		t.Errorf("expected 2 open notices, got %d (%v)", count, err)
+	}
+
+	all, err := r.GetNotices(ctx, repo.NoticeFilter{})
+	if err != nil {
+		t.Fatalf("failed to get notices: %v", err)
+	}
+	if len(all) != 3 || all[0].ID != reopened.ID || all[2].ID != first.ID {
+		t.Fatalf("expected 3 notices, the latest first, got %+v", all)
+	}
+	if all[2].AcknowledgedBy != "admin" || all[2].AcknowledgedAt.IsZero() {
+		t.Errorf("expected the acknowledgement to be stored, got %+v", all[2])
+	}
+	open, err := r.GetNotices(ctx, repo.NoticeFilter{Unacknowledged: true, Limit: 1})
+	if err != nil || len(open) != 1 || open[0].ID != reopened.ID {
+		t.Errorf("expected the latest open notice, got %+v (%v)", open, err)
+	}
+
+	// 3. Without IDs all open notices are acknowledged
+	if n, err := r.AcknowledgeNotices(ctx, nil, "admin"); err != nil || n != 2 {
+		t.Errorf("expected 2 acknowledged notices, got %d (%v)", n, err)
+	}
+
+	// 4. Notices not repeated within the retention are deleted
+	if n, err := r.DeleteNotices(ctx, time.Hour-150*time.Second); err != nil || n != 2 {
+		t.Errorf("expected 2 deleted notices, got %d (%v)", n, err)
+	}
+	if rest, _ := r.GetNotices(ctx, repo.NoticeFilter{}); len(rest) != 1 || rest[0].ID != reopened.ID {
+		t.Errorf("expected only the latest notice to remain, got %+v", rest)
+	}
+}
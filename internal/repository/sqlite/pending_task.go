@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var pendingTaskColumns = []string{
+	"id", "database_id", "entry_id", "task_type",
+	"attempts", "next_attempt_at", "last_error", "created_at",
+}
+
+// GetDuePendingTasks returns the tasks whose next attempt is due, oldest first.
+func (r *SQLiteRepository) GetDuePendingTasks(ctx context.Context, limit int) ([]repo.PendingTask, error) {
+	query, args, err := r.Builder.Select(pendingTaskColumns...).
+		From("pending_tasks").
+		Where(squirrel.LtOrEq{"next_attempt_at": time.Now().UnixMilli()}).
+		OrderBy("next_attempt_at ASC", "id ASC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get due pending_tasks query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get due pending_tasks query: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []repo.PendingTask
+	for rows.Next() {
+		var t repo.PendingTask
+		var dbID string
+		var nextAttemptAt, createdAt int64
+		if err := rows.Scan(&t.ID, &dbID, &t.EntryID, &t.Type, &t.Attempts, &nextAttemptAt, &t.LastError, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending_task: %w", err)
+		}
+		t.DatabaseID = repo.ULID(dbID)
+		t.NextAttemptAt = time.UnixMilli(nextAttemptAt)
+		t.CreatedAt = time.UnixMilli(createdAt)
+		tasks = append(tasks, t)
+	}
+
+	return tasks, rows.Err()
+}
+
+// ClaimPendingTask postpones a due task by the lease, so no other runner picks it up meanwhile.
+// It returns false if the task is gone or not due anymore.
+func (r *SQLiteRepository) ClaimPendingTask(ctx context.Context, id int64, lease time.Duration) (bool, error) {
+	now := time.Now()
+	query, args, err := r.Builder.Update("pending_tasks").
+		Set("next_attempt_at", now.Add(lease).UnixMilli()).
+		Where(squirrel.Eq{"id": id}).
+		Where(squirrel.LtOrEq{"next_attempt_at": now.UnixMilli()}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build claim pending_task query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim pending_task: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// CompletePendingTask removes a finished task.
+func (r *SQLiteRepository) CompletePendingTask(ctx context.Context, id int64) error {
+	query, args, err := r.Builder.Delete("pending_tasks").
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete pending_task query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete pending_task: %w", err)
+	}
+	return nil
+}
+
+// RetryPendingTask records a failed attempt and schedules the next one after the backoff.
+func (r *SQLiteRepository) RetryPendingTask(ctx context.Context, id int64, backoff time.Duration, lastError string) error {
+	query, args, err := r.Builder.Update("pending_tasks").
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Set("next_attempt_at", time.Now().Add(backoff).UnixMilli()).
+		Set("last_error", lastError).
+		Where(squirrel.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build retry pending_task query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update pending_task: %w", err)
+	}
+	return nil
+}
+
+// ReleasePendingTasks makes all tasks due immediately. After a restart, no task can still be running.
+func (r *SQLiteRepository) ReleasePendingTasks(ctx context.Context) (int64, error) {
+	now := time.Now().UnixMilli()
+	query, args, err := r.Builder.Update("pending_tasks").
+		Set("next_attempt_at", now).
+		Where(squirrel.Gt{"next_attempt_at": now}).
+		ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build release pending_tasks query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release pending_tasks: %w", err)
+	}
+	return res.RowsAffected()
+}
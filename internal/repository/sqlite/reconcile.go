@@ -0,0 +1,322 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	repo "mediahub_oss/internal/repository"
+)
+
+// reconcileExpectedTable is the temporary table created from the current schema of an entry table, so
+// SQLite reports its columns the same way as those of the existing table.
+const reconcileExpectedTable = "reconcile_expected"
+
+// columnDef is a column as reported by pragma_table_info.
+type columnDef struct {
+	Type    string
+	NotNull bool
+	Default sql.NullString
+	PK      bool
+}
+
+func (c columnDef) String() string {
+	def := c.Type
+	if c.PK {
+		def += " PRIMARY KEY"
+	}
+	if c.NotNull {
+		def += " NOT NULL"
+	}
+	if c.Default.Valid {
+		def += " DEFAULT " + c.Default.String
+	}
+	return strings.TrimSpace(def)
+}
+
+// entryTableDiff is the comparison of an entry table with the current schema.
+type entryTableDiff struct {
+	differences []string
+	columns     []string // columns of both tables, copied by a rebuild
+	blocked     string   // why a rebuild would lose data, empty if it is safe
+}
+
+// ReconcileEntryTables compares the entry table of every database with the table BuildDynamicTableSchema
+// creates today: the column definitions and the CHECK constraints, e.g. the allowed statuses or the mime
+// types of tables created by older versions. Differing tables are rebuilt with SQLite's table rebuild
+// procedure (create, copy, drop, rename, recreate indexes and triggers), in a transaction per database.
+// Tables with columns the schema does not know are reported but not rebuilt, as their data would be lost.
+// With dryRun, the differences are only reported.
+func (r *SQLiteRepository) ReconcileEntryTables(ctx context.Context, dryRun bool) ([]repo.EntryTableReconciliation, error) {
+	databases, err := r.GetDatabases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []repo.EntryTableReconciliation
+	for _, db := range databases {
+		ddl, err := r.BuildDynamicTableSchema(db.ID.String(), db.ContentType, db.CustomFields)
+		if err != nil {
+			return results, fmt.Errorf("failed to build the schema of database %s: %w", db.Name, err)
+		}
+		diff, err := r.diffEntryTable(ctx, db.ID.String(), ddl)
+		if err != nil {
+			return results, fmt.Errorf("failed to compare the entry table of database %s: %w", db.Name, err)
+		}
+		if len(diff.differences) == 0 {
+			continue
+		}
+
+		result := repo.EntryTableReconciliation{DatabaseID: db.ID, DatabaseName: db.Name, Differences: diff.differences}
+		switch {
+		case diff.blocked != "":
+			result.Error = diff.blocked
+		case !dryRun:
+			if err := r.rebuildEntryTable(ctx, db.ID.String(), ddl, diff.columns); err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Rebuilt = true
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// diffEntryTable compares the entry table of a database with its current schema ddl.
+func (r *SQLiteRepository) diffEntryTable(ctx context.Context, dbID string, ddl string) (entryTableDiff, error) {
+	var diff entryTableDiff
+	table := fmt.Sprintf("entries_%s", dbID)
+
+	var actualDDL string
+	err := r.DB.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&actualDDL)
+	if errors.Is(err, sql.ErrNoRows) {
+		diff.differences = append(diff.differences, "the entry table is missing")
+		diff.blocked = "the entry table is missing, it cannot be rebuilt"
+		return diff, nil
+	} else if err != nil {
+		return diff, err
+	}
+
+	// 1. Let SQLite parse the current schema into a temporary table. Without AUTOINCREMENT, as it would
+	// create a temp.sqlite_sequence that hides the one of the main schema.
+	expectedDDL := strings.Replace(ddl, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s"`, table), fmt.Sprintf(`CREATE TEMP TABLE "%s"`, reconcileExpectedTable), 1)
+	expectedDDL = strings.Replace(expectedDDL, " AUTOINCREMENT", "", 1)
+	if _, err := r.DB.ExecContext(ctx, expectedDDL); err != nil {
+		return diff, fmt.Errorf("failed to create the expected table: %w", err)
+	}
+	expectedOrder, expected, err := tableColumns(ctx, r.DB, "temp", reconcileExpectedTable)
+	if _, dropErr := r.DB.ExecContext(ctx, fmt.Sprintf(`DROP TABLE temp."%s"`, reconcileExpectedTable)); err == nil && dropErr != nil {
+		err = dropErr
+	}
+	if err != nil {
+		return diff, err
+	}
+	actualOrder, actual, err := tableColumns(ctx, r.DB, "main", table)
+	if err != nil {
+		return diff, err
+	}
+
+	// 2. Columns, a missing column is filled with its default by the rebuild
+	var unknown []string
+	for _, name := range expectedOrder {
+		want := expected[name]
+		got, ok := actual[name]
+		switch {
+		case !ok:
+			diff.differences = append(diff.differences, fmt.Sprintf("missing column %s %s", name, want))
+			if want.NotNull && !want.Default.Valid {
+				unknown = append(unknown, name)
+			}
+		case got.String() != want.String():
+			diff.differences = append(diff.differences, fmt.Sprintf("column %s is %s, expected %s", name, got, want))
+			diff.columns = append(diff.columns, name)
+		default:
+			diff.columns = append(diff.columns, name)
+		}
+	}
+	for _, name := range actualOrder {
+		if _, ok := expected[name]; !ok {
+			diff.differences = append(diff.differences, fmt.Sprintf("unexpected column %s %s", name, actual[name]))
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		diff.blocked = fmt.Sprintf("not rebuilt, the data of the columns %s cannot be carried over", strings.Join(unknown, ", "))
+	}
+
+	// 3. CHECK constraints
+	wantChecks, gotChecks := checkConstraints(ddl), checkConstraints(actualDDL)
+	for _, check := range gotChecks {
+		if !slices.Contains(wantChecks, check) {
+			diff.differences = append(diff.differences, fmt.Sprintf("constraint %s is no longer used", check))
+		}
+	}
+	for _, check := range wantChecks {
+		if !slices.Contains(gotChecks, check) {
+			diff.differences = append(diff.differences, fmt.Sprintf("missing constraint %s", check))
+		}
+	}
+	return diff, nil
+}
+
+// tableColumns returns the column names in table order and their definitions.
+func tableColumns(ctx context.Context, q Queryer, schema, table string) ([]string, map[string]columnDef, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?, ?)`, table, schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var order []string
+	columns := make(map[string]columnDef)
+	for rows.Next() {
+		var name string
+		var pk int
+		var col columnDef
+		if err := rows.Scan(&name, &col.Type, &col.NotNull, &col.Default, &pk); err != nil {
+			return nil, nil, err
+		}
+		col.Type = strings.ToUpper(col.Type)
+		col.PK = pk > 0
+		order = append(order, name)
+		columns[name] = col
+	}
+	return order, columns, rows.Err()
+}
+
+// checkConstraints extracts the CHECK constraints of a CREATE TABLE statement, with whitespace normalized.
+func checkConstraints(ddl string) []string {
+	var checks []string
+	upper := strings.ToUpper(ddl)
+	for i := 0; i < len(ddl); {
+		idx := strings.Index(upper[i:], "CHECK")
+		if idx < 0 {
+			break
+		}
+		start := i + idx
+		i = start + len("CHECK")
+		if start > 0 && isIdentifierChar(ddl[start-1]) {
+			continue
+		}
+
+		open := i
+		for open < len(ddl) && (ddl[open] == ' ' || ddl[open] == '\t' || ddl[open] == '\n' || ddl[open] == '\r') {
+			open++
+		}
+		if open >= len(ddl) || ddl[open] != '(' {
+			continue
+		}
+
+		// The expression ends with the matching parenthesis, parentheses in string literals are skipped
+		depth, inString, end := 0, false, -1
+		for j := open; j < len(ddl) && end < 0; j++ {
+			switch c := ddl[j]; {
+			case c == '\'':
+				inString = !inString
+			case inString:
+			case c == '(':
+				depth++
+			case c == ')':
+				depth--
+				if depth == 0 {
+					end = j
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+		checks = append(checks, "CHECK("+strings.Join(strings.Fields(ddl[open+1:end]), " ")+")")
+		i = end + 1
+	}
+	return checks
+}
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '"' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// rebuildEntryTable replaces the entry table of a database by a table created from ddl, following the
+// rebuild procedure of https://www.sqlite.org/lang_altertable.html. The indexes and triggers (e.g. of the
+// fulltext search) are recreated and the AUTOINCREMENT sequence is kept, so deleted IDs are not reused.
+// No foreign keys reference the entry tables, so they stay enabled.
+func (r *SQLiteRepository) rebuildEntryTable(ctx context.Context, dbID string, ddl string, columns []string) error {
+	table := fmt.Sprintf("entries_%s", dbID)
+	rebuilt := table + "_rebuild"
+
+	return r.WithTx(ctx, func(tx *sql.Tx) error {
+		// 1. Remember the indexes and triggers, they are dropped with the table
+		rows, err := tx.QueryContext(ctx, `SELECT sql FROM sqlite_master WHERE tbl_name = ? AND type IN ('index', 'trigger') AND sql IS NOT NULL ORDER BY type`, table)
+		if err != nil {
+			return fmt.Errorf("failed to read indexes and triggers: %w", err)
+		}
+		var recreate []string
+		for rows.Next() {
+			var stmt string
+			if err := rows.Scan(&stmt); err != nil {
+				rows.Close()
+				return err
+			}
+			recreate = append(recreate, stmt)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var seq sql.NullInt64
+		if err := tx.QueryRowContext(ctx, `SELECT seq FROM main.sqlite_sequence WHERE name = ?`, table).Scan(&seq); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to read the ID sequence: %w", err)
+		}
+		var before int64
+		if err := tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, table)).Scan(&before); err != nil {
+			return fmt.Errorf("failed to count entries: %w", err)
+		}
+
+		// 2. Create the new table and copy the rows
+		createSQL := strings.Replace(ddl, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s"`, table), fmt.Sprintf(`CREATE TABLE "%s"`, rebuilt), 1)
+		if _, err := tx.ExecContext(ctx, createSQL); err != nil {
+			return fmt.Errorf("failed to create the new table: %w", err)
+		}
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = fmt.Sprintf(`"%s"`, col)
+		}
+		columnList := strings.Join(quoted, ", ")
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO "%s" (%s) SELECT %s FROM "%s"`, rebuilt, columnList, columnList, table)); err != nil {
+			return fmt.Errorf("failed to copy the entries: %w", err)
+		}
+
+		// 3. Replace the old table
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE "%s"`, table)); err != nil {
+			return fmt.Errorf("failed to drop the old table: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE "%s" RENAME TO "%s"`, rebuilt, table)); err != nil {
+			return fmt.Errorf("failed to rename the new table: %w", err)
+		}
+		for _, stmt := range recreate {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to recreate %q: %w", stmt, err)
+			}
+		}
+		if seq.Valid {
+			if _, err := tx.ExecContext(ctx, `UPDATE main.sqlite_sequence SET seq = MAX(seq, ?) WHERE name = ?`, seq.Int64, table); err != nil {
+				return fmt.Errorf("failed to restore the ID sequence: %w", err)
+			}
+		}
+
+		// 4. All entries must have been carried over
+		var after int64
+		if err := tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, table)).Scan(&after); err != nil {
+			return fmt.Errorf("failed to count entries: %w", err)
+		}
+		if after != before {
+			return fmt.Errorf("copied %d of %d entries", after, before)
+		}
+		return nil
+	})
+}
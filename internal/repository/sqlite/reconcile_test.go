@@ -0,0 +1,133 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestReconcileEntryTables(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "audio", ContentType: "audio"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	dbID := db.ID.String()
+	table := fmt.Sprintf(`"entries_%s"`, dbID)
+
+	// 1. Replace the table by one with the mime type constraint of an older version
+	ddl, err := r.BuildDynamicTableSchema(dbID, db.ContentType, db.CustomFields)
+	if err != nil {
+		t.Fatalf("failed to build schema: %v", err)
+	}
+	oldDDL := strings.Replace(ddl, "mime_type TEXT NOT NULL", "mime_type TEXT NOT NULL CHECK(mime_type IN ('audio/mpeg', 'audio/wav'))", 1)
+	if _, err := r.DB.ExecContext(ctx, "DROP TABLE "+table); err != nil {
+		t.Fatalf("failed to drop table: %v", err)
+	}
+	if _, err := r.DB.ExecContext(ctx, oldDDL); err != nil {
+		t.Fatalf("failed to create the old table: %v", err)
+	}
+	for _, stmt := range sqlite.BuildIndexesSQL(dbID, db.CustomFields) {
+		if _, err := r.DB.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("failed to create index: %v", err)
+		}
+	}
+
+	create := func(mimeType string) (repo.Entry, error) {
+		return r.CreateEntry(ctx, db, repo.Entry{
+			FileName:    "a.mp3",
+			Timestamp:   time.Now(),
+			Status:      repo.EntryStatusReady,
+			MimeType:    mimeType,
+			Size:        42,
+			MediaFields: map[string]any{"duration": 1.5, "channels": uint8(2)},
+		})
+	}
+	kept, err := create("audio/mpeg")
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	deleted, err := create("audio/wav")
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if _, err := r.DB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", table), deleted.ID); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if _, err := create("audio/flac"); err == nil {
+		t.Fatal("expected the old constraint to reject audio/flac")
+	}
+
+	// 2. A dry run only reports the constraint
+	results, err := r.ReconcileEntryTables(ctx, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Rebuilt || len(results[0].Differences) != 1 || !strings.Contains(results[0].Differences[0], "mime_type IN") {
+		t.Fatalf("expected the mime type constraint to be reported, got %+v", results)
+	}
+	if _, err := create("audio/flac"); err == nil {
+		t.Fatal("expected the dry run to leave the table unchanged")
+	}
+
+	// 3. The rebuild drops the constraint and keeps the entries, indexes and ID sequence
+	results, err = r.ReconcileEntryTables(ctx, false)
+	if err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if len(results) != 1 || !results[0].Rebuilt || results[0].Error != "" {
+		t.Fatalf("expected the table to be rebuilt, got %+v", results)
+	}
+	flac, err := create("audio/flac")
+	if err != nil {
+		t.Fatalf("expected audio/flac to be accepted after the rebuild: %v", err)
+	}
+	if flac.ID <= deleted.ID {
+		t.Errorf("expected the ID sequence to be kept, got ID %d after deleted ID %d", flac.ID, deleted.ID)
+	}
+	got, err := r.GetEntry(ctx, db.ID, kept.ID)
+	if err != nil {
+		t.Fatalf("failed to get the existing entry: %v", err)
+	}
+	if got.MimeType != "audio/mpeg" || got.Size != 42 || got.FileName != "a.mp3" {
+		t.Errorf("expected the existing entry to be intact, got %+v", got)
+	}
+
+	var indexes int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL`, "entries_"+dbID).Scan(&indexes); err != nil {
+		t.Fatalf("failed to count indexes: %v", err)
+	}
+	if want := len(sqlite.BuildIndexesSQL(dbID, db.CustomFields)); indexes != want {
+		t.Errorf("expected %d indexes after the rebuild, got %d", want, indexes)
+	}
+
+	// 4. The table is now up to date
+	results, err = r.ReconcileEntryTables(ctx, false)
+	if err != nil || len(results) != 0 {
+		t.Errorf("expected no differences after the rebuild, got %+v, %v", results, err)
+	}
+}
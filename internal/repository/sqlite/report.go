@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	repo "mediahub_oss/internal/repository"
+)
+
+// largestEntriesBatchSize keeps the UNION below SQLite's limit of 500 compound SELECT terms.
+const largestEntriesBatchSize = 100
+
+// GetLargestEntries returns the largest entries across all databases. Every entry table contributes
+// its own top rows (ORDER BY filesize DESC LIMIT n), which are merged with UNION ALL.
+func (r *SQLiteRepository) GetLargestEntries(ctx context.Context, limit int) ([]repo.LargestEntry, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	dbIDs, err := r.getDatabaseIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var largest []repo.LargestEntry
+	for batch := range slices.Chunk(dbIDs, largestEntriesBatchSize) {
+		entries, err := r.queryLargestEntries(ctx, batch, limit)
+		if err != nil {
+			return nil, err
+		}
+		largest = append(largest, entries...)
+	}
+
+	// Merge the batches, the query already sorted each of them
+	slices.SortStableFunc(largest, func(a, b repo.LargestEntry) int {
+		switch {
+		case a.Size > b.Size:
+			return -1
+		case a.Size < b.Size:
+			return 1
+		}
+		return 0
+	})
+	if len(largest) > limit {
+		largest = largest[:limit]
+	}
+	return largest, nil
+}
+
+func (r *SQLiteRepository) queryLargestEntries(ctx context.Context, dbIDs []string, limit int) ([]repo.LargestEntry, error) {
+	if len(dbIDs) == 0 {
+		return nil, nil
+	}
+
+	parts := make([]string, 0, len(dbIDs))
+	args := make([]any, 0, 2*len(dbIDs)+1)
+	for _, dbID := range dbIDs {
+		parts = append(parts, fmt.Sprintf(
+			`SELECT * FROM (SELECT ? AS database_id, id, filename, filesize, preview_filesize, mime_type FROM "entries_%s" ORDER BY filesize DESC LIMIT ?)`,
+			dbID))
+		args = append(args, dbID, limit)
+	}
+	query := strings.Join(parts, " UNION ALL ") + " ORDER BY filesize DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query largest entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []repo.LargestEntry
+	for rows.Next() {
+		var e repo.LargestEntry
+		var dbID string
+		if err := rows.Scan(&dbID, &e.ID, &e.FileName, &e.Size, &e.PreviewSize, &e.MimeType); err != nil {
+			return nil, fmt.Errorf("failed to scan largest entry: %w", err)
+		}
+		e.DatabaseID = repo.ULID(dbID)
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// getDatabaseIDs returns the IDs of all databases, i.e. the suffixes of the entry tables.
+func (r *SQLiteRepository) getDatabaseIDs(ctx context.Context) ([]string, error) {
+	query, args, err := r.Builder.Select("id").From("databases").OrderBy("id").ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select database ids query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database ids: %w", err)
+	}
+	defer rows.Close()
+
+	var dbIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan database id: %w", err)
+		}
+		dbIDs = append(dbIDs, id)
+	}
+
+	return dbIDs, rows.Err()
+}
@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var retainedUploadColumns = []string{"database_id", "entry_id", "path", "created_at", "expires_at"}
+
+// SaveRetainedUpload records the source file of a failed upload. A previous record of the same entry is replaced.
+func (r *SQLiteRepository) SaveRetainedUpload(ctx context.Context, upload repo.RetainedUpload) error {
+	if upload.CreatedAt.IsZero() {
+		upload.CreatedAt = time.Now()
+	}
+
+	query, args, err := r.Builder.Insert("retained_uploads").
+		Columns(retainedUploadColumns...).
+		Values(upload.DatabaseID.String(), upload.EntryID, upload.Path, upload.CreatedAt.UnixMilli(), upload.ExpiresAt.UnixMilli()).
+		Suffix("ON CONFLICT (database_id, entry_id) DO UPDATE SET path = excluded.path, created_at = excluded.created_at, expires_at = excluded.expires_at").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert retained_upload query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert retained_upload: %w", err)
+	}
+	return nil
+}
+
+// GetRetainedUpload retrieves the retained source of an entry, if its grace period has not passed yet.
+func (r *SQLiteRepository) GetRetainedUpload(ctx context.Context, dbID repo.ULID, entryID int64) (repo.RetainedUpload, error) {
+	query, args, err := r.Builder.Select(retainedUploadColumns...).
+		From("retained_uploads").
+		Where(squirrel.Eq{"database_id": dbID.String(), "entry_id": entryID}).
+		Where(squirrel.GtOrEq{"expires_at": time.Now().UnixMilli()}).
+		ToSql()
+	if err != nil {
+		return repo.RetainedUpload{}, fmt.Errorf("failed to build get retained_upload query: %w", err)
+	}
+
+	u, err := scanRetainedUpload(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.RetainedUpload{}, customerrors.ErrNotFound
+		}
+		return repo.RetainedUpload{}, fmt.Errorf("failed to execute get retained_upload query: %w", err)
+	}
+
+	return u, nil
+}
+
+// DeleteRetainedUpload removes the record of a retained source. The file itself is left to the caller.
+func (r *SQLiteRepository) DeleteRetainedUpload(ctx context.Context, dbID repo.ULID, entryID int64) error {
+	query, args, err := r.Builder.Delete("retained_uploads").
+		Where(squirrel.Eq{"database_id": dbID.String(), "entry_id": entryID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete retained_upload query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete retained_upload: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredRetainedUploads removes all records whose grace period has passed and returns them.
+func (r *SQLiteRepository) DeleteExpiredRetainedUploads(ctx context.Context) ([]repo.RetainedUpload, error) {
+	query, args, err := r.Builder.Delete("retained_uploads").
+		Where("expires_at < ?", time.Now().UnixMilli()).
+		Suffix("RETURNING database_id, entry_id, path, created_at, expires_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build delete expired retained_uploads query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired retained_uploads: %w", err)
+	}
+	defer rows.Close()
+
+	var uploads []repo.RetainedUpload
+	for rows.Next() {
+		u, err := scanRetainedUpload(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan retained_upload: %w", err)
+		}
+		uploads = append(uploads, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read expired retained_uploads: %w", err)
+	}
+
+	return uploads, nil
+}
+
+func scanRetainedUpload(row interface{ Scan(dest ...any) error }) (repo.RetainedUpload, error) {
+	var u repo.RetainedUpload
+	var dbIDStr string
+	var createdAtVal, expiresAtVal int64
+
+	if err := row.Scan(&dbIDStr, &u.EntryID, &u.Path, &createdAtVal, &expiresAtVal); err != nil {
+		return repo.RetainedUpload{}, err
+	}
+
+	u.DatabaseID = repo.ULID(dbIDStr)
+	u.CreatedAt = time.UnixMilli(createdAtVal)
+	u.ExpiresAt = time.UnixMilli(expiresAtVal)
+
+	return u, nil
+}
@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mediahub_oss/internal/shared/customerrors"
+	"time"
+)
+
+// GetSetting returns the value of a setting, ErrNotFound if it was never stored.
+func (r *SQLiteRepository) GetSetting(ctx context.Context, key string) (string, error) {
+	query, args, err := r.Builder.Select("value").
+		From("settings").
+		Where("key = ?", key).
+		ToSql()
+	if err != nil {
+		return "", fmt.Errorf("failed to build get setting query: %w", err)
+	}
+
+	var value string
+	err = r.DB.QueryRowContext(ctx, query, args...).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", customerrors.ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetSetting stores the value of a setting, replacing the previous one.
+func (r *SQLiteRepository) SetSetting(ctx context.Context, key string, value string) error {
+	_, err := r.DB.ExecContext(ctx,
+		`INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		key, value, time.Now().UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store setting %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var shareLinkColumns = []string{
+	"id", "token_hash", "database_id", "entry_id",
+	"preview_only", "max_downloads", "download_count",
+	"created_by", "created_at", "expires_at",
+}
+
+// createShareLinksTrigger creates the trigger on the entries table of a database that deletes the
+// share links of deleted entries.
+func createShareLinksTrigger(ctx context.Context, tx *sql.Tx, dbID string) error {
+	stmt := fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS "entries_%s_share_links_ad" AFTER DELETE ON "entries_%s" BEGIN DELETE FROM share_links WHERE database_id = '%s' AND entry_id = old.id; END`, dbID, dbID, dbID)
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create share links trigger: %w", err)
+	}
+	return nil
+}
+
+// CreateShareLink stores a new share link in the SQLite database.
+func (r *SQLiteRepository) CreateShareLink(ctx context.Context, link repo.ShareLink) (repo.ShareLink, error) {
+	if link.ID == "" {
+		link.ID = repo.ULID(shared.GenerateULID())
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+
+	query, args, err := r.Builder.Insert("share_links").
+		Columns(shareLinkColumns...).
+		Values(
+			link.ID.String(), link.TokenHash, link.DatabaseID.String(), link.EntryID,
+			link.PreviewOnly, link.MaxDownloads, link.DownloadCount,
+			link.CreatedBy, link.CreatedAt.UnixMilli(), link.ExpiresAt.UnixMilli(),
+		).
+		ToSql()
+	if err != nil {
+		return repo.ShareLink{}, fmt.Errorf("failed to build insert share_link query: %w", err)
+	}
+
+	_, err = r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return repo.ShareLink{}, fmt.Errorf("failed to insert share_link: %w", err)
+	}
+
+	return link, nil
+}
+
+// GetShareLinkByHash retrieves a share link by its token hash.
+func (r *SQLiteRepository) GetShareLinkByHash(ctx context.Context, tokenHash string) (repo.ShareLink, error) {
+	query, args, err := r.Builder.Select(shareLinkColumns...).
+		From("share_links").
+		Where(squirrel.Eq{"token_hash": tokenHash}).
+		ToSql()
+	if err != nil {
+		return repo.ShareLink{}, fmt.Errorf("failed to build get share_link by hash query: %w", err)
+	}
+
+	link, err := scanShareLink(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.ShareLink{}, customerrors.ErrNotFound
+		}
+		return repo.ShareLink{}, fmt.Errorf("failed to execute get share_link by hash query: %w", err)
+	}
+
+	return link, nil
+}
+
+// GetShareLinks retrieves all share links of a single entry, newest first.
+func (r *SQLiteRepository) GetShareLinks(ctx context.Context, dbID repo.ULID, entryID int64) ([]repo.ShareLink, error) {
+	query, args, err := r.Builder.Select(shareLinkColumns...).
+		From("share_links").
+		Where(squirrel.Eq{"database_id": dbID.String(), "entry_id": entryID}).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get share_links query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get share_links query: %w", err)
+	}
+	defer rows.Close()
+
+	links := []repo.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share_link row: %w", err)
+		}
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("share_link row iteration error: %w", err)
+	}
+
+	return links, nil
+}
+
+// DeleteShareLink revokes a share link. The database and entry IDs are part of the filter so that
+// a link can only be revoked through the entry it belongs to.
+func (r *SQLiteRepository) DeleteShareLink(ctx context.Context, dbID repo.ULID, entryID int64, id repo.ULID) error {
+	query, args, err := r.Builder.Delete("share_links").
+		Where(squirrel.Eq{"id": id.String(), "database_id": dbID.String(), "entry_id": entryID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete share_link query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute delete share_link query: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return customerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// ConsumeShareLink increments the download counter in a single conditional UPDATE,
+// so concurrent downloads cannot exceed max_downloads.
+func (r *SQLiteRepository) ConsumeShareLink(ctx context.Context, id repo.ULID) (bool, error) {
+	query, args, err := r.Builder.Update("share_links").
+		Set("download_count", squirrel.Expr("download_count + 1")).
+		Where(squirrel.Eq{"id": id.String()}).
+		Where("expires_at > ?", time.Now().UnixMilli()).
+		Where("(max_downloads = 0 OR download_count < max_downloads)").
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build consume share_link query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute consume share_link query: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to verify rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// scanShareLink scans a single share_links row (in shareLinkColumns order).
+func scanShareLink(row interface{ Scan(dest ...any) error }) (repo.ShareLink, error) {
+	var link repo.ShareLink
+	var idStr, dbIDStr string
+	var createdAtVal, expiresAtVal int64
+
+	err := row.Scan(
+		&idStr, &link.TokenHash, &dbIDStr, &link.EntryID,
+		&link.PreviewOnly, &link.MaxDownloads, &link.DownloadCount,
+		&link.CreatedBy, &createdAtVal, &expiresAtVal,
+	)
+	if err != nil {
+		return repo.ShareLink{}, err
+	}
+
+	link.ID = repo.ULID(idStr)
+	link.DatabaseID = repo.ULID(dbIDStr)
+	link.CreatedAt = time.UnixMilli(createdAtVal)
+	link.ExpiresAt = time.UnixMilli(expiresAtVal)
+
+	return link, nil
+}
@@ -0,0 +1,130 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestShareLinksRepository(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "share_test", ContentType: "image"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// 1. A link limited to two downloads
+	limited, err := r.CreateShareLink(ctx, repo.ShareLink{
+		TokenHash:    "hash_limited",
+		DatabaseID:   db.ID,
+		EntryID:      1,
+		MaxDownloads: 2,
+		CreatedBy:    "tester",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create share link: %v", err)
+	}
+
+	fetched, err := r.GetShareLinkByHash(ctx, "hash_limited")
+	if err != nil {
+		t.Fatalf("failed to get share link by hash: %v", err)
+	}
+	if fetched.ID != limited.ID || fetched.EntryID != 1 || fetched.MaxDownloads != 2 {
+		t.Errorf("unexpected share link: %+v", fetched)
+	}
+
+	for i := 0; i < 2; i++ {
+		ok, err := r.ConsumeShareLink(ctx, limited.ID)
+		if err != nil || !ok {
+			t.Fatalf("expected download %d to be allowed, got ok=%v err=%v", i+1, ok, err)
+		}
+	}
+	if ok, _ := r.ConsumeShareLink(ctx, limited.ID); ok {
+		t.Errorf("expected third download to be rejected")
+	}
+
+	// 2. An expired link can neither be consumed nor survive the sweep
+	expired, err := r.CreateShareLink(ctx, repo.ShareLink{
+		TokenHash:  "hash_expired",
+		DatabaseID: db.ID,
+		EntryID:    1,
+		CreatedBy:  "tester",
+		ExpiresAt:  time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("failed to create expired share link: %v", err)
+	}
+	if ok, _ := r.ConsumeShareLink(ctx, expired.ID); ok {
+		t.Errorf("expected expired link to be rejected")
+	}
+
+	links, err := r.GetShareLinks(ctx, db.ID, 1)
+	if err != nil || len(links) != 2 {
+		t.Fatalf("expected 2 share links, got %d (err: %v)", len(links), err)
+	}
+
+	deleted, err := r.DeleteExpiredRows(ctx, "share_links", "expires_at", 500)
+	if err != nil || deleted != 1 {
+		t.Errorf("expected 1 expired link to be deleted, got %d (err: %v)", deleted, err)
+	}
+
+	// 3. Revocation is scoped to the database and the entry
+	if err := r.DeleteShareLink(ctx, repo.ULID("01ARZ3NDEKTSV4RRFFQ69G5FAV"), 1, limited.ID); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound when revoking through another database, got %v", err)
+	}
+	if err := r.DeleteShareLink(ctx, db.ID, 2, limited.ID); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound when revoking through another entry, got %v", err)
+	}
+	if err := r.DeleteShareLink(ctx, db.ID, 1, limited.ID); err != nil {
+		t.Errorf("failed to revoke share link: %v", err)
+	}
+	if _, err := r.GetShareLinkByHash(ctx, "hash_limited"); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after revocation, got %v", err)
+	}
+
+	// 4. The links of an entry are deleted with it
+	entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "shared.jpg", MimeType: "image/jpeg", Status: repo.EntryStatusReady, Timestamp: time.Now(), MediaFields: map[string]any{"width": uint64(1), "height": uint64(1)}})
+	if err != nil {
+		t.Fatalf("failed to create entry: %v", err)
+	}
+	if _, err := r.CreateShareLink(ctx, repo.ShareLink{
+		TokenHash:  "hash_entry",
+		DatabaseID: db.ID,
+		EntryID:    entry.ID,
+		CreatedBy:  "tester",
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to create share link: %v", err)
+	}
+	if _, err := r.DeleteEntry(ctx, db.ID, entry.ID); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if _, err := r.GetShareLinkByHash(ctx, "hash_entry"); !errors.Is(err, customerrors.ErrNotFound) {
+		t.Errorf("expected the share link to be deleted with its entry, got %v", err)
+	}
+}
@@ -6,12 +6,13 @@ import (
 	"fmt"
 	"mediahub_oss/internal/media"
 	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/cache"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
-	"github.com/patrickmn/go-cache"
+	"golang.org/x/sync/singleflight"
 	_ "modernc.org/sqlite" // SQLite driver
 )
 
@@ -27,7 +28,13 @@ type SQLiteRepository struct {
 	Builder squirrel.StatementBuilderType // SQL Query Builder
 
 	AllowedStatuses []repository.EntryStatus
-	MediaFields     map[string][]MediaField // Added MediaFields
+	MediaFields     map[string][]MediaField   // Added MediaFields
+	PageLimits      repository.PageLimits     // bounds the page size of GetEntries and SearchEntries
+	FieldLimits     repository.FieldLimits    // bounds the custom fields of CreateDatabase and AddCustomField
+	CapacityLimits  repository.CapacityLimits // bounds the databases of CreateDatabase and the entries of CreateEntry
+
+	// coalesces concurrent GetDatabase calls, every upload and worker pick looks up its database
+	dbLookups singleflight.Group
 }
 
 type MediaField struct {
@@ -40,17 +47,19 @@ func NewRepository(path string) (*SQLiteRepository, error) {
 	// 1. Configure the Connection String (DSN) with essential Pragmas
 	dsn := path
 
-	// We'll build a list of required pragmas for a robust concurrent SQLite setup
-	pragmas := map[string]string{
-		"foreign_keys": "1",
-		"journal_mode": "WAL",    // Enables Write-Ahead Logging (concurrent reads/writes)
-		"synchronous":  "NORMAL", // Safe for WAL mode, improves write performance
-		"busy_timeout": "5000",   // Wait up to 5 seconds for a lock instead of failing instantly
+	// We'll build a list of required pragmas for a robust concurrent SQLite setup. They are applied
+	// in order: auto_vacuum only takes effect before the first table is created, so it comes first.
+	pragmas := []struct{ key, val string }{
+		{"auto_vacuum", "INCREMENTAL"}, // Deleted pages can be returned to the file system (see IncrementalVacuum)
+		{"foreign_keys", "1"},
+		{"journal_mode", "WAL"},   // Enables Write-Ahead Logging (concurrent reads/writes)
+		{"synchronous", "NORMAL"}, // Safe for WAL mode, improves write performance
+		{"busy_timeout", "5000"},  // Wait up to 5 seconds for a lock instead of failing instantly
 	}
 
 	// Append missing pragmas to the DSN
-	for key, val := range pragmas {
-		pragmaStr := fmt.Sprintf("_pragma=%s(%s)", key, val)
+	for _, p := range pragmas {
+		pragmaStr := fmt.Sprintf("_pragma=%s(%s)", p.key, p.val)
 		if !strings.Contains(dsn, pragmaStr) {
 			if strings.Contains(dsn, "?") {
 				dsn += "&" + pragmaStr
@@ -83,7 +92,7 @@ func NewRepository(path string) (*SQLiteRepository, error) {
 	builder := squirrel.StatementBuilder.PlaceholderFormat(squirrel.Question)
 
 	// Initialize the Cache
-	c := cache.New(5*time.Minute, 10*time.Minute)
+	c := cache.New(5*time.Minute, cache.DefaultMaxEntries)
 
 	// extract media fields as map[string][]MediaField
 	mediaFields := make(map[string][]MediaField)
@@ -117,6 +126,15 @@ func (s *SQLiteRepository) Close() error {
 	return nil
 }
 
+// Ping runs 'SELECT 1' to verify that the database answers.
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	var one int
+	if err := r.DB.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("database did not answer: %w", err)
+	}
+	return nil
+}
+
 // GetServerTime returns the current database timestamp in UNIX milliseconds.
 func (r *SQLiteRepository) GetDBTime(ctx context.Context) (time.Time, error) {
 
@@ -0,0 +1,173 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestTextMatchOperators(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "cameras",
+		ContentType:  "file",
+		CustomFields: []repo.CustomFieldDef{{Name: "camera", Type: "TEXT", IsIndexed: true}, {Name: "frames", Type: "INTEGER"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	names := map[int64]string{}
+	for _, camera := range []string{"cam07_north", "cam07-south", "CAM07_east", "cam070", "cam7_x", "100%_done", "100 percent", `back\slash`, "Éclair", "éclair", "tower"} {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: camera + ".jpg", Timestamp: time.Now(), MimeType: "image/jpeg",
+			CustomFields: map[string]any{"camera": camera}})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		names[entry.ID] = camera
+	}
+
+	search := func(field, op string, value any) ([]string, error) {
+		t.Helper()
+		req := repo.SearchRequest{
+			Filter:     &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: field, Operator: op, Value: value}}},
+			Pagination: repo.Pagination{Limit: 100},
+		}
+		entries, err := r.SearchEntries(ctx, db.ID, req, db.CustomFields)
+		if err != nil {
+			return nil, err
+		}
+		found := []string{}
+		for _, e := range entries {
+			found = append(found, names[e.ID])
+		}
+		slices.Sort(found)
+		return found, nil
+	}
+
+	// 1. The operators add the wildcards, % and _ in the value match literally
+	tests := []struct {
+		field, op, value string
+		want             []string
+	}{
+		{"camera", "starts_with", "cam07_", []string{"CAM07_east", "cam07_north"}},
+		{"camera", "STARTS_WITH", "cam07", []string{"CAM07_east", "cam07-south", "cam070", "cam07_north"}},
+		{"filename", "starts_with", "cam07_", []string{"CAM07_east", "cam07_north"}},
+		{"camera", "starts_with", "é", []string{"éclair"}},
+		{"camera", "ends_with", "_east", []string{"CAM07_east"}},
+		{"camera", "contains", "%", []string{"100%_done"}},
+		{"camera", "contains", "0%_", []string{"100%_done"}},
+		{"camera", "contains", `\`, []string{`back\slash`}},
+		{"camera", "contains", "7_", []string{"CAM07_east", "cam07_north", "cam7_x"}},
+		{"camera", "starts_with", "", []string{"100 percent", "100%_done", "CAM07_east", "cam07-south", "cam07_north", "cam070", "cam7_x", "tower", "Éclair", "éclair", `back\slash`}},
+		// LIKE keeps taking the pattern as given
+		{"camera", "LIKE", "cam07_%", []string{"CAM07_east", "cam07-south", "cam070", "cam07_north"}},
+	}
+	for _, tc := range tests {
+		got, err := search(tc.field, tc.op, tc.value)
+		if err != nil {
+			t.Fatalf("%s %s %q: failed to search: %v", tc.field, tc.op, tc.value, err)
+		}
+		want := slices.Clone(tc.want)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s %s %q: expected %v, got %v", tc.field, tc.op, tc.value, want, got)
+		}
+	}
+
+	// 2. The case-insensitive operators fold ASCII letters, other letters are matched exactly
+	ciTests := []struct {
+		op, value string
+		want      []string
+	}{
+		{"equals_ci", "TOWER", []string{"tower"}},
+		{"equals_ci", "cam07_EAST", []string{"CAM07_east"}},
+		{"contains_ci", "M07_E", []string{"CAM07_east"}},
+		{"contains_ci", "CLAIR", []string{"Éclair", "éclair"}},
+		{"equals_ci", "éclair", []string{"éclair"}},
+		{"contains_ci", "Écl", []string{"Éclair"}},
+	}
+	for _, tc := range ciTests {
+		got, err := search("camera", tc.op, tc.value)
+		if err != nil {
+			t.Fatalf("%s %q: failed to search: %v", tc.op, tc.value, err)
+		}
+		want := slices.Clone(tc.want)
+		slices.Sort(want)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s %q: expected %v, got %v", tc.op, tc.value, want, got)
+		}
+	}
+
+	// 3. A prefix search on an indexed field uses the index
+	req := repo.SearchRequest{Filter: &repo.FilterGroup{Operator: "and", Conditions: []repo.Condition{{Field: "camera", Operator: "starts_with", Value: "cam07_"}}}}
+	plan, err := r.ExplainSearch(ctx, db.ID, req, db.CustomFields)
+	if err != nil {
+		t.Fatalf("failed to explain search: %v", err)
+	}
+	var details []string
+	for _, step := range plan.Steps {
+		details = append(details, step.Detail)
+	}
+	if want := "USING INDEX idx_entries_" + db.ID.String() + "_cf_0"; !strings.Contains(strings.Join(details, "\n"), want) {
+		t.Errorf("expected the prefix search to use the index (%s), got %q for %s", want, details, plan.SQL)
+	}
+
+	// 4. The operators need a text value on a TEXT field
+	invalid := []struct {
+		field, op string
+		value     any
+	}{
+		{"camera", "contains", 7},
+		{"frames", "starts_with", "1"},
+		{"id", "equals_ci", "1"},
+	}
+	for _, tc := range invalid {
+		if _, err := search(tc.field, tc.op, tc.value); !errors.Is(err, customerrors.ErrValidation) {
+			t.Errorf("%s %s %v: expected a validation error, got %v", tc.field, tc.op, tc.value, err)
+		}
+	}
+
+	// 5. The comparisons order TEXT fields by their bytes, as before the text match operators
+	ranges := []struct {
+		field, op, value string
+		want             []string
+	}{
+		{"camera", ">=", "tower", []string{"tower", "Éclair", "éclair"}},
+		{"filename", "<", "100%", []string{"100 percent"}},
+	}
+	for _, tc := range ranges {
+		found, err := search(tc.field, tc.op, tc.value)
+		if err != nil {
+			t.Errorf("%s %s %q: %v", tc.field, tc.op, tc.value, err)
+		} else if !slices.Equal(found, tc.want) {
+			t.Errorf("%s %s %q: expected %v, got %v", tc.field, tc.op, tc.value, tc.want, found)
+		}
+	}
+}
@@ -96,32 +96,6 @@ func (r *SQLiteRepository) DeleteRefreshToken(ctx context.Context, tokenHash str
 	return nil
 }
 
-// DeleteExpiredRefreshTokens removes all tokens that have passed their expiration date.
-// Returns the number of tokens that were purged.
-func (r *SQLiteRepository) DeleteExpiredRefreshTokens(ctx context.Context) (int64, error) {
-	// Build the DELETE query.
-	query, args, err := r.Builder.Delete("refresh_tokens").
-		Where("expiry < CAST(unixepoch('subsec') * 1000 AS INTEGER)").
-		ToSql()
-	if err != nil {
-		return 0, fmt.Errorf("failed to build delete expired tokens query: %w", err)
-	}
-
-	// Execute the query
-	res, err := r.DB.ExecContext(ctx, query, args...)
-	if err != nil {
-		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
-	}
-
-	// Retrieve how many expired tokens were cleaned up
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to retrieve rows affected: %w", err)
-	}
-
-	return rowsAffected, nil
-}
-
 // DeleteAllRefreshTokensForUser removes all active sessions for a specific user.
 func (r *SQLiteRepository) DeleteAllRefreshTokensForUser(ctx context.Context, userID repo.ULID) error {
 	// Build the DELETE query targeting the specific user_id
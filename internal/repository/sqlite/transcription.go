@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// ScheduleTranscription sets the transcription status of a ready entry to pending and creates its
+// transcription task in the same transaction. It returns ErrNotFound if the entry is gone or not ready.
+func (r *SQLiteRepository) ScheduleTranscription(ctx context.Context, dbID repo.ULID, entryID int64) (repo.PendingTask, error) {
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+	now := time.Now()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return repo.PendingTask{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query, args, err := r.Builder.Update(tableName).
+		Set("transcription_status", repo.TranscriptionPending).
+		Where(squirrel.Eq{"id": entryID, "status": repo.EntryStatusReady}).
+		ToSql()
+	if err != nil {
+		return repo.PendingTask{}, fmt.Errorf("failed to build transcription status query: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return repo.PendingTask{}, fmt.Errorf("failed to set transcription status: %w", err)
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return repo.PendingTask{}, customerrors.ErrNotFound
+	}
+
+	task := repo.PendingTask{
+		DatabaseID:    dbID,
+		EntryID:       entryID,
+		Type:          repo.TaskTypeTranscription,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	taskQuery, taskArgs, err := r.Builder.Insert("pending_tasks").
+		Columns("database_id", "entry_id", "task_type", "next_attempt_at", "created_at").
+		Values(task.DatabaseID.String(), task.EntryID, task.Type, task.NextAttemptAt.UnixMilli(), task.CreatedAt.UnixMilli()).
+		ToSql()
+	if err != nil {
+		return repo.PendingTask{}, fmt.Errorf("failed to build insert pending_task query: %w", err)
+	}
+	res, err = tx.ExecContext(ctx, taskQuery, taskArgs...)
+	if err != nil {
+		return repo.PendingTask{}, fmt.Errorf("failed to insert pending_task: %w", err)
+	}
+	if task.ID, err = res.LastInsertId(); err != nil {
+		return repo.PendingTask{}, fmt.Errorf("failed to retrieve pending_task ID: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repo.PendingTask{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return task, nil
+}
+
+// RecordTranscription stores the transcription status of a ready entry. Unless field is negative,
+// the text is written into the custom field with that ID. It returns ErrNotFound if the entry is
+// gone or not ready anymore.
+func (r *SQLiteRepository) RecordTranscription(ctx context.Context, dbID repo.ULID, entryID int64, status string, field int, text string) error {
+	tableName := fmt.Sprintf(`"entries_%s"`, dbID.String())
+
+	builder := r.Builder.Update(tableName).
+		Set("transcription_status", status)
+	if field >= 0 {
+		builder = builder.
+			Set(fmt.Sprintf(`"%s%d"`, customFieldsPrefix, field), text).
+			Set("updated_at", time.Now().UnixMilli())
+	}
+
+	query, args, err := builder.
+		Where(squirrel.Eq{"id": entryID, "status": repo.EntryStatusReady}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build record transcription query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to record transcription: %w", err)
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return customerrors.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WithTx runs fn in a transaction bound to ctx. The transaction is committed if fn returns nil and rolled
+// back if fn returns an error, panics or the context is cancelled before the commit. A panic is re-raised
+// after the rollback. With a single connection in the pool, a transaction that is left open blocks every
+// other query, so it must be released on every path.
+func (r *SQLiteRepository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Do not commit the writes of a cancelled request
+	if err := ctx.Err(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+func TestWithTx(t *testing.T) {
+	r, _ := newDatabaseTestRepo(t)
+	ctx := context.Background()
+
+	insert := func(ctx context.Context, tx *sql.Tx, key string) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO settings (key, value, updated_at) VALUES (?, 'x', 0)`, key)
+		return err
+	}
+	stored := func(key string) bool {
+		t.Helper()
+		readCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err := r.GetSetting(readCtx, key)
+		if err != nil && !errors.Is(err, customerrors.ErrNotFound) {
+			t.Fatalf("failed to read setting: %v", err)
+		}
+		return err == nil
+	}
+	// The pool has a single connection, a transaction left open would block these queries until the deadline
+	assertReleased := func(name string) {
+		t.Helper()
+		writeCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		if err := r.SetSetting(writeCtx, "probe_"+name, "x"); err != nil {
+			t.Errorf("%s: expected the write lock to be released, got %v", name, err)
+		}
+	}
+
+	// 1. Commit on nil
+	if err := r.WithTx(ctx, func(tx *sql.Tx) error { return insert(ctx, tx, "committed") }); err != nil {
+		t.Fatalf("expected the transaction to commit, got %v", err)
+	}
+	if !stored("committed") {
+		t.Error("expected the committed write to be stored")
+	}
+
+	// 2. Rollback on error
+	errBoom := errors.New("boom")
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := insert(ctx, tx, "failed"); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Errorf("expected the error of fn, got %v", err)
+	}
+	if stored("failed") {
+		t.Error("expected the failed write to be rolled back")
+	}
+	assertReleased("error")
+
+	// 3. Rollback on panic, the panic is re-raised
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("expected the panic to be re-raised, got %v", p)
+			}
+		}()
+		r.WithTx(ctx, func(tx *sql.Tx) error {
+			if err := insert(ctx, tx, "panicked"); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+	if stored("panicked") {
+		t.Error("expected the write before the panic to be rolled back")
+	}
+	assertReleased("panic")
+
+	// 4. A cancelled context stops the transaction between statements
+	cancelCtx, cancel := context.WithCancel(ctx)
+	err = r.WithTx(cancelCtx, func(tx *sql.Tx) error {
+		if err := insert(cancelCtx, tx, "cancelled_first"); err != nil {
+			return err
+		}
+		cancel()
+		return insert(cancelCtx, tx, "cancelled_second")
+	})
+	if err == nil {
+		t.Error("expected the cancelled transaction to fail")
+	}
+	if stored("cancelled_first") || stored("cancelled_second") {
+		t.Error("expected the writes of the cancelled transaction to be rolled back")
+	}
+	assertReleased("cancel")
+
+	// 5. ... and is not committed if it is cancelled after the last statement
+	cancelCtx, cancel = context.WithCancel(ctx)
+	err = r.WithTx(cancelCtx, func(tx *sql.Tx) error {
+		err := insert(cancelCtx, tx, "cancelled_late")
+		cancel()
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if stored("cancelled_late") {
+		t.Error("expected the write of the cancelled transaction to be rolled back")
+	}
+	assertReleased("late cancel")
+}
@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+var uploadGrantColumns = []string{
+	"id", "token_hash", "database_id",
+	"metadata", "max_file_size",
+	"user_id", "created_by", "created_at", "expires_at", "consumed_at",
+}
+
+// CreateUploadGrant stores a new, unused upload grant in the SQLite database.
+func (r *SQLiteRepository) CreateUploadGrant(ctx context.Context, grant repo.UploadGrant) (repo.UploadGrant, error) {
+	if grant.ID == "" {
+		grant.ID = repo.ULID(shared.GenerateULID())
+	}
+	if grant.CreatedAt.IsZero() {
+		grant.CreatedAt = time.Now()
+	}
+	grant.ConsumedAt = time.Time{}
+
+	query, args, err := r.Builder.Insert("upload_grants").
+		Columns(uploadGrantColumns...).
+		Values(
+			grant.ID.String(), grant.TokenHash, grant.DatabaseID.String(),
+			grant.Metadata, grant.MaxFileSize,
+			grant.UserID.String(), grant.CreatedBy, grant.CreatedAt.UnixMilli(), grant.ExpiresAt.UnixMilli(), nil,
+		).
+		ToSql()
+	if err != nil {
+		return repo.UploadGrant{}, fmt.Errorf("failed to build insert upload_grant query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return repo.UploadGrant{}, fmt.Errorf("failed to insert upload_grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+// GetUploadGrantByHash retrieves an upload grant by its token hash.
+func (r *SQLiteRepository) GetUploadGrantByHash(ctx context.Context, tokenHash string) (repo.UploadGrant, error) {
+	query, args, err := r.Builder.Select(uploadGrantColumns...).
+		From("upload_grants").
+		Where(squirrel.Eq{"token_hash": tokenHash}).
+		ToSql()
+	if err != nil {
+		return repo.UploadGrant{}, fmt.Errorf("failed to build get upload_grant by hash query: %w", err)
+	}
+
+	grant, err := scanUploadGrant(r.DB.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repo.UploadGrant{}, customerrors.ErrNotFound
+		}
+		return repo.UploadGrant{}, fmt.Errorf("failed to execute get upload_grant by hash query: %w", err)
+	}
+
+	return grant, nil
+}
+
+// GetUploadGrants retrieves the upload grants matching the filter, newest first.
+func (r *SQLiteRepository) GetUploadGrants(ctx context.Context, filter repo.UploadGrantFilter) ([]repo.UploadGrant, error) {
+	builder := r.Builder.Select(uploadGrantColumns...).
+		From("upload_grants").
+		OrderBy("created_at DESC")
+	if filter.DatabaseID != "" {
+		builder = builder.Where(squirrel.Eq{"database_id": filter.DatabaseID.String()})
+	}
+	if filter.UserID != "" {
+		builder = builder.Where(squirrel.Eq{"user_id": filter.UserID.String()})
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build get upload_grants query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute get upload_grants query: %w", err)
+	}
+	defer rows.Close()
+
+	grants := []repo.UploadGrant{}
+	for rows.Next() {
+		grant, err := scanUploadGrant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan upload_grant row: %w", err)
+		}
+		grants = append(grants, grant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("upload_grant row iteration error: %w", err)
+	}
+
+	return grants, nil
+}
+
+// DeleteUploadGrant revokes an upload grant. If userID is set, only a grant created by that user is deleted.
+func (r *SQLiteRepository) DeleteUploadGrant(ctx context.Context, id repo.ULID, userID repo.ULID) error {
+	builder := r.Builder.Delete("upload_grants").
+		Where(squirrel.Eq{"id": id.String()})
+	if userID != "" {
+		builder = builder.Where(squirrel.Eq{"user_id": userID.String()})
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete upload_grant query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute delete upload_grant query: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return customerrors.ErrNotFound
+	}
+
+	return nil
+}
+
+// ConsumeUploadGrant marks the grant as used in a single conditional UPDATE, so of concurrent
+// uploads with the same grant exactly one succeeds.
+func (r *SQLiteRepository) ConsumeUploadGrant(ctx context.Context, id repo.ULID) (bool, error) {
+	now := time.Now().UnixMilli()
+	query, args, err := r.Builder.Update("upload_grants").
+		Set("consumed_at", now).
+		Where(squirrel.Eq{"id": id.String(), "consumed_at": nil}).
+		Where("expires_at > ?", now).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build consume upload_grant query: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute consume upload_grant query: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to verify rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ReleaseUploadGrant resets a consumed grant, used when the upload failed after the grant was consumed.
+func (r *SQLiteRepository) ReleaseUploadGrant(ctx context.Context, id repo.ULID) error {
+	query, args, err := r.Builder.Update("upload_grants").
+		Set("consumed_at", nil).
+		Where(squirrel.Eq{"id": id.String()}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build release upload_grant query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to execute release upload_grant query: %w", err)
+	}
+
+	return nil
+}
+
+// scanUploadGrant scans a single upload_grants row (in uploadGrantColumns order).
+func scanUploadGrant(row interface{ Scan(dest ...any) error }) (repo.UploadGrant, error) {
+	var grant repo.UploadGrant
+	var idStr, dbIDStr, userIDStr string
+	var createdAtVal, expiresAtVal int64
+	var consumedAtVal sql.NullInt64
+
+	err := row.Scan(
+		&idStr, &grant.TokenHash, &dbIDStr,
+		&grant.Metadata, &grant.MaxFileSize,
+		&userIDStr, &grant.CreatedBy, &createdAtVal, &expiresAtVal, &consumedAtVal,
+	)
+	if err != nil {
+		return repo.UploadGrant{}, err
+	}
+
+	grant.ID = repo.ULID(idStr)
+	grant.DatabaseID = repo.ULID(dbIDStr)
+	grant.UserID = repo.ULID(userIDStr)
+	grant.CreatedAt = time.UnixMilli(createdAtVal)
+	grant.ExpiresAt = time.UnixMilli(expiresAtVal)
+	if consumedAtVal.Valid {
+		grant.ConsumedAt = time.UnixMilli(consumedAtVal.Int64)
+	}
+
+	return grant, nil
+}
@@ -0,0 +1,102 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestConsumeUploadGrantIsSingleUse(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(filepath.Join(t.TempDir(), "mediahub.db"))
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "grant_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	user, err := r.CreateUser(ctx, repo.User{Username: "provisioner", PasswordHash: "x"})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	grant, err := r.CreateUploadGrant(ctx, repo.UploadGrant{
+		TokenHash:   "hash_grant",
+		DatabaseID:  db.ID,
+		Metadata:    `{"custom_fields":{"device":"cam-07"}}`,
+		MaxFileSize: 1024,
+		UserID:      user.ID,
+		CreatedBy:   user.Username,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create upload grant: %v", err)
+	}
+
+	// 1. Of concurrent uploads exactly one consumes the grant
+	var wg sync.WaitGroup
+	var consumed atomic.Int32
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := r.ConsumeUploadGrant(ctx, grant.ID)
+			if err != nil {
+				t.Errorf("failed to consume upload grant: %v", err)
+			}
+			if ok {
+				consumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if consumed.Load() != 1 {
+		t.Fatalf("expected exactly one consumer, got %d", consumed.Load())
+	}
+
+	fetched, err := r.GetUploadGrantByHash(ctx, "hash_grant")
+	if err != nil {
+		t.Fatalf("failed to get upload grant by hash: %v", err)
+	}
+	if fetched.ConsumedAt.IsZero() || fetched.Metadata != grant.Metadata || fetched.UserID != user.ID {
+		t.Errorf("unexpected upload grant: %+v", fetched)
+	}
+
+	// 2. A released grant can be consumed again
+	if err := r.ReleaseUploadGrant(ctx, grant.ID); err != nil {
+		t.Fatalf("failed to release upload grant: %v", err)
+	}
+	if ok, err := r.ConsumeUploadGrant(ctx, grant.ID); err != nil || !ok {
+		t.Errorf("expected the released grant to be consumable, got %v (err %v)", ok, err)
+	}
+
+	// 3. Grants are removed with their creator
+	if err := r.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if grants, err := r.GetUploadGrants(ctx, repo.UploadGrantFilter{DatabaseID: db.ID}); err != nil || len(grants) != 0 {
+		t.Errorf("expected no grants after deleting the creator, got %+v (err %v)", grants, err)
+	}
+}
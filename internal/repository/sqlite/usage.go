@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// dayMillis is the length of the day buckets of the user usage.
+const dayMillis = 24 * 60 * 60 * 1000
+
+// usageSource is the summary and the changes not folded into it yet, together the exact usage.
+const usageSource = `(SELECT username, database_id, day, entries, bytes FROM user_usage
+	UNION ALL SELECT username, database_id, day, entries, bytes FROM user_usage_changes) AS usage`
+
+// usageChange is the change of the usage of an uploader by a write of entries.
+type usageChange struct {
+	username string
+	day      int64 // start of the UTC day the entry was uploaded, in unix milliseconds
+	entries  int64
+	bytes    int64
+}
+
+// usageDay returns the start of the UTC day of the upload time createdAt, in unix milliseconds.
+func usageDay(createdAt int64) int64 {
+	return createdAt - createdAt%dayMillis
+}
+
+// recordUsageChanges appends the changes of a write of entries to the journal that AggregateUserUsage folds
+// into the summary. Changes of the same uploader and day are merged, entries without uploader are not counted.
+func (r *SQLiteRepository) recordUsageChanges(ctx context.Context, tx *sql.Tx, dbID repo.ULID, changes []usageChange) error {
+	type key struct {
+		username string
+		day      int64
+	}
+	merged := make(map[key]usageChange)
+	var order []key
+	for _, c := range changes {
+		if c.username == "" || (c.entries == 0 && c.bytes == 0) {
+			continue
+		}
+		k := key{c.username, c.day}
+		m, ok := merged[k]
+		if !ok {
+			order = append(order, k)
+			m = usageChange{username: c.username, day: c.day}
+		}
+		m.entries += c.entries
+		m.bytes += c.bytes
+		merged[k] = m
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	insert := r.Builder.Insert("user_usage_changes").Columns("username", "database_id", "day", "entries", "bytes")
+	for _, k := range order {
+		c := merged[k]
+		insert = insert.Values(c.username, dbID.String(), c.day, c.entries, c.bytes)
+	}
+	query, args, err := insert.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build usage change query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record usage changes: %w", err)
+	}
+	return nil
+}
+
+// GetUserStoredBytes returns the bytes of the entries uploaded by the user in all databases.
+func (r *SQLiteRepository) GetUserStoredBytes(ctx context.Context, username string) (uint64, error) {
+	var stored int64
+	err := r.DB.QueryRowContext(ctx, `SELECT COALESCE((SELECT SUM(bytes) FROM user_usage WHERE username = ?), 0)
+		+ COALESCE((SELECT SUM(bytes) FROM user_usage_changes WHERE username = ?), 0)`, username, username).Scan(&stored)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query stored bytes of user: %w", err)
+	}
+	return uint64(max(stored, 0)), nil
+}
+
+// GetUserUsage returns the stored entries and bytes per uploader or per database, of the entries uploaded
+// within the days of the filter. Groups without stored entries are left out.
+func (r *SQLiteRepository) GetUserUsage(ctx context.Context, filter repo.UsageFilter) ([]repo.UsageRecord, error) {
+	var column string
+	switch filter.GroupBy {
+	case repo.UsageByUser:
+		column = "username"
+	case repo.UsageByDatabase:
+		column = "database_id"
+	default:
+		return nil, fmt.Errorf("%w: invalid usage grouping '%s', expected '%s' or '%s'", customerrors.ErrValidation, filter.GroupBy, repo.UsageByUser, repo.UsageByDatabase)
+	}
+
+	where := squirrel.And{}
+	if !filter.From.IsZero() {
+		where = append(where, squirrel.GtOrEq{"day": usageDay(filter.From.UnixMilli())})
+	}
+	if !filter.To.IsZero() {
+		where = append(where, squirrel.Lt{"day": filter.To.UnixMilli()})
+	}
+	query, args, err := r.Builder.Select(column, "SUM(entries)", "SUM(bytes)").
+		From(usageSource).
+		Where(where).
+		GroupBy(column).
+		Having("SUM(entries) != 0 OR SUM(bytes) != 0").
+		OrderBy(column).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build usage query: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	records := []repo.UsageRecord{}
+	for rows.Next() {
+		var record repo.UsageRecord
+		var group string
+		if err := rows.Scan(&group, &record.Entries, &record.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		if filter.GroupBy == repo.UsageByUser {
+			record.Username = group
+		} else {
+			record.DatabaseID = repo.ULID(group)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	return records, nil
+}
+
+// AggregateUserUsage folds the recorded usage changes into the summary table. Only the changes since the last
+// run are read, the entry tables are not scanned.
+func (r *SQLiteRepository) AggregateUserUsage(ctx context.Context) (int64, error) {
+	var folded int64
+	err := r.WithTx(ctx, func(tx *sql.Tx) error {
+		var lastID sql.NullInt64
+		if err := tx.QueryRowContext(ctx, "SELECT MAX(id) FROM user_usage_changes").Scan(&lastID); err != nil {
+			return fmt.Errorf("failed to query usage changes: %w", err)
+		}
+		if !lastID.Valid {
+			return nil
+		}
+
+		statements := []string{
+			`INSERT INTO user_usage (username, database_id, day, entries, bytes)
+			SELECT username, database_id, day, SUM(entries), SUM(bytes) FROM user_usage_changes WHERE id <= ? GROUP BY username, database_id, day
+			ON CONFLICT (username, database_id, day) DO UPDATE SET entries = entries + excluded.entries, bytes = bytes + excluded.bytes`,
+			// Days whose entries are all deleted are removed
+			`DELETE FROM user_usage WHERE entries = 0 AND bytes = 0
+			AND (username, database_id, day) IN (SELECT username, database_id, day FROM user_usage_changes WHERE id <= ?)`,
+		}
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt, lastID.Int64); err != nil {
+				return fmt.Errorf("failed to aggregate usage changes: %w", err)
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, "DELETE FROM user_usage_changes WHERE id <= ?", lastID.Int64)
+		if err != nil {
+			return fmt.Errorf("failed to delete aggregated usage changes: %w", err)
+		}
+		folded, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return folded, nil
+}
@@ -0,0 +1,144 @@
+package sqlite_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestUserUsage(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	var dbs []repo.Database
+	for _, name := range []string{"finance", "marketing"} {
+		db, err := r.CreateDatabase(ctx, repo.Database{Name: name, ContentType: "file"})
+		if err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		dbs = append(dbs, db)
+	}
+	upload := func(db repo.Database, user string, size uint64) repo.Entry {
+		t.Helper()
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "a.bin", Timestamp: time.Now(), MimeType: "application/octet-stream",
+			Size: size, Origin: repo.UploadOrigin{UploadedBy: user}})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		return entry
+	}
+	usage := func(groupBy string) []repo.UsageRecord {
+		t.Helper()
+		records, err := r.GetUserUsage(ctx, repo.UsageFilter{GroupBy: groupBy})
+		if err != nil {
+			t.Fatalf("failed to get usage: %v", err)
+		}
+		return records
+	}
+	aggregate := func() {
+		t.Helper()
+		if _, err := r.AggregateUserUsage(ctx); err != nil {
+			t.Fatalf("failed to aggregate usage: %v", err)
+		}
+	}
+
+	// 1. Uploads, a size change by processing and deletions of two users in two databases, half of them
+	// before the first aggregation
+	a1 := upload(dbs[0], "alice", 100)
+	a2 := upload(dbs[0], "alice", 200)
+	upload(dbs[1], "alice", 50)
+	b1 := upload(dbs[0], "bob", 1000)
+	upload(dbs[1], "anonymous", 0)
+	upload(dbs[1], "", 999) // without uploader, not attributed
+	aggregate()
+
+	b2 := upload(dbs[1], "bob", 10)
+	if err := r.UpdateEntryTechMetadata(ctx, dbs[1].ID, b2.ID, repo.EntryTechMetadata{Size: 30, PreviewSize: 5, MimeType: "application/octet-stream"}); err != nil {
+		t.Fatalf("failed to update entry: %v", err)
+	}
+	if _, err := r.DeleteEntry(ctx, dbs[0].ID, a1.ID); err != nil {
+		t.Fatalf("failed to delete entry: %v", err)
+	}
+	if _, err := r.DeleteEntries(ctx, dbs[0].ID, []int64{a2.ID, b1.ID}); err != nil {
+		t.Fatalf("failed to delete entries: %v", err)
+	}
+	upload(dbs[0], "bob", 7)
+
+	wantUsers := []repo.UsageRecord{
+		{Username: "alice", Entries: 1, Bytes: 50},
+		{Username: "anonymous", Entries: 1, Bytes: 0},
+		{Username: "bob", Entries: 2, Bytes: 42},
+	}
+	wantDatabases := []repo.UsageRecord{
+		{DatabaseID: dbs[0].ID, Entries: 1, Bytes: 7},
+		{DatabaseID: dbs[1].ID, Entries: 3, Bytes: 85},
+	}
+	slices.SortFunc(wantDatabases, func(a, b repo.UsageRecord) int { return strings.Compare(a.DatabaseID.String(), b.DatabaseID.String()) })
+
+	// 2. The report is exact before and after the changes are folded into the summary
+	for _, stage := range []string{"before", "after"} {
+		if stage == "after" {
+			aggregate()
+		}
+		if got := usage(repo.UsageByUser); !slices.Equal(got, wantUsers) {
+			t.Errorf("%s the aggregation: expected %+v per user, got %+v", stage, wantUsers, got)
+		}
+		if got := usage(repo.UsageByDatabase); !slices.Equal(got, wantDatabases) {
+			t.Errorf("%s the aggregation: expected %+v per database, got %+v", stage, wantDatabases, got)
+		}
+		if stored, err := r.GetUserStoredBytes(ctx, "bob"); err != nil || stored != 42 {
+			t.Errorf("%s the aggregation: expected 42 stored bytes of bob, got %d (err %v)", stage, stored, err)
+		}
+	}
+
+	// 3. The aggregation folds only the new changes, days without entries are removed
+	var changes, rows int
+	if err := r.DB.QueryRow("SELECT COUNT(*) FROM user_usage_changes").Scan(&changes); err != nil || changes != 0 {
+		t.Errorf("expected no changes left after the aggregation, got %d (err %v)", changes, err)
+	}
+	if err := r.DB.QueryRow("SELECT COUNT(*) FROM user_usage").Scan(&rows); err != nil || rows != 4 {
+		t.Errorf("expected 4 summary rows, got %d (err %v)", rows, err)
+	}
+	if folded, err := r.AggregateUserUsage(ctx); err != nil || folded != 0 {
+		t.Errorf("expected nothing to fold, got %d (err %v)", folded, err)
+	}
+
+	// 4. The time range selects by the day of the upload
+	tomorrow := time.Now().Add(24 * time.Hour)
+	if records, err := r.GetUserUsage(ctx, repo.UsageFilter{GroupBy: repo.UsageByUser, From: tomorrow}); err != nil || len(records) != 0 {
+		t.Errorf("expected no usage from tomorrow on, got %+v (err %v)", records, err)
+	}
+	if records, err := r.GetUserUsage(ctx, repo.UsageFilter{GroupBy: repo.UsageByUser, From: time.Now(), To: tomorrow}); err != nil || !slices.Equal(records, wantUsers) {
+		t.Errorf("expected the usage of today, got %+v (err %v)", records, err)
+	}
+
+	// 5. Deleting a database removes its usage
+	if err := r.DeleteDatabase(ctx, dbs[1].ID); err != nil {
+		t.Fatalf("failed to delete database: %v", err)
+	}
+	if stored, err := r.GetUserStoredBytes(ctx, "bob"); err != nil || stored != 7 {
+		t.Errorf("expected 7 stored bytes of bob after deleting the database, got %d (err %v)", stored, err)
+	}
+}
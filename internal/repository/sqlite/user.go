@@ -9,17 +9,20 @@ import (
 	"mediahub_oss/internal/shared"
 	"mediahub_oss/internal/shared/customerrors"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 )
 
+var userColumns = []string{"id", "username", "password_hash", "is_admin", "is_service_account", "disabled", "last_login_at", "last_activity_at", "storage_quota_bytes"}
+
 // CreateUser inserts a new user into the database and returns the populated user object (with ID).
 func (r *SQLiteRepository) CreateUser(ctx context.Context, user repo.User) (repo.User, error) {
 	user.ID = repo.ULID(shared.GenerateULID())
 
 	query, args, err := r.Builder.Insert("users").
-		Columns("id", "username", "password_hash", "is_admin", "is_service_account").
-		Values(user.ID.String(), user.Username, user.PasswordHash, user.IsAdmin, user.IsServiceAccount).
+		Columns("id", "username", "password_hash", "is_admin", "is_service_account", "disabled", "storage_quota_bytes").
+		Values(user.ID.String(), user.Username, user.PasswordHash, user.IsAdmin, user.IsServiceAccount, user.Disabled, user.StorageQuota).
 		ToSql()
 	if err != nil {
 		return repo.User{}, fmt.Errorf("failed to build insert user query: %w", err)
@@ -37,11 +40,15 @@ func (r *SQLiteRepository) CreateUser(ctx context.Context, user repo.User) (repo
 	return user, nil
 }
 
-// CountAdminUsers returns the total number of users who have the global 'is_admin' flag set to true.
+// CountAdminUsers returns the total number of users who are global admins,
+// either through their own 'is_admin' flag or through membership in an admin group.
 func (r *SQLiteRepository) CountAdminUsers(ctx context.Context) (int64, error) {
 	query, args, err := r.Builder.Select("COUNT(*)").
-		From("users").
-		Where(squirrel.Eq{"is_admin": true}).
+		From("users u").
+		Where(squirrel.Or{
+			squirrel.Eq{"u.is_admin": true},
+			squirrel.Expr("EXISTS (SELECT 1 FROM user_groups ug JOIN groups g ON g.id = ug.group_id WHERE ug.user_id = u.id AND g.is_admin = 1)"),
+		}).
 		ToSql()
 	if err != nil {
 		return 0, fmt.Errorf("failed to build count admin query: %w", err)
@@ -90,27 +97,35 @@ func (r *SQLiteRepository) UpdateUser(ctx context.Context, user repo.User) (repo
 		Set("password_hash", user.PasswordHash).
 		Set("is_admin", user.IsAdmin).
 		Set("is_service_account", user.IsServiceAccount).
+		Set("disabled", user.Disabled).
+		Set("storage_quota_bytes", user.StorageQuota).
 		Where(squirrel.Eq{"id": user.ID.String()}).
 		ToSql()
 	if err != nil {
 		return repo.User{}, fmt.Errorf("failed to build update user query: %w", err)
 	}
 
-	res, err := r.DB.ExecContext(ctx, query, args...)
-	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			return repo.User{}, customerrors.ErrUserExists
+	err = r.WithTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				return customerrors.ErrUserExists
+			}
+			return fmt.Errorf("failed to update user: %w", err)
 		}
-		return repo.User{}, fmt.Errorf("failed to update user: %w", err)
-	}
 
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
-		return repo.User{}, fmt.Errorf("failed to check rows affected: %w", err)
-	}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check rows affected: %w", err)
+		}
 
-	if rowsAffected == 0 {
-		return repo.User{}, customerrors.ErrNotFound
+		if rowsAffected == 0 {
+			return customerrors.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return repo.User{}, err
 	}
 
 	return user, nil
@@ -118,7 +133,7 @@ func (r *SQLiteRepository) UpdateUser(ctx context.Context, user repo.User) (repo
 
 // GetUsers retrieves a list of all user accounts from the database.
 func (r *SQLiteRepository) GetUsers(ctx context.Context, isServiceAccount *bool) ([]repo.User, error) {
-	b := r.Builder.Select("id", "username", "password_hash", "is_admin", "is_service_account").
+	b := r.Builder.Select(userColumns...).
 		From("users")
 
 	if isServiceAccount != nil {
@@ -138,12 +153,10 @@ func (r *SQLiteRepository) GetUsers(ctx context.Context, isServiceAccount *bool)
 
 	var users []repo.User
 	for rows.Next() {
-		var user repo.User
-		var idStr string
-		if err := rows.Scan(&idStr, &user.Username, &user.PasswordHash, &user.IsAdmin, &user.IsServiceAccount); err != nil {
+		user, err := scanUser(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan user row: %w", err)
 		}
-		user.ID = repo.ULID(idStr)
 		users = append(users, user)
 	}
 
@@ -156,7 +169,7 @@ func (r *SQLiteRepository) GetUsers(ctx context.Context, isServiceAccount *bool)
 
 // GetUserByID retrieves a single user record by its unique ID.
 func (r *SQLiteRepository) GetUserByID(ctx context.Context, id repo.ULID) (repo.User, error) {
-	query, args, err := r.Builder.Select("id", "username", "password_hash", "is_admin", "is_service_account").
+	query, args, err := r.Builder.Select(userColumns...).
 		From("users").
 		Where(squirrel.Eq{"id": id.String()}).
 		ToSql()
@@ -164,23 +177,20 @@ func (r *SQLiteRepository) GetUserByID(ctx context.Context, id repo.ULID) (repo.
 		return repo.User{}, fmt.Errorf("failed to build get user by id query: %w", err)
 	}
 
-	var user repo.User
-	var idStr string
-	err = r.DB.QueryRowContext(ctx, query, args...).Scan(&idStr, &user.Username, &user.PasswordHash, &user.IsAdmin, &user.IsServiceAccount)
+	user, err := scanUser(r.DB.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return repo.User{}, customerrors.ErrNotFound
 		}
 		return repo.User{}, fmt.Errorf("failed to scan user by id: %w", err)
 	}
-	user.ID = repo.ULID(idStr)
 
 	return user, nil
 }
 
 // GetUserByUsername retrieves a single user record by their unique username.
 func (r *SQLiteRepository) GetUserByUsername(ctx context.Context, username string) (repo.User, error) {
-	query, args, err := r.Builder.Select("id", "username", "password_hash", "is_admin", "is_service_account").
+	query, args, err := r.Builder.Select(userColumns...).
 		From("users").
 		Where(squirrel.Eq{"username": username}).
 		ToSql()
@@ -188,21 +198,51 @@ func (r *SQLiteRepository) GetUserByUsername(ctx context.Context, username strin
 		return repo.User{}, fmt.Errorf("failed to build get user by username query: %w", err)
 	}
 
-	var user repo.User
-	var idStr string
-	err = r.DB.QueryRowContext(ctx, query, args...).Scan(&idStr, &user.Username, &user.PasswordHash, &user.IsAdmin, &user.IsServiceAccount)
+	user, err := scanUser(r.DB.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return repo.User{}, customerrors.ErrNotFound
 		}
 		return repo.User{}, fmt.Errorf("failed to scan user by username: %w", err)
 	}
-	user.ID = repo.ULID(idStr)
 
 	return user, nil
 }
 
-// SetUserPermissions creates, updates, or deletes database-specific permissions for a user.
+// RecordUserLogin sets the last login and activity of a user. Older timestamps than the stored ones are ignored.
+func (r *SQLiteRepository) RecordUserLogin(ctx context.Context, id repo.ULID, at time.Time) error {
+	millis := at.UnixMilli()
+	query, args, err := r.Builder.Update("users").
+		Set("last_login_at", squirrel.Expr("MAX(COALESCE(last_login_at, 0), ?)", millis)).
+		Set("last_activity_at", squirrel.Expr("MAX(COALESCE(last_activity_at, 0), ?)", millis)).
+		Where(squirrel.Eq{"id": id.String()}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build record login query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record user login: %w", err)
+	}
+	return nil
+}
+
+// RecordUserActivity sets the last activity of a user. Older timestamps than the stored one are ignored.
+func (r *SQLiteRepository) RecordUserActivity(ctx context.Context, id repo.ULID, at time.Time) error {
+	query, args, err := r.Builder.Update("users").
+		Set("last_activity_at", squirrel.Expr("MAX(COALESCE(last_activity_at, 0), ?)", at.UnixMilli())).
+		Where(squirrel.Eq{"id": id.String()}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build record activity query: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record user activity: %w", err)
+	}
+	return nil
+}
+
 // SetUserPermissions creates, updates, or deletes database-specific permissions for a user.
 func (r *SQLiteRepository) SetUserPermissions(ctx context.Context, permissions repo.UserPermissions) error {
 	// If Roles is empty, the intention is to delete the permission entry.
@@ -309,3 +349,21 @@ func (r *SQLiteRepository) GetAllUserPermissions(ctx context.Context, userID rep
 
 	return permissions, nil
 }
+
+// scanUser scans a single users row (in userColumns order).
+func scanUser(row scanner) (repo.User, error) {
+	var user repo.User
+	var idStr string
+	var lastLoginAt, lastActivityAt sql.NullInt64
+	if err := row.Scan(&idStr, &user.Username, &user.PasswordHash, &user.IsAdmin, &user.IsServiceAccount, &user.Disabled, &lastLoginAt, &lastActivityAt, &user.StorageQuota); err != nil {
+		return repo.User{}, err
+	}
+	user.ID = repo.ULID(idStr)
+	if lastLoginAt.Valid {
+		user.LastLoginAt = time.UnixMilli(lastLoginAt.Int64)
+	}
+	if lastActivityAt.Valid {
+		user.LastActivityAt = time.UnixMilli(lastActivityAt.Int64)
+	}
+	return user, nil
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"mediahub_oss/internal/repository"
+)
+
+// autoVacuumModes maps the values of 'PRAGMA auto_vacuum' to their names.
+var autoVacuumModes = map[int]string{0: "none", 1: "full", 2: "incremental"}
+
+// GetFileStats reads the size and the number of free pages of the database file.
+func (r *SQLiteRepository) GetFileStats(ctx context.Context) (repository.FileStats, error) {
+	var stats repository.FileStats
+	var autoVacuum int
+	for _, p := range []struct {
+		pragma string
+		dest   any
+	}{
+		{"page_size", &stats.PageSize},
+		{"page_count", &stats.PageCount},
+		{"freelist_count", &stats.FreelistPages},
+		{"auto_vacuum", &autoVacuum},
+	} {
+		if err := r.DB.QueryRowContext(ctx, "PRAGMA "+p.pragma).Scan(p.dest); err != nil {
+			return repository.FileStats{}, fmt.Errorf("failed to read %s: %w", p.pragma, err)
+		}
+	}
+	stats.SizeBytes = stats.PageSize * stats.PageCount
+	stats.AutoVacuum = autoVacuumModes[autoVacuum]
+	return stats, nil
+}
+
+// IncrementalVacuum moves up to pages free pages to the end of the file and truncates it, 0 releases
+// all of them. It only has an effect on databases with auto_vacuum = INCREMENTAL, which is set for
+// new files; older files have to be converted once with Vacuum.
+func (r *SQLiteRepository) IncrementalVacuum(ctx context.Context, pages int64) (int64, error) {
+	if pages < 0 {
+		return 0, fmt.Errorf("invalid number of pages: %d", pages)
+	}
+
+	var before, after int64
+	if err := r.DB.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&before); err != nil {
+		return 0, fmt.Errorf("failed to read freelist_count: %w", err)
+	}
+	if before == 0 {
+		return 0, nil
+	}
+	if _, err := r.DB.ExecContext(ctx, fmt.Sprintf("PRAGMA incremental_vacuum(%d)", pages)); err != nil {
+		return 0, fmt.Errorf("incremental vacuum failed: %w", err)
+	}
+	// In WAL mode the file is truncated when the pages are checkpointed
+	if _, err := r.DB.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return 0, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	if err := r.DB.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&after); err != nil {
+		return 0, fmt.Errorf("failed to read freelist_count: %w", err)
+	}
+	return before - after, nil
+}
+
+// Vacuum rebuilds the database file with auto_vacuum = INCREMENTAL, which releases all free pages
+// and converts files created before incremental vacuuming was enabled. It rewrites the whole file,
+// needs up to twice its size on disk and blocks all other queries while it runs.
+func (r *SQLiteRepository) Vacuum(ctx context.Context) error {
+	// The mode of an existing file only changes with the next VACUUM
+	if _, err := r.DB.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return fmt.Errorf("failed to set auto_vacuum: %w", err)
+	}
+	if _, err := r.DB.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("vacuum failed: %w", err)
+	}
+	// Shrink the WAL, which holds a copy of every rewritten page
+	if _, err := r.DB.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestIncrementalVacuum(t *testing.T) {
+	ctx := context.Background()
+
+	dbPath := filepath.Join(t.TempDir(), "mediahub.db")
+	r, err := sqlite.NewRepository(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// 1. New files use incremental vacuuming
+	stats, err := r.GetFileStats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get file stats: %v", err)
+	}
+	if stats.AutoVacuum != "incremental" || stats.SizeBytes != stats.PageSize*stats.PageCount {
+		t.Fatalf("expected an incrementally vacuumed file, got %+v", stats)
+	}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{
+		Name:         "Vacuum",
+		ContentType:  "file",
+		CustomFields: []repo.CustomFieldDef{{Name: "payload", Type: "TEXT"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// 2. Deleting entries leaves their pages on the freelist
+	payload := strings.Repeat("x", 4000)
+	var ids []int64
+	for range 300 {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:     "blob.bin",
+			Status:       repo.EntryStatusReady,
+			MimeType:     "application/octet-stream",
+			CustomFields: map[string]any{"payload": payload},
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	if _, err := r.DeleteEntries(ctx, db.ID, ids); err != nil {
+		t.Fatalf("failed to delete entries: %v", err)
+	}
+
+	full, err := r.GetFileStats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get file stats: %v", err)
+	}
+	if full.FreelistPages < 200 {
+		t.Fatalf("expected the deleted entries on the freelist, got %+v", full)
+	}
+	if _, err := r.DB.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		t.Fatalf("failed to checkpoint: %v", err)
+	}
+	sizeBefore := fileSize(t, dbPath)
+
+	// 3. A limited run releases that many pages
+	released, err := r.IncrementalVacuum(ctx, 10)
+	if err != nil {
+		t.Fatalf("incremental vacuum failed: %v", err)
+	}
+	if released != 10 {
+		t.Errorf("expected 10 released pages, got %d", released)
+	}
+
+	// 4. An unlimited run drains the freelist and shrinks the file
+	released, err = r.IncrementalVacuum(ctx, 0)
+	if err != nil {
+		t.Fatalf("incremental vacuum failed: %v", err)
+	}
+	if uint64(released) != full.FreelistPages-10 {
+		t.Errorf("expected %d released pages, got %d", full.FreelistPages-10, released)
+	}
+	drained, err := r.GetFileStats(ctx)
+	if err != nil {
+		t.Fatalf("failed to get file stats: %v", err)
+	}
+	if drained.FreelistPages != 0 || drained.PageCount != full.PageCount-full.FreelistPages {
+		t.Errorf("expected a drained freelist and %d pages, got %+v", full.PageCount-full.FreelistPages, drained)
+	}
+	if sizeAfter := fileSize(t, dbPath); sizeAfter >= sizeBefore || uint64(sizeAfter) != drained.SizeBytes {
+		t.Errorf("expected the file to shrink from %d to %d bytes, got %d", sizeBefore, drained.SizeBytes, sizeAfter)
+	}
+
+	// 5. Nothing left to release
+	if released, err := r.IncrementalVacuum(ctx, 0); err != nil || released != 0 {
+		t.Errorf("expected nothing to release, got %d (err %v)", released, err)
+	}
+}
+
+func TestVacuumConvertsExistingFile(t *testing.T) {
+	ctx := context.Background()
+
+	// A file created before incremental vacuuming was enabled
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+	legacy, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open legacy file: %v", err)
+	}
+	if _, err := legacy.ExecContext(ctx, "CREATE TABLE blobs (data TEXT)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	legacy.Close()
+
+	r, err := sqlite.NewRepository(dbPath)
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+	if stats, err := r.GetFileStats(ctx); err != nil || stats.AutoVacuum != "none" {
+		t.Fatalf("expected the pragma to have no effect on an existing file, got %+v (err %v)", stats, err)
+	}
+
+	if err := r.Vacuum(ctx); err != nil {
+		t.Fatalf("vacuum failed: %v", err)
+	}
+	if stats, err := r.GetFileStats(ctx); err != nil || stats.AutoVacuum != "incremental" {
+		t.Errorf("expected the file to be converted, got %+v (err %v)", stats, err)
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat %s: %v", path, err)
+	}
+	return info.Size()
+}
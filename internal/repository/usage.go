@@ -0,0 +1,26 @@
+package repository
+
+import "time"
+
+// Groupings of the usage report.
+const (
+	UsageByUser     = "user"
+	UsageByDatabase = "database"
+)
+
+// UsageFilter selects the usage of the entries uploaded in a time range, by whole UTC days. Zero times
+// leave the range open.
+type UsageFilter struct {
+	GroupBy string // UsageByUser or UsageByDatabase
+	From    time.Time
+	To      time.Time // exclusive, the day it falls into is included unless it is the start of that day
+}
+
+// UsageRecord is the number and size of the stored entries of an uploader or a database. Username is
+// empty when grouped by database, DatabaseID when grouped by user.
+type UsageRecord struct {
+	Username   string
+	DatabaseID ULID
+	Entries    int64
+	Bytes      int64 // files, previews and kept originals
+}
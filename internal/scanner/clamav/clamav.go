@@ -0,0 +1,120 @@
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"mediahub_oss/internal/scanner"
+)
+
+// chunkSize is the size of the INSTREAM chunks sent to clamd.
+const chunkSize = 64 * 1024
+
+// ClamAVScanner talks to a clamd daemon using the INSTREAM command.
+type ClamAVScanner struct {
+	network string // "tcp" or "unix"
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the given clamd address, either "tcp://host:port"
+// or "unix:///path/to/clamd.sock". The timeout bounds a single scan including the upload.
+func NewClamAVScanner(address string, timeout time.Duration) (*ClamAVScanner, error) {
+	var network, addr string
+	switch {
+	case strings.HasPrefix(address, "tcp://"):
+		network, addr = "tcp", strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "unix://"):
+		network, addr = "unix", strings.TrimPrefix(address, "unix://")
+	default:
+		return nil, fmt.Errorf("invalid clamd address '%s': must start with tcp:// or unix://", address)
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("invalid clamd address '%s': missing host or socket path", address)
+	}
+
+	return &ClamAVScanner{network: network, address: addr, timeout: timeout}, nil
+}
+
+// Scan streams the content to clamd and parses its verdict.
+func (s *ClamAVScanner) Scan(ctx context.Context, content io.Reader) (scanner.Result, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return scanner.Result{}, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Abort blocking reads/writes if the context is cancelled before the deadline
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return scanner.Result{}, fmt.Errorf("failed to send INSTREAM command: %w", err)
+	}
+
+	// Each chunk is prefixed with its length (4 bytes, network byte order); a zero length ends the stream
+	buf := make([]byte, chunkSize)
+	var size [4]byte
+	for {
+		n, readErr := content.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := conn.Write(size[:]); err != nil {
+				return scanner.Result{}, fmt.Errorf("failed to send chunk to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return scanner.Result{}, fmt.Errorf("failed to send chunk to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return scanner.Result{}, fmt.Errorf("failed to read content for scanning: %w", readErr)
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return scanner.Result{}, fmt.Errorf("failed to terminate stream: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(err == io.EOF && reply != "") {
+		return scanner.Result{}, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return parseReply(reply)
+}
+
+// parseReply interprets a clamd reply such as "stream: OK" or "stream: Eicar-Signature FOUND".
+func parseReply(reply string) (scanner.Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	verdict := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+
+	switch {
+	case verdict == "OK":
+		return scanner.Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return scanner.Result{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	default:
+		// e.g. "INSTREAM size limit exceeded. ERROR"
+		return scanner.Result{}, fmt.Errorf("clamd returned an error: %s", reply)
+	}
+}
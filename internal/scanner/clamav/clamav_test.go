@@ -0,0 +1,117 @@
+package clamav
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts a single INSTREAM session and answers with reply(payload).
+func fakeClamd(t *testing.T, reply func(payload []byte) string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		cmd := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+			return
+		}
+
+		var payload bytes.Buffer
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size[:])
+			if n == 0 {
+				break
+			}
+			if _, err := io.CopyN(&payload, conn, int64(n)); err != nil {
+				return
+			}
+		}
+
+		if answer := reply(payload.Bytes()); answer != "" {
+			conn.Write([]byte(answer + "\x00"))
+		}
+	}()
+
+	return "tcp://" + ln.Addr().String()
+}
+
+func TestScan(t *testing.T) {
+	verdict := func(payload []byte) string {
+		if bytes.Contains(payload, []byte("EICAR")) {
+			return "stream: Eicar-Signature FOUND"
+		}
+		return "stream: OK"
+	}
+
+	// Larger than one chunk to exercise the chunked protocol
+	clean := strings.Repeat("a", chunkSize*2+17)
+
+	s, err := NewClamAVScanner(fakeClamd(t, verdict), time.Second)
+	if err != nil {
+		t.Fatalf("failed to create scanner: %v", err)
+	}
+	res, err := s.Scan(context.Background(), strings.NewReader(clean))
+	if err != nil || res.Infected {
+		t.Errorf("expected clean result, got %+v (err: %v)", res, err)
+	}
+
+	s, _ = NewClamAVScanner(fakeClamd(t, verdict), time.Second)
+	res, err = s.Scan(context.Background(), strings.NewReader("X5O!P%@AP EICAR test"))
+	if err != nil || !res.Infected || res.Signature != "Eicar-Signature" {
+		t.Errorf("expected infected result, got %+v (err: %v)", res, err)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	// clamd reports an error
+	addr := fakeClamd(t, func([]byte) string { return "INSTREAM size limit exceeded. ERROR" })
+	s, _ := NewClamAVScanner(addr, time.Second)
+	if _, err := s.Scan(context.Background(), strings.NewReader("data")); err == nil {
+		t.Errorf("expected an error for a clamd ERROR reply")
+	}
+
+	// clamd never answers, the timeout must end the scan
+	addr = fakeClamd(t, func([]byte) string { time.Sleep(2 * time.Second); return "" })
+	s, _ = NewClamAVScanner(addr, 100*time.Millisecond)
+	start := time.Now()
+	if _, err := s.Scan(context.Background(), strings.NewReader("data")); err == nil {
+		t.Errorf("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("scan did not respect the timeout, took %v", elapsed)
+	}
+
+	// Unreachable daemon
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedAddr := "tcp://" + ln.Addr().String()
+	ln.Close()
+	s, _ = NewClamAVScanner(closedAddr, time.Second)
+	if _, err := s.Scan(context.Background(), strings.NewReader("data")); err == nil {
+		t.Errorf("expected a connection error")
+	}
+
+	if _, err := NewClamAVScanner("localhost:3310", time.Second); err == nil {
+		t.Errorf("expected an error for an address without scheme")
+	}
+}
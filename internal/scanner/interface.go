@@ -0,0 +1,19 @@
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+// Scanner checks uploaded files for malware before they are moved to permanent storage.
+type Scanner interface {
+	// Scan reads the full stream and reports whether it is infected.
+	// An error means the scan could not be completed (e.g. the scanner is unreachable).
+	Scan(ctx context.Context, content io.Reader) (Result, error)
+}
+
+// Result is the verdict of a single scan.
+type Result struct {
+	Infected  bool
+	Signature string // name of the detected signature, empty if clean
+}
@@ -0,0 +1,160 @@
+package seed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"math/rand/v2"
+)
+
+// File is a generated file with the mime type and extension it is uploaded with.
+type File struct {
+	Data      []byte
+	MimeType  string
+	Extension string
+}
+
+// Bounds of the generated images, larger files are padded instead.
+const (
+	minImageSide = 16
+	maxImageSide = 2048
+)
+
+// bytesPerPixel estimates the encoded size of the noise images conservatively, so they
+// rarely exceed the target size and are padded up to it.
+const bytesPerPixel = 8
+
+// wavSampleRate is the rate of the generated 16-bit mono tones.
+const wavSampleRate = 16000
+
+// ContentTypes are the content types of the databases Generate creates files for.
+var ContentTypes = []string{"image", "audio", "file"}
+
+// Generate creates a file of about size bytes for a database of the content type: a noise or
+// gradient JPEG or PNG for images, a sine tone as WAV for audio and random bytes otherwise.
+// Images are padded to the size with metadata the decoders skip, tones last as long as the size allows.
+func Generate(rng *rand.Rand, contentType string, size uint64) (File, error) {
+	switch contentType {
+	case "image":
+		return generateImage(rng, size)
+	case "audio":
+		return generateTone(rng, size), nil
+	case "file":
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(rng.Uint32())
+		}
+		return File{Data: data, MimeType: "application/octet-stream", Extension: "bin"}, nil
+	default:
+		return File{}, fmt.Errorf("cannot generate files for content type '%s', expected image, audio or file", contentType)
+	}
+}
+
+func generateImage(rng *rand.Rand, size uint64) (File, error) {
+	side := int(math.Sqrt(float64(size / bytesPerPixel)))
+	side = min(max(side, minImageSide), maxImageSide)
+	width, height := side, side*3/4
+
+	// A gradient between two random colors, with noise on about half of the images
+	from := color.RGBA{uint8(rng.Uint32()), uint8(rng.Uint32()), uint8(rng.Uint32()), 255}
+	to := color.RGBA{uint8(rng.Uint32()), uint8(rng.Uint32()), uint8(rng.Uint32()), 255}
+	noise := 0
+	if rng.IntN(2) == 0 {
+		noise = 32 + rng.IntN(96)
+	}
+	mix := func(a, b uint8, t float64) int { return int(float64(a) + (float64(b)-float64(a))*t) }
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			t := float64(x+y) / float64(width+height)
+			rgb := [3]int{mix(from.R, to.R, t), mix(from.G, to.G, t), mix(from.B, to.B, t)}
+			for i := range rgb {
+				if noise > 0 {
+					rgb[i] += rng.IntN(2*noise+1) - noise
+				}
+				rgb[i] = min(max(rgb[i], 0), 255)
+			}
+			img.SetRGBA(x, y, color.RGBA{uint8(rgb[0]), uint8(rgb[1]), uint8(rgb[2]), 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if rng.IntN(2) == 0 {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return File{}, fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+		return File{Data: padJPEG(buf.Bytes(), size), MimeType: "image/jpeg", Extension: "jpg"}, nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return File{}, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return File{Data: padPNG(buf.Bytes(), size), MimeType: "image/png", Extension: "png"}, nil
+}
+
+// padJPEG inserts comment segments after the start of image marker until the file has the size.
+func padJPEG(data []byte, size uint64) []byte {
+	const segmentHeader = 4 // marker and length
+	missing := int(size) - len(data)
+	if missing < segmentHeader {
+		return data
+	}
+	var padding bytes.Buffer
+	for missing >= segmentHeader {
+		n := min(missing-segmentHeader, math.MaxUint16-2)
+		padding.Write([]byte{0xFF, 0xFE})
+		padding.Write(binary.BigEndian.AppendUint16(nil, uint16(n+2)))
+		padding.Write(bytes.Repeat([]byte{' '}, n))
+		missing -= n + segmentHeader
+	}
+	return append(append(data[:2:2], padding.Bytes()...), data[2:]...)
+}
+
+// padPNG inserts a private ancillary chunk before the IEND chunk until the file has the size.
+func padPNG(data []byte, size uint64) []byte {
+	const chunkOverhead = 12 // length, type and CRC
+	const iendSize = 12
+	missing := int(size) - len(data)
+	if missing < chunkOverhead || len(data) < iendSize {
+		return data
+	}
+	chunk := make([]byte, missing)
+	binary.BigEndian.PutUint32(chunk, uint32(missing-chunkOverhead))
+	copy(chunk[4:], "paDd") // ancillary, private, safe to copy
+	binary.BigEndian.PutUint32(chunk[len(chunk)-4:], crc32.ChecksumIEEE(chunk[4:len(chunk)-4]))
+
+	end := len(data) - iendSize
+	return append(append(data[:end:end], chunk...), data[end:]...)
+}
+
+// generateTone creates a 16-bit mono WAV of a sine tone whose length follows from the size.
+func generateTone(rng *rand.Rand, size uint64) File {
+	const headerSize = 44
+	samples := max(int(size)-headerSize, 2) / 2
+	frequency := 220 + rng.Float64()*660
+	volume := 0.2 + rng.Float64()*0.6
+
+	data := make([]byte, headerSize+2*samples)
+	copy(data, "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)              // size of the format chunk
+	binary.LittleEndian.PutUint16(data[20:], 1)               // PCM
+	binary.LittleEndian.PutUint16(data[22:], 1)               // mono
+	binary.LittleEndian.PutUint32(data[24:], wavSampleRate)   // sample rate
+	binary.LittleEndian.PutUint32(data[28:], 2*wavSampleRate) // byte rate
+	binary.LittleEndian.PutUint16(data[32:], 2)               // block align
+	binary.LittleEndian.PutUint16(data[34:], 16)              // bits per sample
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(2*samples))
+	for i := range samples {
+		sample := volume * math.Sin(2*math.Pi*frequency*float64(i)/wavSampleRate)
+		binary.LittleEndian.PutUint16(data[headerSize+2*i:], uint16(int16(sample*math.MaxInt16)))
+	}
+	return File{Data: data, MimeType: "audio/wav", Extension: "wav"}
+}
@@ -0,0 +1,236 @@
+// Package seed fills a database with generated entries for demos, load and smoke tests. The entries
+// are uploaded through the processor like uploads of the API, so they are probed, converted and
+// counted like real ones.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+)
+
+// UploadedBy is the uploader recorded on the generated entries.
+const UploadedBy = "seed"
+
+// Options describe a seed run.
+type Options struct {
+	DatabaseName string
+	ContentType  string // of the database if it is created, must match an existing one if set
+	Count        int
+	Sizes        SizeRange
+	Window       time.Duration // the timestamps are spread over [Now-Window, Now]
+	Now          time.Time     // zero for the current time
+	Fields       []FieldSpec
+	Previews     bool // create the database with previews, existing databases keep their setting
+	Append       bool // add to a database that already has entries
+	Concurrency  int  // uploads processed at the same time, at least 1
+	Seed         uint64
+	Progress     func(Progress) // called after every entry, from the uploading goroutines
+}
+
+// Progress is the state of a running seed.
+type Progress struct {
+	Done   int // entries created
+	Failed int
+	Total  int
+	Bytes  uint64 // size of the created entries
+}
+
+// Result summarizes a seed run.
+type Result struct {
+	Database repo.Database
+	Created  bool // the database was created by the run
+	Progress
+}
+
+// Run creates the database if it does not exist and uploads Count generated entries to it. Existing
+// databases with entries are refused unless Append is set. The contents depend only on Seed, not on
+// the concurrency. The processor should allow Concurrency synchronous uploads, otherwise uploads are
+// queued and finished in the background. Failed uploads do not stop the run, the first is returned.
+func Run(ctx context.Context, r repo.Repository, proc *processing.Processor, opts Options) (Result, error) {
+	if opts.Count <= 0 {
+		return Result{}, fmt.Errorf("%w: the count must be at least 1", customerrors.ErrValidation)
+	}
+	if opts.Sizes.Min == 0 || opts.Sizes.Max < opts.Sizes.Min {
+		return Result{}, fmt.Errorf("%w: invalid size range %d..%d", customerrors.ErrValidation, opts.Sizes.Min, opts.Sizes.Max)
+	}
+	if opts.Window < 0 {
+		return Result{}, fmt.Errorf("%w: the time window must not be negative", customerrors.ErrValidation)
+	}
+	if opts.ContentType != "" && !slices.Contains(ContentTypes, opts.ContentType) {
+		return Result{}, fmt.Errorf("%w: cannot generate %s entries, only %s", customerrors.ErrValidation, opts.ContentType, strings.Join(ContentTypes, ", "))
+	}
+	opts.Concurrency = max(opts.Concurrency, 1)
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	db, created, err := prepareDatabase(ctx, r, opts)
+	if err != nil {
+		return Result{}, err
+	}
+	result := Result{Database: db, Created: created, Progress: Progress{Total: opts.Count}}
+
+	// Every entry gets its own seed, so the content does not depend on the order of the uploads
+	master := rand.New(rand.NewPCG(opts.Seed, uint64(opts.Count)))
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i := range opts.Count {
+			select {
+			case jobs <- job{index: i, seed: master.Uint64()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for range opts.Concurrency {
+		wg.Go(func() {
+			for j := range jobs {
+				size, err := upload(ctx, proc, db, opts, j)
+
+				mu.Lock()
+				if err != nil {
+					result.Failed++
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to upload entry %d: %w", j.index+1, err)
+					}
+				} else {
+					result.Done++
+					result.Bytes += size
+				}
+				if opts.Progress != nil {
+					opts.Progress(result.Progress)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	return result, firstErr
+}
+
+type job struct {
+	index int
+	seed  uint64
+}
+
+// upload generates the entry of a job and processes it, returning the size of the file.
+func upload(ctx context.Context, proc *processing.Processor, db repo.Database, opts Options, j job) (uint64, error) {
+	rng := rand.New(rand.NewPCG(j.seed, uint64(j.index)))
+	file, err := Generate(rng, db.ContentType, opts.Sizes.pick(rng))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := opts.Now.Add(-time.Duration(rng.Int64N(int64(opts.Window/time.Millisecond)+1)) * time.Millisecond)
+	fields := make(map[string]any, len(opts.Fields))
+	for _, field := range opts.Fields {
+		fields[field.Name] = field.gen(rng)
+	}
+	fileName := fmt.Sprintf("seed_%06d.%s", j.index+1, file.Extension)
+
+	req := processing.EntryRequest{
+		Timestamp:    timestamp.UnixMilli(),
+		FileName:     fileName,
+		CustomFields: fields,
+		Origin:       repo.UploadOrigin{UploadedBy: UploadedBy},
+		Size:         int64(len(file.Data)),
+		SyncPreview:  db.Config.CreatePreview, // finished before the run ends
+	}
+	entry, _, err := proc.ProcessEntry(ctx, db, req, bytes.NewReader(file.Data), file.MimeType, fileName)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Size, nil
+}
+
+// prepareDatabase returns the database of the run, created if it does not exist yet.
+func prepareDatabase(ctx context.Context, r repo.Repository, opts Options) (repo.Database, bool, error) {
+	databases, err := r.GetDatabases(ctx)
+	if err != nil {
+		return repo.Database{}, false, fmt.Errorf("failed to list databases: %w", err)
+	}
+	for _, db := range databases {
+		if db.Name != opts.DatabaseName {
+			continue
+		}
+		db, err := r.GetDatabase(ctx, db.ID)
+		if err != nil {
+			return repo.Database{}, false, fmt.Errorf("failed to get database '%s': %w", opts.DatabaseName, err)
+		}
+		if err := checkExisting(ctx, r, db, opts); err != nil {
+			return repo.Database{}, false, err
+		}
+		return db, false, nil
+	}
+
+	if opts.ContentType == "" {
+		return repo.Database{}, false, fmt.Errorf("%w: database '%s' does not exist, a content type is required to create it", customerrors.ErrValidation, opts.DatabaseName)
+	}
+	newDB := repo.Database{
+		Name:        opts.DatabaseName,
+		ContentType: opts.ContentType,
+		Config:      repo.DatabaseConfig{CreatePreview: opts.Previews},
+	}
+	for _, field := range opts.Fields {
+		newDB.CustomFields = append(newDB.CustomFields, repo.CustomFieldDef{Name: field.Name, Type: field.Type})
+	}
+	db, err := r.CreateDatabase(ctx, newDB)
+	if err != nil {
+		return repo.Database{}, false, fmt.Errorf("failed to create database '%s': %w", opts.DatabaseName, err)
+	}
+	return db, true, nil
+}
+
+// checkExisting refuses existing databases of another content type, without the generated fields
+// or, unless appending, with entries.
+func checkExisting(ctx context.Context, r repo.Repository, db repo.Database, opts Options) error {
+	if opts.ContentType != "" && opts.ContentType != db.ContentType {
+		return fmt.Errorf("%w: database '%s' holds %s entries, not %s", customerrors.ErrValidation, db.Name, db.ContentType, opts.ContentType)
+	}
+	if !slices.Contains(ContentTypes, db.ContentType) {
+		return fmt.Errorf("%w: cannot generate %s entries for database '%s', only %s", customerrors.ErrValidation, db.ContentType, db.Name, strings.Join(ContentTypes, ", "))
+	}
+	var problems []string
+	for _, field := range opts.Fields {
+		i := slices.IndexFunc(db.CustomFields, func(cf repo.CustomFieldDef) bool { return cf.Name == field.Name })
+		if i < 0 {
+			problems = append(problems, fmt.Sprintf("custom field '%s' does not exist", field.Name))
+		} else if !strings.EqualFold(db.CustomFields[i].Type, field.Type) {
+			problems = append(problems, fmt.Sprintf("custom field '%s' is %s, the generator produces %s", field.Name, db.CustomFields[i].Type, field.Type))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: database '%s': %s", customerrors.ErrValidation, db.Name, strings.Join(problems, ", "))
+	}
+
+	if opts.Append {
+		return nil
+	}
+	stats, err := r.GetDatabaseStats(ctx, db.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get the statistics of database '%s': %w", db.Name, err)
+	}
+	if stats.EntryCount > 0 {
+		return fmt.Errorf("%w: database '%s' already has %d entries, append to add more", customerrors.ErrConflict, db.Name, stats.EntryCount)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// probelessConverter neither converts nor probes, like a server without FFmpeg.
+type probelessConverter struct {
+	media.MediaConverter
+}
+
+func (c *probelessConverter) CanCreatePreview(string) bool { return false }
+
+func (c *probelessConverter) CanConvert(string, string) media.ConversionCheck {
+	return media.ConversionCheck{}
+}
+
+func (c *probelessConverter) ReadMediaFieldsFromStream(context.Context, io.ReadSeeker, string) (map[string]any, error) {
+	return nil, customerrors.ErrDependencies
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, &probelessConverter{}, 1, 3, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var fields []FieldSpec
+	for _, spec := range []string{"ml_score=rand(0,1)", "camera=choice(front, back)"} {
+		field, err := ParseFieldSpec(spec)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", spec, err)
+		}
+		fields = append(fields, field)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var progress []Progress
+	opts := Options{
+		DatabaseName: "Demo",
+		ContentType:  "image",
+		Count:        12,
+		Sizes:        SizeRange{Min: 2 << 10, Max: 40 << 10},
+		Window:       30 * 24 * time.Hour,
+		Now:          now,
+		Fields:       fields,
+		Concurrency:  3,
+		Seed:         42,
+		Progress:     func(p Progress) { progress = append(progress, p) },
+	}
+
+	// 1. The database is created with the fields and filled
+	result, err := Run(ctx, r, proc, opts)
+	if err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+	if !result.Created || result.Done != 12 || result.Failed != 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if len(progress) != 12 || progress[11].Done != 12 || progress[11].Bytes != result.Bytes {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+
+	stats, err := r.GetDatabaseStats(ctx, result.Database.ID)
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.EntryCount != 12 || stats.TotalDiskSpaceBytes != result.Bytes {
+		t.Errorf("expected 12 entries of %d bytes, got %+v", result.Bytes, stats)
+	}
+	oldest, newest, err := r.GetEntryTimeRange(ctx, result.Database.ID)
+	if err != nil || oldest.Before(now.Add(-opts.Window)) || newest.After(now) || !oldest.Before(newest) {
+		t.Errorf("timestamps outside of the window: %v..%v (%v)", oldest, newest, err)
+	}
+
+	// 2. The generated values are searchable
+	search := func(conditions ...repo.Condition) []repo.Entry {
+		t.Helper()
+		entries, err := r.SearchEntries(ctx, result.Database.ID, repo.SearchRequest{
+			Filter:     &repo.FilterGroup{Operator: "and", Conditions: conditions},
+			Pagination: repo.Pagination{Limit: 100},
+		}, result.Database.CustomFields)
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		return entries
+	}
+	if n := len(search(repo.Condition{Field: "ml_score", Operator: ">=", Value: 0.0}, repo.Condition{Field: "ml_score", Operator: "<", Value: 1.0})); n != 12 {
+		t.Errorf("expected 12 scores in [0, 1), got %d", n)
+	}
+	front := search(repo.Condition{Field: "camera", Operator: "=", Value: "front"})
+	back := search(repo.Condition{Field: "camera", Operator: "=", Value: "back"})
+	if len(front) == 0 || len(back) == 0 || len(front)+len(back) != 12 {
+		t.Errorf("expected both cameras, got %d front and %d back", len(front), len(back))
+	}
+	for _, entry := range append(front, back...) {
+		if entry.Size < opts.Sizes.Min || entry.Size > opts.Sizes.Max || entry.Status != repo.EntryStatusReady {
+			t.Errorf("entry %d (%s) has %d bytes and status %v", entry.ID, entry.FileName, entry.Size, entry.Status)
+		}
+	}
+
+	// 3. Entries are only added on request, to a matching database
+	if _, err := Run(ctx, r, proc, opts); !errors.Is(err, customerrors.ErrConflict) {
+		t.Errorf("expected a conflict for a filled database, got %v", err)
+	}
+	mismatch := opts
+	mismatch.Append, mismatch.ContentType = true, "audio"
+	if _, err := Run(ctx, r, proc, mismatch); !errors.Is(err, customerrors.ErrValidation) {
+		t.Errorf("expected a validation error for another content type, got %v", err)
+	}
+	opts.Append, opts.Count, opts.Progress = true, 3, nil
+	if result, err := Run(ctx, r, proc, opts); err != nil || result.Created || result.Done != 3 {
+		t.Fatalf("expected 3 more entries, got %+v (%v)", result, err)
+	}
+	if stats, _ := r.GetDatabaseStats(ctx, result.Database.ID); stats.EntryCount != 15 {
+		t.Errorf("expected 15 entries, got %d", stats.EntryCount)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 8 {
+		for _, size := range []uint64{3 << 10, 300 << 10} {
+			file, err := Generate(rng, "image", size)
+			if err != nil {
+				t.Fatalf("failed to generate image: %v", err)
+			}
+			if uint64(len(file.Data)) != size {
+				t.Errorf("expected a %s of %d bytes, got %d", file.MimeType, size, len(file.Data))
+			}
+			img, format, err := image.Decode(bytes.NewReader(file.Data))
+			if err != nil || "image/"+format != file.MimeType || img.Bounds().Dx() < minImageSide {
+				t.Errorf("the padded %s does not decode: %v", file.MimeType, err)
+			}
+		}
+	}
+
+	tone, err := Generate(rng, "audio", 32044)
+	if err != nil || len(tone.Data) != 32044 || string(tone.Data[:4]) != "RIFF" || string(tone.Data[36:40]) != "data" {
+		t.Errorf("unexpected tone of %d bytes (%v)", len(tone.Data), err)
+	}
+	if _, err := Generate(rng, "video", 1000); err == nil {
+		t.Error("expected an error for video")
+	}
+}
+
+func TestParseSpecs(t *testing.T) {
+	for s, want := range map[string]SizeRange{
+		"50KB..2MB": {Min: 50 << 10, Max: 2 << 20},
+		"100K":      {Min: 100 << 10, Max: 100 << 10},
+	} {
+		if got, err := ParseSizeRange(s); err != nil || got != want {
+			t.Errorf("ParseSizeRange(%q) = %+v, %v", s, got, err)
+		}
+	}
+	for _, s := range []string{"", "2MB..50KB", "0..1KB", "1KB..lots"} {
+		if _, err := ParseSizeRange(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	for s, check := range map[string]func(any) bool{
+		"score=rand(0.5, 2)":  func(v any) bool { f, ok := v.(float64); return ok && f >= 0.5 && f < 2 },
+		"frame=randint(-3,3)": func(v any) bool { n, ok := v.(int64); return ok && n >= -3 && n <= 3 },
+		"side=choice(l,r)":    func(v any) bool { return v == "l" || v == "r" },
+		"flag=bool(1)":        func(v any) bool { return v == true },
+	} {
+		field, err := ParseFieldSpec(s)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", s, err)
+		}
+		for range 20 {
+			if v := field.gen(rng); !check(v) {
+				t.Errorf("%s generated %v", s, v)
+			}
+		}
+	}
+	for _, s := range []string{"score", "=rand(0,1)", "score=rand(1,0)", "score=rand(0)", "n=randint(0,x)", "c=choice()", "f=bool(2)", "x=gauss(0,1)", "x=rand(0,1"} {
+		if _, err := ParseFieldSpec(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"mediahub_oss/internal/shared"
+)
+
+// SizeRange is the range of the generated file sizes in bytes, both inclusive.
+type SizeRange struct {
+	Min uint64
+	Max uint64
+}
+
+// ParseSizeRange parses "50KB..2MB", or a single size like "100KB" for files of one size.
+// The sizes use the syntax of shared.ParseSize.
+func ParseSizeRange(s string) (SizeRange, error) {
+	lower, upper, isRange := strings.Cut(s, "..")
+	if !isRange {
+		upper = lower
+	}
+	minSize, err := shared.ParseSize(lower)
+	if err != nil {
+		return SizeRange{}, fmt.Errorf("invalid size range '%s': %w", s, err)
+	}
+	maxSize, err := shared.ParseSize(upper)
+	if err != nil {
+		return SizeRange{}, fmt.Errorf("invalid size range '%s': %w", s, err)
+	}
+	if minSize == 0 || maxSize < minSize {
+		return SizeRange{}, fmt.Errorf("invalid size range '%s': expected a size above 0, or MIN..MAX with MIN <= MAX", s)
+	}
+	return SizeRange{Min: minSize, Max: maxSize}, nil
+}
+
+// pick returns a size of the range.
+func (s SizeRange) pick(rng *rand.Rand) uint64 {
+	return s.Min + rng.Uint64N(s.Max-s.Min+1)
+}
+
+// FieldSpec generates the values of a custom field.
+type FieldSpec struct {
+	Name string
+	Type string // type of the generated values, TEXT, INTEGER, REAL or BOOLEAN
+	gen  func(rng *rand.Rand) any
+}
+
+// FieldSyntax lists the accepted generator expressions, for help texts and error messages.
+const FieldSyntax = `NAME=rand(MIN,MAX) (REAL), NAME=randint(MIN,MAX) (INTEGER), NAME=choice(A,B,...) (TEXT) or NAME=bool(P) (BOOLEAN, true with probability P)`
+
+// ParseFieldSpec parses a generator expression like "ml_score=rand(0,1)", see FieldSyntax.
+func ParseFieldSpec(s string) (FieldSpec, error) {
+	name, expr, ok := strings.Cut(s, "=")
+	name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+	fn, args, isCall := strings.Cut(expr, "(")
+	if !ok || name == "" || !isCall || !strings.HasSuffix(args, ")") {
+		return FieldSpec{}, fmt.Errorf("invalid custom field '%s': expected %s", s, FieldSyntax)
+	}
+	params := strings.Split(strings.TrimSuffix(args, ")"), ",")
+	for i := range params {
+		params[i] = strings.TrimSpace(params[i])
+	}
+
+	spec := FieldSpec{Name: name}
+	switch strings.ToLower(strings.TrimSpace(fn)) {
+	case "rand":
+		lower, upper, err := parseBounds(params, func(p string) (float64, error) { return strconv.ParseFloat(p, 64) })
+		if err != nil {
+			return FieldSpec{}, fmt.Errorf("invalid custom field '%s': %w", s, err)
+		}
+		spec.Type = "REAL"
+		spec.gen = func(rng *rand.Rand) any { return lower + rng.Float64()*(upper-lower) }
+	case "randint":
+		lower, upper, err := parseBounds(params, func(p string) (int64, error) { return strconv.ParseInt(p, 10, 64) })
+		if err == nil && upper-lower+1 <= 0 {
+			err = fmt.Errorf("the range is too large")
+		}
+		if err != nil {
+			return FieldSpec{}, fmt.Errorf("invalid custom field '%s': %w", s, err)
+		}
+		spec.Type = "INTEGER"
+		spec.gen = func(rng *rand.Rand) any { return lower + rng.Int64N(upper-lower+1) }
+	case "choice":
+		if len(params) == 1 && params[0] == "" {
+			return FieldSpec{}, fmt.Errorf("invalid custom field '%s': choice needs at least one value", s)
+		}
+		spec.Type = "TEXT"
+		spec.gen = func(rng *rand.Rand) any { return params[rng.IntN(len(params))] }
+	case "bool":
+		p, err := strconv.ParseFloat(params[0], 64)
+		if len(params) != 1 || err != nil || p < 0 || p > 1 {
+			return FieldSpec{}, fmt.Errorf("invalid custom field '%s': bool expects a probability between 0 and 1", s)
+		}
+		spec.Type = "BOOLEAN"
+		spec.gen = func(rng *rand.Rand) any { return rng.Float64() < p }
+	default:
+		return FieldSpec{}, fmt.Errorf("invalid custom field '%s': unknown generator '%s', expected %s", s, fn, FieldSyntax)
+	}
+	return spec, nil
+}
+
+// parseBounds parses the two bounds of rand and randint.
+func parseBounds[T int64 | float64](params []string, parse func(string) (T, error)) (T, T, error) {
+	if len(params) != 2 {
+		return 0, 0, fmt.Errorf("expected two bounds MIN,MAX")
+	}
+	lower, err := parse(params[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid lower bound '%s'", params[0])
+	}
+	upper, err := parse(params[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid upper bound '%s'", params[1])
+	}
+	if upper < lower {
+		return 0, 0, fmt.Errorf("the upper bound %v is below the lower bound %v", upper, lower)
+	}
+	return lower, upper, nil
+}
@@ -21,13 +21,22 @@ const (
 	ErrRepoUnavailable     = Error("could not connect to the repository")
 	ErrUserExists          = Error("user already exists")
 	ErrUserNotFound        = Error("user not found")
+	ErrGroupExists         = Error("group already exists")
 	ErrInvalidName         = Error("invalid name")
 	ErrDatabaseExists      = Error("database already exists")
 	ErrDatabaseNotExisting = Error("database does not exist")
+	ErrLegalHold           = Error("entry is under legal hold")
+	ErrLimitReached        = Error("limit reached")
+	ErrQuotaExceeded       = Error("storage quota exceeded")
 
 	// Media errors
 	ErrUnsupportedMedia = Error("unsupported media type")
 	ErrBadMimeType      = Error("mime type not matching content type")
+	ErrDependencies     = Error("required media tool support is missing")
+
+	// Scanner errors
+	ErrInfected           = Error("file is infected")
+	ErrScannerUnavailable = Error("virus scanner unavailable")
 
 	// Import errors
 	ErrUnmappedFieldAbort = Error("unmapped field encountered, aborting import")
@@ -38,9 +47,11 @@ const (
 
 	// Generic errors
 	ErrPermissionDenied = Error("permission denied")
+	ErrUnauthenticated  = Error("unauthenticated")
 	ErrNotFound         = Error("not found")
 	ErrUnavailable      = Error("service unavailable")
 	ErrValidation       = Error("validation error")
 	ErrNotImplemented   = Error("not implemented")
 	ErrConflict         = Error("conflict")
+	ErrGone             = Error("no longer available")
 )
@@ -3,13 +3,26 @@ package shared
 import (
 	"context"
 	"errors"
+	"fmt"
 	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
 	"mediahub_oss/internal/storage"
 )
 
+// BulkDeletion describes the outcome of DeleteMultipleAtomic.
+type BulkDeletion struct {
+	Deleted    []repository.DeletedEntryMeta // entries whose rows were removed from the database
+	Missing    []int64                       // requested IDs that did not exist
+	FileErrors map[int64]string              // per-ID warnings for files that could not be removed from storage
+}
+
 // DeleteSafe safely deletes a single entry from the DB and storage using a 2-Phase approach.
+// Entries under legal hold are refused with ErrLegalHold before anything is touched.
 // Returns the entry data of the deleted file and any error if encountered.
 func DeleteSafe(ctx context.Context, repo repository.Repository, storage storage.StorageProvider, dbID repository.ULID, id int64) (repository.DeletedEntryMeta, error) {
+	if err := refuseHeld(ctx, repo, dbID, []int64{id}); err != nil {
+		return repository.DeletedEntryMeta{}, err
+	}
 
 	// PHASE 1: LOCK
 	// Mark as "Deleting" so it disappears from normal API usage
@@ -29,8 +42,9 @@ func DeleteSafe(ctx context.Context, repo repository.Repository, storage storage
 		return repository.DeletedEntryMeta{}, err
 	}
 
-	// We only try to delete the preview if the main file deletion succeeded
+	// We only try to delete the preview and original if the main file deletion succeeded
 	_ = storage.DeletePreview(ctx, dbID.String(), id)
+	_ = storage.DeleteOriginal(ctx, dbID.String(), id)
 
 	// PHASE 3: COMMIT
 	// Hard delete the record that was successfully wiped from disk
@@ -43,11 +57,14 @@ func DeleteSafe(ctx context.Context, repo repository.Repository, storage storage
 }
 
 // Function to delete files with database entries in a 2-phase approach, to avoid discrepancies
-// between the database and the storage.
+// between the database and the storage. Nothing is deleted if any entry is under legal hold.
 // Returns
 // - entry data of deleted files
 // - error if any
 func DeleteMultipleSafe(ctx context.Context, repo repository.Repository, storage storage.StorageProvider, dbID repository.ULID, ids []int64) ([]repository.DeletedEntryMeta, error) {
+	if err := refuseHeld(ctx, repo, dbID, ids); err != nil {
+		return make([]repository.DeletedEntryMeta, 0), err
+	}
 
 	// PHASE 1: LOCK
 	// Mark as "Deleting" so they disappear from normal API usage
@@ -55,12 +72,66 @@ func DeleteMultipleSafe(ctx context.Context, repo repository.Repository, storage
 		return make([]repository.DeletedEntryMeta, 0), err // Abort early; database untouched, files untouched!
 	}
 
+	return deleteMarkedEntries(ctx, repo, storage, dbID, ids)
+}
+
+// DeleteSettledSafe works like DeleteMultipleSafe, but only deletes entries that are ready or errored.
+// Entries that are still queued or processing are left alone, so a running worker never loses its row,
+// and so are entries under legal hold.
+// Returns
+// - entry data of deleted files
+// - IDs that were skipped because of their status or legal hold (or because they no longer exist)
+// - error if any
+func DeleteSettledSafe(ctx context.Context, repo repository.Repository, storage storage.StorageProvider, dbID repository.ULID, ids []int64) ([]repository.DeletedEntryMeta, []int64, error) {
+
+	// PHASE 1: LOCK
+	// The status is re-checked while marking, anything not settled is skipped
+	marked, err := repo.MarkEntriesDeleting(ctx, dbID, ids)
+	if err != nil {
+		return make([]repository.DeletedEntryMeta, 0), make([]int64, 0), err
+	}
+
+	isMarked := make(map[int64]bool, len(marked))
+	for _, id := range marked {
+		isMarked[id] = true
+	}
+	skipped := make([]int64, 0)
+	for _, id := range ids {
+		if !isMarked[id] {
+			skipped = append(skipped, id)
+		}
+	}
+
+	if len(marked) == 0 {
+		return make([]repository.DeletedEntryMeta, 0), skipped, nil
+	}
+
+	deletedMeta, err := deleteMarkedEntries(ctx, repo, storage, dbID, marked)
+	return deletedMeta, skipped, err
+}
+
+// refuseHeld returns ErrLegalHold if any of the entries is under legal hold.
+func refuseHeld(ctx context.Context, repo repository.Repository, dbID repository.ULID, ids []int64) error {
+	held, err := repo.GetHeldEntries(ctx, dbID, ids)
+	if err != nil {
+		return err
+	}
+	if len(held) > 0 {
+		return fmt.Errorf("%w: entries %v", customerrors.ErrLegalHold, held)
+	}
+	return nil
+}
+
+// deleteMarkedEntries runs the storage and commit phases for entries already marked as deleting.
+func deleteMarkedEntries(ctx context.Context, repo repository.Repository, storage storage.StorageProvider, dbID repository.ULID, ids []int64) ([]repository.DeletedEntryMeta, error) {
+
 	// PHASE 2: STORAGE DELETION
 	delResult, err := storage.DeleteMultiple(ctx, dbID.String(), ids)
 
-	// We only try to delete previews for the files where the main file deletion succeeded
+	// We only try to delete previews and originals for the files where the main file deletion succeeded
 	if len(delResult.Success) > 0 {
 		_, _ = storage.DeleteMultiplePreviews(ctx, dbID.String(), delResult.Success)
+		_, _ = storage.DeleteMultipleOriginals(ctx, dbID.String(), delResult.Success)
 	}
 
 	// PHASE 3: COMMIT OR ROLLBACK
@@ -80,3 +151,64 @@ func DeleteMultipleSafe(ctx context.Context, repo repository.Repository, storage
 
 	return deletedMeta, err
 }
+
+// DeleteMultipleAtomic deletes entries with the database as the source of truth:
+// all rows (and the statistics) are removed in one repository transaction first, files and
+// previews are only deleted after the commit. If the transaction fails, every file stays intact,
+// e.g. with ErrLegalHold if any of the entries is under legal hold.
+// Files that cannot be removed afterwards are reported as warnings in FileErrors; they are orphans
+// that the integrity check can clean up later.
+func DeleteMultipleAtomic(ctx context.Context, repo repository.Repository, storage storage.StorageProvider, dbID repository.ULID, ids []int64) (BulkDeletion, error) {
+	result := BulkDeletion{
+		Deleted:    []repository.DeletedEntryMeta{},
+		Missing:    []int64{},
+		FileErrors: map[int64]string{},
+	}
+
+	// PHASE 1: DATABASE
+	// The rows are deleted with RETURNING, so missing IDs are detected within the same transaction
+	deletedMeta, err := repo.DeleteEntries(ctx, dbID, ids)
+	if err != nil {
+		return result, err // Nothing committed, files untouched!
+	}
+	result.Deleted = append(result.Deleted, deletedMeta...)
+
+	seen := make(map[int64]bool, len(ids))
+	for _, meta := range deletedMeta {
+		seen[meta.ID] = true
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			result.Missing = append(result.Missing, id)
+			seen[id] = true
+		}
+	}
+
+	// PHASE 2: STORAGE
+	result.FileErrors = DeleteEntryFiles(ctx, storage, dbID, deletedMeta)
+
+	return result, nil
+}
+
+// DeleteEntryFiles removes the files, previews and originals of entries whose rows are already deleted.
+// Files that cannot be removed are reported per ID; they are orphans the integrity check can clean up later.
+func DeleteEntryFiles(ctx context.Context, storage storage.StorageProvider, dbID repository.ULID, deleted []repository.DeletedEntryMeta) map[int64]string {
+	fileErrors := map[int64]string{}
+	for _, meta := range deleted {
+		id := meta.ID
+		if err := storage.Delete(ctx, dbID.String(), id); err != nil {
+			fileErrors[id] = "failed to delete file: " + err.Error()
+			continue
+		}
+		if err := storage.DeletePreview(ctx, dbID.String(), id); err != nil {
+			fileErrors[id] = "failed to delete preview: " + err.Error()
+			continue
+		}
+		if meta.OriginalSize > 0 {
+			if err := storage.DeleteOriginal(ctx, dbID.String(), id); err != nil {
+				fileErrors[id] = "failed to delete original: " + err.Error()
+			}
+		}
+	}
+	return fileErrors
+}
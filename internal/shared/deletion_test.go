@@ -0,0 +1,238 @@
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/shared"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage/localstorage"
+
+	"github.com/pressly/goose/v3"
+)
+
+// failingRepo simulates a repository whose bulk delete transaction fails.
+type failingRepo struct {
+	repo.Repository
+}
+
+func (failingRepo) DeleteEntries(ctx context.Context, dbID repo.ULID, entryIDs []int64) ([]repo.DeletedEntryMeta, error) {
+	return nil, errors.New("transaction failed")
+}
+
+func TestDeleteMultipleAtomic(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	root := t.TempDir()
+	store := &localstorage.LocalStorage{RootPath: root}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "delete_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// 1. Three entries with files and previews
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{
+			FileName:    "file.bin",
+			Size:        4,
+			PreviewSize: 2,
+			Timestamp:   time.Now(),
+			MimeType:    "application/octet-stream",
+		})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := store.WritePreview(ctx, db.ID.String(), entry.ID, strings.NewReader("pv")); err != nil {
+			t.Fatalf("failed to write preview: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	filePath := func(id int64) string {
+		return filepath.Join(root, db.ID.String(), "0", strconv.FormatInt(id, 10))
+	}
+	previewPath := func(id int64) string {
+		return filepath.Join(root, "previews", db.ID.String(), "0", strconv.FormatInt(id, 10))
+	}
+
+	// 2. A failing transaction must leave every file intact
+	if _, err := shared.DeleteMultipleAtomic(ctx, failingRepo{r}, store, db.ID, ids); err == nil {
+		t.Fatalf("expected the transaction error to be returned")
+	}
+	for _, id := range ids {
+		if _, err := os.Stat(filePath(id)); err != nil {
+			t.Errorf("expected file %d to survive a failed transaction: %v", id, err)
+		}
+		if _, err := os.Stat(previewPath(id)); err != nil {
+			t.Errorf("expected preview %d to survive a failed transaction: %v", id, err)
+		}
+	}
+
+	// 3. Make the preview of the second entry undeletable. A read-only file is not enough
+	// when the tests run as root, so the preview is replaced by a non-empty directory.
+	blocked := ids[1]
+	if err := os.Remove(previewPath(blocked)); err != nil {
+		t.Fatalf("failed to remove preview: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(previewPath(blocked), "locked"), 0o755); err != nil {
+		t.Fatalf("failed to block preview: %v", err)
+	}
+
+	const missingID = 999
+	result, err := shared.DeleteMultipleAtomic(ctx, r, store, db.ID, append(ids, missingID))
+	if err != nil {
+		t.Fatalf("DeleteMultipleAtomic failed: %v", err)
+	}
+
+	if len(result.Deleted) != 3 {
+		t.Errorf("expected 3 deleted entries, got %d", len(result.Deleted))
+	}
+	if len(result.Missing) != 1 || result.Missing[0] != missingID {
+		t.Errorf("expected missing [%d], got %v", missingID, result.Missing)
+	}
+	if len(result.FileErrors) != 1 || result.FileErrors[blocked] == "" {
+		t.Errorf("expected a single file warning for entry %d, got %v", blocked, result.FileErrors)
+	}
+
+	// 4. Rows and statistics are gone even for the entry with the warning
+	for _, id := range ids {
+		if _, err := r.GetEntry(ctx, db.ID, id); err == nil {
+			t.Errorf("expected entry %d to be deleted", id)
+		}
+		if _, err := os.Stat(filePath(id)); !os.IsNotExist(err) {
+			t.Errorf("expected file %d to be deleted", id)
+		}
+	}
+	updated, err := r.GetDatabase(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	if updated.Stats.EntryCount != 0 || updated.Stats.TotalDiskSpaceBytes != 0 {
+		t.Errorf("expected zeroed stats, got %+v", updated.Stats)
+	}
+}
+
+func TestDeleteRefusesLegalHold(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	root := t.TempDir()
+	store := &localstorage.LocalStorage{RootPath: root}
+
+	db, err := r.CreateDatabase(ctx, repo.Database{Name: "hold_test", ContentType: "file"})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+
+	// One held and one free entry, both with files
+	var ids []int64
+	for range 2 {
+		entry, err := r.CreateEntry(ctx, db, repo.Entry{FileName: "file.bin", Size: 4, Timestamp: time.Now(), MimeType: "application/octet-stream"})
+		if err != nil {
+			t.Fatalf("failed to create entry: %v", err)
+		}
+		if _, err := store.Write(ctx, db.ID.String(), entry.ID, strings.NewReader("data")); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+	held, free := ids[0], ids[1]
+	if _, err := r.SetLegalHold(ctx, db.ID, []int64{held}, true); err != nil {
+		t.Fatalf("failed to hold entry: %v", err)
+	}
+
+	// 1. Every deletion path refuses the held entry
+	attempts := map[string]func() error{
+		"repo.DeleteEntry": func() error {
+			_, err := r.DeleteEntry(ctx, db.ID, held)
+			return err
+		},
+		"repo.DeleteEntries": func() error {
+			_, err := r.DeleteEntries(ctx, db.ID, ids)
+			return err
+		},
+		"DeleteSafe": func() error {
+			_, err := shared.DeleteSafe(ctx, r, store, db.ID, held)
+			return err
+		},
+		"DeleteMultipleSafe": func() error {
+			_, err := shared.DeleteMultipleSafe(ctx, r, store, db.ID, ids)
+			return err
+		},
+		"DeleteMultipleAtomic": func() error {
+			_, err := shared.DeleteMultipleAtomic(ctx, r, store, db.ID, ids)
+			return err
+		},
+	}
+	for name, attempt := range attempts {
+		if err := attempt(); !errors.Is(err, customerrors.ErrLegalHold) {
+			t.Errorf("%s: expected ErrLegalHold, got %v", name, err)
+		}
+	}
+
+	// Batches are refused as a whole, nothing was touched
+	for _, id := range ids {
+		entry, err := r.GetEntry(ctx, db.ID, id)
+		if err != nil || entry.Status != repo.EntryStatusReady {
+			t.Errorf("expected entry %d to be ready, got %+v (%v)", id, entry, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, db.ID.String(), "0", strconv.FormatInt(id, 10))); err != nil {
+			t.Errorf("expected the file of entry %d to survive: %v", id, err)
+		}
+	}
+
+	// 2. Settled deletions skip the held entry and delete the rest
+	deleted, skipped, err := shared.DeleteSettledSafe(ctx, r, store, db.ID, ids)
+	if err != nil || len(deleted) != 1 || deleted[0].ID != free || len(skipped) != 1 || skipped[0] != held {
+		t.Errorf("expected the free entry deleted and the held one skipped, got %v and %v (%v)", deleted, skipped, err)
+	}
+
+	// 3. Once released, the entry can be deleted
+	if _, err := r.SetLegalHold(ctx, db.ID, []int64{held}, false); err != nil {
+		t.Fatalf("failed to release entry: %v", err)
+	}
+	if _, err := shared.DeleteSafe(ctx, r, store, db.ID, held); err != nil {
+		t.Errorf("expected the released entry to be deleted, got %v", err)
+	}
+}
@@ -8,13 +8,33 @@ import (
 	"time"
 )
 
+// Disabled can be given instead of "0" to switch off a size or duration limit.
+const Disabled = "disabled"
+
+// Accepted syntax, for error messages shown to users.
+const (
+	SizeSyntax     = `a whole number with an optional unit B, K/KB, M/MB, G/GB or T/TB (e.g. "100G"), or "0"/"disabled"`
+	DurationSyntax = `one or more whole numbers with a unit s/sec, m/min, h/hr/hour or d/day (e.g. "30d", "1d 12h"), or "0"/"disabled"`
+)
+
+var (
+	sizeRegex         = regexp.MustCompile(`(?i)^(\d+)\s*([a-z]*)$`)
+	durationRegex     = regexp.MustCompile(`(?i)^(\d+\s*[a-z]+\s*)+$`)
+	durationPartRegex = regexp.MustCompile(`(?i)(\d+)\s*([a-z]+)`)
+)
+
 // ParseSize parses a size string (e.g., "100G", "500MB", "1024 bytes") into bytes.
+// "0" and "disabled" return 0.
 func ParseSize(sizeStr string) (uint64, error) {
+	trimmedStr := strings.TrimSpace(sizeStr)
+	if strings.EqualFold(trimmedStr, Disabled) {
+		return 0, nil
+	}
+
 	// (?i) makes it case-insensitive.
 	// \s* allows optional spaces between the number and the unit.
 	// ([a-z]*) captures any alphabetical characters that follow the number.
-	re := regexp.MustCompile(`(?i)^(\d+)\s*([a-z]*)$`)
-	matches := re.FindStringSubmatch(strings.TrimSpace(sizeStr))
+	matches := sizeRegex.FindStringSubmatch(trimmedStr)
 
 	if len(matches) < 2 {
 		return 0, fmt.Errorf("invalid size format: %s", sizeStr)
@@ -47,44 +67,43 @@ func ParseSize(sizeStr string) (uint64, error) {
 }
 
 // ParseDuration parses a duration string with support for days and various aliases
-// (e.g., "30d", "24 hours", "15 mins").
+// (e.g., "30d", "24 hours", "15 mins"). Several parts are summed up, so the output of
+// DurationToString (e.g., "1d 12h") can be parsed again. "0" and "disabled" return 0.
 func ParseDuration(durationStr string) (time.Duration, error) {
 	trimmedStr := strings.TrimSpace(durationStr)
 
 	// Handle "0" as a special case for "disabled"
-	if trimmedStr == "0" {
+	if trimmedStr == "0" || strings.EqualFold(trimmedStr, Disabled) {
 		return 0, nil
 	}
 
-	// Capture the number and any trailing alphabetical characters
-	re := regexp.MustCompile(`(?i)^(\d+)\s*([a-z]+)$`)
-	matches := re.FindStringSubmatch(trimmedStr)
-
-	if len(matches) < 3 {
+	if !durationRegex.MatchString(trimmedStr) {
 		return 0, fmt.Errorf("invalid duration format: %s", durationStr)
 	}
 
-	value, err := strconv.Atoi(matches[1])
-	if err != nil {
-		return 0, fmt.Errorf("invalid duration number: %s", matches[1])
-	}
+	var total time.Duration
+	for _, matches := range durationPartRegex.FindAllStringSubmatch(trimmedStr, -1) {
+		value, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration number: %s", matches[1])
+		}
 
-	// If value is 0 (e.g., "0d"), return 0 duration
-	if value == 0 {
-		return 0, nil
+		var unitDuration time.Duration
+		unit := strings.ToLower(matches[2]) // Normalize to lowercase for the switch
+		switch unit {
+		case "d", "day", "days":
+			unitDuration = 24 * time.Hour
+		case "h", "hr", "hrs", "hour", "hours":
+			unitDuration = time.Hour
+		case "m", "min", "mins", "minute", "minutes":
+			unitDuration = time.Minute
+		case "s", "sec", "secs", "second", "seconds":
+			unitDuration = time.Second
+		default:
+			return 0, fmt.Errorf("unsupported duration unit: %s", unit)
+		}
+		total += time.Duration(value) * unitDuration
 	}
 
-	unit := strings.ToLower(matches[2]) // Normalize to lowercase for the switch
-	switch unit {
-	case "d", "day", "days":
-		return time.Duration(value) * 24 * time.Hour, nil
-	case "h", "hr", "hrs", "hour", "hours":
-		return time.Duration(value) * time.Hour, nil
-	case "m", "min", "mins", "minute", "minutes":
-		return time.Duration(value) * time.Minute, nil
-	case "s", "sec", "secs", "second", "seconds":
-		return time.Duration(value) * time.Second, nil
-	default:
-		return 0, fmt.Errorf("unsupported duration unit: %s", unit)
-	}
+	return total, nil
 }
@@ -0,0 +1,84 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	valid := map[string]uint64{
+		"1024":     1024,
+		"10B":      10,
+		"10 bytes": 10,
+		"1byte":    1,
+		"2K":       2 << 10,
+		"2kb":      2 << 10,
+		"3M":       3 << 20,
+		"3 MB":     3 << 20,
+		"4G":       4 << 30,
+		"4gb":      4 << 30,
+		"5T":       5 << 40,
+		"5TB":      5 << 40,
+		"0":        0,
+		"0G":       0,
+		"disabled": 0,
+		"Disabled": 0,
+	}
+	for input, want := range valid {
+		got, err := ParseSize(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "ten gigs", "10X", "1.5G", "-1G", "G"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("ParseSize(%q): expected an error", input)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	valid := map[string]time.Duration{
+		"30s":        30 * time.Second,
+		"30 sec":     30 * time.Second,
+		"1secs":      time.Second,
+		"2 seconds":  2 * time.Second,
+		"15m":        15 * time.Minute,
+		"15min":      15 * time.Minute,
+		"15 mins":    15 * time.Minute,
+		"1 minute":   time.Minute,
+		"2h":         2 * time.Hour,
+		"2hr":        2 * time.Hour,
+		"2 hrs":      2 * time.Hour,
+		"1 hour":     time.Hour,
+		"24 hours":   24 * time.Hour,
+		"30d":        30 * 24 * time.Hour,
+		"1 day":      24 * time.Hour,
+		"365 days":   365 * 24 * time.Hour,
+		"1d 12h":     36 * time.Hour,
+		"1d12h30min": 36*time.Hour + 30*time.Minute,
+		"0":          0,
+		"0d":         0,
+		"disabled":   0,
+		"DISABLED":   0,
+	}
+	for input, want := range valid {
+		got, err := ParseDuration(input)
+		if err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+
+	for _, input := range []string{"", "30x", "30", "1.5h", "h", "1d, 2h", "-1d"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q): expected an error", input)
+		}
+	}
+
+	// The rendered form can be parsed again
+	d := 3*24*time.Hour + 4*time.Hour + 5*time.Minute + 6*time.Second
+	if got, err := ParseDuration(DurationToString(d)); err != nil || got != d {
+		t.Errorf("round trip of %q gave %v, %v", DurationToString(d), got, err)
+	}
+}
@@ -3,6 +3,7 @@ package localstorage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"mediahub_oss/internal/storage"
 	"os"
@@ -143,3 +144,58 @@ func (ds *LocalStorage) WalkPreview(ctx context.Context, dbID string, walkFn fun
 	basePath := filepath.Join(previewRoot, dbID)
 	return ds.walkDirectory(basePath, walkFn)
 }
+
+// WriteOriginal streams the original of a converted upload to the local filesystem's originals directory.
+func (ds *LocalStorage) WriteOriginal(ctx context.Context, dbID string, id int64, original io.Reader) (int64, error) {
+	// Originals are stored in a separate root folder (e.g., .../storage_root/originals/)
+	fullPath := getFilePath(ds.originalRoot(), dbID, id)
+	return writeFileStream(fullPath, original)
+}
+
+// ReadOriginal retrieves a stream of the kept original file content.
+func (ds *LocalStorage) ReadOriginal(ctx context.Context, dbID string, id int64) (io.ReadCloser, error) {
+	return os.Open(getFilePath(ds.originalRoot(), dbID, id))
+}
+
+// DeleteOriginal removes the kept original file from storage.
+func (ds *LocalStorage) DeleteOriginal(ctx context.Context, dbID string, id int64) error {
+	return removeFile(getFilePath(ds.originalRoot(), dbID, id))
+}
+
+// DeleteMultipleOriginals removes multiple original files from storage.
+func (ds *LocalStorage) DeleteMultipleOriginals(ctx context.Context, dbID string, ids []int64) (storage.BulkDeleteResult, error) {
+	deletedIDs, failedIDs, errs := deleteMultiple(ds.originalRoot(), dbID, ids)
+
+	result := storage.BulkDeleteResult{
+		Success: deletedIDs,
+		Failed:  failedIDs,
+	}
+	return result, errors.Join(errs...)
+}
+
+// WalkOriginal iterates over all kept original files in the storage for a given database.
+func (ds *LocalStorage) WalkOriginal(ctx context.Context, dbID string, walkFn func(id int64, info storage.FileInfo) error) error {
+	return ds.walkDirectory(filepath.Join(ds.originalRoot(), dbID), walkFn)
+}
+
+// originalRoot returns the folder holding the originals of converted uploads, next to the database folders.
+func (ds *LocalStorage) originalRoot() string {
+	return filepath.Join(ds.RootPath, "originals")
+}
+
+// Probe writes and removes a small file in the storage root, failing if the root is missing or read-only.
+func (ds *LocalStorage) Probe(ctx context.Context) error {
+	f, err := os.CreateTemp(ds.RootPath, ".health-probe-*")
+	if err != nil {
+		return fmt.Errorf("failed to create probe file in %s: %w", ds.RootPath, err)
+	}
+	_, writeErr := f.Write([]byte("ok"))
+	closeErr := f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return fmt.Errorf("failed to remove probe file: %w", err)
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write probe file: %w", writeErr)
+	}
+	return closeErr
+}
@@ -0,0 +1,89 @@
+package localstorage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// removedPrefix marks the folders of a deleted database. Folders with this prefix left behind by a
+// crash during the removal are deleted by the next PurgeRemovedDatabases.
+const removedPrefix = ".deleting-"
+
+// databaseRoots returns the folders holding one subfolder per database.
+func (ds *LocalStorage) databaseRoots() []string {
+	return []string{ds.RootPath, filepath.Join(ds.RootPath, "previews"), ds.originalRoot()}
+}
+
+// EnsureDatabase creates the main folder of a database if it is missing, e.g. after it was deleted by hand.
+// The preview and original folders are optional and created with their first file.
+func (ds *LocalStorage) EnsureDatabase(ctx context.Context, dbID string) (bool, error) {
+	if dbID == "" || dbID == "." || dbID == ".." || strings.ContainsAny(dbID, `/\`) {
+		return false, fmt.Errorf("invalid database id: %q", dbID)
+	}
+
+	dir := filepath.Join(ds.RootPath, dbID)
+	info, err := os.Stat(dir)
+	if err == nil {
+		if !info.IsDir() {
+			return false, fmt.Errorf("%s is not a folder", dir)
+		}
+		return false, nil
+	}
+	if !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to check %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	return true, nil
+}
+
+// RemoveDatabase renames the folders of a database to '.deleting-<dbID>'. A rename is a single,
+// cheap operation, the recursive delete is left to PurgeRemovedDatabases.
+func (ds *LocalStorage) RemoveDatabase(ctx context.Context, dbID string) error {
+	if dbID == "" || dbID == "." || dbID == ".." || strings.ContainsAny(dbID, `/\`) {
+		return fmt.Errorf("invalid database id: %q", dbID)
+	}
+
+	for _, root := range ds.databaseRoots() {
+		src := filepath.Join(root, dbID)
+		if err := os.Rename(src, filepath.Join(root, removedPrefix+dbID)); err != nil {
+			if os.IsNotExist(err) {
+				continue // e.g. a database without previews
+			}
+			return fmt.Errorf("failed to move %s out of the way: %w", src, err)
+		}
+	}
+	return nil
+}
+
+// PurgeRemovedDatabases recursively deletes all '.deleting-<dbID>' folders.
+func (ds *LocalStorage) PurgeRemovedDatabases(ctx context.Context) (int, error) {
+	purged := make(map[string]struct{})
+	for _, root := range ds.databaseRoots() {
+		dirEntries, err := os.ReadDir(root)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return len(purged), fmt.Errorf("failed to list %s: %w", root, err)
+		}
+
+		for _, d := range dirEntries {
+			if !d.IsDir() || !strings.HasPrefix(d.Name(), removedPrefix) {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return len(purged), err
+			}
+			if err := os.RemoveAll(filepath.Join(root, d.Name())); err != nil {
+				return len(purged), fmt.Errorf("failed to delete %s: %w", filepath.Join(root, d.Name()), err)
+			}
+			purged[strings.TrimPrefix(d.Name(), removedPrefix)] = struct{}{}
+		}
+	}
+	return len(purged), nil
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package localstorage
+
+import (
+	"context"
+	"fmt"
+	"mediahub_oss/internal/storage"
+
+	"golang.org/x/sys/unix"
+)
+
+// GetVolumeUsage reports the capacity of the filesystem containing the storage root.
+func (ds *LocalStorage) GetVolumeUsage(ctx context.Context) (storage.VolumeUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(ds.RootPath, &st); err != nil {
+		return storage.VolumeUsage{}, fmt.Errorf("failed to stat volume of %s: %w", ds.RootPath, err)
+	}
+
+	return storage.VolumeUsage{
+		TotalBytes: st.Blocks * uint64(st.Bsize),
+		FreeBytes:  uint64(st.Bavail) * uint64(st.Bsize), // Bavail excludes blocks reserved for root
+	}, nil
+}
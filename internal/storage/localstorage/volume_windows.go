@@ -0,0 +1,30 @@
+//go:build windows
+
+package localstorage
+
+import (
+	"context"
+	"fmt"
+	"mediahub_oss/internal/storage"
+
+	"golang.org/x/sys/windows"
+)
+
+// GetVolumeUsage reports the capacity of the drive containing the storage root.
+func (ds *LocalStorage) GetVolumeUsage(ctx context.Context) (storage.VolumeUsage, error) {
+	pathPtr, err := windows.UTF16PtrFromString(ds.RootPath)
+	if err != nil {
+		return storage.VolumeUsage{}, fmt.Errorf("invalid storage path %s: %w", ds.RootPath, err)
+	}
+
+	// freeBytes honours per-user quotas, unlike the total number of free bytes
+	var freeBytes, totalBytes, totalFreeBytes uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &freeBytes, &totalBytes, &totalFreeBytes); err != nil {
+		return storage.VolumeUsage{}, fmt.Errorf("failed to stat volume of %s: %w", ds.RootPath, err)
+	}
+
+	return storage.VolumeUsage{
+		TotalBytes: totalBytes,
+		FreeBytes:  freeBytes,
+	}, nil
+}
@@ -13,3 +13,9 @@ type FileInfo struct {
 	Size         int64
 	LastModified time.Time
 }
+
+// VolumeUsage describes the capacity of the volume a storage backend writes to.
+type VolumeUsage struct {
+	TotalBytes uint64
+	FreeBytes  uint64 // available to the server process, may be less than the unused space
+}
@@ -63,3 +63,43 @@ func (s *S3StorageProvider) Walk(ctx context.Context, dbID string, walkFn func(i
 func (s *S3StorageProvider) WalkPreview(ctx context.Context, dbID string, walkFn func(id int64, info storage.FileInfo) error) error {
 	return customerrors.ErrNotImplemented
 }
+
+func (s *S3StorageProvider) WriteOriginal(ctx context.Context, dbID string, id int64, original io.Reader) (int64, error) {
+	return 0, customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) ReadOriginal(ctx context.Context, dbID string, id int64) (io.ReadCloser, error) {
+	return nil, customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) DeleteOriginal(ctx context.Context, dbID string, id int64) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) DeleteMultipleOriginals(ctx context.Context, dbID string, ids []int64) (storage.BulkDeleteResult, error) {
+	return storage.BulkDeleteResult{}, customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) WalkOriginal(ctx context.Context, dbID string, walkFn func(id int64, info storage.FileInfo) error) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) GetVolumeUsage(ctx context.Context) (storage.VolumeUsage, error) {
+	return storage.VolumeUsage{}, customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) Probe(ctx context.Context) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) EnsureDatabase(ctx context.Context, dbID string) (bool, error) {
+	return false, customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) RemoveDatabase(ctx context.Context, dbID string) error {
+	return customerrors.ErrNotImplemented
+}
+
+func (s *S3StorageProvider) PurgeRemovedDatabases(ctx context.Context) (int, error) {
+	return 0, customerrors.ErrNotImplemented
+}
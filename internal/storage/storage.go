@@ -41,4 +41,38 @@ type StorageProvider interface {
 
 	// WalkPreview iterates over all preview files in the storage for a given database. It calls the provided walkFn for each discovered preview file.
 	WalkPreview(ctx context.Context, dbID string, walkFn func(id int64, info FileInfo) error) error
+
+	// WriteOriginal stores the original of a converted upload next to the converted main file and returns the amount of bytes written.
+	WriteOriginal(ctx context.Context, dbID string, id int64, original io.Reader) (int64, error)
+
+	// ReadOriginal retrieves a stream of the kept original file content.
+	ReadOriginal(ctx context.Context, dbID string, id int64) (io.ReadCloser, error)
+
+	// DeleteOriginal removes the kept original file from storage. A missing original is not an error.
+	DeleteOriginal(ctx context.Context, dbID string, id int64) error
+
+	// Delete multiple original files, possibly more efficient than looping over DeleteOriginal, return the ids of actually deleted files
+	DeleteMultipleOriginals(ctx context.Context, dbID string, ids []int64) (BulkDeleteResult, error)
+
+	// WalkOriginal iterates over all kept original files in the storage for a given database.
+	WalkOriginal(ctx context.Context, dbID string, walkFn func(id int64, info FileInfo) error) error
+
+	// GetVolumeUsage reports the total and free space of the underlying volume.
+	// Backends without a volume (e.g. object storage) return customerrors.ErrNotImplemented.
+	GetVolumeUsage(ctx context.Context) (VolumeUsage, error)
+
+	// Probe writes and removes a tiny file to verify that the backend is reachable and writable.
+	Probe(ctx context.Context) error
+
+	// EnsureDatabase creates the missing main folder of a database and reports whether it had to be created.
+	// Backends without folders (e.g. object storage) return customerrors.ErrNotImplemented.
+	EnsureDatabase(ctx context.Context, dbID string) (bool, error)
+
+	// RemoveDatabase moves all files of a database (main files, previews and originals) out of the way
+	// without deleting them, so that it returns quickly. The files are deleted by PurgeRemovedDatabases.
+	RemoveDatabase(ctx context.Context, dbID string) error
+
+	// PurgeRemovedDatabases deletes the files moved away by RemoveDatabase, including those of removals
+	// interrupted by a restart, and returns the number of purged databases.
+	PurgeRemovedDatabases(ctx context.Context) (int, error)
 }
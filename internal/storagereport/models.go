@@ -0,0 +1,53 @@
+package storagereport
+
+import (
+	"time"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/storage"
+)
+
+// Report answers "who is using the disk" across all databases.
+type Report struct {
+	GeneratedAt    time.Time
+	Volume         *storage.VolumeUsage  // nil if the storage backend has no volume or it could not be read
+	DatabaseFile   *repository.FileStats // nil if the repository does not report it
+	Databases      []DatabaseUsage
+	ContentTypes   []ContentTypeUsage
+	LargestEntries []LargeEntry
+}
+
+// DatabaseUsage breaks down the disk usage of a single database.
+type DatabaseUsage struct {
+	DatabaseID    repository.ULID
+	Name          string
+	ContentType   string
+	EntryCount    uint64
+	TotalBytes    uint64 // from the database stats, originals and previews
+	OriginalBytes uint64 // TotalBytes minus the measured previews
+	Previews      PreviewUsage
+}
+
+// PreviewUsage is the measured size of a database's preview folder.
+type PreviewUsage struct {
+	Files     uint64
+	Bytes     uint64
+	ScannedAt time.Time
+	Error     string // set if the preview folder could not be walked
+}
+
+// ContentTypeUsage aggregates the databases of one content type.
+type ContentTypeUsage struct {
+	ContentType   string
+	Databases     int
+	EntryCount    uint64
+	TotalBytes    uint64
+	OriginalBytes uint64
+	PreviewBytes  uint64
+}
+
+// LargeEntry is one of the largest entries across all databases.
+type LargeEntry struct {
+	repository.LargestEntry
+	DatabaseName string
+}
@@ -0,0 +1,178 @@
+package storagereport
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"mediahub_oss/internal/repository"
+	"mediahub_oss/internal/shared/customerrors"
+	"mediahub_oss/internal/storage"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	DefaultCacheTTL    = 15 * time.Minute // walking preview folders is IO heavy
+	DefaultConcurrency = 2                // preview folders walked in parallel, low to spare spinning disks
+)
+
+// Reporter computes storage usage reports. Entry totals come from the database stats,
+// preview sizes are measured by walking the preview folders and cached per database.
+type Reporter struct {
+	Repo        repository.Repository
+	Storage     storage.StorageProvider
+	Logger      *slog.Logger
+	Concurrency int
+
+	previews *cache.Cache
+}
+
+// NewReporter creates a reporter whose preview measurements expire after cacheTTL.
+func NewReporter(repo repository.Repository, storage storage.StorageProvider, logger *slog.Logger, cacheTTL time.Duration) *Reporter {
+	return &Reporter{
+		Repo:        repo,
+		Storage:     storage,
+		Logger:      logger,
+		Concurrency: DefaultConcurrency,
+		previews:    cache.New(cacheTTL, 2*cacheTTL),
+	}
+}
+
+// Generate builds a report including the topN largest entries. If refresh is set,
+// the preview folders are walked again instead of using cached measurements.
+func (r *Reporter) Generate(ctx context.Context, topN int, refresh bool) (Report, error) {
+	dbs, err := r.Repo.GetDatabases(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to get databases: %w", err)
+	}
+
+	report := Report{
+		GeneratedAt: time.Now(),
+		Databases:   make([]DatabaseUsage, len(dbs)),
+	}
+
+	// 1. Per database usage, measuring previews with bounded concurrency
+	sem := make(chan struct{}, max(1, r.Concurrency))
+	var wg sync.WaitGroup
+	for i, db := range dbs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			previews := r.getPreviewUsage(ctx, db.ID, refresh)
+			report.Databases[i] = DatabaseUsage{
+				DatabaseID:    db.ID,
+				Name:          db.Name,
+				ContentType:   db.ContentType,
+				EntryCount:    db.Stats.EntryCount,
+				TotalBytes:    db.Stats.TotalDiskSpaceBytes,
+				OriginalBytes: db.Stats.TotalDiskSpaceBytes - min(db.Stats.TotalDiskSpaceBytes, previews.Bytes),
+				Previews:      previews,
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return Report{}, err
+	}
+
+	// 2. Aggregates per content type
+	report.ContentTypes = aggregateContentTypes(report.Databases)
+
+	// 3. Largest entries across all databases
+	largest, err := r.Repo.GetLargestEntries(ctx, topN)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to get largest entries: %w", err)
+	}
+	names := make(map[repository.ULID]string, len(dbs))
+	for _, db := range dbs {
+		names[db.ID] = db.Name
+	}
+	report.LargestEntries = make([]LargeEntry, 0, len(largest))
+	for _, e := range largest {
+		report.LargestEntries = append(report.LargestEntries, LargeEntry{LargestEntry: e, DatabaseName: names[e.DatabaseID]})
+	}
+
+	// 4. Capacity of the storage volume
+	volume, err := r.Storage.GetVolumeUsage(ctx)
+	if err == nil {
+		report.Volume = &volume
+	} else if !errors.Is(err, customerrors.ErrNotImplemented) {
+		r.Logger.Warn("StorageReport: Failed to get volume usage", "error", err)
+	}
+
+	// 5. Size and free pages of the metadata database file
+	fileStats, err := r.Repo.GetFileStats(ctx)
+	if err == nil {
+		report.DatabaseFile = &fileStats
+	} else if !errors.Is(err, customerrors.ErrNotImplemented) {
+		r.Logger.Warn("StorageReport: Failed to get database file stats", "error", err)
+	}
+
+	return report, nil
+}
+
+// getPreviewUsage returns the cached preview measurement of a database or walks its preview folder.
+func (r *Reporter) getPreviewUsage(ctx context.Context, dbID repository.ULID, refresh bool) PreviewUsage {
+	if !refresh {
+		if cached, found := r.previews.Get(dbID.String()); found {
+			return cached.(PreviewUsage)
+		}
+	}
+
+	var usage PreviewUsage
+	err := r.Storage.WalkPreview(ctx, dbID.String(), func(id int64, info storage.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		usage.Files++
+		usage.Bytes += uint64(info.Size)
+		return nil
+	})
+	usage.ScannedAt = time.Now()
+
+	if err != nil {
+		if ctx.Err() == nil {
+			r.Logger.Warn("StorageReport: Failed to walk preview folder", "database", dbID, "error", err)
+		}
+		// Failed measurements are not cached, the next report tries again
+		return PreviewUsage{ScannedAt: usage.ScannedAt, Error: err.Error()}
+	}
+
+	r.previews.SetDefault(dbID.String(), usage)
+	return usage
+}
+
+// aggregateContentTypes sums up the database usages per content type, sorted by name.
+func aggregateContentTypes(databases []DatabaseUsage) []ContentTypeUsage {
+	byType := make(map[string]*ContentTypeUsage)
+	for _, db := range databases {
+		agg, ok := byType[db.ContentType]
+		if !ok {
+			agg = &ContentTypeUsage{ContentType: db.ContentType}
+			byType[db.ContentType] = agg
+		}
+		agg.Databases++
+		agg.EntryCount += db.EntryCount
+		agg.TotalBytes += db.TotalBytes
+		agg.OriginalBytes += db.OriginalBytes
+		agg.PreviewBytes += db.Previews.Bytes
+	}
+
+	result := make([]ContentTypeUsage, 0, len(byType))
+	for _, agg := range byType {
+		result = append(result, *agg)
+	}
+	slices.SortFunc(result, func(a, b ContentTypeUsage) int {
+		return cmp.Compare(a.ContentType, b.ContentType)
+	})
+	return result
+}
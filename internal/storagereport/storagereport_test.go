@@ -0,0 +1,129 @@
+package storagereport_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+	"mediahub_oss/internal/storagereport"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestStorageReport(t *testing.T) {
+	ctx := context.Background()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	defer r.Close()
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	reporter := storagereport.NewReporter(r, store, slog.New(slog.NewTextHandler(io.Discard, nil)), time.Hour)
+
+	// Two databases with entries of the given sizes, each entry has a 10 byte preview
+	addEntries := func(name string, sizes ...uint64) repo.Database {
+		t.Helper()
+		db, err := r.CreateDatabase(ctx, repo.Database{Name: name, ContentType: "file"})
+		if err != nil {
+			t.Fatalf("failed to create database: %v", err)
+		}
+		for _, size := range sizes {
+			entry, err := r.CreateEntry(ctx, db, repo.Entry{
+				FileName:    "file.bin",
+				Size:        size,
+				PreviewSize: 10,
+				Timestamp:   time.Now(),
+				MimeType:    "application/octet-stream",
+			})
+			if err != nil {
+				t.Fatalf("failed to create entry: %v", err)
+			}
+			if _, err := store.WritePreview(ctx, db.ID.String(), entry.ID, strings.NewReader(strings.Repeat("p", 10))); err != nil {
+				t.Fatalf("failed to write preview: %v", err)
+			}
+		}
+		return db
+	}
+	dbA := addEntries("report_a", 100, 500, 300)
+	dbB := addEntries("report_b", 400, 50)
+
+	report, err := reporter.Generate(ctx, 3, false)
+	if err != nil {
+		t.Fatalf("failed to generate report: %v", err)
+	}
+
+	// 1. Largest entries across both databases
+	var sizes []uint64
+	for _, e := range report.LargestEntries {
+		sizes = append(sizes, e.Size)
+	}
+	if len(sizes) != 3 || sizes[0] != 500 || sizes[1] != 400 || sizes[2] != 300 {
+		t.Errorf("expected largest entries [500 400 300], got %v", sizes)
+	}
+	if report.LargestEntries[1].DatabaseID != dbB.ID || report.LargestEntries[1].DatabaseName != "report_b" {
+		t.Errorf("expected the second largest entry in report_b, got %+v", report.LargestEntries[1])
+	}
+
+	// 2. Per database breakdown
+	for _, db := range report.Databases {
+		if db.DatabaseID != dbA.ID {
+			continue
+		}
+		if db.EntryCount != 3 || db.TotalBytes != 930 || db.Previews.Bytes != 30 || db.Previews.Files != 3 || db.OriginalBytes != 900 {
+			t.Errorf("unexpected usage for report_a: %+v", db)
+		}
+	}
+
+	// 3. Content type aggregate
+	if len(report.ContentTypes) != 1 || report.ContentTypes[0].Databases != 2 || report.ContentTypes[0].OriginalBytes != 1350 || report.ContentTypes[0].PreviewBytes != 50 {
+		t.Errorf("unexpected content type aggregates: %+v", report.ContentTypes)
+	}
+
+	if report.Volume == nil || report.Volume.TotalBytes == 0 {
+		t.Errorf("expected the volume capacity of the local storage, got %+v", report.Volume)
+	}
+	if report.DatabaseFile == nil || report.DatabaseFile.SizeBytes == 0 || report.DatabaseFile.AutoVacuum != "incremental" {
+		t.Errorf("expected the stats of the database file, got %+v", report.DatabaseFile)
+	}
+
+	// 4. Preview measurements are cached until a refresh is requested
+	if _, err := store.WritePreview(ctx, dbB.ID.String(), 999, strings.NewReader("extra")); err != nil {
+		t.Fatalf("failed to write preview: %v", err)
+	}
+	previewBytes := func(refresh bool) uint64 {
+		report, err := reporter.Generate(ctx, 0, refresh)
+		if err != nil {
+			t.Fatalf("failed to generate report: %v", err)
+		}
+		for _, db := range report.Databases {
+			if db.DatabaseID == dbB.ID {
+				return db.Previews.Bytes
+			}
+		}
+		return 0
+	}
+	if got := previewBytes(false); got != 20 {
+		t.Errorf("expected the cached 20 preview bytes, got %d", got)
+	}
+	if got := previewBytes(true); got != 25 {
+		t.Errorf("expected 25 preview bytes after a refresh, got %d", got)
+	}
+}
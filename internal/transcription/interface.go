@@ -0,0 +1,21 @@
+package transcription
+
+import (
+	"context"
+	"io"
+)
+
+// Transcriber turns the audio of an entry into text using an external service.
+type Transcriber interface {
+	// Transcribe reads the full stream and returns the recognized text.
+	// An error means the transcription could not be completed and may be retried.
+	Transcribe(ctx context.Context, req Request, audio io.Reader) (string, error)
+}
+
+// Request carries the per-database settings of a single transcription.
+type Request struct {
+	Endpoint string // base URL of the service
+	Model    string
+	Language string // optional language hint
+	FileName string // name of the uploaded file, services derive the format from its extension
+}
@@ -0,0 +1,107 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"mediahub_oss/internal/transcription"
+)
+
+// transcriptionsPath is appended to endpoints that do not already point to it.
+const transcriptionsPath = "/v1/audio/transcriptions"
+
+// Client talks to an OpenAI-compatible transcription service, e.g. a Whisper inference server.
+type Client struct {
+	httpClient *http.Client
+	authHeader string // sent as Authorization header, e.g. "Bearer <key>", omitted if empty
+}
+
+// NewClient creates a client whose requests carry the given Authorization header.
+// The timeout bounds a single transcription including the upload.
+func NewClient(authHeader string, timeout time.Duration) *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: timeout},
+		authHeader: authHeader,
+	}
+}
+
+// Transcribe uploads the audio as multipart form and returns the text of the JSON response.
+func (c *Client) Transcribe(ctx context.Context, req transcription.Request, audio io.Reader) (string, error) {
+	// Stream the form, so large files are not held in memory
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeForm(form, req, audio))
+	}()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, transcriptionsURL(req.Endpoint), pr)
+	if err != nil {
+		pr.Close()
+		return "", fmt.Errorf("failed to create transcription request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", form.FormDataContentType())
+	if c.authHeader != "" {
+		httpReq.Header.Set("Authorization", c.authHeader)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach transcription service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("transcription service returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode transcription response: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// writeForm writes the fields of the transcription request followed by the file.
+func writeForm(form *multipart.Writer, req transcription.Request, audio io.Reader) error {
+	fields := [][2]string{{"model", req.Model}, {"response_format", "json"}}
+	if req.Language != "" {
+		fields = append(fields, [2]string{"language", req.Language})
+	}
+	for _, f := range fields {
+		if err := form.WriteField(f[0], f[1]); err != nil {
+			return err
+		}
+	}
+
+	part, err := form.CreateFormFile("file", req.FileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// transcriptionsURL completes an endpoint given as base URL ("http://whisper:8000" or ".../v1")
+// to the transcriptions URL. Endpoints that already point to it are kept.
+func transcriptionsURL(endpoint string) string {
+	endpoint = strings.TrimRight(endpoint, "/")
+	switch {
+	case strings.HasSuffix(endpoint, "/audio/transcriptions"):
+		return endpoint
+	case strings.HasSuffix(endpoint, "/v1"):
+		return strings.TrimSuffix(endpoint, "/v1") + transcriptionsPath
+	default:
+		return endpoint + transcriptionsPath
+	}
+}
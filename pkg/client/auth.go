@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is how long before its expiry an access token is refreshed.
+const tokenRefreshMargin = 30 * time.Second
+
+// authenticator adds the credentials to a request.
+type authenticator interface {
+	authorize(req *http.Request) error
+}
+
+type noAuth struct{}
+
+func (noAuth) authorize(*http.Request) error { return nil }
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (a basicAuth) authorize(req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+// bearerAuth sends a static bearer token, i.e. an API key.
+type bearerAuth string
+
+func (a bearerAuth) authorize(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+string(a))
+	return nil
+}
+
+// tokenSource holds a JWT pair and renews it: the access token is refreshed shortly before it expires,
+// and a new pair is requested with the username and password (if known) once refreshing fails.
+type tokenSource struct {
+	client   *Client
+	username string
+	password string
+
+	mu      sync.Mutex
+	access  string
+	refresh string
+	expiry  time.Time // zero if the token has no readable expiry
+}
+
+// tokenPair is the payload of /api/token and /api/token/refresh.
+type tokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (ts *tokenSource) authorize(req *http.Request) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.access == "" || (!ts.expiry.IsZero() && time.Until(ts.expiry) < tokenRefreshMargin) {
+		if err := ts.renewLocked(req.Context()); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+ts.access)
+	return nil
+}
+
+// renew replaces an access token the server rejected. If another request already renewed it, nothing is done.
+func (ts *tokenSource) renew(ctx context.Context, rejectedHeader string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if rejectedHeader != "Bearer "+ts.access {
+		return nil
+	}
+	return ts.renewLocked(ctx)
+}
+
+// renewLocked refreshes the token pair, falling back to a new login. ts.mu must be held.
+func (ts *tokenSource) renewLocked(ctx context.Context) error {
+	var err error
+	if ts.refresh != "" {
+		if err = ts.requestTokens(ctx, "/token/refresh", tokenPair{RefreshToken: ts.refresh}); err == nil {
+			return nil
+		}
+	}
+	if ts.username != "" {
+		return ts.requestTokens(ctx, "/token", nil)
+	}
+	if err == nil {
+		err = fmt.Errorf("%w: no valid token and no credentials to log in", ErrUnauthorized)
+	}
+	return err
+}
+
+// requestTokens obtains a new pair from the token endpoint. Without a body, it logs in with Basic Auth.
+func (ts *tokenSource) requestTokens(ctx context.Context, path string, body any) error {
+	req, err := ts.client.newJSONRequest(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		req.SetBasicAuth(ts.username, ts.password)
+	}
+
+	resp, err := ts.client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST %s failed: %w", req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp)
+	}
+
+	var pair tokenPair
+	if err := json.NewDecoder(resp.Body).Decode(&pair); err != nil {
+		return fmt.Errorf("failed to decode token response: %w", err)
+	}
+	ts.access, ts.refresh = pair.AccessToken, pair.RefreshToken
+	ts.expiry = tokenExpiry(pair.AccessToken)
+	return nil
+}
+
+// tokenExpiry reads the exp claim of a JWT without verifying it, the zero time if it cannot be read.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(data, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,176 @@
+// Package client is a typed Go client for the MediaHub HTTP API.
+//
+//	c, err := client.New("https://mediahub.example.com", client.WithAPIKey("srv_..."))
+//	entry, err := c.GetEntryMeta(ctx, dbID, 42)
+//
+// Errors returned by the server are *APIError values, which match ErrNotFound, ErrConflict etc. with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to a MediaHub server. It is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	auth         authenticator
+	pollInterval time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for all requests, e.g. for timeouts or custom transports.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithPollInterval sets how often WaitForEntry checks the status of an entry (default 1s).
+func WithPollInterval(d time.Duration) Option {
+	return func(c *Client) { c.pollInterval = d }
+}
+
+// WithBasicAuth sends the username and password with every request.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) { c.auth = basicAuth{username: username, password: password} }
+}
+
+// WithAPIKey authenticates with an API key ("srv_...").
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.auth = bearerAuth(key) }
+}
+
+// WithLogin authenticates with JWTs obtained from /api/token with the username and password.
+// Tokens are refreshed through /api/token/refresh before they expire, and the client logs in again
+// once the refresh token is no longer accepted.
+func WithLogin(username, password string) Option {
+	return func(c *Client) { c.auth = &tokenSource{client: c, username: username, password: password} }
+}
+
+// WithTokens authenticates with an existing JWT pair, which is refreshed through /api/token/refresh.
+// Use Tokens to store the current pair once the client is done.
+func WithTokens(accessToken, refreshToken string) Option {
+	return func(c *Client) {
+		c.auth = &tokenSource{client: c, access: accessToken, refresh: refreshToken, expiry: tokenExpiry(accessToken)}
+	}
+}
+
+// New creates a client for the server at baseURL, including the base path if the server is mounted
+// below a prefix (e.g. "https://example.com/mediahub").
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   http.DefaultClient,
+		auth:         noAuth{},
+		pollInterval: defaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Tokens returns the current JWT pair of a client created with WithLogin or WithTokens, empty otherwise.
+func (c *Client) Tokens() (accessToken, refreshToken string) {
+	if ts, ok := c.auth.(*tokenSource); ok {
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+		return ts.access, ts.refresh
+	}
+	return "", ""
+}
+
+// newRequest builds an API request for the path below /api.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api"+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// newJSONRequest builds an API request with payload as JSON body.
+func (c *Client) newJSONRequest(ctx context.Context, method, path string, payload any) (*http.Request, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do sends an authenticated request. Responses with a status outside of 2xx are returned as *APIError.
+// Requests with a replayable body are sent again once if a rejected JWT could be refreshed.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if err := c.auth.authorize(req); err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", req.Method, req.URL.Path, err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && (req.Body == nil || req.GetBody != nil) {
+		if retry, ok := c.auth.(*tokenSource); ok && retry.renew(req.Context(), req.Header.Get("Authorization")) == nil {
+			resp.Body.Close()
+			if req.GetBody != nil {
+				if req.Body, err = req.GetBody(); err != nil {
+					return nil, fmt.Errorf("failed to rewind request body: %w", err)
+				}
+			}
+			if err := c.auth.authorize(req); err != nil {
+				return nil, err
+			}
+			if resp, err = c.httpClient.Do(req); err != nil {
+				return nil, fmt.Errorf("%s %s failed: %w", req.Method, req.URL.Path, err)
+			}
+		}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
+	}
+	return resp, nil
+}
+
+// doJSON sends a request and decodes the JSON response into out, if it is not nil.
+func (c *Client) doJSON(req *http.Request, out any) (int, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response of %s %s: %w", req.Method, req.URL.Path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
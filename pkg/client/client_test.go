@@ -0,0 +1,243 @@
+package client_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mediahub_oss/internal/httpserver"
+	"mediahub_oss/internal/httpserver/auth"
+	dbh "mediahub_oss/internal/httpserver/databasehandler"
+	eh "mediahub_oss/internal/httpserver/entryhandler"
+	th "mediahub_oss/internal/httpserver/tokenhandler"
+	"mediahub_oss/internal/logging/audit"
+	"mediahub_oss/internal/media"
+	"mediahub_oss/internal/processing"
+	repo "mediahub_oss/internal/repository"
+	"mediahub_oss/internal/repository/migrations"
+	_ "mediahub_oss/internal/repository/migrations/sqlite"
+	"mediahub_oss/internal/repository/sqlite"
+	"mediahub_oss/internal/storage/localstorage"
+	"mediahub_oss/pkg/client"
+	"mediahub_oss/pkg/models"
+
+	"github.com/pressly/goose/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// plainFileConverter handles generic files, which need neither conversion nor previews.
+type plainFileConverter struct {
+	media.MediaConverter
+}
+
+func (plainFileConverter) CanCreatePreview(string) bool { return false }
+
+func (plainFileConverter) CanConvert(string, string) media.ConversionCheck {
+	return media.ConversionCheck{}
+}
+
+func (plainFileConverter) ReadMediaFieldsFromStream(context.Context, io.ReadSeeker, string) (map[string]any, error) {
+	return map[string]any{}, nil
+}
+
+// newTestServer serves the real router on an in-memory repository with the user admin:secret.
+func newTestServer(t *testing.T) (*httptest.Server, *sqlite.SQLiteRepository) {
+	t.Helper()
+
+	r, err := sqlite.NewRepository(":memory:")
+	if err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		t.Fatalf("failed to set goose dialect: %v", err)
+	}
+	goose.SetBaseFS(migrations.EmbedFS)
+	if err := goose.Up(r.DB, "sqlite"); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if _, err := r.CreateUser(context.Background(), repo.User{Username: "admin", PasswordHash: string(hash), IsAdmin: true}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	keys, err := auth.NewKeyring("test-secret")
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	auditor := audit.NewAlNoopLogger()
+	store := &localstorage.LocalStorage{RootPath: t.TempDir()}
+	proc, _ := processing.NewProcessor(r, store, plainFileConverter{}, 1, 4, logger)
+
+	h := &httpserver.Handlers{
+		EntryHandler: eh.EntryHandler{
+			Logger:         logger,
+			Auditor:        auditor,
+			Repo:           r,
+			Storage:        store,
+			Limits:         eh.NewUploadLimits(1024, 0), // larger uploads are processed asynchronously
+			MediaConverter: plainFileConverter{},
+			Processor:      proc,
+		},
+		DatabaseHandler: dbh.DatabaseHandler{Logger: logger, Auditor: auditor, Repo: r, MediaConverter: plainFileConverter{}},
+		TokenHandler:    th.TokenHandler{Logger: logger, Auditor: auditor, Repo: r, Keys: keys, AccessDuration: time.Minute, RefreshDuration: time.Hour},
+	}
+	server := httptest.NewServer(httpserver.SetupRouter(h, http.Dir(t.TempDir()), auth.NewAuthMiddleware(r, keys), "/", "", nil))
+	t.Cleanup(server.Close)
+	return server, r
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server, r := newTestServer(t)
+
+	c, err := client.New(server.URL, client.WithBasicAuth("admin", "secret"), client.WithPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	db, err := c.CreateDatabase(ctx, models.DatabaseCreate{
+		Name:         "sdk_test",
+		ContentType:  "file",
+		Config:       models.DatabaseConfig{UniqueExternalID: true},
+		CustomFields: []models.CustomField{{Name: "source", Type: "TEXT"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	if db.ID == "" || db.Name != "sdk_test" || len(db.CustomFields) != 1 {
+		t.Fatalf("unexpected database %+v", db)
+	}
+
+	// 1. Small uploads are processed synchronously, large ones in the background
+	small, err := c.UploadEntry(ctx, db.ID, client.Upload{
+		File:     strings.NewReader("small file"),
+		FileName: "small.bin",
+		Metadata: models.EntryMetadata{Timestamp: 1000, CustomFields: map[string]any{"source": "a"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to upload small file: %v", err)
+	}
+	if small.Async || small.Entry.Status != "ready" || small.Entry.Size != 10 || small.Entry.FileName != "small.bin" {
+		t.Errorf("expected a ready synchronous entry, got %+v", small)
+	}
+
+	externalID := "large-1"
+	large, err := c.UploadEntry(ctx, db.ID, client.Upload{
+		File:     bytes.NewReader(bytes.Repeat([]byte("x"), 64<<10)),
+		FileName: "large.bin",
+		Metadata: models.EntryMetadata{Timestamp: 2000, ExternalID: &externalID, CustomFields: map[string]any{"source": "b"}},
+		Wait:     true,
+	})
+	if err != nil {
+		t.Fatalf("failed to upload large file: %v", err)
+	}
+	if !large.Async || large.Entry.Status != "ready" || large.Entry.Size != 64<<10 {
+		t.Errorf("expected a ready asynchronous entry, got %+v", large)
+	}
+
+	// The envelope of a conflicting upload carries the existing entry
+	_, err = c.UploadEntry(ctx, db.ID, client.Upload{
+		File:     strings.NewReader("again"),
+		FileName: "again.bin",
+		Metadata: models.EntryMetadata{ExternalID: &externalID},
+	})
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrConflict) || apiErr.Entry == nil || apiErr.Entry.EntryID != large.Entry.EntryID {
+		t.Errorf("expected a conflict with entry %d, got %v", large.Entry.EntryID, err)
+	}
+
+	// 2. Metadata and search
+	meta, err := c.GetEntryMeta(ctx, db.ID, small.Entry.EntryID)
+	if err != nil || meta.CustomFields["source"] != "a" {
+		t.Errorf("expected the metadata of the small entry, got %+v (%v)", meta, err)
+	}
+	found, err := c.SearchEntries(ctx, db.ID, models.SearchRequest{
+		Filter: &models.FilterGroup{Operator: "and", Conditions: []models.Condition{{Field: "source", Operator: "=", Value: "b"}}},
+	})
+	if err != nil || len(found) != 1 || found[0].EntryID != large.Entry.EntryID {
+		t.Errorf("expected the large entry, got %+v (%v)", found, err)
+	}
+	if _, err := c.SearchEntries(ctx, db.ID, models.SearchRequest{Sort: &models.SortCriteria{Field: "unknown"}}); !errors.Is(err, client.ErrBadRequest) {
+		t.Errorf("expected a bad request for an unknown sort field, got %v", err)
+	}
+
+	// 3. Export
+	var archive bytes.Buffer
+	if _, err := c.ExportEntries(ctx, db.ID, models.ExportRequest{IDs: []int64{small.Entry.EntryID, large.Entry.EntryID}}, &archive); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	if len(zr.File) != 3 || zr.File[0].Name != "entries.csv" {
+		t.Errorf("expected entries.csv and two files, got %d files", len(zr.File))
+	}
+
+	// 4. Delete
+	if err := c.DeleteEntry(ctx, db.ID, small.Entry.EntryID); err != nil {
+		t.Fatalf("failed to delete: %v", err)
+	}
+	if _, err := c.GetEntryMeta(ctx, db.ID, small.Entry.EntryID); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("expected the deleted entry to be not found, got %v", err)
+	}
+
+	// 5. Credentials
+	wrong, _ := client.New(server.URL, client.WithBasicAuth("admin", "wrong"))
+	if _, err := wrong.GetEntryMeta(ctx, db.ID, large.Entry.EntryID); !errors.As(err, &apiErr) || !errors.Is(err, client.ErrUnauthorized) || apiErr.Message == "" {
+		t.Errorf("expected an unauthorized error with the plain text message, got %v", err)
+	}
+
+	login, _ := client.New(server.URL, client.WithLogin("admin", "secret"))
+	if _, err := login.GetEntryMeta(ctx, db.ID, large.Entry.EntryID); err != nil {
+		t.Fatalf("failed to read with a JWT: %v", err)
+	}
+	_, refreshToken := login.Tokens()
+
+	// A rejected access token is refreshed and the request is sent again
+	stale, _ := client.New(server.URL, client.WithTokens("expired", refreshToken))
+	if _, err := stale.SearchEntries(ctx, db.ID, models.SearchRequest{}); err != nil {
+		t.Fatalf("expected the token to be refreshed, got %v", err)
+	}
+	if access, refresh := stale.Tokens(); access == "expired" || refresh == refreshToken {
+		t.Error("expected a new token pair")
+	}
+
+	user, err := r.GetUserByUsername(ctx, "admin")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	secret := "0123456789abcdef"
+	hash := sha256.Sum256([]byte(secret))
+	if _, err := r.CreateAPIKey(ctx, repo.APIKey{
+		ID:        repo.ULID("01HZX5K8J6Q9V3T2W1R0P7N4M5"),
+		UserID:    user.ID,
+		Name:      "sdk",
+		KeyHash:   hex.EncodeToString(hash[:]),
+		Scope:     repo.NewAccessGrant(true, false, false, false, false),
+		CreatedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("failed to create API key: %v", err)
+	}
+	withKey, _ := client.New(server.URL, client.WithAPIKey("srv_"+secret))
+	if _, err := withKey.GetEntryMeta(ctx, db.ID, large.Entry.EntryID); err != nil {
+		t.Errorf("failed to read with an API key: %v", err)
+	}
+	if err := withKey.DeleteEntry(ctx, db.ID, large.Entry.EntryID); !errors.Is(err, client.ErrForbidden) {
+		t.Errorf("expected the view-only key to be forbidden to delete, got %v", err)
+	}
+}
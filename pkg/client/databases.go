@@ -0,0 +1,19 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"mediahub_oss/pkg/models"
+)
+
+// CreateDatabase creates a database. Requires global admin rights.
+func (c *Client) CreateDatabase(ctx context.Context, payload models.DatabaseCreate) (models.Database, error) {
+	req, err := c.newJSONRequest(ctx, http.MethodPost, "/database", payload)
+	if err != nil {
+		return models.Database{}, err
+	}
+	var db models.Database
+	_, err = c.doJSON(req, &db)
+	return db, err
+}
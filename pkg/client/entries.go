@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"mediahub_oss/pkg/models"
+)
+
+// ErrProcessingFailed is returned by WaitForEntry if the server could not process the entry.
+var ErrProcessingFailed = errors.New("processing failed")
+
+// defaultPollInterval is how often WaitForEntry checks the status of an entry.
+const defaultPollInterval = time.Second
+
+// Upload describes a new entry. The file is streamed to the server and is never held in memory.
+type Upload struct {
+	File           io.Reader
+	FileName       string // name of the uploaded file, its extension helps to detect the mime type
+	MimeType       string // sent as Content-Type of the file, application/octet-stream if empty
+	Metadata       models.EntryMetadata
+	IdempotencyKey string // identifies retries of the same upload, see the Idempotency-Key header
+
+	// Wait polls an asynchronously processed upload until it is ready (or failed), so the result
+	// always holds the complete entry.
+	Wait bool
+}
+
+// UploadResult is the entry created by an upload.
+type UploadResult struct {
+	Entry models.Entry
+	// Async is set if the server processes the upload in the background (202 Accepted). Without
+	// Upload.Wait, Entry then only holds the fields of the partial response.
+	Async bool
+}
+
+// UploadEntry uploads a file into a database. Small files are processed while the request is handled,
+// large files in the background, which is reported by UploadResult.Async.
+func (c *Client) UploadEntry(ctx context.Context, databaseID string, upload Upload) (UploadResult, error) {
+	metadata, err := json.Marshal(upload.Metadata)
+	if err != nil {
+		return UploadResult{}, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeUploadForm(mw, metadata, upload))
+	}()
+	defer pr.Close()
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/database/"+url.PathEscape(databaseID)+"/entry", pr)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if upload.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", upload.IdempotencyKey)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer resp.Body.Close()
+
+	result := UploadResult{Async: resp.StatusCode == http.StatusAccepted}
+	if err := json.NewDecoder(resp.Body).Decode(&result.Entry); err != nil {
+		return UploadResult{}, fmt.Errorf("failed to decode upload response: %w", err)
+	}
+	if result.Async && upload.Wait {
+		result.Entry, err = c.WaitForEntry(ctx, databaseID, result.Entry.EntryID)
+	}
+	return result, err
+}
+
+// writeUploadForm writes the multipart body of an upload: the metadata, then the file.
+func writeUploadForm(mw *multipart.Writer, metadata []byte, upload Upload) error {
+	if err := mw.WriteField("metadata", string(metadata)); err != nil {
+		return err
+	}
+
+	mimeType := upload.MimeType
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(upload.FileName)))
+	header.Set("Content-Type", mimeType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, upload.File); err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// WaitForEntry polls an entry until its processing has finished. Entries that failed are returned together
+// with an error matching ErrProcessingFailed.
+func (c *Client) WaitForEntry(ctx context.Context, databaseID string, entryID int64) (models.Entry, error) {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		entry, err := c.GetEntryMeta(ctx, databaseID, entryID)
+		if err != nil {
+			return models.Entry{}, err
+		}
+		switch entry.Status {
+		case "queued", "processing":
+		case "error":
+			return entry, fmt.Errorf("%w: entry %d: %s", ErrProcessingFailed, entryID, entry.ErrorReason)
+		default:
+			return entry, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return entry, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetEntryMeta returns the metadata of an entry.
+func (c *Client) GetEntryMeta(ctx context.Context, databaseID string, entryID int64) (models.Entry, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, entryPath(databaseID, entryID), nil)
+	if err != nil {
+		return models.Entry{}, err
+	}
+	var entry models.Entry
+	_, err = c.doJSON(req, &entry)
+	return entry, err
+}
+
+// SearchEntries returns the entries of a database matching the search. If search.Fields is set,
+// only the id and the selected fields of the entries are filled in.
+func (c *Client) SearchEntries(ctx context.Context, databaseID string, search models.SearchRequest) ([]models.Entry, error) {
+	req, err := c.newJSONRequest(ctx, http.MethodPost, "/database/"+url.PathEscape(databaseID)+"/entries/search", search)
+	if err != nil {
+		return nil, err
+	}
+	var entries []models.Entry
+	_, err = c.doJSON(req, &entries)
+	return entries, err
+}
+
+// DeleteEntry deletes an entry and its files.
+func (c *Client) DeleteEntry(ctx context.Context, databaseID string, entryID int64) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, entryPath(databaseID, entryID), nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.doJSON(req, nil)
+	return err
+}
+
+// ExportEntries streams a ZIP archive of the entries (their files and an entries.csv) to w
+// and returns the number of bytes written. With export.Format "parquet" it is a Parquet file of their metadata.
+func (c *Client) ExportEntries(ctx context.Context, databaseID string, export models.ExportRequest, w io.Writer) (int64, error) {
+	req, err := c.newJSONRequest(ctx, http.MethodPost, "/database/"+url.PathEscape(databaseID)+"/entries/export", export)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/zip")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return n, fmt.Errorf("failed to stream export: %w", err)
+	}
+	return n, nil
+}
+
+func entryPath(databaseID string, entryID int64) string {
+	return "/database/" + url.PathEscape(databaseID) + "/entry/" + strconv.FormatInt(entryID, 10)
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"mediahub_oss/pkg/models"
+)
+
+// Errors matched by an *APIError with errors.Is, by the status code of the response.
+var (
+	ErrBadRequest   = errors.New("bad request")            // 400
+	ErrUnauthorized = errors.New("unauthorized")           // 401
+	ErrForbidden    = errors.New("forbidden")              // 403
+	ErrNotFound     = errors.New("not found")              // 404
+	ErrConflict     = errors.New("conflict")               // 409
+	ErrTooLarge     = errors.New("request too large")      // 413
+	ErrUnsupported  = errors.New("unsupported media type") // 415
+	ErrRejected     = errors.New("rejected")               // 422, e.g. by the virus scanner
+	ErrUnavailable  = errors.New("service unavailable")    // 503, e.g. the upload queue is full
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:            ErrBadRequest,
+	http.StatusUnauthorized:          ErrUnauthorized,
+	http.StatusForbidden:             ErrForbidden,
+	http.StatusNotFound:              ErrNotFound,
+	http.StatusConflict:              ErrConflict,
+	http.StatusRequestEntityTooLarge: ErrTooLarge,
+	http.StatusUnsupportedMediaType:  ErrUnsupported,
+	http.StatusUnprocessableEntity:   ErrRejected,
+	http.StatusServiceUnavailable:    ErrUnavailable,
+}
+
+// APIError is an error response of the server.
+type APIError struct {
+	StatusCode int
+	Message    string        // the error of the envelope, or the plain text body
+	Entry      *models.Entry // the entry that already has the external ID of a rejected upload (409)
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mediahub: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is reports whether the status code of the response matches target.
+func (e *APIError) Is(target error) bool {
+	return statusErrors[e.StatusCode] == target
+}
+
+// newAPIError reads the error envelope of a response. Some errors (e.g. of the authentication) are plain text.
+func newAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	var envelope struct {
+		models.ErrorResponse
+		Entry *models.Entry `json:"entry"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && envelope.Error != "" {
+		apiErr.Message = envelope.Error
+		apiErr.Entry = envelope.Entry
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
@@ -0,0 +1,959 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: mediahub/v1/entries.proto
+
+package mediahubpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type UploadEntryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*UploadEntryRequest_Metadata
+	//	*UploadEntryRequest_Chunk
+	Payload       isUploadEntryRequest_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadEntryRequest) Reset() {
+	*x = UploadEntryRequest{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadEntryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadEntryRequest) ProtoMessage() {}
+
+func (x *UploadEntryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadEntryRequest.ProtoReflect.Descriptor instead.
+func (*UploadEntryRequest) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UploadEntryRequest) GetPayload() isUploadEntryRequest_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *UploadEntryRequest) GetMetadata() *UploadMetadata {
+	if x != nil {
+		if x, ok := x.Payload.(*UploadEntryRequest_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+func (x *UploadEntryRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*UploadEntryRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isUploadEntryRequest_Payload interface {
+	isUploadEntryRequest_Payload()
+}
+
+type UploadEntryRequest_Metadata struct {
+	Metadata *UploadMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"` // first message only
+}
+
+type UploadEntryRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"` // the next part of the file
+}
+
+func (*UploadEntryRequest_Metadata) isUploadEntryRequest_Payload() {}
+
+func (*UploadEntryRequest_Chunk) isUploadEntryRequest_Payload() {}
+
+type UploadMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DatabaseId    string                 `protobuf:"bytes,1,opt,name=database_id,json=databaseId,proto3" json:"database_id,omitempty"`
+	Filename      string                 `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`                 // name of the file, stored with the entry
+	MimeType      string                 `protobuf:"bytes,3,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"` // detected from the content and the filename if empty
+	Timestamp     *int64                 `protobuf:"varint,4,opt,name=timestamp,proto3,oneof" json:"timestamp,omitempty"`        // Unix milliseconds, the time of the upload if unset
+	ExternalId    *string                `protobuf:"bytes,5,opt,name=external_id,json=externalId,proto3,oneof" json:"external_id,omitempty"`
+	CustomFields  *structpb.Struct       `protobuf:"bytes,6,opt,name=custom_fields,json=customFields,proto3" json:"custom_fields,omitempty"`
+	Size          int64                  `protobuf:"varint,7,opt,name=size,proto3" json:"size,omitempty"` // total bytes of the chunks, uploads of another size are rejected as truncated; unchecked if 0
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadMetadata) Reset() {
+	*x = UploadMetadata{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadMetadata) ProtoMessage() {}
+
+func (x *UploadMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadMetadata.ProtoReflect.Descriptor instead.
+func (*UploadMetadata) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UploadMetadata) GetDatabaseId() string {
+	if x != nil {
+		return x.DatabaseId
+	}
+	return ""
+}
+
+func (x *UploadMetadata) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *UploadMetadata) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *UploadMetadata) GetTimestamp() int64 {
+	if x != nil && x.Timestamp != nil {
+		return *x.Timestamp
+	}
+	return 0
+}
+
+func (x *UploadMetadata) GetExternalId() string {
+	if x != nil && x.ExternalId != nil {
+		return *x.ExternalId
+	}
+	return ""
+}
+
+func (x *UploadMetadata) GetCustomFields() *structpb.Struct {
+	if x != nil {
+		return x.CustomFields
+	}
+	return nil
+}
+
+func (x *UploadMetadata) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type Entry struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DatabaseId      string                 `protobuf:"bytes,1,opt,name=database_id,json=databaseId,proto3" json:"database_id,omitempty"`
+	Id              int64                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	ExternalId      string                 `protobuf:"bytes,3,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	Filename        string                 `protobuf:"bytes,4,opt,name=filename,proto3" json:"filename,omitempty"`
+	MimeType        string                 `protobuf:"bytes,5,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	Status          string                 `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"` // "ready", "processing", "queued" or "error"
+	ErrorReason     string                 `protobuf:"bytes,7,opt,name=error_reason,json=errorReason,proto3" json:"error_reason,omitempty"`
+	ErrorDetail     string                 `protobuf:"bytes,8,opt,name=error_detail,json=errorDetail,proto3" json:"error_detail,omitempty"`
+	Size            uint64                 `protobuf:"varint,9,opt,name=size,proto3" json:"size,omitempty"`
+	PreviewSize     uint64                 `protobuf:"varint,10,opt,name=preview_size,json=previewSize,proto3" json:"preview_size,omitempty"`
+	Timestamp       int64                  `protobuf:"varint,11,opt,name=timestamp,proto3" json:"timestamp,omitempty"` // Unix milliseconds, like the other times
+	CreatedAt       int64                  `protobuf:"varint,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt       int64                  `protobuf:"varint,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	ClientTimestamp *int64                 `protobuf:"varint,14,opt,name=client_timestamp,json=clientTimestamp,proto3,oneof" json:"client_timestamp,omitempty"` // the timestamp sent by the client if the server clamped it
+	MediaFields     *structpb.Struct       `protobuf:"bytes,15,opt,name=media_fields,json=mediaFields,proto3" json:"media_fields,omitempty"`
+	CustomFields    *structpb.Struct       `protobuf:"bytes,16,opt,name=custom_fields,json=customFields,proto3" json:"custom_fields,omitempty"`
+	LegalHold       bool                   `protobuf:"varint,17,opt,name=legal_hold,json=legalHold,proto3" json:"legal_hold,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Entry) GetDatabaseId() string {
+	if x != nil {
+		return x.DatabaseId
+	}
+	return ""
+}
+
+func (x *Entry) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Entry) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+func (x *Entry) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Entry) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *Entry) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Entry) GetErrorReason() string {
+	if x != nil {
+		return x.ErrorReason
+	}
+	return ""
+}
+
+func (x *Entry) GetErrorDetail() string {
+	if x != nil {
+		return x.ErrorDetail
+	}
+	return ""
+}
+
+func (x *Entry) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Entry) GetPreviewSize() uint64 {
+	if x != nil {
+		return x.PreviewSize
+	}
+	return 0
+}
+
+func (x *Entry) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Entry) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Entry) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+func (x *Entry) GetClientTimestamp() int64 {
+	if x != nil && x.ClientTimestamp != nil {
+		return *x.ClientTimestamp
+	}
+	return 0
+}
+
+func (x *Entry) GetMediaFields() *structpb.Struct {
+	if x != nil {
+		return x.MediaFields
+	}
+	return nil
+}
+
+func (x *Entry) GetCustomFields() *structpb.Struct {
+	if x != nil {
+		return x.CustomFields
+	}
+	return nil
+}
+
+func (x *Entry) GetLegalHold() bool {
+	if x != nil {
+		return x.LegalHold
+	}
+	return false
+}
+
+type GetEntryMetaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DatabaseId    string                 `protobuf:"bytes,1,opt,name=database_id,json=databaseId,proto3" json:"database_id,omitempty"`
+	Id            int64                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEntryMetaRequest) Reset() {
+	*x = GetEntryMetaRequest{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEntryMetaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEntryMetaRequest) ProtoMessage() {}
+
+func (x *GetEntryMetaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEntryMetaRequest.ProtoReflect.Descriptor instead.
+func (*GetEntryMetaRequest) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetEntryMetaRequest) GetDatabaseId() string {
+	if x != nil {
+		return x.DatabaseId
+	}
+	return ""
+}
+
+func (x *GetEntryMetaRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type SearchEntriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DatabaseId    string                 `protobuf:"bytes,1,opt,name=database_id,json=databaseId,proto3" json:"database_id,omitempty"`
+	Filter        *Filter                `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+	Sort          *Sort                  `protobuf:"bytes,3,opt,name=sort,proto3" json:"sort,omitempty"`
+	Offset        int32                  `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit         int32                  `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"` // the default page size if 0, clamped to the maximum page size
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchEntriesRequest) Reset() {
+	*x = SearchEntriesRequest{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchEntriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchEntriesRequest) ProtoMessage() {}
+
+func (x *SearchEntriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchEntriesRequest.ProtoReflect.Descriptor instead.
+func (*SearchEntriesRequest) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchEntriesRequest) GetDatabaseId() string {
+	if x != nil {
+		return x.DatabaseId
+	}
+	return ""
+}
+
+func (x *SearchEntriesRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *SearchEntriesRequest) GetSort() *Sort {
+	if x != nil {
+		return x.Sort
+	}
+	return nil
+}
+
+func (x *SearchEntriesRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SearchEntriesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type Filter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operator      string                 `protobuf:"bytes,1,opt,name=operator,proto3" json:"operator,omitempty"` // "and" or "or"
+	Conditions    []*Condition           `protobuf:"bytes,2,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Filter) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *Filter) GetConditions() []*Condition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+type Condition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Operator      string                 `protobuf:"bytes,2,opt,name=operator,proto3" json:"operator,omitempty"` // e.g. "=", ">", "<", "LIKE", "MATCH" on full-text fields
+	Value         *structpb.Value        `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`       // timestamp fields also take relative times like "now-24h"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Condition) Reset() {
+	*x = Condition{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Condition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Condition) ProtoMessage() {}
+
+func (x *Condition) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Condition.ProtoReflect.Descriptor instead.
+func (*Condition) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Condition) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *Condition) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *Condition) GetValue() *structpb.Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type Sort struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`         // or "fts_rank", the relevance of the first MATCH condition
+	Direction     string                 `protobuf:"bytes,2,opt,name=direction,proto3" json:"direction,omitempty"` // "asc" or "desc"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sort) Reset() {
+	*x = Sort{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sort) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sort) ProtoMessage() {}
+
+func (x *Sort) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sort.ProtoReflect.Descriptor instead.
+func (*Sort) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Sort) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *Sort) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+type SearchEntriesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchEntriesResponse) Reset() {
+	*x = SearchEntriesResponse{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchEntriesResponse) ProtoMessage() {}
+
+func (x *SearchEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchEntriesResponse.ProtoReflect.Descriptor instead.
+func (*SearchEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SearchEntriesResponse) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type DeleteEntryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DatabaseId    string                 `protobuf:"bytes,1,opt,name=database_id,json=databaseId,proto3" json:"database_id,omitempty"`
+	Id            int64                  `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteEntryRequest) Reset() {
+	*x = DeleteEntryRequest{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteEntryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteEntryRequest) ProtoMessage() {}
+
+func (x *DeleteEntryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteEntryRequest.ProtoReflect.Descriptor instead.
+func (*DeleteEntryRequest) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteEntryRequest) GetDatabaseId() string {
+	if x != nil {
+		return x.DatabaseId
+	}
+	return ""
+}
+
+func (x *DeleteEntryRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteEntryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteEntryResponse) Reset() {
+	*x = DeleteEntryResponse{}
+	mi := &file_mediahub_v1_entries_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteEntryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteEntryResponse) ProtoMessage() {}
+
+func (x *DeleteEntryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mediahub_v1_entries_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteEntryResponse.ProtoReflect.Descriptor instead.
+func (*DeleteEntryResponse) Descriptor() ([]byte, []int) {
+	return file_mediahub_v1_entries_proto_rawDescGZIP(), []int{10}
+}
+
+var File_mediahub_v1_entries_proto protoreflect.FileDescriptor
+
+const file_mediahub_v1_entries_proto_rawDesc = "" +
+	"\n" +
+	"\x19mediahub/v1/entries.proto\x12\vmediahub.v1\x1a\x1cgoogle/protobuf/struct.proto\"r\n" +
+	"\x12UploadEntryRequest\x129\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x1b.mediahub.v1.UploadMetadataH\x00R\bmetadata\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
+	"\apayload\"\xa3\x02\n" +
+	"\x0eUploadMetadata\x12\x1f\n" +
+	"\vdatabase_id\x18\x01 \x01(\tR\n" +
+	"databaseId\x12\x1a\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\x12\x1b\n" +
+	"\tmime_type\x18\x03 \x01(\tR\bmimeType\x12!\n" +
+	"\ttimestamp\x18\x04 \x01(\x03H\x00R\ttimestamp\x88\x01\x01\x12$\n" +
+	"\vexternal_id\x18\x05 \x01(\tH\x01R\n" +
+	"externalId\x88\x01\x01\x12<\n" +
+	"\rcustom_fields\x18\x06 \x01(\v2\x17.google.protobuf.StructR\fcustomFields\x12\x12\n" +
+	"\x04size\x18\a \x01(\x03R\x04sizeB\f\n" +
+	"\n" +
+	"_timestampB\x0e\n" +
+	"\f_external_id\"\xe1\x04\n" +
+	"\x05Entry\x12\x1f\n" +
+	"\vdatabase_id\x18\x01 \x01(\tR\n" +
+	"databaseId\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x03R\x02id\x12\x1f\n" +
+	"\vexternal_id\x18\x03 \x01(\tR\n" +
+	"externalId\x12\x1a\n" +
+	"\bfilename\x18\x04 \x01(\tR\bfilename\x12\x1b\n" +
+	"\tmime_type\x18\x05 \x01(\tR\bmimeType\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\x12!\n" +
+	"\ferror_reason\x18\a \x01(\tR\verrorReason\x12!\n" +
+	"\ferror_detail\x18\b \x01(\tR\verrorDetail\x12\x12\n" +
+	"\x04size\x18\t \x01(\x04R\x04size\x12!\n" +
+	"\fpreview_size\x18\n" +
+	" \x01(\x04R\vpreviewSize\x12\x1c\n" +
+	"\ttimestamp\x18\v \x01(\x03R\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\f \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\r \x01(\x03R\tupdatedAt\x12.\n" +
+	"\x10client_timestamp\x18\x0e \x01(\x03H\x00R\x0fclientTimestamp\x88\x01\x01\x12:\n" +
+	"\fmedia_fields\x18\x0f \x01(\v2\x17.google.protobuf.StructR\vmediaFields\x12<\n" +
+	"\rcustom_fields\x18\x10 \x01(\v2\x17.google.protobuf.StructR\fcustomFields\x12\x1d\n" +
+	"\n" +
+	"legal_hold\x18\x11 \x01(\bR\tlegalHoldB\x13\n" +
+	"\x11_client_timestamp\"F\n" +
+	"\x13GetEntryMetaRequest\x12\x1f\n" +
+	"\vdatabase_id\x18\x01 \x01(\tR\n" +
+	"databaseId\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x03R\x02id\"\xb9\x01\n" +
+	"\x14SearchEntriesRequest\x12\x1f\n" +
+	"\vdatabase_id\x18\x01 \x01(\tR\n" +
+	"databaseId\x12+\n" +
+	"\x06filter\x18\x02 \x01(\v2\x13.mediahub.v1.FilterR\x06filter\x12%\n" +
+	"\x04sort\x18\x03 \x01(\v2\x11.mediahub.v1.SortR\x04sort\x12\x16\n" +
+	"\x06offset\x18\x04 \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05limit\x18\x05 \x01(\x05R\x05limit\"\\\n" +
+	"\x06Filter\x12\x1a\n" +
+	"\boperator\x18\x01 \x01(\tR\boperator\x126\n" +
+	"\n" +
+	"conditions\x18\x02 \x03(\v2\x16.mediahub.v1.ConditionR\n" +
+	"conditions\"k\n" +
+	"\tCondition\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x1a\n" +
+	"\boperator\x18\x02 \x01(\tR\boperator\x12,\n" +
+	"\x05value\x18\x03 \x01(\v2\x16.google.protobuf.ValueR\x05value\":\n" +
+	"\x04Sort\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x1c\n" +
+	"\tdirection\x18\x02 \x01(\tR\tdirection\"E\n" +
+	"\x15SearchEntriesResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.mediahub.v1.EntryR\aentries\"E\n" +
+	"\x12DeleteEntryRequest\x12\x1f\n" +
+	"\vdatabase_id\x18\x01 \x01(\tR\n" +
+	"databaseId\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\x03R\x02id\"\x15\n" +
+	"\x13DeleteEntryResponse2\xc4\x02\n" +
+	"\fEntryService\x12D\n" +
+	"\vUploadEntry\x12\x1f.mediahub.v1.UploadEntryRequest\x1a\x12.mediahub.v1.Entry(\x01\x12D\n" +
+	"\fGetEntryMeta\x12 .mediahub.v1.GetEntryMetaRequest\x1a\x12.mediahub.v1.Entry\x12V\n" +
+	"\rSearchEntries\x12!.mediahub.v1.SearchEntriesRequest\x1a\".mediahub.v1.SearchEntriesResponse\x12P\n" +
+	"\vDeleteEntry\x12\x1f.mediahub.v1.DeleteEntryRequest\x1a .mediahub.v1.DeleteEntryResponseB\x1dZ\x1bmediahub_oss/pkg/mediahubpbb\x06proto3"
+
+var (
+	file_mediahub_v1_entries_proto_rawDescOnce sync.Once
+	file_mediahub_v1_entries_proto_rawDescData []byte
+)
+
+func file_mediahub_v1_entries_proto_rawDescGZIP() []byte {
+	file_mediahub_v1_entries_proto_rawDescOnce.Do(func() {
+		file_mediahub_v1_entries_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_mediahub_v1_entries_proto_rawDesc), len(file_mediahub_v1_entries_proto_rawDesc)))
+	})
+	return file_mediahub_v1_entries_proto_rawDescData
+}
+
+var file_mediahub_v1_entries_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_mediahub_v1_entries_proto_goTypes = []any{
+	(*UploadEntryRequest)(nil),    // 0: mediahub.v1.UploadEntryRequest
+	(*UploadMetadata)(nil),        // 1: mediahub.v1.UploadMetadata
+	(*Entry)(nil),                 // 2: mediahub.v1.Entry
+	(*GetEntryMetaRequest)(nil),   // 3: mediahub.v1.GetEntryMetaRequest
+	(*SearchEntriesRequest)(nil),  // 4: mediahub.v1.SearchEntriesRequest
+	(*Filter)(nil),                // 5: mediahub.v1.Filter
+	(*Condition)(nil),             // 6: mediahub.v1.Condition
+	(*Sort)(nil),                  // 7: mediahub.v1.Sort
+	(*SearchEntriesResponse)(nil), // 8: mediahub.v1.SearchEntriesResponse
+	(*DeleteEntryRequest)(nil),    // 9: mediahub.v1.DeleteEntryRequest
+	(*DeleteEntryResponse)(nil),   // 10: mediahub.v1.DeleteEntryResponse
+	(*structpb.Struct)(nil),       // 11: google.protobuf.Struct
+	(*structpb.Value)(nil),        // 12: google.protobuf.Value
+}
+var file_mediahub_v1_entries_proto_depIdxs = []int32{
+	1,  // 0: mediahub.v1.UploadEntryRequest.metadata:type_name -> mediahub.v1.UploadMetadata
+	11, // 1: mediahub.v1.UploadMetadata.custom_fields:type_name -> google.protobuf.Struct
+	11, // 2: mediahub.v1.Entry.media_fields:type_name -> google.protobuf.Struct
+	11, // 3: mediahub.v1.Entry.custom_fields:type_name -> google.protobuf.Struct
+	5,  // 4: mediahub.v1.SearchEntriesRequest.filter:type_name -> mediahub.v1.Filter
+	7,  // 5: mediahub.v1.SearchEntriesRequest.sort:type_name -> mediahub.v1.Sort
+	6,  // 6: mediahub.v1.Filter.conditions:type_name -> mediahub.v1.Condition
+	12, // 7: mediahub.v1.Condition.value:type_name -> google.protobuf.Value
+	2,  // 8: mediahub.v1.SearchEntriesResponse.entries:type_name -> mediahub.v1.Entry
+	0,  // 9: mediahub.v1.EntryService.UploadEntry:input_type -> mediahub.v1.UploadEntryRequest
+	3,  // 10: mediahub.v1.EntryService.GetEntryMeta:input_type -> mediahub.v1.GetEntryMetaRequest
+	4,  // 11: mediahub.v1.EntryService.SearchEntries:input_type -> mediahub.v1.SearchEntriesRequest
+	9,  // 12: mediahub.v1.EntryService.DeleteEntry:input_type -> mediahub.v1.DeleteEntryRequest
+	2,  // 13: mediahub.v1.EntryService.UploadEntry:output_type -> mediahub.v1.Entry
+	2,  // 14: mediahub.v1.EntryService.GetEntryMeta:output_type -> mediahub.v1.Entry
+	8,  // 15: mediahub.v1.EntryService.SearchEntries:output_type -> mediahub.v1.SearchEntriesResponse
+	10, // 16: mediahub.v1.EntryService.DeleteEntry:output_type -> mediahub.v1.DeleteEntryResponse
+	13, // [13:17] is the sub-list for method output_type
+	9,  // [9:13] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_mediahub_v1_entries_proto_init() }
+func file_mediahub_v1_entries_proto_init() {
+	if File_mediahub_v1_entries_proto != nil {
+		return
+	}
+	file_mediahub_v1_entries_proto_msgTypes[0].OneofWrappers = []any{
+		(*UploadEntryRequest_Metadata)(nil),
+		(*UploadEntryRequest_Chunk)(nil),
+	}
+	file_mediahub_v1_entries_proto_msgTypes[1].OneofWrappers = []any{}
+	file_mediahub_v1_entries_proto_msgTypes[2].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_mediahub_v1_entries_proto_rawDesc), len(file_mediahub_v1_entries_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_mediahub_v1_entries_proto_goTypes,
+		DependencyIndexes: file_mediahub_v1_entries_proto_depIdxs,
+		MessageInfos:      file_mediahub_v1_entries_proto_msgTypes,
+	}.Build()
+	File_mediahub_v1_entries_proto = out.File
+	file_mediahub_v1_entries_proto_goTypes = nil
+	file_mediahub_v1_entries_proto_depIdxs = nil
+}
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: mediahub/v1/entries.proto
+
+package mediahubpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	EntryService_UploadEntry_FullMethodName   = "/mediahub.v1.EntryService/UploadEntry"
+	EntryService_GetEntryMeta_FullMethodName  = "/mediahub.v1.EntryService/GetEntryMeta"
+	EntryService_SearchEntries_FullMethodName = "/mediahub.v1.EntryService/SearchEntries"
+	EntryService_DeleteEntry_FullMethodName   = "/mediahub.v1.EntryService/DeleteEntry"
+)
+
+// EntryServiceClient is the client API for EntryService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// EntryService is the gRPC counterpart of the entry endpoints of the REST API, for programmatic ingestion.
+// Calls are authenticated with the "authorization" metadata, "Bearer <token>" with a JWT or an API key,
+// and need the same permissions on the database as the REST endpoints.
+type EntryServiceClient interface {
+	// UploadEntry creates an entry. The first message carries the metadata, the following ones the file in
+	// chunks of any size. Files larger than the sync upload size are processed asynchronously, the returned
+	// entry is then still "processing".
+	UploadEntry(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadEntryRequest, Entry], error)
+	// GetEntryMeta returns the metadata of an entry.
+	GetEntryMeta(ctx context.Context, in *GetEntryMetaRequest, opts ...grpc.CallOption) (*Entry, error)
+	// SearchEntries filters, sorts and pages the entries of a database like POST /database/{id}/entries/search.
+	SearchEntries(ctx context.Context, in *SearchEntriesRequest, opts ...grpc.CallOption) (*SearchEntriesResponse, error)
+	// DeleteEntry deletes the file and the metadata of an entry.
+	DeleteEntry(ctx context.Context, in *DeleteEntryRequest, opts ...grpc.CallOption) (*DeleteEntryResponse, error)
+}
+
+type entryServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEntryServiceClient(cc grpc.ClientConnInterface) EntryServiceClient {
+	return &entryServiceClient{cc}
+}
+
+func (c *entryServiceClient) UploadEntry(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[UploadEntryRequest, Entry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EntryService_ServiceDesc.Streams[0], EntryService_UploadEntry_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[UploadEntryRequest, Entry]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EntryService_UploadEntryClient = grpc.ClientStreamingClient[UploadEntryRequest, Entry]
+
+func (c *entryServiceClient) GetEntryMeta(ctx context.Context, in *GetEntryMetaRequest, opts ...grpc.CallOption) (*Entry, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Entry)
+	err := c.cc.Invoke(ctx, EntryService_GetEntryMeta_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entryServiceClient) SearchEntries(ctx context.Context, in *SearchEntriesRequest, opts ...grpc.CallOption) (*SearchEntriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchEntriesResponse)
+	err := c.cc.Invoke(ctx, EntryService_SearchEntries_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *entryServiceClient) DeleteEntry(ctx context.Context, in *DeleteEntryRequest, opts ...grpc.CallOption) (*DeleteEntryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteEntryResponse)
+	err := c.cc.Invoke(ctx, EntryService_DeleteEntry_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EntryServiceServer is the server API for EntryService service.
+// All implementations must embed UnimplementedEntryServiceServer
+// for forward compatibility.
+//
+// EntryService is the gRPC counterpart of the entry endpoints of the REST API, for programmatic ingestion.
+// Calls are authenticated with the "authorization" metadata, "Bearer <token>" with a JWT or an API key,
+// and need the same permissions on the database as the REST endpoints.
+type EntryServiceServer interface {
+	// UploadEntry creates an entry. The first message carries the metadata, the following ones the file in
+	// chunks of any size. Files larger than the sync upload size are processed asynchronously, the returned
+	// entry is then still "processing".
+	UploadEntry(grpc.ClientStreamingServer[UploadEntryRequest, Entry]) error
+	// GetEntryMeta returns the metadata of an entry.
+	GetEntryMeta(context.Context, *GetEntryMetaRequest) (*Entry, error)
+	// SearchEntries filters, sorts and pages the entries of a database like POST /database/{id}/entries/search.
+	SearchEntries(context.Context, *SearchEntriesRequest) (*SearchEntriesResponse, error)
+	// DeleteEntry deletes the file and the metadata of an entry.
+	DeleteEntry(context.Context, *DeleteEntryRequest) (*DeleteEntryResponse, error)
+	mustEmbedUnimplementedEntryServiceServer()
+}
+
+// UnimplementedEntryServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedEntryServiceServer struct{}
+
+func (UnimplementedEntryServiceServer) UploadEntry(grpc.ClientStreamingServer[UploadEntryRequest, Entry]) error {
+	return status.Errorf(codes.Unimplemented, "method UploadEntry not implemented")
+}
+func (UnimplementedEntryServiceServer) GetEntryMeta(context.Context, *GetEntryMetaRequest) (*Entry, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEntryMeta not implemented")
+}
+func (UnimplementedEntryServiceServer) SearchEntries(context.Context, *SearchEntriesRequest) (*SearchEntriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchEntries not implemented")
+}
+func (UnimplementedEntryServiceServer) DeleteEntry(context.Context, *DeleteEntryRequest) (*DeleteEntryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteEntry not implemented")
+}
+func (UnimplementedEntryServiceServer) mustEmbedUnimplementedEntryServiceServer() {}
+func (UnimplementedEntryServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeEntryServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EntryServiceServer will
+// result in compilation errors.
+type UnsafeEntryServiceServer interface {
+	mustEmbedUnimplementedEntryServiceServer()
+}
+
+func RegisterEntryServiceServer(s grpc.ServiceRegistrar, srv EntryServiceServer) {
+	// If the following call pancis, it indicates UnimplementedEntryServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&EntryService_ServiceDesc, srv)
+}
+
+func _EntryService_UploadEntry_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EntryServiceServer).UploadEntry(&grpc.GenericServerStream[UploadEntryRequest, Entry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EntryService_UploadEntryServer = grpc.ClientStreamingServer[UploadEntryRequest, Entry]
+
+func _EntryService_GetEntryMeta_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntryMetaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntryServiceServer).GetEntryMeta(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EntryService_GetEntryMeta_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntryServiceServer).GetEntryMeta(ctx, req.(*GetEntryMetaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EntryService_SearchEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntryServiceServer).SearchEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EntryService_SearchEntries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntryServiceServer).SearchEntries(ctx, req.(*SearchEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EntryService_DeleteEntry_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteEntryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EntryServiceServer).DeleteEntry(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EntryService_DeleteEntry_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EntryServiceServer).DeleteEntry(ctx, req.(*DeleteEntryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// EntryService_ServiceDesc is the grpc.ServiceDesc for EntryService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EntryService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mediahub.v1.EntryService",
+	HandlerType: (*EntryServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetEntryMeta",
+			Handler:    _EntryService_GetEntryMeta_Handler,
+		},
+		{
+			MethodName: "SearchEntries",
+			Handler:    _EntryService_SearchEntries_Handler,
+		},
+		{
+			MethodName: "DeleteEntry",
+			Handler:    _EntryService_DeleteEntry_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadEntry",
+			Handler:       _EntryService_UploadEntry_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "mediahub/v1/entries.proto",
+}
@@ -0,0 +1,4 @@
+// Package mediahubpb holds the generated messages and the client of the gRPC API, see proto/mediahub/v1.
+package mediahubpb
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=module=mediahub_oss/pkg/mediahubpb --go-grpc_out=. --go-grpc_opt=module=mediahub_oss/pkg/mediahubpb mediahub/v1/entries.proto
@@ -0,0 +1,127 @@
+package models
+
+// The database payloads mirror those of the database handler, which are built on the repository types.
+
+// DatabaseCreate defines the required JSON payload for POST /api/database.
+type DatabaseCreate struct {
+	Name         string         `json:"name"`
+	ContentType  string         `json:"content_type"` // image, audio, video or file
+	NMaxQueued   int            `json:"n_max_queued"`
+	Config       DatabaseConfig `json:"config"`
+	Housekeeping Housekeeping   `json:"housekeeping"`
+	CustomFields []CustomField  `json:"custom_fields"`
+}
+
+type CustomField struct {
+	ID        *int   `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Type      string `json:"type"` // TEXT, INTEGER, REAL or BOOLEAN
+	IsIndexed *bool  `json:"is_indexed,omitempty"`
+	// IsSensitive hides the field from users who may only view entries.
+	IsSensitive *bool `json:"is_sensitive,omitempty"`
+}
+
+// DatabaseConfig defines the JSON structure for type-specific settings.
+type DatabaseConfig struct {
+	CreatePreview    bool   `json:"create_preview"`
+	AutoConversion   string `json:"auto_conversion"`
+	KeepOriginal     bool   `json:"keep_original"`      // keep the uploaded file next to the auto converted one
+	UniqueExternalID bool   `json:"unique_external_id"` // reject uploads and updates reusing an external_id
+	PublicRead       bool   `json:"public_read"`        // entries can be read without authentication
+
+	// Per-mime conversions, they take precedence over auto_conversion
+	ConversionRules []ConversionRule `json:"conversion_rules"`
+
+	// Transcription of audio entries, omitted if disabled
+	Transcription *TranscriptionConfig `json:"transcription,omitempty"`
+
+	// TEXT custom fields searchable with the MATCH operator
+	FulltextFields []string `json:"fulltext_fields"`
+
+	// REAL custom fields holding the coordinates of the entries, for geo searches, omitted if not declared
+	GeoFields *GeoFields `json:"geo_fields,omitempty"`
+
+	// Custom field values filled into uploads whose metadata lacks them
+	MetadataDefaults map[string]any `json:"metadata_defaults,omitempty"`
+	// Custom field values set on every upload, replacing those of the client
+	MetadataOverrides map[string]any `json:"metadata_overrides,omitempty"`
+
+	// Waveform previews of audio entries, omitted values select the defaults (200x120, "#1E90FF")
+	WaveformWidth      int    `json:"waveform_width,omitempty"`
+	WaveformHeight     int    `json:"waveform_height,omitempty"`
+	WaveformColor      string `json:"waveform_color,omitempty"`
+	WaveformBackground string `json:"waveform_background,omitempty"` // hex or "transparent" (PNG previews)
+}
+
+// ConversionRule converts uploads of one mime type to another.
+type ConversionRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// TranscriptionConfig sends ready audio entries to an OpenAI-compatible transcription service.
+type TranscriptionConfig struct {
+	Endpoint string `json:"endpoint"`           // base URL of the service, e.g. "http://whisper:8000"
+	Model    string `json:"model"`              // model name sent with each request
+	Field    string `json:"field"`              // name of the TEXT custom field receiving the text
+	Language string `json:"language,omitempty"` // optional language hint, e.g. "en"
+}
+
+// GeoFields names the REAL custom fields holding the latitude and longitude of the entries.
+type GeoFields struct {
+	Lat string `json:"lat"`
+	Lon string `json:"lon"`
+}
+
+// Housekeeping defines the JSON structure for housekeeping rules.
+// "0" or "disabled" switches a rule off, an empty string applies the default.
+type Housekeeping struct {
+	Interval  string `json:"interval"`
+	DiskSpace string `json:"disk_space"`
+	MaxAge    string `json:"max_age"`
+
+	// The time max_age is measured from: "capture" (the entry timestamp, default) or "ingestion" (created_at)
+	AgeBasis string `json:"age_basis,omitempty"`
+
+	// An alert is sent once the usage reaches this percentage of disk_space (default 85), 0 disables it
+	DiskSpaceWarnPercent *int `json:"disk_space_warn_percent,omitempty"`
+}
+
+// Database is a database as returned by the API.
+type Database struct {
+	ID           string               `json:"id"`
+	Name         string               `json:"name"`
+	ContentType  string               `json:"content_type"`
+	NMaxQueued   int                  `json:"n_max_queued"`
+	Config       DatabaseConfig       `json:"config"`
+	Housekeeping DatabaseHousekeeping `json:"housekeeping"`
+	CustomFields []CustomField        `json:"custom_fields"`
+	Stats        DatabaseStats        `json:"stats"`
+}
+
+type DatabaseHousekeeping struct {
+	Interval  string `json:"interval"`   // e.g."10min"
+	DiskSpace string `json:"disk_space"` // e.g. "10G"
+	MaxAge    string `json:"max_age"`    // e.g. "365d"
+	AgeBasis  string `json:"age_basis"`  // "capture" or "ingestion"
+
+	// The values as understood by the server, 0 if the rule is disabled
+	IntervalSeconds int64  `json:"interval_seconds"`
+	DiskSpaceBytes  uint64 `json:"disk_space_bytes"`
+	MaxAgeSeconds   int64  `json:"max_age_seconds"`
+
+	DiskSpaceWarnPercent int `json:"disk_space_warn_percent"` // 0 if disk space alerts are disabled
+}
+
+type DatabaseStats struct {
+	EntryCount          uint64   `json:"entry_count"` // all entries, including processing_count and failed ones
+	TotalDiskSpaceBytes uint64   `json:"total_disk_space_bytes"`
+	ProcessingCount     uint64   `json:"processing_count"` // queued or processing entries
+	UsagePercent        *float64 `json:"usage_percent"`    // of the housekeeping disk_space, null if it is disabled
+	AlertActive         bool     `json:"alert_active"`
+}
+
+// ErrorResponse is the error envelope of the API.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,82 @@
+// Package models holds the JSON payloads of the HTTP API that are shared by the server and its clients.
+// It only depends on the standard library, so importing it does not pull in any server code.
+package models
+
+// EntryMetadata is the metadata that can be added when sending a new entry, or changed by a patch.
+type EntryMetadata struct {
+	Timestamp    int64          `json:"timestamp"`
+	FileName     string         `json:"filename"`
+	ExternalID   *string        `json:"external_id"` // omitted keeps the current value, an empty string removes it
+	CustomFields map[string]any `json:"custom_fields"`
+}
+
+// ExportRequest defines the payload for the export endpoint.
+type ExportRequest struct {
+	IDs              []int64 `json:"ids"`
+	IncludeOriginals bool    `json:"include_originals,omitempty"` // add the kept originals of converted entries under originals/
+	Format           string  `json:"format,omitempty"`            // "zip" (default) or "parquet" (metadata only, no files)
+	IncludeComments  bool    `json:"include_comments,omitempty"`  // add the comments of each entry as comments/<id>.json (zip only)
+	IncludeChecksums bool    `json:"include_checksums,omitempty"` // add checksums.sha256 and manifest.json listing the SHA-256 of every file (zip only)
+}
+
+// Entry is returned in case of sync file handling or entry requests.
+type Entry struct {
+	DatabaseID     string         `json:"database_id"`
+	EntryID        int64          `json:"id"`
+	ExternalID     string         `json:"external_id,omitempty"`
+	FileName       string         `json:"filename"`
+	Size           uint64         `json:"filesize"`
+	ContentVersion string         `json:"content_version"` // changes whenever the stored file is written, pass it as ?version= to the file download
+	PreviewSize    uint64         `json:"preview_filesize"`
+	OriginalSize   uint64         `json:"original_filesize,omitempty"`  // only set if the original of a converted upload is kept
+	OriginalMime   string         `json:"original_mime_type,omitempty"` // download it with ?variant=original
+	Status         string         `json:"status"`
+	ErrorReason    string         `json:"error_reason,omitempty"` // why processing failed, or on ready entries why the preview is missing
+	ErrorDetail    string         `json:"error_detail,omitempty"` // end of the stderr of the media tool that failed
+	Timestamp      int64          `json:"timestamp"`
+	ClientTS       *int64         `json:"client_timestamp,omitempty"` // the client's timestamp if it was out of bounds and replaced by the server time
+	CreatedAt      int64          `json:"created_at"`
+	UpdatedAt      int64          `json:"updated_at"`
+	MimeType       string         `json:"mime_type"`
+	MediaFields    map[string]any `json:"media_fields"`
+	CustomFields   map[string]any `json:"custom_fields"`
+	UploadedBy     *string        `json:"uploaded_by"`                    // null for entries uploaded before the origin was recorded
+	UploadSource   *UploadSource  `json:"upload_source"`                  // null for entries uploaded before the origin was recorded
+	Transcription  string         `json:"transcription_status,omitempty"` // pending, done or failed, omitted if the entry is not transcribed
+	LegalHold      bool           `json:"legal_hold"`                     // preserved for compliance, it cannot be deleted until released
+	CommentCount   *int64         `json:"comment_count,omitempty"`        // added with ?include_comment_count=true
+	HasPreview     *bool          `json:"has_preview,omitempty"`          // in the response of uploads with ?sync_preview=true
+	Links          *EntryLinks    `json:"_links,omitempty"`
+}
+
+// UploadSource is where an entry was uploaded from.
+type UploadSource struct {
+	IP        string `json:"ip"` // the client address, resolved through the trusted proxies
+	UserAgent string `json:"user_agent"`
+}
+
+// EntryLinks holds the URLs of an entry's endpoints, added with ?include_links=true.
+type EntryLinks struct {
+	Meta    string `json:"meta"`
+	File    string `json:"file"`
+	Preview string `json:"preview,omitempty"` // only set if the entry has a preview (the waveform for audio)
+	Shares  string `json:"shares"`
+}
+
+// PartialEntry is returned in case of async file handling.
+type PartialEntry struct {
+	DatabaseID   string         `json:"database_id"`
+	EntryID      int64          `json:"id"`
+	ExternalID   string         `json:"external_id,omitempty"`
+	Status       string         `json:"status"`
+	ErrorReason  string         `json:"error_reason,omitempty"` // why processing failed, or on ready entries why the preview is missing
+	ErrorDetail  string         `json:"error_detail,omitempty"` // end of the stderr of the media tool that failed
+	Timestamp    int64          `json:"timestamp"`
+	CreatedAt    int64          `json:"created_at"`
+	UpdatedAt    int64          `json:"updated_at"`
+	MimeType     string         `json:"mime_type"`
+	CustomFields map[string]any `json:"custom_fields"`
+}
+
+func (e Entry) GetID() int64        { return e.EntryID }
+func (p PartialEntry) GetID() int64 { return p.EntryID }
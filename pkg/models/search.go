@@ -0,0 +1,62 @@
+package models
+
+// SearchRequest defines the JSON structure for the complex search endpoint.
+type SearchRequest struct {
+	Filter     *FilterGroup  `json:"filter,omitempty"`
+	Sort       *SortCriteria `json:"sort,omitempty"`
+	Pagination Pagination    `json:"pagination"`
+	Fields     []string      `json:"fields,omitempty"` // only return these fields (plus the id), all if empty
+	Geo        *GeoQuery     `json:"geo,omitempty"`    // only entries within the area, needs config.geo_fields
+}
+
+// GeoQuery restricts a search to an area, either bbox or radius. Coordinates are in degrees.
+type GeoQuery struct {
+	BBox   *GeoBBox   `json:"bbox,omitempty"`
+	Radius *GeoRadius `json:"radius,omitempty"`
+}
+
+// GeoBBox is a bounding box, its edges are included. A min_lon greater than max_lon crosses the antimeridian.
+type GeoBBox struct {
+	MinLat float64 `json:"min_lat"`
+	MinLon float64 `json:"min_lon"`
+	MaxLat float64 `json:"max_lat"`
+	MaxLon float64 `json:"max_lon"`
+}
+
+// GeoRadius is a circle around a center, the results can be sorted by "geo_distance" to it.
+type GeoRadius struct {
+	Center GeoPoint `json:"center"`
+	Meters float64  `json:"meters"`
+}
+
+// GeoPoint is a position in degrees.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// FilterGroup allows chaining multiple conditions together.
+type FilterGroup struct {
+	Operator   string      `json:"operator"`   // e.g., "and", "or"
+	Conditions []Condition `json:"conditions"` // The individual rules
+}
+
+// Condition represents a single query filter.
+type Condition struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"` // e.g., "=", ">", "<", "LIKE", "starts_with", "MATCH" on full-text fields
+	Value    any    `json:"value"`    // strings, numbers or booleans; timestamp fields also take relative times like "now-24h"
+}
+
+// SortCriteria defines how the results should be ordered.
+type SortCriteria struct {
+	Field     string `json:"field"`               // or "fts_rank", the relevance of the first MATCH condition, or "geo_distance"
+	Direction string `json:"direction"`           // "asc" or "desc"
+	Collation string `json:"collation,omitempty"` // TEXT fields only: "binary" (default), "nocase" or "unicode" (alphabetical in the server's sort locale)
+}
+
+// Pagination controls the subset of results returned.
+type Pagination struct {
+	Offset int  `json:"offset"`
+	Limit  *int `json:"limit,omitempty"` // the default page size if omitted, clamped to the maximum page size, must be positive
+}